@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/command"
+	"github.com/mitchellh/cli"
+	"github.com/mitchellh/go-homedir"
+)
+
+const (
+	// EnvAutocompleteLine and EnvAutocompletePoint are the environment
+	// variables that bash sets to the command line being completed, and
+	// the position of the cursor in it, when it runs terraform to complete
+	// a command line.
+	EnvAutocompleteLine  = "COMP_LINE"
+	EnvAutocompletePoint = "COMP_POINT"
+
+	// The flags that install and uninstall the shell completion.
+	flagInstallAutocomplete   = "-install-autocomplete"
+	flagUninstallAutocomplete = "-uninstall-autocomplete"
+)
+
+// autocomplete returns the candidates for the last word of line, which is
+// a terraform command line: the names of the commands, and the arguments
+// suggested by the command being completed, if it is a command.Completer.
+func autocomplete(line string, commands map[string]cli.CommandFactory) []string {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return nil
+	}
+
+	// The first word is terraform itself, and the last one is completed
+	// unless the cursor is past it.
+	words = words[1:]
+	var prefix string
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		prefix = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	var candidates []string
+	if len(words) == 0 {
+		candidates = append(candidates,
+			flagInstallAutocomplete, flagUninstallAutocomplete)
+	}
+
+	typed := strings.Join(words, " ")
+	for name := range commands {
+		// Internal commands, such as internal-plugin, aren't suggested
+		if strings.HasPrefix(name, "internal-") {
+			continue
+		}
+
+		parts := strings.Fields(name)
+		if len(parts) == len(words)+1 &&
+			strings.Join(parts[:len(words)], " ") == typed {
+			candidates = append(candidates, parts[len(words)])
+		}
+	}
+
+	// Find the command that's typed, which is the longest that matches,
+	// and ask it for its arguments.
+	for i := len(words); i > 0; i-- {
+		f, ok := commands[strings.Join(words[:i], " ")]
+		if !ok {
+			continue
+		}
+
+		cmd, err := f()
+		if err != nil {
+			break
+		}
+		if c, ok := cmd.(command.Completer); ok {
+			candidates = append(candidates, c.AutocompleteArgs(words[i:], prefix)...)
+		}
+
+		break
+	}
+
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) && !seen[c] {
+			seen[c] = true
+			result = append(result, c)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// runAutocomplete writes the candidates to complete the command line that is
+// set in the environment, one per line, for the shell to read.
+func runAutocomplete() int {
+	line := os.Getenv(EnvAutocompleteLine)
+	if v := os.Getenv(EnvAutocompletePoint); v != "" {
+		if point, err := strconv.Atoi(v); err == nil && point >= 0 && point < len(line) {
+			line = line[:point]
+		}
+	}
+
+	for _, c := range autocomplete(line, Commands) {
+		fmt.Println(c)
+	}
+
+	return 0
+}
+
+// autocompleteScripts returns the shell scripts to install the completion
+// in, with the lines that set it up in each.
+func autocompleteScripts() (map[string][]string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil, err
+	}
+	bin, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	complete := fmt.Sprintf("complete -C %s terraform", bin)
+	return map[string][]string{
+		filepath.Join(home, ".bashrc"): []string{complete},
+		filepath.Join(home, ".zshrc"): []string{
+			"autoload -U +X bashcompinit && bashcompinit",
+			complete,
+		},
+	}, nil
+}
+
+// installAutocomplete sets up the completion of terraform command lines in
+// the scripts of the shells the user has.
+func installAutocomplete() int {
+	scripts, err := autocompleteScripts()
+	if err != nil {
+		Ui.Error(fmt.Sprintf("Error installing the shell completion: %s", err))
+		return 1
+	}
+
+	installed := false
+	for path, lines := range scripts {
+		raw, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			Ui.Error(fmt.Sprintf("Error installing the shell completion: %s", err))
+			return 1
+		}
+
+		existing := strings.Split(string(raw), "\n")
+		var add []string
+		for _, line := range lines {
+			if !containsLine(existing, line) {
+				add = append(add, line)
+			}
+		}
+		installed = true
+		if len(add) == 0 {
+			Ui.Output(fmt.Sprintf("The shell completion is already installed in %s.", path))
+			continue
+		}
+
+		content := string(raw)
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += strings.Join(add, "\n") + "\n"
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			Ui.Error(fmt.Sprintf("Error installing the shell completion: %s", err))
+			return 1
+		}
+
+		Ui.Output(fmt.Sprintf("Installed the shell completion in %s.", path))
+	}
+
+	if !installed {
+		Ui.Error("Error installing the shell completion: " +
+			"no .bashrc or .zshrc was found in the home directory.")
+		return 1
+	}
+
+	return 0
+}
+
+// uninstallAutocomplete removes the completion of terraform command lines
+// from the scripts of the shells the user has. The bashcompinit line is kept
+// for zsh, as other completions may rely on it.
+func uninstallAutocomplete() int {
+	scripts, err := autocompleteScripts()
+	if err != nil {
+		Ui.Error(fmt.Sprintf("Error uninstalling the shell completion: %s", err))
+		return 1
+	}
+
+	for path, lines := range scripts {
+		raw, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			Ui.Error(fmt.Sprintf("Error uninstalling the shell completion: %s", err))
+			return 1
+		}
+
+		complete := lines[len(lines)-1]
+		existing := strings.Split(string(raw), "\n")
+		if !containsLine(existing, complete) {
+			continue
+		}
+
+		kept := make([]string, 0, len(existing))
+		for _, line := range existing {
+			if strings.TrimSpace(line) != complete {
+				kept = append(kept, line)
+			}
+		}
+		if err := ioutil.WriteFile(path, []byte(strings.Join(kept, "\n")), 0644); err != nil {
+			Ui.Error(fmt.Sprintf("Error uninstalling the shell completion: %s", err))
+			return 1
+		}
+
+		Ui.Output(fmt.Sprintf("Uninstalled the shell completion from %s.", path))
+	}
+
+	return 0
+}
+
+func containsLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/mitchellh/go-homedir"
+)
+
+// testCommandCompleter is a command that completes its arguments with the
+// values of Candidates.
+type testCommandCompleter struct {
+	testCommandCLI
+
+	Candidates []string
+	Args       []string
+}
+
+func (c *testCommandCompleter) AutocompleteArgs(args []string, prefix string) []string {
+	c.Args = args
+	return c.Candidates
+}
+
+func TestAutocomplete(t *testing.T) {
+	completer := &testCommandCompleter{
+		Candidates: []string{"aws_instance.foo", "aws_instance.bar", "module.foo"},
+	}
+	commands := map[string]cli.CommandFactory{
+		"plan":            func() (cli.Command, error) { return &testCommandCLI{}, nil },
+		"state":           func() (cli.Command, error) { return &testCommandCLI{}, nil },
+		"state list":      func() (cli.Command, error) { return &testCommandCLI{}, nil },
+		"state show":      func() (cli.Command, error) { return completer, nil },
+		"internal-plugin": func() (cli.Command, error) { return &testCommandCLI{}, nil },
+	}
+
+	cases := []struct {
+		Line     string
+		Expected []string
+		Args     []string
+	}{
+		{
+			"terraform ",
+			[]string{"-install-autocomplete", "-uninstall-autocomplete", "plan", "state"},
+			nil,
+		},
+
+		{
+			"terraform p",
+			[]string{"plan"},
+			nil,
+		},
+
+		{
+			"terraform -i",
+			[]string{"-install-autocomplete"},
+			nil,
+		},
+
+		{
+			"terraform state ",
+			[]string{"list", "show"},
+			nil,
+		},
+
+		{
+			"terraform state show aws",
+			[]string{"aws_instance.bar", "aws_instance.foo"},
+			[]string{},
+		},
+
+		{
+			"terraform state show -state=foo.tfstate ",
+			[]string{"aws_instance.bar", "aws_instance.foo", "module.foo"},
+			[]string{"-state=foo.tfstate"},
+		},
+
+		{
+			"terraform plan ",
+			[]string{},
+			nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Line, func(t *testing.T) {
+			completer.Args = nil
+
+			actual := autocomplete(tc.Line, commands)
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+			if !reflect.DeepEqual(completer.Args, tc.Args) {
+				t.Fatalf("bad args: %#v", completer.Args)
+			}
+		})
+	}
+}
+
+func TestInstallAutocomplete(t *testing.T) {
+	home, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(home)
+
+	oldHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("HOME", home)
+	homedir.DisableCache = true
+	defer func() { homedir.DisableCache = false }()
+
+	oldUi := Ui
+	defer func() { Ui = oldUi }()
+	Ui = new(cli.MockUi)
+
+	// Without any shell scripts there's nothing to install in
+	if code := installAutocomplete(); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+
+	bashrc := filepath.Join(home, ".bashrc")
+	if err := ioutil.WriteFile(bashrc, []byte("export FOO=bar"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Installing twice adds the line once
+	for i := 0; i < 2; i++ {
+		if code := installAutocomplete(); code != 0 {
+			t.Fatalf("bad: %d", code)
+		}
+	}
+
+	raw, err := ioutil.ReadFile(bashrc)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) != 2 || lines[0] != "export FOO=bar" ||
+		!strings.HasPrefix(lines[1], "complete -C ") {
+		t.Fatalf("bad: %q", raw)
+	}
+
+	if _, err := os.Stat(filepath.Join(home, ".zshrc")); !os.IsNotExist(err) {
+		t.Fatalf("the .zshrc should not be created: %v", err)
+	}
+
+	if code := uninstallAutocomplete(); code != 0 {
+		t.Fatalf("bad: %d", code)
+	}
+
+	raw, err = ioutil.ReadFile(bashrc)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if strings.TrimSpace(string(raw)) != "export FOO=bar" {
+		t.Fatalf("bad: %q", raw)
+	}
+}
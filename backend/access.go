@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// The steps of CheckAccess, in the order they're checked.
+const (
+	AccessRead  = "read"
+	AccessLock  = "lock"
+	AccessWrite = "write"
+)
+
+// AccessError is the error returned by CheckAccess when a step of the check
+// fails.
+type AccessError struct {
+	// Step is the step that failed, such as AccessWrite.
+	Step string
+
+	// Err is the error that the backend returned.
+	Err error
+
+	// Hint explains which permissions the step needs, if the backend
+	// knows. It's empty otherwise.
+	Hint string
+}
+
+func (e *AccessError) Error() string {
+	return fmt.Sprintf("failed to %s the state: %s", e.Step, e.Err)
+}
+
+// AccessHinter is an optional interface that a Backend can implement to
+// explain which permissions each step of CheckAccess needs, so that the
+// user doesn't have to work it out from the error of the storage.
+type AccessHinter interface {
+	// AccessHint returns the permissions that the given step needs for
+	// the state with the given name, or "" if there's nothing to add to
+	// the error.
+	AccessHint(name, step string) string
+}
+
+// CheckAccess checks that the state with the given name can be read with b
+// and, if write is true, locked and written, so that missing permissions
+// are found when the backend is initialized rather than when the state is
+// first written at the end of an apply.
+//
+// The state is written back unchanged, so its serial doesn't change, and
+// an empty state is written if there isn't one yet. It's only written while
+// it's locked, since writing it back could otherwise overwrite the write of
+// a concurrent operation. If the state is locked by another operation,
+// writing it isn't checked.
+func CheckAccess(b Backend, name string, write bool) error {
+	// The check isn't worth notifying anyone about
+	if n, ok := b.(*notifyingBackend); ok {
+		b = n.Backend
+	}
+
+	fail := func(step string, err error) error {
+		return &AccessError{Step: step, Err: err, Hint: AccessHint(b, name, step)}
+	}
+
+	if _, err := b.States(); err != nil && err != ErrNamedStatesNotSupported {
+		return fail(AccessRead, err)
+	}
+	s, err := b.State(name)
+	if err != nil {
+		return fail(AccessRead, err)
+	}
+	if err := s.RefreshState(); err != nil {
+		return fail(AccessRead, err)
+	}
+
+	if !write {
+		return nil
+	}
+
+	info := state.NewLockInfo()
+	info.Operation = "init"
+	id, err := s.Lock(info)
+	if err != nil {
+		if le, ok := err.(*state.LockError); ok && le.Info != nil {
+			return &AccessError{
+				Step: AccessLock,
+				Err: fmt.Errorf(
+					"the state is locked by another operation, so writing "+
+						"it couldn't be checked:\n%s", err),
+			}
+		}
+
+		return fail(AccessLock, err)
+	}
+
+	writeErr := writeAccess(s, fail)
+
+	// Unlocking is checked too, since a lock that can't be released blocks
+	// every later operation.
+	if err := s.Unlock(id); err != nil && writeErr == nil {
+		return fail(AccessLock, err)
+	}
+
+	return writeErr
+}
+
+// writeAccess writes the current state of s back to the storage, or an
+// empty state if there isn't one yet.
+func writeAccess(s state.State, fail func(string, error) error) error {
+	current := s.State()
+	if current == nil {
+		current = terraform.NewState()
+	}
+	if err := s.WriteState(current); err != nil {
+		return fail(AccessWrite, err)
+	}
+	if err := s.PersistState(); err != nil {
+		return fail(AccessWrite, err)
+	}
+
+	return nil
+}
+
+// AccessHint returns the permissions that the given step of CheckAccess
+// needs for the state with the given name in b, if b is an AccessHinter,
+// possibly wrapped by the backends of this package.
+func AccessHint(b Backend, name, step string) string {
+	for {
+		// The lock of an externally locked backend is in another backend
+		if l, ok := b.(*lockedBackend); ok && step == AccessLock {
+			b = l.locker
+			continue
+		}
+
+		switch w := b.(type) {
+		case unwrapper:
+			b = w.Unwrap()
+		case AccessHinter:
+			return w.AccessHint(name, step)
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package backend
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// failingClient is a memClient that fails to read or store the state.
+type failingClient struct {
+	memClient
+
+	getErr, putErr error
+}
+
+func (c *failingClient) Get() (*remote.Payload, error) {
+	if c.getErr != nil {
+		return nil, c.getErr
+	}
+
+	return c.memClient.Get()
+}
+
+func (c *failingClient) Put(data []byte) error {
+	if c.putErr != nil {
+		return c.putErr
+	}
+
+	return c.memClient.Put(data)
+}
+
+// hintedNil is a remoteNil that explains the permissions it needs.
+type hintedNil struct {
+	remoteNil
+}
+
+func (b *hintedNil) AccessHint(name, step string) string {
+	return "need " + step + " on " + name
+}
+
+func TestCheckAccess(t *testing.T) {
+	client := new(memClient)
+	if err := CheckAccess(&remoteNil{client: client}, DefaultStateName, true); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// An empty state is written when there isn't one
+	if client.data == nil {
+		t.Fatal("state should be written")
+	}
+}
+
+func TestCheckAccess_readOnly(t *testing.T) {
+	client := new(memClient)
+	if err := CheckAccess(&remoteNil{client: client}, DefaultStateName, false); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if client.data != nil {
+		t.Fatal("state shouldn't be written")
+	}
+}
+
+func TestCheckAccess_unchanged(t *testing.T) {
+	original := terraform.NewState()
+	original.Serial = 5
+	var buf bytes.Buffer
+	if err := terraform.WriteState(original, &buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	client := &memClient{data: buf.Bytes()}
+	if err := CheckAccess(&remoteNil{client: client}, DefaultStateName, true); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := terraform.ReadState(bytes.NewReader(client.data))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual.Serial != 5 || actual.Lineage != original.Lineage {
+		t.Fatalf("state should be unchanged: %s", client.data)
+	}
+}
+
+func TestCheckAccess_read(t *testing.T) {
+	client := &failingClient{getErr: errors.New("access denied")}
+	err := CheckAccess(&hintedNil{remoteNil{client: client}}, "prod", true)
+
+	ae, ok := err.(*AccessError)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+	if ae.Step != AccessRead || ae.Hint != "need read on prod" {
+		t.Fatalf("bad: %#v", ae)
+	}
+	if !strings.Contains(ae.Error(), "failed to read the state: access denied") {
+		t.Fatalf("bad: %s", ae)
+	}
+}
+
+func TestCheckAccess_write(t *testing.T) {
+	defer testEncryptionKey(t)()
+
+	client := &failingClient{putErr: errors.New("access denied")}
+	b, err := Encrypted(&hintedNil{remoteNil{client: client}}, &EncryptionConfig{
+		Type:   "aes_gcm",
+		Config: map[string]string{},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The hint is found through the wrapper
+	ae, ok := CheckAccess(b, DefaultStateName, true).(*AccessError)
+	if !ok || ae.Step != AccessWrite || ae.Hint != "need write on default" {
+		t.Fatalf("bad: %#v", ae)
+	}
+}
+
+func TestAccessHint_wrapped(t *testing.T) {
+	var b Backend = &hintedNil{remoteNil{client: new(memClient)}}
+	b, err := Compressed(b, &CompressionConfig{Gzip: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	b, err = StoringBlobs(b, &BlobsConfig{MinSize: DefaultBlobMinSize})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	b, err = ReadOnly(b)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	b, err = Notifying(b, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if hint := AccessHint(b, "prod", AccessRead); hint != "need read on prod" {
+		t.Fatalf("bad: %q", hint)
+	}
+}
+
+func TestCheckAccess_lock(t *testing.T) {
+	locker := &lockerNil{locked: map[string]bool{DefaultStateName: true}}
+	b, err := ExternallyLocked(&remoteNil{client: new(memClient)}, locker)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ae, ok := CheckAccess(b, DefaultStateName, true).(*AccessError)
+	if !ok || ae.Step != AccessLock {
+		t.Fatalf("bad: %#v", ae)
+	}
+
+	// Without writing, the lock isn't checked
+	if err := CheckAccess(b, DefaultStateName, false); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestCheckAccess_locked(t *testing.T) {
+	ae, ok := CheckAccess(new(lockedNil), DefaultStateName, true).(*AccessError)
+	if !ok || ae.Step != AccessLock || ae.Hint != "" {
+		t.Fatalf("bad: %#v", ae)
+	}
+	if !strings.Contains(ae.Error(), "locked by another operation") {
+		t.Fatalf("bad: %s", ae)
+	}
+}
+
+// lockedNil is a backend whose states are locked by another operation.
+type lockedNil struct {
+	Nil
+}
+
+func (b *lockedNil) State(string) (state.State, error) {
+	return &lockedState{InmemState: new(state.InmemState)}, nil
+}
+
+type lockedState struct {
+	*state.InmemState
+}
+
+func (s *lockedState) Lock(*state.LockInfo) (string, error) {
+	info := state.NewLockInfo()
+	info.Operation = "apply"
+	return "", &state.LockError{Info: info, Err: errors.New("state locked")}
+}
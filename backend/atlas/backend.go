@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/helper/credentials"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/state/remote"
@@ -61,6 +62,11 @@ func (b *Backend) Configure(c *terraform.ResourceConfig) error {
 	return b.schema.Configure(c)
 }
 
+func (b *Backend) SensitiveKeys() map[string]bool {
+	b.once.Do(b.init)
+	return b.schema.SensitiveKeys()
+}
+
 func (b *Backend) States() ([]string, error) {
 	return nil, backend.ErrNamedStatesNotSupported
 }
@@ -102,7 +108,8 @@ func (b *Backend) init() {
 
 			"access_token": &schema.Schema{
 				Type:        schema.TypeString,
-				Required:    true,
+				Optional:    true,
+				Sensitive:   true,
 				Description: schemaDescriptions["access_token"],
 				DefaultFunc: schema.EnvDefaultFunc("ATLAS_TOKEN", nil),
 			},
@@ -138,11 +145,17 @@ func (b *Backend) schemaConfigure(ctx context.Context) error {
 	org := parts[0]
 	env := parts[1]
 
+	// Without a token, use the one saved by "terraform login" for the host
+	token := d.Get("access_token").(string)
+	if token == "" && addrUrl.Scheme == "https" {
+		token = credentials.Token(addrUrl.Host)
+	}
+
 	// Setup the client
 	b.stateClient = &stateClient{
 		Server:      addr,
 		ServerURL:   addrUrl,
-		AccessToken: d.Get("access_token").(string),
+		AccessToken: token,
 		User:        org,
 		Name:        env,
 
@@ -156,7 +169,8 @@ func (b *Backend) schemaConfigure(ctx context.Context) error {
 var schemaDescriptions = map[string]string{
 	"name": "Full name of the environment in Atlas, such as 'hashicorp/myenv'",
 	"access_token": "Access token to use to access Atlas. If ATLAS_TOKEN is set then\n" +
-		"this will override any saved value for this.",
+		"this will override any saved value for this. Defaults to the token for the\n" +
+		"host saved by \"terraform login\".",
 	"address": "Address to your Atlas installation. This defaults to the publicly\n" +
 		"hosted version at 'https://atlas.hashicorp.com/'. This address\n" +
 		"should contain the full HTTP scheme to use.",
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/credentials"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -47,3 +48,23 @@ func TestConfigure_envToken(t *testing.T) {
 		t.Fatalf("bad: %#v", b.stateClient)
 	}
 }
+
+func TestConfigure_credentialsToken(t *testing.T) {
+	defer os.Setenv("ATLAS_TOKEN", os.Getenv("ATLAS_TOKEN"))
+	os.Unsetenv("ATLAS_TOKEN")
+	defer func(static map[string]string) { credentials.Default.Static = static }(credentials.Default.Static)
+	credentials.Default.Static = map[string]string{"atlas.example.com": "foo"}
+
+	b := &Backend{}
+	err := b.Configure(terraform.NewResourceConfig(config.TestRawConfig(t, map[string]interface{}{
+		"name":    "foo/bar",
+		"address": "https://atlas.example.com",
+	})))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if b.stateClient.AccessToken != "foo" {
+		t.Fatalf("bad: %#v", b.stateClient)
+	}
+}
@@ -364,6 +364,7 @@ var testStateSimple = []byte(
     "version": 3,
     "serial": 2,
     "lineage": "c00ad9ac-9b35-42fe-846e-b06f0ef877e9",
+    "checksum": "fa356bbf576183fc01f2ffbfe57adf3c8ef458025ae6a0b0e1521b6c7fc87e64",
     "modules": [
         {
             "path": [
@@ -70,6 +70,15 @@ type Enhanced interface {
 	Operation(context.Context, *Operation) (*RunningOperation, error)
 }
 
+// Sensitive is an optional interface for backends that know which
+// arguments of their configuration are secrets, so that their values can
+// be kept out of output.
+type Sensitive interface {
+	// SensitiveKeys returns whether the value of each argument of the
+	// configuration is a secret, by the name of the argument.
+	SensitiveKeys() map[string]bool
+}
+
 // Local implements additional behavior on a Backend that allows local
 // operations in addition to remote operations.
 //
@@ -112,6 +121,28 @@ type Operation struct {
 	PlanOutPath    string // PlanOutPath is the path to save the plan
 	PlanOutBackend *terraform.BackendState
 
+	// PlanRefreshChanged limits the refresh before a plan to the resources
+	// that changes to the configuration can affect. See
+	// terraform.Context.RefreshChanged.
+	PlanRefreshChanged bool
+
+	// PlanExplain are the addresses of resources to explain the changes to
+	// after a plan, along with the changes to the resources they depend on.
+	PlanExplain []string
+
+	// PlanConfidence reports which parts of the plan are speculative after
+	// a plan: the values that are unknown until apply, and the changes that
+	// depend on them. See terraform.Plan.Confidence.
+	PlanConfidence bool
+
+	// PlanGraphOutPath is the path to write the apply graph of the plan to,
+	// as a terraform.GraphExport.
+	PlanGraphOutPath string
+
+	// GraphOrder is an ordering of the apply graph, computed outside of
+	// Terraform, for an apply to follow.
+	GraphOrder *terraform.GraphOrder
+
 	// Module settings specify the root module to use for operations.
 	Module *module.Tree
 
@@ -121,9 +152,10 @@ type Operation struct {
 
 	// The options below are more self-explanatory and affect the runtime
 	// behavior of the operation.
-	Destroy   bool
-	Targets   []string
-	Variables map[string]interface{}
+	Destroy      bool
+	Targets      []string
+	ForceReplace []string
+	Variables    map[string]interface{}
 
 	// Input/output/control options.
 	UIIn  terraform.UIInput
@@ -136,8 +168,18 @@ type Operation struct {
 	// The duration to retry obtaining a State lock.
 	StateLockTimeout time.Duration
 
+	// If LockSubtree is true, only the modules of the Targets are locked,
+	// if the state is a state.SubtreeLocker that supports it, so that other
+	// modules of the state can be changed at the same time.
+	LockSubtree bool
+
 	// Environment is the named state that should be loaded from the Backend.
 	Environment string
+
+	// ResultOutPath is the path to write a machine-readable result of an
+	// apply operation to, with the outcome of each resource and the outputs.
+	// It is written whether the apply succeeds or not.
+	ResultOutPath string
 }
 
 // RunningOperation is the result of starting an operation.
@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/mitchellh/mapstructure"
+)
+
+// BlobsConfigKey is the key of the block in the configuration of a backend
+// that configures storing the large values of its states as separate
+// blobs. Like the compression block, it's handled outside of the backend,
+// and backends must not use this key themselves.
+const BlobsConfigKey = "blobs"
+
+// DefaultBlobMinSize is the size in bytes of the smallest values that are
+// stored as blobs if the blobs block doesn't set it.
+const DefaultBlobMinSize = 64 * 1024
+
+// BlobsConfig is the configuration of storing values as blobs for a
+// backend.
+type BlobsConfig struct {
+	// MinSize is the size in bytes of the smallest values that are stored
+	// as blobs.
+	MinSize int `mapstructure:"min_size"`
+}
+
+// SplitBlobsConfig removes the blobs block from the raw configuration of a
+// backend. It returns the rest of the configuration, along with the blobs
+// configuration if the block was present.
+func SplitBlobsConfig(raw map[string]interface{}) (map[string]interface{}, *BlobsConfig, error) {
+	rest, block, err := splitBlock(raw, BlobsConfigKey)
+	if err != nil || block == nil {
+		return rest, nil, err
+	}
+
+	c := &BlobsConfig{MinSize: DefaultBlobMinSize}
+	var md mapstructure.Metadata
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Metadata:         &md,
+		Result:           c,
+		WeaklyTypedInput: true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := decoder.Decode(block); err != nil {
+		return nil, nil, fmt.Errorf("%s: %s", BlobsConfigKey, err)
+	}
+	if len(md.Unused) > 0 {
+		return nil, nil, fmt.Errorf(
+			"%s: unknown keys: %v", BlobsConfigKey, md.Unused)
+	}
+	if c.MinSize < 1 {
+		return nil, nil, fmt.Errorf(
+			"%s: min_size must be positive", BlobsConfigKey)
+	}
+
+	return rest, c, nil
+}
+
+// WrapStorage returns b, wrapped to encrypt and compress its states and to
+// store their large values as blobs, as configured by the blocks that are
+// given. The wrappers are applied in the same order wherever the states of
+// a backend are read or written, so that they can be read back.
+func WrapStorage(b Backend, enc *EncryptionConfig, comp *CompressionConfig, blobs *BlobsConfig) (Backend, error) {
+	var err error
+	if enc != nil {
+		b, err = Encrypted(b, enc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// States are compressed before they're encrypted, since encrypted data
+	// doesn't compress.
+	if comp != nil {
+		b, err = Compressed(b, comp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Large values are taken out of the states first, so that the blobs
+	// are compressed and encrypted like the states.
+	if blobs != nil {
+		b, err = StoringBlobs(b, blobs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// StoringBlobs returns a Backend that stores the values of at least the
+// configured size in the states of b as blobs, next to the states.
+//
+// Only backends that store their states with a remote.Client that
+// implements remote.ClientBlobStorer can store blobs.
+func StoringBlobs(b Backend, c *BlobsConfig) (Backend, error) {
+	return wrapClient(b, "storing blobs", func(client remote.Client) (remote.Client, error) {
+		if remote.BlobStorer(client) == nil {
+			return nil, errors.New("backend doesn't support storing blobs")
+		}
+		return remote.NewBlobClient(client, c.MinSize), nil
+	})
+}
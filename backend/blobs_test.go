@@ -0,0 +1,176 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestSplitBlobsConfig(t *testing.T) {
+	cases := map[string]struct {
+		Raw      map[string]interface{}
+		Expected *BlobsConfig
+		Err      bool
+	}{
+		"none": {
+			map[string]interface{}{"path": "foo"},
+			nil,
+			false,
+		},
+
+		"defaults": {
+			map[string]interface{}{
+				"path":  "foo",
+				"blobs": []interface{}{map[string]interface{}{}},
+			},
+			&BlobsConfig{MinSize: DefaultBlobMinSize},
+			false,
+		},
+
+		"configured": {
+			map[string]interface{}{
+				"path": "foo",
+				"blobs": []map[string]interface{}{
+					map[string]interface{}{"min_size": "1024"},
+				},
+			},
+			&BlobsConfig{MinSize: 1024},
+			false,
+		},
+
+		"unknown key": {
+			map[string]interface{}{
+				"blobs": []interface{}{
+					map[string]interface{}{"prefix": "foo"},
+				},
+			},
+			nil,
+			true,
+		},
+
+		"bad size": {
+			map[string]interface{}{
+				"blobs": []interface{}{
+					map[string]interface{}{"min_size": 0},
+				},
+			},
+			nil,
+			true,
+		},
+	}
+
+	for name, tc := range cases {
+		rest, blobs, err := SplitBlobsConfig(tc.Raw)
+		if err != nil != tc.Err {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(blobs, tc.Expected) {
+			t.Fatalf("%s: bad: %#v", name, blobs)
+		}
+		if !reflect.DeepEqual(rest, map[string]interface{}{"path": "foo"}) {
+			t.Fatalf("%s: bad: %#v", name, rest)
+		}
+	}
+}
+
+// memBlobClient is a memClient that stores blobs.
+type memBlobClient struct {
+	memClient
+	blobs map[string][]byte
+}
+
+func (c *memBlobClient) PutBlob(id string, data []byte) error {
+	if c.blobs == nil {
+		c.blobs = make(map[string][]byte)
+	}
+
+	c.blobs[id] = data
+	return nil
+}
+
+func (c *memBlobClient) GetBlob(id string) ([]byte, error) {
+	data, ok := c.blobs[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown blob %q", id)
+	}
+
+	return data, nil
+}
+
+func (c *memBlobClient) DeleteBlob(id string) error {
+	delete(c.blobs, id)
+	return nil
+}
+
+func TestStoringBlobs(t *testing.T) {
+	client := new(memBlobClient)
+	b, err := StoringBlobs(&remoteNil{client: client}, &BlobsConfig{MinSize: 1024})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	s, err := b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	rendered := strings.Repeat("rendered", 1000)
+	original := terraform.NewState()
+	original.AddModuleState(&terraform.ModuleState{
+		Path: terraform.RootModulePath,
+		Resources: map[string]*terraform.ResourceState{
+			"template_file.foo": &terraform.ResourceState{
+				Type: "template_file",
+				Primary: &terraform.InstanceState{
+					ID:         "foo",
+					Attributes: map[string]string{"rendered": rendered},
+				},
+			},
+		},
+	})
+	if err := s.WriteState(original); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if bytes.Contains(client.data, []byte(rendered)) {
+		t.Fatalf("value should be stored as a blob: %s", client.data)
+	}
+	if len(client.blobs) != 1 {
+		t.Fatalf("bad: %#v", client.blobs)
+	}
+
+	// A new state reads it back with the blobs
+	s, err = b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	rs := s.State().RootModule().Resources["template_file.foo"]
+	if rs == nil || rs.Primary.Attributes["rendered"] != rendered {
+		t.Fatalf("bad: %s", s.State())
+	}
+}
+
+func TestStoringBlobs_notSupported(t *testing.T) {
+	b, err := StoringBlobs(&remoteNil{client: new(memClient)}, &BlobsConfig{MinSize: 1024})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := b.State(DefaultStateName); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"github.com/hashicorp/terraform/helper/warnings"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 	"github.com/mitchellh/colorstring"
@@ -71,4 +72,12 @@ type CLIOpts struct {
 	// Validate.
 	Input      bool
 	Validation bool
+
+	// Warnings collects the warnings that are shown, and decides which are
+	// suppressed. If it is nil, every warning is shown.
+	Warnings *warnings.Collector
+
+	// ReadOnly is true in read-only mode, in which states must not be
+	// written, created or deleted.
+	ReadOnly bool
 }
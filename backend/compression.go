@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/mitchellh/mapstructure"
+)
+
+// CompressionConfigKey is the key of the block in the configuration of a
+// backend that configures compression of the states it stores. Like the
+// encryption block, it's handled outside of the backend, and backends must
+// not use this key themselves.
+const CompressionConfigKey = "compression"
+
+// DefaultMinStringLength is the length of the shortest strings that are
+// deduplicated if the compression block doesn't set it.
+const DefaultMinStringLength = 256
+
+// CompressionConfig is the configuration of state compression for a backend.
+type CompressionConfig struct {
+	// Gzip compresses the states with gzip.
+	Gzip bool `mapstructure:"gzip"`
+
+	// DeduplicateStrings stores the strings of at least MinStringLength
+	// that occur more than once in a state only once.
+	DeduplicateStrings bool `mapstructure:"deduplicate_strings"`
+	MinStringLength    int  `mapstructure:"min_string_length"`
+}
+
+// SplitCompressionConfig removes the compression block from the raw
+// configuration of a backend. It returns the rest of the configuration,
+// along with the compression configuration if the block was present.
+func SplitCompressionConfig(raw map[string]interface{}) (map[string]interface{}, *CompressionConfig, error) {
+	rest, block, err := splitBlock(raw, CompressionConfigKey)
+	if err != nil || block == nil {
+		return rest, nil, err
+	}
+
+	c := &CompressionConfig{
+		Gzip:               true,
+		DeduplicateStrings: true,
+		MinStringLength:    DefaultMinStringLength,
+	}
+	var md mapstructure.Metadata
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Metadata:         &md,
+		Result:           c,
+		WeaklyTypedInput: true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := decoder.Decode(block); err != nil {
+		return nil, nil, fmt.Errorf("%s: %s", CompressionConfigKey, err)
+	}
+	if len(md.Unused) > 0 {
+		return nil, nil, fmt.Errorf(
+			"%s: unknown keys: %v", CompressionConfigKey, md.Unused)
+	}
+	if c.MinStringLength < 1 {
+		return nil, nil, fmt.Errorf(
+			"%s: min_string_length must be positive", CompressionConfigKey)
+	}
+
+	return rest, c, nil
+}
+
+// Compressed returns a Backend that compresses every state stored by b as
+// configured by c.
+//
+// Only backends that store their states with a remote.Client can be
+// compressed.
+func Compressed(b Backend, c *CompressionConfig) (Backend, error) {
+	var minStringLength int
+	if c.DeduplicateStrings {
+		minStringLength = c.MinStringLength
+	}
+
+	return wrapClient(b, "state compression", func(client remote.Client) (remote.Client, error) {
+		return remote.NewCompressedClient(client, c.Gzip, minStringLength), nil
+	})
+}
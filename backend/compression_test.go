@@ -0,0 +1,145 @@
+package backend
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestSplitCompressionConfig(t *testing.T) {
+	cases := map[string]struct {
+		Raw      map[string]interface{}
+		Expected *CompressionConfig
+		Err      bool
+	}{
+		"none": {
+			map[string]interface{}{"path": "foo"},
+			nil,
+			false,
+		},
+
+		"defaults": {
+			map[string]interface{}{
+				"path":        "foo",
+				"compression": []interface{}{map[string]interface{}{}},
+			},
+			&CompressionConfig{
+				Gzip:               true,
+				DeduplicateStrings: true,
+				MinStringLength:    DefaultMinStringLength,
+			},
+			false,
+		},
+
+		"configured": {
+			map[string]interface{}{
+				"path": "foo",
+				"compression": []map[string]interface{}{
+					map[string]interface{}{
+						"gzip":              false,
+						"min_string_length": "64",
+					},
+				},
+			},
+			&CompressionConfig{
+				Gzip:               false,
+				DeduplicateStrings: true,
+				MinStringLength:    64,
+			},
+			false,
+		},
+
+		"unknown key": {
+			map[string]interface{}{
+				"compression": []interface{}{
+					map[string]interface{}{"level": 9},
+				},
+			},
+			nil,
+			true,
+		},
+
+		"bad length": {
+			map[string]interface{}{
+				"compression": []interface{}{
+					map[string]interface{}{"min_string_length": 0},
+				},
+			},
+			nil,
+			true,
+		},
+	}
+
+	for name, tc := range cases {
+		rest, comp, err := SplitCompressionConfig(tc.Raw)
+		if err != nil != tc.Err {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(comp, tc.Expected) {
+			t.Fatalf("%s: bad: %#v", name, comp)
+		}
+		if !reflect.DeepEqual(rest, map[string]interface{}{"path": "foo"}) {
+			t.Fatalf("%s: bad: %#v", name, rest)
+		}
+	}
+}
+
+func TestCompressed(t *testing.T) {
+	client := new(memClient)
+	b, err := Compressed(&remoteNil{client: client}, &CompressionConfig{
+		Gzip:               true,
+		DeduplicateStrings: true,
+		MinStringLength:    DefaultMinStringLength,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	s, err := b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	original := terraform.NewState()
+	original.Lineage = strings.Repeat("lineage", 100)
+	if err := s.WriteState(original); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if bytes.Contains(client.data, []byte(original.Lineage)) {
+		t.Fatalf("state should be compressed: %s", client.data)
+	}
+
+	// A new state reads it back through the compressed client
+	s, err = b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if s.State().Lineage != original.Lineage {
+		t.Fatalf("bad: %#v", s.State())
+	}
+}
+
+func TestCompressed_notRemote(t *testing.T) {
+	b, err := Compressed(Nil{}, &CompressionConfig{Gzip: true})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := b.State(DefaultStateName); err == nil {
+		t.Fatal("should error")
+	}
+}
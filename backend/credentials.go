@@ -0,0 +1,143 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// Credentials supplies credentials that a backend is configured with, such
+// as ones read from Vault, which can change while the backend is used.
+type Credentials interface {
+	// BackendCredentials returns the arguments to configure the backend
+	// with, and a generation that changes whenever the arguments do.
+	// Arguments that are set in the configuration of the backend take
+	// precedence.
+	BackendCredentials() (map[string]string, uint64, error)
+}
+
+// WithCredentials returns a Backend that configures b with the arguments
+// from creds in addition to its configuration, and configures it again
+// when they change. The states of b that are stored with a remote client
+// are then stored with the client of the backend as it's configured now.
+//
+// It must wrap b before it's configured, and before it's wrapped by
+// anything else. Enhanced backends manage their own storage, so they can't
+// be configured with credentials.
+func WithCredentials(b Backend, creds Credentials) (Backend, error) {
+	if _, ok := b.(Enhanced); ok {
+		return nil, fmt.Errorf("the %T backend doesn't support credentials from Vault", b)
+	}
+
+	return &credentialsBackend{wrapper: wrapper{b}, creds: creds}, nil
+}
+
+type credentialsBackend struct {
+	wrapper
+
+	creds Credentials
+
+	// config is the configuration of the backend without the credentials,
+	// and gen the generation of the credentials it was configured with.
+	mu     sync.Mutex
+	config *terraform.ResourceConfig
+	gen    uint64
+}
+
+func (b *credentialsBackend) Configure(c *terraform.ResourceConfig) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.config = c
+	return b.configure()
+}
+
+// configure configures the backend with its configuration and the current
+// credentials. The lock must be held.
+func (b *credentialsBackend) configure() error {
+	creds, gen, err := b.creds.BackendCredentials()
+	if err != nil {
+		return fmt.Errorf("Error reading the credentials of the backend: %s", err)
+	}
+
+	if err := b.Backend.Configure(b.config.WithDefaults(creds)); err != nil {
+		return err
+	}
+
+	b.gen = gen
+	return nil
+}
+
+// generation configures the backend again if the credentials changed since
+// it was configured, and returns the generation of the credentials it's
+// configured with.
+func (b *credentialsBackend) generation() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, gen, err := b.creds.BackendCredentials()
+	if err != nil {
+		return 0, fmt.Errorf("Error reading the credentials of the backend: %s", err)
+	}
+	if gen == b.gen {
+		return gen, nil
+	}
+
+	log.Printf("[INFO] Configuring the backend again with new credentials")
+	if err := b.configure(); err != nil {
+		return 0, err
+	}
+
+	return b.gen, nil
+}
+
+func (b *credentialsBackend) State(name string) (state.State, error) {
+	b.mu.Lock()
+	gen := b.gen
+	b.mu.Unlock()
+
+	s, err := b.Backend.State(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// States that aren't stored with a remote client keep storing them as
+	// the backend was configured when they were returned.
+	rs := remoteState(s)
+	if rs == nil {
+		return s, nil
+	}
+
+	var mu sync.Mutex
+	client := rs.Client
+	rs.Client = remote.NewDynamicClient(client, func() (remote.Client, error) {
+		newGen, err := b.generation()
+		if err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if newGen != gen {
+			s, err := b.Backend.State(name)
+			if err != nil {
+				return nil, err
+			}
+			rs := remoteState(s)
+			if rs == nil {
+				return nil, fmt.Errorf("backend no longer stores state %q with a remote client", name)
+			}
+
+			client, gen = rs.Client, newGen
+		}
+
+		return client, nil
+	})
+
+	return s, nil
+}
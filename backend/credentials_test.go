@@ -0,0 +1,111 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestWithCredentials(t *testing.T) {
+	creds := &mockCredentials{Creds: map[string]string{"token": "first", "path": "creds"}}
+	inner := &credentialsNil{clients: make(map[string]*memClient)}
+	b, err := WithCredentials(inner, creds)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	raw, err := config.NewRawConfig(map[string]interface{}{"path": "foo"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := b.Configure(terraform.NewResourceConfig(raw)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The configuration takes precedence over the credentials
+	if inner.token != "first" || inner.path != "foo" {
+		t.Fatalf("bad: %#v", inner)
+	}
+
+	s, err := b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.WriteState(terraform.NewState()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if inner.clients["first"].data == nil {
+		t.Fatal("state should be stored with the first credentials")
+	}
+
+	// Once the credentials change, the backend is configured again, and
+	// the state is stored with its new client
+	creds.Creds["token"] = "second"
+	creds.Gen++
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if inner.token != "second" {
+		t.Fatalf("bad: %#v", inner)
+	}
+	if inner.clients["second"] == nil || inner.clients["second"].data == nil {
+		t.Fatal("state should be stored with the second credentials")
+	}
+}
+
+func TestWithCredentials_enhanced(t *testing.T) {
+	if _, err := WithCredentials(new(enhancedNil), new(mockCredentials)); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+// enhancedNil is an enhanced backend that does nothing.
+type enhancedNil struct {
+	Nil
+}
+
+func (enhancedNil) Operation(context.Context, *Operation) (*RunningOperation, error) {
+	return nil, nil
+}
+
+// mockCredentials is a Credentials with static credentials.
+type mockCredentials struct {
+	Creds map[string]string
+	Gen   uint64
+}
+
+func (c *mockCredentials) BackendCredentials() (map[string]string, uint64, error) {
+	return c.Creds, c.Gen, nil
+}
+
+// credentialsNil is a backend that stores its states with a client for
+// each token it's configured with.
+type credentialsNil struct {
+	Nil
+
+	token, path string
+	clients     map[string]*memClient
+}
+
+func (b *credentialsNil) Configure(c *terraform.ResourceConfig) error {
+	v, _ := c.Get("token")
+	b.token, _ = v.(string)
+	v, _ = c.Get("path")
+	b.path, _ = v.(string)
+	return nil
+}
+
+func (b *credentialsNil) State(string) (state.State, error) {
+	if b.clients[b.token] == nil {
+		b.clients[b.token] = new(memClient)
+	}
+
+	return &remote.State{Client: b.clients[b.token]}, nil
+}
@@ -0,0 +1,145 @@
+package backend
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+)
+
+// EncryptionConfigKey is the key of the block in the configuration of a
+// backend that configures encryption of the states it stores. The block is
+// handled outside of the backend so that it works the same way for every
+// backend, and backends must not use this key themselves.
+const EncryptionConfigKey = "encryption"
+
+// EncryptionConfig is the configuration of state encryption for a backend.
+type EncryptionConfig struct {
+	// Type is the type of encrypter, as registered in
+	// state.BuiltinEncrypters.
+	Type string
+
+	// Config is the configuration of the encrypter.
+	Config map[string]string
+
+	// AllowUnencryptedRead allows reading states that aren't encrypted,
+	// while migrating states that were stored before encryption was
+	// enabled. Otherwise reading them is an error.
+	AllowUnencryptedRead bool
+}
+
+// SplitEncryptionConfig removes the encryption block from the raw
+// configuration of a backend. It returns the rest of the configuration,
+// along with the encryption configuration if the block was present.
+func SplitEncryptionConfig(raw map[string]interface{}) (map[string]interface{}, *EncryptionConfig, error) {
+	rest, block, err := splitBlock(raw, EncryptionConfigKey)
+	if err != nil || block == nil {
+		return rest, nil, err
+	}
+
+	c := &EncryptionConfig{Config: make(map[string]string)}
+	for k, v := range block {
+		switch v.(type) {
+		case string, int, bool, float64:
+		default:
+			return nil, nil, fmt.Errorf(
+				"%s: %s must be a primitive value", EncryptionConfigKey, k)
+		}
+
+		switch k {
+		case "type":
+			c.Type = fmt.Sprintf("%v", v)
+			continue
+		case "allow_unencrypted_read":
+			allow, err := strconv.ParseBool(fmt.Sprintf("%v", v))
+			if err != nil {
+				return nil, nil, fmt.Errorf(
+					"%s: %s must be a boolean", EncryptionConfigKey, k)
+			}
+			c.AllowUnencryptedRead = allow
+			continue
+		}
+
+		c.Config[k] = fmt.Sprintf("%v", v)
+	}
+	if c.Type == "" {
+		return nil, nil, fmt.Errorf("%s: type is required", EncryptionConfigKey)
+	}
+
+	return rest, c, nil
+}
+
+// Encrypted returns a Backend that encrypts every state stored by b as
+// configured by c.
+//
+// Only backends that store their states with a remote.Client can be
+// encrypted. Published outputs aren't encrypted, since they are meant to be
+// read by other configurations.
+func Encrypted(b Backend, c *EncryptionConfig) (Backend, error) {
+	e, err := state.NewEncrypter(c.Type, c.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapClient(b, "state encryption", func(client remote.Client) (remote.Client, error) {
+		if c.AllowUnencryptedRead {
+			return remote.NewMigratingEncryptedClient(client, c.Type, e), nil
+		}
+		return remote.NewEncryptedClient(client, c.Type, e), nil
+	})
+}
+
+// splitBlock removes the block with the given key from the raw
+// configuration of a backend. It returns the rest of the configuration,
+// along with the contents of the block if it was present.
+func splitBlock(raw map[string]interface{}, key string) (map[string]interface{}, map[string]interface{}, error) {
+	rest, blocks, err := splitBlocks(raw, key)
+	if err != nil || blocks == nil {
+		return rest, nil, err
+	}
+	if len(blocks) != 1 {
+		return nil, nil, fmt.Errorf("%s: must be a single block", key)
+	}
+
+	return rest, blocks[0], nil
+}
+
+// splitBlocks removes the blocks with the given key from the raw
+// configuration of a backend. It returns the rest of the configuration,
+// along with the contents of the blocks if any were present.
+func splitBlocks(raw map[string]interface{}, key string) (map[string]interface{}, []map[string]interface{}, error) {
+	v, ok := raw[key]
+	if !ok {
+		return raw, nil, nil
+	}
+
+	rest := make(map[string]interface{}, len(raw)-1)
+	for k, v := range raw {
+		if k != key {
+			rest[k] = v
+		}
+	}
+
+	// Blocks decode as a list of maps, but the list type depends on
+	// whether the configuration came from HCL or from saved JSON.
+	blocks := []map[string]interface{}{}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		blocks = append(blocks, v)
+	case []map[string]interface{}:
+		blocks = append(blocks, v...)
+	case []interface{}:
+		for _, b := range v {
+			block, ok := b.(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("%s: must be a block", key)
+			}
+			blocks = append(blocks, block)
+		}
+	default:
+		return nil, nil, fmt.Errorf("%s: must be a block", key)
+	}
+
+	return rest, blocks, nil
+}
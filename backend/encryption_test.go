@@ -0,0 +1,262 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestSplitEncryptionConfig(t *testing.T) {
+	block := map[string]interface{}{
+		"type": "aes_gcm",
+		"key":  "foo",
+	}
+	expected := &EncryptionConfig{
+		Type:   "aes_gcm",
+		Config: map[string]string{"key": "foo"},
+	}
+
+	cases := map[string]struct {
+		Raw      map[string]interface{}
+		Expected *EncryptionConfig
+		Err      bool
+	}{
+		"none": {
+			map[string]interface{}{"path": "foo"},
+			nil,
+			false,
+		},
+
+		"hcl": {
+			map[string]interface{}{
+				"path":       "foo",
+				"encryption": []map[string]interface{}{block},
+			},
+			expected,
+			false,
+		},
+
+		"saved": {
+			map[string]interface{}{
+				"path":       "foo",
+				"encryption": []interface{}{block},
+			},
+			expected,
+			false,
+		},
+
+		"allow unencrypted read": {
+			map[string]interface{}{
+				"path": "foo",
+				"encryption": []interface{}{
+					map[string]interface{}{
+						"type":                   "aes_gcm",
+						"key":                    "foo",
+						"allow_unencrypted_read": true,
+					},
+				},
+			},
+			&EncryptionConfig{
+				Type:                 "aes_gcm",
+				Config:               map[string]string{"key": "foo"},
+				AllowUnencryptedRead: true,
+			},
+			false,
+		},
+
+		"invalid allow unencrypted read": {
+			map[string]interface{}{
+				"encryption": []interface{}{
+					map[string]interface{}{
+						"type":                   "aes_gcm",
+						"allow_unencrypted_read": "maybe",
+					},
+				},
+			},
+			nil,
+			true,
+		},
+
+		"no type": {
+			map[string]interface{}{
+				"encryption": []interface{}{
+					map[string]interface{}{"key": "foo"},
+				},
+			},
+			nil,
+			true,
+		},
+
+		"multiple blocks": {
+			map[string]interface{}{
+				"encryption": []interface{}{block, block},
+			},
+			nil,
+			true,
+		},
+	}
+
+	for name, tc := range cases {
+		rest, enc, err := SplitEncryptionConfig(tc.Raw)
+		if err != nil != tc.Err {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(enc, tc.Expected) {
+			t.Fatalf("%s: bad: %#v", name, enc)
+		}
+		if !reflect.DeepEqual(rest, map[string]interface{}{"path": "foo"}) {
+			t.Fatalf("%s: bad: %#v", name, rest)
+		}
+	}
+}
+
+// remoteNil is a backend that stores its state with a remote client.
+type remoteNil struct {
+	Nil
+
+	client remote.Client
+}
+
+func (b *remoteNil) State(string) (state.State, error) {
+	return &remote.State{Client: b.client}, nil
+}
+
+type memClient struct {
+	data []byte
+}
+
+func (c *memClient) Get() (*remote.Payload, error) {
+	if c.data == nil {
+		return nil, nil
+	}
+
+	return &remote.Payload{Data: c.data}, nil
+}
+
+func (c *memClient) Put(data []byte) error {
+	c.data = data
+	return nil
+}
+
+func (c *memClient) Delete() error {
+	c.data = nil
+	return nil
+}
+
+// testEncryptionKey sets the key of the aes_gcm encrypter in the
+// environment, and returns a func that unsets it.
+func testEncryptionKey(t *testing.T) func() {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	if err := os.Setenv(state.AESGCMKeyEnvVar, key); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return func() { os.Unsetenv(state.AESGCMKeyEnvVar) }
+}
+
+func TestEncrypted(t *testing.T) {
+	defer testEncryptionKey(t)()
+
+	client := new(memClient)
+	b, err := Encrypted(&remoteNil{client: client}, &EncryptionConfig{
+		Type:   "aes_gcm",
+		Config: map[string]string{},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	s, err := b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	original := terraform.NewState()
+	original.Lineage = "secret-lineage"
+	if err := s.WriteState(original); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if bytes.Contains(client.data, []byte("secret-lineage")) {
+		t.Fatalf("state should be encrypted: %s", client.data)
+	}
+
+	// A new state reads it back through the encrypted client
+	s, err = b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if s.State().Lineage != "secret-lineage" {
+		t.Fatalf("bad: %#v", s.State())
+	}
+}
+
+func TestEncrypted_unencrypted(t *testing.T) {
+	defer testEncryptionKey(t)()
+
+	client := &memClient{data: []byte(`{"version": 3, "lineage": "plain"}`)}
+	config := &EncryptionConfig{
+		Type:   "aes_gcm",
+		Config: map[string]string{},
+	}
+
+	b, err := Encrypted(&remoteNil{client: client}, config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	s, err := b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.RefreshState(); err == nil {
+		t.Fatal("should error reading an unencrypted state")
+	}
+
+	config.AllowUnencryptedRead = true
+	b, err = Encrypted(&remoteNil{client: client}, config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	s, err = b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if s.State().Lineage != "plain" {
+		t.Fatalf("bad: %#v", s.State())
+	}
+}
+
+func TestEncrypted_notRemote(t *testing.T) {
+	defer testEncryptionKey(t)()
+
+	b, err := Encrypted(Nil{}, &EncryptionConfig{
+		Type:   "aes_gcm",
+		Config: map[string]string{},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := b.State(DefaultStateName); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -12,7 +12,11 @@ import (
 	backendlocal "github.com/hashicorp/terraform/backend/local"
 	backendconsul "github.com/hashicorp/terraform/backend/remote-state/consul"
 	backendinmem "github.com/hashicorp/terraform/backend/remote-state/inmem"
+	backendkubernetes "github.com/hashicorp/terraform/backend/remote-state/kubernetes"
+	backendOCI "github.com/hashicorp/terraform/backend/remote-state/oci"
+	backendOSS "github.com/hashicorp/terraform/backend/remote-state/oss"
 	backendS3 "github.com/hashicorp/terraform/backend/remote-state/s3"
+	backendSFTP "github.com/hashicorp/terraform/backend/remote-state/sftp"
 )
 
 // backends is the list of available backends. This is a global variable
@@ -33,11 +37,15 @@ func init() {
 	// Our hardcoded backends. We don't need to acquire a lock here
 	// since init() code is serial and can't spawn goroutines.
 	backends = map[string]func() backend.Backend{
-		"atlas":  func() backend.Backend { return &backendatlas.Backend{} },
-		"local":  func() backend.Backend { return &backendlocal.Local{} },
-		"consul": func() backend.Backend { return backendconsul.New() },
-		"inmem":  func() backend.Backend { return backendinmem.New() },
-		"s3":     func() backend.Backend { return backendS3.New() },
+		"atlas":      func() backend.Backend { return &backendatlas.Backend{} },
+		"local":      func() backend.Backend { return &backendlocal.Local{} },
+		"consul":     func() backend.Backend { return backendconsul.New() },
+		"inmem":      func() backend.Backend { return backendinmem.New() },
+		"kubernetes": func() backend.Backend { return backendkubernetes.New() },
+		"oci":        func() backend.Backend { return backendOCI.New() },
+		"oss":        func() backend.Backend { return backendOSS.New() },
+		"s3":         func() backend.Backend { return backendS3.New() },
+		"sftp":       func() backend.Backend { return backendSFTP.New() },
 	}
 
 	// Add the legacy remote backends that haven't yet been convertd to
@@ -2,6 +2,7 @@ package local
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -12,7 +13,9 @@ import (
 	"sync"
 
 	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/helper/logging"
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/warnings"
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
@@ -20,13 +23,17 @@ import (
 )
 
 const (
-	DefaultEnvDir          = "terraform.tfstate.d"
-	DefaultEnvFile         = "environment"
-	DefaultStateFilename   = "terraform.tfstate"
-	DefaultDataDir         = ".terraform"
-	DefaultBackupExtension = ".backup"
+	DefaultEnvDir           = "terraform.tfstate.d"
+	DefaultEnvFile          = "environment"
+	DefaultStateFilename    = "terraform.tfstate"
+	DefaultDataDir          = ".terraform"
+	DefaultBackupExtension  = ".backup"
+	DefaultOutputsExtension = ".outputs"
 )
 
+// logger logs the operations of the local backend.
+var logger = logging.New("backend/local")
+
 // Local is an implementation of EnhancedBackend that performs all operations
 // locally. This is the "default" backend and implements normal Terraform
 // behavior as it is well known.
@@ -50,10 +57,15 @@ type Local struct {
 	//
 	// StateEnvPath is the path to the folder containing environments. This
 	// defaults to DefaultEnvDir if not set.
+	//
+	// StateFilename is the name of the state file in the directory of each
+	// environment, where "{workspace}" is replaced by the name of the
+	// environment. This defaults to DefaultStateFilename if not set.
 	StatePath       string
 	StateOutPath    string
 	StateBackupPath string
 	StateEnvDir     string
+	StateFilename   string
 
 	// We only want to create a single instance of a local state, so store them
 	// here as they're loaded.
@@ -71,6 +83,14 @@ type Local struct {
 	OpInput      bool
 	OpValidation bool
 
+	// Warnings collects the warnings that are shown, and decides which are
+	// suppressed. If it is nil, every warning is shown.
+	Warnings *warnings.Collector
+
+	// ReadOnly fails to write, delete or publish the outputs of states with
+	// state.ErrReadOnly.
+	ReadOnly bool
+
 	// Backend, if non-nil, will use this backend for non-enhanced behavior.
 	// This allows local behavior with remote state storage. It is a way to
 	// "upgrade" a non-enhanced backend to an enhanced backend with typical
@@ -152,6 +172,10 @@ func (b *Local) States() ([]string, error) {
 // DeleteState removes a named state.
 // The "default" state cannot be removed.
 func (b *Local) DeleteState(name string) error {
+	if b.ReadOnly {
+		return state.ErrReadOnly
+	}
+
 	// If we have a backend handling state, defer to that.
 	if b.Backend != nil {
 		return b.Backend.DeleteState(name)
@@ -170,6 +194,15 @@ func (b *Local) DeleteState(name string) error {
 }
 
 func (b *Local) State(name string) (state.State, error) {
+	s, err := b.state(name)
+	if err != nil || !b.ReadOnly {
+		return s, err
+	}
+
+	return state.NewReadOnly(s), nil
+}
+
+func (b *Local) state(name string) (state.State, error) {
 	statePath, stateOutPath, backupPath := b.StatePaths(name)
 
 	// If we have a backend handling state, defer to that.
@@ -293,10 +326,30 @@ func (b *Local) init() {
 			},
 
 			"environment_dir": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				Default:       "",
+				Deprecated:    "please use the workspace_dir attribute",
+				ConflictsWith: []string{"workspace_dir"},
+			},
+
+			"workspace_dir": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "",
+			},
+
+			"state_filename": &schema.Schema{
 				Type:     schema.TypeString,
 				Optional: true,
 				Default:  "",
 			},
+
+			"data_home": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
 		},
 
 		ConfigureFunc: b.schemaConfigure,
@@ -306,7 +359,30 @@ func (b *Local) init() {
 func (b *Local) schemaConfigure(ctx context.Context) error {
 	d := schema.FromContextBackendConfig(ctx)
 
-	// Set the path if it is set
+	// If the states are kept in the data directory of the user, relative
+	// paths are relative to the directory for this working directory.
+	var base string
+	if d.Get("data_home").(bool) {
+		var err error
+		base, err = projectDataDir()
+		if err != nil {
+			return fmt.Errorf("error finding the data directory: %s", err)
+		}
+	}
+	resolve := func(path string) string {
+		if base == "" || filepath.IsAbs(path) {
+			return path
+		}
+
+		return filepath.Join(base, path)
+	}
+
+	if raw, ok := d.GetOk("state_filename"); ok {
+		b.StateFilename = raw.(string)
+	}
+
+	// Set the path if it is set, or if the default one is changed. It's
+	// set in either case so that it isn't replaced by the CLI's default.
 	pathRaw, ok := d.GetOk("path")
 	if ok {
 		path := pathRaw.(string)
@@ -314,20 +390,51 @@ func (b *Local) schemaConfigure(ctx context.Context) error {
 			return fmt.Errorf("configured path is empty")
 		}
 
-		b.StatePath = path
-		b.StateOutPath = path
+		b.StatePath = resolve(path)
+		b.StateOutPath = b.StatePath
+	} else if base != "" || b.StateFilename != "" {
+		b.StatePath = resolve(b.stateFilename(backend.DefaultStateName))
+		b.StateOutPath = b.StatePath
 	}
 
-	if raw, ok := d.GetOk("environment_dir"); ok {
-		path := raw.(string)
-		if path != "" {
-			b.StateEnvDir = path
-		}
+	envDir := d.Get("workspace_dir").(string)
+	if envDir == "" {
+		envDir = d.Get("environment_dir").(string)
+	}
+	if envDir == "" && base != "" {
+		envDir = DefaultEnvDir
+	}
+	if envDir != "" {
+		b.StateEnvDir = resolve(envDir)
 	}
 
 	return nil
 }
 
+// projectDataDir returns the directory in the data directory of the user
+// where the states of the working directory are kept. It's named after the
+// working directory, with a hash of its path so that working directories
+// with the same name don't share states.
+func projectDataDir() (string, error) {
+	home, err := dataHome()
+	if err != nil {
+		return "", err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	wd, err = filepath.Abs(wd)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(wd))
+	name := fmt.Sprintf("%s-%x", filepath.Base(wd), sum[:4])
+	return filepath.Join(home, "terraform", "states", name), nil
+}
+
 // StatePaths returns the StatePath, StateOutPath, and StateBackupPath as
 // configured from the CLI.
 func (b *Local) StatePaths(name string) (string, string, string) {
@@ -341,10 +448,13 @@ func (b *Local) StatePaths(name string) (string, string, string) {
 
 	if name == backend.DefaultStateName {
 		if statePath == "" {
-			statePath = DefaultStateFilename
+			statePath = b.stateFilename(name)
 		}
 	} else {
-		statePath = filepath.Join(b.stateEnvDir(), name, DefaultStateFilename)
+		// The configured paths are for the default state only, so the
+		// state of an environment is always written where it's read.
+		statePath = filepath.Join(b.stateEnvDir(), name, b.stateFilename(name))
+		stateOutPath = statePath
 	}
 
 	if stateOutPath == "" {
@@ -375,6 +485,10 @@ func (b *Local) createState(name string) error {
 		return nil
 	}
 
+	if b.ReadOnly {
+		return fmt.Errorf("state %q doesn't exist: %s", name, state.ErrReadOnly)
+	}
+
 	err = os.MkdirAll(stateDir, 0755)
 	if err != nil {
 		return err
@@ -392,6 +506,16 @@ func (b *Local) stateEnvDir() string {
 	return DefaultEnvDir
 }
 
+// stateFilename returns the name of the state file of the named state.
+func (b *Local) stateFilename(name string) string {
+	filename := b.StateFilename
+	if filename == "" {
+		filename = DefaultStateFilename
+	}
+
+	return strings.Replace(filename, "{workspace}", name, -1)
+}
+
 // currentStateName returns the name of the current named state as set in the
 // configuration files.
 // If there are no configured environments, currentStateName returns "default"
@@ -5,8 +5,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-multierror"
@@ -21,7 +21,7 @@ func (b *Local) opApply(
 	ctx context.Context,
 	op *backend.Operation,
 	runningOp *backend.RunningOperation) {
-	log.Printf("[INFO] backend/local: starting Apply operation")
+	logger.Info("starting Apply operation")
 
 	// If we have a nil module at this point, then set it to an empty tree
 	// to avoid any potential crashes.
@@ -36,15 +36,31 @@ func (b *Local) opApply(
 		op.Module = module.NewEmptyTree()
 	}
 
-	// Setup our count hook that keeps track of resource changes
+	// Setup our count hook that keeps track of resource changes, our
+	// progress hook that tracks what was done in case we're interrupted,
+	// and our result hook that records the outcome of each resource.
 	countHook := new(CountHook)
 	stateHook := new(StateHook)
+	progressHook := new(ProgressHook)
+	resultHook := new(ResultHook)
 	if b.ContextOpts == nil {
 		b.ContextOpts = new(terraform.ContextOpts)
 	}
 	old := b.ContextOpts.Hooks
 	defer func() { b.ContextOpts.Hooks = old }()
-	b.ContextOpts.Hooks = append(b.ContextOpts.Hooks, countHook, stateHook)
+	b.ContextOpts.Hooks = append(
+		b.ContextOpts.Hooks, countHook, stateHook, progressHook, resultHook)
+
+	// Write the result of the apply if asked to, whether it succeeds or not
+	var opState state.State
+	var serialBefore int64
+	var triage *ApplyTriage
+	if op.ResultOutPath != "" {
+		start := time.Now()
+		defer func() {
+			b.writeApplyResult(op, runningOp, resultHook, triage, opState, start, serialBefore)
+		}()
+	}
 
 	// Get our context
 	tfCtx, opState, err := b.context(op)
@@ -52,13 +68,22 @@ func (b *Local) opApply(
 		runningOp.Err = err
 		return
 	}
+	if s := opState.State(); s != nil {
+		serialBefore = s.Serial
+	}
 
+	var lockModules []string
 	if op.LockState {
 		lockCtx, cancel := context.WithTimeout(ctx, op.StateLockTimeout)
 		defer cancel()
 
-		lockInfo := state.NewLockInfo()
-		lockInfo.Operation = op.Type.String()
+		lockInfo, err := b.opLockInfo(op, opState)
+		if err != nil {
+			runningOp.Err = err
+			return
+		}
+		lockModules = lockInfo.Modules
+
 		lockID, err := clistate.Lock(lockCtx, opState, lockInfo, b.CLI, b.Colorize())
 		if err != nil {
 			runningOp.Err = errwrap.Wrapf("Error locking state: {{err}}", err)
@@ -76,11 +101,12 @@ func (b *Local) opApply(
 	runningOp.State = tfCtx.State()
 
 	// If we weren't given a plan, then we refresh/plan
-	if op.Plan == nil {
+	plan := op.Plan
+	if plan == nil {
 		// If we're refreshing before apply, perform that
 		if op.PlanRefresh {
-			log.Printf("[INFO] backend/local: apply calling Refresh")
-			_, err := tfCtx.Refresh()
+			logger.Info("apply calling Refresh")
+			_, err := refresh(tfCtx, op)
 			if err != nil {
 				runningOp.Err = errwrap.Wrapf("Error refreshing state: {{err}}", err)
 				return
@@ -88,16 +114,39 @@ func (b *Local) opApply(
 		}
 
 		// Perform the plan
-		log.Printf("[INFO] backend/local: apply calling Plan")
-		if _, err := tfCtx.Plan(); err != nil {
+		logger.Info("apply calling Plan")
+		plan, err = tfCtx.Plan()
+		if err != nil {
 			runningOp.Err = errwrap.Wrapf("Error running plan: {{err}}", err)
 			return
 		}
 	}
 
+	if len(lockModules) > 0 {
+		if err := checkSubtreeDiff(lockModules, plan.Diff); err != nil {
+			runningOp.Err = err
+			return
+		}
+	}
+
+	if b.CLI != nil {
+		if err := b.opTargetReport(tfCtx); err != nil {
+			runningOp.Err = err
+			return
+		}
+	}
+
+	// Record everything we're about to change
+	progressHook.SetDiff(plan.Diff)
+	resultHook.SetDiff(plan.Diff)
+
 	// Setup our hook for continuous state updates
 	stateHook.State = opState
 
+	// The apply graph is built before the apply, which removes what it
+	// applies from the diff, so that a failed apply can be triaged.
+	applyGraph := triageApplyGraph(tfCtx)
+
 	// Start the apply in a goroutine so that we can be interrupted.
 	var applyState *terraform.State
 	var applyErr error
@@ -120,8 +169,10 @@ func (b *Local) opApply(
 	// Wait for the apply to finish or for us to be interrupted so
 	// we can handle it properly.
 	err = nil
+	interrupted := false
 	select {
 	case <-ctx.Done():
+		interrupted = true
 		if b.CLI != nil {
 			b.CLI.Output("stopping apply operation...")
 		}
@@ -137,14 +188,29 @@ func (b *Local) opApply(
 			}
 		}
 
-		// Stop execution
+		// Stop execution. This also asks the providers to stop any
+		// in-flight operations.
 		go tfCtx.Stop()
 
 		// Wait for completion still
 		<-doneCh
+
+		// Tell the user what was and wasn't done
+		if b.CLI != nil {
+			b.CLI.Output(b.Colorize().Color(interruptSummary(progressHook)))
+		}
 	case <-doneCh:
 	}
 
+	// Sort out what failed and what was skipped because of it, unless the
+	// apply was interrupted, which is summarized above.
+	if applyErr != nil {
+		triage = applyTriage(resultHook.Resources(), applyGraph)
+		if b.CLI != nil && !interrupted {
+			b.CLI.Output(b.Colorize().Color(triageSummary(triage)))
+		}
+	}
+
 	// Store the final state
 	runningOp.State = applyState
 
@@ -235,6 +301,89 @@ func (b *Local) backupStateForError(applyState *terraform.State, err error) erro
 	return errors.New(stateWriteBackedUpError)
 }
 
+// writeApplyResult writes the result of the apply to op.ResultOutPath. The
+// state is nil if the apply failed before it was loaded. An error writing
+// the result is added to the error of the operation.
+func (b *Local) writeApplyResult(
+	op *backend.Operation,
+	runningOp *backend.RunningOperation,
+	h *ResultHook,
+	triage *ApplyTriage,
+	s state.State,
+	start time.Time,
+	serialBefore int64) {
+	finish := time.Now()
+	result := &ApplyResult{
+		Version:          ApplyResultVersion,
+		TerraformVersion: terraform.VersionString(),
+		Operation:        "apply",
+		Success:          runningOp.Err == nil,
+		StartedAt:        start.UTC(),
+		FinishedAt:       finish.UTC(),
+		Duration:         finish.Sub(start).Seconds(),
+		Environment:      op.Environment,
+		SerialBefore:     serialBefore,
+		SerialAfter:      serialBefore,
+		Resources:        h.Resources(),
+		Triage:           triage,
+		Outputs:          make(map[string]*terraform.OutputState),
+	}
+	if op.Destroy {
+		result.Operation = "destroy"
+	}
+	if runningOp.Err != nil {
+		result.Error = runningOp.Err.Error()
+	}
+
+	if s != nil {
+		if st := s.State(); st != nil {
+			result.Lineage = st.Lineage
+			result.SerialAfter = st.Serial
+			if mod := st.RootModule(); mod != nil && mod.Outputs != nil {
+				result.Outputs = mod.Outputs
+			}
+		}
+	}
+
+	if err := WriteApplyResult(op.ResultOutPath, result); err != nil {
+		runningOp.Err = multierror.Append(runningOp.Err, errwrap.Wrapf(
+			fmt.Sprintf("Error writing the apply result to %s: {{err}}", op.ResultOutPath), err))
+	}
+}
+
+// triageApplyGraph returns the apply graph of tfCtx for the triage of a
+// failed apply, or nil if it can't be built.
+func triageApplyGraph(tfCtx *terraform.Context) *terraform.GraphExport {
+	g, err := tfCtx.ApplyGraph()
+	if err != nil {
+		logger.Warn("failed to build the apply graph for the triage: %s", err)
+		return nil
+	}
+
+	return g
+}
+
+// interruptSummary returns the output for the user describing the progress
+// of an interrupted apply.
+func interruptSummary(h *ProgressHook) string {
+	completed, inFlight, pending := h.Summary()
+
+	var buf bytes.Buffer
+	buf.WriteString("[reset][bold][yellow]\nApply interrupted![reset]\n")
+	writeList := func(title string, ids []string) {
+		buf.WriteString(fmt.Sprintf("\n[bold]%s: %d[reset]\n", title, len(ids)))
+		for _, id := range ids {
+			buf.WriteString(fmt.Sprintf("  %s\n", id))
+		}
+	}
+	writeList("Completed", completed)
+	writeList("In progress when interrupted", inFlight)
+	writeList("Not started", pending)
+	buf.WriteString("\n" + strings.TrimSpace(interruptSummaryFooter))
+
+	return buf.String()
+}
+
 const applyErrNoConfig = `
 No configuration files found!
 
@@ -288,3 +437,9 @@ Terraform encountered an error attempting to save the state before canceling
 the current operation. Once the operation is complete another attempt will be
 made to save the final state.
 `
+
+const interruptSummaryFooter = `
+The state has been saved with every resource that completed. Resources that
+were in progress may have been partially created or changed; run
+"terraform plan" to see what still needs to be done.
+`
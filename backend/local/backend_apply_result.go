@@ -0,0 +1,67 @@
+package local
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ApplyResultVersion is the version of the format of ApplyResult.
+const ApplyResultVersion = 1
+
+// ApplyResult is the machine-readable result of an apply that is written to
+// the path of backend.Operation.ResultOutPath, whether the apply succeeded
+// or not.
+type ApplyResult struct {
+	Version          int    `json:"version"`
+	TerraformVersion string `json:"terraform_version"`
+
+	// Operation is "apply" or "destroy".
+	Operation string `json:"operation"`
+
+	// Success is true if the apply completed without errors, in which case
+	// Error is empty.
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Duration   float64   `json:"duration_seconds"`
+
+	// The serials of the state before and after the apply. They're the
+	// same if the state didn't change.
+	Environment  string `json:"environment"`
+	Lineage      string `json:"lineage"`
+	SerialBefore int64  `json:"serial_before"`
+	SerialAfter  int64  `json:"serial_after"`
+
+	// Resources are the resources that the apply had changes for.
+	Resources []*ResourceResult `json:"resources"`
+
+	// Triage is the triage of the resources of a failed apply. It's nil if
+	// the apply didn't fail while applying resources.
+	Triage *ApplyTriage `json:"triage,omitempty"`
+
+	// Outputs are the outputs of the root module after the apply, as shown
+	// by "terraform output -json".
+	Outputs map[string]*terraform.OutputState `json:"outputs"`
+}
+
+// WriteApplyResult writes the result as JSON to the given path, creating
+// the directory it's in if needed.
+func WriteApplyResult(path string, r *ApplyResult) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -2,10 +2,13 @@ package local
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
@@ -163,6 +166,204 @@ test_instance.foo:
 	`)
 }
 
+func TestLocal_applyResultOut(t *testing.T) {
+	b := TestLocal(t)
+	p := TestLocalProvider(t, b, "test")
+
+	p.ApplyFn = func(
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState,
+		d *terraform.InstanceDiff) (*terraform.InstanceState, error) {
+		if info.Id == "test_instance.bar" {
+			return nil, fmt.Errorf("bar failed")
+		}
+
+		return &terraform.InstanceState{ID: "foo"}, nil
+	}
+	p.DiffFn = func(
+		*terraform.InstanceInfo,
+		*terraform.InstanceState,
+		*terraform.ResourceConfig) (*terraform.InstanceDiff, error) {
+		return &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{
+					New: "bar",
+				},
+			},
+		}, nil
+	}
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply-error")
+	defer modCleanup()
+
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	// Start from an existing state, so that its serial is incremented
+	existing := terraform.NewState()
+	existing.Serial = 3
+	f, err := os.Create(b.StatePath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err = terraform.WriteState(existing, f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	op := testOperationApply()
+	op.Module = mod
+	op.ResultOutPath = filepath.Join(td, "out", "result.json")
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err == nil {
+		t.Fatal("should error")
+	}
+
+	data, err := ioutil.ReadFile(op.ResultOutPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var result ApplyResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if result.Version != ApplyResultVersion || result.Operation != "apply" {
+		t.Fatalf("bad: %#v", result)
+	}
+	if result.Success || !strings.Contains(result.Error, "bar failed") {
+		t.Fatalf("bad: %#v", result)
+	}
+	if result.SerialBefore != 3 || result.SerialAfter != 4 {
+		t.Fatalf("bad serials: %d, %d", result.SerialBefore, result.SerialAfter)
+	}
+	if result.FinishedAt.Before(result.StartedAt) {
+		t.Fatalf("bad times: %s, %s", result.StartedAt, result.FinishedAt)
+	}
+
+	var actual []string
+	for _, r := range result.Resources {
+		actual = append(actual, fmt.Sprintf(
+			"%s %s %s %s", r.Address, r.Action, r.Status, r.Error))
+	}
+	expected := []string{
+		"test_instance.bar create error test_instance.bar: bar failed",
+		"test_instance.foo create complete ",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestLocal_applyErrorTriage(t *testing.T) {
+	b := TestLocal(t)
+	p := TestLocalProvider(t, b, "test")
+	ui := new(cli.MockUi)
+	b.CLI = ui
+
+	p.ApplyFn = func(
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState,
+		d *terraform.InstanceDiff) (*terraform.InstanceState, error) {
+		if info.Id == "test_instance.bar" {
+			return nil, fmt.Errorf("bar failed")
+		}
+
+		return &terraform.InstanceState{ID: "foo"}, nil
+	}
+	p.DiffFn = func(
+		*terraform.InstanceInfo,
+		*terraform.InstanceState,
+		*terraform.ResourceConfig) (*terraform.InstanceDiff, error) {
+		return &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{
+					New: "bar",
+				},
+			},
+		}, nil
+	}
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply-error-chain")
+	defer modCleanup()
+
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	op := testOperationApply()
+	op.Module = mod
+	op.ResultOutPath = filepath.Join(td, "result.json")
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err == nil {
+		t.Fatal("should error")
+	}
+
+	output := ui.OutputWriter.String()
+	expected := strings.TrimSpace(`
+Apply failed!
+
+Failed: 1
+  test_instance.bar (create)
+    test_instance.bar: bar failed
+
+Skipped: 2
+  test_instance.baz (create)
+    depends on: test_instance.bar -> test_instance.baz
+  test_instance.qux (create)
+    depends on: test_instance.bar -> test_instance.baz -> test_instance.qux
+
+Applied: 1
+  test_instance.foo (create)
+`)
+	if !strings.Contains(output, expected) {
+		t.Fatalf("expected %q in output:\n%s", expected, output)
+	}
+
+	data, err := ioutil.ReadFile(op.ResultOutPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var result ApplyResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	triage := result.Triage
+	if triage == nil {
+		t.Fatal("no triage in the result")
+	}
+	if len(triage.Failed) != 1 || triage.Failed[0].Address != "test_instance.bar" {
+		t.Fatalf("bad: %#v", triage.Failed)
+	}
+	if len(triage.Applied) != 1 || triage.Applied[0].Address != "test_instance.foo" {
+		t.Fatalf("bad: %#v", triage.Applied)
+	}
+	if len(triage.Skipped) != 2 {
+		t.Fatalf("bad: %#v", triage.Skipped)
+	}
+	chain := []string{"test_instance.bar", "test_instance.baz", "test_instance.qux"}
+	if actual := triage.Skipped[1].DependencyChain; !reflect.DeepEqual(actual, chain) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestLocal_applyBackendFail(t *testing.T) {
 	mod, modCleanup := module.TestTree(t, "./test-fixtures/apply")
 	defer modCleanup()
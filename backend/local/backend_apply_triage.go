@@ -0,0 +1,235 @@
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ApplyTriage is a summary of a failed apply, for working out what went
+// wrong: the resources that failed, the resources that were skipped because
+// they depend on them, and the resources that were applied.
+type ApplyTriage struct {
+	// Failed are the resources that failed to apply, with the errors of
+	// their providers.
+	Failed []*ResourceResult `json:"failed"`
+
+	// Skipped are the resources that were never started.
+	Skipped []*SkippedResource `json:"skipped"`
+
+	// Incomplete are the resources that were only partially applied, such
+	// as replacements that were destroyed but not created.
+	Incomplete []*ResourceResult `json:"incomplete"`
+
+	// Applied are the resources whose changes were applied.
+	Applied []*ResourceResult `json:"applied"`
+}
+
+// SkippedResource is a resource that a failed apply never started.
+type SkippedResource struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+
+	// DependencyChain is the chain of dependencies from a failed resource
+	// to this one, starting with the failed resource and ending with this
+	// one. It's empty if the resource doesn't depend on a failed resource.
+	DependencyChain []string `json:"dependency_chain"`
+}
+
+// applyTriage returns the triage of a failed apply from the results of its
+// resources, and the apply graph that the dependency chains are found in.
+// The graph is nil if it couldn't be built, in which case the chains are
+// empty.
+func applyTriage(results []*ResourceResult, g *terraform.GraphExport) *ApplyTriage {
+	result := &ApplyTriage{
+		Failed:     make([]*ResourceResult, 0),
+		Skipped:    make([]*SkippedResource, 0),
+		Incomplete: make([]*ResourceResult, 0),
+		Applied:    make([]*ResourceResult, 0),
+	}
+
+	failed := make(map[string]bool)
+	for _, r := range results {
+		switch r.Status {
+		case ResultStatusError:
+			result.Failed = append(result.Failed, r)
+			failed[r.Address] = true
+		case ResultStatusIncomplete:
+			result.Incomplete = append(result.Incomplete, r)
+		case ResultStatusComplete:
+			result.Applied = append(result.Applied, r)
+		}
+	}
+
+	deps := newTriageGraph(g)
+	for _, r := range results {
+		if r.Status != ResultStatusNotStarted {
+			continue
+		}
+
+		result.Skipped = append(result.Skipped, &SkippedResource{
+			Address:         r.Address,
+			Action:          r.Action,
+			DependencyChain: deps.chain(r.Address, failed),
+		})
+	}
+
+	return result
+}
+
+// triageGraph is the apply graph of an apply, for finding the chains of
+// dependencies between its resources.
+type triageGraph struct {
+	// addrs are the addresses of the resource nodes by their IDs, in the
+	// form of the addresses of ResourceResult.
+	addrs map[string]string
+
+	// nodes are the IDs of the nodes of each resource address.
+	nodes map[string][]string
+
+	// deps are the IDs of the nodes that each node depends on.
+	deps map[string][]string
+}
+
+func newTriageGraph(g *terraform.GraphExport) *triageGraph {
+	result := &triageGraph{
+		addrs: make(map[string]string),
+		nodes: make(map[string][]string),
+		deps:  make(map[string][]string),
+	}
+	if g == nil {
+		return result
+	}
+
+	for _, n := range g.Nodes {
+		if n.Type != terraform.GraphExportResource {
+			continue
+		}
+
+		addr, err := resultAddress(n.Address)
+		if err != nil {
+			logger.Warn("unexpected address of node %q: %s", n.ID, err)
+			continue
+		}
+		result.addrs[n.ID] = addr
+		result.nodes[addr] = append(result.nodes[addr], n.ID)
+	}
+
+	for _, e := range g.Edges {
+		result.deps[e.To] = append(result.deps[e.To], e.From)
+	}
+
+	return result
+}
+
+// chain returns the shortest chain of dependencies from a failed resource
+// to the resource with the given address, or nil if it doesn't depend on
+// any failed resource.
+func (g *triageGraph) chain(addr string, failed map[string]bool) []string {
+	// Search the dependencies breadth first, remembering how each node was
+	// reached so that the chain can be followed back.
+	next := make(map[string]string)
+	queue := append([]string(nil), g.nodes[addr]...)
+	for _, id := range queue {
+		next[id] = ""
+	}
+
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		if a := g.addrs[id]; a != addr && failed[a] {
+			var result []string
+			for ; id != ""; id = next[id] {
+				a, ok := g.addrs[id]
+				if !ok || (len(result) > 0 && result[len(result)-1] == a) {
+					continue
+				}
+				result = append(result, a)
+			}
+
+			return result
+		}
+
+		for _, dep := range g.deps[id] {
+			if _, ok := next[dep]; ok {
+				continue
+			}
+			next[dep] = id
+			queue = append(queue, dep)
+		}
+	}
+
+	return nil
+}
+
+// resultAddress returns the address of a resource node of an exported
+// graph in the form of the addresses of ResourceResult, which are those of
+// terraform.InstanceInfo.
+func resultAddress(s string) (string, error) {
+	addr, err := terraform.ParseResourceAddress(s)
+	if err != nil {
+		return "", err
+	}
+
+	key := &terraform.ResourceStateKey{
+		Mode:  addr.Mode,
+		Type:  addr.Type,
+		Name:  addr.Name,
+		Index: addr.Index,
+		Key:   addr.Key,
+	}
+	info := &terraform.InstanceInfo{
+		Id:         key.String(),
+		ModulePath: append([]string{"root"}, addr.Path...),
+	}
+
+	return info.HumanId(), nil
+}
+
+// triageSummary returns the output for the user describing what failed in
+// an apply, and what was skipped and applied regardless.
+func triageSummary(t *ApplyTriage) string {
+	var buf bytes.Buffer
+	buf.WriteString("[reset][bold][red]\nApply failed![reset]\n")
+
+	writeTitle := func(title string, n int) {
+		buf.WriteString(fmt.Sprintf("\n[bold]%s: %d[reset]\n", title, n))
+	}
+	writeResource := func(r *ResourceResult) {
+		buf.WriteString(fmt.Sprintf("  %s (%s)\n", r.Address, r.Action))
+	}
+
+	writeTitle("Failed", len(t.Failed))
+	for _, r := range t.Failed {
+		writeResource(r)
+		for _, line := range strings.Split(r.Error, "\n") {
+			buf.WriteString(fmt.Sprintf("    %s\n", line))
+		}
+	}
+
+	writeTitle("Skipped", len(t.Skipped))
+	for _, r := range t.Skipped {
+		writeResource(&ResourceResult{Address: r.Address, Action: r.Action})
+		if len(r.DependencyChain) > 0 {
+			buf.WriteString(fmt.Sprintf(
+				"    depends on: %s\n", strings.Join(r.DependencyChain, " -> ")))
+		}
+	}
+
+	if len(t.Incomplete) > 0 {
+		writeTitle("Incomplete", len(t.Incomplete))
+		for _, r := range t.Incomplete {
+			writeResource(r)
+		}
+	}
+
+	writeTitle("Applied", len(t.Applied))
+	for _, r := range t.Applied {
+		writeResource(r)
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,70 @@
+package local
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestApplyTriage(t *testing.T) {
+	results := []*ResourceResult{
+		{Address: "aws_instance.a", Action: ResultActionCreate, Status: ResultStatusComplete},
+		{Address: "aws_instance.b.0", Action: ResultActionReplace, Status: ResultStatusError},
+		{Address: "aws_instance.c", Action: ResultActionCreate, Status: ResultStatusNotStarted},
+		{Address: "aws_instance.d", Action: ResultActionUpdate, Status: ResultStatusNotStarted},
+		{Address: "module.child.aws_instance.e", Action: ResultActionCreate, Status: ResultStatusNotStarted},
+	}
+
+	// c depends on b through the provider of c, and e depends on c through
+	// a variable of its module. d depends only on a.
+	g := &terraform.GraphExport{
+		Nodes: []*terraform.GraphExportNode{
+			{ID: "aws_instance.a", Type: terraform.GraphExportResource, Address: "aws_instance.a"},
+			{ID: "aws_instance.b[0]", Type: terraform.GraphExportResource, Address: "aws_instance.b[0]"},
+			{ID: "aws_instance.b[0] (destroy)", Type: terraform.GraphExportResource, Address: "aws_instance.b[0]"},
+			{ID: "aws_instance.c", Type: terraform.GraphExportResource, Address: "aws_instance.c"},
+			{ID: "aws_instance.d", Type: terraform.GraphExportResource, Address: "aws_instance.d"},
+			{ID: "module.child.aws_instance.e", Type: terraform.GraphExportResource, Address: "module.child.aws_instance.e"},
+			{ID: "module.child.var.c", Type: terraform.GraphExportVariable},
+			{ID: "provider.aws", Type: terraform.GraphExportProvider, Address: "aws"},
+		},
+		Edges: []*terraform.GraphExportEdge{
+			{From: "aws_instance.a", To: "aws_instance.d"},
+			{From: "aws_instance.b[0] (destroy)", To: "aws_instance.b[0]"},
+			{From: "aws_instance.b[0]", To: "aws_instance.c"},
+			{From: "aws_instance.c", To: "module.child.var.c"},
+			{From: "module.child.var.c", To: "module.child.aws_instance.e"},
+			{From: "provider.aws", To: "aws_instance.a"},
+			{From: "provider.aws", To: "aws_instance.b[0]"},
+			{From: "provider.aws", To: "aws_instance.c"},
+		},
+	}
+
+	triage := applyTriage(results, g)
+	if len(triage.Failed) != 1 || len(triage.Applied) != 1 || len(triage.Incomplete) != 0 {
+		t.Fatalf("bad: %#v", triage)
+	}
+
+	chains := make(map[string][]string)
+	for _, r := range triage.Skipped {
+		chains[r.Address] = r.DependencyChain
+	}
+	expected := map[string][]string{
+		"aws_instance.c": []string{"aws_instance.b.0", "aws_instance.c"},
+		"aws_instance.d": nil,
+		"module.child.aws_instance.e": []string{
+			"aws_instance.b.0", "aws_instance.c", "module.child.aws_instance.e"},
+	}
+	if !reflect.DeepEqual(chains, expected) {
+		t.Fatalf("bad: %#v", chains)
+	}
+
+	// Without a graph, there are no chains
+	triage = applyTriage(results, nil)
+	for _, r := range triage.Skipped {
+		if r.DependencyChain != nil {
+			t.Fatalf("bad: %#v", r)
+		}
+	}
+}
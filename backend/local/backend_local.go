@@ -1,13 +1,10 @@
 package local
 
 import (
-	"fmt"
-	"log"
-	"strings"
-
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/helper/warnings"
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/terraform"
 )
@@ -42,6 +39,8 @@ func (b *Local) context(op *backend.Operation) (*terraform.Context, state.State,
 	opts.Destroy = op.Destroy
 	opts.Module = op.Module
 	opts.Targets = op.Targets
+	opts.ForceReplace = op.ForceReplace
+	opts.GraphOrder = op.GraphOrder
 	opts.UIInput = op.UIIn
 	if op.Variables != nil {
 		opts.Variables = op.Variables
@@ -64,17 +63,29 @@ func (b *Local) context(op *backend.Operation) (*terraform.Context, state.State,
 	// If we have an operation, then we automatically do the input/validate
 	// here since every option requires this.
 	if op.Type != backend.OperationTypeInvalid {
-		// If input asking is enabled, then do that
-		if op.Plan == nil && b.OpInput {
-			mode := terraform.InputModeProvider
-			mode |= terraform.InputModeVar
+		// If input asking is enabled, then do that. A plan has the values
+		// of every variable but the ephemeral ones, so only those are asked
+		// for when it's applied.
+		if b.OpInput {
+			mode := terraform.InputModeVar
 			mode |= terraform.InputModeVarUnset
+			if op.Plan == nil {
+				mode |= terraform.InputModeProvider
+			}
 
 			if err := tfCtx.Input(mode); err != nil {
 				return nil, nil, errwrap.Wrapf("Error asking for user input: {{err}}", err)
 			}
 		}
 
+		// Plans aren't validated again, but the values of ephemeral
+		// variables aren't stored in them, so those are checked.
+		if op.Plan != nil {
+			if es := tfCtx.ValidateVariables(); len(es) > 0 {
+				return nil, nil, multierror.Append(nil, es...)
+			}
+		}
+
 		// If validation is enabled, validate
 		if b.OpValidation {
 			// We ignore warnings here on purpose. We expect users to be listening
@@ -82,18 +93,13 @@ func (b *Local) context(op *backend.Operation) (*terraform.Context, state.State,
 			ws, es := tfCtx.Validate()
 			if len(ws) > 0 {
 				// Log just in case the CLI isn't enabled
-				log.Printf("[WARN] backend/local: %d warnings: %v", len(ws), ws)
-
-				// If we have a CLI, output the warnings
-				if b.CLI != nil {
-					b.CLI.Warn(strings.TrimSpace(validateWarnHeader) + "\n")
-					for _, w := range ws {
-						b.CLI.Warn(fmt.Sprintf("  * %s", w))
-					}
-
-					// Make a newline before continuing
-					b.CLI.Output("")
-				}
+				logger.Warn("%d warnings: %v", len(ws), ws)
+			}
+
+			// If we have a CLI, output the warnings that aren't suppressed
+			if b.CLI != nil {
+				b.Warnings.WarnList(
+					b.CLI, validateWarnHeader, warnings.FromStrings(ws))
 			}
 
 			if len(es) > 0 {
@@ -0,0 +1,105 @@
+package local
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/helper/warnings"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// opLockInfo returns the info of the lock of the state for an operation.
+// With LockSubtree, only the modules of the targets are locked if the state
+// supports it, and otherwise the whole state is locked with a warning.
+func (b *Local) opLockInfo(op *backend.Operation, s state.State) (*state.LockInfo, error) {
+	info := state.NewLockInfo()
+	info.Operation = op.Type.String()
+	if !op.LockSubtree {
+		return info, nil
+	}
+
+	modules, err := subtreeLockModules(op)
+	if err != nil {
+		return nil, err
+	}
+
+	if l, ok := s.(state.SubtreeLocker); !ok || !l.SubtreeLocks() {
+		if b.CLI != nil {
+			b.Warnings.Warn(b.CLI, warnings.Warning{
+				Code:    warnings.CodeLockSubtree,
+				Message: strings.TrimSpace(lockSubtreeUnsupported),
+			})
+		}
+		return info, nil
+	}
+
+	info.Modules = modules
+	return info, nil
+}
+
+// subtreeLockModules returns the addresses of the modules that the targets
+// of an operation are in, for locking them.
+func subtreeLockModules(op *backend.Operation) ([]string, error) {
+	targets := op.Targets
+	if len(targets) == 0 && op.Plan != nil {
+		targets = op.Plan.Targets
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf(
+			"-lock-subtree requires -target addresses of modules or of the resources in them")
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, t := range targets {
+		addr, err := terraform.ParseResourceAddress(t)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing target %q: %s", t, err)
+		}
+		if len(addr.Path) == 0 {
+			return nil, fmt.Errorf(
+				"-lock-subtree can't lock the root module, which the target %q is in", t)
+		}
+
+		module := "module." + strings.Join(addr.Path, ".module.")
+		if !seen[module] {
+			seen[module] = true
+			result = append(result, module)
+		}
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// checkSubtreeDiff returns an error if the diff changes modules outside of
+// the locked modules, such as the modules that the targets depend on.
+func checkSubtreeDiff(modules []string, diff *terraform.Diff) error {
+	if diff == nil {
+		return nil
+	}
+
+	for _, md := range diff.Modules {
+		if md.Empty() || state.ModulesContain(modules, md.Path) {
+			continue
+		}
+
+		addr := "the root module"
+		if len(md.Path) > 1 {
+			addr = "module." + strings.Join(md.Path[1:], ".module.")
+		}
+		return fmt.Errorf(
+			"The plan changes %s, which isn't locked by -lock-subtree. Add it to the "+
+				"targets, or apply it separately.", addr)
+	}
+
+	return nil
+}
+
+const lockSubtreeUnsupported = `
+The backend can't lock module subtrees of the state, so the whole state is
+locked instead of the modules of the targets.
+`
@@ -0,0 +1,82 @@
+package local
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestSubtreeLockModules(t *testing.T) {
+	cases := []struct {
+		Targets []string
+		Result  []string
+		Err     string
+	}{
+		{nil, nil, "requires -target"},
+		{[]string{"aws_instance.foo"}, nil, "root module"},
+		{[]string{"module.a"}, []string{"module.a"}, ""},
+		{
+			[]string{"module.b.aws_instance.foo", "module.a", "module.b"},
+			[]string{"module.a", "module.b"},
+			"",
+		},
+		{[]string{"module.a.module.c.aws_instance.foo"}, []string{"module.a.module.c"}, ""},
+	}
+
+	for _, tc := range cases {
+		result, err := subtreeLockModules(&backend.Operation{Targets: tc.Targets})
+		if tc.Err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.Err) {
+				t.Fatalf("%v: expected an error containing %q, got %v", tc.Targets, tc.Err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %s", tc.Targets, err)
+		}
+		if !reflect.DeepEqual(result, tc.Result) {
+			t.Fatalf("%v: expected %v, got %v", tc.Targets, tc.Result, result)
+		}
+	}
+
+	// The targets of a plan are used when applying it.
+	op := &backend.Operation{Plan: &terraform.Plan{Targets: []string{"module.a"}}}
+	result, err := subtreeLockModules(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, []string{"module.a"}) {
+		t.Fatalf("expected the module of the plan's target, got %v", result)
+	}
+}
+
+func TestCheckSubtreeDiff(t *testing.T) {
+	diff := &terraform.Diff{
+		Modules: []*terraform.ModuleDiff{
+			{Path: []string{"root"}},
+			{
+				Path: []string{"root", "a", "c"},
+				Resources: map[string]*terraform.InstanceDiff{
+					"test_instance.foo": {Destroy: true},
+				},
+			},
+		},
+	}
+	if err := checkSubtreeDiff([]string{"module.a"}, diff); err != nil {
+		t.Fatal(err)
+	}
+
+	diff.Modules = append(diff.Modules, &terraform.ModuleDiff{
+		Path: []string{"root", "b"},
+		Resources: map[string]*terraform.InstanceDiff{
+			"test_instance.foo": {Destroy: true},
+		},
+	})
+	err := checkSubtreeDiff([]string{"module.a"}, diff)
+	if err == nil || !strings.Contains(err.Error(), "module.b") {
+		t.Fatalf("expected an error about module.b, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package local
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// PublishOutputs implements backend.OutputPublisher. The outputs are
+// written next to the state, at the path from outputsPath.
+func (b *Local) PublishOutputs(name string, outputs map[string]*terraform.OutputState) error {
+	if b.ReadOnly {
+		return state.ErrReadOnly
+	}
+
+	// If we have a backend handling state, defer to that.
+	if b.Backend != nil {
+		p, ok := b.Backend.(backend.OutputPublisher)
+		if !ok {
+			return backend.ErrOutputPublishingNotSupported
+		}
+
+		return p.PublishOutputs(name, outputs)
+	}
+
+	if name == "" {
+		name = backend.DefaultStateName
+	}
+	if err := b.createState(name); err != nil {
+		return err
+	}
+
+	data, err := backend.EncodeOutputs(outputs)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(b.outputsPath(name), data, 0644)
+}
+
+// PublishedOutputs implements backend.OutputPublisher.
+func (b *Local) PublishedOutputs(name string) (map[string]*terraform.OutputState, error) {
+	// If we have a backend handling state, defer to that.
+	if b.Backend != nil {
+		p, ok := b.Backend.(backend.OutputPublisher)
+		if !ok {
+			return nil, backend.ErrOutputPublishingNotSupported
+		}
+
+		return p.PublishedOutputs(name)
+	}
+
+	data, err := ioutil.ReadFile(b.outputsPath(name))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return backend.DecodeOutputs(data)
+}
+
+// outputsPath returns the path that the published outputs of the state
+// with the given name are written to and read from. They describe the
+// state that was last written, so they're kept next to the path that the
+// state is written to, StateOutPath, even if it's read from another path.
+func (b *Local) outputsPath(name string) string {
+	_, stateOutPath, _ := b.StatePaths(name)
+	return stateOutPath + DefaultOutputsExtension
+}
@@ -1,11 +1,12 @@
 package local
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-multierror"
@@ -13,6 +14,7 @@ import (
 	"github.com/hashicorp/terraform/command/clistate"
 	"github.com/hashicorp/terraform/command/format"
 	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/helper/warnings"
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/terraform"
 )
@@ -21,7 +23,7 @@ func (b *Local) opPlan(
 	ctx context.Context,
 	op *backend.Operation,
 	runningOp *backend.RunningOperation) {
-	log.Printf("[INFO] backend/local: starting Plan operation")
+	logger.Info("starting Plan operation")
 
 	if b.CLI != nil && op.Plan != nil {
 		b.CLI.Output(b.Colorize().Color(
@@ -60,12 +62,18 @@ func (b *Local) opPlan(
 		return
 	}
 
+	var lockModules []string
 	if op.LockState {
 		lockCtx, cancel := context.WithTimeout(ctx, op.StateLockTimeout)
 		defer cancel()
 
-		lockInfo := state.NewLockInfo()
-		lockInfo.Operation = op.Type.String()
+		lockInfo, err := b.opLockInfo(op, opState)
+		if err != nil {
+			runningOp.Err = err
+			return
+		}
+		lockModules = lockInfo.Modules
+
 		lockID, err := clistate.Lock(lockCtx, opState, lockInfo, b.CLI, b.Colorize())
 		if err != nil {
 			runningOp.Err = errwrap.Wrapf("Error locking state: {{err}}", err)
@@ -82,29 +90,58 @@ func (b *Local) opPlan(
 	// Setup the state
 	runningOp.State = tfCtx.State()
 
+	// Keep the state from before refreshing, to tell which changes in the
+	// plan undo drift.
+	prior := tfCtx.State()
+
 	// If we're refreshing before plan, perform that
 	if op.PlanRefresh {
-		log.Printf("[INFO] backend/local: plan calling Refresh")
+		logger.Info("plan calling Refresh")
 
 		if b.CLI != nil {
-			b.CLI.Output(b.Colorize().Color(strings.TrimSpace(planRefreshing) + "\n"))
+			msg := planRefreshing
+			if op.PlanRefreshChanged {
+				msg = planRefreshingChanged
+			}
+			b.CLI.Output(b.Colorize().Color(strings.TrimSpace(msg) + "\n"))
 		}
 
-		_, err := tfCtx.Refresh()
+		_, err := refresh(tfCtx, op)
 		if err != nil {
 			runningOp.Err = errwrap.Wrapf("Error refreshing state: {{err}}", err)
 			return
 		}
 	}
 
+	// Without a refresh, the plan is only as current as the state, so the
+	// values from the state are marked with how old they are.
+	var stale *format.StaleState
+	if !op.PlanRefresh && op.Plan == nil && runningOp.State.HasResources() {
+		stale = &format.StaleState{Serial: runningOp.State.Serial}
+		if mt, ok := opState.(state.StateModTimer); ok {
+			stale.Written = mt.ModTime()
+		}
+
+		if b.CLI != nil && b.Warnings.Show(warnings.CodeStateNotRefreshed) {
+			b.CLI.Output(b.Colorize().Color(planNotRefreshedWarning(stale)))
+		}
+	}
+
 	// Perform the plan
-	log.Printf("[INFO] backend/local: plan calling Plan")
+	logger.Info("plan calling Plan")
 	plan, err := tfCtx.Plan()
 	if err != nil {
 		runningOp.Err = errwrap.Wrapf("Error running plan: {{err}}", err)
 		return
 	}
 
+	if len(lockModules) > 0 {
+		if err := checkSubtreeDiff(lockModules, plan.Diff); err != nil {
+			runningOp.Err = err
+			return
+		}
+	}
+
 	// Record state
 	runningOp.PlanEmpty = plan.Diff.Empty()
 
@@ -119,7 +156,7 @@ func (b *Local) opPlan(
 			plan.State.Remote = nil
 		}
 
-		log.Printf("[INFO] backend/local: writing plan output to: %s", path)
+		logger.Info("writing plan output to: %s", path)
 		f, err := os.Create(path)
 		if err == nil {
 			err = terraform.WritePlan(plan, f)
@@ -131,13 +168,39 @@ func (b *Local) opPlan(
 		}
 	}
 
+	// Write the apply graph of the plan for tools outside of Terraform
+	if path := op.PlanGraphOutPath; path != "" {
+		logger.Info("writing the apply graph to: %s", path)
+		if err := writePlanGraph(tfCtx, path); err != nil {
+			runningOp.Err = fmt.Errorf("Error writing the apply graph: %s", err)
+			return
+		}
+	}
+
 	// Perform some output tasks if we have a CLI to output to.
 	if b.CLI != nil {
+		if err := b.opTargetReport(tfCtx); err != nil {
+			runningOp.Err = err
+			return
+		}
+
 		if plan.Diff.Empty() {
 			b.CLI.Output(b.Colorize().Color(strings.TrimSpace(planNoChanges)))
+			if err := b.opPlanExplain(op, plan); err != nil {
+				runningOp.Err = err
+			}
 			return
 		}
 
+		// If the last apply was targeted and this plan isn't, point out
+		// what that apply skipped so that it isn't mistaken for new changes.
+		if len(op.Targets) == 0 && !op.Destroy && plan.State != nil {
+			msg := planTargetedApplyNotice(plan)
+			if msg != "" && b.Warnings.Show(warnings.CodeTargetedApply) {
+				b.CLI.Output(b.Colorize().Color(msg))
+			}
+		}
+
 		if path := op.PlanOutPath; path == "" {
 			b.CLI.Output(strings.TrimSpace(planHeaderNoOutput) + "\n")
 		} else {
@@ -146,10 +209,19 @@ func (b *Local) opPlan(
 				path))
 		}
 
+		// Drift is only known after refreshing, and the changes of a
+		// destroy plan are all caused by -destroy.
+		var causes map[string][]terraform.PlanChangeCause
+		if op.PlanRefresh && !op.Destroy {
+			causes = plan.Causes(prior)
+		}
+
 		b.CLI.Output(format.Plan(&format.PlanOpts{
 			Plan:        plan,
 			Color:       b.Colorize(),
 			ModuleDepth: -1,
+			StaleState:  stale,
+			Causes:      causes,
 		}))
 
 		b.CLI.Output(b.Colorize().Color(fmt.Sprintf(
@@ -158,9 +230,102 @@ func (b *Local) opPlan(
 			countHook.ToAdd+countHook.ToRemoveAndAdd,
 			countHook.ToChange,
 			countHook.ToRemove+countHook.ToRemoveAndAdd)))
+
+		if op.PlanConfidence {
+			b.CLI.Output("\n" + format.PlanConfidence(plan.Confidence(), b.Colorize()))
+		}
+
+		if err := b.opPlanExplain(op, plan); err != nil {
+			runningOp.Err = err
+			return
+		}
 	}
 }
 
+// writePlanGraph writes the apply graph of the plan that tfCtx made to the
+// given path.
+func writePlanGraph(tfCtx *terraform.Context, path string) error {
+	g, err := tfCtx.ApplyGraph()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return terraform.WriteGraphExport(g, f)
+}
+
+// opTargetReport outputs the resources that the targets of the operation
+// leave out, and those they only include as dependencies, if it has any.
+func (b *Local) opTargetReport(tfCtx *terraform.Context) error {
+	report, err := tfCtx.TargetReport()
+	if err != nil {
+		return fmt.Errorf("Error reporting targeted resources: %s", err)
+	}
+	if report != nil {
+		b.CLI.Output(format.TargetReport(report, b.Colorize()) + "\n")
+	}
+
+	return nil
+}
+
+// opPlanExplain outputs the explanations of the changes to the resources
+// that the operation asks about.
+func (b *Local) opPlanExplain(op *backend.Operation, plan *terraform.Plan) error {
+	for _, addr := range op.PlanExplain {
+		explanations, err := plan.Explain(addr)
+		if err != nil {
+			return fmt.Errorf("Error explaining %s: %s", addr, err)
+		}
+
+		for _, e := range explanations {
+			b.CLI.Output("\n" + format.PlanExplanation(e, b.Colorize()))
+		}
+	}
+
+	return nil
+}
+
+// planTargetedApplyNotice returns a notice about the resources with changes
+// in the plan that were skipped by a targeted apply, or an empty string if
+// there aren't any.
+func planTargetedApplyNotice(plan *terraform.Plan) string {
+	ta := plan.State.TargetedApply
+	skipped := ta.Skipped(plan.Diff)
+	if len(skipped) == 0 {
+		return ""
+	}
+
+	var targets, resources bytes.Buffer
+	for _, t := range ta.Targets {
+		targets.WriteString(fmt.Sprintf("  - %s\n", t))
+	}
+	for _, addr := range skipped {
+		resources.WriteString(fmt.Sprintf("  - %s\n", addr))
+	}
+
+	return fmt.Sprintf(
+		strings.TrimSpace(planTargetedApplySkipped)+"\n",
+		ta.Time.Local().Format(time.RFC1123), targets.String(), resources.String())
+}
+
+// planNotRefreshedWarning returns the warning that a plan is based on the
+// given state, which wasn't refreshed.
+func planNotRefreshedWarning(stale *format.StaleState) string {
+	var age string
+	if !stale.Written.IsZero() {
+		d := time.Since(stale.Written)
+		age = fmt.Sprintf(", which is %s old", d-d%time.Second)
+	}
+
+	return fmt.Sprintf(
+		strings.TrimSpace(planNotRefreshed)+"\n", stale, age)
+}
+
 const planErrNoConfig = `
 No configuration files found!
 
@@ -196,6 +361,31 @@ plan.
 Path: %s
 `
 
+const planTargetedApplySkipped = `
+[reset][bold][yellow]Warning: The last apply was targeted.[reset][yellow]
+
+The last apply to this state, on %s,
+was limited to these targets:
+
+%s
+The following resources have changes that the targeted apply skipped. These
+changes may have been pending since then:
+
+%s
+This warning is shown until an apply is run without -target. To hide it, use
+-suppress-warning=targeted-apply.[reset]
+`
+
+const planNotRefreshed = `
+[reset][bold][yellow]Warning: The state wasn't refreshed.[reset][yellow]
+
+This plan was made without checking the real resources first, so the
+values of existing resources are only %s%s.
+Changes made outside of Terraform since then aren't in this plan.
+
+To hide this warning, use -suppress-warning=state-not-refreshed.[reset]
+`
+
 const planNoChanges = `
 [reset][bold][green]No changes. Infrastructure is up-to-date.[reset][green]
 
@@ -209,3 +399,12 @@ const planRefreshing = `
 The refreshed state will be used to calculate this plan, but will not be
 persisted to local or remote state storage.
 `
+
+const planRefreshingChanged = `
+[reset][bold]Refreshing the resources affected by configuration changes
+in-memory prior to plan...[reset]
+Other resources aren't refreshed, so changes made to them outside of
+Terraform won't be in this plan. The refreshed state will be used to
+calculate this plan, but will not be persisted to local or remote state
+storage.
+`
@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/config/module"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
 )
 
 func TestLocal_planBasic(t *testing.T) {
@@ -89,6 +90,268 @@ func TestLocal_planRefreshFalse(t *testing.T) {
 	}
 }
 
+func TestLocal_planRefreshFalseStale(t *testing.T) {
+	b := TestLocal(t)
+	p := TestLocalProvider(t, b, "test")
+	p.DiffReturn = &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"ami": &terraform.ResourceAttrDiff{Old: "ami-1", New: "ami-2"},
+		},
+	}
+	state := testPlanState()
+	state.Serial = 4
+	terraform.TestStateFile(t, b.StatePath, state)
+	ui := new(cli.MockUi)
+	b.CLI = ui
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/plan")
+	defer modCleanup()
+
+	op := testOperationPlan()
+	op.Module = mod
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "The state wasn't refreshed") {
+		t.Fatalf("missing warning:\n%s", output)
+	}
+	if !strings.Contains(output, "~ test_instance.foo\n    (values as of state serial 4, written at ") {
+		t.Fatalf("missing annotation:\n%s", output)
+	}
+	if strings.Contains(output, "an unknown time") {
+		t.Fatalf("the time the state was written should be known:\n%s", output)
+	}
+
+	// A refreshed plan is current, so it isn't marked
+	ui.OutputWriter.Reset()
+	op.PlanRefresh = true
+	run, err = b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+	if output := ui.OutputWriter.String(); strings.Contains(output, "serial 4") {
+		t.Fatalf("unexpected annotation:\n%s", output)
+	}
+}
+
+func TestLocal_planCauses(t *testing.T) {
+	b := TestLocal(t)
+	p := TestLocalProvider(t, b, "test")
+	p.RefreshFn = func(
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState) (*terraform.InstanceState, error) {
+		s = s.DeepCopy()
+		s.Attributes["ami"] = "baz"
+		return s, nil
+	}
+	p.DiffReturn = &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"ami": &terraform.ResourceAttrDiff{Old: "baz", New: "bar"},
+		},
+	}
+	state := testPlanState()
+	state.RootModule().Resources["test_instance.foo"].Primary.Attributes = map[string]string{
+		"ami": "bar",
+	}
+	terraform.TestStateFile(t, b.StatePath, state)
+	ui := new(cli.MockUi)
+	b.CLI = ui
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/plan")
+	defer modCleanup()
+
+	op := testOperationPlan()
+	op.Module = mod
+	op.PlanRefresh = true
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	// The change sets the ami back to what it was before refreshing
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "~ test_instance.foo (cause: drift)\n") {
+		t.Fatalf("missing cause:\n%s", output)
+	}
+
+	// Without refreshing, drift isn't known, so there are no causes
+	ui.OutputWriter.Reset()
+	op.PlanRefresh = false
+	run, err = b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+	if output := ui.OutputWriter.String(); strings.Contains(output, "cause:") {
+		t.Fatalf("unexpected cause:\n%s", output)
+	}
+}
+
+func TestLocal_planRefreshChanged(t *testing.T) {
+	b := TestLocal(t)
+	p := TestLocalProvider(t, b, "test")
+	terraform.TestStateFile(t, b.StatePath, testPlanState())
+	ui := new(cli.MockUi)
+	b.CLI = ui
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/plan")
+	defer modCleanup()
+
+	op := testOperationPlan()
+	op.Module = mod
+	op.PlanRefresh = true
+	op.PlanRefreshChanged = true
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	// The provider doesn't return a diff, so nothing is affected by changes
+	if p.RefreshCalled {
+		t.Fatal("refresh should not be called")
+	}
+	if output := ui.OutputWriter.String(); !strings.Contains(output, "affected by configuration changes") {
+		t.Fatalf("bad: %s", output)
+	}
+}
+
+func TestLocal_planTargetedApplyNotice(t *testing.T) {
+	b := TestLocal(t)
+	TestLocalProvider(t, b, "test")
+	terraform.TestStateFile(t, b.StatePath, &terraform.State{
+		Version: 2,
+		TargetedApply: &terraform.TargetedApplyState{
+			Targets: []string{"test_instance.other"},
+		},
+	})
+	ui := new(cli.MockUi)
+	b.CLI = ui
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/plan")
+	defer modCleanup()
+
+	op := testOperationPlan()
+	op.Module = mod
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "The last apply was targeted") {
+		t.Fatalf("missing targeted apply notice:\n%s", output)
+	}
+	if !strings.Contains(output, "  - test_instance.other\n") {
+		t.Fatalf("missing target:\n%s", output)
+	}
+	if !strings.Contains(output, "  - test_instance.foo\n") {
+		t.Fatalf("missing skipped resource:\n%s", output)
+	}
+
+	// A targeted plan doesn't show the notice
+	ui.OutputWriter.Reset()
+	op.Targets = []string{"test_instance.foo"}
+	run, err = b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if output := ui.OutputWriter.String(); strings.Contains(output, "The last apply was targeted") {
+		t.Fatalf("unexpected targeted apply notice:\n%s", output)
+	}
+}
+
+func TestLocal_planTargetReport(t *testing.T) {
+	b := TestLocal(t)
+	TestLocalProvider(t, b, "test")
+	state := testPlanState()
+	state.RootModule().Resources["test_instance.other"] = &terraform.ResourceState{
+		Type: "test_instance",
+		Primary: &terraform.InstanceState{
+			ID: "other",
+		},
+	}
+	terraform.TestStateFile(t, b.StatePath, state)
+	ui := new(cli.MockUi)
+	b.CLI = ui
+
+	mod, modCleanup := module.TestTree(t, "./test-fixtures/plan")
+	defer modCleanup()
+
+	op := testOperationPlan()
+	op.Module = mod
+	op.Targets = []string{"test_instance.foo"}
+
+	run, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+
+	output := ui.OutputWriter.String()
+	for _, expected := range []string{
+		"Targeted run: 1 of 2 resources considered.",
+		"Targeted (1):\n  - test_instance.foo\n",
+		"Included as dependencies (0):\n  (none)\n",
+		"Excluded (1):\n  - test_instance.other\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q in output:\n%s", expected, output)
+		}
+	}
+
+	// An untargeted plan has no report
+	ui.OutputWriter.Reset()
+	op.Targets = nil
+	run, err = b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	<-run.Done()
+	if run.Err != nil {
+		t.Fatalf("err: %s", run.Err)
+	}
+	if output := ui.OutputWriter.String(); strings.Contains(output, "Targeted run") {
+		t.Fatalf("unexpected target report:\n%s", output)
+	}
+}
+
 func TestLocal_planDestroy(t *testing.T) {
 	b := TestLocal(t)
 	p := TestLocalProvider(t, b, "test")
@@ -12,6 +12,7 @@ import (
 	"github.com/hashicorp/terraform/command/clistate"
 	"github.com/hashicorp/terraform/config/module"
 	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
 )
 
 func (b *Local) opRefresh(
@@ -104,3 +105,14 @@ Refresh will do nothing. Refresh does not error or return an erroneous
 exit status because many automation scripts use refresh, plan, then apply
 and may not have a state file yet for the first run.
 `
+
+// refresh refreshes the state of tfCtx before the plan of op, limited to the
+// resources affected by changes to the configuration if op asks for it.
+func refresh(tfCtx *terraform.Context, op *backend.Operation) (*terraform.State, error) {
+	if op.PlanRefreshChanged {
+		logger.Info("refreshing only the resources affected by changes")
+		return tfCtx.RefreshChanged()
+	}
+
+	return tfCtx.Refresh()
+}
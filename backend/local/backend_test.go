@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -87,6 +88,96 @@ func TestLocal_StatePaths(t *testing.T) {
 
 }
 
+func TestLocal_StatePathsConfigured(t *testing.T) {
+	b := &Local{}
+	backend.TestBackendConfig(t, b, map[string]interface{}{
+		"workspace_dir":  "states",
+		"state_filename": "{workspace}.tfstate",
+	})
+
+	// CLI defaults don't replace the configured paths
+	b.CLIInit(&backend.CLIOpts{StatePath: DefaultStateFilename})
+
+	cases := map[string]string{
+		backend.DefaultStateName: "default.tfstate",
+		"prod":                   filepath.Join("states", "prod", "prod.tfstate"),
+	}
+	for name, expected := range cases {
+		path, out, back := b.StatePaths(name)
+		if path != expected || out != expected || back != expected+DefaultBackupExtension {
+			t.Fatalf("%s: bad: %q %q %q", name, path, out, back)
+		}
+	}
+}
+
+func TestLocal_StatePathsDataHome(t *testing.T) {
+	defer testTmpDir(t)()
+
+	home, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	old := os.Getenv("XDG_DATA_HOME")
+	os.Setenv("XDG_DATA_HOME", home)
+	defer os.Setenv("XDG_DATA_HOME", old)
+	if runtime.GOOS == "windows" {
+		old := os.Getenv("LOCALAPPDATA")
+		os.Setenv("LOCALAPPDATA", home)
+		defer os.Setenv("LOCALAPPDATA", old)
+	}
+
+	b := &Local{}
+	backend.TestBackendConfig(t, b, map[string]interface{}{
+		"data_home": true,
+	})
+
+	dir, err := projectDataDir()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.HasPrefix(dir, filepath.Join(home, "terraform", "states")) {
+		t.Fatalf("bad: %s", dir)
+	}
+
+	cases := map[string]string{
+		backend.DefaultStateName: filepath.Join(dir, DefaultStateFilename),
+		"prod":                   filepath.Join(dir, DefaultEnvDir, "prod", DefaultStateFilename),
+	}
+	for name, expected := range cases {
+		if path, _, _ := b.StatePaths(name); path != expected {
+			t.Fatalf("%s: bad: %q", name, path)
+		}
+	}
+
+	// The states are written outside of the working directory
+	s, err := b.State("prod")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.WriteState(terraform.NewState()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(cases["prod"]); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(DefaultEnvDir); !os.IsNotExist(err) {
+		t.Fatalf("states shouldn't be in the working directory: %v", err)
+	}
+
+	states, err := b.States()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(states, []string{backend.DefaultStateName, "prod"}) {
+		t.Fatalf("bad: %#v", states)
+	}
+}
+
 func TestLocal_addAndRemoveStates(t *testing.T) {
 	defer testTmpDir(t)()
 	dflt := backend.DefaultStateName
@@ -288,3 +379,58 @@ func testTmpDir(t *testing.T) func() {
 		os.RemoveAll(tmp)
 	}
 }
+
+func TestLocal_readOnly(t *testing.T) {
+	defer testTmpDir(t)()
+
+	b := &Local{ReadOnly: true}
+	s, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteState(terraform.NewState()); err != state.ErrReadOnly {
+		t.Fatalf("bad: %s", err)
+	}
+	if err := s.PersistState(); err != state.ErrReadOnly {
+		t.Fatalf("bad: %s", err)
+	}
+	if _, err := os.Stat(DefaultStateFilename); err == nil {
+		t.Fatal("state shouldn't be written")
+	}
+
+	if _, err := b.State("foo"); err == nil {
+		t.Fatal("creating a state should error")
+	}
+	if err := b.DeleteState("foo"); err != state.ErrReadOnly {
+		t.Fatalf("bad: %s", err)
+	}
+	if err := b.PublishOutputs(backend.DefaultStateName, nil); err != state.ErrReadOnly {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestLocal_publishOutputsStateOut(t *testing.T) {
+	defer testTmpDir(t)()
+
+	// The state is read from one path and written to another, as with
+	// -state and -state-out
+	b := &Local{StatePath: "in.tfstate", StateOutPath: "out.tfstate"}
+	outputs := map[string]*terraform.OutputState{
+		"foo": &terraform.OutputState{Type: "string", Value: "bar"},
+	}
+	if err := b.PublishOutputs(backend.DefaultStateName, outputs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("out.tfstate" + DefaultOutputsExtension); err != nil {
+		t.Fatalf("outputs should be next to the state that's written: %s", err)
+	}
+
+	// They're read back from the same place
+	actual, err := b.PublishedOutputs(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual["foo"] == nil || actual["foo"].Value != "bar" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
@@ -11,6 +11,8 @@ func (b *Local) CLIInit(opts *backend.CLIOpts) error {
 	b.ContextOpts = opts.ContextOpts
 	b.OpInput = opts.Input
 	b.OpValidation = opts.Validation
+	b.Warnings = opts.Warnings
+	b.ReadOnly = opts.ReadOnly
 
 	// Only configure state paths if we didn't do so via the configure func.
 	if b.StatePath == "" {
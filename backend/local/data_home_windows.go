@@ -0,0 +1,25 @@
+// +build windows
+
+package local
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// dataHome returns the directory for the data of the user that isn't
+// roamed between machines.
+func dataHome() (string, error) {
+	if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, "AppData", "Local"), nil
+}
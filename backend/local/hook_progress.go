@@ -0,0 +1,107 @@
+package local
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ProgressHook is a hook that tracks which resources in a diff have been
+// applied, are being applied, or haven't been started yet. It is used to
+// report what happened to the user when an apply is interrupted.
+type ProgressHook struct {
+	terraform.NilHook
+	sync.Mutex
+
+	progress map[string]progressState
+}
+
+type progressState byte
+
+const (
+	progressPending progressState = iota
+	progressInFlight
+	progressCompleted
+)
+
+// SetDiff resets the hook and records every resource with changes in
+// the given diff as pending.
+func (h *ProgressHook) SetDiff(d *terraform.Diff) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.progress = make(map[string]progressState)
+	if d == nil {
+		return
+	}
+
+	for _, m := range d.Modules {
+		for k, rd := range m.Resources {
+			// We don't report anything for data sources
+			if rd.Empty() || strings.HasPrefix(k, "data.") {
+				continue
+			}
+
+			info := &terraform.InstanceInfo{Id: k, ModulePath: m.Path}
+			h.progress[info.HumanId()] = progressPending
+		}
+	}
+}
+
+func (h *ProgressHook) PreApply(
+	n *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	d *terraform.InstanceDiff) (terraform.HookAction, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.progress != nil && !d.Empty() {
+		h.progress[n.HumanId()] = progressInFlight
+	}
+
+	return terraform.HookActionContinue, nil
+}
+
+func (h *ProgressHook) PostApply(
+	n *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	e error) (terraform.HookAction, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	// Resources that errored are left in-flight since we can't be sure
+	// how far the provider got before it returned.
+	if h.progress != nil && e == nil {
+		if _, ok := h.progress[n.HumanId()]; ok {
+			h.progress[n.HumanId()] = progressCompleted
+		}
+	}
+
+	return terraform.HookActionContinue, nil
+}
+
+// Summary returns the sorted IDs of the resources that completed, the
+// resources that were started but didn't complete, and the resources that
+// were never started.
+func (h *ProgressHook) Summary() (completed, inFlight, pending []string) {
+	h.Lock()
+	defer h.Unlock()
+
+	for id, s := range h.progress {
+		switch s {
+		case progressCompleted:
+			completed = append(completed, id)
+		case progressInFlight:
+			inFlight = append(inFlight, id)
+		case progressPending:
+			pending = append(pending, id)
+		}
+	}
+
+	sort.Strings(completed)
+	sort.Strings(inFlight)
+	sort.Strings(pending)
+	return
+}
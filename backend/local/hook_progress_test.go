@@ -0,0 +1,78 @@
+package local
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestProgressHook_impl(t *testing.T) {
+	var _ terraform.Hook = new(ProgressHook)
+}
+
+func TestProgressHook(t *testing.T) {
+	h := new(ProgressHook)
+
+	change := &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"foo": &terraform.ResourceAttrDiff{Old: "a", New: "b"},
+		},
+	}
+	h.SetDiff(&terraform.Diff{
+		Modules: []*terraform.ModuleDiff{
+			&terraform.ModuleDiff{
+				Path: []string{"root"},
+				Resources: map[string]*terraform.InstanceDiff{
+					"aws_instance.a":   change,
+					"aws_instance.b":   change,
+					"aws_instance.c":   change,
+					"aws_instance.d":   change,
+					"aws_instance.e":   &terraform.InstanceDiff{},
+					"data.aws_ami.ami": change,
+				},
+			},
+			&terraform.ModuleDiff{
+				Path: []string{"root", "child"},
+				Resources: map[string]*terraform.InstanceDiff{
+					"aws_instance.a": change,
+				},
+			},
+		},
+	})
+
+	state := &terraform.InstanceState{ID: "foo"}
+	info := func(id string, path ...string) *terraform.InstanceInfo {
+		return &terraform.InstanceInfo{
+			Id:         id,
+			ModulePath: append([]string{"root"}, path...),
+		}
+	}
+
+	// a completes, b is still in flight, c fails
+	h.PreApply(info("aws_instance.a"), state, change)
+	h.PostApply(info("aws_instance.a"), state, nil)
+	h.PreApply(info("aws_instance.b"), state, change)
+	h.PreApply(info("aws_instance.c"), state, change)
+	h.PostApply(info("aws_instance.c"), state, errors.New("stopped"))
+	h.PreApply(info("aws_instance.a", "child"), state, change)
+	h.PostApply(info("aws_instance.a", "child"), state, nil)
+
+	completed, inFlight, pending := h.Summary()
+
+	expected := []string{"aws_instance.a", "module.child.aws_instance.a"}
+	if !reflect.DeepEqual(completed, expected) {
+		t.Fatalf("bad completed: %#v", completed)
+	}
+
+	expected = []string{"aws_instance.b", "aws_instance.c"}
+	if !reflect.DeepEqual(inFlight, expected) {
+		t.Fatalf("bad in-flight: %#v", inFlight)
+	}
+
+	expected = []string{"aws_instance.d"}
+	if !reflect.DeepEqual(pending, expected) {
+		t.Fatalf("bad pending: %#v", pending)
+	}
+}
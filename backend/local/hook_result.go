@@ -0,0 +1,190 @@
+package local
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// The actions of the resources in an apply result.
+const (
+	ResultActionCreate  = "create"
+	ResultActionUpdate  = "update"
+	ResultActionDestroy = "destroy"
+	ResultActionReplace = "replace"
+)
+
+// The statuses of the resources in an apply result.
+const (
+	// ResultStatusComplete is for resources whose changes were applied.
+	ResultStatusComplete = "complete"
+
+	// ResultStatusError is for resources that failed to apply.
+	ResultStatusError = "error"
+
+	// ResultStatusIncomplete is for resources that were being applied when
+	// the apply was interrupted, or that are replaced and only had one of
+	// the two steps applied.
+	ResultStatusIncomplete = "incomplete"
+
+	// ResultStatusNotStarted is for resources that were never applied,
+	// because the apply stopped first.
+	ResultStatusNotStarted = "not_started"
+)
+
+// ResourceResult is the outcome of applying the changes to a resource.
+type ResourceResult struct {
+	Address  string  `json:"address"`
+	Action   string  `json:"action"`
+	Status   string  `json:"status"`
+	Duration float64 `json:"duration_seconds"`
+	Error    string  `json:"error,omitempty"`
+
+	// steps is how many times the resource is applied: twice for a
+	// replacement, once to destroy it and once to create it.
+	steps   int
+	applied int
+	started time.Time
+}
+
+// ResultHook is a hook that records the outcome and duration of applying
+// each resource in a diff, for the result written by
+// "terraform apply -result-out".
+type ResultHook struct {
+	terraform.NilHook
+	sync.Mutex
+
+	resources map[string]*ResourceResult
+
+	// now returns the current time. It's only replaced by tests.
+	now func() time.Time
+}
+
+// SetDiff resets the hook and records every resource with changes in the
+// given diff as not started.
+func (h *ResultHook) SetDiff(d *terraform.Diff) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.resources = make(map[string]*ResourceResult)
+	if d == nil {
+		return
+	}
+
+	for _, m := range d.Modules {
+		for k, rd := range m.Resources {
+			// We don't report anything for data sources
+			if rd.Empty() || strings.HasPrefix(k, "data.") {
+				continue
+			}
+
+			r := &ResourceResult{Status: ResultStatusNotStarted, steps: 1}
+			switch rd.ChangeType() {
+			case terraform.DiffCreate:
+				r.Action = ResultActionCreate
+			case terraform.DiffDestroy:
+				r.Action = ResultActionDestroy
+			case terraform.DiffDestroyCreate:
+				r.Action = ResultActionReplace
+				r.steps = 2
+			default:
+				r.Action = ResultActionUpdate
+			}
+
+			info := &terraform.InstanceInfo{Id: k, ModulePath: m.Path}
+			r.Address = info.HumanId()
+			h.resources[r.Address] = r
+		}
+	}
+}
+
+func (h *ResultHook) PreApply(
+	n *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	d *terraform.InstanceDiff) (terraform.HookAction, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	r, ok := h.resources[n.HumanId()]
+	if !ok || d.Empty() {
+		return terraform.HookActionContinue, nil
+	}
+
+	// The duration of a replacement is from the start of its first step
+	if r.Status == ResultStatusNotStarted {
+		r.Status = ResultStatusIncomplete
+		r.started = h.timeNow()
+	}
+
+	return terraform.HookActionContinue, nil
+}
+
+func (h *ResultHook) PostApply(
+	n *terraform.InstanceInfo,
+	s *terraform.InstanceState,
+	e error) (terraform.HookAction, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	r, ok := h.resources[n.HumanId()]
+	if !ok || r.Status != ResultStatusIncomplete {
+		return terraform.HookActionContinue, nil
+	}
+
+	r.Duration = h.timeNow().Sub(r.started).Seconds()
+	r.applied++
+	switch {
+	case e != nil:
+		r.Status = ResultStatusError
+		r.Error = resultError(e)
+	case r.applied >= r.steps:
+		r.Status = ResultStatusComplete
+	}
+
+	return terraform.HookActionContinue, nil
+}
+
+// Resources returns the results of the resources, sorted by address.
+func (h *ResultHook) Resources() []*ResourceResult {
+	h.Lock()
+	defer h.Unlock()
+
+	result := make([]*ResourceResult, 0, len(h.resources))
+	for _, r := range h.resources {
+		copy := *r
+		result = append(result, &copy)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Address < result[j].Address
+	})
+
+	return result
+}
+
+// resultError returns the message of an error without the header that
+// multierror adds to it.
+func resultError(err error) string {
+	merr, ok := err.(*multierror.Error)
+	if !ok {
+		return err.Error()
+	}
+
+	var msgs []string
+	for _, e := range multierror.Flatten(merr).(*multierror.Error).Errors {
+		msgs = append(msgs, e.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+func (h *ResultHook) timeNow() time.Time {
+	if h.now != nil {
+		return h.now()
+	}
+
+	return time.Now()
+}
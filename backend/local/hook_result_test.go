@@ -0,0 +1,95 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestResultHook_impl(t *testing.T) {
+	var _ terraform.Hook = new(ResultHook)
+}
+
+func TestResultHook(t *testing.T) {
+	now := time.Unix(0, 0)
+	h := &ResultHook{now: func() time.Time { return now }}
+
+	change := &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"foo": &terraform.ResourceAttrDiff{Old: "a", New: "b"},
+		},
+	}
+	replace := &terraform.InstanceDiff{
+		Destroy: true,
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"foo": &terraform.ResourceAttrDiff{Old: "a", New: "b", RequiresNew: true},
+		},
+	}
+	destroy := &terraform.InstanceDiff{Destroy: true}
+	h.SetDiff(&terraform.Diff{
+		Modules: []*terraform.ModuleDiff{
+			&terraform.ModuleDiff{
+				Path: []string{"root"},
+				Resources: map[string]*terraform.InstanceDiff{
+					"aws_instance.a":   change,
+					"aws_instance.b":   replace,
+					"aws_instance.c":   destroy,
+					"aws_instance.d":   change,
+					"aws_instance.e":   &terraform.InstanceDiff{},
+					"data.aws_ami.ami": change,
+				},
+			},
+			&terraform.ModuleDiff{
+				Path: []string{"root", "child"},
+				Resources: map[string]*terraform.InstanceDiff{
+					"aws_instance.a": replace,
+				},
+			},
+		},
+	})
+
+	state := &terraform.InstanceState{ID: "foo"}
+	info := func(id string, path ...string) *terraform.InstanceInfo {
+		return &terraform.InstanceInfo{
+			Id:         id,
+			ModulePath: append([]string{"root"}, path...),
+		}
+	}
+
+	// a is updated, b is replaced in two steps, c fails, d isn't started
+	// and the child's a is only destroyed.
+	h.PreApply(info("aws_instance.a"), state, change)
+	now = now.Add(time.Second)
+	h.PostApply(info("aws_instance.a"), state, nil)
+	h.PreApply(info("aws_instance.b"), state, destroy)
+	now = now.Add(time.Second)
+	h.PostApply(info("aws_instance.b"), nil, nil)
+	h.PreApply(info("aws_instance.b"), nil, change)
+	now = now.Add(time.Second)
+	h.PostApply(info("aws_instance.b"), state, nil)
+	h.PreApply(info("aws_instance.c"), state, destroy)
+	h.PostApply(info("aws_instance.c"), state, errors.New("failed"))
+	h.PreApply(info("aws_instance.a", "child"), state, destroy)
+	h.PostApply(info("aws_instance.a", "child"), nil, nil)
+
+	var actual []string
+	for _, r := range h.Resources() {
+		actual = append(actual, fmt.Sprintf(
+			"%s %s %s %v %s", r.Address, r.Action, r.Status, r.Duration, r.Error))
+	}
+
+	expected := []string{
+		"aws_instance.a update complete 1 ",
+		"aws_instance.b replace complete 2 ",
+		"aws_instance.c destroy error 0 failed",
+		"aws_instance.d update not_started 0 ",
+		"module.child.aws_instance.a replace incomplete 0 ",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform/state"
+)
+
+// LockConfigKey is the key of the block in the configuration of a backend
+// that configures a separate backend to lock its states with. Like the
+// encryption block, it's handled outside of the backend, and backends must
+// not use this key themselves. It isn't "lock", since backends such as
+// consul already have a lock argument.
+const LockConfigKey = "state_lock"
+
+// LockConfig is the configuration of the backend that locks the states of
+// another backend.
+type LockConfig struct {
+	// Type is the type of the locking backend, such as "consul".
+	Type string
+
+	// Config is the configuration of the locking backend.
+	Config map[string]interface{}
+}
+
+// SplitLockConfig removes the lock block from the raw configuration of a
+// backend. It returns the rest of the configuration, along with the lock
+// configuration if the block was present.
+func SplitLockConfig(raw map[string]interface{}) (map[string]interface{}, *LockConfig, error) {
+	rest, block, err := splitBlock(raw, LockConfigKey)
+	if err != nil || block == nil {
+		return rest, nil, err
+	}
+
+	c := &LockConfig{Config: make(map[string]interface{})}
+	for k, v := range block {
+		if k == "type" {
+			c.Type, _ = v.(string)
+			continue
+		}
+
+		c.Config[k] = v
+	}
+	if c.Type == "" {
+		return nil, nil, fmt.Errorf("%s: type is required", LockConfigKey)
+	}
+
+	return rest, c, nil
+}
+
+// StateLocker is an optional interface that a Backend can implement to
+// lock the state with the given name without loading it, so that it can
+// lock the states of another backend. Loading a state with State may store
+// an empty state, which a backend that only locks shouldn't do.
+//
+// Backends whose locking is optional return ErrLockingNotConfigured when
+// it isn't enabled in their configuration, rather than a Locker that does
+// nothing.
+type StateLocker interface {
+	StateLocker(name string) (state.Locker, error)
+}
+
+// ErrLockingNotConfigured is returned by StateLocker when locking isn't
+// enabled in the configuration of the backend.
+var ErrLockingNotConfigured = errors.New("locking isn't configured for the backend")
+
+// ExternallyLocked returns a Backend that stores states with b, and locks
+// them with the lock of the state of the same name in locker. Nothing is
+// stored in locker besides what it stores to be able to lock.
+func ExternallyLocked(b Backend, locker Backend) (Backend, error) {
+	// Enhanced backends lock their own states
+	if _, ok := b.(Enhanced); ok {
+		return nil, fmt.Errorf("the %T backend doesn't support a separate lock", b)
+	}
+
+	if _, ok := locker.(StateLocker); !ok {
+		return nil, fmt.Errorf("the %T backend can't lock the states of another backend", locker)
+	}
+
+	return &lockedBackend{wrapper: wrapper{b}, locker: locker}, nil
+}
+
+type lockedBackend struct {
+	wrapper
+
+	locker Backend
+}
+
+func (b *lockedBackend) State(name string) (state.State, error) {
+	s, err := b.Backend.State(name)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := b.locker.(StateLocker).StateLocker(name)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the lock: %s", err)
+	}
+
+	return &state.ExternalLock{Inner: s, Locker: l}, nil
+}
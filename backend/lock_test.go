@@ -0,0 +1,146 @@
+package backend
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestSplitLockConfig(t *testing.T) {
+	raw := map[string]interface{}{
+		"path": "foo",
+		"state_lock": []interface{}{
+			map[string]interface{}{
+				"type":    "consul",
+				"address": "localhost:8500",
+			},
+		},
+	}
+
+	rest, lock, err := SplitLockConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &LockConfig{
+		Type:   "consul",
+		Config: map[string]interface{}{"address": "localhost:8500"},
+	}
+	if !reflect.DeepEqual(lock, expected) {
+		t.Fatalf("bad: %#v", lock)
+	}
+	if !reflect.DeepEqual(rest, map[string]interface{}{"path": "foo"}) {
+		t.Fatalf("bad: %#v", rest)
+	}
+
+	// The type is required
+	raw["state_lock"] = []interface{}{map[string]interface{}{"address": "foo"}}
+	if _, _, err := SplitLockConfig(raw); err == nil {
+		t.Fatal("should error")
+	}
+
+	// The lock argument of backends such as consul is left alone
+	raw = map[string]interface{}{"path": "foo", "lock": false}
+	rest, lock, err = SplitLockConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if lock != nil {
+		t.Fatalf("bad: %#v", lock)
+	}
+	if !reflect.DeepEqual(rest, raw) {
+		t.Fatalf("bad: %#v", rest)
+	}
+}
+
+// lockerNil is a backend that only locks.
+type lockerNil struct {
+	Nil
+
+	locked map[string]bool
+}
+
+func (b *lockerNil) State(name string) (state.State, error) {
+	return nil, errors.New("the state of the locker shouldn't be loaded")
+}
+
+func (b *lockerNil) StateLocker(name string) (state.Locker, error) {
+	return &testLocker{backend: b, name: name}, nil
+}
+
+type testLocker struct {
+	backend *lockerNil
+	name    string
+}
+
+func (s *testLocker) Lock(*state.LockInfo) (string, error) {
+	if s.backend.locked[s.name] {
+		return "", errors.New("state locked")
+	}
+
+	s.backend.locked[s.name] = true
+	return s.name, nil
+}
+
+func (s *testLocker) Unlock(string) error {
+	delete(s.backend.locked, s.name)
+	return nil
+}
+
+func TestExternallyLocked_notLocker(t *testing.T) {
+	if _, err := ExternallyLocked(&remoteNil{client: new(memClient)}, new(Nil)); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestExternallyLocked(t *testing.T) {
+	client := new(memClient)
+	locker := &lockerNil{locked: make(map[string]bool)}
+	b, err := ExternallyLocked(&remoteNil{client: client}, locker)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	s, err := b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	id, err := s.Lock(state.NewLockInfo())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !locker.locked[DefaultStateName] {
+		t.Fatal("should be locked by the locker")
+	}
+
+	// Another state of the same name can't be locked
+	other, err := b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := other.Lock(state.NewLockInfo()); err == nil {
+		t.Fatal("should error")
+	}
+
+	// The state is still stored by the backend
+	if err := s.WriteState(terraform.NewState()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if client.data == nil {
+		t.Fatal("state should be stored")
+	}
+
+	if err := s.Unlock(id); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if locker.locked[DefaultStateName] {
+		t.Fatal("should be unlocked")
+	}
+}
@@ -0,0 +1,362 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/terraform/helper/logging"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// NotifyConfigKey is the key of the blocks in the configuration of a backend
+// that configure HTTP notifications of changes to the states it stores.
+// Like the encryption block, they're handled outside of the backend, and
+// backends must not use this key themselves.
+const NotifyConfigKey = "notify"
+
+// NotifySecretEnvVar is the environment variable that sets the secret of
+// the notify blocks that don't set one, so that it doesn't have to be
+// saved with the backend configuration.
+const NotifySecretEnvVar = "TF_BACKEND_NOTIFY_SECRET"
+
+// NotifySignatureHeader is the header of notifications that holds the
+// signature of the body, as "sha256=" followed by the hex encoded
+// HMAC-SHA256 of the body with the secret as the key.
+const NotifySignatureHeader = "X-Terraform-Signature"
+
+// The events that notifications are sent for.
+const (
+	NotifyEventWrite  = "write"
+	NotifyEventLock   = "lock"
+	NotifyEventUnlock = "unlock"
+)
+
+// notifyTimeout is how long sending a notification can take.
+const notifyTimeout = 10 * time.Second
+
+// notifyLog logs the notifications that fail to be sent.
+var notifyLog = logging.New("backend")
+
+// NotifyConfig is the configuration of a notification hook for a backend.
+type NotifyConfig struct {
+	// URL is where notifications are sent, with a POST request.
+	URL string
+
+	// Secret, if set, is the key of the signature of each notification.
+	Secret string
+
+	// Events are the events that notifications are sent for. All events
+	// are notified if it's empty.
+	Events []string
+}
+
+// SplitNotifyConfig removes the notify blocks from the raw configuration of
+// a backend. It returns the rest of the configuration, along with the
+// configuration of each notify block.
+func SplitNotifyConfig(raw map[string]interface{}) (map[string]interface{}, []*NotifyConfig, error) {
+	rest, blocks, err := splitBlocks(raw, NotifyConfigKey)
+	if err != nil || blocks == nil {
+		return rest, nil, err
+	}
+
+	result := make([]*NotifyConfig, 0, len(blocks))
+	for _, block := range blocks {
+		c := new(NotifyConfig)
+		for k, v := range block {
+			switch k {
+			case "url":
+				c.URL, _ = v.(string)
+			case "secret":
+				c.Secret, _ = v.(string)
+			case "events":
+				events, ok := notifyEvents(v)
+				if !ok {
+					return nil, nil, fmt.Errorf(
+						"%s: events must be a list of %q, %q or %q", NotifyConfigKey,
+						NotifyEventWrite, NotifyEventLock, NotifyEventUnlock)
+				}
+				c.Events = events
+			default:
+				return nil, nil, fmt.Errorf("%s: unknown key %q", NotifyConfigKey, k)
+			}
+		}
+
+		if c.URL == "" {
+			return nil, nil, fmt.Errorf("%s: url is required", NotifyConfigKey)
+		}
+		if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, nil, fmt.Errorf("%s: invalid url %q", NotifyConfigKey, c.URL)
+		}
+
+		result = append(result, c)
+	}
+
+	return rest, result, nil
+}
+
+// notifyEvents returns the events in the raw value of the events key, and
+// whether they are all valid.
+func notifyEvents(v interface{}) ([]string, bool) {
+	var raw []interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		raw = v
+	case []string:
+		for _, e := range v {
+			raw = append(raw, e)
+		}
+	default:
+		return nil, false
+	}
+
+	events := make([]string, 0, len(raw))
+	for _, e := range raw {
+		s, _ := e.(string)
+		switch s {
+		case NotifyEventWrite, NotifyEventLock, NotifyEventUnlock:
+			events = append(events, s)
+		default:
+			return nil, false
+		}
+	}
+
+	return events, true
+}
+
+// Notifying returns a Backend that stores states with b, and sends a
+// notification as configured by each of cs when a state is written, locked
+// or unlocked. This lets other systems keep track of the states without
+// polling the backend.
+//
+// Notifications are sent after the event, and failing to send one only
+// logs a warning, so that a notification hook that's down doesn't stop
+// Terraform from running.
+func Notifying(b Backend, cs []*NotifyConfig) (Backend, error) {
+	// Enhanced backends run operations themselves, so we don't see the
+	// states change.
+	if _, ok := b.(Enhanced); ok {
+		return nil, fmt.Errorf("the %T backend doesn't support notifications", b)
+	}
+
+	hooks := make([]*notifyHook, len(cs))
+	for i, c := range cs {
+		secret := c.Secret
+		if secret == "" {
+			secret = os.Getenv(NotifySecretEnvVar)
+		}
+
+		hooks[i] = &notifyHook{
+			url:    c.URL,
+			secret: secret,
+			events: make(map[string]bool),
+			client: &http.Client{
+				Transport: cleanhttp.DefaultPooledTransport(),
+				Timeout:   notifyTimeout,
+			},
+		}
+		for _, e := range c.Events {
+			hooks[i].events[e] = true
+		}
+	}
+
+	return &notifyingBackend{wrapper: wrapper{b}, hooks: hooks}, nil
+}
+
+type notifyingBackend struct {
+	wrapper
+
+	hooks []*notifyHook
+}
+
+func (b *notifyingBackend) State(name string) (state.State, error) {
+	s, err := b.Backend.State(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &notifyingState{Inner: s, name: name, hooks: b.hooks}, nil
+}
+
+// notifyingState is a State that sends notifications when it's persisted,
+// locked or unlocked.
+type notifyingState struct {
+	Inner state.State
+
+	name  string
+	hooks []*notifyHook
+}
+
+func (s *notifyingState) Unwrap() state.State {
+	return s.Inner
+}
+
+func (s *notifyingState) State() *terraform.State {
+	return s.Inner.State()
+}
+
+func (s *notifyingState) WriteState(v *terraform.State) error {
+	return s.Inner.WriteState(v)
+}
+
+func (s *notifyingState) RefreshState() error {
+	return s.Inner.RefreshState()
+}
+
+func (s *notifyingState) PersistState() error {
+	if err := s.Inner.PersistState(); err != nil {
+		return err
+	}
+
+	n := &Notification{Event: NotifyEventWrite}
+	if current := s.Inner.State(); current != nil {
+		n.Serial = current.Serial
+		n.Lineage = current.Lineage
+	}
+	s.notify(n)
+
+	return nil
+}
+
+func (s *notifyingState) ModTime() time.Time {
+	if mt, ok := s.Inner.(state.StateModTimer); ok {
+		return mt.ModTime()
+	}
+
+	return time.Time{}
+}
+
+func (s *notifyingState) notify(n *Notification) {
+	n.State = s.name
+	n.Time = time.Now().UTC()
+	for _, h := range s.hooks {
+		h.Send(n)
+	}
+}
+
+func (s *notifyingState) Lock(info *state.LockInfo) (string, error) {
+	id, err := s.Inner.Lock(info)
+	if err != nil {
+		return id, err
+	}
+
+	// States that don't support locking lock without an ID, and there's
+	// nothing to notify then.
+	if id == "" {
+		return id, nil
+	}
+
+	n := &Notification{Event: NotifyEventLock, LockID: id}
+	if info != nil {
+		n.Operation = info.Operation
+		n.Who = info.Who
+	}
+	s.notify(n)
+
+	return id, nil
+}
+
+func (s *notifyingState) Unlock(id string) error {
+	if err := s.Inner.Unlock(id); err != nil {
+		return err
+	}
+
+	if id != "" {
+		s.notify(&Notification{Event: NotifyEventUnlock, LockID: id})
+	}
+
+	return nil
+}
+
+// SubtreeLocks implements state.SubtreeLocker, if the inner state does.
+func (s *notifyingState) SubtreeLocks() bool {
+	l, ok := s.Inner.(state.SubtreeLocker)
+	return ok && l.SubtreeLocks()
+}
+
+// Notification is the JSON body of a notification.
+type Notification struct {
+	// Event is the event that happened: NotifyEventWrite, NotifyEventLock
+	// or NotifyEventUnlock.
+	Event string `json:"event"`
+
+	// State is the name of the state, which is the environment.
+	State string `json:"state"`
+
+	Time time.Time `json:"time"`
+
+	// Serial and Lineage identify the state that was written.
+	Serial  int64  `json:"serial,omitempty"`
+	Lineage string `json:"lineage,omitempty"`
+
+	// LockID is the ID of the lock that was taken or released, and
+	// Operation and Who describe the lock that was taken.
+	LockID    string `json:"lock_id,omitempty"`
+	Operation string `json:"operation,omitempty"`
+	Who       string `json:"who,omitempty"`
+}
+
+// notifyHook sends notifications to one URL.
+type notifyHook struct {
+	url    string
+	secret string
+	events map[string]bool
+	client *http.Client
+}
+
+// Send sends n if the hook is notified of its event. Failures are logged.
+func (h *notifyHook) Send(n *Notification) {
+	if len(h.events) > 0 && !h.events[n.Event] {
+		return
+	}
+
+	if err := h.send(n); err != nil {
+		notifyLog.Warn("error sending %s notification to %s: %s",
+			n.Event, h.url, err)
+	}
+}
+
+func (h *notifyHook) send(n *Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.secret != "" {
+		req.Header.Set(NotifySignatureHeader, NotifySignature(h.secret, body))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// NotifySignature returns the value of NotifySignatureHeader for a
+// notification with the given body, signed with secret.
+func NotifySignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,230 @@
+package backend
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestSplitNotifyConfig(t *testing.T) {
+	raw := map[string]interface{}{
+		"path": "foo",
+		"notify": []interface{}{
+			map[string]interface{}{
+				"url":    "https://example.com/a",
+				"secret": "s3cr3t",
+			},
+			map[string]interface{}{
+				"url":    "http://example.com/b",
+				"events": []interface{}{"write"},
+			},
+		},
+	}
+
+	rest, cs, err := SplitNotifyConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []*NotifyConfig{
+		{URL: "https://example.com/a", Secret: "s3cr3t"},
+		{URL: "http://example.com/b", Events: []string{"write"}},
+	}
+	if !reflect.DeepEqual(cs, expected) {
+		t.Fatalf("bad: %#v", cs)
+	}
+	if !reflect.DeepEqual(rest, map[string]interface{}{"path": "foo"}) {
+		t.Fatalf("bad: %#v", rest)
+	}
+
+	cases := map[string]map[string]interface{}{
+		"no url":        {"secret": "foo"},
+		"bad url":       {"url": "ftp://example.com"},
+		"bad event":     {"url": "https://example.com", "events": []interface{}{"delete"}},
+		"unknown key":   {"url": "https://example.com", "foo": "bar"},
+		"events string": {"url": "https://example.com", "events": "write"},
+	}
+	for name, block := range cases {
+		raw["notify"] = []interface{}{block}
+		if _, _, err := SplitNotifyConfig(raw); err == nil {
+			t.Fatalf("%s: should error", name)
+		}
+	}
+}
+
+// notifyServer records the notifications it receives, and checks their
+// signatures.
+type notifyServer struct {
+	*httptest.Server
+
+	t      *testing.T
+	secret string
+
+	lock          sync.Mutex
+	notifications []*Notification
+}
+
+func newNotifyServer(t *testing.T, secret string) *notifyServer {
+	s := &notifyServer{t: t, secret: secret}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *notifyServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.t.Errorf("err: %s", err)
+		return
+	}
+
+	if sig := r.Header.Get(NotifySignatureHeader); sig != NotifySignature(s.secret, body) {
+		s.t.Errorf("bad signature: %q", sig)
+	}
+
+	var n Notification
+	if err := json.Unmarshal(body, &n); err != nil {
+		s.t.Errorf("err: %s", err)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.notifications = append(s.notifications, &n)
+}
+
+func (s *notifyServer) Events() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var events []string
+	for _, n := range s.notifications {
+		events = append(events, n.Event)
+	}
+	return events
+}
+
+func TestNotifying(t *testing.T) {
+	all := newNotifyServer(t, "s3cr3t")
+	defer all.Close()
+	writes := newNotifyServer(t, "other")
+	defer writes.Close()
+
+	client := new(memClient)
+	locker := &lockerNil{locked: make(map[string]bool)}
+	locked, err := ExternallyLocked(&remoteNil{client: client}, locker)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	b, err := Notifying(locked, []*NotifyConfig{
+		{URL: all.URL, Secret: "s3cr3t"},
+		{URL: writes.URL, Secret: "other", Events: []string{NotifyEventWrite}},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	s, err := b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	info := state.NewLockInfo()
+	info.Operation = "apply"
+	id, err := s.Lock(info)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !locker.locked[DefaultStateName] {
+		t.Fatal("should be locked")
+	}
+
+	st := terraform.NewState()
+	st.Serial = 3
+	if err := s.WriteState(st); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if client.data == nil {
+		t.Fatal("state should be stored")
+	}
+
+	if err := s.Unlock(id); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{NotifyEventLock, NotifyEventWrite, NotifyEventUnlock}
+	if events := all.Events(); !reflect.DeepEqual(events, expected) {
+		t.Fatalf("bad: %#v", events)
+	}
+	if events := writes.Events(); !reflect.DeepEqual(events, []string{NotifyEventWrite}) {
+		t.Fatalf("bad: %#v", events)
+	}
+
+	lock := all.notifications[0]
+	if lock.State != DefaultStateName || lock.LockID != id || lock.Operation != "apply" {
+		t.Fatalf("bad: %#v", lock)
+	}
+	write := all.notifications[1]
+	if write.Serial != st.Serial || write.Lineage != st.Lineage || write.Lineage == "" {
+		t.Fatalf("bad: %#v", write)
+	}
+}
+
+func TestNotifying_failure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	b, err := Notifying(&remoteNil{client: new(memClient)}, []*NotifyConfig{{URL: server.URL}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	s, err := b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Failing to notify doesn't fail the write
+	if err := s.WriteState(terraform.NewState()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestNotifying_subtreeLocks(t *testing.T) {
+	inner := new(subtreeLockerState)
+	s := &notifyingState{Inner: inner}
+	if s.SubtreeLocks() {
+		t.Fatal("subtree locks shouldn't be supported")
+	}
+
+	inner.subtrees = true
+	if !s.SubtreeLocks() {
+		t.Fatal("subtree locks should be supported")
+	}
+}
+
+// subtreeLockerState is a state.SubtreeLocker that supports subtree locks
+// if subtrees is true.
+type subtreeLockerState struct {
+	state.InmemState
+	subtrees bool
+}
+
+func (s *subtreeLockerState) SubtreeLocks() bool {
+	return s.subtrees
+}
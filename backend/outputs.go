@@ -0,0 +1,84 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// Error value to return when publishing outputs isn't supported by a
+// backend, for example when a backend that implements OutputPublisher
+// delegates to one that doesn't.
+var ErrOutputPublishingNotSupported = errors.New("publishing outputs not supported")
+
+// OutputPublisher is an optional interface a Backend can implement to
+// publish selected outputs of a state to a separate, well-known location.
+//
+// Published outputs are stored apart from the state they come from so that
+// consumers such as terraform_remote_state can read them with permissions
+// that don't grant access to the full state, which may contain secrets.
+type OutputPublisher interface {
+	// PublishOutputs replaces the published outputs for the named state
+	// with the given outputs.
+	PublishOutputs(name string, outputs map[string]*terraform.OutputState) error
+
+	// PublishedOutputs returns the published outputs for the named state.
+	// If no outputs have been published, it returns an empty map.
+	PublishedOutputs(name string) (map[string]*terraform.OutputState, error)
+}
+
+// EncodeOutputs encodes outputs in the format they are published in.
+func EncodeOutputs(outputs map[string]*terraform.OutputState) ([]byte, error) {
+	if outputs == nil {
+		outputs = make(map[string]*terraform.OutputState)
+	}
+
+	data, err := json.MarshalIndent(outputs, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode outputs: %s", err)
+	}
+
+	return data, nil
+}
+
+// DecodeOutputs decodes outputs that were encoded with EncodeOutputs.
+func DecodeOutputs(data []byte) (map[string]*terraform.OutputState, error) {
+	outputs := make(map[string]*terraform.OutputState)
+	if len(data) == 0 {
+		return outputs, nil
+	}
+
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("Failed to decode published outputs: %s", err)
+	}
+
+	return outputs, nil
+}
+
+// PublishOutputsToClient publishes outputs using a remote state client
+// that points at the location reserved for the published outputs.
+func PublishOutputsToClient(c remote.Client, outputs map[string]*terraform.OutputState) error {
+	data, err := EncodeOutputs(outputs)
+	if err != nil {
+		return err
+	}
+
+	return c.Put(data)
+}
+
+// PublishedOutputsFromClient reads outputs published with
+// PublishOutputsToClient.
+func PublishedOutputsFromClient(c remote.Client) (map[string]*terraform.OutputState, error) {
+	payload, err := c.Get()
+	if err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		return DecodeOutputs(nil)
+	}
+
+	return DecodeOutputs(payload.Data)
+}
@@ -0,0 +1,66 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ReadOnly returns a Backend that fails to write, create or delete the
+// states of b with state.ErrReadOnly, for read-only mode. Since this is
+// enforced where the states are written, every command and state
+// migration honors it.
+//
+// Enhanced backends write their states themselves, so they must enforce
+// read-only mode on their own.
+func ReadOnly(b Backend) (Backend, error) {
+	if _, ok := b.(Enhanced); ok {
+		return nil, fmt.Errorf("the %T backend can't be made read-only", b)
+	}
+
+	return &readOnlyBackend{wrapper: wrapper{b}}, nil
+}
+
+type readOnlyBackend struct {
+	wrapper
+}
+
+func (b *readOnlyBackend) State(name string) (state.State, error) {
+	// Backends create the states that don't exist yet, which is a write.
+	names, err := b.Backend.States()
+	switch err {
+	case nil:
+		if !containsString(names, name) {
+			return nil, fmt.Errorf("state %q doesn't exist: %s", name, state.ErrReadOnly)
+		}
+	case ErrNamedStatesNotSupported:
+	default:
+		return nil, err
+	}
+
+	s, err := b.Backend.State(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return state.NewReadOnly(s), nil
+}
+
+func (b *readOnlyBackend) DeleteState(name string) error {
+	return state.ErrReadOnly
+}
+
+func (b *readOnlyBackend) PublishOutputs(name string, outputs map[string]*terraform.OutputState) error {
+	return state.ErrReadOnly
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
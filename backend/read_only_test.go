@@ -0,0 +1,56 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestReadOnly(t *testing.T) {
+	client := &memClient{data: []byte(`{"version": 3, "lineage": "foo"}`)}
+	b, err := ReadOnly(&remoteNil{client: client})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	s, err := b.State(DefaultStateName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if s.State().Lineage != "foo" {
+		t.Fatalf("bad: %#v", s.State())
+	}
+
+	if err := s.WriteState(terraform.NewState()); err != state.ErrReadOnly {
+		t.Fatalf("bad: %s", err)
+	}
+	if err := s.PersistState(); err != state.ErrReadOnly {
+		t.Fatalf("bad: %s", err)
+	}
+	if string(client.data) != `{"version": 3, "lineage": "foo"}` {
+		t.Fatalf("state shouldn't be written: %s", client.data)
+	}
+
+	if err := b.DeleteState("foo"); err != state.ErrReadOnly {
+		t.Fatalf("bad: %s", err)
+	}
+	if err := b.(OutputPublisher).PublishOutputs(DefaultStateName, nil); err != state.ErrReadOnly {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestReadOnly_newState(t *testing.T) {
+	b, err := ReadOnly(&remoteNil{client: new(memClient)})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The state would be created
+	if _, err := b.State("foo"); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -5,6 +5,7 @@ package remotestate
 
 import (
 	"context"
+	"errors"
 
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/helper/schema"
@@ -55,6 +56,21 @@ func (b *Backend) DeleteState(name string) error {
 	return backend.ErrNamedStatesNotSupported
 }
 
+// StateLocker implements backend.StateLocker, locking the state with the
+// client if it supports locking.
+func (b *Backend) StateLocker(name string) (state.Locker, error) {
+	if name != backend.DefaultStateName {
+		return nil, backend.ErrNamedStatesNotSupported
+	}
+
+	l, ok := b.client.(remote.ClientLocker)
+	if !ok {
+		return nil, errors.New("the backend doesn't support locking")
+	}
+
+	return l, nil
+}
+
 func (b *Backend) State(name string) (state.State, error) {
 	// This shouldn't happen
 	if b.client == nil {
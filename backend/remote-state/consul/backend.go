@@ -21,6 +21,7 @@ func New() backend.Backend {
 
 			"access_token": &schema.Schema{
 				Type:        schema.TypeString,
+				Sensitive:   true,
 				Optional:    true,
 				Description: "Access token for a Consul ACL",
 				Default:     "", // To prevent input
@@ -49,6 +50,7 @@ func New() backend.Backend {
 
 			"http_auth": &schema.Schema{
 				Type:        schema.TypeString,
+				Sensitive:   true,
 				Optional:    true,
 				Description: "HTTP Auth in the format of 'username:password'",
 				Default:     "", // To prevent input
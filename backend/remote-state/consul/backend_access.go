@@ -0,0 +1,29 @@
+package consul
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/backend"
+)
+
+func (b *Backend) AccessHint(name, step string) string {
+	path := b.path(name)
+
+	switch step {
+	case backend.AccessRead:
+		// Loading a state that doesn't exist yet writes an empty one, so
+		// reading needs write access too.
+		return fmt.Sprintf(
+			"The ACL token needs key read access to %q, and key write "+
+				"access to it if the state doesn't exist yet.", path)
+	case backend.AccessLock:
+		return fmt.Sprintf(
+			"The ACL token needs key write access to %q and %q, and session "+
+				"write access to create the session that holds the lock.",
+			path+lockSuffix, path+lockInfoSuffix)
+	case backend.AccessWrite:
+		return fmt.Sprintf("The ACL token needs key write access to %q.", path)
+	}
+
+	return ""
+}
@@ -0,0 +1,43 @@
+package consul
+
+import (
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// outputsSuffix is appended to the path of a state to get the path where
+// its published outputs are stored.
+const outputsSuffix = "/outputs"
+
+// PublishOutputs implements backend.OutputPublisher.
+func (b *Backend) PublishOutputs(name string, outputs map[string]*terraform.OutputState) error {
+	client, err := b.outputsClient(name)
+	if err != nil {
+		return err
+	}
+
+	return backend.PublishOutputsToClient(client, outputs)
+}
+
+// PublishedOutputs implements backend.OutputPublisher.
+func (b *Backend) PublishedOutputs(name string) (map[string]*terraform.OutputState, error) {
+	client, err := b.outputsClient(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return backend.PublishedOutputsFromClient(client)
+}
+
+func (b *Backend) outputsClient(name string) (*RemoteClient, error) {
+	client, err := b.clientRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoteClient{
+		Client: client,
+		Path:   b.path(name) + outputsSuffix,
+		GZip:   b.configData.Get("gzip").(bool),
+	}, nil
+}
@@ -71,7 +71,12 @@ func (b *Backend) DeleteState(name string) error {
 
 	// Delete it. We just delete it without any locking since
 	// the DeleteState API is documented as such.
-	_, err = client.KV().Delete(path, nil)
+	if _, err := client.KV().Delete(path, nil); err != nil {
+		return err
+	}
+
+	// Delete any published outputs along with it
+	_, err = client.KV().Delete(path+outputsSuffix, nil)
 	return err
 }
 
@@ -148,6 +153,25 @@ func (b *Backend) State(name string) (state.State, error) {
 	return stateMgr, nil
 }
 
+// StateLocker implements backend.StateLocker, locking the state with the
+// given name without creating it.
+func (b *Backend) StateLocker(name string) (state.Locker, error) {
+	if !b.lock {
+		return nil, backend.ErrLockingNotConfigured
+	}
+
+	client, err := b.clientRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoteClient{
+		Client:    client,
+		Path:      b.path(name),
+		lockState: b.lock,
+	}, nil
+}
+
 func (b *Backend) path(name string) string {
 	path := b.configData.Get("path").(string)
 	if name != backend.DefaultStateName {
@@ -13,6 +13,7 @@ import (
 
 func TestBackend_impl(t *testing.T) {
 	var _ backend.Backend = new(Backend)
+	var _ backend.AccessHinter = new(Backend)
 }
 
 func newConsulTestServer(t *testing.T) *testutil.TestServer {
@@ -92,3 +93,9 @@ func TestBackend_gzip(t *testing.T) {
 	// Test
 	backend.TestBackend(t, b, nil)
 }
+
+func TestBackendStateLocker_notConfigured(t *testing.T) {
+	if _, err := new(Backend).StateLocker(backend.DefaultStateName); err != backend.ErrLockingNotConfigured {
+		t.Fatalf("bad: %v", err)
+	}
+}
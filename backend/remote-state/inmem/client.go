@@ -3,6 +3,8 @@ package inmem
 import (
 	"crypto/md5"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/hashicorp/terraform/state"
@@ -15,6 +17,12 @@ type RemoteClient struct {
 	MD5  []byte
 
 	LockInfo *state.LockInfo
+
+	// History is the previous versions of Data, oldest first.
+	History []*remote.Payload
+
+	// Blobs are the blobs stored with PutBlob, by ID.
+	Blobs map[string][]byte
 }
 
 func (c *RemoteClient) Get() (*remote.Payload, error) {
@@ -29,6 +37,14 @@ func (c *RemoteClient) Get() (*remote.Payload, error) {
 }
 
 func (c *RemoteClient) Put(data []byte) error {
+	if c.Data != nil {
+		c.History = append(c.History, &remote.Payload{
+			Data:    c.Data,
+			MD5:     c.MD5,
+			ModTime: time.Now().UTC(),
+		})
+	}
+
 	md5 := md5.Sum(data)
 
 	c.Data = data
@@ -36,12 +52,53 @@ func (c *RemoteClient) Put(data []byte) error {
 	return nil
 }
 
+func (c *RemoteClient) Versions() ([]*remote.Version, error) {
+	versions := make([]*remote.Version, len(c.History))
+	for i, p := range c.History {
+		versions[i] = &remote.Version{ID: strconv.Itoa(i + 1), ModTime: p.ModTime}
+	}
+
+	return versions, nil
+}
+
+func (c *RemoteClient) GetVersion(id string) (*remote.Payload, error) {
+	i, err := strconv.Atoi(id)
+	if err != nil || i < 1 || i > len(c.History) {
+		return nil, fmt.Errorf("unknown version %q", id)
+	}
+
+	return c.History[i-1], nil
+}
+
 func (c *RemoteClient) Delete() error {
 	c.Data = nil
 	c.MD5 = nil
 	return nil
 }
 
+func (c *RemoteClient) PutBlob(id string, data []byte) error {
+	if c.Blobs == nil {
+		c.Blobs = make(map[string][]byte)
+	}
+
+	c.Blobs[id] = data
+	return nil
+}
+
+func (c *RemoteClient) GetBlob(id string) ([]byte, error) {
+	data, ok := c.Blobs[id]
+	if !ok {
+		return nil, remote.ErrBlobNotFound
+	}
+
+	return data, nil
+}
+
+func (c *RemoteClient) DeleteBlob(id string) error {
+	delete(c.Blobs, id)
+	return nil
+}
+
 func (c *RemoteClient) Lock(info *state.LockInfo) (string, error) {
 	lockErr := &state.LockError{
 		Info: &state.LockInfo{},
@@ -11,6 +11,8 @@ import (
 func TestRemoteClient_impl(t *testing.T) {
 	var _ remote.Client = new(RemoteClient)
 	var _ remote.ClientLocker = new(RemoteClient)
+	var _ remote.ClientVersioner = new(RemoteClient)
+	var _ remote.ClientBlobStorer = new(RemoteClient)
 }
 
 func TestRemoteClient(t *testing.T) {
@@ -18,6 +20,10 @@ func TestRemoteClient(t *testing.T) {
 	remotestate.TestClient(t, b)
 }
 
+func TestRemoteClient_blobs(t *testing.T) {
+	remote.TestBlobStorer(t, new(RemoteClient))
+}
+
 func TestInmemLocks(t *testing.T) {
 	s, err := backend.TestBackendConfig(t, New(), nil).State(backend.DefaultStateName)
 	if err != nil {
@@ -0,0 +1,186 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (b *Backend) States() ([]string, error) {
+	selector := fmt.Sprintf("%s=%s,%s=%s,%s=%s",
+		managedByLabel, "terraform",
+		suffixLabel, b.nameSuffix,
+		roleLabel, roleState)
+
+	secrets, err := b.kubeClient.Core().Secrets(b.namespace).List(
+		metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	envs := []string{backend.DefaultStateName}
+	for _, secret := range secrets.Items {
+		env := secret.Labels[workspaceLabel]
+		if env != "" && env != backend.DefaultStateName {
+			envs = append(envs, env)
+		}
+	}
+
+	sort.Strings(envs[1:])
+	return envs, nil
+}
+
+func (b *Backend) DeleteState(name string) error {
+	if name == backend.DefaultStateName || name == "" {
+		return fmt.Errorf("can't delete default state")
+	}
+
+	client, err := b.remoteClient(name)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Delete(); err != nil {
+		return err
+	}
+
+	err = client.leases.Delete(client.leaseName)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (b *Backend) State(name string) (state.State, error) {
+	if name == "" {
+		return nil, errors.New("missing state name")
+	}
+
+	client, err := b.remoteClient(name)
+	if err != nil {
+		return nil, err
+	}
+
+	stateMgr := &remote.State{Client: client}
+
+	// Check to see if this state already exists.
+	// If we're trying to force-unlock a state, we can't take the lock before
+	// fetching the state. If the state doesn't exist, we have to assume this
+	// is a normal create operation, and take the lock at that point.
+	existing, err := b.States()
+	if err != nil {
+		return nil, err
+	}
+
+	exists := false
+	for _, s := range existing {
+		if s == name {
+			exists = true
+			break
+		}
+	}
+
+	// We need to create the secret so it's listed by States.
+	if !exists {
+		// take a lock on this state while we write it
+		lockInfo := state.NewLockInfo()
+		lockInfo.Operation = "init"
+		lockId, err := client.Lock(lockInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock Kubernetes state: %s", err)
+		}
+
+		// Local helper function so we can call it multiple places
+		lockUnlock := func(parent error) error {
+			if err := stateMgr.Unlock(lockId); err != nil {
+				return fmt.Errorf(strings.TrimSpace(errStateUnlock), lockId, err)
+			}
+			return parent
+		}
+
+		// Grab the value
+		// This is to ensure that no one beat us to writing a state between
+		// the `exists` check and taking the lock.
+		if err := stateMgr.RefreshState(); err != nil {
+			err = lockUnlock(err)
+			return nil, err
+		}
+
+		// If we have no state, we have to create an empty state
+		if v := stateMgr.State(); v == nil {
+			if err := stateMgr.WriteState(terraform.NewState()); err != nil {
+				err = lockUnlock(err)
+				return nil, err
+			}
+			if err := stateMgr.PersistState(); err != nil {
+				err = lockUnlock(err)
+				return nil, err
+			}
+		}
+
+		// Unlock, the state should now be initialized
+		if err := lockUnlock(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return stateMgr, nil
+}
+
+// StateLocker implements backend.StateLocker, locking the state with the
+// given name without creating it.
+func (b *Backend) StateLocker(name string) (state.Locker, error) {
+	return b.remoteClient(name)
+}
+
+func (b *Backend) remoteClient(name string) (*RemoteClient, error) {
+	core := b.kubeClient.Core()
+
+	return &RemoteClient{
+		secrets: core.Secrets(b.namespace),
+		leases: &leaseClient{
+			rest:      core.RESTClient(),
+			namespace: b.namespace,
+		},
+		namespace: b.namespace,
+		workspace: name,
+		suffix:    b.nameSuffix,
+		name:      b.secretName(name),
+		leaseName: "lock-" + b.secretName(name),
+		labels:    b.labels,
+	}, nil
+}
+
+// secretName returns the name of the secret that the state of the given
+// environment is stored in.
+func (b *Backend) secretName(name string) string {
+	return strings.Join([]string{"tfstate", name, b.nameSuffix}, "-")
+}
+
+// isReservedLabel returns true if the label k is set by the backend, and so
+// can't be configured.
+func isReservedLabel(k string) bool {
+	switch k {
+	case managedByLabel, workspaceLabel, suffixLabel, roleLabel:
+		return true
+	}
+
+	return false
+}
+
+const errStateUnlock = `
+Error unlocking Kubernetes state. Lock ID: %s
+
+Error: %s
+
+You may have to force-unlock this state in order to use it again.
+`
@@ -0,0 +1,127 @@
+package kubernetes
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// verify that we are doing ACC tests or the Kubernetes tests specifically
+func testACC(t *testing.T) {
+	skip := os.Getenv("TF_ACC") == "" && os.Getenv("TF_K8S_TEST") == ""
+	if skip {
+		t.Log("kubernetes backend tests require setting TF_ACC or TF_K8S_TEST")
+		t.Skip()
+	}
+}
+
+func TestBackend_impl(t *testing.T) {
+	var _ backend.Backend = new(Backend)
+}
+
+func TestBackendConfig(t *testing.T) {
+	config := map[string]interface{}{
+		"secret_suffix":    "test",
+		"namespace":        "tf",
+		"load_config_file": false,
+		"host":             "https://127.0.0.1:6443",
+		"labels": map[string]interface{}{
+			"team": "infra",
+		},
+	}
+
+	b := backend.TestBackendConfig(t, New(), config).(*Backend)
+
+	if b.kubeClient == nil {
+		t.Fatalf("Kubernetes client was not created")
+	}
+	if b.namespace != "tf" {
+		t.Fatalf("Incorrect namespace was populated")
+	}
+	if b.nameSuffix != "test" {
+		t.Fatalf("Incorrect nameSuffix was populated")
+	}
+	if b.labels["team"] != "infra" {
+		t.Fatalf("Incorrect labels were populated: %#v", b.labels)
+	}
+
+	if name := b.secretName("foo"); name != "tfstate-foo-test" {
+		t.Fatalf("bad: %s", name)
+	}
+}
+
+func TestBackendConfig_reservedLabel(t *testing.T) {
+	rawConfig, err := config.NewRawConfig(map[string]interface{}{
+		"secret_suffix":    "test",
+		"load_config_file": false,
+		"labels": map[string]interface{}{
+			workspaceLabel: "foo",
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = New().Configure(terraform.NewResourceConfig(rawConfig))
+	if err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	testACC(t)
+
+	suffix := fmt.Sprintf("test-%x", time.Now().Unix())
+	b := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"secret_suffix": suffix,
+	}).(*Backend)
+	defer cleanupK8sResources(t, b)
+
+	backend.TestBackend(t, b, nil)
+}
+
+func TestBackendLocked(t *testing.T) {
+	testACC(t)
+
+	suffix := fmt.Sprintf("test-%x", time.Now().Unix())
+	b1 := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"secret_suffix": suffix,
+	}).(*Backend)
+	b2 := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"secret_suffix": suffix,
+	}).(*Backend)
+	defer cleanupK8sResources(t, b1)
+
+	backend.TestBackend(t, b1, b2)
+}
+
+// cleanupK8sResources deletes the secrets and the leases created by the
+// backend b.
+func cleanupK8sResources(t *testing.T, b *Backend) {
+	warning := "WARNING: Failed to delete the test Kubernetes %s. It may have been left in your cluster. (error was %s)"
+
+	selector := fmt.Sprintf("%s=%s", suffixLabel, b.nameSuffix)
+	secrets := b.kubeClient.Core().Secrets(b.namespace)
+	list, err := secrets.List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		t.Logf(warning, "secrets", err)
+		return
+	}
+
+	for _, secret := range list.Items {
+		env := secret.Labels[workspaceLabel]
+		if err := secrets.Delete(secret.Name, &metav1.DeleteOptions{}); err != nil {
+			t.Logf(warning, "secret "+secret.Name, err)
+		}
+
+		client, _ := b.remoteClient(env)
+		client.leases.Delete(client.leaseName)
+	}
+}
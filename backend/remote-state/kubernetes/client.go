@@ -0,0 +1,453 @@
+package kubernetes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/kubernetes/pkg/api/v1"
+	corev1 "k8s.io/kubernetes/pkg/client/clientset_generated/clientset/typed/core/v1"
+)
+
+// The labels set on the secrets of the backend. The role label tells the
+// secrets that states are read from apart from the chunks of large states.
+const (
+	managedByLabel = "app.terraform.io/managed-by"
+	workspaceLabel = "tfstate_workspace"
+	suffixLabel    = "tfstate_secret_suffix"
+	roleLabel      = "tfstate_role"
+
+	roleState = "state"
+	roleChunk = "chunk"
+)
+
+// The keys of the data of the secrets.
+const (
+	// stateKey holds the gzipped state, or a chunk of it.
+	stateKey = "tfstate"
+
+	// chunksKey lists the names of the chunk secrets of a large state, one
+	// per line, in order.
+	chunksKey = "chunks"
+
+	// md5Key holds the hex MD5 of the gzipped state of a chunked state, to
+	// check that the chunks read belong together.
+	md5Key = "md5"
+)
+
+// modTimeAnnotation records when the state was last written.
+const modTimeAnnotation = "app.terraform.io/last-modified"
+
+// md5Annotation records the hex MD5 of the state when it was written, so
+// that the state read back can be checked against it.
+const md5Annotation = "app.terraform.io/md5"
+
+// maxChunkSize is the most gzipped state data stored in a single secret, a
+// little under the 1MiB that Kubernetes allows a secret.
+const maxChunkSize = 1000 * 1000
+
+type RemoteClient struct {
+	secrets   corev1.SecretInterface
+	leases    *leaseClient
+	namespace string
+	workspace string
+	suffix    string
+	name      string
+	leaseName string
+	labels    map[string]string
+}
+
+func (c *RemoteClient) Get() (*remote.Payload, error) {
+	secret, err := c.secrets.Get(c.name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	gz, err := c.readState(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decompressState(gz)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read remote state: %s", err)
+	}
+
+	// If there was no data, then return nil
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	payload := &remote.Payload{Data: data}
+	if raw := secret.Annotations[md5Annotation]; raw != "" {
+		sum, err := hex.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid checksum of remote state %q: %s", raw, err)
+		}
+		payload.MD5 = sum
+	}
+	if raw := secret.Annotations[modTimeAnnotation]; raw != "" {
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			payload.ModTime = t
+		}
+	}
+
+	return payload, nil
+}
+
+// readState returns the gzipped state stored in secret, putting it back
+// together from its chunks if there are any.
+func (c *RemoteClient) readState(secret *v1.Secret) ([]byte, error) {
+	chunks := secretChunks(secret)
+	if len(chunks) == 0 {
+		return secret.Data[stateKey], nil
+	}
+
+	var buf bytes.Buffer
+	for _, name := range chunks {
+		chunk, err := c.secrets.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read state chunk %s: %s", name, err)
+		}
+		buf.Write(chunk.Data[stateKey])
+	}
+
+	sum := md5.Sum(buf.Bytes())
+	if expected := string(secret.Data[md5Key]); hex.EncodeToString(sum[:]) != expected {
+		return nil, fmt.Errorf(
+			"the chunks of the state in secret %s don't match its checksum; "+
+				"the state may have been modified while it was being read", c.name)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *RemoteClient) Put(data []byte) error {
+	gz, err := compressState(data)
+	if err != nil {
+		return err
+	}
+
+	existing, err := c.secrets.Get(c.name, metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return err
+		}
+		existing = nil
+	}
+
+	sum := md5.Sum(data)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.name,
+			Namespace: c.namespace,
+			Labels:    c.secretLabels(roleState),
+			Annotations: map[string]string{
+				modTimeAnnotation: time.Now().UTC().Format(time.RFC3339),
+				md5Annotation:     hex.EncodeToString(sum[:]),
+			},
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: make(map[string][]byte),
+	}
+
+	// Large states are written to chunks named after their content before
+	// the secret is switched over to them, so that readers always see a
+	// complete state.
+	parts := splitChunks(gz, maxChunkSize)
+	var chunks []string
+	if len(parts) == 1 {
+		secret.Data[stateKey] = gz
+	} else {
+		chunks, err = c.putChunks(gz, parts)
+		if err != nil {
+			return err
+		}
+
+		gzSum := md5.Sum(gz)
+		secret.Data[chunksKey] = []byte(strings.Join(chunks, "\n"))
+		secret.Data[md5Key] = []byte(hex.EncodeToString(gzSum[:]))
+	}
+
+	log.Printf("[DEBUG] Uploading remote state to Kubernetes secret %s/%s (%d chunks)",
+		c.namespace, c.name, len(chunks))
+
+	if existing == nil {
+		_, err = c.secrets.Create(secret)
+	} else {
+		secret.ResourceVersion = existing.ResourceVersion
+		_, err = c.secrets.Update(secret)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to upload state: %s", err)
+	}
+
+	// The chunks of the previous state are no longer used.
+	if existing != nil {
+		if err := c.deleteChunks(secretChunks(existing), chunks); err != nil {
+			log.Printf("[WARN] Failed to delete old state chunks: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// putChunks writes each of parts of the gzipped state gz to its own secret,
+// and returns the names of the secrets.
+func (c *RemoteClient) putChunks(gz []byte, parts [][]byte) ([]string, error) {
+	sum := sha256.Sum256(gz)
+	hash := hex.EncodeToString(sum[:])[:10]
+
+	names := make([]string, len(parts))
+	for i, part := range parts {
+		names[i] = fmt.Sprintf("%s-%s-%d", c.name, hash, i)
+
+		chunk := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      names[i],
+				Namespace: c.namespace,
+				Labels:    c.secretLabels(roleChunk),
+			},
+			Type: v1.SecretTypeOpaque,
+			Data: map[string][]byte{stateKey: part},
+		}
+
+		// A chunk that already exists has the same content, since it's
+		// named after it.
+		if _, err := c.secrets.Create(chunk); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("Failed to upload state chunk %s: %s", names[i], err)
+		}
+	}
+
+	return names, nil
+}
+
+// deleteChunks deletes the chunk secrets named in old that aren't in keep.
+func (c *RemoteClient) deleteChunks(old, keep []string) error {
+	kept := make(map[string]bool)
+	for _, name := range keep {
+		kept[name] = true
+	}
+
+	var result error
+	for _, name := range old {
+		if kept[name] {
+			continue
+		}
+
+		err := c.secrets.Delete(name, &metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	return result
+}
+
+func (c *RemoteClient) Delete() error {
+	secret, err := c.secrets.Get(c.name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if err := c.secrets.Delete(c.name, &metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	return c.deleteChunks(secretChunks(secret), nil)
+}
+
+func (c *RemoteClient) Lock(info *state.LockInfo) (string, error) {
+	info.Path = c.lockPath()
+
+	if info.ID == "" {
+		lockID, err := uuid.GenerateUUID()
+		if err != nil {
+			return "", err
+		}
+
+		info.ID = lockID
+	}
+
+	l, err := c.leases.Get(c.leaseName)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return "", err
+		}
+
+		l = newLease(c.namespace, c.leaseName, c.secretLabels(""))
+		l.hold(info)
+		if err := c.leases.Create(l); err != nil {
+			return "", c.lockError(err)
+		}
+
+		return info.ID, nil
+	}
+
+	if l.holder() != "" {
+		return "", c.lockError(fmt.Errorf("state is already locked"))
+	}
+
+	// The update fails if the lease changed since it was read, so only one
+	// of the clients taking the lock at the same time succeeds.
+	l.hold(info)
+	if err := c.leases.Update(l); err != nil {
+		return "", c.lockError(err)
+	}
+
+	return info.ID, nil
+}
+
+// lockError returns a state.LockError for err with the information of the
+// current lock, if there is one.
+func (c *RemoteClient) lockError(err error) error {
+	lockErr := &state.LockError{Err: err}
+
+	lockInfo, infoErr := c.getLockInfo()
+	if infoErr != nil {
+		lockErr.Err = multierror.Append(err, infoErr)
+	}
+	lockErr.Info = lockInfo
+
+	return lockErr
+}
+
+func (c *RemoteClient) getLockInfo() (*state.LockInfo, error) {
+	l, err := c.leases.Get(c.leaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := l.Metadata.Annotations[lockInfoAnnotation]
+	if raw == "" {
+		return nil, fmt.Errorf("no lock info in lease %s", c.leaseName)
+	}
+
+	lockInfo := &state.LockInfo{}
+	if err := json.Unmarshal([]byte(raw), lockInfo); err != nil {
+		return nil, err
+	}
+
+	return lockInfo, nil
+}
+
+func (c *RemoteClient) Unlock(id string) error {
+	lockErr := &state.LockError{}
+
+	l, err := c.leases.Get(c.leaseName)
+	if err != nil {
+		lockErr.Err = fmt.Errorf("failed to retrieve lock: %s", err)
+		return lockErr
+	}
+
+	lockInfo, err := c.getLockInfo()
+	if err == nil {
+		lockErr.Info = lockInfo
+	}
+
+	if l.holder() != id {
+		lockErr.Err = fmt.Errorf("lock id %q does not match existing lock", id)
+		return lockErr
+	}
+
+	l.release()
+	if err := c.leases.Update(l); err != nil {
+		lockErr.Err = err
+		return lockErr
+	}
+
+	return nil
+}
+
+func (c *RemoteClient) lockPath() string {
+	return fmt.Sprintf("%s/%s", c.namespace, c.leaseName)
+}
+
+// secretLabels returns the labels of the secrets of the state with the
+// given role, including the extra labels configured.
+func (c *RemoteClient) secretLabels(role string) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range c.labels {
+		labels[k] = v
+	}
+	labels[managedByLabel] = "terraform"
+	labels[workspaceLabel] = c.workspace
+	labels[suffixLabel] = c.suffix
+	if role != "" {
+		labels[roleLabel] = role
+	}
+
+	return labels
+}
+
+// secretChunks returns the names of the chunk secrets of the state stored
+// in secret, or nil if the state isn't chunked.
+func secretChunks(secret *v1.Secret) []string {
+	raw := strings.TrimSpace(string(secret.Data[chunksKey]))
+	if raw == "" {
+		return nil
+	}
+
+	return strings.Split(raw, "\n")
+}
+
+// splitChunks splits data into parts of at most size bytes. There is always
+// at least one part.
+func splitChunks(data []byte, size int) [][]byte {
+	parts := [][]byte{}
+	for len(data) > size {
+		parts = append(parts, data[:size])
+		data = data[size:]
+	}
+
+	return append(parts, data)
+}
+
+func compressState(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decompressState(gz []byte) ([]byte, error) {
+	if len(gz) == 0 {
+		return nil, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,178 @@
+package kubernetes
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+	v1 "k8s.io/kubernetes/pkg/api/v1"
+)
+
+func TestRemoteClient_impl(t *testing.T) {
+	var _ remote.Client = new(RemoteClient)
+	var _ remote.ClientLocker = new(RemoteClient)
+}
+
+func TestSplitChunks(t *testing.T) {
+	cases := []struct {
+		Data     string
+		Size     int
+		Expected []string
+	}{
+		{"", 3, []string{""}},
+		{"ab", 3, []string{"ab"}},
+		{"abc", 3, []string{"abc"}},
+		{"abcdefg", 3, []string{"abc", "def", "g"}},
+	}
+
+	for _, tc := range cases {
+		var actual []string
+		for _, part := range splitChunks([]byte(tc.Data), tc.Size) {
+			actual = append(actual, string(part))
+		}
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("%q: expected %q, got %q", tc.Data, tc.Expected, actual)
+		}
+	}
+}
+
+func TestCompressState(t *testing.T) {
+	data := []byte(strings.Repeat(`{"version": 3}`, 100))
+
+	gz, err := compressState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gz) >= len(data) {
+		t.Fatalf("state wasn't compressed: %d bytes from %d", len(gz), len(data))
+	}
+
+	actual, err := decompressState(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(actual, data) {
+		t.Fatalf("bad: %s", actual)
+	}
+
+	if actual, err := decompressState(nil); err != nil || actual != nil {
+		t.Fatalf("bad: %q, %v", actual, err)
+	}
+}
+
+func TestSecretChunks(t *testing.T) {
+	secret := &v1.Secret{Data: map[string][]byte{stateKey: []byte("x")}}
+	if chunks := secretChunks(secret); chunks != nil {
+		t.Fatalf("bad: %#v", chunks)
+	}
+
+	secret.Data[chunksKey] = []byte("a-0\na-1\n")
+	if chunks := secretChunks(secret); !reflect.DeepEqual(chunks, []string{"a-0", "a-1"}) {
+		t.Fatalf("bad: %#v", chunks)
+	}
+}
+
+func TestRemoteClient(t *testing.T) {
+	testACC(t)
+
+	suffix := fmt.Sprintf("test-%x", time.Now().Unix())
+	b := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"secret_suffix": suffix,
+	}).(*Backend)
+	defer cleanupK8sResources(t, b)
+
+	state, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote.TestClient(t, state.(*remote.State).Client)
+}
+
+// A state too large for a single secret is stored in chunks.
+func TestRemoteClient_chunked(t *testing.T) {
+	testACC(t)
+
+	suffix := fmt.Sprintf("test-%x", time.Now().Unix())
+	b := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"secret_suffix": suffix,
+	}).(*Backend)
+	defer cleanupK8sResources(t, b)
+
+	s, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := s.(*remote.State).Client.(*RemoteClient)
+
+	// Random resource IDs, so that the state doesn't compress down to a
+	// single chunk.
+	st := terraform.NewState()
+	mod := st.RootModule()
+	for i := 0; i < 100000; i++ {
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		mod.Resources[fmt.Sprintf("test_instance.foo.%d", i)] = &terraform.ResourceState{
+			Type:    "test_instance",
+			Primary: &terraform.InstanceState{ID: id},
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := terraform.WriteState(st, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Put(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := client.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p.Data, buf.Bytes()) {
+		t.Fatal("chunked state wasn't read back")
+	}
+	if sum := md5.Sum(buf.Bytes()); !bytes.Equal(p.MD5, sum[:]) {
+		t.Fatalf("bad MD5: %x", p.MD5)
+	}
+
+	if err := client.Delete(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoteClientLocks(t *testing.T) {
+	testACC(t)
+
+	suffix := fmt.Sprintf("test-%x", time.Now().Unix())
+	b1 := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"secret_suffix": suffix,
+	}).(*Backend)
+	b2 := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"secret_suffix": suffix,
+	}).(*Backend)
+	defer cleanupK8sResources(t, b1)
+
+	s1, err := b1.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := b2.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote.TestRemoteLocks(t, s1.(*remote.State).Client, s2.(*remote.State).Client)
+}
@@ -0,0 +1,122 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hashicorp/terraform/state"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	restclient "k8s.io/client-go/rest"
+)
+
+// lockInfoAnnotation holds the JSON lock info of the holder of a lease.
+const lockInfoAnnotation = "app.terraform.io/lock-info"
+
+// The vendored Kubernetes client predates the coordination.k8s.io API, so
+// leases are read and written as JSON through the REST client.
+const leaseAPIVersion = "coordination.k8s.io/v1"
+
+// lease is a coordination.k8s.io/v1 Lease, with only the fields that the
+// backend uses.
+type lease struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   metav1.ObjectMeta `json:"metadata"`
+	Spec       leaseSpec         `json:"spec"`
+}
+
+type leaseSpec struct {
+	HolderIdentity *string `json:"holderIdentity"`
+	AcquireTime    *string `json:"acquireTime"`
+}
+
+func newLease(namespace, name string, labels map[string]string) *lease {
+	return &lease{
+		APIVersion: leaseAPIVersion,
+		Kind:       "Lease",
+		Metadata: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+	}
+}
+
+// holder returns the ID of the lock holding the lease, or "" if it isn't
+// held.
+func (l *lease) holder() string {
+	if l.Spec.HolderIdentity == nil {
+		return ""
+	}
+
+	return *l.Spec.HolderIdentity
+}
+
+// hold sets the lease as held by the lock described by info.
+func (l *lease) hold(info *state.LockInfo) {
+	id := info.ID
+	acquired := time.Now().UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+	l.Spec.HolderIdentity = &id
+	l.Spec.AcquireTime = &acquired
+
+	if l.Metadata.Annotations == nil {
+		l.Metadata.Annotations = make(map[string]string)
+	}
+	l.Metadata.Annotations[lockInfoAnnotation] = string(info.Marshal())
+}
+
+// release sets the lease as not held.
+func (l *lease) release() {
+	l.Spec.HolderIdentity = nil
+	l.Spec.AcquireTime = nil
+	delete(l.Metadata.Annotations, lockInfoAnnotation)
+}
+
+// leaseClient reads and writes the leases of a namespace.
+type leaseClient struct {
+	rest      restclient.Interface
+	namespace string
+}
+
+func (c *leaseClient) path(name ...string) []string {
+	return append([]string{
+		"/apis", leaseAPIVersion, "namespaces", c.namespace, "leases"}, name...)
+}
+
+func (c *leaseClient) Get(name string) (*lease, error) {
+	raw, err := c.rest.Get().AbsPath(c.path(name)...).Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &lease{}
+	if err := json.Unmarshal(raw, l); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Create creates the lease l. It fails if the lease already exists.
+func (c *leaseClient) Create(l *lease) error {
+	return c.write(c.rest.Post().AbsPath(c.path()...), l)
+}
+
+// Update replaces the lease l. It fails with a conflict if the lease was
+// changed since l was read.
+func (c *leaseClient) Update(l *lease) error {
+	return c.write(c.rest.Put().AbsPath(c.path(l.Metadata.Name)...), l)
+}
+
+func (c *leaseClient) Delete(name string) error {
+	return c.rest.Delete().AbsPath(c.path(name)...).Do().Error()
+}
+
+func (c *leaseClient) write(req *restclient.Request, l *lease) error {
+	body, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	return req.SetHeader("Content-Type", "application/json").Body(body).Do().Error()
+}
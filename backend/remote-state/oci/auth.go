@@ -0,0 +1,356 @@
+package oci
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The auth methods that the backend can sign its requests with.
+const (
+	authAPIKey            = "api_key"
+	authInstancePrincipal = "instance_principal"
+)
+
+// requestSigner signs the requests to the OCI APIs.
+type requestSigner interface {
+	// Sign signs req, whose body is body. If signBody is false, the body
+	// isn't part of the signature, as for the uploads of objects.
+	Sign(req *http.Request, body []byte, signBody bool) error
+}
+
+// keySigner signs requests with an RSA key, with the OCI version of the
+// HTTP signatures draft.
+type keySigner struct {
+	KeyID string
+	Key   *rsa.PrivateKey
+}
+
+func (s *keySigner) Sign(req *http.Request, body []byte, signBody bool) error {
+	return signRequest(req, body, signBody, s.KeyID, s.Key)
+}
+
+// signRequest signs req with key, and sets its Authorization header.
+func signRequest(req *http.Request, body []byte, signBody bool, keyID string, key *rsa.PrivateKey) error {
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	headers := []string{"date", "(request-target)", "host"}
+	if signBody && (req.Method == http.MethodPost || req.Method == http.MethodPut) {
+		sum := sha256.Sum256(body)
+		req.Header.Set("X-Content-Sha256", base64.StdEncoding.EncodeToString(sum[:]))
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		headers = append(headers, "content-length", "content-type", "x-content-sha256")
+	}
+
+	lines := make([]string, len(headers))
+	for i, h := range headers {
+		var v string
+		switch h {
+		case "(request-target)":
+			target := req.URL.EscapedPath()
+			if req.URL.RawQuery != "" {
+				target += "?" + req.URL.RawQuery
+			}
+			v = strings.ToLower(req.Method) + " " + target
+		case "host":
+			v = req.URL.Host
+		default:
+			v = req.Header.Get(h)
+		}
+		lines[i] = h + ": " + v
+	}
+
+	digest := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return fmt.Errorf("error signing the request: %s", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		`Signature version="1",keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// apiKeySigner returns the signer for the API key of a user.
+func apiKeySigner(tenancy, user, fingerprint string, key *rsa.PrivateKey) *keySigner {
+	return &keySigner{
+		KeyID: tenancy + "/" + user + "/" + fingerprint,
+		Key:   key,
+	}
+}
+
+// parsePrivateKey parses an RSA private key in PEM format, which is
+// decrypted with password if it's encrypted.
+func parsePrivateKey(data []byte, password string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("the private key isn't in PEM format")
+	}
+
+	der := block.Bytes
+	if x509.IsEncryptedPEMBlock(block) {
+		if password == "" {
+			return nil, fmt.Errorf("the private key is encrypted, but private_key_password isn't set")
+		}
+
+		var err error
+		der, err = x509.DecryptPEMBlock(block, []byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting the private key: %s", err)
+		}
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the private key: %s", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key isn't an RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+// The instance metadata service, and the authentication service of a
+// region. These are variables so that tests can replace them.
+var (
+	metadataURL = "http://169.254.169.254/opc/v2"
+	authURL     = func(region string) string {
+		return fmt.Sprintf("https://auth.%s.oraclecloud.com", region)
+	}
+)
+
+// instancePrincipalSigner signs requests as the instance that Terraform
+// runs on, with a session token that the instance gets from the
+// authentication service with the certificate in its metadata.
+//
+// The token and the certificate both expire, so the token is replaced,
+// with a fresh certificate, shortly before it expires.
+type instancePrincipalSigner struct {
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+	key     *rsa.PrivateKey
+}
+
+// instanceRegion returns the name of the region of the instance that
+// Terraform runs on, such as "us-ashburn-1".
+func instanceRegion(client *http.Client) (string, error) {
+	data, err := getMetadata(client, "/instance/canonicalRegionName")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (s *instancePrincipalSigner) Sign(req *http.Request, body []byte, signBody bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == "" || time.Now().Add(time.Minute).After(s.expires) {
+		if err := s.refresh(); err != nil {
+			return err
+		}
+	}
+
+	return signRequest(req, body, signBody, "ST$"+s.token, s.key)
+}
+
+// refresh gets a new session token for a new session key.
+func (s *instancePrincipalSigner) refresh() error {
+	region, err := instanceRegion(s.client)
+	if err != nil {
+		return err
+	}
+
+	leafPEM, err := getMetadata(s.client, "/identity/cert.pem")
+	if err != nil {
+		return err
+	}
+	keyPEM, err := getMetadata(s.client, "/identity/key.pem")
+	if err != nil {
+		return err
+	}
+	intermediatePEM, err := getMetadata(s.client, "/identity/intermediate.pem")
+	if err != nil {
+		return err
+	}
+
+	leafBlock, _ := pem.Decode(leafPEM)
+	if leafBlock == nil {
+		return fmt.Errorf("the instance certificate isn't in PEM format")
+	}
+	leaf, err := x509.ParseCertificate(leafBlock.Bytes)
+	if err != nil {
+		return fmt.Errorf("error parsing the instance certificate: %s", err)
+	}
+	leafKey, err := parsePrivateKey(keyPEM, "")
+	if err != nil {
+		return fmt.Errorf("instance key: %s", err)
+	}
+	tenancy, err := certTenancy(leaf)
+	if err != nil {
+		return err
+	}
+
+	// The session key is new for every token, so that it's never stored
+	sessionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	sessionPub, err := x509.MarshalPKIXPublicKey(&sessionKey.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	var intermediates []string
+	for rest := intermediatePEM; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		intermediates = append(intermediates, base64.StdEncoding.EncodeToString(block.Bytes))
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"certificate":              base64.StdEncoding.EncodeToString(leafBlock.Bytes),
+		"publicKey":                base64.StdEncoding.EncodeToString(sessionPub),
+		"intermediateCertificates": intermediates,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, authURL(region)+"/v1/x509", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	keyID := tenancy + "/fed-x509/" + certFingerprint(leaf)
+	if err := signRequest(req, body, true, keyID, leafKey); err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error getting a token for the instance principal: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf(
+			"error getting a token for the instance principal: %s", responseError(resp))
+	}
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("error decoding the token for the instance principal: %s", err)
+	}
+
+	expires, err := tokenExpiry(result.Token)
+	if err != nil {
+		return err
+	}
+
+	s.token = result.Token
+	s.expires = expires
+	s.key = sessionKey
+	return nil
+}
+
+// getMetadata returns the instance metadata at the given path.
+func getMetadata(client *http.Client, path string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, metadataURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer Oracle")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error reading the instance metadata, which is only available on "+
+				"OCI instances: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error reading the instance metadata %s: %s", path, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// certTenancy returns the OCID of the tenancy of the instance with the
+// given certificate, which is in an organizational unit of its subject.
+func certTenancy(cert *x509.Certificate) (string, error) {
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		for _, prefix := range []string{"opc-tenant:", "opc-identity:"} {
+			if strings.HasPrefix(ou, prefix) {
+				return strings.TrimPrefix(ou, prefix), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("the instance certificate doesn't name a tenancy")
+}
+
+// certFingerprint returns the SHA1 fingerprint of cert, in the form the
+// authentication service expects, such as "AB:CD:...".
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// tokenExpiry returns when the session token, which is a JWT, expires.
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("the session token isn't a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error decoding the session token: %s", err)
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("error decoding the session token: %s", err)
+	}
+
+	return time.Unix(claims.Exp, 0), nil
+}
@@ -0,0 +1,320 @@
+package oci
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+)
+
+// testPrivateKey returns an RSA key for tests, which is only generated
+// once, since that's slow.
+func testPrivateKey(t *testing.T) *rsa.PrivateKey {
+	testKeyOnce.Do(func() {
+		var err error
+		testKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	})
+
+	return testKey
+}
+
+// testPrivateKeyPEM returns the PEM of testPrivateKey.
+func testPrivateKeyPEM(t *testing.T) string {
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(testPrivateKey(t)),
+	}))
+}
+
+// marshalPKCS8PrivateKey encodes key in PKCS #8 form, which x509 can only
+// parse in the Go versions that Terraform is built with.
+func marshalPKCS8PrivateKey(key *rsa.PrivateKey) ([]byte, error) {
+	return asn1.Marshal(struct {
+		Version    int
+		Algo       pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}{
+		Algo: pkix.AlgorithmIdentifier{
+			Algorithm:  asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1},
+			Parameters: asn1.RawValue{Tag: 5},
+		},
+		PrivateKey: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
+
+// verifyRequest checks the signature of req with pub, and returns the key
+// ID that it was signed with.
+func verifyRequest(req *http.Request, pub *rsa.PublicKey) (string, error) {
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Signature ") {
+		return "", fmt.Errorf("not signed: %q", auth)
+	}
+
+	params := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "Signature "), ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return "", fmt.Errorf("bad Authorization: %q", auth)
+		}
+		params[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+
+	var lines []string
+	for _, h := range strings.Split(params["headers"], " ") {
+		var v string
+		switch h {
+		case "(request-target)":
+			v = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			v = req.Host
+		default:
+			v = req.Header.Get(h)
+		}
+		lines = append(lines, h+": "+v)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+		return "", fmt.Errorf("bad signature of %q: %s", strings.Join(lines, "\n"), err)
+	}
+
+	return params["keyId"], nil
+}
+
+func TestSignRequest(t *testing.T) {
+	key := testPrivateKey(t)
+	signer := apiKeySigner("tenancy", "user", "aa:bb", key)
+
+	body := []byte(`{"foo": "bar"}`)
+	req, err := http.NewRequest("POST", "https://example.com/n/ns/b/bucket/o/env%3A%2Ffoo?prefix=a", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := signer.Sign(req, body, true); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The server sees the host in Host rather than in the URL
+	req.Host = req.URL.Host
+	keyID, err := verifyRequest(req, &key.PublicKey)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if keyID != "tenancy/user/aa:bb" {
+		t.Fatalf("bad: %s", keyID)
+	}
+	if !strings.Contains(req.Header.Get("Authorization"), `headers="date (request-target) host content-length content-type x-content-sha256"`) {
+		t.Fatalf("bad: %s", req.Header.Get("Authorization"))
+	}
+
+	// The body of an object isn't signed
+	req.Header = make(http.Header)
+	if err := signer.Sign(req, body, false); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(req.Header.Get("Authorization"), `headers="date (request-target) host"`) {
+		t.Fatalf("bad: %s", req.Header.Get("Authorization"))
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key := testPrivateKey(t)
+
+	pkcs8, err := marshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	encrypted, err := x509.EncryptPEMBlock(
+		rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key),
+		[]byte("password"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := map[string]struct {
+		PEM      []byte
+		Password string
+		Err      bool
+	}{
+		"pkcs1": {
+			[]byte(testPrivateKeyPEM(t)),
+			"",
+			false,
+		},
+		"pkcs8": {
+			pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+			"",
+			false,
+		},
+		"encrypted": {
+			pem.EncodeToMemory(encrypted),
+			"password",
+			false,
+		},
+		"encrypted without password": {
+			pem.EncodeToMemory(encrypted),
+			"",
+			true,
+		},
+		"not pem": {
+			[]byte("foo"),
+			"",
+			true,
+		},
+	}
+
+	for name, tc := range cases {
+		actual, err := parsePrivateKey(tc.PEM, tc.Password)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: err: %v", name, err)
+		}
+		if err == nil && actual.N.Cmp(key.N) != 0 {
+			t.Fatalf("%s: wrong key", name)
+		}
+	}
+}
+
+func TestInstancePrincipalSigner(t *testing.T) {
+	leafKey := testPrivateKey(t)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName:         "ocid1.instance.test",
+			OrganizationalUnit: []string{"opc-instance:ocid1.instance.test", "opc-tenant:ocid1.tenancy.test"},
+		},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, template, template, &leafKey.PublicKey, leafKey)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	leafPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})
+
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer Oracle" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/opc/v2/instance/canonicalRegionName":
+			w.Write([]byte("us-test-1"))
+		case "/opc/v2/identity/cert.pem", "/opc/v2/identity/intermediate.pem":
+			w.Write(leafPEM)
+		case "/opc/v2/identity/key.pem":
+			w.Write([]byte(testPrivateKeyPEM(t)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer metadata.Close()
+
+	// The token is a JWT that expires in an hour
+	claims, _ := json.Marshal(map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()})
+	token := "header." + base64.RawURLEncoding.EncodeToString(claims) + ".signature"
+
+	var sessionKey *rsa.PublicKey
+	var logins int
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyID, err := verifyRequest(r, &leafKey.PublicKey)
+		if err != nil {
+			t.Errorf("err: %s", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if want := "ocid1.tenancy.test/fed-x509/" + certFingerprint(leaf); keyID != want {
+			t.Errorf("bad key ID %q; want %q", keyID, want)
+		}
+
+		var body struct {
+			Certificate   string   `json:"certificate"`
+			PublicKey     string   `json:"publicKey"`
+			Intermediates []string `json:"intermediateCertificates"`
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("err: %s", err)
+		}
+		if body.Certificate != base64.StdEncoding.EncodeToString(leafDER) || len(body.Intermediates) != 1 {
+			t.Errorf("bad: %s", data)
+		}
+
+		der, _ := base64.StdEncoding.DecodeString(body.PublicKey)
+		pub, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			t.Errorf("err: %s", err)
+		}
+		sessionKey, _ = pub.(*rsa.PublicKey)
+		logins++
+
+		json.NewEncoder(w).Encode(map[string]string{"token": token})
+	}))
+	defer auth.Close()
+
+	oldMetadataURL, oldAuthURL := metadataURL, authURL
+	defer func() { metadataURL, authURL = oldMetadataURL, oldAuthURL }()
+	metadataURL = metadata.URL + "/opc/v2"
+	authURL = func(region string) string {
+		if region != "us-test-1" {
+			t.Errorf("bad region: %s", region)
+		}
+		return auth.URL
+	}
+
+	signer := &instancePrincipalSigner{client: http.DefaultClient}
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("GET", "https://example.com/n/ns/b/bucket/o/state", nil)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := signer.Sign(req, nil, false); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		req.Host = req.URL.Host
+		keyID, err := verifyRequest(req, sessionKey)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if keyID != "ST$"+token {
+			t.Fatalf("bad: %s", keyID)
+		}
+	}
+
+	// The token is reused until it's about to expire
+	if logins != 1 {
+		t.Fatalf("logged in %d times", logins)
+	}
+}
@@ -0,0 +1,244 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/mitchellh/go-homedir"
+)
+
+// New creates a new backend for OCI Object Storage remote state.
+func New() backend.Backend {
+	s := &schema.Backend{
+		Schema: map[string]*schema.Schema{
+			"bucket": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the Object Storage bucket",
+			},
+
+			"namespace": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The Object Storage namespace of the tenancy of the bucket",
+			},
+
+			"key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The name of the state object inside the bucket",
+				Default:     "terraform.tfstate",
+			},
+
+			"env_prefix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The prefix of the names of the states of environments other than the default",
+				Default:     "env:",
+			},
+
+			"region": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The region of the bucket, such as us-ashburn-1",
+				DefaultFunc: schema.EnvDefaultFunc("OCI_REGION", ""),
+			},
+
+			"endpoint": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "A custom endpoint for the Object Storage API",
+				DefaultFunc: schema.EnvDefaultFunc("OCI_OBJECT_STORAGE_ENDPOINT", ""),
+			},
+
+			"auth": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "How to authenticate: api_key or instance_principal",
+				Default:     authAPIKey,
+			},
+
+			"tenancy_ocid": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The OCID of the tenancy, for api_key auth",
+				DefaultFunc: schema.EnvDefaultFunc("OCI_TENANCY_OCID", ""),
+			},
+
+			"user_ocid": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The OCID of the user, for api_key auth",
+				DefaultFunc: schema.EnvDefaultFunc("OCI_USER_OCID", ""),
+			},
+
+			"fingerprint": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The fingerprint of the API key, for api_key auth",
+				DefaultFunc: schema.EnvDefaultFunc("OCI_FINGERPRINT", ""),
+			},
+
+			"private_key": {
+				Type:        schema.TypeString,
+				Sensitive:   true,
+				Optional:    true,
+				Description: "The PEM private key of the API key, for api_key auth",
+				DefaultFunc: schema.EnvDefaultFunc("OCI_PRIVATE_KEY", ""),
+			},
+
+			"private_key_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The path to the PEM private key of the API key, for api_key auth",
+				DefaultFunc: schema.EnvDefaultFunc("OCI_PRIVATE_KEY_PATH", ""),
+			},
+
+			"private_key_password": {
+				Type:        schema.TypeString,
+				Sensitive:   true,
+				Optional:    true,
+				Description: "The password of the private key, if it's encrypted",
+				DefaultFunc: schema.EnvDefaultFunc("OCI_PRIVATE_KEY_PASSWORD", ""),
+			},
+
+			"lock": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Whether to lock the state with lock objects",
+				Default:     true,
+			},
+
+			"lock_bucket": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The bucket of the lock objects, if it isn't the bucket of the state",
+				Default:     "",
+			},
+		},
+	}
+
+	result := &Backend{Backend: s}
+	result.Backend.ConfigureFunc = result.configure
+	return result
+}
+
+type Backend struct {
+	*schema.Backend
+
+	// The fields below are set from configure
+	storage     *objectStorage
+	lockStorage *objectStorage
+
+	keyName   string
+	envPrefix string
+}
+
+func (b *Backend) configure(ctx context.Context) error {
+	if b.storage != nil {
+		return nil
+	}
+
+	// Grab the resource data
+	data := schema.FromContextBackendConfig(ctx)
+
+	b.keyName = strings.Trim(data.Get("key").(string), "/")
+	b.envPrefix = strings.Trim(data.Get("env_prefix").(string), "/")
+
+	if b.keyName == "" {
+		return fmt.Errorf("key must not be empty")
+	}
+	if b.envPrefix == "" {
+		return fmt.Errorf("env_prefix must not be empty")
+	}
+
+	client := cleanhttp.DefaultPooledClient()
+	region := data.Get("region").(string)
+
+	var signer requestSigner
+	switch auth := data.Get("auth").(string); auth {
+	case authAPIKey:
+		var err error
+		signer, err = configureAPIKey(data)
+		if err != nil {
+			return err
+		}
+	case authInstancePrincipal:
+		signer = &instancePrincipalSigner{client: client}
+	default:
+		return fmt.Errorf("auth must be %q or %q, not %q", authAPIKey, authInstancePrincipal, auth)
+	}
+
+	endpoint := strings.TrimRight(data.Get("endpoint").(string), "/")
+	if endpoint == "" {
+		if region == "" && data.Get("auth").(string) == authInstancePrincipal {
+			var err error
+			region, err = instanceRegion(client)
+			if err != nil {
+				return err
+			}
+		}
+		if region == "" {
+			return fmt.Errorf("region must be set, or OCI_REGION")
+		}
+
+		endpoint = fmt.Sprintf("https://objectstorage.%s.oraclecloud.com", region)
+	}
+
+	b.storage = &objectStorage{
+		client:    client,
+		signer:    signer,
+		endpoint:  endpoint,
+		namespace: data.Get("namespace").(string),
+		bucket:    data.Get("bucket").(string),
+	}
+
+	if data.Get("lock").(bool) {
+		lockStorage := *b.storage
+		if lockBucket := data.Get("lock_bucket").(string); lockBucket != "" {
+			lockStorage.bucket = lockBucket
+		}
+		b.lockStorage = &lockStorage
+	}
+
+	return nil
+}
+
+// configureAPIKey returns the signer for api_key auth.
+func configureAPIKey(data *schema.ResourceData) (requestSigner, error) {
+	tenancy := data.Get("tenancy_ocid").(string)
+	user := data.Get("user_ocid").(string)
+	fingerprint := data.Get("fingerprint").(string)
+	if tenancy == "" || user == "" || fingerprint == "" {
+		return nil, fmt.Errorf(
+			"tenancy_ocid, user_ocid and fingerprint must be set for %s auth", authAPIKey)
+	}
+
+	keyPEM := []byte(data.Get("private_key").(string))
+	if path := data.Get("private_key_path").(string); len(keyPEM) == 0 && path != "" {
+		path, err := homedir.Expand(path)
+		if err != nil {
+			return nil, err
+		}
+
+		keyPEM, err = ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading private_key_path: %s", err)
+		}
+	}
+	if len(keyPEM) == 0 {
+		return nil, fmt.Errorf("private_key or private_key_path must be set for %s auth", authAPIKey)
+	}
+
+	key, err := parsePrivateKey(keyPEM, data.Get("private_key_password").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKeySigner(tenancy, user, fingerprint, key), nil
+}
@@ -0,0 +1,177 @@
+package oci
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func (b *Backend) States() ([]string, error) {
+	names, err := b.storage.List(b.envPrefix + "/")
+	if err != nil {
+		return nil, fmt.Errorf("Error listing the states in bucket %s: %s", b.storage.bucket, err)
+	}
+
+	envs := []string{backend.DefaultStateName}
+	for _, name := range names {
+		env := b.keyEnv(name)
+		if env != "" {
+			envs = append(envs, env)
+		}
+	}
+
+	sort.Strings(envs[1:])
+	return envs, nil
+}
+
+// extract the env name from the name of an object
+func (b *Backend) keyEnv(key string) string {
+	// shouldn't happen since we listed by prefix
+	prefix := b.envPrefix + "/"
+	if !strings.HasPrefix(key, prefix) {
+		return ""
+	}
+
+	// we have 2 parts after the prefix, the env name and the key name
+	parts := strings.SplitN(strings.TrimPrefix(key, prefix), "/", 2)
+	if len(parts) < 2 {
+		// no env here
+		return ""
+	}
+
+	// not our key, so don't include it in our listing
+	if parts[1] != b.keyName {
+		return ""
+	}
+
+	return parts[0]
+}
+
+func (b *Backend) DeleteState(name string) error {
+	if name == backend.DefaultStateName || name == "" {
+		return fmt.Errorf("can't delete default state")
+	}
+
+	client, err := b.remoteClient(name)
+	if err != nil {
+		return err
+	}
+
+	return client.Delete()
+}
+
+func (b *Backend) State(name string) (state.State, error) {
+	if name == "" {
+		return nil, errors.New("missing state name")
+	}
+
+	client, err := b.remoteClient(name)
+	if err != nil {
+		return nil, err
+	}
+
+	stateMgr := &remote.State{Client: client}
+
+	// Check to see if this state already exists.
+	// If we're trying to force-unlock a state, we can't take the lock before
+	// fetching the state. If the state doesn't exist, we have to assume this
+	// is a normal create operation, and take the lock at that point.
+	existing, err := b.States()
+	if err != nil {
+		return nil, err
+	}
+
+	exists := false
+	for _, s := range existing {
+		if s == name {
+			exists = true
+			break
+		}
+	}
+
+	// We need to create the object so it's listed by States.
+	if !exists {
+		// take a lock on this state while we write it
+		lockInfo := state.NewLockInfo()
+		lockInfo.Operation = "init"
+		lockId, err := client.Lock(lockInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock OCI state: %s", err)
+		}
+
+		// Local helper function so we can call it multiple places
+		lockUnlock := func(parent error) error {
+			if err := stateMgr.Unlock(lockId); err != nil {
+				return fmt.Errorf(strings.TrimSpace(errStateUnlock), lockId, err)
+			}
+			return parent
+		}
+
+		// Grab the value
+		// This is to ensure that no one beat us to writing a state between
+		// the `exists` check and taking the lock.
+		if err := stateMgr.RefreshState(); err != nil {
+			err = lockUnlock(err)
+			return nil, err
+		}
+
+		// If we have no state, we have to create an empty state
+		if v := stateMgr.State(); v == nil {
+			if err := stateMgr.WriteState(terraform.NewState()); err != nil {
+				err = lockUnlock(err)
+				return nil, err
+			}
+			if err := stateMgr.PersistState(); err != nil {
+				err = lockUnlock(err)
+				return nil, err
+			}
+		}
+
+		// Unlock, the state should now be initialized
+		if err := lockUnlock(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return stateMgr, nil
+}
+
+// StateLocker implements backend.StateLocker, locking the state with the
+// given name without creating it.
+func (b *Backend) StateLocker(name string) (state.Locker, error) {
+	if b.lockStorage == nil {
+		return nil, backend.ErrLockingNotConfigured
+	}
+
+	return b.remoteClient(name)
+}
+
+func (b *Backend) remoteClient(name string) (*RemoteClient, error) {
+	return &RemoteClient{
+		storage:     b.storage,
+		lockStorage: b.lockStorage,
+		path:        b.path(name),
+	}, nil
+}
+
+func (b *Backend) path(name string) string {
+	if name == backend.DefaultStateName {
+		return b.keyName
+	}
+
+	return strings.Join([]string{b.envPrefix, name, b.keyName}, "/")
+}
+
+const errStateUnlock = `
+Error unlocking OCI state. Lock ID: %s
+
+Error: %s
+
+You may have to force-unlock this state in order to use it again.
+`
@@ -0,0 +1,369 @@
+package oci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// verify that we are doing ACC tests or the OCI tests specifically
+func testACC(t *testing.T) {
+	skip := os.Getenv("TF_ACC") == "" && os.Getenv("TF_OCI_TEST") == ""
+	if skip {
+		t.Log("oci backend tests require setting TF_ACC or TF_OCI_TEST")
+		t.Skip()
+	}
+	if os.Getenv("OCI_TEST_BUCKET") == "" || os.Getenv("OCI_TEST_NAMESPACE") == "" {
+		t.Fatal("OCI_TEST_BUCKET and OCI_TEST_NAMESPACE must be set to an existing bucket to test with")
+	}
+}
+
+// fakeObjectStorage is an Object Storage API for tests, which checks the
+// signatures of the requests and the conditions of uploads and deletes.
+type fakeObjectStorage struct {
+	t *testing.T
+
+	mu      sync.Mutex
+	objects map[string]*object
+	etag    int
+}
+
+func (s *fakeObjectStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keyID, err := verifyRequest(r, &testPrivateKey(s.t).PublicKey)
+	if err != nil || keyID != "ocid1.tenancy.test/ocid1.user.test/aa:bb" {
+		s.error(w, http.StatusUnauthorized, "NotAuthenticated", fmt.Sprintf("%s %v", keyID, err))
+		return
+	}
+
+	// The paths are /n/NAMESPACE/b/BUCKET/o[/OBJECT]
+	parts := strings.SplitN(r.URL.EscapedPath(), "/", 7)
+	if len(parts) < 6 || parts[1] != "n" || parts[2] != "ns" || parts[3] != "b" || parts[5] != "o" {
+		s.error(w, http.StatusNotFound, "NotFound", r.URL.Path)
+		return
+	}
+	bucket := parts[4]
+
+	if len(parts) == 6 {
+		s.list(w, r, bucket)
+		return
+	}
+
+	name, _ := url.PathUnescape(parts[6])
+	key := bucket + "/" + name
+	obj := s.objects[key]
+
+	switch r.Method {
+	case http.MethodGet:
+		if obj == nil {
+			s.error(w, http.StatusNotFound, "ObjectNotFound", name)
+			return
+		}
+		w.Header().Set("ETag", obj.ETag)
+		w.Header().Set("Last-Modified", obj.ModTime.Format(http.TimeFormat))
+		w.Write(obj.Data)
+	case http.MethodPut:
+		if r.Header.Get("If-None-Match") == "*" && obj != nil {
+			s.error(w, http.StatusPreconditionFailed, "IfNoneMatchFailed", name)
+			return
+		}
+
+		data, _ := ioutil.ReadAll(r.Body)
+		s.etag++
+		s.objects[key] = &object{
+			Data:    data,
+			ETag:    strconv.Itoa(s.etag),
+			ModTime: time.Now(),
+		}
+	case http.MethodDelete:
+		if obj == nil {
+			s.error(w, http.StatusNotFound, "ObjectNotFound", name)
+			return
+		}
+		if etag := r.Header.Get("If-Match"); etag != "" && etag != obj.ETag {
+			s.error(w, http.StatusPreconditionFailed, "IfMatchFailed", name)
+			return
+		}
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// list lists the objects of a bucket, two at a time to test the paging.
+func (s *fakeObjectStorage) list(w http.ResponseWriter, r *http.Request, bucket string) {
+	prefix := bucket + "/" + r.URL.Query().Get("prefix")
+	start := bucket + "/" + r.URL.Query().Get("start")
+
+	var names []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, prefix) && k >= start {
+			names = append(names, strings.TrimPrefix(k, bucket+"/"))
+		}
+	}
+	sort.Strings(names)
+
+	type listObject struct {
+		Name string `json:"name"`
+	}
+	result := struct {
+		Objects       []listObject `json:"objects"`
+		NextStartWith string       `json:"nextStartWith,omitempty"`
+	}{Objects: []listObject{}}
+	for i, name := range names {
+		if i == 2 {
+			result.NextStartWith = name
+			break
+		}
+		result.Objects = append(result.Objects, listObject{Name: name})
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+func (s *fakeObjectStorage) error(w http.ResponseWriter, status int, code, msg string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"code": code, "message": msg})
+}
+
+// testFakeBackend returns a backend of a fakeObjectStorage, configured with
+// the given extra config, and a function that stops the server.
+func testFakeBackend(t *testing.T, extra map[string]interface{}) (*Backend, *fakeObjectStorage, func()) {
+	fake := &fakeObjectStorage{t: t, objects: make(map[string]*object)}
+	srv := httptest.NewServer(fake)
+
+	b := testFakeBackendFor(t, srv.URL, extra)
+	return b, fake, srv.Close
+}
+
+// testFakeBackendFor returns another backend of the fakeObjectStorage at
+// the given URL.
+func testFakeBackendFor(t *testing.T, endpoint string, extra map[string]interface{}) *Backend {
+	config := map[string]interface{}{
+		"bucket":       "bucket",
+		"namespace":    "ns",
+		"key":          "test/state",
+		"endpoint":     endpoint,
+		"tenancy_ocid": "ocid1.tenancy.test",
+		"user_ocid":    "ocid1.user.test",
+		"fingerprint":  "aa:bb",
+		"private_key":  testPrivateKeyPEM(t),
+	}
+	for k, v := range extra {
+		config[k] = v
+	}
+
+	return backend.TestBackendConfig(t, New(), config).(*Backend)
+}
+
+func TestBackend_impl(t *testing.T) {
+	var _ backend.Backend = new(Backend)
+}
+
+func TestBackendConfig(t *testing.T) {
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	keyPath := filepath.Join(td, "key.pem")
+	if err := ioutil.WriteFile(keyPath, []byte(testPrivateKeyPEM(t)), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config := map[string]interface{}{
+		"region":           "us-ashburn-1",
+		"bucket":           "tf-test",
+		"namespace":        "ns",
+		"key":              "/state/",
+		"tenancy_ocid":     "ocid1.tenancy.test",
+		"user_ocid":        "ocid1.user.test",
+		"fingerprint":      "aa:bb",
+		"private_key_path": keyPath,
+		"lock_bucket":      "tf-locks",
+	}
+
+	b := backend.TestBackendConfig(t, New(), config).(*Backend)
+
+	if b.storage.endpoint != "https://objectstorage.us-ashburn-1.oraclecloud.com" {
+		t.Fatalf("Incorrect endpoint was populated: %s", b.storage.endpoint)
+	}
+	if b.storage.bucket != "tf-test" || b.storage.namespace != "ns" {
+		t.Fatalf("Incorrect bucket was populated")
+	}
+	if b.lockStorage.bucket != "tf-locks" {
+		t.Fatalf("Incorrect lock bucket was populated")
+	}
+	if b.keyName != "state" {
+		t.Fatalf("Incorrect keyName was populated")
+	}
+	if b.envPrefix != "env:" {
+		t.Fatalf("Incorrect envPrefix was populated")
+	}
+	if signer, ok := b.storage.signer.(*keySigner); !ok || signer.KeyID != "ocid1.tenancy.test/ocid1.user.test/aa:bb" {
+		t.Fatalf("Incorrect signer: %#v", b.storage.signer)
+	}
+}
+
+func TestBackendConfig_invalid(t *testing.T) {
+	os.Unsetenv("OCI_REGION")
+	os.Unsetenv("OCI_FINGERPRINT")
+
+	cases := map[string]struct {
+		Config map[string]interface{}
+		Err    string
+	}{
+		"no fingerprint": {
+			map[string]interface{}{"fingerprint": ""},
+			"fingerprint must be set",
+		},
+		"no key": {
+			map[string]interface{}{"private_key": ""},
+			"private_key or private_key_path",
+		},
+		"bad auth": {
+			map[string]interface{}{"auth": "password"},
+			"auth must be",
+		},
+		"no region": {
+			map[string]interface{}{"endpoint": ""},
+			"region must be set",
+		},
+	}
+
+	for name, tc := range cases {
+		raw := map[string]interface{}{
+			"bucket":       "bucket",
+			"namespace":    "ns",
+			"endpoint":     "https://objectstorage.example.com",
+			"tenancy_ocid": "ocid1.tenancy.test",
+			"user_ocid":    "ocid1.user.test",
+			"fingerprint":  "aa:bb",
+			"private_key":  testPrivateKeyPEM(t),
+		}
+		for k, v := range tc.Config {
+			raw[k] = v
+		}
+
+		rawConfig, err := config.NewRawConfig(raw)
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+
+		err = New().Configure(terraform.NewResourceConfig(rawConfig))
+		if err == nil || !strings.Contains(err.Error(), tc.Err) {
+			t.Fatalf("%s: bad: %v", name, err)
+		}
+	}
+}
+
+func TestBackendKeyEnv(t *testing.T) {
+	b := &Backend{
+		keyName:   "test/state",
+		envPrefix: "tf/env",
+	}
+
+	cases := map[string]string{
+		"tf/env/foo/test/state":         "foo",
+		"tf/env/foo/test/state.tflock":  "",
+		"tf/env/foo/other/state":        "",
+		"tf/env/test/state":             "",
+		"env:/foo/test/state":           "",
+		"test/state":                    "",
+		"tf/env/bar/test/state/another": "",
+	}
+
+	for key, expected := range cases {
+		if actual := b.keyEnv(key); actual != expected {
+			t.Fatalf("%s: expected %q, got %q", key, expected, actual)
+		}
+	}
+
+	if path := b.path("foo"); path != "tf/env/foo/test/state" {
+		t.Fatalf("bad: %s", path)
+	}
+	if path := b.path(backend.DefaultStateName); path != "test/state" {
+		t.Fatalf("bad: %s", path)
+	}
+}
+
+func TestBackend_fake(t *testing.T) {
+	b1, _, closer := testFakeBackend(t, nil)
+	defer closer()
+	b2 := testFakeBackendFor(t, b1.storage.endpoint, nil)
+
+	backend.TestBackend(t, b1, b2)
+}
+
+func TestBackend(t *testing.T) {
+	testACC(t)
+
+	b := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"bucket":     os.Getenv("OCI_TEST_BUCKET"),
+		"namespace":  os.Getenv("OCI_TEST_NAMESPACE"),
+		"key":        fmt.Sprintf("terraform-remote-oci-test-%x/state", time.Now().Unix()),
+		"env_prefix": fmt.Sprintf("terraform-remote-oci-test-env-%x", time.Now().Unix()),
+		"lock":       false,
+	}).(*Backend)
+	defer deleteOCIObjects(t, b)
+
+	backend.TestBackend(t, b, nil)
+}
+
+func TestBackendLocked(t *testing.T) {
+	testACC(t)
+
+	config := map[string]interface{}{
+		"bucket":     os.Getenv("OCI_TEST_BUCKET"),
+		"namespace":  os.Getenv("OCI_TEST_NAMESPACE"),
+		"key":        fmt.Sprintf("terraform-remote-oci-test-%x/state", time.Now().Unix()),
+		"env_prefix": fmt.Sprintf("terraform-remote-oci-test-env-%x", time.Now().Unix()),
+	}
+	b1 := backend.TestBackendConfig(t, New(), config).(*Backend)
+	b2 := backend.TestBackendConfig(t, New(), config).(*Backend)
+	defer deleteOCIObjects(t, b1)
+
+	backend.TestBackend(t, b1, b2)
+}
+
+// deleteOCIObjects deletes the objects that a test of b created in the
+// test bucket.
+func deleteOCIObjects(t *testing.T, b *Backend) {
+	warning := "WARNING: Failed to delete the test objects. They may have been left in your OCI bucket and may incur storage charges. (error was %s)"
+
+	for _, prefix := range []string{strings.SplitN(b.keyName, "/", 2)[0], b.envPrefix} {
+		names, err := b.storage.List(prefix)
+		if err != nil {
+			t.Logf(warning, err)
+			return
+		}
+		for _, name := range names {
+			if err := b.storage.Delete(name, ""); err != nil {
+				t.Logf(warning, err)
+			}
+		}
+	}
+}
+
+func TestBackendStateLocker_notConfigured(t *testing.T) {
+	if _, err := new(Backend).StateLocker(backend.DefaultStateName); err != backend.ErrLockingNotConfigured {
+		t.Fatalf("bad: %v", err)
+	}
+}
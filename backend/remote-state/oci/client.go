@@ -0,0 +1,151 @@
+package oci
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	multierror "github.com/hashicorp/go-multierror"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+)
+
+// lockSuffix is the suffix of the name of the lock object of a state.
+const lockSuffix = ".tflock"
+
+type RemoteClient struct {
+	storage *objectStorage
+
+	// lockStorage is where the lock object is kept, which is the bucket of
+	// the state unless lock_bucket is set, or nil if locking is disabled.
+	lockStorage *objectStorage
+
+	path string
+}
+
+func (c *RemoteClient) Get() (*remote.Payload, error) {
+	obj, err := c.storage.Get(c.path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read remote state: %s", err)
+	}
+
+	// If there was no data, then return nil
+	if obj == nil || len(obj.Data) == 0 {
+		return nil, nil
+	}
+
+	sum := md5.Sum(obj.Data)
+	return &remote.Payload{
+		Data:    obj.Data,
+		MD5:     sum[:],
+		ModTime: obj.ModTime,
+	}, nil
+}
+
+func (c *RemoteClient) Put(data []byte) error {
+	log.Printf("[DEBUG] Uploading remote state to OCI: %s/%s", c.storage.bucket, c.path)
+
+	if err := c.storage.Put(c.path, data, nil); err != nil {
+		return fmt.Errorf("Failed to upload state: %s", err)
+	}
+
+	return nil
+}
+
+func (c *RemoteClient) Delete() error {
+	return c.storage.Delete(c.path, "")
+}
+
+// Lock creates the lock object of the state, only if it doesn't exist yet,
+// with the If-None-Match condition of the upload. Object Storage checks the
+// condition atomically, so at most one client holds the lock.
+func (c *RemoteClient) Lock(info *state.LockInfo) (string, error) {
+	if c.lockStorage == nil {
+		return "", nil
+	}
+
+	info.Path = c.lockPath()
+
+	if info.ID == "" {
+		lockID, err := uuid.GenerateUUID()
+		if err != nil {
+			return "", err
+		}
+
+		info.ID = lockID
+	}
+
+	err := c.lockStorage.Put(c.lockPath(), info.Marshal(), map[string]string{
+		"If-None-Match": "*",
+	})
+	if err != nil {
+		lockErr := &state.LockError{Err: err}
+		if isStatus(err, http.StatusConflict, http.StatusPreconditionFailed) {
+			lockErr.Err = fmt.Errorf("the state is already locked")
+
+			lockInfo, _, infoErr := c.getLockInfo()
+			if infoErr != nil {
+				lockErr.Err = multierror.Append(lockErr.Err, infoErr)
+			}
+			lockErr.Info = lockInfo
+		}
+
+		return "", lockErr
+	}
+
+	return info.ID, nil
+}
+
+// getLockInfo returns the info of the lock object of the state, and its
+// ETag.
+func (c *RemoteClient) getLockInfo() (*state.LockInfo, string, error) {
+	obj, err := c.lockStorage.Get(c.lockPath())
+	if err != nil {
+		return nil, "", err
+	}
+	if obj == nil {
+		return nil, "", fmt.Errorf("the state isn't locked")
+	}
+
+	lockInfo := &state.LockInfo{}
+	if err := json.Unmarshal(obj.Data, lockInfo); err != nil {
+		return nil, "", err
+	}
+
+	return lockInfo, obj.ETag, nil
+}
+
+func (c *RemoteClient) Unlock(id string) error {
+	if c.lockStorage == nil {
+		return nil
+	}
+
+	lockErr := &state.LockError{}
+
+	lockInfo, etag, err := c.getLockInfo()
+	if err != nil {
+		lockErr.Err = fmt.Errorf("failed to retrieve lock info: %s", err)
+		return lockErr
+	}
+	lockErr.Info = lockInfo
+
+	if lockInfo.ID != id {
+		lockErr.Err = fmt.Errorf("lock id %q does not match existing lock", id)
+		return lockErr
+	}
+
+	// The lock is only deleted if it's still the one that was read, in case
+	// it was force-unlocked and taken by someone else in the meantime.
+	if err := c.lockStorage.Delete(c.lockPath(), etag); err != nil {
+		lockErr.Err = err
+		return lockErr
+	}
+	return nil
+}
+
+func (c *RemoteClient) lockPath() string {
+	return c.path + lockSuffix
+}
@@ -0,0 +1,117 @@
+package oci
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+)
+
+func TestRemoteClient_impl(t *testing.T) {
+	var _ remote.Client = new(RemoteClient)
+	var _ remote.ClientLocker = new(RemoteClient)
+}
+
+func TestRemoteClient_fake(t *testing.T) {
+	b, _, closer := testFakeBackend(t, nil)
+	defer closer()
+
+	state, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote.TestClient(t, state.(*remote.State).Client)
+}
+
+func TestRemoteClientLocks_fake(t *testing.T) {
+	b1, _, closer := testFakeBackend(t, nil)
+	defer closer()
+	b2 := testFakeBackendFor(t, b1.storage.endpoint, nil)
+
+	s1, err := b1.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := b2.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote.TestRemoteLocks(t, s1.(*remote.State).Client, s2.(*remote.State).Client)
+}
+
+func TestRemoteClientLocks_lockBucket(t *testing.T) {
+	b, fake, closer := testFakeBackend(t, map[string]interface{}{
+		"lock_bucket": "locks",
+	})
+	defer closer()
+
+	s, err := b.State("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := state.NewLockInfo()
+	info.Operation = "test"
+	id, err := s.Lock(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mu.Lock()
+	_, ok := fake.objects["locks/env:/foo/test/state.tflock"]
+	fake.mu.Unlock()
+	if !ok {
+		t.Fatal("the lock isn't in the lock bucket")
+	}
+
+	if err := s.Unlock(id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoteClientLocks_disabled(t *testing.T) {
+	b, fake, closer := testFakeBackend(t, map[string]interface{}{
+		"lock": false,
+	})
+	defer closer()
+
+	s, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Lock(state.NewLockInfo()); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if _, ok := fake.objects["bucket/test/state.tflock"]; ok {
+		t.Fatal("the state shouldn't be locked")
+	}
+}
+
+func TestRemoteClient(t *testing.T) {
+	testACC(t)
+
+	b := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"bucket":    os.Getenv("OCI_TEST_BUCKET"),
+		"namespace": os.Getenv("OCI_TEST_NAMESPACE"),
+		"key":       fmt.Sprintf("terraform-remote-oci-test-%x/state", time.Now().Unix()),
+	}).(*Backend)
+	defer deleteOCIObjects(t, b)
+
+	state, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote.TestClient(t, state.(*remote.State).Client)
+}
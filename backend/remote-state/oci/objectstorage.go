@@ -0,0 +1,203 @@
+package oci
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// objectStorage is a client of the Object Storage API, for the objects of
+// a single bucket.
+type objectStorage struct {
+	client    *http.Client
+	signer    requestSigner
+	endpoint  string
+	namespace string
+	bucket    string
+}
+
+// objectStorageError is an error response of the Object Storage API.
+type objectStorageError struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *objectStorageError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%s (%d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+// isStatus returns true if err is an error response with one of the given
+// status codes.
+func isStatus(err error, codes ...int) bool {
+	if e, ok := err.(*objectStorageError); ok {
+		for _, code := range codes {
+			if e.StatusCode == code {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// responseError returns the error of a response whose status isn't a
+// success.
+func responseError(resp *http.Response) error {
+	result := &objectStorageError{StatusCode: resp.StatusCode}
+	body, _ := ioutil.ReadAll(resp.Body)
+	json.Unmarshal(body, result)
+	return result
+}
+
+// object is an object read from the bucket.
+type object struct {
+	Data    []byte
+	ETag    string
+	ModTime time.Time
+}
+
+// Get returns the object with the given name, or nil if there isn't one.
+func (s *objectStorage) Get(name string) (*object, error) {
+	resp, err := s.do(http.MethodGet, s.objectPath(name), nil, nil, nil)
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &object{
+		Data: data,
+		ETag: resp.Header.Get("ETag"),
+	}
+	if raw := resp.Header.Get("Last-Modified"); raw != "" {
+		if t, err := http.ParseTime(raw); err == nil {
+			result.ModTime = t
+		}
+	}
+
+	return result, nil
+}
+
+// Put uploads the object with the given name, with the given extra
+// headers, such as If-None-Match.
+func (s *objectStorage) Put(name string, data []byte, headers map[string]string) error {
+	resp, err := s.do(http.MethodPut, s.objectPath(name), nil, headers, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Delete deletes the object with the given name. If etag isn't empty, the
+// object is only deleted if it's still the same.
+func (s *objectStorage) Delete(name, etag string) error {
+	var headers map[string]string
+	if etag != "" {
+		headers = map[string]string{"If-Match": etag}
+	}
+
+	resp, err := s.do(http.MethodDelete, s.objectPath(name), nil, headers, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List returns the names of the objects whose names start with prefix.
+func (s *objectStorage) List(prefix string) ([]string, error) {
+	var names []string
+	start := ""
+	for {
+		query := url.Values{"prefix": {prefix}}
+		if start != "" {
+			query.Set("start", start)
+		}
+
+		resp, err := s.do(http.MethodGet, s.bucketPath()+"/o", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Objects []struct {
+				Name string `json:"name"`
+			} `json:"objects"`
+			NextStartWith string `json:"nextStartWith"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error decoding the objects of bucket %s: %s", s.bucket, err)
+		}
+
+		for _, obj := range result.Objects {
+			names = append(names, obj.Name)
+		}
+
+		if result.NextStartWith == "" {
+			return names, nil
+		}
+		start = result.NextStartWith
+	}
+}
+
+func (s *objectStorage) bucketPath() string {
+	return "/n/" + url.PathEscape(s.namespace) + "/b/" + url.PathEscape(s.bucket)
+}
+
+func (s *objectStorage) objectPath(name string) string {
+	return s.bucketPath() + "/o/" + url.PathEscape(name)
+}
+
+// do sends a signed request to the API, and returns the response if it's a
+// success. The body of the request is only signed if it isn't an object.
+func (s *objectStorage) do(method, path string, query url.Values, headers map[string]string, body []byte) (*http.Response, error) {
+	u, err := url.Parse(s.endpoint + path)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if method == http.MethodPut {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if err := s.signer.Sign(req, body, false); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,203 @@
+package oss
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/aliyun/aliyun-tablestore-go-sdk/tablestore"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// New creates a new backend for OSS remote state.
+func New() backend.Backend {
+	s := &schema.Backend{
+		Schema: map[string]*schema.Schema{
+			"bucket": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The name of the OSS bucket",
+			},
+
+			"key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The path to the state file inside the bucket",
+				Default:     "terraform.tfstate",
+			},
+
+			"env_prefix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The directory in the bucket of the states of environments other than the default",
+				Default:     "env:",
+			},
+
+			"region": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The region of the OSS bucket.",
+				DefaultFunc: schema.EnvDefaultFunc("ALICLOUD_REGION", "cn-beijing"),
+			},
+
+			"endpoint": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "A custom endpoint for the OSS API",
+				DefaultFunc: schema.EnvDefaultFunc("ALICLOUD_OSS_ENDPOINT", ""),
+			},
+
+			"encrypt": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Whether to enable server side encryption of the state file",
+				Default:     false,
+			},
+
+			"acl": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Object ACL to be applied to the state file",
+				Default:     "",
+			},
+
+			"access_key": {
+				Type:        schema.TypeString,
+				Sensitive:   true,
+				Optional:    true,
+				Description: "Alibaba Cloud access key",
+				DefaultFunc: schema.EnvDefaultFunc("ALICLOUD_ACCESS_KEY", ""),
+			},
+
+			"secret_key": {
+				Type:        schema.TypeString,
+				Sensitive:   true,
+				Optional:    true,
+				Description: "Alibaba Cloud secret key",
+				DefaultFunc: schema.EnvDefaultFunc("ALICLOUD_SECRET_KEY", ""),
+			},
+
+			"security_token": {
+				Type:        schema.TypeString,
+				Sensitive:   true,
+				Optional:    true,
+				Description: "STS security token, for temporary credentials",
+				DefaultFunc: schema.EnvDefaultFunc("ALICLOUD_SECURITY_TOKEN", ""),
+			},
+
+			"tablestore_endpoint": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The endpoint of the Tablestore instance for state locking",
+				DefaultFunc: schema.EnvDefaultFunc("ALICLOUD_TABLESTORE_ENDPOINT", ""),
+			},
+
+			"tablestore_table": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Tablestore table for state locking",
+				Default:     "",
+			},
+		},
+	}
+
+	result := &Backend{Backend: s}
+	result.Backend.ConfigureFunc = result.configure
+	return result
+}
+
+type Backend struct {
+	*schema.Backend
+
+	// The fields below are set from configure
+	ossClient *oss.Client
+	otsClient *tablestore.TableStoreClient
+
+	bucketName           string
+	keyName              string
+	envPrefix            string
+	serverSideEncryption bool
+	acl                  string
+	otsTable             string
+}
+
+func (b *Backend) configure(ctx context.Context) error {
+	if b.ossClient != nil {
+		return nil
+	}
+
+	// Grab the resource data
+	data := schema.FromContextBackendConfig(ctx)
+
+	b.bucketName = data.Get("bucket").(string)
+	b.keyName = strings.Trim(data.Get("key").(string), "/")
+	b.envPrefix = strings.Trim(data.Get("env_prefix").(string), "/")
+	b.serverSideEncryption = data.Get("encrypt").(bool)
+	b.acl = data.Get("acl").(string)
+	b.otsTable = data.Get("tablestore_table").(string)
+
+	if b.keyName == "" {
+		return fmt.Errorf("key must not be empty")
+	}
+	if b.envPrefix == "" {
+		return fmt.Errorf("env_prefix must not be empty")
+	}
+
+	accessKey := data.Get("access_key").(string)
+	secretKey := data.Get("secret_key").(string)
+	securityToken := data.Get("security_token").(string)
+
+	endpoint := data.Get("endpoint").(string)
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://oss-%s.aliyuncs.com", data.Get("region").(string))
+	}
+
+	var options []oss.ClientOption
+	if securityToken != "" {
+		options = append(options, oss.SecurityToken(securityToken))
+	}
+
+	client, err := oss.New(endpoint, accessKey, secretKey, options...)
+	if err != nil {
+		return fmt.Errorf("Error creating OSS client: %s", err)
+	}
+	b.ossClient = client
+
+	if b.otsTable != "" {
+		otsEndpoint := data.Get("tablestore_endpoint").(string)
+		if otsEndpoint == "" {
+			return fmt.Errorf("tablestore_endpoint is required to lock with tablestore_table")
+		}
+
+		instance, err := otsInstanceName(otsEndpoint)
+		if err != nil {
+			return err
+		}
+
+		b.otsClient = tablestore.NewClientWithConfig(
+			otsEndpoint, instance, accessKey, secretKey, securityToken, nil)
+	}
+
+	return nil
+}
+
+// otsInstanceName returns the name of the Tablestore instance from its
+// endpoint, which is always the first part of the host name.
+func otsInstanceName(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid tablestore_endpoint %q: %s", endpoint, err)
+	}
+
+	host := u.Hostname()
+	if u.Scheme == "" || host == "" {
+		return "", fmt.Errorf(
+			"invalid tablestore_endpoint %q: must be a URL, such as "+
+				"https://myinstance.cn-beijing.ots.aliyuncs.com", endpoint)
+	}
+
+	return strings.SplitN(host, ".", 2)[0], nil
+}
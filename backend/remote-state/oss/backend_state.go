@@ -0,0 +1,201 @@
+package oss
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func (b *Backend) States() ([]string, error) {
+	bucket, err := b.ossClient.Bucket(b.bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting OSS bucket: %s", err)
+	}
+
+	envs := []string{backend.DefaultStateName}
+	marker := ""
+	for {
+		resp, err := bucket.ListObjects(
+			oss.Prefix(b.envPrefix+"/"), oss.Marker(marker))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range resp.Objects {
+			env := b.keyEnv(obj.Key)
+			if env != "" {
+				envs = append(envs, env)
+			}
+		}
+
+		if !resp.IsTruncated {
+			break
+		}
+		marker = resp.NextMarker
+	}
+
+	sort.Strings(envs[1:])
+	return envs, nil
+}
+
+// extract the env name from the OSS key
+func (b *Backend) keyEnv(key string) string {
+	// shouldn't happen since we listed by prefix
+	prefix := b.envPrefix + "/"
+	if !strings.HasPrefix(key, prefix) {
+		return ""
+	}
+
+	// we have 2 parts after the prefix, the env name and the key name
+	parts := strings.SplitN(strings.TrimPrefix(key, prefix), "/", 2)
+	if len(parts) < 2 {
+		// no env here
+		return ""
+	}
+
+	// not our key, so don't include it in our listing
+	if parts[1] != b.keyName {
+		return ""
+	}
+
+	return parts[0]
+}
+
+func (b *Backend) DeleteState(name string) error {
+	if name == backend.DefaultStateName || name == "" {
+		return fmt.Errorf("can't delete default state")
+	}
+
+	client, err := b.remoteClient(name)
+	if err != nil {
+		return err
+	}
+
+	return client.Delete()
+}
+
+func (b *Backend) State(name string) (state.State, error) {
+	if name == "" {
+		return nil, errors.New("missing state name")
+	}
+
+	client, err := b.remoteClient(name)
+	if err != nil {
+		return nil, err
+	}
+
+	stateMgr := &remote.State{Client: client}
+
+	// Check to see if this state already exists.
+	// If we're trying to force-unlock a state, we can't take the lock before
+	// fetching the state. If the state doesn't exist, we have to assume this
+	// is a normal create operation, and take the lock at that point.
+	existing, err := b.States()
+	if err != nil {
+		return nil, err
+	}
+
+	exists := false
+	for _, s := range existing {
+		if s == name {
+			exists = true
+			break
+		}
+	}
+
+	// We need to create the object so it's listed by States.
+	if !exists {
+		// take a lock on this state while we write it
+		lockInfo := state.NewLockInfo()
+		lockInfo.Operation = "init"
+		lockId, err := client.Lock(lockInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock OSS state: %s", err)
+		}
+
+		// Local helper function so we can call it multiple places
+		lockUnlock := func(parent error) error {
+			if err := stateMgr.Unlock(lockId); err != nil {
+				return fmt.Errorf(strings.TrimSpace(errStateUnlock), lockId, err)
+			}
+			return parent
+		}
+
+		// Grab the value
+		// This is to ensure that no one beat us to writing a state between
+		// the `exists` check and taking the lock.
+		if err := stateMgr.RefreshState(); err != nil {
+			err = lockUnlock(err)
+			return nil, err
+		}
+
+		// If we have no state, we have to create an empty state
+		if v := stateMgr.State(); v == nil {
+			if err := stateMgr.WriteState(terraform.NewState()); err != nil {
+				err = lockUnlock(err)
+				return nil, err
+			}
+			if err := stateMgr.PersistState(); err != nil {
+				err = lockUnlock(err)
+				return nil, err
+			}
+		}
+
+		// Unlock, the state should now be initialized
+		if err := lockUnlock(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return stateMgr, nil
+}
+
+// StateLocker implements backend.StateLocker, locking the state with the
+// given name without creating it.
+func (b *Backend) StateLocker(name string) (state.Locker, error) {
+	if b.otsTable == "" {
+		return nil, backend.ErrLockingNotConfigured
+	}
+
+	return b.remoteClient(name)
+}
+
+func (b *Backend) remoteClient(name string) (*RemoteClient, error) {
+	bucket, err := b.ossClient.Bucket(b.bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting OSS bucket: %s", err)
+	}
+
+	return &RemoteClient{
+		bucket:               bucket,
+		otsClient:            b.otsClient,
+		bucketName:           b.bucketName,
+		path:                 b.path(name),
+		serverSideEncryption: b.serverSideEncryption,
+		acl:                  b.acl,
+		otsTable:             b.otsTable,
+	}, nil
+}
+
+func (b *Backend) path(name string) string {
+	if name == backend.DefaultStateName {
+		return b.keyName
+	}
+
+	return strings.Join([]string{b.envPrefix, name, b.keyName}, "/")
+}
+
+const errStateUnlock = `
+Error unlocking OSS state. Lock ID: %s
+
+Error: %s
+
+You may have to force-unlock this state in order to use it again.
+`
@@ -0,0 +1,251 @@
+package oss
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/aliyun/aliyun-tablestore-go-sdk/tablestore"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// verify that we are doing ACC tests or the OSS tests specifically
+func testACC(t *testing.T) {
+	skip := os.Getenv("TF_ACC") == "" && os.Getenv("TF_OSS_TEST") == ""
+	if skip {
+		t.Log("oss backend tests require setting TF_ACC or TF_OSS_TEST")
+		t.Skip()
+	}
+	if os.Getenv("ALICLOUD_REGION") == "" {
+		os.Setenv("ALICLOUD_REGION", "cn-beijing")
+	}
+}
+
+func TestBackend_impl(t *testing.T) {
+	var _ backend.Backend = new(Backend)
+}
+
+func TestBackendConfig(t *testing.T) {
+	config := map[string]interface{}{
+		"region":              "cn-hangzhou",
+		"bucket":              "tf-test",
+		"key":                 "/state/",
+		"encrypt":             true,
+		"access_key":          "foo",
+		"secret_key":          "bar",
+		"security_token":      "baz",
+		"tablestore_endpoint": "https://tf-lock.cn-hangzhou.ots.aliyuncs.com",
+		"tablestore_table":    "locks",
+	}
+
+	b := backend.TestBackendConfig(t, New(), config).(*Backend)
+
+	if b.ossClient.Config.Endpoint != "https://oss-cn-hangzhou.aliyuncs.com" {
+		t.Fatalf("Incorrect endpoint was populated: %s", b.ossClient.Config.Endpoint)
+	}
+	if b.ossClient.Config.SecurityToken != "baz" {
+		t.Fatalf("Incorrect security token was populated")
+	}
+	if b.bucketName != "tf-test" {
+		t.Fatalf("Incorrect bucketName was populated")
+	}
+	if b.keyName != "state" {
+		t.Fatalf("Incorrect keyName was populated")
+	}
+	if b.envPrefix != "env:" {
+		t.Fatalf("Incorrect envPrefix was populated")
+	}
+	if b.otsClient == nil {
+		t.Fatalf("Tablestore client was not created")
+	}
+}
+
+func TestBackendConfig_tablestoreEndpoint(t *testing.T) {
+	cases := map[string]struct {
+		Endpoint string
+		Instance string
+		Err      bool
+	}{
+		"valid": {
+			"https://tf-lock.cn-hangzhou.ots.aliyuncs.com",
+			"tf-lock",
+			false,
+		},
+		"no scheme": {
+			"tf-lock.cn-hangzhou.ots.aliyuncs.com",
+			"",
+			true,
+		},
+	}
+
+	for name, tc := range cases {
+		instance, err := otsInstanceName(tc.Endpoint)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if instance != tc.Instance {
+			t.Fatalf("%s: bad: %s", name, instance)
+		}
+	}
+
+	// The endpoint is required to lock
+	rawConfig, err := config.NewRawConfig(map[string]interface{}{
+		"bucket":           "tf-test",
+		"tablestore_table": "locks",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	os.Unsetenv("ALICLOUD_TABLESTORE_ENDPOINT")
+	err = New().Configure(terraform.NewResourceConfig(rawConfig))
+	if err == nil || !strings.Contains(err.Error(), "tablestore_endpoint") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestBackendKeyEnv(t *testing.T) {
+	b := &Backend{
+		keyName:   "test/state",
+		envPrefix: "tf/env",
+	}
+
+	cases := map[string]string{
+		"tf/env/foo/test/state":  "foo",
+		"tf/env/foo/other/state": "",
+		"tf/env/test/state":      "",
+		"env:/foo/test/state":    "",
+		"test/state":             "",
+	}
+
+	for key, expected := range cases {
+		if actual := b.keyEnv(key); actual != expected {
+			t.Fatalf("%s: expected %q, got %q", key, expected, actual)
+		}
+	}
+
+	if path := b.path("foo"); path != "tf/env/foo/test/state" {
+		t.Fatalf("bad: %s", path)
+	}
+	if path := b.path(backend.DefaultStateName); path != "test/state" {
+		t.Fatalf("bad: %s", path)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	testACC(t)
+
+	bucketName := fmt.Sprintf("terraform-remote-oss-test-%x", time.Now().Unix())
+	keyName := "testState"
+
+	b := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"bucket":  bucketName,
+		"key":     keyName,
+		"encrypt": true,
+	}).(*Backend)
+
+	createOSSBucket(t, b.ossClient, bucketName)
+	defer deleteOSSBucket(t, b.ossClient, bucketName)
+
+	backend.TestBackend(t, b, nil)
+}
+
+func TestBackendLocked(t *testing.T) {
+	testACC(t)
+
+	bucketName := fmt.Sprintf("terraform-remote-oss-test-%x", time.Now().Unix())
+	tableName := strings.Replace(bucketName, "-", "_", -1)
+	keyName := "test/state"
+
+	b1 := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"bucket":           bucketName,
+		"key":              keyName,
+		"encrypt":          true,
+		"tablestore_table": tableName,
+	}).(*Backend)
+
+	b2 := backend.TestBackendConfig(t, New(), map[string]interface{}{
+		"bucket":           bucketName,
+		"key":              keyName,
+		"encrypt":          true,
+		"tablestore_table": tableName,
+	}).(*Backend)
+
+	createOSSBucket(t, b1.ossClient, bucketName)
+	defer deleteOSSBucket(t, b1.ossClient, bucketName)
+	createTablestoreTable(t, b1.otsClient, tableName)
+	defer deleteTablestoreTable(t, b1.otsClient, tableName)
+
+	backend.TestBackend(t, b1, b2)
+}
+
+func createOSSBucket(t *testing.T, ossClient *oss.Client, bucketName string) {
+	// Be clear about what we're doing in case the user needs to clean
+	// this up later.
+	t.Logf("creating OSS bucket %s at %s", bucketName, ossClient.Config.Endpoint)
+	if err := ossClient.CreateBucket(bucketName); err != nil {
+		t.Fatal("failed to create test OSS bucket:", err)
+	}
+}
+
+func deleteOSSBucket(t *testing.T, ossClient *oss.Client, bucketName string) {
+	warning := "WARNING: Failed to delete the test OSS bucket. It may have been left in your Alibaba Cloud account and may incur storage charges. (error was %s)"
+
+	// first we have to get rid of the env objects, or we can't delete the bucket
+	bucket, err := ossClient.Bucket(bucketName)
+	if err != nil {
+		t.Logf(warning, err)
+		return
+	}
+	resp, err := bucket.ListObjects()
+	if err != nil {
+		t.Logf(warning, err)
+		return
+	}
+	for _, obj := range resp.Objects {
+		if err := bucket.DeleteObject(obj.Key); err != nil {
+			// this will need cleanup no matter what, so just warn and exit
+			t.Logf(warning, err)
+			return
+		}
+	}
+
+	if err := ossClient.DeleteBucket(bucketName); err != nil {
+		t.Logf(warning, err)
+	}
+}
+
+func createTablestoreTable(t *testing.T, otsClient *tablestore.TableStoreClient, tableName string) {
+	meta := &tablestore.TableMeta{TableName: tableName}
+	meta.AddPrimaryKeyColumn(otsPKName, tablestore.PrimaryKeyType_STRING)
+
+	_, err := otsClient.CreateTable(&tablestore.CreateTableRequest{
+		TableMeta:          meta,
+		TableOption:        tablestore.NewTableOption(-1, 1),
+		ReservedThroughput: &tablestore.ReservedThroughput{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The table takes a little while to be ready to use
+	time.Sleep(30 * time.Second)
+}
+
+func deleteTablestoreTable(t *testing.T, otsClient *tablestore.TableStoreClient, tableName string) {
+	_, err := otsClient.DeleteTable(&tablestore.DeleteTableRequest{TableName: tableName})
+	if err != nil {
+		t.Logf("WARNING: Failed to delete the test Tablestore table %q. It has been left in your Alibaba Cloud account and may incur charges. (error was %s)", tableName, err)
+	}
+}
+
+func TestBackendStateLocker_notConfigured(t *testing.T) {
+	if _, err := new(Backend).StateLocker(backend.DefaultStateName); err != backend.ErrLockingNotConfigured {
+		t.Fatalf("bad: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package oss
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/aliyun/aliyun-tablestore-go-sdk/tablestore"
+	multierror "github.com/hashicorp/go-multierror"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+)
+
+// The names of the primary key and the column of the lock table.
+const (
+	otsPKName     = "LockID"
+	otsColumnName = "Info"
+)
+
+type RemoteClient struct {
+	bucket               *oss.Bucket
+	otsClient            *tablestore.TableStoreClient
+	bucketName           string
+	path                 string
+	serverSideEncryption bool
+	acl                  string
+	otsTable             string
+}
+
+func (c *RemoteClient) Get() (*remote.Payload, error) {
+	result, err := c.bucket.DoGetObject(&oss.GetObjectRequest{ObjectKey: c.path}, nil)
+	if err != nil {
+		if serr, ok := err.(oss.ServiceError); ok && serr.Code == "NoSuchKey" {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+	defer result.Response.Close()
+
+	buf := bytes.NewBuffer(nil)
+	if _, err := io.Copy(buf, result.Response); err != nil {
+		return nil, fmt.Errorf("Failed to read remote state: %s", err)
+	}
+
+	// The state is uploaded with its MD5, which OSS returns with it.
+	payload := &remote.Payload{Data: buf.Bytes()}
+	if raw := result.Response.Headers.Get(oss.HTTPHeaderContentMD5); raw != "" {
+		sum, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid checksum of remote state %q: %s", raw, err)
+		}
+		payload.MD5 = sum
+	}
+	if raw := result.Response.Headers.Get(oss.HTTPHeaderLastModified); raw != "" {
+		if t, err := http.ParseTime(raw); err == nil {
+			payload.ModTime = t
+		}
+	}
+
+	// If there was no data, then return nil
+	if len(payload.Data) == 0 {
+		return nil, nil
+	}
+
+	return payload, nil
+}
+
+func (c *RemoteClient) Put(data []byte) error {
+	sum := md5.Sum(data)
+	options := []oss.Option{
+		oss.ContentType("application/json"),
+		oss.ContentMD5(base64.StdEncoding.EncodeToString(sum[:])),
+	}
+
+	if c.serverSideEncryption {
+		options = append(options, oss.ServerSideEncryption("AES256"))
+	}
+
+	if c.acl != "" {
+		options = append(options, oss.ObjectACL(oss.ACLType(c.acl)))
+	}
+
+	log.Printf("[DEBUG] Uploading remote state to OSS: %s/%s", c.bucketName, c.path)
+
+	if err := c.bucket.PutObject(c.path, bytes.NewReader(data), options...); err != nil {
+		return fmt.Errorf("Failed to upload state: %v", err)
+	}
+
+	return nil
+}
+
+func (c *RemoteClient) Delete() error {
+	return c.bucket.DeleteObject(c.path)
+}
+
+func (c *RemoteClient) Lock(info *state.LockInfo) (string, error) {
+	if c.otsTable == "" {
+		return "", nil
+	}
+
+	info.Path = c.lockPath()
+
+	if info.ID == "" {
+		lockID, err := uuid.GenerateUUID()
+		if err != nil {
+			return "", err
+		}
+
+		info.ID = lockID
+	}
+
+	change := &tablestore.PutRowChange{
+		TableName:  c.otsTable,
+		PrimaryKey: c.lockPK(),
+	}
+	change.AddColumn(otsColumnName, string(info.Marshal()))
+	change.SetCondition(tablestore.RowExistenceExpectation_EXPECT_NOT_EXIST)
+
+	_, err := c.otsClient.PutRow(&tablestore.PutRowRequest{PutRowChange: change})
+	if err != nil {
+		lockInfo, infoErr := c.getLockInfo()
+		if infoErr != nil {
+			err = multierror.Append(err, infoErr)
+		}
+
+		lockErr := &state.LockError{
+			Err:  err,
+			Info: lockInfo,
+		}
+		return "", lockErr
+	}
+
+	return info.ID, nil
+}
+
+func (c *RemoteClient) getLockInfo() (*state.LockInfo, error) {
+	criteria := &tablestore.SingleRowQueryCriteria{
+		TableName:    c.otsTable,
+		PrimaryKey:   c.lockPK(),
+		ColumnsToGet: []string{otsColumnName},
+		MaxVersion:   1,
+	}
+
+	resp, err := c.otsClient.GetRow(&tablestore.GetRowRequest{
+		SingleRowQueryCriteria: criteria,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var infoData string
+	for _, col := range resp.Columns {
+		if col.ColumnName == otsColumnName {
+			infoData, _ = col.Value.(string)
+		}
+	}
+
+	lockInfo := &state.LockInfo{}
+	err = json.Unmarshal([]byte(infoData), lockInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return lockInfo, nil
+}
+
+func (c *RemoteClient) Unlock(id string) error {
+	if c.otsTable == "" {
+		return nil
+	}
+
+	lockErr := &state.LockError{}
+
+	lockInfo, err := c.getLockInfo()
+	if err != nil {
+		lockErr.Err = fmt.Errorf("failed to retrieve lock info: %s", err)
+		return lockErr
+	}
+	lockErr.Info = lockInfo
+
+	if lockInfo.ID != id {
+		lockErr.Err = fmt.Errorf("lock id %q does not match existing lock", id)
+		return lockErr
+	}
+
+	change := &tablestore.DeleteRowChange{
+		TableName:  c.otsTable,
+		PrimaryKey: c.lockPK(),
+	}
+	change.SetCondition(tablestore.RowExistenceExpectation_EXPECT_EXIST)
+
+	_, err = c.otsClient.DeleteRow(&tablestore.DeleteRowRequest{DeleteRowChange: change})
+	if err != nil {
+		lockErr.Err = err
+		return lockErr
+	}
+	return nil
+}
+
+func (c *RemoteClient) lockPK() *tablestore.PrimaryKey {
+	pk := &tablestore.PrimaryKey{}
+	pk.AddPrimaryKeyColumn(otsPKName, c.lockPath())
+	return pk
+}
+
+func (c *RemoteClient) lockPath() string {
+	return fmt.Sprintf("%s/%s", c.bucketName, c.path)
+}
@@ -57,6 +57,7 @@ func New() backend.Backend {
 
 			"access_key": {
 				Type:        schema.TypeString,
+				Sensitive:   true,
 				Optional:    true,
 				Description: "AWS access key",
 				Default:     "",
@@ -64,6 +65,7 @@ func New() backend.Backend {
 
 			"secret_key": {
 				Type:        schema.TypeString,
+				Sensitive:   true,
 				Optional:    true,
 				Description: "AWS secret key",
 				Default:     "",
@@ -107,6 +109,7 @@ func New() backend.Backend {
 
 			"token": {
 				Type:        schema.TypeString,
+				Sensitive:   true,
 				Optional:    true,
 				Description: "MFA token",
 				Default:     "",
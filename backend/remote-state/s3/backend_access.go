@@ -0,0 +1,46 @@
+package s3
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/backend"
+)
+
+func (b *Backend) AccessHint(name, step string) string {
+	key := fmt.Sprintf("arn:aws:s3:::%s/%s", b.bucketName, b.path(name))
+	table := fmt.Sprintf("the DynamoDB table %q", b.ddbTable)
+
+	var hint string
+	switch step {
+	case backend.AccessRead:
+		hint = fmt.Sprintf(
+			"The credentials need s3:ListBucket on arn:aws:s3:::%s and "+
+				"s3:GetObject on %s.", b.bucketName, key)
+		if b.ddbTable != "" {
+			hint += fmt.Sprintf(" They also need dynamodb:GetItem on %s, "+
+				"where the digest of the state is stored.", table)
+		}
+	case backend.AccessLock:
+		if b.ddbTable == "" {
+			return ""
+		}
+		return fmt.Sprintf(
+			"The credentials need dynamodb:GetItem, dynamodb:PutItem and "+
+				"dynamodb:DeleteItem on %s.", table)
+	case backend.AccessWrite:
+		hint = fmt.Sprintf("The credentials need s3:PutObject on %s.", key)
+		if b.ddbTable != "" {
+			hint += fmt.Sprintf(" They also need dynamodb:PutItem on %s.", table)
+		}
+	default:
+		return ""
+	}
+
+	if b.kmsKeyID != "" {
+		hint += fmt.Sprintf(" The state is encrypted with the KMS key %q, "+
+			"so they need kms:Decrypt and kms:GenerateDataKey on it too.",
+			b.kmsKeyID)
+	}
+
+	return hint
+}
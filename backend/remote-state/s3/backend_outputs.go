@@ -0,0 +1,45 @@
+package s3
+
+import (
+	"errors"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// outputsSuffix is appended to the key of a state to get the key where
+// its published outputs are stored.
+const outputsSuffix = ".outputs"
+
+// PublishOutputs implements backend.OutputPublisher.
+func (b *Backend) PublishOutputs(name string, outputs map[string]*terraform.OutputState) error {
+	if name == "" {
+		return errors.New("missing state name")
+	}
+
+	return backend.PublishOutputsToClient(b.outputsClient(name), outputs)
+}
+
+// PublishedOutputs implements backend.OutputPublisher.
+func (b *Backend) PublishedOutputs(name string) (map[string]*terraform.OutputState, error) {
+	if name == "" {
+		return nil, errors.New("missing state name")
+	}
+
+	return backend.PublishedOutputsFromClient(b.outputsClient(name))
+}
+
+// outputsClient returns a client for the published outputs of the named
+// state. The outputs are a separate object so that they can be given
+// different permissions than the state. They aren't locked or checksummed
+// in DynamoDB since they are only ever replaced as a whole.
+func (b *Backend) outputsClient(name string) *RemoteClient {
+	return &RemoteClient{
+		s3Client:             b.s3Client,
+		bucketName:           b.bucketName,
+		path:                 b.path(name) + outputsSuffix,
+		serverSideEncryption: b.serverSideEncryption,
+		acl:                  b.acl,
+		kmsKeyID:             b.kmsKeyID,
+	}
+}
@@ -80,6 +80,11 @@ func (b *Backend) DeleteState(name string) error {
 		return err
 	}
 
+	// Delete any published outputs along with it
+	if err := b.outputsClient(name).Delete(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -170,6 +175,24 @@ func (b *Backend) State(name string) (state.State, error) {
 	return stateMgr, nil
 }
 
+// StateLocker implements backend.StateLocker, locking the state with the
+// given name without creating it.
+func (b *Backend) StateLocker(name string) (state.Locker, error) {
+	if name == "" {
+		return nil, errors.New("missing state name")
+	}
+	if b.ddbTable == "" {
+		return nil, backend.ErrLockingNotConfigured
+	}
+
+	return &RemoteClient{
+		dynClient:  b.dynClient,
+		bucketName: b.bucketName,
+		path:       b.path(name),
+		ddbTable:   b.ddbTable,
+	}, nil
+}
+
 func (b *Backend) client() *RemoteClient {
 	return &RemoteClient{}
 }
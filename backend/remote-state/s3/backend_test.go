@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -29,6 +30,29 @@ func testACC(t *testing.T) {
 
 func TestBackend_impl(t *testing.T) {
 	var _ backend.Backend = new(Backend)
+	var _ backend.AccessHinter = new(Backend)
+}
+
+func TestBackend_accessHint(t *testing.T) {
+	b := &Backend{bucketName: "tf-state", keyName: "app/terraform.tfstate"}
+	hint := b.AccessHint("staging", backend.AccessWrite)
+	if !strings.Contains(hint, "s3:PutObject on arn:aws:s3:::tf-state/env:/staging/app/terraform.tfstate") {
+		t.Fatalf("bad: %s", hint)
+	}
+	if strings.Contains(hint, "dynamodb") {
+		t.Fatalf("hint shouldn't mention DynamoDB without a lock table: %s", hint)
+	}
+
+	// Without a lock table there's nothing to lock with
+	if hint := b.AccessHint(backend.DefaultStateName, backend.AccessLock); hint != "" {
+		t.Fatalf("bad: %s", hint)
+	}
+
+	b.ddbTable = "tf-locks"
+	hint = b.AccessHint(backend.DefaultStateName, backend.AccessLock)
+	if !strings.Contains(hint, "dynamodb:PutItem") || !strings.Contains(hint, `"tf-locks"`) {
+		t.Fatalf("bad: %s", hint)
+	}
 }
 
 func TestBackendConfig(t *testing.T) {
@@ -337,3 +361,9 @@ func deleteDynamoDBTable(t *testing.T, dynClient *dynamodb.DynamoDB, tableName s
 		t.Logf("WARNING: Failed to delete the test DynamoDB table %q. It has been left in your AWS account and may incur charges. (error was %s)", tableName, err)
 	}
 }
+
+func TestBackendStateLocker_notConfigured(t *testing.T) {
+	if _, err := new(Backend).StateLocker(backend.DefaultStateName); err != backend.ErrLockingNotConfigured {
+		t.Fatalf("bad: %v", err)
+	}
+}
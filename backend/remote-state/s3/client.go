@@ -121,6 +121,9 @@ func (c *RemoteClient) get() (*remote.Payload, error) {
 		Data: buf.Bytes(),
 		MD5:  sum[:],
 	}
+	if output.LastModified != nil {
+		payload.ModTime = *output.LastModified
+	}
 
 	// If there was no data, then return nil
 	if len(payload.Data) == 0 {
@@ -131,7 +134,29 @@ func (c *RemoteClient) get() (*remote.Payload, error) {
 }
 
 func (c *RemoteClient) Put(data []byte) error {
-	contentType := "application/json"
+	i := c.putObjectInput(c.path, "application/json", data)
+
+	log.Printf("[DEBUG] Uploading remote state to S3: %#v", i)
+
+	_, err := c.s3Client.PutObject(i)
+	if err != nil {
+		return fmt.Errorf("Failed to upload state: %v", err)
+	}
+
+	sum := md5.Sum(data)
+	if err := c.putMD5(sum[:]); err != nil {
+		// if this errors out, we unfortunately have to error out altogether,
+		// since the next Get will inevitably fail.
+		return fmt.Errorf("failed to store state MD5: %s", err)
+
+	}
+
+	return nil
+}
+
+// putObjectInput returns the input to store data with the given key, with
+// the encryption and ACL of the state.
+func (c *RemoteClient) putObjectInput(key, contentType string, data []byte) *s3.PutObjectInput {
 	contentLength := int64(len(data))
 
 	i := &s3.PutObjectInput{
@@ -139,7 +164,7 @@ func (c *RemoteClient) Put(data []byte) error {
 		ContentLength: &contentLength,
 		Body:          bytes.NewReader(data),
 		Bucket:        &c.bucketName,
-		Key:           &c.path,
+		Key:           &key,
 	}
 
 	if c.serverSideEncryption {
@@ -155,22 +180,108 @@ func (c *RemoteClient) Put(data []byte) error {
 		i.ACL = aws.String(c.acl)
 	}
 
-	log.Printf("[DEBUG] Uploading remote state to S3: %#v", i)
+	return i
+}
 
-	_, err := c.s3Client.PutObject(i)
+// blobPath is the key of the blob with the given ID. Blobs are stored
+// under the key of the state, so they aren't listed as workspaces.
+func (c *RemoteClient) blobPath(id string) string {
+	return c.path + ".blobs/" + id
+}
+
+func (c *RemoteClient) PutBlob(id string, data []byte) error {
+	_, err := c.s3Client.PutObject(
+		c.putObjectInput(c.blobPath(id), "application/octet-stream", data))
 	if err != nil {
-		return fmt.Errorf("Failed to upload state: %v", err)
+		return fmt.Errorf("Failed to upload blob: %v", err)
 	}
 
-	sum := md5.Sum(data)
-	if err := c.putMD5(sum[:]); err != nil {
-		// if this errors out, we unfortunately have to error out altogether,
-		// since the next Get will inevitably fail.
-		return fmt.Errorf("failed to store state MD5: %s", err)
+	return nil
+}
+
+func (c *RemoteClient) GetBlob(id string) ([]byte, error) {
+	output, err := c.s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: &c.bucketName,
+		Key:    aws.String(c.blobPath(id)),
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "NoSuchKey" {
+		return nil, remote.ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
 
+	buf := bytes.NewBuffer(nil)
+	if _, err := io.Copy(buf, output.Body); err != nil {
+		return nil, fmt.Errorf("Failed to read blob %s: %s", id, err)
 	}
 
-	return nil
+	return buf.Bytes(), nil
+}
+
+func (c *RemoteClient) DeleteBlob(id string) error {
+	_, err := c.s3Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: &c.bucketName,
+		Key:    aws.String(c.blobPath(id)),
+	})
+
+	return err
+}
+
+// Versions lists the previous versions of the state. There are only
+// previous versions when versioning is enabled on the bucket.
+func (c *RemoteClient) Versions() ([]*remote.Version, error) {
+	var versions []*remote.Version
+	err := c.s3Client.ListObjectVersionsPages(&s3.ListObjectVersionsInput{
+		Bucket: &c.bucketName,
+		Prefix: &c.path,
+	}, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		for _, v := range page.Versions {
+			// Skip other objects with the same prefix, the current
+			// version, and objects stored without versioning.
+			if aws.StringValue(v.Key) != c.path || aws.BoolValue(v.IsLatest) ||
+				aws.StringValue(v.VersionId) == "null" {
+				continue
+			}
+
+			versions = append(versions, &remote.Version{
+				ID:      aws.StringValue(v.VersionId),
+				ModTime: aws.TimeValue(v.LastModified),
+			})
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (c *RemoteClient) GetVersion(id string) (*remote.Payload, error) {
+	output, err := c.s3Client.GetObject(&s3.GetObjectInput{
+		Bucket:    &c.bucketName,
+		Key:       &c.path,
+		VersionId: aws.String(id),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	buf := bytes.NewBuffer(nil)
+	if _, err := io.Copy(buf, output.Body); err != nil {
+		return nil, fmt.Errorf("Failed to read version %s of the remote state: %s", id, err)
+	}
+
+	sum := md5.Sum(buf.Bytes())
+	return &remote.Payload{
+		Data:    buf.Bytes(),
+		MD5:     sum[:],
+		ModTime: aws.TimeValue(output.LastModified),
+	}, nil
 }
 
 func (c *RemoteClient) Delete() error {
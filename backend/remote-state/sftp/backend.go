@@ -0,0 +1,250 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/pkg/sftp"
+	sshagent "github.com/xanzy/ssh-agent"
+	"golang.org/x/crypto/ssh"
+)
+
+// New creates a new backend for SFTP remote state.
+func New() backend.Backend {
+	s := &schema.Backend{
+		Schema: map[string]*schema.Schema{
+			"host": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The host of the SSH server to store the state on",
+			},
+
+			"port": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "The port of the SSH server",
+				Default:     22,
+			},
+
+			"user": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The user to log in as",
+			},
+
+			"password": {
+				Type:        schema.TypeString,
+				Sensitive:   true,
+				Optional:    true,
+				Description: "The password of the user",
+				DefaultFunc: schema.EnvDefaultFunc("SFTP_PASSWORD", ""),
+			},
+
+			"private_key": {
+				Type:        schema.TypeString,
+				Sensitive:   true,
+				Optional:    true,
+				Description: "The contents of an unencrypted private key to log in with",
+				DefaultFunc: schema.EnvDefaultFunc("SFTP_PRIVATE_KEY", ""),
+			},
+
+			"agent": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Log in with the keys of the running SSH agent",
+				Default:     false,
+			},
+
+			"host_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The public key of the host, in the authorized_keys format, to verify it with",
+			},
+
+			"insecure_ignore_host_key": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Connect without verifying the host, if host_key isn't set",
+				Default:     false,
+			},
+
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The directory on the host to store the state in",
+			},
+
+			"key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The name of the state file in the directory",
+				Default:     "terraform.tfstate",
+			},
+
+			"workspace_dir": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The directory in path of the states of workspaces other than the default",
+				Default:     "terraform.tfstate.d",
+			},
+
+			"timeout": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "How long to wait for the SSH connection",
+				Default:     "30s",
+			},
+		},
+	}
+
+	result := &Backend{Backend: s}
+	result.Backend.ConfigureFunc = result.configure
+	return result
+}
+
+type Backend struct {
+	*schema.Backend
+
+	// The fields below are set from configure
+	sftpClient *sftp.Client
+	extensions *extensionClient
+
+	dir          string
+	keyName      string
+	workspaceDir string
+}
+
+func (b *Backend) configure(ctx context.Context) error {
+	if b.sftpClient != nil {
+		return nil
+	}
+
+	// Grab the resource data
+	data := schema.FromContextBackendConfig(ctx)
+
+	b.dir = path.Clean(data.Get("path").(string))
+	b.keyName = data.Get("key").(string)
+	b.workspaceDir = strings.Trim(data.Get("workspace_dir").(string), "/")
+
+	if b.keyName == "" || strings.Contains(b.keyName, "/") {
+		return fmt.Errorf("key must be a file name")
+	}
+	if b.workspaceDir == "" {
+		return fmt.Errorf("workspace_dir must not be empty")
+	}
+
+	timeout, err := time.ParseDuration(data.Get("timeout").(string))
+	if err != nil {
+		return fmt.Errorf("invalid timeout: %s", err)
+	}
+
+	conf, err := sshClientConfig(data)
+	if err != nil {
+		return err
+	}
+	conf.Timeout = timeout
+
+	addr := net.JoinHostPort(data.Get("host").(string), strconv.Itoa(data.Get("port").(int)))
+	log.Printf("[DEBUG] Connecting to SFTP server %s as %s", addr, conf.User)
+	conn, err := ssh.Dial("tcp", addr, conf)
+	if err != nil {
+		return fmt.Errorf("Error connecting to SSH server %s: %s", addr, err)
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("Error starting SFTP session on %s: %s", addr, err)
+	}
+	b.sftpClient = client
+
+	// Without the extensions the state is replaced through the backup
+	if b.extensions, err = newExtensionClient(conn); err != nil {
+		log.Printf("[WARN] Error starting SFTP session for extensions on %s: %s", addr, err)
+	}
+
+	return nil
+}
+
+// sshClientConfig returns the configuration of the SSH client, with the
+// authentication methods that are configured and the verification of the
+// host key.
+func sshClientConfig(data *schema.ResourceData) (*ssh.ClientConfig, error) {
+	conf := &ssh.ClientConfig{
+		User: data.Get("user").(string),
+	}
+
+	if v := data.Get("private_key").(string); v != "" {
+		block, _ := pem.Decode([]byte(v))
+		if block == nil {
+			return nil, fmt.Errorf("Failed to read private_key: no key found")
+		}
+		if block.Headers["Proc-Type"] == "4,ENCRYPTED" {
+			return nil, fmt.Errorf(
+				"Failed to read private_key: password protected keys are not " +
+					"supported. Use the agent to log in with them instead.")
+		}
+
+		signer, err := ssh.ParsePrivateKey([]byte(v))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse private_key: %s", err)
+		}
+		conf.Auth = append(conf.Auth, ssh.PublicKeys(signer))
+	}
+
+	if data.Get("agent").(bool) {
+		agent, _, err := sshagent.New()
+		if err != nil {
+			return nil, fmt.Errorf("Error connecting to SSH agent: %s", err)
+		}
+		conf.Auth = append(conf.Auth, ssh.PublicKeysCallback(agent.Signers))
+	}
+
+	if v := data.Get("password").(string); v != "" {
+		conf.Auth = append(conf.Auth, ssh.Password(v))
+	}
+
+	if len(conf.Auth) == 0 {
+		return nil, fmt.Errorf("one of password, private_key or agent is required")
+	}
+
+	v := data.Get("host_key").(string)
+	if v == "" {
+		if !data.Get("insecure_ignore_host_key").(bool) {
+			return nil, fmt.Errorf(
+				"host_key is required to verify the host. To connect without " +
+					"verifying it, set insecure_ignore_host_key to true.")
+		}
+
+		log.Printf("[WARN] SFTP backend: insecure_ignore_host_key is set, so the host isn't verified")
+		conf.HostKeyCallback = func(string, net.Addr, ssh.PublicKey) error {
+			return nil
+		}
+		return conf, nil
+	}
+
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(v))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse host_key: %s", err)
+	}
+	conf.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if !bytes.Equal(key.Marshal(), hostKey.Marshal()) {
+			return fmt.Errorf(
+				"host key of %s doesn't match host_key: got %s %s",
+				hostname, key.Type(), ssh.FingerprintSHA256(key))
+		}
+		return nil
+	}
+
+	return conf, nil
+}
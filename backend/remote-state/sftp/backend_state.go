@@ -0,0 +1,172 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func (b *Backend) States() ([]string, error) {
+	envs := []string{backend.DefaultStateName}
+
+	entries, err := b.sftpClient.ReadDir(path.Join(b.dir, b.workspaceDir))
+	if err != nil {
+		if isNotExist(err) {
+			return envs, nil
+		}
+		return nil, fmt.Errorf("Error listing workspaces: %s", err)
+	}
+
+	for _, fi := range entries {
+		if !fi.IsDir() {
+			continue
+		}
+
+		// Only the directories with a state are workspaces
+		if _, err := b.sftpClient.Stat(b.path(fi.Name())); err == nil {
+			envs = append(envs, fi.Name())
+		}
+	}
+
+	sort.Strings(envs[1:])
+	return envs, nil
+}
+
+func (b *Backend) DeleteState(name string) error {
+	if name == backend.DefaultStateName || name == "" {
+		return fmt.Errorf("can't delete default state")
+	}
+
+	client, err := b.remoteClient(name)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Delete(); err != nil {
+		return err
+	}
+
+	// The directory of the workspace is left if it has other files
+	if err := b.sftpClient.Remove(path.Dir(client.path)); err != nil && !isNotExist(err) {
+		return fmt.Errorf("Error removing workspace directory: %s", err)
+	}
+
+	return nil
+}
+
+func (b *Backend) State(name string) (state.State, error) {
+	if name == "" {
+		return nil, errors.New("missing state name")
+	}
+
+	client, err := b.remoteClient(name)
+	if err != nil {
+		return nil, err
+	}
+
+	stateMgr := &remote.State{Client: client}
+
+	// Check to see if this state already exists.
+	// If we're trying to force-unlock a state, we can't take the lock before
+	// fetching the state. If the state doesn't exist, we have to assume this
+	// is a normal create operation, and take the lock at that point.
+	existing, err := b.States()
+	if err != nil {
+		return nil, err
+	}
+
+	exists := false
+	for _, s := range existing {
+		if s == name {
+			exists = true
+			break
+		}
+	}
+
+	// We need to create the file so it's listed by States.
+	if !exists {
+		// take a lock on this state while we write it
+		lockInfo := state.NewLockInfo()
+		lockInfo.Operation = "init"
+		lockId, err := client.Lock(lockInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock SFTP state: %s", err)
+		}
+
+		// Local helper function so we can call it multiple places
+		lockUnlock := func(parent error) error {
+			if err := stateMgr.Unlock(lockId); err != nil {
+				return fmt.Errorf(strings.TrimSpace(errStateUnlock), lockId, err)
+			}
+			return parent
+		}
+
+		// Grab the value
+		// This is to ensure that no one beat us to writing a state between
+		// the `exists` check and taking the lock.
+		if err := stateMgr.RefreshState(); err != nil {
+			err = lockUnlock(err)
+			return nil, err
+		}
+
+		// If we have no state, we have to create an empty state
+		if v := stateMgr.State(); v == nil {
+			if err := stateMgr.WriteState(terraform.NewState()); err != nil {
+				err = lockUnlock(err)
+				return nil, err
+			}
+			if err := stateMgr.PersistState(); err != nil {
+				err = lockUnlock(err)
+				return nil, err
+			}
+		}
+
+		// Unlock, the state should now be initialized
+		if err := lockUnlock(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return stateMgr, nil
+}
+
+// StateLocker implements backend.StateLocker, locking the state with the
+// given name without creating it.
+func (b *Backend) StateLocker(name string) (state.Locker, error) {
+	return b.remoteClient(name)
+}
+
+func (b *Backend) remoteClient(name string) (*RemoteClient, error) {
+	if strings.Contains(name, "/") || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid workspace name %q", name)
+	}
+
+	return &RemoteClient{
+		client:     b.sftpClient,
+		extensions: b.extensions,
+		path:       b.path(name),
+	}, nil
+}
+
+func (b *Backend) path(name string) string {
+	if name == backend.DefaultStateName {
+		return path.Join(b.dir, b.keyName)
+	}
+
+	return path.Join(b.dir, b.workspaceDir, name, b.keyName)
+}
+
+const errStateUnlock = `
+Error unlocking SFTP state. Lock ID: %s
+
+Error: %s
+
+You may have to force-unlock this state in order to use it again.
+`
@@ -0,0 +1,319 @@
+package sftp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestBackend_impl(t *testing.T) {
+	var _ backend.Backend = new(Backend)
+}
+
+// testServer starts an SSH server that serves SFTP on the local file
+// system, for the user "user" with the password "pass". It returns the
+// port of the server and its host key in the authorized_keys format.
+func testServer(t *testing.T) (int, string) {
+	return testServerExtensions(t, false)
+}
+
+// testServerExtensions starts a server like testServer does, which also
+// supports the OpenSSH hardlink and posix-rename extensions if extensions
+// is true.
+func testServerExtensions(t *testing.T, extensions bool) (int, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == "user" && string(pass) == "pass" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("password rejected for %s", c.User())
+		},
+	}
+	conf.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		defer l.Close()
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go testServeConn(c, conf, extensions)
+		}
+	}()
+
+	hostKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	return l.Addr().(*net.TCPAddr).Port, hostKey
+}
+
+func testServeConn(c net.Conn, conf *ssh.ServerConfig, extensions bool) {
+	defer c.Close()
+	_, chans, reqs, err := ssh.NewServerConn(c, conf)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(in <-chan *ssh.Request) {
+			for req := range in {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+			}
+		}(requests)
+
+		go func() {
+			defer channel.Close()
+			if extensions {
+				testServeExtensions(channel)
+				return
+			}
+
+			server, err := sftp.NewServer(channel)
+			if err != nil {
+				return
+			}
+			server.Serve()
+		}()
+	}
+}
+
+// testServeExtensions serves SFTP on channel, handling the hardlink and
+// posix-rename extensions itself and passing everything else on to the
+// SFTP server, whose version packet is amended to list them.
+func testServeExtensions(channel ssh.Channel) {
+	toServer, toServerW := io.Pipe()
+	fromServer, fromServerW := io.Pipe()
+	defer toServerW.Close()
+
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{toServer, fromServerW})
+	if err != nil {
+		return
+	}
+	go func() {
+		server.Serve()
+		fromServerW.Close()
+	}()
+
+	var mu sync.Mutex
+	go func() {
+		for {
+			typ, data, err := readFxpPacket(fromServer)
+			if err != nil {
+				return
+			}
+			if typ == fxpVersion {
+				data = fxpAppendString(data, extHardlink)
+				data = fxpAppendString(data, "1")
+				data = fxpAppendString(data, extPosixRename)
+				data = fxpAppendString(data, "1")
+			}
+
+			mu.Lock()
+			writeFxpPacket(channel, typ, data)
+			mu.Unlock()
+		}
+	}()
+
+	for {
+		typ, data, err := readFxpPacket(channel)
+		if err != nil {
+			return
+		}
+
+		if typ == fxpExtended {
+			id := data[:4]
+			name, rest, _ := fxpString(data[4:])
+			a, rest, _ := fxpString(rest)
+			b, _, _ := fxpString(rest)
+
+			var err error
+			switch name {
+			case extHardlink:
+				err = os.Link(a, b)
+			case extPosixRename:
+				err = os.Rename(a, b)
+			default:
+				err = fmt.Errorf("unsupported extension %s", name)
+			}
+
+			// The status is SSH_FX_OK or SSH_FX_FAILURE
+			status := append([]byte{}, id...)
+			msg := ""
+			if err != nil {
+				status = fxpUint32(status, 4)
+				msg = err.Error()
+			} else {
+				status = fxpUint32(status, 0)
+			}
+			status = fxpAppendString(status, msg)
+			status = fxpAppendString(status, "")
+
+			mu.Lock()
+			writeFxpPacket(channel, fxpStatus, status)
+			mu.Unlock()
+			continue
+		}
+
+		if err := writeFxpPacket(toServerW, typ, data); err != nil {
+			return
+		}
+	}
+}
+
+func testBackendConfig(port int, hostKey, dir string) map[string]interface{} {
+	return map[string]interface{}{
+		"host":     "127.0.0.1",
+		"port":     port,
+		"user":     "user",
+		"password": "pass",
+		"host_key": hostKey,
+		"path":     dir,
+	}
+}
+
+func TestBackend(t *testing.T) {
+	port, hostKey := testServer(t)
+
+	dir, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Get the backend. We need two to test locking.
+	b1 := backend.TestBackendConfig(t, New(), testBackendConfig(port, hostKey, dir))
+	b2 := backend.TestBackendConfig(t, New(), testBackendConfig(port, hostKey, dir))
+
+	// Test
+	backend.TestBackend(t, b1, b2)
+}
+
+func TestBackendConfig(t *testing.T) {
+	port, hostKey := testServer(t)
+
+	c := testBackendConfig(port, hostKey, "/srv/terraform/")
+	c["key"] = "network.tfstate"
+	b := backend.TestBackendConfig(t, New(), c).(*Backend)
+
+	if b.sftpClient == nil {
+		t.Fatal("SFTP client was not created")
+	}
+	if b.dir != "/srv/terraform" {
+		t.Fatalf("Incorrect dir was populated: %s", b.dir)
+	}
+	if p := b.path(backend.DefaultStateName); p != "/srv/terraform/network.tfstate" {
+		t.Fatalf("Incorrect default state path: %s", p)
+	}
+	if p := b.path("staging"); p != "/srv/terraform/terraform.tfstate.d/staging/network.tfstate" {
+		t.Fatalf("Incorrect workspace state path: %s", p)
+	}
+}
+
+func TestBackendConfig_insecureIgnoreHostKey(t *testing.T) {
+	port, _ := testServer(t)
+
+	c := testBackendConfig(port, "", "/srv/terraform")
+	c["insecure_ignore_host_key"] = true
+	b := backend.TestBackendConfig(t, New(), c).(*Backend)
+
+	if b.sftpClient == nil {
+		t.Fatal("SFTP client was not created")
+	}
+}
+
+func TestBackendConfig_invalid(t *testing.T) {
+	port, hostKey := testServer(t)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigner, err := ssh.NewSignerFromKey(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherHostKey := string(ssh.MarshalAuthorizedKey(otherSigner.PublicKey()))
+
+	cases := map[string]struct {
+		Config map[string]interface{}
+		Err    string
+	}{
+		"host key mismatch": {
+			map[string]interface{}{"host_key": otherHostKey},
+			"doesn't match host_key",
+		},
+		"bad password": {
+			map[string]interface{}{"password": "wrong"},
+			"unable to authenticate",
+		},
+		"no host key": {
+			map[string]interface{}{"host_key": ""},
+			"host_key is required",
+		},
+		"no auth": {
+			map[string]interface{}{"password": ""},
+			"one of password, private_key or agent is required",
+		},
+		"key with a directory": {
+			map[string]interface{}{"key": "states/terraform.tfstate"},
+			"key must be a file name",
+		},
+	}
+
+	for name, tc := range cases {
+		c := testBackendConfig(port, hostKey, "/srv/terraform")
+		for k, v := range tc.Config {
+			c[k] = v
+		}
+
+		rc, err := config.NewRawConfig(c)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		err = New().Configure(terraform.NewResourceConfig(rc))
+		if err == nil || !strings.Contains(err.Error(), tc.Err) {
+			t.Fatalf("%s: expected an error containing %q, got %v", name, tc.Err, err)
+		}
+	}
+}
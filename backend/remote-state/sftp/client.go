@@ -0,0 +1,293 @@
+package sftp
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/pkg/sftp"
+)
+
+// RemoteClient is a remote client that stores the state in a file on an
+// SFTP server.
+//
+// The state is replaced by writing a temporary file next to it and renaming
+// it into place, since SFTP has no atomic writes. Plain SFTP can't rename
+// over an existing file, so unless the server has the OpenSSH extensions to
+// do so, the previous state is first renamed to the backup file, which is
+// read instead of the state while the state is missing. The lock is a file
+// that is created exclusively next to the state.
+type RemoteClient struct {
+	client     *sftp.Client
+	extensions *extensionClient
+	path       string
+}
+
+func (c *RemoteClient) Get() (*remote.Payload, error) {
+	payload, err := c.get(c.path)
+	if err == nil && payload == nil {
+		// The state is only missing while it's being replaced, or if it
+		// doesn't exist, when there's no backup either.
+		payload, err = c.get(c.backupPath())
+	}
+
+	return payload, err
+}
+
+func (c *RemoteClient) get(p string) (*remote.Payload, error) {
+	f, err := c.client.Open(p)
+	if err != nil {
+		if isNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Failed to open remote state %s: %s", p, err)
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read remote state %s: %s", p, err)
+	}
+
+	// If there was no data, then return nil
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	sum := md5.Sum(data)
+	payload := &remote.Payload{
+		Data: data,
+		MD5:  sum[:],
+	}
+	if fi, err := f.Stat(); err == nil {
+		payload.ModTime = fi.ModTime()
+	}
+
+	return payload, nil
+}
+
+func (c *RemoteClient) Put(data []byte) error {
+	if err := c.mkdirAll(path.Dir(c.path)); err != nil {
+		return fmt.Errorf("Failed to create remote state directory: %s", err)
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp-" + id
+
+	log.Printf("[DEBUG] Uploading remote state to SFTP: %s", c.path)
+	if err := c.write(tmp, data); err != nil {
+		c.client.Remove(tmp)
+		return fmt.Errorf("Failed to upload state: %s", err)
+	}
+
+	if err := c.replace(tmp); err != nil {
+		c.client.Remove(tmp)
+		return fmt.Errorf("Failed to replace state: %s", err)
+	}
+
+	return nil
+}
+
+// replace replaces the state with the file tmp, keeping the previous state
+// as the backup.
+func (c *RemoteClient) replace(tmp string) error {
+	backup := c.backupPath()
+	if err := c.client.Remove(backup); err != nil && !isNotExist(err) {
+		return fmt.Errorf("removing previous backup: %s", err)
+	}
+
+	// Not every server reports renaming a missing file as such
+	exists := true
+	if _, err := c.client.Stat(c.path); err != nil {
+		if !isNotExist(err) {
+			return err
+		}
+		exists = false
+	}
+
+	// With the OpenSSH extensions the state is never missing
+	if c.extensions.Supports(extHardlink, extPosixRename) {
+		if exists {
+			if err := c.extensions.Link(c.path, backup); err != nil {
+				return fmt.Errorf("backing up previous state: %s", err)
+			}
+		}
+		return c.extensions.PosixRename(tmp, c.path)
+	}
+
+	if exists {
+		if err := c.client.Rename(c.path, backup); err != nil {
+			return fmt.Errorf("backing up previous state: %s", err)
+		}
+	}
+	if err := c.client.Rename(tmp, c.path); err != nil {
+		if exists {
+			c.client.Rename(backup, c.path)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// mkdirAll creates the directory p along with any parents that don't
+// exist yet.
+func (c *RemoteClient) mkdirAll(p string) error {
+	fi, err := c.client.Stat(p)
+	if err == nil {
+		if !fi.IsDir() {
+			return fmt.Errorf("%s is not a directory", p)
+		}
+		return nil
+	}
+	if !isNotExist(err) {
+		return err
+	}
+
+	if parent := path.Dir(p); parent != p {
+		if err := c.mkdirAll(parent); err != nil {
+			return err
+		}
+	}
+
+	if err := c.client.Mkdir(p); err != nil {
+		// Someone else may have created it in the meantime
+		if fi, serr := c.client.Stat(p); serr == nil && fi.IsDir() {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// write writes the file at p, which must not exist.
+func (c *RemoteClient) write(p string, data []byte) error {
+	f, err := c.client.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func (c *RemoteClient) Delete() error {
+	for _, p := range []string{c.path, c.backupPath()} {
+		if err := c.client.Remove(p); err != nil && !isNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *RemoteClient) Lock(info *state.LockInfo) (string, error) {
+	info.Path = c.lockPath()
+
+	if info.ID == "" {
+		lockID, err := uuid.GenerateUUID()
+		if err != nil {
+			return "", err
+		}
+
+		info.ID = lockID
+	}
+
+	if err := c.mkdirAll(path.Dir(c.path)); err != nil {
+		return "", fmt.Errorf("Failed to create remote state directory: %s", err)
+	}
+
+	// Creating the lock file fails if it exists
+	if err := c.write(c.lockPath(), info.Marshal()); err != nil {
+		lockErr := &state.LockError{Err: err}
+
+		lockInfo, infoErr := c.getLockInfo()
+		if infoErr != nil {
+			lockErr.Err = fmt.Errorf("%s; and failed to read lock info: %s", err, infoErr)
+		}
+		lockErr.Info = lockInfo
+
+		return "", lockErr
+	}
+
+	return info.ID, nil
+}
+
+func (c *RemoteClient) getLockInfo() (*state.LockInfo, error) {
+	f, err := c.client.Open(c.lockPath())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	raw, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	lockInfo := &state.LockInfo{}
+	if err := json.Unmarshal(raw, lockInfo); err != nil {
+		return nil, err
+	}
+
+	return lockInfo, nil
+}
+
+func (c *RemoteClient) Unlock(id string) error {
+	lockErr := &state.LockError{}
+
+	lockInfo, err := c.getLockInfo()
+	if err != nil {
+		lockErr.Err = fmt.Errorf("failed to retrieve lock info: %s", err)
+		return lockErr
+	}
+	lockErr.Info = lockInfo
+
+	if lockInfo.ID != id {
+		lockErr.Err = fmt.Errorf("lock id %q does not match existing lock", id)
+		return lockErr
+	}
+
+	if err := c.client.Remove(c.lockPath()); err != nil {
+		lockErr.Err = err
+		return lockErr
+	}
+
+	return nil
+}
+
+// sshFxNoSuchFile is the SFTP status code of a file that doesn't exist.
+const sshFxNoSuchFile = 2
+
+// isNotExist returns whether err is that a file doesn't exist, which the
+// SFTP client only reports as os.ErrNotExist for some requests.
+func isNotExist(err error) bool {
+	if serr, ok := err.(*sftp.StatusError); ok {
+		return serr.Code == sshFxNoSuchFile
+	}
+
+	return os.IsNotExist(err)
+}
+
+func (c *RemoteClient) backupPath() string {
+	return c.path + ".backup"
+}
+
+func (c *RemoteClient) lockPath() string {
+	return c.path + ".lock"
+}
@@ -0,0 +1,144 @@
+package sftp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/state/remote"
+)
+
+func TestRemoteClient_impl(t *testing.T) {
+	var _ remote.Client = new(RemoteClient)
+	var _ remote.ClientLocker = new(RemoteClient)
+}
+
+func TestRemoteClient(t *testing.T) {
+	port, hostKey := testServer(t)
+
+	dir, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := backend.TestBackendConfig(t, New(), testBackendConfig(port, hostKey, dir)).(*Backend)
+
+	state, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote.TestClient(t, state.(*remote.State).Client)
+}
+
+func TestRemoteClientLocks(t *testing.T) {
+	port, hostKey := testServer(t)
+
+	dir, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b1 := backend.TestBackendConfig(t, New(), testBackendConfig(port, hostKey, dir)).(*Backend)
+	b2 := backend.TestBackendConfig(t, New(), testBackendConfig(port, hostKey, dir)).(*Backend)
+
+	s1, err := b1.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := b2.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote.TestRemoteLocks(t, s1.(*remote.State).Client, s2.(*remote.State).Client)
+}
+
+func TestRemoteClient_backup(t *testing.T) {
+	// With the OpenSSH extensions the state is replaced atomically
+	t.Run("openssh", func(t *testing.T) {
+		testRemoteClientBackup(t, true)
+	})
+
+	// Without them, the state is renamed to the backup first
+	t.Run("plain", func(t *testing.T) {
+		testRemoteClientBackup(t, false)
+	})
+}
+
+func testRemoteClientBackup(t *testing.T, extensions bool) {
+	port, hostKey := testServerExtensions(t, extensions)
+
+	dir, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := backend.TestBackendConfig(t, New(), testBackendConfig(port, hostKey, dir)).(*Backend)
+	client, err := b.remoteClient(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := client.extensions.Supports(extHardlink, extPosixRename); actual != extensions {
+		t.Fatalf("expected the extensions to be supported: %t, got %t", extensions, actual)
+	}
+
+	for _, data := range []string{"first", "second", "third"} {
+		if err := client.Put([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The previous state is kept as the backup
+	path := filepath.Join(dir, "terraform.tfstate")
+	raw, err := ioutil.ReadFile(path + ".backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "second" {
+		t.Fatalf("expected the previous state in the backup, got %q", raw)
+	}
+
+	// While the state is being replaced, the backup is read
+	if err := os.Rename(path, path+".new"); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := client.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload == nil || string(payload.Data) != "second" {
+		t.Fatalf("expected the backup to be read, got %#v", payload)
+	}
+
+	// Nothing is left after deleting
+	if err := client.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	payload, err = client.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload != nil {
+		t.Fatalf("expected no state after deleting, got %#v", payload)
+	}
+
+	// No temporary files are left
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "terraform.tfstate.new" {
+		t.Fatalf("expected no other files, got %v", entries)
+	}
+
+	if _, err := b.remoteClient("../default"); err == nil {
+		t.Fatal("expected an error for a workspace name with a slash")
+	}
+}
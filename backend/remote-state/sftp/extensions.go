@@ -0,0 +1,195 @@
+package sftp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// The OpenSSH extensions that replace the state atomically.
+const (
+	extHardlink    = "hardlink@openssh.com"
+	extPosixRename = "posix-rename@openssh.com"
+)
+
+// The SFTP packet types that extensions are requested with.
+const (
+	fxpInit     = 1
+	fxpVersion  = 2
+	fxpStatus   = 101
+	fxpExtended = 200
+)
+
+// extensionClient requests the OpenSSH extensions on an SFTP session of its
+// own, since the vendored SFTP client can't send them. The extensions that
+// the server supports are the ones it lists when the session starts.
+type extensionClient struct {
+	mu        sync.Mutex
+	session   *ssh.Session
+	w         io.WriteCloser
+	r         io.Reader
+	id        uint32
+	supported map[string]bool
+}
+
+// newExtensionClient starts an SFTP session on conn for requesting
+// extensions.
+func newExtensionClient(conn *ssh.Client) (*extensionClient, error) {
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &extensionClient{session: session, supported: make(map[string]bool)}
+	if err := c.start(); err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *extensionClient) start() error {
+	var err error
+	if c.w, err = c.session.StdinPipe(); err != nil {
+		return err
+	}
+	if c.r, err = c.session.StdoutPipe(); err != nil {
+		return err
+	}
+	if err := c.session.RequestSubsystem("sftp"); err != nil {
+		return err
+	}
+
+	// The version is followed by the name and data of each extension
+	if err := writeFxpPacket(c.w, fxpInit, fxpUint32(nil, 3)); err != nil {
+		return err
+	}
+	typ, data, err := readFxpPacket(c.r)
+	if err != nil {
+		return err
+	}
+	if typ != fxpVersion || len(data) < 4 {
+		return fmt.Errorf("unexpected SFTP packet type %d", typ)
+	}
+	data = data[4:]
+	for len(data) > 0 {
+		var name string
+		if name, data, err = fxpString(data); err != nil {
+			return err
+		}
+		if _, data, err = fxpString(data); err != nil {
+			return err
+		}
+		c.supported[name] = true
+	}
+
+	return nil
+}
+
+// Supports returns whether the server supports all of the extensions.
+func (c *extensionClient) Supports(names ...string) bool {
+	if c == nil {
+		return false
+	}
+
+	for _, name := range names {
+		if !c.supported[name] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Link creates newname as a hard link to oldname.
+func (c *extensionClient) Link(oldname, newname string) error {
+	return c.request(extHardlink, oldname, newname)
+}
+
+// PosixRename renames oldname to newname, replacing newname if it exists.
+func (c *extensionClient) PosixRename(oldname, newname string) error {
+	return c.request(extPosixRename, oldname, newname)
+}
+
+// request requests the extension with the paths a and b, as both hardlink
+// and posix-rename take.
+func (c *extensionClient) request(name, a, b string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.id++
+	data := fxpUint32(nil, c.id)
+	data = fxpAppendString(data, name)
+	data = fxpAppendString(data, a)
+	data = fxpAppendString(data, b)
+	if err := writeFxpPacket(c.w, fxpExtended, data); err != nil {
+		return err
+	}
+
+	typ, data, err := readFxpPacket(c.r)
+	if err != nil {
+		return err
+	}
+	if typ != fxpStatus || len(data) < 8 {
+		return fmt.Errorf("unexpected SFTP packet type %d", typ)
+	}
+	if id := binary.BigEndian.Uint32(data); id != c.id {
+		return fmt.Errorf("unexpected SFTP response id %d", id)
+	}
+	if code := binary.BigEndian.Uint32(data[4:]); code != 0 {
+		msg, _, _ := fxpString(data[8:])
+		return fmt.Errorf("%s: %s (SFTP status %d)", name, msg, code)
+	}
+
+	return nil
+}
+
+func writeFxpPacket(w io.Writer, typ byte, data []byte) error {
+	b := fxpUint32(nil, uint32(len(data)+1))
+	b = append(b, typ)
+	_, err := w.Write(append(b, data...))
+	return err
+}
+
+func readFxpPacket(r io.Reader) (byte, []byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n == 0 || n > 256*1024 {
+		return 0, nil, fmt.Errorf("invalid SFTP packet length %d", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, nil, err
+	}
+
+	return b[0], b[1:], nil
+}
+
+func fxpUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func fxpAppendString(b []byte, s string) []byte {
+	return append(fxpUint32(b, uint32(len(s))), s...)
+}
+
+func fxpString(b []byte) (string, []byte, error) {
+	if len(b) < 4 {
+		return "", nil, fmt.Errorf("short SFTP packet")
+	}
+
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return "", nil, fmt.Errorf("short SFTP packet")
+	}
+
+	return string(b[4 : 4+n]), b[4+n:], nil
+}
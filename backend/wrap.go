@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// wrapper is embedded by the backends of this package that wrap another
+// backend, and forwards what they don't change to it.
+type wrapper struct {
+	Backend
+}
+
+// unwrapper is implemented by the backends of this package that wrap
+// another backend.
+type unwrapper interface {
+	// Unwrap returns the wrapped backend.
+	Unwrap() Backend
+}
+
+func (w *wrapper) Unwrap() Backend {
+	return w.Backend
+}
+
+func (w *wrapper) PublishOutputs(name string, outputs map[string]*terraform.OutputState) error {
+	if p, ok := w.Backend.(OutputPublisher); ok {
+		return p.PublishOutputs(name, outputs)
+	}
+
+	return ErrOutputPublishingNotSupported
+}
+
+func (w *wrapper) PublishedOutputs(name string) (map[string]*terraform.OutputState, error) {
+	if p, ok := w.Backend.(OutputPublisher); ok {
+		return p.PublishedOutputs(name)
+	}
+
+	return nil, ErrOutputPublishingNotSupported
+}
+
+// wrapClient returns a Backend that stores the states of b with the
+// remote.Client returned by wrap for the client that b stores them with,
+// such as to encrypt them. feature describes what the client does, for
+// errors.
+//
+// Enhanced backends manage their own storage, so their states can't be
+// stored with another client.
+func wrapClient(b Backend, feature string, wrap func(remote.Client) (remote.Client, error)) (Backend, error) {
+	if _, ok := b.(Enhanced); ok {
+		return nil, fmt.Errorf("the %T backend doesn't support %s", b, feature)
+	}
+
+	return &clientBackend{wrapper: wrapper{b}, feature: feature, wrap: wrap}, nil
+}
+
+type clientBackend struct {
+	wrapper
+
+	feature string
+	wrap    func(remote.Client) (remote.Client, error)
+}
+
+func (b *clientBackend) State(name string) (state.State, error) {
+	s, err := b.Backend.State(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := remoteState(s)
+	if rs == nil {
+		return nil, fmt.Errorf(
+			"backend doesn't store state with a remote client, so it doesn't support %s", b.feature)
+	}
+	c, err := b.wrap(rs.Client)
+	if err != nil {
+		return nil, err
+	}
+	rs.Client = c
+
+	return s, nil
+}
+
+// remoteState returns the remote.State that s stores the state with, or
+// nil if it doesn't store it with a remote client.
+func remoteState(s state.State) *remote.State {
+	if ld, ok := s.(*state.LockDisabled); ok {
+		s = ld.Inner
+	}
+
+	rs, _ := s.(*remote.State)
+	return rs
+}
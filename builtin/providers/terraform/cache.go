@@ -0,0 +1,177 @@
+package terraform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultCacheDir is the directory that the remote state reads are cached
+// in if the provider isn't configured with one. Terraform configures it
+// with the one under its data directory.
+const defaultCacheDir = ".terraform/remote-state-cache"
+
+// remoteStateCacheKey is what a read is cached by: the same backend,
+// configuration and workspace read the same outputs.
+type remoteStateCacheKey struct {
+	Backend          string                 `json:"backend"`
+	Config           map[string]interface{} `json:"config"`
+	Workspace        string                 `json:"workspace"`
+	PublishedOutputs bool                   `json:"published_outputs"`
+}
+
+// path returns the path of the cache file of the key in dir, named after
+// the SHA256 digest of the key.
+func (k *remoteStateCacheKey) path(dir string) (string, error) {
+	raw, err := json.Marshal(k)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(raw)
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// remoteStateCacheEntry is the content of a cache file. Expires is when the
+// outputs expire with the TTL they were cached with, after which the file
+// is evicted even if nothing reads it again.
+type remoteStateCacheEntry struct {
+	Expires time.Time              `json:"expires"`
+	Outputs map[string]interface{} `json:"outputs"`
+}
+
+// readRemoteStateCache returns the outputs cached for the key in dir, if
+// they were cached less than ttl ago. Any error reading the cache is treated
+// as the outputs not being cached.
+func readRemoteStateCache(dir string, k *remoteStateCacheKey, ttl time.Duration) (map[string]interface{}, bool) {
+	path, err := k.path(dir)
+	if err != nil {
+		return nil, false
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if time.Since(fi.ModTime()) >= ttl {
+		log.Printf("[DEBUG] remote state cache %s expired", path)
+		return nil, false
+	}
+
+	entry, err := readRemoteStateCacheEntry(path)
+	if err != nil {
+		log.Printf("[WARN] error reading remote state cache %s: %s", path, err)
+		return nil, false
+	}
+	if entry.Outputs == nil {
+		entry.Outputs = make(map[string]interface{})
+	}
+
+	log.Printf("[DEBUG] using remote state cache %s", path)
+	return entry.Outputs, true
+}
+
+// readRemoteStateCacheEntry reads and decodes the cache file at path.
+func readRemoteStateCacheEntry(path string) (*remoteStateCacheEntry, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry remoteStateCacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Expires.IsZero() {
+		return nil, fmt.Errorf("no expiry time")
+	}
+
+	return &entry, nil
+}
+
+// writeRemoteStateCache caches the outputs for the key in dir, for ttl. The
+// file is replaced by renaming, so that concurrent reads never see it partly
+// written. The entries that have expired are evicted along the way, so that
+// the cache doesn't grow with every configuration that was ever read.
+func writeRemoteStateCache(dir string, k *remoteStateCacheKey, ttl time.Duration, outputs map[string]interface{}) error {
+	path, err := k.path(dir)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(&remoteStateCacheEntry{
+		Expires: time.Now().Add(ttl).UTC(),
+		Outputs: outputs,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("Error creating remote state cache directory: %s", err)
+	}
+
+	f, err := ioutil.TempFile(dir, "tmp")
+	if err != nil {
+		return fmt.Errorf("Error writing remote state cache: %s", err)
+	}
+	_, err = f.Write(raw)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("Error writing remote state cache: %s", err)
+	}
+
+	evictRemoteStateCache(dir)
+	return nil
+}
+
+// removeRemoteStateCache removes the outputs cached for the key in dir, if
+// any.
+func removeRemoteStateCache(dir string, k *remoteStateCacheKey) error {
+	path, err := k.path(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Error removing remote state cache: %s", err)
+	}
+
+	return nil
+}
+
+// evictRemoteStateCache removes the cache files in dir that have expired,
+// along with those that can't be read, such as ones cached before entries
+// had an expiry time. Eviction is best effort, so errors are only logged.
+func evictRemoteStateCache(dir string) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		log.Printf("[WARN] error listing remote state cache %s: %s", dir, err)
+		return
+	}
+
+	now := time.Now()
+	for _, path := range paths {
+		entry, err := readRemoteStateCacheEntry(path)
+		if err == nil && now.Before(entry.Expires) {
+			continue
+		}
+
+		log.Printf("[DEBUG] evicting remote state cache %s", path)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("[WARN] error evicting remote state cache %s: %s", path, err)
+		}
+	}
+}
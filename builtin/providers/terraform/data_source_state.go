@@ -3,6 +3,7 @@ package terraform
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform/backend"
@@ -38,9 +39,55 @@ func dataSourceRemoteState() *schema.Resource {
 			},
 
 			"environment": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Deprecated:    "please use the workspace attribute",
+				ConflictsWith: []string{"workspace"},
+			},
+
+			// The workspace defaults to the one Terraform is running in,
+			// which it configures the provider with.
+			"workspace": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+
+			"encryption": {
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+
+			"compression": {
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+
+			"blobs": {
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+
+			"published_outputs": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+
+			// The outputs are cached on disk for this long, if it's set.
+			"cache_ttl": {
 				Type:     schema.TypeString,
 				Optional: true,
-				Default:  backend.DefaultStateName,
+				ValidateFunc: func(v interface{}, k string) (ws []string, errors []error) {
+					d, err := time.ParseDuration(v.(string))
+					if err != nil {
+						errors = append(errors, fmt.Errorf("%s: %s", k, err))
+					} else if d < 0 {
+						errors = append(errors, fmt.Errorf("%s: must not be negative", k))
+					}
+
+					return
+				},
 			},
 
 			"__has_dynamic_attributes": {
@@ -54,59 +101,226 @@ func dataSourceRemoteState() *schema.Resource {
 func dataSourceRemoteStateRead(d *schema.ResourceData, meta interface{}) error {
 	backend := d.Get("backend").(string)
 
-	// Get the configuration in a type we want.
-	rawConfig, err := config.NewRawConfig(d.Get("config").(map[string]interface{}))
-	if err != nil {
-		return fmt.Errorf("error initializing backend: %s", err)
-	}
-
 	// Don't break people using the old _local syntax - but note warning above
 	if backend == "_local" {
 		log.Println(`[INFO] Switching old (unsupported) backend "_local" to "local"`)
 		backend = "local"
 	}
 
+	env := workspace(meta)
+	if v, ok := d.GetOk("workspace"); ok {
+		env = v.(string)
+	}
+	if v, ok := d.GetOk("environment"); ok {
+		env = v.(string)
+	}
+	d.Set("workspace", env)
+
+	var ttl time.Duration
+	if v, ok := d.GetOk("cache_ttl"); ok {
+		var err error
+		if ttl, err = time.ParseDuration(v.(string)); err != nil {
+			return fmt.Errorf("error parsing cache_ttl: %s", err)
+		}
+	}
+	key := &remoteStateCacheKey{
+		Backend:          backend,
+		Config:           d.Get("config").(map[string]interface{}),
+		Workspace:        env,
+		PublishedOutputs: d.Get("published_outputs").(bool),
+	}
+
+	// The cache is stored in plain text, so the outputs of encrypted states
+	// aren't cached.
+	_, encrypted := d.GetOk("encryption")
+	cache := ttl > 0 && !encrypted
+
+	var outputMap map[string]interface{}
+	cached := false
+	dir := cacheDir(meta)
+	if cache && !refreshCache(meta) {
+		outputMap, cached = readRemoteStateCache(dir, key, ttl)
+	}
+	if !cached {
+		var sensitive bool
+		var err error
+		outputMap, sensitive, err = dataSourceRemoteStateOutputs(d, backend, env)
+		if err != nil {
+			return err
+		}
+
+		// The cache only saves reading the state, so failing to write it
+		// doesn't fail the read. Nor are sensitive outputs cached, and the
+		// outputs cached before they were sensitive are removed.
+		switch {
+		case !cache:
+		case sensitive:
+			if err := removeRemoteStateCache(dir, key); err != nil {
+				log.Printf("[WARN] %s", err)
+			}
+		default:
+			if err := writeRemoteStateCache(dir, key, ttl, outputMap); err != nil {
+				log.Printf("[WARN] %s", err)
+			}
+		}
+	}
+
+	d.SetId(time.Now().UTC().String())
+
+	mappedOutputs := remoteStateFlatten(outputMap)
+
+	for key, val := range mappedOutputs {
+		d.UnsafeSetFieldRaw(key, val)
+	}
+	return nil
+}
+
+// dataSourceRemoteStateOutputs reads the root outputs of the workspace env
+// of the remote state, by name, and whether any of them is sensitive.
+func dataSourceRemoteStateOutputs(
+	d *schema.ResourceData, backend, env string) (map[string]interface{}, bool, error) {
+	// Get the configuration in a type we want.
+	rawConfig, err := config.NewRawConfig(d.Get("config").(map[string]interface{}))
+	if err != nil {
+		return nil, false, fmt.Errorf("error initializing backend: %s", err)
+	}
+
 	// Create the client to access our remote state
 	log.Printf("[DEBUG] Initializing remote state backend: %s", backend)
 	f := backendinit.Backend(backend)
 	if f == nil {
-		return fmt.Errorf("Unknown backend type: %s", backend)
+		return nil, false, fmt.Errorf("Unknown backend type: %s", backend)
 	}
 	b := f()
 
 	// Configure the backend
 	if err := b.Configure(terraform.NewResourceConfig(rawConfig)); err != nil {
-		return fmt.Errorf("error initializing backend: %s", err)
+		return nil, false, fmt.Errorf("error initializing backend: %s", err)
+	}
+
+	b, err = dataSourceRemoteStateStorage(d, b)
+	if err != nil {
+		return nil, false, fmt.Errorf("error initializing backend: %s", err)
+	}
+
+	if err := dataSourceRemoteStateWorkspace(b, backend, env); err != nil {
+		return nil, false, err
+	}
+
+	// If requested, read only the published outputs rather than the state
+	if d.Get("published_outputs").(bool) {
+		return dataSourceRemoteStatePublishedOutputs(b, env)
 	}
 
 	// Get the state
-	env := d.Get("environment").(string)
 	state, err := b.State(env)
 	if err != nil {
-		return fmt.Errorf("error loading the remote state: %s", err)
+		return nil, false, fmt.Errorf("error loading the remote state: %s", err)
 	}
 	if err := state.RefreshState(); err != nil {
-		return err
+		return nil, false, err
 	}
 
-	d.SetId(time.Now().UTC().String())
-
-	outputMap := make(map[string]interface{})
-
 	remoteState := state.State()
 	if remoteState.Empty() {
 		log.Println("[DEBUG] empty remote state")
+		return make(map[string]interface{}), false, nil
+	}
+
+	outputMap, sensitive := remoteStateOutputMap(remoteState.RootModule().Outputs)
+	return outputMap, sensitive, nil
+}
+
+// dataSourceRemoteStateWorkspace checks that the workspace exists in the
+// backend, so that a mistyped or missing workspace is an error rather than
+// an empty state that is silently created.
+func dataSourceRemoteStateWorkspace(b backend.Backend, name, env string) error {
+	if env == backend.DefaultStateName {
 		return nil
 	}
 
-	for key, val := range remoteState.RootModule().Outputs {
-		outputMap[key] = val.Value
+	envs, err := b.States()
+	if err == backend.ErrNamedStatesNotSupported {
+		return fmt.Errorf(
+			"workspace %q doesn't exist: the %s backend only has the %q workspace",
+			env, name, backend.DefaultStateName)
+	}
+	if err != nil {
+		return fmt.Errorf("error listing the workspaces of the remote state: %s", err)
 	}
 
-	mappedOutputs := remoteStateFlatten(outputMap)
+	for _, e := range envs {
+		if e == env {
+			return nil
+		}
+	}
 
-	for key, val := range mappedOutputs {
-		d.UnsafeSetFieldRaw(key, val)
+	return fmt.Errorf(
+		"workspace %q doesn't exist in the remote state, which has the workspaces: %s. "+
+			"The workspace defaults to the current one, so set it explicitly to "+
+			"read the state of another.",
+		env, strings.Join(envs, ", "))
+}
+
+// dataSourceRemoteStateStorage wraps the backend to decrypt and decompress
+// the state and to read its blobs, as configured, the same way as the
+// backend that stores the state.
+func dataSourceRemoteStateStorage(
+	d *schema.ResourceData, b backend.Backend) (backend.Backend, error) {
+	raw := make(map[string]interface{})
+	for _, k := range []string{
+		backend.EncryptionConfigKey, backend.CompressionConfigKey, backend.BlobsConfigKey,
+	} {
+		if v, ok := d.GetOk(k); ok {
+			raw[k] = v
+		}
 	}
-	return nil
+
+	raw, enc, err := backend.SplitEncryptionConfig(raw)
+	if err != nil {
+		return nil, err
+	}
+	raw, comp, err := backend.SplitCompressionConfig(raw)
+	if err != nil {
+		return nil, err
+	}
+	_, blobs, err := backend.SplitBlobsConfig(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return backend.WrapStorage(b, enc, comp, blobs)
+}
+
+// dataSourceRemoteStatePublishedOutputs reads the outputs published by
+// "terraform output -publish" instead of the full state.
+func dataSourceRemoteStatePublishedOutputs(
+	b backend.Backend, env string) (map[string]interface{}, bool, error) {
+	p, ok := b.(backend.OutputPublisher)
+	if !ok {
+		return nil, false, fmt.Errorf("backend doesn't support published outputs")
+	}
+
+	outputs, err := p.PublishedOutputs(env)
+	if err != nil {
+		return nil, false, fmt.Errorf("error loading the published outputs: %s", err)
+	}
+
+	outputMap, sensitive := remoteStateOutputMap(outputs)
+	return outputMap, sensitive, nil
+}
+
+// remoteStateOutputMap returns the values of outputs by name, and whether
+// any of them is sensitive.
+func remoteStateOutputMap(outputs map[string]*terraform.OutputState) (map[string]interface{}, bool) {
+	outputMap := make(map[string]interface{})
+	sensitive := false
+	for key, val := range outputs {
+		outputMap[key] = val.Value
+		if val.Sensitive {
+			sensitive = true
+		}
+	}
+
+	return outputMap, sensitive
 }
@@ -2,9 +2,17 @@ package terraform
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/terraform/backend"
 	backendinit "github.com/hashicorp/terraform/backend/init"
+	backendlegacy "github.com/hashicorp/terraform/backend/legacy"
+	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/terraform"
 )
@@ -44,6 +52,67 @@ func TestState_backends(t *testing.T) {
 	})
 }
 
+func TestState_workspace(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccState_workspace, "default"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStateValue(
+						"data.terraform_remote_state.foo", "foo", "bar"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccState_workspace, "staging"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStateValue(
+						"data.terraform_remote_state.foo", "foo", "staging"),
+				),
+			},
+			{
+				Config:      fmt.Sprintf(testAccState_workspace, "prod"),
+				ExpectError: regexp.MustCompile(`workspace "prod" doesn't exist`),
+			},
+		},
+	})
+}
+
+func TestState_workspaceCurrent(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccState_workspaceCurrent,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStateValue(
+						"data.terraform_remote_state.foo", "foo", "staging"),
+					testAccCheckStateValue(
+						"data.terraform_remote_state.foo", "workspace", "staging"),
+				),
+			},
+		},
+	})
+}
+
+func TestState_publishedOutputs(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccState_publishedOutputs,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStateValue(
+						"data.terraform_remote_state.foo", "foo", "bar"),
+				),
+			},
+		},
+	})
+}
+
 func TestState_complexOutputs(t *testing.T) {
 	resource.UnitTest(t, resource.TestCase{
 		PreCheck:  func() { testAccPreCheck(t) },
@@ -63,6 +132,278 @@ func TestState_complexOutputs(t *testing.T) {
 	})
 }
 
+func TestState_blobs(t *testing.T) {
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	// The local backend stores its own states, so the state is written
+	// with the legacy file client, which can store blobs
+	newBackend := func() backend.Backend { return &backendlegacy.Backend{Type: "local"} }
+	backendinit.Set("_ds_blobs", newBackend)
+	defer backendinit.Set("_ds_blobs", nil)
+
+	statePath := filepath.Join(td, "terraform.tfstate")
+	rc, err := config.NewRawConfig(map[string]interface{}{"path": statePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := newBackend()
+	if err := b.Configure(terraform.NewResourceConfig(rc)); err != nil {
+		t.Fatal(err)
+	}
+	b, err = backend.WrapStorage(b, nil, nil, &backend.BlobsConfig{MinSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := terraform.NewState()
+	state.RootModule().Outputs["foo"] = &terraform.OutputState{Type: "string", Value: "bar"}
+	if err := s.WriteState(state); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PersistState(); err != nil {
+		t.Fatal(err)
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccState_blobs, statePath),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStateValue(
+						"data.terraform_remote_state.foo", "foo", "bar"),
+				),
+			},
+		},
+	})
+}
+
+func TestState_cache(t *testing.T) {
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	cacheDir := filepath.Join(td, "cache")
+	statePath := filepath.Join(td, "terraform.tfstate")
+	writeState := func(value string) {
+		state := fmt.Sprintf(`{"version": 1, "modules": [{"path": ["root"], "outputs": {"foo": %q}}]}`, value)
+		if err := ioutil.WriteFile(statePath, []byte(state), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeState("bar")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccState_cache, statePath, "1h", cacheDir, false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStateValue(
+						"data.terraform_remote_state.foo", "foo", "bar"),
+				),
+			},
+			// The cached outputs are read while they're fresh
+			{
+				PreConfig: func() { writeState("baz") },
+				Config:    fmt.Sprintf(testAccState_cache, statePath, "1h", cacheDir, false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStateValue(
+						"data.terraform_remote_state.foo", "foo", "bar"),
+				),
+			},
+			// A zero TTL doesn't use the cache
+			{
+				Config: fmt.Sprintf(testAccState_cache, statePath, "0s", cacheDir, false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStateValue(
+						"data.terraform_remote_state.foo", "foo", "baz"),
+				),
+			},
+			// Refreshing skips the cache, and updates it
+			{
+				PreConfig: func() { writeState("qux") },
+				Config:    fmt.Sprintf(testAccState_cache, statePath, "1h", cacheDir, true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStateValue(
+						"data.terraform_remote_state.foo", "foo", "qux"),
+				),
+			},
+			{
+				PreConfig: func() { writeState("quux") },
+				Config:    fmt.Sprintf(testAccState_cache, statePath, "1h", cacheDir, false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStateValue(
+						"data.terraform_remote_state.foo", "foo", "qux"),
+				),
+			},
+		},
+	})
+}
+
+func TestState_cacheSensitive(t *testing.T) {
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	cacheDir := filepath.Join(td, "cache")
+
+	statePath := filepath.Join(td, "terraform.tfstate")
+	state := `{"version": 3, "modules": [{"path": ["root"], "outputs": {"foo": {"sensitive": true, "type": "string", "value": "bar"}}}]}`
+	if err := ioutil.WriteFile(statePath, []byte(state), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The outputs cached before the output was sensitive are removed when
+	// the state is next read
+	key := &remoteStateCacheKey{
+		Backend:   "local",
+		Config:    map[string]interface{}{"path": statePath},
+		Workspace: "default",
+	}
+	err = writeRemoteStateCache(
+		cacheDir, key, 3*time.Hour, map[string]interface{}{"foo": "stale"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := key.path(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccState_cache, statePath, "1h", cacheDir, false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStateValue(
+						"data.terraform_remote_state.foo", "foo", "bar"),
+					func(*terraform.State) error {
+						paths, err := filepath.Glob(filepath.Join(cacheDir, "*"))
+						if err != nil {
+							return err
+						}
+						if len(paths) > 0 {
+							return fmt.Errorf("expected the outputs not to be cached, got %v", paths)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestState_cacheTTLValidate(t *testing.T) {
+	validate := dataSourceRemoteState().Schema["cache_ttl"].ValidateFunc
+	cases := map[string]bool{
+		"10m":  false,
+		"0s":   false,
+		"soon": true,
+		"-1h":  true,
+	}
+
+	for v, expected := range cases {
+		_, errs := validate(v, "cache_ttl")
+		if (len(errs) > 0) != expected {
+			t.Fatalf("%s: expected error %t, got %v", v, expected, errs)
+		}
+	}
+}
+
+func TestReadRemoteStateCache_expired(t *testing.T) {
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	key := &remoteStateCacheKey{Backend: "local", Workspace: "default"}
+	err = writeRemoteStateCache(td, key, time.Hour, map[string]interface{}{"foo": "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, ok := readRemoteStateCache(td, key, time.Hour)
+	if !ok || outputs["foo"] != "bar" {
+		t.Fatalf("expected the cached outputs, got %#v", outputs)
+	}
+
+	// Another workspace isn't cached
+	other := &remoteStateCacheKey{Backend: "local", Workspace: "staging"}
+	if _, ok := readRemoteStateCache(td, other, time.Hour); ok {
+		t.Fatal("expected another workspace not to be cached")
+	}
+
+	path, err := key.path(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := readRemoteStateCache(td, key, time.Hour); ok {
+		t.Fatal("expected the cache to have expired")
+	}
+}
+
+func TestWriteRemoteStateCache_evict(t *testing.T) {
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	// An entry that has expired, and one cached in the old format without
+	// an expiry time, are evicted by the next write
+	expired := &remoteStateCacheKey{Backend: "local", Workspace: "expired"}
+	if err := writeRemoteStateCache(td, expired, -time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+	legacyPath := filepath.Join(td, "legacy.json")
+	if err := ioutil.WriteFile(legacyPath, []byte(`{"foo": "bar"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	key := &remoteStateCacheKey{Backend: "local", Workspace: "default"}
+	if err := writeRemoteStateCache(td, key, time.Hour, nil); err != nil {
+		t.Fatal(err)
+	}
+	path, err := key.path(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(td, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != path {
+		t.Fatalf("expected only %s to be left, got %v", path, paths)
+	}
+}
+
 func testAccCheckStateValue(id, name, value string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[id]
@@ -101,6 +442,41 @@ data "terraform_remote_state" "foo" {
 	}
 }`
 
+const testAccState_workspace = `
+data "terraform_remote_state" "foo" {
+	backend   = "local"
+	workspace = "%s"
+
+	config {
+		path          = "./test-fixtures/basic.tfstate"
+		workspace_dir = "./test-fixtures/workspaces"
+	}
+}`
+
+const testAccState_workspaceCurrent = `
+provider "terraform" {
+	workspace = "staging"
+}
+
+data "terraform_remote_state" "foo" {
+	backend = "local"
+
+	config {
+		path          = "./test-fixtures/basic.tfstate"
+		workspace_dir = "./test-fixtures/workspaces"
+	}
+}`
+
+const testAccState_publishedOutputs = `
+data "terraform_remote_state" "foo" {
+	backend           = "local"
+	published_outputs = true
+
+	config {
+		path = "./test-fixtures/published.tfstate"
+	}
+}`
+
 const testAccState_complexOutputs = `
 resource "terraform_remote_state" "foo" {
 	backend = "local"
@@ -109,3 +485,31 @@ resource "terraform_remote_state" "foo" {
 		path = "./test-fixtures/complex_outputs.tfstate"
 	}
 }`
+
+const testAccState_blobs = `
+data "terraform_remote_state" "foo" {
+	backend = "_ds_blobs"
+
+	config {
+		path = "%s"
+	}
+
+	blobs {
+		min_size = 1
+	}
+}`
+
+const testAccState_cache = `
+provider "terraform" {
+	cache_dir     = "%[3]s"
+	refresh_cache = %[4]t
+}
+
+data "terraform_remote_state" "foo" {
+	backend   = "local"
+	cache_ttl = "%[2]s"
+
+	config {
+		path = "%[1]s"
+	}
+}`
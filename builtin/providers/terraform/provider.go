@@ -1,6 +1,7 @@
 package terraform
 
 import (
+	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 )
@@ -8,6 +9,30 @@ import (
 // Provider returns a terraform.ResourceProvider.
 func Provider() terraform.ResourceProvider {
 	return &schema.Provider{
+		Schema: map[string]*schema.Schema{
+			// Terraform configures the provider with the current workspace,
+			// which the terraform_remote_state data sources default to.
+			"workspace": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  backend.DefaultStateName,
+			},
+
+			// Terraform configures the provider with the directory that
+			// the data sources cache the remote states they read in, and
+			// whether to skip the cache, from -refresh-remote-state.
+			"cache_dir": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  defaultCacheDir,
+			},
+			"refresh_cache": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+		},
+
 		ResourcesMap: map[string]*schema.Resource{
 			"terraform_remote_state": schema.DataSourceResourceShim(
 				"terraform_remote_state",
@@ -17,5 +42,49 @@ func Provider() terraform.ResourceProvider {
 		DataSourcesMap: map[string]*schema.Resource{
 			"terraform_remote_state": dataSourceRemoteState(),
 		},
+
+		ConfigureFunc: providerConfigure,
+	}
+}
+
+// providerMeta is the configuration of the provider, which Terraform passes
+// what the data sources can't determine on their own with.
+type providerMeta struct {
+	Workspace    string
+	CacheDir     string
+	RefreshCache bool
+}
+
+func providerConfigure(d *schema.ResourceData) (interface{}, error) {
+	return &providerMeta{
+		Workspace:    d.Get("workspace").(string),
+		CacheDir:     d.Get("cache_dir").(string),
+		RefreshCache: d.Get("refresh_cache").(bool),
+	}, nil
+}
+
+// workspace returns the workspace that the provider with meta is configured
+// with.
+func workspace(meta interface{}) string {
+	if m, ok := meta.(*providerMeta); ok && m.Workspace != "" {
+		return m.Workspace
 	}
+
+	return backend.DefaultStateName
+}
+
+// cacheDir returns the directory that the provider with meta caches the
+// remote states it reads in.
+func cacheDir(meta interface{}) string {
+	if m, ok := meta.(*providerMeta); ok && m.CacheDir != "" {
+		return m.CacheDir
+	}
+
+	return defaultCacheDir
+}
+
+// refreshCache returns whether the provider with meta skips the cache.
+func refreshCache(meta interface{}) bool {
+	m, ok := meta.(*providerMeta)
+	return ok && m.RefreshCache
 }
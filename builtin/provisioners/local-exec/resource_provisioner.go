@@ -6,7 +6,10 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"regexp"
 	"runtime"
+	"sort"
+	"strings"
 
 	"github.com/armon/circbuf"
 	"github.com/hashicorp/terraform/helper/schema"
@@ -21,6 +24,10 @@ const (
 	maxBufSize = 8 * 1024
 )
 
+// outputAttributeRe matches the names that the output can be recorded
+// under. They can't contain dots, since they are keys of a map attribute.
+var outputAttributeRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
 func Provisioner() terraform.ResourceProvisioner {
 	return &schema.Provisioner{
 		Schema: map[string]*schema.Schema{
@@ -28,6 +35,28 @@ func Provisioner() terraform.ResourceProvisioner {
 				Type:     schema.TypeString,
 				Required: true,
 			},
+
+			"interpreter": &schema.Schema{
+				Type:     schema.TypeList,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Optional: true,
+			},
+
+			"working_dir": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"environment": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+
+			"output_attribute": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateOutputAttribute,
+			},
 		},
 
 		ApplyFunc: applyFn,
@@ -43,15 +72,19 @@ func applyFn(ctx context.Context) error {
 		return fmt.Errorf("local-exec provisioner command must be a non-empty string")
 	}
 
-	// Execute the command using a shell
-	var shell, flag string
-	if runtime.GOOS == "windows" {
-		shell = "cmd"
-		flag = "/C"
-	} else {
-		shell = "/bin/sh"
-		flag = "-c"
+	// Execute the command using the interpreter, or else a shell
+	var argv []string
+	for _, v := range data.Get("interpreter").([]interface{}) {
+		argv = append(argv, v.(string))
 	}
+	if len(argv) == 0 {
+		if runtime.GOOS == "windows" {
+			argv = []string{"cmd", "/C"}
+		} else {
+			argv = []string{"/bin/sh", "-c"}
+		}
+	}
+	argv = append(argv, command)
 
 	// Setup the reader that will read the output from the command.
 	// We use an os.Pipe so that the *os.File can be passed directly to the
@@ -63,9 +96,11 @@ func applyFn(ctx context.Context) error {
 	}
 
 	// Setup the command
-	cmd := exec.CommandContext(ctx, shell, flag, command)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
 	cmd.Stderr = pw
 	cmd.Stdout = pw
+	cmd.Dir = data.Get("working_dir").(string)
+	cmd.Env = environment(data.Get("environment").(map[string]interface{}))
 
 	output, _ := circbuf.NewBuffer(maxBufSize)
 
@@ -77,9 +112,7 @@ func applyFn(ctx context.Context) error {
 	go copyOutput(o, tee, copyDoneCh)
 
 	// Output what we're about to run
-	o.Output(fmt.Sprintf(
-		"Executing: %s %s \"%s\"",
-		shell, flag, command))
+	o.Output(fmt.Sprintf("Executing: %q", argv))
 
 	// Start the command
 	err = cmd.Start()
@@ -104,9 +137,47 @@ func applyFn(ctx context.Context) error {
 			command, err, output.Bytes())
 	}
 
+	// Record the output in the state of the resource, if requested
+	name := data.Get("output_attribute").(string)
+	state := ctx.Value(schema.ProvRawStateKey).(*terraform.InstanceState)
+	if name != "" && state != nil {
+		state.SetProvisionerOutput(name, strings.TrimSpace(string(output.Bytes())))
+	}
+
 	return nil
 }
 
+// environment returns the environment of the command: that of Terraform,
+// with the given variables added, in a stable order.
+func environment(vars map[string]interface{}) []string {
+	if len(vars) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	env := os.Environ()
+	for _, k := range keys {
+		env = append(env, fmt.Sprintf("%s=%v", k, vars[k]))
+	}
+
+	return env
+}
+
+func validateOutputAttribute(v interface{}, k string) (ws []string, es []error) {
+	if !outputAttributeRe.MatchString(v.(string)) {
+		es = append(es, fmt.Errorf(
+			"%s may only contain letters, digits, underscores and dashes, got %q",
+			k, v))
+	}
+
+	return
+}
+
 func copyOutput(o terraform.UIOutput, r io.Reader, doneCh chan<- struct{}) {
 	defer close(doneCh)
 	lr := linereader.New(r)
@@ -48,6 +48,77 @@ func TestResourceProvider_Apply(t *testing.T) {
 	}
 }
 
+func TestResourceProvider_ApplyCustomWorkingDirectory(t *testing.T) {
+	testdir := "working_dir_test"
+	os.Mkdir(testdir, 0755)
+	defer os.Remove(testdir)
+
+	c := testConfig(t, map[string]interface{}{
+		"command":          "echo `pwd`",
+		"working_dir":      testdir,
+		"output_attribute": "dir",
+	})
+
+	output := new(terraform.MockUIOutput)
+	state := &terraform.InstanceState{ID: "foo"}
+	if err := Provisioner().Apply(output, state, c); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	actual := state.ProvisionerOutputs()["dir"]
+	if expected := dir + "/" + testdir; actual != expected {
+		t.Fatalf("bad: %q, expected %q", actual, expected)
+	}
+}
+
+func TestResourceProvider_ApplyCustomEnv(t *testing.T) {
+	c := testConfig(t, map[string]interface{}{
+		"command": "echo $FOO $BAR $BAZ",
+		"environment": map[string]interface{}{
+			"FOO": "BAR",
+			"BAR": 1,
+			"BAZ": "true",
+		},
+		"output_attribute": "env",
+	})
+
+	output := new(terraform.MockUIOutput)
+	state := &terraform.InstanceState{ID: "foo"}
+	if err := Provisioner().Apply(output, state, c); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if actual := state.ProvisionerOutputs()["env"]; actual != "BAR 1 true" {
+		t.Fatalf("bad: %q", actual)
+	}
+	if actual := state.Attributes["provisioner_output.%"]; actual != "1" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
+func TestResourceProvider_ApplyInterpreter(t *testing.T) {
+	c := testConfig(t, map[string]interface{}{
+		"command":          "echo foo",
+		"interpreter":      []interface{}{"echo", "interpreted"},
+		"output_attribute": "out",
+	})
+
+	output := new(terraform.MockUIOutput)
+	state := &terraform.InstanceState{ID: "foo"}
+	if err := Provisioner().Apply(output, state, c); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if actual := state.ProvisionerOutputs()["out"]; actual != "interpreted echo foo" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
 func TestResourceProvider_stop(t *testing.T) {
 	c := testConfig(t, map[string]interface{}{
 		// bash/zsh/ksh will exec a single command in the same process. This
@@ -58,11 +129,10 @@ func TestResourceProvider_stop(t *testing.T) {
 	output := new(terraform.MockUIOutput)
 	p := Provisioner()
 
-	var err error
 	doneCh := make(chan struct{})
 	go func() {
 		defer close(doneCh)
-		err = p.Apply(output, nil, c)
+		p.Apply(output, nil, c)
 	}()
 
 	select {
@@ -107,6 +177,18 @@ func TestResourceProvider_Validate_missing(t *testing.T) {
 	}
 }
 
+func TestResourceProvider_Validate_outputAttribute(t *testing.T) {
+	c := testConfig(t, map[string]interface{}{
+		"command":          "echo foo",
+		"output_attribute": "foo.bar",
+	})
+
+	_, errs := Provisioner().Validate(c)
+	if len(errs) == 0 {
+		t.Fatalf("Should have errors")
+	}
+}
+
 func testConfig(t *testing.T, c map[string]interface{}) *terraform.ResourceConfig {
 	r, err := config.NewRawConfig(c)
 	if err != nil {
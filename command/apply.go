@@ -29,26 +29,39 @@ type ApplyCommand struct {
 }
 
 func (c *ApplyCommand) Run(args []string) int {
-	var destroyForce, refresh bool
+	var destroyForce, refresh, refreshChanged bool
+	var resultOut, graphOrderPath string
 	args = c.Meta.process(args, true)
 
 	cmdName := "apply"
 	if c.Destroy {
 		cmdName = "destroy"
 	}
+	if c.Meta.checkReadOnly(cmdName) {
+		return 1
+	}
 
 	cmdFlags := c.Meta.flagSet(cmdName)
 	if c.Destroy {
 		cmdFlags.BoolVar(&destroyForce, "force", false, "force")
+	} else {
+		cmdFlags.Var((*FlagStringSlice)(&c.Meta.forceReplace), "replace", "resource to replace")
+		cmdFlags.BoolVar(&refreshChanged, "refresh-changed", false, "refresh-changed")
+		cmdFlags.StringVar(&graphOrderPath, "graph-order", "", "path")
 	}
 	cmdFlags.BoolVar(&refresh, "refresh", true, "refresh")
+	cmdFlags.StringVar(&resultOut, "result-out", "", "path")
+	c.Meta.parallelismFlag(cmdFlags, DefaultParallelism)
 	cmdFlags.IntVar(
-		&c.Meta.parallelism, "parallelism", DefaultParallelism, "parallelism")
+		&c.Meta.refreshParallelism, "refresh-parallelism", 0, "refresh-parallelism")
+	c.Meta.providerRetryFlags(cmdFlags)
+	c.Meta.refreshRemoteStateFlag(cmdFlags)
 	cmdFlags.StringVar(&c.Meta.statePath, "state", "", "path")
 	cmdFlags.StringVar(&c.Meta.stateOutPath, "state-out", "", "path")
 	cmdFlags.StringVar(&c.Meta.backupPath, "backup", "", "path")
 	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
 	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
+	cmdFlags.BoolVar(&c.Meta.stateLockSubtree, "lock-subtree", false, "lock subtree")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
@@ -108,6 +121,15 @@ func (c *ApplyCommand) Run(args []string) int {
 		configPath = ""
 	}
 
+	var graphOrder *terraform.GraphOrder
+	if graphOrderPath != "" {
+		graphOrder, err = readGraphOrder(graphOrderPath)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading the graph order: %s", err))
+			return 1
+		}
+	}
+
 	// Load the module if we don't have one yet (not running from plan)
 	var mod *module.Tree
 	if plan == nil {
@@ -188,6 +210,9 @@ func (c *ApplyCommand) Run(args []string) int {
 	opReq.Module = mod
 	opReq.Plan = plan
 	opReq.PlanRefresh = refresh
+	opReq.PlanRefreshChanged = refreshChanged
+	opReq.ResultOutPath = resultOut
+	opReq.GraphOrder = graphOrder
 	opReq.Type = backend.OperationTypeApply
 
 	// Perform the operation
@@ -279,20 +304,64 @@ Options:
                          modifying. Defaults to the "-state-out" path with
                          ".backup" extension. Set to "-" to disable backup.
 
+  -graph-order=path      Follow the ordering of the apply graph in the given
+                         JSON file, computed from the graph written by
+                         "terraform plan -graph-out", on top of the
+                         dependencies. The apply fails before changing
+                         anything if the ordering doesn't match the graph.
+
   -lock=true             Lock the state file when locking is supported.
 
   -lock-timeout=0s       Duration to retry a state lock.
 
+  -lock-subtree          Only lock the modules of the -target addresses, so
+                         that other modules of the state can be changed at
+                         the same time. The backend must support it.
+
   -input=true            Ask for input for variables if not directly set.
 
   -no-color              If specified, output won't contain any color.
 
   -parallelism=n         Limit the number of parallel resource operations.
-                         Defaults to 10.
+                         Defaults to 10. "auto" starts at 10 and adjusts the
+                         limit as transient provider errors, such as
+                         throttling, and system load come and go.
+
+  -provider-retries=3     Retry provider operations that fail with errors the
+                         provider marks as transient, such as throttling, up
+                         to this many times. 0 disables retries.
+
+  -provider-retry-max-wait=30s
+                         The longest wait between retries of a provider
+                         operation. The wait doubles with each retry.
+
+  -refresh-changed       Only refresh the resources whose configuration
+                         doesn't match their state, the resources that
+                         depend on them, and data sources. Changes made to
+                         other resources outside of Terraform aren't seen.
+
+  -refresh-parallelism=n Limit the number of concurrent refreshes. Defaults
+                         to five times -parallelism.
+
+  -refresh-remote-state  Read the remote states of the terraform_remote_state
+                         data sources rather than the outputs they've cached.
 
   -refresh=true          Update state prior to checking for differences. This
                          has no effect if a plan file is given to apply.
 
+  -replace=resource      Resource to replace. The resource will be destroyed
+                         and recreated even if its configuration hasn't
+                         changed, without marking it tainted. The address
+                         must name a single instance in the state, such as
+                         aws_instance.web[0] for a resource with count. This
+                         has no effect if a plan file is given to apply. This
+                         flag can be used multiple times.
+
+  -result-out=path       Write a JSON result of the apply to this path, with
+                         the outcome and duration of each change, the errors,
+                         the outputs and the state serial before and after.
+                         It's written whether the apply succeeds or not.
+
   -state=path            Path to read and save state (unless state-out
                          is specified). Defaults to "terraform.tfstate".
 
@@ -300,6 +369,13 @@ Options:
                          "-state". This can be used to preserve the old
                          state.
 
+  -strict=code           Treat warnings with the given code as errors, in
+                         addition to those listed in the strict block of the
+                         configuration. This flag can be set multiple times.
+
+  -suppress-warning=code Don't show warnings with the given code. This flag
+                         can be set multiple times.
+
   -target=resource       Resource to target. Operation will be limited to this
                          resource and its dependencies. This flag can be used
                          multiple times.
@@ -311,6 +387,16 @@ Options:
                          a file. If "terraform.tfvars" is present, it will be
                          automatically loaded if this flag is not specified.
 
+  -var-dir=vars          Load layered variables from the given directory:
+                         "common.tfvars", then each -var-layer, then the file
+                         named after the current environment. -var and -var-file
+                         override layered variables.
+
+  -var-layer=name        Load "name.tfvars" from the -var-dir directory,
+                         which defaults to "vars", as a layer of variables.
+                         This flag can be set multiple times, and later
+                         layers override earlier ones.
+
 
 `
 	return strings.TrimSpace(helpText)
@@ -334,14 +420,34 @@ Options:
 
   -lock-timeout=0s       Duration to retry a state lock.
 
+  -lock-subtree          Only lock the modules of the -target addresses, so
+                         that other modules of the state can be changed at
+                         the same time. The backend must support it.
+
   -no-color              If specified, output won't contain any color.
 
   -parallelism=n         Limit the number of concurrent operations.
-                         Defaults to 10.
+                         Defaults to 10. "auto" starts at 10 and adjusts the
+                         limit as transient provider errors, such as
+                         throttling, and system load come and go.
+
+  -provider-retries=3     Retry provider operations that fail with errors the
+                         provider marks as transient, such as throttling, up
+                         to this many times. 0 disables retries.
+
+  -provider-retry-max-wait=30s
+                         The longest wait between retries of a provider
+                         operation. The wait doubles with each retry.
+
+  -refresh-parallelism=n Limit the number of concurrent refreshes. Defaults
+                         to five times -parallelism.
 
   -refresh=true          Update state prior to checking for differences. This
                          has no effect if a plan file is given to apply.
 
+  -result-out=path       Write a JSON result of the destroy to this path. See
+                         "terraform apply -help".
+
   -state=path            Path to read and save state (unless state-out
                          is specified). Defaults to "terraform.tfstate".
 
@@ -349,6 +455,13 @@ Options:
                          "-state". This can be used to preserve the old
                          state.
 
+  -strict=code           Treat warnings with the given code as errors, in
+                         addition to those listed in the strict block of the
+                         configuration. This flag can be set multiple times.
+
+  -suppress-warning=code Don't show warnings with the given code. This flag
+                         can be set multiple times.
+
   -target=resource       Resource to target. Operation will be limited to this
                          resource and its dependencies. This flag can be used
                          multiple times.
@@ -360,11 +473,32 @@ Options:
                          a file. If "terraform.tfvars" is present, it will be
                          automatically loaded if this flag is not specified.
 
+  -var-dir=vars          Load layered variables from the given directory:
+                         "common.tfvars", then each -var-layer, then the file
+                         named after the current environment. -var and -var-file
+                         override layered variables.
+
+  -var-layer=name        Load "name.tfvars" from the -var-dir directory,
+                         which defaults to "vars", as a layer of variables.
+                         This flag can be set multiple times, and later
+                         layers override earlier ones.
+
 
 `
 	return strings.TrimSpace(helpText)
 }
 
+// readGraphOrder reads the ordering of the apply graph at the given path.
+func readGraphOrder(path string) (*terraform.GraphOrder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return terraform.ReadGraphOrder(f)
+}
+
 func outputsAsString(state *terraform.State, modPath []string, schema []*config.Output, includeHeader bool) string {
 	if state == nil {
 		return ""
@@ -401,8 +535,10 @@ func outputsAsString(state *terraform.State, modPath []string, schema []*config.
 		sort.Strings(ks)
 
 		for _, k := range ks {
+			// Outputs of sensitive outputs of child modules are only
+			// marked sensitive in the state.
 			schema, ok := schemaMap[k]
-			if ok && schema.Sensitive {
+			if (ok && schema.Sensitive) || outputs[k].Sensitive {
 				outputBuf.WriteString(fmt.Sprintf("%s = <sensitive>\n", k))
 				continue
 			}
@@ -2,6 +2,7 @@ package command
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -839,6 +840,240 @@ func TestApply_planVars(t *testing.T) {
 	}
 }
 
+func TestApply_graphOrder(t *testing.T) {
+	planPath := testTempFile(t)
+	graphPath := filepath.Join(testTempDir(t), "graph.json")
+	statePath := testTempFile(t)
+
+	p := testProvider()
+	p.DiffReturn = &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"ami": &terraform.ResourceAttrDiff{New: "bar"},
+		},
+	}
+	ui := new(cli.MockUi)
+	pc := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-out", planPath,
+		"-graph-out", graphPath,
+		testFixturePath("apply"),
+	}
+	if code := pc.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	f, err := os.Open(graphPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var g terraform.GraphExport
+	err = json.NewDecoder(f).Decode(&g)
+	f.Close()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Order the graph in stages, each with the nodes whose dependencies
+	// are in earlier stages, like a scheduler would.
+	stageOf := make(map[string]int)
+	for len(stageOf) < len(g.Nodes) {
+		for _, n := range g.Nodes {
+			if _, ok := stageOf[n.ID]; ok {
+				continue
+			}
+
+			stage, ready := 0, true
+			for _, e := range g.Edges {
+				if e.To != n.ID {
+					continue
+				}
+				s, ok := stageOf[e.From]
+				if !ok {
+					ready = false
+					break
+				}
+				if s+1 > stage {
+					stage = s + 1
+				}
+			}
+			if ready {
+				stageOf[n.ID] = stage
+			}
+		}
+	}
+	order := &terraform.GraphOrder{Version: terraform.GraphOrderVersion}
+	for id, stage := range stageOf {
+		for len(order.Stages) <= stage {
+			order.Stages = append(order.Stages, nil)
+		}
+		order.Stages[stage] = append(order.Stages[stage], id)
+	}
+	if _, ok := stageOf["test_instance.foo"]; !ok {
+		t.Fatalf("bad: %#v", g.Nodes)
+	}
+
+	orderPath := filepath.Join(testTempDir(t), "order.json")
+	data, err := json.Marshal(order)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(orderPath, data, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui = new(cli.MockUi)
+	c := &ApplyCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+	args = []string{
+		"-state-out", statePath,
+		"-graph-order", orderPath,
+		planPath,
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !p.ApplyCalled {
+		t.Fatal("apply should be called")
+	}
+
+	// An ordering that doesn't match the graph is rejected before
+	// anything is applied
+	order.Stages = append(order.Stages, []string{"test_instance.bar"})
+	data, err = json.Marshal(order)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(orderPath, data, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p.ApplyCalled = false
+	ui = new(cli.MockUi)
+	c = &ApplyCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), `"test_instance.bar" isn't a node`) {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+	if p.ApplyCalled {
+		t.Fatal("apply shouldn't be called")
+	}
+}
+
+func TestApply_graphOrderInvalid(t *testing.T) {
+	orderPath := filepath.Join(testTempDir(t), "order.json")
+	if err := ioutil.WriteFile(orderPath, []byte(`{"version": 99}`), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ApplyCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+	args := []string{
+		"-state", testTempFile(t),
+		"-graph-order", orderPath,
+		testFixturePath("apply"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "unsupported graph order version") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+func TestApply_planVarEphemeral(t *testing.T) {
+	planPath := testTempFile(t)
+	statePath := testTempFile(t)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	pc := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-var", "password=secret",
+		"-out", planPath,
+		testFixturePath("apply-plan-var-ephemeral"),
+	}
+	if code := pc.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	data, err := ioutil.ReadFile(planPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Fatal("plan shouldn't contain the value of an ephemeral variable")
+	}
+
+	// Ephemeral variables aren't in the plan, so they must be set again
+	ui = new(cli.MockUi)
+	c := &ApplyCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+	args = []string{
+		"-state-out", statePath,
+		"-input=false",
+		planPath,
+	}
+	if code := c.Run(args); code == 0 {
+		t.Fatal("should've failed without the ephemeral variable")
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "password") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+
+	ui = new(cli.MockUi)
+	c = &ApplyCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+	args = []string{
+		"-state-out", statePath,
+		"-var", "password=secret",
+		planPath,
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if v, _ := p.ConfigureConfig.Get("password"); v != "secret" {
+		t.Fatalf("bad: %#v", p.ConfigureConfig)
+	}
+}
+
 // we should be able to apply a plan file with no other file dependencies
 func TestApply_planNoModuleFiles(t *testing.T) {
 	// temporary data directory which we can remove between commands
@@ -1187,6 +1422,62 @@ func TestApply_sensitiveOutput(t *testing.T) {
 	}
 }
 
+func TestApply_resultOut(t *testing.T) {
+	statePath := testTempFile(t)
+	resultPath := filepath.Join(filepath.Dir(statePath), "result.json")
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ApplyCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-result-out", resultPath,
+		testFixturePath("apply-result-out"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	data, err := ioutil.ReadFile(resultPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var result struct {
+		Operation string
+		Success   bool
+		Resources []struct {
+			Address string
+			Action  string
+			Status  string
+		}
+		Outputs map[string]struct {
+			Value interface{}
+		}
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if result.Operation != "apply" || !result.Success {
+		t.Fatalf("bad: %s", data)
+	}
+	if len(result.Resources) != 1 {
+		t.Fatalf("bad: %s", data)
+	}
+	if r := result.Resources[0]; r.Address != "test_instance.foo" || r.Action != "create" || r.Status != "complete" {
+		t.Fatalf("bad: %s", data)
+	}
+	if v := result.Outputs["greeting"].Value; v != "hello" {
+		t.Fatalf("bad: %s", data)
+	}
+}
+
 func TestApply_stateFuture(t *testing.T) {
 	originalState := testState()
 	originalState.TFVersion = "99.99.99"
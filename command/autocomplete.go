@@ -0,0 +1,202 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+// Completer is implemented by the commands that can suggest values for their
+// arguments when the shell completes a command line.
+type Completer interface {
+	// AutocompleteArgs returns the candidates for the argument being
+	// completed, which starts with prefix. args are the arguments of the
+	// command before it, flags included.
+	AutocompleteArgs(args []string, prefix string) []string
+}
+
+// completePositional returns the number of positional arguments in args, so
+// that commands can tell which of their arguments is being completed.
+func completePositional(args []string) int {
+	n := 0
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			n++
+		}
+	}
+
+	return n
+}
+
+// completeFlag returns the value of the flag name in args, in its -name=value
+// form, or an empty string if it isn't set.
+func completeFlag(args []string, name string) string {
+	for _, arg := range args {
+		for _, p := range []string{"-", "--"} {
+			if v := strings.TrimPrefix(arg, p+name+"="); v != arg {
+				return v
+			}
+		}
+	}
+
+	return ""
+}
+
+// completeQuiet prepares the meta for completing a command line. The shell
+// reads the candidates from the output, so nothing else may be written to
+// it, and there is no one to answer questions.
+func (m *Meta) completeQuiet() {
+	m.Ui = &cli.BasicUi{
+		Reader:      strings.NewReader(""),
+		Writer:      ioutil.Discard,
+		ErrorWriter: ioutil.Discard,
+	}
+	m.input = false
+}
+
+// completeEnvs returns the names of the environments of the backend
+// configured in the working directory.
+func (m *Meta) completeEnvs() []string {
+	m.completeQuiet()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	cfg, err := m.ConfigTerraform(pwd)
+	if err != nil {
+		return nil
+	}
+	b, err := m.Backend(&BackendOpts{Config: cfg})
+	if err != nil {
+		return nil
+	}
+
+	states, err := b.States()
+	if err != nil {
+		return nil
+	}
+
+	return states
+}
+
+// completeState returns the state of the current environment, or nil if it
+// can't be read.
+func (m *Meta) completeState() *terraform.State {
+	m.completeQuiet()
+
+	b, err := m.Backend(nil)
+	if err != nil {
+		return nil
+	}
+	s, err := b.State(m.Env())
+	if err != nil {
+		return nil
+	}
+	if err := s.RefreshState(); err != nil {
+		return nil
+	}
+
+	return s.State()
+}
+
+// completeResourceAddresses returns the addresses of the resource instances
+// in the state, as accepted by the state commands.
+func (m *Meta) completeResourceAddresses() []string {
+	s := m.completeState()
+	if s == nil {
+		return nil
+	}
+
+	filter := &terraform.StateFilter{State: s}
+	results, err := filter.Filter()
+	if err != nil {
+		return nil
+	}
+
+	var addrs []string
+	for _, result := range results {
+		if _, ok := result.Value.(*terraform.InstanceState); ok {
+			addrs = append(addrs, result.Address)
+		}
+	}
+
+	return addrs
+}
+
+// completeResourceNames returns the names of the resources of the module
+// with the given path in the state, as accepted by the taint commands.
+func (m *Meta) completeResourceNames(path []string) []string {
+	s := m.completeState()
+	if s == nil {
+		return nil
+	}
+
+	mod := s.ModuleByPath(path)
+	if mod == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(mod.Resources))
+	for k := range mod.Resources {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// completeModuleSources returns the module sources that start with prefix:
+// the directories it names, and the sources of the modules that are used by
+// the configuration in the working directory.
+func completeModuleSources(prefix string) []string {
+	sources := completeDirs(prefix)
+
+	cfg, err := config.LoadDir(".")
+	if err != nil {
+		return sources
+	}
+	for _, c := range cfg.Modules {
+		sources = append(sources, c.Source)
+	}
+
+	return sources
+}
+
+// completeDirs returns the directories that start with prefix, with a
+// trailing separator so that their subdirectories can be completed next.
+func completeDirs(prefix string) []string {
+	dir, base := filepath.Split(prefix)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+	infos, err := ioutil.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+
+	var dirs []string
+	for _, info := range infos {
+		name := info.Name()
+		if !info.IsDir() || !strings.HasPrefix(name, base) {
+			continue
+		}
+
+		// Hidden directories, such as the data directory, are only
+		// suggested if asked for.
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+
+		dirs = append(dirs, dir+name+string(filepath.Separator))
+	}
+
+	return dirs
+}
@@ -0,0 +1,144 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/terraform/backend/local"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+func TestEnvSelect_autocomplete(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	for _, env := range []string{"foo", "bar"} {
+		if err := os.MkdirAll(filepath.Join(local.DefaultEnvDir, env), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &EnvSelectCommand{
+		Meta: Meta{Ui: new(cli.MockUi)},
+	}
+
+	actual := c.AutocompleteArgs(nil, "")
+	sort.Strings(actual)
+	expected := []string{"bar", "default", "foo"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Only the name is completed
+	if actual := c.AutocompleteArgs([]string{"foo"}, ""); len(actual) != 0 {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestStateShow_autocomplete(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	state := testState()
+	state.AddModuleState(&terraform.ModuleState{
+		Path: []string{"root", "child"},
+		Resources: map[string]*terraform.ResourceState{
+			"test_instance.bar": &terraform.ResourceState{
+				Type: "test_instance",
+				Primary: &terraform.InstanceState{
+					ID: "bar",
+				},
+			},
+		},
+	})
+	testStateFileDefault(t, state)
+
+	c := &StateShowCommand{
+		Meta: Meta{Ui: new(cli.MockUi)},
+	}
+
+	actual := c.AutocompleteArgs(nil, "")
+	sort.Strings(actual)
+	expected := []string{"module.child.test_instance.bar", "test_instance.foo"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestTaint_autocomplete(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	state := testState()
+	state.AddModuleState(&terraform.ModuleState{
+		Path: []string{"root", "child"},
+		Resources: map[string]*terraform.ResourceState{
+			"test_instance.bar": &terraform.ResourceState{
+				Type: "test_instance",
+				Primary: &terraform.InstanceState{
+					ID: "bar",
+				},
+			},
+		},
+	})
+	testStateFileDefault(t, state)
+
+	c := &TaintCommand{
+		Meta: Meta{Ui: new(cli.MockUi)},
+	}
+
+	actual := c.AutocompleteArgs(nil, "")
+	if expected := []string{"test_instance.foo"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	actual = c.AutocompleteArgs([]string{"-module=child"}, "")
+	if expected := []string{"test_instance.bar"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestInit_autocomplete(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	for _, dir := range []string{"modules/network", "modules/compute", ".terraform"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := `
+module "consul" {
+  source = "hashicorp/consul/aws"
+}
+`
+	if err := ioutil.WriteFile("main.tf", []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &InitCommand{
+		Meta: Meta{Ui: new(cli.MockUi)},
+	}
+
+	actual := c.AutocompleteArgs(nil, "")
+	sort.Strings(actual)
+	expected := []string{"hashicorp/consul/aws", "modules" + string(filepath.Separator)}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The path is completed as a directory
+	prefix := "modules" + string(filepath.Separator) + "n"
+	actual = c.AutocompleteArgs([]string{"hashicorp/consul/aws"}, prefix)
+	expected = []string{filepath.Join("modules", "network") + string(filepath.Separator)}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
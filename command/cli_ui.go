@@ -2,6 +2,9 @@ package command
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/mitchellh/cli"
 	"github.com/mitchellh/colorstring"
@@ -49,3 +52,134 @@ func (u *ColorizeUi) colorize(message string, color string) string {
 
 	return u.Colorize.Color(fmt.Sprintf("%s%s[reset]", color, message))
 }
+
+// RenderUi is a Ui implementation that renders messages for where they're
+// shown: it replaces the symbols of messages with ASCII ones if ASCII is
+// set, and wraps error and warning messages to Width if it's set. Output is
+// never changed, since it carries values and may be read by programs, such
+// as the values of outputs and -json output.
+type RenderUi struct {
+	ASCII bool
+	Width int
+
+	// Colors are the colors that messages are marked up with, which take
+	// up no width.
+	Colors map[string]string
+
+	Ui cli.Ui
+}
+
+func (u *RenderUi) Ask(query string) (string, error) {
+	return u.Ui.Ask(u.render(query, false))
+}
+
+func (u *RenderUi) AskSecret(query string) (string, error) {
+	return u.Ui.AskSecret(u.render(query, false))
+}
+
+func (u *RenderUi) Output(message string) {
+	u.Ui.Output(message)
+}
+
+func (u *RenderUi) Info(message string) {
+	u.Ui.Info(u.render(message, false))
+}
+
+func (u *RenderUi) Error(message string) {
+	u.Ui.Error(u.render(message, true))
+}
+
+func (u *RenderUi) Warn(message string) {
+	u.Ui.Warn(u.render(message, true))
+}
+
+func (u *RenderUi) render(message string, wrap bool) string {
+	if u.ASCII {
+		message = asciiSymbols.Replace(message)
+	}
+	if wrap && u.Width > 0 {
+		message = wrapText(message, u.Width, u.Colors)
+	}
+
+	return message
+}
+
+// asciiSymbols replaces the typographic symbols that messages are
+// decorated with, such as quotes, dashes, arrows and box drawing, with ASCII
+// ones. Other characters are left alone, since they're part of names and
+// values rather than decorations.
+var asciiSymbols = strings.NewReplacer(
+	"\u2018", "'", // left single quotation mark
+	"\u2019", "'", // right single quotation mark
+	"\u201c", `"`, // left double quotation mark
+	"\u201d", `"`, // right double quotation mark
+	"\u2013", "-", // en dash
+	"\u2014", "--", // em dash
+	"\u2026", "...", // horizontal ellipsis
+	"\u2022", "*", // bullet
+	"\u2192", "->", // rightwards arrow
+	"\u2190", "<-", // leftwards arrow
+	"\u2713", "+", // check mark
+	"\u2717", "x", // ballot x
+	"\u2500", "-", // box drawings light horizontal
+	"\u2502", "|", // box drawings light vertical
+	"\u250c", "+", // box drawings light down and right
+	"\u2514", "+", // box drawings light up and right
+	"\u251c", "+", // box drawings light vertical and right
+	"\u2577", "|", // box drawings light down
+	"\u2575", "|", // box drawings light up
+)
+
+// ansiEscapeRe matches the ANSI escape sequences that colors are set with.
+var ansiEscapeRe = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// colorMarkupRe matches the markup that colorstring replaces with colors.
+var colorMarkupRe = regexp.MustCompile(`\[[a-z_]+\]`)
+
+// wrapText wraps the lines of s that are longer than width at spaces. The
+// lines they're wrapped onto are indented as the line was. Words longer
+// than width aren't broken. Color markup and escapes take up no width.
+func wrapText(s string, width int, colors map[string]string) string {
+	textWidth := func(s string) int {
+		s = ansiEscapeRe.ReplaceAllString(s, "")
+		s = colorMarkupRe.ReplaceAllStringFunc(s, func(m string) string {
+			if _, ok := colors[m[1:len(m)-1]]; ok {
+				return ""
+			}
+			return m
+		})
+		return utf8.RuneCountInString(s)
+	}
+
+	lines := strings.Split(s, "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if textWidth(line) <= width {
+			result = append(result, line)
+			continue
+		}
+
+		text := strings.TrimLeft(line, " \t")
+		indent := line[:len(line)-len(text)]
+		current := indent
+		currentWidth := textWidth(indent)
+		empty := true
+		for _, word := range strings.Split(text, " ") {
+			w := textWidth(word)
+			if !empty && currentWidth+1+w > width {
+				result = append(result, current)
+				current, currentWidth, empty = indent, textWidth(indent), true
+			}
+			if !empty {
+				current += " "
+				currentWidth++
+			}
+			current += word
+			currentWidth += w
+			empty = false
+		}
+		result = append(result, current)
+	}
+
+	return strings.Join(result, "\n")
+}
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/mitchellh/cli"
@@ -9,3 +10,53 @@ import (
 func TestColorizeUi_impl(t *testing.T) {
 	var _ cli.Ui = new(ColorizeUi)
 }
+
+func TestRenderUi_impl(t *testing.T) {
+	var _ cli.Ui = new(RenderUi)
+}
+
+func TestAsciiSymbols(t *testing.T) {
+	cases := map[string]string{
+		"plain":                                "plain",
+		"\u201cquoted\u201d \u2014 text\u2026": `"quoted" -- text...`,
+		"\u2502 \u2192 next":                   "| -> next",
+		"caf\u00e9 \U0001f600":                 "caf\u00e9 \U0001f600",
+	}
+
+	for input, expected := range cases {
+		if actual := asciiSymbols.Replace(input); actual != expected {
+			t.Errorf("%q: expected %q, got %q", input, expected, actual)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	colors := map[string]string{"red": "31", "reset": "0"}
+
+	input := strings.Join([]string{
+		"short line",
+		"this line is longer than the width",
+		"    indented lines stay indented when wrapped",
+		"[red]markup takes[reset] up no \x1b[31mwidth\x1b[0m at all",
+		"averyveryverylongword is not broken",
+		"[unknown] markup counts",
+	}, "\n")
+	expected := strings.Join([]string{
+		"short line",
+		"this line is longer",
+		"than the width",
+		"    indented lines",
+		"    stay indented",
+		"    when wrapped",
+		"[red]markup takes[reset] up no",
+		"\x1b[31mwidth\x1b[0m at all",
+		"averyveryverylongword",
+		"is not broken",
+		"[unknown] markup",
+		"counts",
+	}, "\n")
+
+	if actual := wrapText(input, 20, colors); actual != expected {
+		t.Fatalf("expected:\n%s\n\ngot:\n%s", expected, actual)
+	}
+}
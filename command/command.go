@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
+	"github.com/mitchellh/panicwrap"
 )
 
 // Set to true when we're testing
@@ -21,6 +23,14 @@ const DefaultStateFilename = "terraform.tfstate"
 // DefaultVarsFilename is the default filename used for vars
 const DefaultVarsFilename = "terraform.tfvars"
 
+// DefaultVarLayersDir is the default directory that layered variables are
+// loaded from, when they are enabled with -var-layer.
+const DefaultVarLayersDir = "vars"
+
+// VarLayersCommonName is the name of the layer of variables that's loaded
+// first, before any other layers, when layered variables are enabled.
+const VarLayersCommonName = "common"
+
 // DefaultBackupExtension is added to the state file to form the path
 const DefaultBackupExtension = ".backup"
 
@@ -105,3 +115,18 @@ func validateContext(ctx *terraform.Context, ui cli.Ui) bool {
 
 	return true
 }
+
+// childEnv returns the environment to run the current executable in as a
+// child process, with the given variables added. The variable that marks
+// this process as wrapped by panicwrap is removed, so that the child wraps
+// itself and its output is the same as when it's run directly.
+func childEnv(vars ...string) []string {
+	var result []string
+	for _, v := range os.Environ() {
+		if !strings.HasPrefix(v, panicwrap.DEFAULT_COOKIE_KEY+"=") {
+			result = append(result, v)
+		}
+	}
+
+	return append(result, vars...)
+}
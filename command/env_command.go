@@ -34,6 +34,7 @@ Subcommands:
     select    Select an environment.
     new       Create a new environment.
     delete    Delete an existing environment.
+    foreach   Run a command in each environment.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -46,7 +47,7 @@ func (c *EnvCommand) Synopsis() string {
 // Since most named states are accessed via a filesystem path or URL, check if
 // escaping the name would be required.
 func validEnvName(name string) bool {
-	return name == url.PathEscape(name)
+	return name == url.PathEscape(name) && name != "." && name != ".."
 }
 
 const (
@@ -1,11 +1,17 @@
 package command
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/backend/local"
@@ -191,6 +197,73 @@ func TestEnv_createWithState(t *testing.T) {
 	}
 }
 
+func TestEnv_listVerbose(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	// create a non-empty state
+	originalState := &terraform.State{
+		Serial: 3,
+		Modules: []*terraform.ModuleState{
+			&terraform.ModuleState{
+				Path: []string{"root"},
+				Resources: map[string]*terraform.ResourceState{
+					"test_instance.foo": &terraform.ResourceState{
+						Type: "test_instance",
+						Primary: &terraform.InstanceState{
+							ID: "bar",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := (&state.LocalState{Path: "test.tfstate"}).WriteState(originalState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newCmd := &EnvNewCommand{}
+	for _, args := range [][]string{{"empty"}, {"-state", "test.tfstate", "test"}} {
+		ui := new(cli.MockUi)
+		newCmd.Meta = Meta{Ui: ui}
+		if code := newCmd.Run(args); code != 0 {
+			t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter)
+		}
+	}
+
+	newPath := filepath.Join(local.DefaultEnvDir, "test", DefaultStateFilename)
+	modTime := time.Date(2017, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(newPath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	envState := state.LocalState{Path: newPath}
+	if err := envState.RefreshState(); err != nil {
+		t.Fatal(err)
+	}
+
+	listCmd := &EnvListCommand{}
+	ui := new(cli.MockUi)
+	listCmd.Meta = Meta{Ui: ui}
+	if code := listCmd.Run([]string{"-verbose"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter)
+	}
+
+	actual := strings.TrimSpace(ui.OutputWriter.String())
+	expected := strings.TrimSpace(fmt.Sprintf(`
+NAME     SERIAL  RESOURCES  LAST MODIFIED
+  default  -       -          -
+  empty    -       -          -
+* test     %-6d  1          %s`,
+		envState.State().Serial, modTime.Local().Format(time.RFC3339)))
+	if actual != expected {
+		t.Fatalf("\nexpected:\n%s\n\nactual:\n%s", expected, actual)
+	}
+}
+
 func TestEnv_delete(t *testing.T) {
 	td := tempDir(t)
 	os.MkdirAll(td, 0755)
@@ -298,3 +371,144 @@ func TestEnv_deleteWithState(t *testing.T) {
 		t.Fatal("env 'test' still exists!")
 	}
 }
+
+func TestEnv_workspaceEnvVar(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	m := &Meta{}
+	if err := m.SetEnv("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Setenv(WorkspaceEnvVar, os.Getenv(WorkspaceEnvVar))
+	os.Setenv(WorkspaceEnvVar, "other")
+	if env := m.Env(); env != "other" {
+		t.Fatalf("env should be set by %s, got %q", WorkspaceEnvVar, env)
+	}
+
+	os.Unsetenv(WorkspaceEnvVar)
+	if env := m.Env(); env != "test" {
+		t.Fatalf("current env should be 'test', got %q", env)
+	}
+}
+
+func TestEnv_foreach(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	for _, env := range []string{"dev", "prod-a", "prod-b"} {
+		if err := os.MkdirAll(filepath.Join(local.DefaultEnvDir, env), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	var ran []string
+	ui := new(cli.MockUi)
+	c := &EnvForeachCommand{
+		Meta: Meta{Ui: ui},
+		runEnv: func(env string, args []string) ([]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			ran = append(ran, env)
+
+			if !reflect.DeepEqual(args, []string{"plan", "-input=false", "-no-color"}) {
+				t.Errorf("bad args: %#v", args)
+			}
+			if env == "prod-b" {
+				return []byte("planning " + env + " failed\n"), errors.New("exit status 1")
+			}
+			return []byte("planned " + env + "\n"), nil
+		},
+	}
+
+	args := []string{"-filter=prod-*", "-parallelism=2", "--", "plan", "-input=false"}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter)
+	}
+
+	sort.Strings(ran)
+	if !reflect.DeepEqual(ran, []string{"prod-a", "prod-b"}) {
+		t.Fatalf("bad: %#v", ran)
+	}
+
+	output := ui.OutputWriter.String()
+	for _, s := range []string{
+		"planned prod-a",
+		"planning prod-b failed",
+		`Environment "prod-b" failed: exit status 1`,
+	} {
+		if !strings.Contains(output, s) {
+			t.Fatalf("output should contain %q:\n%s", s, output)
+		}
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "failed in 1 of 2 environments") {
+		t.Fatalf("bad: %s", ui.ErrorWriter)
+	}
+
+	// The summary lists the environments in order
+	summary := output[strings.Index(output, "ENVIRONMENT"):]
+	if strings.Index(summary, "prod-a") > strings.Index(summary, "prod-b") {
+		t.Fatalf("bad: %s", summary)
+	}
+}
+
+func TestEnv_foreachFlags(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	if err := os.MkdirAll("config", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ui := new(cli.MockUi)
+	c := &EnvForeachCommand{
+		Meta: Meta{Ui: ui, Color: true},
+		runEnv: func(env string, args []string) ([]byte, error) {
+			// The flags that were taken out are passed on, and the
+			// directory follows them
+			expected := []string{"plan", "-read-only", "config"}
+			if !reflect.DeepEqual(args, expected) {
+				t.Errorf("bad args: %#v", args)
+			}
+			return nil, nil
+		},
+	}
+
+	args := []string{"-read-only", "config", "--", "plan"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter)
+	}
+}
+
+func TestEnv_workspaceEnvVarInvalid(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	defer os.Setenv(WorkspaceEnvVar, os.Getenv(WorkspaceEnvVar))
+	for _, name := range []string{"../x", ".."} {
+		os.Setenv(WorkspaceEnvVar, name)
+
+		m := &Meta{Ui: new(cli.MockUi)}
+		if _, err := m.Backend(nil); err == nil {
+			t.Fatalf("%q: should error", name)
+		}
+	}
+}
+
+func TestEnv_foreachNoCommand(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &EnvForeachCommand{Meta: Meta{Ui: ui}}
+	if code := c.Run([]string{"-parallelism=2"}); code == 0 {
+		t.Fatal("expected failure without a command")
+	}
+}
@@ -16,6 +16,9 @@ type EnvDeleteCommand struct {
 
 func (c *EnvDeleteCommand) Run(args []string) int {
 	args = c.Meta.process(args, true)
+	if c.Meta.checkReadOnly("env delete") {
+		return 1
+	}
 
 	force := false
 	cmdFlags := c.Meta.flagSet("env")
@@ -43,13 +46,15 @@ func (c *EnvDeleteCommand) Run(args []string) int {
 		return 1
 	}
 
-	cfg, err := c.Config(configPath)
+	cfg, err := c.ConfigTerraform(configPath)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to load root config module: %s", err))
 		return 1
 	}
 
-	// Load the backend
+	// Load the backend of the workspace to delete, since its configuration
+	// can depend on the workspace.
+	c.backendEnv = delEnv
 	b, err := c.Backend(&BackendOpts{
 		Config: cfg,
 	})
@@ -139,6 +144,14 @@ func (c *EnvDeleteCommand) Run(args []string) int {
 
 	return 0
 }
+func (c *EnvDeleteCommand) AutocompleteArgs(args []string, prefix string) []string {
+	if completePositional(args) > 0 {
+		return nil
+	}
+
+	return c.completeEnvs()
+}
+
 func (c *EnvDeleteCommand) Help() string {
 	helpText := `
 Usage: terraform env delete [OPTIONS] NAME [DIR]
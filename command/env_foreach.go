@@ -0,0 +1,277 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+)
+
+// EnvForeachCommand is a Command implementation that runs another command
+// in each environment.
+type EnvForeachCommand struct {
+	Meta
+
+	// runEnv runs terraform with the given arguments in the environment
+	// with the given name, and returns its combined output. This runs the
+	// current executable by default, but is provided here as a way to mock
+	// running commands for tests.
+	runEnv func(env string, args []string) ([]byte, error)
+}
+
+// envForeachResult is the result of running the command in an environment.
+type envForeachResult struct {
+	Output   []byte
+	Err      error
+	Duration time.Duration
+}
+
+func (c *EnvForeachCommand) Run(args []string) int {
+	var parallelism int
+	var filters []string
+
+	// Everything after "--" is the command to run, along with its own
+	// flags, which mustn't be parsed as ours.
+	var command []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, command = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	args = c.Meta.process(args, true)
+
+	cmdFlags := c.Meta.flagSet("env foreach")
+	cmdFlags.IntVar(&parallelism, "parallelism", 4, "parallelism")
+	cmdFlags.Var((*FlagStringSlice)(&filters), "filter", "filter")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(command) == 0 {
+		c.Ui.Error("Expected a command to run after \"--\".\n")
+		return cli.RunResultHelp
+	}
+	if parallelism < 1 {
+		c.Ui.Error("The -parallelism option must be at least 1.\n")
+		return 1
+	}
+	for _, f := range filters {
+		if _, err := path.Match(f, ""); err != nil {
+			c.Ui.Error(fmt.Sprintf("Invalid -filter pattern %q: %s\n", f, err))
+			return 1
+		}
+	}
+
+	configPath, err := ModulePath(cmdFlags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	cfg, err := c.ConfigTerraform(configPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load root config module: %s", err))
+		return 1
+	}
+
+	// Load the backend
+	b, err := c.Backend(&BackendOpts{
+		Config: cfg,
+	})
+
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load backend: %s", err))
+		return 1
+	}
+
+	states, err := b.States()
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	var envs []string
+	for _, name := range states {
+		if envForeachMatch(filters, name) {
+			envs = append(envs, name)
+		}
+	}
+	if len(envs) == 0 {
+		c.Ui.Error("No environments match the filters.\n")
+		return 1
+	}
+
+	if c.runEnv == nil {
+		c.runEnv = envForeachExec
+	}
+
+	// The flags that were taken out of our arguments are passed on, along
+	// with the directory that the backend was loaded from. They're taken
+	// out of the arguments wherever they are, so they can follow those of
+	// the command.
+	command = append([]string(nil), command...)
+	if !c.color {
+		command = append(command, "-no-color")
+	}
+	if c.readOnly {
+		command = append(command, "-read-only")
+	}
+	if len(cmdFlags.Args()) > 0 {
+		command = append(command, configPath)
+	}
+
+	// The output of each environment is shown as a whole once its command
+	// is done, so that the output of environments running at the same time
+	// isn't interleaved.
+	var mu sync.Mutex
+	results := make(map[string]*envForeachResult)
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for _, name := range envs {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			output, err := c.runEnv(name, command)
+			r := &envForeachResult{
+				Output:   output,
+				Err:      err,
+				Duration: time.Since(start),
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = r
+			c.outputResult(name, r)
+		}(name)
+	}
+	wg.Wait()
+
+	// Summarize the results in the order of the environments
+	failed := 0
+	summary := []string{"ENVIRONMENT | RESULT | DURATION"}
+	for _, name := range envs {
+		r := results[name]
+		result := "ok"
+		if r.Err != nil {
+			result = r.Err.Error()
+			failed++
+		}
+
+		summary = append(summary, fmt.Sprintf(
+			"%s | %s | %s", name, result, roundSeconds(r.Duration)))
+	}
+
+	c.Ui.Output("\n" + columnize.SimpleFormat(summary) + "\n")
+	if failed > 0 {
+		c.Ui.Error(fmt.Sprintf(
+			"The command failed in %d of %d environments.", failed, len(envs)))
+		return 1
+	}
+
+	return 0
+}
+
+// outputResult outputs the result of running the command in the
+// environment with the given name.
+func (c *EnvForeachCommand) outputResult(name string, r *envForeachResult) {
+	status := "[green]done"
+	if r.Err != nil {
+		status = "[red]failed: " + r.Err.Error()
+	}
+
+	c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
+		"[reset][bold]Environment %q %s[reset][bold] after %s:",
+		name, status, roundSeconds(r.Duration))))
+	c.Ui.Output(string(bytes.TrimRight(r.Output, "\n")) + "\n")
+}
+
+// roundSeconds rounds d to the nearest second, like d.Round(time.Second),
+// which isn't available before Go 1.9.
+func roundSeconds(d time.Duration) time.Duration {
+	return (d + time.Second/2) / time.Second * time.Second
+}
+
+// envForeachMatch returns true if the environment with the given name
+// matches any of the filters, or there are no filters.
+func envForeachMatch(filters []string, name string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+
+	for _, f := range filters {
+		if ok, _ := path.Match(f, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// envForeachExec runs the current executable with the given arguments in
+// the environment with the given name. Input is disabled, since the
+// commands run at the same time and there's nobody to answer them.
+func envForeachExec(env string, args []string) ([]byte, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(exe, args...)
+	cmd.Env = childEnv(
+		WorkspaceEnvVar+"="+env,
+		InputModeEnvVar+"=0",
+	)
+	return cmd.CombinedOutput()
+}
+
+func (c *EnvForeachCommand) Help() string {
+	helpText := `
+Usage: terraform env foreach [options] [DIR] -- COMMAND [ARGS...]
+
+  Run a Terraform command in each environment, such as
+  "terraform env foreach -- plan -input=false". The command runs in several
+  environments at the same time, without changing the selected environment,
+  and its output is shown for each environment once it's done, followed by
+  a summary of the results.
+
+  The command runs with the TF_WORKSPACE environment variable set to the
+  name of each environment, and with input disabled. If DIR is given, it's
+  passed to the command as its last argument, so the command must take a
+  directory, such as "plan" or "apply".
+
+Options:
+
+  -filter=pattern     Only run the command in environments whose names match
+                      the pattern, such as "prod-*". This flag can be set
+                      multiple times, to run the command in the environments
+                      that match any of them.
+
+  -parallelism=4      The number of environments to run the command in at
+                      the same time.
+
+  -no-color           If specified, output won't contain any color, in
+                      the output of the command too.
+
+  -read-only          Run the command in read-only mode. This is also the
+                      case if TF_READ_ONLY is set.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *EnvForeachCommand) Synopsis() string {
+	return "Run a command in each environment"
+}
@@ -0,0 +1,24 @@
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoundSeconds(t *testing.T) {
+	cases := []struct {
+		In, Out time.Duration
+	}{
+		{0, 0},
+		{499 * time.Millisecond, 0},
+		{500 * time.Millisecond, time.Second},
+		{90*time.Second + 400*time.Millisecond, 90 * time.Second},
+		{90*time.Second + 600*time.Millisecond, 91 * time.Second},
+	}
+
+	for _, tc := range cases {
+		if actual := roundSeconds(tc.In); actual != tc.Out {
+			t.Fatalf("%s: expected %s, got %s", tc.In, tc.Out, actual)
+		}
+	}
+}
@@ -4,6 +4,11 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/state"
+	"github.com/ryanuber/columnize"
 )
 
 type EnvListCommand struct {
@@ -11,9 +16,12 @@ type EnvListCommand struct {
 }
 
 func (c *EnvListCommand) Run(args []string) int {
+	var verbose bool
+
 	args = c.Meta.process(args, true)
 
 	cmdFlags := c.Meta.flagSet("env list")
+	cmdFlags.BoolVar(&verbose, "verbose", false, "verbose")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
@@ -26,7 +34,7 @@ func (c *EnvListCommand) Run(args []string) int {
 		return 1
 	}
 
-	cfg, err := c.Config(configPath)
+	cfg, err := c.ConfigTerraform(configPath)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to load root config module: %s", err))
 		return 1
@@ -50,6 +58,10 @@ func (c *EnvListCommand) Run(args []string) int {
 
 	env := c.Env()
 
+	if verbose {
+		return c.listVerbose(b, states, env)
+	}
+
 	var out bytes.Buffer
 	for _, s := range states {
 		if s == env {
@@ -64,11 +76,77 @@ func (c *EnvListCommand) Run(args []string) int {
 	return 0
 }
 
+// listVerbose lists the environments along with the serial, resource count
+// and modification time of their states, which reads each state from the
+// backend.
+func (c *EnvListCommand) listVerbose(b backend.Backend, states []string, env string) int {
+	output := []string{"NAME | SERIAL | RESOURCES | LAST MODIFIED"}
+	failed := false
+	for _, name := range states {
+		serial, resources, modified := "-", "-", "-"
+		s, err := b.State(name)
+		if err == nil {
+			err = s.RefreshState()
+		}
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to read the state of %q: %s", name, err))
+			failed = true
+		} else {
+			if st := s.State(); st != nil {
+				serial = fmt.Sprintf("%d", st.Serial)
+
+				count := 0
+				for _, m := range st.Modules {
+					count += len(m.Resources)
+				}
+				resources = fmt.Sprintf("%d", count)
+			}
+
+			if mt, ok := s.(state.StateModTimer); ok {
+				if t := mt.ModTime(); !t.IsZero() {
+					modified = t.Local().Format(time.RFC3339)
+				}
+			}
+		}
+
+		output = append(output, fmt.Sprintf(
+			"%s | %s | %s | %s", name, serial, resources, modified))
+	}
+
+	// Columnize trims the columns, so the current environment is marked
+	// once they have been lined up.
+	var out bytes.Buffer
+	for i, line := range strings.Split(columnize.SimpleFormat(output), "\n") {
+		if i > 0 && states[i-1] == env {
+			out.WriteString("* ")
+		} else {
+			out.WriteString("  ")
+		}
+		out.WriteString(line + "\n")
+	}
+
+	c.Ui.Output(out.String())
+	if failed {
+		return 1
+	}
+
+	return 0
+}
+
 func (c *EnvListCommand) Help() string {
 	helpText := `
-Usage: terraform env list [DIR]
+Usage: terraform env list [options] [DIR]
 
   List Terraform environments.
+
+Options:
+
+  -verbose    Also show the serial, the number of resources and the time
+              of the last change of each environment's state, to help find
+              environments that are no longer used. This reads the state
+              of every environment from the backend. A "-" is shown for
+              anything that isn't known, such as for environments without
+              a state.
 `
 	return strings.TrimSpace(helpText)
 }
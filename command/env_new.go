@@ -18,6 +18,9 @@ type EnvNewCommand struct {
 
 func (c *EnvNewCommand) Run(args []string) int {
 	args = c.Meta.process(args, true)
+	if c.Meta.checkReadOnly("env new") {
+		return 1
+	}
 
 	statePath := ""
 
@@ -46,12 +49,14 @@ func (c *EnvNewCommand) Run(args []string) int {
 		return 1
 	}
 
-	conf, err := c.Config(configPath)
+	conf, err := c.ConfigTerraform(configPath)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to load root config module: %s", err))
 	}
 
-	// Load the backend
+	// Load the backend of the new workspace, since its configuration can
+	// depend on the workspace.
+	c.backendEnv = newEnv
 	b, err := c.Backend(&BackendOpts{
 		Config: conf,
 	})
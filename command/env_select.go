@@ -31,13 +31,21 @@ func (c *EnvSelectCommand) Run(args []string) int {
 		return 1
 	}
 
-	conf, err := c.Config(configPath)
+	conf, err := c.ConfigTerraform(configPath)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to load root config module: %s", err))
 		return 1
 	}
 
-	// Load the backend
+	name := args[0]
+	if !validEnvName(name) {
+		c.Ui.Error(fmt.Sprintf(envInvalidName, name))
+		return 1
+	}
+
+	// Load the backend of the workspace to select, since its configuration
+	// can depend on the workspace.
+	c.backendEnv = name
 	b, err := c.Backend(&BackendOpts{
 		Config: conf,
 	})
@@ -47,12 +55,6 @@ func (c *EnvSelectCommand) Run(args []string) int {
 		return 1
 	}
 
-	name := args[0]
-	if !validEnvName(name) {
-		c.Ui.Error(fmt.Sprintf(envInvalidName, name))
-		return 1
-	}
-
 	states, err := b.States()
 	if err != nil {
 		c.Ui.Error(err.Error())
@@ -92,6 +94,14 @@ func (c *EnvSelectCommand) Run(args []string) int {
 	return 0
 }
 
+func (c *EnvSelectCommand) AutocompleteArgs(args []string, prefix string) []string {
+	if completePositional(args) > 0 {
+		return nil
+	}
+
+	return c.completeEnvs()
+}
+
 func (c *EnvSelectCommand) Help() string {
 	helpText := `
 Usage: terraform env select NAME [DIR]
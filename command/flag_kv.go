@@ -2,6 +2,7 @@ package command
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -41,3 +42,31 @@ func (v *FlagStringSlice) Set(raw string) error {
 
 	return nil
 }
+
+// FlagParallelism is a flag.Value implementation for parsing -parallelism,
+// which is either a number or "auto" to tune the parallelism while
+// operations run.
+type FlagParallelism struct {
+	N    *int
+	Auto *bool
+}
+
+func (v FlagParallelism) String() string {
+	return ""
+}
+
+func (v FlagParallelism) Set(raw string) error {
+	if raw == "auto" {
+		*v.Auto = true
+		return nil
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return fmt.Errorf("must be a number or \"auto\": %s", raw)
+	}
+
+	*v.N = n
+	*v.Auto = false
+	return nil
+}
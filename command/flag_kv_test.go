@@ -66,3 +66,29 @@ func TestFlagStringKV(t *testing.T) {
 		}
 	}
 }
+
+func TestFlagParallelism(t *testing.T) {
+	cases := []struct {
+		Input string
+		N     int
+		Auto  bool
+		Error bool
+	}{
+		{"5", 5, false, false},
+		{"auto", 10, true, false},
+		{"-1", 10, false, true},
+		{"fast", 10, false, true},
+	}
+
+	for _, tc := range cases {
+		n, auto := 10, false
+		err := FlagParallelism{N: &n, Auto: &auto}.Set(tc.Input)
+		if err != nil != tc.Error {
+			t.Fatalf("bad error. Input: %#v\n\nError: %s", tc.Input, err)
+		}
+
+		if n != tc.N || auto != tc.Auto {
+			t.Fatalf("%s: bad: %d %t", tc.Input, n, auto)
+		}
+	}
+}
@@ -1,13 +1,16 @@
 package command
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
-	"github.com/hashicorp/hcl/hcl/fmtcmd"
+	"github.com/hashicorp/hcl/hcl/printer"
 	"github.com/mitchellh/cli"
 )
 
@@ -20,8 +23,11 @@ const (
 // files to a canonical format and style.
 type FmtCommand struct {
 	Meta
-	opts  fmtcmd.Options
 	input io.Reader // STDIN if nil
+
+	list  bool
+	write bool
+	diff  bool
 }
 
 func (c *FmtCommand) Run(args []string) int {
@@ -32,9 +38,9 @@ func (c *FmtCommand) Run(args []string) int {
 	args = c.Meta.process(args, false)
 
 	cmdFlags := flag.NewFlagSet("fmt", flag.ContinueOnError)
-	cmdFlags.BoolVar(&c.opts.List, "list", true, "list")
-	cmdFlags.BoolVar(&c.opts.Write, "write", true, "write")
-	cmdFlags.BoolVar(&c.opts.Diff, "diff", false, "diff")
+	cmdFlags.BoolVar(&c.list, "list", true, "list")
+	cmdFlags.BoolVar(&c.write, "write", true, "write")
+	cmdFlags.BoolVar(&c.diff, "diff", false, "diff")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 
 	if err := cmdFlags.Parse(args); err != nil {
@@ -48,18 +54,22 @@ func (c *FmtCommand) Run(args []string) int {
 		return 1
 	}
 
-	var dirs []string
-	if len(args) == 0 {
-		dirs = []string{"."}
-	} else if args[0] == stdinArg {
-		c.opts.List = false
-		c.opts.Write = false
-	} else {
-		dirs = []string{args[0]}
-	}
-
 	output := &cli.UiWriter{Ui: c.Ui}
-	err := fmtcmd.Run(dirs, []string{fileExtension}, c.input, output, c.opts)
+
+	var err error
+	switch {
+	case len(args) == 0:
+		err = c.processPath(".", output)
+	case args[0] == stdinArg:
+		// Configuration read from STDIN is written to STDOUT, or shown as
+		// a diff, so that editors can format files without Terraform
+		// touching them.
+		c.list = false
+		c.write = false
+		err = c.processFile("<standard input>", c.input, output)
+	default:
+		err = c.processPath(args[0], output)
+	}
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error running fmt: %s", err))
 		return 2
@@ -68,6 +78,75 @@ func (c *FmtCommand) Run(args []string) int {
 	return 0
 }
 
+// processPath formats the configuration files in the directory at path and
+// its subdirectories, or the file at path, whatever its name.
+func (c *FmtCommand) processPath(path string, output io.Writer) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return c.processPathFile(path, output)
+	}
+
+	return filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") || filepath.Ext(path) != "."+fileExtension {
+			return nil
+		}
+
+		return c.processPathFile(path, output)
+	})
+}
+
+func (c *FmtCommand) processPathFile(path string, output io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return c.processFile(path, f, output)
+}
+
+// processFile formats the configuration read from r, which is the file at
+// the given path, and lists, writes or shows a diff of the changes to it
+// as configured. Without any of those, the formatted configuration is
+// written to output.
+func (c *FmtCommand) processFile(path string, r io.Reader, output io.Writer) error {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	res, err := printer.Format(src)
+	if err != nil {
+		return fmt.Errorf("In %s: %s", path, err)
+	}
+
+	if !bytes.Equal(src, res) {
+		if c.list {
+			fmt.Fprintln(output, path)
+		}
+		if c.write {
+			if err := ioutil.WriteFile(path, res, 0644); err != nil {
+				return err
+			}
+		}
+		if c.diff {
+			output.Write(fmtUnifiedDiff(filepath.ToSlash(path), src, res))
+		}
+	}
+
+	if !c.list && !c.write && !c.diff {
+		_, err = output.Write(res)
+	}
+
+	return err
+}
+
 func (c *FmtCommand) Help() string {
 	helpText := `
 Usage: terraform fmt [options] [DIR]
@@ -75,7 +154,8 @@ Usage: terraform fmt [options] [DIR]
 	Rewrites all Terraform configuration files to a canonical format.
 
 	If DIR is not specified then the current working directory will be used.
-	If DIR is "-" then content will be read from STDIN.
+	If DIR is a file then only that file is formatted. If DIR is "-" then
+	content will be read from STDIN and the result written to STDOUT.
 
 Options:
 
@@ -83,7 +163,8 @@ Options:
 
   -write=true      Write result to source file instead of STDOUT (always false if using STDIN)
 
-  -diff=false      Display diffs of formatting changes
+  -diff=false      Display unified diffs of formatting changes. Use with
+                   -write=false to see the changes without making them.
 
 `
 	return strings.TrimSpace(helpText)
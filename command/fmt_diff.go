@@ -0,0 +1,212 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// fmtDiffContext is the number of unchanged lines shown around the changes
+// in a diff.
+const fmtDiffContext = 3
+
+// fmtDiffLine is a line of a diff: an unchanged line, or a line that was
+// removed from or added to the original.
+type fmtDiffLine struct {
+	Op   byte // ' ', '-' or '+'
+	Text string
+}
+
+// fmtUnifiedDiff returns the changes from a to b, which are the contents of
+// the file with the given name before and after it's formatted, as a
+// unified diff like "diff -u" shows. It returns nil if they're the same.
+func fmtUnifiedDiff(name string, a, b []byte) []byte {
+	lines := fmtDiffLines(fmtSplitLines(a), fmtSplitLines(b))
+
+	// Each hunk is a range of the lines, with the changes and their context
+	var hunks [][2]int
+	for i, l := range lines {
+		if l.Op == ' ' {
+			continue
+		}
+
+		start, end := i-fmtDiffContext, i+fmtDiffContext+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		if n := len(hunks); n > 0 && start <= hunks[n-1][1] {
+			hunks[n-1][1] = end
+			continue
+		}
+		hunks = append(hunks, [2]int{start, end})
+	}
+	if len(hunks) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- a/%s\n+++ b/%s\n", name, name)
+
+	// aLine and bLine are the numbers of the lines of a and b before the
+	// current line.
+	aLine, bLine, i := 0, 0, 0
+	for _, h := range hunks {
+		for ; i < h[0]; i++ {
+			aLine++
+			bLine++
+		}
+
+		var aCount, bCount int
+		for _, l := range lines[h[0]:h[1]] {
+			if l.Op != '+' {
+				aCount++
+			}
+			if l.Op != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n",
+			fmtDiffRange(aLine, aCount), fmtDiffRange(bLine, bCount))
+
+		for ; i < h[1]; i++ {
+			l := lines[i]
+			if l.Op != '+' {
+				aLine++
+			}
+			if l.Op != '-' {
+				bLine++
+			}
+
+			buf.WriteByte(l.Op)
+			buf.WriteString(l.Text)
+			if !strings.HasSuffix(l.Text, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// fmtDiffRange returns the range of count lines after the line numbered
+// before, as it's shown in the header of a hunk.
+func fmtDiffRange(before, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	default:
+		return fmt.Sprintf("%d,%d", before+1, count)
+	}
+}
+
+// fmtSplitLines splits data into lines, each with its trailing newline, if
+// it has one.
+func fmtSplitLines(data []byte) []string {
+	var lines []string
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n') + 1
+		if i == 0 {
+			i = len(data)
+		}
+
+		lines = append(lines, string(data[:i]))
+		data = data[i:]
+	}
+
+	return lines
+}
+
+// fmtDiffLines returns the shortest edit from the lines a to the lines b,
+// found with Myers' diff algorithm, as the lines of a diff.
+func fmtDiffLines(a, b []string) []fmtDiffLine {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+
+	// v holds the furthest x reached on each diagonal k = x - y for the
+	// current number of edits, and trace holds v before each number of
+	// edits d, to find the edit again afterwards. Only the diagonals -d to d
+	// can be read at that point, so only those and the ones either side are
+	// kept, which keeps the memory quadratic in the number of edits rather
+	// than proportional to the size of the files.
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return fmtDiffBacktrack(a, b, trace)
+			}
+		}
+	}
+
+	// Not reached, since max edits always get from a to b
+	return nil
+}
+
+// fmtDiffBacktrack follows the trace of fmtDiffLines back from the end of
+// a and b to their start, and returns the lines of the diff in order. Each
+// entry d of the trace holds the diagonals -d-1 to d+1, so diagonal k is at
+// index k+d+1.
+func fmtDiffBacktrack(a, b []string, trace [][]int) []fmtDiffLine {
+	var lines []fmtDiffLine
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[k+d] < v[k+d+2]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d+1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			lines = append(lines, fmtDiffLine{Op: ' ', Text: a[x]})
+		}
+
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			lines = append(lines, fmtDiffLine{Op: '+', Text: b[y]})
+		} else {
+			x--
+			lines = append(lines, fmtDiffLine{Op: '-', Text: a[x]})
+		}
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+
+	return lines
+}
@@ -0,0 +1,57 @@
+package command
+
+import (
+	"testing"
+)
+
+func TestFmtUnifiedDiff(t *testing.T) {
+	cases := map[string]struct {
+		A, B     string
+		Expected string
+	}{
+		"same": {
+			"a\nb\n",
+			"a\nb\n",
+			"",
+		},
+
+		"changed line": {
+			"a\nb\nc\n",
+			"a\nB\nc\n",
+			"--- a/main.tf\n+++ b/main.tf\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+
+		"added and removed lines": {
+			"a\nb\nc\n",
+			"a\nc\nd\n",
+			"--- a/main.tf\n+++ b/main.tf\n@@ -1,3 +1,3 @@\n a\n-b\n c\n+d\n",
+		},
+
+		"separate hunks": {
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			"--- a/main.tf\n+++ b/main.tf\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+
+		"empty original": {
+			"",
+			"a\n",
+			"--- a/main.tf\n+++ b/main.tf\n@@ -0,0 +1 @@\n+a\n",
+		},
+
+		"no newline at end": {
+			"a\nb",
+			"a\nb\n",
+			"--- a/main.tf\n+++ b/main.tf\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for name, tc := range cases {
+		actual := string(fmtUnifiedDiff("main.tf", []byte(tc.A), []byte(tc.B)))
+		if actual != tc.Expected {
+			t.Fatalf("%s: got:\n%s\nexpected:\n%s", name, actual, tc.Expected)
+		}
+	}
+}
@@ -204,3 +204,139 @@ func fmtFixtureWriteDir() (string, error) {
 
 	return dir, nil
 }
+
+func TestFmt_diff(t *testing.T) {
+	tempDir, err := fmtFixtureWriteDir()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	ui := new(cli.MockUi)
+	c := &FmtCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-list=false",
+		"-write=false",
+		"-diff",
+		tempDir,
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("wrong exit code. errors: \n%s", ui.ErrorWriter.String())
+	}
+
+	name := filepath.ToSlash(filepath.Join(tempDir, fmtFixture.filename))
+	expected := fmt.Sprintf(
+		"--- a/%s\n+++ b/%s\n@@ -1 +1 @@\n-%s+%s", name, name, fmtFixture.input, fmtFixture.golden)
+	if actual := ui.OutputWriter.String(); actual != expected {
+		t.Fatalf("got: %q\nexpected: %q", actual, expected)
+	}
+
+	// The file isn't changed
+	data, err := ioutil.ReadFile(filepath.Join(tempDir, fmtFixture.filename))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(data, fmtFixture.input) {
+		t.Fatalf("file changed: %q", data)
+	}
+}
+
+func TestFmt_stdinDiff(t *testing.T) {
+	input := new(bytes.Buffer)
+	input.Write(fmtFixture.input)
+
+	ui := new(cli.MockUi)
+	c := &FmtCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+		input: input,
+	}
+
+	args := []string{"-diff", "-"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("wrong exit code. errors: \n%s", ui.ErrorWriter.String())
+	}
+
+	expected := fmt.Sprintf(
+		"--- a/<standard input>\n+++ b/<standard input>\n@@ -1 +1 @@\n-%s+%s",
+		fmtFixture.input, fmtFixture.golden)
+	if actual := ui.OutputWriter.String(); actual != expected {
+		t.Fatalf("got: %q\nexpected: %q", actual, expected)
+	}
+}
+
+func TestFmt_stdinError(t *testing.T) {
+	input := bytes.NewBufferString(`foo = "bar`)
+
+	ui := new(cli.MockUi)
+	c := &FmtCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+		input: input,
+	}
+
+	if code := c.Run([]string{"-"}); code != 2 {
+		t.Fatalf("wrong exit code. errors: \n%s", ui.ErrorWriter.String())
+	}
+	if ui.OutputWriter.Len() != 0 {
+		t.Fatalf("unexpected output: %s", ui.OutputWriter.String())
+	}
+	if actual := ui.ErrorWriter.String(); !strings.Contains(actual, "In <standard input>") {
+		t.Fatalf("bad: %s", actual)
+	}
+}
+
+func TestFmt_fileArg(t *testing.T) {
+	tempDir, err := fmtFixtureWriteDir()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Only the given file is formatted
+	other := filepath.Join(tempDir, "other.tf")
+	if err := ioutil.WriteFile(other, fmtFixture.input, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	c := &FmtCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	path := filepath.Join(tempDir, fmtFixture.filename)
+	if code := c.Run([]string{path}); code != 0 {
+		t.Fatalf("wrong exit code. errors: \n%s", ui.ErrorWriter.String())
+	}
+
+	if actual := ui.OutputWriter.String(); actual != path+"\n" {
+		t.Fatalf("bad: %q", actual)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(data, fmtFixture.golden) {
+		t.Fatalf("bad: %q", data)
+	}
+	data, err = ioutil.ReadFile(other)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(data, fmtFixture.input) {
+		t.Fatalf("other file changed: %q", data)
+	}
+}
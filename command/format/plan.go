@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/colorstring"
@@ -21,6 +22,39 @@ type PlanOpts struct {
 	// ModuleDepth is the depth of the modules to expand. By default this
 	// is zero which will not expand modules at all.
 	ModuleDepth int
+
+	// StaleState, if set, is the state that the plan was made from without
+	// refreshing it. The values of each resource that come from the state
+	// are marked with how old they are, so that they aren't mistaken for
+	// the current values.
+	StaleState *StaleState
+
+	// Causes, if set, are why each resource instance has its change, by
+	// address, as returned by terraform.Plan.Causes. Each change is tagged
+	// with its causes so that the changes from the configuration can be
+	// told apart from the ones that undo drift or are forced.
+	Causes map[string][]terraform.PlanChangeCause
+}
+
+// StaleState describes a state that wasn't refreshed before planning.
+type StaleState struct {
+	// Serial is the serial of the state.
+	Serial int64
+
+	// Written is when the state was written, or the zero time if it isn't
+	// known.
+	Written time.Time
+}
+
+// String returns when the values of s are from, such as "as of state
+// serial 3, written at Mon, 02 Jan 2006 15:04:05 MST".
+func (s *StaleState) String() string {
+	written := "an unknown time"
+	if !s.Written.IsZero() {
+		written = s.Written.Local().Format(time.RFC1123)
+	}
+
+	return fmt.Sprintf("as of state serial %d, written at %s", s.Serial, written)
 }
 
 // Plan takes a plan and returns a
@@ -38,7 +72,9 @@ func Plan(opts *PlanOpts) string {
 	}
 
 	buf := new(bytes.Buffer)
-	for _, m := range p.Diff.Modules {
+	// Plans that were read from older plan files may have their modules
+	// in any order
+	for _, m := range p.Diff.SortedModules() {
 		if len(m.Path)-1 <= opts.ModuleDepth || opts.ModuleDepth == -1 {
 			formatPlanModuleExpand(buf, m, opts)
 		} else {
@@ -49,6 +85,16 @@ func Plan(opts *PlanOpts) string {
 	return strings.TrimSpace(buf.String())
 }
 
+// formatPlanCauses formats the causes of a change, such as "config+drift".
+func formatPlanCauses(causes []terraform.PlanChangeCause) string {
+	parts := make([]string, len(causes))
+	for i, c := range causes {
+		parts[i] = string(c)
+	}
+
+	return strings.Join(parts, "+")
+}
+
 // formatPlanModuleExpand will output the given module and all of its
 // resources.
 func formatPlanModuleExpand(
@@ -114,13 +160,30 @@ func formatPlanModuleExpand(
 			symbol = "-"
 		}
 
+		// Work out which attribute changes force a replacement so that we
+		// can tell the user about it, rather than leaving them to hunt for
+		// the "forces new resource" markers themselves.
+		var reasons []string
+		if rdiff.ChangeType() == terraform.DiffDestroyCreate {
+			reasons = planReplaceReasons(rdiff)
+		}
+
 		var extraAttr []string
+		if len(reasons) > 0 {
+			extraAttr = append(extraAttr, "new resource required")
+		}
 		if rdiff.DestroyTainted {
 			extraAttr = append(extraAttr, "tainted")
 		}
 		if rdiff.DestroyDeposed {
 			extraAttr = append(extraAttr, "deposed")
 		}
+		if rdiff.AdoptID != "" {
+			extraAttr = append(extraAttr, fmt.Sprintf("adopting %s", rdiff.AdoptID))
+		}
+		if causes := opts.Causes[name]; len(causes) > 0 {
+			extraAttr = append(extraAttr, "cause: "+formatPlanCauses(causes))
+		}
 		var extraStr string
 		if len(extraAttr) > 0 {
 			extraStr = fmt.Sprintf(" (%s)", strings.Join(extraAttr, ", "))
@@ -129,6 +192,10 @@ func formatPlanModuleExpand(
 		buf.WriteString(opts.Color.Color(fmt.Sprintf(
 			"[%s]%s %s%s\n",
 			color, symbol, name, extraStr)))
+		if oldValues && opts.StaleState != nil {
+			buf.WriteString(opts.Color.Color(fmt.Sprintf(
+				"    [reset][dark_gray](values %s)[%s]\n", opts.StaleState, color)))
+		}
 
 		// Get all the attributes that are changing, and sort them. Also
 		// determine the longest key so that we can align them all.
@@ -191,6 +258,13 @@ func formatPlanModuleExpand(
 			}
 		}
 
+		if len(reasons) > 0 {
+			buf.WriteString(opts.Color.Color("    [red]replacement forced by:[reset]\n"))
+			for _, r := range reasons {
+				buf.WriteString(fmt.Sprintf("      %s\n", r))
+			}
+		}
+
 		// Write the reset color so we don't overload the user's terminal
 		buf.WriteString(opts.Color.Color("[reset]\n"))
 	}
@@ -0,0 +1,63 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/colorstring"
+)
+
+// PlanConfidence returns a human readable report of which parts of a plan
+// are speculative, as returned by Plan.Confidence.
+func PlanConfidence(c *terraform.PlanConfidence, color *colorstring.Colorize) string {
+	if color == nil {
+		color = &colorstring.Colorize{
+			Colors:  colorstring.DefaultColors,
+			Disable: true,
+		}
+	}
+
+	if len(c.Unknown) == 0 {
+		return color.Color(fmt.Sprintf(
+			"[reset][bold]Plan confidence:[reset] all values of the %d resources "+
+				"with changes are known.", c.Changes))
+	}
+
+	have := "have"
+	if len(c.Unknown) == 1 {
+		have = "has"
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(color.Color(fmt.Sprintf(
+		"[reset][bold]Plan confidence:[reset] %d of %d resources with changes "+
+			"%s values that are unknown until apply.\n",
+		len(c.Unknown), c.Changes, have)))
+	if len(c.Speculative) > 0 {
+		buf.WriteString(color.Color(fmt.Sprintf(
+			"[yellow]The changes to %d of them depend on unknown values of other "+
+				"resources, and may differ at apply.[reset]\n",
+			len(c.Speculative))))
+	}
+
+	for _, u := range c.Unknown {
+		values := "values"
+		if len(u.Keys) == 1 {
+			values = "value"
+		}
+
+		buf.WriteString(color.Color(fmt.Sprintf(
+			"\n  [bold]%s[reset] (%s): %d unknown %s\n",
+			u.Address, planExplainChange(u.Address, u.ChangeType), len(u.Keys), values)))
+		buf.WriteString(fmt.Sprintf("    %s\n", strings.Join(u.Keys, ", ")))
+		if len(u.Downstream) > 0 {
+			buf.WriteString(fmt.Sprintf(
+				"    may change the diffs at apply of: %s\n",
+				strings.Join(u.Downstream, ", ")))
+		}
+	}
+
+	return strings.TrimSpace(buf.String())
+}
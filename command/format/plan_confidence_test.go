@@ -0,0 +1,52 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestPlanConfidence(t *testing.T) {
+	c := &terraform.PlanConfidence{
+		Changes: 4,
+		Unknown: []*terraform.PlanUnknownValues{
+			{
+				Address:    "aws_instance.web",
+				ChangeType: terraform.DiffUpdate,
+				Keys:       []string{"subnet"},
+			},
+			{
+				Address:    "aws_subnet.main",
+				ChangeType: terraform.DiffCreate,
+				Keys:       []string{"id", "vpc_id"},
+				Downstream: []string{"aws_instance.web"},
+			},
+		},
+		Speculative: []string{"aws_instance.web"},
+	}
+
+	actual := PlanConfidence(c, nil)
+	expected := strings.TrimSpace(`
+Plan confidence: 2 of 4 resources with changes have values that are unknown until apply.
+The changes to 1 of them depend on unknown values of other resources, and may differ at apply.
+
+  aws_instance.web (updated in-place): 1 unknown value
+    subnet
+
+  aws_subnet.main (created): 2 unknown values
+    id, vpc_id
+    may change the diffs at apply of: aws_instance.web
+`)
+	if actual != expected {
+		t.Fatalf("bad:\n\n%s\n\nexpected:\n\n%s", actual, expected)
+	}
+}
+
+func TestPlanConfidence_known(t *testing.T) {
+	actual := PlanConfidence(&terraform.PlanConfidence{Changes: 2}, nil)
+	expected := "Plan confidence: all values of the 2 resources with changes are known."
+	if actual != expected {
+		t.Fatalf("bad: %s", actual)
+	}
+}
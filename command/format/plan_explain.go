@@ -0,0 +1,106 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/colorstring"
+)
+
+// PlanExplanation returns a human readable explanation of the change to a
+// resource instance in a plan, as returned by Plan.Explain.
+func PlanExplanation(e *terraform.PlanExplanation, color *colorstring.Colorize) string {
+	if color == nil {
+		color = &colorstring.Colorize{
+			Colors:  colorstring.DefaultColors,
+			Disable: true,
+		}
+	}
+
+	if e.ChangeType == terraform.DiffNone {
+		return color.Color(fmt.Sprintf(
+			"[reset][bold]%s[reset] has no changes in this plan.", e.Address))
+	}
+
+	buf := new(bytes.Buffer)
+	var reason string
+	switch {
+	case e.Tainted:
+		reason = ", because it is tainted"
+	case e.Deposed:
+		reason = ", because it is deposed"
+	}
+	buf.WriteString(color.Color(fmt.Sprintf(
+		"[reset][bold]%s[reset] will be %s%s.\n",
+		e.Address, planExplainChange(e.Address, e.ChangeType), reason)))
+
+	if len(e.Attributes) > 0 {
+		keyLen := 0
+		for _, attr := range e.Attributes {
+			if len(attr.Key) > keyLen {
+				keyLen = len(attr.Key)
+			}
+		}
+
+		buf.WriteString("\n  Changing attributes:\n")
+		for _, attr := range e.Attributes {
+			var forces string
+			if attr.Diff.RequiresNew && e.ChangeType == terraform.DiffDestroyCreate {
+				forces = color.Color(" [red](forces new resource)[reset]")
+			}
+
+			buf.WriteString(fmt.Sprintf(
+				"    %s:%s %#v => %#v%s\n",
+				attr.Key,
+				strings.Repeat(" ", keyLen-len(attr.Key)),
+				planOldValue(attr.Diff),
+				planNewValue(attr.Diff),
+				forces))
+			if len(attr.Upstream) > 0 {
+				buf.WriteString(fmt.Sprintf(
+					"      from: %s\n", strings.Join(attr.Upstream, ", ")))
+			}
+		}
+	}
+
+	buf.WriteString("\n  Changes to resources it depends on:\n")
+	if len(e.Upstream) == 0 {
+		buf.WriteString("    (none)\n")
+	}
+	for _, u := range e.Upstream {
+		var through string
+		if len(u.Through) > 0 {
+			through = ", through " + strings.Join(u.Through, " -> ")
+		}
+
+		buf.WriteString(fmt.Sprintf(
+			"    %s will be %s%s\n",
+			u.Address, planExplainChange(u.Address, u.ChangeType), through))
+	}
+
+	return strings.TrimSpace(buf.String())
+}
+
+// planExplainChange describes a change to the resource at addr.
+func planExplainChange(addr string, t terraform.DiffChangeType) string {
+	switch t {
+	case terraform.DiffCreate:
+		// Data resources are "created" by reading them
+		a, err := terraform.ParseResourceAddress(addr)
+		if err == nil && a.Mode == config.DataResourceMode {
+			return "read"
+		}
+		return "created"
+	case terraform.DiffUpdate:
+		return "updated in-place"
+	case terraform.DiffDestroy:
+		return "destroyed"
+	case terraform.DiffDestroyCreate:
+		return "replaced"
+	}
+
+	return "unchanged"
+}
@@ -0,0 +1,79 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestPlanExplanation(t *testing.T) {
+	e := &terraform.PlanExplanation{
+		Address:    "aws_instance.web",
+		ChangeType: terraform.DiffDestroyCreate,
+		Attributes: []*terraform.PlanExplainedAttribute{
+			{
+				Key: "ami",
+				Diff: &terraform.ResourceAttrDiff{
+					Old:         "ami-1",
+					NewComputed: true,
+					RequiresNew: true,
+				},
+				Upstream: []string{"aws_vpc.main", "module.child.aws_ami.base"},
+			},
+			{
+				Key: "tags.Name",
+				Diff: &terraform.ResourceAttrDiff{
+					Old: "a",
+					New: "b",
+				},
+			},
+		},
+		Upstream: []*terraform.PlanUpstreamChange{
+			{
+				Address:    "aws_vpc.main",
+				ChangeType: terraform.DiffUpdate,
+				Through:    []string{"module.child.aws_ami.base"},
+			},
+			{
+				Address:    "data.aws_ami.latest",
+				ChangeType: terraform.DiffCreate,
+			},
+			{
+				Address:    "module.child.aws_ami.base",
+				ChangeType: terraform.DiffDestroyCreate,
+			},
+		},
+	}
+
+	actual := PlanExplanation(e, nil)
+	expected := strings.TrimSpace(`
+aws_instance.web will be replaced.
+
+  Changing attributes:
+    ami:       "ami-1" => "<computed>" (forces new resource)
+      from: aws_vpc.main, module.child.aws_ami.base
+    tags.Name: "a" => "b"
+
+  Changes to resources it depends on:
+    aws_vpc.main will be updated in-place, through module.child.aws_ami.base
+    data.aws_ami.latest will be read
+    module.child.aws_ami.base will be replaced
+`)
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+}
+
+func TestPlanExplanation_noChanges(t *testing.T) {
+	e := &terraform.PlanExplanation{
+		Address:    "aws_instance.web",
+		ChangeType: terraform.DiffNone,
+	}
+
+	actual := PlanExplanation(e, nil)
+	expected := "aws_instance.web has no changes in this plan."
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+}
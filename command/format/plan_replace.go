@@ -0,0 +1,176 @@
+package format
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// setHashMinLen is the length from which a numeric key segment is assumed
+// to be a set hash rather than a list index, when the diff doesn't include
+// the count of the collection it belongs to. Set hashes are CRC32 sums, so
+// they are almost always far longer than this.
+const setHashMinLen = 5
+
+// planSetElement is the part of a resource diff that describes a single
+// element of a set, addressed by its hash.
+type planSetElement struct {
+	Name  string
+	Attrs map[string]*terraform.ResourceAttrDiff
+
+	requiresNew bool
+}
+
+// planReplaceReasons returns a human readable description of each
+// attribute change that forces the resource in the given diff to be
+// replaced, sorted by attribute name.
+//
+// Changes to elements of a set are grouped by element, and the element is
+// described by its content instead of its hash, since the hash means
+// nothing to the user.
+func planReplaceReasons(rdiff *terraform.InstanceDiff) []string {
+	var reasons []string
+	elements := make(map[string]*planSetElement)
+	for k, attr := range rdiff.Attributes {
+		if name, hash, rest, ok := planSplitSetKey(k, rdiff.Attributes); ok {
+			id := name + "." + hash
+			e, ok := elements[id]
+			if !ok {
+				e = &planSetElement{
+					Name:  name,
+					Attrs: make(map[string]*terraform.ResourceAttrDiff),
+				}
+				elements[id] = e
+			}
+
+			e.Attrs[rest] = attr
+			if attr.RequiresNew {
+				e.requiresNew = true
+			}
+			continue
+		}
+
+		if !attr.RequiresNew {
+			continue
+		}
+
+		reasons = append(reasons, fmt.Sprintf(
+			"%s: %#v => %#v", k, planOldValue(attr), planNewValue(attr)))
+	}
+
+	for _, e := range elements {
+		if e.requiresNew {
+			reasons = append(reasons, e.String())
+		}
+	}
+
+	sort.Strings(reasons)
+	return reasons
+}
+
+// String returns the description of the change to the set element.
+func (e *planSetElement) String() string {
+	removed := true
+	added := true
+	for _, attr := range e.Attrs {
+		if !attr.NewRemoved {
+			removed = false
+		}
+		if attr.NewRemoved || attr.Old != "" {
+			added = false
+		}
+	}
+
+	status := "changed"
+	value := planNewValue
+	switch {
+	case removed:
+		status = "removed"
+		value = planOldValue
+	case added:
+		status = "added"
+	}
+
+	// A set of primitives has no attributes, just the value
+	if attr, ok := e.Attrs[""]; ok && len(e.Attrs) == 1 {
+		return fmt.Sprintf("%s: %s %#v", e.Name, status, value(attr))
+	}
+
+	keys := make([]string, 0, len(e.Attrs))
+	for k := range e.Attrs {
+		// The counts of nested collections are just noise
+		if strings.HasSuffix(k, ".#") || strings.HasSuffix(k, ".%") {
+			continue
+		}
+
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	content := make([]string, len(keys))
+	for i, k := range keys {
+		content[i] = fmt.Sprintf("%s = %#v", k, value(e.Attrs[k]))
+	}
+
+	return fmt.Sprintf("%s: %s {%s}", e.Name, status, strings.Join(content, ", "))
+}
+
+// planSplitSetKey splits an attribute key that addresses (part of) an
+// element of a set into the name of the set, the hash of the element, and
+// the key of the attribute within the element.
+func planSplitSetKey(
+	k string,
+	attrs map[string]*terraform.ResourceAttrDiff) (string, string, string, bool) {
+	parts := strings.Split(k, ".")
+	for i := 1; i < len(parts); i++ {
+		name := strings.Join(parts[:i], ".")
+		if planIsSetHash(parts[i], attrs[name+".#"]) {
+			return name, parts[i], strings.Join(parts[i+1:], "."), true
+		}
+	}
+
+	return "", "", "", false
+}
+
+// planIsSetHash reports whether the given key segment is the hash of a set
+// element rather than the index of a list element. count is the diff of the
+// collection's count, if the diff has one.
+func planIsSetHash(seg string, count *terraform.ResourceAttrDiff) bool {
+	n, err := strconv.Atoi(seg)
+	if err != nil || n < 0 {
+		return false
+	}
+
+	if count != nil {
+		oldCount, oldErr := strconv.Atoi(count.Old)
+		newCount, newErr := strconv.Atoi(count.New)
+		if oldErr == nil || newErr == nil {
+			// List indexes are always less than the larger count
+			return n >= oldCount && n >= newCount
+		}
+	}
+
+	return len(seg) >= setHashMinLen
+}
+
+func planOldValue(attr *terraform.ResourceAttrDiff) string {
+	if attr.Sensitive {
+		return "<sensitive>"
+	}
+
+	return attr.Old
+}
+
+func planNewValue(attr *terraform.ResourceAttrDiff) string {
+	if attr.Sensitive {
+		return "<sensitive>"
+	}
+	if attr.New == "" && attr.NewComputed {
+		return "<computed>"
+	}
+
+	return attr.New
+}
@@ -3,6 +3,7 @@ package format
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/colorstring"
@@ -168,3 +169,379 @@ func TestPlan_nestedDataSource(t *testing.T) {
 		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
 	}
 }
+
+// Test that the reasons for a replacement are listed, with set elements
+// described by their content
+func TestPlan_replaceReasons(t *testing.T) {
+	plan := &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{
+				&terraform.ModuleDiff{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.InstanceDiff{
+						"aws_instance.foo": &terraform.InstanceDiff{
+							Destroy: true,
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"ami": &terraform.ResourceAttrDiff{
+									Old:         "ami-1",
+									New:         "ami-2",
+									RequiresNew: true,
+								},
+								"tags.Name": &terraform.ResourceAttrDiff{
+									Old: "foo",
+									New: "bar",
+								},
+								"ebs.1234567.device": &terraform.ResourceAttrDiff{
+									Old:         "/dev/sdb",
+									New:         "",
+									NewRemoved:  true,
+									RequiresNew: true,
+								},
+								"ebs.1234567.size": &terraform.ResourceAttrDiff{
+									Old:         "10",
+									New:         "",
+									NewRemoved:  true,
+									RequiresNew: true,
+								},
+								"ebs.7654321.device": &terraform.ResourceAttrDiff{
+									Old:         "",
+									New:         "/dev/sdb",
+									RequiresNew: true,
+								},
+								"ebs.7654321.size": &terraform.ResourceAttrDiff{
+									Old:         "",
+									New:         "20",
+									RequiresNew: true,
+								},
+								"disks.#": &terraform.ResourceAttrDiff{
+									Old: "1",
+									New: "1",
+								},
+								"disks.0": &terraform.ResourceAttrDiff{
+									Old:         "a",
+									New:         "b",
+									RequiresNew: true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	opts := &PlanOpts{
+		Plan: plan,
+		Color: &colorstring.Colorize{
+			Colors:  colorstring.DefaultColors,
+			Disable: true,
+		},
+		ModuleDepth: 1,
+	}
+
+	actual := Plan(opts)
+
+	expected := strings.TrimSpace(`
+-/+ aws_instance.foo (new resource required)
+    ami:                "ami-1" => "ami-2" (forces new resource)
+    disks.#:            "1" => "1"
+    disks.0:            "a" => "b" (forces new resource)
+    ebs.1234567.device: "/dev/sdb" => "" (forces new resource)
+    ebs.1234567.size:   "10" => "" (forces new resource)
+    ebs.7654321.device: "" => "/dev/sdb" (forces new resource)
+    ebs.7654321.size:   "" => "20" (forces new resource)
+    tags.Name:          "foo" => "bar"
+    replacement forced by:
+      ami: "ami-1" => "ami-2"
+      disks.0: "a" => "b"
+      ebs: added {device = "/dev/sdb", size = "20"}
+      ebs: removed {device = "/dev/sdb", size = "10"}
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+}
+
+func TestPlan_replaceReasonsTainted(t *testing.T) {
+	plan := &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{
+				&terraform.ModuleDiff{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.InstanceDiff{
+						"aws_instance.foo": &terraform.InstanceDiff{
+							DestroyTainted: true,
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"ami": &terraform.ResourceAttrDiff{
+									Old: "ami-1",
+									New: "ami-1",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	opts := &PlanOpts{
+		Plan: plan,
+		Color: &colorstring.Colorize{
+			Colors:  colorstring.DefaultColors,
+			Disable: true,
+		},
+		ModuleDepth: 1,
+	}
+
+	actual := Plan(opts)
+
+	expected := strings.TrimSpace(`
+-/+ aws_instance.foo (tainted)
+    ami: "ami-1" => "ami-1"
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+}
+
+func TestPlan_adopt(t *testing.T) {
+	plan := &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{
+				&terraform.ModuleDiff{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.InstanceDiff{
+						"aws_instance.foo": &terraform.InstanceDiff{
+							AdoptID: "i-abc123",
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"ami": &terraform.ResourceAttrDiff{
+									Old: "ami-1",
+									New: "ami-2",
+								},
+							},
+						},
+						"aws_instance.bar": &terraform.InstanceDiff{
+							AdoptID: "i-def456",
+						},
+					},
+				},
+			},
+		},
+	}
+	opts := &PlanOpts{
+		Plan: plan,
+		Color: &colorstring.Colorize{
+			Colors:  colorstring.DefaultColors,
+			Disable: true,
+		},
+		ModuleDepth: 1,
+	}
+
+	actual := Plan(opts)
+
+	expected := strings.TrimSpace(`
+~ aws_instance.bar (adopting i-def456)
+
+~ aws_instance.foo (adopting i-abc123)
+    ami: "ami-1" => "ami-2"
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+}
+
+func TestPlan_staleState(t *testing.T) {
+	plan := &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{
+				&terraform.ModuleDiff{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.InstanceDiff{
+						"aws_instance.foo": &terraform.InstanceDiff{
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"ami": &terraform.ResourceAttrDiff{
+									Old: "ami-1",
+									New: "ami-2",
+								},
+							},
+						},
+						"aws_instance.bar": &terraform.InstanceDiff{
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"ami": &terraform.ResourceAttrDiff{
+									New:         "ami-2",
+									RequiresNew: true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	written := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	opts := &PlanOpts{
+		Plan: plan,
+		Color: &colorstring.Colorize{
+			Colors:  colorstring.DefaultColors,
+			Disable: true,
+		},
+		ModuleDepth: 1,
+		StaleState: &StaleState{
+			Serial:  3,
+			Written: written,
+		},
+	}
+
+	actual := Plan(opts)
+
+	// Only the values that come from the state are marked
+	expected := strings.TrimSpace(`
++ aws_instance.bar
+    ami: "ami-2"
+
+~ aws_instance.foo
+    (values as of state serial 3, written at ` + written.Local().Format(time.RFC1123) + `)
+    ami: "ami-1" => "ami-2"
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+
+	// The time the state was written may not be known
+	opts.StaleState.Written = time.Time{}
+	if actual := Plan(opts); !strings.Contains(actual, "written at an unknown time") {
+		t.Fatalf("bad: %s", actual)
+	}
+}
+
+func TestPlan_causes(t *testing.T) {
+	plan := &terraform.Plan{
+		Diff: &terraform.Diff{
+			Modules: []*terraform.ModuleDiff{
+				&terraform.ModuleDiff{
+					Path: []string{"root"},
+					Resources: map[string]*terraform.InstanceDiff{
+						"aws_instance.foo": &terraform.InstanceDiff{
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"ami": &terraform.ResourceAttrDiff{
+									Old: "ami-1",
+									New: "ami-2",
+								},
+							},
+						},
+						"aws_instance.bar": &terraform.InstanceDiff{
+							DestroyTainted: true,
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"ami": &terraform.ResourceAttrDiff{
+									Old: "ami-1",
+									New: "ami-1",
+								},
+							},
+						},
+					},
+				},
+				&terraform.ModuleDiff{
+					Path: []string{"root", "child"},
+					Resources: map[string]*terraform.InstanceDiff{
+						"aws_instance.baz": &terraform.InstanceDiff{
+							Attributes: map[string]*terraform.ResourceAttrDiff{
+								"ami": &terraform.ResourceAttrDiff{
+									Old: "ami-1",
+									New: "ami-2",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	opts := &PlanOpts{
+		Plan: plan,
+		Color: &colorstring.Colorize{
+			Colors:  colorstring.DefaultColors,
+			Disable: true,
+		},
+		ModuleDepth: -1,
+		Causes: map[string][]terraform.PlanChangeCause{
+			"aws_instance.foo": []terraform.PlanChangeCause{
+				terraform.PlanCauseConfig, terraform.PlanCauseDrift,
+			},
+			"aws_instance.bar":              []terraform.PlanChangeCause{terraform.PlanCauseForced},
+			"module.child.aws_instance.baz": []terraform.PlanChangeCause{terraform.PlanCauseDrift},
+		},
+	}
+
+	actual := Plan(opts)
+	expected := strings.TrimSpace(`
+-/+ aws_instance.bar (tainted, cause: forced)
+    ami: "ami-1" => "ami-1"
+
+~ aws_instance.foo (cause: config+drift)
+    ami: "ami-1" => "ami-2"
+
+~ module.child.aws_instance.baz (cause: drift)
+    ami: "ami-1" => "ami-2"
+	`)
+	if actual != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+}
+
+func TestPlan_moduleOrder(t *testing.T) {
+	modules := []*terraform.ModuleDiff{
+		&terraform.ModuleDiff{
+			Path: []string{"root"},
+			Resources: map[string]*terraform.InstanceDiff{
+				"aws_instance.foo": &terraform.InstanceDiff{Destroy: true},
+			},
+		},
+		&terraform.ModuleDiff{
+			Path: []string{"root", "a"},
+			Resources: map[string]*terraform.InstanceDiff{
+				"aws_instance.foo": &terraform.InstanceDiff{Destroy: true},
+			},
+		},
+		&terraform.ModuleDiff{
+			Path: []string{"root", "a", "child"},
+			Resources: map[string]*terraform.InstanceDiff{
+				"aws_instance.foo": &terraform.InstanceDiff{Destroy: true},
+			},
+		},
+		&terraform.ModuleDiff{
+			Path: []string{"root", "b"},
+			Resources: map[string]*terraform.InstanceDiff{
+				"aws_instance.foo": &terraform.InstanceDiff{Destroy: true},
+			},
+		},
+	}
+
+	expected := strings.TrimSpace(`
+- aws_instance.foo
+
+- module.a.aws_instance.foo
+
+- module.b.aws_instance.foo
+
+- module.a.child.aws_instance.foo
+	`)
+
+	// The walk adds the modules to the diff in any order
+	orders := [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {2, 0, 3, 1}}
+	for _, order := range orders {
+		diff := new(terraform.Diff)
+		for _, i := range order {
+			diff.Modules = append(diff.Modules, modules[i])
+		}
+
+		actual := Plan(&PlanOpts{
+			Plan: &terraform.Plan{Diff: diff},
+			Color: &colorstring.Colorize{
+				Colors:  colorstring.DefaultColors,
+				Disable: true,
+			},
+			ModuleDepth: -1,
+		})
+		if actual != expected {
+			t.Fatalf("order %v: expected:\n\n%s\n\ngot:\n\n%s", order, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/colorstring"
+)
+
+// TargetReport returns a human readable report of the resources that the
+// targets of an operation leave out or only include as dependencies, as
+// returned by Context.TargetReport.
+func TargetReport(r *terraform.TargetReport, color *colorstring.Colorize) string {
+	if color == nil {
+		color = &colorstring.Colorize{
+			Colors:  colorstring.DefaultColors,
+			Disable: true,
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(color.Color(fmt.Sprintf(
+		"[reset][bold][yellow]Targeted run: %d of %d resources considered.[reset]\n",
+		len(r.Targeted)+len(r.Dependencies),
+		len(r.Targeted)+len(r.Dependencies)+len(r.Excluded))))
+	buf.WriteString(color.Color(
+		"[yellow]Changes to the excluded resources are neither planned nor applied.[reset]\n"))
+
+	targetReportList(buf, color, "Targeted", r.Targeted)
+	targetReportList(buf, color, "Included as dependencies", r.Dependencies)
+	targetReportList(buf, color, "Excluded", r.Excluded)
+
+	return strings.TrimSpace(buf.String())
+}
+
+func targetReportList(buf *bytes.Buffer, color *colorstring.Colorize, title string, addrs []string) {
+	buf.WriteString(color.Color(fmt.Sprintf("\n[reset][bold]%s (%d):[reset]\n", title, len(addrs))))
+	if len(addrs) == 0 {
+		buf.WriteString("  (none)\n")
+		return
+	}
+
+	for _, addr := range addrs {
+		buf.WriteString(fmt.Sprintf("  - %s\n", addr))
+	}
+}
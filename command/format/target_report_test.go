@@ -0,0 +1,36 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestTargetReport(t *testing.T) {
+	r := &terraform.TargetReport{
+		Targets:      []string{"aws_instance.web"},
+		Targeted:     []string{"aws_instance.web"},
+		Dependencies: []string{"aws_security_group.sg"},
+		Excluded:     []string{"aws_instance.db", "module.child.aws_instance.foo"},
+	}
+
+	actual := TargetReport(r, nil)
+	expected := strings.TrimSpace(`
+Targeted run: 2 of 4 resources considered.
+Changes to the excluded resources are neither planned nor applied.
+
+Targeted (1):
+  - aws_instance.web
+
+Included as dependencies (1):
+  - aws_security_group.sg
+
+Excluded (2):
+  - aws_instance.db
+  - module.child.aws_instance.foo
+`)
+	if actual != expected {
+		t.Fatalf("bad:\n\n%s\n\nexpected:\n\n%s", actual, expected)
+	}
+}
@@ -23,6 +23,7 @@ func (c *GraphCommand) Run(args []string) int {
 	var verbose bool
 	var drawCycles bool
 	var graphTypeStr string
+	var filter string
 
 	args = c.Meta.process(args, false)
 
@@ -31,6 +32,7 @@ func (c *GraphCommand) Run(args []string) int {
 	cmdFlags.BoolVar(&verbose, "verbose", false, "verbose")
 	cmdFlags.BoolVar(&drawCycles, "draw-cycles", false, "draw-cycles")
 	cmdFlags.StringVar(&graphTypeStr, "type", "", "type")
+	cmdFlags.StringVar(&filter, "filter", "", "filter")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
@@ -124,10 +126,13 @@ func (c *GraphCommand) Run(args []string) int {
 		return 1
 	}
 
-	graphStr, err := terraform.GraphDot(g, &dag.DotOpts{
-		DrawCycles: drawCycles,
-		MaxDepth:   moduleDepth,
-		Verbose:    verbose,
+	graphStr, err := terraform.GraphDot(g, &terraform.GraphDotOpts{
+		DotOpts: dag.DotOpts{
+			DrawCycles: drawCycles,
+			MaxDepth:   moduleDepth,
+			Verbose:    verbose,
+		},
+		Filter: filter,
 	})
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error converting graph: %s", err))
@@ -158,13 +163,23 @@ Usage: terraform graph [options] [DIR]
 
 Options:
 
-  -draw-cycles   Highlight any cycles in the graph with colored edges.
-                 This helps when diagnosing cycle errors.
+  -draw-cycles        Highlight any cycles in the graph with colored edges.
+                      This helps when diagnosing cycle errors.
 
-  -no-color      If specified, output won't contain any color.
+  -filter=patterns    Only draw the nodes whose names match one of these
+                      comma-separated patterns, such as "aws_instance.*".
+                      Patterns that start with "!" leave out the nodes that
+                      match them. Dependencies through the nodes that are
+                      left out are still drawn.
 
-  -type=plan     Type of graph to output. Can be: plan, plan-destroy, apply,
-                 validate, input, refresh.
+  -module-depth=n     Collapse each module nested deeper than n into a
+                      single node. 0 collapses every module. By default,
+                      every node of every module is drawn.
+
+  -no-color           If specified, output won't contain any color.
+
+  -type=plan          Type of graph to output. Can be: plan, plan-destroy,
+                      apply, validate, input, refresh.
 
 
 `
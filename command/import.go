@@ -1,15 +1,23 @@
 package command
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/terraform/backend"
+	backendlocal "github.com/hashicorp/terraform/backend/local"
+	"github.com/hashicorp/terraform/command/clistate"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/ryanuber/columnize"
 )
 
 // ImportCommand is a cli.Command implementation that imports resources
@@ -26,16 +34,22 @@ func (c *ImportCommand) Run(args []string) int {
 		return 1
 	}
 
-	var configPath string
+	var configPath, generateConfigPath string
+	var dryRun bool
 	args = c.Meta.process(args, true)
+	if c.Meta.checkReadOnly("import") {
+		return 1
+	}
 
 	cmdFlags := c.Meta.flagSet("import")
-	cmdFlags.IntVar(&c.Meta.parallelism, "parallelism", 0, "parallelism")
-	cmdFlags.StringVar(&c.Meta.statePath, "state", DefaultStateFilename, "path")
+	c.Meta.parallelismFlag(cmdFlags, 0)
+	cmdFlags.StringVar(&c.Meta.statePath, "state", "", "path")
 	cmdFlags.StringVar(&c.Meta.stateOutPath, "state-out", "", "path")
 	cmdFlags.StringVar(&c.Meta.backupPath, "backup", "", "path")
 	cmdFlags.StringVar(&configPath, "config", pwd, "path")
 	cmdFlags.StringVar(&c.Meta.provider, "provider", "", "provider")
+	cmdFlags.StringVar(&generateConfigPath, "generate-config", "", "path")
+	cmdFlags.BoolVar(&dryRun, "dry-run", false, "dry run")
 	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
 	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
@@ -66,6 +80,15 @@ func (c *ImportCommand) Run(args []string) int {
 		c.Ui.Error(importCommandResourceModeMsg)
 		return 1
 	}
+	if generateConfigPath != "" && dryRun {
+		c.Ui.Error("Error: -generate-config can't be used with -dry-run.")
+		return 1
+	}
+	if generateConfigPath != "" && addr.Index != -1 {
+		// a generated resource block can't describe a single instance
+		c.Ui.Error(importCommandGenerateConfigIndexMsg)
+		return 1
+	}
 
 	// Load the module
 	var mod *module.Tree
@@ -100,7 +123,20 @@ func (c *ImportCommand) Run(args []string) int {
 			break
 		}
 	}
-	if rc == nil {
+	if generateConfigPath != "" {
+		if rc != nil {
+			c.Ui.Error(fmt.Sprintf(importCommandGenerateConfigExistsFmt, addr))
+			return 1
+		}
+
+		// Don't overwrite anything the user already has
+		if _, err := os.Stat(generateConfigPath); err == nil {
+			c.Ui.Error(fmt.Sprintf(
+				"Error: %s already exists. Please choose another path for -generate-config.",
+				generateConfigPath))
+			return 1
+		}
+	} else if rc == nil {
 		modulePath := addr.WholeModuleAddress().String()
 		if modulePath == "" {
 			modulePath = "the root module"
@@ -128,17 +164,82 @@ func (c *ImportCommand) Run(args []string) int {
 		return 1
 	}
 
+	// The state is imported into the state of the selected environment in
+	// the configured backend. The state flags only choose a local state
+	// file, so they'd otherwise be ignored, and the resource would end up
+	// somewhere other than where the user expects.
+	env := c.Env()
+	if c.Meta.statePath != "" || c.Meta.stateOutPath != "" {
+		if !c.usesStateFlags(b) {
+			c.Ui.Error(importCommandStateFlagBackendMsg)
+			return 1
+		}
+		if env != backend.DefaultStateName {
+			c.Ui.Error(fmt.Sprintf(importCommandStateFlagEnvFmt, env))
+			return 1
+		}
+	}
+
 	// Build the operation
 	opReq := c.Operation()
 	opReq.Module = mod
 
 	// Get the context
-	ctx, state, err := local.Context(opReq)
+	ctx, st, err := local.Context(opReq)
 	if err != nil {
 		c.Ui.Error(err.Error())
 		return 1
 	}
 
+	if c.stateLock {
+		lockCtx, cancel := context.WithTimeout(context.Background(), c.stateLockTimeout)
+		defer cancel()
+
+		lockInfo := state.NewLockInfo()
+		lockInfo.Operation = "import"
+		lockID, err := clistate.Lock(lockCtx, st, lockInfo, c.Ui, c.Colorize())
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error locking state: %s", err))
+			return 1
+		}
+
+		defer clistate.Unlock(st, lockID, c.Ui, c.Colorize())
+
+		// The state was read before it was locked, and may have been
+		// written since, so the import starts from the state as it is now
+		// that nothing else can write it. Nothing has been asked for yet,
+		// so the context can be replaced without losing any input.
+		if err := st.RefreshState(); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error loading state: %s", err))
+			return 1
+		}
+
+		opts := c.contextOpts()
+		opts.Module = mod
+		opts.State = st.State()
+		opts.Variables = ctx.Variables()
+		ctx, err = terraform.NewContext(opts)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+	}
+
+	// The providers are configured with the variables, so unset ones are
+	// asked for just like for plan and apply, and a required variable that
+	// still has no value is an error here rather than a confusing one from
+	// the provider configuration.
+	if err := ctx.Input(c.InputMode()); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error asking for user input: %s", err))
+		return 1
+	}
+	if es := ctx.ValidateVariables(); len(es) > 0 {
+		for _, e := range es {
+			c.Ui.Error(fmt.Sprintf("Error: %s", e))
+		}
+		return 1
+	}
+
 	// Perform the import. Note that as you can see it is possible for this
 	// API to import more than one resource at once. For now, we only allow
 	// one while we stabilize this feature.
@@ -156,22 +257,166 @@ func (c *ImportCommand) Run(args []string) int {
 		return 1
 	}
 
+	// A dry run only shows what would be added to the state
+	if dryRun {
+		c.Ui.Output(fmt.Sprintf(importCommandDryRunFmt, env))
+		c.Ui.Output(importDryRunOutput(st.State(), newState))
+		return 0
+	}
+
 	// Persist the final state
-	log.Printf("[INFO] Writing state output to: %s", c.Meta.StateOutPath())
-	if err := state.WriteState(newState); err != nil {
+	log.Printf("[INFO] Writing state for environment %q", env)
+	if err := st.WriteState(newState); err != nil {
 		c.Ui.Error(fmt.Sprintf("Error writing state file: %s", err))
 		return 1
 	}
-	if err := state.PersistState(); err != nil {
+	if err := st.PersistState(); err != nil {
 		c.Ui.Error(fmt.Sprintf("Error writing state file: %s", err))
 		return 1
 	}
 
+	if generateConfigPath != "" {
+		if err := c.generateConfig(generateConfigPath, addr, mod, newState); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error generating configuration: %s", err))
+			return 1
+		}
+
+		c.Ui.Output(fmt.Sprintf(
+			"Configuration for %s written to %s.", addr, generateConfigPath))
+	}
+
 	c.Ui.Output(c.Colorize().Color("[reset][green]\n" + importCommandSuccessMsg))
 
 	return 0
 }
 
+// usesStateFlags returns true if b reads and writes the state files given
+// with -state and -state-out. Backends other than local, and local backends
+// configured with a path, manage the state themselves.
+func (c *ImportCommand) usesStateFlags(b backend.Backend) bool {
+	l, ok := b.(*backendlocal.Local)
+	if !ok || l.Backend != nil {
+		return false
+	}
+
+	if c.Meta.statePath != "" && l.StatePath != c.Meta.statePath {
+		return false
+	}
+	if c.Meta.stateOutPath != "" && l.StateOutPath != c.Meta.stateOutPath {
+		return false
+	}
+
+	return true
+}
+
+// importDryRunOutput returns the attributes of the resources in newState
+// that aren't in old, or that have a different ID, in the format of
+// "terraform state show".
+func importDryRunOutput(old, newState *terraform.State) string {
+	var buf bytes.Buffer
+	for _, ms := range newState.Modules {
+		var oldMs *terraform.ModuleState
+		if old != nil {
+			oldMs = old.ModuleByPath(ms.Path)
+		}
+
+		prefix := ""
+		for _, name := range ms.Path[1:] {
+			prefix += "module." + name + "."
+		}
+
+		keys := make([]string, 0, len(ms.Resources))
+		for k := range ms.Resources {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			rs := ms.Resources[k]
+			if rs.Primary == nil {
+				continue
+			}
+			if oldMs != nil {
+				if oldRs, ok := oldMs.Resources[k]; ok && oldRs.Primary != nil && oldRs.Primary.ID == rs.Primary.ID {
+					continue
+				}
+			}
+
+			attrs := make([]string, 0, len(rs.Primary.Attributes))
+			for a := range rs.Primary.Attributes {
+				if a != "id" {
+					attrs = append(attrs, a)
+				}
+			}
+			sort.Strings(attrs)
+
+			output := []string{fmt.Sprintf("id | %s", rs.Primary.ID)}
+			for _, a := range attrs {
+				output = append(output, fmt.Sprintf("%s | %s", a, rs.Primary.Attributes[a]))
+			}
+
+			config := columnize.DefaultConfig()
+			config.Glue = " = "
+			config.Prefix = "  "
+			fmt.Fprintf(&buf, "%s%s:\n%s\n\n", prefix, k, columnize.Format(output, config))
+		}
+	}
+
+	return strings.TrimSpace(buf.String())
+}
+
+// generateConfig writes a configuration skeleton for the imported
+// resource at addr to path.
+func (c *ImportCommand) generateConfig(
+	path string,
+	addr *terraform.ResourceAddress,
+	mod *module.Tree,
+	state *terraform.State) error {
+	ms := state.ModuleByPath(append([]string{"root"}, addr.Path...))
+	if ms == nil {
+		return fmt.Errorf("%s was not found in the state", addr)
+	}
+	rs, ok := ms.Resources[addr.Type+"."+addr.Name]
+	if !ok || rs.Primary == nil {
+		return fmt.Errorf("%s was not found in the state", addr)
+	}
+
+	// The provider is only used to validate the generated configuration,
+	// so it doesn't need to be configured.
+	providerName := strings.SplitN(addr.Type, "_", 2)[0]
+	if c.Meta.provider != "" {
+		providerName = strings.SplitN(c.Meta.provider, ".", 2)[0]
+	}
+
+	var p terraform.ResourceProvider
+	opts := c.contextOpts()
+	if opts.ProviderResolver != nil {
+		deps := terraform.ModuleTreeDependencies(mod, state)
+		factories, errs := opts.ProviderResolver.ResolveProviders(deps.AllPluginRequirements())
+		if len(errs) > 0 {
+			return errs[0]
+		}
+
+		if f, ok := factories[providerName]; ok {
+			var err error
+			p, err = f()
+			if err != nil {
+				return err
+			}
+			if closer, ok := p.(terraform.ResourceProviderCloser); ok {
+				defer closer.Close()
+			}
+		}
+	}
+
+	src, err := generateImportConfig(addr, rs.Primary, p)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, []byte(src), 0644)
+}
+
 func (c *ImportCommand) Help() string {
 	helpText := `
 Usage: terraform import [options] ADDR ID
@@ -189,11 +434,11 @@ Usage: terraform import [options] ADDR ID
   determine the ID syntax to use. It typically matches directly to the ID
   that the provider uses.
 
-  In the current state of Terraform import, the resource is only imported
-  into your state file. Once it is imported, you must manually write
-  configuration for the new resource or Terraform will mark it for destruction.
-  Future versions of Terraform will expand the functionality of Terraform
-  import.
+  The resource is imported into the state of the current environment in
+  the configured backend, which is locked while importing. Once it is imported,
+  you must write configuration for the new resource or Terraform will mark
+  it for destruction. The -generate-config option can write a starting
+  point for that configuration.
 
   This command will not modify your infrastructure, but it will make
   network requests to inspect parts of your infrastructure relevant to
@@ -210,6 +455,16 @@ Options:
                       If no config files are present, they must be provided
                       via the input prompts or env vars.
 
+  -dry-run            Import the resource without saving it, and show the
+                      state that would be added for it instead.
+
+  -generate-config=path
+                      Write a configuration skeleton for the imported
+                      resource to this file, populated from its imported
+                      attributes. Attributes that the provider computes are
+                      commented out. The resource must not already exist
+                      in the configuration, and the file must not exist.
+
   -input=true         Ask for input for variables if not directly set.
 
   -lock=true          Lock the state file when locking is supported.
@@ -223,19 +478,32 @@ Options:
                       normal provider prefix of the resource being imported.
 
   -state=path         Path to read and save state (unless state-out
-                      is specified). Defaults to "terraform.tfstate".
+                      is specified). Defaults to "terraform.tfstate". Only
+                      allowed with the local backend and the default
+                      environment.
 
   -state-out=path     Path to write updated state file. By default, the
-                      "-state" path will be used.
+                      "-state" path will be used. Only allowed with the
+                      local backend and the default environment.
 
   -var 'foo=bar'      Set a variable in the Terraform configuration. This
-                      flag can be set multiple times. This is only useful
-                      with the "-config" flag.
+                      flag can be set multiple times. The providers are
+                      configured with the variables, including those of
+                      child modules.
 
   -var-file=foo       Set variables in the Terraform configuration from
                       a file. If "terraform.tfvars" is present, it will be
                       automatically loaded if this flag is not specified.
 
+  -var-dir=vars       Load layered variables from the given directory:
+                      "common.tfvars", then each -var-layer, then the file
+                      named after the current environment. -var and -var-file
+                      override layered variables.
+
+  -var-layer=name     Load "name.tfvars" from the -var-dir directory,
+                      which defaults to "vars", as a layer of variables.
+                      This flag can be set multiple times, and later
+                      layers override earlier ones.
 
 `
 	return strings.TrimSpace(helpText)
@@ -262,6 +530,17 @@ const importCommandResourceModeMsg = `Error: resource address must refer to a ma
 Data resources cannot be imported.
 `
 
+const importCommandGenerateConfigIndexMsg = `Error: -generate-config can't be used with an indexed resource address.
+
+Import to an address without an index, such as "aws_instance.foo".
+`
+
+const importCommandGenerateConfigExistsFmt = `Error: resource address %q already exists in the configuration.
+
+-generate-config writes a new resource block, so it can only be used for
+resources that aren't in the configuration yet.
+`
+
 const importCommandMissingConfigMsg = `Error: no configuration files in this directory.
 
 "terraform import" can only be run in a Terraform configuration directory.
@@ -282,6 +561,23 @@ resource %q %q {
 }
 `
 
+const importCommandStateFlagBackendMsg = `Error: -state and -state-out can't be used with a configured backend.
+
+The resource is imported into the state stored by the backend, which would
+ignore these flags. To import into a local state file, remove the backend
+configuration first.
+`
+
+const importCommandStateFlagEnvFmt = `Error: -state and -state-out can't be used in the %q environment.
+
+The resource is imported into the state of the current environment. To import
+into a local state file, select the "default" environment first.
+`
+
+const importCommandDryRunFmt = `Dry run: nothing was saved to the state of the %q environment.
+Importing would add the following to it:
+`
+
 const importCommandSuccessMsg = `Import successful!
 
 The resources that were imported are shown above. These resources are now in
@@ -0,0 +1,291 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// importConfigObject is a nested block in generated configuration. It's
+// kept apart from map[string]interface{}, which is used for map attributes,
+// since the two are written differently.
+type importConfigObject map[string]interface{}
+
+// importConfigIdent matches map keys that can be written without quotes.
+var importConfigIdent = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+// generateImportConfig returns a configuration skeleton for the resource
+// at addr, populated from the attributes of its imported state.
+//
+// Since the attributes in the state include the ones that are computed by
+// the provider, the skeleton is validated with the provider, if there is
+// one, and any attribute the provider won't accept in configuration is
+// commented out.
+func generateImportConfig(
+	addr *terraform.ResourceAddress,
+	is *terraform.InstanceState,
+	p terraform.ResourceProvider) (string, error) {
+	values := importConfigExpandObject(is.Attributes, "")
+
+	// The ID is always computed and is never part of the configuration
+	delete(values, "id")
+
+	keys := make([]string, 0, len(values))
+	for k, v := range values {
+		// Empty collections are the same as leaving the attribute unset
+		if l, ok := v.([]interface{}); ok && len(l) == 0 {
+			delete(values, k)
+			continue
+		}
+		if m, ok := v.(map[string]interface{}); ok && len(m) == 0 {
+			delete(values, k)
+			continue
+		}
+
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	rejected := make(map[string]bool)
+	if p != nil {
+		var err error
+		rejected, err = importConfigRejected(addr.Type, values, p)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf(
+		"# Generated by \"terraform import\" from the imported state of %s.\n", addr))
+	buf.WriteString("# Review it before planning, as attributes that the provider computes\n")
+	buf.WriteString("# are commented out and defaults are included.\n")
+	buf.WriteString(fmt.Sprintf("resource %q %q {\n", addr.Type, addr.Name))
+	for _, k := range keys {
+		prefix := "  "
+		if rejected[k] {
+			prefix = "  # "
+		}
+
+		importConfigWriteAttr(&buf, prefix, k, values[k])
+	}
+	buf.WriteString("}\n")
+
+	return buf.String(), nil
+}
+
+// importConfigRejected validates the given values as the configuration of
+// a resource of type t, and returns the top-level attributes that the
+// provider rejects. Validation is repeated without the rejected attributes
+// until the rest are accepted, since one error can hide another.
+func importConfigRejected(
+	t string,
+	values importConfigObject,
+	p terraform.ResourceProvider) (map[string]bool, error) {
+	rejected := make(map[string]bool)
+	for {
+		raw := make(map[string]interface{})
+		for k, v := range values {
+			if !rejected[k] {
+				raw[k] = importConfigRaw(v)
+			}
+		}
+
+		rc, err := config.NewRawConfig(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		_, es := p.ValidateResource(t, terraform.NewResourceConfig(rc))
+
+		found := false
+		for k := range raw {
+			for _, e := range es {
+				if importConfigErrorIsFor(e, k) {
+					rejected[k] = true
+					found = true
+					break
+				}
+			}
+		}
+
+		if !found {
+			return rejected, nil
+		}
+	}
+}
+
+// importConfigErrorIsFor reports whether a validation error is about the
+// attribute k or something nested within it. Only errors that carry the
+// path of their attribute, as a terraform.AttributeError, are about one.
+func importConfigErrorIsFor(err error, k string) bool {
+	ae := terraform.ErrorAttribute(err)
+	if ae == nil {
+		return false
+	}
+
+	return ae.Path == k || strings.HasPrefix(ae.Path, k+".")
+}
+
+func importConfigWriteAttr(buf *bytes.Buffer, prefix, k string, v interface{}) {
+	switch v := v.(type) {
+	case string:
+		buf.WriteString(fmt.Sprintf("%s%s = %s\n", prefix, k, importConfigString(v)))
+	case map[string]interface{}:
+		buf.WriteString(fmt.Sprintf("%s%s {\n", prefix, k))
+		for _, mk := range importConfigSortedKeys(v) {
+			name := mk
+			if !importConfigIdent.MatchString(mk) {
+				name = strconv.Quote(mk)
+			}
+
+			importConfigWriteAttr(buf, prefix+"  ", name, v[mk])
+		}
+		buf.WriteString(fmt.Sprintf("%s}\n", prefix))
+	case importConfigObject:
+		buf.WriteString(fmt.Sprintf("%s%s {\n", prefix, k))
+		for _, ok := range importConfigSortedKeys(v) {
+			importConfigWriteAttr(buf, prefix+"  ", ok, v[ok])
+		}
+		buf.WriteString(fmt.Sprintf("%s}\n", prefix))
+	case []interface{}:
+		// Lists of objects are written as repeated blocks
+		if len(v) > 0 {
+			if _, ok := v[0].(importConfigObject); ok {
+				for _, elem := range v {
+					importConfigWriteAttr(buf, prefix, k, elem)
+				}
+				return
+			}
+		}
+
+		elems := make([]string, 0, len(v))
+		for _, elem := range v {
+			if s, ok := elem.(string); ok {
+				elems = append(elems, importConfigString(s))
+			}
+		}
+		buf.WriteString(fmt.Sprintf(
+			"%s%s = [%s]\n", prefix, k, strings.Join(elems, ", ")))
+	}
+}
+
+// importConfigString quotes s as an HCL string, escaping anything that
+// would otherwise be interpolated.
+func importConfigString(s string) string {
+	return strings.Replace(strconv.Quote(s), "${", "$${", -1)
+}
+
+func importConfigSortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// importConfigRaw converts a value to the types used by raw configuration.
+func importConfigRaw(v interface{}) interface{} {
+	switch v := v.(type) {
+	case importConfigObject:
+		result := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			result[k] = importConfigRaw(elem)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, elem := range v {
+			result[i] = importConfigRaw(elem)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// importConfigExpandObject expands the flattened attributes under prefix
+// into an object. Unlike flatmap.Expand, this also handles sets, whose
+// elements are keyed by hash rather than by index.
+func importConfigExpandObject(attrs map[string]string, prefix string) importConfigObject {
+	result := make(importConfigObject)
+	for k := range attrs {
+		if prefix != "" {
+			if !strings.HasPrefix(k, prefix+".") {
+				continue
+			}
+			k = k[len(prefix)+1:]
+		}
+
+		name := strings.SplitN(k, ".", 2)[0]
+		if _, ok := result[name]; ok {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		result[name] = importConfigExpand(attrs, key)
+	}
+
+	return result
+}
+
+func importConfigExpand(attrs map[string]string, k string) interface{} {
+	if v, ok := attrs[k]; ok {
+		return v
+	}
+
+	if _, ok := attrs[k+".#"]; ok {
+		// The elements of a list are keyed by index, and those of a set by
+		// hash, so we collect whatever keys are present.
+		var elems []string
+		seen := make(map[string]bool)
+		for ak := range attrs {
+			if !strings.HasPrefix(ak, k+".") {
+				continue
+			}
+
+			elem := strings.SplitN(ak[len(k)+1:], ".", 2)[0]
+			if elem == "#" || seen[elem] {
+				continue
+			}
+
+			seen[elem] = true
+			elems = append(elems, elem)
+		}
+		sort.Slice(elems, func(i, j int) bool {
+			a, _ := strconv.Atoi(elems[i])
+			b, _ := strconv.Atoi(elems[j])
+			return a < b
+		})
+
+		result := make([]interface{}, len(elems))
+		for i, elem := range elems {
+			result[i] = importConfigExpand(attrs, k+"."+elem)
+		}
+		return result
+	}
+
+	if _, ok := attrs[k+".%"]; ok {
+		// Map keys can contain dots, so everything after the prefix is
+		// the key.
+		result := make(map[string]interface{})
+		for ak, v := range attrs {
+			if strings.HasPrefix(ak, k+".") && ak != k+".%" {
+				result[ak[len(k)+1:]] = v
+			}
+		}
+		return result
+	}
+
+	return importConfigExpandObject(attrs, k)
+}
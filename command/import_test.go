@@ -2,9 +2,16 @@ package command
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/helper/copy"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 )
@@ -49,6 +56,356 @@ func TestImport(t *testing.T) {
 	testStateOutput(t, statePath, testImportStr)
 }
 
+func TestImport_backend(t *testing.T) {
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("import-backend"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ImportCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	p.ImportStateFn = nil
+	p.ImportStateReturn = []*terraform.InstanceState{
+		&terraform.InstanceState{
+			ID: "yay",
+			Ephemeral: terraform.EphemeralState{
+				Type: "test_instance",
+			},
+		},
+	}
+
+	args := []string{
+		"test_instance.foo",
+		"bar",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	// The state is written by the backend, not to a local state file
+	testStateOutput(t, "local-state.tfstate", testImportStr)
+	if _, err := os.Stat(DefaultStateFilename); !os.IsNotExist(err) {
+		t.Fatalf("%s should not exist", DefaultStateFilename)
+	}
+}
+
+func TestImport_backendStateFlag(t *testing.T) {
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("import-backend"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ImportCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", "other.tfstate",
+		"test_instance.foo",
+		"bar",
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "configured backend") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+	if p.ImportStateCalled {
+		t.Fatal("ImportState should not be called")
+	}
+}
+
+func TestImport_dryRun(t *testing.T) {
+	defer testChdir(t, testFixturePath("import-provider-implicit"))()
+
+	statePath := testTempFile(t)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ImportCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	p.ImportStateFn = nil
+	p.ImportStateReturn = []*terraform.InstanceState{
+		&terraform.InstanceState{
+			ID: "yay",
+			Attributes: map[string]string{
+				"ami": "ami-123",
+			},
+			Ephemeral: terraform.EphemeralState{
+				Type: "test_instance",
+			},
+		},
+	}
+	p.RefreshFn = func(info *terraform.InstanceInfo, s *terraform.InstanceState) (*terraform.InstanceState, error) {
+		return s, nil
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-dry-run",
+		"test_instance.foo",
+		"bar",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	expected := strings.TrimSpace(`
+test_instance.foo:
+  id  = yay
+  ami = ami-123
+`)
+	if !strings.Contains(output, expected) {
+		t.Fatalf("bad:\n%s", output)
+	}
+
+	// Nothing is written
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Fatalf("state should not be written: %s", err)
+	}
+}
+
+func TestImport_lockedState(t *testing.T) {
+	statePath := testTempFile(t)
+
+	unlock, err := testLockState("./testdata", statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	defer testChdir(t, testFixturePath("import-provider-implicit"))()
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ImportCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"test_instance.foo",
+		"bar",
+	}
+	if code := c.Run(args); code == 0 {
+		t.Fatal("expected error")
+	}
+
+	output := ui.ErrorWriter.String()
+	if !strings.Contains(output, "lock") {
+		t.Fatal("command output does not look like a lock error:", output)
+	}
+}
+
+// test import with locked state, where the state is written before it's
+// unlocked
+func TestImport_lockedStateWritten(t *testing.T) {
+	original := terraform.NewState()
+	statePath := testStateFile(t, original)
+
+	unlock, err := testLockState("./testdata", statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// write the state and unlock it while import waits for the lock
+	written := original.DeepCopy()
+	written.Serial++
+	written.RootModule().Resources["test_instance.baz"] = &terraform.ResourceState{
+		Type: "test_instance",
+		Primary: &terraform.InstanceState{
+			ID: "baz",
+		},
+	}
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		f, err := os.Create(statePath)
+		if err == nil {
+			terraform.WriteState(written, f)
+			f.Close()
+		}
+		unlock()
+	}()
+
+	defer testChdir(t, testFixturePath("import-provider-implicit"))()
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ImportCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	p.ImportStateFn = nil
+	p.ImportStateReturn = []*terraform.InstanceState{
+		&terraform.InstanceState{
+			ID: "yay",
+			Ephemeral: terraform.EphemeralState{
+				Type: "test_instance",
+			},
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-lock-timeout", "4s",
+		"test_instance.foo",
+		"bar",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	actual := testStateRead(t, statePath)
+	for _, name := range []string{"test_instance.baz", "test_instance.foo"} {
+		if _, ok := actual.RootModule().Resources[name]; !ok {
+			t.Fatalf("%s missing from state:\n\n%s", name, actual)
+		}
+	}
+}
+
+func TestImport_generateConfig(t *testing.T) {
+	defer testChdir(t, testFixturePath("import-missing-resource-config"))()
+
+	statePath := testTempFile(t)
+	configPath := filepath.Join(testTempDir(t), "foo.tf")
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ImportCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	p.ImportStateFn = nil
+	p.ImportStateReturn = []*terraform.InstanceState{
+		&terraform.InstanceState{
+			ID: "yay",
+			Attributes: map[string]string{
+				"id":                  "yay",
+				"ami":                 "ami-${foo}",
+				"arn":                 "arn:yay",
+				"tags.%":              "2",
+				"tags.Name":           "yay",
+				"tags.example.com/id": "1",
+				"ebs.#":               "2",
+				"ebs.1234.size":       "10",
+				"ebs.1234.type":       "gp2",
+				"ebs.5678.size":       "20",
+				"ebs.5678.type":       "io1",
+				"groups.#":            "2",
+				"groups.0":            "a",
+				"groups.1":            "b",
+				"empty.#":             "0",
+			},
+			Ephemeral: terraform.EphemeralState{
+				Type: "test_instance",
+			},
+		},
+	}
+
+	// arn is computed, so the provider won't accept it in configuration.
+	// The other error isn't about an attribute, so it comments out nothing
+	// even though it names one.
+	p.ValidateResourceFn = func(
+		t string, c *terraform.ResourceConfig) ([]string, []error) {
+		es := []error{fmt.Errorf(`"groups" can't be checked yet`)}
+		if _, ok := c.Get("arn"); ok {
+			es = append(es, &terraform.AttributeError{
+				Path: "arn",
+				Err:  fmt.Errorf("arn: this field cannot be set"),
+			})
+		}
+
+		return nil, es
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-generate-config", configPath,
+		"test_instance.foo",
+		"bar",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	actual, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := strings.TrimSpace(testImportGenerateConfigStr) + "\n"
+	if string(actual) != expected {
+		t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", expected, actual)
+	}
+
+	// The generated configuration must be loadable
+	if _, err := config.LoadFile(configPath); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestImport_generateConfigExists(t *testing.T) {
+	defer testChdir(t, testFixturePath("import-provider-implicit"))()
+
+	statePath := testTempFile(t)
+	configPath := filepath.Join(testTempDir(t), "foo.tf")
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ImportCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-generate-config", configPath,
+		"test_instance.foo",
+		"bar",
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("import succeeded; expected failure")
+	}
+
+	if p.ImportStateCalled {
+		t.Fatal("ImportState should not be called")
+	}
+
+	msg := ui.ErrorWriter.String()
+	if want := "already exists in the configuration"; !strings.Contains(msg, want) {
+		t.Errorf("incorrect message\nwant substring: %s\ngot:\n%s", want, msg)
+	}
+}
+
 func TestImport_providerConfig(t *testing.T) {
 	defer testChdir(t, testFixturePath("import-provider"))()
 
@@ -105,6 +462,72 @@ func TestImport_providerConfig(t *testing.T) {
 	testStateOutput(t, statePath, testImportStr)
 }
 
+// The provider configuration that is asked for is used after the state is
+// locked.
+func TestImport_providerConfigInput(t *testing.T) {
+	defer testChdir(t, testFixturePath("import-provider"))()
+
+	// Disable test mode so input would be asked
+	test = false
+	defer func() { test = true }()
+
+	statePath := testTempFile(t)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ImportCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	p.ImportStateFn = nil
+	p.ImportStateReturn = []*terraform.InstanceState{
+		&terraform.InstanceState{
+			ID: "yay",
+			Ephemeral: terraform.EphemeralState{
+				Type: "test_instance",
+			},
+		},
+	}
+
+	rc, err := config.NewRawConfig(map[string]interface{}{"foo": "input"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	p.InputReturnConfig = terraform.NewResourceConfig(rc)
+
+	configured := false
+	p.ConfigureFn = func(c *terraform.ResourceConfig) error {
+		configured = true
+
+		if v, ok := c.Get("foo"); !ok || v.(string) != "input" {
+			return fmt.Errorf("bad value: %#v", v)
+		}
+
+		return nil
+	}
+
+	args := []string{
+		"-state", statePath,
+		"test_instance.foo",
+		"bar",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if !p.InputCalled {
+		t.Fatal("Input should be called")
+	}
+	if !configured {
+		t.Fatal("Configure should be called")
+	}
+
+	testStateOutput(t, statePath, testImportStr)
+}
+
 func TestImport_providerConfigWithVar(t *testing.T) {
 	defer testChdir(t, testFixturePath("import-provider-var"))()
 
@@ -275,6 +698,106 @@ func TestImport_providerConfigWithVarFile(t *testing.T) {
 	testStateOutput(t, statePath, testImportStr)
 }
 
+func TestImport_providerConfigWithVarModule(t *testing.T) {
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("import-module-provider-var"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	statePath := testTempFile(t)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ImportCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	if err := getModules(&c.Meta, ".", module.GetModeGet); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p.ImportStateFn = nil
+	p.ImportStateReturn = []*terraform.InstanceState{
+		&terraform.InstanceState{
+			ID: "yay",
+			Ephemeral: terraform.EphemeralState{
+				Type: "test_instance",
+			},
+		},
+	}
+
+	// The root provider.test has no configuration, so only the provider of
+	// the child module has a foo.
+	configured := false
+	p.ConfigureFn = func(c *terraform.ResourceConfig) error {
+		v, ok := c.Get("foo")
+		if !ok {
+			return nil
+		}
+		if v.(string) != "bar" {
+			return fmt.Errorf("bad value: %#v", v)
+		}
+
+		configured = true
+		return nil
+	}
+
+	// The provider of the child module is configured with the variable
+	// that its module block sets from -var, while the other variable of
+	// the module, which refers to a resource that doesn't exist yet, isn't
+	// needed.
+	args := []string{
+		"-state", statePath,
+		"-var", "foo=bar",
+		"module.child.test_instance.foo",
+		"bar",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if !configured {
+		t.Fatal("Configure should be called")
+	}
+
+	testStateOutput(t, statePath, testImportModuleStr)
+}
+
+func TestImport_providerConfigWithVarUnset(t *testing.T) {
+	defer testChdir(t, testFixturePath("import-provider-var"))()
+
+	statePath := testTempFile(t)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ImportCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-input=false",
+		"test_instance.foo",
+		"bar",
+	}
+	if code := c.Run(args); code == 0 {
+		t.Fatal("import should fail")
+	}
+
+	if msg := ui.ErrorWriter.String(); !strings.Contains(msg, "Required variable not set: foo") {
+		t.Fatalf("bad: %s", msg)
+	}
+	if p.ImportStateCalled {
+		t.Fatal("ImportState shouldn't be called")
+	}
+}
+
 func TestImport_customProvider(t *testing.T) {
 	defer testChdir(t, testFixturePath("import-provider-aliased"))()
 
@@ -472,8 +995,39 @@ test_instance.foo:
   provider = test
 `
 
+const testImportModuleStr = `
+<no state>
+module.child:
+  test_instance.foo:
+    ID = yay
+    provider = test
+`
+
 const testImportCustomProviderStr = `
 test_instance.foo:
   ID = yay
   provider = test.alias
 `
+
+const testImportGenerateConfigStr = `
+# Generated by "terraform import" from the imported state of test_instance.foo.
+# Review it before planning, as attributes that the provider computes
+# are commented out and defaults are included.
+resource "test_instance" "foo" {
+  ami = "ami-$${foo}"
+  # arn = "arn:yay"
+  ebs {
+    size = "10"
+    type = "gp2"
+  }
+  ebs {
+    size = "20"
+    type = "io1"
+  }
+  groups = ["a", "b"]
+  tags {
+    Name = "yay"
+    "example.com/id" = "1"
+  }
+}
+`
@@ -1,22 +1,29 @@
 package command
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
+	humanize "github.com/dustin/go-humanize"
 	getter "github.com/hashicorp/go-getter"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/terraform/backend"
+	backendlocal "github.com/hashicorp/terraform/backend/local"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/config/module"
 	"github.com/hashicorp/terraform/helper/variables"
+	"github.com/hashicorp/terraform/helper/warnings"
 	"github.com/hashicorp/terraform/plugin"
 	"github.com/hashicorp/terraform/plugin/discovery"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
 )
 
 // InitCommand is a Command implementation that takes a Terraform
@@ -24,36 +31,82 @@ import (
 type InitCommand struct {
 	Meta
 
-	// getProvider fetches providers that aren't found locally, and unpacks
-	// them into the dst directory.
-	// This uses discovery.GetProvider by default, but it provided here as a
+	// findProvider finds the release to fetch of a provider that isn't found
+	// locally, and getProvider fetches it and unpacks it into the dst
+	// directory. These use discovery.FindProvider and
+	// discovery.GetProviderRelease by default, but are provided here as a
 	// way to mock fetching providers for tests.
-	getProvider func(dst, provider string, req discovery.Constraints, protoVersion uint) error
+	findProvider func(provider string, req discovery.Constraints, protoVersion uint, platform discovery.Platform) (*discovery.ProviderRelease, error)
+	getProvider  func(dst string, r *discovery.ProviderRelease, progress discovery.ProgressFunc) error
+
+	// summary is what init decided, which is output at the end.
+	summary *initSummary
 }
 
 func (c *InitCommand) Run(args []string) int {
-	var flagBackend, flagGet, flagGetPlugins bool
+	var flagBackend, flagGet, flagGetPlugins, flagCheckBackendAccess bool
 	var flagConfigExtra map[string]interface{}
+	var flagPlatforms, flagSummaryOut string
 
 	args = c.Meta.process(args, false)
 	cmdFlags := c.flagSet("init")
 	cmdFlags.BoolVar(&flagBackend, "backend", true, "")
 	cmdFlags.Var((*variables.FlagAny)(&flagConfigExtra), "backend-config", "")
+	cmdFlags.BoolVar(&flagCheckBackendAccess, "check-backend-access", false, "check backend access")
 	cmdFlags.BoolVar(&flagGet, "get", true, "")
 	cmdFlags.BoolVar(&flagGetPlugins, "get-plugins", true, "")
 	cmdFlags.BoolVar(&c.forceInitCopy, "force-copy", false, "suppress prompts about copying state data")
 	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
 	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
 	cmdFlags.BoolVar(&c.reconfigure, "reconfigure", false, "reconfigure")
+	cmdFlags.StringVar(&flagPlatforms, "platforms", "", "platforms")
+	cmdFlags.BoolVar(&c.Meta.quiet, "quiet", false, "quiet")
+	cmdFlags.StringVar(&flagSummaryOut, "summary-out", "", "path")
 
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
 	}
 
-	// set getProvider if we don't have a test version already
+	// Init is run unattended in automation, so when input is disabled it
+	// must fail at any question rather than wait for an answer that will
+	// never come. TF_INPUT disables input just like -input=false.
+	c.strictInput = true
+	if v := os.Getenv(InputModeEnvVar); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil && !b {
+			c.input = false
+		}
+	}
+
+	// set findProvider and getProvider if we don't have test versions
+	// already
+	if c.findProvider == nil {
+		c.findProvider = discovery.FindProvider
+	}
 	if c.getProvider == nil {
-		c.getProvider = discovery.GetProvider
+		c.getProvider = discovery.GetProviderRelease
+	}
+
+	var platforms []discovery.Platform
+	if flagPlatforms != "" {
+		if !flagGetPlugins {
+			c.Ui.Error("The -platforms option can't be used with -get-plugins=false.\n")
+			return 1
+		}
+
+		for _, s := range strings.Split(flagPlatforms, ",") {
+			p, err := discovery.ParsePlatform(strings.TrimSpace(s))
+			if err != nil {
+				c.Ui.Error(fmt.Sprintf("Invalid -platforms value: %s\n", err))
+				return 1
+			}
+			platforms = append(platforms, p)
+		}
+	}
+
+	if flagCheckBackendAccess && !flagBackend {
+		c.Ui.Error("The -check-backend-access option can't be used with -backend=false.\n")
+		return 1
 	}
 
 	// Validate the arg count
@@ -122,6 +175,7 @@ func (c *InitCommand) Run(args []string) int {
 		return 0
 	}
 
+	c.summary = &initSummary{}
 	var back backend.Backend
 
 	// If we're performing a get or loading the backend, then we perform
@@ -141,12 +195,24 @@ func (c *InitCommand) Run(args []string) int {
 			c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
 				"[reset][bold]" +
 					"Downloading modules (if any)...")))
-			if err := getModules(&c.Meta, path, module.GetModeGet); err != nil {
+			restore := c.disableGetterPrompts()
+			err := getModules(&c.Meta, path, module.GetModeGet)
+			restore()
+			if err != nil {
 				c.Ui.Error(fmt.Sprintf(
 					"Error downloading modules: %s", err))
 				return 1
 			}
 
+			mod, err := c.Module(path)
+			if err != nil {
+				c.Ui.Error(fmt.Sprintf(
+					"Error loading modules: %s", err))
+				return 1
+			}
+			c.Warnings.WarnList(c.Ui, warnInitModules,
+				warnings.FromStrings(terraform.ModuleWarnings(mod)))
+			c.summarizeModules(mod)
 		}
 
 		// If we're requesting backend configuration or looking for required
@@ -172,6 +238,20 @@ func (c *InitCommand) Run(args []string) int {
 				c.Ui.Error(err.Error())
 				return 1
 			}
+
+			if err := c.summarizeBackend(back); err != nil {
+				c.Ui.Error(fmt.Sprintf(
+					"Error summarizing the backend: %s", err))
+				return 1
+			}
+		}
+	}
+
+	if flagCheckBackendAccess && back != nil {
+		if err := c.checkBackendAccess(back); err != nil {
+			// this function provides its own output
+			log.Printf("[ERROR] %s", err)
+			return 1
 		}
 	}
 
@@ -194,7 +274,7 @@ func (c *InitCommand) Run(args []string) int {
 			"[reset][bold]Initializing provider plugins...",
 		))
 
-		err = c.getProviders(path, sMgr.State())
+		err = c.getProviders(path, sMgr.State(), platforms)
 		if err != nil {
 			// this function provides its own output
 			log.Printf("[ERROR] %s", err)
@@ -206,6 +286,14 @@ func (c *InitCommand) Run(args []string) int {
 	// so that our success message is nicely spaced out from prior text.
 	if header {
 		c.Ui.Output("")
+		c.Ui.Output(c.summary.String() + "\n")
+	}
+
+	if flagSummaryOut != "" {
+		if err := c.summary.writeJSON(flagSummaryOut); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error writing the summary: %s", err))
+			return 1
+		}
 	}
 
 	c.Ui.Output(c.Colorize().Color(strings.TrimSpace(outputInitSuccess)))
@@ -213,9 +301,54 @@ func (c *InitCommand) Run(args []string) int {
 	return 0
 }
 
-// Load the complete module tree, and fetch any missing providers.
+// checkBackendAccess checks that the state of the current workspace can be
+// read, locked and written with the backend, so that missing permissions
+// are reported now rather than when the state is first written by an
+// apply. Only reading is checked with -lock=false or in read-only mode.
 // This method outputs its own Ui.
-func (c *InitCommand) getProviders(path string, state *terraform.State) error {
+func (c *InitCommand) checkBackendAccess(b backend.Backend) error {
+	c.Ui.Output(c.Colorize().Color(
+		"[reset][bold]Checking access to the backend...",
+	))
+
+	// The local backend wraps the backend that stores the state, and its
+	// states would write a backup of the state next to the configuration.
+	if l, ok := b.(*backendlocal.Local); ok {
+		if l.Backend == nil {
+			c.Ui.Output("The state is stored in a local file, so there's no access to check.")
+			return nil
+		}
+		b = l.Backend
+	}
+
+	// Writing the state is only checked while it's locked, and not at all
+	// in read-only mode.
+	write := c.stateLock && !c.readOnly
+	err := backend.CheckAccess(b, c.Env(), write)
+	switch {
+	case err == nil && write:
+		c.Ui.Output("The state can be read, locked and written.")
+		return nil
+	case err == nil && c.readOnly:
+		c.Ui.Output("The state can be read. Writing it isn't checked in read-only mode.")
+		return nil
+	case err == nil:
+		c.Ui.Output("The state can be read. Writing it isn't checked without locking it.")
+		return nil
+	}
+
+	msg := err.Error()
+	if ae, ok := err.(*backend.AccessError); ok && ae.Hint != "" {
+		msg += "\n\n" + ae.Hint
+	}
+	c.Ui.Error(fmt.Sprintf(strings.TrimSpace(errInitBackendAccess), msg))
+	return err
+}
+
+// Load the complete module tree, and fetch any missing providers.
+// The chosen providers are also fetched and locked for any other given
+// platforms. This method outputs its own Ui.
+func (c *InitCommand) getProviders(path string, state *terraform.State, platforms []discovery.Platform) error {
 	mod, err := c.Module(path)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error getting plugins: %s", err))
@@ -229,22 +362,15 @@ func (c *InitCommand) getProviders(path string, state *terraform.State) error {
 
 	available := c.providerPluginSet()
 	requirements := terraform.ModuleTreeDependencies(mod, state).AllPluginRequirements()
+	c.removeDevProviders(requirements)
 	missing := c.missingPlugins(available, requirements)
 
-	dst := c.pluginDir()
-	var errs error
-	for provider, reqd := range missing {
-		c.Ui.Output(fmt.Sprintf("- downloading plugin for provider %q...", provider))
-		err := c.getProvider(dst, provider, reqd.Versions, plugin.Handshake.ProtocolVersion)
-
-		if err != nil {
-			c.Ui.Error(fmt.Sprintf(errProviderNotFound, err, provider, reqd.Versions))
-			errs = multierror.Append(errs, err)
-		}
+	releases, err := c.findProviders(missing, discovery.CurrentPlatform())
+	if err != nil {
+		return err
 	}
-
-	if errs != nil {
-		return errs
+	if err := c.fetchProviders(c.pluginDir(), releases, ""); err != nil {
+		return err
 	}
 
 	// With all the providers downloaded, we'll generate our lock file
@@ -261,6 +387,7 @@ func (c *InitCommand) getProviders(path string, state *terraform.State) error {
 			return err
 		}
 		digests[name] = digest
+		c.summarizeProvider(name, meta, requirements[name], digest)
 	}
 	err = c.providerPluginsLock().Write(digests)
 	if err != nil {
@@ -268,6 +395,16 @@ func (c *InitCommand) getProviders(path string, state *terraform.State) error {
 		return err
 	}
 
+	for _, platform := range platforms {
+		if platform == discovery.CurrentPlatform() {
+			continue
+		}
+
+		if err := c.getPlatformProviders(platform, chosen); err != nil {
+			return err
+		}
+	}
+
 	// If any providers have "floating" versions (completely unconstrained)
 	// we'll suggest the user constrain with a pessimistic constraint to
 	// avoid implicitly adopting a later major release.
@@ -302,6 +439,180 @@ func (c *InitCommand) getProviders(path string, state *terraform.State) error {
 	return nil
 }
 
+// getPlatformProviders fetches the same versions of the chosen providers
+// that were built for another platform, and writes the lock file for that
+// platform. This lets a working directory initialized on one platform be
+// used on another, for example a lock generated on a workstation with a
+// CI server that runs a different OS. This method outputs its own Ui.
+func (c *InitCommand) getPlatformProviders(platform discovery.Platform, chosen map[string]discovery.PluginMeta) error {
+	// Other platforms must use exactly the versions we chose, so that
+	// they behave the same.
+	requirements := make(discovery.PluginRequirements)
+	for name, meta := range chosen {
+		v := meta.Version.MustParse()
+		requirements[name] = &discovery.PluginConstraints{
+			Versions: discovery.ConstraintStr(v.String()).MustParse(),
+		}
+	}
+
+	// Only plugins that were installed for this platform are considered,
+	// since the other plugin directories hold plugins for the current one.
+	dst := c.platformPluginDir(platform)
+	available := discovery.FindPlugins("provider", []string{dst})
+	available, _ = available.ValidateVersions()
+	missing := c.missingPlugins(available, requirements)
+
+	releases, err := c.findProviders(missing, platform)
+	if err != nil {
+		return err
+	}
+	if err := c.fetchProviders(dst, releases, " for "+platform.String()); err != nil {
+		return err
+	}
+
+	available = discovery.FindPlugins("provider", []string{dst})
+	available, _ = available.ValidateVersions()
+	digests := map[string][]byte{}
+	for name, meta := range choosePlugins(available, requirements) {
+		digest, err := meta.SHA256()
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("failed to read provider plugin %s: %s", meta.Path, err))
+			return err
+		}
+		digests[name] = digest
+		c.summarizePlatformProvider(name, platform, digest)
+	}
+	err = c.platformProviderPluginsLock(platform).Write(digests)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("failed to save provider manifest for %s: %s", platform, err))
+		return err
+	}
+
+	return nil
+}
+
+// findProviders finds the releases to fetch of the missing providers for
+// the given platform, sorted by name. This method outputs its own Ui.
+func (c *InitCommand) findProviders(missing discovery.PluginRequirements, platform discovery.Platform) ([]*discovery.ProviderRelease, error) {
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var releases []*discovery.ProviderRelease
+	var errs error
+	for _, provider := range names {
+		reqd := missing[provider]
+		r, err := c.findProvider(provider, reqd.Versions, plugin.Handshake.ProtocolVersion, platform)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf(errProviderNotFound, err, provider, reqd.Versions))
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		releases = append(releases, r)
+	}
+
+	return releases, errs
+}
+
+// fetchProviders fetches the given provider releases into the dst
+// directory. Unless -quiet is set, the versions and the total size of the
+// downloads are shown before they start, so that a slow download is
+// expected, and each download shows its progress. This method outputs its
+// own Ui.
+func (c *InitCommand) fetchProviders(dst string, releases []*discovery.ProviderRelease, suffix string) error {
+	if len(releases) == 0 {
+		return nil
+	}
+
+	if !c.quiet {
+		var total int64
+		sizeUnknown := false
+		lines := make([]string, len(releases))
+		for i, r := range releases {
+			size := "size unknown"
+			if r.Size >= 0 {
+				size = humanize.Bytes(uint64(r.Size))
+				total += r.Size
+			} else {
+				sizeUnknown = true
+			}
+			lines[i] = fmt.Sprintf("  - %s %s (%s)", r.Name, r.Version, size)
+		}
+
+		totalStr := humanize.Bytes(uint64(total))
+		if sizeUnknown {
+			totalStr = "at least " + totalStr
+		}
+		c.Ui.Output(fmt.Sprintf(
+			"- %d provider plugin(s) to download%s, %s in total:\n%s",
+			len(releases), suffix, totalStr, strings.Join(lines, "\n")))
+	}
+
+	var errs error
+	for _, r := range releases {
+		c.Ui.Output(fmt.Sprintf(
+			"- downloading plugin for provider %q (%s)%s...", r.Name, r.Version, suffix))
+
+		var progress discovery.ProgressFunc
+		if !c.quiet {
+			progress = (&initProgressBar{Ui: c.Ui}).Progress
+		}
+		if err := c.getProvider(dst, r, progress); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error downloading provider %q (%s): %s", r.Name, r.Version, err))
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// initProgressBar outputs the progress of a download as a bar, on a new line
+// each time another tenth of it is done. A download of unknown size instead
+// outputs how much has been downloaded every few megabytes.
+type initProgressBar struct {
+	Ui cli.Ui
+
+	// step is the step that the progress was last output at, which is the
+	// tenth of the download, or the number of progressBarUnknownStep bytes
+	// downloaded if the size isn't known.
+	step int64
+}
+
+// progressBarWidth is the number of characters in a progress bar, and
+// progressBarUnknownStep the number of bytes between progress outputs for a
+// download of unknown size.
+const (
+	progressBarWidth       = 30
+	progressBarUnknownStep = 5 * 1024 * 1024
+)
+
+func (b *initProgressBar) Progress(done, total int64) {
+	if total <= 0 {
+		if step := done / progressBarUnknownStep; step > b.step {
+			b.step = step
+			b.Ui.Output(fmt.Sprintf("  %s downloaded", humanize.Bytes(uint64(done))))
+		}
+		return
+	}
+
+	step := done * 10 / total
+	if step <= b.step {
+		return
+	}
+	b.step = step
+
+	filled := int(done * progressBarWidth / total)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	b.Ui.Output(fmt.Sprintf(
+		"  [%s%s] %3d%% %s of %s",
+		strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled),
+		done*100/total, humanize.Bytes(uint64(done)), humanize.Bytes(uint64(total))))
+}
+
 func (c *InitCommand) copySource(dst, src, pwd string) error {
 	// Verify the directory is empty
 	if empty, err := config.IsEmptyDir(dst); err != nil {
@@ -317,9 +628,56 @@ func (c *InitCommand) copySource(dst, src, pwd string) error {
 	}
 
 	// Get it!
+	defer c.disableGetterPrompts()()
 	return module.GetCopy(dst, source)
 }
 
+// disableGetterPrompts configures the tools used to download modules so
+// that they fail instead of asking for credentials on the terminal when
+// input is disabled, until the returned function restores the environment.
+// The SSH command isn't changed if one is configured, including with git's
+// core.sshCommand, which GIT_SSH_COMMAND would override.
+func (c *InitCommand) disableGetterPrompts() func() {
+	var set []string
+	setenv := func(key, value string) {
+		if _, ok := os.LookupEnv(key); !ok {
+			os.Setenv(key, value)
+			set = append(set, key)
+		}
+	}
+
+	if !c.input {
+		setenv("GIT_TERMINAL_PROMPT", "0")
+		if os.Getenv("GIT_SSH") == "" && !gitSSHCommandConfigured() {
+			setenv("GIT_SSH_COMMAND", "ssh -o BatchMode=yes")
+		}
+	}
+
+	return func() {
+		for _, key := range set {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// gitSSHCommandConfigured returns whether git is configured with an SSH
+// command with core.sshCommand.
+func gitSSHCommandConfigured() bool {
+	out, err := exec.Command("git", "config", "--get", "core.sshCommand").Output()
+	return err == nil && len(bytes.TrimSpace(out)) > 0
+}
+
+func (c *InitCommand) AutocompleteArgs(args []string, prefix string) []string {
+	switch completePositional(args) {
+	case 0:
+		return completeModuleSources(prefix)
+	case 1:
+		return completeDirs(prefix)
+	default:
+		return nil
+	}
+}
+
 func (c *InitCommand) Help() string {
 	helpText := `
 Usage: terraform init [options] [SOURCE] [PATH]
@@ -359,6 +717,14 @@ Options:
                        times. The backend type must be in the configuration
                        itself.
 
+  -check-backend-access=false
+                       Check that the state can be read, locked and written
+                       with the backend, and explain which permissions are
+                       missing if it can't, rather than failing when the
+                       state is first written by an apply. The state is
+                       written back unchanged, or created empty if it
+                       doesn't exist.
+
   -force-copy          Suppress prompts about copying state data. This is
                        equivalent to providing a "yes" to all confirmation
                        prompts.
@@ -368,7 +734,9 @@ Options:
   -get-plugins=true    Download any missing plugins for this configuration.
 
   -input=true          Ask for input if necessary. If false, will error if
-                       input was required.
+                       input was required, with a message that includes the
+                       ID of the question, such as "input required
+                       [backend-migrate-to-new]".
 
   -lock=true           Lock the state file when locking is supported.
 
@@ -376,7 +744,24 @@ Options:
 
   -no-color            If specified, output won't contain any color.
 
+  -platforms=os_arch,...
+                       Also download the chosen provider plugins built for
+                       these platforms, such as "linux_amd64,darwin_amd64",
+                       and lock them, so this working directory can be used
+                       on each of them.
+
+  -quiet               Don't show the provider plugins to download and their
+                       sizes before downloading them, or the progress of
+                       each download.
+
   -reconfigure          Reconfigure the backend, ignoring any saved configuration.
+
+  -summary-out=path    Also write the summary of what was initialized, which
+                       is shown at the end, to the given path as JSON.
+
+  -suppress-warning=code
+                       Don't show warnings with the given code. This flag
+                       can be set multiple times.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -393,6 +778,15 @@ Terraform files.
 Please resolve this issue and try again.
 `
 
+const errInitBackendAccess = `
+Error checking access to the backend: %s
+
+Terraform must be able to read, lock and write the state to run. Grant the
+missing permissions and run "terraform init -check-backend-access" again.
+If the state is locked by another operation, wait for it to finish, or run
+with -lock=false to check access without locking.
+`
+
 const outputInitEmpty = `
 [reset][bold]Terraform initialized in an empty directory![reset]
 
@@ -422,6 +816,13 @@ corresponding provider blocks in configuration, with the constraint strings
 suggested below.
 `
 
+const warnInitModules = `
+Some of the modules used by this configuration have warnings. Check the
+documentation of these modules for how to resolve them.
+
+Warnings:
+`
+
 const errProviderNotFound = `
 [reset][red]%[1]s
 
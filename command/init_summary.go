@@ -0,0 +1,443 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	getter "github.com/hashicorp/go-getter"
+	"github.com/hashicorp/terraform/backend"
+	backendinit "github.com/hashicorp/terraform/backend/init"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/hashicorp/terraform/state"
+)
+
+// initSummary is what init decided, so that it doesn't have to be worked
+// out from the contents of the data directory. It is the JSON format of
+// the summary.
+type initSummary struct {
+	// Backend and Workspaces are set if the backend was initialized.
+	Backend    *initSummaryBackend `json:"backend,omitempty"`
+	Workspaces []string            `json:"workspaces,omitempty"`
+
+	// Modules are set if modules were downloaded, and Providers if
+	// provider plugins were.
+	Modules   []*initSummaryModule   `json:"modules,omitempty"`
+	Providers []*initSummaryProvider `json:"providers,omitempty"`
+}
+
+// initSummaryBackend is the backend that the state is stored with.
+type initSummaryBackend struct {
+	Type string `json:"type"`
+
+	// Config is the configuration of the backend, including the settings
+	// from -backend-config. The values of settings that are secrets are
+	// replaced with initSummarySensitive.
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// initSummaryModule is a module that was downloaded.
+type initSummaryModule struct {
+	Address string `json:"address"`
+	Source  string `json:"source"`
+
+	// Version is the version that was asked for in the source, such as the
+	// ref of a Git repository, or the tag or digest of an OCI artifact.
+	Version string `json:"version,omitempty"`
+
+	// Commit is the commit that was checked out, for Git repositories.
+	Commit string `json:"commit,omitempty"`
+
+	// Dir is where the module was downloaded to.
+	Dir string `json:"dir"`
+}
+
+// initSummaryProvider is a provider plugin that was chosen and locked.
+type initSummaryProvider struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Constraints string `json:"constraints"`
+
+	// SHA256 is the digest of the plugin that was written to the lock
+	// file, and PlatformSHA256s the digests of the plugins for the other
+	// platforms from -platforms, by platform.
+	SHA256          string            `json:"sha256"`
+	PlatformSHA256s map[string]string `json:"platform_sha256s,omitempty"`
+}
+
+// initSummarySensitive replaces the values of backend settings that are
+// secrets.
+const initSummarySensitive = "<sensitive>"
+
+// initSummarySecretWords are the words in the names of the backend settings
+// whose values are secrets, such as "secret_key" and "sas_token". They
+// catch the secrets of the backends that don't mark them in their schema,
+// such as the legacy ones, and of the blocks that every backend has, such
+// as notify.
+var initSummarySecretWords = []string{
+	"access_key", "auth", "credentials", "encryption_key", "key_material",
+	"password", "private_key", "secret", "token",
+}
+
+// summarizeBackend sets the backend and workspaces of the summary from the
+// backend configuration that init saved and the initialized backend.
+func (c *InitCommand) summarizeBackend(b backend.Backend) error {
+	summary := &initSummaryBackend{Type: "local"}
+
+	sMgr := &state.LocalState{Path: filepath.Join(c.DataDir(), DefaultStateFilename)}
+	if err := sMgr.RefreshState(); err != nil {
+		return err
+	}
+	if s := sMgr.State(); s != nil && !s.Backend.Empty() {
+		summary.Type = s.Backend.Type
+		summary.Config = redactBackendConfig(s.Backend.Type, s.Backend.Config)
+	}
+	c.summary.Backend = summary
+
+	workspaces, err := b.States()
+	if err == backend.ErrNamedStatesNotSupported {
+		workspaces, err = []string{backend.DefaultStateName}, nil
+	}
+	if err != nil {
+		return err
+	}
+	c.summary.Workspaces = workspaces
+
+	return nil
+}
+
+// redactBackendConfig returns a copy of the configuration of a backend of
+// the given type with the values of the settings that are secrets
+// replaced, including those in nested blocks such as notify and
+// state_lock.
+func redactBackendConfig(typ string, raw map[string]interface{}) map[string]interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	return redactBackendMap(typ, raw, false)
+}
+
+// redactBackendMap returns a copy of m, the configuration of a backend of
+// the given type, with the values of the keys that are secrets replaced,
+// or of every key but the type if all is true. Keys that are in the schema
+// of the backend are secrets if the schema marks them as sensitive, and
+// other keys if they look like secrets.
+func redactBackendMap(typ string, m map[string]interface{}, all bool) map[string]interface{} {
+	keys := backendSensitiveKeys(typ)
+
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		sensitive, ok := keys[k]
+		if !ok {
+			sensitive = redactBackendKey(k)
+		}
+
+		switch {
+		case all && k != "type", sensitive:
+			result[k] = initSummarySensitive
+		default:
+			// Every setting of the encryption block but its type, such as
+			// the key, is secret.
+			result[k] = redactBackendValue(v, k == backend.EncryptionConfigKey)
+		}
+	}
+
+	return result
+}
+
+// redactBackendValue returns a copy of v with the secrets in the maps that
+// it contains replaced, like redactBackendMap. Maps with a type, such as
+// the state_lock block, are the configuration of a backend of that type.
+func redactBackendValue(v interface{}, all bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		typ, _ := v["type"].(string)
+		return redactBackendMap(typ, v, all)
+	case []map[string]interface{}:
+		result := make([]map[string]interface{}, len(v))
+		for i, m := range v {
+			typ, _ := m["type"].(string)
+			result[i] = redactBackendMap(typ, m, all)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, e := range v {
+			result[i] = redactBackendValue(e, all)
+		}
+		return result
+	}
+
+	return v
+}
+
+// backendSensitiveKeys returns whether each setting in the schema of the
+// backends of the given type is a secret, or nil if they don't have one.
+func backendSensitiveKeys(typ string) map[string]bool {
+	f := backendinit.Backend(typ)
+	if f == nil {
+		return nil
+	}
+	if b, ok := f().(backend.Sensitive); ok {
+		return b.SensitiveKeys()
+	}
+
+	return nil
+}
+
+// redactBackendKey returns true if the value of the backend setting with
+// the given name looks like a secret.
+func redactBackendKey(k string) bool {
+	for _, w := range initSummarySecretWords {
+		if strings.Contains(strings.ToLower(k), w) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// summarizeModules sets the modules of the summary from the module tree,
+// sorted by address.
+func (c *InitCommand) summarizeModules(mod *module.Tree) {
+	storageDir := filepath.Join(c.DataDir(), "modules")
+
+	var modules []*initSummaryModule
+	var walk func(t *module.Tree, prefix string)
+	walk = func(t *module.Tree, prefix string) {
+		children := t.Children()
+		for _, m := range t.Config().Modules {
+			child, ok := children[m.Name]
+			if !ok {
+				continue
+			}
+
+			addr := prefix + "module." + m.Name
+			modules = append(modules, &initSummaryModule{
+				Address: addr,
+				Source:  m.Source,
+				Version: moduleSourceVersion(m.Source),
+				Commit:  gitCommit(child.Config().Dir, storageDir),
+				Dir:     child.Config().Dir,
+			})
+			walk(child, addr+".")
+		}
+	}
+	walk(mod, "")
+
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Address < modules[j].Address
+	})
+	c.summary.Modules = modules
+}
+
+// moduleSourceVersion returns the version that the module source asks for,
+// which is the "ref" argument of most sources, or the tag or digest of an
+// OCI artifact. It returns an empty string if there's none.
+func moduleSourceVersion(source string) string {
+	source, _ = getter.SourceDirSubdir(source)
+
+	if strings.HasPrefix(source, "oci://") {
+		repo := strings.TrimPrefix(source, "oci://")
+		if i := strings.Index(repo, "@"); i >= 0 {
+			return repo[i+1:]
+		}
+		if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+			return repo[i+1:]
+		}
+		return ""
+	}
+
+	i := strings.Index(source, "?")
+	if i < 0 {
+		return ""
+	}
+	q, err := url.ParseQuery(source[i+1:])
+	if err != nil {
+		return ""
+	}
+
+	return q.Get("ref")
+}
+
+// gitCommit returns the commit that is checked out in the Git repository
+// that dir is in, or an empty string if it isn't in one. Only the
+// directories up to the module storage directory are looked in, so that
+// the repository of a local module isn't mistaken for a download.
+func gitCommit(dir, storageDir string) string {
+	storageDir = filepath.Clean(storageDir)
+	for dir = filepath.Clean(dir); strings.HasPrefix(dir, storageDir+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		gitDir := filepath.Join(dir, ".git")
+		if _, err := os.Stat(gitDir); err != nil {
+			continue
+		}
+
+		head, err := ioutil.ReadFile(filepath.Join(gitDir, "HEAD"))
+		if err != nil {
+			return ""
+		}
+		ref := strings.TrimSpace(string(head))
+		if !strings.HasPrefix(ref, "ref: ") {
+			// The HEAD is detached, as it is for tags
+			return ref
+		}
+
+		return gitRef(gitDir, strings.TrimPrefix(ref, "ref: "))
+	}
+
+	return ""
+}
+
+// gitRef returns the commit of the ref in the Git directory, from its file
+// or the packed refs.
+func gitRef(gitDir, ref string) string {
+	if raw, err := ioutil.ReadFile(filepath.Join(gitDir, filepath.FromSlash(ref))); err == nil {
+		return strings.TrimSpace(string(raw))
+	}
+
+	f, err := os.Open(filepath.Join(gitDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == ref {
+			return fields[0]
+		}
+	}
+
+	return ""
+}
+
+// summarizeProvider adds a provider plugin that was chosen and locked to
+// the summary.
+func (c *InitCommand) summarizeProvider(
+	name string, meta discovery.PluginMeta, reqd *discovery.PluginConstraints, digest []byte) {
+	p := &initSummaryProvider{
+		Name:    name,
+		Version: string(meta.Version),
+		SHA256:  hex.EncodeToString(digest),
+	}
+	if reqd != nil {
+		p.Constraints = reqd.Versions.String()
+	}
+
+	c.summary.Providers = append(c.summary.Providers, p)
+	sort.Slice(c.summary.Providers, func(i, j int) bool {
+		return c.summary.Providers[i].Name < c.summary.Providers[j].Name
+	})
+}
+
+// summarizePlatformProvider adds the digest of the plugin of a provider for
+// another platform to the summary.
+func (c *InitCommand) summarizePlatformProvider(name string, platform discovery.Platform, digest []byte) {
+	for _, p := range c.summary.Providers {
+		if p.Name != name {
+			continue
+		}
+
+		if p.PlatformSHA256s == nil {
+			p.PlatformSHA256s = make(map[string]string)
+		}
+		p.PlatformSHA256s[platform.String()] = hex.EncodeToString(digest)
+	}
+}
+
+// writeJSON writes the summary as JSON to the given path.
+func (s *initSummary) writeJSON(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// String formats the summary for humans.
+func (s *initSummary) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("Initialization summary:\n")
+
+	if b := s.Backend; b != nil {
+		buf.WriteString(fmt.Sprintf("\nBackend: %s\n", b.Type))
+
+		keys := make([]string, 0, len(b.Config))
+		keyLen := 0
+		for k := range b.Config {
+			keys = append(keys, k)
+			if len(k) > keyLen {
+				keyLen = len(k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v := b.Config[k]
+			if v != initSummarySensitive {
+				v = fmt.Sprintf("%#v", v)
+			}
+			buf.WriteString(fmt.Sprintf(
+				"  %s:%s %s\n", k, strings.Repeat(" ", keyLen-len(k)), v))
+		}
+	}
+
+	if len(s.Workspaces) > 0 {
+		buf.WriteString(fmt.Sprintf("\nWorkspaces: %s\n", strings.Join(s.Workspaces, ", ")))
+	}
+
+	if len(s.Modules) > 0 {
+		buf.WriteString("\nModules:\n")
+		for _, m := range s.Modules {
+			var extra []string
+			if m.Version != "" {
+				extra = append(extra, "version "+m.Version)
+			}
+			if m.Commit != "" {
+				extra = append(extra, "commit "+m.Commit)
+			}
+
+			var extraStr string
+			if len(extra) > 0 {
+				extraStr = fmt.Sprintf(" (%s)", strings.Join(extra, ", "))
+			}
+			buf.WriteString(fmt.Sprintf("  - %s: %s%s\n", m.Address, m.Source, extraStr))
+		}
+	}
+
+	if len(s.Providers) > 0 {
+		buf.WriteString("\nProviders:\n")
+		for _, p := range s.Providers {
+			constraints := p.Constraints
+			if constraints == "" {
+				constraints = "any version"
+			}
+			buf.WriteString(fmt.Sprintf(
+				"  - %s %s, satisfying %s\n    sha256: %s\n",
+				p.Name, p.Version, constraints, p.SHA256))
+
+			platforms := make([]string, 0, len(p.PlatformSHA256s))
+			for platform := range p.PlatformSHA256s {
+				platforms = append(platforms, platform)
+			}
+			sort.Strings(platforms)
+			for _, platform := range platforms {
+				buf.WriteString(fmt.Sprintf(
+					"    sha256 (%s): %s\n", platform, p.PlatformSHA256s[platform]))
+			}
+		}
+	}
+
+	return strings.TrimSpace(buf.String())
+}
@@ -0,0 +1,362 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/terraform/helper/credentials"
+)
+
+// loginDiscoveryPath is the path of the document that describes how to log
+// in to a host.
+const loginDiscoveryPath = "/.well-known/terraform.json"
+
+// loginServiceKey is the key of the login service in the discovery
+// document.
+const loginServiceKey = "login.v1"
+
+// loginDeviceGrantType is the OAuth 2.0 grant type of the device
+// authorization flow, from RFC 8628.
+const loginDeviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// loginDefaultExpiry is how long a device code is valid for if the host
+// doesn't say.
+const loginDefaultExpiry = 15 * time.Minute
+
+// loginClient is the HTTP client that logins use, and loginInterval is how
+// long to wait between checking whether a device login has finished, if
+// the host doesn't say. They're variables so that tests can replace them.
+var (
+	loginClient   = cleanhttp.DefaultClient()
+	loginInterval = 5 * time.Second
+)
+
+// LoginCommand is a Command implementation that obtains an API token for a
+// host and saves it, so that it's used for the module registry, provider
+// downloads and backends on that host.
+type LoginCommand struct {
+	Meta
+}
+
+func (c *LoginCommand) Run(args []string) int {
+	var manual bool
+
+	args = c.Meta.process(args, false)
+
+	cmdFlags := c.Meta.flagSet("login")
+	cmdFlags.BoolVar(&manual, "manual", false, "manual")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = cmdFlags.Args()
+	if len(args) != 1 {
+		c.Ui.Error("The login command expects exactly one argument: the hostname to log in to.")
+		cmdFlags.Usage()
+		return 1
+	}
+
+	host := credentials.NormalizeHost(args[0])
+	if u, err := url.Parse("https://" + host); err != nil || u.Host != host || host == "" {
+		c.Ui.Error(fmt.Sprintf(
+			"Invalid hostname %q. The hostname to log in to is given without a\n"+
+				"scheme or path, such as \"app.example.com\".", args[0]))
+		return 1
+	}
+
+	store := c.credentials()
+	if store.IsStatic(host) {
+		c.Ui.Error(fmt.Sprintf(
+			"The token for %s is set by a credentials block in the CLI configuration,\n"+
+				"so a token saved by login wouldn't be used. Remove the block to log in.",
+			host))
+		return 1
+	}
+
+	if !c.input {
+		c.Ui.Error("Logging in needs input, so it can't be done with -input=false.")
+		return 1
+	}
+
+	var service *loginService
+	if !manual {
+		var err error
+		service, err = discoverLogin(host)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error finding how to log in to %s: %s", host, err))
+			return 1
+		}
+	}
+
+	var token string
+	var err error
+	if service != nil {
+		token, err = c.deviceLogin(host, service)
+	} else {
+		token, err = c.manualLogin(host)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error logging in to %s: %s", host, err))
+		return 1
+	}
+
+	if err := store.SetToken(host, token); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error saving the token for %s: %s", host, err))
+		return 1
+	}
+
+	c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
+		strings.TrimSpace(loginSuccess), host, store.Path)))
+	return 0
+}
+
+// loginService is the login service of a host, from its discovery
+// document.
+type loginService struct {
+	// Client is the OAuth client ID that Terraform logs in as.
+	Client string `json:"client"`
+
+	// Device and Token are the device authorization and token endpoints.
+	// They can be relative to the discovery document.
+	Device string `json:"device"`
+	Token  string `json:"token"`
+}
+
+// discoverLogin returns the login service of host, or nil if it doesn't
+// have one, so that the token has to be entered manually.
+func discoverLogin(host string) (*loginService, error) {
+	discoveryURL := &url.URL{Scheme: "https", Host: host, Path: loginDiscoveryPath}
+	resp, err := loginClient.Get(discoveryURL.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s responded with %s", discoveryURL, resp.Status)
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %s", discoveryURL, err)
+	}
+	raw, ok := doc[loginServiceKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var s loginService
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %s", discoveryURL, err)
+	}
+	if s.Client == "" || s.Device == "" || s.Token == "" {
+		return nil, fmt.Errorf(
+			"%s: %s must have a client, device and token", discoveryURL, loginServiceKey)
+	}
+
+	// The endpoints are resolved against the discovery document, and the
+	// token has to be sent back over HTTPS.
+	for _, endpoint := range []*string{&s.Device, &s.Token} {
+		u, err := discoveryURL.Parse(*endpoint)
+		if err != nil || u.Scheme != "https" {
+			return nil, fmt.Errorf("%s: invalid endpoint %q", discoveryURL, *endpoint)
+		}
+		*endpoint = u.String()
+	}
+
+	return &s, nil
+}
+
+// loginResponse is the response of the endpoints of the device
+// authorization flow, or the error they respond with.
+type loginResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+
+	AccessToken string `json:"access_token"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// deviceLogin obtains a token with the OAuth 2.0 device authorization flow:
+// the user approves the login in a browser, on any device, while we wait
+// for the token.
+func (c *LoginCommand) deviceLogin(host string, s *loginService) (string, error) {
+	device, err := loginPost(s.Device, url.Values{"client_id": {s.Client}})
+	if err != nil {
+		return "", err
+	}
+	if device.Error != "" {
+		return "", device.error()
+	}
+	if device.DeviceCode == "" || device.UserCode == "" || device.VerificationURI == "" {
+		return "", fmt.Errorf("%s responded without a device code", s.Device)
+	}
+
+	verificationURI := device.VerificationURI
+	if device.VerificationURIComplete != "" {
+		verificationURI = device.VerificationURIComplete
+	}
+	c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
+		strings.TrimSpace(loginDeviceInstructions), host, verificationURI, device.UserCode)))
+
+	interval := loginInterval
+	if device.Interval > 0 {
+		interval = time.Duration(device.Interval) * time.Second
+	}
+	expiry := loginDefaultExpiry
+	if device.ExpiresIn > 0 {
+		expiry = time.Duration(device.ExpiresIn) * time.Second
+	}
+
+	deadline := time.Now().Add(expiry)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		resp, err := loginPost(s.Token, url.Values{
+			"grant_type":  {loginDeviceGrantType},
+			"device_code": {device.DeviceCode},
+			"client_id":   {s.Client},
+		})
+		if err != nil {
+			return "", err
+		}
+
+		switch resp.Error {
+		case "":
+			if resp.AccessToken == "" {
+				return "", fmt.Errorf("%s responded without a token", s.Token)
+			}
+			return resp.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "access_denied":
+			return "", fmt.Errorf("the login was denied")
+		case "expired_token":
+			return "", fmt.Errorf("the code expired before the login was approved")
+		default:
+			return "", resp.error()
+		}
+	}
+
+	return "", fmt.Errorf("the code expired before the login was approved")
+}
+
+func (r *loginResponse) error() error {
+	if r.ErrorDescription != "" {
+		return fmt.Errorf("%s: %s", r.Error, r.ErrorDescription)
+	}
+
+	return fmt.Errorf("%s", r.Error)
+}
+
+// loginPost posts a form to an endpoint of the device authorization flow.
+// Errors are returned in the response, as long as they're well formed.
+func loginPost(endpoint string, form url.Values) (*loginResponse, error) {
+	resp, err := loginClient.PostForm(endpoint, form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var r loginResponse
+	if err := json.Unmarshal(body, &r); err != nil || (resp.StatusCode != http.StatusOK && r.Error == "") {
+		return nil, fmt.Errorf("%s responded with %s", endpoint, resp.Status)
+	}
+
+	return &r, nil
+}
+
+// manualLogin asks for a token that was created on the host.
+func (c *LoginCommand) manualLogin(host string) (string, error) {
+	c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
+		strings.TrimSpace(loginManualInstructions), host)))
+
+	token, err := c.Ui.AskSecret(fmt.Sprintf("Token for %s:", host))
+	if err != nil {
+		return "", err
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("no token was entered")
+	}
+
+	return token, nil
+}
+
+func (c *LoginCommand) Help() string {
+	helpText := `
+Usage: terraform login [options] HOSTNAME
+
+  Obtains an API token for the given host and saves it in the credentials
+  file in the CLI configuration directory.
+
+  The token is then sent, as a bearer token, with every HTTPS request to
+  the host: publishing and downloading modules, downloading providers, and
+  reading and writing states with the atlas and http backends.
+
+  If the host describes a login service in /.well-known/terraform.json,
+  the login is approved in a browser, on any device. Otherwise, a token
+  created on the host is entered at a prompt.
+
+Options:
+
+  -manual             Enter a token at a prompt, even if the host has a
+                      login service.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *LoginCommand) Synopsis() string {
+	return "Obtain and save an API token for a host"
+}
+
+const loginDeviceInstructions = `
+[reset][bold]Terraform will log in to %s.[reset]
+
+Open this URL in a browser, on any device, and approve the login:
+
+  %s
+
+Confirm that it shows this code: [bold]%s[reset]
+
+Waiting for the login to be approved...
+`
+
+const loginManualInstructions = `
+[reset][bold]Terraform will save a token for %s.[reset]
+
+Create an API token in the settings of your account on the host, and enter
+it below. The token isn't shown as it's entered.
+`
+
+const loginSuccess = `
+[reset][bold][green]Logged in to %s.[reset]
+
+The token was saved in %s, and will be used for requests
+to the host.
+`
@@ -0,0 +1,288 @@
+package command
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/credentials"
+	"github.com/mitchellh/cli"
+)
+
+// loginServer is a host with a login service, which approves the login,
+// or denies it if deny is set, the second time the token is asked for.
+type loginServer struct {
+	*httptest.Server
+
+	deny bool
+
+	lock  sync.Mutex
+	polls int
+	form  map[string]string
+}
+
+func newLoginServer(t *testing.T) *loginServer {
+	s := new(loginServer)
+	s.form = make(map[string]string)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"login.v1": map[string]string{
+				"client": "terraform-cli",
+				"device": "/oauth/device",
+				"token":  "/oauth/token",
+			},
+		})
+	})
+	mux.HandleFunc("/oauth/device", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("client_id") != "terraform-cli" {
+			t.Errorf("bad client: %q", r.PostFormValue("client_id"))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": s.URL + "/device",
+		})
+	})
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.polls++
+		for _, k := range []string{"grant_type", "device_code", "client_id"} {
+			s.form[k] = r.PostFormValue(k)
+		}
+
+		if s.polls < 2 || s.deny {
+			err := "authorization_pending"
+			if s.deny && s.polls >= 2 {
+				err = "access_denied"
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "device-token",
+			"token_type":   "bearer",
+		})
+	})
+
+	s.Server = httptest.NewTLSServer(mux)
+	return s
+}
+
+// testTLSClient returns a client that trusts the certificate of the TLS
+// server s, like s.Client does in newer versions of Go.
+func testTLSClient(s *httptest.Server) *http.Client {
+	certs := x509.NewCertPool()
+	for _, c := range s.TLS.Certificates {
+		for _, der := range c.Certificate {
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				panic(err)
+			}
+			certs.AddCert(cert)
+		}
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: certs},
+		},
+	}
+}
+
+// testLogin sets up logins to the TLS server s, and returns a function to
+// undo it.
+func testLogin(s *httptest.Server) func() {
+	client, interval := loginClient, loginInterval
+	loginClient = testTLSClient(s)
+	loginInterval = time.Millisecond
+	return func() {
+		loginClient, loginInterval = client, interval
+	}
+}
+
+func TestLogin_device(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+
+	server := newLoginServer(t)
+	defer server.Close()
+	defer testLogin(server.Server)()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	store := &credentials.Store{Path: filepath.Join(td, credentials.FileName)}
+	ui := new(cli.MockUi)
+	c := &LoginCommand{
+		Meta: Meta{
+			Ui:          ui,
+			Credentials: store,
+		},
+	}
+
+	if code := c.Run([]string{host}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, server.URL+"/device") || !strings.Contains(output, "ABCD-EFGH") {
+		t.Fatalf("bad: %s", output)
+	}
+
+	if server.polls != 2 {
+		t.Fatalf("bad: %d polls", server.polls)
+	}
+	if server.form["grant_type"] != loginDeviceGrantType || server.form["device_code"] != "device-code" {
+		t.Fatalf("bad: %#v", server.form)
+	}
+
+	// The token is saved, so a new store reads it
+	token, err := (&credentials.Store{Path: store.Path}).Token(host)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if token != "device-token" {
+		t.Fatalf("bad: %q", token)
+	}
+}
+
+func TestLogin_manual(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+
+	// A host without a login service
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	defer testLogin(server)()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	store := &credentials.Store{Path: filepath.Join(td, credentials.FileName)}
+	ui := new(cli.MockUi)
+	ui.InputReader = strings.NewReader("manual-token\n")
+	c := &LoginCommand{
+		Meta: Meta{
+			Ui:          ui,
+			Credentials: store,
+		},
+	}
+
+	if code := c.Run([]string{host}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	token, err := store.Token(host)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if token != "manual-token" {
+		t.Fatalf("bad: %q", token)
+	}
+}
+
+func TestLogin_manualFlag(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+
+	server := newLoginServer(t)
+	defer server.Close()
+	defer testLogin(server.Server)()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	store := &credentials.Store{Path: filepath.Join(td, credentials.FileName)}
+	ui := new(cli.MockUi)
+	ui.InputReader = strings.NewReader("manual-token\n")
+	c := &LoginCommand{
+		Meta: Meta{
+			Ui:          ui,
+			Credentials: store,
+		},
+	}
+
+	if code := c.Run([]string{"-manual", host}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if server.polls != 0 {
+		t.Fatal("the login service shouldn't be used")
+	}
+
+	token, err := store.Token(host)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if token != "manual-token" {
+		t.Fatalf("bad: %q", token)
+	}
+}
+
+func TestLogin_denied(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+
+	server := newLoginServer(t)
+	defer server.Close()
+	defer testLogin(server.Server)()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	// Deny the login instead of approving it
+	server.deny = true
+
+	store := &credentials.Store{Path: filepath.Join(td, credentials.FileName)}
+	ui := new(cli.MockUi)
+	c := &LoginCommand{
+		Meta: Meta{
+			Ui:          ui,
+			Credentials: store,
+		},
+	}
+
+	if code := c.Run([]string{host}); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if output := ui.ErrorWriter.String(); !strings.Contains(output, "denied") {
+		t.Fatalf("bad: %s", output)
+	}
+	if token, _ := store.Token(host); token != "" {
+		t.Fatalf("bad: %q", token)
+	}
+}
+
+func TestLogin_invalid(t *testing.T) {
+	store := &credentials.Store{Static: map[string]string{"app.example.com": "foo"}}
+
+	cases := map[string][]string{
+		"no host":      {},
+		"two hosts":    {"a.example.com", "b.example.com"},
+		"scheme":       {"https://app.example.com"},
+		"path":         {"app.example.com/foo"},
+		"static token": {"app.example.com"},
+		"no input":     {"-input=false", "b.example.com"},
+	}
+	for name, args := range cases {
+		ui := new(cli.MockUi)
+		c := &LoginCommand{
+			Meta: Meta{
+				Ui:          ui,
+				Credentials: store,
+			},
+		}
+
+		if code := c.Run(args); code != 1 {
+			t.Fatalf("%s: bad: %d\n\n%s", name, code, ui.OutputWriter.String())
+		}
+	}
+}
@@ -3,7 +3,6 @@ package command
 import (
 	"bufio"
 	"bytes"
-	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -16,10 +15,14 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-getter"
+	plugin "github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/backend/local"
+	"github.com/hashicorp/terraform/helper/credentials"
 	"github.com/hashicorp/terraform/helper/experiment"
 	"github.com/hashicorp/terraform/helper/variables"
+	"github.com/hashicorp/terraform/helper/vault"
+	"github.com/hashicorp/terraform/helper/warnings"
 	"github.com/hashicorp/terraform/helper/wrappedstreams"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
@@ -40,6 +43,18 @@ type Meta struct {
 	// ExtraHooks are extra hooks to add to the context.
 	ExtraHooks []terraform.Hook
 
+	// Warnings collects the warnings shown during the run, so that they
+	// can be summarized at the end. If it is nil, a new one is created.
+	Warnings *warnings.Collector
+
+	// Credentials are the API tokens for services, which "terraform login"
+	// saves tokens to. If it is nil, credentials.Default is used.
+	Credentials *credentials.Store
+
+	// UIOptions are the options for rendering output, from the CLI
+	// configuration. If it is nil, the defaults are used.
+	UIOptions *UIOptions
+
 	//----------------------------------------------------------
 	// Protected: commands can set these
 	//----------------------------------------------------------
@@ -47,6 +62,12 @@ type Meta struct {
 	// Modify the data directory location. Defaults to DefaultDataDir
 	dataDir string
 
+	// strictInput makes every attempt to ask for input fail when input
+	// is disabled, rather than only the attempts that check for it. This
+	// is for commands, such as init, that must never wait at a prompt
+	// when they're run in automation.
+	strictInput bool
+
 	// Override certain behavior for tests within this package
 	testingOverrides *testingOverrides
 
@@ -57,6 +78,14 @@ type Meta struct {
 	// backendState is the currently active backend state
 	backendState *terraform.BackendState
 
+	// providerDev are the providers that -provider-dev replaces, by name,
+	// with the path of their executable or ProviderDevInProcess.
+	providerDev map[string]string
+
+	// providerAttach are the running providers that Terraform connects to
+	// instead of starting, from TF_REATTACH_PROVIDERS, by name.
+	providerAttach map[string]*plugin.ReattachConfig
+
 	// Variables for the context (private)
 	autoKey       string
 	autoVariables map[string]interface{}
@@ -66,9 +95,29 @@ type Meta struct {
 	// Targets for this context (private)
 	targets []string
 
+	// Extra directories to search for plugins, from -plugin-dir (private)
+	pluginDirFlags []string
+
+	// Resources to force replacement of for this context (private)
+	forceReplace []string
+
 	// Internal fields
-	color bool
-	oldUi cli.Ui
+	color    bool
+	colors   map[string]string
+	oldUi    cli.Ui
+	readOnly bool
+
+	// refreshRemoteState makes the terraform_remote_state data sources skip
+	// their cache, from the -refresh-remote-state flag.
+	refreshRemoteState bool
+
+	// quiet disables the progress output from operations, for commands
+	// whose output must be machine readable.
+	quiet bool
+
+	// strictWarnings are the codes of the warnings that are errors, from
+	// the -strict flag. See processStrict.
+	strictWarnings []string
 
 	// The fields below are expected to be set by the command via
 	// command line flags. See the Apply command for an example.
@@ -88,6 +137,15 @@ type Meta struct {
 	// parallelism is used to control the number of concurrent operations
 	// allowed when walking the graph
 	//
+	// parallelismAuto tunes parallelism while operations run. It is set
+	// by parallelismFlag.
+	//
+	// refreshParallelism is used to control the number of concurrent
+	// refreshes, and defaults to a multiple of parallelism
+	//
+	// providerRetry limits the retries of provider operations that fail
+	// with transient errors. It is set by providerRetryFlags.
+	//
 	// shadow is used to enable/disable the shadow graph
 	//
 	// provider is to specify specific resource providers
@@ -97,25 +155,41 @@ type Meta struct {
 	// stateLockTimeout is the optional duration to retry a state locks locks
 	// when it is already locked by another process.
 	//
+	// stateLockSubtree locks only the modules of the targets, for backends
+	// that support it.
+	//
 	// forceInitCopy suppresses confirmation for copying state data during
 	// init.
 	//
 	// reconfigure forces init to ignore any stored configuration.
-	statePath        string
-	stateOutPath     string
-	backupPath       string
-	parallelism      int
-	shadow           bool
-	provider         string
-	stateLock        bool
-	stateLockTimeout time.Duration
-	forceInitCopy    bool
-	reconfigure      bool
+	//
+	// backendEnv, if set, is the workspace to configure the backend for
+	// instead of the current one, for the commands that switch to another
+	// workspace.
+	statePath          string
+	stateOutPath       string
+	backupPath         string
+	parallelism        int
+	parallelismAuto    bool
+	refreshParallelism int
+	providerRetry      *terraform.RetryPolicy
+	shadow             bool
+	provider           string
+	stateLock          bool
+	stateLockTimeout   time.Duration
+	stateLockSubtree   bool
+	forceInitCopy      bool
+	reconfigure        bool
+	backendEnv         string
 }
 
 type PluginOverrides struct {
 	Providers    map[string]string
 	Provisioners map[string]string
+
+	// SearchDirs, if set, replace the default directories that are
+	// searched for plugins.
+	SearchDirs []string
 }
 
 type testingOverrides struct {
@@ -144,13 +218,27 @@ func (m *Meta) StateOutPath() string {
 
 // Colorize returns the colorization structure for a command.
 func (m *Meta) Colorize() *colorstring.Colorize {
+	colors := m.colors
+	if colors == nil {
+		colors = colorstring.DefaultColors
+	}
+
 	return &colorstring.Colorize{
-		Colors:  colorstring.DefaultColors,
+		Colors:  colors,
 		Disable: !m.color,
 		Reset:   true,
 	}
 }
 
+// credentials returns the store of API tokens.
+func (m *Meta) credentials() *credentials.Store {
+	if m.Credentials == nil {
+		return credentials.Default
+	}
+
+	return m.Credentials
+}
+
 // DataDir returns the directory where local data will be stored.
 func (m *Meta) DataDir() string {
 	dataDir := DefaultDataDir
@@ -166,6 +254,38 @@ const (
 	// "0", causes terraform commands to behave as if the `-input=false` flag was
 	// specified.
 	InputModeEnvVar = "TF_INPUT"
+
+	// ReadOnlyEnvVar is the environment variable that, if set to "true" or
+	// "1", causes terraform commands to behave as if the `-read-only` flag
+	// was specified.
+	ReadOnlyEnvVar = "TF_READ_ONLY"
+
+	// WorkspaceEnvVar is the environment variable that, if set, is the name
+	// of the environment to use instead of the selected one. It lets
+	// commands run in several environments at the same time in the same
+	// working directory.
+	WorkspaceEnvVar = "TF_WORKSPACE"
+
+	// RemoteStateRefreshEnvVar is the environment variable that, if set to
+	// "true" or "1", is the default of the -refresh-remote-state flag.
+	RemoteStateRefreshEnvVar = "TF_REMOTE_STATE_REFRESH"
+
+	// UIThemeEnvVar is the environment variable that, if set, is the name
+	// of the color theme to use instead of the configured one.
+	UIThemeEnvVar = "TF_UI_THEME"
+
+	// UIASCIIEnvVar is the environment variable that, if set to "true" or
+	// "1", replaces the symbols of messages with ASCII ones.
+	UIASCIIEnvVar = "TF_UI_ASCII"
+
+	// UIWidthEnvVar is the environment variable that, if set, is the width
+	// to wrap error and warning messages to instead of the configured one.
+	UIWidthEnvVar = "TF_UI_WIDTH"
+
+	// RunIDEnvVar is the environment variable that, if set, identifies the
+	// run in the lifecycles of the resources it changes, such as the ID of
+	// a CI job. Otherwise each run has a random ID.
+	RunIDEnvVar = "TF_RUN_ID"
 )
 
 // InputMode returns the type of input we should ask for in the form of
@@ -191,10 +311,23 @@ func (m *Meta) InputMode() terraform.InputMode {
 	return mode
 }
 
+// checkReadOnly should be called by commands that write state before they
+// do anything. If read-only mode is enabled, it outputs an error naming the
+// command and returns true, in which case the command must exit.
+func (m *Meta) checkReadOnly(name string) bool {
+	if !m.readOnly {
+		return false
+	}
+
+	m.Ui.Error(fmt.Sprintf(strings.TrimSpace(errReadOnly), name))
+	return true
+}
+
 // UIInput returns a UIInput object to be used for asking for input.
 func (m *Meta) UIInput() terraform.UIInput {
 	return &UIInput{
 		Colorize: m.Colorize(),
+		Disabled: m.strictInput && !m.input,
 	}
 }
 
@@ -213,7 +346,10 @@ func (m *Meta) StdinPiped() bool {
 // context with the settings from this Meta.
 func (m *Meta) contextOpts() *terraform.ContextOpts {
 	var opts terraform.ContextOpts
-	opts.Hooks = []terraform.Hook{m.uiHook(), &terraform.DebugHook{}}
+	if !m.quiet {
+		opts.Hooks = append(opts.Hooks, m.uiHook())
+	}
+	opts.Hooks = append(opts.Hooks, &terraform.DebugHook{})
 	opts.Hooks = append(opts.Hooks, m.ExtraHooks...)
 
 	vs := make(map[string]interface{})
@@ -229,8 +365,13 @@ func (m *Meta) contextOpts() *terraform.ContextOpts {
 	opts.Variables = vs
 
 	opts.Targets = m.targets
+	opts.ForceReplace = m.forceReplace
 	opts.UIInput = m.UIInput()
 	opts.Parallelism = m.parallelism
+	opts.ParallelismAuto = m.parallelismAuto
+	opts.StrictWarnings = m.strictWarnings
+	opts.RefreshParallelism = m.refreshParallelism
+	opts.ProviderRetry = m.providerRetry
 	opts.Shadow = m.shadow
 
 	// If testingOverrides are set, we'll skip the plugin discovery process
@@ -246,13 +387,60 @@ func (m *Meta) contextOpts() *terraform.ContextOpts {
 
 	opts.ProviderSHA256s = m.providerPluginsLock().Read()
 
+	// Providers are configured with the credentials read from Vault for
+	// their type, and configured again when they're renewed.
+	if vault.Default != nil {
+		opts.ProviderCredentials = vault.Default
+	}
+
+	// The current workspace is passed to the built-in terraform provider
+	// in its configuration, since a provider that's already running, such
+	// as one served with plugin-serve, can't see it in its environment.
 	opts.Meta = &terraform.ContextMeta{
-		Env: m.Env(),
+		Env:   m.Env(),
+		RunID: os.Getenv(RunIDEnvVar),
+		ProviderDefaults: map[string]map[string]string{
+			"terraform": {
+				"workspace":     m.Env(),
+				"cache_dir":     filepath.Join(m.DataDir(), "remote-state-cache"),
+				"refresh_cache": strconv.FormatBool(m.refreshRemoteState),
+			},
+		},
 	}
 
 	return &opts
 }
 
+// parallelismFlag adds the -parallelism flag, which is either a limit or
+// "auto", to f.
+func (m *Meta) parallelismFlag(f *flag.FlagSet, def int) {
+	m.parallelism = def
+	f.Var(FlagParallelism{N: &m.parallelism, Auto: &m.parallelismAuto},
+		"parallelism", "parallelism")
+}
+
+// providerRetryFlags adds the flags that limit the retries of provider
+// operations that fail with transient errors to f.
+func (m *Meta) providerRetryFlags(f *flag.FlagSet) {
+	m.providerRetry = terraform.DefaultRetryPolicy()
+	f.IntVar(&m.providerRetry.MaxRetries, "provider-retries",
+		m.providerRetry.MaxRetries, "provider-retries")
+	f.DurationVar(&m.providerRetry.MaxWait, "provider-retry-max-wait",
+		m.providerRetry.MaxWait, "provider-retry-max-wait")
+}
+
+// refreshRemoteStateFlag adds the -refresh-remote-state flag, which makes
+// the terraform_remote_state data sources skip their cache, to f. It's only
+// added to the commands that read data sources.
+func (m *Meta) refreshRemoteStateFlag(f *flag.FlagSet) {
+	m.refreshRemoteState = false
+	if v, err := strconv.ParseBool(os.Getenv(RemoteStateRefreshEnvVar)); err == nil {
+		m.refreshRemoteState = v
+	}
+	f.BoolVar(&m.refreshRemoteState, "refresh-remote-state",
+		m.refreshRemoteState, "refresh-remote-state")
+}
+
 // flags adds the meta flags to the given FlagSet.
 func (m *Meta) flagSet(n string) *flag.FlagSet {
 	f := flag.NewFlagSet(n, flag.ContinueOnError)
@@ -260,6 +448,7 @@ func (m *Meta) flagSet(n string) *flag.FlagSet {
 	f.Var((*variables.Flag)(&m.variables), "var", "variables")
 	f.Var((*variables.FlagFile)(&m.variables), "var-file", "variable file")
 	f.Var((*FlagStringSlice)(&m.targets), "target", "resource to target")
+	f.Var((*FlagStringSlice)(&m.pluginDirFlags), "plugin-dir", "plugin directory")
 
 	if m.autoKey != "" {
 		f.Var((*variables.FlagFile)(&m.autoVariables), m.autoKey, "variable file")
@@ -328,16 +517,49 @@ func (m *Meta) process(args []string, vars bool) []string {
 		}
 	}
 
-	// Set the UI
+	// Set read-only mode, from either the flag or the environment
+	m.readOnly = false
+	if envVar := os.Getenv(ReadOnlyEnvVar); envVar != "" {
+		if v, err := strconv.ParseBool(envVar); err == nil {
+			m.readOnly = v
+		}
+	}
+	args, readOnly, set, err := extractBoolFlag(args, "-read-only")
+	switch {
+	case err != nil:
+		// Fail safe: a mistyped value doesn't allow writing state
+		m.Ui.Error(fmt.Sprintf("Invalid -read-only: %s. Read-only mode is enabled.", err))
+		m.readOnly = true
+	case set:
+		m.readOnly = readOnly
+	}
+
+	// Set the UI, rendered as the CLI configuration and the environment
+	// ask for
+	uiOpts := m.uiOptions()
+	m.colors = uiOpts.colors()
 	m.oldUi = m.Ui
-	m.Ui = &cli.ConcurrentUi{
-		Ui: &ColorizeUi{
-			Colorize:   m.Colorize(),
-			ErrorColor: "[red]",
-			WarnColor:  "[yellow]",
-			Ui:         m.oldUi,
-		},
+	var ui cli.Ui = &ColorizeUi{
+		Colorize:   m.Colorize(),
+		ErrorColor: "[red]",
+		WarnColor:  "[yellow]",
+		Ui:         m.oldUi,
+	}
+	if width := uiOpts.wrapWidth(); uiOpts.ASCII || width > 0 {
+		ui = &RenderUi{
+			ASCII:  uiOpts.ASCII,
+			Width:  width,
+			Colors: m.colors,
+			Ui:     ui,
+		}
 	}
+	m.Ui = &cli.ConcurrentUi{Ui: ui}
+
+	// Suppress the warnings that were asked to be suppressed
+	args = m.processSuppressWarnings(args)
+	args = m.processStrict(args)
+	args = m.processProviderDev(args)
+	m.processProviderAttach()
 
 	// If we support vars and the default var file exists, add it to
 	// the args...
@@ -358,6 +580,10 @@ func (m *Meta) process(args []string, vars bool) []string {
 			args[0] = "-" + m.autoKey
 			args[1] = DefaultVarsFilename + ".json"
 		}
+
+		// Layered variables are loaded ahead of the other arguments, so
+		// that -var and -var-file override them.
+		args = m.processVarLayers(args)
 	}
 
 	return args
@@ -374,7 +600,7 @@ func (m *Meta) uiHook() *UiHook {
 // confirm asks a yes/no confirmation.
 func (m *Meta) confirm(opts *terraform.InputOpts) (bool, error) {
 	if !m.input {
-		return false, errors.New("input disabled")
+		return false, &inputRequiredError{Id: opts.Id, Query: opts.Query}
 	}
 	for {
 		v, err := m.UIInput().Input(opts)
@@ -392,6 +618,20 @@ func (m *Meta) confirm(opts *terraform.InputOpts) (bool, error) {
 	}
 }
 
+// inputRequiredError is the error returned when a question needs to be
+// answered to continue, but input is disabled. The ID of the question is
+// part of the message so that automation can tell which question it was.
+type inputRequiredError struct {
+	Id    string
+	Query string
+}
+
+func (e *inputRequiredError) Error() string {
+	return fmt.Sprintf(
+		"input required [%s]: %s (input is disabled with -input=false)",
+		e.Id, e.Query)
+}
+
 const (
 	// ModuleDepthDefault is the default value for
 	// module depth, which can be overridden by flag
@@ -457,6 +697,10 @@ func (m *Meta) outputShadowError(err error, output bool) bool {
 // Env returns the name of the currently configured environment, corresponding
 // to the desired named state.
 func (m *Meta) Env() string {
+	if v := os.Getenv(WorkspaceEnvVar); v != "" {
+		return v
+	}
+
 	dataDir := m.dataDir
 	if m.dataDir == "" {
 		dataDir = DefaultDataDir
@@ -476,6 +720,16 @@ func (m *Meta) Env() string {
 	return current
 }
 
+// backendWorkspace returns the workspace that "${terraform.workspace}" in
+// the backend configuration is replaced with.
+func (m *Meta) backendWorkspace() string {
+	if m.backendEnv != "" {
+		return m.backendEnv
+	}
+
+	return m.Env()
+}
+
 // SetEnv saves the named environment to the local filesystem.
 func (m *Meta) SetEnv(name string) error {
 	dataDir := m.dataDir
@@ -494,3 +748,12 @@ func (m *Meta) SetEnv(name string) error {
 	}
 	return nil
 }
+
+const errReadOnly = `
+Terraform is running in read-only mode, so %q is not allowed.
+
+Read-only mode was enabled by the -read-only flag or the TF_READ_ONLY
+environment variable. Commands that modify state are disabled in this mode,
+while commands that only read state, such as "plan", "show", "output" and
+"state list", continue to work.
+`
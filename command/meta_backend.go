@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -17,6 +18,8 @@ import (
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/command/clistate"
 	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/vault"
+	"github.com/hashicorp/terraform/helper/warnings"
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/mapstructure"
@@ -74,6 +77,12 @@ func (m *Meta) Backend(opts *BackendOpts) (backend.Enhanced, error) {
 		opts = &BackendOpts{}
 	}
 
+	// The environment from the environment variable is checked like the
+	// names given to "env new", since it's used as a path by backends.
+	if v := os.Getenv(WorkspaceEnvVar); v != "" && !validEnvName(v) {
+		return nil, fmt.Errorf(strings.TrimSpace(errBackendWorkspaceEnvVar), WorkspaceEnvVar, v)
+	}
+
 	// Initialize a backend from the config unless we're forcing a purely
 	// local operation.
 	var b backend.Backend
@@ -103,6 +112,8 @@ func (m *Meta) Backend(opts *BackendOpts) (backend.Enhanced, error) {
 		StateBackupPath: m.backupPath,
 		ContextOpts:     m.contextOpts(),
 		Input:           m.Input(),
+		Warnings:        m.Warnings,
+		ReadOnly:        m.readOnly,
 	}
 
 	// Don't validate if we have a plan.  Validation is normally harmless here,
@@ -167,18 +178,21 @@ func (m *Meta) Operation() *backend.Operation {
 	return &backend.Operation{
 		PlanOutBackend:   m.backendState,
 		Targets:          m.targets,
+		ForceReplace:     m.forceReplace,
 		UIIn:             m.UIInput(),
 		Environment:      m.Env(),
 		LockState:        m.stateLock,
 		StateLockTimeout: m.stateLockTimeout,
+		LockSubtree:      m.stateLockSubtree,
 	}
 }
 
 // backendConfig returns the local configuration for the backend
 func (m *Meta) backendConfig(opts *BackendOpts) (*config.Backend, error) {
 	if opts.Config == nil {
-		// check if the config was missing, or just not required
-		conf, err := m.Config(".")
+		// check if the config was missing, or just not required. Only the
+		// terraform block is needed, so the rest isn't loaded.
+		conf, err := m.ConfigTerraform(".")
 		if err != nil {
 			return nil, err
 		}
@@ -242,6 +256,12 @@ func (m *Meta) backendConfig(opts *BackendOpts) (*config.Backend, error) {
 		return nil, multierror.Append(nil, errs...)
 	}
 
+	// Fill in any path variables, which are the only interpolations that
+	// are allowed.
+	if err := backend.Interpolate(c.Dir); err != nil {
+		return nil, fmt.Errorf("Error interpolating backend config: %s", err)
+	}
+
 	// Return the configuration which may or may not be set
 	return backend, nil
 }
@@ -744,7 +764,10 @@ func (m *Meta) backend_c_R_s(
 	s := sMgr.State()
 
 	// Warn the user
-	m.Ui.Warn(strings.TrimSpace(warnBackendLegacy) + "\n")
+	m.Warnings.Warn(m.Ui, warnings.Warning{
+		Code:    warnings.CodeBackendLegacy,
+		Message: strings.TrimSpace(warnBackendLegacy),
+	})
 
 	// We need to convert the config to map[string]interface{} since that
 	// is what the backends expect.
@@ -1173,25 +1196,22 @@ func (m *Meta) backend_C_r_S_unchanged(
 	// Create the config. We do this from the backend state since this
 	// has the complete configuration data whereas the config itself
 	// may require input.
-	rawC, err := config.NewRawConfig(s.Backend.Config)
+	config, wrap, err := backendSplitConfig(s.Backend.Config, m.backendWorkspace())
 	if err != nil {
 		return nil, fmt.Errorf("Error configuring backend: %s", err)
 	}
-	config := terraform.NewResourceConfig(rawC)
 
 	// Get the backend
 	f := backendinit.Backend(s.Backend.Type)
 	if f == nil {
 		return nil, fmt.Errorf(strings.TrimSpace(errBackendSavedUnknown), s.Backend.Type)
 	}
-	b := f()
-
-	// Configure
-	if err := b.Configure(config); err != nil {
+	b, err := m.backendConfigure(f(), config)
+	if err != nil {
 		return nil, fmt.Errorf(errBackendSavedConfig, s.Backend.Type, err)
 	}
 
-	return b, nil
+	return m.backendWrap(b, wrap)
 }
 
 // Initiailizing a changed saved backend with legacy remote state.
@@ -1318,7 +1338,10 @@ func (m *Meta) backend_C_R_S_unchanged(
 
 func (m *Meta) backendInitFromConfig(c *config.Backend) (backend.Backend, error) {
 	// Create the config.
-	config := terraform.NewResourceConfig(c.RawConfig)
+	config, wrap, err := backendSplitConfig(c.RawConfig.Raw, m.backendWorkspace())
+	if err != nil {
+		return nil, fmt.Errorf("Error configuring the backend %q: %s", c.Type, err)
+	}
 
 	// Get the backend
 	f := backendinit.Backend(c.Type)
@@ -1346,12 +1369,19 @@ func (m *Meta) backendInitFromConfig(c *config.Backend) (backend.Backend, error)
 			"Error configuring the backend %q: %s",
 			c.Type, multierror.Append(nil, errs...))
 	}
-	if len(warns) > 0 {
-		// TODO: warnings are currently ignored
+	for _, w := range warns {
+		m.Warnings.Warn(m.Ui, warnings.New(
+			warnings.CodeBackendConfig, "backend %q: %s", c.Type, w))
 	}
 
 	// Configure
-	if err := b.Configure(config); err != nil {
+	b, err = m.backendConfigure(b, config)
+	if err != nil {
+		return nil, fmt.Errorf(errBackendNewConfig, c.Type, err)
+	}
+
+	b, err = m.backendWrap(b, wrap)
+	if err != nil {
 		return nil, fmt.Errorf(errBackendNewConfig, c.Type, err)
 	}
 
@@ -1392,22 +1422,176 @@ func (m *Meta) backendInitFromSaved(s *terraform.BackendState) (backend.Backend,
 	// Create the config. We do this from the backend state since this
 	// has the complete configuration data whereas the config itself
 	// may require input.
-	rawC, err := config.NewRawConfig(s.Config)
+	config, wrap, err := backendSplitConfig(s.Config, m.backendWorkspace())
 	if err != nil {
 		return nil, fmt.Errorf("Error configuring backend: %s", err)
 	}
-	config := terraform.NewResourceConfig(rawC)
 
 	// Get the backend
 	f := backendinit.Backend(s.Type)
 	if f == nil {
 		return nil, fmt.Errorf(strings.TrimSpace(errBackendSavedUnknown), s.Type)
 	}
-	b := f()
+	b, err := m.backendConfigure(f(), config)
+	if err != nil {
+		return nil, fmt.Errorf(errBackendSavedConfig, s.Type, err)
+	}
+
+	return m.backendWrap(b, wrap)
+}
+
+// backendConfigure configures b with config. If backend credentials are
+// read from Vault, b is configured with them too, and configured again
+// whenever they're read again, so that a backend that's in use doesn't
+// keep expired credentials.
+func (m *Meta) backendConfigure(b backend.Backend, config *terraform.ResourceConfig) (backend.Backend, error) {
+	if vault.Default != nil {
+		creds, _, err := vault.Default.BackendCredentials()
+		if err != nil {
+			return nil, err
+		}
+		if len(creds) > 0 {
+			if b, err = backend.WithCredentials(b, vault.Default); err != nil {
+				return nil, err
+			}
+		}
+	}
 
-	// Configure
 	if err := b.Configure(config); err != nil {
-		return nil, fmt.Errorf(errBackendSavedConfig, s.Type, err)
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// backendWrappers is the configuration of the blocks that are handled for
+// every backend by wrapping it, rather than by the backend itself: the
+// encryption, compression, blobs, lock and notify blocks.
+type backendWrappers struct {
+	Encryption  *backend.EncryptionConfig
+	Compression *backend.CompressionConfig
+	Blobs       *backend.BlobsConfig
+	Lock        *backend.LockConfig
+	Notify      []*backend.NotifyConfig
+}
+
+// backendSplitConfig returns the configuration to pass to a backend from
+// its raw configuration for the given workspace, without the blocks that
+// are handled for every backend, along with the configuration of those
+// blocks.
+func backendSplitConfig(
+	raw map[string]interface{}, workspace string) (*terraform.ResourceConfig, *backendWrappers, error) {
+	raw, err := config.BackendWorkspaceConfig(raw, workspace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var wrap backendWrappers
+	rest, enc, err := backend.SplitEncryptionConfig(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrap.Encryption = enc
+
+	rest, comp, err := backend.SplitCompressionConfig(rest)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrap.Compression = comp
+
+	rest, blobs, err := backend.SplitBlobsConfig(rest)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrap.Blobs = blobs
+
+	rest, lock, err := backend.SplitLockConfig(rest)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrap.Lock = lock
+
+	rest, notify, err := backend.SplitNotifyConfig(rest)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrap.Notify = notify
+
+	rc, err := config.NewRawConfig(rest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return terraform.NewResourceConfig(rc), &wrap, nil
+}
+
+// backendWrap returns b, wrapped as configured by the blocks that are
+// handled for every backend: to encrypt and compress its states, to store
+// their large values as blobs, to lock them with a separate backend, and to
+// send notifications when they change. In read-only mode, writing its
+// states fails.
+func (m *Meta) backendWrap(b backend.Backend, wrap *backendWrappers) (backend.Backend, error) {
+	b, err := backend.WrapStorage(b, wrap.Encryption, wrap.Compression, wrap.Blobs)
+	if err != nil {
+		return nil, err
+	}
+
+	if wrap.Lock != nil {
+		locker, err := m.backendInitLocker(wrap.Lock)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err = backend.ExternallyLocked(b, locker)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Notifications are sent last, so that they're only sent once the
+	// state is stored and locked.
+	if len(wrap.Notify) > 0 {
+		b, err = backend.Notifying(b, wrap.Notify)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if m.readOnly {
+		// The local backend writes its states itself.
+		if local, ok := b.(*backendlocal.Local); ok {
+			local.ReadOnly = true
+		} else if b, err = backend.ReadOnly(b); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// backendInitLocker initializes the backend configured by the lock block
+// of another backend.
+func (m *Meta) backendInitLocker(c *backend.LockConfig) (backend.Backend, error) {
+	f := backendinit.Backend(c.Type)
+	if f == nil {
+		return nil, fmt.Errorf("%s: unknown backend type %q", backend.LockConfigKey, c.Type)
+	}
+	b := f()
+
+	rc, err := config.NewRawConfig(c.Config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", backend.LockConfigKey, err)
+	}
+	conf := terraform.NewResourceConfig(rc)
+
+	if _, errs := b.Validate(conf); len(errs) > 0 {
+		return nil, fmt.Errorf(
+			"%s: error configuring the %q backend: %s",
+			backend.LockConfigKey, c.Type, multierror.Append(nil, errs...))
+	}
+	if err := b.Configure(conf); err != nil {
+		return nil, fmt.Errorf(
+			"%s: error configuring the %q backend: %s", backend.LockConfigKey, c.Type, err)
 	}
 
 	return b, nil
@@ -1477,6 +1661,11 @@ Your local state remains intact and unmodified. Please resolve the error
 above and try again.
 `
 
+const errBackendWorkspaceEnvVar = `
+The environment name in %s, %q, is not allowed. The name must contain
+only URL safe characters, and no path separators.
+`
+
 const errBackendNewConfig = `
 Error configuring the backend %q: %s
 
@@ -2,7 +2,6 @@ package command
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -281,6 +280,7 @@ func (m *Meta) backendMigrateState_s_s(opts *backendMigrateOpts) error {
 	}
 
 	var confirmFunc func(state.State, state.State, *backendMigrateOpts) (bool, error)
+	var confirmId string
 	switch {
 	// No migration necessary
 	case one.Empty() && two.Empty():
@@ -294,11 +294,13 @@ func (m *Meta) backendMigrateState_s_s(opts *backendMigrateOpts) error {
 	// they'd like to do this.
 	case !one.Empty() && two.Empty():
 		confirmFunc = m.backendMigrateEmptyConfirm
+		confirmId = "backend-migrate-copy-to-empty"
 
 	// Both states are non-empty, meaning we need to determine which
 	// state should be used and update accordingly.
 	case !one.Empty() && !two.Empty():
 		confirmFunc = m.backendMigrateNonEmptyConfirm
+		confirmId = "backend-migrate-to-backend"
 	}
 
 	if confirmFunc == nil {
@@ -308,7 +310,14 @@ func (m *Meta) backendMigrateState_s_s(opts *backendMigrateOpts) error {
 	if !opts.force {
 		// Abort if we can't ask for input.
 		if !m.input {
-			return errors.New("error asking for state migration action: input disabled")
+			return fmt.Errorf(
+				"Error asking for state migration action: %s",
+				&inputRequiredError{
+					Id: confirmId,
+					Query: fmt.Sprintf(
+						"Do you want to copy state from %q to %q?",
+						opts.OneType, opts.TwoType),
+				})
 		}
 
 		// Confirm with the user whether we want to copy state over
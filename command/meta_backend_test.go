@@ -351,6 +351,186 @@ func TestMetaBackend_configureNew(t *testing.T) {
 	}
 }
 
+// Newly configured backend with the workspace in its configuration
+func TestMetaBackend_configureNewWorkspace(t *testing.T) {
+	// Create a temporary working directory that is empty
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("backend-new-workspace"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	// Setup the meta
+	m := testMetaBackend(t, nil)
+
+	// Get the backend
+	b, err := m.Backend(&BackendOpts{Init: true})
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// Write some state
+	s, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	s.WriteState(terraform.NewState())
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if _, err := os.Stat("default.tfstate"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The saved configuration keeps the workspace
+	{
+		actual := testStateRead(t, filepath.Join(m.DataDir(), DefaultStateFilename))
+		if v := actual.Backend.Config["path"]; v != "${terraform.workspace}.tfstate" {
+			t.Fatalf("bad: %#v", v)
+		}
+	}
+
+	// Selecting another workspace doesn't require init, and configures the
+	// backend for that workspace
+	if err := m.SetEnv("prod"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	m = testMetaBackend(t, nil)
+	b, err = m.Backend(&BackendOpts{})
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	s, err = b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	s.WriteState(terraform.NewState())
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if _, err := os.Stat("prod.tfstate"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+// Newly configured backend with encryption
+func TestMetaBackend_configureNewEncrypted(t *testing.T) {
+	// Create a temporary working directory that is empty
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("backend-new-encrypted"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	// The key is read from the environment, never from the configuration
+	os.Setenv(state.AESGCMKeyEnvVar, "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=")
+	defer os.Unsetenv(state.AESGCMKeyEnvVar)
+
+	// Setup the meta
+	m := testMetaBackend(t, nil)
+
+	// Get the backend
+	b, err := m.Backend(&BackendOpts{Init: true})
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// Write some state
+	s, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	state := terraform.NewState()
+	state.Lineage = "changing"
+	s.WriteState(state)
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// Read it back
+	if err := s.RefreshState(); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if actual := s.State(); actual.Lineage != state.Lineage {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The encryption must be saved with the backend config, so that
+	// changing it is detected as a change to the backend
+	saved := testStateRead(t, filepath.Join(m.DataDir(), DefaultStateFilename))
+	if _, ok := saved.Backend.Config["encryption"]; !ok {
+		t.Fatalf("bad: %#v", saved.Backend.Config)
+	}
+}
+
+// Newly configured backend with encryption that it doesn't support
+func TestMetaBackend_configureNewEncryptedLocal(t *testing.T) {
+	// Create a temporary working directory that is empty
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("backend-new-encrypted-local"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+	os.Setenv(state.AESGCMKeyEnvVar, "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=")
+	defer os.Unsetenv(state.AESGCMKeyEnvVar)
+
+	// Setup the meta
+	m := testMetaBackend(t, nil)
+
+	// Get the backend
+	_, err := m.Backend(&BackendOpts{Init: true})
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(err.Error(), "doesn't support state encryption") {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+// Newly configured backend with its states locked by another backend
+func TestMetaBackend_configureNewLocked(t *testing.T) {
+	// Create a temporary working directory that is empty
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("backend-new-locked"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	// Setup the meta
+	m := testMetaBackend(t, nil)
+
+	// Get the backend
+	b, err := m.Backend(&BackendOpts{Init: true})
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	s, err := b.State(backend.DefaultStateName)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// The locking backend already holds the lock
+	_, err = s.Lock(state.NewLockInfo())
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(err.Error(), "held-elsewhere") {
+		t.Fatalf("bad: %s", err)
+	}
+
+	// The state is still stored by the configured backend
+	state := terraform.NewState()
+	state.Lineage = "changing"
+	s.WriteState(state)
+	if err := s.PersistState(); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if err := s.RefreshState(); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if actual := s.State(); actual.Lineage != state.Lineage {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 // Newly configured backend with prior local state and no remote state
 func TestMetaBackend_configureNewWithState(t *testing.T) {
 	// Create a temporary working directory that is empty
@@ -1083,6 +1263,32 @@ func TestMetaBackend_configuredChangeCopy(t *testing.T) {
 	}
 }
 
+// Changing a configured backend in read-only mode doesn't copy the state.
+func TestMetaBackend_configuredChangeCopy_readOnly(t *testing.T) {
+	// Create a temporary working directory that is empty
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("backend-change"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	// Ask input
+	defer testInteractiveInput(t, []string{"yes", "yes"})()
+
+	// Setup the meta
+	m := testMetaBackend(t, nil)
+	m.readOnly = true
+
+	// Get the backend
+	if _, err := m.Backend(&BackendOpts{Init: true}); err == nil {
+		t.Fatal("should error")
+	}
+
+	// Verify the state wasn't copied
+	if _, err := os.Stat("local-state-2.tfstate"); err == nil {
+		t.Fatal("file should not exist")
+	}
+}
+
 // Changing a configured backend that supports only single states to another
 // backend that only supports single states.
 func TestMetaBackend_configuredChangeCopy_singleState(t *testing.T) {
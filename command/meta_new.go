@@ -33,9 +33,10 @@ func (m *Meta) Input() bool {
 // Module loads the module tree for the given root path.
 //
 // It expects the modules to already be downloaded. This will never
-// download any modules.
+// download any modules. The override files for the current environment
+// are merged in.
 func (m *Meta) Module(path string) (*module.Tree, error) {
-	mod, err := module.NewTreeModule("", path)
+	mod, err := module.NewTreeModuleEnv("", path, m.Env())
 	if err != nil {
 		// Check for the error where we have no config files
 		if errwrap.ContainsType(err, new(config.ErrNoConfigsFound)) {
@@ -56,6 +57,19 @@ func (m *Meta) Module(path string) (*module.Tree, error) {
 // Config loads the root config for the path specified. Path may be a directory
 // or file. The absence of configuration is not an error and returns a nil Config.
 func (m *Meta) Config(path string) (*config.Config, error) {
+	return m.config(path, config.LoadFile, config.LoadDir)
+}
+
+// ConfigTerraform is like Config, but only loads the terraform block of the
+// root config. It's for commands that only need the backend configuration,
+// so that they don't have to load and check the whole configuration.
+func (m *Meta) ConfigTerraform(path string) (*config.Config, error) {
+	return m.config(path, config.LoadFileTerraform, config.LoadDirTerraform)
+}
+
+// config loads the root config for the path specified with loadFile if it's
+// a file, or loadDir if it's a directory.
+func (m *Meta) config(path string, loadFile, loadDir func(string) (*config.Config, error)) (*config.Config, error) {
 	// If no explicit path was given then it is okay for there to be
 	// no backend configuration found.
 	emptyOk := path == ""
@@ -90,9 +104,9 @@ func (m *Meta) Config(path string) (*config.Config, error) {
 		return nil, err
 	}
 
-	var f func(string) (*config.Config, error) = config.LoadFile
+	f := loadFile
 	if fi.IsDir() {
-		f = config.LoadDir
+		f = loadDir
 	}
 
 	// Load the configuration
@@ -150,13 +164,23 @@ func (m *Meta) Plan(path string) (*terraform.Plan, error) {
 
 	// We do a validation here that seems odd but if any plan is given,
 	// we must not have set any extra variables. The plan itself contains
-	// the variables and those aren't overwritten.
-	if len(m.variables) > 0 {
-		return nil, fmt.Errorf(
-			"You can't set variables with the '-var' or '-var-file' flag\n" +
-				"when you're applying a plan file. The variables used when\n" +
-				"the plan was created will be used. If you wish to use different\n" +
-				"variable values, create a new plan file.")
+	// the variables and those aren't overwritten. Ephemeral variables are
+	// the exception, since their values aren't stored in the plan.
+	ephemeral := make(map[string]bool)
+	if p.Module != nil && p.Module.Config() != nil {
+		for _, v := range p.Module.Config().Variables {
+			ephemeral[v.Name] = v.Ephemeral
+		}
+	}
+	for k := range m.variables {
+		if !ephemeral[k] {
+			return nil, fmt.Errorf(
+				"You can't set variables with the '-var' or '-var-file' flag\n" +
+					"when you're applying a plan file. The variables used when\n" +
+					"the plan was created will be used. If you wish to use different\n" +
+					"variable values, create a new plan file. Only ephemeral\n" +
+					"variables, whose values aren't stored in the plan, can be set.")
+		}
 	}
 
 	return p, nil
@@ -6,10 +6,13 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+	"github.com/mitchellh/colorstring"
 )
 
 func TestMetaColorize(t *testing.T) {
@@ -55,6 +58,107 @@ func TestMetaColorize(t *testing.T) {
 	}
 }
 
+func TestMetaColorize_theme(t *testing.T) {
+	m := &Meta{
+		Color: true,
+		Ui:    new(cli.MockUi),
+		UIOptions: &UIOptions{
+			Theme:  "light",
+			Colors: map[string]string{"red": "1;31"},
+		},
+	}
+	m.process(nil, false)
+
+	colors := m.Colorize().Colors
+	if colors["yellow"] != "35" || colors["red"] != "1;31" || colors["green"] != "32" {
+		t.Fatalf("bad: %#v", colors)
+	}
+	if colorstring.DefaultColors["yellow"] != "33" {
+		t.Fatal("the default colors were changed")
+	}
+
+	// The environment overrides the configured theme
+	defer os.Setenv(UIThemeEnvVar, os.Getenv(UIThemeEnvVar))
+	os.Setenv(UIThemeEnvVar, "dark")
+	m.process(nil, false)
+	if colors := m.Colorize().Colors; colors["yellow"] != "33" || colors["red"] != "1;31" {
+		t.Fatalf("bad: %#v", colors)
+	}
+}
+
+func TestMeta_contextOptsProviderDefaults(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	m := new(Meta)
+	if err := m.SetEnv("staging"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The workspace and the remote state cache are passed to the terraform
+	// provider explicitly
+	f := m.flagSet("test")
+	m.refreshRemoteStateFlag(f)
+	if err := f.Parse([]string{"-refresh-remote-state"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	opts := m.contextOpts()
+	expected := map[string]string{
+		"workspace":     "staging",
+		"cache_dir":     filepath.Join(DefaultDataDir, "remote-state-cache"),
+		"refresh_cache": "true",
+	}
+	if actual := opts.Meta.ProviderDefaults["terraform"]; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestMeta_refreshRemoteStateFlagEnv(t *testing.T) {
+	defer os.Setenv(RemoteStateRefreshEnvVar, os.Getenv(RemoteStateRefreshEnvVar))
+	os.Setenv(RemoteStateRefreshEnvVar, "1")
+
+	m := new(Meta)
+	f := m.flagSet("test")
+	m.refreshRemoteStateFlag(f)
+	if err := f.Parse(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !m.refreshRemoteState {
+		t.Fatal("expected the environment to set the default")
+	}
+
+	// The flag is only registered by the commands that read data sources
+	f = new(Meta).flagSet("test")
+	f.SetOutput(ioutil.Discard)
+	if err := f.Parse([]string{"-refresh-remote-state"}); err == nil {
+		t.Fatal("expected the flag to be unknown")
+	}
+}
+
+func TestMetaUi_render(t *testing.T) {
+	defer os.Setenv(UIASCIIEnvVar, os.Getenv(UIASCIIEnvVar))
+	defer os.Setenv(UIWidthEnvVar, os.Getenv(UIWidthEnvVar))
+	os.Setenv(UIASCIIEnvVar, "1")
+	os.Setenv(UIWidthEnvVar, "20")
+
+	ui := new(cli.MockUi)
+	m := &Meta{Ui: ui}
+	m.process(nil, false)
+
+	// Output, such as values and JSON, is never changed
+	m.Ui.Output("{\"caf\u00e9\": \"\u201cquoted\u201d \u2014 long text\"}")
+	m.Ui.Error("caf\u00e9 \u2192 \u201cquoted\u201d is long")
+	if actual, expected := ui.OutputWriter.String(), "{\"caf\u00e9\": \"\u201cquoted\u201d \u2014 long text\"}\n"; actual != expected {
+		t.Fatalf("bad output: %q", actual)
+	}
+	if actual, expected := ui.ErrorWriter.String(), "caf\u00e9 -> \"quoted\" is\nlong\n"; actual != expected {
+		t.Fatalf("bad error: %q", actual)
+	}
+}
+
 func TestMetaInputMode(t *testing.T) {
 	test = false
 	defer func() { test = true }()
@@ -106,6 +210,45 @@ func TestMetaInputMode_envVar(t *testing.T) {
 	}
 }
 
+func TestMetaReadOnly(t *testing.T) {
+	old := os.Getenv(ReadOnlyEnvVar)
+	defer os.Setenv(ReadOnlyEnvVar, old)
+
+	cases := []struct {
+		EnvVar   string
+		Args     []string
+		Expected bool
+	}{
+		{"", []string{"foo"}, false},
+		{"", []string{"-read-only", "foo"}, true},
+		{"true", []string{"foo"}, true},
+		{"1", []string{"foo"}, true},
+		{"false", []string{"foo"}, false},
+		{"0", []string{"-read-only", "foo"}, true},
+		{"", []string{"-read-only=true", "foo"}, true},
+		{"", []string{"--read-only", "foo"}, true},
+		{"true", []string{"-read-only=false", "foo"}, false},
+		{"", []string{"-read-only=0", "foo"}, false},
+
+		// A value that isn't a boolean fails safe
+		{"", []string{"-read-only=maybe", "foo"}, true},
+	}
+
+	for i, tc := range cases {
+		os.Setenv(ReadOnlyEnvVar, tc.EnvVar)
+
+		m := &Meta{Ui: new(cli.MockUi)}
+		args := m.process(tc.Args, false)
+		if !reflect.DeepEqual(args, []string{"foo"}) {
+			t.Fatalf("%d: bad args: %#v", i, args)
+		}
+
+		if m.checkReadOnly("foo") != tc.Expected {
+			t.Fatalf("%d: expected read-only: %t", i, tc.Expected)
+		}
+	}
+}
+
 func TestMetaInputMode_disable(t *testing.T) {
 	test = false
 	defer func() { test = true }()
@@ -307,3 +450,127 @@ func TestMeta_Env(t *testing.T) {
 		t.Fatalf("expected env %q, got env %q", backend.DefaultStateName, env)
 	}
 }
+
+func TestMeta_varLayers(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(filepath.Join(td, DefaultVarLayersDir), 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	files := map[string]string{
+		DefaultVarsFilename:                          "a = \"auto\"\nd = \"auto\"\n",
+		filepath.Join("vars", "common.tfvars"):       "a = \"common\"\nb = \"common\"\nc = \"common\"\nd = \"common\"\n",
+		filepath.Join("vars", "us-east-1.tfvars"):    "b = \"region\"\nc = \"region\"\n",
+		filepath.Join("vars", "prod.tfvars.json"):    `{"c": "prod"}`,
+		filepath.Join("vars", "staging.tfvars.json"): `{"c": "staging"}`,
+	}
+	for path, content := range files {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	m := new(Meta)
+	if err := m.SetEnv("prod"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	args := m.process([]string{"-var-layer", "us-east-1", "-var", "d=cli"}, true)
+	fs := m.flagSet("foo")
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]interface{}{
+		"a": "common",
+		"b": "region",
+		"c": "prod",
+		"d": "cli",
+	}
+	if !reflect.DeepEqual(m.variables, expected) {
+		t.Fatalf("bad: %#v", m.variables)
+	}
+}
+
+func TestMeta_varLayersMissing(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(filepath.Join(td, "layers"), 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	m := new(Meta)
+	args := m.process([]string{"-var-dir=layers"}, true)
+	fs := m.flagSet("foo")
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(m.variables) != 0 {
+		t.Fatalf("bad: %#v", m.variables)
+	}
+
+	// A layer that was asked for must exist
+	m = new(Meta)
+	args = m.process([]string{"-var-dir=layers", "-var-layer=missing"}, true)
+	fs = m.flagSet("foo")
+	fs.SetOutput(ioutil.Discard)
+	if err := fs.Parse(args); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestMeta_suppressWarnings(t *testing.T) {
+	ui := new(cli.MockUi)
+	m := &Meta{Ui: ui}
+	args := m.process([]string{
+		"-suppress-warning=module-deprecated,module-version",
+		"-suppress-warning", "validation",
+		"-suppress-warning=unknown",
+		"foo",
+	}, false)
+	if !reflect.DeepEqual(args, []string{"foo"}) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	for _, code := range []string{"module-deprecated", "module-version", "validation"} {
+		if m.Warnings.Show(code) {
+			t.Fatalf("%s should be suppressed", code)
+		}
+	}
+	if !m.Warnings.Show("backend-legacy") {
+		t.Fatal("backend-legacy should be shown")
+	}
+
+	if !strings.Contains(ui.ErrorWriter.String(), "unknown warning code(s): unknown") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+func TestMeta_providerDev(t *testing.T) {
+	ui := new(cli.MockUi)
+	m := &Meta{Ui: ui}
+	args := m.process([]string{
+		"-provider-dev=aws=in-process",
+		"-provider-dev", "example=/tmp/terraform-provider-example",
+		"-provider-dev=bad",
+		"foo",
+	}, false)
+	if !reflect.DeepEqual(args, []string{"foo"}) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	expected := map[string]string{
+		"aws":     ProviderDevInProcess,
+		"example": "/tmp/terraform-provider-example",
+	}
+	if !reflect.DeepEqual(m.providerDev, expected) {
+		t.Fatalf("bad: %#v", m.providerDev)
+	}
+
+	errs := ui.ErrorWriter.String()
+	if !strings.Contains(errs, "Ignoring -provider-dev=bad") {
+		t.Fatalf("bad: %s", errs)
+	}
+	if !strings.Contains(errs, "Using development providers: aws (in-process), example (/tmp/terraform-provider-example)") {
+		t.Fatalf("bad: %s", errs)
+	}
+}
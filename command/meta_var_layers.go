@@ -0,0 +1,94 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// processVarLayers removes the -var-dir and -var-layer flags from args, and
+// if either was given, adds a -var-file flag to the front of args for each
+// file of layered variables. Later files override earlier ones, so the
+// files are added from the lowest precedence to the highest:
+//
+//  1. common.tfvars
+//  2. <layer>.tfvars for each -var-layer, in the order given
+//  3. <environment>.tfvars for the current state environment
+//
+// The common and environment files are optional, but the file for a layer
+// that was asked for must exist. JSON files with a ".tfvars.json" extension
+// are loaded along with the HCL files of the same name.
+func (m *Meta) processVarLayers(args []string) []string {
+	var dir string
+	var layers []string
+	enabled := false
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		name := ""
+		for _, n := range []string{"var-dir", "var-layer"} {
+			if args[i] == "-"+n || strings.HasPrefix(args[i], "-"+n+"=") {
+				name = n
+				break
+			}
+		}
+		if name == "" {
+			rest = append(rest, args[i])
+			continue
+		}
+
+		value := strings.TrimPrefix(args[i], "-"+name)
+		if strings.HasPrefix(value, "=") {
+			value = value[1:]
+		} else if i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+
+		enabled = true
+		if name == "var-dir" {
+			dir = value
+		} else {
+			layers = append(layers, value)
+		}
+	}
+	if !enabled {
+		return args
+	}
+
+	if dir == "" {
+		dir = DefaultVarLayersDir
+	}
+
+	var files []string
+	files = append(files, varLayerFiles(dir, VarLayersCommonName, false)...)
+	for _, layer := range layers {
+		files = append(files, varLayerFiles(dir, layer, true)...)
+	}
+	files = append(files, varLayerFiles(dir, m.Env(), false)...)
+
+	result := make([]string, 0, len(files)*2+len(rest))
+	for _, f := range files {
+		result = append(result, "-var-file", f)
+	}
+
+	return append(result, rest...)
+}
+
+// varLayerFiles returns the files of the named layer of variables in dir.
+// If the layer is required and has no files, the HCL file is returned
+// anyway, so that loading it reports that it's missing.
+func varLayerFiles(dir, name string, required bool) []string {
+	var result []string
+	for _, ext := range []string{".tfvars", ".tfvars.json"} {
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			result = append(result, path)
+		}
+	}
+
+	if len(result) == 0 && required {
+		result = append(result, filepath.Join(dir, name+".tfvars"))
+	}
+
+	return result
+}
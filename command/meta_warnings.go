@@ -0,0 +1,133 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/warnings"
+)
+
+// processSuppressWarnings removes the -suppress-warning flags from args and
+// suppresses the warnings with the codes they give. The flag can be given
+// more than once, and each value can be a comma-separated list of codes.
+//
+// This is done here rather than with the other flags so that it applies to
+// every command, including the warnings shown before a command's own flags
+// are parsed.
+func (m *Meta) processSuppressWarnings(args []string) []string {
+	if m.Warnings == nil {
+		m.Warnings = warnings.NewCollector()
+	}
+
+	args, codes := extractCodesFlag(args, "-suppress-warning")
+	if err := m.Warnings.Suppress(codes...); err != nil {
+		m.Warnings.Warn(m.Ui, warnings.New(
+			warnings.CodeIgnoredSetting, "Ignoring -suppress-warning: %s", err))
+	}
+
+	return args
+}
+
+// processStrict removes the -strict flags from args and turns the warnings
+// with the codes they give into errors, like the strict block in the
+// configuration. The flag takes the same values as -suppress-warning.
+func (m *Meta) processStrict(args []string) []string {
+	args, codes := extractCodesFlag(args, "-strict")
+
+	m.strictWarnings = nil
+	var unknown []string
+	for _, code := range codes {
+		if !warnings.IsCode(code) {
+			unknown = append(unknown, code)
+			continue
+		}
+		m.strictWarnings = append(m.strictWarnings, code)
+	}
+	if len(unknown) > 0 {
+		m.Warnings.Warn(m.Ui, warnings.New(warnings.CodeIgnoredSetting,
+			"Ignoring -strict: unknown warning code(s): %s (valid codes are: %s)",
+			strings.Join(unknown, ", "), strings.Join(warnings.Codes, ", ")))
+	}
+
+	return args
+}
+
+// extractCodesFlag removes the flag with the given name from args, and
+// returns the rest of the args along with the codes that the flag gives.
+// The flag can be given more than once, and each value can be a
+// comma-separated list of codes.
+func extractCodesFlag(args []string, name string) ([]string, []string) {
+	rest, values := extractFlag(args, name)
+
+	var codes []string
+	for _, value := range values {
+		for _, code := range strings.Split(value, ",") {
+			if code = strings.TrimSpace(code); code != "" {
+				codes = append(codes, code)
+			}
+		}
+	}
+
+	return rest, codes
+}
+
+// extractFlag removes the flag with the given name, which can be given more
+// than once, from args, and returns the rest of the args along with its
+// values.
+func extractFlag(args []string, name string) ([]string, []string) {
+	var values []string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] != name && !strings.HasPrefix(args[i], name+"=") {
+			rest = append(rest, args[i])
+			continue
+		}
+
+		value := strings.TrimPrefix(args[i], name)
+		if strings.HasPrefix(value, "=") {
+			value = value[1:]
+		} else if i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+
+		values = append(values, value)
+	}
+
+	return rest, values
+}
+
+// extractBoolFlag removes the boolean flag with the given name from args,
+// and returns the rest of the args along with its value and whether it was
+// given, with the semantics of the flag package: "-name" is true, and
+// "-name=value" is the boolean value, such as "-name=false". The flag can
+// also be given with two dashes, and the last occurrence wins.
+func extractBoolFlag(args []string, name string) ([]string, bool, bool, error) {
+	var value, set bool
+	var err error
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		flag := arg
+		if strings.HasPrefix(flag, "--") {
+			flag = flag[1:]
+		}
+		if flag != name && !strings.HasPrefix(flag, name+"=") {
+			rest = append(rest, arg)
+			continue
+		}
+
+		set = true
+		if flag == name {
+			value, err = true, nil
+			continue
+		}
+
+		raw := flag[len(name)+1:]
+		if value, err = strconv.ParseBool(raw); err != nil {
+			err = fmt.Errorf("%q isn't a boolean", raw)
+		}
+	}
+
+	return rest, value, set, err
+}
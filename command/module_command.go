@@ -0,0 +1,148 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/atlas-go/archive"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform/config"
+	"github.com/mitchellh/cli"
+)
+
+// ModuleCommand is a Command implementation that just shows help for
+// the subcommands nested below it.
+type ModuleCommand struct {
+	Meta
+}
+
+func (c *ModuleCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *ModuleCommand) Help() string {
+	helpText := `
+Usage: terraform module <subcommand> [options] [args]
+
+  This command has subcommands for publishing modules.
+
+  A module is checked before it is packaged: its variables and outputs
+  must be documented, and it must have examples of its use. The package
+  is a gzipped tarball that can be used as a module source, along with
+  its SHA256 checksum.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ModuleCommand) Synopsis() string {
+	return "Package and publish modules"
+}
+
+// moduleExamplesDir is the directory within a module with the example
+// configurations that use it.
+const moduleExamplesDir = "examples"
+
+// moduleCheck checks that the module in dir is ready to be published. It
+// returns an error listing all the problems found.
+func moduleCheck(dir string) error {
+	conf, err := config.LoadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var result error
+	if err := conf.Validate(); err != nil {
+		result = multierror.Append(result, err)
+	}
+
+	for _, v := range conf.Variables {
+		if strings.TrimSpace(v.Description) == "" {
+			result = multierror.Append(result, fmt.Errorf(
+				"variable %q has no description", v.Name))
+		}
+	}
+	for _, o := range conf.Outputs {
+		if strings.TrimSpace(o.Description) == "" {
+			result = multierror.Append(result, fmt.Errorf(
+				"output %q has no description", o.Name))
+		}
+	}
+
+	examples, err := moduleExamples(dir)
+	if err != nil {
+		return multierror.Append(result, err)
+	}
+	if len(examples) == 0 {
+		result = multierror.Append(result, fmt.Errorf(
+			"no examples: each subdirectory of %s/ should be an example "+
+				"configuration that uses the module", moduleExamplesDir))
+	}
+	for _, name := range examples {
+		_, err := config.LoadDir(filepath.Join(dir, moduleExamplesDir, name))
+		if err != nil {
+			result = multierror.Append(result, fmt.Errorf(
+				"example %q: %s", name, err))
+		}
+	}
+
+	return result
+}
+
+// moduleExamples returns the names of the example directories of the
+// module in dir, sorted.
+func moduleExamples(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(dir, moduleExamplesDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var result []string
+	for _, info := range infos {
+		if info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+			result = append(result, info.Name())
+		}
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
+// modulePackage writes the module in dir as a gzipped tarball to w, and
+// returns the hex SHA256 checksum of the tarball. If vcs is true, only the
+// files tracked by the version control system of the module are included.
+func modulePackage(dir string, vcs bool, w io.Writer) (string, error) {
+	archiveR, err := archive.CreateArchive(dir, &archive.ArchiveOpts{
+		VCS: vcs,
+
+		// Local data and state are never part of a module, and neither
+		// are packages, including the one that may be being written.
+		Exclude: []string{
+			DefaultDataDir,
+			"*" + DefaultStateFilename + "*",
+			"*.tar.gz",
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer archiveR.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), archiveR); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,99 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ModulePackageCommand is a Command implementation that checks a module
+// and packages it into a tarball.
+type ModulePackageCommand struct {
+	Meta
+}
+
+func (c *ModulePackageCommand) Run(args []string) int {
+	var outPath string
+	var archiveVCS bool
+
+	args = c.Meta.process(args, false)
+
+	cmdFlags := c.Meta.flagSet("module package")
+	cmdFlags.StringVar(&outPath, "out", "", "path")
+	cmdFlags.BoolVar(&archiveVCS, "vcs", true, "vcs")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	dir, err := ModulePath(cmdFlags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	if err := moduleCheck(dir); err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"The module in %s isn't ready to be packaged:\n\n%s", dir, err))
+		return 1
+	}
+
+	if outPath == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error expanding the module path: %s", err))
+			return 1
+		}
+		outPath = filepath.Base(abs) + ".tar.gz"
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error creating the package: %s", err))
+		return 1
+	}
+	sum, err := modulePackage(dir, archiveVCS, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(outPath)
+		c.Ui.Error(fmt.Sprintf("Error packaging the module: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Module packaged to %s\nSHA256: %s", outPath, sum))
+	return 0
+}
+
+func (c *ModulePackageCommand) Help() string {
+	helpText := `
+Usage: terraform module package [options] [DIR]
+
+  Checks the module in the given directory, or the current directory,
+  and packages it into a gzipped tarball.
+
+  The variables and the outputs of the module must have descriptions, and
+  each subdirectory of "examples" in the module must be an example
+  configuration that uses the module. There must be at least one example.
+
+  The SHA256 checksum of the package is shown, for use in the "checksum"
+  parameter of module sources.
+
+Options:
+
+  -out=path           Path to write the package to. Defaults to the name of
+                      the module directory with ".tar.gz" appended, in the
+                      current directory.
+
+  -vcs=true           If true (default), only the files tracked by the
+                      version control system of the module are packaged.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ModulePackageCommand) Synopsis() string {
+	return "Check a module and package it"
+}
@@ -0,0 +1,125 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestModulePackage(t *testing.T) {
+	outPath := filepath.Join(testTempDir(t), "module.tar.gz")
+	defer os.RemoveAll(filepath.Dir(outPath))
+
+	ui := new(cli.MockUi)
+	c := &ModulePackageCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	args := []string{
+		"-vcs=false",
+		"-out", outPath,
+		testFixturePath("module-publish"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	data, err := ioutil.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	sum := sha256.Sum256(data)
+	if output := ui.OutputWriter.String(); !strings.Contains(output, hex.EncodeToString(sum[:])) {
+		t.Fatalf("output should contain the checksum:\n\n%s", output)
+	}
+
+	actual := testModulePackageFiles(t, outPath)
+	expected := []string{"examples/basic/main.tf", "main.tf"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestModulePackage_invalid(t *testing.T) {
+	td := testTempDir(t)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	ui := new(cli.MockUi)
+	c := &ModulePackageCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	args := []string{
+		"-vcs=false",
+		testFixturePath("module-publish-invalid"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+
+	output := ui.ErrorWriter.String()
+	expected := []string{
+		`variable "name" has no description`,
+		`output "id" has no description`,
+		"no examples",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Fatalf("output should contain %q:\n\n%s", e, output)
+		}
+	}
+
+	if _, err := os.Stat("module-publish-invalid.tar.gz"); !os.IsNotExist(err) {
+		t.Fatalf("no package should be written: %v", err)
+	}
+}
+
+// testModulePackageFiles returns the names of the regular files in the
+// module package at path, sorted.
+func testModulePackageFiles(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+
+	gzipR, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var result []string
+	tarR := tar.NewReader(gzipR)
+	for {
+		hdr, err := tarR.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if hdr.Typeflag == tar.TypeReg {
+			result = append(result, hdr.Name)
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,208 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-version"
+)
+
+// These are the environment variables that configure the registry that
+// modules are published to.
+const (
+	EnvModuleRegistry      = "TF_MODULE_REGISTRY"
+	EnvModuleRegistryToken = "TF_MODULE_REGISTRY_TOKEN"
+)
+
+// moduleNameRe matches the names that modules are published under, such as
+// "network/vpc".
+var moduleNameRe = regexp.MustCompile(`^[\w-]+/[\w-]+$`)
+
+// ModulePublishCommand is a Command implementation that checks a module,
+// packages it, and uploads the package to a registry.
+type ModulePublishCommand struct {
+	Meta
+}
+
+func (c *ModulePublishCommand) Run(args []string) int {
+	var registry, token, name, versionStr string
+	var archiveVCS bool
+
+	args = c.Meta.process(args, false)
+
+	cmdFlags := c.Meta.flagSet("module publish")
+	cmdFlags.StringVar(&registry, "registry", os.Getenv(EnvModuleRegistry), "registry")
+	cmdFlags.StringVar(&token, "token", os.Getenv(EnvModuleRegistryToken), "token")
+	cmdFlags.StringVar(&name, "name", "", "name")
+	cmdFlags.StringVar(&versionStr, "version", "", "version")
+	cmdFlags.BoolVar(&archiveVCS, "vcs", true, "vcs")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	if registry == "" {
+		c.Ui.Error(fmt.Sprintf(
+			"The registry to publish to must be set with -registry or %s.",
+			EnvModuleRegistry))
+		return 1
+	}
+	registryURL, err := url.Parse(strings.TrimSuffix(registry, "/"))
+	if err != nil || registryURL.Scheme == "" || registryURL.Host == "" {
+		c.Ui.Error(fmt.Sprintf("Invalid registry URL %q.", registry))
+		return 1
+	}
+
+	// Without a token, use the one saved by "terraform login" or set in the
+	// CLI configuration for the host of the registry, if there is one.
+	// Like everywhere else, those are only sent over HTTPS.
+	if token == "" && registryURL.Scheme == "https" {
+		token, err = c.credentials().Token(registryURL.Host)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading the token for %s: %s", registryURL.Host, err))
+			return 1
+		}
+	}
+
+	if !moduleNameRe.MatchString(name) {
+		c.Ui.Error("The -name of the module must be in the form NAMESPACE/NAME.")
+		return 1
+	}
+	v, err := version.NewVersion(versionStr)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Invalid -version %q: %s", versionStr, err))
+		return 1
+	}
+
+	dir, err := ModulePath(cmdFlags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	if err := moduleCheck(dir); err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"The module in %s isn't ready to be published:\n\n%s", dir, err))
+		return 1
+	}
+
+	// Package into a temporary file, since we need the checksum before
+	// uploading it.
+	f, err := ioutil.TempFile("", "terraform-module")
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error creating the package: %s", err))
+		return 1
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	sum, err := modulePackage(dir, archiveVCS, f)
+	if err == nil {
+		_, err = f.Seek(0, 0)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error packaging the module: %s", err))
+		return 1
+	}
+
+	packageURL := fmt.Sprintf(
+		"%s/%s/%s.tar.gz", registryURL.String(), name, v.String())
+	req, err := http.NewRequest("PUT", packageURL, f)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error publishing the module: %s", err))
+		return 1
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	req.Header.Set("X-Checksum-Sha256", sum)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error publishing the module: %s", err))
+		return 1
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		c.Ui.Error(fmt.Sprintf(
+			"Version %s of %s has already been published. Published versions\n"+
+				"can't be replaced, so that the modules that use them don't change.",
+			v, name))
+		return 1
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		body, _ := ioutil.ReadAll(resp.Body)
+		c.Ui.Error(fmt.Sprintf(
+			"Error publishing the module: the registry responded with %s\n\n%s",
+			resp.Status, strings.TrimSpace(string(body))))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf(
+		strings.TrimSpace(modulePublishSuccess),
+		name, v, sum, moduleSourceName(name), packageURL, sum))
+	return 0
+}
+
+// moduleSourceName returns a name to give a module block in examples.
+func moduleSourceName(name string) string {
+	return strings.Replace(name[strings.Index(name, "/")+1:], "-", "_", -1)
+}
+
+func (c *ModulePublishCommand) Help() string {
+	helpText := `
+Usage: terraform module publish [options] [DIR]
+
+  Checks the module in the given directory, or the current directory,
+  packages it, and uploads the package to a module registry.
+
+  The module is checked and packaged as by "terraform module package".
+  The package is uploaded with an HTTP PUT request to
+  REGISTRY/NAMESPACE/NAME/VERSION.tar.gz, and the checksum of the package
+  is sent in the X-Checksum-Sha256 header. Once published, the module can
+  be used with the source that is shown.
+
+Options:
+
+  -name=namespace/name  The name to publish the module as. Required.
+
+  -registry=url         The URL of the registry. Defaults to the
+                        TF_MODULE_REGISTRY environment variable.
+
+  -token=token          Token to authenticate with the registry, sent as a
+                        bearer token. Defaults to the
+                        TF_MODULE_REGISTRY_TOKEN environment variable,
+                        then to the token for the host of the registry
+                        saved by "terraform login".
+
+  -vcs=true             If true (default), only the files tracked by the
+                        version control system of the module are packaged.
+
+  -version=version      The version to publish the module as. Required.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ModulePublishCommand) Synopsis() string {
+	return "Check a module and publish it to a registry"
+}
+
+const modulePublishSuccess = `
+Published %s version %s.
+SHA256: %s
+
+To use this version of the module:
+
+  module "%s" {
+    source = "%s?checksum=sha256:%s"
+  }
+`
@@ -0,0 +1,114 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestModulePublish(t *testing.T) {
+	var method, path, auth, checksum string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		path = r.URL.Path
+		auth = r.Header.Get("Authorization")
+		checksum = r.Header.Get("X-Checksum-Sha256")
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	ui := new(cli.MockUi)
+	c := &ModulePublishCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	args := []string{
+		"-vcs=false",
+		"-registry", srv.URL + "/modules/",
+		"-token", "secret",
+		"-name", "acme/test-instance",
+		"-version", "1.2.0",
+		testFixturePath("module-publish"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if method != "PUT" || path != "/modules/acme/test-instance/1.2.0.tar.gz" {
+		t.Fatalf("bad request: %s %s", method, path)
+	}
+	if auth != "Bearer secret" {
+		t.Fatalf("bad authorization: %s", auth)
+	}
+
+	sum := sha256.Sum256(body)
+	if checksum != hex.EncodeToString(sum[:]) {
+		t.Fatalf("bad checksum: %s", checksum)
+	}
+
+	output := ui.OutputWriter.String()
+	source := srv.URL + "/modules/acme/test-instance/1.2.0.tar.gz?checksum=sha256:" + checksum
+	if !strings.Contains(output, source) {
+		t.Fatalf("output should contain the source %q:\n\n%s", source, output)
+	}
+}
+
+func TestModulePublish_conflict(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer srv.Close()
+
+	ui := new(cli.MockUi)
+	c := &ModulePublishCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	args := []string{
+		"-vcs=false",
+		"-registry", srv.URL,
+		"-name", "acme/test-instance",
+		"-version", "1.2.0",
+		testFixturePath("module-publish"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if output := ui.ErrorWriter.String(); !strings.Contains(output, "already been published") {
+		t.Fatalf("bad: %s", output)
+	}
+}
+
+func TestModulePublish_invalidArgs(t *testing.T) {
+	cases := map[string][]string{
+		"no registry": {"-name", "acme/vpc", "-version", "1.0.0"},
+		"bad name":    {"-registry", "https://example.com", "-name", "vpc", "-version", "1.0.0"},
+		"bad version": {"-registry", "https://example.com", "-name", "acme/vpc", "-version", "latest"},
+	}
+
+	for name, args := range cases {
+		ui := new(cli.MockUi)
+		c := &ModulePublishCommand{
+			Meta: Meta{
+				Ui: ui,
+			},
+		}
+
+		args = append(args, testFixturePath("module-publish"))
+		if code := c.Run(args); code != 1 {
+			t.Fatalf("%s: bad: %d\n\n%s", name, code, ui.OutputWriter.String())
+		}
+	}
+}
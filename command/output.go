@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/terraform"
 )
 
 // OutputCommand is a Command implementation that reads an output
@@ -19,9 +22,10 @@ func (c *OutputCommand) Run(args []string) int {
 	args = c.Meta.process(args, false)
 
 	var module string
-	var jsonOutput bool
+	var jsonOutput, publish bool
 	cmdFlags := flag.NewFlagSet("output", flag.ContinueOnError)
 	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.BoolVar(&publish, "publish", false, "publish")
 	cmdFlags.StringVar(&c.Meta.statePath, "state", DefaultStateFilename, "path")
 	cmdFlags.StringVar(&module, "module", "", "module")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
@@ -30,7 +34,17 @@ func (c *OutputCommand) Run(args []string) int {
 	}
 
 	args = cmdFlags.Args()
-	if len(args) > 1 {
+	if publish {
+		if c.Meta.checkReadOnly("output -publish") {
+			return 1
+		}
+
+		if module != "" {
+			c.Ui.Error("Only outputs of the root module can be published.\n")
+			cmdFlags.Usage()
+			return 1
+		}
+	} else if len(args) > 1 {
 		c.Ui.Error(
 			"The output command expects exactly one argument with the name\n" +
 				"of an output variable or no arguments to show all outputs.\n")
@@ -74,6 +88,15 @@ func (c *OutputCommand) Run(args []string) int {
 	modPath := strings.Split(module, ".")
 
 	state := stateStore.State()
+	if publish {
+		var outputs map[string]*terraform.OutputState
+		if mod := state.RootModule(); mod != nil {
+			outputs = mod.Outputs
+		}
+
+		return c.publish(b, env, outputs, args)
+	}
+
 	mod := state.ModuleByPath(modPath)
 	if mod == nil {
 		c.Ui.Error(fmt.Sprintf(
@@ -145,6 +168,66 @@ func (c *OutputCommand) Run(args []string) int {
 	return 0
 }
 
+// publish publishes the named outputs, or all non-sensitive outputs if
+// no names are given, with a backend that supports it.
+func (c *OutputCommand) publish(
+	b backend.Backend, env string,
+	outputs map[string]*terraform.OutputState, names []string) int {
+	p, ok := b.(backend.OutputPublisher)
+	if !ok {
+		c.Ui.Error(fmt.Sprintf(
+			strings.TrimSpace(errOutputPublishNotSupported), c.backendType()))
+		return 1
+	}
+
+	published := make(map[string]*terraform.OutputState)
+	if len(names) == 0 {
+		for k, v := range outputs {
+			// Sensitive outputs are only published if named explicitly
+			if v.Sensitive {
+				continue
+			}
+
+			published[k] = v
+		}
+	}
+	for _, name := range names {
+		v, ok := outputs[name]
+		if !ok {
+			c.Ui.Error(fmt.Sprintf(
+				"The output variable %q could not be found in the state.", name))
+			return 1
+		}
+
+		published[name] = v
+	}
+
+	err := p.PublishOutputs(env, published)
+	if err == backend.ErrOutputPublishingNotSupported {
+		c.Ui.Error(fmt.Sprintf(
+			strings.TrimSpace(errOutputPublishNotSupported), c.backendType()))
+		return 1
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to publish outputs: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Published %d output(s).", len(published)))
+	return 0
+}
+
+// backendType returns the type of the backend in the configuration, which
+// is "local" if the configuration has none.
+func (c *OutputCommand) backendType() string {
+	conf, err := c.ConfigTerraform(".")
+	if err != nil || conf == nil || conf.Terraform == nil || conf.Terraform.Backend == nil {
+		return "local"
+	}
+
+	return conf.Terraform.Backend.Type
+}
+
 func formatNestedList(indent string, outputList []interface{}) string {
 	outputBuf := new(bytes.Buffer)
 	outputBuf.WriteString(fmt.Sprintf("%s[", indent))
@@ -261,12 +344,19 @@ func formatMapOutput(indent, outputName string, outputMap map[string]interface{}
 func (c *OutputCommand) Help() string {
 	helpText := `
 Usage: terraform output [options] [NAME]
+       terraform output -publish [NAME ...]
 
   Reads an output variable from a Terraform state file and prints
   the value. With no additional arguments, output will display all
   the outputs for the root module.  If NAME is not specified, all
   outputs are printed.
 
+  With -publish, the named outputs of the root module, or all of its
+  non-sensitive outputs if no names are given, are published by the
+  backend to a location separate from the state. The terraform_remote_state
+  data source can read them with "published_outputs = true", without
+  access to the full state.
+
 Options:
 
   -state=path      Path to the state file to read. Defaults to
@@ -280,6 +370,9 @@ Options:
   -json            If specified, machine readable output will be
                    printed in JSON format
 
+  -publish         If specified, publishes outputs rather than
+                   printing them. See above.
+
 `
 	return strings.TrimSpace(helpText)
 }
@@ -287,3 +380,7 @@ Options:
 func (c *OutputCommand) Synopsis() string {
 	return "Read an output from a state file"
 }
+
+const errOutputPublishNotSupported = `
+The configured backend, %q, doesn't support publishing outputs.
+`
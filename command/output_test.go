@@ -4,9 +4,12 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/backend/local"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 )
@@ -50,6 +53,131 @@ func TestOutput(t *testing.T) {
 	}
 }
 
+func TestOutput_publish(t *testing.T) {
+	originalState := &terraform.State{
+		Modules: []*terraform.ModuleState{
+			{
+				Path: []string{"root"},
+				Outputs: map[string]*terraform.OutputState{
+					"foo": {
+						Value: "bar",
+						Type:  "string",
+					},
+					"secret": {
+						Value:     "hunter2",
+						Type:      "string",
+						Sensitive: true,
+					},
+				},
+			},
+		},
+	}
+
+	statePath := testStateFile(t, originalState)
+
+	cases := []struct {
+		Args     []string
+		Expected []string
+	}{
+		// Sensitive outputs are skipped unless named
+		{nil, []string{"foo"}},
+		{[]string{"secret"}, []string{"secret"}},
+	}
+
+	for i, tc := range cases {
+		ui := new(cli.MockUi)
+		c := &OutputCommand{
+			Meta: Meta{
+				testingOverrides: metaOverridesForProvider(testProvider()),
+				Ui:               ui,
+			},
+		}
+
+		args := append([]string{"-state", statePath, "-publish"}, tc.Args...)
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("%d: bad: \n%s", i, ui.ErrorWriter.String())
+		}
+
+		data, err := ioutil.ReadFile(statePath + local.DefaultOutputsExtension)
+		if err != nil {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+		outputs, err := backend.DecodeOutputs(data)
+		if err != nil {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+
+		var actual []string
+		for k := range outputs {
+			actual = append(actual, k)
+		}
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("%d: bad: %#v", i, actual)
+		}
+	}
+}
+
+func TestOutput_publishMissing(t *testing.T) {
+	originalState := &terraform.State{
+		Modules: []*terraform.ModuleState{
+			{
+				Path: []string{"root"},
+				Outputs: map[string]*terraform.OutputState{
+					"foo": {
+						Value: "bar",
+						Type:  "string",
+					},
+				},
+			},
+		},
+	}
+
+	statePath := testStateFile(t, originalState)
+
+	ui := new(cli.MockUi)
+	c := &OutputCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-publish",
+		"nope",
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: \n%s", ui.OutputWriter.String())
+	}
+
+	if _, err := os.Stat(statePath + local.DefaultOutputsExtension); !os.IsNotExist(err) {
+		t.Fatalf("outputs should not be published: %s", err)
+	}
+}
+
+func TestOutput_backendType(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	c := &OutputCommand{Meta: Meta{Ui: new(cli.MockUi)}}
+
+	// Without a backend in the configuration, the state is local
+	if actual := c.backendType(); actual != "local" {
+		t.Fatalf("bad: %q", actual)
+	}
+
+	config := []byte("terraform {\n  backend \"inmem\" {}\n}\n")
+	if err := ioutil.WriteFile(filepath.Join(td, "main.tf"), config, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual := c.backendType(); actual != "inmem" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
 func TestModuleOutput(t *testing.T) {
 	originalState := &terraform.State{
 		Modules: []*terraform.ModuleState{
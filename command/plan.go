@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/terraform/backend"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/terraform"
 )
 
 // PlanCommand is a Command implementation that compares a Terraform
@@ -17,23 +18,36 @@ type PlanCommand struct {
 }
 
 func (c *PlanCommand) Run(args []string) int {
-	var destroy, refresh, detailed bool
-	var outPath string
+	var destroy, refresh, refreshChanged, detailed, driftOnly, jsonOutput, confidence bool
+	var outPath, graphOut, compare string
 	var moduleDepth int
+	var explain []string
 
 	args = c.Meta.process(args, true)
 
 	cmdFlags := c.Meta.flagSet("plan")
 	cmdFlags.BoolVar(&destroy, "destroy", false, "destroy")
 	cmdFlags.BoolVar(&refresh, "refresh", true, "refresh")
+	cmdFlags.BoolVar(&refreshChanged, "refresh-changed", false, "refresh-changed")
+	cmdFlags.Var((*FlagStringSlice)(&c.Meta.forceReplace), "replace", "resource to replace")
 	c.addModuleDepthFlag(cmdFlags, &moduleDepth)
 	cmdFlags.StringVar(&outPath, "out", "", "path")
+	cmdFlags.StringVar(&graphOut, "graph-out", "", "path")
+	c.Meta.parallelismFlag(cmdFlags, DefaultParallelism)
 	cmdFlags.IntVar(
-		&c.Meta.parallelism, "parallelism", DefaultParallelism, "parallelism")
+		&c.Meta.refreshParallelism, "refresh-parallelism", 0, "refresh-parallelism")
+	c.Meta.providerRetryFlags(cmdFlags)
+	c.Meta.refreshRemoteStateFlag(cmdFlags)
 	cmdFlags.StringVar(&c.Meta.statePath, "state", "", "path")
 	cmdFlags.BoolVar(&detailed, "detailed-exitcode", false, "detailed-exitcode")
+	cmdFlags.StringVar(&compare, "compare", "", "base configuration")
+	cmdFlags.Var((*FlagStringSlice)(&explain), "explain", "resource to explain")
+	cmdFlags.BoolVar(&confidence, "confidence", false, "confidence")
+	cmdFlags.BoolVar(&driftOnly, "detect-drift-only", false, "detect-drift-only")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
 	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
 	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
+	cmdFlags.BoolVar(&c.Meta.stateLockSubtree, "lock-subtree", false, "lock subtree")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
@@ -51,6 +65,44 @@ func (c *PlanCommand) Run(args []string) int {
 		c.Ui.Error(err.Error())
 		return 1
 	}
+
+	if jsonOutput && !driftOnly && compare == "" {
+		c.Ui.Error("The -json flag can only be used with -detect-drift-only or -compare.")
+		return 1
+	}
+	if compare != "" && (plan != nil || destroy || driftOnly || outPath != "" ||
+		graphOut != "" || len(explain) > 0 || confidence) {
+		c.Ui.Error(
+			"The -compare flag can't be used with a saved plan, or with the\n" +
+				"-destroy, -detect-drift-only, -out, -graph-out, -explain, or\n" +
+				"-confidence flags.")
+		return 1
+	}
+	if driftOnly && (plan != nil || destroy || !refresh || outPath != "" ||
+		graphOut != "" || len(c.Meta.forceReplace) > 0 || len(explain) > 0 ||
+		confidence) {
+		c.Ui.Error(
+			"The -detect-drift-only flag can't be used with a saved plan, or\n" +
+				"with the -destroy, -refresh=false, -out, -graph-out, -replace,\n" +
+				"-explain, or -confidence flags.")
+		return 1
+	}
+	if refreshChanged && (!refresh || driftOnly || compare != "") {
+		c.Ui.Error(
+			"The -refresh-changed flag can't be used with the -refresh=false,\n" +
+				"-detect-drift-only, or -compare flags.")
+		return 1
+	}
+	for _, addr := range explain {
+		parsed, err := terraform.ParseResourceAddress(addr)
+		if err == nil && !parsed.HasResourceSpec() {
+			err = fmt.Errorf("not the address of a resource")
+		}
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Invalid -explain address %q: %s", addr, err))
+			return 1
+		}
+	}
 	if plan != nil {
 		// Disable refreshing no matter what since we only want to show the plan
 		refresh = false
@@ -73,6 +125,9 @@ func (c *PlanCommand) Run(args []string) int {
 	if mod != nil {
 		conf = mod.Config()
 	}
+	// Progress output would get mixed up with the JSON
+	c.Meta.quiet = jsonOutput
+
 	// Load the backend
 	b, err := c.Backend(&BackendOpts{
 		Config: conf,
@@ -83,13 +138,24 @@ func (c *PlanCommand) Run(args []string) int {
 		return 1
 	}
 
+	if driftOnly {
+		return c.detectDrift(b, mod, jsonOutput, detailed)
+	}
+	if compare != "" {
+		return c.comparePlans(b, mod, configPath, compare, refresh, jsonOutput, detailed)
+	}
+
 	// Build the operation
 	opReq := c.Operation()
 	opReq.Destroy = destroy
 	opReq.Module = mod
 	opReq.Plan = plan
 	opReq.PlanRefresh = refresh
+	opReq.PlanRefreshChanged = refreshChanged
 	opReq.PlanOutPath = outPath
+	opReq.PlanGraphOutPath = graphOut
+	opReq.PlanExplain = explain
+	opReq.PlanConfidence = confidence
 	opReq.Type = backend.OperationTypePlan
 
 	// Perform the operation
@@ -137,6 +203,18 @@ Usage: terraform plan [options] [DIR-OR-PLAN]
 
 Options:
 
+  -compare=base       Also plan the configuration in the directory or git ref
+                      "base" against the same state, and report only the
+                      changes that differ between the two plans: those
+                      caused by the differences between the configurations.
+                      A git ref is looked up in the repository that the
+                      configuration is in.
+
+  -confidence         After the plan, report which parts of it are speculative:
+                      the resources with values that are unknown until apply,
+                      and the changes that depend on those values, which may
+                      differ at apply.
+
   -destroy            If set, a plan will be generated to destroy all resources
                       managed by the given configuration and state.
 
@@ -144,14 +222,39 @@ Options:
                       will change the meaning of exit codes to:
                       0 - Succeeded, diff is empty (no changes)
                       1 - Errored
-                      2 - Succeeded, there is a diff
+                      2 - Succeeded, there is a diff (or drift, with
+                          -detect-drift-only, or a difference between the
+                          plans, with -compare)
+
+  -detect-drift-only  Instead of planning, refresh a copy of the state and
+                      report the resources whose real settings differ from
+                      the state, regardless of the configuration. The state
+                      isn't modified.
+
+  -explain=resource   Explain why the given resource is changed or replaced:
+                      which of its attributes change, and which changes to
+                      the resources that it depends on cause them. This
+                      flag can be used multiple times.
+
+  -graph-out=path     Write the apply graph of the plan to the given path as
+                      JSON, with the nodes, their operations and the
+                      dependencies between them, for tools such as custom
+                      schedulers. An ordering of the graph can be given to
+                      "apply" with -graph-order.
 
   -input=true         Ask for input for variables if not directly set.
 
+  -json               Output the -detect-drift-only or -compare report as
+                      JSON.
+
   -lock=true          Lock the state file when locking is supported.
 
   -lock-timeout=0s    Duration to retry a state lock.
 
+  -lock-subtree       Only lock the modules of the -target addresses, so
+                      that other modules of the state can be changed at the
+                      same time. The backend must support it.
+
   -module-depth=n     Specifies the depth of modules to show in the output.
                       This does not affect the plan itself, only the output
                       shown. By default, this is -1, which will expand all.
@@ -162,13 +265,55 @@ Options:
                       input to the "apply" command.
 
   -parallelism=n      Limit the number of concurrent operations. Defaults to 10.
+                      "auto" starts at 10 and adjusts the limit as transient
+                      provider errors, such as throttling, and system load
+                      come and go.
+
+  -provider-retries=3  Retry provider operations that fail with errors the
+                      provider marks as transient, such as throttling, up to
+                      this many times. 0 disables retries.
+
+  -provider-retry-max-wait=30s
+                      The longest wait between retries of a provider
+                      operation. The wait doubles with each retry.
+
+  -refresh-changed    Only refresh the resources whose configuration doesn't
+                      match their state, the resources that depend on them,
+                      and data sources. This is much faster for large
+                      configurations that are mostly unchanged, but changes
+                      made to other resources outside of Terraform aren't
+                      seen.
+
+  -refresh-parallelism=n
+                      Limit the number of concurrent refreshes. Defaults to
+                      five times -parallelism.
+
+  -refresh-remote-state
+                      Read the remote states of the terraform_remote_state
+                      data sources rather than the outputs they've cached.
 
   -refresh=true       Update state prior to checking for differences.
 
+  -replace=resource   Resource to replace. The resource will be planned for
+                      destruction and recreation even if its configuration
+                      hasn't changed, as if it were tainted. The address must
+                      name a single instance, such as aws_instance.web[0] for
+                      a resource with count, and it's an error if it doesn't
+                      match one in the state. This flag can be used multiple
+                      times.
+
   -state=statefile    Path to a Terraform state file to use to look
                       up Terraform-managed resources. By default it will
                       use the state "terraform.tfstate" if it exists.
 
+  -strict=code        Treat warnings with the given code as errors, in
+                      addition to those listed in the strict block of the
+                      configuration. This flag can be set multiple times.
+
+  -suppress-warning=code
+                      Don't show warnings with the given code. This flag
+                      can be set multiple times.
+
   -target=resource    Resource to target. Operation will be limited to this
                       resource and its dependencies. This flag can be used
                       multiple times.
@@ -179,6 +324,16 @@ Options:
   -var-file=foo       Set variables in the Terraform configuration from
                       a file. If "terraform.tfvars" is present, it will be
                       automatically loaded if this flag is not specified.
+
+  -var-dir=vars       Load layered variables from the given directory:
+                      "common.tfvars", then each -var-layer, then the file
+                      named after the current environment. -var and -var-file
+                      override layered variables.
+
+  -var-layer=name     Load "name.tfvars" from the -var-dir directory,
+                      which defaults to "vars", as a layer of variables.
+                      This flag can be set multiple times, and later
+                      layers override earlier ones.
 `
 	return strings.TrimSpace(helpText)
 }
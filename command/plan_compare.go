@@ -0,0 +1,500 @@
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-getter"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/command/format"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/colorstring"
+)
+
+// planComparison is the comparison of the plans of two configurations
+// against the same state. It is the JSON format of the comparison report.
+type planComparison struct {
+	// Changes are the resources whose planned changes differ between the
+	// plans, sorted by address.
+	Changes []*comparedChange `json:"changes"`
+
+	// Unchanged is the number of resources with the same planned changes
+	// in both plans.
+	Unchanged int `json:"unchanged"`
+
+	// Targets is the report of the resources that the targets leave out,
+	// if the plans are targeted.
+	Targets *terraform.TargetReport `json:"targets,omitempty"`
+}
+
+// comparedChange is a resource whose planned change differs between the
+// plan of the base configuration and the plan of the configuration.
+type comparedChange struct {
+	Address string `json:"address"`
+
+	// Base and Head are the planned actions: "none", "create", "update",
+	// "destroy" or "replace".
+	Base string `json:"base"`
+	Head string `json:"head"`
+
+	// Attributes are the attributes whose planned values differ.
+	Attributes map[string]*comparedAttribute `json:"attributes,omitempty"`
+}
+
+// comparedAttribute is the planned value of an attribute in each plan. An
+// empty value means the attribute isn't changed.
+type comparedAttribute struct {
+	Base string `json:"base"`
+	Head string `json:"head"`
+}
+
+// comparePlans refreshes the state once, plans both the configuration and
+// the base configuration against it, and reports the planned changes that
+// differ, which are those caused by the differences between the
+// configurations. It returns the exit code for the command.
+func (c *PlanCommand) comparePlans(
+	b backend.Enhanced, mod *module.Tree, configPath, base string,
+	refresh, jsonOutput, detailed bool) int {
+	local, ok := b.(backend.Local)
+	if !ok {
+		c.Ui.Error(ErrUnsupportedLocalOp)
+		return 1
+	}
+
+	baseMod, cleanup, err := c.compareModule(configPath, base)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load the configuration to compare to: %s", err))
+		return 1
+	}
+	defer cleanup()
+
+	opReq := c.Operation()
+	opReq.Module = mod
+
+	ctx, _, err := local.Context(opReq)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if err := ctx.Input(c.InputMode()); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error configuring: %s", err))
+		return 1
+	}
+
+	if refresh {
+		if _, err := ctx.Refresh(); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error refreshing state: %s", err))
+			return 1
+		}
+	}
+
+	headPlan, err := ctx.Plan()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error running plan: %s", err))
+		return 1
+	}
+
+	// The base configuration is planned against the same refreshed state,
+	// so that the only differences between the plans are those caused by
+	// the configuration.
+	opts := c.contextOpts()
+	opts.Module = baseMod
+	opts.State = ctx.State()
+	baseCtx, err := terraform.NewContext(opts)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if err := baseCtx.Input(c.InputMode()); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error configuring: %s", err))
+		return 1
+	}
+
+	basePlan, err := baseCtx.Plan()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error running plan of %s: %s", base, err))
+		return 1
+	}
+
+	comparison := comparePlanDiffs(basePlan.Diff, headPlan.Diff)
+	comparison.Targets, err = ctx.TargetReport()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reporting targeted resources: %s", err))
+		return 1
+	}
+
+	if jsonOutput {
+		if comparison.Changes == nil {
+			comparison.Changes = []*comparedChange{}
+		}
+
+		data, err := json.MarshalIndent(comparison, "", "    ")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error encoding plan comparison: %s", err))
+			return 1
+		}
+		c.Ui.Output(string(data))
+	} else {
+		if comparison.Targets != nil {
+			c.Ui.Output(format.TargetReport(comparison.Targets, c.Colorize()) + "\n")
+		}
+		c.Ui.Output(formatPlanComparison(c.Colorize(), base, comparison))
+	}
+
+	if detailed && len(comparison.Changes) > 0 {
+		return 2
+	}
+
+	return 0
+}
+
+// compareModule loads the base configuration to compare the configuration
+// in configPath to. base is either a directory, or a git ref of the
+// repository that configPath is in. Modules are fetched into a temporary
+// directory, which the returned function removes.
+func (c *PlanCommand) compareModule(configPath, base string) (*module.Tree, func(), error) {
+	tmp, err := ioutil.TempDir("", "terraform-compare")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+
+	dir := base
+	if info, err := os.Stat(base); err != nil || !info.IsDir() {
+		dir, err = gitExtract(configPath, base, filepath.Join(tmp, "config"))
+		if err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf(
+				"%q is neither a directory nor a git ref: %s", base, err)
+		}
+	}
+
+	mod, err := module.NewTreeModuleEnv("", dir, c.Env())
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	storage := &getter.FolderStorage{StorageDir: filepath.Join(tmp, "modules")}
+	if err := mod.Load(storage, module.GetModeGet); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("Error loading modules: %s", err)
+	}
+
+	return mod, cleanup, nil
+}
+
+// gitExtract extracts the git repository that dir is in, as of ref, into
+// dst, and returns the path within dst that corresponds to dir.
+func gitExtract(dir, ref, dst string) (string, error) {
+	out, err := exec.Command(
+		"git", "-C", dir, "rev-parse", "--show-toplevel", "--show-prefix").Output()
+	if err != nil {
+		return "", gitError(err)
+	}
+
+	// The whole repository is extracted, since the configuration can use
+	// modules from outside its directory.
+	lines := strings.SplitN(string(out), "\n", 2)
+	if len(lines) != 2 {
+		return "", fmt.Errorf("unexpected output of git rev-parse: %q", out)
+	}
+	top, prefix := lines[0], strings.TrimSpace(lines[1])
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", top, "archive", "--format=tar", ref)
+	cmd.Stderr = &stderr
+	archive, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	extractErr := untar(archive, dst)
+	io.Copy(ioutil.Discard, archive)
+	if err := cmd.Wait(); err != nil {
+		return "", fmt.Errorf("%s", strings.TrimSpace(stderr.String()))
+	}
+	if extractErr != nil {
+		return "", errwrap.Wrapf("Error extracting git archive: {{err}}", extractErr)
+	}
+
+	return filepath.Join(dst, filepath.FromSlash(prefix)), nil
+}
+
+// gitError returns the error output of a failed git command as an error.
+func gitError(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+	}
+
+	return err
+}
+
+// untar extracts the tar archive read from r into the directory dst.
+func untar(r io.Reader, dst string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dst, filepath.FromSlash(hdr.Name))
+		if !strings.HasPrefix(path, filepath.Clean(dst)+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)|0600)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, path); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// comparePlanDiffs compares the planned changes of each resource in the
+// diffs of the base plan and the plan of the configuration.
+func comparePlanDiffs(base, head *terraform.Diff) *planComparison {
+	baseDiffs := instanceDiffs(base)
+	headDiffs := instanceDiffs(head)
+
+	addrs := make(map[string]bool)
+	for addr := range baseDiffs {
+		addrs[addr] = true
+	}
+	for addr := range headDiffs {
+		addrs[addr] = true
+	}
+
+	result := &planComparison{}
+	for addr := range addrs {
+		b, h := baseDiffs[addr], headDiffs[addr]
+		change := &comparedChange{
+			Address:    addr,
+			Base:       diffAction(b),
+			Head:       diffAction(h),
+			Attributes: comparedAttributes(b, h),
+		}
+
+		if change.Base == change.Head && len(change.Attributes) == 0 {
+			if change.Base != "none" {
+				result.Unchanged++
+			}
+			continue
+		}
+
+		result.Changes = append(result.Changes, change)
+	}
+
+	sort.Slice(result.Changes, func(i, j int) bool {
+		return result.Changes[i].Address < result.Changes[j].Address
+	})
+
+	return result
+}
+
+// instanceDiffs returns the diffs of the resources in d by address.
+func instanceDiffs(d *terraform.Diff) map[string]*terraform.InstanceDiff {
+	result := make(map[string]*terraform.InstanceDiff)
+	if d == nil {
+		return result
+	}
+
+	for _, m := range d.Modules {
+		for k, rd := range m.Resources {
+			info := &terraform.InstanceInfo{Id: k, ModulePath: m.Path}
+			result[info.HumanId()] = rd
+		}
+	}
+
+	return result
+}
+
+// diffAction describes the change planned by d.
+func diffAction(d *terraform.InstanceDiff) string {
+	switch d.ChangeType() {
+	case terraform.DiffCreate:
+		return "create"
+	case terraform.DiffUpdate:
+		return "update"
+	case terraform.DiffDestroy:
+		return "destroy"
+	case terraform.DiffDestroyCreate:
+		return "replace"
+	default:
+		return "none"
+	}
+}
+
+// comparedAttributes returns the attributes whose planned values differ
+// between the diffs.
+func comparedAttributes(base, head *terraform.InstanceDiff) map[string]*comparedAttribute {
+	var baseAttrs, headAttrs map[string]*terraform.ResourceAttrDiff
+	if base != nil {
+		baseAttrs = base.CopyAttributes()
+	}
+	if head != nil {
+		headAttrs = head.CopyAttributes()
+	}
+
+	result := make(map[string]*comparedAttribute)
+	for k, ad := range baseAttrs {
+		if !attrDiffEqual(ad, headAttrs[k]) {
+			result[k] = &comparedAttribute{
+				Base: plannedValue(ad),
+				Head: plannedValue(headAttrs[k]),
+			}
+		}
+	}
+	for k, ad := range headAttrs {
+		if _, ok := baseAttrs[k]; !ok {
+			result[k] = &comparedAttribute{Head: plannedValue(ad)}
+		}
+	}
+
+	return result
+}
+
+func attrDiffEqual(a, b *terraform.ResourceAttrDiff) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.New == b.New &&
+		a.NewComputed == b.NewComputed &&
+		a.NewRemoved == b.NewRemoved &&
+		a.RequiresNew == b.RequiresNew &&
+		reflect.DeepEqual(a.NewExtra, b.NewExtra)
+}
+
+// plannedValue describes the value planned for an attribute.
+func plannedValue(ad *terraform.ResourceAttrDiff) string {
+	var v string
+	switch {
+	case ad == nil:
+		return ""
+	case ad.NewRemoved:
+		v = "<removed>"
+	case ad.NewComputed:
+		v = "<computed>"
+	case ad.Sensitive:
+		v = "<sensitive>"
+	default:
+		v = fmt.Sprintf("%q", ad.New)
+	}
+
+	if ad.RequiresNew {
+		v += " (forces new resource)"
+	}
+
+	return v
+}
+
+// formatPlanComparison formats the comparison report for humans.
+func formatPlanComparison(color *colorstring.Colorize, base string, comparison *planComparison) string {
+	var buf bytes.Buffer
+	if len(comparison.Changes) == 0 {
+		buf.WriteString(color.Color(fmt.Sprintf(
+			"[reset][green]The configuration plans the same changes as %s.[reset]", base)))
+	} else {
+		buf.WriteString(color.Color(fmt.Sprintf(
+			"[reset][bold]%d resource(s) are planned differently than by %s:[reset]\n\n",
+			len(comparison.Changes), base)))
+	}
+
+	// Only the fixed parts of the report are colorized, since values could
+	// contain anything.
+	for _, change := range comparison.Changes {
+		switch change.Head {
+		case "create":
+			buf.WriteString(color.Color("[green]+ "))
+		case "destroy":
+			buf.WriteString(color.Color("[red]- "))
+		case "replace":
+			buf.WriteString(color.Color("[yellow]-/+ "))
+		default:
+			buf.WriteString(color.Color("[yellow]~ "))
+		}
+		buf.WriteString(change.Address)
+		buf.WriteString(color.Color(fmt.Sprintf(
+			"[reset] (%s => %s)\n", change.Base, change.Head)))
+
+		keyLen := 0
+		keys := make([]string, 0, len(change.Attributes))
+		for k := range change.Attributes {
+			keys = append(keys, k)
+			if len(k) > keyLen {
+				keyLen = len(k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			attr := change.Attributes[k]
+			buf.WriteString(fmt.Sprintf(
+				"    %s:%s %s => %s\n",
+				k, strings.Repeat(" ", keyLen-len(k)),
+				unchangedIfEmpty(attr.Base), unchangedIfEmpty(attr.Head)))
+		}
+	}
+
+	if comparison.Unchanged > 0 {
+		if len(comparison.Changes) > 0 {
+			buf.WriteString("\n")
+		} else {
+			buf.WriteString("\n\n")
+		}
+		buf.WriteString(fmt.Sprintf(
+			"%d other resource(s) have the same planned changes in both plans.",
+			comparison.Unchanged))
+	}
+
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+func unchangedIfEmpty(v string) string {
+	if v == "" {
+		return "(no change)"
+	}
+
+	return v
+}
@@ -0,0 +1,187 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/colorstring"
+)
+
+// driftedResource is a resource whose real settings differ from what is
+// recorded in the state. It is the JSON format of the drift report.
+type driftedResource struct {
+	Address string `json:"address"`
+
+	// Deleted is true if the resource no longer exists.
+	Deleted bool `json:"deleted"`
+
+	// Attributes are the names of the attributes that differ, sorted, if
+	// the resource still exists. Only the names are reported, since the
+	// values could be secrets and the report often ends up in CI logs.
+	Attributes []string `json:"attributes,omitempty"`
+}
+
+// detectDrift refreshes a copy of the state, compares it to the state, and
+// reports the resources that have drifted without planning any changes or
+// modifying the state. It returns the exit code for the command.
+func (c *PlanCommand) detectDrift(
+	b backend.Enhanced, mod *module.Tree, jsonOutput, detailed bool) int {
+	local, ok := b.(backend.Local)
+	if !ok {
+		c.Ui.Error(ErrUnsupportedLocalOp)
+		return 1
+	}
+
+	opReq := c.Operation()
+	opReq.Module = mod
+
+	ctx, _, err := local.Context(opReq)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if err := ctx.Input(c.InputMode()); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error configuring: %s", err))
+		return 1
+	}
+
+	prior := ctx.State()
+	refreshed, err := ctx.Refresh()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error refreshing state: %s", err))
+		return 1
+	}
+
+	drifted := detectDrift(prior, refreshed)
+	if jsonOutput {
+		if drifted == nil {
+			drifted = []*driftedResource{}
+		}
+
+		data, err := json.MarshalIndent(drifted, "", "    ")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error encoding drift report: %s", err))
+			return 1
+		}
+		c.Ui.Output(string(data))
+	} else {
+		c.Ui.Output(formatDrift(c.Colorize(), drifted))
+	}
+
+	if detailed && len(drifted) > 0 {
+		return 2
+	}
+
+	return 0
+}
+
+// detectDrift compares the managed resources in the prior state with the
+// same state after refreshing, and returns the resources that differ,
+// sorted by address.
+func detectDrift(prior, refreshed *terraform.State) []*driftedResource {
+	if prior == nil {
+		return nil
+	}
+
+	var result []*driftedResource
+	for _, m := range prior.Modules {
+		var rm *terraform.ModuleState
+		if refreshed != nil {
+			rm = refreshed.ModuleByPath(m.Path)
+		}
+
+		for k, rs := range m.Resources {
+			// Data sources are read again on every run, so they can't drift
+			if rs.Primary == nil || strings.HasPrefix(k, "data.") {
+				continue
+			}
+
+			info := &terraform.InstanceInfo{Id: k, ModulePath: m.Path}
+			d := &driftedResource{Address: info.HumanId()}
+
+			var actual *terraform.InstanceState
+			if rm != nil {
+				if rrs, ok := rm.Resources[k]; ok {
+					actual = rrs.Primary
+				}
+			}
+			if actual == nil || actual.ID == "" {
+				d.Deleted = true
+				result = append(result, d)
+				continue
+			}
+
+			d.Attributes = driftedAttributes(rs.Primary.Attributes, actual.Attributes)
+			if len(d.Attributes) > 0 {
+				result = append(result, d)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Address < result[j].Address
+	})
+
+	return result
+}
+
+// driftedAttributes returns the sorted names of the attributes whose values
+// differ between the prior and the actual attributes.
+func driftedAttributes(prior, actual map[string]string) []string {
+	var result []string
+	for k, v := range prior {
+		if av, ok := actual[k]; !ok || av != v {
+			result = append(result, k)
+		}
+	}
+	for k := range actual {
+		if _, ok := prior[k]; !ok {
+			result = append(result, k)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// formatDrift formats the drift report for humans.
+func formatDrift(color *colorstring.Colorize, drifted []*driftedResource) string {
+	if len(drifted) == 0 {
+		return color.Color(
+			"[reset][green]No drift detected. The state matches the real infrastructure.")
+	}
+
+	// Only the fixed parts of the report are colorized, since addresses and
+	// attribute names could contain anything.
+	var buf bytes.Buffer
+	buf.WriteString(color.Color(fmt.Sprintf(
+		"[reset][bold]Drift detected in %d resource(s):[reset]\n\n", len(drifted))))
+	for _, d := range drifted {
+		if d.Deleted {
+			buf.WriteString(color.Color("[red]- "))
+			buf.WriteString(d.Address)
+			buf.WriteString(color.Color(" (deleted)[reset]\n"))
+			continue
+		}
+
+		buf.WriteString(color.Color("[yellow]~ "))
+		buf.WriteString(d.Address)
+		buf.WriteString(color.Color("[reset]\n"))
+
+		for _, k := range d.Attributes {
+			buf.WriteString(fmt.Sprintf("    %s\n", k))
+		}
+	}
+
+	buf.WriteString("\nThe state hasn't been modified. Run \"terraform refresh\" to\n")
+	buf.WriteString("accept these changes into the state, or \"terraform apply\" to\n")
+	buf.WriteString("change the infrastructure back to match the configuration.")
+
+	return buf.String()
+}
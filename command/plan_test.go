@@ -2,8 +2,10 @@ package command
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
 	"strings"
@@ -40,22 +42,19 @@ func TestPlan(t *testing.T) {
 }
 
 func TestPlan_lockedState(t *testing.T) {
-	cwd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("err: %s", err)
-	}
+	// The state is locked in a copy of the fixture, so that a state file
+	// left behind by the locker isn't read by the other tests.
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("plan"), td)
+	defer os.RemoveAll(td)
 
-	testPath := testFixturePath("plan")
-	unlock, err := testLockState("./testdata", filepath.Join(testPath, DefaultStateFilename))
+	unlock, err := testLockState("./testdata", filepath.Join(td, DefaultStateFilename))
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer unlock()
 
-	if err := os.Chdir(testPath); err != nil {
-		t.Fatalf("err: %s", err)
-	}
-	defer os.Chdir(cwd)
+	defer testChdir(t, td)()
 
 	p := testProvider()
 	ui := new(cli.MockUi)
@@ -430,6 +429,68 @@ func TestPlan_refresh(t *testing.T) {
 	}
 }
 
+func TestPlan_refreshChanged(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	statePath := testStateFile(t, testState())
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-refresh-changed",
+		"-state", statePath,
+		testFixturePath("plan"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	// The provider doesn't return a diff, so the configuration matches the
+	// state and nothing is refreshed.
+	if p.RefreshCalled {
+		t.Fatal("refresh should not be called")
+	}
+
+	p.DiffReturn = &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"ami": &terraform.ResourceAttrDiff{Old: "foo", New: "bar"},
+		},
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !p.RefreshCalled {
+		t.Fatal("refresh should be called")
+	}
+}
+
+func TestPlan_refreshChangedRefreshFalse(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-refresh-changed",
+		"-refresh=false",
+		testFixturePath("plan"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+}
+
 func TestPlan_state(t *testing.T) {
 	// Write out some prior state
 	tf, err := ioutil.TempFile("", "tf")
@@ -471,6 +532,625 @@ func TestPlan_state(t *testing.T) {
 	}
 }
 
+func TestPlan_detectDriftOnly(t *testing.T) {
+	originalState := testState()
+	originalState.RootModule().Resources["test_instance.foo"].Primary.Attributes = map[string]string{
+		"id":  "bar",
+		"ami": "foo",
+	}
+	originalState.RootModule().Resources["test_instance.gone"] = &terraform.ResourceState{
+		Type: "test_instance",
+		Primary: &terraform.InstanceState{
+			ID: "gone",
+		},
+	}
+	statePath := testStateFile(t, originalState)
+
+	p := testProvider()
+	p.RefreshFn = func(
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState) (*terraform.InstanceState, error) {
+		if s.ID == "gone" {
+			return nil, nil
+		}
+
+		s = s.DeepCopy()
+		s.Attributes["ami"] = "changed"
+		return s, nil
+	}
+
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-detect-drift-only",
+		"-detailed-exitcode",
+		"-no-color",
+		"-state", statePath,
+		testFixturePath("plan"),
+	}
+	if code := c.Run(args); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if p.DiffCalled {
+		t.Fatal("diff should not be called")
+	}
+
+	actual := ui.OutputWriter.String()
+	for _, expected := range []string{
+		"Drift detected in 2 resource(s)",
+		`~ test_instance.foo`,
+		"    ami\n",
+		`- test_instance.gone (deleted)`,
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Fatalf("expected %q in output:\n\n%s", expected, actual)
+		}
+	}
+
+	// The state must not be modified
+	testStateOutput(t, statePath, originalState.String())
+}
+
+func TestPlan_detectDriftOnlyJSON(t *testing.T) {
+	statePath := testStateFile(t, testState())
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-detect-drift-only",
+		"-detailed-exitcode",
+		"-json",
+		"-state", statePath,
+		testFixturePath("plan"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if actual := strings.TrimSpace(ui.OutputWriter.String()); actual != "[]" {
+		t.Fatalf("bad: %s", actual)
+	}
+}
+
+func TestPlan_detectDriftOnlyValues(t *testing.T) {
+	originalState := testState()
+	originalState.RootModule().Resources["test_instance.foo"].Primary.Attributes = map[string]string{
+		"id":       "bar",
+		"password": "hunter2",
+	}
+	statePath := testStateFile(t, originalState)
+
+	p := testProvider()
+	p.RefreshFn = func(
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState) (*terraform.InstanceState, error) {
+		s = s.DeepCopy()
+		s.Attributes["password"] = "correcthorse"
+		return s, nil
+	}
+
+	for _, jsonOutput := range []bool{false, true} {
+		ui := new(cli.MockUi)
+		c := &PlanCommand{
+			Meta: Meta{
+				testingOverrides: metaOverridesForProvider(p),
+				Ui:               ui,
+			},
+		}
+
+		args := []string{
+			"-detect-drift-only",
+			"-no-color",
+			"-state", statePath,
+		}
+		if jsonOutput {
+			args = append(args, "-json")
+		}
+		args = append(args, testFixturePath("plan"))
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+		}
+
+		actual := ui.OutputWriter.String()
+		if !strings.Contains(actual, "password") {
+			t.Fatalf("expected the attribute name in output:\n\n%s", actual)
+		}
+		for _, v := range []string{"hunter2", "correcthorse"} {
+			if strings.Contains(actual, v) {
+				t.Fatalf("value %q should not be in output:\n\n%s", v, actual)
+			}
+		}
+	}
+}
+
+func TestPlan_jsonWithoutDrift(t *testing.T) {
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-json",
+		testFixturePath("plan"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+}
+
+func TestPlan_compare(t *testing.T) {
+	statePath := testPlanCompareState(t)
+
+	p := testPlanCompareProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-compare", testFixturePath("plan-compare/base"),
+		"-detailed-exitcode",
+		"-no-color",
+		"-state", statePath,
+		testFixturePath("plan-compare/head"),
+	}
+	if code := c.Run(args); code != 2 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	actual := ui.OutputWriter.String()
+	for _, expected := range []string{
+		"2 resource(s) are planned differently",
+		"~ test_instance.foo (none => update)",
+		`ami: (no change) => "changed"`,
+		"+ test_instance.new (none => create)",
+		`ami: (no change) => "new"`,
+		"1 other resource(s) have the same planned changes",
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Fatalf("expected %q in output:\n\n%s", expected, actual)
+		}
+	}
+	if strings.Contains(actual, "test_instance.baz") {
+		t.Fatalf("unchanged resource in output:\n\n%s", actual)
+	}
+}
+
+func TestPlan_compareJSON(t *testing.T) {
+	statePath := testPlanCompareState(t)
+
+	p := testPlanCompareProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-compare", testFixturePath("plan-compare/head"),
+		"-detailed-exitcode",
+		"-json",
+		"-state", statePath,
+		testFixturePath("plan-compare/head"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var actual planComparison
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &actual); err != nil {
+		t.Fatalf("err: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	expected := planComparison{Changes: []*comparedChange{}, Unchanged: 3}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestPlan_compareJSONTargets(t *testing.T) {
+	statePath := testPlanCompareState(t)
+
+	p := testPlanCompareProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-compare", testFixturePath("plan-compare/head"),
+		"-json",
+		"-target", "test_instance.foo",
+		"-state", statePath,
+		testFixturePath("plan-compare/head"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var actual planComparison
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &actual); err != nil {
+		t.Fatalf("err: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	expected := &terraform.TargetReport{
+		Targets:      []string{"test_instance.foo"},
+		Targeted:     []string{"test_instance.foo"},
+		Dependencies: []string{},
+		Excluded:     []string{"test_instance.baz", "test_instance.new"},
+	}
+	if !reflect.DeepEqual(actual.Targets, expected) {
+		t.Fatalf("bad: %#v", actual.Targets)
+	}
+}
+
+func TestPlan_compareGitRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	td := testTempDir(t)
+	defer os.RemoveAll(td)
+
+	git := func(args ...string) {
+		args = append([]string{
+			"-C", td, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		}, args...)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s\n\n%s", args, err, out)
+		}
+	}
+
+	configDir := filepath.Join(td, "config")
+	copyFixture := func(name string) {
+		if err := os.MkdirAll(configDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(testFixturePath(name + "/main.tf"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(configDir, "main.tf"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	copyFixture("plan-compare/base")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+	copyFixture("plan-compare/head")
+
+	statePath := testPlanCompareState(t)
+
+	p := testPlanCompareProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-compare", "HEAD",
+		"-json",
+		"-state", statePath,
+		configDir,
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var actual planComparison
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &actual); err != nil {
+		t.Fatalf("err: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	var addrs []string
+	for _, change := range actual.Changes {
+		addrs = append(addrs, change.Address)
+	}
+	if expected := []string{"test_instance.foo", "test_instance.new"}; !reflect.DeepEqual(addrs, expected) {
+		t.Fatalf("bad: %#v", addrs)
+	}
+}
+
+func TestPlan_compareInvalidFlags(t *testing.T) {
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-compare", testFixturePath("plan"),
+		"-destroy",
+		testFixturePath("plan"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "-compare flag can't be used") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+// testPlanCompareState writes a state with test_instance.foo, as created by
+// the plan-compare/base fixture, and returns its path.
+func testPlanCompareState(t *testing.T) string {
+	state := testState()
+	state.RootModule().Resources["test_instance.foo"].Primary.Attributes = map[string]string{
+		"id":  "bar",
+		"ami": "bar",
+	}
+
+	return testStateFile(t, state)
+}
+
+// testPlanCompareProvider returns a provider that plans to set ami to its
+// configured value.
+func testPlanCompareProvider() *terraform.MockResourceProvider {
+	p := testProvider()
+	p.DiffFn = func(
+		info *terraform.InstanceInfo,
+		s *terraform.InstanceState,
+		c *terraform.ResourceConfig) (*terraform.InstanceDiff, error) {
+		ami, _ := c.Get("ami")
+		if s == nil {
+			return &terraform.InstanceDiff{
+				Attributes: map[string]*terraform.ResourceAttrDiff{
+					"ami": &terraform.ResourceAttrDiff{
+						New:         ami.(string),
+						RequiresNew: true,
+					},
+				},
+			}, nil
+		}
+
+		if s.Attributes["ami"] == ami {
+			return &terraform.InstanceDiff{}, nil
+		}
+
+		return &terraform.InstanceDiff{
+			Attributes: map[string]*terraform.ResourceAttrDiff{
+				"ami": &terraform.ResourceAttrDiff{
+					Old: s.Attributes["ami"],
+					New: ami.(string),
+				},
+			},
+		}, nil
+	}
+
+	return p
+}
+
+func TestPlan_readOnly(t *testing.T) {
+	old := os.Getenv(ReadOnlyEnvVar)
+	defer os.Setenv(ReadOnlyEnvVar, old)
+	os.Setenv(ReadOnlyEnvVar, "1")
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		testFixturePath("plan"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+}
+
+func TestPlan_replace(t *testing.T) {
+	// Write out some prior state
+	tf, err := ioutil.TempFile("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	statePath := tf.Name()
+	defer os.Remove(tf.Name())
+
+	originalState := testState()
+	err = terraform.WriteState(originalState, tf)
+	tf.Close()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"-replace", "test_instance.foo",
+		testFixturePath("plan"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	// Verify that the provider was asked to diff as if tainted
+	if !p.DiffState.Tainted {
+		t.Fatalf("bad: %#v", p.DiffState)
+	}
+
+	// Verify that the state on disk wasn't tainted
+	f, err := os.Open(statePath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+
+	state, err := terraform.ReadState(f)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if state.RootModule().Resources["test_instance.foo"].Primary.Tainted {
+		t.Fatal("state should not be tainted")
+	}
+}
+
+func TestPlan_replaceInvalid(t *testing.T) {
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-replace", "not an address",
+		testFixturePath("plan"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+}
+
+func TestPlan_explain(t *testing.T) {
+	p := testProvider()
+	p.DiffReturn = &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"ami": &terraform.ResourceAttrDiff{
+				New: "bar",
+			},
+		},
+	}
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-no-color",
+		"-explain", "test_instance.foo",
+		"-explain", "test_instance.bar",
+		testFixturePath("plan"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	expected := []string{
+		"test_instance.foo will be created.",
+		`ami: "" => "bar"`,
+		"test_instance.bar has no changes in this plan.",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Fatalf("output should contain %q:\n\n%s", e, output)
+		}
+	}
+}
+
+func TestPlan_explainInvalid(t *testing.T) {
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-explain", "module.foo",
+		testFixturePath("plan"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if p.DiffCalled {
+		t.Fatal("plan should not run with an invalid address")
+	}
+}
+
+func TestPlan_confidence(t *testing.T) {
+	p := testProvider()
+	p.DiffReturn = &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"ami": &terraform.ResourceAttrDiff{
+				New: "bar",
+			},
+			"id": &terraform.ResourceAttrDiff{
+				NewComputed: true,
+				RequiresNew: true,
+			},
+		},
+	}
+	ui := new(cli.MockUi)
+	c := &PlanCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-no-color",
+		"-confidence",
+		testFixturePath("plan"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	expected := []string{
+		"Plan confidence: 1 of 1 resources with changes has values that are unknown until apply.",
+		"test_instance.foo (created): 1 unknown value",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Fatalf("output should contain %q:\n\n%s", e, output)
+		}
+	}
+}
+
 func TestPlan_stateDefault(t *testing.T) {
 	originalState := testState()
 
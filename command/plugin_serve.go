@@ -0,0 +1,249 @@
+package command
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	plugin "github.com/hashicorp/go-plugin"
+	tfplugin "github.com/hashicorp/terraform/plugin"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// PluginServeFile is the name of the file in the data directory that lists
+// the provider processes started by "terraform plugin-serve".
+const PluginServeFile = "plugin-serve.json"
+
+// pluginServeDialTimeout is how long to wait to connect to a served plugin
+// before starting a new process for it instead.
+const pluginServeDialTimeout = time.Second
+
+// PluginServeCommand is a Command implementation that starts the provider
+// plugins of a configuration and keeps them running, so that the commands
+// run in the same working directory connect to them instead of starting
+// their own.
+type PluginServeCommand struct {
+	Meta
+
+	ShutdownCh <-chan struct{}
+}
+
+// pluginServeEntry is a provider process in the plugin serve file. The file
+// maps the SHA256 digest of each plugin executable to its entry, so that
+// a process is only reused for exactly the same plugin.
+type pluginServeEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Network string `json:"network"`
+	Addr    string `json:"addr"`
+	Pid     int    `json:"pid"`
+}
+
+func (c *PluginServeCommand) Run(args []string) int {
+	args = c.Meta.process(args, false)
+
+	cmdFlags := c.Meta.flagSet("plugin-serve")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	configPath, err := ModulePath(cmdFlags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	mod, err := c.Module(configPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load root config module: %s", err))
+		return 1
+	}
+	if err := mod.Validate(); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	path := filepath.Join(c.DataDir(), PluginServeFile)
+	if len(readPluginServe(path)) > 0 {
+		c.Ui.Error(fmt.Sprintf(
+			"The providers of this working directory are already being served.\n"+
+				"Stop the other \"terraform plugin-serve\", or remove %s\n"+
+				"if it isn't running.", path))
+		return 1
+	}
+
+	available := c.providerPluginSet()
+	reqd := terraform.ModuleTreeDependencies(mod, nil).AllPluginRequirements()
+	c.removeDevProviders(reqd)
+	if missing := c.missingPlugins(available, reqd); len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		c.Ui.Error(fmt.Sprintf(
+			"No suitable version is installed for the providers: %s.\n"+
+				"Run \"terraform init\" to install them.",
+			strings.Join(names, ", ")))
+		return 1
+	}
+
+	chosen := choosePlugins(available, reqd)
+	names := make([]string, 0, len(chosen))
+	for name := range chosen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := make(map[string]*pluginServeEntry)
+	for _, name := range names {
+		meta := chosen[name]
+		digest, err := meta.SHA256()
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading provider.%s: %s", name, err))
+			return 1
+		}
+
+		// The clients are managed, so the processes are stopped along
+		// with this command.
+		client := tfplugin.Client(meta)
+		if _, err := client.Client(); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error starting provider.%s: %s", name, err))
+			return 1
+		}
+
+		rc := client.ReattachConfig()
+		entries[hex.EncodeToString(digest)] = &pluginServeEntry{
+			Name:    name,
+			Version: string(meta.Version),
+			Network: rc.Addr.Network(),
+			Addr:    rc.Addr.String(),
+			Pid:     rc.Pid,
+		}
+		c.Ui.Output(fmt.Sprintf("- provider.%s %s (pid %d)", name, meta.Version, rc.Pid))
+	}
+
+	if err := writePluginServe(path, entries); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error writing %s: %s", path, err))
+		return 1
+	}
+	defer os.Remove(path)
+
+	c.Ui.Output(c.Colorize().Color(strings.TrimSpace(pluginServeRunning)))
+
+	<-c.ShutdownCh
+	c.Ui.Output("Stopping the providers...")
+	return 0
+}
+
+// readPluginServe returns the reattach configurations of the provider
+// processes in the plugin serve file at path, by the digest of their
+// executable. Processes that can't be connected to are left out, so that
+// a stale file only means that the providers are started as usual.
+func readPluginServe(path string) map[string]*plugin.ReattachConfig {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[WARN] Error reading %s: %s", path, err)
+		}
+		return nil
+	}
+
+	var entries map[string]*pluginServeEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Printf("[WARN] Error parsing %s: %s", path, err)
+		return nil
+	}
+
+	result := make(map[string]*plugin.ReattachConfig)
+	for digest, e := range entries {
+		var addr net.Addr
+		switch e.Network {
+		case "unix":
+			addr = &net.UnixAddr{Net: e.Network, Name: e.Addr}
+		case "tcp":
+			addr, err = net.ResolveTCPAddr(e.Network, e.Addr)
+		default:
+			err = fmt.Errorf("unsupported network %q", e.Network)
+		}
+		if err != nil {
+			log.Printf("[WARN] Ignoring served provider.%s: %s", e.Name, err)
+			continue
+		}
+
+		// The plugin client kills the process if it can't connect to it,
+		// which mustn't happen to an unrelated process that reused the
+		// pid, so check that it's still there first.
+		conn, err := net.DialTimeout(addr.Network(), addr.String(), pluginServeDialTimeout)
+		if err != nil {
+			log.Printf("[DEBUG] Served provider.%s isn't running: %s", e.Name, err)
+			continue
+		}
+		conn.Close()
+
+		result[digest] = &plugin.ReattachConfig{Addr: addr, Pid: e.Pid}
+	}
+
+	return result
+}
+
+// writePluginServe writes the plugin serve file at path. It's only readable
+// by the user, since anyone who can read it can use the providers.
+func writePluginServe(path string, entries map[string]*pluginServeEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+func (c *PluginServeCommand) Help() string {
+	helpText := `
+Usage: terraform plugin-serve [options] [DIR]
+
+  Starts the provider plugins that the configuration in DIR needs, and
+  keeps them running until interrupted.
+
+  Other commands run in the same working directory connect to these
+  providers instead of starting their own, which saves the time it takes
+  to start large providers on every plan during development. A provider
+  is only reused if it's exactly the same plugin that the command would
+  otherwise start.
+
+  This is meant for a developer's machine. Each command still configures
+  the providers it uses, but anything a provider keeps in memory outside
+  of its configuration is shared between commands.
+
+Options:
+
+  -no-color           If specified, output won't contain any color.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *PluginServeCommand) Synopsis() string {
+	return "Keep the providers of a configuration running between commands"
+}
+
+const pluginServeRunning = `
+[reset][bold][green]Serving the providers.[reset]
+
+Commands run in this working directory will use these providers until
+this command is interrupted.
+`
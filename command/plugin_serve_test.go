@@ -0,0 +1,149 @@
+package command
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestPluginServe(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	shutdownCh := make(chan struct{})
+	ui := new(cli.MockUi)
+	c := &PluginServeCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+		ShutdownCh: shutdownCh,
+	}
+
+	doneCh := make(chan int)
+	go func() {
+		doneCh <- c.Run([]string{testFixturePath("plugin-serve-empty")})
+	}()
+
+	// The file is written once the providers are running
+	path := filepath.Join(DefaultDataDir, PluginServeFile)
+	for i := 0; ; i++ {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("%s wasn't written\n\n%s", path, ui.ErrorWriter.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(shutdownCh)
+	if code := <-doneCh; code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	// The file is removed when it stops
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("%s should be removed: %v", path, err)
+	}
+}
+
+func TestPluginServe_missing(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	ui := new(cli.MockUi)
+	c := &PluginServeCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+		ShutdownCh: make(chan struct{}),
+	}
+
+	if code := c.Run([]string{testFixturePath("init-get-providers")}); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if output := ui.ErrorWriter.String(); !strings.Contains(output, "between, exact, greater_than") {
+		t.Fatalf("bad: %s", output)
+	}
+}
+
+func TestPluginServe_running(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer ln.Close()
+
+	path := filepath.Join(DefaultDataDir, PluginServeFile)
+	err = writePluginServe(path, map[string]*pluginServeEntry{
+		"abcd": {Name: "test", Network: "tcp", Addr: ln.Addr().String(), Pid: os.Getpid()},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	c := &PluginServeCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+		ShutdownCh: make(chan struct{}),
+	}
+
+	if code := c.Run([]string{testFixturePath("plugin-serve-empty")}); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if output := ui.ErrorWriter.String(); !strings.Contains(output, "already being served") {
+		t.Fatalf("bad: %s", output)
+	}
+}
+
+func TestReadPluginServe(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+
+	live, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer live.Close()
+
+	dead, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	dead.Close()
+
+	path := filepath.Join(td, PluginServeFile)
+	err = writePluginServe(path, map[string]*pluginServeEntry{
+		"live":    {Name: "live", Network: "tcp", Addr: live.Addr().String(), Pid: 1},
+		"dead":    {Name: "dead", Network: "tcp", Addr: dead.Addr().String(), Pid: 2},
+		"network": {Name: "network", Network: "udp", Addr: live.Addr().String(), Pid: 3},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := readPluginServe(path)
+	if len(actual) != 1 {
+		t.Fatalf("bad: %#v", actual)
+	}
+	rc, ok := actual["live"]
+	if !ok || rc.Pid != 1 || rc.Addr.String() != live.Addr().String() {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// A missing file means no providers are served
+	if actual := readPluginServe(filepath.Join(td, "missing.json")); len(actual) != 0 {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
@@ -1,11 +1,11 @@
 package command
 
 import (
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
 
 	plugin "github.com/hashicorp/go-plugin"
@@ -21,6 +21,20 @@ import (
 // each that satisfies the given constraints.
 type multiVersionProviderResolver struct {
 	Available discovery.PluginMetaSet
+
+	// Reattach are the provider processes that are already running, by the
+	// SHA256 digest of their executable. A provider that's in Reattach is
+	// connected to instead of started.
+	Reattach map[string]*plugin.ReattachConfig
+
+	// Dev are the providers replaced with -provider-dev, by name. They're
+	// used whatever the requirements are.
+	Dev map[string]string
+
+	// Attach are the running providers attached with TF_REATTACH_PROVIDERS,
+	// by name. They're connected to whatever the requirements are, and
+	// before the providers of Dev.
+	Attach map[string]*plugin.ReattachConfig
 }
 
 func choosePlugins(avail discovery.PluginMetaSet, reqd discovery.PluginRequirements) map[string]discovery.PluginMeta {
@@ -43,6 +57,30 @@ func (r *multiVersionProviderResolver) ResolveProviders(
 
 	chosen := choosePlugins(r.Available, reqd)
 	for name := range reqd {
+		if rc, ok := r.Attach[name]; ok {
+			factory, err := providerAttachFactory(rc)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("provider.%s: %s", name, err))
+				continue
+			}
+
+			log.Printf("[INFO] Using attached provider.%s (pid %d)", name, rc.Pid)
+			factories[name] = factory
+			continue
+		}
+
+		if path, ok := r.Dev[name]; ok {
+			factory, err := providerDevFactory(name, path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("provider.%s: %s", name, err))
+				continue
+			}
+
+			log.Printf("[INFO] Using development provider.%s from %s", name, path)
+			factories[name] = factory
+			continue
+		}
+
 		if newest, available := chosen[name]; available {
 			digest, err := newest.SHA256()
 			if err != nil {
@@ -59,7 +97,13 @@ func (r *multiVersionProviderResolver) ResolveProviders(
 				continue
 			}
 
-			client := tfplugin.Client(newest)
+			var client *plugin.Client
+			if rc, ok := r.Reattach[hex.EncodeToString(digest)]; ok {
+				log.Printf("[INFO] Using served provider.%s (pid %d)", name, rc.Pid)
+				client = tfplugin.ReattachClient(rc)
+			} else {
+				client = tfplugin.Client(newest)
+			}
 			factories[name] = providerFactory(client)
 		} else {
 			errs = append(errs, fmt.Errorf("provider.%s: no suitable version installed", name))
@@ -71,34 +115,74 @@ func (r *multiVersionProviderResolver) ResolveProviders(
 
 // the default location for automatically installed plugins
 func (m *Meta) pluginDir() string {
-	return filepath.Join(m.DataDir(), "plugins", fmt.Sprintf("%s_%s", runtime.GOOS, runtime.GOARCH))
+	return m.platformPluginDir(discovery.CurrentPlatform())
 }
 
-// pluginDirs return a list of directories to search for plugins.
+// the location for automatically installed plugins built for the given
+// platform, which need not be the one we're running on.
+func (m *Meta) platformPluginDir(platform discovery.Platform) string {
+	return filepath.Join(m.DataDir(), "plugins", platform.String())
+}
+
+// pluginSearchPath is a directory that is searched for plugins, along with
+// the reason that it's searched.
+type pluginSearchPath struct {
+	Dir    string
+	Source string
+}
+
+// pluginSearchPaths returns the directories to search for plugins, in order
+// of precedence.
 //
-// Earlier entries in this slice get priority over later when multiple copies
-// of the same plugin version are found, but newer versions always override
-// older versions where both satisfy the provider version constraints.
-func (m *Meta) pluginDirs() []string {
+// The directories given with -plugin-dir come first. They are followed by
+// the plugin_dirs of the CLI configuration or, if those aren't set, by the
+// default directories. The directory that "terraform init" installs plugins
+// into is always searched.
+func (m *Meta) pluginSearchPaths() []pluginSearchPath {
+	var paths []pluginSearchPath
+	for _, dir := range m.pluginDirFlags {
+		paths = append(paths, pluginSearchPath{dir, "-plugin-dir"})
+	}
 
-	// When searching the following directories, earlier entries get precedence
-	// if the same plugin version is found twice, but newer versions will
-	// always get preference below regardless of where they are coming from.
-	// TODO: Add auto-install dir, default vendor dir and optional override
-	// vendor dir(s).
-	dirs := []string{"."}
+	if m.PluginOverrides != nil && len(m.PluginOverrides.SearchDirs) > 0 {
+		for _, dir := range m.PluginOverrides.SearchDirs {
+			paths = append(paths, pluginSearchPath{dir, "CLI configuration"})
+		}
+
+		return append(paths, pluginSearchPath{m.pluginDir(), "terraform init"})
+	}
+
+	paths = append(paths, pluginSearchPath{".", "current directory"})
 
 	// Look in the same directory as the Terraform executable.
-	// If found, this replaces what we found in the config path.
 	exePath, err := osext.Executable()
 	if err != nil {
 		log.Printf("[ERROR] Error discovering exe directory: %s", err)
 	} else {
-		dirs = append(dirs, filepath.Dir(exePath))
+		paths = append(paths, pluginSearchPath{
+			filepath.Dir(exePath), "Terraform executable directory"})
+	}
+
+	paths = append(paths, pluginSearchPath{m.pluginDir(), "terraform init"})
+	for _, dir := range m.GlobalPluginDirs {
+		paths = append(paths, pluginSearchPath{dir, "user plugin directory"})
+	}
+
+	return paths
+}
+
+// pluginDirs return a list of directories to search for plugins.
+//
+// Earlier entries in this slice get priority over later when multiple copies
+// of the same plugin version are found, but newer versions always override
+// older versions where both satisfy the provider version constraints.
+func (m *Meta) pluginDirs() []string {
+	paths := m.pluginSearchPaths()
+	dirs := make([]string, len(paths))
+	for i, p := range paths {
+		dirs[i] = p.Dir
 	}
 
-	dirs = append(dirs, m.pluginDir())
-	dirs = append(dirs, m.GlobalPluginDirs...)
 	return dirs
 }
 
@@ -118,6 +202,9 @@ func (m *Meta) providerPluginSet() discovery.PluginMetaSet {
 func (m *Meta) providerResolver() terraform.ResourceProviderResolver {
 	return &multiVersionProviderResolver{
 		Available: m.providerPluginSet(),
+		Reattach:  readPluginServe(filepath.Join(m.DataDir(), PluginServeFile)),
+		Dev:       m.providerDev,
+		Attach:    m.providerAttach,
 	}
 }
 
@@ -0,0 +1,148 @@
+package command
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	plugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform/helper/warnings"
+	tfplugin "github.com/hashicorp/terraform/plugin"
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ProviderDevInProcess is the path given to -provider-dev to run a provider
+// in the Terraform process, from InternalProviders, rather than as a plugin.
+// Builds of Terraform for testing can add their own providers to
+// InternalProviders to run them this way.
+const ProviderDevInProcess = "in-process"
+
+// processProviderDev removes the -provider-dev flags from args and records
+// the providers that they replace. Each flag is NAME=PATH, where PATH is
+// the executable of the provider plugin or ProviderDevInProcess.
+//
+// These providers are used whatever the version constraints and the lock
+// file say, and "terraform init" doesn't install them, so that a provider
+// that is being developed can be tested or run in a debugger.
+func (m *Meta) processProviderDev(args []string) []string {
+	args, values := extractFlag(args, "-provider-dev")
+
+	m.providerDev = nil
+	for _, v := range values {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			m.Warnings.Warn(m.Ui, warnings.New(warnings.CodeIgnoredSetting,
+				"Ignoring -provider-dev=%s: must be NAME=PATH or NAME=%s",
+				v, ProviderDevInProcess))
+			continue
+		}
+
+		if m.providerDev == nil {
+			m.providerDev = make(map[string]string)
+		}
+		m.providerDev[parts[0]] = parts[1]
+	}
+
+	if len(m.providerDev) > 0 {
+		names := make([]string, 0, len(m.providerDev))
+		for name, path := range m.providerDev {
+			names = append(names, fmt.Sprintf("%s (%s)", name, path))
+		}
+		sort.Strings(names)
+
+		m.Warnings.Warn(m.Ui, warnings.New(warnings.CodeProviderDev,
+			"Using development providers: %s. Their versions aren't checked.",
+			strings.Join(names, ", ")))
+	}
+
+	return args
+}
+
+// processProviderAttach records the running providers that are listed in
+// the TF_REATTACH_PROVIDERS environment variable, such as providers started
+// in a debugger with plugin.Debug. Like the providers of -provider-dev,
+// their versions aren't checked and they aren't installed.
+func (m *Meta) processProviderAttach() {
+	m.providerAttach = nil
+
+	v := os.Getenv(tfplugin.ReattachEnvVar)
+	if v == "" {
+		return
+	}
+
+	attach, err := tfplugin.ParseReattachProviders(v)
+	if err != nil {
+		m.Warnings.Warn(m.Ui, warnings.New(warnings.CodeIgnoredSetting,
+			"Ignoring %s: %s", tfplugin.ReattachEnvVar, err))
+		return
+	}
+	if len(attach) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(attach))
+	for name, rc := range attach {
+		names = append(names, fmt.Sprintf("%s (pid %d)", name, rc.Pid))
+	}
+	sort.Strings(names)
+
+	m.providerAttach = attach
+	m.Warnings.Warn(m.Ui, warnings.New(warnings.CodeProviderDev,
+		"Using providers attached with %s: %s. Their versions aren't checked.",
+		tfplugin.ReattachEnvVar, strings.Join(names, ", ")))
+}
+
+// removeDevProviders removes the providers replaced by -provider-dev or
+// attached with TF_REATTACH_PROVIDERS from reqd, so that they aren't
+// installed or locked.
+func (m *Meta) removeDevProviders(reqd discovery.PluginRequirements) {
+	for name := range m.providerDev {
+		delete(reqd, name)
+	}
+	for name := range m.providerAttach {
+		delete(reqd, name)
+	}
+}
+
+// providerDevFactory returns the factory for the provider with the given
+// name that -provider-dev replaces with path.
+func providerDevFactory(name, path string) (terraform.ResourceProviderFactory, error) {
+	if path == ProviderDevInProcess {
+		f, ok := InternalProviders[name]
+		if !ok {
+			return nil, fmt.Errorf(
+				"-provider-dev: no provider with this name is built into Terraform")
+		}
+
+		return func() (terraform.ResourceProvider, error) {
+			return f(), nil
+		}, nil
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("-provider-dev: %s", err)
+	}
+
+	client := tfplugin.Client(discovery.PluginMeta{Name: name, Path: path})
+	return providerFactory(client), nil
+}
+
+// providerAttachFactory returns the factory for a provider attached with
+// TF_REATTACH_PROVIDERS.
+func providerAttachFactory(rc *plugin.ReattachConfig) (terraform.ResourceProviderFactory, error) {
+	// The plugin client kills the process if it can't connect to it, which
+	// mustn't happen to a provider that's stopped at a breakpoint, nor to
+	// an unrelated process that reused its pid.
+	conn, err := net.DialTimeout(rc.Addr.Network(), rc.Addr.String(), pluginServeDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%s: can't connect to the provider at %s: %s",
+			tfplugin.ReattachEnvVar, rc.Addr, err)
+	}
+	conn.Close()
+
+	return providerFactory(tfplugin.ReattachClient(rc)), nil
+}
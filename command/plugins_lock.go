@@ -7,11 +7,21 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/hashicorp/terraform/plugin/discovery"
 )
 
 func (m *Meta) providerPluginsLock() *pluginSHA256LockFile {
+	return m.platformProviderPluginsLock(discovery.CurrentPlatform())
+}
+
+// platformProviderPluginsLock returns the lock file for the plugins built
+// for the given platform. Each platform has its own lock, since the same
+// plugin version has a different executable, and so a different digest, on
+// each platform.
+func (m *Meta) platformProviderPluginsLock(platform discovery.Platform) *pluginSHA256LockFile {
 	return &pluginSHA256LockFile{
-		Filename: filepath.Join(m.pluginDir(), "lock.json"),
+		Filename: filepath.Join(m.platformPluginDir(platform), "lock.json"),
 	}
 }
 
@@ -2,12 +2,65 @@ package command
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
+	"testing"
 
+	plugin "github.com/hashicorp/go-plugin"
+	tfplugin "github.com/hashicorp/terraform/plugin"
 	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
 )
 
+func TestMultiVersionProviderResolver_dev(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+	InternalProviders["devtest"] = func() terraform.ResourceProvider { return p }
+	defer delete(InternalProviders, "devtest")
+
+	r := &multiVersionProviderResolver{
+		Dev: map[string]string{
+			"devtest":  ProviderDevInProcess,
+			"unknown":  ProviderDevInProcess,
+			"notfound": filepath.Join(tempDir(t), "terraform-provider-notfound"),
+		},
+	}
+
+	// The versions that are required don't matter
+	reqd := discovery.PluginRequirements{
+		"devtest": &discovery.PluginConstraints{
+			Versions: discovery.ConstraintStr("> 99.0").MustParse(),
+		},
+		"unknown":  &discovery.PluginConstraints{Versions: discovery.AllVersions},
+		"notfound": &discovery.PluginConstraints{Versions: discovery.AllVersions},
+	}
+	factories, errs := r.ResolveProviders(reqd)
+
+	if len(factories) != 1 {
+		t.Fatalf("bad: %#v", factories)
+	}
+	actual, err := factories["devtest"]()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual != p {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	msg := strings.Join(msgs, "\n")
+	if len(errs) != 2 ||
+		!strings.Contains(msg, "provider.unknown: -provider-dev: no provider with this name") ||
+		!strings.Contains(msg, "provider.notfound: -provider-dev: stat") {
+		t.Fatalf("bad: %s", msg)
+	}
+}
+
 // mockGetProvider providers a GetProvider method for testing automatic
 // provider downloads
 type mockGetProvider struct {
@@ -20,17 +73,12 @@ func (m mockGetProvider) FileName(provider, version string) string {
 	return fmt.Sprintf("terraform-provider-%s_v%s_x4", provider, version)
 }
 
-// GetProvider will check the Providers map to see if it can find a suitable
-// version, and put an empty file in the dst directory.
-func (m mockGetProvider) GetProvider(dst, provider string, req discovery.Constraints, protoVersion uint) error {
+// FindProvider will check the Providers map to see if it can find a
+// suitable version.
+func (m mockGetProvider) FindProvider(provider string, req discovery.Constraints, protoVersion uint, platform discovery.Platform) (*discovery.ProviderRelease, error) {
 	versions := m.Providers[provider]
 	if len(versions) == 0 {
-		return fmt.Errorf("provider %q not found", provider)
-	}
-
-	err := os.MkdirAll(dst, 0755)
-	if err != nil {
-		return fmt.Errorf("error creating plugins directory: %s", err)
+		return nil, fmt.Errorf("provider %q not found", provider)
 	}
 
 	for _, v := range versions {
@@ -40,17 +88,112 @@ func (m mockGetProvider) GetProvider(dst, provider string, req discovery.Constra
 		}
 
 		if req.Allows(version) {
-			// provider filename
-			name := m.FileName(provider, v)
-			path := filepath.Join(dst, name)
-			f, err := os.Create(path)
-			if err != nil {
-				return fmt.Errorf("error fetching provider: %s", err)
-			}
-			f.Close()
-			return nil
+			return &discovery.ProviderRelease{
+				Name:     provider,
+				Version:  version,
+				Platform: platform,
+				Size:     1024,
+			}, nil
 		}
 	}
 
-	return fmt.Errorf("no suitable version for provider %q found with constraints %s", provider, req)
+	return nil, fmt.Errorf("no suitable version for provider %q found with constraints %s", provider, req)
+}
+
+// GetProvider will put an empty file for the release in the dst directory.
+func (m mockGetProvider) GetProvider(dst string, r *discovery.ProviderRelease, progress discovery.ProgressFunc) error {
+	err := os.MkdirAll(dst, 0755)
+	if err != nil {
+		return fmt.Errorf("error creating plugins directory: %s", err)
+	}
+
+	// provider filename
+	name := m.FileName(r.Name, r.Version.String())
+	path := filepath.Join(dst, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error fetching provider: %s", err)
+	}
+	f.Close()
+
+	if progress != nil {
+		progress(r.Size/2, r.Size)
+		progress(r.Size, r.Size)
+	}
+	return nil
+}
+
+func TestMultiVersionProviderResolver_attach(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	stopped, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped.Close()
+
+	r := &multiVersionProviderResolver{
+		Attach: map[string]*plugin.ReattachConfig{
+			"running": {Addr: l.Addr(), Pid: os.Getpid()},
+			"stopped": {Addr: stopped.Addr(), Pid: os.Getpid()},
+		},
+		Dev: map[string]string{
+			"running": ProviderDevInProcess,
+		},
+	}
+
+	// The versions that are required don't matter, and attached providers
+	// are used before those of -provider-dev.
+	reqd := discovery.PluginRequirements{
+		"running": &discovery.PluginConstraints{
+			Versions: discovery.ConstraintStr("> 99.0").MustParse(),
+		},
+		"stopped": &discovery.PluginConstraints{Versions: discovery.AllVersions},
+	}
+	factories, errs := r.ResolveProviders(reqd)
+
+	if _, ok := factories["running"]; !ok || len(factories) != 1 {
+		t.Fatalf("bad: %#v", factories)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "provider.stopped: TF_REATTACH_PROVIDERS: can't connect") {
+		t.Fatalf("bad: %v", errs)
+	}
+}
+
+func TestMetaProcessProviderAttach(t *testing.T) {
+	defer os.Setenv(tfplugin.ReattachEnvVar, os.Getenv(tfplugin.ReattachEnvVar))
+
+	ui := new(cli.MockUi)
+	m := &Meta{Ui: ui}
+
+	os.Setenv(tfplugin.ReattachEnvVar, `{"foo": {"Pid": 12, "Addr": {"Network": "unix", "String": "/tmp/plugin.sock"}}}`)
+	m.processProviderAttach()
+	if rc, ok := m.providerAttach["foo"]; !ok || rc.Pid != 12 {
+		t.Fatalf("bad: %#v", m.providerAttach)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Using providers attached with TF_REATTACH_PROVIDERS: foo (pid 12)") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+
+	reqd := discovery.PluginRequirements{
+		"foo": &discovery.PluginConstraints{Versions: discovery.AllVersions},
+		"bar": &discovery.PluginConstraints{Versions: discovery.AllVersions},
+	}
+	m.removeDevProviders(reqd)
+	if _, ok := reqd["foo"]; ok || len(reqd) != 1 {
+		t.Fatalf("bad: %#v", reqd)
+	}
+
+	os.Setenv(tfplugin.ReattachEnvVar, "not json")
+	m.processProviderAttach()
+	if m.providerAttach != nil {
+		t.Fatalf("bad: %#v", m.providerAttach)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Ignoring TF_REATTACH_PROVIDERS") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
 }
@@ -1,10 +1,12 @@
 package command
 
 import (
+	"bytes"
 	"fmt"
 	"sort"
 
 	"github.com/hashicorp/terraform/moduledeps"
+	"github.com/hashicorp/terraform/plugin/discovery"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/xlab/treeprint"
 )
@@ -24,8 +26,10 @@ func (c *ProvidersCommand) Synopsis() string {
 }
 
 func (c *ProvidersCommand) Run(args []string) int {
+	var paths bool
 
 	cmdFlags := c.Meta.flagSet("providers")
+	cmdFlags.BoolVar(&paths, "paths", false, "paths")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
@@ -37,52 +41,93 @@ func (c *ProvidersCommand) Run(args []string) int {
 		return 1
 	}
 
-	// Load the config
-	root, err := c.Module(configPath)
+	depTree, err := c.providerDependencies(configPath)
 	if err != nil {
-		c.Ui.Error(fmt.Sprintf("Failed to load root config module: %s", err))
+		c.Ui.Error(err.Error())
 		return 1
 	}
 
-	// Validate the config (to ensure the version constraints are valid)
-	err = root.Validate()
+	if paths {
+		c.outputPaths(depTree.AllPluginRequirements())
+		return 0
+	}
+
+	printRoot := treeprint.New()
+	providersCommandPopulateTreeNode(printRoot, depTree)
+
+	c.Ui.Output(printRoot.String())
+
+	return 0
+}
+
+// providerDependencies returns the provider dependencies of the
+// configuration in configPath and of its state.
+func (m *Meta) providerDependencies(configPath string) (*moduledeps.Module, error) {
+	// Load the config
+	root, err := m.Module(configPath)
 	if err != nil {
-		c.Ui.Error(err.Error())
-		return 1
+		return nil, fmt.Errorf("Failed to load root config module: %s", err)
+	}
+
+	// Validate the config (to ensure the version constraints are valid)
+	if err := root.Validate(); err != nil {
+		return nil, err
 	}
 
 	// Load the backend
-	b, err := c.Backend(&BackendOpts{
+	b, err := m.Backend(&BackendOpts{
 		Config: root.Config(),
 	})
 	if err != nil {
-		c.Ui.Error(fmt.Sprintf("Failed to load backend: %s", err))
-		return 1
+		return nil, fmt.Errorf("Failed to load backend: %s", err)
 	}
 
 	// Get the state
-	env := c.Env()
-	state, err := b.State(env)
+	state, err := b.State(m.Env())
 	if err != nil {
-		c.Ui.Error(fmt.Sprintf("Failed to load state: %s", err))
-		return 1
+		return nil, fmt.Errorf("Failed to load state: %s", err)
 	}
 	if err := state.RefreshState(); err != nil {
-		c.Ui.Error(fmt.Sprintf("Failed to load state: %s", err))
-		return 1
+		return nil, fmt.Errorf("Failed to load state: %s", err)
 	}
 
-	s := state.State()
-
-	depTree := terraform.ModuleTreeDependencies(root, s)
+	depTree := terraform.ModuleTreeDependencies(root, state.State())
 	depTree.SortDescendents()
+	return depTree, nil
+}
 
-	printRoot := treeprint.New()
-	providersCommandPopulateTreeNode(printRoot, depTree)
+// outputPaths shows the directories that are searched for plugins, and the
+// plugin that each of the required providers resolves to.
+func (c *ProvidersCommand) outputPaths(reqd discovery.PluginRequirements) {
+	var buf bytes.Buffer
+	buf.WriteString("Plugin search paths, in order of precedence:\n\n")
+	for _, p := range c.pluginSearchPaths() {
+		buf.WriteString(fmt.Sprintf("  %s (%s)\n", p.Dir, p.Source))
+	}
 
-	c.Ui.Output(printRoot.String())
+	names := make([]string, 0, len(reqd))
+	for name := range reqd {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-	return 0
+	if len(names) > 0 {
+		buf.WriteString("\nProviders:\n\n")
+	}
+	chosen := choosePlugins(c.providerPluginSet(), reqd)
+	for _, name := range names {
+		meta, ok := chosen[name]
+		if !ok {
+			buf.WriteString(fmt.Sprintf(
+				"  provider.%s: no suitable version installed\n", name))
+			continue
+		}
+
+		buf.WriteString(fmt.Sprintf(
+			"  provider.%s %s: %s\n", name, meta.Version, meta.Path))
+	}
+
+	c.Ui.Output(buf.String())
 }
 
 func providersCommandPopulateTreeNode(node treeprint.Tree, deps *moduledeps.Module) {
@@ -115,7 +160,7 @@ func providersCommandPopulateTreeNode(node treeprint.Tree, deps *moduledeps.Modu
 }
 
 const providersCommandHelp = `
-Usage: terraform providers [dir]
+Usage: terraform providers [options] [dir]
 
   Prints out a tree of modules in the referenced configuration annotated with
   their provider requirements.
@@ -124,4 +169,18 @@ Usage: terraform providers [dir]
   referenced modules, as an aid to understanding why particular provider
   plugins are needed and why particular versions are selected.
 
+  To verify the installed plugins against the digests recorded by
+  "terraform init" and against their upstream releases, use
+  "terraform providers verify".
+
+Options:
+
+  -paths              Instead of the tree, print the directories that are
+                      searched for plugins, in order of precedence, and the
+                      plugin that each required provider resolves to.
+
+  -plugin-dir=path    A directory to search for plugins before all the
+                      others. Can be used multiple times, with earlier ones
+                      taking precedence.
+
 `
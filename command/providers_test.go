@@ -1,7 +1,10 @@
 package command
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -41,3 +44,91 @@ func TestProviders(t *testing.T) {
 		t.Errorf("output missing provider.baz\n\n%s", output)
 	}
 }
+
+func TestProviders_paths(t *testing.T) {
+	td := tempDir(t)
+	defer os.RemoveAll(td)
+
+	// The same version of baz is in both directories, so the one in the
+	// first takes precedence.
+	dirA := filepath.Join(td, "a")
+	dirB := filepath.Join(td, "b")
+	plugins := []string{
+		filepath.Join(dirA, "terraform-provider-baz_v1.2.0"),
+		filepath.Join(dirB, "terraform-provider-baz_v1.2.0"),
+		filepath.Join(dirB, "terraform-provider-foo_v1.0.0"),
+	}
+	for _, path := range plugins {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0755); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	defer testChdir(t, testFixturePath("providers"))()
+
+	ui := new(cli.MockUi)
+	c := &ProvidersCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	args := []string{"-paths", "-plugin-dir", dirA, "-plugin-dir", dirB}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	expected := fmt.Sprintf(
+		"Plugin search paths, in order of precedence:\n\n"+
+			"  %s (-plugin-dir)\n"+
+			"  %s (-plugin-dir)\n"+
+			"  . (current directory)\n",
+		dirA, dirB)
+	if !strings.HasPrefix(output, expected) {
+		t.Fatalf("bad:\n\n%s", output)
+	}
+
+	for _, line := range []string{
+		"provider.bar: no suitable version installed",
+		"provider.baz 1.2.0: " + plugins[0],
+		"provider.foo 1.0.0: " + plugins[2],
+	} {
+		if !strings.Contains(output, line) {
+			t.Errorf("output missing %q\n\n%s", line, output)
+		}
+	}
+}
+
+func TestProviders_pathsConfig(t *testing.T) {
+	defer testChdir(t, testFixturePath("providers"))()
+
+	ui := new(cli.MockUi)
+	c := &ProvidersCommand{
+		Meta: Meta{
+			GlobalPluginDirs: []string{"/global"},
+			PluginOverrides: &PluginOverrides{
+				SearchDirs: []string{"/opt/terraform/plugins"},
+			},
+			Ui: ui,
+		},
+	}
+
+	// The directories from the CLI configuration replace the defaults
+	if code := c.Run([]string{"-paths"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	expected := fmt.Sprintf(
+		"Plugin search paths, in order of precedence:\n\n"+
+			"  /opt/terraform/plugins (CLI configuration)\n"+
+			"  %s (terraform init)\n\n",
+		c.pluginDir())
+	if !strings.HasPrefix(output, expected) {
+		t.Fatalf("bad:\n\n%s", output)
+	}
+}
@@ -0,0 +1,283 @@
+package command
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/keybase/go-crypto/openpgp"
+)
+
+// ProvidersVerifyCommand is a Command implementation that verifies the
+// installed provider plugins against the digests recorded by "terraform
+// init" and against their upstream releases.
+type ProvidersVerifyCommand struct {
+	Meta
+
+	// verifyProvider checks a provider plugin against its upstream release.
+	// It's discovery.VerifyProvider unless a test replaces it.
+	verifyProvider func(meta discovery.PluginMeta, platform discovery.Platform, keyring openpgp.KeyRing) (*discovery.ProviderProvenance, error)
+}
+
+// providerVerification is the result of verifying a provider plugin. It is
+// the JSON format of the report.
+type providerVerification struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Path    string `json:"path,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
+
+	// LockedSHA256 is the digest recorded by "terraform init".
+	LockedSHA256 string `json:"locked_sha256,omitempty"`
+
+	// ReleaseURL and ReleaseSHA256 are the URL and digest of the upstream
+	// release archive that the plugin is in.
+	ReleaseURL    string `json:"release_url,omitempty"`
+	ReleaseSHA256 string `json:"release_sha256,omitempty"`
+
+	// SigningKey and SigningIdentity are the key that signed the release,
+	// and its identity.
+	SigningKey      string `json:"signing_key,omitempty"`
+	SigningIdentity string `json:"signing_identity,omitempty"`
+
+	// Errors are the reasons the plugin failed verification.
+	Errors []string `json:"errors"`
+}
+
+func (c *ProvidersVerifyCommand) Run(args []string) int {
+	var jsonOutput, upstream bool
+	var keyringPath string
+
+	args = c.Meta.process(args, false)
+
+	cmdFlags := c.Meta.flagSet("providers verify")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.BoolVar(&upstream, "upstream", true, "upstream")
+	cmdFlags.StringVar(&keyringPath, "keyring", "", "path")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	configPath, err := ModulePath(cmdFlags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	if keyringPath != "" && !upstream {
+		c.Ui.Error("The -keyring flag can't be used with -upstream=false.")
+		return 1
+	}
+
+	var keyring openpgp.KeyRing
+	if keyringPath != "" {
+		keyring, err = readKeyring(keyringPath)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading -keyring: %s", err))
+			return 1
+		}
+	}
+
+	// set verifyProvider if we don't have a test version
+	if c.verifyProvider == nil {
+		c.verifyProvider = discovery.VerifyProvider
+	}
+
+	depTree, err := c.providerDependencies(configPath)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	reqd := depTree.AllPluginRequirements()
+	names := make([]string, 0, len(reqd))
+	for name := range reqd {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	platform := discovery.CurrentPlatform()
+	locked := c.providerPluginsLock().Read()
+	chosen := choosePlugins(c.providerPluginSet(), reqd)
+
+	results := make([]*providerVerification, 0, len(names))
+	failed := false
+	for _, name := range names {
+		r := &providerVerification{Name: name, Errors: []string{}}
+		results = append(results, r)
+
+		if digest, ok := locked[name]; ok {
+			r.LockedSHA256 = hex.EncodeToString(digest)
+		}
+
+		meta, ok := chosen[name]
+		if !ok {
+			r.Errors = append(r.Errors, "no suitable version installed")
+			failed = true
+			continue
+		}
+		r.Version = string(meta.Version)
+		r.Path = meta.Path
+
+		digest, err := meta.SHA256()
+		if err != nil {
+			r.Errors = append(r.Errors, fmt.Sprintf("failed to read the plugin: %s", err))
+			failed = true
+			continue
+		}
+		r.SHA256 = hex.EncodeToString(digest)
+
+		switch {
+		case r.LockedSHA256 == "":
+			r.Errors = append(r.Errors,
+				"no digest is recorded; run \"terraform init\" to record one")
+		case r.LockedSHA256 != r.SHA256:
+			r.Errors = append(r.Errors,
+				"the digest differs from the one recorded by \"terraform init\"")
+		}
+
+		if upstream {
+			p, err := c.verifyProvider(meta, platform, keyring)
+			if err != nil {
+				r.Errors = append(r.Errors, fmt.Sprintf("upstream release: %s", err))
+			} else {
+				r.ReleaseURL = p.URL
+				r.ReleaseSHA256 = hex.EncodeToString(p.ArchiveSHA256)
+				r.SigningKey = p.SigningKeyID
+				r.SigningIdentity = p.SigningIdentity
+			}
+		}
+
+		if len(r.Errors) > 0 {
+			failed = true
+		}
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(results, "", "    ")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error encoding the report: %s", err))
+			return 1
+		}
+		c.Ui.Output(string(data))
+	} else {
+		c.Ui.Output(c.formatVerifyReport(results, upstream))
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// formatVerifyReport returns the human readable report of the verification
+// of the providers.
+func (c *ProvidersVerifyCommand) formatVerifyReport(results []*providerVerification, upstream bool) string {
+	if len(results) == 0 {
+		return "The configuration and state require no providers."
+	}
+
+	var buf bytes.Buffer
+	for _, r := range results {
+		status := "[green]OK[reset]"
+		if len(r.Errors) > 0 {
+			status = "[red]FAILED[reset]"
+		}
+
+		version := ""
+		if r.Version != "" {
+			version = " " + r.Version
+		}
+		buf.WriteString(c.Colorize().Color(fmt.Sprintf(
+			"[reset][bold]provider.%s%s[reset]: %s\n", r.Name, version, status)))
+
+		if r.Path != "" {
+			buf.WriteString(fmt.Sprintf("  path:      %s\n", r.Path))
+			buf.WriteString(fmt.Sprintf("  sha256:    %s\n", r.SHA256))
+		}
+		if r.LockedSHA256 != "" {
+			buf.WriteString(fmt.Sprintf("  locked:    %s\n", r.LockedSHA256))
+		}
+
+		if upstream && r.ReleaseURL != "" {
+			buf.WriteString(fmt.Sprintf("  release:   %s\n", r.ReleaseURL))
+			buf.WriteString(fmt.Sprintf(
+				"  signed by: %s (%s)\n", r.SigningKey, r.SigningIdentity))
+		}
+
+		for _, e := range r.Errors {
+			buf.WriteString(c.Colorize().Color(fmt.Sprintf("  [red]error:[reset]     %s\n", e)))
+		}
+		buf.WriteString("\n")
+	}
+
+	return strings.TrimSpace(buf.String())
+}
+
+// readKeyring reads an OpenPGP public keyring, which may be ASCII armored.
+func readKeyring(path string) (openpgp.KeyRing, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keyring, err := openpgp.ReadArmoredKeyRing(f)
+	if err == nil {
+		return keyring, nil
+	}
+
+	if _, err := f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	return openpgp.ReadKeyRing(f)
+}
+
+func (c *ProvidersVerifyCommand) Help() string {
+	helpText := `
+Usage: terraform providers verify [options] [dir]
+
+  Verifies the installed provider plugins that the configuration and state
+  require, for audits of the machines that run Terraform.
+
+  The SHA256 digest of each plugin is compared with the one recorded by
+  "terraform init". Each plugin is then compared with its upstream release:
+  the SHA256SUMS file of the release must be signed by the HashiCorp release
+  key, which is built into Terraform, or by a key in -keyring; the release
+  archive must be listed in it; and the plugin must be the executable in the
+  archive. The key that signed each release is reported.
+
+  The exit code is 1 if any plugin fails verification.
+
+Options:
+
+  -json               Output the report as JSON.
+
+  -no-color           If specified, output won't contain any color.
+
+  -keyring=path       Check the signatures of the releases against the
+                      OpenPGP public keys in this keyring, which may be
+                      ASCII armored, instead of the HashiCorp release key.
+                      For providers released by someone else.
+
+  -plugin-dir=path    A directory to search for plugins before all the
+                      others. Can be used multiple times, with earlier ones
+                      taking precedence.
+
+  -upstream=true      Compare the plugins with their upstream releases. If
+                      false, only the digests recorded by "terraform init"
+                      are checked, which needs no network access.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ProvidersVerifyCommand) Synopsis() string {
+	return "Verifies the installed provider plugins"
+}
@@ -0,0 +1,182 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/copy"
+	"github.com/hashicorp/terraform/plugin/discovery"
+	"github.com/keybase/go-crypto/openpgp"
+	"github.com/mitchellh/cli"
+)
+
+// testProvidersVerify sets up the providers-verify fixture in a temporary
+// directory, with the plugins foo and baz installed in the returned plugin
+// directory. The digest of foo is recorded by init, and that of baz is
+// recorded wrongly. It returns a function to clean up.
+func testProvidersVerify(t *testing.T, c *Meta) (string, func()) {
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("providers-verify"), td)
+	undo := testChdir(t, td)
+
+	pluginDir := filepath.Join(td, "plugins")
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, name := range []string{"terraform-provider-foo_v1.0.0", "terraform-provider-baz_v1.2.0"} {
+		if err := ioutil.WriteFile(filepath.Join(pluginDir, name), []byte(name), 0755); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	fooSum := sha256.Sum256([]byte("terraform-provider-foo_v1.0.0"))
+	err := c.providerPluginsLock().Write(map[string][]byte{
+		"foo": fooSum[:],
+		"baz": []byte("wrong"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return pluginDir, func() {
+		undo()
+		os.RemoveAll(td)
+	}
+}
+
+// testVerifyProvider verifies foo against its upstream release, and fails
+// to verify the others.
+func testVerifyProvider(meta discovery.PluginMeta, platform discovery.Platform, keyring openpgp.KeyRing) (*discovery.ProviderProvenance, error) {
+	if meta.Name != "foo" {
+		return nil, errors.New("the digest differs from the release")
+	}
+
+	digest, err := meta.SHA256()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &discovery.ProviderProvenance{
+		URL:           "https://releases.example.com/terraform-provider-foo_1.0.0_" + platform.String() + ".zip",
+		ArchiveSHA256: []byte{0xab, 0xcd},
+		SHA256:        digest,
+	}
+	if keyring == nil {
+		p.SigningKeyID = "51852D87348FFC4C"
+		p.SigningIdentity = "HashiCorp Security <security@hashicorp.com>"
+	} else {
+		p.SigningKeyID = "0123456789ABCDEF"
+		p.SigningIdentity = "Releases <releases@example.com>"
+	}
+
+	return p, nil
+}
+
+func TestProvidersVerify(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &ProvidersVerifyCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+		verifyProvider: testVerifyProvider,
+	}
+
+	pluginDir, closer := testProvidersVerify(t, &c.Meta)
+	defer closer()
+
+	args := []string{"-no-color", "-plugin-dir", pluginDir}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	fooSum := sha256.Sum256([]byte("terraform-provider-foo_v1.0.0"))
+	output := ui.OutputWriter.String()
+	for _, line := range []string{
+		"provider.baz 1.2.0: FAILED",
+		"error:     the digest differs from the one recorded by \"terraform init\"",
+		"error:     upstream release: the digest differs from the release",
+		"provider.foo 1.0.0: OK",
+		"sha256:    " + hex.EncodeToString(fooSum[:]),
+		"release:   https://releases.example.com/terraform-provider-foo_1.0.0_",
+		"signed by: 51852D87348FFC4C (HashiCorp Security <security@hashicorp.com>)",
+	} {
+		if !strings.Contains(output, line) {
+			t.Errorf("output missing %q\n\n%s", line, output)
+		}
+	}
+}
+
+func TestProvidersVerify_json(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &ProvidersVerifyCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+		verifyProvider: testVerifyProvider,
+	}
+
+	pluginDir, closer := testProvidersVerify(t, &c.Meta)
+	defer closer()
+
+	// Only the digests recorded by init are checked without -upstream
+	c.verifyProvider = func(discovery.PluginMeta, discovery.Platform, openpgp.KeyRing) (*discovery.ProviderProvenance, error) {
+		t.Fatal("upstream releases shouldn't be checked")
+		return nil, nil
+	}
+
+	args := []string{"-json", "-upstream=false", "-plugin-dir", pluginDir}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var results []*providerVerification
+	if err := json.Unmarshal([]byte(ui.OutputWriter.String()), &results); err != nil {
+		t.Fatalf("err: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	if len(results) != 2 {
+		t.Fatalf("bad: %#v", results)
+	}
+
+	baz, foo := results[0], results[1]
+	if baz.Name != "baz" || len(baz.Errors) != 1 {
+		t.Fatalf("bad: %#v", baz)
+	}
+	if foo.Name != "foo" || foo.Version != "1.0.0" || len(foo.Errors) != 0 ||
+		foo.SHA256 != foo.LockedSHA256 || foo.ReleaseURL != "" {
+		t.Fatalf("bad: %#v", foo)
+	}
+}
+
+func TestProvidersVerify_notInstalled(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &ProvidersVerifyCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+		verifyProvider: testVerifyProvider,
+	}
+
+	_, closer := testProvidersVerify(t, &c.Meta)
+	defer closer()
+
+	empty := tempDir(t)
+	defer os.RemoveAll(empty)
+
+	args := []string{"-no-color", "-plugin-dir", empty}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "provider.foo: FAILED\n  locked:    ") ||
+		!strings.Contains(output, "error:     no suitable version installed") {
+		t.Fatalf("bad:\n\n%s", output)
+	}
+}
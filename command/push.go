@@ -210,7 +210,9 @@ func (c *PushCommand) Run(args []string) int {
 	// the variables we're going to get.
 	// We are going to keep these separate from the atlas variables until
 	// upload, so we can notify the user which local variables we're sending.
-	serializedVars, err := tfVars(ctx.Variables())
+	// Ephemeral variables must never be stored, so they aren't uploaded.
+	vars := terraform.PersistedVariables(mod, ctx.Variables())
+	serializedVars, err := tfVars(vars)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"An error has occurred while serializing the variables for uploading:\n"+
@@ -329,7 +331,7 @@ func (c *PushCommand) Run(args []string) int {
 	opts := &pushUpsertOptions{
 		Name:      name,
 		Archive:   archiveR,
-		Variables: ctx.Variables(),
+		Variables: vars,
 		TFVars:    uploadVars,
 	}
 
@@ -18,10 +18,16 @@ type RefreshCommand struct {
 
 func (c *RefreshCommand) Run(args []string) int {
 	args = c.Meta.process(args, true)
+	if c.Meta.checkReadOnly("refresh") {
+		return 1
+	}
 
 	cmdFlags := c.Meta.flagSet("refresh")
 	cmdFlags.StringVar(&c.Meta.statePath, "state", DefaultStateFilename, "path")
-	cmdFlags.IntVar(&c.Meta.parallelism, "parallelism", 0, "parallelism")
+	c.Meta.parallelismFlag(cmdFlags, 0)
+	cmdFlags.IntVar(&c.Meta.refreshParallelism, "refresh-parallelism", 0, "refresh-parallelism")
+	c.Meta.providerRetryFlags(cmdFlags)
+	c.Meta.refreshRemoteStateFlag(cmdFlags)
 	cmdFlags.StringVar(&c.Meta.stateOutPath, "state-out", "", "path")
 	cmdFlags.StringVar(&c.Meta.backupPath, "backup", "", "path")
 	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
@@ -110,12 +116,41 @@ Options:
 
   -no-color           If specified, output won't contain any color.
 
+  -parallelism=n      Limit the number of concurrent operations. Defaults to 10.
+                      "auto" starts at 10 and adjusts the limit as transient
+                      provider errors, such as throttling, and system load
+                      come and go.
+
+  -provider-retries=3  Retry provider operations that fail with errors the
+                      provider marks as transient, such as throttling, up to
+                      this many times. 0 disables retries.
+
+  -provider-retry-max-wait=30s
+                      The longest wait between retries of a provider
+                      operation. The wait doubles with each retry.
+
+  -refresh-parallelism=n
+                      Limit the number of concurrent refreshes. Defaults to
+                      five times -parallelism.
+
+  -refresh-remote-state
+                      Read the remote states of the terraform_remote_state
+                      data sources rather than the outputs they've cached.
+
   -state=path         Path to read and save state (unless state-out
                       is specified). Defaults to "terraform.tfstate".
 
   -state-out=path     Path to write updated state file. By default, the
                       "-state" path will be used.
 
+  -strict=code        Treat warnings with the given code as errors, in
+                      addition to those listed in the strict block of the
+                      configuration. This flag can be set multiple times.
+
+  -suppress-warning=code
+                      Don't show warnings with the given code. This flag
+                      can be set multiple times.
+
   -target=resource    Resource to target. Operation will be limited to this
                       resource and its dependencies. This flag can be used
                       multiple times.
@@ -127,6 +162,16 @@ Options:
                       a file. If "terraform.tfvars" is present, it will be
                       automatically loaded if this flag is not specified.
 
+  -var-dir=vars       Load layered variables from the given directory:
+                      "common.tfvars", then each -var-layer, then the file
+                      named after the current environment. -var and -var-file
+                      override layered variables.
+
+  -var-layer=name     Load "name.tfvars" from the -var-dir directory,
+                      which defaults to "vars", as a layer of variables.
+                      This flag can be set multiple times, and later
+                      layers override earlier ones.
+
 `
 	return strings.TrimSpace(helpText)
 }
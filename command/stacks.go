@@ -0,0 +1,512 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ryanuber/columnize"
+)
+
+// StacksCommand is a Command implementation that plans and applies the
+// components of a stack in order.
+type StacksCommand struct {
+	Meta
+}
+
+func (c *StacksCommand) Run(args []string) int {
+	args = c.Meta.process(args, true)
+
+	cmdFlags := c.Meta.flagSet("stacks")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+
+	c.Ui.Output(c.Help())
+	return 0
+}
+
+func (c *StacksCommand) Help() string {
+	helpText := `
+Usage: terraform stacks <subcommand> [options] [args]
+
+  Plan and apply stacks: sets of root modules, called components, with their
+  own states, where the outputs of some components are the variables of
+  others. The components are configured in a stacks.hcl file.
+
+Subcommands:
+
+    plan     Plan each component, in order.
+    apply    Apply each component, in order.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *StacksCommand) Synopsis() string {
+	return "Plan and apply several root modules in order"
+}
+
+// stackRunner runs a Terraform command in each component of a stack, in
+// order, setting the inputs of each from the outputs of the components
+// before it.
+type stackRunner struct {
+	Meta   *Meta
+	Config *stacksConfig
+
+	// Command is the command that runs in each component, "plan" or
+	// "apply", and Args are the extra arguments given to it.
+	Command string
+	Args    []string
+
+	// Components are the names of the components to run the command in.
+	// If it's empty, it runs in all of them.
+	Components []string
+
+	// Run runs terraform with the given arguments in the given directory,
+	// and returns its exit status.
+	Run func(dir string, args []string, stdout, stderr io.Writer) (int, error)
+
+	// outputs are the outputs of the components that have been read.
+	outputs map[string]map[string]interface{}
+}
+
+// stackStatus is the status of the command in a component.
+type stackStatus int
+
+const (
+	stackStatusOK stackStatus = iota
+	stackStatusChanges
+	stackStatusFailed
+
+	// stackStatusSkipped is the status of components that depend on
+	// components that failed.
+	stackStatusSkipped
+
+	// stackStatusWaiting is the status of components that can't be
+	// planned until the components they depend on are applied.
+	stackStatusWaiting
+)
+
+// stackResult is the result of running the command in a component.
+type stackResult struct {
+	Status   stackStatus
+	Message  string
+	Duration time.Duration
+
+	// Unsettled is true if the plan of the component has changes, or its
+	// inputs may change once the components it depends on are applied.
+	Unsettled bool
+}
+
+func (r *stackResult) String() string {
+	if r.Message == "" {
+		return "ok"
+	}
+	return r.Message
+}
+
+// RunAll runs the command in each component, in order. The result of each
+// component is output as soon as it's done, followed by a summary of the
+// results of all of them. It returns false if the command failed or was
+// skipped in any component.
+func (r *stackRunner) RunAll() bool {
+	order, err := r.Config.Order()
+	if err != nil {
+		r.Meta.Ui.Error(err.Error())
+		return false
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range r.Components {
+		selected[name] = true
+	}
+
+	// Components that weren't selected are treated as though they were
+	// applied already, so that inputs are read from their states.
+	results := make(map[string]*stackResult)
+	var names []string
+	for _, comp := range order {
+		if len(selected) > 0 && !selected[comp.Name] {
+			continue
+		}
+		names = append(names, comp.Name)
+
+		start := time.Now()
+		result, output := r.runComponent(comp, results)
+		result.Duration = time.Since(start)
+		results[comp.Name] = result
+
+		r.outputResult(comp.Name, result, output)
+	}
+
+	// Components waiting for others to be applied aren't failures, since
+	// that's expected when planning changes to outputs.
+	failed := 0
+	summary := []string{"COMPONENT | RESULT | DURATION"}
+	for _, name := range names {
+		result := results[name]
+		if result.Status == stackStatusFailed || result.Status == stackStatusSkipped {
+			failed++
+		}
+
+		summary = append(summary, fmt.Sprintf(
+			"%s | %s | %s", name, result, roundSeconds(result.Duration)))
+	}
+
+	r.Meta.Ui.Output("\n" + columnize.SimpleFormat(summary) + "\n")
+	if failed > 0 {
+		r.Meta.Ui.Error(fmt.Sprintf(
+			"The %s failed or was skipped in %d of %d components.", r.Command, failed, len(names)))
+		return false
+	}
+
+	return true
+}
+
+// runComponent runs the command in a component, given the results of the
+// components before it, and returns its result and output.
+func (r *stackRunner) runComponent(comp *stackComponent, results map[string]*stackResult) (*stackResult, []byte) {
+	var changed []string
+	for _, dep := range comp.dependencies() {
+		result, ok := results[dep]
+		if !ok {
+			continue
+		}
+
+		switch result.Status {
+		case stackStatusFailed, stackStatusSkipped:
+			return &stackResult{
+				Status:  stackStatusSkipped,
+				Message: fmt.Sprintf("skipped: %s didn't succeed", dep),
+			}, nil
+		case stackStatusWaiting:
+			return &stackResult{
+				Status:  stackStatusWaiting,
+				Message: result.Message,
+			}, nil
+		}
+		if result.Unsettled {
+			changed = append(changed, dep)
+		}
+	}
+
+	vars := make(map[string]interface{})
+	for _, name := range comp.inputNames() {
+		ref, _ := comp.inputRef(name)
+		v, ok, err := r.output(ref)
+		if err != nil {
+			return &stackResult{
+				Status:  stackStatusFailed,
+				Message: fmt.Sprintf("input %s: %s", name, err),
+			}, nil
+		}
+
+		// When planning, an output that doesn't exist yet is created by
+		// applying the component that it's from.
+		if !ok && r.Command == "plan" {
+			return &stackResult{
+				Status:  stackStatusWaiting,
+				Message: fmt.Sprintf("waiting for %s to be applied", ref.Component),
+			}, nil
+		}
+		if !ok {
+			return &stackResult{
+				Status:  stackStatusFailed,
+				Message: fmt.Sprintf("input %s: %s has no output %q", name, ref.Component, ref.Output),
+			}, nil
+		}
+		vars[name] = v
+	}
+
+	args := []string{r.Command, "-input=false"}
+	if !r.Meta.color {
+		args = append(args, "-no-color")
+	}
+
+	// The flag is taken out of the arguments along with -no-color, so it's
+	// passed on the same way to keep the components from writing.
+	if r.Meta.readOnly {
+		args = append(args, "-read-only")
+	}
+	if r.Command == "plan" {
+		args = append(args, "-detailed-exitcode")
+	}
+	if len(vars) > 0 {
+		path, err := writeStackVars(vars)
+		if err != nil {
+			return &stackResult{
+				Status:  stackStatusFailed,
+				Message: fmt.Sprintf("failed to write the inputs: %s", err),
+			}, nil
+		}
+		defer os.RemoveAll(filepath.Dir(path))
+
+		args = append(args, "-var-file="+path)
+	}
+	args = append(args, r.Args...)
+
+	var output bytes.Buffer
+	status, err := r.Run(comp.Dir, args, &output, &output)
+	if err != nil {
+		return &stackResult{Status: stackStatusFailed, Message: err.Error()}, output.Bytes()
+	}
+
+	result := &stackResult{Status: stackStatusOK}
+	switch {
+	case status == 2 && r.Command == "plan":
+		result.Status = stackStatusChanges
+		result.Message = "changes"
+		result.Unsettled = true
+	case status != 0:
+		result.Status = stackStatusFailed
+		result.Message = fmt.Sprintf("exit status %d", status)
+	case r.Command == "plan":
+		result.Message = "no changes"
+	}
+
+	// The plan of a component that depends on components that will change
+	// may change again once those are applied.
+	if result.Status != stackStatusFailed && len(changed) > 0 {
+		result.Message += fmt.Sprintf(" (planned before applying %s)", strings.Join(changed, ", "))
+		result.Unsettled = true
+	}
+
+	return result, output.Bytes()
+}
+
+// output returns the value of the referenced output, from the state of its
+// component, and whether it exists. The outputs of each component are read
+// once, after the command has run in it.
+func (r *stackRunner) output(ref *stackOutputRef) (interface{}, bool, error) {
+	if r.outputs == nil {
+		r.outputs = make(map[string]map[string]interface{})
+	}
+
+	outputs, ok := r.outputs[ref.Component]
+	if !ok {
+		var err error
+		outputs, err = r.readOutputs(r.Config.Component(ref.Component))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read the outputs of %s: %s", ref.Component, err)
+		}
+		r.outputs[ref.Component] = outputs
+	}
+
+	v, ok := outputs[ref.Output]
+	return v, ok, nil
+}
+
+// readOutputs reads the outputs of a component with "terraform output".
+func (r *stackRunner) readOutputs(comp *stackComponent) (map[string]interface{}, error) {
+	var stdout, stderr bytes.Buffer
+	status, err := r.Run(comp.Dir, []string{"output", "-no-color", "-json"}, &stdout, &stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{})
+	if status != 0 {
+		// The output command fails when there's no state or the state has
+		// no outputs, which is the case for components that haven't been
+		// applied yet.
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "no outputs defined") || strings.Contains(msg, "module root could not be found") {
+			return result, nil
+		}
+		return nil, fmt.Errorf("exit status %d: %s", status, msg)
+	}
+
+	var states map[string]struct {
+		Value interface{} `json:"value"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &states); err != nil {
+		return nil, err
+	}
+	for name, s := range states {
+		result[name] = s.Value
+	}
+
+	return result, nil
+}
+
+// outputResult outputs the result of running the command in the component
+// with the given name.
+func (r *stackRunner) outputResult(name string, result *stackResult, output []byte) {
+	color := "[green]"
+	switch result.Status {
+	case stackStatusChanges, stackStatusSkipped, stackStatusWaiting:
+		color = "[yellow]"
+	case stackStatusFailed:
+		color = "[red]"
+	}
+
+	r.Meta.Ui.Output(r.Meta.Colorize().Color(fmt.Sprintf(
+		"[reset][bold]Component %q: %s%s[reset][bold] after %s:",
+		name, color, result, roundSeconds(result.Duration))))
+	if len(output) > 0 {
+		r.Meta.Ui.Output(string(bytes.TrimRight(output, "\n")) + "\n")
+	}
+}
+
+// writeStackVars writes the inputs of a component to a variables file in
+// a new temporary directory, and returns its path.
+func writeStackVars(vars map[string]interface{}) (string, error) {
+	data, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := ioutil.TempDir("", "tf-stacks")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "inputs.tfvars")
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	return path, nil
+}
+
+// stacksExec runs the current executable with the given arguments in the
+// given directory, with input disabled.
+func stacksExec(dir string, args []string, stdout, stderr io.Writer) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(exe, args...)
+	cmd.Dir = dir
+	cmd.Env = childEnv(InputModeEnvVar + "=0")
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus(), nil
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return 0, nil
+}
+
+// stacksRunArgs parses the arguments of the stacks subcommands, which are
+// the same for each, and returns the runner for the command. The extra
+// arguments for the command follow "--".
+func (m *Meta) stacksRunArgs(command string, args []string) (*stackRunner, bool) {
+	var components []string
+
+	var extra []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, extra = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	args = m.process(args, true)
+
+	cmdFlags := m.flagSet("stacks " + command)
+	cmdFlags.Var((*FlagStringSlice)(&components), "component", "component")
+	cmdFlags.Usage = func() { m.Ui.Error(stacksHelp(command)) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return nil, false
+	}
+
+	args = cmdFlags.Args()
+	if len(args) > 1 {
+		m.Ui.Error("The stacks command expects at most one argument.")
+		cmdFlags.Usage()
+		return nil, false
+	}
+
+	path := DefaultStacksFilename
+	if len(args) == 1 {
+		path = args[0]
+		if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+			path = filepath.Join(path, DefaultStacksFilename)
+		}
+	}
+
+	config, err := loadStacksConfig(path)
+	if err != nil {
+		m.Ui.Error(err.Error())
+		return nil, false
+	}
+
+	for _, name := range components {
+		if config.Component(name) == nil {
+			m.Ui.Error(fmt.Sprintf("Unknown component %q given to -component.", name))
+			return nil, false
+		}
+	}
+
+	return &stackRunner{
+		Meta:       m,
+		Config:     config,
+		Command:    command,
+		Args:       extra,
+		Components: components,
+		Run:        stacksExec,
+	}, true
+}
+
+// stacksHelp returns the help of the stacks subcommand that runs the given
+// command.
+func stacksHelp(command string) string {
+	helpText := `
+Usage: terraform stacks %[1]s [options] [PATH] [-- ARGS...]
+
+  Runs "terraform %[1]s" in each component of the stack configured in the
+  stacks file at PATH, or in the stacks.hcl file of the directory PATH. By
+  default it's the stacks.hcl file of the current directory.
+
+  The components run one at a time, each after the components that it
+  depends on. The inputs of each component are read from the outputs of
+  the components they refer to, and passed to it as variables. The output
+  of each component is shown once it's done, followed by a summary of the
+  results.
+%[2]s
+  Everything after "--" is passed to each "terraform %[1]s".
+
+Options:
+
+  -component=name     Only run in the component with this name. This flag
+                      can be set multiple times. The inputs are still read
+                      from the components that weren't run.
+
+  -no-color           If specified, output won't contain any color.
+
+  -read-only          Run each "terraform %[1]s" in read-only mode. This is
+                      also the case if TF_READ_ONLY is set.
+`
+	var details string
+	switch command {
+	case "plan":
+		details = `
+  A component whose inputs come from components with changes is planned with
+  their current outputs. If the outputs don't exist yet, the component can't
+  be planned until those components are applied.
+`
+	case "apply":
+		details = `
+  If a component fails, the components that depend on it are skipped, but
+  the others are still applied.
+`
+	}
+
+	return strings.TrimSpace(fmt.Sprintf(helpText, command, details))
+}
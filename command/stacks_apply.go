@@ -0,0 +1,40 @@
+package command
+
+import (
+	"io"
+)
+
+// StacksApplyCommand is a Command implementation that runs "terraform apply"
+// in each component of a stack, in order.
+type StacksApplyCommand struct {
+	Meta
+
+	// runComponent runs terraform with the given arguments in the directory
+	// of a component, and returns its exit status. This runs the current
+	// executable by default, but is provided here as a way to mock running
+	// commands for tests.
+	runComponent func(dir string, args []string, stdout, stderr io.Writer) (int, error)
+}
+
+func (c *StacksApplyCommand) Run(args []string) int {
+	runner, ok := c.Meta.stacksRunArgs("apply", args)
+	if !ok {
+		return 1
+	}
+	if c.runComponent != nil {
+		runner.Run = c.runComponent
+	}
+
+	if !runner.RunAll() {
+		return 1
+	}
+	return 0
+}
+
+func (c *StacksApplyCommand) Help() string {
+	return stacksHelp("apply")
+}
+
+func (c *StacksApplyCommand) Synopsis() string {
+	return "Apply each component of a stack, in order"
+}
@@ -0,0 +1,250 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/terraform/config"
+)
+
+// DefaultStacksFilename is the name of the file in the current directory
+// that the components of a stack are configured in.
+const DefaultStacksFilename = "stacks.hcl"
+
+// stacksConfig is the configuration of a stack: the root modules, called
+// components, that are planned and applied together, and how the outputs of
+// some are wired to the variables of others.
+type stacksConfig struct {
+	Components []*stackComponent `hcl:"component"`
+}
+
+// stackComponent is a root module of a stack, with its own state.
+type stackComponent struct {
+	Name string `hcl:",key"`
+
+	// Dir is the directory of the component, relative to the directory of
+	// the stacks file.
+	Dir string `hcl:"dir"`
+
+	// Inputs are the variables of the component that are set from the
+	// outputs of other components, as references such as
+	// "network.vpc_id".
+	Inputs map[string]string `hcl:"inputs"`
+
+	// DependsOn are the names of the components that must be applied
+	// before this one, in addition to those that its inputs refer to.
+	DependsOn []string `hcl:"depends_on"`
+}
+
+// stackOutputRef is a reference to an output of a component.
+type stackOutputRef struct {
+	Component string
+	Output    string
+}
+
+func (r *stackOutputRef) String() string {
+	return r.Component + "." + r.Output
+}
+
+// loadStacksConfig loads the stacks file at the given path. The directories
+// of the components are made absolute, relative to the directory of the
+// file.
+func loadStacksConfig(path string) (*stacksConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := hcl.Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing %s: %s", path, err)
+	}
+
+	var result stacksConfig
+	if err := hcl.DecodeObject(&result, obj); err != nil {
+		return nil, fmt.Errorf("Error decoding %s: %s", path, err)
+	}
+
+	base, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range result.Components {
+		if c.Dir != "" && !filepath.IsAbs(c.Dir) {
+			c.Dir = filepath.Join(base, c.Dir)
+		}
+	}
+
+	if err := result.Validate(); err != nil {
+		return nil, fmt.Errorf("Error in %s: %s", path, err)
+	}
+
+	return &result, nil
+}
+
+// Validate checks that the components have unique names and existing
+// directories, and that their inputs and dependencies refer to other
+// components without any cycles.
+func (c *stacksConfig) Validate() error {
+	if len(c.Components) == 0 {
+		return fmt.Errorf("no components are configured")
+	}
+
+	names := make(map[string]bool)
+	for _, comp := range c.Components {
+		if !config.NameRegexp.MatchString(comp.Name) {
+			return fmt.Errorf("component %q: invalid name", comp.Name)
+		}
+		if names[comp.Name] {
+			return fmt.Errorf("component %q: declared more than once", comp.Name)
+		}
+		names[comp.Name] = true
+	}
+
+	for _, comp := range c.Components {
+		if comp.Dir == "" {
+			return fmt.Errorf("component %q: dir must be set", comp.Name)
+		}
+		if fi, err := os.Stat(comp.Dir); err != nil || !fi.IsDir() {
+			return fmt.Errorf("component %q: dir %s isn't a directory", comp.Name, comp.Dir)
+		}
+
+		for _, name := range comp.inputNames() {
+			if !config.NameRegexp.MatchString(name) {
+				return fmt.Errorf("component %q: input %q: invalid variable name", comp.Name, name)
+			}
+			ref, err := comp.inputRef(name)
+			if err != nil {
+				return fmt.Errorf("component %q: input %q: %s", comp.Name, name, err)
+			}
+			if !names[ref.Component] {
+				return fmt.Errorf(
+					"component %q: input %q: unknown component %q", comp.Name, name, ref.Component)
+			}
+		}
+
+		for _, dep := range comp.DependsOn {
+			if !names[dep] {
+				return fmt.Errorf("component %q: depends_on: unknown component %q", comp.Name, dep)
+			}
+		}
+
+		for _, dep := range comp.dependencies() {
+			if dep == comp.Name {
+				return fmt.Errorf("component %q: depends on itself", comp.Name)
+			}
+		}
+	}
+
+	if _, err := c.Order(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Order returns the components in the order that they're planned and
+// applied: each after the components that it depends on, and otherwise in
+// the order they're declared.
+func (c *stacksConfig) Order() ([]*stackComponent, error) {
+	done := make(map[string]bool)
+	result := make([]*stackComponent, 0, len(c.Components))
+	for len(result) < len(c.Components) {
+		var next *stackComponent
+		for _, comp := range c.Components {
+			if done[comp.Name] {
+				continue
+			}
+
+			ready := true
+			for _, dep := range comp.dependencies() {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = comp
+				break
+			}
+		}
+
+		if next == nil {
+			var names []string
+			for _, comp := range c.Components {
+				if !done[comp.Name] {
+					names = append(names, comp.Name)
+				}
+			}
+			return nil, fmt.Errorf(
+				"the dependencies of these components form a cycle: %s",
+				strings.Join(names, ", "))
+		}
+
+		done[next.Name] = true
+		result = append(result, next)
+	}
+
+	return result, nil
+}
+
+// Component returns the component with the given name, or nil if there is
+// none.
+func (c *stacksConfig) Component(name string) *stackComponent {
+	for _, comp := range c.Components {
+		if comp.Name == name {
+			return comp
+		}
+	}
+
+	return nil
+}
+
+// dependencies returns the names of the components that the component
+// depends on, through its inputs or depends_on, sorted.
+func (c *stackComponent) dependencies() []string {
+	seen := make(map[string]bool)
+	for _, name := range c.inputNames() {
+		if ref, err := c.inputRef(name); err == nil {
+			seen[ref.Component] = true
+		}
+	}
+	for _, dep := range c.DependsOn {
+		seen[dep] = true
+	}
+
+	result := make([]string, 0, len(seen))
+	for name := range seen {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// inputNames returns the names of the inputs of the component, sorted.
+func (c *stackComponent) inputNames() []string {
+	result := make([]string, 0, len(c.Inputs))
+	for name := range c.Inputs {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// inputRef parses the reference of the input with the given name.
+func (c *stackComponent) inputRef(name string) (*stackOutputRef, error) {
+	parts := strings.Split(c.Inputs[name], ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf(
+			"%q isn't a reference to an output, such as \"network.vpc_id\"", c.Inputs[name])
+	}
+
+	return &stackOutputRef{Component: parts[0], Output: parts[1]}, nil
+}
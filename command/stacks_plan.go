@@ -0,0 +1,40 @@
+package command
+
+import (
+	"io"
+)
+
+// StacksPlanCommand is a Command implementation that runs "terraform plan"
+// in each component of a stack, in order.
+type StacksPlanCommand struct {
+	Meta
+
+	// runComponent runs terraform with the given arguments in the directory
+	// of a component, and returns its exit status. This runs the current
+	// executable by default, but is provided here as a way to mock running
+	// commands for tests.
+	runComponent func(dir string, args []string, stdout, stderr io.Writer) (int, error)
+}
+
+func (c *StacksPlanCommand) Run(args []string) int {
+	runner, ok := c.Meta.stacksRunArgs("plan", args)
+	if !ok {
+		return 1
+	}
+	if c.runComponent != nil {
+		runner.Run = c.runComponent
+	}
+
+	if !runner.RunAll() {
+		return 1
+	}
+	return 0
+}
+
+func (c *StacksPlanCommand) Help() string {
+	return stacksHelp("plan")
+}
+
+func (c *StacksPlanCommand) Synopsis() string {
+	return "Plan each component of a stack, in order"
+}
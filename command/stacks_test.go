@@ -0,0 +1,320 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+// testStacksRun returns a function to mock running terraform in the
+// components of the test-fixtures/stacks fixture. The outputs of each
+// component are the given JSON, and the exit status of each other command
+// is the given one. The commands that run are recorded in calls, with the
+// inputs they're given.
+func testStacksRun(t *testing.T, outputs map[string]string, statuses map[string]int, calls *[]string) func(string, []string, io.Writer, io.Writer) (int, error) {
+	return func(dir string, args []string, stdout, stderr io.Writer) (int, error) {
+		name := filepath.Base(dir)
+		if args[0] == "output" {
+			out, ok := outputs[name]
+			if !ok {
+				fmt.Fprintln(stderr, "The state file either has no outputs defined")
+				return 1, nil
+			}
+			fmt.Fprint(stdout, out)
+			return 0, nil
+		}
+
+		call := fmt.Sprintf("%s %s", args[0], name)
+		for _, arg := range args[1:] {
+			if !strings.HasPrefix(arg, "-var-file=") {
+				continue
+			}
+
+			data, err := ioutil.ReadFile(strings.TrimPrefix(arg, "-var-file="))
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			var vars map[string]interface{}
+			if err := json.Unmarshal(data, &vars); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			call += fmt.Sprintf(" %v", vars)
+		}
+		*calls = append(*calls, call)
+
+		fmt.Fprintf(stdout, "%s output", name)
+		return statuses[name], nil
+	}
+}
+
+func TestLoadStacksConfig(t *testing.T) {
+	config, err := loadStacksConfig(testFixturePath("stacks/stacks.hcl"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	order, err := config.Order()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var names []string
+	for _, comp := range order {
+		names = append(names, comp.Name)
+	}
+	expected := []string{"network", "cluster", "apps"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad: %#v", names)
+	}
+
+	cluster := config.Component("cluster")
+	if cluster.Dir != filepath.Join(testFixturePath("stacks"), "cluster") {
+		t.Fatalf("bad: %s", cluster.Dir)
+	}
+	if deps := cluster.dependencies(); !reflect.DeepEqual(deps, []string{"network"}) {
+		t.Fatalf("bad: %#v", deps)
+	}
+}
+
+func TestLoadStacksConfig_invalid(t *testing.T) {
+	cases := map[string]struct {
+		Config string
+		Err    string
+	}{
+		"empty": {
+			"",
+			"no components",
+		},
+
+		"duplicate": {
+			`
+component "a" { dir = "." }
+component "a" { dir = "." }
+`,
+			"declared more than once",
+		},
+
+		"missing dir": {
+			`component "a" { dir = "missing" }`,
+			"isn't a directory",
+		},
+
+		"bad reference": {
+			`
+component "a" {
+  dir    = "."
+  inputs { x = "b" }
+}
+`,
+			"isn't a reference to an output",
+		},
+
+		"unknown component": {
+			`
+component "a" {
+  dir    = "."
+  inputs { x = "b.x" }
+}
+`,
+			`unknown component "b"`,
+		},
+
+		"cycle": {
+			`
+component "a" {
+  dir    = "."
+  inputs { x = "b.x" }
+}
+component "b" {
+  dir        = "."
+  depends_on = ["a"]
+}
+`,
+			"form a cycle: a, b",
+		},
+	}
+
+	for name, tc := range cases {
+		td := tempDir(t)
+		os.MkdirAll(td, 0755)
+		defer os.RemoveAll(td)
+
+		path := filepath.Join(td, DefaultStacksFilename)
+		if err := ioutil.WriteFile(path, []byte(tc.Config), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		_, err := loadStacksConfig(path)
+		if err == nil || !strings.Contains(err.Error(), tc.Err) {
+			t.Fatalf("%s: bad: %v", name, err)
+		}
+	}
+}
+
+func TestStacksPlan(t *testing.T) {
+	var calls []string
+	ui := new(cli.MockUi)
+	c := &StacksPlanCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+		runComponent: testStacksRun(t,
+			map[string]string{
+				"network": `{"vpc_id": {"value": "vpc-1"}, "subnet_ids": {"value": ["a", "b"]}}`,
+			},
+			map[string]int{"network": 2},
+			&calls),
+	}
+
+	args := []string{testFixturePath("stacks")}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	// The apps can't be planned until the cluster has outputs
+	expected := []string{
+		"plan network",
+		"plan cluster map[subnet_ids:[a b] vpc_id:vpc-1]",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("bad: %#v", calls)
+	}
+
+	output := ui.OutputWriter.String()
+	for _, s := range []string{
+		"network output",
+		`Component "cluster": no changes (planned before applying network)`,
+		"apps       waiting for cluster to be applied",
+	} {
+		if !strings.Contains(output, s) {
+			t.Fatalf("expected %q in output:\n\n%s", s, output)
+		}
+	}
+}
+
+func TestStacksApply(t *testing.T) {
+	var calls []string
+	ui := new(cli.MockUi)
+	c := &StacksApplyCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+		runComponent: testStacksRun(t,
+			map[string]string{
+				"network": `{"vpc_id": {"value": "vpc-1"}, "subnet_ids": {"value": ["a"]}}`,
+				"cluster": `{"endpoint": {"value": "https://cluster"}}`,
+			},
+			nil,
+			&calls),
+	}
+
+	args := []string{testFixturePath("stacks"), "--", "-parallelism=2"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	expected := []string{
+		"apply network",
+		"apply cluster map[subnet_ids:[a] vpc_id:vpc-1]",
+		"apply apps map[cluster_endpoint:https://cluster]",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("bad: %#v", calls)
+	}
+}
+
+func TestStacksApply_failed(t *testing.T) {
+	var calls []string
+	ui := new(cli.MockUi)
+	c := &StacksApplyCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+		runComponent: testStacksRun(t, nil, map[string]int{"network": 1}, &calls),
+	}
+
+	args := []string{testFixturePath("stacks")}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if !reflect.DeepEqual(calls, []string{"apply network"}) {
+		t.Fatalf("bad: %#v", calls)
+	}
+
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "cluster    skipped: network didn't succeed") {
+		t.Fatalf("bad:\n\n%s", output)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "failed or was skipped in 3 of 3 components") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+func TestStacksApply_component(t *testing.T) {
+	var calls []string
+	ui := new(cli.MockUi)
+	c := &StacksApplyCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+		runComponent: testStacksRun(t,
+			map[string]string{
+				"cluster": `{"endpoint": {"value": "https://cluster"}}`,
+			},
+			nil,
+			&calls),
+	}
+
+	args := []string{"-component=apps", testFixturePath("stacks")}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	expected := []string{"apply apps map[cluster_endpoint:https://cluster]"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("bad: %#v", calls)
+	}
+}
+
+func TestStacksApply_readOnly(t *testing.T) {
+	var applyArgs [][]string
+	ui := new(cli.MockUi)
+	c := &StacksApplyCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+		runComponent: func(dir string, args []string, stdout, stderr io.Writer) (int, error) {
+			if args[0] == "apply" {
+				applyArgs = append(applyArgs, args)
+			}
+			return 0, nil
+		},
+	}
+
+	args := []string{"-read-only", "-component=network", testFixturePath("stacks")}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if len(applyArgs) != 1 {
+		t.Fatalf("bad: %#v", applyArgs)
+	}
+	found := false
+	for _, arg := range applyArgs[0] {
+		if arg == "-read-only" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected -read-only to be passed to the component, got %#v", applyArgs[0])
+	}
+}
@@ -2,8 +2,10 @@ package command
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
+	"github.com/hashicorp/terraform/config/module"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 )
@@ -16,10 +18,21 @@ type StateListCommand struct {
 }
 
 func (c *StateListCommand) Run(args []string) int {
+	// Get the pwd since its our default -config flag value
+	pwd, err := os.Getwd()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error getting pwd: %s", err))
+		return 1
+	}
+
+	var configPath string
+	var orphans bool
 	args = c.Meta.process(args, true)
 
 	cmdFlags := c.Meta.flagSet("state list")
 	cmdFlags.StringVar(&c.Meta.statePath, "state", DefaultStateFilename, "path")
+	cmdFlags.StringVar(&configPath, "config", pwd, "path")
+	cmdFlags.BoolVar(&orphans, "orphans", false, "orphans")
 	if err := cmdFlags.Parse(args); err != nil {
 		return cli.RunResultHelp
 	}
@@ -58,15 +71,64 @@ func (c *StateListCommand) Run(args []string) int {
 		return cli.RunResultHelp
 	}
 
+	var mod *module.Tree
+	if orphans {
+		mod, err = c.Module(configPath)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to load root config module: %s", err))
+			return 1
+		}
+
+		// Every resource would be an orphan, which is more likely to be
+		// from running in the wrong directory than what's meant.
+		if mod == nil {
+			c.Ui.Error(fmt.Sprintf(errStateListNoConfig, configPath))
+			return 1
+		}
+	}
+
 	for _, result := range results {
-		if _, ok := result.Value.(*terraform.InstanceState); ok {
-			c.Ui.Output(result.Address)
+		if _, ok := result.Value.(*terraform.InstanceState); !ok {
+			continue
 		}
+		if orphans && !stateListOrphan(mod, result.Address) {
+			continue
+		}
+
+		c.Ui.Output(result.Address)
 	}
 
 	return 0
 }
 
+// stateListOrphan returns true if the resource of the instance at addr
+// isn't in the configuration, because either its module or its resource
+// block was removed. Instances of resources that are still in the
+// configuration aren't orphans, even if their count was reduced.
+func stateListOrphan(mod *module.Tree, addr string) bool {
+	ra, err := terraform.ParseResourceAddress(addr)
+	if err != nil {
+		return false
+	}
+
+	child := mod.Child(ra.Path)
+	if child == nil {
+		return true
+	}
+
+	for _, r := range child.Config().Resources {
+		if r.Mode == ra.Mode && r.Type == ra.Type && r.Name == ra.Name {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *StateListCommand) AutocompleteArgs(args []string, prefix string) []string {
+	return c.completeResourceAddresses()
+}
+
 func (c *StateListCommand) Help() string {
 	helpText := `
 Usage: terraform state list [options] [pattern...]
@@ -85,8 +147,19 @@ Usage: terraform state list [options] [pattern...]
   refer to the documentation on resource targeting syntax for more
   information.
 
+  With -orphans, only the resources that are in the state but are no
+  longer in the configuration are listed, such as those whose resource
+  block or module was removed. A plan would destroy these, unless they're
+  moved to their new address with "terraform state mv".
+
 Options:
 
+  -config=path        Path to the configuration to compare the state to
+                      with -orphans. Defaults to the current directory.
+
+  -orphans            Only list the resources that aren't in the
+                      configuration.
+
   -state=statefile    Path to a Terraform state file to use to look
                       up Terraform-managed resources. By default it will
                       use the state "terraform.tfstate" if it exists.
@@ -99,6 +172,13 @@ func (c *StateListCommand) Synopsis() string {
 	return "List resources in the state"
 }
 
+const errStateListNoConfig = `No configuration files found in %s!
+
+The -orphans flag lists the resources in the state that aren't in the
+configuration, so it needs a configuration to compare the state to. Run
+this command in the directory of the configuration, or use the -config
+flag to point to it.`
+
 const errStateFilter = `Error filtering state: %[1]s
 
 Please ensure that all your addresses are formatted properly.`
@@ -1,11 +1,13 @@
 package command
 
 import (
+	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/copy"
+	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 )
 
@@ -66,6 +68,143 @@ func TestStateList_backendState(t *testing.T) {
 	}
 }
 
+// The rest of the configuration isn't loaded when only the backend is needed
+func TestStateList_backendInvalidConfig(t *testing.T) {
+	td := tempDir(t)
+	copy.CopyDir(testFixturePath("state-list-backend"), td)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	invalid := `resource "null_resource" "b" { triggers = "${var.foo var.bar}" }`
+	if err := ioutil.WriteFile("invalid.tf", []byte(invalid), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &StateListCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	if code := c.Run(nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	expected := "null_resource.a\n"
+	actual := ui.OutputWriter.String()
+	if actual != expected {
+		t.Fatalf("Expected:\n%q\n\nTo equal: %q", actual, expected)
+	}
+}
+
+func TestStateList_orphans(t *testing.T) {
+	dataDir := tempDir(t)
+	defer os.RemoveAll(dataDir)
+
+	state := &terraform.State{
+		Modules: []*terraform.ModuleState{
+			&terraform.ModuleState{
+				Path: []string{"root"},
+				Resources: map[string]*terraform.ResourceState{
+					"test_instance.foo.0": testStateListResource("foo0"),
+					"test_instance.foo.1": testStateListResource("foo1"),
+					"test_instance.old":   testStateListResource("old"),
+				},
+			},
+			&terraform.ModuleState{
+				Path: []string{"root", "child"},
+				Resources: map[string]*terraform.ResourceState{
+					"test_instance.bar":  testStateListResource("bar"),
+					"test_instance.gone": testStateListResource("gone"),
+				},
+			},
+			&terraform.ModuleState{
+				Path: []string{"root", "removed"},
+				Resources: map[string]*terraform.ResourceState{
+					"test_instance.baz": testStateListResource("baz"),
+				},
+			},
+		},
+	}
+	statePath := testStateFile(t, state)
+
+	p := testProvider()
+	meta := Meta{
+		testingOverrides: metaOverridesForProvider(p),
+		dataDir:          dataDir,
+	}
+
+	// Install the child module
+	{
+		ui := new(cli.MockUi)
+		meta.Ui = ui
+		c := &GetCommand{Meta: meta}
+		if code := c.Run([]string{testFixturePath("state-list-orphans")}); code != 0 {
+			t.Fatalf("bad: \n%s", ui.ErrorWriter.String())
+		}
+	}
+
+	ui := new(cli.MockUi)
+	meta.Ui = ui
+	c := &StateListCommand{Meta: meta}
+
+	args := []string{
+		"-orphans",
+		"-config", testFixturePath("state-list-orphans"),
+		"-state", statePath,
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	// Instances beyond the count are still declared, so they aren't orphans
+	expected := strings.TrimSpace(testStateListOrphansOutput) + "\n"
+	actual := ui.OutputWriter.String()
+	if actual != expected {
+		t.Fatalf("Expected:\n%q\n\nTo equal: %q", actual, expected)
+	}
+}
+
+func TestStateList_orphansNoConfig(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+
+	statePath := testStateFile(t, testState())
+
+	ui := new(cli.MockUi)
+	c := &StateListCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-orphans",
+		"-config", td,
+		"-state", statePath,
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if output := ui.ErrorWriter.String(); !strings.Contains(output, "No configuration files found") {
+		t.Fatalf("bad: %s", output)
+	}
+}
+
+func testStateListResource(id string) *terraform.ResourceState {
+	return &terraform.ResourceState{
+		Type: "test_instance",
+		Primary: &terraform.InstanceState{
+			ID: id,
+		},
+	}
+}
+
 func TestStateList_noState(t *testing.T) {
 	tmp, cwd := testCwd(t)
 	defer testFixCwd(t, tmp, cwd)
@@ -88,3 +227,9 @@ func TestStateList_noState(t *testing.T) {
 const testStateListOutput = `
 test_instance.foo
 `
+
+const testStateListOrphansOutput = `
+module.child.test_instance.gone
+module.removed.test_instance.baz
+test_instance.old
+`
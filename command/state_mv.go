@@ -16,6 +16,9 @@ type StateMvCommand struct {
 
 func (c *StateMvCommand) Run(args []string) int {
 	args = c.Meta.process(args, true)
+	if c.Meta.checkReadOnly("state mv") {
+		return 1
+	}
 
 	// We create two metas to track the two states
 	var meta1, meta2 Meta
@@ -89,6 +92,14 @@ func (c *StateMvCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Update the dependencies on and of the moved item for its new address
+	// before moving it, so that it carries its own along with it.
+	external := stateTo != stateFrom
+	if err := stateFromReal.MoveDependencies(args[0], args[1], external); err != nil {
+		c.Ui.Error(fmt.Sprintf(errStateMv, err))
+		return 1
+	}
+
 	// Get the item to add to the state
 	add := c.addableResult(results)
 
@@ -180,6 +191,14 @@ func (c *StateMvCommand) addableResult(results []*terraform.StateFilterResult) i
 	}
 }
 
+func (c *StateMvCommand) AutocompleteArgs(args []string, prefix string) []string {
+	if completePositional(args) > 0 {
+		return nil
+	}
+
+	return c.completeResourceAddresses()
+}
+
 func (c *StateMvCommand) Help() string {
 	helpText := `
 Usage: terraform state mv [options] ADDRESS ADDRESS
@@ -198,6 +217,9 @@ Usage: terraform state mv [options] ADDRESS ADDRESS
   If you're moving from one state file to a different state file, a backup
   will be created for each state file.
 
+  Dependencies recorded in the state on and of the moved item are updated
+  for its new address.
+
 Options:
 
   -backup=PATH        Path where Terraform should write the backup for the original
@@ -72,6 +72,64 @@ func TestStateMv(t *testing.T) {
 	testStateOutput(t, backups[0], testStateMvOutputOriginal)
 }
 
+func TestStateMv_dependencies(t *testing.T) {
+	state := &terraform.State{
+		Modules: []*terraform.ModuleState{
+			&terraform.ModuleState{
+				Path: []string{"root"},
+				Resources: map[string]*terraform.ResourceState{
+					"test_instance.foo": &terraform.ResourceState{
+						Type:         "test_instance",
+						Dependencies: []string{"test_instance.qux"},
+						Primary: &terraform.InstanceState{
+							ID: "bar",
+						},
+					},
+
+					"test_instance.baz": &terraform.ResourceState{
+						Type:         "test_instance",
+						Dependencies: []string{"test_instance.foo"},
+						Primary: &terraform.InstanceState{
+							ID: "foo",
+						},
+					},
+
+					"test_instance.qux": &terraform.ResourceState{
+						Type: "test_instance",
+						Primary: &terraform.InstanceState{
+							ID: "qux",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	statePath := testStateFile(t, state)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &StateMvCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"test_instance.foo",
+		"module.child.test_instance.foo",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	// The dependency on the moved resource is now on its module, and the
+	// moved resource no longer depends on a resource it can't reference.
+	testStateOutput(t, statePath, testStateMvDependenciesOutput)
+}
+
 func TestStateMv_backupExplicit(t *testing.T) {
 	td := tempDir(t)
 	defer os.RemoveAll(td)
@@ -651,6 +709,20 @@ test_instance.baz:
   foo = value
 `
 
+const testStateMvDependenciesOutput = `
+test_instance.baz:
+  ID = foo
+
+  Dependencies:
+    module.child
+test_instance.qux:
+  ID = qux
+
+module.child:
+  test_instance.foo:
+    ID = bar
+`
+
 const testStateMvCount_stateOut = `
 test_instance.bar.0:
   ID = foo
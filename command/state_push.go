@@ -18,6 +18,9 @@ type StatePushCommand struct {
 
 func (c *StatePushCommand) Run(args []string) int {
 	args = c.Meta.process(args, true)
+	if c.Meta.checkReadOnly("state push") {
+		return 1
+	}
 
 	var flagForce bool
 	cmdFlags := c.Meta.flagSet("state push")
@@ -0,0 +1,486 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	backendlocal "github.com/hashicorp/terraform/backend/local"
+	"github.com/hashicorp/terraform/command/clistate"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+// StateRecoverCommand is a Command implementation that restores the state
+// from a backup or from a previous version stored by the backend.
+type StateRecoverCommand struct {
+	Meta
+	StateMeta
+}
+
+func (c *StateRecoverCommand) Run(args []string) int {
+	args = c.Meta.process(args, true)
+	if c.Meta.checkReadOnly("state recover") {
+		return 1
+	}
+
+	var restore int
+	var force bool
+	cmdFlags := c.Meta.flagSet("state recover")
+	cmdFlags.IntVar(&restore, "restore", 0, "restore")
+	cmdFlags.BoolVar(&force, "force", false, "force")
+	cmdFlags.BoolVar(&c.Meta.stateLock, "lock", true, "lock state")
+	cmdFlags.DurationVar(&c.Meta.stateLockTimeout, "lock-timeout", 0, "lock timeout")
+	if err := cmdFlags.Parse(args); err != nil {
+		return cli.RunResultHelp
+	}
+	if len(cmdFlags.Args()) > 0 {
+		c.Ui.Error("The state recover command expects no arguments.")
+		return cli.RunResultHelp
+	}
+
+	// Load the backend
+	b, err := c.Backend(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to load backend: %s", err))
+		return 1
+	}
+
+	env := c.Env()
+	s, err := b.State(env)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(errStateLoadingState, err))
+		return 1
+	}
+
+	// Lock the state for the whole recovery, so that nothing writes it
+	// while the candidates are compared to it.
+	if c.stateLock {
+		lockCtx, cancel := context.WithTimeout(context.Background(), c.stateLockTimeout)
+		defer cancel()
+
+		lockInfo := state.NewLockInfo()
+		lockInfo.Operation = "state recover"
+		lockID, err := clistate.Lock(lockCtx, s, lockInfo, c.Ui, c.Colorize())
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error locking state: %s", err))
+			return 1
+		}
+		defer clistate.Unlock(s, lockID, c.Ui, c.Colorize())
+	}
+
+	// The state is read without its wrappers, since those can't read a
+	// corrupted state, and written without the wrappers that back it up,
+	// for the same reason. It's backed up below.
+	inner := stateUnwrap(s)
+	writer := stateWithoutBackup(s)
+
+	currentRaw, err := stateRaw(inner)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to read the current state: %s", err))
+		return 1
+	}
+	current := readRecoverState(currentRaw, nil)
+
+	// Find the states that can be restored
+	localRaw, err := c.Backend(&BackendOpts{ForceLocal: true})
+	if err != nil {
+		// This should never fail
+		panic(err)
+	}
+	_, stateOutPath, backupPath := localRaw.(*backendlocal.Local).StatePaths(env)
+
+	candidates, err := recoverBackups(stateOutPath, backupPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to find the state backups: %s", err))
+		return 1
+	}
+	if v := stateVersioner(inner); v != nil {
+		versions, err := recoverVersions(v)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to list the previous versions of the state: %s", err))
+			return 1
+		}
+		candidates = append(candidates, versions...)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].ModTime.After(candidates[j].ModTime)
+	})
+
+	c.Ui.Output(formatRecoverCandidates(current, candidates))
+	if len(candidates) == 0 {
+		return 0
+	}
+
+	// Choose the state to restore
+	if restore == 0 {
+		if !c.Input() {
+			c.Ui.Output("To restore one of these states, run this command again with -restore=N.")
+			return 0
+		}
+
+		v, err := c.UIInput().Input(&terraform.InputOpts{
+			Id:          "restore",
+			Query:       "Which state do you want to restore?",
+			Description: "Enter the number of a state above, or nothing to cancel.",
+		})
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error asking which state to restore: %s", err))
+			return 1
+		}
+		if v == "" {
+			c.Ui.Output("Nothing was restored.")
+			return 0
+		}
+		restore, err = strconv.Atoi(v)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Invalid number %q", v))
+			return 1
+		}
+	}
+	if restore < 1 || restore > len(candidates) {
+		c.Ui.Error(fmt.Sprintf("There is no state %d to restore.", restore))
+		return 1
+	}
+
+	chosen := candidates[restore-1]
+	if chosen.Err != nil {
+		c.Ui.Error(fmt.Sprintf("The %s is corrupt and can't be restored: %s", chosen, chosen.Err))
+		return 1
+	}
+	if !force && current.State != nil && !current.State.SameLineage(chosen.State) {
+		c.Ui.Error(strings.TrimSpace(errStateRecoverLineage))
+		return 1
+	}
+
+	// Keep the current state, even if it's corrupt, in case the wrong
+	// state is restored.
+	var currentBackup string
+	if currentRaw != nil {
+		currentBackup = fmt.Sprintf(
+			"%s.%d%s", stateOutPath, time.Now().UTC().Unix(), DefaultBackupExtension)
+		if err := ioutil.WriteFile(currentBackup, currentRaw, 0644); err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to back up the current state: %s", err))
+			return 1
+		}
+	}
+
+	// The restored state must have a higher serial than the current state,
+	// or it could be mistaken for an older state.
+	restored := chosen.State.DeepCopy()
+	if current.State != nil && restored.Serial <= current.State.Serial {
+		restored.Serial = current.State.Serial + 1
+	}
+	if err := writer.WriteState(restored); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to write state: %s", err))
+		return 1
+	}
+	if err := writer.PersistState(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to write state: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Restored the state from the %s.", chosen))
+	if currentBackup != "" {
+		c.Ui.Output(fmt.Sprintf("The previous state was saved to %s.", currentBackup))
+	}
+	c.Ui.Output(`Run "terraform plan" to compare the restored state to the infrastructure.`)
+	return 0
+}
+
+// recoverCandidate is a state that can be restored by "state recover".
+type recoverCandidate struct {
+	// Source describes where the state is, such as "local backup".
+	Source string
+
+	// Name identifies the state in its source, such as a path.
+	Name string
+
+	ModTime time.Time
+
+	// State is the state, unless it couldn't be read, in which case
+	// Err is set.
+	State *terraform.State
+	Err   error
+}
+
+func (c *recoverCandidate) String() string {
+	return fmt.Sprintf("%s %s", c.Source, c.Name)
+}
+
+// readRecoverState reads a state, checking its integrity. A nil state
+// means there's no state.
+func readRecoverState(raw []byte, sum []byte) *recoverCandidate {
+	var c recoverCandidate
+	if raw == nil {
+		return &c
+	}
+
+	if len(sum) > 0 {
+		if actual := md5.Sum(raw); !bytes.Equal(actual[:], sum) {
+			c.Err = fmt.Errorf("MD5 mismatch: expected %x, got %x", sum, actual)
+			return &c
+		}
+	}
+
+	raw, c.Err = remote.Decompress(raw)
+	if c.Err != nil {
+		return &c
+	}
+
+	c.State, c.Err = terraform.ReadState(bytes.NewReader(raw))
+	if c.Err == nil && c.State == nil {
+		c.Err = fmt.Errorf("empty state")
+	}
+	return &c
+}
+
+// recoverBackups returns the local backups of the state written to
+// stateOutPath, including the timestamped backups made by the state
+// commands.
+func recoverBackups(stateOutPath, backupPath string) ([]*recoverCandidate, error) {
+	paths, err := filepath.Glob(stateOutPath + "*" + DefaultBackupExtension)
+	if err != nil {
+		return nil, err
+	}
+	if backupPath != "" && backupPath != "-" {
+		found := false
+		for _, p := range paths {
+			found = found || p == backupPath
+		}
+		if !found {
+			paths = append(paths, backupPath)
+		}
+	}
+
+	var result []*recoverCandidate
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+
+		c := readRecoverState(raw, nil)
+		c.Source = "local backup"
+		c.Name = p
+		c.ModTime = info.ModTime()
+		result = append(result, c)
+	}
+
+	return result, nil
+}
+
+// recoverVersions returns the previous versions of the state stored by
+// the backend.
+func recoverVersions(v remote.ClientVersioner) ([]*recoverCandidate, error) {
+	versions, err := v.Versions()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*recoverCandidate
+	for _, version := range versions {
+		var c *recoverCandidate
+		payload, err := v.GetVersion(version.ID)
+		switch {
+		case err != nil:
+			c = &recoverCandidate{Err: err}
+		case payload == nil:
+			c = readRecoverState(nil, nil)
+		default:
+			c = readRecoverState(payload.Data, payload.MD5)
+		}
+		if c.State == nil && c.Err == nil {
+			c.Err = fmt.Errorf("empty version")
+		}
+
+		c.Source = "remote version"
+		c.Name = version.ID
+		c.ModTime = version.ModTime
+		result = append(result, c)
+	}
+
+	return result, nil
+}
+
+// formatRecoverCandidates describes the current state and the states that
+// can be restored, numbered from 1.
+func formatRecoverCandidates(current *recoverCandidate, candidates []*recoverCandidate) string {
+	var buf bytes.Buffer
+	switch {
+	case current.Err != nil:
+		fmt.Fprintf(&buf, "The current state is corrupt: %s\n", current.Err)
+	case current.State == nil:
+		buf.WriteString("There is no current state.\n")
+	default:
+		fmt.Fprintf(&buf, "The current state has serial %d and %d resource(s).\n",
+			current.State.Serial, recoverResourceCount(current.State))
+	}
+	buf.WriteString("\n")
+
+	if len(candidates) == 0 {
+		buf.WriteString("No backups or previous versions of the state were found.")
+		return buf.String()
+	}
+
+	buf.WriteString("States that can be restored, newest first:\n")
+	for i, c := range candidates {
+		fmt.Fprintf(&buf, "\n  %d. %s\n     ", i+1, c)
+		if !c.ModTime.IsZero() {
+			fmt.Fprintf(&buf, "saved %s, ", c.ModTime.UTC().Format(time.RFC1123))
+		}
+		if c.Err != nil {
+			fmt.Fprintf(&buf, "corrupt: %s\n", c.Err)
+			continue
+		}
+
+		fmt.Fprintf(&buf, "serial %d, %d resource(s)", c.State.Serial, recoverResourceCount(c.State))
+		if current.State != nil && !current.State.SameLineage(c.State) {
+			buf.WriteString(", different lineage")
+		}
+		buf.WriteString("\n")
+	}
+
+	return buf.String()
+}
+
+func recoverResourceCount(s *terraform.State) int {
+	count := 0
+	for _, m := range s.Modules {
+		count += len(m.Resources)
+	}
+
+	return count
+}
+
+// stateUnwrap returns the state that s wraps, such as to back it up or to
+// change how it's locked.
+func stateUnwrap(s state.State) state.State {
+	for {
+		u, ok := s.(state.Unwrapper)
+		if !ok {
+			return s
+		}
+		s = u.Unwrap()
+	}
+}
+
+// stateWithoutBackup returns s without the wrappers around it that back it
+// up before it's written.
+func stateWithoutBackup(s state.State) state.State {
+	for {
+		b, ok := s.(*state.BackupState)
+		if !ok {
+			return s
+		}
+		s = b.Real
+	}
+}
+
+// stateRaw returns the stored data of s, without parsing it, or nil if
+// there's no state.
+func stateRaw(s state.State) ([]byte, error) {
+	switch s := s.(type) {
+	case *state.LocalState:
+		raw, err := ioutil.ReadFile(s.Path)
+		if os.IsNotExist(err) || len(raw) == 0 {
+			return nil, nil
+		}
+		return raw, err
+	case *remote.State:
+		payload, err := s.Client.Get()
+		if err != nil || payload == nil {
+			return nil, err
+		}
+		return payload.Data, nil
+	}
+
+	// Other states can only be read by parsing them
+	if err := s.RefreshState(); err != nil {
+		return nil, err
+	}
+	if s.State() == nil {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := terraform.WriteState(s.State(), &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// stateVersioner returns the ClientVersioner that s is stored with, or nil
+// if the previous versions of s aren't kept.
+func stateVersioner(s state.State) remote.ClientVersioner {
+	if rs, ok := s.(*remote.State); ok {
+		return remote.Versioner(rs.Client)
+	}
+
+	return nil
+}
+
+func (c *StateRecoverCommand) Help() string {
+	helpText := `
+Usage: terraform state recover [options]
+
+  Restore the state from a local backup or from a previous version.
+
+  This command lists the current state, the local backups of the state,
+  and the previous versions of the state kept by the backend, such as an
+  S3 bucket with versioning enabled. Each is checked for corruption, and
+  shown with its serial and the number of resources in it.
+
+  One of the states can then be restored, with -restore or by entering
+  its number. The current state is backed up first, even if it's corrupt.
+  A state with a different lineage than the current state is only
+  restored with -force.
+
+Options:
+
+  -force              Restore the state even if its lineage doesn't match
+                      the current state.
+
+  -lock=true          Lock the state file when locking is supported.
+
+  -lock-timeout=0s    Duration to retry a state lock.
+
+  -restore=n          Restore the state with the given number without
+                      asking.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *StateRecoverCommand) Synopsis() string {
+	return "Restore the state from a backup or a previous version"
+}
+
+const errStateRecoverLineage = `
+The lineages do not match! The state will not be restored.
+
+The "lineage" is a unique identifier given to a state on creation. A state
+with a different lineage was created for another configuration or
+environment, so restoring it could lose track of real infrastructure.
+
+Please verify you're restoring the correct state. If you're sure you are,
+you can force the behavior with the "-force" flag.
+`
@@ -0,0 +1,274 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform/backend"
+	backendinit "github.com/hashicorp/terraform/backend/init"
+	"github.com/hashicorp/terraform/backend/remote-state"
+	backendinmem "github.com/hashicorp/terraform/backend/remote-state/inmem"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/mitchellh/cli"
+)
+
+// testStateRecoverBackup writes s as the backup of the default state.
+func testStateRecoverBackup(t *testing.T, s *terraform.State) {
+	f, err := os.Create(DefaultStateFilename + DefaultBackupExtension)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+
+	if err := terraform.WriteState(s, f); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestStateRecover_corrupt(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	backup := testState()
+	backup.Serial = 4
+	testStateRecoverBackup(t, backup)
+	if err := ioutil.WriteFile(DefaultStateFilename, []byte("{corrupt"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	c := &StateRecoverCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{"-restore=1"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, expected := range []string{
+		"The current state is corrupt",
+		"1. local backup terraform.tfstate.backup",
+		"serial 4, 1 resource(s)",
+		"Restored the state from the local backup terraform.tfstate.backup.",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected:\n%s\n\nto include: %q", output, expected)
+		}
+	}
+
+	actual := testStateRead(t, DefaultStateFilename)
+	if actual.Lineage != backup.Lineage || actual.Serial != 4 {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The corrupt state must be kept
+	paths, err := filepath.Glob(DefaultStateFilename + ".*" + DefaultBackupExtension)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(paths) != 1 {
+		t.Fatalf("bad: %#v", paths)
+	}
+	raw, err := ioutil.ReadFile(paths[0])
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(raw) != "{corrupt" {
+		t.Fatalf("bad: %s", raw)
+	}
+}
+
+func TestStateRecover_list(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	current := testState()
+	current.Serial = 5
+	testStateFileDefault(t, current)
+	backup := testState()
+	backup.Lineage = current.Lineage
+	backup.Serial = 4
+	testStateRecoverBackup(t, backup)
+
+	ui := new(cli.MockUi)
+	c := &StateRecoverCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	if code := c.Run(nil); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, expected := range []string{
+		"The current state has serial 5 and 1 resource(s).",
+		"serial 4, 1 resource(s)\n",
+		"run this command again with -restore=N",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected:\n%s\n\nto include: %q", output, expected)
+		}
+	}
+
+	// Nothing is restored
+	if actual := testStateRead(t, DefaultStateFilename); actual.Serial != 5 {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestStateRecover_lineage(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	current := testState()
+	current.Serial = 5
+	testStateFileDefault(t, current)
+	backup := testState()
+	backup.Lineage = "other"
+	testStateRecoverBackup(t, backup)
+
+	ui := new(cli.MockUi)
+	c := &StateRecoverCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+
+	if code := c.Run([]string{"-restore=1"}); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "lineages do not match") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), "different lineage") {
+		t.Fatalf("bad: %s", ui.OutputWriter.String())
+	}
+
+	// With -force, the state is restored with a higher serial than the
+	// current state.
+	ui = new(cli.MockUi)
+	c.Meta.Ui = ui
+	if code := c.Run([]string{"-restore=1", "-force"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	actual := testStateRead(t, DefaultStateFilename)
+	if actual.Lineage != "other" || actual.Serial != 6 {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestStateRecover_notify(t *testing.T) {
+	tmp, cwd := testCwd(t)
+	defer testFixCwd(t, tmp, cwd)
+
+	var mu sync.Mutex
+	var notifications []*backend.Notification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := new(backend.Notification)
+		if err := json.NewDecoder(r.Body).Decode(n); err != nil {
+			t.Errorf("err: %s", err)
+		}
+		mu.Lock()
+		notifications = append(notifications, n)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	// The remote client keeps the previous versions of the state, which
+	// must be found through the notifying wrapper.
+	client := new(backendinmem.RemoteClient)
+	backendinit.Set("_recover_notify", func() backend.Backend {
+		return &remotestate.Backend{
+			ConfigureFunc: func(context.Context) (remote.Client, error) {
+				return client, nil
+			},
+			Backend: &schema.Backend{},
+		}
+	})
+	defer backendinit.Set("_recover_notify", nil)
+
+	rs := &remote.State{Client: client}
+	previous := testState()
+	previous.Serial = 4
+	current := previous.DeepCopy()
+	current.Serial = 5
+	for _, s := range []*terraform.State{previous, current} {
+		if err := rs.WriteState(s); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := rs.PersistState(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	config := fmt.Sprintf(`
+terraform {
+  backend "_recover_notify" {
+    notify {
+      url    = %q
+      events = ["write"]
+    }
+  }
+}
+`, srv.URL)
+	if err := ioutil.WriteFile("main.tf", []byte(config), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	c := &StateRecoverCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(testProvider()),
+			Ui:               ui,
+		},
+	}
+	if _, err := c.Backend(&BackendOpts{Init: true}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if code := c.Run([]string{"-restore=1"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, expected := range []string{
+		"1. remote version 1",
+		"serial 4, 1 resource(s)",
+		"Restored the state from the remote version 1.",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected:\n%s\n\nto include: %q", output, expected)
+		}
+	}
+
+	// The restored state is written through the notifying wrapper
+	mu.Lock()
+	defer mu.Unlock()
+	if len(notifications) != 1 {
+		t.Fatalf("bad: %#v", notifications)
+	}
+	if n := notifications[0]; n.Event != backend.NotifyEventWrite || n.Serial != 6 {
+		t.Fatalf("bad: %#v", n)
+	}
+}
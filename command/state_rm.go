@@ -15,6 +15,9 @@ type StateRmCommand struct {
 
 func (c *StateRmCommand) Run(args []string) int {
 	args = c.Meta.process(args, true)
+	if c.Meta.checkReadOnly("state rm") {
+		return 1
+	}
 
 	cmdFlags := c.Meta.flagSet("state show")
 	cmdFlags.StringVar(&c.Meta.backupPath, "backup", "-", "backup")
@@ -59,6 +62,10 @@ func (c *StateRmCommand) Run(args []string) int {
 	return 0
 }
 
+func (c *StateRmCommand) AutocompleteArgs(args []string, prefix string) []string {
+	return c.completeResourceAddresses()
+}
+
 func (c *StateRmCommand) Help() string {
 	helpText := `
 Usage: terraform state rm [options] ADDRESS...
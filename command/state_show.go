@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
@@ -94,9 +95,44 @@ func (c *StateShowCommand) Run(args []string) int {
 	config := columnize.DefaultConfig()
 	config.Glue = " = "
 	c.Ui.Output(columnize.Format(output, config))
+
+	// Deposed instances are leftovers of the primary, so the lifecycle
+	// of the resource is only about the primary.
+	if rs, ok := instance.Parent.Value.(*terraform.ResourceState); ok && rs.Primary == is {
+		if l := rs.Lifecycle; l != nil {
+			c.Ui.Output("\n" + formatStateLifecycle(l))
+		}
+	}
+
 	return 0
 }
 
+// formatStateLifecycle formats the lifecycle of a resource for humans.
+func formatStateLifecycle(l *terraform.ResourceLifecycle) string {
+	created := "unknown, before this was recorded"
+	if !l.CreatedAt.IsZero() {
+		created = l.CreatedAt.Format(time.RFC3339)
+	}
+
+	config := columnize.DefaultConfig()
+	config.Glue = ": "
+	config.Prefix = "  "
+	return "Lifecycle:\n" + columnize.Format([]string{
+		"created at | " + created,
+		"last modified at | " + l.ModifiedAt.Format(time.RFC3339),
+		"last action | " + l.LastAction,
+		"run ID | " + l.RunID,
+	}, config)
+}
+
+func (c *StateShowCommand) AutocompleteArgs(args []string, prefix string) []string {
+	if completePositional(args) > 0 {
+		return nil
+	}
+
+	return c.completeResourceAddresses()
+}
+
 func (c *StateShowCommand) Help() string {
 	helpText := `
 Usage: terraform state show [options] ADDRESS
@@ -107,6 +143,10 @@ Usage: terraform state show [options] ADDRESS
   state. The address argument must be used to specify a single resource.
   You can view the list of available resources with "terraform state list".
 
+  If Terraform has changed the resource since it started recording it, the
+  lifecycle of the resource is shown as well: when it was created, when it
+  was last modified, with which action, and by which run.
+
 Options:
 
   -state=statefile    Path to a Terraform state file to use to look
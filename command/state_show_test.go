@@ -3,6 +3,7 @@ package command
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
@@ -56,6 +57,61 @@ func TestStateShow(t *testing.T) {
 	}
 }
 
+func TestStateShow_lifecycle(t *testing.T) {
+	created := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	state := &terraform.State{
+		Modules: []*terraform.ModuleState{
+			&terraform.ModuleState{
+				Path: []string{"root"},
+				Resources: map[string]*terraform.ResourceState{
+					"test_instance.foo": &terraform.ResourceState{
+						Type: "test_instance",
+						Primary: &terraform.InstanceState{
+							ID: "bar",
+							Attributes: map[string]string{
+								"foo": "value",
+								"bar": "value",
+							},
+						},
+						Lifecycle: &terraform.ResourceLifecycle{
+							CreatedAt:  created,
+							ModifiedAt: created.Add(time.Hour),
+							LastAction: terraform.LifecycleActionUpdate,
+							RunID:      "job-42",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	statePath := testStateFile(t, state)
+
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &StateShowCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-state", statePath,
+		"test_instance.foo",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	expected := strings.TrimSpace(testStateShowOutput) + "\n\n" +
+		strings.TrimSpace(testStateShowLifecycleOutput) + "\n"
+	actual := ui.OutputWriter.String()
+	if actual != expected {
+		t.Fatalf("Expected:\n%s\n\nTo equal:\n%s", actual, expected)
+	}
+}
+
 func TestStateShow_multi(t *testing.T) {
 	state := &terraform.State{
 		Modules: []*terraform.ModuleState{
@@ -182,3 +238,11 @@ id  = bar
 bar = value
 foo = value
 `
+
+const testStateShowLifecycleOutput = `
+Lifecycle:
+  created at      : 2017-06-01T12:00:00Z
+  last modified at: 2017-06-01T13:00:00Z
+  last action     : update
+  run ID          : job-42
+`
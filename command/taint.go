@@ -19,6 +19,9 @@ type TaintCommand struct {
 
 func (c *TaintCommand) Run(args []string) int {
 	args = c.Meta.process(args, false)
+	if c.Meta.checkReadOnly("taint") {
+		return 1
+	}
 
 	var allowMissing bool
 	var module string
@@ -169,6 +172,19 @@ func (c *TaintCommand) Run(args []string) int {
 	return 0
 }
 
+func (c *TaintCommand) AutocompleteArgs(args []string, prefix string) []string {
+	if completePositional(args) > 0 {
+		return nil
+	}
+
+	path := []string{"root"}
+	if module := completeFlag(args, "module"); module != "" {
+		path = append(path, strings.Split(module, ".")...)
+	}
+
+	return c.completeResourceNames(path)
+}
+
 func (c *TaintCommand) Help() string {
 	helpText := `
 Usage: terraform taint [options] name
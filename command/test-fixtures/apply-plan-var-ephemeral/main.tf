@@ -0,0 +1,9 @@
+variable "password" {
+    ephemeral = true
+}
+
+provider "test" {
+    password = "${var.password}"
+}
+
+resource "test_instance" "foo" {}
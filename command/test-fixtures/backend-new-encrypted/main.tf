@@ -0,0 +1,7 @@
+terraform {
+    backend "inmem" {
+        encryption {
+            type = "aes_gcm"
+        }
+    }
+}
@@ -0,0 +1,8 @@
+terraform {
+    backend "inmem" {
+        state_lock {
+            type    = "inmem"
+            lock_id = "held-elsewhere"
+        }
+    }
+}
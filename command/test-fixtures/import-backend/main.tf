@@ -0,0 +1,8 @@
+terraform {
+    backend "local" {
+        path = "local-state.tfstate"
+    }
+}
+
+resource "test_instance" "foo" {
+}
@@ -0,0 +1,10 @@
+variable "foo" {}
+variable "bar" {}
+
+provider "test" {
+  foo = "${var.foo}"
+}
+
+resource "test_instance" "foo" {
+  ami = "${var.bar}"
+}
@@ -0,0 +1,10 @@
+variable "foo" {}
+
+resource "test_instance" "other" {
+}
+
+module "child" {
+  source = "./child"
+  foo    = "${var.foo}"
+  bar    = "${test_instance.other.id}"
+}
@@ -0,0 +1,3 @@
+module "old" {
+    source = "./old"
+}
@@ -0,0 +1,3 @@
+terraform {
+    deprecated = "Use the new module instead."
+}
@@ -0,0 +1,13 @@
+terraform {
+    backend "local" {
+        path = "foo"
+    }
+}
+
+module "child" {
+    source = "./child"
+}
+
+provider "exact" {
+    version = "1.2.3"
+}
@@ -0,0 +1,9 @@
+variable "name" {}
+
+resource "test_instance" "foo" {
+  ami = "${var.name}"
+}
+
+output "id" {
+  value = "${test_instance.foo.id}"
+}
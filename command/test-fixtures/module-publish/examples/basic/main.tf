@@ -0,0 +1,4 @@
+module "instance" {
+  source = "../.."
+  name   = "example"
+}
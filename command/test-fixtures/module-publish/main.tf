@@ -0,0 +1,12 @@
+variable "name" {
+  description = "The name of the instance"
+}
+
+resource "test_instance" "foo" {
+  ami = "${var.name}"
+}
+
+output "id" {
+  description = "The ID of the instance"
+  value       = "${test_instance.foo.id}"
+}
@@ -0,0 +1,11 @@
+resource "test_instance" "foo" {
+    ami = "changed"
+}
+
+resource "test_instance" "baz" {
+    ami = "same"
+}
+
+resource "test_instance" "new" {
+    ami = "new"
+}
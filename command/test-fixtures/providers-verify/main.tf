@@ -0,0 +1,5 @@
+provider "foo" {}
+
+provider "baz" {
+  version = "1.2.0"
+}
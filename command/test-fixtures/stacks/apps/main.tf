@@ -0,0 +1 @@
+variable "cluster_endpoint" {}
@@ -0,0 +1,4 @@
+variable "vpc_id" {}
+variable "subnet_ids" { type = "list" }
+
+output "endpoint" { value = "https://cluster" }
@@ -0,0 +1,20 @@
+component "apps" {
+  dir = "apps"
+
+  inputs {
+    cluster_endpoint = "cluster.endpoint"
+  }
+}
+
+component "cluster" {
+  dir = "cluster"
+
+  inputs {
+    vpc_id     = "network.vpc_id"
+    subnet_ids = "network.subnet_ids"
+  }
+}
+
+component "network" {
+  dir = "network"
+}
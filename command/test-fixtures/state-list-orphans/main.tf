@@ -0,0 +1,7 @@
+resource "test_instance" "foo" {
+  count = 1
+}
+
+module "child" {
+  source = "./child"
+}
@@ -0,0 +1,11 @@
+provider "test" {
+  version = "~> 1.0"
+}
+
+resource "test_instance" "foo" {
+  tags {
+    Owner = "ops"
+  }
+}
+
+resource "test_instance" "Bar" {}
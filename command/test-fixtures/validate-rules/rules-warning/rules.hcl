@@ -0,0 +1,4 @@
+rule "naming" {
+  severity     = "warning"
+  name_pattern = "^[a-z_]+$"
+}
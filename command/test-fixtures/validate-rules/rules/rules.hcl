@@ -0,0 +1,3 @@
+rule "owner-tag" {
+  required_tags = ["Owner"]
+}
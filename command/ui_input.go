@@ -33,6 +33,9 @@ type UIInput struct {
 	Reader io.Reader
 	Writer io.Writer
 
+	// Disabled makes every call to Input fail without asking anything.
+	Disabled bool
+
 	interrupted bool
 	l           sync.Mutex
 	once        sync.Once
@@ -61,6 +64,10 @@ func (i *UIInput) Input(opts *terraform.InputOpts) (string, error) {
 	i.l.Lock()
 	defer i.l.Unlock()
 
+	if i.Disabled {
+		return "", &inputRequiredError{Id: opts.Id, Query: opts.Query}
+	}
+
 	// If we're interrupted, then don't ask for input
 	if i.interrupted {
 		return "", errors.New("interrupted")
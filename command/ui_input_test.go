@@ -2,6 +2,7 @@ package command
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/terraform"
@@ -42,3 +43,19 @@ func TestUIInputInput_spaces(t *testing.T) {
 		t.Fatalf("bad: %#v", v)
 	}
 }
+
+func TestUIInputInput_disabled(t *testing.T) {
+	i := &UIInput{
+		Reader:   bytes.NewBufferString("foo\n"),
+		Writer:   bytes.NewBuffer(nil),
+		Disabled: true,
+	}
+
+	_, err := i.Input(&terraform.InputOpts{Id: "foo", Query: "Foo?"})
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(err.Error(), "input required [foo]") {
+		t.Fatalf("bad: %s", err)
+	}
+}
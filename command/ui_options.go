@@ -0,0 +1,184 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/colorstring"
+)
+
+// uiThemes are the built-in color themes, by name. Each overrides the codes
+// of some of colorstring.DefaultColors, which are the names that output is
+// marked up with. The default is "dark", for terminals with a dark
+// background.
+var uiThemes = map[string]map[string]string{
+	"dark": nil,
+
+	// Yellow, cyan and the light colors are hard to read on a light
+	// background, so they're replaced with darker ones.
+	"light": {
+		"yellow":        "35",
+		"cyan":          "34",
+		"light_gray":    "90",
+		"light_red":     "31",
+		"light_green":   "32",
+		"light_yellow":  "35",
+		"light_blue":    "34",
+		"light_magenta": "35",
+		"light_cyan":    "34",
+		"white":         "30",
+	},
+}
+
+// uiColorCodeRe matches the ANSI codes that colors can be set to.
+var uiColorCodeRe = regexp.MustCompile(`^[0-9]+(;[0-9]+)*$`)
+
+// UIOptions are the options for rendering output, from the ui block of the
+// CLI configuration.
+type UIOptions struct {
+	// Theme is the name of the color theme: "dark", the default, or
+	// "light".
+	Theme string `hcl:"theme"`
+
+	// Colors override the ANSI codes of the colors of the theme, by the
+	// name they're marked up with in output, such as "yellow" = "35".
+	Colors map[string]string `hcl:"colors"`
+
+	// ASCII, if true, replaces the symbols of messages with ASCII ones, for
+	// logs that can't show them.
+	ASCII bool `hcl:"ascii"`
+
+	// Width is the width to wrap error and warning messages to. If it's
+	// zero, they're wrapped to the width of the terminal, if there is one,
+	// and if it's negative, they aren't wrapped.
+	Width int `hcl:"width"`
+
+	// TerminalWidth is the width of the terminal that output is written
+	// to, or zero if it isn't a terminal. It isn't configurable.
+	TerminalWidth int `hcl:"-"`
+}
+
+// Validate checks that the theme and colors exist, and that the colors are
+// ANSI codes.
+func (o *UIOptions) Validate() error {
+	if _, ok := uiThemes[o.Theme]; o.Theme != "" && !ok {
+		return fmt.Errorf("unknown theme %q, expected one of: %s", o.Theme, uiThemeNames())
+	}
+
+	for name, code := range o.Colors {
+		if _, ok := colorstring.DefaultColors[name]; !ok {
+			return fmt.Errorf("colors: unknown color %q", name)
+		}
+		if !uiColorCodeRe.MatchString(code) {
+			return fmt.Errorf("colors: %s: %q isn't an ANSI code, such as \"31\" or \"1;34\"", name, code)
+		}
+	}
+
+	return nil
+}
+
+// Merge returns the options of o overridden by those set in o2.
+func (o *UIOptions) Merge(o2 *UIOptions) *UIOptions {
+	result := *o
+	if o2.Theme != "" {
+		result.Theme = o2.Theme
+	}
+	if o2.ASCII {
+		result.ASCII = true
+	}
+	if o2.Width != 0 {
+		result.Width = o2.Width
+	}
+
+	if len(o.Colors) > 0 || len(o2.Colors) > 0 {
+		result.Colors = make(map[string]string)
+		for k, v := range o.Colors {
+			result.Colors[k] = v
+		}
+		for k, v := range o2.Colors {
+			result.Colors[k] = v
+		}
+	}
+
+	return &result
+}
+
+// uiOptions returns the options for rendering output, which are those of
+// the CLI configuration overridden by the environment. Invalid settings in
+// the environment are ignored, as they are for the other variables.
+func (m *Meta) uiOptions() *UIOptions {
+	result := new(UIOptions)
+	if m.UIOptions != nil {
+		*result = *m.UIOptions
+	}
+
+	if v := os.Getenv(UIThemeEnvVar); v != "" {
+		if _, ok := uiThemes[v]; ok {
+			result.Theme = v
+		} else {
+			log.Printf("[WARN] Ignoring unknown %s %q", UIThemeEnvVar, v)
+		}
+	}
+	if v := os.Getenv(UIASCIIEnvVar); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			result.ASCII = b
+		}
+	}
+	if v := os.Getenv(UIWidthEnvVar); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			result.Width = n
+		}
+	}
+
+	return result
+}
+
+// colors returns the codes of the colors that output is marked up with,
+// from the theme and the colors that override it.
+func (o *UIOptions) colors() map[string]string {
+	theme := uiThemes[o.Theme]
+	if len(theme) == 0 && len(o.Colors) == 0 {
+		return colorstring.DefaultColors
+	}
+
+	result := make(map[string]string, len(colorstring.DefaultColors))
+	for k, v := range colorstring.DefaultColors {
+		result[k] = v
+	}
+	for k, v := range theme {
+		result[k] = v
+	}
+	for k, v := range o.Colors {
+		result[k] = v
+	}
+
+	return result
+}
+
+// wrapWidth returns the width to wrap messages to, or zero if they aren't
+// wrapped.
+func (o *UIOptions) wrapWidth() int {
+	switch {
+	case o.Width > 0:
+		return o.Width
+	case o.Width == 0:
+		return o.TerminalWidth
+	}
+
+	return 0
+}
+
+func uiThemeNames() string {
+	names := make([]string, 0, len(uiThemes))
+	for name := range uiThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
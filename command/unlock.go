@@ -17,6 +17,9 @@ type UnlockCommand struct {
 
 func (c *UnlockCommand) Run(args []string) int {
 	args = c.Meta.process(args, false)
+	if c.Meta.checkReadOnly("force-unlock") {
+		return 1
+	}
 
 	force := false
 	cmdFlags := c.Meta.flagSet("force-unlock")
@@ -43,7 +46,7 @@ func (c *UnlockCommand) Run(args []string) int {
 		return 1
 	}
 
-	conf, err := c.Config(configPath)
+	conf, err := c.ConfigTerraform(configPath)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to load root config module: %s", err))
 		return 1
@@ -18,6 +18,9 @@ type UntaintCommand struct {
 
 func (c *UntaintCommand) Run(args []string) int {
 	args = c.Meta.process(args, false)
+	if c.Meta.checkReadOnly("untaint") {
+		return 1
+	}
 
 	var allowMissing bool
 	var module string
@@ -157,6 +160,19 @@ func (c *UntaintCommand) Run(args []string) int {
 	return 0
 }
 
+func (c *UntaintCommand) AutocompleteArgs(args []string, prefix string) []string {
+	if completePositional(args) > 0 {
+		return nil
+	}
+
+	path := []string{"root"}
+	if module := completeFlag(args, "module"); module != "" {
+		path = append(path, strings.Split(module, ".")...)
+	}
+
+	return c.completeResourceNames(path)
+}
+
 func (c *UntaintCommand) Help() string {
 	helpText := `
 Usage: terraform untaint [options] name
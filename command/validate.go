@@ -1,12 +1,15 @@
 package command
 
 import (
-	"flag"
 	"fmt"
 	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/helper/rules"
+	"github.com/hashicorp/terraform/helper/warnings"
+	"github.com/hashicorp/terraform/terraform"
 )
 
 // ValidateCommand is a Command implementation that validates the terraform files
@@ -17,15 +20,20 @@ type ValidateCommand struct {
 const defaultPath = "."
 
 func (c *ValidateCommand) Run(args []string) int {
-	args = c.Meta.process(args, false)
-	var dirPath string
+	args = c.Meta.process(args, true)
+	var dirPath, rulesDir string
+	var checkProviders bool
 
-	cmdFlags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	cmdFlags := c.Meta.flagSet("validate")
+	cmdFlags.BoolVar(&checkProviders, "check-providers", false, "check-providers")
+	cmdFlags.StringVar(&rulesDir, "rules", "", "path")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
 	}
 
+	args = cmdFlags.Args()
+
 	if len(args) == 1 {
 		dirPath = args[0]
 	} else {
@@ -38,6 +46,12 @@ func (c *ValidateCommand) Run(args []string) int {
 	}
 
 	rtnCode := c.validate(dir)
+	if rtnCode == 0 && checkProviders {
+		rtnCode = c.validateProviders(dir)
+	}
+	if rtnCode == 0 && rulesDir != "" {
+		rtnCode = c.validateRules(dir, rulesDir)
+	}
 
 	return rtnCode
 }
@@ -59,14 +73,44 @@ Usage: terraform validate [options] [path]
 
 Options:
 
+  -check-providers    If specified, also loads the provider plugins and runs
+                      their validation of the provider and resource
+                      configuration, catching unknown attributes and type
+                      errors. Providers are not configured, so no
+                      credentials are required. Plugins must be installed
+                      with "terraform init" and required variables must be
+                      set.
+
   -no-color           If specified, output won't contain any color.
 
+  -rules=path         Also check the resources of the configuration and its
+                      modules against the rules in the .hcl and .json files
+                      in the given directory, such as required tags,
+                      disallowed resource types and naming patterns.
+
+  -strict=code        Treat warnings with the given code as errors, in
+                      addition to those listed in the strict block of the
+                      configuration. This flag can be set multiple times.
+
+  -suppress-warning=code
+                      Don't show warnings with the given code. This flag
+                      can be set multiple times.
+
+  -var 'foo=bar'      Set a variable in the Terraform configuration. This
+                      flag can be set multiple times. This is only useful
+                      with -check-providers.
+
+  -var-file=foo       Set variables in the Terraform configuration from
+                      a file. If "terraform.tfvars" is present, it will be
+                      automatically loaded if this flag is not specified.
+                      This is only useful with -check-providers.
+
 `
 	return strings.TrimSpace(helpText)
 }
 
 func (c *ValidateCommand) validate(dir string) int {
-	cfg, err := config.LoadDir(dir)
+	cfg, err := config.LoadDirEnv(dir, c.Env())
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Error loading files %v\n", err.Error()))
@@ -78,5 +122,104 @@ func (c *ValidateCommand) validate(dir string) int {
 			"Error validating: %v\n", err.Error()))
 		return 1
 	}
+
+	return c.validateWarnings(dir, cfg)
+}
+
+// validateRules checks the configuration against the rules in rulesDir.
+// Only the root module is checked if the child modules haven't been
+// installed with "terraform init".
+func (c *ValidateCommand) validateRules(dir, rulesDir string) int {
+	rs, err := rules.LoadDir(rulesDir)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error loading rules: %s", err))
+		return 1
+	}
+
+	mod, err := c.Module(dir)
+	if err != nil || mod == nil {
+		cfg, err := config.LoadDirEnv(dir, c.Env())
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error loading files %s", err))
+			return 1
+		}
+		mod = module.NewTree("", cfg)
+	}
+
+	rtnCode := 0
+	for _, f := range rules.Check(mod, rs) {
+		if f.Severity == rules.SeverityWarning {
+			w := warnings.Warning{Code: warnings.CodeRule, Message: f.String()}
+			if c.Warnings.Show(w.Code) {
+				c.Ui.Warn(fmt.Sprintf("Warning: %s", w))
+			}
+			continue
+		}
+
+		c.Ui.Error(fmt.Sprintf("Error: %s", f))
+		rtnCode = 1
+	}
+
+	return rtnCode
+}
+
+// validateWarnings shows the warnings about the configuration, which are
+// errors in strict mode. Only the root module is checked if the child
+// modules haven't been installed with "terraform init".
+func (c *ValidateCommand) validateWarnings(dir string, cfg *config.Config) int {
+	mod, err := c.Module(dir)
+	if err != nil || mod == nil {
+		mod = module.NewTree("", cfg)
+	}
+
+	ws, errs := warnings.Strict(
+		terraform.ConfigWarnings(mod), terraform.StrictCodes(mod, c.strictWarnings))
+	for _, w := range c.Warnings.Filter(warnings.FromStrings(ws)) {
+		c.Ui.Warn(fmt.Sprintf("Warning: %s", w))
+	}
+	if len(errs) > 0 {
+		for _, e := range errs {
+			c.Ui.Error(fmt.Sprintf("Error validating: %s", e))
+		}
+
+		return 1
+	}
+
+	return 0
+}
+
+// validateProviders validates the configuration with the provider plugins.
+// This runs the validate walk, which never configures the providers, so
+// it doesn't require any credentials.
+func (c *ValidateCommand) validateProviders(dir string) int {
+	mod, err := c.Module(dir)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error loading modules: %s", err))
+		return 1
+	}
+	if mod == nil {
+		return 0
+	}
+
+	opts := c.contextOpts()
+	opts.Module = mod
+	ctx, err := terraform.NewContext(opts)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error loading providers: %s", err))
+		return 1
+	}
+
+	ws, es := ctx.Validate()
+	for _, w := range c.Warnings.Filter(warnings.FromStrings(ws)) {
+		c.Ui.Warn(fmt.Sprintf("Warning: %s", w))
+	}
+	if len(es) > 0 {
+		for _, e := range es {
+			c.Ui.Error(fmt.Sprintf("Error validating: %s", e))
+		}
+
+		return 1
+	}
+
 	return 0
 }
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -60,7 +61,7 @@ func TestSameProviderMutipleTimesShouldFail(t *testing.T) {
 	if code != 1 {
 		t.Fatalf("Should have failed: %d\n\n%s", code, ui.ErrorWriter.String())
 	}
-	if !strings.HasSuffix(strings.TrimSpace(ui.ErrorWriter.String()), "provider.aws: declared multiple times, you can only declare a provider once") {
+	if !strings.Contains(ui.ErrorWriter.String(), "provider.aws: declared multiple times, you can only declare provider.aws once") {
 		t.Fatalf("Should have failed: %d\n\n'%s'", code, ui.ErrorWriter.String())
 	}
 }
@@ -122,3 +123,200 @@ func TestWronglyUsedInterpolationShouldFail(t *testing.T) {
 		t.Fatalf("Should have failed: %d\n\n'%s'", code, ui.ErrorWriter.String())
 	}
 }
+
+func TestValidate_checkProviders(t *testing.T) {
+	p := testProvider()
+	ui := new(cli.MockUi)
+	c := &ValidateCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-check-providers",
+		testFixturePath("validate-providers"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if !p.ValidateResourceCalled {
+		t.Fatal("ValidateResource should be called")
+	}
+	if p.ConfigureCalled {
+		t.Fatal("Configure should not be called")
+	}
+}
+
+func TestValidate_checkProvidersInvalid(t *testing.T) {
+	p := testProvider()
+	p.ValidateResourceReturnErrors = []error{errors.New("bad attribute")}
+	ui := new(cli.MockUi)
+	c := &ValidateCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		"-check-providers",
+		testFixturePath("validate-providers"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+
+	if !strings.Contains(ui.ErrorWriter.String(), "bad attribute") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+func TestValidate_noCheckProviders(t *testing.T) {
+	p := testProvider()
+	p.ValidateResourceReturnErrors = []error{errors.New("bad attribute")}
+	ui := new(cli.MockUi)
+	c := &ValidateCommand{
+		Meta: Meta{
+			testingOverrides: metaOverridesForProvider(p),
+			Ui:               ui,
+		},
+	}
+
+	args := []string{
+		testFixturePath("validate-providers"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if p.ValidateResourceCalled {
+		t.Fatal("ValidateResource should not be called")
+	}
+}
+
+func TestValidate_strict(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &ValidateCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	// Without strict mode, the unconstrained provider is only a warning
+	args := []string{testFixturePath("validate-valid")}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Warning: [provider-unconstrained] provider.test") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+
+	ui = new(cli.MockUi)
+	c = &ValidateCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	args = []string{
+		"-strict=provider-unconstrained",
+		testFixturePath("validate-valid"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "strict mode: [provider-unconstrained] provider.test") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+func TestValidate_rules(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &ValidateCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	args := []string{
+		"-rules", testFixturePath("validate-rules/rules"),
+		testFixturePath("validate-rules/config"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+
+	output := ui.ErrorWriter.String()
+	if !strings.Contains(output, `Error: rule "owner-tag": test_instance.Bar: missing tags: Owner`) {
+		t.Fatalf("bad: %s", output)
+	}
+	if strings.Contains(output, "test_instance.foo") {
+		t.Fatalf("bad: %s", output)
+	}
+}
+
+func TestValidate_rulesWarning(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &ValidateCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	args := []string{
+		"-rules", testFixturePath("validate-rules/rules-warning"),
+		testFixturePath("validate-rules/config"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if output := ui.ErrorWriter.String(); !strings.Contains(output, `Warning: [rule] rule "naming": test_instance.Bar`) {
+		t.Fatalf("bad: %s", output)
+	}
+}
+
+func TestValidate_rulesWarningSuppressed(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &ValidateCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	args := []string{
+		"-suppress-warning=rule",
+		"-rules", testFixturePath("validate-rules/rules-warning"),
+		testFixturePath("validate-rules/config"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if output := ui.ErrorWriter.String(); strings.Contains(output, "naming") {
+		t.Fatalf("bad: %s", output)
+	}
+}
+
+func TestValidate_rulesInvalid(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &ValidateCommand{
+		Meta: Meta{
+			Ui: ui,
+		},
+	}
+
+	args := []string{
+		"-rules", testFixturePath("validate-rules/missing"),
+		testFixturePath("validate-rules/config"),
+	}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.OutputWriter.String())
+	}
+	if output := ui.ErrorWriter.String(); !strings.Contains(output, "Error loading rules") {
+		t.Fatalf("bad: %s", output)
+	}
+}
@@ -2,7 +2,12 @@ package command
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform/state"
 )
 
 // VersionCommand is a Command implementation prints the version.
@@ -28,14 +33,54 @@ type VersionCheckInfo struct {
 	Alerts   []string
 }
 
+// versionOutput is the JSON format of the version command.
+type versionOutput struct {
+	Version  string `json:"terraform_version"`
+	Revision string `json:"terraform_revision,omitempty"`
+
+	// ProviderSelections are the versions of the provider plugins that
+	// "terraform init" locked in the working directory, by name. A provider
+	// whose locked plugin can't be found has an empty version.
+	ProviderSelections map[string]string `json:"provider_selections"`
+
+	// Backend is the type of the backend that the working directory is
+	// initialized with, which is "local" by default.
+	Backend string `json:"backend"`
+}
+
 func (c *VersionCommand) Help() string {
-	return ""
+	helpText := `
+Usage: terraform version [options]
+
+  Prints the version of Terraform, and checks whether it's the latest.
+
+Options:
+
+  -json               Print the version, the versions of the providers
+                      that "terraform init" selected in the current
+                      working directory, and the type of its backend as
+                      JSON, without checking for the latest version.
+
+`
+	return strings.TrimSpace(helpText)
 }
 
 func (c *VersionCommand) Run(args []string) int {
 	var versionString bytes.Buffer
+	var jsonOutput bool
 	args = c.Meta.process(args, false)
 
+	cmdFlags := c.Meta.flagSet("version")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "json")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	if jsonOutput {
+		return c.runJSON()
+	}
+
 	fmt.Fprintf(&versionString, "Terraform v%s", c.Version)
 	if c.VersionPrerelease != "" {
 		fmt.Fprintf(&versionString, "-%s", c.VersionPrerelease)
@@ -70,6 +115,55 @@ func (c *VersionCommand) Run(args []string) int {
 	return 0
 }
 
+// runJSON prints the version in the JSON format of versionOutput.
+func (c *VersionCommand) runJSON() int {
+	out := &versionOutput{
+		Version:            c.Version,
+		Revision:           c.Revision,
+		ProviderSelections: make(map[string]string),
+		Backend:            "local",
+	}
+	if c.VersionPrerelease != "" {
+		out.Version += "-" + c.VersionPrerelease
+	}
+
+	// The locked digests identify the selected plugins among those that
+	// are installed.
+	available := c.providerPluginSet()
+	for name, digest := range c.providerPluginsLock().Read() {
+		out.ProviderSelections[name] = ""
+		for meta := range available.WithName(name) {
+			if d, err := meta.SHA256(); err == nil && bytes.Equal(d, digest) {
+				out.ProviderSelections[name] = string(meta.Version)
+				break
+			}
+		}
+	}
+
+	sMgr := &state.LocalState{Path: filepath.Join(c.DataDir(), DefaultStateFilename)}
+	if err := sMgr.RefreshState(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error loading the backend state: %s", err))
+		return 1
+	}
+	if s := sMgr.State(); s != nil {
+		switch {
+		case !s.Backend.Empty():
+			out.Backend = s.Backend.Type
+		case s.Remote != nil && !s.Remote.Empty():
+			out.Backend = s.Remote.Type
+		}
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error encoding the version: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(string(data))
+	return 0
+}
+
 func (c *VersionCommand) Synopsis() string {
 	return "Prints the Terraform version"
 }
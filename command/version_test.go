@@ -1,11 +1,129 @@
 package command
 
 import (
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform/terraform"
 	"github.com/mitchellh/cli"
 )
 
 func TestVersionCommand_implements(t *testing.T) {
 	var _ cli.Command = &VersionCommand{}
 }
+
+func TestVersion(t *testing.T) {
+	ui := new(cli.MockUi)
+	c := &VersionCommand{
+		Meta:              Meta{Ui: ui},
+		Version:           "4.5.6",
+		VersionPrerelease: "foo",
+		Revision:          "abc123",
+	}
+
+	if code := c.Run([]string{}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	if actual := strings.TrimSpace(ui.OutputWriter.String()); actual != "Terraform v4.5.6-foo (abc123)" {
+		t.Fatalf("bad: %s", actual)
+	}
+}
+
+func TestVersion_json(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	// An installed provider that's locked, and one that's locked but not
+	// installed
+	m := Meta{}
+	pluginDir := m.pluginDir()
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	pluginPath := filepath.Join(pluginDir, "terraform-provider-test_v1.2.3_x4")
+	if err := ioutil.WriteFile(pluginPath, []byte("test"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("test"))
+	if err := m.providerPluginsLock().Write(map[string][]byte{
+		"test":    digest[:],
+		"missing": digest[:],
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := terraform.NewState()
+	s.Backend = &terraform.BackendState{Type: "s3", Config: map[string]interface{}{}}
+	testStateFileRemote(t, s)
+
+	ui := new(cli.MockUi)
+	c := &VersionCommand{
+		Meta:     Meta{Ui: ui},
+		Version:  "4.5.6",
+		Revision: "abc123",
+		CheckFunc: func() (VersionCheckInfo, error) {
+			t.Fatal("the latest version shouldn't be checked")
+			return VersionCheckInfo{}, nil
+		},
+	}
+
+	if code := c.Run([]string{"-json"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var actual versionOutput
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &actual); err != nil {
+		t.Fatalf("bad: %s\n\n%s", err, ui.OutputWriter.String())
+	}
+	expected := versionOutput{
+		Version:  "4.5.6",
+		Revision: "abc123",
+		ProviderSelections: map[string]string{
+			"test":    "1.2.3",
+			"missing": "",
+		},
+		Backend: "s3",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestVersion_jsonEmpty(t *testing.T) {
+	td := tempDir(t)
+	os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	defer testChdir(t, td)()
+
+	ui := new(cli.MockUi)
+	c := &VersionCommand{
+		Meta:    Meta{Ui: ui},
+		Version: "4.5.6",
+	}
+
+	if code := c.Run([]string{"-json"}); code != 0 {
+		t.Fatalf("bad: %d\n\n%s", code, ui.ErrorWriter.String())
+	}
+
+	var actual map[string]interface{}
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &actual); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	expected := map[string]interface{}{
+		"terraform_version":   "4.5.6",
+		"provider_selections": map[string]interface{}{},
+		"backend":             "local",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os/signal"
 
 	"github.com/hashicorp/terraform/command"
+	"github.com/hashicorp/terraform/helper/warnings"
 	"github.com/mitchellh/cli"
 )
 
@@ -15,6 +16,10 @@ var PlumbingCommands map[string]struct{}
 // Ui is the cli.Ui used for communicating to the outside world.
 var Ui cli.Ui
 
+// Warnings collects the warnings shown by commands, for the summary at the
+// end of the run.
+var Warnings = warnings.NewCollector()
+
 const (
 	ErrorPrefix  = "e:"
 	OutputPrefix = "o:"
@@ -31,9 +36,12 @@ func init() {
 
 	meta := command.Meta{
 		Color:            true,
+		Credentials:      Credentials,
 		GlobalPluginDirs: globalPluginDirs(),
 		PluginOverrides:  &PluginOverrides,
+		UIOptions:        &UIOptions,
 		Ui:               Ui,
+		Warnings:         Warnings,
 	}
 
 	// The command list is included in the terraform -help
@@ -46,6 +54,7 @@ func init() {
 		"state":        struct{}{}, // includes all subcommands
 		"debug":        struct{}{}, // includes all subcommands
 		"force-unlock": struct{}{},
+		"plugin-serve": struct{}{},
 	}
 
 	Commands = map[string]cli.CommandFactory{
@@ -101,6 +110,12 @@ func init() {
 			}, nil
 		},
 
+		"env foreach": func() (cli.Command, error) {
+			return &command.EnvForeachCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"fmt": func() (cli.Command, error) {
 			return &command.FmtCommand{
 				Meta: meta,
@@ -137,6 +152,30 @@ func init() {
 			}, nil
 		},
 
+		"login": func() (cli.Command, error) {
+			return &command.LoginCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"module": func() (cli.Command, error) {
+			return &command.ModuleCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"module package": func() (cli.Command, error) {
+			return &command.ModulePackageCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"module publish": func() (cli.Command, error) {
+			return &command.ModulePublishCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"output": func() (cli.Command, error) {
 			return &command.OutputCommand{
 				Meta: meta,
@@ -149,12 +188,25 @@ func init() {
 			}, nil
 		},
 
+		"plugin-serve": func() (cli.Command, error) {
+			return &command.PluginServeCommand{
+				Meta:       meta,
+				ShutdownCh: makeShutdownCh(),
+			}, nil
+		},
+
 		"providers": func() (cli.Command, error) {
 			return &command.ProvidersCommand{
 				Meta: meta,
 			}, nil
 		},
 
+		"providers verify": func() (cli.Command, error) {
+			return &command.ProvidersVerifyCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"push": func() (cli.Command, error) {
 			return &command.PushCommand{
 				Meta: meta,
@@ -173,6 +225,24 @@ func init() {
 			}, nil
 		},
 
+		"stacks": func() (cli.Command, error) {
+			return &command.StacksCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"stacks plan": func() (cli.Command, error) {
+			return &command.StacksPlanCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"stacks apply": func() (cli.Command, error) {
+			return &command.StacksApplyCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"taint": func() (cli.Command, error) {
 			return &command.TaintCommand{
 				Meta: meta,
@@ -257,6 +327,12 @@ func init() {
 			}, nil
 		},
 
+		"state recover": func() (cli.Command, error) {
+			return &command.StateRecoverCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"state show": func() (cli.Command, error) {
 			return &command.StateShowCommand{
 				Meta: meta,
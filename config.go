@@ -6,9 +6,13 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/terraform/command"
+	"github.com/hashicorp/terraform/helper/credentials"
+	"github.com/hashicorp/terraform/helper/keychain"
+	"github.com/hashicorp/terraform/helper/vault"
 )
 
 // Config is the structure of the configuration for the Terraform CLI.
@@ -21,8 +25,37 @@ type Config struct {
 
 	DisableCheckpoint          bool `hcl:"disable_checkpoint"`
 	DisableCheckpointSignature bool `hcl:"disable_checkpoint_signature"`
+
+	// PluginDirs are the directories to search for plugins, in order of
+	// precedence. If set, they replace the default directories.
+	PluginDirs []string `hcl:"plugin_dirs"`
+
+	// KeychainEnv maps the names of environment variables to the names of
+	// secrets in the credential store of the operating system. The
+	// variables are set to the secrets on startup, so that providers and
+	// backends can read credentials from the environment without them
+	// being kept in files.
+	KeychainEnv map[string]string `hcl:"keychain_env"`
+
+	// Credentials are the API tokens for services, by hostname, from the
+	// credentials blocks. They take precedence over the tokens saved by
+	// "terraform login".
+	Credentials map[string]map[string]interface{} `hcl:"credentials"`
+
+	// Vault configures the Vault client that credentials, such as dynamic
+	// cloud credentials, are read from on startup and renewed while
+	// Terraform runs.
+	Vault *vault.Config `hcl:"vault"`
+
+	// UI sets how output is rendered: its colors, whether it's only ASCII
+	// and the width messages are wrapped to.
+	UI *command.UIOptions `hcl:"ui"`
 }
 
+// keychainGet reads a secret from the credential store. It's a variable so
+// that tests can replace it.
+var keychainGet = keychain.Get
+
 // BuiltinConfig is the built-in defaults for the configuration. These
 // can be overridden by user configurations.
 var BuiltinConfig Config
@@ -31,6 +64,13 @@ var BuiltinConfig Config
 // the config file.
 var PluginOverrides command.PluginOverrides
 
+// UIOptions are the options for rendering output, set from the config file.
+var UIOptions command.UIOptions
+
+// Credentials is the store of API tokens, set up from the config file and
+// the credentials file in the config directory.
+var Credentials = credentials.Default
+
 // ConfigFile returns the default path to the configuration file.
 //
 // On Unix-like systems this is the ".terraformrc" file in the home directory.
@@ -74,6 +114,52 @@ func LoadConfig(path string) (*Config, error) {
 	for k, v := range result.Provisioners {
 		result.Provisioners[k] = os.ExpandEnv(v)
 	}
+	for i, v := range result.PluginDirs {
+		result.PluginDirs[i] = os.ExpandEnv(v)
+	}
+	for host, block := range result.Credentials {
+		for k, v := range block {
+			if k != "token" && k != "vault" {
+				return nil, fmt.Errorf(
+					"Error parsing %s: credentials %q: unknown key %q", path, host, k)
+			}
+			if s, ok := v.(string); ok {
+				block[k] = os.ExpandEnv(s)
+			} else {
+				return nil, fmt.Errorf(
+					"Error parsing %s: credentials %q: %s must be a string", path, host, k)
+			}
+		}
+
+		// The token is either set directly, or read from Vault.
+		if _, ok := block["vault"]; ok {
+			if _, ok := block["token"]; ok {
+				return nil, fmt.Errorf(
+					"Error parsing %s: credentials %q: only one of token and vault can be set",
+					path, host)
+			}
+			if _, _, err := vault.ParseRef(block["vault"].(string)); err != nil {
+				return nil, fmt.Errorf(
+					"Error parsing %s: credentials %q: vault: %s", path, host, err)
+			}
+		}
+	}
+
+	if v := result.Vault; v != nil {
+		v.Address = os.ExpandEnv(v.Address)
+		v.Token = os.ExpandEnv(v.Token)
+		v.RoleID = os.ExpandEnv(v.RoleID)
+		v.SecretID = os.ExpandEnv(v.SecretID)
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("Error parsing %s: vault: %s", path, err)
+		}
+	}
+
+	if u := result.UI; u != nil {
+		if err := u.Validate(); err != nil {
+			return nil, fmt.Errorf("Error parsing %s: ui: %s", path, err)
+		}
+	}
 
 	return &result, nil
 }
@@ -105,5 +191,150 @@ func (c1 *Config) Merge(c2 *Config) *Config {
 	result.DisableCheckpoint = c1.DisableCheckpoint || c2.DisableCheckpoint
 	result.DisableCheckpointSignature = c1.DisableCheckpointSignature || c2.DisableCheckpointSignature
 
+	// The directories are in order of precedence, so they can't be merged
+	// sensibly: the later configuration replaces them.
+	result.PluginDirs = c1.PluginDirs
+	if len(c2.PluginDirs) > 0 {
+		result.PluginDirs = c2.PluginDirs
+	}
+
+	if len(c1.KeychainEnv) > 0 || len(c2.KeychainEnv) > 0 {
+		result.KeychainEnv = make(map[string]string)
+		for k, v := range c1.KeychainEnv {
+			result.KeychainEnv[k] = v
+		}
+		for k, v := range c2.KeychainEnv {
+			result.KeychainEnv[k] = v
+		}
+	}
+
+	if len(c1.Credentials) > 0 || len(c2.Credentials) > 0 {
+		result.Credentials = make(map[string]map[string]interface{})
+		for k, v := range c1.Credentials {
+			result.Credentials[k] = v
+		}
+		for k, v := range c2.Credentials {
+			result.Credentials[k] = v
+		}
+	}
+
+	// A vault block is a single connection, so the later configuration
+	// replaces it.
+	result.Vault = c1.Vault
+	if c2.Vault != nil {
+		result.Vault = c2.Vault
+	}
+
+	switch {
+	case c1.UI != nil && c2.UI != nil:
+		result.UI = c1.UI.Merge(c2.UI)
+	case c2.UI != nil:
+		result.UI = c2.UI
+	default:
+		result.UI = c1.UI
+	}
+
 	return &result
 }
+
+// CredentialsTokens returns the tokens set in the credentials blocks, by
+// hostname.
+func (c *Config) CredentialsTokens() map[string]string {
+	if len(c.Credentials) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(c.Credentials))
+	for host, block := range c.Credentials {
+		if _, ok := block["vault"]; ok {
+			continue
+		}
+
+		token, _ := block["token"].(string)
+		result[host] = token
+	}
+
+	return result
+}
+
+// CredentialsVault returns the references to the tokens in Vault set in
+// the credentials blocks, as "PATH#FIELD", by hostname.
+func (c *Config) CredentialsVault() map[string]string {
+	var result map[string]string
+	for host, block := range c.Credentials {
+		ref, ok := block["vault"].(string)
+		if !ok {
+			continue
+		}
+
+		if result == nil {
+			result = make(map[string]string)
+		}
+		result[host] = ref
+	}
+
+	return result
+}
+
+// SetKeychainEnv sets each environment variable in KeychainEnv to the
+// secret it names in the credential store. Variables that are already set
+// are left alone, so that they can still be overridden.
+//
+// Secrets that can't be read only log a warning and leave their variable
+// unset, since most commands never need them. The providers and backends
+// that do report the missing credentials themselves.
+func (c *Config) SetKeychainEnv() error {
+	names := make([]string, 0, len(c.KeychainEnv))
+	for k := range c.KeychainEnv {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		if _, ok := os.LookupEnv(k); ok {
+			log.Printf("[DEBUG] %s is already set, not reading it from the keychain", k)
+			continue
+		}
+
+		v, err := keychainGet(c.KeychainEnv[k])
+		if err != nil {
+			log.Printf("[WARN] Error reading %s from the keychain, leaving it unset: %s", k, err)
+			continue
+		}
+
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+		log.Printf("[INFO] Set %s from the keychain", k)
+	}
+
+	return nil
+}
+
+// vaultNewClient logs in to Vault. It's a variable so that tests can
+// replace it.
+var vaultNewClient = vault.NewClient
+
+// SetVaultEnv logs in to Vault, if it's configured, and sets each
+// environment variable in its env block to the secret it refers to.
+// Variables that are already set are left alone, so that they can still be
+// overridden.
+//
+// The returned client renews the leases of the secrets until it's stopped,
+// and is nil if Vault isn't configured.
+func (c *Config) SetVaultEnv() (*vault.Client, error) {
+	if c.Vault == nil {
+		return nil, nil
+	}
+
+	client, err := vaultNewClient(c.Vault)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.SetEnv(c.Vault.Env); err != nil {
+		client.Stop()
+		return nil, err
+	}
+
+	return client, nil
+}
@@ -1,5 +1,7 @@
 package config
 
+import "fmt"
+
 // Append appends one configuration to another.
 //
 // Append assumes that both configurations will not have
@@ -35,6 +37,18 @@ func Append(c1, c2 *Config) (*Config, error) {
 		c.Atlas = c2.Atlas
 	}
 
+	// A module only has one default connection, so a second one in another
+	// file is more likely a mistake than something to merge.
+	if c1.Connection != nil && c2.Connection != nil {
+		return nil, fmt.Errorf(
+			"connection: only one top-level connection block is allowed in a module; " +
+				"use an override file to override it")
+	}
+	c.Connection = c1.Connection
+	if c2.Connection != nil {
+		c.Connection = c2.Connection
+	}
+
 	// merge Terraform blocks
 	if c1.Terraform != nil {
 		c.Terraform = c1.Terraform
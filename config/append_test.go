@@ -143,6 +143,29 @@ func TestAppend(t *testing.T) {
 			},
 			false,
 		},
+
+		// Only one config can have a default connection
+		{
+			&Config{
+				Connection: &RawConfig{Raw: map[string]interface{}{"user": "a"}},
+			},
+			&Config{},
+			&Config{
+				Connection: &RawConfig{Raw: map[string]interface{}{"user": "a"}},
+			},
+			false,
+		},
+
+		{
+			&Config{
+				Connection: &RawConfig{Raw: map[string]interface{}{"user": "a"}},
+			},
+			&Config{
+				Connection: &RawConfig{Raw: map[string]interface{}{"user": "b"}},
+			},
+			nil,
+			true,
+		},
 	}
 
 	for i, tc := range cases {
@@ -3,15 +3,19 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hil"
 	"github.com/hashicorp/hil/ast"
 	"github.com/hashicorp/terraform/helper/hilmapstructure"
+	"github.com/hashicorp/terraform/helper/typedjson"
 	"github.com/hashicorp/terraform/plugin/discovery"
 	"github.com/mitchellh/reflectwalk"
 )
@@ -26,15 +30,20 @@ type Config struct {
 	// Dir is the path to the directory where this configuration was
 	// loaded from. If it is blank, this configuration wasn't loaded from
 	// any meaningful directory.
-	Dir string
+	Dir string `json:"dir"`
 
-	Terraform       *Terraform
-	Atlas           *AtlasConfig
-	Modules         []*Module
-	ProviderConfigs []*ProviderConfig
-	Resources       []*Resource
-	Variables       []*Variable
-	Outputs         []*Output
+	Terraform       *Terraform        `json:"terraform"`
+	Atlas           *AtlasConfig      `json:"atlas"`
+	Modules         []*Module         `json:"modules"`
+	ProviderConfigs []*ProviderConfig `json:"providers"`
+	Resources       []*Resource       `json:"resources"`
+	Variables       []*Variable       `json:"variables"`
+	Outputs         []*Output         `json:"outputs"`
+
+	// Connection is the default connection of the provisioners in the
+	// module, from its top-level connection block. The connections of
+	// resources and provisioners override it key by key.
+	Connection *RawConfig `json:"connection"`
 
 	// The fields below can be filled in by loaders for validation
 	// purposes.
@@ -43,9 +52,9 @@ type Config struct {
 
 // AtlasConfig is the configuration for building in HashiCorp's Atlas.
 type AtlasConfig struct {
-	Name    string
-	Include []string
-	Exclude []string
+	Name    string   `json:"name"`
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
 }
 
 // Module is a module used within a configuration.
@@ -53,9 +62,18 @@ type AtlasConfig struct {
 // This does not represent a module itself, this represents a module
 // call-site within an existing configuration.
 type Module struct {
-	Name      string
-	Source    string
-	RawConfig *RawConfig
+	Name      string     `json:"name"`
+	Source    string     `json:"source"`
+	RawConfig *RawConfig `json:"config"`
+
+	// ExportOutputs, if true, makes every output of the module an output
+	// of the module that calls it, with the same name. See Tree.Load in
+	// the module package.
+	ExportOutputs bool `json:"export_outputs"`
+
+	// Positions are where the module block is, under "", and its
+	// arguments, by name, as "file:line".
+	Positions map[string]string `json:"-"`
 }
 
 // ProviderConfig is the configuration for a resource provider.
@@ -63,10 +81,21 @@ type Module struct {
 // For example, Terraform needs to set the AWS access keys for the AWS
 // resource provider.
 type ProviderConfig struct {
-	Name      string
-	Alias     string
-	Version   string
-	RawConfig *RawConfig
+	Name      string     `json:"name"`
+	Alias     string     `json:"alias"`
+	Version   string     `json:"version"`
+	RawConfig *RawConfig `json:"config"`
+
+	// Pos is where the provider block is, as "file:line", and Override is
+	// true if it's marked to override a block of the same name.
+	Pos      string `json:"-"`
+	Override bool   `json:"-"`
+
+	// Base is the full name of the provider configuration whose arguments
+	// this one overrides, if it was expanded from a provider_config block.
+	// The base configuration, including what it inherits from parent
+	// modules, is resolved when the graph is walked.
+	Base string `json:"-"`
 }
 
 // A resource represents a single Terraform resource in the configuration.
@@ -74,15 +103,42 @@ type ProviderConfig struct {
 // usual "create, read, update, delete" operations, depending on
 // the given Mode.
 type Resource struct {
-	Mode         ResourceMode // which operations the resource supports
-	Name         string
-	Type         string
-	RawCount     *RawConfig
-	RawConfig    *RawConfig
-	Provisioners []*Provisioner
-	Provider     string
-	DependsOn    []string
-	Lifecycle    ResourceLifecycle
+	Mode         ResourceMode      `json:"mode"` // which operations the resource supports
+	Name         string            `json:"name"`
+	Type         string            `json:"type"`
+	RawCount     *RawConfig        `json:"count"`
+	RawForEach   *RawConfig        `json:"for_each"`
+	RawConfig    *RawConfig        `json:"config"`
+	Provisioners []*Provisioner    `json:"provisioners"`
+	Provider     string            `json:"provider"`
+	DependsOn    []string          `json:"depends_on"`
+	Lifecycle    ResourceLifecycle `json:"lifecycle"`
+
+	// RawProvider is set instead of Provider when the provider
+	// meta-argument is an expression. It is interpolated for each instance
+	// to select one of ProviderChoices, the configurations of the provider
+	// of the resource type that are declared in the module.
+	RawProvider     *RawConfig `json:"provider_expr"`
+	ProviderChoices []string   `json:"provider_choices"`
+
+	// ProviderOverrides is the provider_config block, which overrides
+	// arguments of the provider configuration for this resource only. When
+	// the module is loaded, it is expanded into a configuration of the
+	// provider with the alias returned by ProviderOverrideAlias, which
+	// Provider is set to.
+	ProviderOverrides *RawConfig `json:"provider_config"`
+
+	// Stage is the apply stage of the resource, from the stage
+	// meta-argument, or 0 if it doesn't have one. Every resource in a stage
+	// is created and updated before any resource in a later stage is, even
+	// if they don't depend on each other.
+	Stage int `json:"stage"`
+
+	// Positions are where the block of the resource, under "", and the
+	// attributes of its configuration are in the configuration files, as
+	// "file:line". Repeated blocks are also under their index, such as
+	// "ingress.1".
+	Positions map[string]string `json:"-"`
 }
 
 // Copy returns a copy of this Resource. Helpful for avoiding shared
@@ -94,11 +150,26 @@ func (r *Resource) Copy() *Resource {
 		Name:         r.Name,
 		Type:         r.Type,
 		RawCount:     r.RawCount.Copy(),
+		RawForEach:   r.RawForEach.Copy(),
 		RawConfig:    r.RawConfig.Copy(),
 		Provisioners: make([]*Provisioner, 0, len(r.Provisioners)),
 		Provider:     r.Provider,
 		DependsOn:    make([]string, len(r.DependsOn)),
 		Lifecycle:    *r.Lifecycle.Copy(),
+		RawProvider:  r.RawProvider.Copy(),
+
+		ProviderOverrides: r.ProviderOverrides.Copy(),
+		Stage:             r.Stage,
+	}
+	if r.ProviderChoices != nil {
+		n.ProviderChoices = make([]string, len(r.ProviderChoices))
+		copy(n.ProviderChoices, r.ProviderChoices)
+	}
+	if r.Positions != nil {
+		n.Positions = make(map[string]string, len(r.Positions))
+		for k, v := range r.Positions {
+			n.Positions[k] = v
+		}
 	}
 	for _, p := range r.Provisioners {
 		n.Provisioners = append(n.Provisioners, p.Copy())
@@ -110,9 +181,60 @@ func (r *Resource) Copy() *Resource {
 // ResourceLifecycle is used to store the lifecycle tuning parameters
 // to allow customized behavior
 type ResourceLifecycle struct {
-	CreateBeforeDestroy bool     `mapstructure:"create_before_destroy"`
-	PreventDestroy      bool     `mapstructure:"prevent_destroy"`
-	IgnoreChanges       []string `mapstructure:"ignore_changes"`
+	CreateBeforeDestroy bool     `mapstructure:"create_before_destroy" json:"create_before_destroy"`
+	PreventDestroy      bool     `mapstructure:"prevent_destroy" json:"prevent_destroy"`
+	IgnoreChanges       []string `mapstructure:"ignore_changes" json:"ignore_changes"`
+
+	// DestroyPriority orders the destruction of resources that don't
+	// depend on each other: resources with a higher priority are destroyed
+	// before those with a lower one.
+	DestroyPriority int `mapstructure:"destroy_priority" json:"destroy_priority"`
+
+	// Priority is the scheduling priority of the resource. When more
+	// resources are ready to be applied than the parallelism allows, those
+	// with a higher priority, and the resources they depend on, are started
+	// first.
+	Priority int `mapstructure:"priority" json:"priority"`
+
+	// WaitForReady makes Terraform wait, after creating the resource, for
+	// the provider to report that it is ready for use.
+	WaitForReady bool `mapstructure:"wait_for_ready" json:"wait_for_ready"`
+
+	// AdoptExisting makes Terraform adopt an existing object that matches
+	// the configuration, if the provider can find one, instead of creating
+	// a new one that would conflict with it.
+	AdoptExisting bool `mapstructure:"adopt_existing" json:"adopt_existing"`
+
+	// The hooks are shell commands that Terraform runs on the machine it
+	// runs on before and after the resource is created, updated or
+	// destroyed. They're text/template templates of the resource, which
+	// are executed with a terraform.ResourceHookData.
+	BeforeCreate  string `mapstructure:"before_create" json:"before_create"`
+	AfterCreate   string `mapstructure:"after_create" json:"after_create"`
+	BeforeUpdate  string `mapstructure:"before_update" json:"before_update"`
+	AfterUpdate   string `mapstructure:"after_update" json:"after_update"`
+	BeforeDestroy string `mapstructure:"before_destroy" json:"before_destroy"`
+	AfterDestroy  string `mapstructure:"after_destroy" json:"after_destroy"`
+}
+
+// Hooks returns the hooks of the lifecycle that are set, by the name of
+// their setting, such as "before_create".
+func (r *ResourceLifecycle) Hooks() map[string]string {
+	result := make(map[string]string)
+	for name, command := range map[string]string{
+		"before_create":  r.BeforeCreate,
+		"after_create":   r.AfterCreate,
+		"before_update":  r.BeforeUpdate,
+		"after_update":   r.AfterUpdate,
+		"before_destroy": r.BeforeDestroy,
+		"after_destroy":  r.AfterDestroy,
+	} {
+		if command != "" {
+			result[name] = command
+		}
+	}
+
+	return result
 }
 
 // Copy returns a copy of this ResourceLifecycle
@@ -120,6 +242,16 @@ func (r *ResourceLifecycle) Copy() *ResourceLifecycle {
 	n := &ResourceLifecycle{
 		CreateBeforeDestroy: r.CreateBeforeDestroy,
 		PreventDestroy:      r.PreventDestroy,
+		DestroyPriority:     r.DestroyPriority,
+		Priority:            r.Priority,
+		WaitForReady:        r.WaitForReady,
+		AdoptExisting:       r.AdoptExisting,
+		BeforeCreate:        r.BeforeCreate,
+		AfterCreate:         r.AfterCreate,
+		BeforeUpdate:        r.BeforeUpdate,
+		AfterUpdate:         r.AfterUpdate,
+		BeforeDestroy:       r.BeforeDestroy,
+		AfterDestroy:        r.AfterDestroy,
 		IgnoreChanges:       make([]string, len(r.IgnoreChanges)),
 	}
 	copy(n.IgnoreChanges, r.IgnoreChanges)
@@ -128,12 +260,12 @@ func (r *ResourceLifecycle) Copy() *ResourceLifecycle {
 
 // Provisioner is a configured provisioner step on a resource.
 type Provisioner struct {
-	Type      string
-	RawConfig *RawConfig
-	ConnInfo  *RawConfig
+	Type      string     `json:"type"`
+	RawConfig *RawConfig `json:"config"`
+	ConnInfo  *RawConfig `json:"connection"`
 
-	When      ProvisionerWhen
-	OnFailure ProvisionerOnFailure
+	When      ProvisionerWhen      `json:"when"`
+	OnFailure ProvisionerOnFailure `json:"on_failure"`
 }
 
 // Copy returns a copy of this Provisioner
@@ -149,10 +281,30 @@ func (p *Provisioner) Copy() *Provisioner {
 
 // Variable is a variable defined within the configuration.
 type Variable struct {
-	Name         string
-	DeclaredType string `mapstructure:"type"`
-	Default      interface{}
-	Description  string
+	Name         string      `json:"name"`
+	DeclaredType string      `mapstructure:"type" json:"type"`
+	Default      interface{} `json:"-"` // See MarshalJSON
+	Description  string      `json:"description"`
+
+	// Sensitive hides the value of the variable, and of every value that
+	// is interpolated from it, in the output of plan and apply.
+	Sensitive bool `json:"sensitive"`
+
+	// Deprecated is a message explaining that the variable is deprecated,
+	// and what to use instead. Module blocks that set the variable are
+	// warned about it.
+	Deprecated string `json:"deprecated"`
+
+	// Ephemeral means that the value of the variable is only used while
+	// Terraform runs, and is never stored in a plan or the state. It can
+	// only be referenced where values aren't stored, such as in provider
+	// and provisioner configuration.
+	Ephemeral bool `json:"ephemeral"`
+
+	// Pos is where the variable block is, as "file:line", and Override is
+	// true if it's marked to override a block of the same name.
+	Pos      string `json:"-"`
+	Override bool   `json:"-"`
 }
 
 // Output is an output defined within the configuration. An output is
@@ -160,11 +312,20 @@ type Variable struct {
 // output marked Sensitive will be output in a masked form following
 // application, but will still be available in state.
 type Output struct {
-	Name        string
-	DependsOn   []string
-	Description string
-	Sensitive   bool
-	RawConfig   *RawConfig
+	Name        string     `json:"name"`
+	DependsOn   []string   `json:"depends_on"`
+	Description string     `json:"description"`
+	Sensitive   bool       `json:"sensitive"`
+	RawConfig   *RawConfig `json:"config"`
+
+	// ExportedFrom is the name of the module that the output is exported
+	// from with export_outputs, if it isn't declared in the configuration.
+	ExportedFrom string `json:"exported_from"`
+
+	// Pos is where the output block is, as "file:line", and Override is
+	// true if it's marked to override a block of the same name.
+	Pos      string `json:"-"`
+	Override bool   `json:"-"`
 }
 
 // VariableType is the type of value a variable is holding, and returned
@@ -211,6 +372,17 @@ func (r *Module) Id() string {
 	return fmt.Sprintf("%s", r.Name)
 }
 
+// ArgumentPos returns where the argument with the given name is set in the
+// module block, or where the block is if it isn't known, as "file:line".
+// It returns an empty string if neither is known.
+func (r *Module) ArgumentPos(name string) string {
+	if pos, ok := r.Positions[name]; ok {
+		return pos
+	}
+
+	return r.Positions[""]
+}
+
 // Count returns the count of this resource.
 func (r *Resource) Count() (int, error) {
 	raw := r.RawCount.Value()
@@ -228,6 +400,62 @@ func (r *Resource) Count() (int, error) {
 	return int(v), nil
 }
 
+// ForEach returns the instance keys of this resource mapped to the value
+// each instance will see as "each.value". A map produces one instance per
+// key; a list is treated as a set of strings, and each element is used as
+// both the key and the value.
+//
+// This returns nil if the resource doesn't use for_each.
+func (r *Resource) ForEach() (map[string]interface{}, error) {
+	if r.RawForEach == nil {
+		return nil, nil
+	}
+
+	raw := r.RawForEach.Value()
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case []map[string]interface{}:
+		// HCL decodes map literals into a list of maps
+		result := make(map[string]interface{})
+		for _, m := range v {
+			for k, mv := range m {
+				result[k] = mv
+			}
+		}
+		return result, nil
+	case []interface{}:
+		result := make(map[string]interface{}, len(v))
+		for _, elem := range v {
+			key, ok := elem.(string)
+			if !ok {
+				return nil, fmt.Errorf(
+					"for_each list elements must be strings, got %T", elem)
+			}
+			if _, ok := result[key]; ok {
+				return nil, fmt.Errorf(
+					"for_each list contains duplicate value %q", key)
+			}
+			result[key] = key
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf(
+			"expected for_each to be a map or a list, got %T", raw)
+	}
+}
+
+// rawConfigs returns the RawConfigs of this resource that are evaluated
+// per-instance: the resource configuration itself along with the
+// configuration and connection info of its provisioners.
+func (r *Resource) rawConfigs() []*RawConfig {
+	result := []*RawConfig{r.RawConfig}
+	for _, p := range r.Provisioners {
+		result = append(result, p.RawConfig, p.ConnInfo)
+	}
+	return result
+}
+
 // A unique identifier for this resource.
 func (r *Resource) Id() string {
 	switch r.Mode {
@@ -240,6 +468,26 @@ func (r *Resource) Id() string {
 	}
 }
 
+// AttributePos returns where the attribute with the given path is set in
+// the configuration files, or the closest block that contains it, as
+// "file:line". It returns an empty string if the position isn't known.
+func (r *Resource) AttributePos(path string) string {
+	for {
+		if pos, ok := r.Positions[path]; ok {
+			return pos
+		}
+		if path == "" {
+			return ""
+		}
+
+		idx := strings.LastIndex(path, ".")
+		if idx < 0 {
+			idx = 0
+		}
+		path = path[:idx]
+	}
+}
+
 // ProviderFullName returns the full name of the provider for this resource,
 // which may either be specified explicitly using the "provider" meta-argument
 // or implied by the prefix on the resource type name.
@@ -247,6 +495,36 @@ func (r *Resource) ProviderFullName() string {
 	return ResourceProviderFullName(r.Type, r.Provider)
 }
 
+// SelectedProvider returns the provider configuration selected by the
+// interpolated RawProvider, which must be one of ProviderChoices.
+func (r *Resource) SelectedProvider() (string, error) {
+	if r.RawProvider == nil {
+		return r.ProviderFullName(), nil
+	}
+
+	if len(r.RawProvider.UnknownKeys()) > 0 {
+		return "", fmt.Errorf(
+			"%s: provider can't be computed, it must be known before planning",
+			r.Id())
+	}
+
+	name, ok := r.RawProvider.Value().(string)
+	if !ok {
+		return "", fmt.Errorf(
+			"%s: provider must be a string, got %T", r.Id(), r.RawProvider.Value())
+	}
+
+	for _, choice := range r.ProviderChoices {
+		if name == choice {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf(
+		"%s: provider %q isn't declared in the module, expected one of: %s",
+		r.Id(), name, strings.Join(r.ProviderChoices, ", "))
+}
+
 // ResourceProviderFullName returns the full (dependable) name of the
 // provider for a hypothetical resource with the given resource type and
 // explicit provider string. If the explicit provider string is empty then
@@ -288,10 +566,13 @@ func (c *Config) Validate() error {
 	vars := c.InterpolatedVariables()
 	varMap := make(map[string]*Variable)
 	for _, v := range c.Variables {
-		if _, ok := varMap[v.Name]; ok {
-			errs = append(errs, fmt.Errorf(
-				"Variable '%s': duplicate found. Variable names must be unique.",
-				v.Name))
+		if prev, ok := varMap[v.Name]; ok {
+			errs = append(errs, &DuplicateError{
+				Address: v.mergerAddress(),
+				Pos:     v.Pos,
+				PrevPos: prev.Pos,
+			})
+			continue
 		}
 
 		varMap[v.Name] = v
@@ -356,6 +637,55 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Check that ephemeral variables are only referenced where their values
+	// aren't stored in a plan or the state.
+	ephemeralSources := c.ephemeralSources()
+	for source, vs := range vars {
+		if ephemeralSources[source] {
+			continue
+		}
+
+		for _, v := range vs {
+			uv, ok := v.(*UserVariable)
+			if !ok {
+				continue
+			}
+
+			if cv, ok := varMap[uv.Name]; ok && cv.Ephemeral {
+				errs = append(errs, fmt.Errorf(
+					"%s: ephemeral variable '%s' can only be referenced in provider "+
+						"and provisioner configuration, since its value is never "+
+						"stored in a plan or the state",
+					source,
+					uv.Name))
+			}
+		}
+	}
+
+	// Check that references to globals were opted in to with the
+	// "globals" setting of the terraform block.
+	globals := make(map[string]struct{})
+	if c.Terraform != nil {
+		for _, name := range c.Terraform.Globals {
+			globals[name] = struct{}{}
+		}
+	}
+	for source, vs := range vars {
+		for _, v := range vs {
+			gv, ok := v.(*GlobalVariable)
+			if !ok {
+				continue
+			}
+
+			if _, ok := globals[gv.Name]; !ok {
+				errs = append(errs, fmt.Errorf(
+					"%s: global %q must be listed in 'globals' in the terraform block to be referenced",
+					source,
+					gv.Name))
+			}
+		}
+	}
+
 	// Check that all count variables are valid.
 	for source, vs := range vars {
 		for _, rawV := range vs {
@@ -367,6 +697,13 @@ func (c *Config) Validate() error {
 						source,
 						v.FullKey()))
 				}
+			case *EachVariable:
+				if v.Type == EachValueInvalid {
+					errs = append(errs, fmt.Errorf(
+						"%s: invalid each variable: %s",
+						source,
+						v.FullKey()))
+				}
 			case *PathVariable:
 				if v.Type == PathValueInvalid {
 					errs = append(errs, fmt.Errorf(
@@ -380,13 +717,15 @@ func (c *Config) Validate() error {
 
 	// Check that providers aren't declared multiple times and that their
 	// version constraints, where present, are syntactically valid.
-	providerSet := make(map[string]struct{})
+	providerSet := make(map[string]*ProviderConfig)
 	for _, p := range c.ProviderConfigs {
 		name := p.FullName()
-		if _, ok := providerSet[name]; ok {
-			errs = append(errs, fmt.Errorf(
-				"provider.%s: declared multiple times, you can only declare a provider once",
-				name))
+		if prev, ok := providerSet[name]; ok {
+			errs = append(errs, &DuplicateError{
+				Address: p.mergerAddress(),
+				Pos:     p.Pos,
+				PrevPos: prev.Pos,
+			})
 			continue
 		}
 
@@ -400,7 +739,7 @@ func (c *Config) Validate() error {
 			}
 		}
 
-		providerSet[name] = struct{}{}
+		providerSet[name] = p
 	}
 
 	// Check that all references to modules are valid
@@ -477,6 +816,9 @@ func (c *Config) Validate() error {
 			case *CountVariable:
 				errs = append(errs, fmt.Errorf(
 					"%s: count variables are only valid within resources", m.Name))
+			case *EachVariable:
+				errs = append(errs, fmt.Errorf(
+					"%s: each variables are only valid within resources", m.Name))
 			case *SelfVariable:
 				errs = append(errs, fmt.Errorf(
 					"%s: self variables are only valid within resources", m.Name))
@@ -546,6 +888,7 @@ func (c *Config) Validate() error {
 					v.FullKey()))
 
 			// Good
+			case *GlobalVariable:
 			case *ModuleVariable:
 			case *ResourceVariable:
 			case *TerraformVariable:
@@ -580,9 +923,58 @@ func (c *Config) Validate() error {
 		}
 		r.RawCount.init()
 
+		// Verify for_each variables and that each.* is only used by
+		// resources that set for_each.
+		if r.RawForEach != nil {
+			for _, v := range r.RawForEach.Variables {
+				switch v.(type) {
+				case *CountVariable, *EachVariable, *SelfVariable, *SimpleVariable:
+					errs = append(errs, fmt.Errorf(
+						"%s: resource for_each can't reference variable: %s",
+						n,
+						v.FullKey()))
+				}
+			}
+		} else {
+			for _, rc := range r.rawConfigs() {
+				for _, v := range rc.Variables {
+					if _, ok := v.(*EachVariable); ok {
+						errs = append(errs, fmt.Errorf(
+							"%s: each variables are only valid in resources "+
+								"that set for_each: %s",
+							n,
+							v.FullKey()))
+					}
+				}
+			}
+		}
+
+		// Verify that the provider only references values that are known
+		// before the graph is walked, since it decides which provider the
+		// instances depend on.
+		if r.RawProvider != nil && r.ProviderOverrides != nil {
+			errs = append(errs, fmt.Errorf(
+				"%s: provider_config can't be used with a provider expression", n))
+		}
+		if r.RawProvider != nil {
+			for _, v := range r.RawProvider.Variables {
+				switch v.(type) {
+				case *ModuleVariable, *ResourceVariable, *SelfVariable, *SimpleVariable:
+					errs = append(errs, fmt.Errorf(
+						"%s: resource provider can't reference variable: %s",
+						n,
+						v.FullKey()))
+				}
+			}
+		}
+
 		// Validate DependsOn
 		errs = append(errs, c.validateDependsOn(n, r.DependsOn, resources, modules)...)
 
+		// Verify that the resource doesn't depend on one that's applied
+		// in a later stage.
+		errs = append(errs, c.validateStage(n, r, resources)...)
+
 		// Verify provisioners
 		for _, p := range r.Provisioners {
 			// This validation checks that there are no splat variables
@@ -651,6 +1043,20 @@ func (c *Config) Validate() error {
 				n))
 		}
 
+		// Verify the hooks are valid templates
+		hooks := r.Lifecycle.Hooks()
+		hookNames := make([]string, 0, len(hooks))
+		for name := range hooks {
+			hookNames = append(hookNames, name)
+		}
+		sort.Strings(hookNames)
+		for _, name := range hookNames {
+			if _, err := template.New(name).Parse(hooks[name]); err != nil {
+				errs = append(errs, fmt.Errorf(
+					"%s: lifecycle %s is an invalid template: %s", n, name, err))
+			}
+		}
+
 		// If it is a data source then it can't have provisioners
 		if r.Mode == DataResourceMode {
 			if _, ok := r.RawConfig.Raw["provisioner"]; ok {
@@ -682,16 +1088,18 @@ func (c *Config) Validate() error {
 
 	// Check that all outputs are valid
 	{
-		found := make(map[string]struct{})
+		found := make(map[string]*Output)
 		for _, o := range c.Outputs {
 			// Verify the output is new
-			if _, ok := found[o.Name]; ok {
-				errs = append(errs, fmt.Errorf(
-					"%s: duplicate output. output names must be unique.",
-					o.Name))
+			if prev, ok := found[o.Name]; ok {
+				errs = append(errs, &DuplicateError{
+					Address: o.mergerAddress(),
+					Pos:     o.Pos,
+					PrevPos: prev.Pos,
+				})
 				continue
 			}
-			found[o.Name] = struct{}{}
+			found[o.Name] = o
 
 			var invalidKeys []string
 			valueKeyFound := false
@@ -737,9 +1145,13 @@ func (c *Config) Validate() error {
 			}
 
 			for _, v := range o.RawConfig.Variables {
-				if _, ok := v.(*CountVariable); ok {
+				switch v.(type) {
+				case *CountVariable:
 					errs = append(errs, fmt.Errorf(
 						"%s: count variables are only valid within resources", o.Name))
+				case *EachVariable:
+					errs = append(errs, fmt.Errorf(
+						"%s: each variables are only valid within resources", o.Name))
 				}
 			}
 		}
@@ -798,6 +1210,12 @@ func (c *Config) rawConfigs() map[string]*RawConfig {
 	for _, rc := range c.Resources {
 		source := fmt.Sprintf("resource '%s'", rc.Id())
 		result[source+" count"] = rc.RawCount
+		if rc.RawForEach != nil {
+			result[source+" for_each"] = rc.RawForEach
+		}
+		if rc.RawProvider != nil {
+			result[source+" provider"] = rc.RawProvider
+		}
 		result[source+" config"] = rc.RawConfig
 
 		for i, p := range rc.Provisioners {
@@ -816,6 +1234,118 @@ func (c *Config) rawConfigs() map[string]*RawConfig {
 	return result
 }
 
+// ephemeralSources returns the sources of rawConfigs whose values are only
+// used while Terraform runs, and are never stored in a plan or the state,
+// so they can reference ephemeral variables.
+func (c *Config) ephemeralSources() map[string]bool {
+	result := make(map[string]bool)
+	for _, pc := range c.ProviderConfigs {
+		result[fmt.Sprintf("provider config '%s'", pc.Name)] = true
+	}
+
+	for _, rc := range c.Resources {
+		for i, p := range rc.Provisioners {
+			result[fmt.Sprintf(
+				"resource '%s' provisioner %s (#%d)", rc.Id(), p.Type, i+1)] = true
+		}
+	}
+
+	return result
+}
+
+// DeprecatedInterpolations returns a message, sorted, for each part of the
+// configuration that uses interpolation syntax that still works but is
+// deprecated: an interpolation nested in another with "${...}", such as
+// "${concat("${var.a}", var.b)}", where the value can be referred to
+// directly.
+func (c *Config) DeprecatedInterpolations() []string {
+	var result []string
+	for source, rc := range c.rawConfigs() {
+		if rc == nil {
+			continue
+		}
+
+		for _, n := range rc.Interpolations {
+			if nestedInterpolation(n) {
+				result = append(result, fmt.Sprintf(
+					"%s: \"${...}\" nested in an interpolation is deprecated; "+
+						"refer to the values directly", source))
+				break
+			}
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+// nestedInterpolation returns true if the interpolation n has another
+// interpolation nested in it.
+func nestedInterpolation(n ast.Node) bool {
+	exprs := []ast.Node{n}
+	if out, ok := n.(*ast.Output); ok {
+		exprs = out.Exprs
+	}
+
+	nested := false
+	for _, e := range exprs {
+		e.Accept(func(n ast.Node) ast.Node {
+			if _, ok := n.(*ast.Output); ok {
+				nested = true
+			}
+			return n
+		})
+	}
+
+	return nested
+}
+
+// validateStage returns an error for each resource in the module that r
+// depends on and that's in a later stage than r, since r can't be applied
+// both before and after it.
+func (c *Config) validateStage(
+	n string,
+	r *Resource,
+	resources map[string]*Resource) []error {
+	if r.Stage == 0 {
+		return nil
+	}
+
+	deps := make(map[string]struct{})
+	for _, d := range r.DependsOn {
+		deps[d] = struct{}{}
+	}
+	for _, rc := range append(r.rawConfigs(), r.RawCount, r.RawForEach) {
+		if rc == nil {
+			continue
+		}
+
+		for _, v := range rc.Variables {
+			if rv, ok := v.(*ResourceVariable); ok {
+				deps[rv.ResourceId()] = struct{}{}
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(deps))
+	for id := range deps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var errs []error
+	for _, id := range ids {
+		if dep, ok := resources[id]; ok && dep.Stage > r.Stage {
+			errs = append(errs, fmt.Errorf(
+				"%s: resource in stage %d depends on %s in stage %d, "+
+					"which is applied after it",
+				n, r.Stage, id, dep.Stage))
+		}
+	}
+
+	return errs
+}
+
 func (c *Config) validateDependsOn(
 	n string,
 	v []string,
@@ -873,6 +1403,20 @@ func (m *Module) mergerMerge(other merger) merger {
 		result.Source = m2.Source
 	}
 
+	if m2.ExportOutputs {
+		result.ExportOutputs = true
+	}
+
+	if len(m2.Positions) > 0 {
+		result.Positions = make(map[string]string)
+		for k, v := range m.Positions {
+			result.Positions[k] = v
+		}
+		for k, v := range m2.Positions {
+			result.Positions[k] = v
+		}
+	}
+
 	return &result
 }
 
@@ -880,6 +1424,18 @@ func (o *Output) mergerName() string {
 	return o.Name
 }
 
+func (o *Output) mergerAddress() string {
+	return "output." + o.Name
+}
+
+func (o *Output) mergerPos() string {
+	return o.Pos
+}
+
+func (o *Output) mergerOverride() bool {
+	return o.Override
+}
+
 func (o *Output) mergerMerge(m merger) merger {
 	o2 := m.(*Output)
 
@@ -909,6 +1465,18 @@ func (c *ProviderConfig) mergerName() string {
 	return c.Name
 }
 
+func (c *ProviderConfig) mergerAddress() string {
+	return "provider." + c.FullName()
+}
+
+func (c *ProviderConfig) mergerPos() string {
+	return c.Pos
+}
+
+func (c *ProviderConfig) mergerOverride() bool {
+	return c.Override
+}
+
 func (c *ProviderConfig) mergerMerge(m merger) merger {
 	c2 := m.(*ProviderConfig)
 
@@ -923,6 +1491,109 @@ func (c *ProviderConfig) mergerMerge(m merger) merger {
 	return &result
 }
 
+// inheritConnection merges the default connection of the module into the
+// connection of each provisioner, so that the connection settings shared
+// by a module's resources, such as a bastion host, are set only once.
+func (c *Config) inheritConnection() {
+	if c.Connection == nil {
+		return
+	}
+
+	for _, r := range c.Resources {
+		for _, p := range r.Provisioners {
+			p.ConnInfo = c.Connection.merge(p.ConnInfo)
+		}
+	}
+}
+
+// setProviderChoices sets the ProviderChoices of the resources that select
+// their provider with an expression. If the module declares no
+// configuration of the provider, the default one, which is inherited or
+// empty, is the only choice.
+func (c *Config) setProviderChoices() {
+	for _, r := range c.Resources {
+		if r.RawProvider == nil {
+			continue
+		}
+
+		name := r.ProviderFullName()
+		var choices []string
+		for _, pc := range c.ProviderConfigs {
+			if pc.Name == name {
+				choices = append(choices, pc.FullName())
+			}
+		}
+		if len(choices) == 0 {
+			choices = []string{name}
+		}
+		sort.Strings(choices)
+
+		r.ProviderChoices = choices
+	}
+}
+
+// ProviderOverrideAlias returns the alias of the provider configuration
+// that the provider_config block of the resource is expanded into.
+func (r *Resource) ProviderOverrideAlias() string {
+	prefix := "provider_config_"
+	if r.Mode == DataResourceMode {
+		prefix += "data_"
+	}
+
+	return fmt.Sprintf("%s%s_%s", prefix, r.Type, r.Name)
+}
+
+// ProviderOverrideArgs are the provider arguments that a provider_config
+// block can override. They're the ones that commonly vary between the
+// resources of a provider, rather than credentials or settings of the
+// provider itself.
+var ProviderOverrideArgs = []string{
+	"endpoints",
+	"location",
+	"profile",
+	"project",
+	"region",
+	"zone",
+}
+
+// expandProviderOverrides adds a provider configuration for each resource
+// with a provider_config block, and changes the resource to use it. The
+// configuration only has the arguments of the block, and its Base is the
+// provider configuration that the resource would otherwise use, which
+// supplies the other arguments.
+func (c *Config) expandProviderOverrides() error {
+	for _, r := range c.Resources {
+		// Resources that select their provider with an expression are
+		// rejected by Validate.
+		if r.ProviderOverrides == nil || r.RawProvider != nil {
+			continue
+		}
+
+		base := r.ProviderFullName()
+		pc := &ProviderConfig{
+			Name:      strings.SplitN(base, ".", 2)[0],
+			Alias:     r.ProviderOverrideAlias(),
+			RawConfig: r.ProviderOverrides.Copy(),
+			Base:      base,
+		}
+		for _, existing := range c.ProviderConfigs {
+			if existing.FullName() == pc.FullName() {
+				return fmt.Errorf(
+					"%s: provider_config: the provider %s is already declared",
+					r.Id(), pc.FullName())
+			}
+			if existing.FullName() == base {
+				pc.Version = existing.Version
+			}
+		}
+
+		c.ProviderConfigs = append(c.ProviderConfigs, pc)
+		r.Provider = pc.FullName()
+	}
+
+	return nil
+}
+
 func (r *Resource) mergerName() string {
 	return r.Id()
 }
@@ -940,13 +1611,65 @@ func (r *Resource) mergerMerge(m merger) merger {
 		result.RawCount = r2.RawCount
 	}
 
+	if r2.RawForEach != nil {
+		result.RawForEach = r2.RawForEach
+	}
+
 	if len(r2.Provisioners) > 0 {
 		result.Provisioners = r2.Provisioners
 	}
 
+	if r2.ProviderOverrides != nil {
+		result.ProviderOverrides = r2.ProviderOverrides
+	}
+
+	if r2.Stage != 0 {
+		result.Stage = r2.Stage
+	}
+
+	if len(r2.Positions) > 0 {
+		result.Positions = make(map[string]string)
+		for k, v := range r.Positions {
+			result.Positions[k] = v
+		}
+		for k, v := range r2.Positions {
+			result.Positions[k] = v
+		}
+	}
+
 	return &result
 }
 
+// MarshalJSON encodes the variable as JSON, keeping the type of its
+// default value.
+func (v *Variable) MarshalJSON() ([]byte, error) {
+	type variable Variable
+	return json.Marshal(&struct {
+		*variable
+		Default typedjson.Value `json:"default"`
+	}{
+		variable: (*variable)(v),
+		Default:  typedjson.Value{V: v.Default},
+	})
+}
+
+// UnmarshalJSON decodes a variable that was encoded with MarshalJSON.
+func (v *Variable) UnmarshalJSON(data []byte) error {
+	type variable Variable
+	result := &struct {
+		*variable
+		Default typedjson.Value `json:"default"`
+	}{
+		variable: (*variable)(v),
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return err
+	}
+
+	v.Default = result.Default.V
+	return nil
+}
+
 // Merge merges two variables to create a new third variable.
 func (v *Variable) Merge(v2 *Variable) *Variable {
 	// Shallow copy the variable
@@ -964,6 +1687,15 @@ func (v *Variable) Merge(v2 *Variable) *Variable {
 	if v2.Description != "" {
 		result.Description = v2.Description
 	}
+	if v2.Sensitive {
+		result.Sensitive = true
+	}
+	if v2.Deprecated != "" {
+		result.Deprecated = v2.Deprecated
+	}
+	if v2.Ephemeral {
+		result.Ephemeral = true
+	}
 
 	return &result
 }
@@ -1024,6 +1756,18 @@ func (v *Variable) mergerName() string {
 	return v.Name
 }
 
+func (v *Variable) mergerAddress() string {
+	return "var." + v.Name
+}
+
+func (v *Variable) mergerPos() string {
+	return v.Pos
+}
+
+func (v *Variable) mergerOverride() bool {
+	return v.Override
+}
+
 func (v *Variable) mergerMerge(m merger) merger {
 	return v.Merge(m.(*Variable))
 }
@@ -318,6 +318,19 @@ func variablesStr(vs []*Variable) string {
 			declaredType = fmt.Sprintf(" (%s)", v.DeclaredType)
 		}
 
+		sensitive := ""
+		if v.Sensitive {
+			sensitive = " (sensitive)"
+		}
+		deprecated := ""
+		if v.Deprecated != "" {
+			deprecated = " (deprecated)"
+		}
+		ephemeral := ""
+		if v.Ephemeral {
+			ephemeral = " (ephemeral)"
+		}
+
 		if v.Default == nil || v.Default == "" {
 			v.Default = "<>"
 		}
@@ -326,10 +339,13 @@ func variablesStr(vs []*Variable) string {
 		}
 
 		result += fmt.Sprintf(
-			"%s%s%s\n  %v\n  %s\n",
+			"%s%s%s%s%s%s\n  %v\n  %s\n",
 			k,
 			required,
 			declaredType,
+			sensitive,
+			deprecated,
+			ephemeral,
 			v.Default,
 			v.Description)
 	}
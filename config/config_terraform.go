@@ -1,18 +1,45 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform/helper/warnings"
 	"github.com/mitchellh/hashstructure"
 )
 
 // Terraform is the Terraform meta-configuration that can be present
 // in configuration files for configuring Terraform itself.
 type Terraform struct {
-	RequiredVersion string   `hcl:"required_version"` // Required Terraform version (constraint)
-	Backend         *Backend // See Backend struct docs
+	RequiredVersion string   `hcl:"required_version" json:"required_version"` // Required Terraform version (constraint)
+	Backend         *Backend `json:"backend"`                                 // See Backend struct docs
+
+	// Globals are the names of the root module variables that this module
+	// opts in to referencing as "global.NAME".
+	Globals []string `hcl:"globals" json:"globals"`
+
+	// Deprecated is a message explaining that this module is deprecated,
+	// which is shown as a warning to configurations that use it.
+	Deprecated string `hcl:"deprecated" json:"deprecated"`
+
+	// RecommendedVersion is a Terraform version constraint that, unlike
+	// RequiredVersion, only causes a warning if it isn't met.
+	RecommendedVersion string `hcl:"recommended_version" json:"recommended_version"`
+
+	// Strict turns warnings into errors. Only the strict block of the root
+	// module is used.
+	Strict *Strict `json:"strict"`
+}
+
+// Strict is the configuration of strict mode, which turns the warnings of
+// the given classes into errors, so that a standard can be enforced on
+// configurations once they meet it.
+type Strict struct {
+	// Warnings are the codes of the warnings that are errors.
+	Warnings []string `hcl:"warnings" json:"warnings"`
 }
 
 // Validate performs the validation for just the Terraform configuration.
@@ -20,23 +47,14 @@ func (t *Terraform) Validate() []error {
 	var errs []error
 
 	if raw := t.RequiredVersion; raw != "" {
-		// Check that the value has no interpolations
-		rc, err := NewRawConfig(map[string]interface{}{
-			"root": raw,
-		})
-		if err != nil {
-			errs = append(errs, fmt.Errorf(
-				"terraform.required_version: %s", err))
-		} else if len(rc.Interpolations) > 0 {
-			errs = append(errs, fmt.Errorf(
-				"terraform.required_version: cannot contain interpolations"))
-		} else {
-			// Check it is valid
-			_, err := version.NewConstraint(raw)
-			if err != nil {
-				errs = append(errs, fmt.Errorf(
-					"terraform.required_version: invalid syntax: %s", err))
-			}
+		if err := validateVersionConstraint(raw); err != nil {
+			errs = append(errs, fmt.Errorf("terraform.required_version: %s", err))
+		}
+	}
+
+	if raw := t.RecommendedVersion; raw != "" {
+		if err := validateVersionConstraint(raw); err != nil {
+			errs = append(errs, fmt.Errorf("terraform.recommended_version: %s", err))
 		}
 	}
 
@@ -44,6 +62,29 @@ func (t *Terraform) Validate() []error {
 		errs = append(errs, t.Backend.Validate()...)
 	}
 
+	if t.Strict != nil {
+		for _, code := range t.Strict.Warnings {
+			if !warnings.IsCode(code) {
+				errs = append(errs, fmt.Errorf(
+					"terraform.strict: unknown warning code %q (valid codes are: %s)",
+					code, strings.Join(warnings.Codes, ", ")))
+			}
+		}
+	}
+
+	seen := make(map[string]struct{})
+	for _, name := range t.Globals {
+		if !NameRegexp.MatchString(name) {
+			errs = append(errs, fmt.Errorf(
+				"terraform.globals: invalid variable name %q", name))
+		}
+		if _, ok := seen[name]; ok {
+			errs = append(errs, fmt.Errorf(
+				"terraform.globals: %q listed more than once", name))
+		}
+		seen[name] = struct{}{}
+	}
+
 	return errs
 }
 
@@ -57,6 +98,44 @@ func (t *Terraform) Merge(t2 *Terraform) {
 	if t2.Backend != nil {
 		t.Backend = t2.Backend
 	}
+
+	if t2.Globals != nil {
+		t.Globals = t2.Globals
+	}
+
+	if t2.Deprecated != "" {
+		t.Deprecated = t2.Deprecated
+	}
+
+	if t2.RecommendedVersion != "" {
+		t.RecommendedVersion = t2.RecommendedVersion
+	}
+
+	if t2.Strict != nil {
+		t.Strict = t2.Strict
+	}
+}
+
+// validateVersionConstraint checks that raw is a valid version constraint
+// without interpolations.
+func validateVersionConstraint(raw string) error {
+	// Check that the value has no interpolations
+	rc, err := NewRawConfig(map[string]interface{}{
+		"root": raw,
+	})
+	if err != nil {
+		return err
+	}
+	if len(rc.Interpolations) > 0 {
+		return fmt.Errorf("cannot contain interpolations")
+	}
+
+	// Check it is valid
+	if _, err := version.NewConstraint(raw); err != nil {
+		return fmt.Errorf("invalid syntax: %s", err)
+	}
+
+	return nil
 }
 
 // Backend is the configuration for the "backend" to use with Terraform.
@@ -64,12 +143,12 @@ func (t *Terraform) Merge(t2 *Terraform) {
 // The abstraction layer above the core (the "backend") allows for behavior
 // such as remote operation.
 type Backend struct {
-	Type      string
-	RawConfig *RawConfig
+	Type      string     `json:"type"`
+	RawConfig *RawConfig `json:"config"`
 
 	// Hash is a unique hash code representing the original configuration
 	// of the backend. This won't be recomputed unless Rehash is called.
-	Hash uint64
+	Hash uint64 `json:"hash"`
 }
 
 // Rehash returns a unique content hash for this backend's configuration
@@ -96,20 +175,112 @@ func (b *Backend) Rehash() uint64 {
 }
 
 func (b *Backend) Validate() []error {
-	if len(b.RawConfig.Interpolations) > 0 {
-		return []error{fmt.Errorf(strings.TrimSpace(errBackendInterpolations))}
+	// The path variables and the workspace are the only values that are
+	// known this early, and functions could give a different configuration
+	// every time.
+	for _, v := range b.RawConfig.Variables {
+		switch v := v.(type) {
+		case *PathVariable:
+		case *TerraformVariable:
+			if v.Field != "workspace" && v.Field != "env" {
+				return []error{errors.New(strings.TrimSpace(errBackendInterpolations))}
+			}
+		default:
+			return []error{errors.New(strings.TrimSpace(errBackendInterpolations))}
+		}
+	}
+	for _, n := range b.RawConfig.Interpolations {
+		call := false
+		n.Accept(func(n ast.Node) ast.Node {
+			if _, ok := n.(*ast.Call); ok {
+				call = true
+			}
+			return n
+		})
+		if call {
+			return []error{errors.New(strings.TrimSpace(errBackendInterpolations))}
+		}
+	}
+
+	return nil
+}
+
+// Interpolate interpolates the path variables in the configuration of the
+// backend of the root module in the directory root, and replaces the
+// configuration with the result, so that it has only literal values apart
+// from "${terraform.workspace}".
+//
+// The workspace is left in the configuration, so that the configuration
+// that's saved by "terraform init" doesn't change when another workspace
+// is selected. It's replaced by BackendWorkspaceConfig whenever the backend
+// is configured.
+func (b *Backend) Interpolate(root string) error {
+	if len(b.RawConfig.Interpolations) == 0 {
+		return nil
+	}
+
+	vs, err := PathValues(root, root)
+	if err != nil {
+		return err
+	}
+
+	// Interpolating the workspace to the interpolation of itself keeps it,
+	// and turns terraform.env into terraform.workspace, so that there's only
+	// one name to replace later.
+	for _, k := range []string{"terraform.workspace", "terraform.env"} {
+		vs[k] = ast.Variable{
+			Type:  ast.TypeString,
+			Value: "${terraform.workspace}",
+		}
+	}
+
+	if err := b.RawConfig.Interpolate(vs); err != nil {
+		return err
+	}
+
+	rc, err := NewRawConfig(b.RawConfig.Config())
+	if err != nil {
+		return err
 	}
+	b.RawConfig = rc
 
 	return nil
 }
 
+// BackendWorkspaceConfig returns the raw configuration of a backend, as it
+// is after Interpolate, with "${terraform.workspace}" replaced by the name
+// of the given workspace.
+func BackendWorkspaceConfig(raw map[string]interface{}, workspace string) (map[string]interface{}, error) {
+	rc, err := NewRawConfig(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(rc.Interpolations) == 0 {
+		return raw, nil
+	}
+
+	vs := map[string]ast.Variable{
+		"terraform.workspace": ast.Variable{
+			Type:  ast.TypeString,
+			Value: workspace,
+		},
+	}
+	if err := rc.Interpolate(vs); err != nil {
+		return nil, err
+	}
+
+	return rc.Config(), nil
+}
+
 const errBackendInterpolations = `
-terraform.backend: configuration cannot contain interpolations
+terraform.backend: configuration cannot contain interpolations other than
+path.root, path.module, path.cwd and terraform.workspace
 
 The backend configuration is loaded by Terraform extremely early, before
 the core of Terraform can be initialized. This is necessary because the backend
 dictates the behavior of that core. The core is what handles interpolation
-processing. Because of this, interpolations cannot be used in backend
+processing. Because of this, only the path variables and the name of the
+workspace, which are known before anything else, can be used in backend
 configuration.
 
 If you'd like to parameterize backend configuration, we recommend using
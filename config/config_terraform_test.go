@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -53,3 +54,54 @@ func TestBackendHash(t *testing.T) {
 		})
 	}
 }
+
+func TestBackendInterpolate(t *testing.T) {
+	c := testConfig(t, "validate-backend-interpolate-path")
+	b := c.Terraform.Backend
+	if err := b.Interpolate("/root"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(b.RawConfig.Interpolations) > 0 {
+		t.Fatalf("bad: %#v", b.RawConfig.Interpolations)
+	}
+	if v := b.RawConfig.Raw["path"]; v != "/root/terraform.tfstate" {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+func TestBackendInterpolate_workspace(t *testing.T) {
+	c := testConfig(t, "validate-backend-interpolate-workspace")
+	b := c.Terraform.Backend
+	hash := b.Hash
+	if err := b.Interpolate("/root"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The workspace is kept, so that the saved configuration doesn't depend
+	// on the workspace
+	expected := map[string]interface{}{
+		"path":   "/root/${terraform.workspace}.tfstate",
+		"bucket": "state-${terraform.workspace}",
+		"region": "us-east-1",
+	}
+	if !reflect.DeepEqual(b.RawConfig.Raw, expected) {
+		t.Fatalf("bad: %#v", b.RawConfig.Raw)
+	}
+	if b.Hash != hash {
+		t.Fatal("hash changed")
+	}
+
+	actual, err := BackendWorkspaceConfig(b.RawConfig.Raw, "prod")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected = map[string]interface{}{
+		"path":   "/root/prod.tfstate",
+		"bucket": "state-prod",
+		"region": "us-east-1",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -11,6 +12,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hil/ast"
 	"github.com/hashicorp/terraform/helper/logging"
 )
@@ -77,6 +79,50 @@ func TestConfigCopy(t *testing.T) {
 
 }
 
+func TestConfigJSON(t *testing.T) {
+	cases := []string{
+		"basic.tf",
+		"connection.tf",
+		"modules.tf",
+		"provisioners.tf",
+		"terraform-backend.tf",
+		filepath.Join("count-int", "main.tf"),
+	}
+
+	for _, tc := range cases {
+		c, err := LoadFile(filepath.Join(fixtureDir, tc))
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc, err)
+		}
+
+		data, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc, err)
+		}
+
+		var actual Config
+		if err := json.Unmarshal(data, &actual); err != nil {
+			t.Fatalf("%s: err: %s", tc, err)
+		}
+
+		// The interpolations in the configuration are found by walking
+		// maps, so they aren't in the same order every time. Encoding the
+		// decoded configuration again gives the same result though.
+		actualData, err := json.Marshal(&actual)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc, err)
+		}
+		if string(actualData) != string(data) {
+			t.Fatalf("%s: bad:\n\n%s\n\nexpected:\n\n%s", tc, actualData, data)
+		}
+		for i, r := range c.Resources {
+			if !reflect.DeepEqual(actual.Resources[i].RawConfig.Raw, r.RawConfig.Raw) {
+				t.Fatalf("%s: bad: %#v", tc, actual.Resources[i].RawConfig.Raw)
+			}
+		}
+	}
+}
+
 func TestConfigCount(t *testing.T) {
 	c := testConfig(t, "count-int")
 	actual, err := c.Resources[0].Count()
@@ -201,6 +247,30 @@ func TestConfigValidate_table(t *testing.T) {
 			true,
 			"cannot contain interp",
 		},
+		{
+			"backend config with path interpolations",
+			"validate-backend-interpolate-path",
+			false,
+			"",
+		},
+		{
+			"backend config with function calls",
+			"validate-backend-interpolate-func",
+			true,
+			"cannot contain interp",
+		},
+		{
+			"backend config with workspace interpolations",
+			"validate-backend-interpolate-workspace",
+			false,
+			"",
+		},
+		{
+			"backend config with other terraform interpolations",
+			"validate-backend-interpolate-terraform",
+			true,
+			"cannot contain interp",
+		},
 		{
 			"nested types in variable default",
 			"validate-var-nested",
@@ -261,6 +331,60 @@ func TestConfigValidate_tfVersionInterpolations(t *testing.T) {
 	}
 }
 
+func TestConfigValidate_tfRecommendedVersion(t *testing.T) {
+	c := testConfig(t, "validate-tf-recommended-version")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if got, want := c.Terraform.Deprecated, "Use the network-v2 module instead."; got != want {
+		t.Fatalf("wrong deprecation message %q; want %q", got, want)
+	}
+	if got, want := c.Terraform.RecommendedVersion, ">= 0.10.0"; got != want {
+		t.Fatalf("wrong recommended version %q; want %q", got, want)
+	}
+}
+
+func TestConfigValidate_tfRecommendedVersionBad(t *testing.T) {
+	c := testConfig(t, "validate-bad-tf-recommended-version")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
+func TestConfigValidate_tfStrict(t *testing.T) {
+	c := testConfig(t, "validate-tf-strict")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"deprecated-interpolation", "provider-unconstrained"}
+	if !reflect.DeepEqual(c.Terraform.Strict.Warnings, expected) {
+		t.Fatalf("bad: %#v", c.Terraform.Strict)
+	}
+}
+
+func TestConfigValidate_tfStrictBad(t *testing.T) {
+	c := testConfig(t, "validate-bad-tf-strict")
+	err := c.Validate()
+	if err == nil || !strings.Contains(err.Error(), `"nope"`) {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestConfigDeprecatedInterpolations(t *testing.T) {
+	c := testConfig(t, "deprecated-interpolation")
+
+	actual := c.DeprecatedInterpolations()
+	expected := []string{
+		`output 'foo': "${...}" nested in an interpolation is deprecated; refer to the values directly`,
+		`resource 'aws_instance.foo' config: "${...}" nested in an interpolation is deprecated; refer to the values directly`,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestConfigValidate_badDependsOn(t *testing.T) {
 	c := testConfig(t, "validate-bad-depends-on")
 	if err := c.Validate(); err == nil {
@@ -298,6 +422,20 @@ func TestConfigValidate_countCountVar(t *testing.T) {
 	}
 }
 
+func TestConfigValidate_providerExprResourceVar(t *testing.T) {
+	c := testConfig(t, "validate-provider-expr-resource-var")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
+func TestConfigValidate_providerOverridesExpr(t *testing.T) {
+	c := testConfig(t, "validate-provider-overrides-expr")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
 func TestConfigValidate_countNotInt(t *testing.T) {
 	c := testConfig(t, "validate-count-not-int")
 	if err := c.Validate(); err == nil {
@@ -340,6 +478,20 @@ func TestConfigValidate_dependsOnVar(t *testing.T) {
 	}
 }
 
+func TestConfigValidate_stage(t *testing.T) {
+	c := testConfig(t, "validate-stage")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigValidate_stageLater(t *testing.T) {
+	c := testConfig(t, "validate-stage-later")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
 func TestConfigValidate_dupModule(t *testing.T) {
 	c := testConfig(t, "validate-dup-module")
 	if err := c.Validate(); err == nil {
@@ -375,6 +527,14 @@ func TestConfigValidate_ignoreChangesInterpolate(t *testing.T) {
 	}
 }
 
+func TestConfigValidate_lifecycleHookBad(t *testing.T) {
+	c := testConfig(t, "validate-lifecycle-hook-bad")
+	err := c.Validate()
+	if err == nil || !strings.Contains(err.Error(), "lifecycle before_create is an invalid template") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
 func TestConfigValidate_moduleNameBad(t *testing.T) {
 	c := testConfig(t, "validate-module-name-bad")
 	if err := c.Validate(); err == nil {
@@ -451,6 +611,46 @@ func TestConfigValidate_outputDuplicate(t *testing.T) {
 	}
 }
 
+func TestConfigValidate_dupFiles(t *testing.T) {
+	c, err := LoadDir(filepath.Join(fixtureDir, "validate-dup-files"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = c.Validate()
+	if err == nil {
+		t.Fatal("should not be valid")
+	}
+
+	dir := filepath.Join(fixtureDir, "validate-dup-files")
+	expected := map[string]*DuplicateError{
+		"var.foo": &DuplicateError{
+			Address: "var.foo",
+			Pos:     filepath.Join(dir, "b.tf") + ":5",
+			PrevPos: filepath.Join(dir, "a.tf") + ":1",
+		},
+		"output.foo": &DuplicateError{
+			Address: "output.foo",
+			Pos:     filepath.Join(dir, "b.tf") + ":9",
+			PrevPos: filepath.Join(dir, "a.tf") + ":3",
+		},
+		"provider.aws": &DuplicateError{
+			Address: "provider.aws",
+			Pos:     filepath.Join(dir, "b.tf") + ":1",
+			PrevPos: filepath.Join(dir, "a.tf") + ":7",
+		},
+	}
+	actual := make(map[string]*DuplicateError)
+	for _, err := range err.(*multierror.Error).Errors {
+		if dup, ok := err.(*DuplicateError); ok {
+			actual[dup.Address] = dup
+		}
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestConfigValidate_pathVar(t *testing.T) {
 	c := testConfig(t, "validate-path-var")
 	if err := c.Validate(); err != nil {
@@ -598,6 +798,31 @@ func TestConfigValidate_varDup(t *testing.T) {
 	}
 }
 
+func TestConfigValidate_varEphemeral(t *testing.T) {
+	c := testConfig(t, "validate-var-ephemeral")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("should be valid: %s", err)
+	}
+}
+
+func TestConfigValidate_varEphemeralResource(t *testing.T) {
+	c := testConfig(t, "validate-var-ephemeral-resource")
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("should not be valid")
+	}
+	if !strings.Contains(err.Error(), "ephemeral variable 'password'") {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestConfigValidate_varEphemeralOutput(t *testing.T) {
+	c := testConfig(t, "validate-var-ephemeral-output")
+	if err := c.Validate(); err == nil {
+		t.Fatal("should not be valid")
+	}
+}
+
 func TestConfigValidate_varMultiExactNonSlice(t *testing.T) {
 	c := testConfig(t, "validate-var-multi-exact-non-slice")
 	if err := c.Validate(); err != nil {
@@ -676,6 +901,42 @@ func testConfig(t *testing.T, name string) *Config {
 	return c
 }
 
+func TestResourceSelectedProvider(t *testing.T) {
+	r := &Resource{
+		Name:            "foo",
+		Type:            "aws_instance",
+		ProviderChoices: []string{"aws.east", "aws.west"},
+	}
+
+	cases := []struct {
+		Value    string
+		Expected string
+		Err      bool
+	}{
+		{"aws.east", "aws.east", false},
+		{"aws.west", "aws.west", false},
+		{"aws.north", "", true},
+		{"aws", "", true},
+	}
+
+	for _, tc := range cases {
+		raw, err := NewRawConfig(map[string]interface{}{"provider": tc.Value})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		raw.Key = "provider"
+		r.RawProvider = raw
+
+		actual, err := r.SelectedProvider()
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: err: %v", tc.Value, err)
+		}
+		if actual != tc.Expected {
+			t.Fatalf("%s: bad: %s", tc.Value, actual)
+		}
+	}
+}
+
 func TestConfigDataCount(t *testing.T) {
 	c := testConfig(t, "data-count")
 	actual, err := c.Resources[0].Count()
@@ -693,6 +954,69 @@ func TestConfigDataCount(t *testing.T) {
 	}
 }
 
+func TestConfigForEach(t *testing.T) {
+	c := testConfig(t, "for-each")
+
+	expected := map[string]map[string]interface{}{
+		"aws_instance.list": {"a": "a", "b": "b"},
+		"aws_instance.map":  {"foo": "bar"},
+	}
+	for _, r := range c.Resources {
+		actual, err := r.ForEach()
+		if err != nil {
+			t.Fatalf("%s: err: %s", r.Id(), err)
+		}
+		if !reflect.DeepEqual(actual, expected[r.Id()]) {
+			t.Fatalf("%s: bad: %#v", r.Id(), actual)
+		}
+
+		if _, ok := r.RawConfig.Raw["for_each"]; ok {
+			t.Fatalf("%s: for_each key still exists in RawConfig", r.Id())
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestConfigForEach_count(t *testing.T) {
+	_, err := LoadFile(filepath.Join(fixtureDir, "validate-for-each-count", "main.tf"))
+	if err == nil {
+		t.Fatal("should not load")
+	}
+	if !strings.Contains(err.Error(), "count and for_each cannot both be set") {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestConfigValidate_forEachBadContext(t *testing.T) {
+	c := testConfig(t, "validate-for-each-bad-context")
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("should not be valid")
+	}
+
+	expected := []string{
+		"aws_instance.no_for_each: each variables are only valid in resources that set for_each",
+		"no_each_in_output: each variables are only valid within resources",
+		"no_each_in_module: each variables are only valid within resources",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(err.Error(), exp) {
+			t.Fatalf("expected: %q,\nto contain: %q", err, exp)
+		}
+	}
+}
+
+func TestConfigValidate_forEachVar(t *testing.T) {
+	c := testConfig(t, "validate-for-each-var")
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
 func TestConfigProviderVersion(t *testing.T) {
 	c := testConfig(t, "provider-version")
 
@@ -31,6 +31,31 @@ const (
 	CountValueIndex
 )
 
+// EachVariable is a variable for referencing the key or value of the
+// current instance of a resource that uses for_each.
+type EachVariable struct {
+	Type EachValueType
+	key  string
+}
+
+// EachValueType is the type of the each variable that is referenced.
+type EachValueType byte
+
+const (
+	EachValueInvalid EachValueType = iota
+	EachValueKey
+	EachValueValue
+)
+
+// A GlobalVariable is a variable that is referencing a variable of the
+// root module from any module that opted in to it, such as
+// "${global.region}"
+type GlobalVariable struct {
+	Name string
+
+	key string
+}
+
 // A ModuleVariable is a variable that is referencing the output
 // of a module, such as "${module.foo.bar}"
 type ModuleVariable struct {
@@ -104,6 +129,10 @@ type UserVariable struct {
 func NewInterpolatedVariable(v string) (InterpolatedVariable, error) {
 	if strings.HasPrefix(v, "count.") {
 		return NewCountVariable(v)
+	} else if strings.HasPrefix(v, "each.") {
+		return NewEachVariable(v)
+	} else if strings.HasPrefix(v, "global.") {
+		return NewGlobalVariable(v)
 	} else if strings.HasPrefix(v, "path.") {
 		return NewPathVariable(v)
 	} else if strings.HasPrefix(v, "self.") {
@@ -139,6 +168,46 @@ func (c *CountVariable) FullKey() string {
 	return c.key
 }
 
+func NewEachVariable(key string) (*EachVariable, error) {
+	var fieldType EachValueType
+	parts := strings.SplitN(key, ".", 2)
+	switch parts[1] {
+	case "key":
+		fieldType = EachValueKey
+	case "value":
+		fieldType = EachValueValue
+	}
+
+	return &EachVariable{
+		Type: fieldType,
+		key:  key,
+	}, nil
+}
+
+func (v *EachVariable) FullKey() string {
+	return v.key
+}
+
+func NewGlobalVariable(key string) (*GlobalVariable, error) {
+	name := key[len("global."):]
+	if strings.Contains(name, ".") {
+		return nil, fmt.Errorf("Invalid dot index found: '%s'. Values in maps and lists can be referenced using square bracket indexing, like: 'global.mymap[\"key\"]' or 'global.mylist[1]'.", key)
+	}
+
+	return &GlobalVariable{
+		Name: name,
+		key:  key,
+	}, nil
+}
+
+func (v *GlobalVariable) FullKey() string {
+	return v.key
+}
+
+func (v *GlobalVariable) GoString() string {
+	return fmt.Sprintf("*%#v", *v)
+}
+
 func NewModuleVariable(key string) (*ModuleVariable, error) {
 	parts := strings.SplitN(key, ".", 3)
 	if len(parts) < 3 {
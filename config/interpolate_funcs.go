@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
@@ -20,6 +21,7 @@ import (
 	"time"
 
 	"github.com/apparentlymart/go-cidr/cidr"
+	"github.com/ghodss/yaml"
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/hil"
 	"github.com/hashicorp/hil/ast"
@@ -73,13 +75,14 @@ func Funcs() map[string]ast.Function {
 		"dirname":      interpolationFuncDirname(),
 		"distinct":     interpolationFuncDistinct(),
 		"element":      interpolationFuncElement(),
-		"file":         interpolationFuncFile(),
+		"file":         interpolationFuncFile(nil),
 		"matchkeys":    interpolationFuncMatchKeys(),
 		"floor":        interpolationFuncFloor(),
 		"format":       interpolationFuncFormat(),
 		"formatlist":   interpolationFuncFormatList(),
 		"index":        interpolationFuncIndex(),
 		"join":         interpolationFuncJoin(),
+		"jsondecode":   interpolationFuncJSONDecode(),
 		"jsonencode":   interpolationFuncJSONEncode(),
 		"length":       interpolationFuncLength(),
 		"list":         interpolationFuncList(),
@@ -93,6 +96,8 @@ func Funcs() map[string]ast.Function {
 		"pathexpand":   interpolationFuncPathExpand(),
 		"pow":          interpolationFuncPow(),
 		"uuid":         interpolationFuncUUID(),
+		"regex":        interpolationFuncRegex(),
+		"regexall":     interpolationFuncRegexAll(),
 		"replace":      interpolationFuncReplace(),
 		"sha1":         interpolationFuncSha1(),
 		"sha256":       interpolationFuncSha256(),
@@ -106,6 +111,7 @@ func Funcs() map[string]ast.Function {
 		"title":        interpolationFuncTitle(),
 		"trimspace":    interpolationFuncTrimSpace(),
 		"upper":        interpolationFuncUpper(),
+		"yamldecode":   interpolationFuncYAMLDecode(),
 		"zipmap":       interpolationFuncZipMap(),
 	}
 }
@@ -409,8 +415,9 @@ func interpolationFuncPow() ast.Function {
 }
 
 // interpolationFuncFile implements the "file" function that allows
-// loading contents from a file.
-func interpolationFuncFile() ast.Function {
+// loading contents from a file. Relative paths are relative to the module
+// given by the path variables in vs, if there are any.
+func interpolationFuncFile(vs map[string]ast.Variable) ast.Function {
 	return ast.Function{
 		ArgTypes:   []ast.Type{ast.TypeString},
 		ReturnType: ast.TypeString,
@@ -419,6 +426,7 @@ func interpolationFuncFile() ast.Function {
 			if err != nil {
 				return "", err
 			}
+			path = modulePath(vs, path)
 			data, err := ioutil.ReadFile(path)
 			if err != nil {
 				return "", err
@@ -805,6 +813,134 @@ func interpolationFuncJoin() ast.Function {
 	}
 }
 
+// interpolationFuncJSONDecode implements the "jsondecode" function that
+// decodes a JSON object into a map. The values in the map can be strings,
+// lists or maps, and lists and maps can be nested. Numbers, booleans and
+// nulls become strings, as they are everywhere else in interpolations.
+//
+// Functions return a single type, so the document must be an object, and
+// the elements of a list must all be of the same type, as they are in
+// every other list. Other documents are an error rather than being decoded
+// into something else.
+func interpolationFuncJSONDecode() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString},
+		ReturnType: ast.TypeMap,
+		Callback: func(args []interface{}) (interface{}, error) {
+			return decodeJSONObject([]byte(args[0].(string)))
+		},
+	}
+}
+
+// interpolationFuncYAMLDecode implements the "yamldecode" function that
+// decodes a YAML mapping like "jsondecode" decodes a JSON object.
+func interpolationFuncYAMLDecode() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString},
+		ReturnType: ast.TypeMap,
+		Callback: func(args []interface{}) (interface{}, error) {
+			data, err := yaml.YAMLToJSON([]byte(args[0].(string)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode YAML: %s", err)
+			}
+
+			return decodeJSONObject(data)
+		},
+	}
+}
+
+// decodeJSONObject decodes a JSON document, which must be an object, into
+// the value of a map ast.Variable. Functions return a single type, so the
+// document can't be a list or a string.
+func decodeJSONObject(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var raw interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %s", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("failed to decode JSON: extra data after the value")
+	}
+	if _, ok := raw.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf(
+			"the document must be an object, since the result is a map, got %s. "+
+				"Wrap the value in an object, such as {\"items\": ...}, to decode it",
+			jsonTypeName(raw))
+	}
+
+	v, err := jsonValueToVariable(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Value, nil
+}
+
+// jsonTypeName returns the JSON name of the type of a decoded value.
+func jsonTypeName(raw interface{}) string {
+	switch raw.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// jsonValueToVariable converts a value decoded from JSON into an
+// ast.Variable.
+func jsonValueToVariable(raw interface{}) (ast.Variable, error) {
+	switch v := raw.(type) {
+	case nil:
+		return ast.Variable{Type: ast.TypeString, Value: ""}, nil
+	case string:
+		return ast.Variable{Type: ast.TypeString, Value: v}, nil
+	case json.Number:
+		return ast.Variable{Type: ast.TypeString, Value: v.String()}, nil
+	case bool:
+		return ast.Variable{Type: ast.TypeString, Value: strconv.FormatBool(v)}, nil
+	case []interface{}:
+		list := make([]ast.Variable, len(v))
+		for i, e := range v {
+			ev, err := jsonValueToVariable(e)
+			if err != nil {
+				return ast.Variable{}, err
+			}
+			if i > 0 && ev.Type != list[0].Type {
+				return ast.Variable{}, fmt.Errorf(
+					"the elements of an array must be of the same type: element %d is %s, "+
+						"but element 0 is %s",
+					i, jsonTypeName(e), jsonTypeName(v[0]))
+			}
+			list[i] = ev
+		}
+
+		return ast.Variable{Type: ast.TypeList, Value: list}, nil
+	case map[string]interface{}:
+		m := make(map[string]ast.Variable, len(v))
+		for k, e := range v {
+			ev, err := jsonValueToVariable(e)
+			if err != nil {
+				return ast.Variable{}, err
+			}
+			m[k] = ev
+		}
+
+		return ast.Variable{Type: ast.TypeMap, Value: m}, nil
+	default:
+		return ast.Variable{}, fmt.Errorf("unsupported JSON value of type %T", raw)
+	}
+}
+
 // interpolationFuncJSONEncode implements the "jsonencode" function that encodes
 // a string, list, or map as its JSON representation. For now, values in the
 // list or map may only be strings.
@@ -887,6 +1023,90 @@ func interpolationFuncReplace() ast.Function {
 	}
 }
 
+// interpolationFuncRegex implements the "regex" function that returns what
+// the capture groups of a regular expression captured in the first match
+// in a string, as a list in the order of the groups. Without capture groups,
+// the list has just the match.
+func interpolationFuncRegex() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString, ast.TypeString},
+		ReturnType: ast.TypeList,
+		Callback: func(args []interface{}) (interface{}, error) {
+			re, err := regexp.Compile(args[0].(string))
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %s", args[0], err)
+			}
+
+			s := args[1].(string)
+			match := re.FindStringSubmatchIndex(s)
+			if match == nil {
+				return nil, fmt.Errorf("pattern %q doesn't match %q", re, s)
+			}
+
+			if re.NumSubexp() == 0 {
+				return []ast.Variable{regexGroup(s, match, 0)}, nil
+			}
+			return regexGroups(re, s, match), nil
+		},
+	}
+}
+
+// interpolationFuncRegexAll implements the "regexall" function that returns
+// a list of all of the matches of a regular expression in a string. Without
+// capture groups, each match is a string, and otherwise it's a list of the
+// groups in order, as "regex" returns them. The list is empty if there are
+// no matches.
+func interpolationFuncRegexAll() ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString, ast.TypeString},
+		ReturnType: ast.TypeList,
+		Callback: func(args []interface{}) (interface{}, error) {
+			re, err := regexp.Compile(args[0].(string))
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %s", args[0], err)
+			}
+
+			s := args[1].(string)
+			result := make([]ast.Variable, 0)
+			for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+				if re.NumSubexp() == 0 {
+					result = append(result, regexGroup(s, match, 0))
+					continue
+				}
+
+				result = append(result, ast.Variable{
+					Type:  ast.TypeList,
+					Value: regexGroups(re, s, match),
+				})
+			}
+
+			return result, nil
+		},
+	}
+}
+
+// regexGroups returns what the capture groups of re captured in a match in
+// s, given by the indexes of the match and its groups, in the order of the
+// groups. Named groups are in order too, since the functions return lists.
+func regexGroups(re *regexp.Regexp, s string, match []int) []ast.Variable {
+	result := make([]ast.Variable, 0, re.NumSubexp())
+	for i := 1; i <= re.NumSubexp(); i++ {
+		result = append(result, regexGroup(s, match, i))
+	}
+	return result
+}
+
+// regexGroup returns what the capture group i captured in a match in s,
+// given by the indexes of the match and its groups. Group 0 is the whole
+// match, and groups that didn't take part in the match are empty strings.
+func regexGroup(s string, match []int, i int) ast.Variable {
+	v := ""
+	if match[2*i] >= 0 {
+		v = s[match[2*i]:match[2*i+1]]
+	}
+	return ast.Variable{Type: ast.TypeString, Value: v}
+}
+
 func interpolationFuncLength() ast.Function {
 	return ast.Function{
 		ArgTypes:   []ast.Type{ast.TypeAny},
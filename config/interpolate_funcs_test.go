@@ -1186,6 +1186,41 @@ func TestInterpolateFuncFile(t *testing.T) {
 	})
 }
 
+func TestInterpolateFuncFile_module(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "foo.txt"), []byte("foo"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	testFunction(t, testFunctionConfig{
+		Cases: []testFunctionCase{
+			// Relative to the module
+			{
+				`${file("foo.txt")}`,
+				"foo",
+				false,
+			},
+
+			// Already prefixed with path.module
+			{
+				fmt.Sprintf(`${file("%s/foo.txt")}`, dir),
+				"foo",
+				false,
+			},
+		},
+		Vars: map[string]ast.Variable{
+			"path.module": ast.Variable{
+				Value: dir,
+				Type:  ast.TypeString,
+			},
+		},
+	})
+}
+
 func TestInterpolateFuncFormat(t *testing.T) {
 	testFunction(t, testFunctionConfig{
 		Cases: []testFunctionCase{
@@ -1445,6 +1480,275 @@ func TestInterpolateFuncJSONEncode(t *testing.T) {
 	})
 }
 
+func TestInterpolateFuncJSONDecode(t *testing.T) {
+	testFunction(t, testFunctionConfig{
+		Vars: map[string]ast.Variable{
+			"object": ast.Variable{
+				Value: `{"name": "web", "count": 3, "enabled": true, "tags": ["a", "b"], "nested": {"x": null}}`,
+				Type:  ast.TypeString,
+			},
+			"list": ast.Variable{
+				Value: `[1.5, "two"]`,
+				Type:  ast.TypeString,
+			},
+			"string": ast.Variable{
+				Value: `"foo"`,
+				Type:  ast.TypeString,
+			},
+			"invalid": ast.Variable{
+				Value: `{"name": `,
+				Type:  ast.TypeString,
+			},
+			"extra": ast.Variable{
+				Value: `{} {}`,
+				Type:  ast.TypeString,
+			},
+			"deep": ast.Variable{
+				Value: `{"subnets": [{"cidr": "10.0.0.0/24", "zones": ["a", "b"]}, {"cidr": "10.0.1.0/24", "zones": []}]}`,
+				Type:  ast.TypeString,
+			},
+			"scalars": ast.Variable{
+				Value: `{"ports": [80, "443", true]}`,
+				Type:  ast.TypeString,
+			},
+			"mixed": ast.Variable{
+				Value: `{"items": ["a", {"b": "c"}]}`,
+				Type:  ast.TypeString,
+			},
+		},
+		Cases: []testFunctionCase{
+			{
+				`${jsondecode(object)}`,
+				map[string]interface{}{
+					"name":    "web",
+					"count":   "3",
+					"enabled": "true",
+					"tags":    []interface{}{"a", "b"},
+					"nested":  map[string]interface{}{"x": ""},
+				},
+				false,
+			},
+			{
+				`${lookup(jsondecode(object), "name")}`,
+				"web",
+				false,
+			},
+			{
+				`${length(jsondecode(object))}`,
+				"5",
+				false,
+			},
+
+			// Lists and maps are decoded recursively
+			{
+				`${jsondecode(deep)}`,
+				map[string]interface{}{
+					"subnets": []interface{}{
+						map[string]interface{}{
+							"cidr":  "10.0.0.0/24",
+							"zones": []interface{}{"a", "b"},
+						},
+						map[string]interface{}{
+							"cidr":  "10.0.1.0/24",
+							"zones": []interface{}{},
+						},
+					},
+				},
+				false,
+			},
+			{
+				`${jsondecode(scalars)}`,
+				map[string]interface{}{
+					"ports": []interface{}{"80", "443", "true"},
+				},
+				false,
+			},
+
+			// The elements of a list must be of the same type
+			{
+				`${jsondecode(mixed)}`,
+				nil,
+				true,
+			},
+
+			// Functions return a single type, which is a map
+			{
+				`${jsondecode(list)}`,
+				nil,
+				true,
+			},
+			{
+				`${jsondecode(string)}`,
+				nil,
+				true,
+			},
+			{
+				`${jsondecode(object)} and more`,
+				nil,
+				true,
+			},
+			{
+				`${jsondecode(invalid)}`,
+				nil,
+				true,
+			},
+			{
+				`${jsondecode(extra)}`,
+				nil,
+				true,
+			},
+		},
+	})
+}
+
+func TestInterpolateFuncYAMLDecode(t *testing.T) {
+	testFunction(t, testFunctionConfig{
+		Vars: map[string]ast.Variable{
+			"object": ast.Variable{
+				Value: "name: web\ncount: 3\ntags:\n  - a\n  - b\n",
+				Type:  ast.TypeString,
+			},
+			"list": ast.Variable{
+				Value: "- a\n- b\n",
+				Type:  ast.TypeString,
+			},
+			"invalid": ast.Variable{
+				Value: "name: [web\n",
+				Type:  ast.TypeString,
+			},
+		},
+		Cases: []testFunctionCase{
+			{
+				`${yamldecode(object)}`,
+				map[string]interface{}{
+					"name":  "web",
+					"count": "3",
+					"tags":  []interface{}{"a", "b"},
+				},
+				false,
+			},
+			{
+				`${yamldecode(list)}`,
+				nil,
+				true,
+			},
+			{
+				`${yamldecode(invalid)}`,
+				nil,
+				true,
+			},
+		},
+	})
+}
+
+func TestInterpolateFuncRegex(t *testing.T) {
+	testFunction(t, testFunctionConfig{
+		Cases: []testFunctionCase{
+			// Without capture groups
+			{
+				`${regex("[a-z]+", "53454 AbcDef ghi")}`,
+				[]interface{}{"bc"},
+				false,
+			},
+
+			// Unnamed capture groups
+			{
+				`${regex("(\\d+)-(\\d+)", "ports 8080-8090")}`,
+				[]interface{}{"8080", "8090"},
+				false,
+			},
+
+			// Named capture groups are in order as well
+			{
+				`${regex("(?P<user>[^@]+)@(?P<domain>.+)", "admin@example.com")}`,
+				[]interface{}{"admin", "example.com"},
+				false,
+			},
+
+			// Groups that don't take part in the match
+			{
+				`${regex("a(b)?(c)", "ac")}`,
+				[]interface{}{"", "c"},
+				false,
+			},
+
+			// Capture groups can be accessed with element
+			{
+				`${element(regex("v(\\d+)\\.(\\d+)", "v1.12"), 1)}`,
+				"12",
+				false,
+			},
+
+			// No match
+			{
+				`${regex("[0-9]+", "abc")}`,
+				nil,
+				true,
+			},
+
+			// Bad regexp
+			{
+				`${regex("(a", "abc")}`,
+				nil,
+				true,
+			},
+
+			// Named and unnamed groups can be mixed
+			{
+				`${regex("(?P<a>a)(b)", "ab")}`,
+				[]interface{}{"a", "b"},
+				false,
+			},
+		},
+	})
+}
+
+func TestInterpolateFuncRegexAll(t *testing.T) {
+	testFunction(t, testFunctionConfig{
+		Cases: []testFunctionCase{
+			// Without capture groups
+			{
+				`${regexall("[a-z]+", "abc 123 def")}`,
+				[]interface{}{"abc", "def"},
+				false,
+			},
+
+			// Unnamed capture groups
+			{
+				`${regexall("(\\w)=(\\d)", "a=1, b=2")}`,
+				[]interface{}{
+					[]interface{}{"a", "1"},
+					[]interface{}{"b", "2"},
+				},
+				false,
+			},
+
+			// Named capture groups are in order, as regex returns them
+			{
+				`${regexall("(?P<key>\\w)=(?P<value>\\d)", "a=1")}`,
+				[]interface{}{
+					[]interface{}{"a", "1"},
+				},
+				false,
+			},
+
+			// No match, which is often tested with length
+			{
+				`${length(regexall("[0-9]+", "abc"))}`,
+				"0",
+				false,
+			},
+
+			// Bad regexp
+			{
+				`${regexall("(a", "abc")}`,
+				nil,
+				true,
+			},
+		},
+	})
+}
+
 func TestInterpolateFuncReplace(t *testing.T) {
 	testFunction(t, testFunctionConfig{
 		Cases: []testFunctionCase{
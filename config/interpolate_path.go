@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hil/ast"
+)
+
+// The names of the path variables, as they're found in the variables that
+// are given to interpolation.
+const (
+	PathCwdVariable    = "path.cwd"
+	PathModuleVariable = "path.module"
+	PathRootVariable   = "path.root"
+)
+
+// PathValues returns the values of the path variables for the module in
+// the directory dir, of a configuration whose root module is in the
+// directory root.
+//
+// The path variables have these values wherever they're used, including
+// in provisioner connection blocks and in the backend configuration, where
+// they're the only variables that can be used.
+func PathValues(dir, root string) (map[string]ast.Variable, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Couldn't get cwd for var %s: %s", PathCwdVariable, err)
+	}
+
+	return map[string]ast.Variable{
+		PathCwdVariable:    {Value: wd, Type: ast.TypeString},
+		PathModuleVariable: {Value: dir, Type: ast.TypeString},
+		PathRootVariable:   {Value: root, Type: ast.TypeString},
+	}, nil
+}
+
+// modulePath returns the path that a relative path given to a function
+// such as file() refers to. Relative paths are relative to the directory
+// of the module they're used in, as given by path.module in vs.
+//
+// Paths that begin with one of the path variables are already relative to
+// the working directory, so they're left as they are, as are all paths if
+// vs doesn't have path.module.
+func modulePath(vs map[string]ast.Variable, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	dir, ok := pathVariable(vs, PathModuleVariable)
+	if !ok || dir == "" {
+		return path
+	}
+
+	for _, k := range []string{PathModuleVariable, PathRootVariable, PathCwdVariable} {
+		if prefix, ok := pathVariable(vs, k); ok && hasPathPrefix(path, prefix) {
+			return path
+		}
+	}
+
+	return filepath.Join(dir, path)
+}
+
+func pathVariable(vs map[string]ast.Variable, k string) (string, bool) {
+	v, ok := vs[k]
+	if !ok || v.Type != ast.TypeString {
+		return "", false
+	}
+
+	s, ok := v.Value.(string)
+	return s, ok
+}
+
+// hasPathPrefix returns true if path begins with the directory prefix,
+// exactly as it's written. The current directory isn't a prefix of
+// anything, since it would match every relative path.
+func hasPathPrefix(path, prefix string) bool {
+	if prefix == "" || filepath.Clean(prefix) == "." {
+		return false
+	}
+	if path == prefix {
+		return true
+	}
+
+	for _, sep := range []string{"/", string(filepath.Separator)} {
+		if strings.HasPrefix(path, strings.TrimSuffix(prefix, sep)+sep) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hil/ast"
+)
+
+func TestModulePath(t *testing.T) {
+	vs := map[string]ast.Variable{
+		PathCwdVariable:    {Value: "/work", Type: ast.TypeString},
+		PathModuleVariable: {Value: ".terraform/modules/abc", Type: ast.TypeString},
+		PathRootVariable:   {Value: ".", Type: ast.TypeString},
+	}
+
+	cases := []struct {
+		Vars     map[string]ast.Variable
+		Path     string
+		Expected string
+	}{
+		{vs, "foo.txt", filepath.Join(".terraform/modules/abc", "foo.txt")},
+		{vs, "files/foo.txt", filepath.Join(".terraform/modules/abc", "files/foo.txt")},
+		{vs, "/abs/foo.txt", "/abs/foo.txt"},
+		{vs, ".terraform/modules/abc/foo.txt", ".terraform/modules/abc/foo.txt"},
+		{vs, "./shared/foo.txt", filepath.Join(".terraform/modules/abc", "shared/foo.txt")},
+		{vs, "/work/foo.txt", "/work/foo.txt"},
+		{nil, "foo.txt", "foo.txt"},
+	}
+
+	for _, tc := range cases {
+		if actual := modulePath(tc.Vars, tc.Path); actual != tc.Expected {
+			t.Fatalf("%s: bad: %s", tc.Path, actual)
+		}
+	}
+}
@@ -22,6 +22,14 @@ func TestNewInterpolatedVariable(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"global.foo",
+			&GlobalVariable{
+				Name: "foo",
+				key:  "global.foo",
+			},
+			false,
+		},
 		{
 			"module.foo.bar",
 			&ModuleVariable{
@@ -84,6 +92,12 @@ func TestNewInterpolatedVariable(t *testing.T) {
 	}
 }
 
+func TestNewGlobalVariable_dotIndex(t *testing.T) {
+	if _, err := NewGlobalVariable("global.foo.bar"); err == nil {
+		t.Fatal("should error")
+	}
+}
+
 func TestNewResourceVariable(t *testing.T) {
 	v, err := NewResourceVariable("foo.bar.baz")
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"sort"
@@ -66,6 +67,25 @@ func LoadFile(path string) (*Config, error) {
 	return configTree.Flatten()
 }
 
+// LoadFileTerraform is like LoadFile, but only loads the terraform block of
+// the configuration, which is all that commands that only need the backend
+// configuration use. The rest of the configuration isn't decoded or
+// checked, which makes it much faster to load large configurations.
+func LoadFileTerraform(path string) (*Config, error) {
+	importTree, err := loadTree(path)
+	if err != nil {
+		return nil, err
+	}
+	defer importTree.Close()
+
+	raw, ok := importTree.Raw.(*hclConfigurable)
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown configuration format", path)
+	}
+
+	return raw.terraformConfig()
+}
+
 // LoadDir loads all the Terraform configuration files in a single
 // directory and appends them together.
 //
@@ -75,8 +95,30 @@ func LoadFile(path string) (*Config, error) {
 // are merged into the configuration to create the final configuration.
 //
 // Files are loaded in lexical order.
+//
+// Override files for a specific environment, named "override_ENV.tf" or
+// ending in "_override_ENV.tf", are ignored. Use LoadDirEnv to load them.
 func LoadDir(root string) (*Config, error) {
-	files, overrides, err := dirFiles(root)
+	return LoadDirEnv(root, "")
+}
+
+// LoadDirEnv is like LoadDir, but also merges in the override files for the
+// given environment, after all the other override files. Override files for
+// other environments are ignored.
+func LoadDirEnv(root, env string) (*Config, error) {
+	return loadDir(root, env, LoadFile)
+}
+
+// LoadDirTerraform is like LoadDir, but only loads the terraform blocks of
+// the configuration, like LoadFileTerraform.
+func LoadDirTerraform(root string) (*Config, error) {
+	return loadDir(root, "", LoadFileTerraform)
+}
+
+// loadDir loads the configuration files in root, and the override files
+// for env, with loadFile.
+func loadDir(root, env string, loadFile func(string) (*Config, error)) (*Config, error) {
+	files, overrides, envOverrides, err := dirFiles(root, env)
 	if err != nil {
 		return nil, err
 	}
@@ -95,10 +137,14 @@ func LoadDir(root string) (*Config, error) {
 	// Sort the files and overrides so we have a deterministic order
 	sort.Strings(files)
 	sort.Strings(overrides)
+	sort.Strings(envOverrides)
+
+	// The environment's overrides take precedence over all the others
+	overrides = append(overrides, envOverrides...)
 
 	// Load all the regular files, append them to each other.
 	for _, f := range files {
-		c, err := LoadFile(f)
+		c, err := loadFile(f)
 		if err != nil {
 			return nil, err
 		}
@@ -115,7 +161,7 @@ func LoadDir(root string) (*Config, error) {
 
 	// Load all the overrides, and merge them into the config
 	for _, f := range overrides {
-		c, err := LoadFile(f)
+		c, err := loadFile(f)
 		if err != nil {
 			return nil, err
 		}
@@ -129,6 +175,20 @@ func LoadDir(root string) (*Config, error) {
 	// Mark the directory
 	result.Dir = rootAbs
 
+	// The default connection can be in any file, so it's only inherited
+	// by the provisioners once all the files are merged.
+	result.inheritConnection()
+
+	// The configurations a resource's provider can be selected from are
+	// only known once all the files are merged.
+	result.setProviderChoices()
+
+	// The provider_config blocks are expanded after the choices are set so
+	// that the configurations they add can't be chosen by other resources.
+	if err := result.expandProviderOverrides(); err != nil {
+		return nil, err
+	}
+
 	return result, nil
 }
 
@@ -139,7 +199,7 @@ func IsEmptyDir(root string) (bool, error) {
 		return true, nil
 	}
 
-	fs, os, err := dirFiles(root)
+	fs, os, _, err := dirFiles(root, "")
 	if err != nil {
 		return false, err
 	}
@@ -159,30 +219,32 @@ func ext(path string) string {
 	}
 }
 
-func dirFiles(dir string) ([]string, []string, error) {
+// dirFiles returns the configuration files in dir, the override files, and
+// the override files for the environment env.
+func dirFiles(dir, env string) ([]string, []string, []string, error) {
 	f, err := os.Open(dir)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	defer f.Close()
 
 	fi, err := f.Stat()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	if !fi.IsDir() {
-		return nil, nil, fmt.Errorf(
+		return nil, nil, nil, fmt.Errorf(
 			"configuration path must be a directory: %s",
 			dir)
 	}
 
-	var files, overrides []string
+	var files, overrides, envOverrides []string
 	err = nil
 	for err != io.EOF {
 		var fis []os.FileInfo
 		fis, err = f.Readdir(128)
 		if err != nil && err != io.EOF {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 
 		for _, fi := range fis {
@@ -203,16 +265,43 @@ func dirFiles(dir string) ([]string, []string, error) {
 			override := nameNoExt == "override" ||
 				strings.HasSuffix(nameNoExt, "_override")
 
+			// Overrides for an environment are only loaded if it's the
+			// current one.
+			fileEnv, envOverride := envOverrideName(nameNoExt)
+
 			path := filepath.Join(dir, name)
-			if override {
+			switch {
+			case override:
 				overrides = append(overrides, path)
-			} else {
+			case envOverride && fileEnv == env:
+				envOverrides = append(envOverrides, path)
+			case envOverride:
+				log.Printf(
+					"[DEBUG] config: ignoring %s, which only overrides the %q environment",
+					path, fileEnv)
+				continue
+			default:
 				files = append(files, path)
 			}
 		}
 	}
 
-	return files, overrides, nil
+	return files, overrides, envOverrides, nil
+}
+
+// envOverrideName returns the environment of an override file for a single
+// environment from its name without the extension, which is override_ENV
+// or ends in _override_ENV, and whether it's such a file. Other names with
+// "override" in them, such as prod.override, are ordinary files.
+func envOverrideName(name string) (string, bool) {
+	var env string
+	if strings.HasPrefix(name, "override_") {
+		env = strings.TrimPrefix(name, "override_")
+	} else if i := strings.LastIndex(name, "_override_"); i >= 0 {
+		env = name[i+len("_override_"):]
+	}
+
+	return env, env != ""
 }
 
 // isIgnoredFile returns true or false depending on whether the
@@ -3,6 +3,10 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl"
@@ -19,47 +23,46 @@ type hclConfigurable struct {
 
 func (t *hclConfigurable) Config() (*Config, error) {
 	validKeys := map[string]struct{}{
-		"atlas":     struct{}{},
-		"data":      struct{}{},
-		"module":    struct{}{},
-		"output":    struct{}{},
-		"provider":  struct{}{},
-		"resource":  struct{}{},
-		"terraform": struct{}{},
-		"variable":  struct{}{},
+		"atlas":      struct{}{},
+		"connection": struct{}{},
+		"data":       struct{}{},
+		"module":     struct{}{},
+		"output":     struct{}{},
+		"provider":   struct{}{},
+		"resource":   struct{}{},
+		"terraform":  struct{}{},
+		"variable":   struct{}{},
 	}
 
-	// Top-level item should be the object list
-	list, ok := t.Root.Node.(*ast.ObjectList)
-	if !ok {
-		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
+	// Start building up the actual configuration, from the terraform block.
+	config, err := t.terraformConfig()
+	if err != nil {
+		return nil, err
 	}
+	list := t.Root.Node.(*ast.ObjectList)
 
-	// Start building up the actual configuration.
-	config := new(Config)
-
-	// Terraform config
-	if o := list.Filter("terraform"); len(o.Items) > 0 {
+	// Build the variables
+	if vars := list.Filter("variable"); len(vars.Items) > 0 {
 		var err error
-		config.Terraform, err = loadTerraformHcl(o)
+		config.Variables, err = loadVariablesHcl(t.File, vars)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	// Build the variables
-	if vars := list.Filter("variable"); len(vars.Items) > 0 {
+	// Get Atlas configuration
+	if atlas := list.Filter("atlas"); len(atlas.Items) > 0 {
 		var err error
-		config.Variables, err = loadVariablesHcl(vars)
+		config.Atlas, err = loadAtlasHcl(atlas)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	// Get Atlas configuration
-	if atlas := list.Filter("atlas"); len(atlas.Items) > 0 {
+	// Get the default connection of the module
+	if conn := list.Filter("connection"); len(conn.Items) > 0 {
 		var err error
-		config.Atlas, err = loadAtlasHcl(atlas)
+		config.Connection, err = loadConnectionHcl(conn)
 		if err != nil {
 			return nil, err
 		}
@@ -68,7 +71,7 @@ func (t *hclConfigurable) Config() (*Config, error) {
 	// Build the modules
 	if modules := list.Filter("module"); len(modules.Items) > 0 {
 		var err error
-		config.Modules, err = loadModulesHcl(modules)
+		config.Modules, err = loadModulesHcl(t.File, modules)
 		if err != nil {
 			return nil, err
 		}
@@ -77,7 +80,7 @@ func (t *hclConfigurable) Config() (*Config, error) {
 	// Build the provider configs
 	if providers := list.Filter("provider"); len(providers.Items) > 0 {
 		var err error
-		config.ProviderConfigs, err = loadProvidersHcl(providers)
+		config.ProviderConfigs, err = loadProvidersHcl(t.File, providers)
 		if err != nil {
 			return nil, err
 		}
@@ -94,11 +97,11 @@ func (t *hclConfigurable) Config() (*Config, error) {
 			len(managedResourceConfigs.Items)+len(dataResourceConfigs.Items),
 		)
 
-		managedResources, err := loadManagedResourcesHcl(managedResourceConfigs)
+		managedResources, err := loadManagedResourcesHcl(t.File, managedResourceConfigs)
 		if err != nil {
 			return nil, err
 		}
-		dataResources, err := loadDataResourcesHcl(dataResourceConfigs)
+		dataResources, err := loadDataResourcesHcl(t.File, dataResourceConfigs)
 		if err != nil {
 			return nil, err
 		}
@@ -110,7 +113,7 @@ func (t *hclConfigurable) Config() (*Config, error) {
 	// Build the outputs
 	if outputs := list.Filter("output"); len(outputs.Items) > 0 {
 		var err error
-		config.Outputs, err = loadOutputsHcl(outputs)
+		config.Outputs, err = loadOutputsHcl(t.File, outputs)
 		if err != nil {
 			return nil, err
 		}
@@ -134,6 +137,27 @@ func (t *hclConfigurable) Config() (*Config, error) {
 	return config, nil
 }
 
+// terraformConfig returns a configuration with only the terraform block of
+// the file.
+func (t *hclConfigurable) terraformConfig() (*Config, error) {
+	// Top-level item should be the object list
+	list, ok := t.Root.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
+	}
+
+	config := new(Config)
+	if o := list.Filter("terraform"); len(o.Items) > 0 {
+		var err error
+		config.Terraform, err = loadTerraformHcl(o)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
 // loadFileHcl is a fileLoaderFunc that knows how to read HCL
 // files and turn them into hclConfigurables.
 func loadFileHcl(root string) (configurable, []string, error) {
@@ -255,6 +279,20 @@ func loadTerraformHcl(list *ast.ObjectList) (*Terraform, error) {
 		}
 	}
 
+	if os := listVal.Filter("strict"); len(os.Items) > 0 {
+		if len(os.Items) > 1 {
+			return nil, fmt.Errorf("only one 'strict' block allowed")
+		}
+
+		var strict Strict
+		if err := hcl.DecodeObject(&strict, os.Items[0].Val); err != nil {
+			return nil, fmt.Errorf(
+				"Error reading strict config for terraform block: %s",
+				err)
+		}
+		config.Strict = &strict
+	}
+
 	return &config, nil
 }
 
@@ -320,13 +358,30 @@ func loadAtlasHcl(list *ast.ObjectList) (*AtlasConfig, error) {
 	return &config, nil
 }
 
+// loadConnectionHcl returns the default connection of the provisioners in
+// a module, from its top-level connection block.
+func loadConnectionHcl(list *ast.ObjectList) (*RawConfig, error) {
+	if len(list.Items) > 1 {
+		return nil, fmt.Errorf("only one 'connection' block allowed")
+	}
+
+	var config map[string]interface{}
+	if err := hcl.DecodeObject(&config, list.Items[0].Val); err != nil {
+		return nil, fmt.Errorf(
+			"Error reading connection: %s",
+			err)
+	}
+
+	return NewRawConfig(config)
+}
+
 // Given a handle to a HCL object, this recurses into the structure
 // and pulls out a list of modules.
 //
 // The resulting modules may not be unique, but each module
 // represents exactly one module definition in the HCL configuration.
 // We leave it up to another pass to merge them together.
-func loadModulesHcl(list *ast.ObjectList) ([]*Module, error) {
+func loadModulesHcl(file string, list *ast.ObjectList) ([]*Module, error) {
 	if err := assertAllBlocksHaveNames("module", list); err != nil {
 		return nil, err
 	}
@@ -361,6 +416,7 @@ func loadModulesHcl(list *ast.ObjectList) ([]*Module, error) {
 
 		// Remove the fields we handle specially
 		delete(config, "source")
+		delete(config, "export_outputs")
 
 		rawConfig, err := NewRawConfig(config)
 		if err != nil {
@@ -382,10 +438,23 @@ func loadModulesHcl(list *ast.ObjectList) ([]*Module, error) {
 			}
 		}
 
+		var exportOutputs bool
+		if o := listVal.Filter("export_outputs"); len(o.Items) > 0 {
+			err = hcl.DecodeObject(&exportOutputs, o.Items[0].Val)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"Error parsing export_outputs for %s: %s",
+					k,
+					err)
+			}
+		}
+
 		result = append(result, &Module{
-			Name:      k,
-			Source:    source,
-			RawConfig: rawConfig,
+			Name:          k,
+			Source:        source,
+			RawConfig:     rawConfig,
+			ExportOutputs: exportOutputs,
+			Positions:     hclPositions(file, item, listVal),
 		})
 	}
 
@@ -394,7 +463,7 @@ func loadModulesHcl(list *ast.ObjectList) ([]*Module, error) {
 
 // LoadOutputsHcl recurses into the given HCL object and turns
 // it into a mapping of outputs.
-func loadOutputsHcl(list *ast.ObjectList) ([]*Output, error) {
+func loadOutputsHcl(file string, list *ast.ObjectList) ([]*Output, error) {
 	if err := assertAllBlocksHaveNames("output", list); err != nil {
 		return nil, err
 	}
@@ -420,6 +489,7 @@ func loadOutputsHcl(list *ast.ObjectList) ([]*Output, error) {
 
 		// Delete special keys
 		delete(config, "depends_on")
+		delete(config, "override")
 
 		rawConfig, err := NewRawConfig(config)
 		if err != nil {
@@ -441,10 +511,17 @@ func loadOutputsHcl(list *ast.ObjectList) ([]*Output, error) {
 			}
 		}
 
+		override, err := loadOverrideHcl(listVal)
+		if err != nil {
+			return nil, fmt.Errorf("output %q: %s", n, err)
+		}
+
 		result = append(result, &Output{
 			Name:      n,
 			RawConfig: rawConfig,
 			DependsOn: dependsOn,
+			Pos:       hclPos(file, item),
+			Override:  override,
 		})
 	}
 
@@ -453,7 +530,7 @@ func loadOutputsHcl(list *ast.ObjectList) ([]*Output, error) {
 
 // LoadVariablesHcl recurses into the given HCL object and turns
 // it into a list of variables.
-func loadVariablesHcl(list *ast.ObjectList) ([]*Variable, error) {
+func loadVariablesHcl(file string, list *ast.ObjectList) ([]*Variable, error) {
 	if err := assertAllBlocksHaveNames("variable", list); err != nil {
 		return nil, err
 	}
@@ -465,6 +542,10 @@ func loadVariablesHcl(list *ast.ObjectList) ([]*Variable, error) {
 		DeclaredType string `hcl:"type"`
 		Default      interface{}
 		Description  string
+		Sensitive    bool
+		Deprecated   string
+		Ephemeral    bool
+		Override     bool
 		Fields       []string `hcl:",decodedFields"`
 	}
 
@@ -489,7 +570,7 @@ func loadVariablesHcl(list *ast.ObjectList) ([]*Variable, error) {
 		}
 
 		// Check for invalid keys
-		valid := []string{"type", "default", "description"}
+		valid := []string{"type", "default", "description", "sensitive", "deprecated", "ephemeral", "override"}
 		if err := checkHCLKeys(item.Val, valid); err != nil {
 			return nil, multierror.Prefix(err, fmt.Sprintf(
 				"variable[%s]:", n))
@@ -521,6 +602,11 @@ func loadVariablesHcl(list *ast.ObjectList) ([]*Variable, error) {
 			DeclaredType: hclVar.DeclaredType,
 			Default:      hclVar.Default,
 			Description:  hclVar.Description,
+			Sensitive:    hclVar.Sensitive,
+			Deprecated:   hclVar.Deprecated,
+			Ephemeral:    hclVar.Ephemeral,
+			Pos:          hclPos(file, item),
+			Override:     hclVar.Override,
 		}
 		if err := newVar.ValidateTypeAndDefault(); err != nil {
 			return nil, err
@@ -534,7 +620,7 @@ func loadVariablesHcl(list *ast.ObjectList) ([]*Variable, error) {
 
 // LoadProvidersHcl recurses into the given HCL object and turns
 // it into a mapping of provider configs.
-func loadProvidersHcl(list *ast.ObjectList) ([]*ProviderConfig, error) {
+func loadProvidersHcl(file string, list *ast.ObjectList) ([]*ProviderConfig, error) {
 	if err := assertAllBlocksHaveNames("provider", list); err != nil {
 		return nil, err
 	}
@@ -563,6 +649,7 @@ func loadProvidersHcl(list *ast.ObjectList) ([]*ProviderConfig, error) {
 
 		delete(config, "alias")
 		delete(config, "version")
+		delete(config, "override")
 
 		rawConfig, err := NewRawConfig(config)
 		if err != nil {
@@ -596,11 +683,18 @@ func loadProvidersHcl(list *ast.ObjectList) ([]*ProviderConfig, error) {
 			}
 		}
 
+		override, err := loadOverrideHcl(listVal)
+		if err != nil {
+			return nil, fmt.Errorf("provider[%s]: %s", n, err)
+		}
+
 		result = append(result, &ProviderConfig{
 			Name:      n,
 			Alias:     alias,
 			Version:   version,
 			RawConfig: rawConfig,
+			Pos:       hclPos(file, item),
+			Override:  override,
 		})
 	}
 
@@ -613,7 +707,7 @@ func loadProvidersHcl(list *ast.ObjectList) ([]*ProviderConfig, error) {
 // The resulting data sources may not be unique, but each one
 // represents exactly one data definition in the HCL configuration.
 // We leave it up to another pass to merge them together.
-func loadDataResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
+func loadDataResourcesHcl(file string, list *ast.ObjectList) ([]*Resource, error) {
 	if err := assertAllBlocksHaveNames("data", list); err != nil {
 		return nil, err
 	}
@@ -657,6 +751,7 @@ func loadDataResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 		// Remove the fields we handle specially
 		delete(config, "depends_on")
 		delete(config, "provider")
+		delete(config, "provider_config")
 		delete(config, "count")
 
 		rawConfig, err := NewRawConfig(config)
@@ -713,6 +808,26 @@ func loadDataResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 					err)
 			}
 		}
+		rawProvider, err := loadProviderExprHcl(provider)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Error reading provider for %s[%s]: %s",
+				t,
+				k,
+				err)
+		}
+		if rawProvider != nil {
+			provider = ""
+		}
+
+		providerOverrides, err := loadProviderOverridesHcl(listVal)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Error reading provider_config for %s[%s]: %s",
+				t,
+				k,
+				err)
+		}
 
 		result = append(result, &Resource{
 			Mode:         DataResourceMode,
@@ -721,9 +836,13 @@ func loadDataResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 			RawCount:     countConfig,
 			RawConfig:    rawConfig,
 			Provider:     provider,
+			RawProvider:  rawProvider,
 			Provisioners: []*Provisioner{},
 			DependsOn:    dependsOn,
 			Lifecycle:    ResourceLifecycle{},
+
+			ProviderOverrides: providerOverrides,
+			Positions:         hclPositions(file, item, listVal),
 		})
 	}
 
@@ -736,7 +855,7 @@ func loadDataResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 // The resulting resources may not be unique, but each resource
 // represents exactly one "resource" block in the HCL configuration.
 // We leave it up to another pass to merge them together.
-func loadManagedResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
+func loadManagedResourcesHcl(file string, list *ast.ObjectList) ([]*Resource, error) {
 	list = list.Children()
 	if len(list.Items) == 0 {
 		return nil, nil
@@ -788,10 +907,13 @@ func loadManagedResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 		// Remove the fields we handle specially
 		delete(config, "connection")
 		delete(config, "count")
+		delete(config, "for_each")
 		delete(config, "depends_on")
 		delete(config, "provisioner")
 		delete(config, "provider")
+		delete(config, "provider_config")
 		delete(config, "lifecycle")
+		delete(config, "stage")
 
 		rawConfig, err := NewRawConfig(config)
 		if err != nil {
@@ -822,6 +944,34 @@ func loadManagedResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 		}
 		countConfig.Key = "count"
 
+		// If we have a for_each, then parse it out. It can't be combined
+		// with count since both decide the instances of the resource.
+		var forEachConfig *RawConfig
+		if o := listVal.Filter("for_each"); len(o.Items) > 0 {
+			if len(listVal.Filter("count").Items) > 0 {
+				return nil, fmt.Errorf(
+					"%s[%s]: count and for_each cannot both be set",
+					t, k)
+			}
+
+			var forEach interface{}
+			if err := hcl.DecodeObject(&forEach, o.Items[0].Val); err != nil {
+				return nil, fmt.Errorf(
+					"Error parsing for_each for %s[%s]: %s",
+					t,
+					k,
+					err)
+			}
+
+			forEachConfig, err = NewRawConfig(map[string]interface{}{
+				"for_each": forEach,
+			})
+			if err != nil {
+				return nil, err
+			}
+			forEachConfig.Key = "for_each"
+		}
+
 		// If we have depends fields, then add those in
 		var dependsOn []string
 		if o := listVal.Filter("depends_on"); len(o.Items) > 0 {
@@ -835,6 +985,24 @@ func loadManagedResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 			}
 		}
 
+		// If we have a stage, then parse it out. Stages are numbered from 1,
+		// since 0 is a resource without one.
+		var stage int
+		if o := listVal.Filter("stage"); len(o.Items) > 0 {
+			if err := hcl.DecodeObject(&stage, o.Items[0].Val); err != nil {
+				return nil, fmt.Errorf(
+					"Error reading stage for %s[%s]: %s",
+					t,
+					k,
+					err)
+			}
+			if stage < 1 {
+				return nil, fmt.Errorf(
+					"%s[%s]: stage must be a positive integer, got %d",
+					t, k, stage)
+			}
+		}
+
 		// If we have connection info, then parse those out
 		var connInfo map[string]interface{}
 		if o := listVal.Filter("connection"); len(o.Items) > 0 {
@@ -874,6 +1042,26 @@ func loadManagedResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 					err)
 			}
 		}
+		rawProvider, err := loadProviderExprHcl(provider)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Error reading provider for %s[%s]: %s",
+				t,
+				k,
+				err)
+		}
+		if rawProvider != nil {
+			provider = ""
+		}
+
+		providerOverrides, err := loadProviderOverridesHcl(listVal)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Error reading provider_config for %s[%s]: %s",
+				t,
+				k,
+				err)
+		}
 
 		// Check if the resource should be re-created before
 		// destroying the existing instance
@@ -886,7 +1074,12 @@ func loadManagedResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 			}
 
 			// Check for invalid keys
-			valid := []string{"create_before_destroy", "ignore_changes", "prevent_destroy"}
+			valid := []string{
+				"create_before_destroy", "ignore_changes", "prevent_destroy",
+				"destroy_priority", "priority", "wait_for_ready", "adopt_existing",
+				"before_create", "after_create", "before_update", "after_update",
+				"before_destroy", "after_destroy",
+			}
 			if err := checkHCLKeys(o.Items[0].Val, valid); err != nil {
 				return nil, multierror.Prefix(err, fmt.Sprintf(
 					"%s[%s]:", t, k))
@@ -915,17 +1108,147 @@ func loadManagedResourcesHcl(list *ast.ObjectList) ([]*Resource, error) {
 			Name:         k,
 			Type:         t,
 			RawCount:     countConfig,
+			RawForEach:   forEachConfig,
 			RawConfig:    rawConfig,
 			Provisioners: provisioners,
 			Provider:     provider,
+			RawProvider:  rawProvider,
 			DependsOn:    dependsOn,
 			Lifecycle:    lifecycle,
+			Stage:        stage,
+
+			ProviderOverrides: providerOverrides,
+			Positions:         hclPositions(file, item, listVal),
 		})
 	}
 
 	return result, nil
 }
 
+// hclPositions returns where the block item is, under "", and the
+// attributes in its body, by name, as "file:line". Repeated blocks are also
+// recorded under their index.
+func hclPositions(file string, item *ast.ObjectItem, body *ast.ObjectList) map[string]string {
+	file = hclFile(file)
+	result := map[string]string{
+		"": fmt.Sprintf("%s:%d", file, item.Pos().Line),
+	}
+
+	counts := make(map[string]int)
+	for _, attr := range body.Items {
+		if len(attr.Keys) == 0 {
+			continue
+		}
+
+		k, ok := attr.Keys[0].Token.Value().(string)
+		if !ok {
+			continue
+		}
+		pos := fmt.Sprintf("%s:%d", file, attr.Pos().Line)
+		if _, ok := result[k]; !ok {
+			result[k] = pos
+		}
+		if _, ok := attr.Val.(*ast.ObjectType); ok {
+			result[fmt.Sprintf("%s.%d", k, counts[k])] = pos
+			counts[k]++
+		}
+	}
+
+	return result
+}
+
+// hclPos returns where the block item is, as "file:line", or an empty
+// string if the configuration wasn't loaded from a file.
+func hclPos(file string, item *ast.ObjectItem) string {
+	if file == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s:%d", hclFile(file), item.Pos().Line)
+}
+
+// hclFile returns file as it's shown in positions: files in the working
+// directory are shown relative to it.
+func hclFile(file string) string {
+	if pwd, err := os.Getwd(); err == nil && filepath.IsAbs(file) {
+		if rel, err := filepath.Rel(pwd, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+
+	return file
+}
+
+// loadOverrideHcl returns the value of the override argument in the body
+// of a block, which marks a block in an override file that overrides a
+// block of the same name.
+func loadOverrideHcl(body *ast.ObjectList) (bool, error) {
+	var override bool
+	if o := body.Filter("override"); len(o.Items) > 0 {
+		if err := hcl.DecodeObject(&override, o.Items[0].Val); err != nil {
+			return false, fmt.Errorf("Error reading override: %s", err)
+		}
+	}
+
+	return override, nil
+}
+
+// loadProviderExprHcl returns the provider meta-argument of a resource as
+// a RawConfig if it is an expression, or nil if it is a plain name.
+func loadProviderExprHcl(provider string) (*RawConfig, error) {
+	rc, err := NewRawConfig(map[string]interface{}{
+		"provider": provider,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rc.Interpolations) == 0 {
+		return nil, nil
+	}
+	rc.Key = "provider"
+
+	return rc, nil
+}
+
+// loadProviderOverridesHcl returns the provider_config block of a resource
+// as a RawConfig, or nil if the resource doesn't have one.
+func loadProviderOverridesHcl(list *ast.ObjectList) (*RawConfig, error) {
+	o := list.Filter("provider_config")
+	if len(o.Items) == 0 {
+		return nil, nil
+	}
+	if len(o.Items) > 1 {
+		return nil, fmt.Errorf("multiple provider_config blocks found, expected one")
+	}
+	if _, ok := o.Items[0].Val.(*ast.ObjectType); !ok {
+		return nil, fmt.Errorf("should be a block")
+	}
+
+	var config map[string]interface{}
+	if err := hcl.DecodeObject(&config, o.Items[0].Val); err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]struct{}, len(ProviderOverrideArgs))
+	for _, k := range ProviderOverrideArgs {
+		allowed[k] = struct{}{}
+	}
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, ok := allowed[k]; !ok {
+			return nil, fmt.Errorf(
+				"%s can't be overridden, only %s can",
+				k, strings.Join(ProviderOverrideArgs, ", "))
+		}
+	}
+
+	return NewRawConfig(config)
+}
+
 func loadProvisionersHcl(list *ast.ObjectList, connInfo map[string]interface{}) ([]*Provisioner, error) {
 	if err := assertAllBlocksHaveNames("provisioner", list); err != nil {
 		return nil, err
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"reflect"
@@ -314,6 +315,25 @@ func TestLoadFileBasic_modules(t *testing.T) {
 	}
 }
 
+func TestLoadFile_modulePositions(t *testing.T) {
+	path := filepath.Join(fixtureDir, "modules.tf")
+	c, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m := c.Modules[0]
+	cases := map[string]string{
+		"memory":  path + ":2",
+		"unknown": path + ":1",
+	}
+	for arg, expected := range cases {
+		if actual := m.ArgumentPos(arg); actual != expected {
+			t.Fatalf("%q: bad: %s", arg, actual)
+		}
+	}
+}
+
 func TestLoadFile_unnamedModule(t *testing.T) {
 	_, err := LoadFile(filepath.Join(fixtureDir, "module-unnamed.tf"))
 	if err == nil {
@@ -326,6 +346,28 @@ func TestLoadFile_unnamedModule(t *testing.T) {
 	}
 }
 
+func TestLoadFile_resourcePositions(t *testing.T) {
+	path := filepath.Join(fixtureDir, "resource-positions.tf")
+	c, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	r := c.Resources[0]
+	cases := map[string]string{
+		"":               path + ":1",
+		"name":           path + ":2",
+		"ingress":        path + ":4",
+		"ingress.1.from": path + ":8",
+		"unknown":        path + ":1",
+	}
+	for attr, expected := range cases {
+		if actual := r.AttributePos(attr); actual != expected {
+			t.Fatalf("%q: bad: %s", attr, actual)
+		}
+	}
+}
+
 func TestLoadFile_outputDependsOn(t *testing.T) {
 	c, err := LoadFile(filepath.Join(fixtureDir, "output-depends-on.tf"))
 	if err != nil {
@@ -561,6 +603,45 @@ func TestLoadFile_variables(t *testing.T) {
 	}
 }
 
+func TestLoadFile_variablesSensitive(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "variables-sensitive.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := variablesStr(c.Variables)
+	if actual != strings.TrimSpace(variablesSensitiveVariablesStr) {
+		t.Fatalf("bad:\n%s", actual)
+	}
+}
+
+func TestLoadFile_variablesDeprecated(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "variables-deprecated.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := variablesStr(c.Variables)
+	if actual != strings.TrimSpace(variablesDeprecatedVariablesStr) {
+		t.Fatalf("bad:\n%s", actual)
+	}
+	if actual := c.Variables[0].Deprecated; actual != "Use new_name instead." {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
+func TestLoadFile_variablesEphemeral(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "variables-ephemeral.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := variablesStr(c.Variables)
+	if actual != strings.TrimSpace(variablesEphemeralVariablesStr) {
+		t.Fatalf("bad:\n%s", actual)
+	}
+}
+
 func TestLoadDir_basic(t *testing.T) {
 	dir := filepath.Join(fixtureDir, "dir-basic")
 	c, err := LoadDir(dir)
@@ -677,6 +758,238 @@ func TestLoadDir_overrideVar(t *testing.T) {
 	}
 }
 
+func TestLoadDir_overrideUnmarked(t *testing.T) {
+	dir := filepath.Join(fixtureDir, "dir-override-unmarked")
+	_, err := LoadDir(dir)
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	expected := &OverrideError{
+		Address:       "output.foo",
+		Pos:           filepath.Join(dir, "main_override.tf") + ":1",
+		OverriddenPos: filepath.Join(dir, "main.tf") + ":1",
+	}
+	if !reflect.DeepEqual(err, expected) {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestLoadDir_connection(t *testing.T) {
+	c, err := LoadDir(filepath.Join(fixtureDir, "dir-connection"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	conns := make(map[string]map[string]interface{})
+	for _, r := range c.Resources {
+		for i, p := range r.Provisioners {
+			conns[fmt.Sprintf("%s.%d", r.Id(), i)] = p.ConnInfo.Raw
+		}
+	}
+
+	expected := map[string]map[string]interface{}{
+		"aws_instance.web.0": {
+			"user":         "ubuntu",
+			"bastion_host": "bastion.example.com",
+		},
+		"aws_instance.db.0": {
+			"user":         "admin",
+			"bastion_host": "bastion.example.com",
+		},
+		"aws_instance.db.1": {
+			"user":         "admin",
+			"bastion_host": "",
+		},
+	}
+	if !reflect.DeepEqual(conns, expected) {
+		t.Fatalf("bad: %#v", conns)
+	}
+}
+
+func TestLoadDir_connectionOverride(t *testing.T) {
+	c, err := LoadDir(filepath.Join(fixtureDir, "dir-connection-override"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p := c.Resources[0].Provisioners[0]
+	expected := map[string]interface{}{"user": "ubuntu"}
+	if !reflect.DeepEqual(p.ConnInfo.Raw, expected) {
+		t.Fatalf("bad: %#v", p.ConnInfo.Raw)
+	}
+}
+
+func TestLoadDirTerraform(t *testing.T) {
+	dir := filepath.Join(fixtureDir, "dir-terraform-only")
+
+	// The resource has an invalid interpolation, so the whole
+	// configuration can't be loaded.
+	if _, err := LoadDir(dir); err == nil {
+		t.Fatal("should error")
+	}
+
+	c, err := LoadDirTerraform(dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if c.Terraform == nil || c.Terraform.Backend == nil || c.Terraform.Backend.Type != "s3" {
+		t.Fatalf("bad: %#v", c.Terraform)
+	}
+	if c.Terraform.RequiredVersion != "> 0.9.0" {
+		t.Fatalf("bad: %#v", c.Terraform)
+	}
+	if len(c.Resources) != 0 {
+		t.Fatalf("bad: %#v", c.Resources)
+	}
+}
+
+func TestLoadDir_providerExpr(t *testing.T) {
+	c, err := LoadDir(filepath.Join(fixtureDir, "dir-provider-expr"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var foo, bar *Resource
+	for _, r := range c.Resources {
+		switch r.Name {
+		case "foo":
+			foo = r
+		case "bar":
+			bar = r
+		}
+	}
+
+	if foo.Provider != "" || foo.RawProvider == nil {
+		t.Fatalf("bad: %#v", foo)
+	}
+	expected := []string{"aws.east", "aws.west"}
+	if !reflect.DeepEqual(foo.ProviderChoices, expected) {
+		t.Fatalf("bad: %#v", foo.ProviderChoices)
+	}
+
+	if bar.Provider != "aws.west" || bar.RawProvider != nil || bar.ProviderChoices != nil {
+		t.Fatalf("bad: %#v", bar)
+	}
+}
+
+func TestLoadDir_providerOverrides(t *testing.T) {
+	c, err := LoadDir(filepath.Join(fixtureDir, "dir-provider-overrides"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	resources := make(map[string]*Resource)
+	for _, r := range c.Resources {
+		resources[r.Id()] = r
+	}
+	providers := make(map[string]*ProviderConfig)
+	for _, pc := range c.ProviderConfigs {
+		providers[pc.FullName()] = pc
+	}
+
+	cases := []struct {
+		Resource string
+		Provider string
+		Base     string
+		Version  string
+		Config   map[string]interface{}
+	}{
+		{
+			"aws_instance.foo",
+			"aws.provider_config_aws_instance_foo",
+			"aws",
+			"~> 1.0",
+			map[string]interface{}{"region": "eu-west-1"},
+		},
+		{
+			"aws_instance.bar",
+			"aws.provider_config_aws_instance_bar",
+			"aws.west",
+			"",
+			map[string]interface{}{"profile": "other"},
+		},
+		{
+			"data.aws_ami.baz",
+			"aws.provider_config_data_aws_ami_baz",
+			"aws",
+			"~> 1.0",
+			map[string]interface{}{"region": "eu-west-1"},
+		},
+	}
+
+	for _, tc := range cases {
+		r := resources[tc.Resource]
+		if r.Provider != tc.Provider {
+			t.Fatalf("%s: bad: %s", tc.Resource, r.Provider)
+		}
+
+		pc := providers[tc.Provider]
+		if pc == nil {
+			t.Fatalf("%s: no provider %s", tc.Resource, tc.Provider)
+		}
+		if pc.Base != tc.Base {
+			t.Fatalf("%s: bad base: %s", tc.Resource, pc.Base)
+		}
+		if pc.Version != tc.Version {
+			t.Fatalf("%s: bad version: %s", tc.Resource, pc.Version)
+		}
+		if !reflect.DeepEqual(pc.RawConfig.Raw, tc.Config) {
+			t.Fatalf("%s: bad: %#v", tc.Resource, pc.RawConfig.Raw)
+		}
+	}
+
+	// The declared configurations aren't changed
+	if v := providers["aws"].RawConfig.Raw["region"]; v != "us-east-1" {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestLoadDir_providerOverridesArgs(t *testing.T) {
+	_, err := LoadDir(filepath.Join(fixtureDir, "dir-provider-overrides-args"))
+	if err == nil || !strings.Contains(err.Error(), "access_key can't be overridden") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestLoadDirEnv_override(t *testing.T) {
+	cases := []struct {
+		Env          string
+		AMI          string
+		InstanceType string
+	}{
+		{"", "bar", "t2.micro"},
+		{"default", "bar", "t2.micro"},
+		{"prod", "prod", "m4.large"},
+		{"dev", "bar", "t2.nano"},
+	}
+
+	for _, tc := range cases {
+		c, err := LoadDirEnv(filepath.Join(fixtureDir, "dir-override-env"), tc.Env)
+		if err != nil {
+			t.Fatalf("%q: err: %s", tc.Env, err)
+		}
+
+		// Files with ".override" in their name are ordinary files
+		if len(c.Resources) != 2 {
+			t.Fatalf("%q: bad: %#v", tc.Env, c.Resources)
+		}
+
+		raw := c.Resources[0].RawConfig.Raw
+		if raw["ami"] != tc.AMI {
+			t.Fatalf("%q: bad ami: %#v", tc.Env, raw["ami"])
+		}
+		if raw["instance_type"] != tc.InstanceType {
+			t.Fatalf("%q: bad instance_type: %#v", tc.Env, raw["instance_type"])
+		}
+	}
+}
+
 func TestLoadFile_mismatchedVariableTypes(t *testing.T) {
 	_, err := LoadFile(filepath.Join(fixtureDir, "variable-mismatched-type.tf"))
 	if err == nil {
@@ -832,6 +1145,101 @@ func TestLoadFile_createBeforeDestroy(t *testing.T) {
 	}
 }
 
+func TestLoadFile_destroyPriority(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "destroy-priority.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]int{
+		"web": 10,
+		"bar": -1,
+		"baz": 0,
+	}
+	for _, r := range c.Resources {
+		if r.Lifecycle.DestroyPriority != expected[r.Name] {
+			t.Fatalf("bad: %s: %d", r.Name, r.Lifecycle.DestroyPriority)
+		}
+	}
+}
+
+func TestLoadFile_priority(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "priority.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]int{
+		"db":  10,
+		"bar": -1,
+		"baz": 0,
+	}
+	for _, r := range c.Resources {
+		if r.Lifecycle.Priority != expected[r.Name] {
+			t.Fatalf("bad: %s: %d", r.Name, r.Lifecycle.Priority)
+		}
+	}
+}
+
+func TestLoadFile_stage(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "stage.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]int{
+		"aws_route53_record.www":  1,
+		"aws_acm_certificate.www": 2,
+		"aws_instance.web":        0,
+	}
+	for _, r := range c.Resources {
+		if r.Stage != expected[r.Id()] {
+			t.Fatalf("bad: %s: %d", r.Id(), r.Stage)
+		}
+	}
+}
+
+func TestLoadFile_stageInvalid(t *testing.T) {
+	_, err := LoadFile(filepath.Join(fixtureDir, "stage-invalid.tf"))
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestLoadFile_waitForReady(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "wait-for-ready.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]bool{
+		"web": true,
+		"bar": false,
+	}
+	for _, r := range c.Resources {
+		if r.Lifecycle.WaitForReady != expected[r.Name] {
+			t.Fatalf("bad: %s: %t", r.Name, r.Lifecycle.WaitForReady)
+		}
+	}
+}
+
+func TestLoadFile_adoptExisting(t *testing.T) {
+	c, err := LoadFile(filepath.Join(fixtureDir, "adopt-existing.tf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]bool{
+		"web": true,
+		"bar": false,
+	}
+	for _, r := range c.Resources {
+		if r.Lifecycle.AdoptExisting != expected[r.Name] {
+			t.Fatalf("bad: %s: %t", r.Name, r.Lifecycle.AdoptExisting)
+		}
+	}
+}
+
 func TestLoadFile_ignoreChanges(t *testing.T) {
 	c, err := LoadFile(filepath.Join(fixtureDir, "ignore-changes.tf"))
 	if err != nil {
@@ -1285,6 +1693,27 @@ foo (required)
   <>
 `
 
+const variablesSensitiveVariablesStr = `
+password (required) (sensitive)
+  <>
+  <>
+`
+
+const variablesDeprecatedVariablesStr = `
+old_name (deprecated)
+  <>
+  <>
+`
+
+const variablesEphemeralVariablesStr = `
+password (required) (ephemeral)
+  <>
+  <>
+token (ephemeral)
+  <>
+  <>
+`
+
 const createBeforeDestroyResourcesStr = `
 aws_instance.bar (x1)
   ami
@@ -1,10 +1,17 @@
 package config
 
+import "fmt"
+
 // Merge merges two configurations into a single configuration.
 //
 // Merge allows for the two configurations to have duplicate resources,
 // because the resources will be merged. This differs from a single
 // Config which must only have unique resources.
+//
+// Variables, outputs and providers in c2 are only merged into those of the
+// same name in c1 if they're marked with "override = true", so that they
+// aren't overridden by accident. Merge returns an *OverrideError if one
+// isn't.
 func Merge(c1, c2 *Config) (*Config, error) {
 	c := new(Config)
 
@@ -32,6 +39,13 @@ func Merge(c1, c2 *Config) (*Config, error) {
 		c.Atlas = c2.Atlas
 	}
 
+	// Merge the default connection key by key
+	if c2.Connection != nil {
+		c.Connection = c1.Connection.merge(c2.Connection)
+	} else {
+		c.Connection = c1.Connection
+	}
+
 	// Merge the Terraform configuration
 	if c1.Terraform != nil {
 		c.Terraform = c1.Terraform
@@ -51,6 +65,7 @@ func Merge(c1, c2 *Config) (*Config, error) {
 	// are pretty low-error.
 
 	var m1, m2, mresult []merger
+	var err error
 
 	// Modules
 	m1 = make([]merger, 0, len(c1.Modules))
@@ -61,7 +76,10 @@ func Merge(c1, c2 *Config) (*Config, error) {
 	for _, v := range c2.Modules {
 		m2 = append(m2, v)
 	}
-	mresult = mergeSlice(m1, m2)
+	mresult, err = mergeSlice(m1, m2)
+	if err != nil {
+		return nil, err
+	}
 	if len(mresult) > 0 {
 		c.Modules = make([]*Module, len(mresult))
 		for i, v := range mresult {
@@ -78,7 +96,10 @@ func Merge(c1, c2 *Config) (*Config, error) {
 	for _, v := range c2.Outputs {
 		m2 = append(m2, v)
 	}
-	mresult = mergeSlice(m1, m2)
+	mresult, err = mergeSlice(m1, m2)
+	if err != nil {
+		return nil, err
+	}
 	if len(mresult) > 0 {
 		c.Outputs = make([]*Output, len(mresult))
 		for i, v := range mresult {
@@ -95,7 +116,10 @@ func Merge(c1, c2 *Config) (*Config, error) {
 	for _, v := range c2.ProviderConfigs {
 		m2 = append(m2, v)
 	}
-	mresult = mergeSlice(m1, m2)
+	mresult, err = mergeSlice(m1, m2)
+	if err != nil {
+		return nil, err
+	}
 	if len(mresult) > 0 {
 		c.ProviderConfigs = make([]*ProviderConfig, len(mresult))
 		for i, v := range mresult {
@@ -112,7 +136,10 @@ func Merge(c1, c2 *Config) (*Config, error) {
 	for _, v := range c2.Resources {
 		m2 = append(m2, v)
 	}
-	mresult = mergeSlice(m1, m2)
+	mresult, err = mergeSlice(m1, m2)
+	if err != nil {
+		return nil, err
+	}
 	if len(mresult) > 0 {
 		c.Resources = make([]*Resource, len(mresult))
 		for i, v := range mresult {
@@ -129,7 +156,10 @@ func Merge(c1, c2 *Config) (*Config, error) {
 	for _, v := range c2.Variables {
 		m2 = append(m2, v)
 	}
-	mresult = mergeSlice(m1, m2)
+	mresult, err = mergeSlice(m1, m2)
+	if err != nil {
+		return nil, err
+	}
 	if len(mresult) > 0 {
 		c.Variables = make([]*Variable, len(mresult))
 		for i, v := range mresult {
@@ -148,8 +178,22 @@ type merger interface {
 	mergerMerge(merger) merger
 }
 
+// overrider is implemented by the mergers that can only be merged into an
+// existing one if they're marked to override it.
+type overrider interface {
+	merger
+
+	// mergerAddress returns the address of the block, such as "var.foo",
+	// and mergerPos returns where it is, as "file:line".
+	mergerAddress() string
+	mergerPos() string
+
+	// mergerOverride returns true if the block is marked to override.
+	mergerOverride() bool
+}
+
 // mergeSlice merges a slice of mergers.
-func mergeSlice(m1, m2 []merger) []merger {
+func mergeSlice(m1, m2 []merger) ([]merger, error) {
 	r := make([]merger, len(m1), len(m1)+len(m2))
 	copy(r, m1)
 
@@ -179,6 +223,14 @@ func mergeSlice(m1, m2 []merger) []merger {
 		if original == nil {
 			v = v2
 		} else {
+			if o, ok := v2.(overrider); ok && !o.mergerOverride() {
+				return nil, &OverrideError{
+					Address:       o.mergerAddress(),
+					Pos:           o.mergerPos(),
+					OverriddenPos: original.(overrider).mergerPos(),
+				}
+			}
+
 			v = original.mergerMerge(v2)
 		}
 
@@ -189,5 +241,55 @@ func mergeSlice(m1, m2 []merger) []merger {
 		}
 	}
 
-	return r
+	return r, nil
+}
+
+// DuplicateError is the error for a variable, output or provider that's
+// declared more than once in a module, possibly in different files.
+type DuplicateError struct {
+	// Address is the address of the block, such as "var.foo".
+	Address string
+
+	// Pos is where the duplicate is, and PrevPos is where the block was
+	// first declared, as "file:line". Either may be empty if it isn't
+	// known.
+	Pos     string
+	PrevPos string
+}
+
+func (e *DuplicateError) Error() string {
+	msg := fmt.Sprintf(
+		"%s: declared multiple times, you can only declare %s once",
+		e.Address, e.Address)
+	if e.Pos != "" && e.PrevPos != "" {
+		msg = fmt.Sprintf("%s (declared at %s and at %s)", msg, e.PrevPos, e.Pos)
+	}
+
+	return msg
+}
+
+// OverrideError is the error for a variable, output or provider in an
+// override file that would override a block of the same name, but isn't
+// marked with "override = true".
+type OverrideError struct {
+	// Address is the address of the block, such as "var.foo".
+	Address string
+
+	// Pos is where the block in the override file is, and OverriddenPos is
+	// where the block it would override is, as "file:line". Either may be
+	// empty if it isn't known.
+	Pos           string
+	OverriddenPos string
+}
+
+func (e *OverrideError) Error() string {
+	msg := fmt.Sprintf(
+		"%s: overrides an existing declaration without \"override = true\"; "+
+			"set it in the override file to override the declaration",
+		e.Address)
+	if e.Pos != "" && e.OverriddenPos != "" {
+		msg = fmt.Sprintf("%s (declared at %s, overridden at %s)", msg, e.OverriddenPos, e.Pos)
+	}
+
+	return msg
 }
@@ -122,7 +122,7 @@ func TestMerge(t *testing.T) {
 					&Resource{Name: "bar"},
 				},
 				Variables: []*Variable{
-					&Variable{Name: "foo", Default: "bar"},
+					&Variable{Name: "foo", Default: "bar", Override: true},
 					&Variable{Name: "bar"},
 				},
 
@@ -224,7 +224,7 @@ func TestMerge(t *testing.T) {
 			},
 			&Config{
 				ProviderConfigs: []*ProviderConfig{
-					&ProviderConfig{Alias: "foo"},
+					&ProviderConfig{Alias: "foo", Override: true},
 				},
 			},
 			&Config{
@@ -274,7 +274,7 @@ func TestMerge(t *testing.T) {
 			},
 			&Config{
 				Variables: []*Variable{
-					&Variable{DeclaredType: "foo"},
+					&Variable{DeclaredType: "foo", Override: true},
 				},
 			},
 			&Config{
@@ -324,7 +324,7 @@ func TestMerge(t *testing.T) {
 			},
 			&Config{
 				Outputs: []*Output{
-					&Output{Description: "foo"},
+					&Output{Description: "foo", Override: true},
 				},
 			},
 			&Config{
@@ -374,7 +374,7 @@ func TestMerge(t *testing.T) {
 			},
 			&Config{
 				Outputs: []*Output{
-					&Output{DependsOn: []string{"foo"}},
+					&Output{DependsOn: []string{"foo"}, Override: true},
 				},
 			},
 			&Config{
@@ -424,7 +424,7 @@ func TestMerge(t *testing.T) {
 			},
 			&Config{
 				Outputs: []*Output{
-					&Output{Sensitive: true},
+					&Output{Sensitive: true, Override: true},
 				},
 			},
 			&Config{
@@ -435,6 +435,52 @@ func TestMerge(t *testing.T) {
 			false,
 		},
 
+		// Variables, outputs and providers must be marked to override
+		{
+			&Config{
+				Variables: []*Variable{
+					&Variable{Name: "foo", Default: "foo"},
+				},
+			},
+			&Config{
+				Variables: []*Variable{
+					&Variable{Name: "foo", Default: "bar"},
+				},
+			},
+			nil,
+			true,
+		},
+
+		{
+			&Config{
+				Outputs: []*Output{
+					&Output{Name: "foo"},
+				},
+			},
+			&Config{
+				Outputs: []*Output{
+					&Output{Name: "foo"},
+				},
+			},
+			nil,
+			true,
+		},
+
+		{
+			&Config{
+				ProviderConfigs: []*ProviderConfig{
+					&ProviderConfig{Name: "foo"},
+				},
+			},
+			&Config{
+				ProviderConfigs: []*ProviderConfig{
+					&ProviderConfig{Name: "foo"},
+				},
+			},
+			nil,
+			true,
+		},
+
 		// terraform blocks are merged, not overwritten
 		{
 			&Config{
@@ -0,0 +1,22 @@
+package module
+
+import (
+	"net/http"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-getter"
+	"github.com/hashicorp/terraform/helper/credentials"
+)
+
+func init() {
+	// Modules are downloaded over HTTP with the tokens saved for their host,
+	// such as the token of a private module registry.
+	httpGetter := &getter.HttpGetter{
+		Netrc: true,
+		Client: &http.Client{
+			Transport: credentials.Transport(cleanhttp.DefaultPooledTransport()),
+		},
+	}
+	getter.Getters["http"] = httpGetter
+	getter.Getters["https"] = httpGetter
+}
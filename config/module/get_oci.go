@@ -0,0 +1,449 @@
+package module
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-getter"
+)
+
+func init() {
+	getter.Getters["oci"] = new(ociGetter)
+}
+
+// ociManifestMediaTypes are the manifests that a module package can be
+// published with, in the Accept header of manifest requests.
+var ociManifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ociModuleMediaTypes are the media types of the layers that can hold a
+// module package, and the decompressor of each.
+var ociModuleMediaTypes = map[string]string{
+	"application/vnd.terraform.module.v1.tar+gzip":      "tar.gz",
+	"application/vnd.terraform.module.v1+zip":           "zip",
+	"application/vnd.oci.image.layer.v1.tar+gzip":       "tar.gz",
+	"application/vnd.docker.image.rootfs.diff.tar.gzip": "tar.gz",
+	"application/zip": "zip",
+}
+
+// ociGetter is a getter.Getter for module packages published as OCI
+// artifacts, with sources such as oci://registry/org/module:tag or
+// oci://registry/org/module@sha256:digest. The package is the single
+// layer of the artifact that is a tar.gz or zip archive.
+//
+// Registries are authenticated with as Docker does, with the credentials
+// from the Docker configuration. See dockerCredentials.
+type ociGetter struct {
+	// Client is the HTTP client for registries. If it's nil, a default
+	// client is used.
+	Client *http.Client
+
+	// Credentials returns the credentials for a registry host, or nil if
+	// there are none. If it's nil, dockerCredentials is used.
+	Credentials func(host string) (*registryCredentials, error)
+}
+
+func (g *ociGetter) ClientMode(*url.URL) (getter.ClientMode, error) {
+	return getter.ClientModeDir, nil
+}
+
+func (g *ociGetter) GetFile(string, *url.URL) error {
+	return fmt.Errorf("module packages in OCI registries can only be fetched as directories")
+}
+
+func (g *ociGetter) Get(dst string, u *url.URL) error {
+	r, ref, err := g.registry(u)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] fetching module package %s:%s from OCI registry %s", r.repo, ref, r.host)
+	layer, err := r.moduleLayer(ref)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile("", "tf-oci-module")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if err := r.blob(layer.Digest, f); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// The directory is replaced on update, so that files that were removed
+	// from the package don't linger.
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+
+	d := getter.Decompressors[ociModuleMediaTypes[layer.MediaType]]
+	return d.Decompress(dst, f.Name(), true)
+}
+
+// registry returns the registry of the source u, and the tag or digest of
+// the module package in it.
+func (g *ociGetter) registry(u *url.URL) (*ociRegistry, string, error) {
+	if u.Host == "" {
+		return nil, "", fmt.Errorf("the source %s has no registry host", u)
+	}
+
+	repo := strings.TrimPrefix(u.Path, "/")
+	ref := "latest"
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo, ref = repo[:i], repo[i+1:]
+	} else if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo, ref = repo[:i], repo[i+1:]
+	}
+	if repo == "" || ref == "" {
+		return nil, "", fmt.Errorf("the source %s isn't of the form oci://registry/repository:tag", u)
+	}
+
+	r := &ociRegistry{
+		client:      g.Client,
+		credentials: g.Credentials,
+		scheme:      "https",
+		host:        u.Host,
+		repo:        repo,
+	}
+	if r.client == nil {
+		r.client = cleanhttp.DefaultClient()
+	}
+	if r.credentials == nil {
+		r.credentials = dockerCredentials
+	}
+
+	// Docker Hub is known by another name than the host of its API, and
+	// its official images are in the library namespace.
+	if r.host == "docker.io" {
+		r.host = "registry-1.docker.io"
+		if !strings.Contains(r.repo, "/") {
+			r.repo = "library/" + r.repo
+		}
+	}
+
+	// As with Docker, registries on the local machine are spoken to
+	// without TLS.
+	if host, _, err := net.SplitHostPort(r.host); err == nil && ociIsLocal(host) ||
+		ociIsLocal(r.host) {
+		r.scheme = "http"
+	}
+
+	return r, ref, nil
+}
+
+func ociIsLocal(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ociDescriptor describes a blob in a registry.
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// ociRegistry is a client of a repository in an OCI registry.
+type ociRegistry struct {
+	client      *http.Client
+	credentials func(host string) (*registryCredentials, error)
+
+	scheme string
+	host   string
+	repo   string
+
+	// authorization is the Authorization header of requests, once the
+	// registry has asked for it.
+	authorization string
+}
+
+// moduleLayer returns the layer with the module package of the artifact
+// with the given tag or digest.
+func (r *ociRegistry) moduleLayer(ref string) (*ociDescriptor, error) {
+	// Tags can't have a colon, so the reference is a digest
+	byDigest := strings.Contains(ref, ":")
+	if byDigest && !strings.HasPrefix(ref, "sha256:") {
+		return nil, fmt.Errorf("unsupported digest algorithm of %s", ref)
+	}
+
+	resp, err := r.get("manifests/"+ref, strings.Join(ociManifestMediaTypes, ", "))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error downloading the manifest of %s:%s: %s", r.repo, ref, err)
+	}
+
+	// The layer is only checked against the digest in the manifest, so a
+	// manifest asked for by digest must be checked against that digest.
+	if byDigest {
+		sum := sha256.Sum256(body)
+		if actual := "sha256:" + hex.EncodeToString(sum[:]); actual != ref {
+			return nil, fmt.Errorf("the manifest of %s@%s has the digest %s", r.repo, ref, actual)
+		}
+	}
+
+	var manifest struct {
+		Layers []*ociDescriptor `json:"layers"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("Error decoding the manifest of %s:%s: %s", r.repo, ref, err)
+	}
+
+	var result *ociDescriptor
+	for _, l := range manifest.Layers {
+		if _, ok := ociModuleMediaTypes[l.MediaType]; !ok {
+			continue
+		}
+		if result != nil {
+			return nil, fmt.Errorf(
+				"%s:%s has more than one layer that could be the module package", r.repo, ref)
+		}
+		result = l
+	}
+	if result == nil {
+		return nil, fmt.Errorf(
+			"%s:%s has no tar.gz or zip layer with a module package", r.repo, ref)
+	}
+
+	return result, nil
+}
+
+// blob writes the blob with the given digest to w, and checks that its
+// content matches the digest.
+func (r *ociRegistry) blob(digest string, w io.Writer) error {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return fmt.Errorf("unsupported digest algorithm of %s", digest)
+	}
+
+	resp, err := r.get("blobs/"+digest, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), resp.Body); err != nil {
+		return fmt.Errorf("Error downloading %s: %s", digest, err)
+	}
+	if actual := "sha256:" + hex.EncodeToString(h.Sum(nil)); actual != digest {
+		return fmt.Errorf("the content of blob %s has the digest %s", digest, actual)
+	}
+
+	return nil
+}
+
+// get sends a GET request for the given path of the repository, and
+// authenticates as the registry asks if it needs to.
+func (r *ociRegistry) get(path, accept string) (*http.Response, error) {
+	u := fmt.Sprintf("%s://%s/v2/%s/%s", r.scheme, r.host, r.repo, path)
+	for {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		authorized := r.authorization != ""
+		if authorized {
+			req.Header.Set("Authorization", r.authorization)
+		}
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return resp, nil
+		case resp.StatusCode == http.StatusUnauthorized && !authorized:
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if err := r.authenticate(challenge); err != nil {
+				return nil, fmt.Errorf("Error authenticating with %s: %s", r.host, err)
+			}
+			continue
+		}
+
+		resp.Body.Close()
+		return nil, fmt.Errorf("Error fetching %s: %s", u, resp.Status)
+	}
+}
+
+// authenticate sets the Authorization header of requests, as asked for by
+// the WWW-Authenticate challenge of the registry.
+func (r *ociRegistry) authenticate(challenge string) error {
+	creds, err := r.credentials(r.host)
+	if err != nil {
+		return err
+	}
+
+	scheme, params := parseAuthChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if creds == nil || creds.Username == "" {
+			return fmt.Errorf("no credentials for %s in the Docker configuration", r.host)
+		}
+		r.authorization = "Basic " + base64.StdEncoding.EncodeToString(
+			[]byte(creds.Username+":"+creds.Password))
+		return nil
+
+	case "bearer":
+		scope := params["scope"]
+		if scope == "" {
+			scope = "repository:" + r.repo + ":pull"
+		}
+
+		token, err := r.token(params["realm"], params["service"], scope, creds)
+		if err != nil {
+			return err
+		}
+		r.authorization = "Bearer " + token
+		return nil
+	}
+
+	return fmt.Errorf("unsupported authentication challenge %q", challenge)
+}
+
+// token returns a bearer token from the token service at realm, for the
+// given service and scope. Without credentials, the token is anonymous.
+func (r *ociRegistry) token(realm, service, scope string, creds *registryCredentials) (string, error) {
+	if realm == "" {
+		return "", fmt.Errorf("the registry asked for a token but gave no realm")
+	}
+
+	var req *http.Request
+	var err error
+	if creds != nil && creds.IdentityToken != "" {
+		// An identity token is exchanged for an access token with OAuth2
+		form := url.Values{
+			"grant_type":    {"refresh_token"},
+			"service":       {service},
+			"scope":         {scope},
+			"client_id":     {"terraform"},
+			"refresh_token": {creds.IdentityToken},
+		}
+		req, err = http.NewRequest("POST", realm, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		u, err := url.Parse(realm)
+		if err != nil {
+			return "", err
+		}
+		q := u.Query()
+		if service != "" {
+			q.Set("service", service)
+		}
+		q.Set("scope", scope)
+		u.RawQuery = q.Encode()
+
+		req, err = http.NewRequest("GET", u.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		if creds != nil && creds.Username != "" {
+			req.SetBasicAuth(creds.Username, creds.Password)
+		}
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("the token service %s returned %s", realm, resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("Error decoding the token from %s: %s", realm, err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+
+	return "", fmt.Errorf("the token service %s returned no token", realm)
+}
+
+// parseAuthChallenge parses a WWW-Authenticate header, such as
+// `Bearer realm="https://auth.example.com/token",service="example.com"`,
+// into its scheme and parameters.
+func parseAuthChallenge(h string) (string, map[string]string) {
+	params := make(map[string]string)
+
+	h = strings.TrimSpace(h)
+	i := strings.IndexByte(h, ' ')
+	if i < 0 {
+		return h, params
+	}
+	scheme, rest := h[:i], h[i+1:]
+
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return scheme, params
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = rest[:end], rest[end:]
+		}
+
+		params[key] = value
+	}
+}
@@ -0,0 +1,165 @@
+package module
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// registryCredentials are the credentials for an OCI registry.
+type registryCredentials struct {
+	Username string
+	Password string
+
+	// IdentityToken is an OAuth2 refresh token, which some credential
+	// helpers return instead of a password.
+	IdentityToken string
+}
+
+// dockerConfig is the part of the Docker configuration file that holds the
+// credentials for registries.
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth          string `json:"auth"`
+		Username      string `json:"username"`
+		Password      string `json:"password"`
+		IdentityToken string `json:"identitytoken"`
+	} `json:"auths"`
+
+	CredHelpers map[string]string `json:"credHelpers"`
+	CredsStore  string            `json:"credsStore"`
+}
+
+// dockerHubServer is the key of Docker Hub in the Docker configuration.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// runCredentialHelper runs "docker-credential-HELPER get" for the given
+// server, and returns its output, or nil if the helper has no credentials
+// for the server. It's a variable so that tests can replace it.
+var runCredentialHelper = func(helper, server string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// Helpers report missing credentials on stdout
+		msg := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(msg, "credentials not found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("docker-credential-%s: %s: %s", helper, err, msg)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// dockerCredentials returns the credentials for the registry host from the
+// Docker configuration, as "docker login" saves them, or nil if there are
+// none. The configuration is $DOCKER_CONFIG/config.json, or
+// ~/.docker/config.json.
+//
+// As with Docker, the credential helper for the host in credHelpers is
+// used if there is one, and otherwise the credsStore helper if it's set,
+// and otherwise the credentials in auths.
+func dockerCredentials(host string) (*registryCredentials, error) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config dockerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("Error parsing the Docker configuration: %s", err)
+	}
+
+	server := host
+	if host == "registry-1.docker.io" {
+		server = dockerHubServer
+	}
+
+	helper := config.CredHelpers[host]
+	if helper == "" {
+		helper = config.CredsStore
+	}
+	if helper != "" {
+		out, err := runCredentialHelper(helper, server)
+		if err != nil || out == nil {
+			return nil, err
+		}
+
+		var creds struct {
+			Username string
+			Secret   string
+		}
+		if err := json.Unmarshal(out, &creds); err != nil {
+			return nil, fmt.Errorf(
+				"Error parsing the credentials from docker-credential-%s: %s", helper, err)
+		}
+
+		// Helpers return identity tokens with this username
+		if creds.Username == "<token>" {
+			return &registryCredentials{IdentityToken: creds.Secret}, nil
+		}
+		return &registryCredentials{Username: creds.Username, Password: creds.Secret}, nil
+	}
+
+	for key, auth := range config.Auths {
+		if key != server && dockerConfigHost(key) != host {
+			continue
+		}
+
+		result := &registryCredentials{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			IdentityToken: auth.IdentityToken,
+		}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("Error decoding the credentials of %s: %s", key, err)
+			}
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("the credentials of %s aren't of the form user:password", key)
+			}
+			result.Username, result.Password = parts[0], parts[1]
+		}
+
+		return result, nil
+	}
+
+	return nil, nil
+}
+
+// dockerConfigHost returns the host of a key of the auths of the Docker
+// configuration, which may be a host or a URL.
+func dockerConfigHost(key string) string {
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	if i := strings.IndexByte(key, '/'); i >= 0 {
+		key = key[:i]
+	}
+
+	return key
+}
@@ -0,0 +1,378 @@
+package module
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-getter"
+	"github.com/hashicorp/terraform/config"
+)
+
+// testOCIPackage returns a tar.gz module package with the given files,
+// and the directories they're in, as tar archives them.
+func testOCIPackage(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dirs := make(map[string]bool)
+	for _, name := range names {
+		if dir := path.Dir(name); dir != "." && !dirs[dir] {
+			dirs[dir] = true
+			err := tw.WriteHeader(&tar.Header{
+				Name:     dir + "/",
+				Mode:     0755,
+				Typeflag: tar.TypeDir,
+			})
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+		}
+
+		content := files[name]
+		err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		tw.Write([]byte(content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return buf.Bytes()
+}
+
+func testOCIDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// testOCIRegistry returns a registry with the module package pkg as the
+// artifact org/module:1.0.0, which is only served with a token that the
+// token service gives to user:pass.
+func testOCIRegistry(t *testing.T, pkg []byte) (*httptest.Server, string) {
+	config := []byte("{}")
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"config": map[string]interface{}{
+			"mediaType": "application/vnd.terraform.module.config.v1+json",
+			"digest":    testOCIDigest(config),
+			"size":      len(config),
+		},
+		"layers": []map[string]interface{}{
+			{
+				"mediaType": "application/vnd.terraform.module.v1.tar+gzip",
+				"digest":    testOCIDigest(pkg),
+				"size":      len(pkg),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	manifestDigest := testOCIDigest(manifest)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			user, pass, _ := r.BasicAuth()
+			if user != "user" || pass != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("scope") != "repository:org/module:pull" ||
+				r.URL.Query().Get("service") != "test-registry" {
+				t.Errorf("bad token request: %s", r.URL)
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "secret-token"})
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Bearer realm="%s/token",service="test-registry",scope="repository:org/module:pull"`,
+				server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		// The manifest is served for any reference, as by a registry that
+		// can't be trusted to honor digests.
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v2/org/module/manifests/"):
+			if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.manifest.v1+json") {
+				t.Errorf("bad Accept: %s", r.Header.Get("Accept"))
+			}
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Write(manifest)
+		case r.URL.Path == "/v2/org/module/blobs/"+testOCIDigest(pkg):
+			w.Write(pkg)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return server, manifestDigest
+}
+
+// testDockerConfig points DOCKER_CONFIG to a directory with the given
+// configuration, and returns a function to undo it.
+func testDockerConfig(t *testing.T, config string) func() {
+	dir, err := ioutil.TempDir("", "tf-docker")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	old, ok := os.LookupEnv("DOCKER_CONFIG")
+	os.Setenv("DOCKER_CONFIG", dir)
+	return func() {
+		if ok {
+			os.Setenv("DOCKER_CONFIG", old)
+		} else {
+			os.Unsetenv("DOCKER_CONFIG")
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestOCIGetter(t *testing.T) {
+	pkg := testOCIPackage(t, map[string]string{
+		"main.tf":       `variable "foo" {}`,
+		"child/main.tf": `output "bar" { value = "baz" }`,
+	})
+	server, manifestDigest := testOCIRegistry(t, pkg)
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	defer testDockerConfig(t, fmt.Sprintf(`{"auths": {"%s": {"auth": "%s"}}}`,
+		host, base64.StdEncoding.EncodeToString([]byte("user:pass"))))()
+
+	for _, src := range []string{
+		"oci://" + host + "/org/module:1.0.0",
+		"oci://" + host + "/org/module@" + manifestDigest,
+	} {
+		dst := tempDir(t)
+		defer os.RemoveAll(dst)
+
+		// The getter is registered with go-getter
+		if err := getter.Get(dst, src); err != nil {
+			t.Fatalf("%s: err: %s", src, err)
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dst, "child", "main.tf"))
+		if err != nil {
+			t.Fatalf("%s: err: %s", src, err)
+		}
+		if string(data) != `output "bar" { value = "baz" }` {
+			t.Fatalf("%s: bad: %s", src, data)
+		}
+	}
+}
+
+func TestOCIGetter_manifestDigest(t *testing.T) {
+	pkg := testOCIPackage(t, map[string]string{"main.tf": ""})
+	server, _ := testOCIRegistry(t, pkg)
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	defer testDockerConfig(t, fmt.Sprintf(`{"auths": {"%s": {"auth": "%s"}}}`,
+		host, base64.StdEncoding.EncodeToString([]byte("user:pass"))))()
+
+	dst := tempDir(t)
+	defer os.RemoveAll(dst)
+
+	// The registry serves its manifest for a digest that isn't its own
+	src := "oci://" + host + "/org/module@" + testOCIDigest([]byte("other"))
+	err := getter.Get(dst, src)
+	if err == nil || !strings.Contains(err.Error(), "has the digest") {
+		t.Fatalf("expected a digest mismatch, got: %v", err)
+	}
+}
+
+func TestOCIGetter_subDir(t *testing.T) {
+	pkg := testOCIPackage(t, map[string]string{
+		"main.tf":       `variable "foo" {}`,
+		"child/main.tf": `output "bar" { value = "baz" }`,
+	})
+	server, _ := testOCIRegistry(t, pkg)
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	defer testDockerConfig(t, fmt.Sprintf(`{"auths": {"http://%s/v2/": {"username": "user", "password": "pass"}}}`, host))()
+
+	// A module in a subdirectory of the package is loaded as with any
+	// other source.
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	src := fmt.Sprintf(`module "foo" { source = "oci://%s/org/module:1.0.0//child" }`, host)
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.tf"), []byte(src), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	c, err := config.LoadDir(dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	tree := NewTree("", c)
+	if err := tree.Load(testStorage(t), GetModeGet); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	child := tree.Children()["foo"]
+	if child == nil || len(child.Config().Outputs) != 1 {
+		t.Fatalf("bad: %#v", child)
+	}
+}
+
+func TestOCIGetter_badCredentials(t *testing.T) {
+	pkg := testOCIPackage(t, map[string]string{"main.tf": ""})
+	server, _ := testOCIRegistry(t, pkg)
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	defer testDockerConfig(t, fmt.Sprintf(`{"auths": {"%s": {"auth": "%s"}}}`,
+		host, base64.StdEncoding.EncodeToString([]byte("user:wrong"))))()
+
+	dst := tempDir(t)
+	defer os.RemoveAll(dst)
+
+	err := getter.Get(dst, "oci://"+host+"/org/module:1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "Error authenticating") {
+		t.Fatalf("expected an authentication error, got: %v", err)
+	}
+}
+
+func TestOCIGetter_registry(t *testing.T) {
+	cases := []struct {
+		Src    string
+		Scheme string
+		Host   string
+		Repo   string
+		Ref    string
+		Err    bool
+	}{
+		{"oci://registry.example.com/org/module:1.0.0", "https", "registry.example.com", "org/module", "1.0.0", false},
+		{"oci://registry.example.com:5000/org/module", "https", "registry.example.com:5000", "org/module", "latest", false},
+		{"oci://registry.example.com/org/module@sha256:abcd", "https", "registry.example.com", "org/module", "sha256:abcd", false},
+		{"oci://localhost:5000/module:v1", "http", "localhost:5000", "module", "v1", false},
+		{"oci://docker.io/module:v1", "https", "registry-1.docker.io", "library/module", "v1", false},
+		{"oci://registry.example.com/", "", "", "", "", true},
+	}
+
+	for _, tc := range cases {
+		u, err := url.Parse(tc.Src)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.Src, err)
+		}
+
+		r, ref, err := new(ociGetter).registry(u)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: err: %v", tc.Src, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		actual := []string{r.scheme, r.host, r.repo, ref}
+		expected := []string{tc.Scheme, tc.Host, tc.Repo, tc.Ref}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("%s: bad: %#v", tc.Src, actual)
+		}
+	}
+}
+
+func TestDockerCredentials_helper(t *testing.T) {
+	defer testDockerConfig(t, `{
+		"auths": {"registry.example.com": {}},
+		"credHelpers": {"registry.example.com": "test"},
+		"credsStore": "store"
+	}`)()
+
+	old := runCredentialHelper
+	defer func() { runCredentialHelper = old }()
+	runCredentialHelper = func(helper, server string) ([]byte, error) {
+		switch {
+		case helper == "test" && server == "registry.example.com":
+			return []byte(`{"ServerURL": "registry.example.com", "Username": "user", "Secret": "pass"}`), nil
+		case helper == "store" && server == dockerHubServer:
+			return []byte(`{"ServerURL": "https://index.docker.io/v1/", "Username": "<token>", "Secret": "refresh"}`), nil
+		}
+		return nil, nil
+	}
+
+	cases := map[string]*registryCredentials{
+		"registry.example.com": {Username: "user", Password: "pass"},
+		"registry-1.docker.io": {IdentityToken: "refresh"},
+		"other.example.com":    nil,
+	}
+	for host, expected := range cases {
+		actual, err := dockerCredentials(host)
+		if err != nil {
+			t.Fatalf("%s: err: %s", host, err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("%s: bad: %#v", host, actual)
+		}
+	}
+}
+
+func TestParseAuthChallenge(t *testing.T) {
+	scheme, params := parseAuthChallenge(
+		`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull,push"`)
+	if scheme != "Bearer" {
+		t.Fatalf("bad scheme: %s", scheme)
+	}
+
+	expected := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:a/b:pull,push",
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Fatalf("bad: %#v", params)
+	}
+
+	scheme, params = parseAuthChallenge(`Basic realm=registry`)
+	if scheme != "Basic" || params["realm"] != "registry" {
+		t.Fatalf("bad: %s %#v", scheme, params)
+	}
+}
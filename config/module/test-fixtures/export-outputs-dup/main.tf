@@ -0,0 +1,9 @@
+module "foo" {
+    source = "./foo"
+    export_outputs = true
+}
+
+module "bar" {
+    source = "./bar"
+    export_outputs = true
+}
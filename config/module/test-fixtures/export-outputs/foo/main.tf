@@ -0,0 +1,8 @@
+output "address" {
+    value = "foo"
+}
+
+output "password" {
+    value = "secret"
+    sensitive = true
+}
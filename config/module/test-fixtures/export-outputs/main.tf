@@ -0,0 +1,8 @@
+module "foo" {
+    source = "./foo"
+    export_outputs = true
+}
+
+output "address" {
+    value = "declared"
+}
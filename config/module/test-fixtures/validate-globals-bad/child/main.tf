@@ -0,0 +1,7 @@
+terraform {
+    globals = ["region", "environment"]
+}
+
+resource "aws_instance" "foo" {
+    region = "${global.region}"
+}
@@ -0,0 +1,5 @@
+variable "region" {}
+
+module "child" {
+    source = "./child"
+}
@@ -0,0 +1,25 @@
+variable "zones" {
+    type = "list"
+}
+
+variable "tags" {
+    type = "map"
+}
+
+variable "name" {}
+
+variable "size" {}
+
+variable "labels" {
+    type = "map"
+}
+
+variable "subnets" {
+    type = "list"
+}
+
+variable "prefix" {}
+
+variable "azs" {
+    type = "list"
+}
@@ -0,0 +1,22 @@
+variable "names" {
+    type    = "list"
+    default = []
+}
+
+variable "region" {
+    default = "us-east-1"
+}
+
+module "child" {
+    source = "./child"
+
+    zones = "us-east-1a"
+    tags  = ["web"]
+    name  = "${var.names}"
+
+    size    = 3
+    labels  = { role = "web" }
+    subnets = "${split(",", var.region)}"
+    prefix  = "${var.region}-web"
+    azs     = "${var.names}"
+}
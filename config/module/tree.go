@@ -25,6 +25,7 @@ type Tree struct {
 	config   *config.Config
 	children map[string]*Tree
 	path     []string
+	env      string
 	lock     sync.RWMutex
 }
 
@@ -51,12 +52,21 @@ func NewEmptyTree() *Tree {
 // the directory and gives it a specific name. Use a blank name "" to specify
 // the root module.
 func NewTreeModule(name, dir string) (*Tree, error) {
-	c, err := config.LoadDir(dir)
+	return NewTreeModuleEnv(name, dir, "")
+}
+
+// NewTreeModuleEnv is like NewTreeModule except it also merges in the
+// override files for the given environment. The children are loaded for
+// the same environment.
+func NewTreeModuleEnv(name, dir, env string) (*Tree, error) {
+	c, err := config.LoadDirEnv(dir, env)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewTree(name, c), nil
+	t := NewTree(name, c)
+	t.env = env
+	return t, nil
 }
 
 // Config returns the configuration for this module.
@@ -209,7 +219,7 @@ func (t *Tree) Load(s getter.Storage, mode GetMode) error {
 		}
 
 		// Load the configurations.Dir(source)
-		children[m.Name], err = NewTreeModule(m.Name, dir)
+		children[m.Name], err = NewTreeModuleEnv(m.Name, dir, t.env)
 		if err != nil {
 			return fmt.Errorf(
 				"module %s: %s", m.Name, err)
@@ -229,6 +239,66 @@ func (t *Tree) Load(s getter.Storage, mode GetMode) error {
 	// Set our tree up
 	t.children = children
 
+	return t.exportOutputs()
+}
+
+// exportOutputs adds the outputs of the children whose module blocks set
+// export_outputs to the configuration, as if they were declared in it with
+// the same names. Outputs that are declared take precedence. The outputs
+// exported by an earlier load are replaced.
+func (t *Tree) exportOutputs() error {
+	outputs := make([]*config.Output, 0, len(t.config.Outputs))
+	declared := make(map[string]bool)
+	for _, o := range t.config.Outputs {
+		if o.ExportedFrom == "" {
+			outputs = append(outputs, o)
+			declared[o.Name] = true
+		}
+	}
+
+	exported := make(map[string]string)
+	for _, m := range t.config.Modules {
+		if !m.ExportOutputs {
+			continue
+		}
+
+		for _, o := range t.children[m.Name].config.Outputs {
+			if declared[o.Name] {
+				continue
+			}
+			if other, ok := exported[o.Name]; ok {
+				return fmt.Errorf(
+					"module %s: output %s is also exported by module %s",
+					m.Name, o.Name, other)
+			}
+			exported[o.Name] = m.Name
+
+			raw := map[string]interface{}{
+				"value": fmt.Sprintf("${module.%s.%s}", m.Name, o.Name),
+			}
+			if o.RawConfig != nil {
+				for _, k := range []string{"description", "sensitive"} {
+					if v, ok := o.RawConfig.Raw[k]; ok {
+						raw[k] = v
+					}
+				}
+			}
+			rc, err := config.NewRawConfig(raw)
+			if err != nil {
+				return fmt.Errorf("module %s: output %s: %s", m.Name, o.Name, err)
+			}
+
+			outputs = append(outputs, &config.Output{
+				Name:         o.Name,
+				Description:  o.Description,
+				Sensitive:    o.Sensitive,
+				RawConfig:    rc,
+				ExportedFrom: m.Name,
+			})
+		}
+	}
+
+	t.config.Outputs = outputs
 	return nil
 }
 
@@ -299,6 +369,10 @@ func (t *Tree) Validate() error {
 		if err := t.validateProviderAlias(); err != nil {
 			newErr.Add(err)
 		}
+
+		if err := t.validateGlobals(); err != nil {
+			newErr.Add(err)
+		}
 	}
 
 	// Get the child trees
@@ -360,6 +434,12 @@ func (t *Tree) Validate() error {
 				"module %s: required variable %q not set",
 				m.Name, k))
 		}
+
+		// Check the types of the arguments that are known now, so that
+		// they're reported where they're set, before planning.
+		for _, err := range t.validateModuleArgTypes(m, tree) {
+			newErr.Add(err)
+		}
 	}
 
 	// Go over all the variables used and make sure that any module
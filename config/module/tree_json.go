@@ -0,0 +1,47 @@
+package module
+
+import (
+	"encoding/json"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// UnmarshalJSON decodes a tree that was encoded with MarshalJSON.
+func (t *Tree) UnmarshalJSON(bs []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var data treeJSON
+	if err := json.Unmarshal(bs, &data); err != nil {
+		return err
+	}
+
+	// Set the fields
+	t.name = data.Name
+	t.config = data.Config
+	t.children = data.Children
+	t.path = data.Path
+
+	return nil
+}
+
+// MarshalJSON encodes the tree as JSON, including the configuration of
+// each module, in the same way as GobEncode.
+func (t *Tree) MarshalJSON() ([]byte, error) {
+	data := &treeJSON{
+		Config:   t.config,
+		Children: t.children,
+		Name:     t.name,
+		Path:     t.path,
+	}
+
+	return json.Marshal(data)
+}
+
+// treeJSON is the JSON format of a tree.
+type treeJSON struct {
+	Config   *config.Config   `json:"config"`
+	Children map[string]*Tree `json:"children"`
+	Name     string           `json:"name"`
+	Path     []string         `json:"path"`
+}
@@ -0,0 +1,37 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTreeEncodeDecodeJSON(t *testing.T) {
+	storage := testStorage(t)
+	tree := NewTree("", testConfig(t, "basic"))
+
+	// This should get things
+	if err := tree.Load(storage, GetModeGet); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Encode it.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(tree); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dec := json.NewDecoder(&buf)
+	var actual Tree
+	if err := dec.Decode(&actual); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actualStr := strings.TrimSpace(actual.String())
+	expectedStr := strings.TrimSpace(tree.String())
+	if actualStr != expectedStr {
+		t.Fatalf("\n%s\n\nexpected:\n\n%s", actualStr, expectedStr)
+	}
+}
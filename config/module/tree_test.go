@@ -3,6 +3,7 @@ package module
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -99,6 +100,30 @@ func TestTreeLoad(t *testing.T) {
 	}
 }
 
+func TestTreeLoad_env(t *testing.T) {
+	cases := map[string]string{
+		"":     "foo",
+		"prod": "prod",
+	}
+
+	for env, expected := range cases {
+		tree, err := NewTreeModuleEnv("", filepath.Join(fixtureDir, "basic-env"), env)
+		if err != nil {
+			t.Fatalf("%q: err: %s", env, err)
+		}
+
+		if err := tree.Load(testStorage(t), GetModeGet); err != nil {
+			t.Fatalf("%q: err: %s", env, err)
+		}
+
+		// The child's overrides are loaded for the same environment
+		c := tree.Child([]string{"foo"}).Config()
+		if actual := c.Resources[0].RawConfig.Raw["ami"]; actual != expected {
+			t.Fatalf("%q: bad: %#v", env, actual)
+		}
+	}
+}
+
 func TestTreeLoad_duplicate(t *testing.T) {
 	storage := testStorage(t)
 	tree := NewTree("", testConfig(t, "dup"))
@@ -208,6 +233,58 @@ func TestTreeLoad_parentRef(t *testing.T) {
 	}
 }
 
+func TestTreeLoad_exportOutputs(t *testing.T) {
+	storage := testStorage(t)
+	tree := NewTree("", testConfig(t, "export-outputs"))
+
+	if err := tree.Load(storage, GetModeGet); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Loading again must not export the outputs twice
+	if err := tree.Load(storage, GetModeNone); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	outputs := tree.Config().Outputs
+	if len(outputs) != 2 {
+		t.Fatalf("bad: %#v", outputs)
+	}
+
+	// The declared output takes precedence
+	if o := outputs[0]; o.Name != "address" || o.ExportedFrom != "" {
+		t.Fatalf("bad: %#v", o)
+	}
+
+	o := outputs[1]
+	if o.Name != "password" || o.ExportedFrom != "foo" {
+		t.Fatalf("bad: %#v", o)
+	}
+	if v := o.RawConfig.Raw["value"]; v != "${module.foo.password}" {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	if err := tree.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !o.Sensitive {
+		t.Fatal("exported output should be sensitive")
+	}
+}
+
+func TestTreeLoad_exportOutputsDuplicate(t *testing.T) {
+	storage := testStorage(t)
+	tree := NewTree("", testConfig(t, "export-outputs-dup"))
+
+	err := tree.Load(storage, GetModeGet)
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(err.Error(), "also exported by module") {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
 func TestTreeLoad_subdir(t *testing.T) {
 	storage := testStorage(t)
 	tree := NewTree("", testConfig(t, "basic-subdir"))
@@ -300,6 +377,24 @@ func TestTreeValidate_table(t *testing.T) {
 			"validate-module-root-grandchild",
 			"",
 		},
+
+		{
+			"globals in child",
+			"validate-globals-good",
+			"",
+		},
+
+		{
+			"undeclared global in child",
+			"validate-globals-bad",
+			`global "environment" is not a variable of the root module`,
+		},
+
+		{
+			"global not listed in child",
+			"validate-globals-not-listed",
+			"must be listed in 'globals'",
+		},
 	}
 
 	for i, tc := range cases {
@@ -424,6 +519,41 @@ func TestTreeValidate_requiredChildVar(t *testing.T) {
 	}
 }
 
+func TestTreeValidate_varTypes(t *testing.T) {
+	tree := NewTree("", testConfig(t, "validate-var-types"))
+
+	if err := tree.Load(testStorage(t), GetModeGet); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err := tree.Validate()
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	// Only the arguments with types that are known to be wrong are
+	// reported, where they are set.
+	errMsg := err.Error()
+	expected := []string{
+		"module child: variable zones should be type list, got string (set at ",
+		"main.tf:13)",
+		"module child: variable tags should be type map, got list (set at ",
+		"main.tf:14)",
+		"module child: variable name should be type string, got list (set at ",
+		"main.tf:15)",
+	}
+	for _, v := range expected {
+		if !strings.Contains(errMsg, v) {
+			t.Fatalf("error should contain %q: %s", v, errMsg)
+		}
+	}
+	for _, v := range []string{"size", "labels", "subnets", "prefix", "azs"} {
+		if strings.Contains(errMsg, "variable "+v+" ") {
+			t.Fatalf("unexpected error for %s: %s", v, errMsg)
+		}
+	}
+}
+
 func TestTreeValidate_unknownModule(t *testing.T) {
 	tree := NewTree("", testConfig(t, "validate-module-unknown"))
 
@@ -0,0 +1,52 @@
+package module
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// validateGlobals validates that every global that a module opts in to
+// with the "globals" setting of its terraform block is a variable declared
+// by the root module.
+func (t *Tree) validateGlobals() error {
+	// If we're not the root, don't perform this validation. We must be the
+	// root since we require full tree visibilty.
+	if len(t.path) != 0 {
+		return nil
+	}
+
+	vars := make(map[string]struct{})
+	for _, v := range t.config.Variables {
+		vars[v.Name] = struct{}{}
+	}
+
+	return t.validateGlobalsFor(vars)
+}
+
+func (t *Tree) validateGlobalsFor(vars map[string]struct{}) error {
+	var err error
+	if tf := t.config.Terraform; tf != nil {
+		for _, name := range tf.Globals {
+			if _, ok := vars[name]; !ok {
+				path := "root"
+				if len(t.path) > 0 {
+					path = strings.Join(t.path, ".")
+				}
+
+				err = multierror.Append(err, fmt.Errorf(
+					"module %s: global %q is not a variable of the root module",
+					path, name))
+			}
+		}
+	}
+
+	for _, c := range t.Children() {
+		if cerr := c.validateGlobalsFor(vars); cerr != nil {
+			err = multierror.Append(err, cerr)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,131 @@
+package module
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/hil"
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform/config"
+)
+
+// validateModuleArgTypes validates that the arguments of the module call m
+// in t have the types of the variables they set in the child module, as
+// far as they are known without interpolating: literal values, strings
+// with interpolations in them, and direct references to the variables of
+// t. Otherwise, the type is only checked when planning.
+func (t *Tree) validateModuleArgTypes(m *config.Module, child *Tree) []error {
+	vars := make(map[string]*config.Variable)
+	for _, v := range t.config.Variables {
+		vars[v.Name] = v
+	}
+
+	var errs []error
+	for _, v := range child.config.Variables {
+		raw, ok := m.RawConfig.Raw[v.Name]
+		if !ok {
+			continue
+		}
+
+		declared := v.Type()
+		if declared == config.VariableTypeUnknown {
+			continue
+		}
+
+		got, ok := moduleArgType(raw, vars)
+		if !ok || got == declared.Printable() {
+			continue
+		}
+
+		// A map in HCL is read as a list with a single map in it, which
+		// is turned back into a map for a map variable.
+		if declared == config.VariableTypeMap && isSingleMapList(raw) {
+			continue
+		}
+
+		msg := fmt.Sprintf(
+			"module %s: variable %s should be type %s, got %s",
+			m.Name, v.Name, declared.Printable(), got)
+		if pos := m.ArgumentPos(v.Name); pos != "" {
+			msg = fmt.Sprintf("%s (set at %s)", msg, pos)
+		}
+		errs = append(errs, fmt.Errorf("%s", msg))
+	}
+
+	return errs
+}
+
+// moduleArgType returns the name of the type of the value of a module
+// argument, and whether it's known without interpolating. Numbers and
+// booleans are converted to strings, so they're strings. vars are the
+// variables of the module with the call, to follow references to them.
+func moduleArgType(raw interface{}, vars map[string]*config.Variable) (string, bool) {
+	switch reflect.ValueOf(raw).Kind() {
+	case reflect.Slice, reflect.Array:
+		return "list", true
+	case reflect.Map:
+		return "map", true
+	case reflect.String:
+	case reflect.Invalid:
+		return "", false
+	default:
+		return "string", true
+	}
+
+	s := reflect.ValueOf(raw).String()
+	if !strings.Contains(s, "${") {
+		return "string", true
+	}
+
+	node, err := hil.Parse(s)
+	if err != nil {
+		// Parse errors are reported by the validation of the config
+		return "", false
+	}
+	if out, ok := node.(*ast.Output); ok {
+		if len(out.Exprs) != 1 {
+			// Interpolations within a string result in a string
+			return "string", true
+		}
+		node = out.Exprs[0]
+	}
+
+	switch n := node.(type) {
+	case *ast.LiteralNode:
+		return "string", true
+	case *ast.VariableAccess:
+		iv, err := config.NewInterpolatedVariable(n.Name)
+		if err != nil {
+			return "", false
+		}
+		uv, ok := iv.(*config.UserVariable)
+		if !ok || uv.Elem != "" {
+			return "", false
+		}
+		v, ok := vars[uv.Name]
+		if !ok || v.Type() == config.VariableTypeUnknown {
+			return "", false
+		}
+
+		return v.Type().Printable(), true
+	}
+
+	return "", false
+}
+
+// isSingleMapList returns whether raw is a list with a single map in it.
+func isSingleMapList(raw interface{}) bool {
+	switch v := raw.(type) {
+	case []map[string]interface{}:
+		return len(v) == 1
+	case []interface{}:
+		if len(v) != 1 {
+			return false
+		}
+		_, ok := v[0].(map[string]interface{})
+		return ok
+	}
+
+	return false
+}
@@ -3,10 +3,12 @@ package config
 import (
 	"bytes"
 	"encoding/gob"
+	"encoding/json"
 	"sync"
 
 	"github.com/hashicorp/hil"
 	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform/helper/typedjson"
 	"github.com/mitchellh/copystructure"
 	"github.com/mitchellh/reflectwalk"
 )
@@ -188,6 +190,76 @@ func (r *RawConfig) Merge(other *RawConfig) *RawConfig {
 	return result
 }
 
+// MergeNested is like Merge, but the maps and single nested blocks that
+// both configurations set are merged key by key, with other taking
+// precedence, rather than other replacing them as a whole. Lists, and
+// blocks that are repeated, are still replaced.
+func (r *RawConfig) MergeNested(other *RawConfig) *RawConfig {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	raw, err := copystructure.Copy(r.Raw)
+	if err != nil {
+		panic(err)
+	}
+	result, err := NewRawConfig(mergeNestedValue(raw, other.Raw).(map[string]interface{}))
+	if err != nil {
+		panic(err)
+	}
+
+	config, err := copystructure.Copy(r.config)
+	if err != nil {
+		panic(err)
+	}
+	result.config = mergeNestedValue(config, other.config).(map[string]interface{})
+
+	// Build the unknown keys
+	if len(r.unknownKeys) > 0 || len(other.unknownKeys) > 0 {
+		unknownKeys := make(map[string]struct{})
+		for _, k := range r.unknownKeys {
+			unknownKeys[k] = struct{}{}
+		}
+		for _, k := range other.unknownKeys {
+			unknownKeys[k] = struct{}{}
+		}
+
+		result.unknownKeys = make([]string, 0, len(unknownKeys))
+		for k, _ := range unknownKeys {
+			result.unknownKeys = append(result.unknownKeys, k)
+		}
+	}
+
+	return result
+}
+
+// mergeNestedValue merges b into a for MergeNested. The maps of a may be
+// modified.
+func mergeNestedValue(a, b interface{}) interface{} {
+	switch bv := b.(type) {
+	case map[string]interface{}:
+		av, ok := a.(map[string]interface{})
+		if !ok || av == nil {
+			return b
+		}
+		for k, v := range bv {
+			if existing, ok := av[k]; ok {
+				v = mergeNestedValue(existing, v)
+			}
+			av[k] = v
+		}
+		return av
+	case []map[string]interface{}:
+		av, ok := a.([]map[string]interface{})
+		if !ok || len(av) != 1 || len(bv) != 1 {
+			return b
+		}
+		merged := mergeNestedValue(av[0], bv[0]).(map[string]interface{})
+		return []map[string]interface{}{merged}
+	default:
+		return b
+	}
+}
+
 func (r *RawConfig) init() error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
@@ -254,6 +326,9 @@ func (r *RawConfig) merge(r2 *RawConfig) *RawConfig {
 	}
 
 	raw := rawRaw.(map[string]interface{})
+	if raw == nil {
+		raw = make(map[string]interface{})
+	}
 	if r2 != nil {
 		for k, v := range r2.Raw {
 			raw[k] = v
@@ -316,12 +391,43 @@ type gobRawConfig struct {
 	Raw map[string]interface{}
 }
 
+// See MarshalJSON
+func (r *RawConfig) UnmarshalJSON(b []byte) error {
+	var data jsonRawConfig
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	r.Key = data.Key
+	r.Raw = data.Raw
+
+	return r.init()
+}
+
+// MarshalJSON encodes the raw configuration as JSON. As with GobEncode,
+// only the raw configuration is included, with the types of its values.
+func (r *RawConfig) MarshalJSON() ([]byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return json.Marshal(&jsonRawConfig{
+		Key: r.Key,
+		Raw: r.Raw,
+	})
+}
+
+type jsonRawConfig struct {
+	Key string        `json:"key,omitempty"`
+	Raw typedjson.Map `json:"raw"`
+}
+
 // langEvalConfig returns the evaluation configuration we use to execute.
 func langEvalConfig(vs map[string]ast.Variable) *hil.EvalConfig {
 	funcMap := make(map[string]ast.Function)
 	for k, v := range Funcs() {
 		funcMap[k] = v
 	}
+	funcMap["file"] = interpolationFuncFile(vs)
 	funcMap["lookup"] = interpolationFuncLookup(vs)
 	funcMap["keys"] = interpolationFuncKeys(vs)
 	funcMap["values"] = interpolationFuncValues(vs)
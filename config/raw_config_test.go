@@ -228,6 +228,102 @@ func TestRawConfig_merge(t *testing.T) {
 	}
 }
 
+func TestRawConfig_mergeNested(t *testing.T) {
+	rc1, err := NewRawConfig(map[string]interface{}{
+		"region": "${var.region}",
+		"endpoints": []map[string]interface{}{
+			{"s3": "s3.child"},
+		},
+		"tags": map[string]interface{}{
+			"a": "child",
+			"b": "child",
+		},
+		"ids": []interface{}{"child"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	{
+		vars := map[string]ast.Variable{
+			"var.region": ast.Variable{
+				Value: "child",
+				Type:  ast.TypeString,
+			},
+		}
+		if err := rc1.Interpolate(vars); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	rc2, err := NewRawConfig(map[string]interface{}{
+		"endpoints": []map[string]interface{}{
+			{"ec2": "${var.ec2}"},
+		},
+		"tags": map[string]interface{}{
+			"b": "parent",
+		},
+		"ids": []interface{}{"parent"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	{
+		vars := map[string]ast.Variable{
+			"var.ec2": ast.Variable{
+				Value: UnknownVariableValue,
+				Type:  ast.TypeUnknown,
+			},
+		}
+		if err := rc2.Interpolate(vars); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	rc3 := rc1.MergeNested(rc2)
+
+	rawExpected := map[string]interface{}{
+		"region": "${var.region}",
+		"endpoints": []map[string]interface{}{
+			{"s3": "s3.child", "ec2": "${var.ec2}"},
+		},
+		"tags": map[string]interface{}{
+			"a": "child",
+			"b": "parent",
+		},
+		"ids": []interface{}{"parent"},
+	}
+	if !reflect.DeepEqual(rc3.Raw, rawExpected) {
+		t.Fatalf("bad: %#v", rc3.Raw)
+	}
+
+	expected := map[string]interface{}{
+		"region": "child",
+		"endpoints": []map[string]interface{}{
+			{"s3": "s3.child", "ec2": UnknownVariableValue},
+		},
+		"tags": map[string]interface{}{
+			"a": "child",
+			"b": "parent",
+		},
+		"ids": []interface{}{"parent"},
+	}
+	if actual := rc3.Config(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	expectedKeys := []string{"endpoints.0.ec2"}
+	if !reflect.DeepEqual(rc3.UnknownKeys(), expectedKeys) {
+		t.Fatalf("bad: %#v", rc3.UnknownKeys())
+	}
+
+	// The configurations that were merged are unchanged
+	if v := rc1.Raw["tags"].(map[string]interface{})["b"]; v != "child" {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
 func TestRawConfig_syntax(t *testing.T) {
 	raw := map[string]interface{}{
 		"foo": "${var",
@@ -0,0 +1,7 @@
+resource "aws_instance" "web" {
+    lifecycle {
+        adopt_existing = true
+    }
+}
+
+resource "aws_instance" "bar" {}
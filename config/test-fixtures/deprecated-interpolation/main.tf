@@ -0,0 +1,12 @@
+variable "a" {
+    type = "list"
+}
+
+resource "aws_instance" "foo" {
+    security_groups = ["${concat("${var.a}", list("b"))}"]
+    ami             = "${var.a[0]}"
+}
+
+output "foo" {
+    value = "${element("${var.a}", 0)}"
+}
@@ -0,0 +1,13 @@
+resource "aws_instance" "web" {
+    lifecycle {
+        destroy_priority = 10
+    }
+}
+
+resource "aws_instance" "bar" {
+    lifecycle {
+        destroy_priority = -1
+    }
+}
+
+resource "aws_instance" "baz" {}
@@ -0,0 +1,3 @@
+connection {
+    user = "ubuntu"
+}
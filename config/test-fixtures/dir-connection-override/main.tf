@@ -0,0 +1,5 @@
+resource "aws_instance" "web" {
+    provisioner "remote-exec" {
+        inline = ["echo web"]
+    }
+}
@@ -0,0 +1,4 @@
+connection {
+    user         = "ubuntu"
+    bastion_host = "bastion.example.com"
+}
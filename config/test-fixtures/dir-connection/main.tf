@@ -0,0 +1,23 @@
+resource "aws_instance" "web" {
+    provisioner "remote-exec" {
+        inline = ["echo web"]
+    }
+}
+
+resource "aws_instance" "db" {
+    connection {
+        user = "admin"
+    }
+
+    provisioner "remote-exec" {
+        inline = ["echo db"]
+    }
+
+    provisioner "remote-exec" {
+        inline = ["echo direct"]
+
+        connection {
+            bastion_host = ""
+        }
+    }
+}
@@ -0,0 +1,4 @@
+resource "aws_instance" "web" {
+    ami = "foo"
+    instance_type = "t2.micro"
+}
@@ -0,0 +1,4 @@
+resource "aws_instance" "web" {
+    ami = "prod"
+    instance_type = "m4.large"
+}
@@ -1,3 +1,4 @@
 variable "foo" {
     default = "baz"
+    override = true
 }
@@ -0,0 +1,12 @@
+variable "regions" {
+    default = ["east", "west"]
+}
+
+resource "aws_instance" "foo" {
+    count = 2
+    provider = "aws.${element(var.regions, count.index)}"
+}
+
+resource "aws_instance" "bar" {
+    provider = "aws.west"
+}
@@ -0,0 +1,9 @@
+provider "aws" {
+    alias = "west"
+}
+
+provider "aws" {
+    alias = "east"
+}
+
+provider "google" {}
@@ -0,0 +1,10 @@
+provider "aws" {
+    region = "us-east-1"
+}
+
+resource "aws_instance" "foo" {
+    provider_config {
+        region     = "eu-west-1"
+        access_key = "foo"
+    }
+}
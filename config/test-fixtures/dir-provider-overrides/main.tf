@@ -0,0 +1,30 @@
+provider "aws" {
+    version    = "~> 1.0"
+    region     = "us-east-1"
+    access_key = "foo"
+}
+
+provider "aws" {
+    alias  = "west"
+    region = "us-west-2"
+}
+
+resource "aws_instance" "foo" {
+    provider_config {
+        region = "eu-west-1"
+    }
+}
+
+resource "aws_instance" "bar" {
+    provider = "aws.west"
+
+    provider_config {
+        profile = "other"
+    }
+}
+
+data "aws_ami" "baz" {
+    provider_config {
+        region = "eu-west-1"
+    }
+}
@@ -0,0 +1,9 @@
+terraform {
+    backend "s3" {
+        bucket = "foo"
+    }
+}
+
+resource "aws_instance" "web" {
+    ami = "${var.foo var.bar}"
+}
@@ -0,0 +1,12 @@
+resource "aws_instance" "list" {
+    for_each = ["a", "b"]
+    ami      = "ami-${each.value}"
+}
+
+resource "aws_instance" "map" {
+    for_each = {
+        foo = "bar"
+    }
+
+    tags = "${each.key}"
+}
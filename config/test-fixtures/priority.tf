@@ -0,0 +1,13 @@
+resource "aws_db_instance" "db" {
+    lifecycle {
+        priority = 10
+    }
+}
+
+resource "aws_instance" "bar" {
+    lifecycle {
+        priority = -1
+    }
+}
+
+resource "aws_instance" "baz" {}
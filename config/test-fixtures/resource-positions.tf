@@ -0,0 +1,11 @@
+resource "aws_security_group" "web" {
+    name = "web"
+
+    ingress {
+        from = 80
+    }
+
+    ingress {
+        from = 443
+    }
+}
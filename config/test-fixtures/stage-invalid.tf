@@ -0,0 +1,3 @@
+resource "aws_route53_record" "www" {
+    stage = 0
+}
@@ -0,0 +1,9 @@
+resource "aws_route53_record" "www" {
+    stage = 1
+}
+
+resource "aws_acm_certificate" "www" {
+    stage = 2
+}
+
+resource "aws_instance" "web" {}
@@ -0,0 +1,5 @@
+terraform {
+  backend "foo" {
+    path = "${path.root}/terraform.tfstate"
+  }
+}
@@ -0,0 +1,7 @@
+terraform {
+  backend "foo" {
+    path   = "${path.root}/${terraform.workspace}.tfstate"
+    bucket = "state-${terraform.env}"
+    region = "us-east-1"
+  }
+}
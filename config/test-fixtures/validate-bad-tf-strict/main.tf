@@ -0,0 +1,5 @@
+terraform {
+    strict {
+        warnings = ["provider-unconstrained", "nope"]
+    }
+}
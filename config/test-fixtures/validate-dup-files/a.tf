@@ -0,0 +1,9 @@
+variable "foo" {}
+
+output "foo" {
+    value = "a"
+}
+
+provider "aws" {
+    region = "us-east-1"
+}
@@ -0,0 +1,11 @@
+provider "aws" {
+    region = "us-west-2"
+}
+
+variable "foo" {
+    default = "b"
+}
+
+output "foo" {
+    value = "b"
+}
@@ -0,0 +1,12 @@
+resource "aws_instance" "no_for_each" {
+    ami = "${each.key}"
+}
+
+output "no_each_in_output" {
+    value = "${each.value}"
+}
+
+module "no_each_in_module" {
+    source = "./child"
+    foo    = "${each.key}"
+}
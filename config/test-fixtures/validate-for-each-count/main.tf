@@ -0,0 +1,4 @@
+resource "aws_instance" "web" {
+    count    = 2
+    for_each = ["a", "b"]
+}
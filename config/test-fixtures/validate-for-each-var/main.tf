@@ -0,0 +1,8 @@
+variable "names" {
+    type = "map"
+}
+
+resource "aws_instance" "web" {
+    for_each = "${var.names}"
+    ami      = "${each.value}"
+}
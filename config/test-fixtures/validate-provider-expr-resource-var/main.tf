@@ -0,0 +1,9 @@
+provider "aws" {
+    alias = "west"
+}
+
+resource "aws_instance" "foo" {}
+
+resource "aws_instance" "bar" {
+    provider = "aws.${aws_instance.foo.id}"
+}
@@ -0,0 +1,15 @@
+provider "aws" {
+    alias = "west"
+}
+
+resource "aws_instance" "foo" {
+    provider = "aws.${var.region}"
+
+    provider_config {
+        region = "us-west-2"
+    }
+}
+
+variable "region" {
+    default = "west"
+}
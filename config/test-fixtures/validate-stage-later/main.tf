@@ -0,0 +1,8 @@
+resource "aws_route53_record" "www" {
+    records = ["${aws_acm_certificate.www.id}"]
+    stage   = 1
+}
+
+resource "aws_acm_certificate" "www" {
+    stage = 2
+}
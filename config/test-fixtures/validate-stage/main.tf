@@ -0,0 +1,12 @@
+resource "aws_route53_record" "www" {
+    stage = 1
+}
+
+resource "aws_acm_certificate" "www" {
+    domain_name = "${aws_route53_record.www.fqdn}"
+    stage       = 2
+}
+
+resource "aws_instance" "web" {
+    depends_on = ["aws_acm_certificate.www"]
+}
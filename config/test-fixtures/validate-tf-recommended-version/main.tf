@@ -0,0 +1,4 @@
+terraform {
+    deprecated = "Use the network-v2 module instead."
+    recommended_version = ">= 0.10.0"
+}
@@ -0,0 +1,5 @@
+terraform {
+    strict {
+        warnings = ["deprecated-interpolation", "provider-unconstrained"]
+    }
+}
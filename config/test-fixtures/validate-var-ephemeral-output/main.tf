@@ -0,0 +1,7 @@
+variable "password" {
+    ephemeral = true
+}
+
+output "password" {
+    value = "${var.password}"
+}
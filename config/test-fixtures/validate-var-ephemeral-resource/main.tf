@@ -0,0 +1,7 @@
+variable "password" {
+    ephemeral = true
+}
+
+resource "aws_db_instance" "db" {
+    password = "${var.password}"
+}
@@ -0,0 +1,13 @@
+variable "password" {
+    ephemeral = true
+}
+
+provider "aws" {
+    password = "${var.password}"
+}
+
+resource "aws_instance" "web" {
+    provisioner "remote-exec" {
+        inline = ["bootstrap ${var.password}"]
+    }
+}
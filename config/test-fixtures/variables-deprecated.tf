@@ -0,0 +1,4 @@
+variable "old_name" {
+    default = ""
+    deprecated = "Use new_name instead."
+}
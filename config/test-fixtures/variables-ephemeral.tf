@@ -0,0 +1,7 @@
+variable "password" {
+    ephemeral = true
+}
+variable "token" {
+    default = ""
+    ephemeral = true
+}
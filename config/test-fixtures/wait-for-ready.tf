@@ -0,0 +1,7 @@
+resource "aws_instance" "web" {
+    lifecycle {
+        wait_for_ready = true
+    }
+}
+
+resource "aws_instance" "bar" {}
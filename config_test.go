@@ -1,10 +1,17 @@
 package main
 
 import (
+	"errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/hashicorp/terraform/command"
+	"github.com/hashicorp/terraform/helper/keychain"
+	"github.com/hashicorp/terraform/helper/vault"
 )
 
 // This is the directory where our test fixtures are.
@@ -52,6 +59,21 @@ func TestLoadConfig_env(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_pluginDirs(t *testing.T) {
+	defer os.Unsetenv("TFTEST")
+	os.Setenv("TFTEST", "hello")
+
+	c, err := LoadConfig(filepath.Join(fixtureDir, "config-plugin-dirs"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"hello/plugins", "/opt/terraform/plugins"}
+	if !reflect.DeepEqual(c.PluginDirs, expected) {
+		t.Fatalf("bad: %#v", c.PluginDirs)
+	}
+}
+
 func TestConfig_Merge(t *testing.T) {
 	c1 := &Config{
 		Providers: map[string]string{
@@ -129,3 +151,318 @@ func TestConfig_Merge_disableCheckpointSignature(t *testing.T) {
 		t.Fatalf("bad: %#v", actual)
 	}
 }
+
+func TestConfig_Merge_pluginDirs(t *testing.T) {
+	c1 := &Config{
+		PluginDirs: []string{"/a", "/b"},
+	}
+
+	// An unset list doesn't replace the earlier one
+	actual := c1.Merge(&Config{})
+	if !reflect.DeepEqual(actual.PluginDirs, c1.PluginDirs) {
+		t.Fatalf("bad: %#v", actual.PluginDirs)
+	}
+
+	c2 := &Config{
+		PluginDirs: []string{"/c"},
+	}
+	actual = c1.Merge(c2)
+	if !reflect.DeepEqual(actual.PluginDirs, c2.PluginDirs) {
+		t.Fatalf("bad: %#v", actual.PluginDirs)
+	}
+}
+
+func TestLoadConfig_keychainEnv(t *testing.T) {
+	c, err := LoadConfig(filepath.Join(fixtureDir, "config-keychain"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &Config{
+		KeychainEnv: map[string]string{
+			"AWS_ACCESS_KEY_ID":     "aws_access_key_id",
+			"AWS_SECRET_ACCESS_KEY": "aws_secret_access_key",
+		},
+	}
+
+	if !reflect.DeepEqual(c, expected) {
+		t.Fatalf("bad: %#v", c)
+	}
+}
+
+func TestConfig_Merge_keychainEnv(t *testing.T) {
+	c1 := &Config{
+		KeychainEnv: map[string]string{
+			"FOO": "foo",
+			"BAR": "bar",
+		},
+	}
+
+	c2 := &Config{
+		KeychainEnv: map[string]string{
+			"BAR": "baz",
+		},
+	}
+
+	expected := &Config{
+		Providers:    map[string]string{},
+		Provisioners: map[string]string{},
+		KeychainEnv: map[string]string{
+			"FOO": "foo",
+			"BAR": "baz",
+		},
+	}
+
+	actual := c1.Merge(c2)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestConfig_SetKeychainEnv(t *testing.T) {
+	defer func(f func(string) (string, error)) { keychainGet = f }(keychainGet)
+	keychainGet = func(name string) (string, error) {
+		if name == "missing" {
+			return "", keychain.ErrNotFound
+		}
+
+		return "secret-" + name, nil
+	}
+
+	defer os.Unsetenv("TFTEST_KEYCHAIN")
+	defer os.Unsetenv("TFTEST_KEYCHAIN_SET")
+	os.Unsetenv("TFTEST_KEYCHAIN")
+	os.Setenv("TFTEST_KEYCHAIN_SET", "env")
+
+	c := &Config{
+		KeychainEnv: map[string]string{
+			"TFTEST_KEYCHAIN":     "foo",
+			"TFTEST_KEYCHAIN_SET": "bar",
+		},
+	}
+	if err := c.SetKeychainEnv(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if v := os.Getenv("TFTEST_KEYCHAIN"); v != "secret-foo" {
+		t.Fatalf("bad: %q", v)
+	}
+
+	// Variables that are already set aren't replaced
+	if v := os.Getenv("TFTEST_KEYCHAIN_SET"); v != "env" {
+		t.Fatalf("bad: %q", v)
+	}
+
+}
+
+func TestConfig_SetKeychainEnv_notFound(t *testing.T) {
+	defer func(f func(string) (string, error)) { keychainGet = f }(keychainGet)
+	keychainGet = func(name string) (string, error) {
+		if name == "missing" {
+			return "", keychain.ErrNotFound
+		}
+
+		return "secret-" + name, nil
+	}
+
+	defer os.Unsetenv("TFTEST_KEYCHAIN")
+	defer os.Unsetenv("TFTEST_KEYCHAIN_MISSING")
+	os.Unsetenv("TFTEST_KEYCHAIN")
+	os.Unsetenv("TFTEST_KEYCHAIN_MISSING")
+
+	// A missing secret leaves its variable unset without stopping the others
+	c := &Config{
+		KeychainEnv: map[string]string{
+			"TFTEST_KEYCHAIN":         "foo",
+			"TFTEST_KEYCHAIN_MISSING": "missing",
+		},
+	}
+	if err := c.SetKeychainEnv(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, ok := os.LookupEnv("TFTEST_KEYCHAIN_MISSING"); ok {
+		t.Fatal("TFTEST_KEYCHAIN_MISSING should not be set")
+	}
+	if v := os.Getenv("TFTEST_KEYCHAIN"); v != "secret-foo" {
+		t.Fatalf("bad: %q", v)
+	}
+}
+
+func TestLoadConfig_credentials(t *testing.T) {
+	defer os.Unsetenv("TFTEST_CREDENTIALS_TOKEN")
+	os.Setenv("TFTEST_CREDENTIALS_TOKEN", "bar")
+
+	c, err := LoadConfig(filepath.Join(fixtureDir, "config-credentials"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]string{
+		"app.example.com":      "foo",
+		"registry.example.com": "bar",
+	}
+	if actual := c.CredentialsTokens(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	expected = map[string]string{
+		"vault.example.com": "secret/terraform#token",
+	}
+	if actual := c.CredentialsVault(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestConfig_Merge_credentials(t *testing.T) {
+	c1 := &Config{
+		Credentials: map[string]map[string]interface{}{
+			"foo.example.com": {"token": "foo"},
+			"bar.example.com": {"token": "bar"},
+		},
+	}
+
+	c2 := &Config{
+		Credentials: map[string]map[string]interface{}{
+			"bar.example.com": {"token": "baz"},
+		},
+	}
+
+	expected := map[string]string{
+		"foo.example.com": "foo",
+		"bar.example.com": "baz",
+	}
+	if actual := c1.Merge(c2).CredentialsTokens(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestLoadConfig_vault(t *testing.T) {
+	defer os.Unsetenv("TFTEST_VAULT_SECRET_ID")
+	os.Setenv("TFTEST_VAULT_SECRET_ID", "s3cret")
+
+	c, err := LoadConfig(filepath.Join(fixtureDir, "config-vault"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &vault.Config{
+		Address:    "https://vault.example.com:8200",
+		AuthMethod: "approle",
+		RoleID:     "deploy",
+		SecretID:   "s3cret",
+		Env: map[string]string{
+			"AWS_ACCESS_KEY_ID":     "aws/creds/deploy#access_key",
+			"AWS_SECRET_ACCESS_KEY": "aws/creds/deploy#secret_key",
+		},
+		Provider: map[string]map[string]string{
+			"aws": {
+				"access_key": "aws/sts/deploy#access_key",
+				"secret_key": "aws/sts/deploy#secret_key",
+				"token":      "aws/sts/deploy#security_token",
+			},
+		},
+		Backend: map[string]string{
+			"access_key": "aws/creds/state#access_key",
+			"secret_key": "aws/creds/state#secret_key",
+		},
+	}
+	if !reflect.DeepEqual(c.Vault, expected) {
+		t.Fatalf("bad: %#v", c.Vault)
+	}
+}
+
+func TestConfig_Merge_vault(t *testing.T) {
+	c1 := &Config{Vault: &vault.Config{Address: "https://foo:8200"}}
+	c2 := &Config{Vault: &vault.Config{Address: "https://bar:8200"}}
+
+	if actual := c1.Merge(c2).Vault; actual != c2.Vault {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if actual := c1.Merge(new(Config)).Vault; actual != c1.Vault {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestLoadConfig_ui(t *testing.T) {
+	c, err := LoadConfig(filepath.Join(fixtureDir, "config-ui"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &command.UIOptions{
+		Theme:  "light",
+		Colors: map[string]string{"red": "1;31"},
+		ASCII:  true,
+		Width:  100,
+	}
+	if !reflect.DeepEqual(c.UI, expected) {
+		t.Fatalf("bad: %#v", c.UI)
+	}
+}
+
+func TestLoadConfig_uiInvalid(t *testing.T) {
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	cases := map[string]string{
+		`ui { theme = "solarized" }`:           `unknown theme "solarized"`,
+		`ui { colors { purple = "35" } }`:      `unknown color "purple"`,
+		`ui { colors { red = "bright red" } }`: `isn't an ANSI code`,
+	}
+	for config, expected := range cases {
+		path := filepath.Join(td, "config")
+		if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		_, err := LoadConfig(path)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("%s: expected %q, got: %v", config, expected, err)
+		}
+	}
+}
+
+func TestConfig_Merge_ui(t *testing.T) {
+	c1 := &Config{UI: &command.UIOptions{
+		Theme:  "light",
+		Colors: map[string]string{"red": "1;31", "green": "1;32"},
+	}}
+	c2 := &Config{UI: &command.UIOptions{
+		Width:  80,
+		Colors: map[string]string{"red": "31"},
+	}}
+
+	expected := &command.UIOptions{
+		Theme:  "light",
+		Width:  80,
+		Colors: map[string]string{"red": "31", "green": "1;32"},
+	}
+	if actual := c1.Merge(c2).UI; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if actual := c1.Merge(new(Config)).UI; actual != c1.UI {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestConfig_SetVaultEnv(t *testing.T) {
+	c := new(Config)
+	client, err := c.SetVaultEnv()
+	if err != nil || client != nil {
+		t.Fatalf("bad: %#v %s", client, err)
+	}
+
+	defer func(f func(*vault.Config) (*vault.Client, error)) { vaultNewClient = f }(vaultNewClient)
+	vaultNewClient = func(*vault.Config) (*vault.Client, error) {
+		return nil, errors.New("permission denied")
+	}
+
+	c.Vault = &vault.Config{Env: map[string]string{"FOO": "secret/foo#value"}}
+	if _, err := c.SetVaultEnv(); err == nil {
+		t.Fatal("should error")
+	}
+}
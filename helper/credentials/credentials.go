@@ -0,0 +1,258 @@
+// Package credentials stores the API tokens that Terraform uses to
+// authenticate with services by hostname, such as module registries and
+// the servers of remote backends.
+//
+// Tokens come from three places: credentials blocks in the CLI
+// configuration, which are read-only, Vault, which those blocks can refer
+// to, and the credentials file that "terraform login" saves tokens to. A
+// token is only ever sent over HTTPS, and only to the host it was obtained
+// for.
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform/helper/vault"
+)
+
+// FileName is the name of the file, in the CLI configuration directory,
+// that tokens are saved to.
+const FileName = "credentials.tfrc.json"
+
+// Default is the store that the CLI sets up from its configuration. It's
+// used by the parts of Terraform that can't be handed a store, such as
+// backends and module downloads.
+var Default = new(Store)
+
+// Token returns the token for host from the Default store, or "" if there
+// is none. Errors reading the store are logged, since they shouldn't stop
+// requests that may not need the token.
+func Token(host string) string {
+	token, err := Default.Token(host)
+	if err != nil {
+		log.Printf("[WARN] credentials: error reading the token for %s: %s", host, err)
+	}
+
+	return token
+}
+
+// Transport returns an http.RoundTripper that sends requests with base,
+// adding the token from the Default store to those that need it. See
+// Store.Transport.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return Default.Transport(base)
+}
+
+// Store holds tokens by hostname.
+type Store struct {
+	// Path is the JSON file that tokens are saved to. If it's empty,
+	// tokens can't be saved.
+	Path string
+
+	// Static are the tokens set in the CLI configuration, by hostname.
+	// They take precedence over the saved tokens.
+	Static map[string]string
+
+	// Vault are the references to tokens in Vault set in the CLI
+	// configuration, as "PATH#FIELD", by hostname. They're read with
+	// vault.Default whenever they're needed, so that tokens that Vault
+	// replaces are picked up, and take precedence over the saved tokens.
+	Vault map[string]string
+
+	lock  sync.Mutex
+	saved map[string]string
+}
+
+// file is the format of the credentials file.
+type file struct {
+	Credentials map[string]*fileEntry `json:"credentials"`
+}
+
+type fileEntry struct {
+	Token string `json:"token"`
+}
+
+// NormalizeHost returns the form of host that tokens are stored under:
+// lower case, and without the default HTTPS port.
+func NormalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ":443")
+}
+
+// Token returns the token for host, or "" if there is none.
+func (s *Store) Token(host string) (string, error) {
+	host = NormalizeHost(host)
+	for k, v := range s.Static {
+		if NormalizeHost(k) == host {
+			return v, nil
+		}
+	}
+	for k, ref := range s.Vault {
+		if NormalizeHost(k) != host {
+			continue
+		}
+		if vault.Default == nil {
+			return "", fmt.Errorf("the token for %s is in Vault, but Vault isn't configured", host)
+		}
+
+		return vault.Default.ReadRef(ref)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.load(); err != nil {
+		return "", err
+	}
+
+	return s.saved[host], nil
+}
+
+// IsStatic returns true if the token for host is set in the CLI
+// configuration, directly or in Vault, so that a saved token for it isn't
+// used.
+func (s *Store) IsStatic(host string) bool {
+	host = NormalizeHost(host)
+	for k := range s.Static {
+		if NormalizeHost(k) == host {
+			return true
+		}
+	}
+	for k := range s.Vault {
+		if NormalizeHost(k) == host {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetToken saves token as the token for host, replacing any token that
+// was saved for it before. An empty token removes the saved token.
+func (s *Store) SetToken(host, token string) error {
+	if s.Path == "" {
+		return fmt.Errorf("no credentials file to save the token for %s to", host)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	saved := make(map[string]string, len(s.saved)+1)
+	for k, v := range s.saved {
+		saved[k] = v
+	}
+	host = NormalizeHost(host)
+	if token == "" {
+		delete(saved, host)
+	} else {
+		saved[host] = token
+	}
+
+	f := &file{Credentials: make(map[string]*fileEntry, len(saved))}
+	for k, v := range saved {
+		f.Credentials[k] = &fileEntry{Token: v}
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// The file only holds secrets, so only the user can read it.
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(s.Path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("error saving credentials to %s: %s", s.Path, err)
+	}
+
+	s.saved = saved
+	return nil
+}
+
+// load reads the saved tokens, if they haven't been read yet. The lock
+// must be held.
+func (s *Store) load() error {
+	if s.saved != nil {
+		return nil
+	}
+
+	s.saved = make(map[string]string)
+	if s.Path == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("error parsing %s: %s", s.Path, err)
+	}
+	for k, v := range f.Credentials {
+		if v != nil && v.Token != "" {
+			s.saved[NormalizeHost(k)] = v.Token
+		}
+	}
+
+	return nil
+}
+
+// Transport returns an http.RoundTripper that sends requests with base,
+// or http.DefaultTransport if it's nil. HTTPS requests that don't already
+// have an Authorization header are sent with the token for their host,
+// as a bearer token, if there is one.
+func (s *Store) Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{store: s, base: base}
+}
+
+type transport struct {
+	store *Store
+	base  http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if req.URL.Scheme != "https" || req.Header.Get("Authorization") != "" {
+		return base.RoundTrip(req)
+	}
+
+	token, err := t.store.Token(req.URL.Host)
+	if err != nil {
+		log.Printf("[WARN] credentials: error reading the token for %s: %s", req.URL.Host, err)
+	}
+	if token == "" {
+		return base.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the request, so the token is added
+	// to a copy.
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	return base.RoundTrip(r)
+}
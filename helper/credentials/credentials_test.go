@@ -0,0 +1,208 @@
+package credentials
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/vault"
+)
+
+func tempStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	s := &Store{Path: filepath.Join(dir, "sub", FileName)}
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func TestStore(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+
+	if token, err := s.Token("example.com"); err != nil || token != "" {
+		t.Fatalf("bad: %q %v", token, err)
+	}
+
+	if err := s.SetToken("Example.com:443", "foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.SetToken("example.net", "bar"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	fi, err := os.Stat(s.Path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Fatalf("bad: %o", perm)
+	}
+
+	// A new store reads the tokens from the file
+	s2 := &Store{
+		Path:   s.Path,
+		Static: map[string]string{"example.net": "static"},
+	}
+	cases := map[string]string{
+		"example.com":      "foo",
+		"EXAMPLE.COM":      "foo",
+		"example.com:8443": "",
+		"example.net":      "static",
+		"example.org":      "",
+	}
+	for host, expected := range cases {
+		token, err := s2.Token(host)
+		if err != nil {
+			t.Fatalf("%s: err: %s", host, err)
+		}
+		if token != expected {
+			t.Fatalf("%s: bad: %q", host, token)
+		}
+	}
+	if !s2.IsStatic("Example.net") || s2.IsStatic("example.com") {
+		t.Fatal("bad static hosts")
+	}
+
+	if err := s2.SetToken("example.com", ""); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if strings.Contains(string(data), "example.com") || !strings.Contains(string(data), "example.net") {
+		t.Fatalf("bad: %s", data)
+	}
+}
+
+func TestStore_noPath(t *testing.T) {
+	s := &Store{Static: map[string]string{"example.com": "foo"}}
+	if token, err := s.Token("example.com"); err != nil || token != "foo" {
+		t.Fatalf("bad: %q %v", token, err)
+	}
+	if err := s.SetToken("example.com", "bar"); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestStore_vault(t *testing.T) {
+	s := &Store{Vault: map[string]string{"example.com": "secret/terraform#token"}}
+	if !s.IsStatic("example.com") {
+		t.Fatal("token in Vault should be static")
+	}
+
+	defer func(old *vault.Client) { vault.Default = old }(vault.Default)
+	vault.Default = nil
+	if _, err := s.Token("example.com"); err == nil {
+		t.Fatal("should error without Vault")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			w.Write([]byte(`{"data": {"renewable": false}}`))
+		case "/v1/secret/terraform":
+			w.Write([]byte(`{"data": {"token": "foo"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": []}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := vault.NewClient(&vault.Config{Address: srv.URL, Token: "root"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer c.Stop()
+	vault.Default = c
+
+	if token, err := s.Token("Example.com:443"); err != nil || token != "foo" {
+		t.Fatalf("bad: %q %v", token, err)
+	}
+}
+
+func TestStore_Transport(t *testing.T) {
+	var auth string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	s := &Store{Static: map[string]string{host: "foo"}}
+	cert, err := x509.ParseCertificate(server.TLS.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	certs := x509.NewCertPool()
+	certs.AddCert(cert)
+	transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: certs}}
+	client := &http.Client{Transport: s.Transport(transport)}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp.Body.Close()
+	if auth != "Bearer foo" {
+		t.Fatalf("bad: %q", auth)
+	}
+
+	// An Authorization header that's already set is kept
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	req.SetBasicAuth("user", "pass")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp.Body.Close()
+	if !strings.HasPrefix(auth, "Basic ") {
+		t.Fatalf("bad: %q", auth)
+	}
+
+	// Other hosts don't get the token
+	s.Static = map[string]string{"example.com": "foo"}
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp.Body.Close()
+	if auth != "" {
+		t.Fatalf("bad: %q", auth)
+	}
+}
+
+func TestStore_TransportHTTP(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	s := &Store{Static: map[string]string{host: "foo"}}
+	client := &http.Client{Transport: s.Transport(nil)}
+
+	// Tokens are never sent in the clear
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp.Body.Close()
+	if auth != "" {
+		t.Fatalf("bad: %q", auth)
+	}
+}
@@ -0,0 +1,34 @@
+// Package keychain reads secrets from the credential store of the
+// operating system: the Keychain on macOS, the Credential Manager on
+// Windows, and the Secret Service, through libsecret, everywhere else.
+//
+// This lets secrets such as cloud credentials be kept out of dotfiles and
+// shell profiles. Secrets are stored under the service "terraform", with
+// the name of the secret as the account, or on Windows as a generic
+// credential with the target "terraform:NAME".
+package keychain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Service is the service that Terraform's secrets are stored under.
+const Service = "terraform"
+
+// ErrNotFound is returned when a secret isn't in the credential store.
+var ErrNotFound = errors.New("secret not found")
+
+// Get returns the secret with the given name from the credential store.
+func Get(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("secret name must not be empty")
+	}
+
+	v, err := get(name)
+	if err != nil {
+		return "", fmt.Errorf("error reading %q from the %s: %s", name, storeName, err)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,30 @@
+package keychain
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+)
+
+const storeName = "macOS Keychain"
+
+// securityNotFound is the exit status of the "security" tool when an item
+// isn't in the keychain.
+const securityNotFound = 44
+
+func get(name string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(
+		"security", "find-generic-password", "-s", Service, "-a", name, "-w")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitStatus(exitErr) == securityNotFound {
+			return "", ErrNotFound
+		}
+
+		return "", commandError(err, stderr.String())
+	}
+
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
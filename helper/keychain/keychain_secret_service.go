@@ -0,0 +1,40 @@
+// +build !darwin,!windows
+
+package keychain
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+const storeName = "Secret Service"
+
+var errSecretToolMissing = errors.New(
+	`the "secret-tool" command wasn't found, it's usually in the ` +
+		`"libsecret-tools" or "libsecret" package`)
+
+func get(name string) (string, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "", errSecretToolMissing
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(
+		"secret-tool", "lookup", "service", Service, "account", name)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// secret-tool exits with a status of 1 and no output when there is
+		// no such secret.
+		if exitErr, ok := err.(*exec.ExitError); ok &&
+			exitStatus(exitErr) == 1 && stdout.Len() == 0 && stderr.Len() == 0 {
+			return "", ErrNotFound
+		}
+
+		return "", commandError(err, stderr.String())
+	}
+
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
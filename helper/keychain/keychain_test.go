@@ -0,0 +1,11 @@
+package keychain
+
+import (
+	"testing"
+)
+
+func TestGet_emptyName(t *testing.T) {
+	if _, err := Get(""); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -14,8 +14,9 @@ import (
 // These are the environmental variables that determine if we log, and if
 // we log whether or not the log should go to a file.
 const (
-	EnvLog     = "TF_LOG"      // Set to True
-	EnvLogFile = "TF_LOG_PATH" // Set to a file
+	EnvLog       = "TF_LOG"        // Set to True
+	EnvLogFile   = "TF_LOG_PATH"   // Set to a file
+	EnvLogFormat = "TF_LOG_FORMAT" // Set to "json" for JSON logs
 )
 
 var validLevels = []logutils.LogLevel{"TRACE", "DEBUG", "INFO", "WARN", "ERROR"}
@@ -24,8 +25,8 @@ var validLevels = []logutils.LogLevel{"TRACE", "DEBUG", "INFO", "WARN", "ERROR"}
 func LogOutput() (logOutput io.Writer, err error) {
 	logOutput = ioutil.Discard
 
-	logLevel := LogLevel()
-	if logLevel == "" {
+	levels := ParseLevels(os.Getenv(EnvLog))
+	if levels.Empty() {
 		return
 	}
 
@@ -38,11 +39,21 @@ func LogOutput() (logOutput io.Writer, err error) {
 		}
 	}
 
-	// This was the default since the beginning
-	logOutput = &logutils.LevelFilter{
-		Levels:   validLevels,
-		MinLevel: logutils.LogLevel(logLevel),
-		Writer:   logOutput,
+	jsonFormat := false
+	switch format := strings.ToLower(os.Getenv(EnvLogFormat)); format {
+	case "", "text":
+	case "json":
+		jsonFormat = true
+	default:
+		log.Printf("[WARN] Invalid log format: %q. Valid formats are: text, json", format)
+	}
+
+	// The entries of a Logger are only readable through a Writer, so it's
+	// used even when it filters the same way as the plain level filter.
+	logOutput = &Writer{
+		Levels: levels,
+		JSON:   jsonFormat,
+		Writer: logOutput,
 	}
 
 	return
@@ -64,23 +75,11 @@ func SetOutput() {
 	log.SetOutput(out)
 }
 
-// LogLevel returns the current log level string based the environment vars
+// LogLevel returns the current log level string based the environment vars.
+// If there are levels for particular components, this is the most verbose
+// of them.
 func LogLevel() string {
-	envLevel := os.Getenv(EnvLog)
-	if envLevel == "" {
-		return ""
-	}
-
-	logLevel := "TRACE"
-	if isValidLogLevel(envLevel) {
-		// allow following for better ux: info, Info or INFO
-		logLevel = strings.ToUpper(envLevel)
-	} else {
-		log.Printf("[WARN] Invalid log level: %q. Defaulting to level: TRACE. Valid levels are: %+v",
-			envLevel, validLevels)
-	}
-
-	return logLevel
+	return ParseLevels(os.Getenv(EnvLog)).Min()
 }
 
 // IsDebugOrHigher returns whether or not the current log level is debug or trace
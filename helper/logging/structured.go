@@ -0,0 +1,409 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a single entry of Terraform's log.
+//
+// Entries logged with a Logger carry their fields as they were given. Lines
+// logged with the log package directly follow the convention
+// "[LEVEL] component: message", where the component is optional and is
+// sometimes the address of a resource instead, and ParseEntry splits them
+// into their parts. Lines logged by providers are relayed by the plugin
+// client as "[LEVEL] plugin: terraform-provider-NAME: LINE", with the
+// provider's own log line at the end.
+type Entry struct {
+	Time      string `json:"@timestamp,omitempty"`
+	Level     string `json:"@level,omitempty"`
+	Component string `json:"@component,omitempty"`
+	Address   string `json:"@address,omitempty"`
+	Provider  string `json:"@provider,omitempty"`
+	Message   string `json:"@message"`
+}
+
+// String returns the entry as a line of the text log, following the
+// convention of the lines logged with the log package directly.
+func (e *Entry) String() string {
+	var buf bytes.Buffer
+	if e.Level != "" {
+		buf.WriteString("[" + e.Level + "] ")
+	}
+	if e.Component != "" {
+		buf.WriteString(e.Component + ": ")
+	}
+	if e.Address != "" {
+		buf.WriteString(e.Address + ": ")
+	}
+	buf.WriteString(e.Message)
+
+	return buf.String()
+}
+
+// entryPrefix starts the log lines written by a Logger, which carry their
+// entry as JSON after it so that it doesn't have to be parsed from text.
+const entryPrefix = "@entry "
+
+// Logger logs the entries of a component of Terraform, such as
+// "backend/local", at the levels given by its callers.
+//
+// The entries are written to the standard logger, so they go wherever
+// SetOutput sends the log, with their fields intact for Writer to filter
+// them by and to show as text or JSON.
+type Logger struct {
+	component string
+	address   string
+	provider  string
+}
+
+// New returns a Logger for the given component.
+func New(component string) *Logger {
+	return &Logger{component: component}
+}
+
+// Resource returns a Logger for the same component whose entries are about
+// the resource with the given address.
+func (l *Logger) Resource(addr string) *Logger {
+	result := *l
+	result.address = addr
+	return &result
+}
+
+// Provider returns a Logger for the same component whose entries are about
+// the provider with the given name.
+func (l *Logger) Provider(name string) *Logger {
+	result := *l
+	result.provider = name
+	return &result
+}
+
+// Trace logs a message at the TRACE level, formatted like fmt.Sprintf.
+func (l *Logger) Trace(format string, a ...interface{}) {
+	l.output("TRACE", format, a)
+}
+
+// Debug logs a message at the DEBUG level, formatted like fmt.Sprintf.
+func (l *Logger) Debug(format string, a ...interface{}) {
+	l.output("DEBUG", format, a)
+}
+
+// Info logs a message at the INFO level, formatted like fmt.Sprintf.
+func (l *Logger) Info(format string, a ...interface{}) {
+	l.output("INFO", format, a)
+}
+
+// Warn logs a message at the WARN level, formatted like fmt.Sprintf.
+func (l *Logger) Warn(format string, a ...interface{}) {
+	l.output("WARN", format, a)
+}
+
+// Error logs a message at the ERROR level, formatted like fmt.Sprintf.
+func (l *Logger) Error(format string, a ...interface{}) {
+	l.output("ERROR", format, a)
+}
+
+func (l *Logger) output(level, format string, a []interface{}) {
+	e := &Entry{
+		Level:     level,
+		Component: l.component,
+		Address:   l.address,
+		Provider:  l.provider,
+		Message:   fmt.Sprintf(format, a...),
+	}
+
+	// An entry only has strings, so it can always be encoded
+	data, _ := json.Marshal(e)
+	log.Output(3, entryPrefix+string(data))
+}
+
+var (
+	logTimestampRe = regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(\.\d+)? `)
+	logLevelRe     = regexp.MustCompile(`^\[([A-Z]+)\] ?`)
+	logPrefixRe    = regexp.MustCompile(`^([^\s:]+): `)
+	logAddressRe   = regexp.MustCompile(
+		`^(module\.[\w-]+\.)*(data\.)?[a-zA-Z0-9]+_[\w-]+\.[\w-]+(\.\d+|\[[^\]]+\])?$`)
+	logProviderRe = regexp.MustCompile(`^terraform-provider-([\w-]+?)(_v.*)?$`)
+)
+
+// ParseEntry parses a single log line. It also returns whether the line
+// starts a new log entry, rather than continuing the message of the one
+// before it over multiple lines.
+//
+// The entries of a Logger are decoded as they were logged. Other lines are
+// split into their parts by the conventions above, which is a best guess.
+func ParseEntry(line string) (*Entry, bool) {
+	e := new(Entry)
+	start := false
+
+	rest := line
+	if ts := logTimestampRe.FindString(rest); ts != "" {
+		rest = rest[len(ts):]
+		start = true
+
+		t, err := time.ParseInLocation(
+			"2006/01/02 15:04:05", strings.TrimSpace(ts), time.Local)
+		if err == nil {
+			e.Time = t.Format(time.RFC3339)
+		}
+	}
+	if strings.HasPrefix(rest, entryPrefix) {
+		if err := json.Unmarshal([]byte(rest[len(entryPrefix):]), e); err == nil {
+			return e, true
+		}
+	}
+	if m := logLevelRe.FindStringSubmatch(rest); m != nil {
+		rest = rest[len(m[0]):]
+		e.Level = m[1]
+		start = true
+	}
+
+	// Panics aren't logged, but they do end up in the log
+	if strings.HasPrefix(rest, "panic: ") {
+		start = true
+	}
+
+	if tok, r, ok := splitLogPrefix(rest); ok {
+		rest = r
+		switch {
+		case tok == "plugin":
+			e.Component = tok
+
+			name, r, ok := splitLogPrefix(rest)
+			if !ok {
+				break
+			}
+			m := logProviderRe.FindStringSubmatch(name)
+			if m == nil {
+				break
+			}
+
+			// The rest is a line logged by the provider itself, whose
+			// level is more accurate than the one the line was relayed at.
+			inner, _ := ParseEntry(r)
+			e.Component = "provider." + m[1]
+			e.Provider = m[1]
+			e.Address = inner.Address
+			if inner.Level != "" {
+				e.Level = inner.Level
+			}
+			rest = inner.Message
+		case logAddressRe.MatchString(tok):
+			e.Address = tok
+		default:
+			e.Component = tok
+			if tok, r, ok := splitLogPrefix(rest); ok && logAddressRe.MatchString(tok) {
+				e.Address = tok
+				rest = r
+			}
+		}
+	}
+
+	e.Message = rest
+	return e, start
+}
+
+// textLine returns the line of the text log for the given log line, with
+// the entry of a Logger in it shown as text rather than JSON.
+func textLine(line string) string {
+	idx := strings.Index(line, entryPrefix)
+	if idx < 0 {
+		return line
+	}
+
+	var e Entry
+	if err := json.Unmarshal([]byte(line[idx+len(entryPrefix):]), &e); err != nil {
+		return line
+	}
+
+	return line[:idx] + e.String()
+}
+
+// splitLogPrefix splits the "prefix: " from the start of a log message.
+func splitLogPrefix(s string) (string, string, bool) {
+	m := logPrefixRe.FindStringSubmatch(s)
+	if m == nil {
+		return "", "", false
+	}
+
+	return m[1], s[len(m[0]):], true
+}
+
+// Levels are the log levels set with TF_LOG: a default level, and levels
+// for particular components. An empty level turns logging off.
+//
+// TF_LOG is a comma-separated list of a level and "component=level" pairs,
+// such as "WARN,backend=DEBUG". A component matches itself and the
+// components within it, so "backend" matches "backend/local", and
+// "provider" matches "provider.aws".
+type Levels struct {
+	Default    string
+	Components map[string]string
+}
+
+// ParseLevels parses the value of TF_LOG. Values that aren't valid levels
+// default to TRACE, since TF_LOG was originally just a switch.
+func ParseLevels(v string) *Levels {
+	result := &Levels{Components: make(map[string]string)}
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		component := ""
+		if idx := strings.Index(part, "="); idx >= 0 {
+			component = strings.TrimSpace(part[:idx])
+			part = strings.TrimSpace(part[idx+1:])
+		}
+
+		level := "TRACE"
+		if isValidLogLevel(part) {
+			// allow following for better ux: info, Info or INFO
+			level = strings.ToUpper(part)
+		} else {
+			log.Printf("[WARN] Invalid log level: %q. Defaulting to level: TRACE. Valid levels are: %+v",
+				part, validLevels)
+		}
+
+		if component == "" {
+			result.Default = level
+		} else {
+			result.Components[component] = level
+		}
+	}
+
+	return result
+}
+
+// Empty returns true if logging is off for everything.
+func (l *Levels) Empty() bool {
+	return l.Default == "" && len(l.Components) == 0
+}
+
+// Min returns the most verbose level that anything is logged at.
+func (l *Levels) Min() string {
+	min := l.Default
+	for _, level := range l.Components {
+		if min == "" || logLevelIndex(level) < logLevelIndex(min) {
+			min = level
+		}
+	}
+
+	return min
+}
+
+// Level returns the level that the given component is logged at. The level
+// of the longest matching component wins.
+func (l *Levels) Level(component string) string {
+	level := l.Default
+	match := ""
+	for c, cl := range l.Components {
+		if len(c) <= len(match) {
+			continue
+		}
+		if component == c ||
+			strings.HasPrefix(component, c+"/") ||
+			strings.HasPrefix(component, c+".") {
+			level = cl
+			match = c
+		}
+	}
+
+	return level
+}
+
+// Allows returns true if the given entry is logged. Like the level filter
+// that Terraform has always used, entries without a level or with an
+// unknown one are always logged, unless logging is off for their component.
+func (l *Levels) Allows(e *Entry) bool {
+	level := l.Level(e.Component)
+	if level == "" {
+		return false
+	}
+
+	idx := logLevelIndex(e.Level)
+	return idx < 0 || idx >= logLevelIndex(level)
+}
+
+func logLevelIndex(level string) int {
+	for i, l := range validLevels {
+		if string(l) == level {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Writer is a log output that filters log lines by their level, with
+// different levels for different components, and writes them as text or
+// as JSON objects, one per line.
+//
+// Lines can be split across writes. Lines that continue the message of a
+// log entry over multiple lines are filtered along with that entry, and
+// each becomes its own object with the same fields in JSON.
+type Writer struct {
+	Levels *Levels
+	JSON   bool
+	Writer io.Writer
+
+	mu   sync.Mutex
+	buf  []byte
+	last *Entry
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+
+		line := string(w.buf[:idx])
+		w.buf = append(w.buf[:0], w.buf[idx+1:]...)
+		if err := w.writeLine(line); err != nil {
+			return len(p), err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (w *Writer) writeLine(line string) error {
+	e, start := ParseEntry(line)
+	if !start && w.last != nil {
+		cont := *w.last
+		cont.Message = line
+		e = &cont
+	} else {
+		w.last = e
+	}
+
+	if !w.Levels.Allows(e) {
+		return nil
+	}
+
+	if !w.JSON {
+		_, err := io.WriteString(w.Writer, textLine(line)+"\n")
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = w.Writer.Write(append(data, '\n'))
+	return err
+}
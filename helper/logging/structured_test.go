@@ -0,0 +1,310 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEntry(t *testing.T) {
+	cases := map[string]struct {
+		Line  string
+		Entry Entry
+		Start bool
+	}{
+		"component": {
+			"[DEBUG] backend/local: starting Plan operation",
+			Entry{
+				Level:     "DEBUG",
+				Component: "backend/local",
+				Message:   "starting Plan operation",
+			},
+			true,
+		},
+		"address": {
+			"[INFO] module.child.aws_instance.web.0: refreshing",
+			Entry{
+				Level:   "INFO",
+				Address: "module.child.aws_instance.web.0",
+				Message: "refreshing",
+			},
+			true,
+		},
+		"component and address": {
+			`[WARN] command: aws_instance.web["a"]: skipped`,
+			Entry{
+				Level:     "WARN",
+				Component: "command",
+				Address:   `aws_instance.web["a"]`,
+				Message:   "skipped",
+			},
+			true,
+		},
+		"no component": {
+			"[INFO] Terraform version: 0.10.0",
+			Entry{
+				Level:   "INFO",
+				Message: "Terraform version: 0.10.0",
+			},
+			true,
+		},
+		"provider": {
+			"[DEBUG] plugin: terraform-provider-aws_v1.0.0_x4: " +
+				"2017/10/02 14:03:11 [ERROR] aws_instance.web: failed",
+			Entry{
+				Level:     "ERROR",
+				Component: "provider.aws",
+				Address:   "aws_instance.web",
+				Provider:  "aws",
+				Message:   "failed",
+			},
+			true,
+		},
+		"plugin": {
+			"[DEBUG] plugin: starting plugin: /bin/terraform",
+			Entry{
+				Level:     "DEBUG",
+				Component: "plugin",
+				Message:   "starting plugin: /bin/terraform",
+			},
+			true,
+		},
+		"continuation": {
+			"---[ REQUEST ]---",
+			Entry{
+				Message: "---[ REQUEST ]---",
+			},
+			false,
+		},
+	}
+
+	for name, tc := range cases {
+		e, start := ParseEntry(tc.Line)
+		if !reflect.DeepEqual(*e, tc.Entry) {
+			t.Fatalf("%s: bad: %#v", name, e)
+		}
+		if start != tc.Start {
+			t.Fatalf("%s: bad start: %t", name, start)
+		}
+	}
+}
+
+func TestParseEntry_time(t *testing.T) {
+	e, start := ParseEntry("2017/10/02 14:03:11 [INFO] terraform: walking")
+	if !start {
+		t.Fatal("should start an entry")
+	}
+	if !strings.HasPrefix(e.Time, "2017-10-02T14:03:11") {
+		t.Fatalf("bad: %s", e.Time)
+	}
+	if e.Level != "INFO" || e.Component != "terraform" || e.Message != "walking" {
+		t.Fatalf("bad: %#v", e)
+	}
+}
+
+func TestParseLevels(t *testing.T) {
+	cases := map[string]*Levels{
+		"": &Levels{Components: map[string]string{}},
+		"debug": &Levels{
+			Default:    "DEBUG",
+			Components: map[string]string{},
+		},
+		"1": &Levels{
+			Default:    "TRACE",
+			Components: map[string]string{},
+		},
+		"WARN, backend=debug,provider.aws=TRACE": &Levels{
+			Default: "WARN",
+			Components: map[string]string{
+				"backend":      "DEBUG",
+				"provider.aws": "TRACE",
+			},
+		},
+	}
+
+	for v, expected := range cases {
+		if actual := ParseLevels(v); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("%q: bad: %#v", v, actual)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	l := ParseLevels("ERROR,backend=DEBUG,backend/local=INFO,provider=WARN")
+
+	levels := map[string]string{
+		"":              "ERROR",
+		"terraform":     "ERROR",
+		"backend":       "DEBUG",
+		"backend/atlas": "DEBUG",
+		"backend/local": "INFO",
+		"backends":      "ERROR",
+		"provider.aws":  "WARN",
+	}
+	for c, expected := range levels {
+		if actual := l.Level(c); actual != expected {
+			t.Fatalf("%q: expected %s, got %s", c, expected, actual)
+		}
+	}
+
+	if min := l.Min(); min != "DEBUG" {
+		t.Fatalf("bad min: %s", min)
+	}
+
+	allows := map[Entry]bool{
+		Entry{Level: "ERROR", Component: "terraform"}:     true,
+		Entry{Level: "INFO", Component: "terraform"}:      false,
+		Entry{Level: "DEBUG", Component: "backend/s3"}:    true,
+		Entry{Level: "TRACE", Component: "backend/s3"}:    false,
+		Entry{Level: "DEBUG", Component: "backend/local"}: false,
+		Entry{Component: "terraform"}:                     true,
+	}
+	for e, expected := range allows {
+		if actual := l.Allows(&e); actual != expected {
+			t.Fatalf("%#v: expected %t", e, expected)
+		}
+	}
+
+	// Only the given components are logged without a default level
+	l = ParseLevels("backend=TRACE")
+	if l.Allows(&Entry{Level: "ERROR", Component: "terraform"}) {
+		t.Fatal("should not allow other components")
+	}
+	if !l.Allows(&Entry{Level: "TRACE", Component: "backend/local"}) {
+		t.Fatal("should allow backend")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &Writer{
+		Levels: ParseLevels("WARN,backend=DEBUG"),
+		Writer: &buf,
+	}
+
+	lines := []string{
+		"[DEBUG] backend/local: starting\n",
+		"[DEBUG] terraform: walking\n",
+		"details of the walk\n",
+		"[DEBUG] backend/local: request:\n---\nGET /\n",
+		"[WARN] terraform: ",
+		"split\n",
+	}
+	for _, l := range lines {
+		if _, err := w.Write([]byte(l)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	expected := strings.TrimSpace(`
+[DEBUG] backend/local: starting
+[DEBUG] backend/local: request:
+---
+GET /
+[WARN] terraform: split
+`)
+	if actual := strings.TrimSpace(buf.String()); actual != expected {
+		t.Fatalf("bad:\n\n%s", actual)
+	}
+}
+
+func TestWriter_json(t *testing.T) {
+	var buf bytes.Buffer
+	w := &Writer{
+		Levels: ParseLevels("DEBUG"),
+		JSON:   true,
+		Writer: &buf,
+	}
+
+	_, err := w.Write([]byte(
+		"[DEBUG] plugin: terraform-provider-aws: [INFO] aws_instance.web: request:\nGET /\n"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := strings.TrimSpace(`
+{"@level":"INFO","@component":"provider.aws","@address":"aws_instance.web","@provider":"aws","@message":"request:"}
+{"@level":"INFO","@component":"provider.aws","@address":"aws_instance.web","@provider":"aws","@message":"GET /"}
+`)
+	if actual := strings.TrimSpace(buf.String()); actual != expected {
+		t.Fatalf("bad:\n\n%s", actual)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var raw bytes.Buffer
+	oldFlags := log.Flags()
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(oldFlags)
+	}()
+	log.SetOutput(&raw)
+
+	l := New("backend/local")
+	l.Debug("starting %s", "Plan")
+	l.Resource("module.child.aws_instance.web").Warn("skipped: %d", 1)
+	New("terraform").Provider("aws").Info("weird: address: in message")
+	log.Printf("[INFO] terraform: not from a logger")
+
+	var text, js bytes.Buffer
+	textW := &Writer{Levels: ParseLevels("WARN,backend=DEBUG"), Writer: &text}
+	jsonW := &Writer{Levels: ParseLevels("INFO"), JSON: true, Writer: &js}
+	if _, err := textW.Write(raw.Bytes()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := jsonW.Write(raw.Bytes()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The lines keep the timestamps of the log package
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(text.String()), "\n") {
+		lines = append(lines, logTimestampRe.ReplaceAllString(line, ""))
+	}
+	expected := []string{
+		"[DEBUG] backend/local: starting Plan",
+		"[WARN] backend/local: module.child.aws_instance.web: skipped: 1",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("bad:\n\n%s", text.String())
+	}
+
+	var entries []Entry
+	for _, line := range strings.Split(strings.TrimSpace(js.String()), "\n") {
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("err: %s\n\n%s", err, line)
+		}
+		if e.Time == "" {
+			t.Fatalf("no time: %s", line)
+		}
+		e.Time = ""
+		entries = append(entries, e)
+	}
+	expectedEntries := []Entry{
+		{
+			Level:     "WARN",
+			Component: "backend/local",
+			Address:   "module.child.aws_instance.web",
+			Message:   "skipped: 1",
+		},
+		{
+			Level:     "INFO",
+			Component: "terraform",
+			Provider:  "aws",
+			Message:   "weird: address: in message",
+		},
+		{
+			Level:     "INFO",
+			Component: "terraform",
+			Message:   "not from a logger",
+		},
+	}
+	if !reflect.DeepEqual(entries, expectedEntries) {
+		t.Fatalf("bad: %#v", entries)
+	}
+}
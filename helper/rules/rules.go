@@ -0,0 +1,297 @@
+// Package rules checks configurations against simple rules, such as tags
+// that resources must have, resource types that mustn't be used and
+// patterns that resource names must match, so that basic standards can be
+// enforced by "terraform validate" without other tools.
+//
+// Rules are read from HCL or JSON files in a directory:
+//
+//	rule "owner-tag" {
+//	  resource_types = ["aws_*"]
+//	  required_tags  = ["Owner"]
+//	}
+//
+// Each rule applies to the managed resources whose types match one of its
+// resource_types patterns, or to every managed resource if it has none.
+package rules
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// The severities of rules.
+const (
+	// SeverityError is for rules that fail validation when they're broken.
+	// It's the default.
+	SeverityError = "error"
+
+	// SeverityWarning is for rules that only warn when they're broken.
+	SeverityWarning = "warning"
+)
+
+// Rule is a rule that the resources of a configuration are checked against.
+type Rule struct {
+	Name string `hcl:",key"`
+
+	// Description is shown with the findings of the rule, to explain it.
+	Description string `hcl:"description"`
+
+	// Severity is SeverityError or SeverityWarning.
+	Severity string `hcl:"severity"`
+
+	// ResourceTypes are the patterns, as for path.Match, of the types of
+	// the resources that the rule applies to. The rule applies to every
+	// resource if there are none.
+	ResourceTypes []string `hcl:"resource_types"`
+
+	// Disallowed forbids the resources that the rule applies to.
+	Disallowed bool `hcl:"disallowed"`
+
+	// RequiredTags are the keys that the tags of the resources must have.
+	// Tags set by interpolating a whole map can't be checked, so they
+	// pass.
+	RequiredTags []string `hcl:"required_tags"`
+
+	// TagsAttribute is the attribute that has the tags. It defaults to
+	// "tags".
+	TagsAttribute string `hcl:"tags_attribute"`
+
+	// NamePattern is a regular expression that the names of the resources
+	// must match.
+	NamePattern string `hcl:"name_pattern"`
+
+	namePattern *regexp.Regexp
+}
+
+// Finding is a resource that breaks a rule.
+type Finding struct {
+	Rule     *Rule
+	Address  string
+	Message  string
+	Severity string
+}
+
+func (f *Finding) String() string {
+	result := fmt.Sprintf("rule %q: %s: %s", f.Rule.Name, f.Address, f.Message)
+	if f.Rule.Description != "" {
+		result += fmt.Sprintf(" (%s)", f.Rule.Description)
+	}
+
+	return result
+}
+
+// LoadDir loads the rules from the files ending in ".hcl" or ".json" in
+// dir, in the order of the file names.
+func LoadDir(dir string) ([]*Rule, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Rule
+	names := make(map[string]string)
+	for _, info := range infos {
+		ext := filepath.Ext(info.Name())
+		if info.IsDir() || (ext != ".hcl" && ext != ".json") {
+			continue
+		}
+
+		p := filepath.Join(dir, info.Name())
+		rules, err := LoadFile(p)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range rules {
+			if other, ok := names[r.Name]; ok {
+				return nil, fmt.Errorf(
+					"%s: rule %q is also defined in %s", p, r.Name, other)
+			}
+			names[r.Name] = p
+		}
+		result = append(result, rules...)
+	}
+
+	return result, nil
+}
+
+// LoadFile loads the rules from a single HCL or JSON file.
+func LoadFile(p string) ([]*Rule, error) {
+	d, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Rules []*Rule `hcl:"rule"`
+	}
+	if err := hcl.Decode(&file, string(d)); err != nil {
+		return nil, fmt.Errorf("%s: %s", p, err)
+	}
+
+	for _, r := range file.Rules {
+		if err := r.init(); err != nil {
+			return nil, fmt.Errorf("%s: rule %q: %s", p, r.Name, err)
+		}
+	}
+
+	return file.Rules, nil
+}
+
+// init sets the defaults of the rule and checks that it's valid.
+func (r *Rule) init() error {
+	if r.Severity == "" {
+		r.Severity = SeverityError
+	}
+	if r.Severity != SeverityError && r.Severity != SeverityWarning {
+		return fmt.Errorf("severity must be %q or %q", SeverityError, SeverityWarning)
+	}
+
+	if r.TagsAttribute == "" {
+		r.TagsAttribute = "tags"
+	}
+
+	for _, p := range r.ResourceTypes {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid resource type pattern %q: %s", p, err)
+		}
+	}
+
+	if r.NamePattern != "" {
+		re, err := regexp.Compile(r.NamePattern)
+		if err != nil {
+			return fmt.Errorf("invalid name pattern: %s", err)
+		}
+		r.namePattern = re
+	}
+
+	if !r.Disallowed && len(r.RequiredTags) == 0 && r.namePattern == nil {
+		return fmt.Errorf("must set disallowed, required_tags or name_pattern")
+	}
+
+	return nil
+}
+
+// Check checks the resources of every module in the tree against the
+// rules, and returns the resources that break them, sorted by address and
+// then by rule.
+func Check(tree *module.Tree, rules []*Rule) []*Finding {
+	var result []*Finding
+	check(tree, rules, &result)
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Address < result[j].Address
+	})
+
+	return result
+}
+
+func check(tree *module.Tree, rules []*Rule, result *[]*Finding) {
+	var prefix string
+	for _, name := range tree.Path() {
+		prefix += fmt.Sprintf("module.%s.", name)
+	}
+
+	if cfg := tree.Config(); cfg != nil {
+		for _, res := range cfg.Resources {
+			if res.Mode != config.ManagedResourceMode {
+				continue
+			}
+
+			for _, r := range rules {
+				if !r.appliesTo(res) {
+					continue
+				}
+
+				for _, msg := range r.check(res) {
+					*result = append(*result, &Finding{
+						Rule:     r,
+						Address:  prefix + res.Id(),
+						Message:  msg,
+						Severity: r.Severity,
+					})
+				}
+			}
+		}
+	}
+
+	for _, child := range tree.Children() {
+		check(child, rules, result)
+	}
+}
+
+func (r *Rule) appliesTo(res *config.Resource) bool {
+	if len(r.ResourceTypes) == 0 {
+		return true
+	}
+
+	for _, p := range r.ResourceTypes {
+		if ok, _ := path.Match(p, res.Type); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// check returns the ways that the resource breaks the rule.
+func (r *Rule) check(res *config.Resource) []string {
+	var result []string
+	if r.Disallowed {
+		result = append(result, fmt.Sprintf("resource type %s is not allowed", res.Type))
+	}
+
+	if r.namePattern != nil && !r.namePattern.MatchString(res.Name) {
+		result = append(result, fmt.Sprintf(
+			"name %q doesn't match %q", res.Name, r.NamePattern))
+	}
+
+	if len(r.RequiredTags) > 0 {
+		if missing := r.missingTags(res); len(missing) > 0 {
+			result = append(result, fmt.Sprintf(
+				"missing tags: %s", strings.Join(missing, ", ")))
+		}
+	}
+
+	return result
+}
+
+// missingTags returns the required tags that the resource doesn't set.
+func (r *Rule) missingTags(res *config.Resource) []string {
+	var tags map[string]interface{}
+	if res.RawConfig != nil {
+		switch v := res.RawConfig.Raw[r.TagsAttribute].(type) {
+		case nil:
+		case map[string]interface{}:
+			tags = v
+		case []map[string]interface{}:
+			tags = make(map[string]interface{})
+			for _, m := range v {
+				for k, tag := range m {
+					tags[k] = tag
+				}
+			}
+		default:
+			// Interpolated as a whole, so the keys aren't known
+			return nil
+		}
+	}
+
+	var result []string
+	for _, tag := range r.RequiredTags {
+		if _, ok := tags[tag]; !ok {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
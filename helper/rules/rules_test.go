@@ -0,0 +1,94 @@
+package rules
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/config/module"
+)
+
+func TestLoadDir(t *testing.T) {
+	rs, err := LoadDir("./test-fixtures/rules")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var names []string
+	for _, r := range rs {
+		names = append(names, fmt.Sprintf("%s:%s", r.Name, r.Severity))
+	}
+	expected := []string{"owner-tag:error", "naming:warning", "no-classic-elb:error"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad: %#v", names)
+	}
+	if rs[0].TagsAttribute != "tags" {
+		t.Fatalf("bad: %q", rs[0].TagsAttribute)
+	}
+}
+
+func TestLoadDir_invalid(t *testing.T) {
+	_, err := LoadDir("./test-fixtures/rules-invalid")
+	if err == nil || !strings.Contains(err.Error(), `rule "bad-name": invalid name pattern`) {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestRuleInit(t *testing.T) {
+	cases := map[string]struct {
+		Rule Rule
+		Err  bool
+	}{
+		"valid": {
+			Rule{Name: "a", Disallowed: true},
+			false,
+		},
+		"no checks": {
+			Rule{Name: "a", ResourceTypes: []string{"aws_*"}},
+			true,
+		},
+		"bad severity": {
+			Rule{Name: "a", Disallowed: true, Severity: "info"},
+			true,
+		},
+		"bad type pattern": {
+			Rule{Name: "a", Disallowed: true, ResourceTypes: []string{"aws_["}},
+			true,
+		},
+	}
+
+	for name, tc := range cases {
+		err := tc.Rule.init()
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: bad: %v", name, err)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	rs, err := LoadDir("./test-fixtures/rules")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	tree, cleanup := module.TestTree(t, "./test-fixtures/config")
+	defer cleanup()
+
+	var actual []string
+	for _, f := range Check(tree, rs) {
+		actual = append(actual, fmt.Sprintf("%s %s", f.Severity, f))
+	}
+
+	// Data sources aren't checked, and neither are tags that are
+	// interpolated as a whole.
+	expected := []string{
+		`error rule "no-classic-elb": aws_elb.lb: resource type aws_elb is not allowed`,
+		`error rule "owner-tag": aws_instance.DB: missing tags: Team (Instances must have an owner)`,
+		`warning rule "naming": aws_instance.DB: name "DB" doesn't match "^[a-z][a-z0-9_]*$"`,
+		`error rule "owner-tag": module.child.aws_ebs_volume.data: missing tags: Owner, Team (Instances must have an owner)`,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad:\n\n%s", strings.Join(actual, "\n"))
+	}
+}
@@ -0,0 +1 @@
+resource "aws_ebs_volume" "data" {}
@@ -0,0 +1,28 @@
+resource "aws_instance" "web" {
+  tags {
+    Owner = "ops"
+    Team  = "web"
+  }
+}
+
+resource "aws_instance" "DB" {
+  tags = {
+    Owner = "ops"
+  }
+}
+
+resource "aws_instance" "tagged" {
+  tags = "${var.tags}"
+}
+
+resource "aws_elb" "lb" {}
+
+data "aws_elb" "Existing" {}
+
+variable "tags" {
+  type = "map"
+}
+
+module "child" {
+  source = "./child"
+}
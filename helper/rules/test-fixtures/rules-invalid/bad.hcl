@@ -0,0 +1,3 @@
+rule "bad-name" {
+  name_pattern = "["
+}
@@ -0,0 +1,10 @@
+rule "owner-tag" {
+  description    = "Instances must have an owner"
+  resource_types = ["aws_instance", "aws_ebs_*"]
+  required_tags  = ["Owner", "Team"]
+}
+
+rule "naming" {
+  severity     = "warning"
+  name_pattern = "^[a-z][a-z0-9_]*$"
+}
@@ -92,3 +92,18 @@ func (b *Backend) Configure(c *terraform.ResourceConfig) error {
 func (b *Backend) Config() *ResourceData {
 	return b.config
 }
+
+// SensitiveKeys returns whether the schema of each argument of the backend
+// is marked as Sensitive, by the name of the argument.
+func (b *Backend) SensitiveKeys() map[string]bool {
+	if b == nil {
+		return nil
+	}
+
+	result := make(map[string]bool, len(b.Schema))
+	for k, s := range b.Schema {
+		result[k] = s.Sensitive
+	}
+
+	return result
+}
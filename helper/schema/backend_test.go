@@ -3,6 +3,7 @@ package schema
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/terraform/config"
@@ -109,3 +110,24 @@ func TestBackendConfigure(t *testing.T) {
 		})
 	}
 }
+
+func TestBackendSensitiveKeys(t *testing.T) {
+	b := &Backend{
+		Schema: map[string]*Schema{
+			"host": &Schema{
+				Type:     TypeString,
+				Optional: true,
+			},
+			"client_key": &Schema{
+				Type:      TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+		},
+	}
+
+	expected := map[string]bool{"host": false, "client_key": true}
+	if actual := b.SensitiveKeys(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
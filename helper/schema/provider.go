@@ -44,6 +44,16 @@ type Provider struct {
 	// and must *not* implement Create, Update or Delete.
 	DataSourcesMap map[string]*Resource
 
+	// Features is the schema for the feature flags of this provider. If
+	// this is set, the provider configuration accepts a single "features"
+	// block with these keys, which lets users opt in or out of behaviors
+	// that are risky or that change existing behavior.
+	//
+	// Every feature must be optional, and should have a Default. The value
+	// of each feature can be read with Feature once the provider has been
+	// configured.
+	Features map[string]*Schema
+
 	// ConfigureFunc is a function for configuring the provider. If the
 	// provider doesn't need to be configured, this can be omitted.
 	//
@@ -55,13 +65,16 @@ type Provider struct {
 	// the provider.
 	MetaReset func() error
 
-	meta interface{}
+	meta     interface{}
+	features map[string]interface{}
 
 	// a mutex is required because TestReset can directly repalce the stopCtx
-	stopMu        sync.Mutex
-	stopCtx       context.Context
-	stopCtxCancel context.CancelFunc
-	stopOnce      sync.Once
+	stopMu          sync.Mutex
+	stopCtx         context.Context
+	stopCtxCancel   context.CancelFunc
+	cancelCtx       context.Context
+	cancelCtxCancel context.CancelFunc
+	stopOnce        sync.Once
 }
 
 // ConfigureFunc is the function used to configure a Provider.
@@ -84,11 +97,15 @@ func (p *Provider) InternalValidate() error {
 	}
 
 	var validationErrors error
-	sm := schemaMap(p.Schema)
+	sm := p.schemaMap()
 	if err := sm.InternalValidate(sm); err != nil {
 		validationErrors = multierror.Append(validationErrors, err)
 	}
 
+	if err := p.internalValidateFeatures(); err != nil {
+		validationErrors = multierror.Append(validationErrors, err)
+	}
+
 	for k, r := range p.ResourcesMap {
 		if err := r.InternalValidate(nil, true); err != nil {
 			validationErrors = multierror.Append(validationErrors, fmt.Errorf("resource %s: %s", k, err))
@@ -138,11 +155,28 @@ func (p *Provider) StopContext() context.Context {
 	return p.stopCtx
 }
 
+// CancelContext returns a context that is canceled once Terraform asks the
+// provider to cancel gracefully, or once it's stopped.
+//
+// Unlike on a stop, the requests in progress shouldn't be aborted. Instead,
+// resources that wait on long-running operations can stop waiting and
+// return the state of what they've created so far, along with an error, so
+// that it's recorded. If they don't return in time, the provider is stopped.
+func (p *Provider) CancelContext() context.Context {
+	p.stopOnce.Do(p.stopInit)
+
+	p.stopMu.Lock()
+	defer p.stopMu.Unlock()
+
+	return p.cancelCtx
+}
+
 func (p *Provider) stopInit() {
 	p.stopMu.Lock()
 	defer p.stopMu.Unlock()
 
 	p.stopCtx, p.stopCtxCancel = context.WithCancel(context.Background())
+	p.cancelCtx, p.cancelCtxCancel = context.WithCancel(p.stopCtx)
 }
 
 // Stop implementation of terraform.ResourceProvider interface.
@@ -156,6 +190,18 @@ func (p *Provider) Stop() error {
 	return nil
 }
 
+// Cancel implementation of terraform.ResourceProvider interface. It
+// cancels the CancelContext, but not the StopContext.
+func (p *Provider) Cancel() error {
+	p.stopOnce.Do(p.stopInit)
+
+	p.stopMu.Lock()
+	defer p.stopMu.Unlock()
+
+	p.cancelCtxCancel()
+	return nil
+}
+
 // TestReset resets any state stored in the Provider, and will call TestReset
 // on Meta if it implements the TestProvider interface.
 // This may be used to reset the schema.Provider at the start of a test, and is
@@ -172,7 +218,7 @@ func (p *Provider) TestReset() error {
 func (p *Provider) Input(
 	input terraform.UIInput,
 	c *terraform.ResourceConfig) (*terraform.ResourceConfig, error) {
-	return schemaMap(p.Schema).Input(input, c)
+	return p.schemaMap().Input(input, c)
 }
 
 // Validate implementation of terraform.ResourceProvider interface.
@@ -184,7 +230,7 @@ func (p *Provider) Validate(c *terraform.ResourceConfig) ([]string, []error) {
 				"this bug:\n\n%s", err)}
 	}
 
-	return schemaMap(p.Schema).Validate(c)
+	return p.schemaMap().Validate(c)
 }
 
 // ValidateResource implementation of terraform.ResourceProvider interface.
@@ -202,11 +248,11 @@ func (p *Provider) ValidateResource(
 // Configure implementation of terraform.ResourceProvider interface.
 func (p *Provider) Configure(c *terraform.ResourceConfig) error {
 	// No configuration
-	if p.ConfigureFunc == nil {
+	if p.ConfigureFunc == nil && len(p.Features) == 0 {
 		return nil
 	}
 
-	sm := schemaMap(p.Schema)
+	sm := p.schemaMap()
 
 	// Get a ResourceData for this configuration. To do this, we actually
 	// generate an intermediary "diff" although that is never exposed.
@@ -220,6 +266,12 @@ func (p *Provider) Configure(c *terraform.ResourceConfig) error {
 		return err
 	}
 
+	// Features are read first so that ConfigureFunc can use them
+	p.features = p.readFeatures(data)
+	if p.ConfigureFunc == nil {
+		return nil
+	}
+
 	meta, err := p.ConfigureFunc(data)
 	if err != nil {
 		return err
@@ -267,6 +319,32 @@ func (p *Provider) Refresh(
 	return r.Refresh(s, p.meta)
 }
 
+// WaitForReady implementation of terraform.ResourceProviderReadyWaiter
+// interface.
+func (p *Provider) WaitForReady(
+	info *terraform.InstanceInfo,
+	s *terraform.InstanceState) (*terraform.InstanceState, error) {
+	r, ok := p.ResourcesMap[info.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown resource type: %s", info.Type)
+	}
+
+	return r.waitForReady(s, p.meta)
+}
+
+// LookupExisting implementation of terraform.ResourceProviderAdopter
+// interface.
+func (p *Provider) LookupExisting(
+	info *terraform.InstanceInfo,
+	c *terraform.ResourceConfig) (*terraform.InstanceState, error) {
+	r, ok := p.ResourcesMap[info.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown resource type: %s", info.Type)
+	}
+
+	return r.lookupExisting(c, p.meta)
+}
+
 // Resources implementation of terraform.ResourceProvider interface.
 func (p *Provider) Resources() []terraform.ResourceType {
 	keys := make([]string, 0, len(p.ResourcesMap))
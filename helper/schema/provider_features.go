@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// FeaturesKey is the key of the block in the provider configuration that
+// sets the provider's feature flags.
+const FeaturesKey = "features"
+
+// Feature returns the value of the named feature flag. It returns nil if
+// the provider has no such feature.
+//
+// Until the provider is configured, and for features that aren't set in
+// the configuration, this returns the default of the feature, or the zero
+// value of its type if it has no default.
+func (p *Provider) Feature(name string) interface{} {
+	if v, ok := p.features[name]; ok {
+		return v
+	}
+
+	s, ok := p.Features[name]
+	if !ok {
+		return nil
+	}
+
+	return featureDefault(s)
+}
+
+// FeatureEnabled is a helper for boolean feature flags that reports
+// whether the named feature is enabled.
+func (p *Provider) FeatureEnabled(name string) bool {
+	v, _ := p.Feature(name).(bool)
+	return v
+}
+
+// schemaMap returns the schema of the provider configuration, including
+// the features block if the provider has any features.
+func (p *Provider) schemaMap() schemaMap {
+	if len(p.Features) == 0 {
+		return schemaMap(p.Schema)
+	}
+
+	sm := make(schemaMap, len(p.Schema)+1)
+	for k, v := range p.Schema {
+		sm[k] = v
+	}
+	sm[FeaturesKey] = &Schema{
+		Type:     TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &Resource{
+			Schema: p.Features,
+		},
+	}
+
+	return sm
+}
+
+func (p *Provider) internalValidateFeatures() error {
+	if len(p.Features) == 0 {
+		return nil
+	}
+
+	var errs error
+	if _, ok := p.Schema[FeaturesKey]; ok {
+		errs = multierror.Append(errs, fmt.Errorf(
+			"%s: can't be set in Schema when the provider has Features", FeaturesKey))
+	}
+
+	for k, s := range p.Features {
+		if s.Required {
+			errs = multierror.Append(errs, fmt.Errorf(
+				"%s.%s: features must be optional", FeaturesKey, k))
+		}
+	}
+
+	return errs
+}
+
+// readFeatures reads the value of every feature from the provider
+// configuration, falling back to the defaults for features that aren't set.
+func (p *Provider) readFeatures(d *ResourceData) map[string]interface{} {
+	if len(p.Features) == 0 {
+		return nil
+	}
+
+	// If the block is present, the defaults are already applied
+	set := len(d.Get(FeaturesKey).([]interface{})) > 0
+
+	result := make(map[string]interface{}, len(p.Features))
+	for k, s := range p.Features {
+		if set {
+			result[k] = d.Get(fmt.Sprintf("%s.0.%s", FeaturesKey, k))
+		} else {
+			result[k] = featureDefault(s)
+		}
+	}
+
+	return result
+}
+
+func featureDefault(s *Schema) interface{} {
+	v, err := s.DefaultValue()
+	if err != nil || v == nil {
+		return s.ZeroValue()
+	}
+
+	return v
+}
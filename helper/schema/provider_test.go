@@ -3,6 +3,7 @@ package schema
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +13,8 @@ import (
 
 func TestProvider_impl(t *testing.T) {
 	var _ terraform.ResourceProvider = new(Provider)
+	var _ terraform.ResourceProviderReadyWaiter = new(Provider)
+	var _ terraform.ResourceProviderCanceler = new(Provider)
 }
 
 func TestProviderConfigure(t *testing.T) {
@@ -382,6 +385,42 @@ func TestProviderStop_stopFirst(t *testing.T) {
 	}
 }
 
+func TestProviderCancel(t *testing.T) {
+	var p Provider
+
+	// Cancel it
+	if err := p.Cancel(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	select {
+	case <-p.CancelContext().Done():
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("should be canceled")
+	}
+
+	// Canceling isn't stopping
+	if p.Stopped() {
+		t.Fatal("should not be stopped")
+	}
+}
+
+func TestProviderCancel_stop(t *testing.T) {
+	var p Provider
+	ch := p.CancelContext().Done()
+
+	// Stopping cancels too
+	if err := p.Stop(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	select {
+	case <-ch:
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("should be canceled")
+	}
+}
+
 func TestProviderReset(t *testing.T) {
 	var p Provider
 	stopCtx := p.StopContext()
@@ -407,3 +446,176 @@ func TestProviderReset(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestProviderFeatures(t *testing.T) {
+	newProvider := func() *Provider {
+		return &Provider{
+			Schema: map[string]*Schema{
+				"region": &Schema{
+					Type:     TypeString,
+					Optional: true,
+				},
+			},
+
+			Features: map[string]*Schema{
+				"delete_protection": &Schema{
+					Type:     TypeBool,
+					Optional: true,
+					Default:  true,
+				},
+				"retries": &Schema{
+					Type:     TypeInt,
+					Optional: true,
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		Config   map[string]interface{}
+		Expected map[string]interface{}
+	}{
+		{
+			nil,
+			map[string]interface{}{
+				"delete_protection": true,
+				"retries":           0,
+			},
+		},
+
+		{
+			map[string]interface{}{
+				"features": []interface{}{
+					map[string]interface{}{
+						"retries": 3,
+					},
+				},
+			},
+			map[string]interface{}{
+				"delete_protection": true,
+				"retries":           3,
+			},
+		},
+
+		{
+			map[string]interface{}{
+				"region": "us-east-1",
+				"features": []interface{}{
+					map[string]interface{}{
+						"delete_protection": false,
+					},
+				},
+			},
+			map[string]interface{}{
+				"delete_protection": false,
+				"retries":           0,
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		c, err := config.NewRawConfig(tc.Config)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		rc := terraform.NewResourceConfig(c)
+
+		p := newProvider()
+		if _, es := p.Validate(rc); len(es) > 0 {
+			t.Fatalf("%d: bad: %#v", i, es)
+		}
+
+		var configured bool
+		p.ConfigureFunc = func(d *ResourceData) (interface{}, error) {
+			// Features must be available while configuring
+			configured = true
+			for k, v := range tc.Expected {
+				if actual := p.Feature(k); actual != v {
+					t.Fatalf("%d: bad %s: %#v", i, k, actual)
+				}
+			}
+
+			return nil, nil
+		}
+
+		if err := p.Configure(rc); err != nil {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+		if !configured {
+			t.Fatalf("%d: should configure", i)
+		}
+
+		if p.FeatureEnabled("delete_protection") != tc.Expected["delete_protection"] {
+			t.Fatalf("%d: bad delete_protection", i)
+		}
+	}
+}
+
+func TestProviderFeatures_unknown(t *testing.T) {
+	p := &Provider{
+		Features: map[string]*Schema{
+			"delete_protection": &Schema{
+				Type:     TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+		},
+	}
+
+	c, err := config.NewRawConfig(map[string]interface{}{
+		"features": []interface{}{
+			map[string]interface{}{
+				"delete_protections": false,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, es := p.Validate(terraform.NewResourceConfig(c))
+	if len(es) != 1 {
+		t.Fatalf("bad: %#v", es)
+	}
+	if !strings.Contains(es[0].Error(), "delete_protections") {
+		t.Fatalf("bad: %s", es[0])
+	}
+
+	if p.Feature("nope") != nil {
+		t.Fatal("unknown feature should be nil")
+	}
+}
+
+func TestProviderFeatures_internalValidate(t *testing.T) {
+	cases := map[string]*Provider{
+		"required feature": &Provider{
+			Features: map[string]*Schema{
+				"foo": &Schema{
+					Type:     TypeBool,
+					Required: true,
+				},
+			},
+		},
+
+		"features in schema": &Provider{
+			Schema: map[string]*Schema{
+				"features": &Schema{
+					Type:     TypeString,
+					Optional: true,
+				},
+			},
+			Features: map[string]*Schema{
+				"foo": &Schema{
+					Type:     TypeBool,
+					Optional: true,
+				},
+			},
+		},
+	}
+
+	for name, p := range cases {
+		if err := p.InternalValidate(); err == nil {
+			t.Fatalf("%s: should error", name)
+		}
+	}
+}
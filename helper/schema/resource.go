@@ -84,6 +84,22 @@ type Resource struct {
 	Delete DeleteFunc
 	Exists ExistsFunc
 
+	// WaitForReady is an optional function that waits for a newly created
+	// resource to be ready for use, such as for an instance to pass its
+	// status checks. It is called after Create, but only for resources that
+	// have wait_for_ready set in their lifecycle. It may update the data if
+	// the resource changed while becoming ready, and should honor the
+	// create timeout.
+	WaitForReady WaitForReadyFunc
+
+	// LookupExisting is an optional function that finds an existing
+	// resource that matches the configuration in the data, such as by its
+	// name, and returns its ID, or an empty ID if there isn't one. It is
+	// called during plan for resources that have adopt_existing set in
+	// their lifecycle and aren't in the state yet. The resource is then
+	// read with Read.
+	LookupExisting LookupExistingFunc
+
 	// Importer is the ResourceImporter implementation for this resource.
 	// If this is nil, then this resource does not support importing. If
 	// this is non-nil, then it supports importing and ResourceImporter
@@ -121,6 +137,12 @@ type DeleteFunc func(*ResourceData, interface{}) error
 // See Resource documentation.
 type ExistsFunc func(*ResourceData, interface{}) (bool, error)
 
+// See Resource documentation.
+type WaitForReadyFunc func(*ResourceData, interface{}) error
+
+// See Resource documentation.
+type LookupExistingFunc func(*ResourceData, interface{}) (string, error)
+
 // See Resource documentation.
 type StateMigrateFunc func(
 	int, *terraform.InstanceState, interface{}) (*terraform.InstanceState, error)
@@ -320,6 +342,60 @@ func (r *Resource) Refresh(
 	return r.recordCurrentSchemaVersion(state), err
 }
 
+// waitForReady waits for the newly created resource with the given state to
+// be ready for use, and returns its state once it is.
+func (r *Resource) waitForReady(
+	s *terraform.InstanceState,
+	meta interface{}) (*terraform.InstanceState, error) {
+	if r.WaitForReady == nil {
+		return nil, terraform.ErrWaitForReadyUnsupported
+	}
+
+	rt := ResourceTimeout{}
+	if _, ok := s.Meta[TimeoutKey]; ok {
+		if err := rt.StateDecode(s); err != nil {
+			log.Printf("[ERR] Error decoding ResourceTimeout: %s", err)
+		}
+	}
+
+	data, err := schemaMap(r.Schema).Data(s, nil)
+	if err != nil {
+		return s, err
+	}
+	data.timeouts = &rt
+
+	err = r.WaitForReady(data, meta)
+	return r.recordCurrentSchemaVersion(data.State()), err
+}
+
+// lookupExisting finds an existing resource that matches the given
+// configuration, and returns its state, or nil if there isn't one.
+func (r *Resource) lookupExisting(
+	c *terraform.ResourceConfig,
+	meta interface{}) (*terraform.InstanceState, error) {
+	if r.LookupExisting == nil {
+		return nil, terraform.ErrLookupExistingUnsupported
+	}
+
+	// The data is built the same way as it is for a create, so that the
+	// function sees the configuration with its defaults.
+	diff, err := schemaMap(r.Schema).Diff(nil, c)
+	if err != nil {
+		return nil, err
+	}
+	data, err := schemaMap(r.Schema).Data(nil, diff)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := r.LookupExisting(data, meta)
+	if err != nil || id == "" {
+		return nil, err
+	}
+
+	return r.Refresh(&terraform.InstanceState{ID: id}, meta)
+}
+
 // InternalValidate should be called to validate the structure
 // of the resource.
 //
@@ -339,6 +415,12 @@ func (r *Resource) InternalValidate(topSchemaMap schemaMap, writable bool) error
 		if r.Create != nil || r.Update != nil || r.Delete != nil {
 			return fmt.Errorf("must not implement Create, Update or Delete")
 		}
+		if r.WaitForReady != nil {
+			return fmt.Errorf("must not implement WaitForReady")
+		}
+		if r.LookupExisting != nil {
+			return fmt.Errorf("must not implement LookupExisting")
+		}
 	}
 
 	tsm := topSchemaMap
@@ -707,6 +707,162 @@ func TestResourceRefresh(t *testing.T) {
 	}
 }
 
+func TestResourceWaitForReady(t *testing.T) {
+	r := &Resource{
+		SchemaVersion: 2,
+		Schema: map[string]*Schema{
+			"status": &Schema{
+				Type:     TypeString,
+				Computed: true,
+			},
+		},
+	}
+
+	r.WaitForReady = func(d *ResourceData, m interface{}) error {
+		if m != 42 {
+			return fmt.Errorf("meta not passed")
+		}
+
+		return d.Set("status", "ready")
+	}
+
+	s := &terraform.InstanceState{
+		ID: "bar",
+		Attributes: map[string]string{
+			"status": "pending",
+		},
+	}
+
+	expected := &terraform.InstanceState{
+		ID: "bar",
+		Attributes: map[string]string{
+			"id":     "bar",
+			"status": "ready",
+		},
+		Meta: map[string]interface{}{
+			"schema_version": "2",
+		},
+	}
+
+	actual, err := r.waitForReady(s, 42)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestResourceWaitForReady_unsupported(t *testing.T) {
+	r := &Resource{
+		Schema: map[string]*Schema{
+			"foo": &Schema{
+				Type:     TypeInt,
+				Optional: true,
+			},
+		},
+	}
+
+	s := &terraform.InstanceState{ID: "bar"}
+	if _, err := r.waitForReady(s, nil); err != terraform.ErrWaitForReadyUnsupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestResourceLookupExisting(t *testing.T) {
+	r := &Resource{
+		SchemaVersion: 2,
+		Schema: map[string]*Schema{
+			"name": &Schema{
+				Type:     TypeString,
+				Required: true,
+			},
+			"size": &Schema{
+				Type:     TypeInt,
+				Optional: true,
+				Default:  1,
+			},
+		},
+	}
+
+	r.LookupExisting = func(d *ResourceData, m interface{}) (string, error) {
+		if m != 42 {
+			return "", fmt.Errorf("meta not passed")
+		}
+		if d.Get("size").(int) != 1 {
+			return "", fmt.Errorf("default not set")
+		}
+		if d.Get("name").(string) != "bob" {
+			return "", nil
+		}
+
+		return "bar", nil
+	}
+
+	r.Read = func(d *ResourceData, m interface{}) error {
+		d.Set("name", "bob")
+		d.Set("size", 3)
+		return nil
+	}
+
+	c, err := config.NewRawConfig(map[string]interface{}{"name": "bob"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &terraform.InstanceState{
+		ID: "bar",
+		Attributes: map[string]string{
+			"id":   "bar",
+			"name": "bob",
+			"size": "3",
+		},
+		Meta: map[string]interface{}{
+			"schema_version": "2",
+		},
+	}
+
+	actual, err := r.lookupExisting(terraform.NewResourceConfig(c), 42)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Nothing matches
+	c, err = config.NewRawConfig(map[string]interface{}{"name": "alice"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err = r.lookupExisting(terraform.NewResourceConfig(c), 42)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual != nil {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestResourceLookupExisting_unsupported(t *testing.T) {
+	r := &Resource{
+		Schema: map[string]*Schema{
+			"foo": &Schema{
+				Type:     TypeInt,
+				Optional: true,
+			},
+		},
+	}
+
+	c := terraform.NewResourceConfig(nil)
+	if _, err := r.lookupExisting(c, nil); err != terraform.ErrLookupExistingUnsupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
 func TestResourceRefresh_blankId(t *testing.T) {
 	r := &Resource{
 		Schema: map[string]*Schema{
@@ -517,7 +517,7 @@ func (m schemaMap) Input(
 
 		var value interface{}
 		switch v.Type {
-		case TypeBool, TypeInt, TypeFloat, TypeSet, TypeList:
+		case TypeBool, TypeInt, TypeFloat, TypeSet, TypeList, TypeMap:
 			continue
 		case TypeString:
 			value, err = m.inputString(input, k, v)
@@ -1110,7 +1110,23 @@ func (m schemaMap) inputString(
 	return result, err
 }
 
+// validate validates the attribute k, marking the errors with the path of
+// the attribute they're about, so that it can be pointed out to the user.
 func (m schemaMap) validate(
+	k string,
+	schema *Schema,
+	c *terraform.ResourceConfig) ([]string, []error) {
+	ws, es := m.validateAttribute(k, schema, c)
+	for i, e := range es {
+		if _, ok := e.(*terraform.AttributeError); !ok {
+			es[i] = &terraform.AttributeError{Path: k, Err: e}
+		}
+	}
+
+	return ws, es
+}
+
+func (m schemaMap) validateAttribute(
 	k string,
 	schema *Schema,
 	c *terraform.ResourceConfig) ([]string, []error) {
@@ -1404,8 +1420,14 @@ func (m schemaMap) validateObject(
 				if subk == TimeoutsConfigKey {
 					continue
 				}
-				es = append(es, fmt.Errorf(
-					"%s: invalid or unknown key: %s", k, subk))
+				key := subk
+				if k != "" {
+					key = fmt.Sprintf("%s.%s", k, subk)
+				}
+				es = append(es, &terraform.AttributeError{
+					Path: key,
+					Err:  fmt.Errorf("%s: invalid or unknown key: %s", k, subk),
+				})
 			}
 		}
 	}
@@ -2054,7 +2054,7 @@ func TestSchemaMap_Diff(t *testing.T) {
 
 			State: &terraform.InstanceState{
 				Attributes: map[string]string{
-					"block_device.#":                                "2",
+					"block_device.#": "2",
 					"block_device.616397234.delete_on_termination":  "true",
 					"block_device.616397234.device_name":            "/dev/sda1",
 					"block_device.2801811477.delete_on_termination": "true",
@@ -2990,6 +2990,32 @@ func TestSchemaMap_Input(t *testing.T) {
 			Result: map[string]interface{}{},
 			Err:    false,
 		},
+
+		"no input for required map and nested block": {
+			Schema: map[string]*Schema{
+				"endpoints": &Schema{
+					Type:     TypeMap,
+					Required: true,
+				},
+				"assume_role": &Schema{
+					Type:     TypeList,
+					Required: true,
+					MaxItems: 1,
+					Elem: &Resource{
+						Schema: map[string]*Schema{
+							"role_arn": &Schema{
+								Type:     TypeString,
+								Optional: true,
+							},
+						},
+					},
+				},
+			},
+
+			Input:  map[string]string{},
+			Result: map[string]interface{}{},
+			Err:    false,
+		},
 	}
 
 	for i, tc := range cases {
@@ -4018,6 +4044,12 @@ func TestSchemaMap_Validate(t *testing.T) {
 			},
 
 			Err: true,
+			Errors: []error{
+				&terraform.AttributeError{
+					Path: "foo",
+					Err:  fmt.Errorf(": invalid or unknown key: foo"),
+				},
+			},
 		},
 
 		"Invalid/unknown field with computed value": {
@@ -4312,7 +4344,10 @@ func TestSchemaMap_Validate(t *testing.T) {
 
 			Err: true,
 			Errors: []error{
-				fmt.Errorf("\"long_gone\": [REMOVED] no longer supported by Cloud API"),
+				&terraform.AttributeError{
+					Path: "long_gone",
+					Err:  fmt.Errorf("\"long_gone\": [REMOVED] no longer supported by Cloud API"),
+				},
 			},
 		},
 
@@ -4348,7 +4383,10 @@ func TestSchemaMap_Validate(t *testing.T) {
 
 			Err: true,
 			Errors: []error{
-				fmt.Errorf("\"blacklist\": conflicts with whitelist (\"white-val\")"),
+				&terraform.AttributeError{
+					Path: "blacklist",
+					Err:  fmt.Errorf("\"blacklist\": conflicts with whitelist (\"white-val\")"),
+				},
 			},
 		},
 
@@ -4392,7 +4430,10 @@ func TestSchemaMap_Validate(t *testing.T) {
 
 			Err: true,
 			Errors: []error{
-				fmt.Errorf(`"optional_att": conflicts with required_att ("required-val")`),
+				&terraform.AttributeError{
+					Path: "optional_att",
+					Err:  fmt.Errorf(`"optional_att": conflicts with required_att ("required-val")`),
+				},
 			},
 		},
 
@@ -4419,8 +4460,14 @@ func TestSchemaMap_Validate(t *testing.T) {
 
 			Err: true,
 			Errors: []error{
-				fmt.Errorf(`"foo_att": conflicts with bar_att ("bar-val")`),
-				fmt.Errorf(`"bar_att": conflicts with foo_att ("foo-val")`),
+				&terraform.AttributeError{
+					Path: "foo_att",
+					Err:  fmt.Errorf(`"foo_att": conflicts with bar_att ("bar-val")`),
+				},
+				&terraform.AttributeError{
+					Path: "bar_att",
+					Err:  fmt.Errorf(`"bar_att": conflicts with foo_att ("foo-val")`),
+				},
 			},
 		},
 
@@ -4500,7 +4547,42 @@ func TestSchemaMap_Validate(t *testing.T) {
 			},
 			Err: true,
 			Errors: []error{
-				fmt.Errorf(`something is not right here`),
+				&terraform.AttributeError{
+					Path: "validate_me",
+					Err:  fmt.Errorf(`something is not right here`),
+				},
+			},
+		},
+
+		"Errors of nested attributes have their path": {
+			Schema: map[string]*Schema{
+				"ingress": &Schema{
+					Type:     TypeList,
+					Optional: true,
+					Elem: &Resource{
+						Schema: map[string]*Schema{
+							"from": &Schema{
+								Type:     TypeInt,
+								Required: true,
+							},
+						},
+					},
+				},
+			},
+			Config: map[string]interface{}{
+				"ingress": []interface{}{
+					map[string]interface{}{
+						"from": 80,
+					},
+					map[string]interface{}{},
+				},
+			},
+			Err: true,
+			Errors: []error{
+				&terraform.AttributeError{
+					Path: "ingress.1.from",
+					Err:  fmt.Errorf(`"ingress.1.from": required field is not set`),
+				},
 			},
 		},
 
@@ -4816,7 +4898,10 @@ func TestSchemaSet_ValidateMaxItems(t *testing.T) {
 			Diff: nil,
 			Err:  true,
 			Errors: []error{
-				fmt.Errorf("aliases: attribute supports 1 item maximum, config has 2 declared"),
+				&terraform.AttributeError{
+					Path: "aliases",
+					Err:  fmt.Errorf("aliases: attribute supports 1 item maximum, config has 2 declared"),
+				},
 			},
 		},
 		"#1": {
@@ -4940,7 +5025,10 @@ func TestSchemaSet_ValidateMinItems(t *testing.T) {
 			Diff: nil,
 			Err:  true,
 			Errors: []error{
-				fmt.Errorf("aliases: attribute supports 2 item as a minimum, config has 1 declared"),
+				&terraform.AttributeError{
+					Path: "aliases",
+					Err:  fmt.Errorf("aliases: attribute supports 2 item as a minimum, config has 1 declared"),
+				},
 			},
 		},
 	}
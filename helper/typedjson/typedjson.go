@@ -0,0 +1,262 @@
+// Package typedjson encodes the dynamically-typed values that are found in
+// configuration, variables and diffs as JSON, along with their Go types, so
+// that decoding them gives back values of exactly the same types.
+//
+// Plain JSON can't be used for these values since it doesn't distinguish
+// integers from floats, or a list of maps from a list of anything else,
+// and Terraform and providers rely on those distinctions.
+package typedjson
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The types of values that can be encoded. Only Go primitives and the
+// collections that configuration and helper/schema produce are supported.
+const (
+	typeNull         = "null"
+	typeString       = "string"
+	typeBool         = "bool"
+	typeInt          = "int"
+	typeInt64        = "int64"
+	typeFloat        = "float"
+	typeList         = "list"
+	typeListOfString = "list_of_strings"
+	typeListOfMap    = "list_of_maps"
+	typeMap          = "map"
+	typeMapOfString  = "map_of_strings"
+)
+
+// Value is a value that is encoded as JSON along with its type.
+type Value struct {
+	V interface{}
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	t, err := encode(v.V)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(t)
+}
+
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var t typed
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+
+	result, err := decode(&t)
+	if err != nil {
+		return err
+	}
+
+	v.V = result
+	return nil
+}
+
+// Map is a map of values that are each encoded as JSON along with their
+// types. A nil Map is encoded as null.
+type Map map[string]interface{}
+
+func (m Map) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+
+	result, err := encodeMap(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(result)
+}
+
+func (m *Map) UnmarshalJSON(data []byte) error {
+	var raw map[string]*typed
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*m = nil
+		return nil
+	}
+
+	result, err := decodeMap(raw)
+	if err != nil {
+		return err
+	}
+
+	*m = result
+	return nil
+}
+
+// typed is the JSON form of a value.
+type typed struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+func encode(v interface{}) (*typed, error) {
+	var t string
+	value := v
+	switch v := v.(type) {
+	case nil:
+		return &typed{Type: typeNull}, nil
+	case string:
+		t = typeString
+	case bool:
+		t = typeBool
+	case int:
+		t = typeInt
+	case int64:
+		t = typeInt64
+	case float64:
+		t = typeFloat
+	case []string:
+		t = typeListOfString
+	case map[string]string:
+		t = typeMapOfString
+	case []interface{}:
+		t = typeList
+		result := make([]*typed, len(v))
+		for i, elem := range v {
+			var err error
+			if result[i], err = encode(elem); err != nil {
+				return nil, err
+			}
+		}
+		value = result
+	case []map[string]interface{}:
+		t = typeListOfMap
+		result := make([]map[string]*typed, len(v))
+		for i, elem := range v {
+			var err error
+			if result[i], err = encodeMap(elem); err != nil {
+				return nil, err
+			}
+		}
+		value = result
+	case map[string]interface{}:
+		t = typeMap
+		result, err := encodeMap(v)
+		if err != nil {
+			return nil, err
+		}
+		value = result
+	default:
+		return nil, fmt.Errorf("can't encode a value of type %T", v)
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &typed{Type: t, Value: raw}, nil
+}
+
+func encodeMap(m map[string]interface{}) (map[string]*typed, error) {
+	result := make(map[string]*typed, len(m))
+	for k, v := range m {
+		t, err := encode(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", k, err)
+		}
+
+		result[k] = t
+	}
+
+	return result, nil
+}
+
+func decode(t *typed) (interface{}, error) {
+	if t == nil {
+		return nil, nil
+	}
+
+	var err error
+	switch t.Type {
+	case typeNull:
+		return nil, nil
+	case typeString:
+		var v string
+		err = json.Unmarshal(t.Value, &v)
+		return v, err
+	case typeBool:
+		var v bool
+		err = json.Unmarshal(t.Value, &v)
+		return v, err
+	case typeInt:
+		var v int
+		err = json.Unmarshal(t.Value, &v)
+		return v, err
+	case typeInt64:
+		var v int64
+		err = json.Unmarshal(t.Value, &v)
+		return v, err
+	case typeFloat:
+		var v float64
+		err = json.Unmarshal(t.Value, &v)
+		return v, err
+	case typeListOfString:
+		var v []string
+		err = json.Unmarshal(t.Value, &v)
+		return v, err
+	case typeMapOfString:
+		var v map[string]string
+		err = json.Unmarshal(t.Value, &v)
+		return v, err
+	case typeList:
+		var raw []*typed
+		if err := json.Unmarshal(t.Value, &raw); err != nil {
+			return nil, err
+		}
+
+		result := make([]interface{}, len(raw))
+		for i, elem := range raw {
+			if result[i], err = decode(elem); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case typeListOfMap:
+		var raw []map[string]*typed
+		if err := json.Unmarshal(t.Value, &raw); err != nil {
+			return nil, err
+		}
+
+		result := make([]map[string]interface{}, len(raw))
+		for i, elem := range raw {
+			if result[i], err = decodeMap(elem); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case typeMap:
+		var raw map[string]*typed
+		if err := json.Unmarshal(t.Value, &raw); err != nil {
+			return nil, err
+		}
+
+		return decodeMap(raw)
+	default:
+		return nil, fmt.Errorf("unknown value type %q", t.Type)
+	}
+}
+
+func decodeMap(m map[string]*typed) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(m))
+	for k, t := range m {
+		v, err := decode(t)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", k, err)
+		}
+
+		result[k] = v
+	}
+
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package typedjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValue(t *testing.T) {
+	cases := []interface{}{
+		nil,
+		"foo",
+		true,
+		42,
+		int64(1800000000000),
+		3.5,
+		float64(2),
+		[]string{"a", "b"},
+		map[string]string{"a": "b"},
+		[]interface{}{1, "two", 3.0, []interface{}{}},
+		[]map[string]interface{}{
+			{"from_port": 80, "cidr_blocks": []interface{}{"0.0.0.0/0"}},
+		},
+		map[string]interface{}{
+			"nested": map[string]interface{}{"count": 2},
+			"null":   nil,
+		},
+	}
+
+	for _, tc := range cases {
+		data, err := json.Marshal(Value{V: tc})
+		if err != nil {
+			t.Fatalf("%#v: err: %s", tc, err)
+		}
+
+		var actual Value
+		if err := json.Unmarshal(data, &actual); err != nil {
+			t.Fatalf("%#v: err: %s", tc, err)
+		}
+
+		if !reflect.DeepEqual(actual.V, tc) {
+			t.Fatalf("%s\n\nbad: %#v\nexpected: %#v", data, actual.V, tc)
+		}
+	}
+}
+
+func TestValue_unsupported(t *testing.T) {
+	if _, err := json.Marshal(Value{V: struct{}{}}); err == nil {
+		t.Fatal("should error")
+	}
+
+	var v Value
+	if err := json.Unmarshal([]byte(`{"type":"complex"}`), &v); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestMap(t *testing.T) {
+	cases := []Map{
+		nil,
+		Map{},
+		Map{"foo": []interface{}{1, 2, 3}, "bar": "baz"},
+	}
+
+	for _, tc := range cases {
+		data, err := json.Marshal(tc)
+		if err != nil {
+			t.Fatalf("%#v: err: %s", tc, err)
+		}
+
+		var actual Map
+		if err := json.Unmarshal(data, &actual); err != nil {
+			t.Fatalf("%#v: err: %s", tc, err)
+		}
+
+		if !reflect.DeepEqual(actual, tc) {
+			t.Fatalf("%s\n\nbad: %#v\nexpected: %#v", data, actual, tc)
+		}
+	}
+}
@@ -0,0 +1,542 @@
+// Package vault reads credentials from HashiCorp Vault for the CLI, and
+// keeps their leases renewed for as long as Terraform runs.
+//
+// Dynamic credentials, such as those of the AWS secrets backend, are only
+// valid for the TTL of their lease. Without renewal, an apply that takes
+// longer than the TTL fails partway through the walk once the credentials
+// expire. The client renews each renewable lease, and its own token, when
+// two thirds of the TTL have passed, up to the maximum TTL that Vault
+// allows. Secrets whose leases can't be renewed any further, such as STS
+// credentials, are read again before they expire.
+//
+// Secrets that are read again reach the providers and the backend that
+// are configured with them through ProviderCredentials and
+// BackendCredentials, which report a new generation so that they can be
+// configured again. Environment variables set by SetEnv are set again too,
+// but only reach the plugins started after that.
+package vault
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// The auth methods that the client can log in with.
+const (
+	AuthToken   = "token"
+	AuthAppRole = "approle"
+)
+
+// Config is the configuration of the Vault client, from the vault block of
+// the CLI configuration.
+type Config struct {
+	// Address is the address of the Vault server. VAULT_ADDR is used if
+	// it's empty.
+	Address string `hcl:"address"`
+
+	// AuthMethod is the method to log in with: AuthToken, the default, or
+	// AuthAppRole.
+	AuthMethod string `hcl:"auth_method"`
+
+	// Token is the token for AuthToken. VAULT_TOKEN is used if it's empty.
+	Token string `hcl:"token"`
+
+	// RoleID and SecretID are the credentials for AuthAppRole.
+	RoleID   string `hcl:"role_id"`
+	SecretID string `hcl:"secret_id"`
+
+	// Env maps the names of environment variables to the secrets to set
+	// them to, as "PATH#FIELD", such as "aws/creds/deploy#access_key".
+	Env map[string]string `hcl:"env"`
+
+	// Provider maps the types of providers, such as "aws", to the
+	// arguments to configure them with and the secrets to set them to.
+	// Unlike environment variables, the arguments are passed to providers
+	// that are already running whenever their secrets are read again.
+	Provider map[string]map[string]string `hcl:"provider"`
+
+	// Backend maps the arguments to configure the backend with to the
+	// secrets to set them to.
+	Backend map[string]string `hcl:"backend"`
+}
+
+// Validate checks the configuration for errors.
+func (c *Config) Validate() error {
+	switch c.AuthMethod {
+	case "", AuthToken:
+	case AuthAppRole:
+		if c.RoleID == "" {
+			return fmt.Errorf("role_id must be set for the %q auth method", AuthAppRole)
+		}
+	default:
+		return fmt.Errorf("unknown auth method %q", c.AuthMethod)
+	}
+
+	for k, v := range c.Env {
+		if _, _, err := ParseRef(v); err != nil {
+			return fmt.Errorf("env %s: %s", k, err)
+		}
+	}
+	for typ, args := range c.Provider {
+		for k, v := range args {
+			if _, _, err := ParseRef(v); err != nil {
+				return fmt.Errorf("provider %s: %s: %s", typ, k, err)
+			}
+		}
+	}
+	for k, v := range c.Backend {
+		if _, _, err := ParseRef(v); err != nil {
+			return fmt.Errorf("backend %s: %s", k, err)
+		}
+	}
+
+	return nil
+}
+
+// ParseRef splits a reference to a secret, as "PATH#FIELD", into its path
+// and field.
+func ParseRef(ref string) (string, string, error) {
+	i := strings.LastIndex(ref, "#")
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", fmt.Errorf("%q must be a secret path and field, like \"PATH#FIELD\"", ref)
+	}
+
+	return strings.Trim(ref[:i], "/"), ref[i+1:], nil
+}
+
+// Default is the client that the CLI sets up from its configuration, or
+// nil if Vault isn't configured. It's used by the parts of Terraform that
+// read credentials from Vault themselves, such as the credentials store
+// that backends and module downloads get their tokens from, and the
+// commands that configure providers and backends with credentials.
+var Default *Client
+
+// Client reads secrets from Vault, and renews their leases in the
+// background until it's stopped.
+type Client struct {
+	client *api.Client
+
+	// secrets are the secrets that have been read, by path, so that the
+	// fields of a secret, such as an access key and its secret key, come
+	// from the same lease.
+	mu      sync.Mutex
+	secrets map[string]*api.Secret
+
+	// env are the environment variables that SetEnv set, with the fields
+	// they were set to, by the path of their secret. They're set again when
+	// the secret is read again.
+	env map[string]map[string]string
+
+	// generation is incremented whenever a secret is read again.
+	generation uint64
+
+	// providers and backend are the arguments from the provider and
+	// backend blocks of the configuration.
+	providers map[string]map[string]string
+	backend   map[string]string
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewClient returns a client that's logged in to Vault with c.
+func NewClient(c *Config) (*Client, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	config := api.DefaultConfig()
+	if err := config.ReadEnvironment(); err != nil {
+		return nil, err
+	}
+	if c.Address != "" {
+		config.Address = c.Address
+	}
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Client{
+		client:    client,
+		secrets:   make(map[string]*api.Secret),
+		env:       make(map[string]map[string]string),
+		providers: c.Provider,
+		backend:   c.Backend,
+		stopCh:    make(chan struct{}),
+	}
+
+	switch c.AuthMethod {
+	case AuthAppRole:
+		secret, err := client.Logical().Write("auth/approle/login", map[string]interface{}{
+			"role_id":   c.RoleID,
+			"secret_id": c.SecretID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error logging in with %s: %s", AuthAppRole, err)
+		}
+		if secret == nil || secret.Auth == nil {
+			return nil, fmt.Errorf("error logging in with %s: no token returned", AuthAppRole)
+		}
+
+		client.SetToken(secret.Auth.ClientToken)
+		if secret.Auth.Renewable {
+			result.renewToken(secret.Auth.LeaseDuration)
+		}
+	default:
+		if c.Token != "" {
+			client.SetToken(c.Token)
+		}
+		if client.Token() == "" {
+			return nil, fmt.Errorf("no token: set token or VAULT_TOKEN")
+		}
+
+		// A token that was created elsewhere may not be renewable, in
+		// which case it's left to expire.
+		secret, err := client.Auth().Token().LookupSelf()
+		if err != nil {
+			return nil, fmt.Errorf("error looking up the token: %s", err)
+		}
+		if renewable, _ := secret.Data["renewable"].(bool); renewable {
+			if ttl, err := secretTTL(secret); err == nil && ttl > 0 {
+				result.renewToken(ttl)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// secretTTL returns the TTL of the token described by the result of a
+// token lookup, in seconds.
+func secretTTL(secret *api.Secret) (int, error) {
+	switch v := secret.Data["ttl"].(type) {
+	case int:
+		return v, nil
+	case float64:
+		return int(v), nil
+	case interface {
+		Int64() (int64, error)
+	}:
+		n, err := v.Int64()
+		return int(n), err
+	}
+
+	return 0, fmt.Errorf("token has no TTL")
+}
+
+// Read returns the given field of the secret at the given path. The secret
+// is kept valid until the client is stopped, see keep, so the value
+// returned can change when the secret is read again.
+func (c *Client) Read(path, field string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	secret, ok := c.secrets[path]
+	if !ok {
+		var err error
+		secret, err = c.read(path)
+		if err != nil {
+			return "", err
+		}
+		c.secrets[path] = secret
+		c.keep(path, secret)
+	}
+
+	v, ok := secret.Data[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %q", path, field)
+	}
+
+	return fmt.Sprint(v), nil
+}
+
+// ReadRef returns the secret that ref refers to, as "PATH#FIELD".
+func (c *Client) ReadRef(ref string) (string, error) {
+	path, field, err := ParseRef(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return c.Read(path, field)
+}
+
+// ProviderCredentials returns the arguments from the provider block of the
+// configuration for providers of the given type, set to their secrets, and
+// the generation of the secrets. The generation changes whenever a secret
+// is read again, so that providers that were configured with an older one
+// can be configured again.
+func (c *Client) ProviderCredentials(typ string) (map[string]string, uint64, error) {
+	return c.readArgs(c.providers[typ])
+}
+
+// BackendCredentials returns the arguments from the backend block of the
+// configuration, set to their secrets, and the generation of the secrets,
+// as ProviderCredentials does.
+func (c *Client) BackendCredentials() (map[string]string, uint64, error) {
+	return c.readArgs(c.backend)
+}
+
+// readArgs returns args with each reference to a secret replaced with the
+// secret, and the generation of the secrets.
+func (c *Client) readArgs(args map[string]string) (map[string]string, uint64, error) {
+	// The generation is taken first, so that a secret that's read again
+	// in the meantime is only reported by the next call.
+	c.mu.Lock()
+	gen := c.generation
+	c.mu.Unlock()
+
+	if len(args) == 0 {
+		return nil, gen, nil
+	}
+
+	result := make(map[string]string, len(args))
+	for k, ref := range args {
+		v, err := c.ReadRef(ref)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %s", k, err)
+		}
+		result[k] = v
+	}
+
+	return result, gen, nil
+}
+
+// read reads the secret at path from Vault.
+func (c *Client) read(path string) (*api.Secret, error) {
+	secret, err := c.client.Logical().Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", path, err)
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("no secret at %s", path)
+	}
+
+	return secret, nil
+}
+
+// keep keeps the secret at path valid until the client is stopped. Its
+// lease is renewed for as long as Vault allows, and the secret is read
+// again before the lease expires after that, or right away if the lease
+// can't be renewed at all. The lock must be held.
+func (c *Client) keep(path string, secret *api.Secret) {
+	switch {
+	case secret.LeaseID == "":
+	case secret.Renewable:
+		c.renewLease(path, secret.LeaseID, secret.LeaseDuration)
+	default:
+		log.Printf(
+			"[DEBUG] vault: the lease of %s isn't renewable, so it's read again before it expires in %s",
+			path, time.Duration(secret.LeaseDuration)*time.Second)
+		c.reread(path, secret.LeaseDuration)
+	}
+}
+
+// reread reads the secret at path again when two thirds of ttl have
+// passed, and keeps the new secret valid in turn. The generation is
+// incremented, and the environment variables that SetEnv set from the
+// secret are set again.
+func (c *Client) reread(path string, ttl int) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		for {
+			select {
+			case <-c.stopCh:
+				return
+			case <-time.After(renewAfter(ttl)):
+			}
+
+			secret, err := c.read(path)
+			if err != nil {
+				log.Printf("[WARN] vault: error reading %s again: %s", path, err)
+				ttl = ttl / 3
+				if ttl < 1 {
+					log.Printf("[ERROR] vault: the lease of %s has expired", path)
+					return
+				}
+				continue
+			}
+
+			log.Printf("[INFO] vault: read %s again, since its lease is expiring", path)
+
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			c.secrets[path] = secret
+			c.generation++
+			c.setEnv(path, secret)
+			c.keep(path, secret)
+			return
+		}
+	}()
+}
+
+// setEnv sets the environment variables that SetEnv set from the secret
+// at path again. The lock must be held.
+func (c *Client) setEnv(path string, secret *api.Secret) {
+	for k, field := range c.env[path] {
+		v, ok := secret.Data[field]
+		if !ok {
+			log.Printf("[WARN] vault: secret %s has no field %q anymore", path, field)
+			continue
+		}
+
+		if err := os.Setenv(k, fmt.Sprint(v)); err != nil {
+			log.Printf("[WARN] vault: error setting %s: %s", k, err)
+		}
+	}
+}
+
+// SetEnv sets each environment variable in env to the secret it refers
+// to. Variables that are already set are left alone, so that they can
+// still be overridden. The variables are set again whenever their secret
+// is read again, so that the plugins started after that get the new
+// credentials. Plugins that are already running don't.
+func (c *Client) SetEnv(env map[string]string) error {
+	names := make([]string, 0, len(env))
+	for k := range env {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		if _, ok := os.LookupEnv(k); ok {
+			log.Printf("[DEBUG] %s is already set, not reading it from Vault", k)
+			continue
+		}
+
+		path, field, err := ParseRef(env[k])
+		if err != nil {
+			return fmt.Errorf("%s: %s", k, err)
+		}
+		v, err := c.Read(path, field)
+		if err != nil {
+			return fmt.Errorf("%s: %s", k, err)
+		}
+
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+
+		c.mu.Lock()
+		if c.env[path] == nil {
+			c.env[path] = make(map[string]string)
+		}
+		c.env[path][k] = field
+		c.mu.Unlock()
+		log.Printf("[INFO] Set %s from Vault", k)
+	}
+
+	return nil
+}
+
+// Stop stops renewing leases. The leases aren't revoked, so credentials
+// that are still in use, such as by other processes, stay valid until
+// they expire.
+func (c *Client) Stop() {
+	c.stopOnce.Do(func() { close(c.stopCh) })
+	c.wg.Wait()
+}
+
+// renewLease renews the lease with the given ID, which has ttl seconds
+// left, in the background. Once it can't be renewed any further, the
+// secret at path is read again.
+func (c *Client) renewLease(path, id string, ttl int) {
+	c.renew("lease of "+path, ttl, func(increment int) (int, error) {
+		secret, err := c.client.Sys().Renew(id, increment)
+		if err != nil {
+			return 0, err
+		}
+		return secret.LeaseDuration, nil
+	}, func(ttl int) {
+		c.reread(path, ttl)
+	})
+}
+
+// renewToken renews the token of the client, which has ttl seconds left,
+// in the background.
+func (c *Client) renewToken(ttl int) {
+	c.renew("token", ttl, func(increment int) (int, error) {
+		secret, err := c.client.Auth().Token().RenewSelf(increment)
+		if err != nil {
+			return 0, err
+		}
+		if secret.Auth == nil {
+			return 0, fmt.Errorf("no token returned")
+		}
+		return secret.Auth.LeaseDuration, nil
+	}, nil)
+}
+
+// renew calls renew when two thirds of ttl have passed, with the original
+// TTL as the increment, until the client is stopped or the lease can't be
+// extended any further. Then expiring, if it isn't nil, is called with the
+// seconds the lease has left.
+func (c *Client) renew(name string, ttl int, renew func(int) (int, error), expiring func(int)) {
+	increment := ttl
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		for {
+			select {
+			case <-c.stopCh:
+				return
+			case <-time.After(renewAfter(ttl)):
+			}
+
+			newTTL, err := renew(increment)
+			if err != nil {
+				// The lease may still be valid for a while, so it's tried
+				// again until it isn't.
+				log.Printf("[WARN] vault: error renewing the %s: %s", name, err)
+				ttl = ttl / 3
+				if ttl < 1 {
+					log.Printf("[ERROR] vault: the %s has expired", name)
+					if expiring != nil {
+						expiring(0)
+					}
+					return
+				}
+				continue
+			}
+
+			log.Printf("[DEBUG] vault: renewed the %s for %ds", name, newTTL)
+			if newTTL < increment {
+				// Vault capped the lease at its maximum TTL, so renewing
+				// it again won't extend it.
+				if expiring != nil {
+					log.Printf(
+						"[DEBUG] vault: the %s has reached its maximum TTL, and expires in %s",
+						name, time.Duration(newTTL)*time.Second)
+					expiring(newTTL)
+					return
+				}
+
+				log.Printf(
+					"[WARN] vault: the %s has reached its maximum TTL, and expires in %s",
+					name, time.Duration(newTTL)*time.Second)
+				return
+			}
+			ttl = newTTL
+		}
+	}()
+}
+
+// renewAfter returns how long to wait before renewing a lease with ttl
+// seconds left.
+func renewAfter(ttl int) time.Duration {
+	return time.Duration(ttl) * time.Second * 2 / 3
+}
@@ -0,0 +1,357 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeVault is a Vault server with an approle login, and the AWS secrets
+// backend, whose leases last a second.
+type fakeVault struct {
+	mu      sync.Mutex
+	reads   int
+	renewCh chan string
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var resp map[string]interface{}
+	switch r.URL.Path {
+	case "/v1/auth/approle/login":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["role_id"] != "deploy" || body["secret_id"] != "s3cret" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		resp = map[string]interface{}{
+			"auth": map[string]interface{}{
+				"client_token":   "token",
+				"lease_duration": 3600,
+				"renewable":      true,
+			},
+		}
+	case "/v1/aws/creds/deploy":
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		v.reads++
+		resp = map[string]interface{}{
+			"lease_id":       "aws/creds/deploy/1",
+			"lease_duration": 1,
+			"renewable":      true,
+			"data": map[string]interface{}{
+				"access_key": "AKIA",
+				"secret_key": "secret",
+			},
+		}
+	case "/v1/aws/sts/deploy":
+		// STS credentials can't be renewed, so they're new on every read
+		v.reads++
+		resp = map[string]interface{}{
+			"lease_id":       fmt.Sprintf("aws/sts/deploy/%d", v.reads),
+			"lease_duration": 1,
+			"renewable":      false,
+			"data": map[string]interface{}{
+				"access_key": fmt.Sprintf("ASIA%d", v.reads),
+			},
+		}
+	case "/v1/sys/renew":
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		id, _ := body["lease_id"].(string)
+		select {
+		case v.renewCh <- id:
+		default:
+		}
+		resp = map[string]interface{}{
+			"lease_id":       id,
+			"lease_duration": 1,
+			"renewable":      true,
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors": []}`))
+		return
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// testClient returns a client of a fakeVault, and a function that stops
+// them both.
+func testClient(t *testing.T) (*Client, *fakeVault, func()) {
+	v := &fakeVault{renewCh: make(chan string, 1)}
+	srv := httptest.NewServer(v)
+
+	c, err := NewClient(&Config{
+		Address:    srv.URL,
+		AuthMethod: AuthAppRole,
+		RoleID:     "deploy",
+		SecretID:   "s3cret",
+	})
+	if err != nil {
+		srv.Close()
+		t.Fatalf("err: %s", err)
+	}
+
+	return c, v, func() {
+		c.Stop()
+		srv.Close()
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cases := map[string]struct {
+		Config *Config
+		Err    bool
+	}{
+		"token": {
+			&Config{Env: map[string]string{"FOO": "secret/foo#value"}},
+			false,
+		},
+		"approle": {
+			&Config{AuthMethod: AuthAppRole, RoleID: "deploy"},
+			false,
+		},
+		"approle without role": {
+			&Config{AuthMethod: AuthAppRole},
+			true,
+		},
+		"unknown auth method": {
+			&Config{AuthMethod: "github"},
+			true,
+		},
+		"no field": {
+			&Config{Env: map[string]string{"FOO": "secret/foo"}},
+			true,
+		},
+		"empty field": {
+			&Config{Env: map[string]string{"FOO": "secret/foo#"}},
+			true,
+		},
+		"provider": {
+			&Config{Provider: map[string]map[string]string{
+				"aws": {"access_key": "aws/creds/deploy#access_key"},
+			}},
+			false,
+		},
+		"provider without field": {
+			&Config{Provider: map[string]map[string]string{
+				"aws": {"access_key": "aws/creds/deploy"},
+			}},
+			true,
+		},
+		"backend without field": {
+			&Config{Backend: map[string]string{"access_key": "aws/creds/deploy"}},
+			true,
+		},
+	}
+
+	for name, tc := range cases {
+		err := tc.Config.Validate()
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: err: %v", name, err)
+		}
+	}
+}
+
+func TestNewClient_appRoleDenied(t *testing.T) {
+	srv := httptest.NewServer(new(fakeVault))
+	defer srv.Close()
+
+	_, err := NewClient(&Config{
+		Address:    srv.URL,
+		AuthMethod: AuthAppRole,
+		RoleID:     "deploy",
+		SecretID:   "wrong",
+	})
+	if err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestClientSetEnv(t *testing.T) {
+	c, v, closer := testClient(t)
+	defer closer()
+
+	defer os.Unsetenv("TFTEST_VAULT_KEY")
+	defer os.Unsetenv("TFTEST_VAULT_SECRET")
+	defer os.Unsetenv("TFTEST_VAULT_SET")
+	os.Unsetenv("TFTEST_VAULT_KEY")
+	os.Unsetenv("TFTEST_VAULT_SECRET")
+	os.Setenv("TFTEST_VAULT_SET", "env")
+
+	err := c.SetEnv(map[string]string{
+		"TFTEST_VAULT_KEY":    "aws/creds/deploy#access_key",
+		"TFTEST_VAULT_SECRET": "aws/creds/deploy#secret_key",
+		"TFTEST_VAULT_SET":    "aws/creds/deploy#secret_key",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if v := os.Getenv("TFTEST_VAULT_KEY"); v != "AKIA" {
+		t.Fatalf("bad: %q", v)
+	}
+	if v := os.Getenv("TFTEST_VAULT_SECRET"); v != "secret" {
+		t.Fatalf("bad: %q", v)
+	}
+
+	// Variables that are already set aren't replaced
+	if v := os.Getenv("TFTEST_VAULT_SET"); v != "env" {
+		t.Fatalf("bad: %q", v)
+	}
+
+	// Both fields come from the same lease
+	v.mu.Lock()
+	reads := v.reads
+	v.mu.Unlock()
+	if reads != 1 {
+		t.Fatalf("secret read %d times", reads)
+	}
+
+	os.Unsetenv("TFTEST_VAULT_KEY")
+	if err := c.SetEnv(map[string]string{"TFTEST_VAULT_KEY": "aws/creds/deploy#missing"}); err == nil {
+		t.Fatal("should error")
+	}
+	if err := c.SetEnv(map[string]string{"TFTEST_VAULT_KEY": "aws/creds/other#access_key"}); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestClientRenew(t *testing.T) {
+	c, v, closer := testClient(t)
+	defer closer()
+
+	if _, err := c.Read("aws/creds/deploy", "access_key"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	select {
+	case id := <-v.renewCh:
+		if id != "aws/creds/deploy/1" {
+			t.Fatalf("bad: %q", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease wasn't renewed")
+	}
+
+	// Stopping waits for the renewals to stop
+	c.Stop()
+}
+
+func TestClientReread(t *testing.T) {
+	c, v, closer := testClient(t)
+	defer closer()
+
+	defer os.Unsetenv("TFTEST_VAULT_KEY")
+	os.Unsetenv("TFTEST_VAULT_KEY")
+
+	err := c.SetEnv(map[string]string{"TFTEST_VAULT_KEY": "aws/sts/deploy#access_key"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v := os.Getenv("TFTEST_VAULT_KEY"); v != "ASIA1" {
+		t.Fatalf("bad: %q", v)
+	}
+
+	// The secret is read again before its lease expires
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		v.mu.Lock()
+		reads := v.reads
+		v.mu.Unlock()
+		if reads > 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("secret wasn't read again")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	c.Stop()
+
+	value, err := c.Read("aws/sts/deploy", "access_key")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if value == "ASIA1" {
+		t.Fatal("the new secret should be read")
+	}
+	if v := os.Getenv("TFTEST_VAULT_KEY"); v != value {
+		t.Fatalf("bad: %q", v)
+	}
+}
+
+func TestClientProviderCredentials(t *testing.T) {
+	c, v, closer := testClient(t)
+	defer closer()
+
+	c.providers = map[string]map[string]string{
+		"aws": {"access_key": "aws/sts/deploy#access_key"},
+	}
+	c.backend = map[string]string{"access_key": "aws/creds/deploy#access_key"}
+
+	creds, gen, err := c.ProviderCredentials("aws")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if creds["access_key"] != "ASIA1" {
+		t.Fatalf("bad: %#v", creds)
+	}
+
+	creds, _, err = c.BackendCredentials()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if creds["access_key"] != "AKIA" {
+		t.Fatalf("bad: %#v", creds)
+	}
+
+	creds, _, err = c.ProviderCredentials("google")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(creds) != 0 {
+		t.Fatalf("bad: %#v", creds)
+	}
+
+	// The generation changes once the secret is read again
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		v.mu.Lock()
+		reads := v.reads
+		v.mu.Unlock()
+		if reads > 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("secret wasn't read again")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	c.Stop()
+
+	creds, newGen, err := c.ProviderCredentials("aws")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if newGen == gen {
+		t.Fatal("the generation should change")
+	}
+	if creds["access_key"] == "ASIA1" {
+		t.Fatalf("bad: %#v", creds)
+	}
+}
@@ -0,0 +1,374 @@
+// Package warnings gives the warnings that Terraform shows stable codes, so
+// that they can be counted and suppressed by code.
+//
+// The CLI shows each warning as a Warning, through a Collector. Terraform
+// core and plugins pass warnings around as strings, so there a code is
+// carried at the start of the message in square brackets, as produced by
+// Format, and FromStrings turns them back into Warnings. Warnings from
+// elsewhere, such as older plugins, may not have a code.
+package warnings
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The codes of the warnings that Terraform shows. Users suppress warnings
+// with these, so they must not change once released.
+const (
+	// CodeDeprecatedAttribute is for attributes in the configuration of a
+	// provider, resource or provisioner that the plugin has deprecated.
+	CodeDeprecatedAttribute = "deprecated-attribute"
+
+	// CodeValidation is for any other warning found while validating the
+	// configuration of a provider, resource or provisioner.
+	CodeValidation = "validation"
+
+	// CodeModuleDeprecated is for modules that their authors have marked
+	// as deprecated.
+	CodeModuleDeprecated = "module-deprecated"
+
+	// CodeModuleVersion is for modules that recommend a version of
+	// Terraform other than the one that is running.
+	CodeModuleVersion = "module-version"
+
+	// CodeBackendLegacy is for states that still use legacy remote state
+	// rather than a backend.
+	CodeBackendLegacy = "backend-legacy"
+
+	// CodeTargetedApply is for plans with changes that the last, targeted,
+	// apply skipped.
+	CodeTargetedApply = "targeted-apply"
+
+	// CodeDeprecatedInterpolation is for interpolations that use syntax
+	// that still works but is deprecated.
+	CodeDeprecatedInterpolation = "deprecated-interpolation"
+
+	// CodeProviderUnconstrained is for providers that the configuration
+	// uses without a version constraint.
+	CodeProviderUnconstrained = "provider-unconstrained"
+
+	// CodeProviderInherited is for child modules that use a provider
+	// configured by a parent module without configuring it themselves.
+	CodeProviderInherited = "provider-inherited"
+
+	// CodeStateNotRefreshed is for plans made from the state without
+	// refreshing it first.
+	CodeStateNotRefreshed = "state-not-refreshed"
+
+	// CodeVariableDeprecated is for module blocks that set variables that
+	// the authors of the module have marked as deprecated.
+	CodeVariableDeprecated = "variable-deprecated"
+
+	// CodeBackendConfig is for warnings that a backend gives about its
+	// configuration.
+	CodeBackendConfig = "backend-config"
+
+	// CodeIgnoredSetting is for flags and environment variables that are
+	// ignored because their values aren't valid.
+	CodeIgnoredSetting = "ignored-setting"
+
+	// CodeLockSubtree is for operations with -lock-subtree that lock the
+	// whole state, because the backend can't lock module subtrees.
+	CodeLockSubtree = "lock-subtree"
+
+	// CodeProviderDev is for providers that are replaced with -provider-dev
+	// or attached with TF_REATTACH_PROVIDERS, whose versions aren't checked.
+	CodeProviderDev = "provider-dev"
+
+	// CodeRule is for the findings of "terraform validate -rules" with the
+	// warning severity.
+	CodeRule = "rule"
+)
+
+// Codes is the list of all warning codes, sorted.
+var Codes = []string{
+	CodeBackendConfig,
+	CodeBackendLegacy,
+	CodeDeprecatedAttribute,
+	CodeDeprecatedInterpolation,
+	CodeIgnoredSetting,
+	CodeLockSubtree,
+	CodeModuleDeprecated,
+	CodeModuleVersion,
+	CodeProviderDev,
+	CodeProviderInherited,
+	CodeProviderUnconstrained,
+	CodeRule,
+	CodeStateNotRefreshed,
+	CodeTargetedApply,
+	CodeValidation,
+	CodeVariableDeprecated,
+}
+
+// IsCode returns true if code is a known warning code.
+func IsCode(code string) bool {
+	for _, c := range Codes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Warning is a warning that Terraform shows, with its code.
+type Warning struct {
+	// Code is one of the codes above, or empty for warnings from elsewhere
+	// that don't have one.
+	Code string
+
+	// Message is the text of the warning, without the code.
+	Message string
+}
+
+// New returns a Warning with the given code, with the message formatted
+// like fmt.Sprintf.
+func New(code, format string, a ...interface{}) Warning {
+	return Warning{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// String returns the warning as it's shown, with the code prepended if it
+// has one.
+func (w Warning) String() string {
+	if w.Code == "" {
+		return w.Message
+	}
+
+	return Format(w.Code, w.Message)
+}
+
+// FromString returns the Warning for the warning w, as formatted by Format.
+func FromString(w string) Warning {
+	code := Parse(w)
+	if code == "" {
+		return Warning{Message: w}
+	}
+
+	return Warning{Code: code, Message: w[len(code)+3:]}
+}
+
+// FromStrings returns the Warnings for the warnings ws, as formatted by
+// Format, such as the warnings from validating a configuration.
+func FromStrings(ws []string) []Warning {
+	if len(ws) == 0 {
+		return nil
+	}
+
+	result := make([]Warning, len(ws))
+	for i, w := range ws {
+		result[i] = FromString(w)
+	}
+
+	return result
+}
+
+// UI is the part of cli.Ui that warnings are shown with.
+type UI interface {
+	Output(string)
+	Warn(string)
+}
+
+// Format returns the warning msg with the code prepended.
+func Format(code, msg string) string {
+	return fmt.Sprintf("[%s] %s", code, msg)
+}
+
+// Parse returns the code of the warning w, as formatted by Format, or an
+// empty string if w has no code.
+func Parse(w string) string {
+	if !strings.HasPrefix(w, "[") {
+		return ""
+	}
+
+	idx := strings.Index(w, "] ")
+	if idx == -1 || !IsCode(w[1:idx]) {
+		return ""
+	}
+
+	return w[1:idx]
+}
+
+// Strict returns the warnings in ws with the given codes as errors, which
+// is how strict mode holds configurations to a standard, along with the
+// rest of the warnings.
+func Strict(ws []string, codes []string) ([]string, []error) {
+	if len(codes) == 0 {
+		return ws, nil
+	}
+
+	strict := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		strict[code] = true
+	}
+
+	var rest []string
+	var errs []error
+	for _, w := range ws {
+		if code := Parse(w); code != "" && strict[code] {
+			errs = append(errs, fmt.Errorf("strict mode: %s", w))
+			continue
+		}
+		rest = append(rest, w)
+	}
+
+	return rest, errs
+}
+
+// Collector keeps track of the warnings that are shown during a run of
+// Terraform, and decides which of them are suppressed.
+//
+// A nil Collector suppresses nothing, so it is safe for code that may not be
+// given one to call its methods.
+type Collector struct {
+	mu         sync.Mutex
+	suppress   map[string]bool
+	shown      map[string]int
+	suppressed map[string]int
+}
+
+// NewCollector returns a Collector that doesn't suppress any warnings.
+func NewCollector() *Collector {
+	return &Collector{
+		suppress:   make(map[string]bool),
+		shown:      make(map[string]int),
+		suppressed: make(map[string]int),
+	}
+}
+
+// Suppress suppresses the warnings with the given codes. It returns an
+// error if any of them aren't known codes, although the known codes are
+// still suppressed.
+func (c *Collector) Suppress(codes ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var unknown []string
+	for _, code := range codes {
+		if !IsCode(code) {
+			unknown = append(unknown, code)
+			continue
+		}
+
+		c.suppress[code] = true
+	}
+
+	if len(unknown) > 0 {
+		return fmt.Errorf(
+			"unknown warning code(s): %s (valid codes are: %s)",
+			strings.Join(unknown, ", "), strings.Join(Codes, ", "))
+	}
+
+	return nil
+}
+
+// Show records a warning with the given code, and returns true if it
+// should be shown or false if it is suppressed.
+func (c *Collector) Show(code string) bool {
+	if c == nil {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.suppress[code] {
+		c.suppressed[code]++
+		return false
+	}
+
+	c.shown[code]++
+	return true
+}
+
+// Filter records each of the warnings ws, and returns those that should be
+// shown.
+func (c *Collector) Filter(ws []Warning) []Warning {
+	if c == nil {
+		return ws
+	}
+
+	result := make([]Warning, 0, len(ws))
+	for _, w := range ws {
+		if c.Show(w.Code) {
+			result = append(result, w)
+		}
+	}
+
+	return result
+}
+
+// Warn records the warning w, and shows it with ui unless it's suppressed.
+func (c *Collector) Warn(ui UI, w Warning) {
+	if c.Show(w.Code) {
+		ui.Warn(w.String() + "\n")
+	}
+}
+
+// WarnList records each of the warnings ws, and shows those that aren't
+// suppressed with ui as a list under the given header. Nothing is shown if
+// they are all suppressed.
+func (c *Collector) WarnList(ui UI, header string, ws []Warning) {
+	ws = c.Filter(ws)
+	if len(ws) == 0 {
+		return
+	}
+
+	ui.Warn(strings.TrimSpace(header) + "\n")
+	for _, w := range ws {
+		ui.Warn(fmt.Sprintf("  * %s", w))
+	}
+	ui.Output("")
+}
+
+// Summary returns a summary of the warnings recorded so far, by code, or an
+// empty string if there weren't any.
+func (c *Collector) Summary() string {
+	if c == nil {
+		return ""
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var shown, suppressed int
+	codes := make(map[string]struct{})
+	for code, n := range c.shown {
+		shown += n
+		codes[code] = struct{}{}
+	}
+	for code, n := range c.suppressed {
+		suppressed += n
+		codes[code] = struct{}{}
+	}
+	if len(codes) == 0 {
+		return ""
+	}
+
+	sorted := make([]string, 0, len(codes))
+	for code := range codes {
+		sorted = append(sorted, code)
+	}
+	sort.Strings(sorted)
+
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf(
+		"Warnings: %d shown, %d suppressed\n", shown, suppressed))
+	for _, code := range sorted {
+		name := code
+		if name == "" {
+			name = "(no code)"
+		}
+
+		buf.WriteString(fmt.Sprintf("  %s: %d", name, c.shown[code]))
+		if n := c.suppressed[code]; n > 0 {
+			buf.WriteString(fmt.Sprintf(" (%d suppressed)", n))
+		}
+		buf.WriteString("\n")
+	}
+
+	return strings.TrimSpace(buf.String())
+}
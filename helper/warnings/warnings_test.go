@@ -0,0 +1,156 @@
+package warnings
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]string{
+		Format(CodeModuleDeprecated, "module.foo: deprecated"): CodeModuleDeprecated,
+		"[unknown] module.foo: deprecated":                     "",
+		"aws_instance.foo: [DEPRECATED] use bar":               "",
+		"[validation]":                                         "",
+		"":                                                     "",
+	}
+
+	for w, expected := range cases {
+		if actual := Parse(w); actual != expected {
+			t.Fatalf("%q: bad: %q", w, actual)
+		}
+	}
+}
+
+func TestFromString(t *testing.T) {
+	cases := map[string]Warning{
+		Format(CodeModuleDeprecated, "module.foo: deprecated"): {
+			Code: CodeModuleDeprecated, Message: "module.foo: deprecated"},
+		"[unknown] module.foo: deprecated": {
+			Message: "[unknown] module.foo: deprecated"},
+		"": {},
+	}
+
+	for w, expected := range cases {
+		actual := FromString(w)
+		if actual != expected {
+			t.Fatalf("%q: bad: %#v", w, actual)
+		}
+		if actual.String() != w {
+			t.Fatalf("%q: bad string: %q", w, actual.String())
+		}
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	if err := c.Suppress(CodeModuleDeprecated); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ws := []Warning{
+		{Code: CodeModuleDeprecated, Message: "module.a: deprecated"},
+		{Code: CodeModuleVersion, Message: "module.a: wrong version"},
+		{Code: CodeModuleDeprecated, Message: "module.b: deprecated"},
+		{Message: "uncoded"},
+	}
+	actual := c.Filter(ws)
+	expected := []Warning{ws[1], ws[3]}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	if !c.Show(CodeBackendLegacy) {
+		t.Fatal("should show")
+	}
+
+	expectedSummary := `Warnings: 3 shown, 2 suppressed
+  (no code): 1
+  backend-legacy: 1
+  module-deprecated: 0 (2 suppressed)
+  module-version: 1`
+	if s := c.Summary(); s != expectedSummary {
+		t.Fatalf("bad:\n%s", s)
+	}
+}
+
+func TestCollector_empty(t *testing.T) {
+	if s := NewCollector().Summary(); s != "" {
+		t.Fatalf("bad: %q", s)
+	}
+}
+
+func TestCollector_nil(t *testing.T) {
+	var c *Collector
+	ws := []Warning{{Code: CodeValidation, Message: "foo"}}
+	if actual := c.Filter(ws); !reflect.DeepEqual(actual, ws) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if s := c.Summary(); s != "" {
+		t.Fatalf("bad: %q", s)
+	}
+}
+
+func TestCollectorWarn(t *testing.T) {
+	c := NewCollector()
+	if err := c.Suppress(CodeProviderDev); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	c.Warn(ui, New(CodeProviderDev, "using %s", "foo"))
+	c.Warn(ui, New(CodeLockSubtree, "locking %s", "everything"))
+	c.WarnList(ui, "\nHeader:\n", []Warning{
+		{Code: CodeProviderDev, Message: "suppressed"},
+		{Code: CodeRule, Message: "shown"},
+	})
+	c.WarnList(ui, "Empty:", []Warning{
+		{Code: CodeProviderDev, Message: "suppressed"},
+	})
+
+	expected := "[lock-subtree] locking everything\n\nHeader:\n\n  * [rule] shown\n"
+	if actual := ui.ErrorWriter.String(); actual != expected {
+		t.Fatalf("bad: %q", actual)
+	}
+
+	expectedSummary := `Warnings: 2 shown, 3 suppressed
+  lock-subtree: 1
+  provider-dev: 0 (3 suppressed)
+  rule: 1`
+	if s := c.Summary(); s != expectedSummary {
+		t.Fatalf("bad:\n%s", s)
+	}
+}
+
+func TestCollectorSuppress_unknown(t *testing.T) {
+	c := NewCollector()
+	if err := c.Suppress("nope", CodeValidation); err == nil {
+		t.Fatal("should error")
+	}
+
+	if c.Show(CodeValidation) {
+		t.Fatal("known codes should still be suppressed")
+	}
+}
+
+func TestStrict(t *testing.T) {
+	ws := []string{
+		Format(CodeProviderUnconstrained, "provider.aws: no version constraint"),
+		Format(CodeModuleVersion, "module.a: wrong version"),
+		"uncoded",
+	}
+
+	rest, errs := Strict(ws, []string{CodeProviderUnconstrained})
+	if !reflect.DeepEqual(rest, ws[1:]) {
+		t.Fatalf("bad: %#v", rest)
+	}
+	if len(errs) != 1 || errs[0].Error() != "strict mode: "+ws[0] {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	rest, errs = Strict(ws, nil)
+	if !reflect.DeepEqual(rest, ws) || len(errs) != 0 {
+		t.Fatalf("bad: %#v %#v", rest, errs)
+	}
+}
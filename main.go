@@ -6,25 +6,37 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform/helper/credentials"
 	"github.com/hashicorp/terraform/helper/logging"
+	"github.com/hashicorp/terraform/helper/vault"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/mattn/go-colorable"
 	"github.com/mattn/go-shellwords"
 	"github.com/mitchellh/cli"
 	"github.com/mitchellh/panicwrap"
 	"github.com/mitchellh/prefixedio"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 const (
 	// EnvCLI is the environment variable name to set additional CLI args.
 	EnvCLI = "TF_CLI_ARGS"
+
+	// envTerminalWidth is the environment variable that the parent process
+	// sets to the width of the terminal for the child process.
+	envTerminalWidth = "TF_TERMINAL_WIDTH"
 )
 
+// logger is the logger of the CLI's startup, before a command runs.
+var logger = logging.New("main")
+
 func main() {
 	// Override global prefix set by go-dynect during init()
 	log.SetPrefix("")
@@ -34,8 +46,9 @@ func main() {
 func realMain() int {
 	var wrapConfig panicwrap.WrapConfig
 
-	// don't re-exec terraform as a child process for easier debugging
-	if os.Getenv("TF_FORK") == "0" {
+	// don't re-exec terraform as a child process for easier debugging, or
+	// when the shell runs it to complete a command line
+	if os.Getenv("TF_FORK") == "0" || os.Getenv(EnvAutocompleteLine) != "" {
 		return wrappedMain()
 	}
 
@@ -63,9 +76,18 @@ func realMain() int {
 		outR, outW := io.Pipe()
 		go copyOutput(outR, doneCh)
 
+		// The output of the child is piped through this process, so it
+		// can't find the width of the terminal itself.
+		if w, _, err := terminal.GetSize(int(os.Stdout.Fd())); err == nil {
+			os.Setenv(envTerminalWidth, strconv.Itoa(w))
+		}
+
 		// Create the configuration for panicwrap and wrap our executable
 		wrapConfig.Handler = panicHandler(logTempFile)
-		wrapConfig.Writer = io.MultiWriter(logTempFile, logWriter)
+		wrapConfig.Writer = io.MultiWriter(&logging.Writer{
+			Levels: logging.ParseLevels("TRACE"),
+			Writer: logTempFile,
+		}, logWriter)
 		wrapConfig.Stdout = outW
 		wrapConfig.IgnoreSignals = ignoreSignals
 		wrapConfig.ForwardSignals = forwardSignals
@@ -99,12 +121,25 @@ func wrappedMain() int {
 	// We always need to close the DebugInfo before we exit.
 	defer terraform.CloseDebugInfo()
 
-	log.SetOutput(os.Stderr)
-	log.Printf(
-		"[INFO] Terraform version: %s %s %s",
+	// The log of a child process is filtered by its parent, which is the
+	// only place it's filtered unless there's no parent.
+	if panicwrap.Wrapped(&panicwrap.WrapConfig{}) {
+		log.SetOutput(os.Stderr)
+	} else {
+		logging.SetOutput()
+	}
+
+	// The shell reads the completions, and has no use for the logs
+	autocompleting := os.Getenv(EnvAutocompleteLine) != ""
+	if autocompleting {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	logger.Info(
+		"Terraform version: %s %s %s",
 		Version, VersionPrerelease, GitCommit)
-	log.Printf("[INFO] Go runtime version: %s", runtime.Version())
-	log.Printf("[INFO] CLI args: %#v", os.Args)
+	logger.Info("Go runtime version: %s", runtime.Version())
+	logger.Info("CLI args: %#v", os.Args)
 
 	// Load the configuration
 	config := BuiltinConfig
@@ -127,6 +162,41 @@ func wrappedMain() int {
 		config = *config.Merge(usrcfg)
 	}
 
+	// Set credentials from the keychain before any providers or backends
+	// can read them from the environment.
+	if err := config.SetKeychainEnv(); err != nil {
+		Ui.Error(fmt.Sprintf("Error reading credentials from the keychain: \n\n%s", err))
+		return 1
+	}
+
+	// Set up the API tokens for services. They're only sent by the HTTP
+	// clients of the services that use them, such as module downloads, to
+	// the host they were saved for.
+	Credentials.Static = config.CredentialsTokens()
+	Credentials.Vault = config.CredentialsVault()
+	if dir, err := ConfigDir(); err != nil {
+		logger.Error("Error finding the credentials file: %s", err)
+	} else {
+		Credentials.Path = filepath.Join(dir, credentials.FileName)
+	}
+
+	if autocompleting {
+		return runAutocomplete()
+	}
+
+	// Set credentials from Vault too. Their leases are renewed until
+	// Terraform exits, so that dynamic credentials don't expire during long
+	// operations.
+	vaultClient, err := config.SetVaultEnv()
+	if err != nil {
+		Ui.Error(fmt.Sprintf("Error reading credentials from Vault: \n\n%s", err))
+		return 1
+	}
+	if vaultClient != nil {
+		vault.Default = vaultClient
+		defer vaultClient.Stop()
+	}
+
 	// Run checkpoint
 	go runCheckpoint(&config)
 
@@ -136,6 +206,15 @@ func wrappedMain() int {
 	// Get the command line args.
 	args := os.Args[1:]
 
+	if len(args) > 0 {
+		switch args[0] {
+		case flagInstallAutocomplete:
+			return installAutocomplete()
+		case flagUninstallAutocomplete:
+			return uninstallAutocomplete()
+		}
+	}
+
 	// Build the CLI so far, we do this so we can query the subcommand.
 	cliRunner := &cli.CLI{
 		Args:       args,
@@ -173,7 +252,7 @@ func wrappedMain() int {
 	}
 
 	// Rebuild the CLI with any modified args.
-	log.Printf("[INFO] CLI command args: %#v", args)
+	logger.Info("CLI command args: %#v", args)
 	cliRunner = &cli.CLI{
 		Args:       args,
 		Commands:   Commands,
@@ -181,9 +260,16 @@ func wrappedMain() int {
 		HelpWriter: os.Stdout,
 	}
 
+	// Pass in the options for rendering output from config
+	if config.UI != nil {
+		UIOptions = *config.UI
+	}
+	UIOptions.TerminalWidth = terminalWidth()
+
 	// Pass in the overriding plugin paths from config
 	PluginOverrides.Providers = config.Providers
 	PluginOverrides.Provisioners = config.Provisioners
+	PluginOverrides.SearchDirs = config.PluginDirs
 
 	exitCode, err := cliRunner.Run()
 	if err != nil {
@@ -191,9 +277,30 @@ func wrappedMain() int {
 		return 1
 	}
 
+	// Summarize the warnings, so that they aren't lost in long output
+	if summary := Warnings.Summary(); summary != "" {
+		Ui.Warn("\n" + summary)
+	}
+
 	return exitCode
 }
 
+// terminalWidth returns the width of the terminal that output is written
+// to, or zero if it isn't a terminal.
+func terminalWidth() int {
+	if v := os.Getenv(envTerminalWidth); v != "" {
+		w, _ := strconv.Atoi(v)
+		return w
+	}
+
+	w, _, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+
+	return w
+}
+
 func cliConfigFile() (string, error) {
 	mustExist := true
 	configFilePath := os.Getenv("TERRAFORM_CONFIG")
@@ -203,13 +310,13 @@ func cliConfigFile() (string, error) {
 		mustExist = false
 
 		if err != nil {
-			log.Printf(
-				"[ERROR] Error detecting default CLI config file path: %s",
+			logger.Error(
+				"Error detecting default CLI config file path: %s",
 				err)
 		}
 	}
 
-	log.Printf("[DEBUG] Attempting to open CLI config file: %s", configFilePath)
+	logger.Debug("Attempting to open CLI config file: %s", configFilePath)
 	f, err := os.Open(configFilePath)
 	if err == nil {
 		f.Close()
@@ -220,7 +327,7 @@ func cliConfigFile() (string, error) {
 		return "", err
 	}
 
-	log.Println("[DEBUG] File doesn't exist, but doesn't need to. Ignoring.")
+	logger.Debug("File doesn't exist, but doesn't need to. Ignoring.")
 	return "", nil
 }
 
@@ -289,7 +396,7 @@ func mergeEnvArgs(envName string, cmd string, args []string) ([]string, error) {
 		return args, nil
 	}
 
-	log.Printf("[INFO] %s value: %q", envName, v)
+	logger.Info("%s value: %q", envName, v)
 	extra, err := shellwords.Parse(v)
 	if err != nil {
 		return nil, fmt.Errorf(
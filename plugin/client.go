@@ -22,3 +22,16 @@ func ClientConfig(m discovery.PluginMeta) *plugin.ClientConfig {
 func Client(m discovery.PluginMeta) *plugin.Client {
 	return plugin.NewClient(ClientConfig(m))
 }
+
+// ReattachClient returns a plugin client for a plugin process that's
+// already running, such as one started by "terraform plugin-serve".
+//
+// The client isn't managed, since killing it would stop the process for
+// every other client too.
+func ReattachClient(rc *plugin.ReattachConfig) *plugin.Client {
+	return plugin.NewClient(&plugin.ClientConfig{
+		Reattach:        rc,
+		HandshakeConfig: Handshake,
+		Plugins:         PluginMap,
+	})
+}
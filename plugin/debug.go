@@ -0,0 +1,164 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/go-plugin"
+)
+
+// ReattachEnvVar is the environment variable that lists the providers that
+// are already running, such as under a debugger, by name. Terraform connects
+// to these providers instead of starting its own.
+const ReattachEnvVar = "TF_REATTACH_PROVIDERS"
+
+// ReattachProvider is how a running provider is listed in ReattachEnvVar.
+type ReattachProvider struct {
+	Pid  int
+	Addr ReattachAddr
+}
+
+// ReattachAddr is the address that a running provider listens on.
+type ReattachAddr struct {
+	Network string
+	String  string
+}
+
+// ParseReattachProviders parses the value of ReattachEnvVar, a JSON object
+// of ReattachProvider by provider name, into the reattach configurations of
+// the providers.
+func ParseReattachProviders(v string) (map[string]*plugin.ReattachConfig, error) {
+	var providers map[string]ReattachProvider
+	if err := json.Unmarshal([]byte(v), &providers); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*plugin.ReattachConfig, len(providers))
+	for name, p := range providers {
+		var addr net.Addr
+		switch p.Addr.Network {
+		case "unix":
+			addr = &net.UnixAddr{Net: p.Addr.Network, Name: p.Addr.String}
+		case "tcp":
+			var err error
+			if addr, err = net.ResolveTCPAddr(p.Addr.Network, p.Addr.String); err != nil {
+				return nil, fmt.Errorf("provider %q: %s", name, err)
+			}
+		default:
+			return nil, fmt.Errorf("provider %q: unsupported network %q", name, p.Addr.Network)
+		}
+		if p.Pid <= 0 {
+			return nil, fmt.Errorf("provider %q: a pid is required", name)
+		}
+
+		result[name] = &plugin.ReattachConfig{Addr: addr, Pid: p.Pid}
+	}
+
+	return result, nil
+}
+
+// Debug serves a provider with the given name from the current process,
+// for running it in a debugger, until the process is interrupted. Rather
+// than being started by Terraform, it prints the value of ReattachEnvVar
+// that makes Terraform connect to it, and it keeps running between
+// commands. Each command gets a new provider from opts.ProviderFunc.
+//
+// Providers call it from their main function instead of Serve, usually
+// when a flag such as -debug is set.
+func Debug(name string, opts *ServeOpts) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	stopCh := make(chan struct{})
+	go func() {
+		<-sigCh
+		close(stopCh)
+	}()
+
+	return debugServe(name, opts, os.Stdout, stopCh)
+}
+
+// debugServe serves the provider of Debug until stopCh is closed, printing
+// the value of ReattachEnvVar to out once it's listening.
+func debugServe(name string, opts *ServeOpts, out io.Writer, stopCh <-chan struct{}) error {
+	l, err := debugListener()
+	if err != nil {
+		return fmt.Errorf("Error listening for Terraform: %s", err)
+	}
+	defer l.Close()
+
+	env, err := json.Marshal(map[string]ReattachProvider{
+		name: {
+			Pid: os.Getpid(),
+			Addr: ReattachAddr{
+				Network: l.Addr().Network(),
+				String:  l.Addr().String(),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, strings.TrimSpace(debugStarted)+"\n\n\t%s='%s'\n",
+		ReattachEnvVar, env)
+
+	// The output of the provider stays in this process, for the debugger,
+	// and commands can't stop it, since DoneCh isn't set.
+	server := &plugin.RPCServer{
+		Plugins: pluginMap(opts),
+		Stdout:  strings.NewReader(""),
+		Stderr:  strings.NewReader(""),
+	}
+	go server.Accept(l)
+
+	<-stopCh
+	return nil
+}
+
+// debugListener listens on a new Unix domain socket, or on a local TCP
+// port on Windows.
+func debugListener() (net.Listener, error) {
+	if runtime.GOOS == "windows" {
+		return net.Listen("tcp", "127.0.0.1:0")
+	}
+
+	dir, err := ioutil.TempDir("", "terraform-provider")
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := net.Listen("unix", filepath.Join(dir, "plugin.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return &debugUnixListener{Listener: l, dir: dir}, nil
+}
+
+// debugUnixListener is a net.Listener on a Unix domain socket that removes
+// the directory of the socket when it's closed.
+type debugUnixListener struct {
+	net.Listener
+	dir string
+}
+
+func (l *debugUnixListener) Close() error {
+	err := l.Listener.Close()
+	os.RemoveAll(l.dir)
+	return err
+}
+
+const debugStarted = `
+Provider started. To connect Terraform to it, set this in the environment
+of the Terraform commands:
+`
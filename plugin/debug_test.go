@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestParseReattachProviders(t *testing.T) {
+	cases := []struct {
+		Input   string
+		Network string
+		Err     bool
+	}{
+		{`{"foo": {"Pid": 12, "Addr": {"Network": "unix", "String": "/tmp/plugin.sock"}}}`, "unix", false},
+		{`{"foo": {"Pid": 12, "Addr": {"Network": "tcp", "String": "127.0.0.1:1234"}}}`, "tcp", false},
+		{`{"foo": {"Pid": 12, "Addr": {"Network": "udp", "String": "127.0.0.1:1234"}}}`, "", true},
+		{`{"foo": {"Addr": {"Network": "unix", "String": "/tmp/plugin.sock"}}}`, "", true},
+		{`not json`, "", true},
+	}
+
+	for _, tc := range cases {
+		result, err := ParseReattachProviders(tc.Input)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: bad error: %s", tc.Input, err)
+		}
+		if tc.Err {
+			continue
+		}
+
+		rc, ok := result["foo"]
+		if !ok {
+			t.Fatalf("%s: expected the provider foo, got %#v", tc.Input, result)
+		}
+		if rc.Pid != 12 || rc.Addr.Network() != tc.Network {
+			t.Fatalf("%s: bad: %#v", tc.Input, rc)
+		}
+	}
+}
+
+func TestDebugServe(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+	p.ResourcesReturn = []terraform.ResourceType{{Name: "foo_instance"}}
+
+	r, w := io.Pipe()
+	stopCh := make(chan struct{})
+	doneCh := make(chan error)
+	go func() {
+		doneCh <- debugServe("foo", &ServeOpts{ProviderFunc: testProviderFixed(p)}, w, stopCh)
+		w.Close()
+	}()
+
+	// The value of the environment variable is quoted on the last line
+	var env string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, ReattachEnvVar+"=") {
+			env = strings.Trim(strings.TrimPrefix(line, ReattachEnvVar+"="), "'")
+			break
+		}
+	}
+	go io.Copy(ioutil.Discard, r)
+
+	attach, err := ParseReattachProviders(env)
+	if err != nil {
+		t.Fatalf("error parsing %q: %s", env, err)
+	}
+
+	// Each client connects to the same process
+	for i := 0; i < 2; i++ {
+		client, err := ReattachClient(attach["foo"]).Client()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := client.Dispense(ProviderPluginName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resources := raw.(terraform.ResourceProvider).Resources()
+		if len(resources) != 1 || resources[0].Name != "foo_instance" {
+			t.Fatalf("bad: %#v", resources)
+		}
+		client.Close()
+	}
+
+	close(stopCh)
+	if err := <-doneCh; err != nil {
+		t.Fatal(err)
+	}
+}
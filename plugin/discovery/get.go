@@ -3,10 +3,11 @@ package discovery
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"runtime"
+	"os"
 	"strconv"
 	"strings"
 
@@ -14,6 +15,7 @@ import (
 
 	cleanhttp "github.com/hashicorp/go-cleanhttp"
 	getter "github.com/hashicorp/go-getter"
+	"github.com/hashicorp/terraform/helper/credentials"
 )
 
 // Releases are located by parsing the html listing from releases.hashicorp.com.
@@ -28,7 +30,11 @@ const protocolVersionHeader = "x-terraform-protocol-version"
 
 var releaseHost = "https://releases.hashicorp.com"
 
-var httpClient = cleanhttp.DefaultClient()
+// httpClient sends the token for the host saved by "terraform login", so
+// that providers can be downloaded from hosts that need authentication.
+var httpClient = &http.Client{
+	Transport: credentials.Transport(cleanhttp.DefaultPooledTransport()),
+}
 
 // Plugins are referred to by the short name, but all URLs and files will use
 // the full name prefixed with terraform-<plugin_type>-
@@ -42,33 +48,69 @@ func providerVersionsURL(name string) string {
 	return releaseHost + "/" + providerName(name) + "/"
 }
 
-// providerURL returns the full path to the provider file, using the current OS
-// and ARCH:
+// providerURL returns the full path to the provider file for the given
+// platform:
 // .../terraform-provider-name_<x.y.z>/terraform-provider-name_<x.y.z>_<os>_<arch>.<ext>
-func providerURL(name, version string) string {
-	fileName := fmt.Sprintf("%s_%s_%s_%s.zip", providerName(name), version, runtime.GOOS, runtime.GOARCH)
+func providerURL(name, version string, platform Platform) string {
+	fileName := fmt.Sprintf("%s_%s_%s.zip", providerName(name), version, platform)
 	u := fmt.Sprintf("%s%s/%s", providerVersionsURL(name), version, fileName)
 	return u
 }
 
+// ProviderRelease is a release of a provider plugin, chosen by
+// FindProvider, that can be fetched with GetProviderRelease.
+type ProviderRelease struct {
+	Name     string
+	Version  Version
+	Platform Platform
+	URL      string
+
+	// Size is the size of the download in bytes, or -1 if the release host
+	// didn't say.
+	Size int64
+}
+
+// ProgressFunc is called as a download progresses, with the number of bytes
+// downloaded so far and the total size of the download, which is -1 if it
+// isn't known.
+type ProgressFunc func(done, total int64)
+
 // GetProvider fetches a provider plugin based on the version constraints, and
 // copies it to the dst directory.
 //
 // TODO: verify checksum and signature
 func GetProvider(dst, provider string, req Constraints, pluginProtocolVersion uint) error {
+	return GetProviderForPlatform(dst, provider, req, pluginProtocolVersion, CurrentPlatform())
+}
+
+// GetProviderForPlatform is like GetProvider, but fetches the plugin built
+// for the given platform rather than the one Terraform is running on.
+func GetProviderForPlatform(dst, provider string, req Constraints, pluginProtocolVersion uint, platform Platform) error {
+	r, err := FindProvider(provider, req, pluginProtocolVersion, platform)
+	if err != nil {
+		return err
+	}
+
+	return GetProviderRelease(dst, r, nil)
+}
+
+// FindProvider finds the newest release of a provider plugin for the given
+// platform that fulfills the version constraints and is compatible with the
+// plugin protocol version, without fetching it.
+func FindProvider(provider string, req Constraints, pluginProtocolVersion uint, platform Platform) (*ProviderRelease, error) {
 	versions, err := listProviderVersions(provider)
 	// TODO: return multiple errors
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(versions) == 0 {
-		return fmt.Errorf("no plugins found for provider %q", provider)
+		return nil, fmt.Errorf("no plugins found for provider %q", provider)
 	}
 
 	versions = allowedVersions(versions, req)
 	if len(versions) == 0 {
-		return fmt.Errorf("no version of %q available that fulfills constraints %s", provider, req)
+		return nil, fmt.Errorf("no version of %q available that fulfills constraints %s", provider, req)
 	}
 
 	// sort them newest to oldest
@@ -76,45 +118,111 @@ func GetProvider(dst, provider string, req Constraints, pluginProtocolVersion ui
 
 	// take the first matching plugin we find
 	for _, v := range versions {
-		url := providerURL(provider, v.String())
+		url := providerURL(provider, v.String(), platform)
 		log.Printf("[DEBUG] fetching provider info for %s version %s", provider, v)
-		if checkPlugin(url, pluginProtocolVersion) {
-			log.Printf("[DEBUG] getting provider %q version %q at %s", provider, v, url)
-			return getter.Get(dst, url)
+		if size, ok := checkPlugin(url, pluginProtocolVersion); ok {
+			return &ProviderRelease{
+				Name:     provider,
+				Version:  v,
+				Platform: platform,
+				URL:      url,
+				Size:     size,
+			}, nil
 		}
 
 		log.Printf("[INFO] incompatible ProtocolVersion for %s version %s", provider, v)
 	}
 
-	return fmt.Errorf("no versions of %q compatible with the plugin ProtocolVersion", provider)
+	return nil, fmt.Errorf("no versions of %q compatible with the plugin ProtocolVersion", provider)
+}
+
+// GetProviderRelease fetches the given release of a provider plugin, and
+// unpacks it into the dst directory. If progress isn't nil, it's called as
+// the download progresses.
+func GetProviderRelease(dst string, r *ProviderRelease, progress ProgressFunc) error {
+	log.Printf("[DEBUG] getting provider %q version %q at %s", r.Name, r.Version, r.URL)
+	resp, err := httpClient.Get(r.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading %s: %s", r.URL, resp.Status)
+	}
+
+	// The archive is downloaded to a temporary file first, since a zip file
+	// can't be unpacked as it's read.
+	f, err := ioutil.TempFile("", "terraform-provider")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	var dstW io.Writer = f
+	if progress != nil {
+		total := resp.ContentLength
+		if total < 0 {
+			total = r.Size
+		}
+		dstW = &progressWriter{W: f, Total: total, Progress: progress}
+	}
+	if _, err := io.Copy(dstW, resp.Body); err != nil {
+		return fmt.Errorf("error downloading %s: %s", r.URL, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return new(getter.ZipDecompressor).Decompress(dst, f.Name(), true)
+}
+
+// progressWriter is an io.Writer that calls Progress with the number of
+// bytes written so far after every write.
+type progressWriter struct {
+	W        io.Writer
+	Total    int64
+	Progress ProgressFunc
+
+	done int64
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.W.Write(p)
+	w.done += int64(n)
+	w.Progress(w.done, w.Total)
+	return n, err
 }
 
-// Return the plugin version by making a HEAD request to the provided url
-func checkPlugin(url string, pluginProtocolVersion uint) bool {
+// Return the download size of the plugin, or -1 if it isn't known, by making
+// a HEAD request to the provided url. The result is false if the plugin
+// can't be fetched or isn't compatible with the plugin protocol version.
+func checkPlugin(url string, pluginProtocolVersion uint) (int64, bool) {
 	resp, err := httpClient.Head(url)
 	if err != nil {
 		log.Printf("[ERROR] error fetching plugin headers: %s", err)
-		return false
+		return 0, false
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		log.Println("[ERROR] non-200 status fetching plugin headers:", resp.Status)
-		return false
+		return 0, false
 	}
 
 	proto := resp.Header.Get(protocolVersionHeader)
 	if proto == "" {
 		log.Printf("[WARNING] missing %s from: %s", protocolVersionHeader, url)
-		return false
+		return 0, false
 	}
 
 	protoVersion, err := strconv.Atoi(proto)
 	if err != nil {
 		log.Printf("[ERROR] invalid ProtocolVersion: %s", proto)
-		return false
+		return 0, false
 	}
 
-	return protoVersion == int(pluginProtocolVersion)
+	return resp.ContentLength, protoVersion == int(pluginProtocolVersion)
 }
 
 var errVersionNotFound = errors.New("version not found")
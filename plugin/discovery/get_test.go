@@ -98,11 +98,11 @@ func TestVersionListing(t *testing.T) {
 }
 
 func TestCheckProtocolVersions(t *testing.T) {
-	if checkPlugin(providerURL("test", VersionStr("1.2.3").MustParse().String()), 4) {
+	if _, ok := checkPlugin(providerURL("test", VersionStr("1.2.3").MustParse().String(), CurrentPlatform()), 4); ok {
 		t.Fatal("protocol version 4 is not compatible")
 	}
 
-	if !checkPlugin(providerURL("test", VersionStr("1.2.3").MustParse().String()), 3) {
+	if _, ok := checkPlugin(providerURL("test", VersionStr("1.2.3").MustParse().String(), CurrentPlatform()), 3); !ok {
 		t.Fatal("protocol version 3 should be compatible")
 	}
 }
@@ -141,6 +141,84 @@ func TestGetProvider(t *testing.T) {
 
 }
 
+func TestGetProviderForPlatform(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tf-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	platform := Platform{OS: "plan9", Arch: "arm"}
+	err = GetProviderForPlatform(tmpDir, "test", AllVersions, 3, platform)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// we should have version 1.2.3 for the requested platform, rather than
+	// the current one
+	dest := filepath.Join(tmpDir, "terraform-provider-test_1.2.3_plan9_arm_X3")
+	if _, err := os.Stat(dest); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindProvider(t *testing.T) {
+	r, err := FindProvider("test", AllVersions, 3, CurrentPlatform())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Version.String() != "1.2.3" {
+		t.Fatalf("wrong version %s", r.Version)
+	}
+	if r.Size <= 0 {
+		t.Fatalf("wrong size %d", r.Size)
+	}
+
+	if _, err := FindProvider("test", AllVersions, 5, CurrentPlatform()); err == nil {
+		t.Fatal("protocol version is incompatible")
+	}
+}
+
+func TestGetProviderRelease(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tf-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	r, err := FindProvider("test", AllVersions, 3, CurrentPlatform())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lastDone, lastTotal int64
+	err = GetProviderRelease(tmpDir, r, func(done, total int64) {
+		if done < lastDone {
+			t.Fatalf("progress went backwards from %d to %d", lastDone, done)
+		}
+		lastDone, lastTotal = done, total
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lastDone != r.Size || lastTotal != r.Size {
+		t.Fatalf("wrong final progress %d of %d; want %d", lastDone, lastTotal, r.Size)
+	}
+
+	fileName := fmt.Sprintf("terraform-provider-test_1.2.3_%s_%s_X3", runtime.GOOS, runtime.GOARCH)
+	f, err := ioutil.ReadFile(filepath.Join(tmpDir, fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(f) != testProviderFile {
+		t.Fatalf("test provider contains: %q", f)
+	}
+}
+
 const versionList = `<!DOCTYPE html>
 <html>
 <body>
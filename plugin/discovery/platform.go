@@ -0,0 +1,34 @@
+package discovery
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Platform is an operating system and architecture combination that plugins
+// are built for, such as linux_amd64.
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// CurrentPlatform returns the platform that Terraform is running on.
+func CurrentPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// ParsePlatform parses a platform in the OS_ARCH form that is used in
+// plugin release filenames, such as "darwin_amd64".
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(s, "_")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("invalid platform %q: must be in the form OS_ARCH, such as linux_amd64", s)
+	}
+
+	return Platform{OS: parts[0], Arch: parts[1]}, nil
+}
+
+func (p Platform) String() string {
+	return p.OS + "_" + p.Arch
+}
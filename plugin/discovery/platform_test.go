@@ -0,0 +1,36 @@
+package discovery
+
+import (
+	"testing"
+)
+
+func TestParsePlatform(t *testing.T) {
+	tests := []struct {
+		Input string
+		Want  Platform
+		Err   bool
+	}{
+		{"linux_amd64", Platform{OS: "linux", Arch: "amd64"}, false},
+		{"darwin_386", Platform{OS: "darwin", Arch: "386"}, false},
+		{"linux", Platform{}, true},
+		{"linux_", Platform{}, true},
+		{"_amd64", Platform{}, true},
+		{"linux_amd64_extra", Platform{}, true},
+		{"", Platform{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Input, func(t *testing.T) {
+			got, err := ParsePlatform(test.Input)
+			if (err != nil) != test.Err {
+				t.Fatalf("wrong error: %v", err)
+			}
+			if got != test.Want {
+				t.Fatalf("wrong result %#v; want %#v", got, test.Want)
+			}
+			if err == nil && got.String() != test.Input {
+				t.Fatalf("wrong string %q; want %q", got.String(), test.Input)
+			}
+		})
+	}
+}
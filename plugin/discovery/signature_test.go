@@ -0,0 +1,37 @@
+package discovery
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/keybase/go-crypto/openpgp/packet"
+)
+
+func TestHashiCorpKeyring(t *testing.T) {
+	keyring, err := HashiCorpKeyring()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(keyring) != 1 {
+		t.Fatalf("bad: %#v", keyring)
+	}
+
+	key := keyring[0].PrimaryKey
+	if id := fmt.Sprintf("%016X", key.KeyId); id != "51852D87348FFC4C" {
+		t.Fatalf("bad key ID: %s", id)
+	}
+	ident, ok := keyring[0].Identities["HashiCorp Security <security@hashicorp.com>"]
+	if !ok {
+		t.Fatalf("bad identities: %#v", keyring[0].Identities)
+	}
+
+	// The identity is signed by the key itself
+	if err := key.VerifyUserIdSignature(ident.Name, key, ident.SelfSignature); err != nil {
+		t.Fatalf("bad self-signature: %s", err)
+	}
+
+	// The key must be usable for checking signatures
+	if keys := keyring.KeysByIdUsage(key.KeyId, packet.KeyFlagSign); len(keys) != 1 {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
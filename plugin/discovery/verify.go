@@ -0,0 +1,192 @@
+package discovery
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/keybase/go-crypto/openpgp"
+)
+
+// ProviderProvenance is where an installed provider plugin comes from, as
+// established by VerifyProvider.
+type ProviderProvenance struct {
+	// URL is the URL of the release archive that the plugin is in, and
+	// ArchiveSHA256 is the digest of the archive listed in the SHA256SUMS
+	// file of the release.
+	URL           string
+	ArchiveSHA256 []byte
+
+	// SHA256 is the digest of the executable in the release archive, which
+	// is the same as the digest of the installed plugin.
+	SHA256 []byte
+
+	// SigningKeyID and SigningIdentity are the ID of the key that signed
+	// the SHA256SUMS file, and its first identity.
+	SigningKeyID    string
+	SigningIdentity string
+}
+
+// providerSumsURL returns the URL of the SHA256SUMS file of a provider
+// release, which lists the digests of its archives. It's signed by the
+// file at the same URL with ".sig" appended.
+func providerSumsURL(name, version string) string {
+	return fmt.Sprintf("%s%s/%s_%s_SHA256SUMS",
+		providerVersionsURL(name), version, providerName(name), version)
+}
+
+// VerifyProvider checks that the given installed provider plugin is the
+// executable released for the given platform: that the SHA256SUMS file of
+// the release is signed by a key in keyring, that the release archive is
+// listed in it, and that the executable in the archive is byte for byte
+// the installed one. The key that made the signature is returned in the
+// provenance.
+//
+// If keyring is nil, the signature is checked against the HashiCorp release
+// key from HashiCorpKeyring.
+func VerifyProvider(meta PluginMeta, platform Platform, keyring openpgp.KeyRing) (*ProviderProvenance, error) {
+	v, err := meta.Version.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q: %s", meta.Version, err)
+	}
+	version := v.String()
+
+	digest, err := meta.SHA256()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", meta.Path, err)
+	}
+
+	result := &ProviderProvenance{URL: providerURL(meta.Name, version, platform)}
+
+	sumsURL := providerSumsURL(meta.Name, version)
+	sums, err := getRelease(sumsURL)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := getRelease(sumsURL + ".sig")
+	if err != nil {
+		return nil, err
+	}
+
+	if keyring == nil {
+		keyring, err = HashiCorpKeyring()
+		if err != nil {
+			return nil, err
+		}
+	}
+	signer, err := openpgp.CheckDetachedSignature(
+		keyring, bytes.NewReader(sums), bytes.NewReader(sig))
+	if err != nil {
+		return nil, fmt.Errorf("bad signature of %s: %s", sumsURL, err)
+	}
+
+	result.SigningKeyID = fmt.Sprintf("%016X", signer.PrimaryKey.KeyId)
+	for name := range signer.Identities {
+		if result.SigningIdentity == "" || name < result.SigningIdentity {
+			result.SigningIdentity = name
+		}
+	}
+
+	archiveName := result.URL[strings.LastIndex(result.URL, "/")+1:]
+	result.ArchiveSHA256, err = findSHA256Sum(sums, archiveName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", sumsURL, err)
+	}
+
+	log.Printf("[DEBUG] verifying provider %q version %q against %s", meta.Name, version, result.URL)
+	archive, err := getRelease(result.URL)
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(archive); !bytes.Equal(sum[:], result.ArchiveSHA256) {
+		return nil, fmt.Errorf(
+			"the SHA256 digest of %s is %x, but %x is listed in the SHA256SUMS",
+			archiveName, sum[:], result.ArchiveSHA256)
+	}
+
+	result.SHA256, err = zipFileSHA256(archive, filepath.Base(meta.Path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", archiveName, err)
+	}
+	if !bytes.Equal(result.SHA256, digest) {
+		return nil, fmt.Errorf(
+			"the SHA256 digest of %s is %x, but it's %x in the release",
+			meta.Path, digest, result.SHA256)
+	}
+
+	return result, nil
+}
+
+// getRelease returns the content of a file of a release.
+func getRelease(url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading %s: %s", url, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading %s: %s", url, err)
+	}
+
+	return data, nil
+}
+
+// findSHA256Sum returns the digest of the named file in the given
+// SHA256SUMS file, which has the format of the sha256sum tool.
+func findSHA256Sum(sums []byte, name string) ([]byte, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+
+		return hex.DecodeString(fields[0])
+	}
+
+	return nil, fmt.Errorf("%s isn't listed", name)
+}
+
+// zipFileSHA256 returns the digest of the named file in the given zip
+// archive.
+func zipFileSHA256(archive []byte, name string) ([]byte, error) {
+	z, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range z.File {
+		if f.Name != name {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		h := sha256.New()
+		if _, err := io.Copy(h, r); err != nil {
+			return nil, err
+		}
+		return h.Sum(nil), nil
+	}
+
+	return nil, fmt.Errorf("%s isn't in the archive", name)
+}
@@ -0,0 +1,140 @@
+package discovery
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/keybase/go-crypto/openpgp"
+)
+
+// testVerifyServer returns a release server for version 1.2.0 of the
+// "test" provider on linux_amd64, with a SHA256SUMS file signed by signer,
+// and the name of the executable in the release.
+func testVerifyServer(t *testing.T, signer *openpgp.Entity) (*httptest.Server, string) {
+	exe := "terraform-provider-test_v1.2.0_x4"
+
+	var archive bytes.Buffer
+	z := zip.NewWriter(&archive)
+	f, err := z.Create(exe)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f.Write([]byte(testProviderFile))
+	if err := z.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	archiveSum := sha256.Sum256(archive.Bytes())
+	sums := fmt.Sprintf(
+		"%x  terraform-provider-test_1.2.0_darwin_amd64.zip\n"+
+			"%x  terraform-provider-test_1.2.0_linux_amd64.zip\n",
+		sha256.Sum256([]byte("other")), archiveSum)
+
+	var sig bytes.Buffer
+	if err := openpgp.DetachSign(&sig, signer, strings.NewReader(sums), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	files := map[string][]byte{
+		"/terraform-provider-test/1.2.0/terraform-provider-test_1.2.0_SHA256SUMS":      []byte(sums),
+		"/terraform-provider-test/1.2.0/terraform-provider-test_1.2.0_SHA256SUMS.sig":  sig.Bytes(),
+		"/terraform-provider-test/1.2.0/terraform-provider-test_1.2.0_linux_amd64.zip": archive.Bytes(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	}))
+
+	return server, exe
+}
+
+func TestVerifyProvider(t *testing.T) {
+	signer, err := openpgp.NewEntity("Test Releases", "", "releases@example.com", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	other, err := openpgp.NewEntity("Someone Else", "", "else@example.com", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	server, exe := testVerifyServer(t, signer)
+	defer server.Close()
+
+	oldHost := releaseHost
+	defer func() { releaseHost = oldHost }()
+	releaseHost = server.URL
+
+	dir, err := ioutil.TempDir("", "tf-verify")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, exe)
+	if err := ioutil.WriteFile(path, []byte(testProviderFile), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	meta := PluginMeta{Name: "test", Version: "1.2.0", Path: path}
+	platform := Platform{OS: "linux", Arch: "amd64"}
+
+	p, err := VerifyProvider(meta, platform, openpgp.EntityList{signer})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	digest, _ := meta.SHA256()
+	if !bytes.Equal(p.SHA256, digest) {
+		t.Fatalf("bad digest: %x", p.SHA256)
+	}
+	if want := fmt.Sprintf("%016X", signer.PrimaryKey.KeyId); p.SigningKeyID != want {
+		t.Fatalf("bad key: %s; want %s", p.SigningKeyID, want)
+	}
+	if p.SigningIdentity != "Test Releases <releases@example.com>" {
+		t.Fatalf("bad identity: %s", p.SigningIdentity)
+	}
+	if !strings.HasSuffix(p.URL, "/terraform-provider-test_1.2.0_linux_amd64.zip") {
+		t.Fatalf("bad URL: %s", p.URL)
+	}
+
+	// Without a keyring, the signature is checked against the HashiCorp
+	// release key, which didn't make it
+	_, err = VerifyProvider(meta, platform, nil)
+	if err == nil || !strings.Contains(err.Error(), "bad signature") {
+		t.Fatalf("expected a bad signature, got: %v", err)
+	}
+
+	// A signature by a key that isn't in the keyring is rejected
+	_, err = VerifyProvider(meta, platform, openpgp.EntityList{other})
+	if err == nil || !strings.Contains(err.Error(), "bad signature") {
+		t.Fatalf("expected a bad signature, got: %v", err)
+	}
+
+	// A release for a platform that isn't listed can't be verified
+	_, err = VerifyProvider(meta, Platform{OS: "windows", Arch: "amd64"}, openpgp.EntityList{signer})
+	if err == nil || !strings.Contains(err.Error(), "isn't listed") {
+		t.Fatalf("expected an unlisted archive, got: %v", err)
+	}
+
+	// An executable that differs from the release is rejected
+	if err := ioutil.WriteFile(path, []byte("tampered"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	_, err = VerifyProvider(meta, platform, openpgp.EntityList{signer})
+	if err == nil || !strings.Contains(err.Error(), "in the release") {
+		t.Fatalf("expected a digest mismatch, got: %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package plugin
 
 import (
 	"net/rpc"
+	"strings"
+	"sync/atomic"
 
 	"github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/terraform/terraform"
@@ -26,6 +28,11 @@ func (p *ResourceProviderPlugin) Client(
 type ResourceProvider struct {
 	Broker *plugin.MuxBroker
 	Client *rpc.Client
+
+	// cancelable is set to 1 once the plugin has reported, in response to
+	// Configure, that it can be asked to Cancel. Plugins built before
+	// Cancel was added don't report it, and are stopped instead.
+	cancelable int32
 }
 
 func (p *ResourceProvider) Stop() error {
@@ -41,6 +48,26 @@ func (p *ResourceProvider) Stop() error {
 	return err
 }
 
+// Cancel asks the provider to cancel gracefully. Providers that haven't
+// reported that they can, including those that haven't been configured,
+// are stopped instead.
+func (p *ResourceProvider) Cancel() error {
+	if atomic.LoadInt32(&p.cancelable) == 0 {
+		return p.Stop()
+	}
+
+	var resp ResourceProviderCancelResponse
+	err := p.Client.Call("Plugin.Cancel", new(interface{}), &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		err = resp.Error
+	}
+
+	return err
+}
+
 func (p *ResourceProvider) Input(
 	input terraform.UIInput,
 	c *terraform.ResourceConfig) (*terraform.ResourceConfig, error) {
@@ -78,15 +105,7 @@ func (p *ResourceProvider) Validate(c *terraform.ResourceConfig) ([]string, []er
 		return nil, []error{err}
 	}
 
-	var errs []error
-	if len(resp.Errors) > 0 {
-		errs = make([]error, len(resp.Errors))
-		for i, err := range resp.Errors {
-			errs[i] = err
-		}
-	}
-
-	return resp.Warnings, errs
+	return resp.Warnings, validateErrors(resp.Errors, resp.ErrorPaths)
 }
 
 func (p *ResourceProvider) ValidateResource(
@@ -102,15 +121,7 @@ func (p *ResourceProvider) ValidateResource(
 		return nil, []error{err}
 	}
 
-	var errs []error
-	if len(resp.Errors) > 0 {
-		errs = make([]error, len(resp.Errors))
-		for i, err := range resp.Errors {
-			errs[i] = err
-		}
-	}
-
-	return resp.Warnings, errs
+	return resp.Warnings, validateErrors(resp.Errors, resp.ErrorPaths)
 }
 
 func (p *ResourceProvider) Configure(c *terraform.ResourceConfig) error {
@@ -119,6 +130,9 @@ func (p *ResourceProvider) Configure(c *terraform.ResourceConfig) error {
 	if err != nil {
 		return err
 	}
+	if resp.Cancelable {
+		atomic.StoreInt32(&p.cancelable, 1)
+	}
 	if resp.Error != nil {
 		err = resp.Error
 	}
@@ -142,7 +156,7 @@ func (p *ResourceProvider) Apply(
 		return nil, err
 	}
 	if resp.Error != nil {
-		err = resp.Error
+		err = responseError(resp.Error, resp.Transient, resp.ErrorPath)
 	}
 
 	return resp.State, err
@@ -163,7 +177,7 @@ func (p *ResourceProvider) Diff(
 		return nil, err
 	}
 	if resp.Error != nil {
-		err = resp.Error
+		err = responseError(resp.Error, resp.Transient, resp.ErrorPath)
 	}
 
 	return resp.Diff, err
@@ -182,15 +196,7 @@ func (p *ResourceProvider) ValidateDataSource(
 		return nil, []error{err}
 	}
 
-	var errs []error
-	if len(resp.Errors) > 0 {
-		errs = make([]error, len(resp.Errors))
-		for i, err := range resp.Errors {
-			errs[i] = err
-		}
-	}
-
-	return resp.Warnings, errs
+	return resp.Warnings, validateErrors(resp.Errors, resp.ErrorPaths)
 }
 
 func (p *ResourceProvider) Refresh(
@@ -207,7 +213,7 @@ func (p *ResourceProvider) Refresh(
 		return nil, err
 	}
 	if resp.Error != nil {
-		err = resp.Error
+		err = responseError(resp.Error, resp.Transient, "")
 	}
 
 	return resp.State, err
@@ -259,7 +265,7 @@ func (p *ResourceProvider) ReadDataDiff(
 		return nil, err
 	}
 	if resp.Error != nil {
-		err = resp.Error
+		err = responseError(resp.Error, resp.Transient, "")
 	}
 
 	return resp.Diff, err
@@ -279,7 +285,7 @@ func (p *ResourceProvider) ReadDataApply(
 		return nil, err
 	}
 	if resp.Error != nil {
-		err = resp.Error
+		err = responseError(resp.Error, resp.Transient, "")
 	}
 
 	return resp.State, err
@@ -297,6 +303,154 @@ func (p *ResourceProvider) DataSources() []terraform.DataSource {
 	return result
 }
 
+func (p *ResourceProvider) WaitForReady(
+	info *terraform.InstanceInfo,
+	s *terraform.InstanceState) (*terraform.InstanceState, error) {
+	var resp ResourceProviderWaitForReadyResponse
+	args := &ResourceProviderWaitForReadyArgs{
+		Info:  info,
+		State: s,
+	}
+
+	err := p.Client.Call("Plugin.WaitForReady", args, &resp)
+	if err != nil {
+		// Plugins built before WaitForReady was added don't have the
+		// method at all.
+		if strings.Contains(err.Error(), "can't find method") {
+			return nil, terraform.ErrWaitForReadyUnsupported
+		}
+
+		return nil, err
+	}
+	if resp.Unsupported {
+		return nil, terraform.ErrWaitForReadyUnsupported
+	}
+	if resp.Error != nil {
+		err = resp.Error
+	}
+
+	return resp.State, err
+}
+
+func (p *ResourceProvider) LookupExisting(
+	info *terraform.InstanceInfo,
+	c *terraform.ResourceConfig) (*terraform.InstanceState, error) {
+	var resp ResourceProviderLookupExistingResponse
+	args := &ResourceProviderLookupExistingArgs{
+		Info:   info,
+		Config: c,
+	}
+
+	err := p.Client.Call("Plugin.LookupExisting", args, &resp)
+	if err != nil {
+		// Plugins built before LookupExisting was added don't have the
+		// method at all.
+		if strings.Contains(err.Error(), "can't find method") {
+			return nil, terraform.ErrLookupExistingUnsupported
+		}
+
+		return nil, err
+	}
+	if resp.Unsupported {
+		return nil, terraform.ErrLookupExistingUnsupported
+	}
+	if resp.Error != nil {
+		err = resp.Error
+	}
+
+	return resp.State, err
+}
+
+func (p *ResourceProvider) RefreshBatch(
+	info []*terraform.InstanceInfo,
+	s []*terraform.InstanceState) ([]*terraform.InstanceState, error) {
+	var resp ResourceProviderRefreshBatchResponse
+	args := &ResourceProviderRefreshBatchArgs{
+		Info:  info,
+		State: s,
+	}
+
+	err := p.Client.Call("Plugin.RefreshBatch", args, &resp)
+	if err != nil {
+		// Plugins built before RefreshBatch was added don't have the
+		// method at all.
+		if strings.Contains(err.Error(), "can't find method") {
+			return nil, terraform.ErrRefreshBatchUnsupported
+		}
+
+		return nil, err
+	}
+	if resp.Unsupported {
+		return nil, terraform.ErrRefreshBatchUnsupported
+	}
+	if resp.Error != nil {
+		return nil, responseError(resp.Error, resp.Transient, "")
+	}
+
+	states := make([]*terraform.InstanceState, len(resp.Results))
+	for i, r := range resp.Results {
+		states[i] = r.State
+	}
+
+	return states, nil
+}
+
+// responseError returns the error of a response, marked again with the path
+// of the attribute it's about, and as transient, if the provider marked it so.
+func responseError(err *plugin.BasicError, transient bool, path string) error {
+	var result error = err
+	if path != "" {
+		result = &terraform.AttributeError{Path: path, Err: result}
+	}
+	if transient {
+		result = terraform.TransientError(result)
+	}
+
+	return result
+}
+
+// errorPath returns the path of the attribute that err is about, to be sent
+// along with it in a response.
+func errorPath(err error) string {
+	if ae := terraform.ErrorAttribute(err); ae != nil {
+		return ae.Path
+	}
+
+	return ""
+}
+
+// validateErrors returns the errors of a validation response, marked again
+// with the paths of the attributes they're about.
+func validateErrors(errs []*plugin.BasicError, paths []string) []error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	result := make([]error, len(errs))
+	for i, err := range errs {
+		var path string
+		if i < len(paths) {
+			path = paths[i]
+		}
+		result[i] = responseError(err, false, path)
+	}
+
+	return result
+}
+
+// validateResponseErrors returns the errors of a validation and the paths of
+// the attributes they're about, to be sent in a response.
+func validateResponseErrors(errs []error) ([]*plugin.BasicError, []string) {
+	berrs := make([]*plugin.BasicError, len(errs))
+	paths := make([]string, len(errs))
+	for i, err := range errs {
+		berrs[i] = plugin.NewBasicError(err)
+		paths[i] = errorPath(err)
+	}
+
+	return berrs, paths
+}
+
 func (p *ResourceProvider) Close() error {
 	return p.Client.Close()
 }
@@ -312,8 +466,17 @@ type ResourceProviderStopResponse struct {
 	Error *plugin.BasicError
 }
 
+type ResourceProviderCancelResponse struct {
+	Error *plugin.BasicError
+}
+
 type ResourceProviderConfigureResponse struct {
 	Error *plugin.BasicError
+
+	// Cancelable is true if the plugin has the Cancel method and its
+	// provider implements ResourceProviderCanceler. Plugins built before
+	// it was added leave it false.
+	Cancelable bool
 }
 
 type ResourceProviderInputArgs struct {
@@ -333,8 +496,10 @@ type ResourceProviderApplyArgs struct {
 }
 
 type ResourceProviderApplyResponse struct {
-	State *terraform.InstanceState
-	Error *plugin.BasicError
+	State     *terraform.InstanceState
+	Error     *plugin.BasicError
+	Transient bool
+	ErrorPath string
 }
 
 type ResourceProviderDiffArgs struct {
@@ -344,8 +509,10 @@ type ResourceProviderDiffArgs struct {
 }
 
 type ResourceProviderDiffResponse struct {
-	Diff  *terraform.InstanceDiff
-	Error *plugin.BasicError
+	Diff      *terraform.InstanceDiff
+	Error     *plugin.BasicError
+	Transient bool
+	ErrorPath string
 }
 
 type ResourceProviderRefreshArgs struct {
@@ -354,8 +521,50 @@ type ResourceProviderRefreshArgs struct {
 }
 
 type ResourceProviderRefreshResponse struct {
+	State     *terraform.InstanceState
+	Error     *plugin.BasicError
+	Transient bool
+}
+
+type ResourceProviderWaitForReadyArgs struct {
+	Info  *terraform.InstanceInfo
+	State *terraform.InstanceState
+}
+
+type ResourceProviderWaitForReadyResponse struct {
+	State       *terraform.InstanceState
+	Unsupported bool
+	Error       *plugin.BasicError
+}
+
+type ResourceProviderLookupExistingArgs struct {
+	Info   *terraform.InstanceInfo
+	Config *terraform.ResourceConfig
+}
+
+type ResourceProviderLookupExistingResponse struct {
+	State       *terraform.InstanceState
+	Unsupported bool
+	Error       *plugin.BasicError
+}
+
+type ResourceProviderRefreshBatchArgs struct {
+	Info  []*terraform.InstanceInfo
+	State []*terraform.InstanceState
+}
+
+type ResourceProviderRefreshBatchResponse struct {
+	Results     []ResourceProviderRefreshBatchResult
+	Unsupported bool
+	Error       *plugin.BasicError
+	Transient   bool
+}
+
+// ResourceProviderRefreshBatchResult wraps each state in a batch, since
+// gob can't encode the nil states of resources that no longer exist as
+// elements of a slice.
+type ResourceProviderRefreshBatchResult struct {
 	State *terraform.InstanceState
-	Error *plugin.BasicError
 }
 
 type ResourceProviderImportStateArgs struct {
@@ -374,8 +583,9 @@ type ResourceProviderReadDataApplyArgs struct {
 }
 
 type ResourceProviderReadDataApplyResponse struct {
-	State *terraform.InstanceState
-	Error *plugin.BasicError
+	State     *terraform.InstanceState
+	Error     *plugin.BasicError
+	Transient bool
 }
 
 type ResourceProviderReadDataDiffArgs struct {
@@ -384,8 +594,9 @@ type ResourceProviderReadDataDiffArgs struct {
 }
 
 type ResourceProviderReadDataDiffResponse struct {
-	Diff  *terraform.InstanceDiff
-	Error *plugin.BasicError
+	Diff      *terraform.InstanceDiff
+	Error     *plugin.BasicError
+	Transient bool
 }
 
 type ResourceProviderValidateArgs struct {
@@ -393,8 +604,9 @@ type ResourceProviderValidateArgs struct {
 }
 
 type ResourceProviderValidateResponse struct {
-	Warnings []string
-	Errors   []*plugin.BasicError
+	Warnings   []string
+	Errors     []*plugin.BasicError
+	ErrorPaths []string
 }
 
 type ResourceProviderValidateResourceArgs struct {
@@ -403,8 +615,9 @@ type ResourceProviderValidateResourceArgs struct {
 }
 
 type ResourceProviderValidateResourceResponse struct {
-	Warnings []string
-	Errors   []*plugin.BasicError
+	Warnings   []string
+	Errors     []*plugin.BasicError
+	ErrorPaths []string
 }
 
 func (s *ResourceProviderServer) Stop(
@@ -418,6 +631,22 @@ func (s *ResourceProviderServer) Stop(
 	return nil
 }
 
+func (s *ResourceProviderServer) Cancel(
+	_ interface{},
+	reply *ResourceProviderCancelResponse) error {
+	var err error
+	if c, ok := s.Provider.(terraform.ResourceProviderCanceler); ok {
+		err = c.Cancel()
+	} else {
+		err = s.Provider.Stop()
+	}
+	*reply = ResourceProviderCancelResponse{
+		Error: plugin.NewBasicError(err),
+	}
+
+	return nil
+}
+
 func (s *ResourceProviderServer) Input(
 	args *ResourceProviderInputArgs,
 	reply *ResourceProviderInputResponse) error {
@@ -446,13 +675,11 @@ func (s *ResourceProviderServer) Validate(
 	args *ResourceProviderValidateArgs,
 	reply *ResourceProviderValidateResponse) error {
 	warns, errs := s.Provider.Validate(args.Config)
-	berrs := make([]*plugin.BasicError, len(errs))
-	for i, err := range errs {
-		berrs[i] = plugin.NewBasicError(err)
-	}
+	berrs, paths := validateResponseErrors(errs)
 	*reply = ResourceProviderValidateResponse{
-		Warnings: warns,
-		Errors:   berrs,
+		Warnings:   warns,
+		Errors:     berrs,
+		ErrorPaths: paths,
 	}
 	return nil
 }
@@ -461,13 +688,11 @@ func (s *ResourceProviderServer) ValidateResource(
 	args *ResourceProviderValidateResourceArgs,
 	reply *ResourceProviderValidateResourceResponse) error {
 	warns, errs := s.Provider.ValidateResource(args.Type, args.Config)
-	berrs := make([]*plugin.BasicError, len(errs))
-	for i, err := range errs {
-		berrs[i] = plugin.NewBasicError(err)
-	}
+	berrs, paths := validateResponseErrors(errs)
 	*reply = ResourceProviderValidateResourceResponse{
-		Warnings: warns,
-		Errors:   berrs,
+		Warnings:   warns,
+		Errors:     berrs,
+		ErrorPaths: paths,
 	}
 	return nil
 }
@@ -476,8 +701,10 @@ func (s *ResourceProviderServer) Configure(
 	config *terraform.ResourceConfig,
 	reply *ResourceProviderConfigureResponse) error {
 	err := s.Provider.Configure(config)
+	_, cancelable := s.Provider.(terraform.ResourceProviderCanceler)
 	*reply = ResourceProviderConfigureResponse{
-		Error: plugin.NewBasicError(err),
+		Error:      plugin.NewBasicError(err),
+		Cancelable: cancelable,
 	}
 	return nil
 }
@@ -487,8 +714,10 @@ func (s *ResourceProviderServer) Apply(
 	result *ResourceProviderApplyResponse) error {
 	state, err := s.Provider.Apply(args.Info, args.State, args.Diff)
 	*result = ResourceProviderApplyResponse{
-		State: state,
-		Error: plugin.NewBasicError(err),
+		State:     state,
+		Error:     plugin.NewBasicError(err),
+		Transient: terraform.IsTransientError(err),
+		ErrorPath: errorPath(err),
 	}
 	return nil
 }
@@ -498,8 +727,10 @@ func (s *ResourceProviderServer) Diff(
 	result *ResourceProviderDiffResponse) error {
 	diff, err := s.Provider.Diff(args.Info, args.State, args.Config)
 	*result = ResourceProviderDiffResponse{
-		Diff:  diff,
-		Error: plugin.NewBasicError(err),
+		Diff:      diff,
+		Error:     plugin.NewBasicError(err),
+		Transient: terraform.IsTransientError(err),
+		ErrorPath: errorPath(err),
 	}
 	return nil
 }
@@ -509,12 +740,85 @@ func (s *ResourceProviderServer) Refresh(
 	result *ResourceProviderRefreshResponse) error {
 	newState, err := s.Provider.Refresh(args.Info, args.State)
 	*result = ResourceProviderRefreshResponse{
+		State:     newState,
+		Error:     plugin.NewBasicError(err),
+		Transient: terraform.IsTransientError(err),
+	}
+	return nil
+}
+
+func (s *ResourceProviderServer) WaitForReady(
+	args *ResourceProviderWaitForReadyArgs,
+	result *ResourceProviderWaitForReadyResponse) error {
+	w, ok := s.Provider.(terraform.ResourceProviderReadyWaiter)
+	if !ok {
+		*result = ResourceProviderWaitForReadyResponse{Unsupported: true}
+		return nil
+	}
+
+	newState, err := w.WaitForReady(args.Info, args.State)
+	if err == terraform.ErrWaitForReadyUnsupported {
+		*result = ResourceProviderWaitForReadyResponse{Unsupported: true}
+		return nil
+	}
+
+	*result = ResourceProviderWaitForReadyResponse{
 		State: newState,
 		Error: plugin.NewBasicError(err),
 	}
 	return nil
 }
 
+func (s *ResourceProviderServer) LookupExisting(
+	args *ResourceProviderLookupExistingArgs,
+	result *ResourceProviderLookupExistingResponse) error {
+	a, ok := s.Provider.(terraform.ResourceProviderAdopter)
+	if !ok {
+		*result = ResourceProviderLookupExistingResponse{Unsupported: true}
+		return nil
+	}
+
+	state, err := a.LookupExisting(args.Info, args.Config)
+	if err == terraform.ErrLookupExistingUnsupported {
+		*result = ResourceProviderLookupExistingResponse{Unsupported: true}
+		return nil
+	}
+
+	*result = ResourceProviderLookupExistingResponse{
+		State: state,
+		Error: plugin.NewBasicError(err),
+	}
+	return nil
+}
+
+func (s *ResourceProviderServer) RefreshBatch(
+	args *ResourceProviderRefreshBatchArgs,
+	result *ResourceProviderRefreshBatchResponse) error {
+	b, ok := s.Provider.(terraform.ResourceProviderBatchRefresher)
+	if !ok {
+		*result = ResourceProviderRefreshBatchResponse{Unsupported: true}
+		return nil
+	}
+
+	states, err := b.RefreshBatch(args.Info, args.State)
+	if err == terraform.ErrRefreshBatchUnsupported {
+		*result = ResourceProviderRefreshBatchResponse{Unsupported: true}
+		return nil
+	}
+
+	results := make([]ResourceProviderRefreshBatchResult, len(states))
+	for i, state := range states {
+		results[i].State = state
+	}
+
+	*result = ResourceProviderRefreshBatchResponse{
+		Results:   results,
+		Error:     plugin.NewBasicError(err),
+		Transient: terraform.IsTransientError(err),
+	}
+	return nil
+}
+
 func (s *ResourceProviderServer) ImportState(
 	args *ResourceProviderImportStateArgs,
 	result *ResourceProviderImportStateResponse) error {
@@ -537,13 +841,11 @@ func (s *ResourceProviderServer) ValidateDataSource(
 	args *ResourceProviderValidateResourceArgs,
 	reply *ResourceProviderValidateResourceResponse) error {
 	warns, errs := s.Provider.ValidateDataSource(args.Type, args.Config)
-	berrs := make([]*plugin.BasicError, len(errs))
-	for i, err := range errs {
-		berrs[i] = plugin.NewBasicError(err)
-	}
+	berrs, paths := validateResponseErrors(errs)
 	*reply = ResourceProviderValidateResourceResponse{
-		Warnings: warns,
-		Errors:   berrs,
+		Warnings:   warns,
+		Errors:     berrs,
+		ErrorPaths: paths,
 	}
 	return nil
 }
@@ -553,8 +855,9 @@ func (s *ResourceProviderServer) ReadDataDiff(
 	result *ResourceProviderReadDataDiffResponse) error {
 	diff, err := s.Provider.ReadDataDiff(args.Info, args.Config)
 	*result = ResourceProviderReadDataDiffResponse{
-		Diff:  diff,
-		Error: plugin.NewBasicError(err),
+		Diff:      diff,
+		Error:     plugin.NewBasicError(err),
+		Transient: terraform.IsTransientError(err),
 	}
 	return nil
 }
@@ -564,8 +867,9 @@ func (s *ResourceProviderServer) ReadDataApply(
 	result *ResourceProviderReadDataApplyResponse) error {
 	newState, err := s.Provider.ReadDataApply(args.Info, args.Diff)
 	*result = ResourceProviderReadDataApplyResponse{
-		State: newState,
-		Error: plugin.NewBasicError(err),
+		State:     newState,
+		Error:     plugin.NewBasicError(err),
+		Transient: terraform.IsTransientError(err),
 	}
 	return nil
 }
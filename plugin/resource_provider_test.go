@@ -12,6 +12,8 @@ import (
 func TestResourceProvider_impl(t *testing.T) {
 	var _ plugin.Plugin = new(ResourceProviderPlugin)
 	var _ terraform.ResourceProvider = new(ResourceProvider)
+	var _ terraform.ResourceProviderReadyWaiter = new(ResourceProvider)
+	var _ terraform.ResourceProviderCanceler = new(ResourceProvider)
 }
 
 func TestResourceProvider_stop(t *testing.T) {
@@ -69,6 +71,155 @@ func TestResourceProvider_stopErrors(t *testing.T) {
 	}
 }
 
+func TestResourceProvider_cancel(t *testing.T) {
+	// Create a mock provider
+	p := new(terraform.MockResourceProvider)
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProvider)
+
+	// The plugin reports that it can cancel when it's configured
+	if err := provider.Configure(&terraform.ResourceConfig{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Cancel
+	e := provider.(terraform.ResourceProviderCanceler).Cancel()
+	if !p.CancelCalled {
+		t.Fatal("cancel should be called")
+	}
+	if p.StopCalled {
+		t.Fatal("stop shouldn't be called")
+	}
+	if e != nil {
+		t.Fatalf("bad: %#v", e)
+	}
+}
+
+// stopOnlyPlugin serves a provider like the plugins built before the
+// Cancel RPC was added.
+type stopOnlyPlugin struct {
+	ResourceProviderPlugin
+}
+
+func (p *stopOnlyPlugin) Server(b *plugin.MuxBroker) (interface{}, error) {
+	return &stopOnlyServer{Provider: p.F()}, nil
+}
+
+type stopOnlyServer struct {
+	Provider terraform.ResourceProvider
+}
+
+func (s *stopOnlyServer) Configure(
+	config *terraform.ResourceConfig,
+	reply *ResourceProviderConfigureResponse) error {
+	*reply = ResourceProviderConfigureResponse{
+		Error: plugin.NewBasicError(s.Provider.Configure(config)),
+	}
+
+	return nil
+}
+
+func (s *stopOnlyServer) Stop(
+	_ interface{},
+	reply *ResourceProviderStopResponse) error {
+	*reply = ResourceProviderStopResponse{
+		Error: plugin.NewBasicError(s.Provider.Stop()),
+	}
+
+	return nil
+}
+
+func TestResourceProvider_cancelOldPlugin(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+	client, _ := plugin.TestPluginRPCConn(t, map[string]plugin.Plugin{
+		ProviderPluginName: &stopOnlyPlugin{
+			ResourceProviderPlugin{F: testProviderFixed(p)},
+		},
+	})
+	defer client.Close()
+
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProvider)
+	if err := provider.Configure(&terraform.ResourceConfig{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The provider is stopped instead
+	if err := provider.(terraform.ResourceProviderCanceler).Cancel(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !p.StopCalled {
+		t.Fatal("stop should be called")
+	}
+}
+
+func TestResourceProvider_cancelNotCanceler(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// The provider only implements ResourceProvider, so it can't cancel
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(struct{ terraform.ResourceProvider }{p}),
+	}))
+	defer client.Close()
+
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProvider)
+	if err := provider.Configure(&terraform.ResourceConfig{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The plugin doesn't report that it can cancel, so it's stopped
+	if err := provider.(terraform.ResourceProviderCanceler).Cancel(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.CancelCalled {
+		t.Fatal("cancel shouldn't be called")
+	}
+	if !p.StopCalled {
+		t.Fatal("stop should be called")
+	}
+}
+
+func TestResourceProvider_cancelUnconfigured(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProvider)
+
+	// The plugin hasn't reported that it can cancel, so it's stopped
+	if err := provider.(terraform.ResourceProviderCanceler).Cancel(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.CancelCalled {
+		t.Fatal("cancel shouldn't be called")
+	}
+	if !p.StopCalled {
+		t.Fatal("stop should be called")
+	}
+}
+
 func TestResourceProvider_input(t *testing.T) {
 	// Create a mock provider
 	p := new(terraform.MockResourceProvider)
@@ -249,6 +400,81 @@ func TestResourceProvider_apply(t *testing.T) {
 	}
 }
 
+func TestResourceProvider_applyTransientError(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProvider)
+
+	p.ApplyReturnError = terraform.TransientError(errors.New("throttled"))
+
+	// Apply
+	info := &terraform.InstanceInfo{}
+	state := &terraform.InstanceState{}
+	diff := &terraform.InstanceDiff{}
+	_, err = provider.Apply(info, state, diff)
+	if err == nil || err.Error() != "throttled" {
+		t.Fatalf("bad: %#v", err)
+	}
+	if !terraform.IsTransientError(err) {
+		t.Fatal("error should be transient")
+	}
+
+	// Other errors aren't transient
+	p.ApplyReturnError = errors.New("invalid")
+	_, err = provider.Apply(info, state, diff)
+	if err == nil || terraform.IsTransientError(err) {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestResourceProvider_applyAttributeError(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProvider)
+
+	p.ApplyReturnError = terraform.TransientError(&terraform.AttributeError{
+		Path: "instance_type",
+		Err:  errors.New("InvalidParameterValue"),
+	})
+
+	// Apply
+	info := &terraform.InstanceInfo{}
+	state := &terraform.InstanceState{}
+	diff := &terraform.InstanceDiff{}
+	_, err = provider.Apply(info, state, diff)
+	if err == nil || err.Error() != "InvalidParameterValue" {
+		t.Fatalf("bad: %#v", err)
+	}
+	if ae := terraform.ErrorAttribute(err); ae == nil || ae.Path != "instance_type" {
+		t.Fatalf("bad: %#v", ae)
+	}
+	if !terraform.IsTransientError(err) {
+		t.Fatal("error should be transient")
+	}
+}
+
 func TestResourceProvider_diff(t *testing.T) {
 	p := new(terraform.MockResourceProvider)
 
@@ -372,6 +598,203 @@ func TestResourceProvider_refresh(t *testing.T) {
 	}
 }
 
+func TestResourceProvider_waitForReady(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProviderReadyWaiter)
+
+	p.WaitForReadyReturn = &terraform.InstanceState{
+		ID: "bob",
+	}
+
+	// WaitForReady
+	info := &terraform.InstanceInfo{}
+	state := &terraform.InstanceState{ID: "bob"}
+	newState, err := provider.WaitForReady(info, state)
+	if !p.WaitForReadyCalled {
+		t.Fatal("wait for ready should be called")
+	}
+	if !reflect.DeepEqual(p.WaitForReadyState, state) {
+		t.Fatalf("bad: %#v", p.WaitForReadyState)
+	}
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+	if !reflect.DeepEqual(p.WaitForReadyReturn, newState) {
+		t.Fatalf("bad: %#v", newState)
+	}
+}
+
+func TestResourceProvider_waitForReadyUnsupported(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+	p.WaitForReadyReturnError = terraform.ErrWaitForReadyUnsupported
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProviderReadyWaiter)
+
+	// The error is recognized across the RPC boundary
+	info := &terraform.InstanceInfo{}
+	state := &terraform.InstanceState{ID: "bob"}
+	if _, err := provider.WaitForReady(info, state); err != terraform.ErrWaitForReadyUnsupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestResourceProvider_lookupExisting(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProviderAdopter)
+
+	p.LookupExistingReturn = &terraform.InstanceState{
+		ID: "bob",
+	}
+
+	// LookupExisting
+	info := &terraform.InstanceInfo{}
+	config := &terraform.ResourceConfig{
+		Raw: map[string]interface{}{"name": "bob"},
+	}
+	state, err := provider.LookupExisting(info, config)
+	if !p.LookupExistingCalled {
+		t.Fatal("lookup existing should be called")
+	}
+	if !reflect.DeepEqual(p.LookupExistingConfig, config) {
+		t.Fatalf("bad: %#v", p.LookupExistingConfig)
+	}
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+	if !reflect.DeepEqual(p.LookupExistingReturn, state) {
+		t.Fatalf("bad: %#v", state)
+	}
+}
+
+func TestResourceProvider_lookupExistingUnsupported(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+	p.LookupExistingReturnError = terraform.ErrLookupExistingUnsupported
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProviderAdopter)
+
+	// The error is recognized across the RPC boundary
+	info := &terraform.InstanceInfo{}
+	config := &terraform.ResourceConfig{}
+	if _, err := provider.LookupExisting(info, config); err != terraform.ErrLookupExistingUnsupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestResourceProvider_refreshBatch(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProviderBatchRefresher)
+
+	p.RefreshBatchReturn = []*terraform.InstanceState{
+		&terraform.InstanceState{ID: "bob"},
+		nil,
+	}
+
+	// RefreshBatch
+	info := []*terraform.InstanceInfo{
+		&terraform.InstanceInfo{},
+		&terraform.InstanceInfo{},
+	}
+	states := []*terraform.InstanceState{
+		&terraform.InstanceState{ID: "bob"},
+		&terraform.InstanceState{ID: "alice"},
+	}
+	newStates, err := provider.RefreshBatch(info, states)
+	if !p.RefreshBatchCalled {
+		t.Fatal("refresh batch should be called")
+	}
+	if !reflect.DeepEqual(p.RefreshBatchState, states) {
+		t.Fatalf("bad: %#v", p.RefreshBatchState)
+	}
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+	if !reflect.DeepEqual(p.RefreshBatchReturn, newStates) {
+		t.Fatalf("bad: %#v", newStates)
+	}
+}
+
+func TestResourceProvider_refreshBatchUnsupported(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProviderBatchRefresher)
+
+	// The error is recognized across the RPC boundary
+	info := []*terraform.InstanceInfo{&terraform.InstanceInfo{}}
+	states := []*terraform.InstanceState{&terraform.InstanceState{ID: "bob"}}
+	if _, err := provider.RefreshBatch(info, states); err != terraform.ErrRefreshBatchUnsupported {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
 func TestResourceProvider_importState(t *testing.T) {
 	p := new(terraform.MockResourceProvider)
 
@@ -712,6 +1135,43 @@ func TestResourceProvider_validateResource_errors(t *testing.T) {
 	}
 }
 
+func TestResourceProvider_validateResource_attributeErrors(t *testing.T) {
+	p := new(terraform.MockResourceProvider)
+
+	// Create a mock provider
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProviderFunc: testProviderFixed(p),
+	}))
+	defer client.Close()
+
+	// Request the provider
+	raw, err := client.Dispense(ProviderPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provider := raw.(terraform.ResourceProvider)
+
+	p.ValidateResourceReturnErrors = []error{
+		errors.New("foo"),
+		&terraform.AttributeError{Path: "ingress.0.from", Err: errors.New("bar")},
+	}
+
+	config := &terraform.ResourceConfig{
+		Raw: map[string]interface{}{"foo": "bar"},
+	}
+	_, e := provider.ValidateResource("foo", config)
+	if len(e) != 2 {
+		t.Fatalf("bad: %#v", e)
+	}
+	if e[0].Error() != "foo" || terraform.ErrorAttribute(e[0]) != nil {
+		t.Fatalf("bad: %#v", e[0])
+	}
+	ae := terraform.ErrorAttribute(e[1])
+	if e[1].Error() != "bar" || ae == nil || ae.Path != "ingress.0.from" {
+		t.Fatalf("bad: %#v", e[1])
+	}
+}
+
 func TestResourceProvider_validateResource_warns(t *testing.T) {
 	p := new(terraform.MockResourceProvider)
 
@@ -74,6 +74,11 @@ func (p *ResourceProvisioner) Apply(
 		err = resp.Error
 	}
 
+	// The provisioner recorded its outputs in its own copy of the state
+	for k, v := range resp.Outputs {
+		s.SetProvisionerOutput(k, v)
+	}
+
 	return err
 }
 
@@ -110,7 +115,8 @@ type ResourceProvisionerApplyArgs struct {
 }
 
 type ResourceProvisionerApplyResponse struct {
-	Error *plugin.BasicError
+	Error   *plugin.BasicError
+	Outputs map[string]string
 }
 
 type ResourceProvisionerStopResponse struct {
@@ -141,7 +147,8 @@ func (s *ResourceProvisionerServer) Apply(
 
 	err = s.Provisioner.Apply(output, args.State, args.Config)
 	*result = ResourceProvisionerApplyResponse{
-		Error: plugin.NewBasicError(err),
+		Error:   plugin.NewBasicError(err),
+		Outputs: args.State.ProvisionerOutputs(),
 	}
 	return nil
 }
@@ -100,6 +100,35 @@ func TestResourceProvisioner_apply(t *testing.T) {
 	}
 }
 
+func TestResourceProvisioner_applyOutputs(t *testing.T) {
+	p := new(terraform.MockResourceProvisioner)
+	p.ApplyFn = func(s *terraform.InstanceState, c *terraform.ResourceConfig) error {
+		s.SetProvisionerOutput("version", "1.2.3")
+		return nil
+	}
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeOpts{
+		ProvisionerFunc: testProvisionerFixed(p),
+	}))
+	defer client.Close()
+
+	raw, err := client.Dispense(ProvisionerPluginName)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provisioner := raw.(terraform.ResourceProvisioner)
+
+	state := &terraform.InstanceState{ID: "foo"}
+	err = provisioner.Apply(&terraform.MockUIOutput{}, state, &terraform.ResourceConfig{})
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	expected := map[string]string{"version": "1.2.3"}
+	if actual := state.ProvisionerOutputs(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestResourceProvisioner_validate(t *testing.T) {
 	// Create a mock provider
 	p := new(terraform.MockResourceProvisioner)
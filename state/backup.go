@@ -2,6 +2,7 @@ package state
 
 import (
 	"sync"
+	"time"
 
 	"github.com/hashicorp/terraform/terraform"
 )
@@ -18,6 +19,10 @@ type BackupState struct {
 	done bool
 }
 
+func (s *BackupState) Unwrap() State {
+	return s.Real
+}
+
 func (s *BackupState) State() *terraform.State {
 	return s.Real.State()
 }
@@ -52,6 +57,15 @@ func (s *BackupState) PersistState() error {
 	return s.Real.PersistState()
 }
 
+// StateModTimer impl.
+func (s *BackupState) ModTime() time.Time {
+	if mt, ok := s.Real.(StateModTimer); ok {
+		return mt.ModTime()
+	}
+
+	return time.Time{}
+}
+
 func (s *BackupState) Lock(info *LockInfo) (string, error) {
 	return s.Real.Lock(info)
 }
@@ -60,6 +74,12 @@ func (s *BackupState) Unlock(id string) error {
 	return s.Real.Unlock(id)
 }
 
+// SubtreeLocks implements SubtreeLocker, if the real state does.
+func (s *BackupState) SubtreeLocks() bool {
+	l, ok := s.Real.(SubtreeLocker)
+	return ok && l.SubtreeLocks()
+}
+
 func (s *BackupState) backup() error {
 	state := s.Real.State()
 	if state == nil {
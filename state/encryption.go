@@ -0,0 +1,48 @@
+package state
+
+import (
+	"fmt"
+)
+
+// Encrypter is the interface implemented by providers that encrypt state
+// at rest. It works on serialized state, so it can be applied to any
+// storage that state is written to, regardless of what encryption the
+// storage itself supports.
+type Encrypter interface {
+	// Encrypt returns the encrypted form of the given serialized state.
+	Encrypt(plaintext []byte) ([]byte, error)
+
+	// Decrypt reverses Encrypt.
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// EncrypterFactory is the factory function to create an Encrypter from
+// its configuration.
+type EncrypterFactory func(map[string]string) (Encrypter, error)
+
+// NewEncrypter returns a new Encrypter with the given type and
+// configuration. The encrypter is looked up in the BuiltinEncrypters
+// variable.
+func NewEncrypter(t string, conf map[string]string) (Encrypter, error) {
+	f, ok := BuiltinEncrypters[t]
+	if !ok {
+		return nil, fmt.Errorf("unknown state encryption type: %s", t)
+	}
+
+	return f(conf)
+}
+
+// BuiltinEncrypters is the list of encrypters that can be used with
+// NewEncrypter. Encryption providers are added here to make them
+// available to every backend.
+//
+// The configuration of an encrypter is saved with the configuration of the
+// backend in the data directory, so encrypters must never accept key
+// material in it. Keys are read from the environment or kept by a key
+// provider instead.
+var BuiltinEncrypters = map[string]EncrypterFactory{
+	"aes_gcm":       aesGCMFactory,
+	"aws_kms":       kmsFactory,
+	"vault_transit": vaultTransitFactory,
+	"age":           ageFactory,
+}
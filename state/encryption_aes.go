@@ -0,0 +1,94 @@
+package state
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// AESGCMKeyEnvVar is the environment variable that the aes_gcm encrypter
+// reads its key from. The key can't be set in the configuration, since
+// the configuration of a backend is saved in the data directory.
+const AESGCMKeyEnvVar = "TF_STATE_ENCRYPTION_KEY"
+
+// aesGCMEncrypter encrypts state with AES-256 in GCM mode, using a key
+// provided by the user.
+type aesGCMEncrypter struct {
+	aead cipher.AEAD
+}
+
+func aesGCMFactory(conf map[string]string) (Encrypter, error) {
+	if _, ok := conf["key"]; ok {
+		return nil, fmt.Errorf(
+			"aes_gcm: the key can't be set in the configuration, since it's saved "+
+				"in the data directory. Set the %s environment variable instead",
+			AESGCMKeyEnvVar)
+	}
+
+	encodedKey := os.Getenv(AESGCMKeyEnvVar)
+	if encodedKey == "" {
+		return nil, fmt.Errorf(
+			"aes_gcm: the key must be set with the %s environment variable",
+			AESGCMKeyEnvVar)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("aes_gcm: key must be base64 encoded: %s", err)
+	}
+
+	e, err := newAESGCMEncrypter(key)
+	if err != nil {
+		return nil, fmt.Errorf("aes_gcm: %s", err)
+	}
+
+	return e, nil
+}
+
+// newAESGCMEncrypter returns an aesGCMEncrypter that encrypts with the
+// given 32 byte key.
+func newAESGCMEncrypter(key []byte) (*aesGCMEncrypter, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGCMEncrypter{aead: aead}, nil
+}
+
+func (e *aesGCMEncrypter) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("aes_gcm: failed to generate nonce: %s", err)
+	}
+
+	// The nonce is stored in front of the ciphertext
+	return e.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (e *aesGCMEncrypter) Decrypt(ciphertext []byte) ([]byte, error) {
+	n := e.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, errors.New("aes_gcm: ciphertext is too short")
+	}
+
+	plaintext, err := e.aead.Open(nil, ciphertext[:n], ciphertext[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("aes_gcm: failed to decrypt state: %s", err)
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,94 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// AgeIdentityFileEnvVar is the environment variable that the age encrypter
+// reads the path of its identity file from if "identity_file" isn't set.
+const AgeIdentityFileEnvVar = "TF_STATE_AGE_IDENTITY_FILE"
+
+// ageCommand is the age command that the age encrypter runs. It's a
+// variable so that tests can replace it.
+var ageCommand = "age"
+
+// ageEncrypter encrypts state with the age command, so that it can be
+// decrypted with age itself, such as to recover a state by hand.
+type ageEncrypter struct {
+	recipients   []string
+	identityFile string
+}
+
+// ageFactory returns the age encrypter, which encrypts state to the
+// comma-separated public keys set by "recipients", and decrypts it with the
+// identity file set by "identity_file" or the TF_STATE_AGE_IDENTITY_FILE
+// environment variable. Only the public keys and the path of the identity
+// file are configured, so the private key is never saved with the
+// configuration.
+func ageFactory(conf map[string]string) (Encrypter, error) {
+	if _, ok := conf["identity"]; ok {
+		return nil, fmt.Errorf(
+			"age: the identity can't be set in the configuration, since it's saved "+
+				"in the data directory. Set identity_file or %s instead",
+			AgeIdentityFileEnvVar)
+	}
+
+	var recipients []string
+	for _, r := range strings.Split(conf["recipients"], ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			recipients = append(recipients, r)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("age: recipients is required")
+	}
+
+	identityFile := conf["identity_file"]
+	if identityFile == "" {
+		identityFile = os.Getenv(AgeIdentityFileEnvVar)
+	}
+
+	return &ageEncrypter{recipients: recipients, identityFile: identityFile}, nil
+}
+
+func (e *ageEncrypter) Encrypt(plaintext []byte) ([]byte, error) {
+	args := []string{"--encrypt"}
+	for _, r := range e.recipients {
+		args = append(args, "--recipient", r)
+	}
+
+	return runAge(args, plaintext)
+}
+
+func (e *ageEncrypter) Decrypt(ciphertext []byte) ([]byte, error) {
+	if e.identityFile == "" {
+		return nil, fmt.Errorf(
+			"age: an identity file must be set with identity_file or %s to decrypt state",
+			AgeIdentityFileEnvVar)
+	}
+
+	return runAge([]string{"--decrypt", "--identity", e.identityFile}, ciphertext)
+}
+
+// runAge runs the age command with args and input on its standard input,
+// and returns its standard output.
+func runAge(args []string, input []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(ageCommand, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("age: %s", msg)
+		}
+		return nil, fmt.Errorf("age: %s", err)
+	}
+
+	return stdout.Bytes(), nil
+}
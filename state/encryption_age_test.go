@@ -0,0 +1,103 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// testAgeCommand replaces the age command with a script that "encrypts"
+// by prefixing its input, and returns a func that restores it.
+func testAgeCommand(t *testing.T) func() {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake age command is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	script := `#!/bin/sh
+case "$*" in
+"--encrypt --recipient age1abc --recipient age1def")
+	printf 'age:'; base64 ;;
+"--decrypt --identity /keys/state.txt")
+	read -r line; printf '%s' "${line#age:}" | base64 -d ;;
+*)
+	echo "unexpected arguments: $*" >&2; exit 1 ;;
+esac
+`
+	path := filepath.Join(dir, "age")
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	old := ageCommand
+	ageCommand = path
+	return func() {
+		ageCommand = old
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAgeEncrypter(t *testing.T) {
+	defer testAgeCommand(t)()
+
+	e, err := NewEncrypter("age", map[string]string{
+		"recipients":    "age1abc, age1def",
+		"identity_file": "/keys/state.txt",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	testEncrypter(t, e)
+}
+
+func TestAgeEncrypter_identityFileEnv(t *testing.T) {
+	defer testAgeCommand(t)()
+
+	os.Setenv(AgeIdentityFileEnvVar, "/keys/state.txt")
+	defer os.Unsetenv(AgeIdentityFileEnvVar)
+
+	e, err := NewEncrypter("age", map[string]string{"recipients": "age1abc,age1def"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	testEncrypter(t, e)
+}
+
+func TestAgeEncrypter_noIdentity(t *testing.T) {
+	defer testAgeCommand(t)()
+
+	e, err := NewEncrypter("age", map[string]string{"recipients": "age1abc,age1def"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ciphertext, err := e.Encrypt([]byte("{}"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := e.Decrypt(ciphertext); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestAgeFactory_badConfig(t *testing.T) {
+	cases := []map[string]string{
+		{},
+		{"recipients": " , "},
+		{"recipients": "age1abc", "identity": "AGE-SECRET-KEY-1"},
+	}
+
+	for _, conf := range cases {
+		if _, err := NewEncrypter("age", conf); err == nil {
+			t.Fatalf("%#v: should error", conf)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// keyProvider is a service that encrypts data keys with a key that never
+// leaves it, such as AWS KMS or Vault's transit secrets engine.
+type keyProvider interface {
+	// GenerateKey returns a new 32 byte data key, and the data key
+	// encrypted by the provider.
+	GenerateKey() (key, encrypted []byte, err error)
+
+	// DecryptKey returns the data key that GenerateKey returned encrypted.
+	DecryptKey(encrypted []byte) ([]byte, error)
+}
+
+// envelopeEncrypter encrypts every state with a new data key from a
+// keyProvider, using AES-256 in GCM mode, and stores the data key
+// encrypted by the provider with the state. Only the provider can decrypt
+// the data key, so nothing that can decrypt the state is kept anywhere
+// else.
+type envelopeEncrypter struct {
+	name string
+	keys keyProvider
+}
+
+// envelope is the format that an envelopeEncrypter encrypts state to.
+type envelope struct {
+	Key  []byte `json:"key"`
+	Data []byte `json:"data"`
+}
+
+func (e *envelopeEncrypter) Encrypt(plaintext []byte) ([]byte, error) {
+	key, encryptedKey, err := e.keys.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to generate a data key: %s", e.name, err)
+	}
+
+	aead, err := newAESGCMEncrypter(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", e.name, err)
+	}
+	data, err := aead.Encrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&envelope{Key: encryptedKey, Data: data})
+}
+
+func (e *envelopeEncrypter) Decrypt(ciphertext []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(ciphertext, &env); err != nil || env.Key == nil {
+		return nil, fmt.Errorf("%s: state isn't encrypted with a data key", e.name)
+	}
+
+	key, err := e.keys.DecryptKey(env.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to decrypt the data key: %s", e.name, err)
+	}
+
+	aead, err := newAESGCMEncrypter(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", e.name, err)
+	}
+
+	return aead.Decrypt(env.Data)
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// mockKeyProvider is a keyProvider that "encrypts" data keys by reversing
+// them, and records the keys it generated.
+type mockKeyProvider struct {
+	generated [][]byte
+	err       error
+}
+
+func (p *mockKeyProvider) GenerateKey() ([]byte, []byte, error) {
+	if p.err != nil {
+		return nil, nil, p.err
+	}
+
+	key := bytes.Repeat([]byte{byte(len(p.generated) + 1)}, 32)
+	p.generated = append(p.generated, key)
+	return key, reverseBytes(key), nil
+}
+
+func (p *mockKeyProvider) DecryptKey(encrypted []byte) ([]byte, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	return reverseBytes(encrypted), nil
+}
+
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+func TestEnvelopeEncrypter(t *testing.T) {
+	keys := new(mockKeyProvider)
+	e := &envelopeEncrypter{name: "mock", keys: keys}
+
+	ciphertext := testEncrypter(t, e)
+
+	// Every state is encrypted with a new data key, which is never stored
+	// in plaintext.
+	testEncrypter(t, e)
+	if len(keys.generated) != 2 {
+		t.Fatalf("bad: %d keys generated", len(keys.generated))
+	}
+	if bytes.Contains(ciphertext, keys.generated[0]) {
+		t.Fatal("data key should be encrypted")
+	}
+
+	keys.err = errors.New("access denied")
+	if _, err := e.Decrypt(ciphertext); err == nil {
+		t.Fatal("should error")
+	}
+	if _, err := e.Encrypt([]byte("{}")); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestEnvelopeEncrypter_notEnveloped(t *testing.T) {
+	e := &envelopeEncrypter{name: "mock", keys: new(mockKeyProvider)}
+	if _, err := e.Decrypt([]byte(`{"version": 3}`)); err == nil {
+		t.Fatal("should error")
+	}
+}
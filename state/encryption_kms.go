@@ -0,0 +1,74 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// kmsAPI is the part of the AWS KMS API that the aws_kms encrypter uses.
+type kmsAPI interface {
+	GenerateDataKey(*kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(*kms.DecryptInput) (*kms.DecryptOutput, error)
+}
+
+// kmsKeyProvider is a keyProvider that encrypts data keys with an AWS KMS
+// key.
+type kmsKeyProvider struct {
+	client kmsAPI
+	keyID  string
+}
+
+// kmsFactory returns the aws_kms encrypter, which encrypts state with data
+// keys that are encrypted with the KMS key set by "key_id". The credentials
+// are found the same way as by the AWS CLI, from the environment, the shared
+// credentials file for the "profile", or the instance role.
+func kmsFactory(conf map[string]string) (Encrypter, error) {
+	keyID := conf["key_id"]
+	if keyID == "" {
+		return nil, fmt.Errorf("aws_kms: key_id is required")
+	}
+
+	awsConfig := aws.Config{}
+	if region := conf["region"]; region != "" {
+		awsConfig.Region = aws.String(region)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            awsConfig,
+		Profile:           conf["profile"],
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws_kms: %s", err)
+	}
+
+	return &envelopeEncrypter{
+		name: "aws_kms",
+		keys: &kmsKeyProvider{client: kms.New(sess), keyID: keyID},
+	}, nil
+}
+
+func (p *kmsKeyProvider) GenerateKey() ([]byte, []byte, error) {
+	out, err := p.client.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:   aws.String(p.keyID),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return out.Plaintext, out.CiphertextBlob, nil
+}
+
+func (p *kmsKeyProvider) DecryptKey(encrypted []byte) ([]byte, error) {
+	// The ciphertext identifies the key it was encrypted with, so a state
+	// can still be read after key_id is changed to another key.
+	out, err := p.client.Decrypt(&kms.DecryptInput{CiphertextBlob: encrypted})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Plaintext, nil
+}
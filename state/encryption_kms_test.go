@@ -0,0 +1,52 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// mockKMS is a kmsAPI that "encrypts" data keys by prefixing them with the
+// ID of the key.
+type mockKMS struct {
+	keyID string
+}
+
+func (m *mockKMS) GenerateDataKey(input *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+	if aws.StringValue(input.KeySpec) != kms.DataKeySpecAes256 {
+		return nil, fmt.Errorf("unexpected key spec %q", aws.StringValue(input.KeySpec))
+	}
+
+	key := bytes.Repeat([]byte{7}, 32)
+	return &kms.GenerateDataKeyOutput{
+		KeyId:          input.KeyId,
+		Plaintext:      key,
+		CiphertextBlob: append([]byte(aws.StringValue(input.KeyId)), key...),
+	}, nil
+}
+
+func (m *mockKMS) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	if !bytes.HasPrefix(input.CiphertextBlob, []byte(m.keyID)) {
+		return nil, fmt.Errorf("ciphertext isn't for key %q", m.keyID)
+	}
+
+	return &kms.DecryptOutput{Plaintext: input.CiphertextBlob[len(m.keyID):]}, nil
+}
+
+func TestKMSEncrypter(t *testing.T) {
+	e := &envelopeEncrypter{
+		name: "aws_kms",
+		keys: &kmsKeyProvider{client: &mockKMS{keyID: "alias/state"}, keyID: "alias/state"},
+	}
+
+	testEncrypter(t, e)
+}
+
+func TestKMSFactory_noKeyID(t *testing.T) {
+	if _, err := NewEncrypter("aws_kms", map[string]string{"region": "us-east-1"}); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -0,0 +1,92 @@
+package state
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"testing"
+)
+
+// testAESGCMKey sets key as the key of the aes_gcm encrypter in the
+// environment, and returns a func that unsets it.
+func testAESGCMKey(t *testing.T, key string) func() {
+	if err := os.Setenv(AESGCMKeyEnvVar, key); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return func() { os.Unsetenv(AESGCMKeyEnvVar) }
+}
+
+// testEncrypter checks that e decrypts what it encrypts, and detects
+// tampering with the ciphertext.
+func testEncrypter(t *testing.T, e Encrypter) []byte {
+	plaintext := []byte(`{"version": 3}`)
+	ciphertext, err := e.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Fatal("state should be encrypted")
+	}
+
+	actual, err := e.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(actual, plaintext) {
+		t.Fatalf("bad: %s", actual)
+	}
+
+	return ciphertext
+}
+
+func TestAESGCMEncrypter(t *testing.T) {
+	defer testAESGCMKey(t, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))()
+
+	e, err := NewEncrypter("aes_gcm", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ciphertext := testEncrypter(t, e)
+
+	// Tampering must be detected
+	ciphertext[len(ciphertext)-1] ^= 1
+	if _, err := e.Decrypt(ciphertext); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestAESGCMEncrypter_badKey(t *testing.T) {
+	cases := []string{
+		"",
+		"not base64!",
+		base64.StdEncoding.EncodeToString([]byte("too short")),
+	}
+
+	for _, key := range cases {
+		func() {
+			defer testAESGCMKey(t, key)()
+			if _, err := NewEncrypter("aes_gcm", nil); err == nil {
+				t.Fatalf("%q: should error", key)
+			}
+		}()
+	}
+}
+
+func TestAESGCMEncrypter_configKey(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	defer testAESGCMKey(t, key)()
+
+	// The configuration is saved in the data directory, so the key must
+	// never be accepted in it.
+	if _, err := NewEncrypter("aes_gcm", map[string]string{"key": key}); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestNewEncrypter_unknown(t *testing.T) {
+	if _, err := NewEncrypter("nope", nil); err == nil {
+		t.Fatal("should error")
+	}
+}
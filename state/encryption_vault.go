@@ -0,0 +1,101 @@
+package state
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// vaultTransitKeyProvider is a keyProvider that encrypts data keys with a
+// key of Vault's transit secrets engine.
+type vaultTransitKeyProvider struct {
+	client *api.Client
+	mount  string
+	key    string
+}
+
+// vaultTransitFactory returns the vault_transit encrypter, which encrypts
+// state with data keys that are encrypted with the transit key set by "key",
+// of the transit secrets engine mounted at "mount", "transit" by default. The
+// address of the Vault server is set by "address", or VAULT_ADDR, and the
+// token is read from VAULT_TOKEN.
+func vaultTransitFactory(conf map[string]string) (Encrypter, error) {
+	if _, ok := conf["token"]; ok {
+		return nil, fmt.Errorf(
+			"vault_transit: the token can't be set in the configuration, since it's " +
+				"saved in the data directory. Set the VAULT_TOKEN environment variable instead")
+	}
+
+	key := conf["key"]
+	if key == "" {
+		return nil, fmt.Errorf("vault_transit: key is required")
+	}
+	mount := strings.Trim(conf["mount"], "/")
+	if mount == "" {
+		mount = "transit"
+	}
+
+	config := api.DefaultConfig()
+	if err := config.ReadEnvironment(); err != nil {
+		return nil, fmt.Errorf("vault_transit: %s", err)
+	}
+	if addr := conf["address"]; addr != "" {
+		config.Address = addr
+	}
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("vault_transit: %s", err)
+	}
+
+	return &envelopeEncrypter{
+		name: "vault_transit",
+		keys: &vaultTransitKeyProvider{client: client, mount: mount, key: key},
+	}, nil
+}
+
+func (p *vaultTransitKeyProvider) GenerateKey() ([]byte, []byte, error) {
+	secret, err := p.client.Logical().Write(
+		fmt.Sprintf("%s/datakey/plaintext/%s", p.mount, p.key),
+		map[string]interface{}{"bits": 256})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := vaultPlaintext(secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	ciphertext, _ := secret.Data["ciphertext"].(string)
+	if ciphertext == "" {
+		return nil, nil, fmt.Errorf("no ciphertext returned")
+	}
+
+	return key, []byte(ciphertext), nil
+}
+
+func (p *vaultTransitKeyProvider) DecryptKey(encrypted []byte) ([]byte, error) {
+	secret, err := p.client.Logical().Write(
+		fmt.Sprintf("%s/decrypt/%s", p.mount, p.key),
+		map[string]interface{}{"ciphertext": string(encrypted)})
+	if err != nil {
+		return nil, err
+	}
+
+	return vaultPlaintext(secret)
+}
+
+// vaultPlaintext returns the base64 encoded plaintext that the transit
+// secrets engine returned.
+func vaultPlaintext(secret *api.Secret) ([]byte, error) {
+	if secret == nil {
+		return nil, fmt.Errorf("no data returned")
+	}
+	plaintext, _ := secret.Data["plaintext"].(string)
+	if plaintext == "" {
+		return nil, fmt.Errorf("no plaintext returned")
+	}
+
+	return base64.StdEncoding.DecodeString(plaintext)
+}
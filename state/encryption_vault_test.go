@@ -0,0 +1,70 @@
+package state
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestVaultTransitEncrypter(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var data map[string]interface{}
+		switch r.URL.Path {
+		case "/v1/secrets/datakey/plaintext/state":
+			data = map[string]interface{}{
+				"plaintext":  key,
+				"ciphertext": "vault:v1:encrypted",
+			}
+		case "/v1/secrets/decrypt/state":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["ciphertext"] != "vault:v1:encrypted" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data = map[string]interface{}{"plaintext": key}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer srv.Close()
+
+	os.Setenv("VAULT_TOKEN", "s.token")
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	e, err := NewEncrypter("vault_transit", map[string]string{
+		"address": srv.URL,
+		"mount":   "secrets/",
+		"key":     "state",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	testEncrypter(t, e)
+}
+
+func TestVaultTransitFactory_badConfig(t *testing.T) {
+	cases := []map[string]string{
+		{},
+		{"key": "state", "token": "s.token"},
+	}
+
+	for _, conf := range cases {
+		if _, err := NewEncrypter("vault_transit", conf); err == nil {
+			t.Fatalf("%#v: should error", conf)
+		}
+	}
+}
@@ -41,6 +41,9 @@ type LocalState struct {
 	state     *terraform.State
 	readState *terraform.State
 	written   bool
+
+	// modTime is the modification time of the state file that was read
+	modTime time.Time
 }
 
 // SetState will force a specific state in-memory for this local state.
@@ -112,6 +115,7 @@ func (s *LocalState) RefreshState() error {
 	defer s.mu.Unlock()
 
 	var reader io.Reader
+	var modTime time.Time
 	if !s.written {
 		// we haven't written a state file yet, so load from Path
 		f, err := os.Open(s.Path)
@@ -128,6 +132,10 @@ func (s *LocalState) RefreshState() error {
 		} else {
 			defer f.Close()
 			reader = f
+
+			if info, err := f.Stat(); err == nil {
+				modTime = info.ModTime()
+			}
 		}
 	} else {
 		// no state to refresh
@@ -138,6 +146,10 @@ func (s *LocalState) RefreshState() error {
 		// we have a state file, make sure we're at the start
 		s.stateFileOut.Seek(0, os.SEEK_SET)
 		reader = s.stateFileOut
+
+		if info, err := s.stateFileOut.Stat(); err == nil {
+			modTime = info.ModTime()
+		}
 	}
 
 	state, err := terraform.ReadState(reader)
@@ -148,9 +160,18 @@ func (s *LocalState) RefreshState() error {
 
 	s.state = state
 	s.readState = state
+	s.modTime = modTime
 	return nil
 }
 
+// StateModTimer impl.
+func (s *LocalState) ModTime() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.modTime
+}
+
 // Lock implements a local filesystem state.Locker.
 func (s *LocalState) Lock(info *LockInfo) (string, error) {
 	s.mu.Lock()
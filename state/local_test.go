@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform/terraform"
 )
@@ -140,11 +141,38 @@ func TestLocalState_nonExist(t *testing.T) {
 	}
 }
 
+func TestLocalState_modTime(t *testing.T) {
+	ls := testLocalState(t)
+	defer os.Remove(ls.Path)
+
+	modTime := time.Date(2017, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(ls.Path, modTime, modTime); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ls.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if actual := ls.ModTime(); !actual.Equal(modTime) {
+		t.Fatalf("bad: %s", actual)
+	}
+
+	// A state that doesn't exist has no modification time
+	ls = &LocalState{Path: "ishouldntexist"}
+	if err := ls.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual := ls.ModTime(); !actual.IsZero() {
+		t.Fatalf("bad: %s", actual)
+	}
+}
+
 func TestLocalState_impl(t *testing.T) {
 	var _ StateReader = new(LocalState)
 	var _ StateWriter = new(LocalState)
 	var _ StatePersister = new(LocalState)
 	var _ StateRefresher = new(LocalState)
+	var _ StateModTimer = new(LocalState)
 }
 
 func testLocalState(t *testing.T) *LocalState {
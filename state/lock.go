@@ -1,6 +1,8 @@
 package state
 
 import (
+	"time"
+
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -13,6 +15,10 @@ type LockDisabled struct {
 	Inner State
 }
 
+func (s *LockDisabled) Unwrap() State {
+	return s.Inner
+}
+
 func (s *LockDisabled) State() *terraform.State {
 	return s.Inner.State()
 }
@@ -29,6 +35,14 @@ func (s *LockDisabled) PersistState() error {
 	return s.Inner.PersistState()
 }
 
+func (s *LockDisabled) ModTime() time.Time {
+	if mt, ok := s.Inner.(StateModTimer); ok {
+		return mt.ModTime()
+	}
+
+	return time.Time{}
+}
+
 func (s *LockDisabled) Lock(info *LockInfo) (string, error) {
 	return "", nil
 }
@@ -36,3 +50,55 @@ func (s *LockDisabled) Lock(info *LockInfo) (string, error) {
 func (s *LockDisabled) Unlock(id string) error {
 	return nil
 }
+
+// ExternalLock implements State and Locker, taking locks with a separate
+// Locker instead of the inner State. This is useful for storing states
+// somewhere that doesn't support locking while still locking them.
+type ExternalLock struct {
+	Inner  State
+	Locker Locker
+}
+
+func (s *ExternalLock) Unwrap() State {
+	return s.Inner
+}
+
+func (s *ExternalLock) State() *terraform.State {
+	return s.Inner.State()
+}
+
+func (s *ExternalLock) WriteState(v *terraform.State) error {
+	return s.Inner.WriteState(v)
+}
+
+func (s *ExternalLock) RefreshState() error {
+	return s.Inner.RefreshState()
+}
+
+func (s *ExternalLock) PersistState() error {
+	return s.Inner.PersistState()
+}
+
+func (s *ExternalLock) ModTime() time.Time {
+	if mt, ok := s.Inner.(StateModTimer); ok {
+		return mt.ModTime()
+	}
+
+	return time.Time{}
+}
+
+func (s *ExternalLock) Lock(info *LockInfo) (string, error) {
+	return s.Locker.Lock(info)
+}
+
+func (s *ExternalLock) Unlock(id string) error {
+	return s.Locker.Unlock(id)
+}
+
+// SubtreeLocks implements SubtreeLocker. Since the locks are taken with
+// the Locker, subtrees can only be locked if the Locker supports it, not
+// the inner State.
+func (s *ExternalLock) SubtreeLocks() bool {
+	l, ok := s.Locker.(SubtreeLocker)
+	return ok && l.SubtreeLocks()
+}
@@ -0,0 +1,102 @@
+package state
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ErrReadOnly is returned when writing a state in read-only mode.
+var ErrReadOnly = errors.New("the state can't be written in read-only mode")
+
+// NewReadOnly returns a State that fails to write or persist s with
+// ErrReadOnly. Locks are still taken with s, so that the state isn't read
+// while it's being written elsewhere.
+//
+// The returned State implements the optional interfaces that s implements,
+// StateModTimer and SubtreeLocker, and only those, so that it's used the
+// same way as s. New optional interfaces must be forwarded here.
+func NewReadOnly(s State) State {
+	ro := &ReadOnly{Inner: s}
+
+	_, modTimer := s.(StateModTimer)
+	_, subtreeLocker := s.(SubtreeLocker)
+	switch {
+	case modTimer && subtreeLocker:
+		return &readOnlyModTimerSubtreeLocker{ro}
+	case modTimer:
+		return &readOnlyModTimer{ro}
+	case subtreeLocker:
+		return &readOnlySubtreeLocker{ro}
+	default:
+		return ro
+	}
+}
+
+// ReadOnly implements State, Locker and Unwrapper, failing to write or
+// persist the inner State with ErrReadOnly. Use NewReadOnly to also forward
+// the optional interfaces of the inner State.
+type ReadOnly struct {
+	Inner State
+}
+
+func (s *ReadOnly) Unwrap() State {
+	return s.Inner
+}
+
+func (s *ReadOnly) State() *terraform.State {
+	return s.Inner.State()
+}
+
+func (s *ReadOnly) WriteState(v *terraform.State) error {
+	return ErrReadOnly
+}
+
+func (s *ReadOnly) RefreshState() error {
+	return s.Inner.RefreshState()
+}
+
+func (s *ReadOnly) PersistState() error {
+	return ErrReadOnly
+}
+
+func (s *ReadOnly) Lock(info *LockInfo) (string, error) {
+	return s.Inner.Lock(info)
+}
+
+func (s *ReadOnly) Unlock(id string) error {
+	return s.Inner.Unlock(id)
+}
+
+// readOnlyModTimer is a ReadOnly that implements StateModTimer.
+type readOnlyModTimer struct {
+	*ReadOnly
+}
+
+func (s *readOnlyModTimer) ModTime() time.Time {
+	return s.Inner.(StateModTimer).ModTime()
+}
+
+// readOnlySubtreeLocker is a ReadOnly that implements SubtreeLocker.
+type readOnlySubtreeLocker struct {
+	*ReadOnly
+}
+
+func (s *readOnlySubtreeLocker) SubtreeLocks() bool {
+	return s.Inner.(SubtreeLocker).SubtreeLocks()
+}
+
+// readOnlyModTimerSubtreeLocker is a ReadOnly that implements both
+// StateModTimer and SubtreeLocker.
+type readOnlyModTimerSubtreeLocker struct {
+	*ReadOnly
+}
+
+func (s *readOnlyModTimerSubtreeLocker) ModTime() time.Time {
+	return s.Inner.(StateModTimer).ModTime()
+}
+
+func (s *readOnlyModTimerSubtreeLocker) SubtreeLocks() bool {
+	return s.Inner.(SubtreeLocker).SubtreeLocks()
+}
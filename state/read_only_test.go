@@ -0,0 +1,112 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// modTimeState is an InmemState that implements StateModTimer.
+type modTimeState struct {
+	*InmemState
+	modTime time.Time
+}
+
+func (s *modTimeState) ModTime() time.Time {
+	return s.modTime
+}
+
+// subtreeLockState is an InmemState that implements SubtreeLocker.
+type subtreeLockState struct {
+	*InmemState
+}
+
+func (s *subtreeLockState) SubtreeLocks() bool {
+	return true
+}
+
+func TestReadOnly(t *testing.T) {
+	initial := terraform.NewState()
+	initial.Lineage = "foo"
+	inner := &InmemState{state: initial}
+	s := NewReadOnly(inner)
+
+	if err := s.WriteState(terraform.NewState()); err != ErrReadOnly {
+		t.Fatalf("bad: %v", err)
+	}
+	if err := s.PersistState(); err != ErrReadOnly {
+		t.Fatalf("bad: %v", err)
+	}
+	if err := s.RefreshState(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if s.State().Lineage != "foo" {
+		t.Fatalf("bad: %#v", s.State())
+	}
+
+	// The optional interfaces that the inner state doesn't implement
+	// aren't implemented either
+	if _, ok := s.(StateModTimer); ok {
+		t.Fatal("shouldn't implement StateModTimer")
+	}
+	if _, ok := s.(SubtreeLocker); ok {
+		t.Fatal("shouldn't implement SubtreeLocker")
+	}
+}
+
+func TestReadOnly_locker(t *testing.T) {
+	inner := &inmemLocker{InmemState: new(InmemState)}
+	s := NewReadOnly(inner)
+
+	id, err := s.Lock(NewLockInfo())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The lock is taken with the inner state
+	if _, err := inner.Lock(NewLockInfo()); err == nil {
+		t.Fatal("inner state should be locked")
+	}
+	if err := s.Unlock(id); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestReadOnly_unwrapper(t *testing.T) {
+	inner := &InmemState{state: terraform.NewState()}
+	s := NewReadOnly(inner)
+
+	u, ok := s.(Unwrapper)
+	if !ok || u.Unwrap() != inner {
+		t.Fatalf("bad: %#v", s)
+	}
+}
+
+func TestReadOnly_modTimer(t *testing.T) {
+	now := time.Now()
+	s := NewReadOnly(&modTimeState{InmemState: new(InmemState), modTime: now})
+
+	mt, ok := s.(StateModTimer)
+	if !ok || !mt.ModTime().Equal(now) {
+		t.Fatalf("bad: %#v", s)
+	}
+	if _, ok := s.(SubtreeLocker); ok {
+		t.Fatal("shouldn't implement SubtreeLocker")
+	}
+}
+
+func TestReadOnly_subtreeLocker(t *testing.T) {
+	s := NewReadOnly(&subtreeLockState{InmemState: new(InmemState)})
+
+	l, ok := s.(SubtreeLocker)
+	if !ok || !l.SubtreeLocks() {
+		t.Fatalf("bad: %#v", s)
+	}
+	if _, ok := s.(StateModTimer); ok {
+		t.Fatal("shouldn't implement StateModTimer")
+	}
+	if err := s.PersistState(); err != ErrReadOnly {
+		t.Fatalf("bad: %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform/helper/logging"
+	"github.com/hashicorp/terraform/state"
+)
+
+// ClientBlobStorer is an optional interface that allows a remote state
+// backend to store blobs next to the state, so that a BlobClient can keep
+// the large values of the state out of the state itself.
+type ClientBlobStorer interface {
+	Client
+
+	// PutBlob stores data as the blob with the given ID, replacing it if
+	// it exists.
+	PutBlob(id string, data []byte) error
+
+	// GetBlob returns the data of the blob with the given ID, or
+	// ErrBlobNotFound if there is no such blob.
+	GetBlob(id string) ([]byte, error)
+
+	// DeleteBlob deletes the blob with the given ID. Deleting a blob that
+	// doesn't exist isn't an error.
+	DeleteBlob(id string) error
+}
+
+// ErrBlobNotFound is returned by ClientBlobStorer.GetBlob when there is
+// no blob with the given ID.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// blobLog logs the blobs that fail to be cleaned up.
+var blobLog = logging.New("state/remote")
+
+// BlobStorer returns c as a ClientBlobStorer, or nil if c can't store
+// blobs.
+func BlobStorer(c Client) ClientBlobStorer {
+	switch c := c.(type) {
+	case *EncryptedClient:
+		return c.blobStorer()
+	case *encryptedLockingClient:
+		return c.blobStorer()
+	case *CompressedClient:
+		return c.blobStorer()
+	case *compressedLockingClient:
+		return c.blobStorer()
+	case *BlobClient:
+		return BlobStorer(c.Client)
+	case *blobLockingClient:
+		return BlobStorer(c.Client)
+	case *DynamicClient:
+		return c.blobStorer()
+	case *dynamicLockingClient:
+		return c.blobStorer()
+	case ClientBlobStorer:
+		return c
+	}
+
+	return nil
+}
+
+// BlobClient is a Client that stores the strings of the state that are at
+// least MinSize bytes long, such as rendered templates, as blobs with a
+// ClientBlobStorer, and replaces them with references to the blobs. This
+// keeps the state small, so that it's fast to store and read, and the
+// blobs of values that didn't change aren't stored again.
+//
+// Blobs are named after the SHA-256 of their data, so a value that occurs
+// more than once is only stored once. The blobs that a new state doesn't
+// refer to anymore are deleted, unless the client keeps previous versions
+// of the state, which may still refer to them.
+type BlobClient struct {
+	Client  Client
+	MinSize int
+
+	// blobs are the values of the blobs referred to by the state that was
+	// last read or stored, by ID.
+	blobs map[string]string
+}
+
+// blobLockingClient is a BlobClient for a client that supports locking.
+type blobLockingClient struct {
+	*BlobClient
+	state.Locker
+}
+
+// NewBlobClient returns a Client that stores the values of at least minSize
+// bytes in the states stored with c as blobs. c must be a ClientBlobStorer,
+// possibly wrapped by an EncryptedClient or CompressedClient, in which
+// case the blobs are encrypted or compressed too. The returned client
+// supports locking if c does.
+func NewBlobClient(c Client, minSize int) Client {
+	bc := &BlobClient{Client: c, MinSize: minSize}
+	if l, ok := c.(ClientLocker); ok {
+		return &blobLockingClient{BlobClient: bc, Locker: l}
+	}
+
+	return bc
+}
+
+// blobRefKey is the only field of the object that replaces a value stored
+// as a blob, and its value is the ID of the blob.
+const blobRefKey = "$blob"
+
+func (c *BlobClient) Get() (*Payload, error) {
+	payload, err := c.Client.Get()
+	if err != nil || payload == nil {
+		return payload, err
+	}
+
+	payload, blobs, err := c.restore(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	c.blobs = blobs
+	return payload, nil
+}
+
+func (c *BlobClient) Put(data []byte) error {
+	store := BlobStorer(c.Client)
+	if store == nil {
+		return fmt.Errorf("the state storage doesn't support blobs")
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+
+	blobs := make(map[string]string)
+	v = walkStrings(v, func(s string) interface{} {
+		if len(s) < c.MinSize {
+			return s
+		}
+
+		sum := sha256.Sum256([]byte(s))
+		id := hex.EncodeToString(sum[:])
+		blobs[id] = s
+		return map[string]interface{}{blobRefKey: id}
+	})
+
+	if len(blobs) > 0 {
+		var err error
+		data, err = json.MarshalIndent(v, "", "    ")
+		if err != nil {
+			return err
+		}
+	}
+
+	// The blobs are stored before the state that refers to them, and the
+	// ones that were already stored aren't stored again.
+	ids := make([]string, 0, len(blobs))
+	for id := range blobs {
+		if _, ok := c.blobs[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := store.PutBlob(id, []byte(blobs[id])); err != nil {
+			return fmt.Errorf("Error storing blob %s: %s", id, err)
+		}
+	}
+
+	if err := c.Client.Put(data); err != nil {
+		return err
+	}
+
+	old := c.blobs
+	c.blobs = blobs
+	c.deleteBlobs(store, old)
+	return nil
+}
+
+func (c *BlobClient) Delete() error {
+	store := BlobStorer(c.Client)
+	if store == nil {
+		return c.Client.Delete()
+	}
+
+	// Find the blobs of the state before it's gone
+	var blobs map[string]string
+	payload, err := c.Client.Get()
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		blobs = make(map[string]string)
+		for _, id := range blobRefs(payload.Data) {
+			blobs[id] = ""
+		}
+	}
+
+	if err := c.Client.Delete(); err != nil {
+		return err
+	}
+
+	c.blobs = nil
+	c.deleteBlobs(store, blobs)
+	return nil
+}
+
+// deleteBlobs deletes the given blobs that the current state doesn't refer
+// to, unless the previous versions of the state are kept. The state is
+// already stored by then, so failing to delete them is only logged.
+func (c *BlobClient) deleteBlobs(store ClientBlobStorer, blobs map[string]string) {
+	if Versioner(c.Client) != nil {
+		return
+	}
+
+	for id := range blobs {
+		if _, ok := c.blobs[id]; ok {
+			continue
+		}
+
+		if err := store.DeleteBlob(id); err != nil {
+			blobLog.Warn("error deleting unused blob %s: %s", id, err)
+		}
+	}
+}
+
+// restore replaces the references to blobs in the state stored by Put with
+// the values of the blobs. It returns the values by ID along with the
+// state. The values of the blobs that were last read or stored aren't read
+// again.
+func (c *BlobClient) restore(payload *Payload) (*Payload, map[string]string, error) {
+	blobs := make(map[string]string)
+
+	// Avoid decoding large states when they don't refer to blobs
+	if !bytes.Contains(payload.Data, []byte(`"`+blobRefKey+`"`)) {
+		return payload, blobs, nil
+	}
+
+	store := BlobStorer(c.Client)
+	if store == nil {
+		return nil, nil, fmt.Errorf("the state storage doesn't support blobs")
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(payload.Data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, nil, err
+	}
+
+	var err error
+	v = walkBlobRefs(v, func(id string) interface{} {
+		if err != nil {
+			return nil
+		}
+
+		if s, ok := blobs[id]; ok {
+			return s
+		}
+		if s, ok := c.blobs[id]; ok {
+			blobs[id] = s
+			return s
+		}
+
+		if !validBlobID(id) {
+			err = fmt.Errorf("Error reading state: invalid blob reference %q", id)
+			return nil
+		}
+
+		data, getErr := store.GetBlob(id)
+		if getErr != nil {
+			err = fmt.Errorf("Error reading blob %s of the state: %s", id, getErr)
+			return nil
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != id {
+			err = fmt.Errorf(
+				"Error reading blob %s of the state: the blob is corrupted", id)
+			return nil
+		}
+
+		blobs[id] = string(data)
+		return blobs[id]
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	md5 := md5.Sum(data)
+	return &Payload{Data: data, MD5: md5[:], ModTime: payload.ModTime}, blobs, nil
+}
+
+// versioner returns a ClientVersioner that restores the blobs of the
+// previous versions of the state, or nil if the client doesn't keep them.
+func (c *BlobClient) versioner() ClientVersioner {
+	v := Versioner(c.Client)
+	if v == nil {
+		return nil
+	}
+
+	return &blobVersioner{BlobClient: c, inner: v}
+}
+
+type blobVersioner struct {
+	*BlobClient
+
+	inner ClientVersioner
+}
+
+func (c *blobVersioner) Versions() ([]*Version, error) {
+	return c.inner.Versions()
+}
+
+func (c *blobVersioner) GetVersion(id string) (*Payload, error) {
+	payload, err := c.inner.GetVersion(id)
+	if err != nil || payload == nil {
+		return payload, err
+	}
+
+	payload, _, err = c.restore(payload)
+	return payload, err
+}
+
+// blobRefs returns the IDs of the blobs that the JSON data refers to.
+func blobRefs(data []byte) []string {
+	if !bytes.Contains(data, []byte(`"`+blobRefKey+`"`)) {
+		return nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+
+	var ids []string
+	walkBlobRefs(v, func(id string) interface{} {
+		if validBlobID(id) {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+
+	return ids
+}
+
+// walkBlobRefs calls fn with the ID of every reference to a blob in v,
+// which was decoded from JSON, and replaces the reference with the result.
+func walkBlobRefs(v interface{}, fn func(string) interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		for i, e := range v {
+			v[i] = walkBlobRefs(e, fn)
+		}
+	case map[string]interface{}:
+		if id, ok := v[blobRefKey].(string); ok && len(v) == 1 {
+			return fn(id)
+		}
+		for k, e := range v {
+			v[k] = walkBlobRefs(e, fn)
+		}
+	}
+
+	return v
+}
+
+// validBlobID returns true if id is a hex encoded SHA-256, which is all
+// that clients need to accept as the ID of a blob.
+func validBlobID(id string) bool {
+	if len(id) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
@@ -0,0 +1,282 @@
+package remote
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+)
+
+// memBlobClient is a memClient that stores blobs.
+type memBlobClient struct {
+	memClient
+	blobs map[string][]byte
+	puts  int
+}
+
+func (c *memBlobClient) PutBlob(id string, data []byte) error {
+	if c.blobs == nil {
+		c.blobs = make(map[string][]byte)
+	}
+
+	c.blobs[id] = data
+	c.puts++
+	return nil
+}
+
+func (c *memBlobClient) GetBlob(id string) ([]byte, error) {
+	data, ok := c.blobs[id]
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+
+	return data, nil
+}
+
+func (c *memBlobClient) DeleteBlob(id string) error {
+	delete(c.blobs, id)
+	return nil
+}
+
+// memVersioningBlobClient is a memVersioningClient that stores blobs.
+type memVersioningBlobClient struct {
+	memVersioningClient
+	memBlobClient
+}
+
+func (c *memVersioningBlobClient) Get() (*Payload, error) {
+	return c.memVersioningClient.Get()
+}
+
+func (c *memVersioningBlobClient) Put(data []byte) error {
+	return c.memVersioningClient.Put(data)
+}
+
+func (c *memVersioningBlobClient) Delete() error {
+	return c.memVersioningClient.Delete()
+}
+
+func TestBlobClient_impl(t *testing.T) {
+	var _ Client = new(BlobClient)
+	var _ ClientLocker = new(blobLockingClient)
+	var _ ClientBlobStorer = new(memBlobClient)
+}
+
+func TestBlobClient(t *testing.T) {
+	inner := new(memBlobClient)
+	c := NewBlobClient(inner, 1024)
+	if _, ok := c.(ClientLocker); ok {
+		t.Fatal("client shouldn't support locking")
+	}
+
+	// States without large values are stored as-is
+	testClient(t, c)
+
+	c = NewBlobClient(inner, 16)
+
+	large := strings.Repeat("x", 32)
+	data := []byte(`{
+    "version": 3,
+    "serial": 1,
+    "resources": [
+        {"rendered": "` + large + `", "short": "foo"},
+        {"rendered": "` + large + `"}
+    ]
+}`)
+	if err := c.Put(data); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The value is only stored once, in a blob
+	if bytes.Contains(inner.data, []byte(large)) {
+		t.Fatalf("value should be stored as a blob: %s", inner.data)
+	}
+	if len(inner.blobs) != 1 || inner.puts != 1 {
+		t.Fatalf("bad: %#v", inner.blobs)
+	}
+
+	p, err := c.Get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if n := bytes.Count(p.Data, []byte(large)); n != 2 {
+		t.Fatalf("bad: %s", p.Data)
+	}
+	if !bytes.Contains(p.Data, []byte(`"foo"`)) {
+		t.Fatalf("bad: %s", p.Data)
+	}
+
+	// Storing the same value again doesn't store the blob again
+	if err := c.Put(data); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if inner.puts != 1 {
+		t.Fatalf("blob stored again: %d", inner.puts)
+	}
+
+	// Blobs that aren't referred to anymore are deleted
+	if err := c.Put([]byte(`{"version": 3, "serial": 2}`)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(inner.blobs) != 0 {
+		t.Fatalf("bad: %#v", inner.blobs)
+	}
+}
+
+func TestBlobClient_readOnly(t *testing.T) {
+	inner := new(memBlobClient)
+	large := strings.Repeat("x", 32)
+	if err := NewBlobClient(inner, 16).Put([]byte(`{"value": "` + large + `"}`)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// A new client reads the blobs back
+	p, err := NewBlobClient(inner, 16).Get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Contains(p.Data, []byte(large)) {
+		t.Fatalf("bad: %s", p.Data)
+	}
+
+	// A missing blob is an error
+	inner.blobs = nil
+	if _, err := NewBlobClient(inner, 16).Get(); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestBlobClient_corrupted(t *testing.T) {
+	inner := new(memBlobClient)
+	if err := NewBlobClient(inner, 16).Put([]byte(`{"value": "` + strings.Repeat("x", 32) + `"}`)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for id := range inner.blobs {
+		inner.blobs[id] = []byte("tampered")
+	}
+	_, err := NewBlobClient(inner, 16).Get()
+	if err == nil || !strings.Contains(err.Error(), "corrupted") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// References that aren't blob IDs are rejected before they're read
+	inner.data = []byte(`{"value": {"$blob": "../terraform.tfstate"}}`)
+	_, err = NewBlobClient(inner, 16).Get()
+	if err == nil || !strings.Contains(err.Error(), "invalid blob reference") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestBlobClient_delete(t *testing.T) {
+	inner := new(memBlobClient)
+	if err := NewBlobClient(inner, 16).Put([]byte(`{"value": "` + strings.Repeat("x", 32) + `"}`)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := NewBlobClient(inner, 16).Delete(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if inner.data != nil || len(inner.blobs) != 0 {
+		t.Fatalf("bad: %s %#v", inner.data, inner.blobs)
+	}
+}
+
+func TestBlobClient_versioned(t *testing.T) {
+	inner := new(memVersioningBlobClient)
+	c := NewBlobClient(inner, 16)
+
+	large := strings.Repeat("x", 32)
+	if err := c.Put([]byte(`{"value": "` + large + `"}`)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := c.Put([]byte(`{"value": "short"}`)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The previous version still refers to the blob, so it's kept
+	if len(inner.blobs) != 1 {
+		t.Fatalf("bad: %#v", inner.blobs)
+	}
+
+	v := Versioner(c)
+	if v == nil {
+		t.Fatal("client should keep versions")
+	}
+	p, err := v.GetVersion("0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Contains(p.Data, []byte(large)) {
+		t.Fatalf("bad: %s", p.Data)
+	}
+}
+
+func TestBlobClient_locking(t *testing.T) {
+	inner := new(memLockingClient)
+	c := NewBlobClient(inner, 16)
+
+	l, ok := c.(ClientLocker)
+	if !ok {
+		t.Fatal("client should support locking")
+	}
+	if _, err := l.Lock(state.NewLockInfo()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !inner.locked {
+		t.Fatal("inner client should be locked")
+	}
+}
+
+func TestBlobClient_wrapped(t *testing.T) {
+	inner := new(memBlobClient)
+	large := strings.Repeat("secret", 10)
+
+	wrapped := NewCompressedClient(
+		NewEncryptedClient(inner, "aes_gcm", testEncrypter(t)), true, 0)
+	if BlobStorer(wrapped) == nil {
+		t.Fatal("wrapped client should store blobs")
+	}
+
+	c := NewBlobClient(wrapped, 16)
+	if err := c.Put([]byte(`{"value": "` + large + `"}`)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The blobs are encrypted like the state
+	if len(inner.blobs) != 1 {
+		t.Fatalf("bad: %#v", inner.blobs)
+	}
+	for _, data := range inner.blobs {
+		if bytes.Contains(data, []byte("secret")) {
+			t.Fatalf("blob should be encrypted: %s", data)
+		}
+	}
+
+	p, err := NewBlobClient(wrapped, 16).Get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Contains(p.Data, []byte(large)) {
+		t.Fatalf("bad: %s", p.Data)
+	}
+}
+
+func TestBlobStorer_notSupported(t *testing.T) {
+	if BlobStorer(new(memClient)) != nil {
+		t.Fatal("memClient can't store blobs")
+	}
+	if BlobStorer(NewEncryptedClient(new(memClient), "aes_gcm", nil)) != nil {
+		t.Fatal("wrapped memClient can't store blobs")
+	}
+}
+
+func TestBlobStorer_blobClient(t *testing.T) {
+	if BlobStorer(NewBlobClient(new(memBlobClient), 16)) == nil {
+		t.Fatal("blob client should store blobs")
+	}
+	if BlobStorer(NewBlobClient(new(memSubtreeClient), 16)) == nil {
+		t.Fatal("locking blob client should store blobs")
+	}
+}
@@ -0,0 +1,336 @@
+package remote
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/hashicorp/terraform/state"
+)
+
+// CompressedClient is a Client that compresses the data it stores, so that
+// large states are faster to upload and download.
+//
+// Compressed data is detected when it's read, so data that was stored
+// before compression was enabled, or after it was disabled, can still be
+// read.
+type CompressedClient struct {
+	Client Client
+
+	// Gzip compresses the data with gzip.
+	Gzip bool
+
+	// MinStringLength, if positive, replaces the strings of at least this
+	// length that occur more than once in the state, such as user data or
+	// policies, with references to a table that holds each of them once.
+	MinStringLength int
+}
+
+// compressedLockingClient is a CompressedClient for a client that supports
+// locking.
+type compressedLockingClient struct {
+	*CompressedClient
+	state.Locker
+}
+
+// NewCompressedClient returns a Client that compresses the data stored with
+// c. The returned client supports locking if c does.
+func NewCompressedClient(c Client, gzip bool, minStringLength int) Client {
+	cc := &CompressedClient{Client: c, Gzip: gzip, MinStringLength: minStringLength}
+	if l, ok := c.(ClientLocker); ok {
+		return &compressedLockingClient{CompressedClient: cc, Locker: l}
+	}
+
+	return cc
+}
+
+func (c *CompressedClient) Get() (*Payload, error) {
+	payload, err := c.Client.Get()
+	if err != nil || payload == nil {
+		return payload, err
+	}
+
+	return decompressPayload(payload)
+}
+
+func (c *CompressedClient) Put(data []byte) error {
+	var err error
+	if c.MinStringLength > 0 {
+		data, err = dedupStrings(data, c.MinStringLength)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Gzip {
+		data, err = gzipData(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.Client.Put(data)
+}
+
+func (c *CompressedClient) Delete() error {
+	return c.Client.Delete()
+}
+
+// versioner returns a ClientVersioner that decompresses the previous
+// versions of the state, or nil if the compressed client doesn't keep them.
+func (c *CompressedClient) versioner() ClientVersioner {
+	v := Versioner(c.Client)
+	if v == nil {
+		return nil
+	}
+
+	return &compressedVersioner{CompressedClient: c, inner: v}
+}
+
+type compressedVersioner struct {
+	*CompressedClient
+
+	inner ClientVersioner
+}
+
+func (c *compressedVersioner) Versions() ([]*Version, error) {
+	return c.inner.Versions()
+}
+
+func (c *compressedVersioner) GetVersion(id string) (*Payload, error) {
+	payload, err := c.inner.GetVersion(id)
+	if err != nil || payload == nil {
+		return payload, err
+	}
+
+	return decompressPayload(payload)
+}
+
+// blobStorer returns a ClientBlobStorer that compresses the blobs it stores
+// if gzip is enabled, or nil if the compressed client can't store blobs.
+func (c *CompressedClient) blobStorer() ClientBlobStorer {
+	b := BlobStorer(c.Client)
+	if b == nil {
+		return nil
+	}
+
+	return &compressedBlobStorer{CompressedClient: c, inner: b}
+}
+
+type compressedBlobStorer struct {
+	*CompressedClient
+
+	inner ClientBlobStorer
+}
+
+func (c *compressedBlobStorer) PutBlob(id string, data []byte) error {
+	if c.Gzip {
+		var err error
+		data, err = gzipData(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.inner.PutBlob(id, data)
+}
+
+func (c *compressedBlobStorer) GetBlob(id string) ([]byte, error) {
+	data, err := c.inner.GetBlob(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return gunzipData(data)
+}
+
+func (c *compressedBlobStorer) DeleteBlob(id string) error {
+	return c.inner.DeleteBlob(id)
+}
+
+func decompressPayload(payload *Payload) (*Payload, error) {
+	data, err := Decompress(payload.Data)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(data, payload.Data) {
+		return payload, nil
+	}
+
+	md5 := md5.Sum(data)
+	return &Payload{Data: data, MD5: md5[:], ModTime: payload.ModTime}, nil
+}
+
+// Decompress returns the state stored as data by a CompressedClient. Data
+// that isn't compressed is returned as-is.
+func Decompress(data []byte) ([]byte, error) {
+	data, err := gunzipData(data)
+	if err != nil {
+		return nil, fmt.Errorf("Error decompressing state: %s", err)
+	}
+
+	return restoreStrings(data)
+}
+
+func gzipData(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// gunzipData decompresses data if it was compressed with gzip, and returns
+// it as-is otherwise.
+func gunzipData(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(r)
+}
+
+// dedupedState is the format of a state whose repeated strings are replaced
+// with references to StringTable. A reference is an object with the index
+// of the string in the table as its only field, named stringRefKey.
+type dedupedState struct {
+	StringTable []string    `json:"string_table"`
+	State       interface{} `json:"state"`
+}
+
+const stringRefKey = "$string"
+
+// dedupStrings replaces the strings of at least min length that occur more
+// than once in the JSON data with references to a table of strings.
+func dedupStrings(data []byte, min int) ([]byte, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	v = walkStrings(v, func(s string) interface{} {
+		if len(s) >= min {
+			counts[s]++
+		}
+		return s
+	})
+
+	var table []string
+	for s, n := range counts {
+		if n > 1 {
+			table = append(table, s)
+		}
+	}
+	if len(table) == 0 {
+		return data, nil
+	}
+	sort.Strings(table)
+
+	index := make(map[string]int, len(table))
+	for i, s := range table {
+		index[s] = i
+	}
+	v = walkStrings(v, func(s string) interface{} {
+		if i, ok := index[s]; ok {
+			return map[string]interface{}{stringRefKey: i}
+		}
+		return s
+	})
+
+	return json.MarshalIndent(&dedupedState{StringTable: table, State: v}, "", "    ")
+}
+
+// restoreStrings replaces the references to the table of strings in JSON
+// data written by dedupStrings with the strings. Data that wasn't written
+// by it is returned as-is.
+func restoreStrings(data []byte) ([]byte, error) {
+	// Avoid decoding large states twice when they aren't deduplicated
+	if !bytes.Contains(data, []byte(`"string_table"`)) {
+		return data, nil
+	}
+
+	var probe struct {
+		StringTable []string `json:"string_table"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil || probe.StringTable == nil {
+		return data, nil
+	}
+
+	var deduped dedupedState
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&deduped); err != nil {
+		return nil, err
+	}
+
+	var err error
+	v := walkRefs(deduped.State, func(n json.Number) interface{} {
+		i, convErr := n.Int64()
+		if convErr != nil || i < 0 || int(i) >= len(deduped.StringTable) {
+			err = fmt.Errorf("Error reading state: invalid string reference %s", n)
+			return nil
+		}
+		return deduped.StringTable[i]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(v, "", "    ")
+}
+
+// walkStrings calls fn for every string value in v, which was decoded from
+// JSON, and replaces the string with the result.
+func walkStrings(v interface{}, fn func(string) interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return fn(v)
+	case []interface{}:
+		for i, e := range v {
+			v[i] = walkStrings(e, fn)
+		}
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = walkStrings(e, fn)
+		}
+	}
+
+	return v
+}
+
+// walkRefs calls fn with the index of every reference in v, which was
+// decoded from JSON, and replaces the reference with the result.
+func walkRefs(v interface{}, fn func(json.Number) interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		for i, e := range v {
+			v[i] = walkRefs(e, fn)
+		}
+	case map[string]interface{}:
+		if n, ok := v[stringRefKey].(json.Number); ok && len(v) == 1 {
+			return fn(n)
+		}
+		for k, e := range v {
+			v[k] = walkRefs(e, fn)
+		}
+	}
+
+	return v
+}
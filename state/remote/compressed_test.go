@@ -0,0 +1,156 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+)
+
+func TestCompressedClient_impl(t *testing.T) {
+	var _ Client = new(CompressedClient)
+	var _ ClientLocker = new(compressedLockingClient)
+}
+
+func TestCompressedClient(t *testing.T) {
+	inner := new(memClient)
+	c := NewCompressedClient(inner, true, 16)
+	if _, ok := c.(ClientLocker); ok {
+		t.Fatal("client shouldn't support locking")
+	}
+
+	testClient(t, c)
+
+	// The inner client must only ever see compressed data
+	data := []byte(`{"version": 3, "lineage": "` + strings.Repeat("lineage", 100) + `"}`)
+	if err := c.Put(data); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if bytes.Contains(inner.data, []byte(strings.Repeat("lineage", 100))) {
+		t.Fatalf("data should be compressed: %s", inner.data)
+	}
+}
+
+func TestCompressedClient_locking(t *testing.T) {
+	inner := new(memLockingClient)
+	c := NewCompressedClient(inner, true, 0)
+
+	l, ok := c.(ClientLocker)
+	if !ok {
+		t.Fatal("client should support locking")
+	}
+	if _, err := l.Lock(state.NewLockInfo()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !inner.locked {
+		t.Fatal("inner client should be locked")
+	}
+}
+
+func TestCompressedClient_dedup(t *testing.T) {
+	long := strings.Repeat("x", 32)
+	data := []byte(`{
+    "version": 3,
+    "serial": 12,
+    "resources": [
+        {"user_data": "` + long + `", "short": "foo"},
+        {"user_data": "` + long + `", "short": "foo"},
+        {"policy": "` + long + `", "unique": "` + strings.Repeat("y", 32) + `"}
+    ]
+}`)
+
+	inner := new(memClient)
+	c := NewCompressedClient(inner, false, 16)
+	if err := c.Put(data); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var stored struct {
+		StringTable []string `json:"string_table"`
+	}
+	if err := json.Unmarshal(inner.data, &stored); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(stored.StringTable) != 1 || stored.StringTable[0] != long {
+		t.Fatalf("bad: %#v", stored.StringTable)
+	}
+	if n := bytes.Count(inner.data, []byte(long)); n != 1 {
+		t.Fatalf("string stored %d times: %s", n, inner.data)
+	}
+
+	p, err := c.Get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var expected, actual interface{}
+	if err := json.Unmarshal(data, &expected); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := json.Unmarshal(p.Data, &actual); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("bad: %s", p.Data)
+	}
+}
+
+func TestCompressedClient_uncompressed(t *testing.T) {
+	// Data stored before compression was enabled is still readable
+	inner := &memClient{data: []byte(`{"version": 3}`)}
+	c := NewCompressedClient(inner, true, 16)
+
+	p, err := c.Get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(p.Data) != `{"version": 3}` {
+		t.Fatalf("bad: %s", p.Data)
+	}
+}
+
+func TestCompressedClient_versions(t *testing.T) {
+	if Versioner(NewCompressedClient(new(memClient), true, 0)) != nil {
+		t.Fatal("client shouldn't keep versions")
+	}
+
+	inner := new(memVersioningClient)
+	c := NewCompressedClient(inner, true, 0)
+	if err := c.Put([]byte("first")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := c.Put([]byte("second")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	v := Versioner(c)
+	if v == nil {
+		t.Fatal("client should keep versions")
+	}
+	versions, err := v.Versions()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("bad: %#v", versions)
+	}
+
+	// The previous version is decompressed
+	p, err := v.GetVersion(versions[0].ID)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(p.Data) != "first" {
+		t.Fatalf("bad: %s", p.Data)
+	}
+}
+
+func TestDecompress_badReference(t *testing.T) {
+	data := []byte(`{"string_table": ["foo"], "state": {"a": {"$string": 1}}}`)
+	if _, err := Decompress(data); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -0,0 +1,217 @@
+package remote
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/terraform/state"
+)
+
+// DynamicClient is a Client that stores the state with the client that
+// Current returns, which can change between calls, such as when the
+// backend that the client belongs to is configured again with new
+// credentials.
+type DynamicClient struct {
+	Current func() (Client, error)
+}
+
+// dynamicLockingClient is a DynamicClient for clients that support
+// locking.
+type dynamicLockingClient struct {
+	*DynamicClient
+
+	// lockers are the clients that hold the locks, by lock ID. Some
+	// clients, such as the Consul one, keep what they need to unlock in
+	// the client itself, so locks are released by the client that took
+	// them.
+	mu      sync.Mutex
+	lockers map[string]ClientLocker
+}
+
+// NewDynamicClient returns a DynamicClient that gets its clients from
+// current, and that supports locking if c, the current client, does.
+func NewDynamicClient(c Client, current func() (Client, error)) Client {
+	dc := &DynamicClient{Current: current}
+	if _, ok := c.(ClientLocker); ok {
+		return &dynamicLockingClient{DynamicClient: dc, lockers: make(map[string]ClientLocker)}
+	}
+
+	return dc
+}
+
+func (c *DynamicClient) Get() (*Payload, error) {
+	client, err := c.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Get()
+}
+
+func (c *DynamicClient) Put(data []byte) error {
+	client, err := c.Current()
+	if err != nil {
+		return err
+	}
+
+	return client.Put(data)
+}
+
+func (c *DynamicClient) Delete() error {
+	client, err := c.Current()
+	if err != nil {
+		return err
+	}
+
+	return client.Delete()
+}
+
+func (c *dynamicLockingClient) Lock(info *state.LockInfo) (string, error) {
+	client, err := c.Current()
+	if err != nil {
+		return "", err
+	}
+	l, ok := client.(ClientLocker)
+	if !ok {
+		return "", fmt.Errorf("remote client %T doesn't support locking", client)
+	}
+
+	id, err := l.Lock(info)
+	if err != nil {
+		return id, err
+	}
+
+	c.mu.Lock()
+	c.lockers[id] = l
+	c.mu.Unlock()
+	return id, nil
+}
+
+func (c *dynamicLockingClient) Unlock(id string) error {
+	c.mu.Lock()
+	l, ok := c.lockers[id]
+	c.mu.Unlock()
+
+	// Locks that weren't taken by this client, such as ones that are
+	// forcibly unlocked, are released by the current client.
+	if !ok {
+		client, err := c.Current()
+		if err != nil {
+			return err
+		}
+		if l, ok = client.(ClientLocker); !ok {
+			return fmt.Errorf("remote client %T doesn't support locking", client)
+		}
+	}
+
+	if err := l.Unlock(id); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	delete(c.lockers, id)
+	c.mu.Unlock()
+	return nil
+}
+
+// versioner returns a ClientVersioner that reads the previous versions of
+// the state with the current client, or nil if the current client doesn't
+// keep them.
+func (c *DynamicClient) versioner() ClientVersioner {
+	client, err := c.Current()
+	if err != nil || Versioner(client) == nil {
+		return nil
+	}
+
+	return &dynamicVersioner{DynamicClient: c}
+}
+
+type dynamicVersioner struct {
+	*DynamicClient
+}
+
+func (c *dynamicVersioner) current() (ClientVersioner, error) {
+	client, err := c.Current()
+	if err != nil {
+		return nil, err
+	}
+	v := Versioner(client)
+	if v == nil {
+		return nil, fmt.Errorf("remote client %T doesn't keep versions of the state", client)
+	}
+
+	return v, nil
+}
+
+func (c *dynamicVersioner) Versions() ([]*Version, error) {
+	v, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Versions()
+}
+
+func (c *dynamicVersioner) GetVersion(id string) (*Payload, error) {
+	v, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return v.GetVersion(id)
+}
+
+// blobStorer returns a ClientBlobStorer that stores blobs with the current
+// client, or nil if the current client can't store blobs.
+func (c *DynamicClient) blobStorer() ClientBlobStorer {
+	client, err := c.Current()
+	if err != nil || BlobStorer(client) == nil {
+		return nil
+	}
+
+	return &dynamicBlobStorer{DynamicClient: c}
+}
+
+type dynamicBlobStorer struct {
+	*DynamicClient
+}
+
+func (c *dynamicBlobStorer) current() (ClientBlobStorer, error) {
+	client, err := c.Current()
+	if err != nil {
+		return nil, err
+	}
+	b := BlobStorer(client)
+	if b == nil {
+		return nil, fmt.Errorf("remote client %T can't store blobs", client)
+	}
+
+	return b, nil
+}
+
+func (c *dynamicBlobStorer) PutBlob(id string, data []byte) error {
+	b, err := c.current()
+	if err != nil {
+		return err
+	}
+
+	return b.PutBlob(id, data)
+}
+
+func (c *dynamicBlobStorer) GetBlob(id string) ([]byte, error) {
+	b, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.GetBlob(id)
+}
+
+func (c *dynamicBlobStorer) DeleteBlob(id string) error {
+	b, err := c.current()
+	if err != nil {
+		return err
+	}
+
+	return b.DeleteBlob(id)
+}
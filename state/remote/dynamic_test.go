@@ -0,0 +1,96 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+)
+
+func TestDynamicClient_impl(t *testing.T) {
+	var _ Client = new(DynamicClient)
+	var _ ClientLocker = new(dynamicLockingClient)
+}
+
+func TestDynamicClient(t *testing.T) {
+	first, second := new(memClient), new(memClient)
+	current := Client(first)
+	c := NewDynamicClient(current, func() (Client, error) { return current, nil })
+	if _, ok := c.(ClientLocker); ok {
+		t.Fatal("client shouldn't support locking")
+	}
+
+	testClient(t, c)
+
+	// Once the client changes, the state is stored with the new one
+	current = second
+	if err := c.Put([]byte("foo")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(second.data) != "foo" {
+		t.Fatalf("bad: %s", second.data)
+	}
+	if string(first.data) == "foo" {
+		t.Fatal("the old client shouldn't be used")
+	}
+}
+
+func TestDynamicClient_locking(t *testing.T) {
+	first, second := new(memLockingClient), new(memLockingClient)
+	current := Client(first)
+	c := NewDynamicClient(current, func() (Client, error) { return current, nil })
+
+	l, ok := c.(ClientLocker)
+	if !ok {
+		t.Fatal("client should support locking")
+	}
+	id, err := l.Lock(state.NewLockInfo())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The lock is released by the client that took it
+	current = second
+	if err := l.Unlock(id); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if first.locked {
+		t.Fatal("the client that took the lock should be unlocked")
+	}
+}
+
+func TestDynamicClient_versions(t *testing.T) {
+	var current Client = new(memClient)
+	c := NewDynamicClient(current, func() (Client, error) { return current, nil })
+	if Versioner(c) != nil {
+		t.Fatal("client shouldn't keep versions")
+	}
+
+	inner := new(memVersioningClient)
+	current = inner
+	c = NewDynamicClient(current, func() (Client, error) { return current, nil })
+	if err := c.Put([]byte("first")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := c.Put([]byte("second")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	v := Versioner(c)
+	if v == nil {
+		t.Fatal("client should keep versions")
+	}
+	versions, err := v.Versions()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("bad: %#v", versions)
+	}
+	p, err := v.GetVersion(versions[0].ID)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(p.Data) != "first" {
+		t.Fatalf("bad: %s", p.Data)
+	}
+}
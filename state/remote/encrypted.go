@@ -0,0 +1,208 @@
+package remote
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform/state"
+)
+
+// EncryptedClient is a Client that encrypts the data it stores with a
+// state.Encrypter, so that any client can store encrypted state.
+//
+// Data that isn't encrypted is an error, since it could have been stored
+// by anyone with access to the storage. While migrating data that was
+// stored before encryption was enabled, AllowUnencryptedRead can be set to
+// return it as-is, and it's encrypted the next time it is written.
+type EncryptedClient struct {
+	Client Client
+
+	// Type is the type of the encrypter, which is recorded with the data
+	// so that data encrypted with another type can be detected.
+	Type      string
+	Encrypter state.Encrypter
+
+	// AllowUnencryptedRead allows reading data that isn't encrypted.
+	AllowUnencryptedRead bool
+}
+
+// encryptedLockingClient is an EncryptedClient for a client that
+// supports locking.
+type encryptedLockingClient struct {
+	*EncryptedClient
+	state.Locker
+}
+
+// NewEncryptedClient returns a Client that encrypts the data stored with c
+// using e. The returned client supports locking if c does.
+func NewEncryptedClient(c Client, t string, e state.Encrypter) Client {
+	return newEncryptedClient(&EncryptedClient{Client: c, Type: t, Encrypter: e})
+}
+
+// NewMigratingEncryptedClient is like NewEncryptedClient, but the returned
+// client also reads data that isn't encrypted yet, for encrypting data that
+// was stored before encryption was enabled.
+func NewMigratingEncryptedClient(c Client, t string, e state.Encrypter) Client {
+	return newEncryptedClient(&EncryptedClient{
+		Client:               c,
+		Type:                 t,
+		Encrypter:            e,
+		AllowUnencryptedRead: true,
+	})
+}
+
+func newEncryptedClient(ec *EncryptedClient) Client {
+	c := ec.Client
+	if l, ok := c.(ClientLocker); ok {
+		return &encryptedLockingClient{EncryptedClient: ec, Locker: l}
+	}
+
+	return ec
+}
+
+// errUnencrypted is returned when reading data that isn't encrypted
+// without AllowUnencryptedRead.
+var errUnencrypted = errors.New(
+	"data isn't encrypted. If it was stored before encryption was enabled, " +
+		"set allow_unencrypted_read to read it until it's written again")
+
+// encryptedPayload is the format encrypted data is stored in.
+type encryptedPayload struct {
+	Encryption string `json:"encryption"`
+	Data       []byte `json:"data"`
+}
+
+func (c *EncryptedClient) Get() (*Payload, error) {
+	payload, err := c.Client.Get()
+	if err != nil || payload == nil {
+		return payload, err
+	}
+
+	return c.decrypt(payload)
+}
+
+func (c *EncryptedClient) decrypt(payload *Payload) (*Payload, error) {
+	var encrypted encryptedPayload
+	if err := json.Unmarshal(payload.Data, &encrypted); err != nil || encrypted.Encryption == "" {
+		if c.AllowUnencryptedRead {
+			// Not encrypted yet
+			return payload, nil
+		}
+
+		return nil, errUnencrypted
+	}
+	if encrypted.Encryption != c.Type {
+		return nil, fmt.Errorf(
+			"state is encrypted with %q, but %q is configured",
+			encrypted.Encryption, c.Type)
+	}
+
+	data, err := c.Encrypter.Decrypt(encrypted.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	md5 := md5.Sum(data)
+	return &Payload{Data: data, MD5: md5[:], ModTime: payload.ModTime}, nil
+}
+
+func (c *EncryptedClient) Put(data []byte) error {
+	encrypted, err := c.encrypt(data)
+	if err != nil {
+		return err
+	}
+
+	return c.Client.Put(encrypted)
+}
+
+func (c *EncryptedClient) encrypt(data []byte) ([]byte, error) {
+	ciphertext, err := c.Encrypter.Encrypt(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(&encryptedPayload{
+		Encryption: c.Type,
+		Data:       ciphertext,
+	}, "", "    ")
+}
+
+func (c *EncryptedClient) Delete() error {
+	return c.Client.Delete()
+}
+
+// versioner returns a ClientVersioner that decrypts the previous versions
+// of the state, or nil if the encrypted client doesn't keep them.
+func (c *EncryptedClient) versioner() ClientVersioner {
+	v := Versioner(c.Client)
+	if v == nil {
+		return nil
+	}
+
+	return &encryptedVersioner{EncryptedClient: c, inner: v}
+}
+
+type encryptedVersioner struct {
+	*EncryptedClient
+
+	inner ClientVersioner
+}
+
+func (c *encryptedVersioner) Versions() ([]*Version, error) {
+	return c.inner.Versions()
+}
+
+func (c *encryptedVersioner) GetVersion(id string) (*Payload, error) {
+	payload, err := c.inner.GetVersion(id)
+	if err != nil || payload == nil {
+		return payload, err
+	}
+
+	return c.decrypt(payload)
+}
+
+// blobStorer returns a ClientBlobStorer that encrypts the blobs it stores,
+// or nil if the encrypted client can't store blobs.
+func (c *EncryptedClient) blobStorer() ClientBlobStorer {
+	b := BlobStorer(c.Client)
+	if b == nil {
+		return nil
+	}
+
+	return &encryptedBlobStorer{EncryptedClient: c, inner: b}
+}
+
+type encryptedBlobStorer struct {
+	*EncryptedClient
+
+	inner ClientBlobStorer
+}
+
+func (c *encryptedBlobStorer) PutBlob(id string, data []byte) error {
+	encrypted, err := c.encrypt(data)
+	if err != nil {
+		return err
+	}
+
+	return c.inner.PutBlob(id, encrypted)
+}
+
+func (c *encryptedBlobStorer) GetBlob(id string) ([]byte, error) {
+	data, err := c.inner.GetBlob(id)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := c.decrypt(&Payload{Data: data})
+	if err != nil {
+		return nil, err
+	}
+
+	return payload.Data, nil
+}
+
+func (c *encryptedBlobStorer) DeleteBlob(id string) error {
+	return c.inner.DeleteBlob(id)
+}
@@ -0,0 +1,203 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+)
+
+// memClient is a Client that stores data in memory.
+type memClient struct {
+	data []byte
+}
+
+func (c *memClient) Get() (*Payload, error) {
+	if c.data == nil {
+		return nil, nil
+	}
+
+	return &Payload{Data: c.data}, nil
+}
+
+func (c *memClient) Put(data []byte) error {
+	c.data = data
+	return nil
+}
+
+func (c *memClient) Delete() error {
+	c.data = nil
+	return nil
+}
+
+// memLockingClient is a memClient that supports locking.
+type memLockingClient struct {
+	memClient
+	locked bool
+}
+
+func (c *memLockingClient) Lock(*state.LockInfo) (string, error) {
+	c.locked = true
+	return "foo", nil
+}
+
+func (c *memLockingClient) Unlock(string) error {
+	c.locked = false
+	return nil
+}
+
+// memVersioningClient is a memClient that keeps the previous versions of
+// the data.
+type memVersioningClient struct {
+	memClient
+	versions [][]byte
+}
+
+func (c *memVersioningClient) Put(data []byte) error {
+	if c.data != nil {
+		c.versions = append(c.versions, c.data)
+	}
+
+	return c.memClient.Put(data)
+}
+
+func (c *memVersioningClient) Versions() ([]*Version, error) {
+	var result []*Version
+	for i := range c.versions {
+		result = append(result, &Version{ID: strconv.Itoa(i)})
+	}
+
+	return result, nil
+}
+
+func (c *memVersioningClient) GetVersion(id string) (*Payload, error) {
+	i, err := strconv.Atoi(id)
+	if err != nil || i >= len(c.versions) {
+		return nil, fmt.Errorf("unknown version %q", id)
+	}
+
+	return &Payload{Data: c.versions[i]}, nil
+}
+
+func testEncrypter(t *testing.T) state.Encrypter {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	os.Setenv(state.AESGCMKeyEnvVar, key)
+	defer os.Unsetenv(state.AESGCMKeyEnvVar)
+
+	e, err := state.NewEncrypter("aes_gcm", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return e
+}
+
+func TestEncryptedClient_impl(t *testing.T) {
+	var _ Client = new(EncryptedClient)
+	var _ ClientLocker = new(encryptedLockingClient)
+}
+
+func TestEncryptedClient(t *testing.T) {
+	inner := new(memClient)
+	c := NewEncryptedClient(inner, "aes_gcm", testEncrypter(t))
+	if _, ok := c.(ClientLocker); ok {
+		t.Fatal("client shouldn't support locking")
+	}
+
+	testClient(t, c)
+
+	// The inner client must only ever see encrypted data
+	if err := c.Put([]byte("secret")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if bytes.Contains(inner.data, []byte("secret")) {
+		t.Fatalf("data should be encrypted: %s", inner.data)
+	}
+}
+
+func TestEncryptedClient_locking(t *testing.T) {
+	inner := new(memLockingClient)
+	c := NewEncryptedClient(inner, "aes_gcm", testEncrypter(t))
+
+	l, ok := c.(ClientLocker)
+	if !ok {
+		t.Fatal("client should support locking")
+	}
+	if _, err := l.Lock(state.NewLockInfo()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !inner.locked {
+		t.Fatal("inner client should be locked")
+	}
+}
+
+func TestEncryptedClient_versions(t *testing.T) {
+	if Versioner(NewEncryptedClient(new(memClient), "aes_gcm", testEncrypter(t))) != nil {
+		t.Fatal("client shouldn't keep versions")
+	}
+
+	inner := new(memVersioningClient)
+	c := NewEncryptedClient(inner, "aes_gcm", testEncrypter(t))
+	if err := c.Put([]byte("first")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := c.Put([]byte("second")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	v := Versioner(c)
+	if v == nil {
+		t.Fatal("client should keep versions")
+	}
+	versions, err := v.Versions()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("bad: %#v", versions)
+	}
+
+	// The previous version is decrypted
+	p, err := v.GetVersion(versions[0].ID)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(p.Data) != "first" {
+		t.Fatalf("bad: %s", p.Data)
+	}
+}
+
+func TestEncryptedClient_unencrypted(t *testing.T) {
+	inner := &memClient{data: []byte(`{"version": 3}`)}
+	c := NewEncryptedClient(inner, "aes_gcm", testEncrypter(t))
+	if _, err := c.Get(); err == nil {
+		t.Fatal("should error reading unencrypted data")
+	}
+
+	// Data stored before encryption was enabled is readable while migrating
+	c = NewMigratingEncryptedClient(inner, "aes_gcm", testEncrypter(t))
+	p, err := c.Get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(p.Data) != `{"version": 3}` {
+		t.Fatalf("bad: %s", p.Data)
+	}
+}
+
+func TestEncryptedClient_otherType(t *testing.T) {
+	inner := new(memClient)
+	c := NewEncryptedClient(inner, "aes_gcm", testEncrypter(t))
+	if err := c.Put([]byte("secret")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	c = NewEncryptedClient(inner, "other", testEncrypter(t))
+	if _, err := c.Get(); err == nil {
+		t.Fatal("should error")
+	}
+}
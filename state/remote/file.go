@@ -5,7 +5,10 @@ import (
 	"crypto/md5"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"time"
 )
 
 func fileFactory(conf map[string]string) (Client, error) {
@@ -41,10 +44,16 @@ func (c *FileClient) Get() (*Payload, error) {
 		return nil, err
 	}
 
+	var modTime time.Time
+	if info, err := f.Stat(); err == nil {
+		modTime = info.ModTime()
+	}
+
 	md5 := md5.Sum(buf.Bytes())
 	return &Payload{
-		Data: buf.Bytes(),
-		MD5:  md5[:],
+		Data:    buf.Bytes(),
+		MD5:     md5[:],
+		ModTime: modTime,
 	}, nil
 }
 
@@ -62,3 +71,36 @@ func (c *FileClient) Put(data []byte) error {
 func (c *FileClient) Delete() error {
 	return os.Remove(c.Path)
 }
+
+// blobPath returns the path of the blob with the given ID, which is kept in
+// a directory next to the state.
+func (c *FileClient) blobPath(id string) string {
+	return filepath.Join(c.Path+".blobs", id)
+}
+
+func (c *FileClient) PutBlob(id string, data []byte) error {
+	path := c.blobPath(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func (c *FileClient) GetBlob(id string) ([]byte, error) {
+	data, err := ioutil.ReadFile(c.blobPath(id))
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+
+	return data, err
+}
+
+func (c *FileClient) DeleteBlob(id string) error {
+	err := os.Remove(c.blobPath(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
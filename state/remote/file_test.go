@@ -3,11 +3,13 @@ package remote
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
 func TestFileClient_impl(t *testing.T) {
 	var _ Client = new(FileClient)
+	var _ ClientBlobStorer = new(FileClient)
 }
 
 func TestFileClient(t *testing.T) {
@@ -27,3 +29,13 @@ func TestFileClient(t *testing.T) {
 
 	testClient(t, client)
 }
+
+func TestFileClient_blobs(t *testing.T) {
+	td, err := ioutil.TempDir("", "tf")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	TestBlobStorer(t, &FileClient{Path: filepath.Join(td, "terraform.tfstate")})
+}
@@ -10,6 +10,9 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/terraform/helper/credentials"
 )
 
 func httpFactory(conf map[string]string) (Client, error) {
@@ -26,7 +29,11 @@ func httpFactory(conf map[string]string) (Client, error) {
 		return nil, fmt.Errorf("address must be HTTP or HTTPS")
 	}
 
-	client := &http.Client{}
+	// The saved token for the host is sent unless the server's certificate
+	// isn't verified, since it could then be sent to anyone.
+	client := &http.Client{
+		Transport: credentials.Transport(cleanhttp.DefaultPooledTransport()),
+	}
 	if skipRaw, ok := conf["skip_cert_verification"]; ok {
 		skip, err := strconv.ParseBool(skipRaw)
 		if err != nil {
@@ -132,6 +139,12 @@ func (c *HTTPClient) Get() (*Payload, error) {
 		payload.MD5 = hash[:]
 	}
 
+	if raw := resp.Header.Get("Last-Modified"); raw != "" {
+		if t, err := http.ParseTime(raw); err == nil {
+			payload.ModTime = t
+		}
+	}
+
 	return payload, nil
 }
 
@@ -7,9 +7,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/terraform/helper/credentials"
 )
 
 func TestHTTPClient_impl(t *testing.T) {
@@ -30,6 +32,34 @@ func TestHTTPClient(t *testing.T) {
 	testClient(t, client)
 }
 
+// The saved token isn't sent to servers whose certificate isn't verified.
+func TestHTTPClient_skipCertVerificationToken(t *testing.T) {
+	var auth string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "https://")
+	defer func(static map[string]string) { credentials.Default.Static = static }(credentials.Default.Static)
+	credentials.Default.Static = map[string]string{host: "foo"}
+
+	client, err := httpFactory(map[string]string{
+		"address":                ts.URL,
+		"skip_cert_verification": "true",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := client.Get(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if auth != "" {
+		t.Fatalf("bad: %q", auth)
+	}
+}
+
 type testHTTPHandler struct {
 	Data []byte
 }
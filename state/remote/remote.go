@@ -2,6 +2,7 @@ package remote
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/terraform/state"
 )
@@ -22,10 +23,60 @@ type ClientLocker interface {
 	state.Locker
 }
 
+// ClientVersioner is an optional interface that allows a remote state
+// backend to list and read the previous versions of the state it stores,
+// for recovering a lost or corrupted state.
+type ClientVersioner interface {
+	Client
+
+	// Versions returns the previous versions of the state, not including
+	// the current one.
+	Versions() ([]*Version, error)
+
+	// GetVersion returns the state stored in the version with the given ID.
+	GetVersion(id string) (*Payload, error)
+}
+
+// Version is a previous version of the state stored by a ClientVersioner.
+type Version struct {
+	ID      string
+	ModTime time.Time
+}
+
+// Versioner returns c as a ClientVersioner, or nil if c doesn't keep the
+// previous versions of the state.
+func Versioner(c Client) ClientVersioner {
+	switch c := c.(type) {
+	case *EncryptedClient:
+		return c.versioner()
+	case *encryptedLockingClient:
+		return c.versioner()
+	case *CompressedClient:
+		return c.versioner()
+	case *compressedLockingClient:
+		return c.versioner()
+	case *BlobClient:
+		return c.versioner()
+	case *blobLockingClient:
+		return c.versioner()
+	case *DynamicClient:
+		return c.versioner()
+	case *dynamicLockingClient:
+		return c.versioner()
+	case ClientVersioner:
+		return c
+	}
+
+	return nil
+}
+
 // Payload is the return value from the remote state storage.
 type Payload struct {
 	MD5  []byte
 	Data []byte
+
+	// ModTime is when the state was last stored, if the storage reports it.
+	ModTime time.Time
 }
 
 // Factory is the factory function to create a remote client.
@@ -2,7 +2,10 @@ package remote
 
 import (
 	"bytes"
+	"crypto/md5"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/terraform/state"
 	"github.com/hashicorp/terraform/terraform"
@@ -18,6 +21,13 @@ type State struct {
 	Client Client
 
 	state, readState *terraform.State
+	modTime          time.Time
+
+	// subtreeLocks are the modules of the subtree locks held by this
+	// state, by lock ID, and subtreeBase is the state as it was read
+	// before the first of them was taken.
+	subtreeLocks map[string][]string
+	subtreeBase  *terraform.State
 }
 
 // StateReader impl.
@@ -49,24 +59,73 @@ func (s *State) RefreshState() error {
 
 	// no remote state is OK
 	if payload == nil {
+		s.modTime = time.Time{}
 		return nil
 	}
 
-	state, err := terraform.ReadState(bytes.NewReader(payload.Data))
+	state, err := readPayload(payload)
 	if err != nil {
 		return err
 	}
 
 	s.state = state
 	s.readState = state
+	s.modTime = payload.ModTime
 	return nil
 }
 
+// readPayload reads the state stored in a payload.
+func readPayload(payload *Payload) (*terraform.State, error) {
+	// If the storage reported the MD5 of what it stored, verify that we
+	// got all of it.
+	if len(payload.MD5) > 0 {
+		sum := md5.Sum(payload.Data)
+		if !bytes.Equal(sum[:], payload.MD5) {
+			return nil, fmt.Errorf(
+				"Remote state MD5 mismatch: expected %x, got %x. The state "+
+					"was truncated or corrupted while it was uploaded or "+
+					"downloaded.", payload.MD5, sum)
+		}
+	}
+
+	// The state may have been stored compressed, even if compression
+	// isn't configured anymore.
+	data, err := Decompress(payload.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := terraform.ReadState(bytes.NewReader(data))
+	if err != nil {
+		if _, ok := err.(*terraform.StateChecksumError); ok {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf(
+			"Error reading remote state, it may have been truncated or "+
+				"corrupted: %s", err)
+	}
+
+	return state, nil
+}
+
+// StateModTimer impl.
+func (s *State) ModTime() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.modTime
+}
+
 // StatePersister impl.
 func (s *State) PersistState() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if len(s.subtreeLocks) > 0 {
+		return s.persistSubtrees()
+	}
+
 	s.state.IncrementSerialMaybe(s.readState)
 
 	var buf bytes.Buffer
@@ -83,6 +142,13 @@ func (s *State) Lock(info *state.LockInfo) (string, error) {
 	defer s.mu.Unlock()
 
 	if c, ok := s.Client.(ClientLocker); ok {
+		if store := s.subtreeStore(); store != nil {
+			if len(info.Modules) > 0 {
+				return s.lockSubtrees(c, store, info)
+			}
+			return s.lockWhole(c, store, info)
+		}
+
 		return c.Lock(info)
 	}
 	return "", nil
@@ -94,6 +160,10 @@ func (s *State) Unlock(id string) error {
 	defer s.mu.Unlock()
 
 	if c, ok := s.Client.(ClientLocker); ok {
+		if store := s.subtreeStore(); store != nil {
+			return s.unlockSubtrees(c, store, id)
+		}
+
 		return c.Unlock(id)
 	}
 	return nil
@@ -1,10 +1,14 @@
 package remote
 
 import (
+	"bytes"
+	"crypto/md5"
+	"strings"
 	"sync"
 	"testing"
 
 	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
 )
 
 func TestState_impl(t *testing.T) {
@@ -13,6 +17,7 @@ func TestState_impl(t *testing.T) {
 	var _ state.StatePersister = new(State)
 	var _ state.StateRefresher = new(State)
 	var _ state.Locker = new(State)
+	var _ state.SubtreeLocker = new(State)
 }
 
 func TestStateRace(t *testing.T) {
@@ -35,3 +40,55 @@ func TestStateRace(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestStateRefresh_corrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := terraform.WriteState(state.TestStateInitial(), &buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	data := buf.Bytes()
+
+	// A state that was changed after it was written
+	client := &corruptClient{
+		Data: bytes.Replace(data, []byte(`"foo"`), []byte(`"bar"`), 1),
+	}
+	s := &State{Client: client}
+	err := s.RefreshState()
+	if _, ok := err.(*terraform.StateChecksumError); !ok {
+		t.Fatalf("expected checksum error, got: %v", err)
+	}
+
+	// A truncated state
+	client.Data = data[:len(data)/2]
+	err = s.RefreshState()
+	if err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// A state that doesn't match the MD5 the storage reported
+	sum := md5.Sum(data)
+	client.Data = data[:len(data)/2]
+	client.MD5 = sum[:]
+	err = s.RefreshState()
+	if err == nil || !strings.Contains(err.Error(), "MD5 mismatch") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	if s.State() != nil {
+		t.Fatalf("bad: %#v", s.State())
+	}
+}
+
+// corruptClient is a Client that returns the data it's given, as it is.
+type corruptClient struct {
+	Data []byte
+	MD5  []byte
+}
+
+func (c *corruptClient) Get() (*Payload, error) {
+	return &Payload{Data: c.Data, MD5: c.MD5}, nil
+}
+
+func (c *corruptClient) Put([]byte) error { return nil }
+
+func (c *corruptClient) Delete() error { return nil }
@@ -0,0 +1,290 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// Subtree locks are supported by clients that can both lock the state and
+// store blobs. They're recorded in a blob next to the state, which is only
+// changed while the whole state is locked for a moment. The same short
+// lock guards the merging of the locked modules into the stored state, and
+// locks of the whole state are only taken while no subtree is locked.
+
+// subtreeLocksBlobID is the ID of the blob that subtree locks are recorded
+// in.
+const subtreeLocksBlobID = "subtree-locks"
+
+// subtreeLockOperation is the operation of the short locks of the whole
+// state that guard the subtree locks.
+const subtreeLockOperation = "subtree-lock"
+
+// subtreeLockTimeout is how long to wait for the short locks of the whole
+// state that guard the subtree locks, when others hold them.
+var subtreeLockTimeout = 30 * time.Second
+
+// subtreeLocksFile is the format of the blob that subtree locks are
+// recorded in.
+type subtreeLocksFile struct {
+	Locks []*state.LockInfo `json:"locks"`
+}
+
+// SubtreeLocks implements state.SubtreeLocker.
+func (s *State) SubtreeLocks() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.subtreeStore() != nil
+}
+
+// subtreeStore returns the blob storer that subtree locks are recorded
+// with, or nil if the client can't lock subtrees.
+func (s *State) subtreeStore() ClientBlobStorer {
+	if _, ok := s.Client.(ClientLocker); !ok {
+		return nil
+	}
+
+	return BlobStorer(s.Client)
+}
+
+// lockWhole locks the whole state, if no subtrees are locked.
+func (s *State) lockWhole(c ClientLocker, store ClientBlobStorer, info *state.LockInfo) (string, error) {
+	id, err := c.Lock(info)
+	if err != nil {
+		return "", err
+	}
+
+	locks, err := readSubtreeLocks(store)
+	if err == nil && len(locks) > 0 {
+		err = &state.LockError{
+			Err:  fmt.Errorf("%d module subtrees of the state are locked", len(locks)),
+			Info: locks[0],
+		}
+	}
+	if err != nil {
+		if unlockErr := c.Unlock(id); unlockErr != nil {
+			return "", multierror.Append(err, unlockErr)
+		}
+		return "", err
+	}
+
+	return id, nil
+}
+
+// lockSubtrees locks the modules of the lock info, if neither they, the
+// modules they're in, nor the modules in them are locked.
+func (s *State) lockSubtrees(c ClientLocker, store ClientBlobStorer, info *state.LockInfo) (string, error) {
+	for _, addr := range info.Modules {
+		if _, err := state.ModuleAddrPath(addr); err != nil {
+			return "", err
+		}
+	}
+
+	err := withShortLock(c, func() error {
+		locks, err := readSubtreeLocks(store)
+		if err != nil {
+			return err
+		}
+
+		for _, l := range locks {
+			if state.ModulesConflict(l.Modules, info.Modules) {
+				return &state.LockError{
+					Err: fmt.Errorf(
+						"module subtrees %s are locked", strings.Join(l.Modules, ", ")),
+					Info: l,
+				}
+			}
+		}
+
+		info.Created = time.Now().UTC()
+		return writeSubtreeLocks(store, append(locks, info))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if s.subtreeLocks == nil {
+		s.subtreeLocks = make(map[string][]string)
+		s.subtreeBase = s.readState.DeepCopy()
+	}
+	s.subtreeLocks[info.ID] = info.Modules
+
+	return info.ID, nil
+}
+
+// unlockSubtrees releases the lock with the given ID, which may be a lock
+// of subtrees or of the whole state. Locks of subtrees held by others are
+// released too, for force-unlock.
+func (s *State) unlockSubtrees(c ClientLocker, store ClientBlobStorer, id string) error {
+	if _, ok := s.subtreeLocks[id]; !ok {
+		err := c.Unlock(id)
+		if err == nil {
+			return nil
+		}
+
+		// It may be a lock of subtrees that another state holds
+		if removed, removeErr := removeSubtreeLock(c, store, id); removeErr != nil || !removed {
+			return err
+		}
+		return nil
+	}
+
+	removed, err := removeSubtreeLock(c, store, id)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("the lock %s of module subtrees was already released", id)
+	}
+
+	delete(s.subtreeLocks, id)
+	if len(s.subtreeLocks) == 0 {
+		s.subtreeLocks = nil
+		s.subtreeBase = nil
+	}
+
+	return nil
+}
+
+// persistSubtrees persists the locked modules of the state, merged into
+// the latest stored state.
+func (s *State) persistSubtrees() error {
+	var modules []string
+	for _, m := range s.subtreeLocks {
+		modules = append(modules, m...)
+	}
+	sort.Strings(modules)
+
+	c := s.Client.(ClientLocker)
+	return withShortLock(c, func() error {
+		var latest *terraform.State
+		payload, err := s.Client.Get()
+		if err != nil {
+			return err
+		}
+		if payload != nil {
+			if latest, err = readPayload(payload); err != nil {
+				return err
+			}
+		}
+
+		merged := state.MergeModules(latest, s.state, s.subtreeBase, modules)
+		merged.IncrementSerialMaybe(latest)
+
+		var buf bytes.Buffer
+		if err := terraform.WriteState(merged, &buf); err != nil {
+			return err
+		}
+		if err := s.Client.Put(buf.Bytes()); err != nil {
+			return err
+		}
+
+		s.state = merged
+		s.readState = merged
+		return nil
+	})
+}
+
+// removeSubtreeLock removes the lock of subtrees with the given ID, and
+// returns whether it was found.
+func removeSubtreeLock(c ClientLocker, store ClientBlobStorer, id string) (bool, error) {
+	removed := false
+	err := withShortLock(c, func() error {
+		locks, err := readSubtreeLocks(store)
+		if err != nil {
+			return err
+		}
+
+		var kept []*state.LockInfo
+		for _, l := range locks {
+			if l.ID == id {
+				removed = true
+				continue
+			}
+			kept = append(kept, l)
+		}
+		if !removed {
+			return nil
+		}
+
+		return writeSubtreeLocks(store, kept)
+	})
+
+	return removed, err
+}
+
+// withShortLock calls f while holding a short lock of the whole state. If
+// the state is locked by another short lock, it's retried until
+// subtreeLockTimeout, but it fails at once if the state is locked by an
+// operation.
+func withShortLock(c ClientLocker, f func() error) error {
+	info := state.NewLockInfo()
+	info.Operation = subtreeLockOperation
+	info.Info = "updating the locks of module subtrees"
+
+	deadline := time.Now().Add(subtreeLockTimeout)
+	delay := 50 * time.Millisecond
+	for {
+		id, err := c.Lock(info)
+		if err == nil {
+			err = f()
+			if unlockErr := c.Unlock(id); unlockErr != nil {
+				err = multierror.Append(err, unlockErr)
+			}
+			return err
+		}
+
+		le, ok := err.(*state.LockError)
+		if !ok || le.Info == nil || le.Info.Operation != subtreeLockOperation {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+
+		time.Sleep(delay)
+		if delay < time.Second {
+			delay *= 2
+		}
+	}
+}
+
+// readSubtreeLocks returns the subtree locks recorded with the store.
+func readSubtreeLocks(store ClientBlobStorer) ([]*state.LockInfo, error) {
+	data, err := store.GetBlob(subtreeLocksBlobID)
+	if err == ErrBlobNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the locks of module subtrees: %s", err)
+	}
+
+	var f subtreeLocksFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("Error parsing the locks of module subtrees: %s", err)
+	}
+
+	return f.Locks, nil
+}
+
+// writeSubtreeLocks records the given subtree locks with the store.
+func writeSubtreeLocks(store ClientBlobStorer, locks []*state.LockInfo) error {
+	if len(locks) == 0 {
+		return store.DeleteBlob(subtreeLocksBlobID)
+	}
+
+	data, err := json.Marshal(&subtreeLocksFile{Locks: locks})
+	if err != nil {
+		return err
+	}
+
+	return store.PutBlob(subtreeLocksBlobID, data)
+}
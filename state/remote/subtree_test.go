@@ -0,0 +1,212 @@
+package remote
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/terraform/state"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// memSubtreeClient is a memBlobClient that supports locking, failing to
+// lock while it's locked.
+type memSubtreeClient struct {
+	memBlobClient
+	lock *state.LockInfo
+}
+
+func (c *memSubtreeClient) Lock(info *state.LockInfo) (string, error) {
+	if c.lock != nil {
+		return "", &state.LockError{Err: errors.New("locked"), Info: c.lock}
+	}
+
+	c.lock = info
+	return info.ID, nil
+}
+
+func (c *memSubtreeClient) Unlock(id string) error {
+	if c.lock == nil || c.lock.ID != id {
+		return errors.New("not locked")
+	}
+
+	c.lock = nil
+	return nil
+}
+
+func testSubtreeState() *terraform.State {
+	s := terraform.NewState()
+	for _, path := range [][]string{{"root"}, {"root", "a"}, {"root", "b"}} {
+		m := s.AddModule(path)
+		m.Resources["test_instance.foo"] = &terraform.ResourceState{
+			Type:    "test_instance",
+			Primary: &terraform.InstanceState{ID: path[len(path)-1]},
+		}
+	}
+
+	return s
+}
+
+func testSubtreeID(t *testing.T, s *terraform.State, path ...string) string {
+	m := s.ModuleByPath(append([]string{"root"}, path...))
+	if m == nil {
+		t.Fatalf("no module %v", path)
+	}
+
+	return m.Resources["test_instance.foo"].Primary.ID
+}
+
+func testSubtreeLock(t *testing.T, s *State, modules ...string) string {
+	info := state.NewLockInfo()
+	info.Operation = "test"
+	info.Modules = modules
+
+	id, err := s.Lock(info)
+	if err != nil {
+		t.Fatalf("error locking %v: %s", modules, err)
+	}
+
+	return id
+}
+
+func TestStateSubtreeLocks(t *testing.T) {
+	c := new(memSubtreeClient)
+	initial := &State{Client: c}
+	if err := initial.WriteState(testSubtreeState()); err != nil {
+		t.Fatal(err)
+	}
+	if err := initial.PersistState(); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &State{Client: c}
+	b := &State{Client: c}
+	for _, s := range []*State{a, b} {
+		if !s.SubtreeLocks() {
+			t.Fatal("should support subtree locks")
+		}
+		if err := s.RefreshState(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	idA := testSubtreeLock(t, a, "module.a")
+	idB := testSubtreeLock(t, b, "module.b")
+
+	// Locks of the same modules, of modules in them, or of the whole state
+	// conflict.
+	other := &State{Client: c}
+	for _, modules := range [][]string{{"module.a"}, {"module.b.module.c"}, nil} {
+		info := state.NewLockInfo()
+		info.Modules = modules
+		_, err := other.Lock(info)
+		le, ok := err.(*state.LockError)
+		if !ok {
+			t.Fatalf("locking %v: expected a lock error, got %v", modules, err)
+		}
+		if le.Info == nil || (le.Info.ID != idA && le.Info.ID != idB) {
+			t.Fatalf("locking %v: expected the info of a subtree lock, got %#v", modules, le.Info)
+		}
+	}
+
+	// Both write their modules, and neither overwrites the other's.
+	stateA := a.State()
+	stateA.ModuleByPath([]string{"root", "a"}).Resources["test_instance.foo"].Primary.ID = "a2"
+	stateA.ModuleByPath([]string{"root", "b"}).Resources["test_instance.foo"].Primary.ID = "ignored"
+	stateB := b.State()
+	stateB.ModuleByPath([]string{"root", "b"}).Resources["test_instance.foo"].Primary.ID = "b2"
+	for s, written := range map[*State]*terraform.State{a: stateA, b: stateB} {
+		if err := s.WriteState(written); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PersistState(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := other.RefreshState(); err != nil {
+		t.Fatal(err)
+	}
+	stored := other.State()
+	if id := testSubtreeID(t, stored, "a"); id != "a2" {
+		t.Fatalf("expected module.a to be a2, got %s", id)
+	}
+	if id := testSubtreeID(t, stored, "b"); id != "b2" {
+		t.Fatalf("expected module.b to be b2, got %s", id)
+	}
+	if id := testSubtreeID(t, stored); id != "root" {
+		t.Fatalf("expected the root module to be unchanged, got %s", id)
+	}
+
+	if err := a.Unlock(idA); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Unlock(idB); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.blobs[subtreeLocksBlobID]; ok {
+		t.Fatal("expected the blob of subtree locks to be deleted")
+	}
+
+	id := testSubtreeLock(t, other)
+	if err := other.Unlock(id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStateSubtreeLocks_forceUnlock(t *testing.T) {
+	c := new(memSubtreeClient)
+	a := &State{Client: c}
+	idA := testSubtreeLock(t, a, "module.a")
+
+	other := &State{Client: c}
+	if err := other.Unlock("unknown"); err == nil {
+		t.Fatal("expected an error unlocking an unknown lock")
+	}
+	if err := other.Unlock(idA); err != nil {
+		t.Fatal(err)
+	}
+
+	id := testSubtreeLock(t, other, "module.a")
+	if err := other.Unlock(id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStateSubtreeLocks_unsupported(t *testing.T) {
+	s := &State{Client: new(memLockingClient)}
+	if s.SubtreeLocks() {
+		t.Fatal("a client without blobs shouldn't support subtree locks")
+	}
+
+	s = &State{Client: new(memBlobClient)}
+	if s.SubtreeLocks() {
+		t.Fatal("a client without locking shouldn't support subtree locks")
+	}
+}
+
+func TestStateSubtreeLocks_invalid(t *testing.T) {
+	s := &State{Client: new(memSubtreeClient)}
+
+	info := state.NewLockInfo()
+	info.Modules = []string{"aws_instance.foo"}
+	if _, err := s.Lock(info); err == nil {
+		t.Fatal("expected an error locking an invalid module address")
+	}
+}
+
+func TestStateSubtreeLocks_wrapped(t *testing.T) {
+	s := &State{Client: NewBlobClient(new(memSubtreeClient), 16)}
+	if !s.SubtreeLocks() {
+		t.Fatal("a blob client should support subtree locks")
+	}
+
+	backup := &state.BackupState{Real: s}
+	if !backup.SubtreeLocks() {
+		t.Fatal("a backed up state should support subtree locks")
+	}
+
+	external := &state.ExternalLock{Inner: s, Locker: new(memSubtreeClient)}
+	if external.SubtreeLocks() {
+		t.Fatal("an externally locked state shouldn't support subtree locks")
+	}
+}
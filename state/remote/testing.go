@@ -2,6 +2,7 @@ package remote
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/state"
@@ -95,3 +96,33 @@ func TestRemoteLocks(t *testing.T, a, b Client) {
 
 	// TODO: Should we enforce that Unlock requires the correct ID?
 }
+
+// TestBlobStorer is a generic function to test the blobs of any client.
+func TestBlobStorer(t *testing.T, c ClientBlobStorer) {
+	id := strings.Repeat("ab", 32)
+	data := []byte("blob data")
+
+	if err := c.PutBlob(id, data); err != nil {
+		t.Fatalf("put: %s", err)
+	}
+
+	actual, err := c.GetBlob(id)
+	if err != nil {
+		t.Fatalf("get: %s", err)
+	}
+	if !bytes.Equal(actual, data) {
+		t.Fatalf("bad: %q", actual)
+	}
+
+	if err := c.DeleteBlob(id); err != nil {
+		t.Fatalf("delete: %s", err)
+	}
+	if _, err := c.GetBlob(id); err != ErrBlobNotFound {
+		t.Fatalf("deleted blob should not be found, got: %v", err)
+	}
+
+	// Deleting it again is fine
+	if err := c.DeleteBlob(id); err != nil {
+		t.Fatalf("delete: %s", err)
+	}
+}
@@ -61,6 +61,37 @@ type StatePersister interface {
 	PersistState() error
 }
 
+// StateModTimer is an optional interface implemented by states that know
+// when they were last persisted. ModTime returns the time as of the last
+// RefreshState, or the zero time if it isn't known.
+type StateModTimer interface {
+	ModTime() time.Time
+}
+
+// SubtreeLocker is an optional interface implemented by states that can
+// lock module subtrees of the state rather than the whole state, so that
+// operations on disjoint modules of one large state can run at the same
+// time. SubtreeLocks returns false if the storage of the state doesn't
+// support it after all.
+//
+// Lock only locks the modules in LockInfo.Modules, and the modules inside
+// them, if it's set, and the whole state otherwise. A subtree lock
+// conflicts with locks of the whole state and with locks of the modules it
+// contains or is contained by. While a state holds subtree locks,
+// PersistState only stores the changes to the locked modules and to the
+// outputs of the root module, merged into the latest stored state.
+type SubtreeLocker interface {
+	Locker
+	SubtreeLocks() bool
+}
+
+// Unwrapper is an optional interface implemented by states that wrap
+// another state, such as to back it up or to change how it's locked.
+type Unwrapper interface {
+	// Unwrap returns the wrapped state.
+	Unwrap() State
+}
+
 // Locker is implemented to lock state during command execution.
 // The info parameter can be recorded with the lock, but the
 // implementation should not depend in its value. The string returned by Lock
@@ -169,6 +200,11 @@ type LockInfo struct {
 
 	// Path to the state file when applicable. Set by the Lock implementation.
 	Path string
+
+	// Modules are the addresses of the module subtrees to lock, such as
+	// "module.network", for states that implement SubtreeLocker. If it's
+	// empty, the whole state is locked.
+	Modules []string `json:",omitempty"`
 }
 
 // Err returns the lock info formatted in an error
@@ -195,9 +231,13 @@ func (l *LockInfo) String() string {
   Version:   {{.Version}}
   Created:   {{.Created}}
   Info:      {{.Info}}
+{{- if .Modules}}
+  Modules:   {{join .Modules ", "}}
+{{- end}}
 `
 
-	t := template.Must(template.New("LockInfo").Parse(tmpl))
+	funcs := template.FuncMap{"join": strings.Join}
+	t := template.Must(template.New("LockInfo").Funcs(funcs).Parse(tmpl))
 	var out bytes.Buffer
 	if err := t.Execute(&out, l); err != nil {
 		panic(err)
@@ -0,0 +1,138 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ModuleAddrPath returns the path of the module with the given address,
+// such as "module.network.module.vpc", in the form of the paths of the
+// modules of terraform.State: []string{"root", "network", "vpc"}.
+func ModuleAddrPath(addr string) ([]string, error) {
+	parts := strings.Split(addr, ".")
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("invalid module address %q", addr)
+	}
+
+	result := append([]string(nil), rootModulePath...)
+	for i := 0; i < len(parts); i += 2 {
+		if parts[i] != "module" || parts[i+1] == "" {
+			return nil, fmt.Errorf("invalid module address %q", addr)
+		}
+		result = append(result, parts[i+1])
+	}
+
+	return result, nil
+}
+
+// ModulesConflict returns true if locks of the two lists of module
+// addresses conflict: if either is empty, since that's a lock of the whole
+// state, or if any module of one contains or is contained by any module of
+// the other. The addresses must be valid.
+func ModulesConflict(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+
+	for _, x := range a {
+		for _, y := range b {
+			px, _ := ModuleAddrPath(x)
+			py, _ := ModuleAddrPath(y)
+			if pathContains(px, py) || pathContains(py, px) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ModulesContain returns true if the module with the given path is one of
+// the modules with the given addresses, or is inside one of them. The
+// addresses must be valid.
+func ModulesContain(modules []string, path []string) bool {
+	for _, addr := range modules {
+		p, _ := ModuleAddrPath(addr)
+		if pathContains(p, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MergeModules returns the latest stored state with the given modules, and
+// the modules inside them, replaced by those of ours, for persisting a state
+// that only holds locks of those modules. The outputs of the root module
+// that ours changed since base, the state that it started from, are merged
+// too, since they often come from the locked modules. The addresses of the
+// modules must be valid.
+func MergeModules(latest, ours, base *terraform.State, modules []string) *terraform.State {
+	ours = ours.DeepCopy()
+	if latest == nil {
+		return ours
+	}
+
+	result := latest.DeepCopy()
+	kept := result.Modules[:0]
+	for _, m := range result.Modules {
+		if !ModulesContain(modules, m.Path) {
+			kept = append(kept, m)
+		}
+	}
+	result.Modules = kept
+	if ours != nil {
+		for _, m := range ours.Modules {
+			if ModulesContain(modules, m.Path) {
+				result.AddModuleState(m)
+			}
+		}
+	}
+
+	// The outputs removed from ours are removed, and those that were added
+	// or changed are set.
+	var oursOutputs, baseOutputs map[string]*terraform.OutputState
+	if root := ours.ModuleByPath(rootModulePath); root != nil {
+		oursOutputs = root.Outputs
+	}
+	if root := base.ModuleByPath(rootModulePath); root != nil {
+		baseOutputs = root.Outputs
+	}
+	root := result.ModuleByPath(rootModulePath)
+	for name := range baseOutputs {
+		if _, ok := oursOutputs[name]; !ok && root != nil {
+			delete(root.Outputs, name)
+		}
+	}
+	for name, o := range oursOutputs {
+		if b, ok := baseOutputs[name]; ok && b.Equal(o) {
+			continue
+		}
+		if root == nil {
+			root = result.AddModule(rootModulePath)
+		}
+		root.Outputs[name] = o
+	}
+
+	return result
+}
+
+// rootModulePath is the path of the root module in terraform.State.
+var rootModulePath = []string{"root"}
+
+// pathContains returns true if the module with the path a is, or contains,
+// the module with the path b.
+func pathContains(a, b []string) bool {
+	if len(a) > len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
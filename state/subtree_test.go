@@ -0,0 +1,119 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestModuleAddrPath(t *testing.T) {
+	cases := []struct {
+		Addr   string
+		Result []string
+		Err    bool
+	}{
+		{"module.a", []string{"root", "a"}, false},
+		{"module.a.module.b", []string{"root", "a", "b"}, false},
+		{"", nil, true},
+		{"module", nil, true},
+		{"module.", nil, true},
+		{"aws_instance.foo", nil, true},
+		{"module.a.aws_instance.foo", nil, true},
+	}
+
+	for _, tc := range cases {
+		result, err := ModuleAddrPath(tc.Addr)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%q: bad error: %s", tc.Addr, err)
+		}
+		if !reflect.DeepEqual(result, tc.Result) {
+			t.Fatalf("%q: expected %v, got %v", tc.Addr, tc.Result, result)
+		}
+	}
+}
+
+func TestModulesConflict(t *testing.T) {
+	cases := []struct {
+		A, B     []string
+		Conflict bool
+	}{
+		{nil, []string{"module.a"}, true},
+		{[]string{"module.a"}, nil, true},
+		{[]string{"module.a"}, []string{"module.a"}, true},
+		{[]string{"module.a"}, []string{"module.a.module.b"}, true},
+		{[]string{"module.a.module.b"}, []string{"module.a"}, true},
+		{[]string{"module.a"}, []string{"module.b"}, false},
+		{[]string{"module.a"}, []string{"module.ab"}, false},
+		{[]string{"module.a.module.b"}, []string{"module.a.module.c"}, false},
+		{[]string{"module.a", "module.c"}, []string{"module.b", "module.c"}, true},
+	}
+
+	for _, tc := range cases {
+		if c := ModulesConflict(tc.A, tc.B); c != tc.Conflict {
+			t.Fatalf("%v, %v: expected %t, got %t", tc.A, tc.B, tc.Conflict, c)
+		}
+	}
+}
+
+func TestMergeModules(t *testing.T) {
+	newState := func(ids map[string]string, outputs map[string]string) *terraform.State {
+		s := terraform.NewState()
+		for name, id := range ids {
+			path := []string{"root"}
+			if name != "" {
+				path = append(path, name)
+			}
+			m := s.ModuleByPath(path)
+			if m == nil {
+				m = s.AddModule(path)
+			}
+			m.Resources["test_instance.foo"] = &terraform.ResourceState{
+				Type:    "test_instance",
+				Primary: &terraform.InstanceState{ID: id},
+			}
+		}
+		for name, v := range outputs {
+			s.RootModule().Outputs[name] = &terraform.OutputState{Type: "string", Value: v}
+		}
+		return s
+	}
+
+	base := newState(
+		map[string]string{"": "root", "a": "a", "b": "b"},
+		map[string]string{"a": "a", "b": "b", "gone": "x"})
+	latest := newState(
+		map[string]string{"": "root2", "a": "a", "b": "b2"},
+		map[string]string{"a": "a", "b": "b2", "gone": "x"})
+	ours := newState(
+		map[string]string{"": "ignored", "a": "a2"},
+		map[string]string{"a": "a2", "b": "b"})
+
+	result := MergeModules(latest, ours, base, []string{"module.a"})
+
+	ids := make(map[string]string)
+	for _, m := range result.Modules {
+		if r, ok := m.Resources["test_instance.foo"]; ok {
+			ids[m.Path[len(m.Path)-1]] = r.Primary.ID
+		}
+	}
+	expectedIDs := map[string]string{"root": "root2", "a": "a2", "b": "b2"}
+	if !reflect.DeepEqual(ids, expectedIDs) {
+		t.Fatalf("expected modules %v, got %v", expectedIDs, ids)
+	}
+
+	outputs := make(map[string]interface{})
+	for name, o := range result.RootModule().Outputs {
+		outputs[name] = o.Value
+	}
+	expectedOutputs := map[string]interface{}{"a": "a2", "b": "b2"}
+	if !reflect.DeepEqual(outputs, expectedOutputs) {
+		t.Fatalf("expected outputs %v, got %v", expectedOutputs, outputs)
+	}
+
+	// Without a stored state, ours is stored as it is.
+	result = MergeModules(nil, ours, base, []string{"module.a"})
+	if !result.Equal(ours) {
+		t.Fatalf("expected our state, got %s", result)
+	}
+}
@@ -0,0 +1,64 @@
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/terraform/config"
+)
+
+// AttributeError is an error about the value of one attribute of the
+// configuration of a resource. Providers return it from validation and
+// apply so that the attribute can be pointed out along with the error,
+// which otherwise may not tell which attribute is at fault.
+type AttributeError struct {
+	// Path is the path of the attribute in the configuration, such as
+	// "ingress.0.from_port".
+	Path string
+
+	Err error
+
+	// Pos is where the attribute is set in the configuration files, as
+	// "file:line". Terraform sets it, when it's known, before the error is
+	// shown.
+	Pos string
+}
+
+func (e *AttributeError) Error() string {
+	if e.Pos == "" {
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("%s\n  (attribute %q, set at %s)", e.Err, e.Path, e.Pos)
+}
+
+// WrappedErrors implements errwrap.Wrapper.
+func (e *AttributeError) WrappedErrors() []error {
+	return []error{e.Err}
+}
+
+// ErrorAttribute returns the AttributeError that err is, or wraps, or nil if
+// there is none.
+func ErrorAttribute(err error) *AttributeError {
+	if ae, ok := errwrap.GetType(err, new(AttributeError)).(*AttributeError); ok {
+		return ae
+	}
+
+	return nil
+}
+
+// locateAttributeErrors sets the positions of the attributes that errs are
+// about, as they are configured in r.
+func locateAttributeErrors(r *config.Resource, errs ...error) {
+	if r == nil {
+		return
+	}
+
+	for _, err := range errs {
+		errwrap.Walk(err, func(err error) {
+			if ae, ok := err.(*AttributeError); ok && ae.Pos == "" {
+				ae.Pos = r.AttributePos(ae.Path)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/helper/warnings"
+)
+
+// ConfigWarnings returns warnings about practices in the configuration in m
+// and its child modules that work, but that teams may want to hold their
+// configurations to a stricter standard on with strict mode: interpolation
+// syntax that is deprecated, providers without a version constraint in the
+// module or in a parent module that it inherits the provider from, and child
+// modules that use a provider configured by a parent module without a
+// provider block of their own.
+//
+// Unlike other warnings, these are only shown by "terraform validate",
+// and otherwise only matter as errors in strict mode.
+func ConfigWarnings(m *module.Tree) []string {
+	ws := configWarnings(m, nil)
+	sort.Strings(ws)
+	return ws
+}
+
+// configWarnings returns the warnings for m and its children. inherited
+// has the full names of the providers configured by the parent modules of
+// m, and whether any of those configurations has a version constraint.
+func configWarnings(m *module.Tree, inherited map[string]bool) []string {
+	c := m.Config()
+	if c == nil {
+		return nil
+	}
+
+	prefix := modulePrefixStr(normalizeModulePath(m.Path()))
+	address := func(s string) string {
+		if prefix == "" {
+			return s
+		}
+		return prefix + "." + s
+	}
+
+	var ws []string
+	for _, msg := range c.DeprecatedInterpolations() {
+		if prefix != "" {
+			msg = prefix + ": " + msg
+		}
+		ws = append(ws, warnings.Format(warnings.CodeDeprecatedInterpolation, msg))
+	}
+
+	constrained := make(map[string]bool, len(inherited))
+	for name, v := range inherited {
+		constrained[name] = v
+	}
+	configs := c.ProviderConfigsByFullName()
+	for name, pc := range configs {
+		constrained[name] = inherited[name] || pc.Version != ""
+		if !constrained[name] {
+			ws = append(ws, warnings.Format(warnings.CodeProviderUnconstrained, fmt.Sprintf(
+				"%s: no version constraint, so any version of the provider can be installed; "+
+					"set \"version\" in the provider block",
+				address("provider."+name))))
+		}
+	}
+
+	// Resources that use a provider without a provider block in the module
+	// either inherit it, or use its default configuration. Only the first
+	// resource, by name, is given for each provider.
+	resources := make([]*config.Resource, len(c.Resources))
+	copy(resources, c.Resources)
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Id() < resources[j].Id()
+	})
+	seen := make(map[string]bool)
+	for _, r := range resources {
+		name := r.ProviderFullName()
+		if _, ok := configs[name]; ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		// A parent module without a version constraint for the provider
+		// has its own warning about it.
+		if _, ok := inherited[name]; ok {
+			ws = append(ws, warnings.Format(warnings.CodeProviderInherited, fmt.Sprintf(
+				"%s: %s uses provider.%s configured by a parent module; "+
+					"add a provider block for it to the module to make this explicit",
+				prefix, r.Id(), name)))
+			continue
+		}
+
+		ws = append(ws, warnings.Format(warnings.CodeProviderUnconstrained, fmt.Sprintf(
+			"%s: used by %s without a version constraint, so any version of the provider "+
+				"can be installed; add a provider block with \"version\"",
+			address("provider."+name), r.Id())))
+	}
+
+	for _, child := range m.Children() {
+		ws = append(ws, configWarnings(child, constrained)...)
+	}
+
+	return ws
+}
+
+// StrictCodes returns the codes of the warnings that are errors in strict
+// mode: the given codes, along with those set in the strict block of the
+// root module of m.
+func StrictCodes(m *module.Tree, codes []string) []string {
+	result := make([]string, 0, len(codes))
+	result = append(result, codes...)
+	if m == nil {
+		return result
+	}
+
+	if c := m.Config(); c != nil && c.Terraform != nil && c.Terraform.Strict != nil {
+		result = append(result, c.Terraform.Strict.Warnings...)
+	}
+
+	return result
+}
@@ -0,0 +1,32 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigWarnings(t *testing.T) {
+	mod := testModule(t, "config-warnings")
+
+	got := ConfigWarnings(mod)
+	want := []string{
+		`[deprecated-interpolation] resource 'aws_instance.foo' config: "${...}" nested in an interpolation is deprecated; refer to the values directly`,
+		`[provider-inherited] module.child: aws_instance.foo uses provider.aws configured by a parent module; add a provider block for it to the module to make this explicit`,
+		`[provider-inherited] module.child: do_droplet.foo uses provider.do configured by a parent module; add a provider block for it to the module to make this explicit`,
+		`[provider-unconstrained] provider.aws: no version constraint, so any version of the provider can be installed; set "version" in the provider block`,
+		`[provider-unconstrained] provider.test: used by test_thing.foo without a version constraint, so any version of the provider can be installed; add a provider block with "version"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong warnings\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestStrictCodes(t *testing.T) {
+	mod := testModule(t, "validate-strict")
+
+	got := StrictCodes(mod, []string{"provider-inherited"})
+	want := []string{"provider-inherited", "provider-unconstrained"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad: %#v", got)
+	}
+}
@@ -3,16 +3,18 @@ package terraform
 import (
 	"context"
 	"fmt"
-	"log"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/terraform/config"
 	"github.com/hashicorp/terraform/config/module"
 	"github.com/hashicorp/terraform/helper/experiment"
+	"github.com/hashicorp/terraform/helper/warnings"
 )
 
 // InputMode defines what sort of input will be asked for when Input
@@ -35,6 +37,10 @@ const (
 	InputModeStd = InputModeVar | InputModeProvider
 )
 
+// defaultRefreshParallelismFactor is how many times the parallelism of
+// other operations refreshes run with if RefreshParallelism isn't set.
+const defaultRefreshParallelismFactor = 5
+
 var (
 	// contextFailOnShadowError will cause Context operations to return
 	// errors when shadow operations fail. This is only used for testing.
@@ -44,6 +50,10 @@ var (
 	// Plan operation, effectively testing the Diff DeepCopy whenever
 	// a Plan occurs. This is enabled for tests.
 	contextTestDeepCopyOnPlan = false
+
+	// providerCancelTimeout is how long the providers are given to return
+	// after they're asked to cancel, before they're stopped.
+	providerCancelTimeout = 10 * time.Second
 )
 
 // ContextOpts are the user-configurable options to create a context with
@@ -55,6 +65,7 @@ type ContextOpts struct {
 	Hooks              []Hook
 	Module             *module.Tree
 	Parallelism        int
+	RefreshParallelism int
 	State              *State
 	StateFutureAllowed bool
 	ProviderResolver   ResourceProviderResolver
@@ -63,10 +74,37 @@ type ContextOpts struct {
 	Targets            []string
 	Variables          map[string]interface{}
 
+	// ForceReplace are the addresses of resources that will be planned
+	// for replacement, as if they were tainted, for this context only.
+	ForceReplace []string
+
 	// If non-nil, will apply as additional constraints on the provider
 	// plugins that will be requested from the provider resolver.
 	ProviderSHA256s map[string][]byte
 
+	// ProviderRetry limits the retries of provider operations that fail
+	// with transient errors. If nil, DefaultRetryPolicy is used.
+	ProviderRetry *RetryPolicy
+
+	// ParallelismAuto tunes the parallelism while operations run, starting
+	// from Parallelism, based on transient provider errors and the load of
+	// the system. The refresh parallelism is tuned along with it unless
+	// RefreshParallelism is set.
+	ParallelismAuto bool
+
+	// StrictWarnings are the codes of the warnings that validation returns
+	// as errors, along with those in the strict block of the configuration.
+	StrictWarnings []string
+
+	// GraphOrder, if set, is an ordering of the apply graph computed
+	// outside of Terraform, such as by a custom scheduler, that Apply
+	// follows on top of the dependencies.
+	GraphOrder *GraphOrder
+
+	// ProviderCredentials, if set, supplies credentials that providers are
+	// configured with in addition to their configuration.
+	ProviderCredentials ProviderCredentials
+
 	UIInput UIInput
 }
 
@@ -76,6 +114,28 @@ type ContextOpts struct {
 // initializer.
 type ContextMeta struct {
 	Env string // Env is the state environment
+
+	// RunID identifies the run in the lifecycles of the resources that it
+	// changes. A random ID is used if it's empty.
+	RunID string
+
+	// ProviderDefaults are the arguments that providers are configured
+	// with, by provider type, unless they're set in their configuration.
+	// They pass what a provider can't determine on its own, such as the
+	// current workspace, explicitly rather than through the environment of
+	// a plugin, which may have been started for another run.
+	ProviderDefaults map[string]map[string]string
+}
+
+// ProviderCredentials supplies credentials that providers are configured
+// with, such as ones read from Vault, which can change while an operation
+// runs.
+type ProviderCredentials interface {
+	// ProviderCredentials returns the arguments to configure providers of
+	// the given type with, and a generation that changes whenever the
+	// arguments do. Arguments that are set in the configuration of a
+	// provider take precedence.
+	ProviderCredentials(typ string) (map[string]string, uint64, error)
 }
 
 // Context represents all the context that Terraform needs in order to
@@ -104,9 +164,16 @@ type Context struct {
 	variables  map[string]interface{}
 
 	l                   sync.Mutex // Lock acquired during any task
-	parallelSem         Semaphore
+	forceReplace        []*ResourceAddress
+	graphOrder          *GraphOrder
+	parallelSem         *PrioritySemaphore
+	refreshSem          *PrioritySemaphore
+	parallelismTuner    *parallelismTuner
+	strictWarnings      []string
 	providerInputConfig map[string]map[string]interface{}
+	providerCredentials ProviderCredentials
 	providerSHA256s     map[string][]byte
+	providerRetry       *RetryPolicy
 	runLock             sync.Mutex
 	runCond             *sync.Cond
 	runContext          context.Context
@@ -164,6 +231,30 @@ func NewContext(opts *ContextOpts) (*Context, error) {
 		par = 10
 	}
 
+	// Refreshes only read, and dominate the time taken by large states, so
+	// by default they can run with more parallelism than other operations.
+	refreshPar := opts.RefreshParallelism
+	if refreshPar == 0 {
+		refreshPar = par * defaultRefreshParallelismFactor
+	}
+
+	parallelSem := NewPrioritySemaphore(par)
+	refreshSem := NewPrioritySemaphore(refreshPar)
+	var tuner *parallelismTuner
+	if opts.ParallelismAuto {
+		tunedRefreshSem := refreshSem
+		if opts.RefreshParallelism != 0 {
+			tunedRefreshSem = nil
+		}
+		tuner = newParallelismTuner(
+			parallelSem, tunedRefreshSem, defaultRefreshParallelismFactor)
+	}
+
+	providerRetry := opts.ProviderRetry
+	if providerRetry == nil {
+		providerRetry = DefaultRetryPolicy()
+	}
+
 	// Set up the variables in the following sequence:
 	//    0 - Take default values from the configuration
 	//    1 - Take values from TF_VAR_x environment variables
@@ -201,6 +292,18 @@ func NewContext(opts *ContextOpts) (*Context, error) {
 		diff = &Diff{}
 	}
 
+	// Parse the addresses of any resources that should be replaced so
+	// that invalid addresses are caught before any operation runs.
+	var forceReplace []*ResourceAddress
+	for _, v := range opts.ForceReplace {
+		addr, err := ParseResourceAddress(v)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid -replace address %q: %s", v, err)
+		}
+
+		forceReplace = append(forceReplace, addr)
+	}
+
 	return &Context{
 		components: &basicComponentFactory{
 			providers:    providers,
@@ -217,9 +320,16 @@ func NewContext(opts *ContextOpts) (*Context, error) {
 		uiInput:   opts.UIInput,
 		variables: variables,
 
-		parallelSem:         NewSemaphore(par),
+		forceReplace:        forceReplace,
+		graphOrder:          opts.GraphOrder,
+		parallelSem:         parallelSem,
+		refreshSem:          refreshSem,
+		parallelismTuner:    tuner,
+		strictWarnings:      opts.StrictWarnings,
 		providerInputConfig: make(map[string]map[string]interface{}),
+		providerCredentials: opts.ProviderCredentials,
 		providerSHA256s:     opts.ProviderSHA256s,
+		providerRetry:       providerRetry,
 		sh:                  sh,
 	}, nil
 }
@@ -240,7 +350,7 @@ func (c *Context) Graph(typ GraphType, opts *ContextGraphOpts) (*Graph, error) {
 		opts = &ContextGraphOpts{Validate: true}
 	}
 
-	log.Printf("[INFO] terraform: building graph: %s", typ)
+	logger.Info("building graph: %s", typ)
 	switch typ {
 	case GraphTypeApply:
 		return (&ApplyGraphBuilder{
@@ -251,6 +361,7 @@ func (c *Context) Graph(typ GraphType, opts *ContextGraphOpts) (*Graph, error) {
 			Provisioners: c.components.ResourceProvisioners(),
 			Targets:      c.targets,
 			Destroy:      c.destroy,
+			Order:        c.graphOrder,
 			Validate:     opts.Validate,
 		}).Build(RootModulePath)
 
@@ -263,11 +374,12 @@ func (c *Context) Graph(typ GraphType, opts *ContextGraphOpts) (*Graph, error) {
 	case GraphTypePlan:
 		// Create the plan graph builder
 		p := &PlanGraphBuilder{
-			Module:    c.module,
-			State:     c.state,
-			Providers: c.components.ResourceProviders(),
-			Targets:   c.targets,
-			Validate:  opts.Validate,
+			Module:       c.module,
+			State:        c.state,
+			Providers:    c.components.ResourceProviders(),
+			Targets:      c.targets,
+			ForceReplace: c.forceReplace,
+			Validate:     opts.Validate,
 		}
 
 		// Some special cases for other graph types shared with plan currently
@@ -352,6 +464,7 @@ func (c *Context) Interpolater() *Interpolater {
 		StateLock:          &stateLock,
 		VariableValues:     c.variables,
 		VariableValuesLock: &varLock,
+		GlobalValues:       c.variables,
 	}
 }
 
@@ -408,7 +521,7 @@ func (c *Context) Input(mode InputMode) error {
 
 			// this should only happen during tests
 			if c.uiInput == nil {
-				log.Println("[WARN] Content.uiInput is nil")
+				logger.Warn("Content.uiInput is nil")
 				continue
 			}
 
@@ -504,18 +617,50 @@ func (c *Context) Apply() (*State, error) {
 		operation = walkDestroy
 	}
 
+	// Collect the resources that are changed, to record their lifecycles
+	lh := new(lifecycleHook)
+	hooks := c.hooks
+	c.hooks = append(hooks[:len(hooks):len(hooks)], lh)
+	defer func() { c.hooks = hooks }()
+
 	// Walk the graph
 	walker, err := c.walk(graph, graph, operation)
 	if len(walker.ValidationErrors) > 0 {
 		err = multierror.Append(err, walker.ValidationErrors...)
 	}
 
+	runID, idErr := c.runID()
+	if idErr != nil {
+		err = multierror.Append(err, idErr)
+	} else {
+		lh.record(c.state, runID, time.Now().UTC())
+	}
+
+	// Record whether this apply was targeted, so that the next full plan
+	// can point out what it skipped.
+	c.state.TargetedApply = nil
+	if len(c.targets) > 0 {
+		c.state.TargetedApply = &TargetedApplyState{
+			Targets: c.targets,
+			Time:    time.Now().UTC(),
+		}
+	}
+
 	// Clean out any unused things
 	c.state.prune()
 
 	return c.state, err
 }
 
+// runID returns the ID of the run from the ContextMeta, or a random one.
+func (c *Context) runID() (string, error) {
+	if c.meta != nil && c.meta.RunID != "" {
+		return c.meta.RunID, nil
+	}
+
+	return uuid.GenerateUUID()
+}
+
 // Plan generates an execution plan for the given context.
 //
 // The execution plan encapsulates the context and can be stored
@@ -528,7 +673,7 @@ func (c *Context) Plan() (*Plan, error) {
 
 	p := &Plan{
 		Module:  c.module,
-		Vars:    c.variables,
+		Vars:    PersistedVariables(c.module, c.variables),
 		State:   c.state,
 		Targets: c.targets,
 
@@ -536,6 +681,14 @@ func (c *Context) Plan() (*Plan, error) {
 		ProviderSHA256s:  c.providerSHA256s,
 	}
 
+	// Every instance to replace must already exist, otherwise a typo in
+	// an address would silently plan nothing to replace.
+	if !c.destroy {
+		if err := c.checkForceReplace(); err != nil {
+			return nil, err
+		}
+	}
+
 	var operation walkOperation
 	if c.destroy {
 		operation = walkPlanDestroy
@@ -580,6 +733,10 @@ func (c *Context) Plan() (*Plan, error) {
 	}
 	p.Diff = c.diff
 
+	// The walk adds the diffs of the modules in the order it visits them,
+	// so they're sorted to make plans of the same changes the same.
+	sort.Sort(moduleDiffSort(p.Diff.Modules))
+
 	// If this is true, it means we're running unit tests. In this case,
 	// we perform a deep copy just to ensure that all context tests also
 	// test that a diff is copy-able. This will panic if it fails. This
@@ -610,6 +767,63 @@ func (c *Context) Plan() (*Plan, error) {
 	return p, errs
 }
 
+// checkForceReplace returns an error for every -replace address that
+// doesn't name an instance in the state. If an address without an index
+// only missed because the resource has count or for_each, the error lists
+// the addresses of its instances.
+func (c *Context) checkForceReplace() error {
+	if len(c.forceReplace) == 0 {
+		return nil
+	}
+
+	var instances []*ResourceAddress
+	if c.state != nil {
+		for _, ms := range c.state.Modules {
+			for name := range ms.Resources {
+				addr, err := parseResourceAddressInternal(name)
+				if err != nil {
+					return fmt.Errorf(
+						"Error parsing internal name, this is a bug: %q", name)
+				}
+				addr.Path = ms.Path[1:]
+				instances = append(instances, addr)
+			}
+		}
+	}
+
+	var errs error
+	for _, addr := range c.forceReplace {
+		var found bool
+		var similar []string
+		for _, inst := range instances {
+			if forceReplaceMatch(addr, inst) {
+				found = true
+				break
+			}
+			if addr.Equals(inst) {
+				similar = append(similar, inst.String())
+			}
+		}
+		if found {
+			continue
+		}
+
+		if len(similar) > 0 {
+			sort.Strings(similar)
+			errs = multierror.Append(errs, fmt.Errorf(
+				"-replace address %q doesn't match a single instance. "+
+					"Set the address of each instance to replace: %s",
+				addr, strings.Join(similar, ", ")))
+			continue
+		}
+
+		errs = multierror.Append(errs, fmt.Errorf(
+			"-replace address %q doesn't match any resource in the state", addr))
+	}
+
+	return errs
+}
+
 // Refresh goes through all the resources in the state and refreshes them
 // to their latest state. This will update the state that this context
 // works with, along with returning it.
@@ -643,14 +857,14 @@ func (c *Context) Refresh() (*State, error) {
 //
 // Stop will block until the task completes.
 func (c *Context) Stop() {
-	log.Printf("[WARN] terraform: Stop called, initiating interrupt sequence")
+	logger.Warn("Stop called, initiating interrupt sequence")
 
 	c.l.Lock()
 	defer c.l.Unlock()
 
 	// If we're running, then stop
 	if c.runContextCancel != nil {
-		log.Printf("[WARN] terraform: run context exists, stopping")
+		logger.Warn("run context exists, stopping")
 
 		// Tell the hook we want to stop
 		c.sh.Stop()
@@ -665,7 +879,20 @@ func (c *Context) Stop() {
 		cond.Wait()
 	}
 
-	log.Printf("[WARN] terraform: stop complete")
+	logger.Warn("stop complete")
+}
+
+// ValidateVariables validates the values of the variables of the root
+// module without the rest of the configuration. It's used when a plan is
+// applied, which isn't validated again, to check the values of the
+// ephemeral variables, which aren't stored in the plan.
+func (c *Context) ValidateVariables() []error {
+	config := c.module.Config()
+	if config == nil {
+		return nil
+	}
+
+	return smcUserVariables(config, c.variables)
 }
 
 // Validate validates the configuration and returns any warnings or errors.
@@ -712,12 +939,23 @@ func (c *Context) Validate() ([]string, []error) {
 	// Return the result
 	rerrs := multierror.Append(errs, walker.ValidationErrors...)
 
-	sort.Strings(walker.ValidationWarnings)
+	// Warn about deprecated modules, which aren't part of the graph
+	warns := append(walker.ValidationWarnings, ModuleWarnings(c.module)...)
+
+	// Strict mode turns warnings into errors. The warnings about the
+	// configuration only matter here if they are errors.
+	strict := StrictCodes(c.module, c.strictWarnings)
+	warns, strictErrs := warnings.Strict(warns, strict)
+	_, configErrs := warnings.Strict(ConfigWarnings(c.module), strict)
+	rerrs = multierror.Append(rerrs, strictErrs...)
+	rerrs = multierror.Append(rerrs, configErrs...)
+
+	sort.Strings(warns)
 	sort.Slice(rerrs.Errors, func(i, j int) bool {
 		return rerrs.Errors[i].Error() < rerrs.Errors[j].Error()
 	})
 
-	return walker.ValidationWarnings, rerrs.Errors
+	return warns, rerrs.Errors
 }
 
 // Module returns the module tree associated with this context.
@@ -804,7 +1042,7 @@ func (c *Context) walk(
 
 	// Just log this so we can see it in a debug log
 	if !c.shadow {
-		log.Printf("[WARN] terraform: shadow graph disabled")
+		logger.Warn("shadow graph disabled")
 		shadow = nil
 	}
 
@@ -818,12 +1056,13 @@ func (c *Context) walk(
 		realCtx, shadowCtx, shadowCloser = newShadowContext(c)
 	}
 
-	log.Printf("[DEBUG] Starting graph walk: %s", operation.String())
+	logger.Debug("starting graph walk: %s", operation.String())
 
 	walker := &ContextGraphWalker{
 		Context:     realCtx,
 		Operation:   operation,
 		StopContext: c.runContext,
+		priorities:  schedulePriorities(graph),
 	}
 
 	// Watch for a stop so we can call the provider Stop() API.
@@ -861,7 +1100,7 @@ func (c *Context) walk(
 
 		// Kick off the shadow walk. This will block on any operations
 		// on the real walk so it is fine to start first.
-		log.Printf("[INFO] Starting shadow graph walk: %s", operation.String())
+		logger.Info("starting shadow graph walk: %s", operation.String())
 		shadowCh := make(chan error)
 		go func() {
 			shadowCh <- shadow.Walk(shadowWalker)
@@ -873,7 +1112,7 @@ func (c *Context) walk(
 		}
 
 		// Wait for the walk to end
-		log.Printf("[DEBUG] Waiting for shadow graph to complete...")
+		logger.Debug("waiting for shadow graph to complete...")
 		shadowWalkErr := <-shadowCh
 
 		// Get any shadow errors
@@ -908,9 +1147,9 @@ func (c *Context) walk(
 		}
 
 		if c.shadowErr == nil {
-			log.Printf("[INFO] Shadow graph success!")
+			logger.Info("shadow graph success!")
 		} else {
-			log.Printf("[ERROR] Shadow graph error: %s", c.shadowErr)
+			logger.Error("shadow graph error: %s", c.shadowErr)
 
 			// If we're supposed to fail on shadow errors, then report it
 			if contextFailOnShadowError {
@@ -925,8 +1164,10 @@ func (c *Context) walk(
 
 // watchStop immediately returns a `stop` and a `wait` chan after dispatching
 // the watchStop goroutine. This will watch the runContext for cancellation and
-// stop the providers accordingly.  When the watch is no longer needed, the
-// `stop` chan should be closed before waiting on the `wait` chan.
+// cancel the providers and stop the provisioners accordingly, then stop the
+// providers if the walk isn't done within providerCancelTimeout. When the
+// watch is no longer needed, the `stop` chan should be closed before waiting
+// on the `wait` chan.
 // The `wait` chan is important, because without synchronizing with the end of
 // the watchStop goroutine, the runContext may also be closed during the select
 // incorrectly causing providers to be stopped. Even if the graph walk is done
@@ -954,23 +1195,28 @@ func (c *Context) watchStop(walker *ContextGraphWalker) (chan struct{}, <-chan s
 
 		// If we're here, we're stopped, trigger the call.
 
-		{
-			// Copy the providers so that a misbehaved blocking Stop doesn't
-			// completely hang Terraform.
-			walker.providerLock.Lock()
-			ps := make([]ResourceProvider, 0, len(walker.providerCache))
-			for _, p := range walker.providerCache {
-				ps = append(ps, p)
-			}
-			defer walker.providerLock.Unlock()
+		// Copy the providers so that a misbehaved blocking Stop doesn't
+		// completely hang Terraform.
+		walker.providerLock.Lock()
+		providers := make([]ResourceProvider, 0, len(walker.providerCache))
+		for _, p := range walker.providerCache {
+			providers = append(providers, p)
+		}
 
-			for _, p := range ps {
-				// We ignore the error for now since there isn't any reasonable
-				// action to take if there is an error here, since the stop is still
-				// advisory: Terraform will exit once the graph node completes.
+		for _, p := range providers {
+			// Providers that can are asked to cancel gracefully, so that
+			// they return the state of what they've created so far, and
+			// the others are stopped. We ignore the error for now since
+			// there isn't any reasonable action to take if there is an
+			// error here, since the cancellation is still advisory:
+			// Terraform will exit once the graph node completes.
+			if c, ok := p.(ResourceProviderCanceler); ok {
+				c.Cancel()
+			} else {
 				p.Stop()
 			}
 		}
+		walker.providerLock.Unlock()
 
 		{
 			// Call stop on all the provisioners
@@ -979,7 +1225,6 @@ func (c *Context) watchStop(walker *ContextGraphWalker) (chan struct{}, <-chan s
 			for _, p := range walker.provisionerCache {
 				ps = append(ps, p)
 			}
-			defer walker.provisionerLock.Unlock()
 
 			for _, p := range ps {
 				// We ignore the error for now since there isn't any reasonable
@@ -987,6 +1232,21 @@ func (c *Context) watchStop(walker *ContextGraphWalker) (chan struct{}, <-chan s
 				// advisory: Terraform will exit once the graph node completes.
 				p.Stop()
 			}
+			walker.provisionerLock.Unlock()
+		}
+
+		// Providers that haven't returned in time, such as those that
+		// ignore a cancellation, are stopped.
+		select {
+		case <-stop:
+			return
+		case <-time.After(providerCancelTimeout):
+		}
+
+		walker.providerLock.Lock()
+		defer walker.providerLock.Unlock()
+		for _, p := range providers {
+			p.Stop()
 		}
 	}()
 
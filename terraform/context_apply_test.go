@@ -605,6 +605,228 @@ func TestContext2Apply_providerAlias(t *testing.T) {
 	}
 }
 
+// A provider selected by an expression is selected for each instance.
+func TestContext2Apply_providerExpr(t *testing.T) {
+	m := testModule(t, "apply-provider-expr")
+	factory := func() (ResourceProvider, error) {
+		var region string
+		p := testProvider("aws")
+		p.DiffFn = testDiffFn
+		p.ConfigureFn = func(c *ResourceConfig) error {
+			if v, ok := c.Get("region"); ok {
+				region = v.(string)
+			}
+			return nil
+		}
+		p.ApplyFn = func(
+			info *InstanceInfo,
+			s *InstanceState,
+			d *InstanceDiff) (*InstanceState, error) {
+			s, err := testApplyFn(info, s, d)
+			if s != nil {
+				s.Attributes["region"] = region
+			}
+			return s, err
+		}
+		return p, nil
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": factory,
+			},
+		),
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	mod := state.RootModule()
+	for key, region := range map[string]string{
+		"aws_instance.foo.0": "east",
+		"aws_instance.foo.1": "west",
+	} {
+		rs := mod.Resources[key]
+		if rs == nil {
+			t.Fatalf("%s not in state:\n%s", key, state)
+		}
+		if rs.Provider != "aws."+region {
+			t.Fatalf("%s: bad provider: %s", key, rs.Provider)
+		}
+		if actual := rs.Primary.Attributes["region"]; actual != region {
+			t.Fatalf("%s: bad region: %s", key, actual)
+		}
+	}
+}
+
+// A provider_config block configures a provider for one resource, with the
+// arguments of the module's provider configuration that it doesn't override.
+func TestContext2Apply_providerConfigOverride(t *testing.T) {
+	m := testModule(t, "apply-provider-config-override")
+	factory := func() (ResourceProvider, error) {
+		var region, zone string
+		p := testProvider("aws")
+		p.DiffFn = testDiffFn
+		p.ConfigureFn = func(c *ResourceConfig) error {
+			if v, ok := c.Get("region"); ok {
+				region = v.(string)
+			}
+			if v, ok := c.Get("zone"); ok {
+				zone = v.(string)
+			}
+			return nil
+		}
+		p.ApplyFn = func(
+			info *InstanceInfo,
+			s *InstanceState,
+			d *InstanceDiff) (*InstanceState, error) {
+			s, err := testApplyFn(info, s, d)
+			if s != nil {
+				s.Attributes["region"] = region
+				s.Attributes["zone"] = zone
+			}
+			return s, err
+		}
+		return p, nil
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": factory,
+			},
+		),
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	mod := state.RootModule()
+	for key, region := range map[string]string{
+		"aws_instance.foo": "west",
+		"aws_instance.bar": "east",
+	} {
+		rs := mod.Resources[key]
+		if rs == nil {
+			t.Fatalf("%s not in state:\n%s", key, state)
+		}
+		if actual := rs.Primary.Attributes["region"]; actual != region {
+			t.Fatalf("%s: bad region: %s", key, actual)
+		}
+		if actual := rs.Primary.Attributes["zone"]; actual != "a" {
+			t.Fatalf("%s: bad zone: %s", key, actual)
+		}
+	}
+	if p := mod.Resources["aws_instance.foo"].Provider; p != "aws.provider_config_aws_instance_foo" {
+		t.Fatalf("bad provider: %s", p)
+	}
+}
+
+// A provider_config block in a child module overrides the provider
+// configuration that the module inherits from its parent.
+func TestContext2Apply_providerConfigOverrideModule(t *testing.T) {
+	m := testModule(t, "apply-provider-config-override-module")
+	factory := func() (ResourceProvider, error) {
+		var region, zone string
+		p := testProvider("aws")
+		p.DiffFn = testDiffFn
+		p.ConfigureFn = func(c *ResourceConfig) error {
+			if v, ok := c.Get("region"); ok {
+				region = v.(string)
+			}
+			if v, ok := c.Get("zone"); ok {
+				zone = v.(string)
+			}
+			return nil
+		}
+		p.ApplyFn = func(
+			info *InstanceInfo,
+			s *InstanceState,
+			d *InstanceDiff) (*InstanceState, error) {
+			s, err := testApplyFn(info, s, d)
+			if s != nil {
+				s.Attributes["region"] = region
+				s.Attributes["zone"] = zone
+			}
+			return s, err
+		}
+		return p, nil
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": factory,
+			},
+		),
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	mod := state.ModuleByPath([]string{"root", "child"})
+	if mod == nil {
+		t.Fatalf("no child module in state:\n%s", state)
+	}
+	for key, region := range map[string]string{
+		"aws_instance.foo": "west",
+		"aws_instance.bar": "east",
+	} {
+		rs := mod.Resources[key]
+		if rs == nil {
+			t.Fatalf("%s not in state:\n%s", key, state)
+		}
+		if actual := rs.Primary.Attributes["region"]; actual != region {
+			t.Fatalf("%s: bad region: %s", key, actual)
+		}
+		if actual := rs.Primary.Attributes["zone"]; actual != "a" {
+			t.Fatalf("%s: bad zone: %s", key, actual)
+		}
+	}
+}
+
+// A provider selected by an expression must be declared in the module.
+func TestContext2Plan_providerExprUndeclared(t *testing.T) {
+	m := testModule(t, "apply-provider-expr")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Variables: map[string]interface{}{
+			"regions": []interface{}{"east", "north"},
+		},
+	})
+
+	_, err := ctx.Plan()
+	if err == nil || !strings.Contains(err.Error(), `provider "aws.north" isn't declared`) {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
 // Two providers that are configured should both be configured prior to apply
 func TestContext2Apply_providerAliasConfigure(t *testing.T) {
 	m := testModule(t, "apply-provider-alias-configure")
@@ -662,6 +884,168 @@ func TestContext2Apply_providerAliasConfigure(t *testing.T) {
 }
 
 // GH-2870
+func TestContext2Apply_transientError(t *testing.T) {
+	m := testModule(t, "apply-provider-warning")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+
+	calls := 0
+	p.ApplyFn = func(info *InstanceInfo, s *InstanceState, d *InstanceDiff) (*InstanceState, error) {
+		calls++
+		if calls < 3 {
+			return nil, TransientError(fmt.Errorf("throttled"))
+		}
+		return testApplyFn(info, s, d)
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		ProviderRetry: &RetryPolicy{MaxRetries: 3},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls to apply, got %d", calls)
+	}
+
+	actual := strings.TrimSpace(state.String())
+	expected := strings.TrimSpace(`
+aws_instance.foo:
+  ID = foo
+	`)
+	if actual != expected {
+		t.Fatalf("got: \n%s\n\nexpected:\n%s", actual, expected)
+	}
+}
+
+func TestContext2Apply_transientErrorLimit(t *testing.T) {
+	m := testModule(t, "apply-provider-warning")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+
+	calls := 0
+	p.ApplyFn = func(info *InstanceInfo, s *InstanceState, d *InstanceDiff) (*InstanceState, error) {
+		calls++
+		return nil, TransientError(fmt.Errorf("throttled"))
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		ProviderRetry: &RetryPolicy{MaxRetries: 2},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err := ctx.Apply()
+	if err == nil || !strings.Contains(err.Error(), "gave up after 2 retries") {
+		t.Fatalf("bad: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls to apply, got %d", calls)
+	}
+}
+
+func TestContext2Apply_parallelismAuto(t *testing.T) {
+	m := testModule(t, "apply-provider-warning")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+
+	calls := 0
+	p.ApplyFn = func(info *InstanceInfo, s *InstanceState, d *InstanceDiff) (*InstanceState, error) {
+		calls++
+		if calls < 3 {
+			return nil, TransientError(fmt.Errorf("throttled"))
+		}
+		return testApplyFn(info, s, d)
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Parallelism:     8,
+		ParallelismAuto: true,
+		ProviderRetry:   &RetryPolicy{MaxRetries: 3},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := ctx.Apply(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The throttled applies halved the parallelism once, since the second
+	// came within the cooldown.
+	if n := ctx.parallelSem.Limit(); n != 4 {
+		t.Fatalf("bad: %d", n)
+	}
+	if n := ctx.refreshSem.Limit(); n != 4*defaultRefreshParallelismFactor {
+		t.Fatalf("bad: %d", n)
+	}
+}
+
+// A transient error isn't retried once the provider has created an object,
+// since retrying would create another.
+func TestContext2Apply_transientErrorCreated(t *testing.T) {
+	m := testModule(t, "apply-provider-warning")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+
+	calls := 0
+	p.ApplyFn = func(info *InstanceInfo, s *InstanceState, d *InstanceDiff) (*InstanceState, error) {
+		calls++
+		return &InstanceState{ID: "foo"}, TransientError(fmt.Errorf("throttled"))
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		ProviderRetry: &RetryPolicy{MaxRetries: 2},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call to apply, got %d", calls)
+	}
+	if rs := state.RootModule().Resources["aws_instance.foo"]; rs == nil || rs.Primary.ID != "foo" {
+		t.Fatalf("created object should be in the state:\n%s", state)
+	}
+}
+
 func TestContext2Apply_providerWarning(t *testing.T) {
 	m := testModule(t, "apply-provider-warning")
 	p := testProvider("aws")
@@ -1305,41 +1689,180 @@ func testContext2Apply_destroyDependsOn(t *testing.T) {
 	}
 }
 
-// Test that destroy ordering is correct with dependencies only
-// in the state.
-func TestContext2Apply_destroyDependsOnStateOnly(t *testing.T) {
+func TestContext2Apply_destroyPriority(t *testing.T) {
 	// It is possible for this to be racy, so we loop a number of times
 	// just to check.
 	for i := 0; i < 10; i++ {
-		testContext2Apply_destroyDependsOnStateOnly(t)
+		testContext2Apply_destroyPriority(t)
 	}
 }
 
-func testContext2Apply_destroyDependsOnStateOnly(t *testing.T) {
-	m := testModule(t, "empty")
+func testContext2Apply_destroyPriority(t *testing.T) {
+	m := testModule(t, "apply-destroy-priority")
 	p := testProvider("aws")
-	p.ApplyFn = testApplyFn
 	p.DiffFn = testDiffFn
 	state := &State{
 		Modules: []*ModuleState{
 			&ModuleState{
 				Path: rootModulePath,
 				Resources: map[string]*ResourceState{
-					"aws_instance.foo": &ResourceState{
+					"aws_instance.alb": &ResourceState{
 						Type: "aws_instance",
 						Primary: &InstanceState{
-							ID:         "foo",
+							ID:         "alb",
 							Attributes: map[string]string{},
 						},
 					},
 
-					"aws_instance.bar": &ResourceState{
+					"aws_instance.asg": &ResourceState{
 						Type: "aws_instance",
 						Primary: &InstanceState{
-							ID:         "bar",
+							ID:         "asg",
 							Attributes: map[string]string{},
 						},
-						Dependencies: []string{"aws_instance.foo"},
+					},
+
+					"aws_instance.web": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID:         "web",
+							Attributes: map[string]string{},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// Record the order we see Apply
+	var actual []string
+	var actualLock sync.Mutex
+	p.ApplyFn = func(
+		info *InstanceInfo, _ *InstanceState, _ *InstanceDiff) (*InstanceState, error) {
+		actualLock.Lock()
+		defer actualLock.Unlock()
+		actual = append(actual, info.Id)
+		return nil, nil
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State:   state,
+		Destroy: true,
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := ctx.Apply(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"aws_instance.asg", "aws_instance.web", "aws_instance.alb"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestContext2Apply_stage(t *testing.T) {
+	// It is possible for this to be racy, so we loop a number of times
+	// just to check.
+	for i := 0; i < 10; i++ {
+		testContext2Apply_stage(t)
+	}
+}
+
+func testContext2Apply_stage(t *testing.T) {
+	m := testModule(t, "apply-stage")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+
+	// Record the order we see Apply
+	var actual []string
+	var actualLock sync.Mutex
+	p.ApplyFn = func(
+		info *InstanceInfo, _ *InstanceState, _ *InstanceDiff) (*InstanceState, error) {
+		actualLock.Lock()
+		defer actualLock.Unlock()
+		actual = append(actual, info.HumanId())
+		return &InstanceState{ID: info.Id}, nil
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := ctx.Apply(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(actual) != 5 {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The certificate is created last of the staged resources, after every
+	// record in stage 1, including the one in the module.
+	order := make(map[string]int)
+	for i, id := range actual {
+		order[id] = i
+	}
+	for _, id := range []string{"aws_instance.dns.0", "aws_instance.dns.1", "module.child.aws_instance.dns"} {
+		if order[id] > order["aws_instance.cert"] {
+			t.Fatalf("bad: %#v", actual)
+		}
+	}
+}
+
+// Test that destroy ordering is correct with dependencies only
+// in the state.
+func TestContext2Apply_destroyDependsOnStateOnly(t *testing.T) {
+	// It is possible for this to be racy, so we loop a number of times
+	// just to check.
+	for i := 0; i < 10; i++ {
+		testContext2Apply_destroyDependsOnStateOnly(t)
+	}
+}
+
+func testContext2Apply_destroyDependsOnStateOnly(t *testing.T) {
+	m := testModule(t, "empty")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	state := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.foo": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID:         "foo",
+							Attributes: map[string]string{},
+						},
+					},
+
+					"aws_instance.bar": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID:         "bar",
+							Attributes: map[string]string{},
+						},
+						Dependencies: []string{"aws_instance.foo"},
 					},
 				},
 			},
@@ -1862,11 +2385,124 @@ func TestContext2Apply_cancel(t *testing.T) {
 		t.Fatalf("bad: \n%s", actual)
 	}
 
+	if !p.CancelCalled {
+		t.Fatal("cancel should be called")
+	}
+}
+
+func TestContext2Apply_cancelStop(t *testing.T) {
+	defer func(d time.Duration) { providerCancelTimeout = d }(providerCancelTimeout)
+	providerCancelTimeout = 10 * time.Millisecond
+
+	m := testModule(t, "apply-cancel-block")
+	p := testProvider("aws")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	// The provider ignores the cancellation, and only returns once it's
+	// stopped
+	stopCh := make(chan struct{})
+	p.StopFn = func() error {
+		close(stopCh)
+		return nil
+	}
+	applyCh := make(chan struct{})
+	p.DiffFn = testDiffFn
+	p.ApplyFn = func(*InstanceInfo, *InstanceState, *InstanceDiff) (*InstanceState, error) {
+		close(applyCh)
+		select {
+		case <-stopCh:
+		case <-time.After(5 * time.Second):
+			t.Error("provider should be stopped")
+		}
+
+		return &InstanceState{
+			ID: "foo",
+		}, nil
+	}
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	go func() {
+		<-applyCh
+		ctx.Stop()
+	}()
+	if _, err := ctx.Apply(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !p.CancelCalled {
+		t.Fatal("cancel should be called")
+	}
 	if !p.StopCalled {
 		t.Fatal("stop should be called")
 	}
 }
 
+func TestContext2Apply_cancelNotCanceler(t *testing.T) {
+	defer func(d time.Duration) { providerCancelTimeout = d }(providerCancelTimeout)
+	providerCancelTimeout = time.Minute
+
+	m := testModule(t, "apply-cancel-block")
+	p := testProvider("aws")
+
+	// The provider only implements ResourceProvider, so it can't cancel
+	provider := struct{ ResourceProvider }{p}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(provider),
+			},
+		),
+	})
+
+	// It's stopped right away instead
+	stopCh := make(chan struct{})
+	p.StopFn = func() error {
+		close(stopCh)
+		return nil
+	}
+	applyCh := make(chan struct{})
+	p.DiffFn = testDiffFn
+	p.ApplyFn = func(*InstanceInfo, *InstanceState, *InstanceDiff) (*InstanceState, error) {
+		close(applyCh)
+		select {
+		case <-stopCh:
+		case <-time.After(5 * time.Second):
+			t.Error("provider should be stopped")
+		}
+
+		return &InstanceState{
+			ID: "foo",
+		}, nil
+	}
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	go func() {
+		<-applyCh
+		ctx.Stop()
+	}()
+	if _, err := ctx.Apply(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if p.CancelCalled {
+		t.Fatal("cancel shouldn't be called")
+	}
+}
+
 func TestContext2Apply_cancelBlock(t *testing.T) {
 	m := testModule(t, "apply-cancel-block")
 	p := testProvider("aws")
@@ -2560,6 +3196,54 @@ module.child:
 	`)
 }
 
+func TestContext2Apply_moduleProviderInheritNested(t *testing.T) {
+	m := testModule(t, "apply-module-provider-inherit-nested")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+
+	var lock sync.Mutex
+	var actual map[string]interface{}
+	p.ConfigureFn = func(c *ResourceConfig) error {
+		lock.Lock()
+		defer lock.Unlock()
+		actual = c.Config
+		return nil
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := ctx.Apply(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The maps and blocks that both set are merged, rather than the ones
+	// of the parent replacing those of the child.
+	expected := map[string]interface{}{
+		"region": "root",
+		"endpoints": []map[string]interface{}{
+			{"s3": "s3.root", "ec2": "ec2.child"},
+		},
+		"tags": []map[string]interface{}{
+			{"a": "root", "b": "child"},
+		},
+		"ids": []interface{}{"child"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestContext2Apply_moduleOrphanInheritAlias(t *testing.T) {
 	m := testModule(t, "apply-module-provider-inherit-alias-orphan")
 	p := testProvider("aws")
@@ -2775,21 +3459,16 @@ func TestContext2Apply_moduleGrandchildProvider(t *testing.T) {
 // in the root weren't being added to the root properly. In this test
 // case: aws is explicitly added to root, but "test" should be added to.
 // With the bug, it wasn't.
-func TestContext2Apply_moduleOnlyProvider(t *testing.T) {
-	m := testModule(t, "apply-module-only-provider")
+func TestContext2Apply_moduleFile(t *testing.T) {
+	m := testModule(t, "apply-module-file")
 	p := testProvider("aws")
 	p.ApplyFn = testApplyFn
 	p.DiffFn = testDiffFn
-	pTest := testProvider("test")
-	pTest.ApplyFn = testApplyFn
-	pTest.DiffFn = testDiffFn
-
 	ctx := testContext2(t, &ContextOpts{
 		Module: m,
 		ProviderResolver: ResourceProviderResolverFixed(
 			map[string]ResourceProviderFactory{
-				"aws":  testProviderFuncFixed(p),
-				"test": testProviderFuncFixed(pTest),
+				"aws": testProviderFuncFixed(p),
 			},
 		),
 	})
@@ -2803,11 +3482,174 @@ func TestContext2Apply_moduleOnlyProvider(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
-	actual := strings.TrimSpace(state.String())
-	expected := strings.TrimSpace(testTerraformApplyModuleOnlyProviderStr)
-	if actual != expected {
-		t.Fatalf("bad: \n%s", actual)
-	}
+	// file() in the child is relative to the child, and the root's
+	// path.root prefix isn't joined with the module directory again.
+	for _, path := range [][]string{rootModulePath, []string{"root", "child"}} {
+		mod := state.ModuleByPath(path)
+		if mod == nil {
+			t.Fatalf("no module: %#v", path)
+		}
+		rs := mod.Resources["aws_instance.foo"]
+		if rs == nil {
+			t.Fatalf("no resource: %#v", path)
+		}
+		if v := rs.Primary.Attributes["foo"]; v != "hello" {
+			t.Fatalf("%#v: bad: %#v", path, v)
+		}
+	}
+}
+
+func TestContext2Apply_moduleOnlyProvider(t *testing.T) {
+	m := testModule(t, "apply-module-only-provider")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	pTest := testProvider("test")
+	pTest.ApplyFn = testApplyFn
+	pTest.DiffFn = testDiffFn
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws":  testProviderFuncFixed(p),
+				"test": testProviderFuncFixed(pTest),
+			},
+		),
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(state.String())
+	expected := strings.TrimSpace(testTerraformApplyModuleOnlyProviderStr)
+	if actual != expected {
+		t.Fatalf("bad: \n%s", actual)
+	}
+}
+
+func TestContext2Apply_moduleSensitiveOutput(t *testing.T) {
+	m := testModule(t, "apply-module-sensitive-output")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	if w, e := ctx.Validate(); len(w) > 0 || len(e) > 0 {
+		t.Fatalf("bad: %#v %#v", w, e)
+	}
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The attribute set from the sensitive output must be hidden
+	rd := plan.Diff.RootModule().Resources["aws_instance.bar"]
+	if rd == nil {
+		t.Fatalf("bad: %s", plan.Diff)
+	}
+	if attr := rd.Attributes["foo"]; attr == nil || !attr.Sensitive {
+		t.Fatalf("bad: %#v", attr)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The output that references it is sensitive as well
+	o := state.RootModule().Outputs["password"]
+	if o == nil || o.Value != "secret" || !o.Sensitive {
+		t.Fatalf("bad: %#v", o)
+	}
+}
+
+func TestContext2Apply_sensitiveVariable(t *testing.T) {
+	m := testModule(t, "apply-sensitive-variable")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The attributes interpolated from the variable are hidden, including
+	// in the module it's passed to, but the others aren't
+	rd := plan.Diff.RootModule().Resources["aws_instance.foo"]
+	if rd == nil {
+		t.Fatalf("bad: %s", plan.Diff)
+	}
+	if attr := rd.Attributes["foo"]; attr == nil || !attr.Sensitive {
+		t.Fatalf("bad: %#v", attr)
+	}
+	if attr := rd.Attributes["num"]; attr == nil || attr.Sensitive {
+		t.Fatalf("bad: %#v", attr)
+	}
+
+	rd = plan.Diff.ModuleByPath([]string{"root", "child"}).Resources["aws_instance.child"]
+	if rd == nil {
+		t.Fatalf("bad: %s", plan.Diff)
+	}
+	if attr := rd.Attributes["foo"]; attr == nil || !attr.Sensitive {
+		t.Fatalf("bad: %#v", attr)
+	}
+
+	// So are the attributes interpolated from those, through resources
+	rd = plan.Diff.RootModule().Resources["aws_instance.bar"]
+	if rd == nil {
+		t.Fatalf("bad: %s", plan.Diff)
+	}
+	if attr := rd.Attributes["foo"]; attr == nil || !attr.Sensitive {
+		t.Fatalf("bad: %#v", attr)
+	}
+	if attr := rd.Attributes["num"]; attr == nil || attr.Sensitive {
+		t.Fatalf("bad: %#v", attr)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// So are the outputs, whether the variable is used in the same module
+	// or passed through another
+	outputs := state.RootModule().Outputs
+	if o := outputs["derived"]; o == nil || o.Value != "HUNTER2" || !o.Sensitive {
+		t.Fatalf("bad: %#v", o)
+	}
+	if o := outputs["from_child"]; o == nil || o.Value != "hello hunter2-suffix" || !o.Sensitive {
+		t.Fatalf("bad: %#v", o)
+	}
+	if o := outputs["from_resource"]; o == nil || o.Value != "hunter2-suffix" || !o.Sensitive {
+		t.Fatalf("bad: %#v", o)
+	}
+	if o := outputs["plain"]; o == nil || o.Sensitive {
+		t.Fatalf("bad: %#v", o)
+	}
 }
 
 func TestContext2Apply_moduleProviderAlias(t *testing.T) {
@@ -3339,6 +4181,74 @@ module.child:
 `)
 }
 
+func TestContext2Apply_forEach(t *testing.T) {
+	m := testModule(t, "apply-for-each")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Variables: map[string]interface{}{
+			"names": []interface{}{"a", "b", "c"},
+		},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := state.RootModule().Outputs["output"]
+	expected := "bar-a,bar-b,bar-c"
+	if actual == nil || actual.Value != expected {
+		t.Fatalf("bad: \n%s", actual)
+	}
+
+	// Remove an element from the middle of the list. Only its instance
+	// should be destroyed, unlike with count.
+	{
+		ctx := testContext2(t, &ContextOpts{
+			Module: m,
+			State:  state,
+			ProviderResolver: ResourceProviderResolverFixed(
+				map[string]ResourceProviderFactory{
+					"aws": testProviderFuncFixed(p),
+				},
+			),
+			Variables: map[string]interface{}{
+				"names": []interface{}{"a", "c"},
+			},
+		})
+
+		plan, err := ctx.Plan()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		resources := plan.Diff.RootModule().Resources
+		if len(resources) != 1 || !resources[`aws_instance.foo["b"]`].GetDestroy() {
+			t.Fatalf("bad: %s", plan.Diff)
+		}
+
+		state, err := ctx.Apply()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		checkStateString(t, state, testTerraformApplyForEachStr)
+	}
+}
+
 func TestContext2Apply_multiVar(t *testing.T) {
 	m := testModule(t, "apply-multi-var")
 	p := testProvider("aws")
@@ -4073,9 +4983,311 @@ func TestContext2Apply_provisionerModule(t *testing.T) {
 				"aws": testProviderFuncFixed(p),
 			},
 		),
-		Provisioners: map[string]ResourceProvisionerFactory{
-			"shell": testProvisionerFuncFixed(pr),
-		},
+		Provisioners: map[string]ResourceProvisionerFactory{
+			"shell": testProvisionerFuncFixed(pr),
+		},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(state.String())
+	expected := strings.TrimSpace(testTerraformApplyProvisionerModuleStr)
+	if actual != expected {
+		t.Fatalf("bad: \n%s", actual)
+	}
+
+	// Verify apply was invoked
+	if !pr.ApplyCalled {
+		t.Fatalf("provisioner not invoked")
+	}
+}
+
+// Outputs that provisioners record can be interpolated, and are kept when
+// the provider refreshes the instance.
+func TestContext2Apply_provisionerOutput(t *testing.T) {
+	m := testModule(t, "apply-provisioner-output")
+	p := testProvider("aws")
+	pr := testProvisioner()
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	pr.ApplyFn = func(rs *InstanceState, c *ResourceConfig) error {
+		rs.SetProvisionerOutput("version", "1.2.3")
+		return nil
+	}
+	providers := ResourceProviderResolverFixed(
+		map[string]ResourceProviderFactory{
+			"aws": testProviderFuncFixed(p),
+		},
+	)
+	ctx := testContext2(t, &ContextOpts{
+		Module:           m,
+		ProviderResolver: providers,
+		Provisioners: map[string]ResourceProvisionerFactory{
+			"shell": testProvisionerFuncFixed(pr),
+		},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	mod := state.RootModule()
+	if actual := mod.Resources["aws_instance.bar"].Primary.Attributes["foo"]; actual != "1.2.3" {
+		t.Fatalf("bad: %q\n\n%s", actual, state)
+	}
+
+	// The provider doesn't know about the outputs
+	p.RefreshFn = func(info *InstanceInfo, s *InstanceState) (*InstanceState, error) {
+		return &InstanceState{
+			ID:         s.ID,
+			Attributes: map[string]string{"id": s.ID, "num": s.Attributes["num"]},
+		}, nil
+	}
+	ctx = testContext2(t, &ContextOpts{
+		Module:           m,
+		ProviderResolver: providers,
+		Provisioners: map[string]ResourceProvisionerFactory{
+			"shell": testProvisionerFuncFixed(pr),
+		},
+		State: state,
+	})
+
+	state, err = ctx.Refresh()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]string{"version": "1.2.3"}
+	actual := state.RootModule().Resources["aws_instance.foo"].Primary.ProvisionerOutputs()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestContext2Apply_Provisioner_compute(t *testing.T) {
+	m := testModule(t, "apply-provisioner-compute")
+	p := testProvider("aws")
+	pr := testProvisioner()
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	pr.ApplyFn = func(rs *InstanceState, c *ResourceConfig) error {
+		val, ok := c.Config["foo"]
+		if !ok || val != "computed_dynamical" {
+			t.Fatalf("bad value for foo: %v %#v", val, c)
+		}
+
+		return nil
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Provisioners: map[string]ResourceProvisionerFactory{
+			"shell": testProvisionerFuncFixed(pr),
+		},
+		Variables: map[string]interface{}{
+			"value": "1",
+		},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(state.String())
+	expected := strings.TrimSpace(testTerraformApplyProvisionerStr)
+	if actual != expected {
+		t.Fatalf("bad: \n%s", actual)
+	}
+
+	// Verify apply was invoked
+	if !pr.ApplyCalled {
+		t.Fatalf("provisioner not invoked")
+	}
+}
+
+func TestContext2Apply_provisionerCreateFail(t *testing.T) {
+	m := testModule(t, "apply-provisioner-fail-create")
+	p := testProvider("aws")
+	pr := testProvisioner()
+	p.DiffFn = testDiffFn
+
+	p.ApplyFn = func(
+		info *InstanceInfo,
+		is *InstanceState,
+		id *InstanceDiff) (*InstanceState, error) {
+		is.ID = "foo"
+		return is, fmt.Errorf("error")
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Provisioners: map[string]ResourceProvisionerFactory{
+			"shell": testProvisionerFuncFixed(pr),
+		},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	actual := strings.TrimSpace(state.String())
+	expected := strings.TrimSpace(testTerraformApplyProvisionerFailCreateStr)
+	if actual != expected {
+		t.Fatalf("bad: \n%s", actual)
+	}
+}
+
+func TestContext2Apply_provisionerCreateFailNoId(t *testing.T) {
+	m := testModule(t, "apply-provisioner-fail-create")
+	p := testProvider("aws")
+	pr := testProvisioner()
+	p.DiffFn = testDiffFn
+
+	p.ApplyFn = func(
+		info *InstanceInfo,
+		is *InstanceState,
+		id *InstanceDiff) (*InstanceState, error) {
+		return nil, fmt.Errorf("error")
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Provisioners: map[string]ResourceProvisionerFactory{
+			"shell": testProvisionerFuncFixed(pr),
+		},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	actual := strings.TrimSpace(state.String())
+	expected := strings.TrimSpace(testTerraformApplyProvisionerFailCreateNoIdStr)
+	if actual != expected {
+		t.Fatalf("bad: \n%s", actual)
+	}
+}
+
+func TestContext2Apply_provisionerFail(t *testing.T) {
+	m := testModule(t, "apply-provisioner-fail")
+	p := testProvider("aws")
+	pr := testProvisioner()
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+
+	pr.ApplyFn = func(*InstanceState, *ResourceConfig) error {
+		return fmt.Errorf("EXPLOSION")
+	}
+
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Provisioners: map[string]ResourceProvisionerFactory{
+			"shell": testProvisionerFuncFixed(pr),
+		},
+		Variables: map[string]interface{}{
+			"value": "1",
+		},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	actual := strings.TrimSpace(state.String())
+	expected := strings.TrimSpace(testTerraformApplyProvisionerFailStr)
+	if actual != expected {
+		t.Fatalf("bad: \n%s", actual)
+	}
+}
+
+func TestContext2Apply_adoptExisting(t *testing.T) {
+	m := testModule(t, "apply-adopt-existing")
+	p := testProvider("aws")
+	p.ApplyFn = func(info *InstanceInfo, s *InstanceState, d *InstanceDiff) (*InstanceState, error) {
+		// The adopted object is updated rather than created
+		if s.ID != "i-abc123" {
+			return nil, fmt.Errorf("bad: %#v", s)
+		}
+
+		result, err := testApplyFn(info, s, d)
+		if result != nil {
+			result.ID = s.ID
+		}
+		return result, err
+	}
+	p.DiffFn = testDiffFn
+	p.LookupExistingReturn = &InstanceState{
+		ID:         "i-abc123",
+		Attributes: map[string]string{"num": "1"},
+	}
+	p.RefreshFn = func(info *InstanceInfo, s *InstanceState) (*InstanceState, error) {
+		if s.ID != "i-abc123" {
+			return nil, fmt.Errorf("bad: %#v", s)
+		}
+
+		return p.LookupExistingReturn.DeepCopy(), nil
+	}
+
+	h := new(MockHook)
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		Hooks:  []Hook{h},
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
 	})
 
 	if _, err := ctx.Plan(); err != nil {
@@ -4087,32 +5299,33 @@ func TestContext2Apply_provisionerModule(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
+	if !h.PostImportStateCalled {
+		t.Fatal("should call PostImportState")
+	}
+
 	actual := strings.TrimSpace(state.String())
-	expected := strings.TrimSpace(testTerraformApplyProvisionerModuleStr)
+	expected := strings.TrimSpace(`
+aws_instance.foo:
+  ID = i-abc123
+  num = 2
+  type = aws_instance
+`)
 	if actual != expected {
 		t.Fatalf("bad: \n%s", actual)
 	}
-
-	// Verify apply was invoked
-	if !pr.ApplyCalled {
-		t.Fatalf("provisioner not invoked")
-	}
 }
 
-func TestContext2Apply_Provisioner_compute(t *testing.T) {
-	m := testModule(t, "apply-provisioner-compute")
+func TestContext2Apply_adoptExistingNoChanges(t *testing.T) {
+	m := testModule(t, "apply-adopt-existing")
 	p := testProvider("aws")
-	pr := testProvisioner()
 	p.ApplyFn = testApplyFn
 	p.DiffFn = testDiffFn
-	pr.ApplyFn = func(rs *InstanceState, c *ResourceConfig) error {
-		val, ok := c.Config["foo"]
-		if !ok || val != "computed_dynamical" {
-			t.Fatalf("bad value for foo: %v %#v", val, c)
-		}
-
-		return nil
+	p.LookupExistingReturn = &InstanceState{
+		ID:         "i-abc123",
+		Attributes: map[string]string{"num": "2"},
 	}
+	p.RefreshFn = nil
+	p.RefreshReturn = p.LookupExistingReturn
 	ctx := testContext2(t, &ContextOpts{
 		Module: m,
 		ProviderResolver: ResourceProviderResolverFixed(
@@ -4120,12 +5333,6 @@ func TestContext2Apply_Provisioner_compute(t *testing.T) {
 				"aws": testProviderFuncFixed(p),
 			},
 		),
-		Provisioners: map[string]ResourceProvisionerFactory{
-			"shell": testProvisionerFuncFixed(pr),
-		},
-		Variables: map[string]interface{}{
-			"value": "1",
-		},
 	})
 
 	if _, err := ctx.Plan(); err != nil {
@@ -4137,32 +5344,34 @@ func TestContext2Apply_Provisioner_compute(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
+	if p.ApplyCalled {
+		t.Fatal("apply should not be called")
+	}
+
 	actual := strings.TrimSpace(state.String())
-	expected := strings.TrimSpace(testTerraformApplyProvisionerStr)
+	expected := strings.TrimSpace(`
+aws_instance.foo:
+  ID = i-abc123
+  num = 2
+`)
 	if actual != expected {
 		t.Fatalf("bad: \n%s", actual)
 	}
-
-	// Verify apply was invoked
-	if !pr.ApplyCalled {
-		t.Fatalf("provisioner not invoked")
-	}
 }
 
-func TestContext2Apply_provisionerCreateFail(t *testing.T) {
-	m := testModule(t, "apply-provisioner-fail-create")
+func TestContext2Apply_adoptExistingGone(t *testing.T) {
+	m := testModule(t, "apply-adopt-existing")
 	p := testProvider("aws")
-	pr := testProvisioner()
+	p.ApplyFn = testApplyFn
 	p.DiffFn = testDiffFn
-
-	p.ApplyFn = func(
-		info *InstanceInfo,
-		is *InstanceState,
-		id *InstanceDiff) (*InstanceState, error) {
-		is.ID = "foo"
-		return is, fmt.Errorf("error")
+	p.LookupExistingReturn = &InstanceState{
+		ID:         "i-abc123",
+		Attributes: map[string]string{"num": "1"},
 	}
 
+	// The object is deleted between the plan and the apply
+	p.RefreshFn = nil
+	p.RefreshReturn = nil
 	ctx := testContext2(t, &ContextOpts{
 		Module: m,
 		ProviderResolver: ResourceProviderResolverFixed(
@@ -4170,38 +5379,177 @@ func TestContext2Apply_provisionerCreateFail(t *testing.T) {
 				"aws": testProviderFuncFixed(p),
 			},
 		),
-		Provisioners: map[string]ResourceProvisionerFactory{
-			"shell": testProvisionerFuncFixed(pr),
-		},
 	})
 
 	if _, err := ctx.Plan(); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 
-	state, err := ctx.Apply()
-	if err == nil {
-		t.Fatal("should error")
+	_, err := ctx.Apply()
+	if err == nil || !strings.Contains(err.Error(), `"i-abc123" no longer exists`) {
+		t.Fatalf("bad: %v", err)
 	}
+	if p.ApplyCalled {
+		t.Fatal("apply should not be called")
+	}
+}
 
-	actual := strings.TrimSpace(state.String())
-	expected := strings.TrimSpace(testTerraformApplyProvisionerFailCreateStr)
-	if actual != expected {
-		t.Fatalf("bad: \n%s", actual)
+// testHookOutput is a Hook that records the output of provisioners and
+// lifecycle hooks.
+type testHookOutput struct {
+	NilHook
+
+	sync.Mutex
+	Lines []string
+}
+
+func (h *testHookOutput) ProvisionOutput(info *InstanceInfo, id string, msg string) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.Lines = append(h.Lines, fmt.Sprintf("%s (%s): %s", info.HumanId(), id, msg))
+}
+
+func TestContext2Apply_lifecycleHooks(t *testing.T) {
+	m := testModule(t, "apply-lifecycle-hooks")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+
+	apply := func(state *State, destroy bool, vars map[string]interface{}) (*State, []string) {
+		h := new(testHookOutput)
+		ctx := testContext2(t, &ContextOpts{
+			Module: m,
+			Hooks:  []Hook{h},
+			ProviderResolver: ResourceProviderResolverFixed(
+				map[string]ResourceProviderFactory{
+					"aws": testProviderFuncFixed(p),
+				},
+			),
+			State:     state,
+			Destroy:   destroy,
+			Variables: vars,
+		})
+
+		if _, err := ctx.Plan(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		state, err := ctx.Apply()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		return state, h.Lines
+	}
+
+	state, lines := apply(nil, false, nil)
+	expected := []string{
+		"aws_instance.foo (before_create): create aws_instance.foo",
+		"aws_instance.foo (after_create): created foo 2",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("bad: %#v", lines)
+	}
+
+	// Nothing runs without changes
+	state, lines = apply(state, false, nil)
+	if len(lines) != 0 {
+		t.Fatalf("bad: %#v", lines)
+	}
+
+	state, lines = apply(state, false, map[string]interface{}{"num": "3"})
+	expected = []string{
+		"aws_instance.foo (before_update): updating 2",
+		"aws_instance.foo (after_update): updated 3",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("bad: %#v", lines)
+	}
+
+	// The values of the resource aren't parsed by the shell
+	state, lines = apply(state, false, map[string]interface{}{"num": "$(echo 4); echo 5"})
+	expected = []string{
+		"aws_instance.foo (before_update): updating 3",
+		"aws_instance.foo (after_update): updated $(echo 4); echo 5",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("bad: %#v", lines)
+	}
+
+	state, lines = apply(state, true, nil)
+	expected = []string{
+		"aws_instance.foo (before_destroy): destroying foo",
+		"aws_instance.foo (after_destroy): destroyed foo",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("bad: %#v", lines)
+	}
+	if len(state.RootModule().Resources) != 0 {
+		t.Fatalf("bad: %s", state)
 	}
 }
 
-func TestContext2Apply_provisionerCreateFailNoId(t *testing.T) {
-	m := testModule(t, "apply-provisioner-fail-create")
+func TestContext2Apply_lifecycleHooksFail(t *testing.T) {
 	p := testProvider("aws")
-	pr := testProvisioner()
+	p.ApplyFn = testApplyFn
 	p.DiffFn = testDiffFn
 
-	p.ApplyFn = func(
-		info *InstanceInfo,
-		is *InstanceState,
-		id *InstanceDiff) (*InstanceState, error) {
-		return nil, fmt.Errorf("error")
+	apply := func(fixture string) (*State, error) {
+		ctx := testContext2(t, &ContextOpts{
+			Module: testModule(t, fixture),
+			ProviderResolver: ResourceProviderResolverFixed(
+				map[string]ResourceProviderFactory{
+					"aws": testProviderFuncFixed(p),
+				},
+			),
+		})
+
+		if _, err := ctx.Plan(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return ctx.Apply()
+	}
+
+	// A hook that fails before creating stops the resource from being
+	// created
+	state, err := apply("apply-lifecycle-hooks-before-fail")
+	if err == nil || !strings.Contains(err.Error(), "lifecycle before_create: exit status 1") {
+		t.Fatalf("bad: %v", err)
+	}
+	if p.ApplyCalled {
+		t.Fatal("apply shouldn't be called")
+	}
+	if rs := state.RootModule().Resources["aws_instance.foo"]; rs != nil {
+		t.Fatalf("bad: %s", state)
+	}
+
+	// A hook that fails after creating keeps the resource
+	state, err = apply("apply-lifecycle-hooks-after-fail")
+	if err == nil || !strings.Contains(err.Error(), "lifecycle after_create: exit status 1") {
+		t.Fatalf("bad: %v", err)
+	}
+	rs := state.RootModule().Resources["aws_instance.foo"]
+	if rs == nil || rs.Primary.ID != "foo" || rs.Primary.Tainted {
+		t.Fatalf("bad: %s", state)
+	}
+}
+
+func TestContext2Apply_waitForReady(t *testing.T) {
+	m := testModule(t, "apply-wait-for-ready")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+
+	var waited []string
+	var lock sync.Mutex
+	p.WaitForReadyFn = func(info *InstanceInfo, s *InstanceState) (*InstanceState, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		waited = append(waited, info.Id)
+
+		s = s.DeepCopy()
+		s.Attributes["ready"] = "true"
+		return s, nil
 	}
 
 	ctx := testContext2(t, &ContextOpts{
@@ -4211,9 +5559,6 @@ func TestContext2Apply_provisionerCreateFailNoId(t *testing.T) {
 				"aws": testProviderFuncFixed(p),
 			},
 		),
-		Provisioners: map[string]ResourceProvisionerFactory{
-			"shell": testProvisionerFuncFixed(pr),
-		},
 	})
 
 	if _, err := ctx.Plan(); err != nil {
@@ -4221,27 +5566,50 @@ func TestContext2Apply_provisionerCreateFailNoId(t *testing.T) {
 	}
 
 	state, err := ctx.Apply()
-	if err == nil {
-		t.Fatal("should error")
+	if err != nil {
+		t.Fatalf("err: %s", err)
 	}
 
-	actual := strings.TrimSpace(state.String())
-	expected := strings.TrimSpace(testTerraformApplyProvisionerFailCreateNoIdStr)
-	if actual != expected {
-		t.Fatalf("bad: \n%s", actual)
+	if !reflect.DeepEqual(waited, []string{"aws_instance.web"}) {
+		t.Fatalf("bad: %#v", waited)
+	}
+
+	resources := state.RootModule().Resources
+	if v := resources["aws_instance.web"].Primary.Attributes["ready"]; v != "true" {
+		t.Fatalf("state not updated: %s", state)
+	}
+	if _, ok := resources["aws_instance.bar"].Primary.Attributes["ready"]; ok {
+		t.Fatalf("bar should not be waited for: %s", state)
+	}
+
+	// Resources are only waited for when they're created
+	waited = nil
+	ctx = testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: state,
+	})
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := ctx.Apply(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(waited) != 0 {
+		t.Fatalf("bad: %#v", waited)
 	}
 }
 
-func TestContext2Apply_provisionerFail(t *testing.T) {
-	m := testModule(t, "apply-provisioner-fail")
+func TestContext2Apply_waitForReadyFail(t *testing.T) {
+	m := testModule(t, "apply-wait-for-ready")
 	p := testProvider("aws")
-	pr := testProvisioner()
 	p.ApplyFn = testApplyFn
 	p.DiffFn = testDiffFn
-
-	pr.ApplyFn = func(*InstanceState, *ResourceConfig) error {
-		return fmt.Errorf("EXPLOSION")
-	}
+	p.WaitForReadyReturnError = fmt.Errorf("status checks failed")
 
 	ctx := testContext2(t, &ContextOpts{
 		Module: m,
@@ -4250,12 +5618,6 @@ func TestContext2Apply_provisionerFail(t *testing.T) {
 				"aws": testProviderFuncFixed(p),
 			},
 		),
-		Provisioners: map[string]ResourceProvisionerFactory{
-			"shell": testProvisionerFuncFixed(pr),
-		},
-		Variables: map[string]interface{}{
-			"value": "1",
-		},
 	})
 
 	if _, err := ctx.Plan(); err != nil {
@@ -4266,11 +5628,16 @@ func TestContext2Apply_provisionerFail(t *testing.T) {
 	if err == nil {
 		t.Fatal("should error")
 	}
+	if !strings.Contains(err.Error(), "status checks failed") {
+		t.Fatalf("bad: %s", err)
+	}
 
-	actual := strings.TrimSpace(state.String())
-	expected := strings.TrimSpace(testTerraformApplyProvisionerFailStr)
-	if actual != expected {
-		t.Fatalf("bad: \n%s", actual)
+	resources := state.RootModule().Resources
+	if !resources["aws_instance.web"].Primary.Tainted {
+		t.Fatalf("web should be tainted: %s", state)
+	}
+	if resources["aws_instance.bar"].Primary.Tainted {
+		t.Fatalf("bar should not be tainted: %s", state)
 	}
 }
 
@@ -7312,6 +8679,68 @@ aws_instance.foo:
 	`)
 }
 
+func TestContext2Apply_targetedRecorded(t *testing.T) {
+	m := testModule(t, "apply-targeted")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Targets: []string{"aws_instance.foo"},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ta := state.TargetedApply
+	if ta == nil {
+		t.Fatal("targeted apply not recorded")
+	}
+	if !reflect.DeepEqual(ta.Targets, []string{"aws_instance.foo"}) {
+		t.Fatalf("wrong targets: %#v", ta.Targets)
+	}
+
+	// The next full plan reports the resource that was skipped
+	ctx = testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: state,
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	skipped := plan.State.TargetedApply.Skipped(plan.Diff)
+	if !reflect.DeepEqual(skipped, []string{"aws_instance.bar"}) {
+		t.Fatalf("wrong skipped resources: %#v", skipped)
+	}
+
+	// A full apply clears the record
+	state, err = ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if state.TargetedApply != nil {
+		t.Fatalf("targeted apply not cleared: %#v", state.TargetedApply)
+	}
+}
+
 func TestContext2Apply_targetedCount(t *testing.T) {
 	m := testModule(t, "apply-targeted-count")
 	p := testProvider("aws")
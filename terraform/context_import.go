@@ -1,6 +1,8 @@
 package terraform
 
 import (
+	"time"
+
 	"github.com/hashicorp/terraform/config/module"
 )
 
@@ -66,10 +68,17 @@ func (c *Context) Import(opts *ImportOpts) (*State, error) {
 	}
 
 	// Walk it
+	prior := c.state.DeepCopy()
 	if _, err := c.walk(graph, nil, walkImport); err != nil {
 		return c.state, err
 	}
 
+	runID, err := c.runID()
+	if err != nil {
+		return c.state, err
+	}
+	recordImportLifecycles(prior, c.state, runID, time.Now().UTC())
+
 	// Clean the state
 	c.state.prune()
 
@@ -333,6 +333,69 @@ func TestContextImport_providerVarConfig(t *testing.T) {
 	}
 }
 
+// Test that the provider of a child module can be configured with the
+// variables of the module, even if other variables of the module refer to
+// resources.
+func TestContextImport_providerVarConfigModule(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "import-provider-vars-module")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Variables: map[string]interface{}{
+			"foo": "bar",
+		},
+	})
+
+	configured := false
+	p.ConfigureFn = func(c *ResourceConfig) error {
+		// The root provider has no configuration
+		v, ok := c.Get("foo")
+		if !ok {
+			return nil
+		}
+		if v.(string) != "bar" {
+			return fmt.Errorf("bad value: %#v", v)
+		}
+
+		configured = true
+		return nil
+	}
+
+	p.ImportStateReturn = []*InstanceState{
+		&InstanceState{
+			ID:        "foo",
+			Ephemeral: EphemeralState{Type: "aws_instance"},
+		},
+	}
+
+	state, err := ctx.Import(&ImportOpts{
+		Targets: []*ImportTarget{
+			&ImportTarget{
+				Addr: "module.foo.aws_instance.foo",
+				ID:   "bar",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !configured {
+		t.Fatal("didn't configure provider")
+	}
+
+	actual := strings.TrimSpace(state.String())
+	expected := strings.TrimSpace(testImportModuleStr)
+	if actual != expected {
+		t.Fatalf("bad: \n%s", actual)
+	}
+}
+
 // Test that provider configs can't reference resources.
 func TestContextImport_providerNonVarConfig(t *testing.T) {
 	p := testProvider("aws")
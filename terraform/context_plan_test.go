@@ -338,6 +338,99 @@ func TestContext2Plan_moduleInput(t *testing.T) {
 	}
 }
 
+func TestContext2Plan_ephemeralVariable(t *testing.T) {
+	m := testModule(t, "plan-var-ephemeral")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	providers := ResourceProviderResolverFixed(
+		map[string]ResourceProviderFactory{
+			"aws": testProviderFuncFixed(p),
+		},
+	)
+	ctx := testContext2(t, &ContextOpts{
+		Module:           m,
+		ProviderResolver: providers,
+		Variables: map[string]interface{}{
+			"password": "secret",
+		},
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The value of the ephemeral variable isn't stored in the plan
+	var buf bytes.Buffer
+	if err := WritePlan(plan, &buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Fatal("plan shouldn't contain the value of an ephemeral variable")
+	}
+	if _, ok := plan.Vars["password"]; ok {
+		t.Fatalf("bad: %#v", plan.Vars)
+	}
+	if plan.Vars["region"] != "us-east-1" {
+		t.Fatalf("bad: %#v", plan.Vars)
+	}
+
+	// It's given again when the plan is applied
+	plan, err = ReadPlan(&buf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ctx, err = plan.Context(&ContextOpts{
+		ProviderResolver: providers,
+		Variables: map[string]interface{}{
+			"password": "secret",
+			"region":   "ignored",
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := ctx.Apply(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if v, _ := p.ConfigureConfig.Get("password"); v != "secret" {
+		t.Fatalf("bad: %#v", p.ConfigureConfig)
+	}
+	if v := ctx.Variables()["region"]; v != "us-east-1" {
+		t.Fatalf("the variables of the plan should be used: %#v", v)
+	}
+}
+
+func TestContext2Plan_globals(t *testing.T) {
+	m := testModule(t, "plan-globals")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Variables: map[string]interface{}{
+			"region": "eu-west-1",
+		},
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(plan.String())
+	expected := strings.TrimSpace(testTerraformPlanGlobalsStr)
+	if actual != expected {
+		t.Fatalf("bad:\n%s", actual)
+	}
+}
+
 func TestContext2Plan_moduleInputComputed(t *testing.T) {
 	m := testModule(t, "plan-module-input-computed")
 	p := testProvider("aws")
@@ -1537,6 +1630,96 @@ func TestContext2Plan_count(t *testing.T) {
 	}
 }
 
+func TestContext2Plan_forEach(t *testing.T) {
+	m := testModule(t, "plan-for-each")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(plan.String())
+	expected := strings.TrimSpace(testTerraformPlanForEachStr)
+	if actual != expected {
+		t.Fatalf("bad:\n%s", actual)
+	}
+}
+
+func TestContext2Plan_forEachKeyRemoved(t *testing.T) {
+	m := testModule(t, "plan-for-each")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	s := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					`aws_instance.foo["a"]`: &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "a",
+							Attributes: map[string]string{
+								"foo":  "a-alpha",
+								"type": "aws_instance",
+							},
+						},
+					},
+					`aws_instance.foo["b"]`: &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "b",
+							Attributes: map[string]string{
+								"foo":  "b-beta",
+								"type": "aws_instance",
+							},
+						},
+					},
+					`aws_instance.foo["c"]`: &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "c",
+							Attributes: map[string]string{
+								"foo":  "c-gamma",
+								"type": "aws_instance",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: s,
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(plan.String())
+	expected := strings.TrimSpace(testTerraformPlanForEachKeyRemovedStr)
+	if actual != expected {
+		t.Fatalf("bad:\n%s", actual)
+	}
+}
+
 func TestContext2Plan_countComputed(t *testing.T) {
 	m := testModule(t, "plan-count-computed")
 	p := testProvider("aws")
@@ -2584,6 +2767,211 @@ func TestContext2Plan_taint(t *testing.T) {
 	}
 }
 
+func TestContext2Plan_forceReplace(t *testing.T) {
+	m := testModule(t, "plan-taint")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	s := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.foo": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID:         "bar",
+							Attributes: map[string]string{"num": "2"},
+						},
+					},
+					"aws_instance.bar": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "baz",
+							Attributes: map[string]string{
+								"foo":  "2",
+								"type": "aws_instance",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State:        s,
+		ForceReplace: []string{"aws_instance.bar"},
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(plan.String())
+	expected := strings.TrimSpace(testTerraformPlanForceReplaceStr)
+	if actual != expected {
+		t.Fatalf("bad:\n%s", actual)
+	}
+}
+
+func TestContext2Plan_forceReplaceInvalid(t *testing.T) {
+	m := testModule(t, "plan-taint")
+	p := testProvider("aws")
+	_, err := NewContext(&ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		ForceReplace: []string{"not an address"},
+	})
+	if err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestContext2Plan_forceReplaceCount(t *testing.T) {
+	m := testModule(t, "plan-count-index")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	s := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.foo.0": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID:         "bar",
+							Attributes: map[string]string{"foo": "0"},
+						},
+					},
+					"aws_instance.foo.1": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID:         "baz",
+							Attributes: map[string]string{"foo": "1"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		Replace []string
+		Err     string
+		Tainted []string
+	}{
+		"one instance": {
+			[]string{"aws_instance.foo[1]"},
+			"",
+			[]string{"aws_instance.foo.1"},
+		},
+		"no index": {
+			[]string{"aws_instance.foo"},
+			"aws_instance.foo[0], aws_instance.foo[1]",
+			nil,
+		},
+		"no instance": {
+			[]string{"aws_instance.foo[2]"},
+			"doesn't match any resource",
+			nil,
+		},
+		"no resource": {
+			[]string{"aws_instance.nope"},
+			"doesn't match any resource",
+			nil,
+		},
+	}
+
+	for name, tc := range cases {
+		ctx := testContext2(t, &ContextOpts{
+			Module: m,
+			ProviderResolver: ResourceProviderResolverFixed(
+				map[string]ResourceProviderFactory{
+					"aws": testProviderFuncFixed(p),
+				},
+			),
+			State:        s,
+			ForceReplace: tc.Replace,
+		})
+
+		plan, err := ctx.Plan()
+		if tc.Err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.Err) {
+				t.Fatalf("%s: expected error containing %q, got: %v", name, tc.Err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+
+		var tainted []string
+		for k, rd := range plan.Diff.RootModule().Resources {
+			if rd.GetDestroyTainted() {
+				tainted = append(tainted, k)
+			}
+		}
+		sort.Strings(tainted)
+		if !reflect.DeepEqual(tainted, tc.Tainted) {
+			t.Fatalf("%s: bad: %#v", name, tainted)
+		}
+	}
+}
+
+func TestContext2Plan_forceReplaceCountOne(t *testing.T) {
+	m := testModule(t, "plan-taint")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	s := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.foo": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID:         "bar",
+							Attributes: map[string]string{"num": "2"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// Index 0 names the only instance of a resource without count.
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State:        s,
+		ForceReplace: []string{"aws_instance.foo[0]"},
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	rd := plan.Diff.RootModule().Resources["aws_instance.foo"]
+	if rd == nil || !rd.GetDestroyTainted() {
+		t.Fatalf("bad: %#v", rd)
+	}
+}
+
 func TestContext2Apply_taintIgnoreChanges(t *testing.T) {
 	m := testModule(t, "plan-taint-ignore-changes")
 	p := testProvider("aws")
@@ -3581,3 +3969,155 @@ aws_instance.foo.1:
 		t.Fatalf("bad:\n%s\n\nexpected\n\n%s", actual, expected)
 	}
 }
+
+func TestContext2Plan_adoptExisting(t *testing.T) {
+	m := testModule(t, "plan-adopt-existing")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	p.LookupExistingReturn = &InstanceState{
+		ID:         "i-abc123",
+		Attributes: map[string]string{"num": "1"},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !p.LookupExistingCalled {
+		t.Fatal("lookup existing should be called")
+	}
+	if v, ok := p.LookupExistingConfig.Get("num"); !ok || v != "2" {
+		t.Fatalf("bad: %#v", p.LookupExistingConfig)
+	}
+
+	// The existing object is adopted and updated rather than created, but
+	// isn't in the state until it's applied.
+	if plan.State.RootModule().Resources["aws_instance.foo"] != nil {
+		t.Fatalf("bad: %s", plan.State)
+	}
+
+	diff := plan.Diff.RootModule().Resources["aws_instance.foo"]
+	if diff == nil || diff.ChangeType() != DiffUpdate || diff.AdoptID != "i-abc123" {
+		t.Fatalf("bad: %#v", diff)
+	}
+	if attr := diff.Attributes["num"]; attr == nil || attr.Old != "1" || attr.New != "2" {
+		t.Fatalf("bad: %#v", attr)
+	}
+}
+
+func TestContext2Plan_adoptExistingNotFound(t *testing.T) {
+	m := testModule(t, "plan-adopt-existing")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !p.LookupExistingCalled {
+		t.Fatal("lookup existing should be called")
+	}
+
+	diff := plan.Diff.RootModule().Resources["aws_instance.foo"]
+	if diff == nil || diff.ChangeType() != DiffCreate {
+		t.Fatalf("bad: %#v", diff)
+	}
+}
+
+func TestContext2Plan_adoptExistingInState(t *testing.T) {
+	m := testModule(t, "plan-adopt-existing")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: &State{
+			Modules: []*ModuleState{
+				&ModuleState{
+					Path: rootModulePath,
+					Resources: map[string]*ResourceState{
+						"aws_instance.foo": &ResourceState{
+							Type: "aws_instance",
+							Primary: &InstanceState{
+								ID: "bar",
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if p.LookupExistingCalled {
+		t.Fatal("lookup existing should not be called")
+	}
+}
+
+func TestContext2Plan_adoptExistingReplace(t *testing.T) {
+	m := testModule(t, "plan-adopt-existing-replace")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	p.LookupExistingReturn = &InstanceState{
+		ID:         "i-abc123",
+		Attributes: map[string]string{"require_new": "no"},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	_, err := ctx.Plan()
+	if err == nil || !strings.Contains(err.Error(), "would have to be replaced") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestContext2Plan_adoptExistingUnsupported(t *testing.T) {
+	m := testModule(t, "plan-adopt-existing")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	p.LookupExistingReturnError = ErrLookupExistingUnsupported
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	_, err := ctx.Plan()
+	if err == nil || !strings.Contains(err.Error(), `can't look up existing resources of type "aws_instance"`) {
+		t.Fatalf("bad: %v", err)
+	}
+}
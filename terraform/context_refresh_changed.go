@@ -0,0 +1,152 @@
+package terraform
+
+import (
+	"sort"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform/config"
+)
+
+// RefreshChanged is like Refresh, but only refreshes the resources that the
+// changes to the configuration since the state was written can affect: the
+// resources whose configuration doesn't match their state, those that depend
+// on them, and data sources, along with the resources they depend on, as
+// with targeting.
+//
+// The resources are found by planning without refreshing first. Planning
+// only compares the configuration to the state, so it's much faster than
+// refreshing every resource of a large configuration that's mostly
+// unchanged. The cost is that changes made outside of Terraform to the
+// resources that aren't refreshed aren't seen.
+func (c *Context) RefreshChanged() (*State, error) {
+	// Destroying changes every resource anyway.
+	if c.destroy {
+		return c.Refresh()
+	}
+
+	targets, err := c.changedTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(targets) == 0 {
+		refreshLog.Info("no resources affected by changes, not refreshing")
+		return c.state, nil
+	}
+	refreshLog.Info("refreshing resources affected by changes: %v", targets)
+
+	old := c.targets
+	c.targets = targets
+	defer func() {
+		c.targets = old
+	}()
+
+	return c.Refresh()
+}
+
+// changedTargets plans without refreshing, and returns the addresses of the
+// resources to refresh for RefreshChanged.
+func (c *Context) changedTargets() ([]string, error) {
+	defer c.acquireRun("plan")()
+
+	// Plan against a copy of the state, and without the hooks of the
+	// context, since this plan is never shown. The stop hook is kept so
+	// that it can still be interrupted.
+	oldState, oldHooks := c.state, c.hooks
+	if oldState == nil {
+		c.state = &State{}
+		c.state.init()
+	} else {
+		c.state = oldState.DeepCopy()
+	}
+	c.hooks = []Hook{c.sh}
+	defer func() {
+		c.state, c.hooks = oldState, oldHooks
+	}()
+
+	c.diffLock.Lock()
+	c.diff = new(Diff)
+	c.diff.init()
+	c.diffLock.Unlock()
+
+	graph, err := c.Graph(GraphTypePlan, nil)
+	if err != nil {
+		return nil, err
+	}
+	walker, err := c.walk(graph, graph, walkPlan)
+	if err != nil {
+		return nil, err
+	}
+	if len(walker.ValidationErrors) > 0 {
+		return nil, multierror.Append(nil, walker.ValidationErrors...)
+	}
+
+	// The resources with changes are refreshed, even if they're no longer
+	// in the configuration.
+	targets := make(map[string]bool)
+	for _, m := range c.diff.Modules {
+		for k, d := range m.Resources {
+			if d.Empty() {
+				continue
+			}
+
+			key, err := ParseResourceStateKey(k)
+			if err != nil {
+				return nil, err
+			}
+
+			targets[refreshTarget(normalizeModulePath(m.Path)[1:], key.Mode, key.Type, key.Name)] = true
+		}
+	}
+
+	// So are the resources that depend on them, and data sources, which
+	// read from outside of Terraform.
+	for _, v := range graph.Vertices() {
+		rn, ok := v.(GraphNodeResource)
+		if !ok {
+			continue
+		}
+
+		addr := rn.ResourceAddr()
+		target := refreshTarget(addr.Path, addr.Mode, addr.Type, addr.Name)
+		if addr.Mode == config.DataResourceMode {
+			targets[target] = true
+		}
+		if !targets[target] || addr.Mode == config.DataResourceMode {
+			continue
+		}
+
+		deps, err := graph.Descendents(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps.List() {
+			if dn, ok := dep.(GraphNodeResource); ok {
+				addr := dn.ResourceAddr()
+				targets[refreshTarget(addr.Path, addr.Mode, addr.Type, addr.Name)] = true
+			}
+		}
+	}
+
+	result := make([]string, 0, len(targets))
+	for target := range targets {
+		result = append(result, target)
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// refreshTarget returns the address that targets every instance of a
+// resource.
+func refreshTarget(path []string, mode config.ResourceMode, typ, name string) string {
+	addr := &ResourceAddress{
+		Path:  path,
+		Index: -1,
+		Mode:  mode,
+		Type:  typ,
+		Name:  name,
+	}
+
+	return addr.String()
+}
@@ -1,6 +1,7 @@
 package terraform
 
 import (
+	"fmt"
 	"reflect"
 	"sort"
 	"strings"
@@ -90,6 +91,137 @@ module.child:
   <no state>`)
 }
 
+func TestContext2Refresh_batch(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "refresh-batch")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: testRefreshBatchState(),
+	})
+
+	var lock sync.Mutex
+	var refreshed []string
+	p.RefreshBatchFn = func(
+		info []*InstanceInfo, states []*InstanceState) ([]*InstanceState, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		result := make([]*InstanceState, len(states))
+		for i, s := range states {
+			refreshed = append(refreshed, info[i].Id)
+
+			s = s.DeepCopy()
+			s.Attributes = map[string]string{"batched": "true"}
+			result[i] = s
+		}
+
+		return result, nil
+	}
+
+	s, err := ctx.Refresh()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.RefreshCalled {
+		t.Fatal("refresh should not be called")
+	}
+
+	sort.Strings(refreshed)
+	expected := []string{"aws_instance.foo.0", "aws_instance.foo.1", "aws_instance.foo.2"}
+	if !reflect.DeepEqual(refreshed, expected) {
+		t.Fatalf("bad: %#v", refreshed)
+	}
+
+	for k, r := range s.RootModule().Resources {
+		if r.Primary.Attributes["batched"] != "true" {
+			t.Fatalf("%s: bad: %#v", k, r.Primary)
+		}
+	}
+}
+
+func TestContext2Refresh_batchError(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "refresh-batch")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: testRefreshBatchState(),
+	})
+
+	p.RefreshBatchReturnError = fmt.Errorf("batch failed")
+
+	if _, err := ctx.Refresh(); err == nil || !strings.Contains(err.Error(), "batch failed") {
+		t.Fatalf("bad: %v", err)
+	}
+	if p.RefreshCalled {
+		t.Fatal("refresh should not be called")
+	}
+}
+
+func TestContext2Refresh_batchUnsupported(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "refresh-batch")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: testRefreshBatchState(),
+	})
+
+	p.RefreshFn = nil
+	p.RefreshReturn = &InstanceState{
+		ID:         "foo",
+		Attributes: map[string]string{"single": "true"},
+	}
+
+	s, err := ctx.Refresh()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !p.RefreshBatchCalled {
+		t.Fatal("refresh batch should be called")
+	}
+
+	for k, r := range s.RootModule().Resources {
+		if r.Primary.Attributes["single"] != "true" {
+			t.Fatalf("%s: bad: %#v", k, r.Primary)
+		}
+	}
+}
+
+func testRefreshBatchState() *State {
+	resources := make(map[string]*ResourceState)
+	for i := 0; i < 3; i++ {
+		resources[fmt.Sprintf("aws_instance.foo.%d", i)] = &ResourceState{
+			Type: "aws_instance",
+			Primary: &InstanceState{
+				ID: fmt.Sprintf("foo%d", i),
+			},
+		}
+	}
+
+	return &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path:      rootModulePath,
+				Resources: resources,
+			},
+		},
+	}
+}
+
 func TestContext2Refresh_targeted(t *testing.T) {
 	p := testProvider("aws")
 	m := testModule(t, "refresh-targeted")
@@ -1049,3 +1181,103 @@ func TestContext2Validate(t *testing.T) {
 		t.Fatalf("bad: %s", e)
 	}
 }
+
+// refreshChangedState returns the state of the resources in the
+// refresh-changed fixture, with the given foo for aws_vpc.changed.
+func refreshChangedState(foo string) *State {
+	state := func(typ, id string, attrs map[string]string) *ResourceState {
+		attrs["id"] = id
+		return &ResourceState{
+			Type:    typ,
+			Primary: &InstanceState{ID: id, Attributes: attrs},
+		}
+	}
+
+	return &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_vpc.changed":        state("aws_vpc", "vpc-abc123", map[string]string{"foo": foo}),
+					"aws_instance.dependent": state("aws_instance", "i-abc123", map[string]string{"foo": "vpc-abc123"}),
+					"aws_instance.unchanged": state("aws_instance", "i-bcd345", map[string]string{"foo": "bar"}),
+					"aws_elb.other":          state("aws_elb", "lb-abc123", map[string]string{"foo": "i-bcd345"}),
+				},
+			},
+		},
+	}
+}
+
+func TestContext2Refresh_changed(t *testing.T) {
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	m := testModule(t, "refresh-changed")
+
+	state := refreshChangedState("old")
+	state.RootModule().Resources["aws_instance.notanymore"] = resourceState("aws_instance", "i-cde456")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: state,
+	})
+
+	var lock sync.Mutex
+	var refreshed []string
+	p.RefreshFn = func(i *InstanceInfo, is *InstanceState) (*InstanceState, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		refreshed = append(refreshed, i.Id)
+		return is, nil
+	}
+
+	if _, err := ctx.RefreshChanged(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	sort.Strings(refreshed)
+	expected := []string{"aws_instance.dependent", "aws_instance.notanymore", "aws_vpc.changed"}
+	if !reflect.DeepEqual(refreshed, expected) {
+		t.Fatalf("expected: %#v, got: %#v", expected, refreshed)
+	}
+
+	// The plan after refreshing still sees the changes
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d := plan.Diff.RootModule().Resources["aws_vpc.changed"]; d.Empty() {
+		t.Fatalf("bad: %s", plan.Diff)
+	}
+	if d := plan.Diff.RootModule().Resources["aws_instance.notanymore"]; d == nil || !d.Destroy {
+		t.Fatalf("bad: %s", plan.Diff)
+	}
+}
+
+func TestContext2Refresh_changedNone(t *testing.T) {
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	m := testModule(t, "refresh-changed")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State: refreshChangedState("new"),
+	})
+
+	p.RefreshFn = func(i *InstanceInfo, is *InstanceState) (*InstanceState, error) {
+		t.Errorf("%s shouldn't be refreshed", i.Id)
+		return is, nil
+	}
+
+	// The state matches the configuration, so nothing is refreshed
+	if _, err := ctx.RefreshChanged(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -0,0 +1,109 @@
+package terraform
+
+import (
+	"sort"
+)
+
+// TargetReport describes which resources an operation limited by targets
+// considers, so that what it leaves out can be reviewed.
+type TargetReport struct {
+	// Targets are the target addresses of the operation.
+	Targets []string `json:"targets"`
+
+	// Targeted are the resources that the targets address.
+	Targeted []string `json:"targeted"`
+
+	// Dependencies are the resources that are only included because the
+	// targeted resources depend on them, or, when destroying, because they
+	// depend on the targeted resources.
+	Dependencies []string `json:"dependencies"`
+
+	// Excluded are the resources that aren't considered at all. Changes to
+	// them aren't planned or applied.
+	Excluded []string `json:"excluded"`
+}
+
+// TargetReport returns the report of the resources that the targets of the
+// context include and exclude, or nil if the context has no targets.
+//
+// The resources are those of the configuration and the state, with the
+// addresses used for targeting, sorted.
+func (c *Context) TargetReport() (*TargetReport, error) {
+	if len(c.targets) == 0 {
+		return nil, nil
+	}
+
+	// The plan graphs, destroying or not, are targeted the same way: the
+	// destroy plan graph has its edges in the order of destruction.
+	t := &TargetsTransformer{Targets: c.targets}
+	addrs, err := t.parseTargetAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the graph without the targets, to see what they leave out
+	typ := GraphTypePlan
+	if c.destroy {
+		typ = GraphTypePlanDestroy
+	}
+	targets := c.targets
+	c.targets = nil
+	graph, err := c.Graph(typ, &ContextGraphOpts{Validate: false})
+	c.targets = targets
+	if err != nil {
+		return nil, err
+	}
+
+	included, err := t.selectTargetedNodes(graph, addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	targeted := make(map[string]bool)
+	dependencies := make(map[string]bool)
+	excluded := make(map[string]bool)
+	for _, v := range graph.Vertices() {
+		rn, ok := v.(GraphNodeResource)
+		if !ok {
+			continue
+		}
+
+		addr := rn.ResourceAddr().String()
+		switch {
+		case t.nodeIsTarget(v, addrs):
+			targeted[addr] = true
+		case included.Include(v):
+			dependencies[addr] = true
+		default:
+			excluded[addr] = true
+		}
+	}
+
+	// A resource can have more than one node, such as when it's both in
+	// the configuration and orphaned in the state. It's reported as the
+	// most included of them.
+	for addr := range targeted {
+		delete(dependencies, addr)
+		delete(excluded, addr)
+	}
+	for addr := range dependencies {
+		delete(excluded, addr)
+	}
+
+	return &TargetReport{
+		Targets:      append([]string(nil), c.targets...),
+		Targeted:     targetReportList(targeted),
+		Dependencies: targetReportList(dependencies),
+		Excluded:     targetReportList(excluded),
+	}, nil
+}
+
+func targetReportList(m map[string]bool) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,137 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContext2TargetReport(t *testing.T) {
+	m := testModule(t, "target-report")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	state := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.old": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{
+							ID: "old",
+						},
+					},
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State:   state,
+		Targets: []string{"aws_instance.web"},
+	})
+
+	actual, err := ctx.TargetReport()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &TargetReport{
+		Targets:      []string{"aws_instance.web"},
+		Targeted:     []string{"aws_instance.web"},
+		Dependencies: []string{"aws_instance.sg"},
+		Excluded:     []string{"aws_instance.db", "aws_instance.old"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The report doesn't change what's planned
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(plan.Diff.RootModule().Resources) != 2 {
+		t.Fatalf("bad: %s", plan)
+	}
+}
+
+func TestContext2TargetReport_destroy(t *testing.T) {
+	m := testModule(t, "target-report")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	state := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.sg": &ResourceState{
+						Type:    "aws_instance",
+						Primary: &InstanceState{ID: "sg"},
+					},
+					"aws_instance.web": &ResourceState{
+						Type:         "aws_instance",
+						Primary:      &InstanceState{ID: "web"},
+						Dependencies: []string{"aws_instance.sg"},
+					},
+					"aws_instance.db": &ResourceState{
+						Type:    "aws_instance",
+						Primary: &InstanceState{ID: "db"},
+					},
+				},
+			},
+		},
+	}
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		State:   state,
+		Targets: []string{"aws_instance.sg"},
+		Destroy: true,
+	})
+
+	actual, err := ctx.TargetReport()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Destroying the target destroys what depends on it first
+	expected := &TargetReport{
+		Targets:      []string{"aws_instance.sg"},
+		Targeted:     []string{"aws_instance.sg"},
+		Dependencies: []string{"aws_instance.web"},
+		Excluded:     []string{"aws_instance.db"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestContext2TargetReport_none(t *testing.T) {
+	m := testModule(t, "target-report")
+	p := testProvider("aws")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	actual, err := ctx.TargetReport()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual != nil {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
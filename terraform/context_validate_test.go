@@ -2,8 +2,12 @@ package terraform
 
 import (
 	"fmt"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/hashicorp/terraform/helper/warnings"
 )
 
 func TestContext2Validate_badCount(t *testing.T) {
@@ -27,6 +31,64 @@ func TestContext2Validate_badCount(t *testing.T) {
 	}
 }
 
+func TestContext2Validate_moduleWarnings(t *testing.T) {
+	m := testModule(t, "module-warnings")
+	c := testContext2(t, &ContextOpts{
+		Module: m,
+	})
+
+	w, e := c.Validate()
+	if len(e) > 0 {
+		t.Fatalf("bad: %#v", e)
+	}
+
+	found := false
+	for _, warning := range w {
+		if strings.Contains(warning, "module.old: module is deprecated") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("deprecation warning not found in %#v", w)
+	}
+}
+
+func TestContext2Validate_strict(t *testing.T) {
+	m := testModule(t, "module-warnings")
+	c := testContext2(t, &ContextOpts{
+		Module:         m,
+		StrictWarnings: []string{warnings.CodeModuleDeprecated},
+	})
+
+	w, e := c.Validate()
+	for _, warning := range w {
+		if strings.Contains(warning, "module is deprecated") {
+			t.Fatalf("deprecation should be an error: %#v", w)
+		}
+	}
+	if len(e) != 1 || !strings.Contains(e[0].Error(), "strict mode: [module-deprecated] module.old") {
+		t.Fatalf("bad: %#v", e)
+	}
+}
+
+func TestContext2Validate_strictConfig(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "validate-strict")
+	c := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	_, e := c.Validate()
+	if len(e) != 1 || !strings.Contains(e[0].Error(), "strict mode: [provider-unconstrained] provider.aws") {
+		t.Fatalf("bad: %#v", e)
+	}
+}
+
 func TestContext2Validate_badVar(t *testing.T) {
 	p := testProvider("aws")
 	m := testModule(t, "validate-bad-var")
@@ -48,6 +110,45 @@ func TestContext2Validate_badVar(t *testing.T) {
 	}
 }
 
+func TestContext2Validate_attributeError(t *testing.T) {
+	p := testProvider("aws")
+	p.ValidateResourceReturnErrors = []error{
+		&AttributeError{
+			Path: "ingress.1.from",
+			Err:  fmt.Errorf("from must be a port number"),
+		},
+	}
+	m := testModule(t, "validate-attribute-error")
+	c := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	w, e := c.Validate()
+	if len(w) > 0 {
+		t.Fatalf("bad: %#v", w)
+	}
+	if len(e) != 1 {
+		t.Fatalf("bad: %#v", e)
+	}
+
+	// The error points to the block the attribute is set in
+	pos := filepath.Join(fixtureDir, "validate-attribute-error", "main.tf") + ":8"
+	expected := fmt.Sprintf(
+		"aws_instance.foo: from must be a port number\n"+
+			"  (attribute \"ingress.1.from\", set at %s)", pos)
+	if actual := e[0].Error(); actual != expected {
+		t.Fatalf("bad: %s", actual)
+	}
+	if ae := ErrorAttribute(e[0]); ae == nil || ae.Pos != pos {
+		t.Fatalf("bad: %#v", ae)
+	}
+}
+
 func TestContext2Validate_varMapOverrideOld(t *testing.T) {
 	m := testModule(t, "validate-module-pc-vars")
 	p := testProvider("aws")
@@ -682,6 +783,37 @@ func TestContext2Validate_resourceConfig_good(t *testing.T) {
 	}
 }
 
+func TestContext2Validate_resourceConfig_warnings(t *testing.T) {
+	m := testModule(t, "validate-bad-rc")
+	p := testProvider("aws")
+	c := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	p.ValidateResourceReturnWarns = []string{
+		`"foo": [DEPRECATED] use bar instead`,
+		"something else",
+	}
+
+	w, e := c.Validate()
+	if len(e) > 0 {
+		t.Fatalf("bad: %#v", e)
+	}
+
+	expected := []string{
+		`[deprecated-attribute] aws_instance.test: "foo": [DEPRECATED] use bar instead`,
+		"[validation] aws_instance.test: something else",
+	}
+	if !reflect.DeepEqual(w, expected) {
+		t.Fatalf("bad: %#v", w)
+	}
+}
+
 func TestContext2Validate_resourceNameSymbol(t *testing.T) {
 	p := testProvider("aws")
 	m := testModule(t, "validate-resource-name-symbol")
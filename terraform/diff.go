@@ -3,6 +3,7 @@ package terraform
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/hashicorp/terraform/helper/typedjson"
 	"github.com/mitchellh/copystructure"
 )
 
@@ -32,7 +34,7 @@ var multiVal = regexp.MustCompile(`\.(#|%)$`)
 // to an existing infrastructure.
 type Diff struct {
 	// Modules contains all the modules that have a diff
-	Modules []*ModuleDiff
+	Modules []*ModuleDiff `json:"modules"`
 }
 
 // Prune cleans out unused structures in the diff without affecting
@@ -143,6 +145,21 @@ func (d *Diff) Equal(d2 *Diff) bool {
 	return reflect.DeepEqual(dCopy, d2Copy)
 }
 
+// SortedModules returns the modules of the diff in order: by the length of
+// their paths, and then by path. This is the order that plans have their
+// modules in, so that the same changes are always saved and shown the same.
+// The diff itself isn't reordered.
+func (d *Diff) SortedModules() []*ModuleDiff {
+	if d == nil {
+		return nil
+	}
+
+	result := make([]*ModuleDiff, len(d.Modules))
+	copy(result, d.Modules)
+	sort.Sort(moduleDiffSort(result))
+	return result
+}
+
 // DeepCopy performs a deep copy of all parts of the Diff, making the
 // resulting Diff safe to use without modifying this one.
 func (d *Diff) DeepCopy() *Diff {
@@ -200,9 +217,9 @@ func (d *Diff) init() {
 // ModuleDiff tracks the differences between resources to apply within
 // a single module.
 type ModuleDiff struct {
-	Path      []string
-	Resources map[string]*InstanceDiff
-	Destroy   bool // Set only by the destroy plan
+	Path      []string                 `json:"path"`
+	Resources map[string]*InstanceDiff `json:"resources"`
+	Destroy   bool                     `json:"destroy"` // Set only by the destroy plan
 }
 
 func (d *ModuleDiff) init() {
@@ -304,6 +321,9 @@ func (d *ModuleDiff) String() string {
 		if !rdiff.GetDestroy() && rdiff.GetDestroyDeposed() {
 			extra = " (deposed only)"
 		}
+		if id := rdiff.GetAdoptID(); id != "" {
+			extra = fmt.Sprintf(" (adopt %s)", id)
+		}
 
 		buf.WriteString(fmt.Sprintf(
 			"%s: %s%s\n",
@@ -363,31 +383,95 @@ func (d *ModuleDiff) String() string {
 // InstanceDiff is the diff of a resource from some state to another.
 type InstanceDiff struct {
 	mu             sync.Mutex
-	Attributes     map[string]*ResourceAttrDiff
-	Destroy        bool
-	DestroyDeposed bool
-	DestroyTainted bool
+	Attributes     map[string]*ResourceAttrDiff `json:"attributes"`
+	Destroy        bool                         `json:"destroy"`
+	DestroyDeposed bool                         `json:"destroy_deposed"`
+	DestroyTainted bool                         `json:"destroy_tainted"`
+
+	// AdoptID is the ID of an existing object that the resource adopts,
+	// because it has adopt_existing set in its lifecycle, instead of
+	// creating a new one.
+	AdoptID string `json:"adopt_id"`
 
 	// Meta is a simple K/V map that is stored in a diff and persisted to
 	// plans but otherwise is completely ignored by Terraform core. It is
 	// mean to be used for additional data a resource may want to pass through.
 	// The value here must only contain Go primitives and collections.
-	Meta map[string]interface{}
+	Meta map[string]interface{} `json:"-"` // See MarshalJSON
 }
 
 func (d *InstanceDiff) Lock()   { d.mu.Lock() }
 func (d *InstanceDiff) Unlock() { d.mu.Unlock() }
 
+// MarshalJSON encodes the diff as JSON, keeping the types of the values
+// in Meta.
+func (d *InstanceDiff) MarshalJSON() ([]byte, error) {
+	type instanceDiff InstanceDiff
+	return json.Marshal(&struct {
+		*instanceDiff
+		Meta typedjson.Map `json:"meta"`
+	}{
+		instanceDiff: (*instanceDiff)(d),
+		Meta:         d.Meta,
+	})
+}
+
+// UnmarshalJSON decodes a diff that was encoded with MarshalJSON.
+func (d *InstanceDiff) UnmarshalJSON(data []byte) error {
+	type instanceDiff InstanceDiff
+	result := &struct {
+		*instanceDiff
+		Meta typedjson.Map `json:"meta"`
+	}{
+		instanceDiff: (*instanceDiff)(d),
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return err
+	}
+
+	d.Meta = result.Meta
+	return nil
+}
+
 // ResourceAttrDiff is the diff of a single attribute of a resource.
 type ResourceAttrDiff struct {
-	Old         string      // Old Value
-	New         string      // New Value
-	NewComputed bool        // True if new value is computed (unknown currently)
-	NewRemoved  bool        // True if this attribute is being removed
-	NewExtra    interface{} // Extra information for the provider
-	RequiresNew bool        // True if change requires new resource
-	Sensitive   bool        // True if the data should not be displayed in UI output
-	Type        DiffAttrType
+	Old         string       `json:"old"`          // Old Value
+	New         string       `json:"new"`          // New Value
+	NewComputed bool         `json:"new_computed"` // True if new value is computed (unknown currently)
+	NewRemoved  bool         `json:"new_removed"`  // True if this attribute is being removed
+	NewExtra    interface{}  `json:"-"`            // Extra information for the provider, see MarshalJSON
+	RequiresNew bool         `json:"requires_new"` // True if change requires new resource
+	Sensitive   bool         `json:"sensitive"`    // True if the data should not be displayed in UI output
+	Type        DiffAttrType `json:"type"`
+}
+
+// MarshalJSON encodes the diff as JSON, keeping the type of NewExtra.
+func (d *ResourceAttrDiff) MarshalJSON() ([]byte, error) {
+	type resourceAttrDiff ResourceAttrDiff
+	return json.Marshal(&struct {
+		*resourceAttrDiff
+		NewExtra typedjson.Value `json:"new_extra"`
+	}{
+		resourceAttrDiff: (*resourceAttrDiff)(d),
+		NewExtra:         typedjson.Value{V: d.NewExtra},
+	})
+}
+
+// UnmarshalJSON decodes a diff that was encoded with MarshalJSON.
+func (d *ResourceAttrDiff) UnmarshalJSON(data []byte) error {
+	type resourceAttrDiff ResourceAttrDiff
+	result := &struct {
+		*resourceAttrDiff
+		NewExtra typedjson.Value `json:"new_extra"`
+	}{
+		resourceAttrDiff: (*resourceAttrDiff)(d),
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return err
+	}
+
+	d.NewExtra = result.NewExtra.V
+	return nil
 }
 
 // Empty returns true if the diff for this attr is neutral
@@ -468,6 +552,7 @@ func (d *InstanceDiff) Empty() bool {
 	return !d.Destroy &&
 		!d.DestroyTainted &&
 		!d.DestroyDeposed &&
+		d.AdoptID == "" &&
 		len(d.Attributes) == 0
 }
 
@@ -502,6 +587,7 @@ func (d *InstanceDiff) GoString() string {
 		Destroy:        d.Destroy,
 		DestroyTainted: d.DestroyTainted,
 		DestroyDeposed: d.DestroyDeposed,
+		AdoptID:        d.AdoptID,
 	})
 }
 
@@ -560,6 +646,20 @@ func (d *InstanceDiff) SetTainted(b bool) {
 	d.DestroyTainted = b
 }
 
+func (d *InstanceDiff) SetAdoptID(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.AdoptID = id
+}
+
+func (d *InstanceDiff) GetAdoptID() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.AdoptID
+}
+
 func (d *InstanceDiff) GetDestroyTainted() bool {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -710,6 +810,12 @@ func (d *InstanceDiff) Same(d2 *InstanceDiff) (bool, string) {
 			"diff: Destroy; old: %t, new: %t", d.Destroy, d2.GetDestroy())
 	}
 
+	// An adopted object must be the one that was planned
+	if d.AdoptID != d2.GetAdoptID() {
+		return false, fmt.Sprintf(
+			"diff: AdoptID; old: %q, new: %q", d.AdoptID, d2.GetAdoptID())
+	}
+
 	// Go through the old diff and make sure the new diff has all the
 	// same attributes. To start, build up the check map to be all the keys.
 	checkOld := make(map[string]struct{})
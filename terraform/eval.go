@@ -1,7 +1,6 @@
 package terraform
 
 import (
-	"log"
 	"strings"
 )
 
@@ -49,13 +48,14 @@ func EvalRaw(n EvalNode, ctx EvalContext) (interface{}, error) {
 		path = strings.Join(ctx.Path(), ".")
 	}
 
-	log.Printf("[DEBUG] %s: eval: %T", path, n)
+	l := logger.Resource(path)
+	l.Debug("eval: %T", n)
 	output, err := n.Eval(ctx)
 	if err != nil {
 		if _, ok := err.(EvalEarlyExitError); ok {
-			log.Printf("[DEBUG] %s: eval: %T, err: %s", path, n, err)
+			l.Debug("eval: %T, err: %s", n, err)
 		} else {
-			log.Printf("[ERROR] %s: eval: %T, err: %s", path, n, err)
+			l.Error("eval: %T, err: %s", n, err)
 		}
 	}
 
@@ -0,0 +1,144 @@
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// EvalAdoptExisting is an EvalNode implementation that looks up an existing
+// object that matches the configuration of a resource that would otherwise
+// be created, if its lifecycle has adopt_existing set. If the provider finds
+// one, it's used as the state to diff against, so that the plan updates the
+// existing object to match the configuration instead of creating a new one
+// that conflicts with it.
+//
+// Output is set to a diff that records the adopted object, which EvalDiff
+// preserves so that it's shown in the plan and read into the state by
+// EvalReadAdopted during apply.
+type EvalAdoptExisting struct {
+	Info     *InstanceInfo
+	Resource *config.Resource
+	Provider *ResourceProvider
+	Config   **ResourceConfig
+	State    **InstanceState
+	Output   **InstanceDiff
+}
+
+func (n *EvalAdoptExisting) Eval(ctx EvalContext) (interface{}, error) {
+	if n.Resource == nil || !n.Resource.Lifecycle.AdoptExisting {
+		return nil, nil
+	}
+
+	// Only resources that would be created can adopt an existing object
+	if state := *n.State; state != nil && state.ID != "" {
+		return nil, nil
+	}
+
+	adopter, ok := (*n.Provider).(ResourceProviderAdopter)
+	if !ok {
+		return nil, n.unsupported()
+	}
+
+	state, err := adopter.LookupExisting(n.Info, *n.Config)
+	if err == ErrLookupExistingUnsupported {
+		return nil, n.unsupported()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: adopt_existing: %s", n.Info.Id, err)
+	}
+	if state == nil || state.ID == "" {
+		planLog.Resource(n.Info.Id).Debug("no existing object to adopt")
+		return nil, nil
+	}
+
+	planLog.Resource(n.Info.Id).Info("adopting existing object %q", state.ID)
+	state.init()
+	state.Attributes["id"] = state.ID
+
+	*n.State = state
+	if n.Output != nil {
+		*n.Output = &InstanceDiff{AdoptID: state.ID}
+	}
+
+	return nil, nil
+}
+
+func (n *EvalAdoptExisting) unsupported() error {
+	return fmt.Errorf(
+		"%s: adopt_existing: the provider can't look up existing resources of type %q",
+		n.Info.Id, n.Info.Type)
+}
+
+// EvalCheckAdoptedReplace is an EvalNode implementation that returns an
+// error if a diff that adopts an existing object would replace it, since
+// destroying an object that Terraform didn't create is never intended.
+type EvalCheckAdoptedReplace struct {
+	Info *InstanceInfo
+	Diff **InstanceDiff
+}
+
+func (n *EvalCheckAdoptedReplace) Eval(ctx EvalContext) (interface{}, error) {
+	diff := *n.Diff
+	if diff == nil || diff.GetAdoptID() == "" || !diff.RequiresNew() {
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf(
+		"%s: adopt_existing: the existing object would have to be replaced "+
+			"to match the configuration. Change the configuration to match "+
+			"the existing object, or import it with \"terraform import\".",
+		n.Info.Id)
+}
+
+// EvalReadAdopted is an EvalNode implementation that reads the existing
+// object that a diff adopts into the state, before the diff is applied.
+type EvalReadAdopted struct {
+	Info     *InstanceInfo
+	Provider *ResourceProvider
+	Diff     **InstanceDiff
+	State    **InstanceState
+	Output   **InstanceState
+}
+
+func (n *EvalReadAdopted) Eval(ctx EvalContext) (interface{}, error) {
+	diff := *n.Diff
+	if diff == nil || diff.GetAdoptID() == "" {
+		return nil, nil
+	}
+
+	// If the object is already in the state, it was adopted by an earlier
+	// apply that failed after reading it.
+	if state := *n.State; state != nil && state.ID != "" {
+		return nil, nil
+	}
+
+	id := diff.GetAdoptID()
+	state, err := (*n.Provider).Refresh(n.Info, &InstanceState{ID: id})
+	if err != nil {
+		return nil, fmt.Errorf("%s: adopt_existing: %s", n.Info.Id, err)
+	}
+	if state == nil || state.ID == "" {
+		return nil, fmt.Errorf(
+			"%s: adopt_existing: the existing object %q no longer exists",
+			n.Info.Id, id)
+	}
+
+	state.init()
+	state.Attributes["id"] = state.ID
+	state.Ephemeral.Type = n.Info.Type
+
+	// Adopting is importing, as far as the hooks are concerned
+	err = ctx.Hook(func(h Hook) (HookAction, error) {
+		return h.PostImportState(n.Info, []*InstanceState{state})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if n.Output != nil {
+		*n.Output = state
+	}
+
+	return nil, nil
+}
@@ -2,9 +2,9 @@ package terraform
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/terraform/config"
 )
@@ -19,6 +19,10 @@ type EvalApply struct {
 	Output    **InstanceState
 	CreateNew *bool
 	Error     *error
+
+	// Resource, if set, is used to point out where the attribute that an
+	// error is about is configured.
+	Resource *config.Resource
 }
 
 // TODO: test
@@ -29,8 +33,18 @@ func (n *EvalApply) Eval(ctx EvalContext) (interface{}, error) {
 
 	// If we have no diff, we have nothing to do!
 	if diff.Empty() {
-		log.Printf(
-			"[DEBUG] apply: %s: diff is empty, doing nothing.", n.Info.Id)
+		applyLog.Resource(n.Info.Id).Debug("diff is empty, doing nothing.")
+		return nil, nil
+	}
+
+	// An adopted object that already matches the configuration only has
+	// to be written to the state.
+	if diff.GetAdoptID() != "" && diff.GetAttributesLen() == 0 {
+		applyLog.Resource(n.Info.Id).Debug("adopted object needs no changes")
+		if n.Output != nil {
+			*n.Output = state
+		}
+
 		return nil, nil
 	}
 
@@ -48,13 +62,24 @@ func (n *EvalApply) Eval(ctx EvalContext) (interface{}, error) {
 	state.init()
 
 	// Flag if we're creating a new instance
+	createNew := state.ID == "" && !diff.GetDestroy() || diff.RequiresNew()
 	if n.CreateNew != nil {
-		*n.CreateNew = state.ID == "" && !diff.GetDestroy() || diff.RequiresNew()
+		*n.CreateNew = createNew
 	}
 
-	// With the completed diff, apply!
-	log.Printf("[DEBUG] apply: %s: executing Apply", n.Info.Id)
-	state, err := provider.Apply(n.Info, state, diff)
+	// With the completed diff, apply! A transient error is only retried if
+	// the provider didn't create a new object before failing, since the
+	// retry would apply the same diff again.
+	applyLog.Resource(n.Info.Id).Debug("executing Apply")
+	prior := state
+	err := ctx.RetryTransient(n.Info.Id, func() error {
+		var err error
+		state, err = provider.Apply(n.Info, prior, diff)
+		if IsTransientError(err) && state != nil && state.ID != prior.ID {
+			return fmt.Errorf("%s", err)
+		}
+		return err
+	})
 	if state == nil {
 		state = new(InstanceState)
 	}
@@ -65,6 +90,12 @@ func (n *EvalApply) Eval(ctx EvalContext) (interface{}, error) {
 		state.Attributes["id"] = state.ID
 	}
 
+	// An instance that is updated in place keeps the outputs of the
+	// provisioners that ran when it was created
+	if !createNew && state.ID != "" {
+		state.keepProvisionerOutputs(prior)
+	}
+
 	// If the value is the unknown variable value, then it is an error.
 	// In this case we record the error and remove it from the state
 	for ak, av := range state.Attributes {
@@ -83,8 +114,10 @@ func (n *EvalApply) Eval(ctx EvalContext) (interface{}, error) {
 	// If there are no errors, then we append it to our output error
 	// if we have one, otherwise we just output it.
 	if err != nil {
+		locateAttributeErrors(n.Resource, err)
+
 		if n.Error != nil {
-			helpfulErr := fmt.Errorf("%s: %s", n.Info.Id, err.Error())
+			helpfulErr := errwrap.Wrapf(n.Info.Id+": {{err}}", err)
 			*n.Error = multierror.Append(*n.Error, helpfulErr)
 		} else {
 			return nil, err
@@ -339,9 +372,9 @@ func (n *EvalApplyProvisioners) apply(ctx EvalContext, provs []*config.Provision
 			// Determine failure behavior
 			switch prov.OnFailure {
 			case config.ProvisionerOnFailureContinue:
-				log.Printf(
-					"[INFO] apply: %s [%s]: error during provision, continue requested",
-					n.Info.Id, prov.Type)
+				applyLog.Resource(n.Info.Id).Info(
+					"provisioner %s: error during provision, continue requested",
+					prov.Type)
 
 			case config.ProvisionerOnFailureFail:
 				return applyErr
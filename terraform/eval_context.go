@@ -35,6 +35,16 @@ type EvalContext interface {
 	// CloseProvider closes provider connections that aren't needed anymore.
 	CloseProvider(string) error
 
+	// RefreshBatch refreshes a resource with the given provider, which must
+	// implement ResourceProviderBatchRefresher, batching the refresh with the
+	// other refreshes for the same provider that run at the same time.
+	RefreshBatch(ResourceProvider, *InstanceInfo, *InstanceState) (*InstanceState, error)
+
+	// RetryTransient calls the given function, which calls a provider, and
+	// retries it while it fails with a transient error according to the
+	// retry policy of the context. The string describes the call in logs.
+	RetryTransient(string, func() error) error
+
 	// ConfigureProvider configures the provider with the given
 	// configuration. This is a separate context call because this call
 	// is used to store the provider configuration for inheritance lookups
@@ -69,6 +79,12 @@ type EvalContext interface {
 	// that is currently being acted upon.
 	Interpolate(*config.RawConfig, *Resource) (*ResourceConfig, error)
 
+	// Sensitive returns true if any of the given variables, interpolated
+	// in the module of this context, refers to a sensitive value: a
+	// variable declared sensitive, or a variable or output whose value is
+	// interpolated from one.
+	Sensitive(map[string]config.InterpolatedVariable) bool
+
 	// SetVariables sets the variables for the module within
 	// this context with the name n. This function call is additive:
 	// the second parameter is merged with any previous call.
@@ -39,11 +39,27 @@ type BuiltinEvalContext struct {
 	ProviderLock        *sync.Mutex
 	ProvisionerCache    map[string]ResourceProvisioner
 	ProvisionerLock     *sync.Mutex
+	RefreshBatchers     map[ResourceProvider]*refreshBatcher
+	RefreshBatchLock    *sync.Mutex
+	RetryPolicy         *RetryPolicy
+	ParallelismTuner    *parallelismTuner
 	DiffValue           *Diff
 	DiffLock            *sync.RWMutex
 	StateValue          *State
 	StateLock           *sync.RWMutex
 
+	// SensitiveValues are the sensitive values of the configuration. It is
+	// shared between all contexts.
+	SensitiveValues *sensitiveValues
+
+	// ProviderCredentials, if set, supplies credentials that providers are
+	// configured with, and ProviderCredGens are the generations of the
+	// credentials that they were configured with, by the cache key of the
+	// provider. Providers are configured again when they're used after
+	// the credentials change.
+	ProviderCredentials ProviderCredentials
+	ProviderCredGens    map[string]uint64
+
 	once sync.Once
 }
 
@@ -111,14 +127,20 @@ func (ctx *BuiltinEvalContext) InitProvider(n string) (ResourceProvider, error)
 func (ctx *BuiltinEvalContext) Provider(n string) ResourceProvider {
 	ctx.once.Do(ctx.init)
 
-	ctx.ProviderLock.Lock()
-	defer ctx.ProviderLock.Unlock()
-
 	providerPath := make([]string, len(ctx.Path())+1)
 	copy(providerPath, ctx.Path())
 	providerPath[len(providerPath)-1] = n
+	key := PathCacheKey(providerPath)
 
-	return ctx.ProviderCache[PathCacheKey(providerPath)]
+	ctx.ProviderLock.Lock()
+	p := ctx.ProviderCache[key]
+	ctx.ProviderLock.Unlock()
+
+	if p != nil && ctx.ProviderCredentials != nil {
+		ctx.reconfigureProvider(key, n, p)
+	}
+
+	return p
 }
 
 func (ctx *BuiltinEvalContext) CloseProvider(n string) error {
@@ -143,6 +165,33 @@ func (ctx *BuiltinEvalContext) CloseProvider(n string) error {
 	return nil
 }
 
+func (ctx *BuiltinEvalContext) RefreshBatch(
+	p ResourceProvider,
+	info *InstanceInfo,
+	state *InstanceState) (*InstanceState, error) {
+	ctx.once.Do(ctx.init)
+
+	// The batchers are shared by every context, since the same provider
+	// can be used by resources in different modules.
+	ctx.RefreshBatchLock.Lock()
+	b, ok := ctx.RefreshBatchers[p]
+	if !ok {
+		b = &refreshBatcher{Provider: p}
+		ctx.RefreshBatchers[p] = b
+	}
+	ctx.RefreshBatchLock.Unlock()
+
+	return b.Refresh(info, state)
+}
+
+func (ctx *BuiltinEvalContext) RetryTransient(desc string, fn func() error) error {
+	return ctx.RetryPolicy.Retry(ctx.Stopped(), desc, func() error {
+		err := fn()
+		ctx.ParallelismTuner.Observe(IsTransientError(err))
+		return err
+	})
+}
+
 func (ctx *BuiltinEvalContext) ConfigureProvider(
 	n string, cfg *ResourceConfig) error {
 	p := ctx.Provider(n)
@@ -154,7 +203,72 @@ func (ctx *BuiltinEvalContext) ConfigureProvider(
 		return nil
 	}
 
-	return p.Configure(cfg)
+	providerPath := make([]string, len(ctx.Path())+1)
+	copy(providerPath, ctx.Path())
+	providerPath[len(providerPath)-1] = n
+
+	return ctx.configureProvider(PathCacheKey(providerPath), n, p, cfg)
+}
+
+// configureProvider configures the provider p with the cache key key with
+// cfg, the defaults for its type from the ContextMeta, and the credentials
+// for its type from ProviderCredentials.
+func (ctx *BuiltinEvalContext) configureProvider(
+	key, n string, p ResourceProvider, cfg *ResourceConfig) error {
+	typeName := strings.SplitN(n, ".", 2)[0]
+	if i := ctx.Interpolater; i != nil && i.Meta != nil {
+		if defaults := i.Meta.ProviderDefaults[typeName]; len(defaults) > 0 {
+			cfg = cfg.WithDefaults(defaults)
+		}
+	}
+
+	if ctx.ProviderCredentials == nil {
+		return p.Configure(cfg)
+	}
+
+	creds, gen, err := ctx.ProviderCredentials.ProviderCredentials(typeName)
+	if err != nil {
+		return fmt.Errorf("Error reading the credentials of provider %s: %s", n, err)
+	}
+
+	ctx.ProviderLock.Lock()
+	ctx.ProviderCredGens[key] = gen
+	ctx.ProviderLock.Unlock()
+
+	if len(creds) == 0 {
+		return p.Configure(cfg)
+	}
+
+	return p.Configure(cfg.WithDefaults(creds))
+}
+
+// reconfigureProvider configures the provider p with the cache key key
+// again if its credentials have changed since it was configured, such as
+// when they were read from Vault again before expiring. Operations that
+// are already running may keep using the old credentials.
+func (ctx *BuiltinEvalContext) reconfigureProvider(key, n string, p ResourceProvider) {
+	typeName := strings.SplitN(n, ".", 2)[0]
+	_, gen, err := ctx.ProviderCredentials.ProviderCredentials(typeName)
+	if err != nil {
+		log.Printf("[WARN] Error reading the credentials of provider %s: %s", n, err)
+		return
+	}
+
+	ctx.ProviderLock.Lock()
+	oldGen, ok := ctx.ProviderCredGens[key]
+	cfg := ctx.ProviderConfigCache[key]
+	ctx.ProviderLock.Unlock()
+
+	// Providers that haven't been configured yet get the new credentials
+	// when they are.
+	if !ok || oldGen == gen || cfg == nil {
+		return
+	}
+
+	log.Printf("[INFO] Configuring provider %s again with new credentials", n)
+	if err := ctx.configureProvider(key, n, p, cfg); err != nil {
+		log.Printf("[WARN] Error configuring provider %s with new credentials: %s", n, err)
+	}
 }
 
 func (ctx *BuiltinEvalContext) SetProviderConfig(
@@ -311,6 +425,14 @@ func (ctx *BuiltinEvalContext) Interpolate(
 	return result, nil
 }
 
+func (ctx *BuiltinEvalContext) Sensitive(vars map[string]config.InterpolatedVariable) bool {
+	if ctx.SensitiveValues == nil {
+		return false
+	}
+
+	return ctx.SensitiveValues.references(ctx.Path(), vars)
+}
+
 func (ctx *BuiltinEvalContext) Path() []string {
 	return ctx.PathValue
 }
@@ -344,4 +466,10 @@ func (ctx *BuiltinEvalContext) State() (*State, *sync.RWMutex) {
 }
 
 func (ctx *BuiltinEvalContext) init() {
+	if ctx.RefreshBatchers == nil {
+		ctx.RefreshBatchers = make(map[ResourceProvider]*refreshBatcher)
+	}
+	if ctx.RefreshBatchLock == nil {
+		ctx.RefreshBatchLock = new(sync.Mutex)
+	}
 }
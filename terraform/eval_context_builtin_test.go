@@ -37,6 +37,110 @@ func TestBuiltinEvalContextProviderInput(t *testing.T) {
 	}
 }
 
+func TestBuiltinEvalContextProviderCredentials(t *testing.T) {
+	creds := &mockProviderCredentials{
+		Creds: map[string]map[string]string{
+			"aws": {"access_key": "AKIA1", "region": "us-west-2"},
+		},
+	}
+	p := new(MockResourceProvider)
+
+	ctx := testBuiltinEvalContext(t)
+	ctx.PathValue = []string{"root"}
+	ctx.ProviderCache = map[string]ResourceProvider{
+		PathCacheKey([]string{"root", "aws"}): p,
+	}
+	ctx.ProviderConfigCache = make(map[string]*ResourceConfig)
+	ctx.ProviderCredentials = creds
+	ctx.ProviderCredGens = make(map[string]uint64)
+	ctx.ProviderLock = new(sync.Mutex)
+
+	cfg := testResourceConfig(t, map[string]interface{}{"region": "us-east-1"})
+	if err := ctx.ConfigureProvider("aws", cfg); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The configuration takes precedence over the credentials
+	expected := map[string]interface{}{"access_key": "AKIA1", "region": "us-east-1"}
+	if !reflect.DeepEqual(p.ConfigureConfig.Config, expected) {
+		t.Fatalf("bad: %#v", p.ConfigureConfig.Config)
+	}
+
+	// The provider isn't configured again while the credentials are the same
+	p.ConfigureCalled = false
+	ctx.Provider("aws")
+	if p.ConfigureCalled {
+		t.Fatal("configure shouldn't be called")
+	}
+
+	// Once they change, it's configured again before it's used
+	creds.Creds["aws"]["access_key"] = "AKIA2"
+	creds.Gen++
+	ctx.Provider("aws")
+	if !p.ConfigureCalled {
+		t.Fatal("configure should be called")
+	}
+	expected["access_key"] = "AKIA2"
+	if !reflect.DeepEqual(p.ConfigureConfig.Config, expected) {
+		t.Fatalf("bad: %#v", p.ConfigureConfig.Config)
+	}
+
+	// The configuration that's saved doesn't include the credentials, so
+	// that they aren't inherited by the providers of other types
+	if _, ok := ctx.ParentProviderConfig("aws").Config["access_key"]; ok {
+		t.Fatal("credentials shouldn't be saved")
+	}
+}
+
+func TestBuiltinEvalContextProviderDefaults(t *testing.T) {
+	p := new(MockResourceProvider)
+
+	ctx := testBuiltinEvalContext(t)
+	ctx.PathValue = []string{"root"}
+	ctx.Interpolater = &Interpolater{
+		Meta: &ContextMeta{
+			Env: "staging",
+			ProviderDefaults: map[string]map[string]string{
+				"terraform": {"workspace": "staging"},
+			},
+		},
+	}
+	ctx.ProviderCache = map[string]ResourceProvider{
+		PathCacheKey([]string{"root", "terraform.other"}): p,
+	}
+	ctx.ProviderConfigCache = make(map[string]*ResourceConfig)
+	ctx.ProviderLock = new(sync.Mutex)
+
+	if err := ctx.ConfigureProvider("terraform.other", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]interface{}{"workspace": "staging"}
+	if !reflect.DeepEqual(p.ConfigureConfig.Config, expected) {
+		t.Fatalf("bad: %#v", p.ConfigureConfig.Config)
+	}
+
+	// The configuration takes precedence over the defaults
+	cfg := testResourceConfig(t, map[string]interface{}{"workspace": "default"})
+	if err := ctx.ConfigureProvider("terraform.other", cfg); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected["workspace"] = "default"
+	if !reflect.DeepEqual(p.ConfigureConfig.Config, expected) {
+		t.Fatalf("bad: %#v", p.ConfigureConfig.Config)
+	}
+}
+
+// mockProviderCredentials is a ProviderCredentials with credentials by
+// provider type.
+type mockProviderCredentials struct {
+	Creds map[string]map[string]string
+	Gen   uint64
+}
+
+func (c *mockProviderCredentials) ProviderCredentials(typ string) (map[string]string, uint64, error) {
+	return c.Creds[typ], c.Gen, nil
+}
+
 func testBuiltinEvalContext(t *testing.T) *BuiltinEvalContext {
 	return &BuiltinEvalContext{}
 }
@@ -32,6 +32,16 @@ type MockEvalContext struct {
 	CloseProviderName     string
 	CloseProviderProvider ResourceProvider
 
+	RefreshBatchCalled   bool
+	RefreshBatchProvider ResourceProvider
+	RefreshBatchInfo     *InstanceInfo
+	RefreshBatchState    *InstanceState
+	RefreshBatchResult   *InstanceState
+	RefreshBatchError    error
+
+	RetryTransientCalled bool
+	RetryTransientDesc   string
+
 	ProviderInputCalled bool
 	ProviderInputName   string
 	ProviderInputConfig map[string]interface{}
@@ -75,6 +85,10 @@ type MockEvalContext struct {
 	PathCalled bool
 	PathPath   []string
 
+	SensitiveCalled    bool
+	SensitiveVariables map[string]config.InterpolatedVariable
+	SensitiveResult    bool
+
 	SetVariablesCalled    bool
 	SetVariablesModule    string
 	SetVariablesVariables map[string]interface{}
@@ -104,6 +118,13 @@ func (c *MockEvalContext) Hook(fn func(Hook) (HookAction, error)) error {
 	return c.HookError
 }
 
+// RetryTransient calls the function once, without retrying.
+func (c *MockEvalContext) RetryTransient(desc string, fn func() error) error {
+	c.RetryTransientCalled = true
+	c.RetryTransientDesc = desc
+	return fn()
+}
+
 func (c *MockEvalContext) Input() UIInput {
 	c.InputCalled = true
 	return c.InputInput
@@ -127,6 +148,17 @@ func (c *MockEvalContext) CloseProvider(n string) error {
 	return nil
 }
 
+func (c *MockEvalContext) RefreshBatch(
+	p ResourceProvider,
+	info *InstanceInfo,
+	state *InstanceState) (*InstanceState, error) {
+	c.RefreshBatchCalled = true
+	c.RefreshBatchProvider = p
+	c.RefreshBatchInfo = info
+	c.RefreshBatchState = state
+	return c.RefreshBatchResult, c.RefreshBatchError
+}
+
 func (c *MockEvalContext) ConfigureProvider(n string, cfg *ResourceConfig) error {
 	c.ConfigureProviderCalled = true
 	c.ConfigureProviderName = n
@@ -186,6 +218,12 @@ func (c *MockEvalContext) Interpolate(
 	return c.InterpolateConfigResult, c.InterpolateError
 }
 
+func (c *MockEvalContext) Sensitive(vars map[string]config.InterpolatedVariable) bool {
+	c.SensitiveCalled = true
+	c.SensitiveVariables = vars
+	return c.SensitiveResult
+}
+
 func (c *MockEvalContext) Path() []string {
 	c.PathCalled = true
 	return c.PathPath
@@ -42,6 +42,12 @@ func (n *EvalCountFixZeroOneBoundaryGlobal) fixModule(m *ModuleState) error {
 			return err
 		}
 
+		// Instances of for_each resources are identified by their key,
+		// so they have no zero/one boundary to fix.
+		if key.Key != "" {
+			continue
+		}
+
 		// Set the index to -1 so that we can keep count
 		key.Index = -1
 
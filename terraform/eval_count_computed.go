@@ -21,5 +21,11 @@ func (n *EvalCountCheckComputed) Eval(ctx EvalContext) (interface{}, error) {
 			n.Resource.Id())
 	}
 
+	if n.Resource.RawForEach != nil && len(n.Resource.RawForEach.UnknownKeys()) > 0 {
+		return nil, fmt.Errorf(
+			"%s: value of 'for_each' cannot be computed",
+			n.Resource.Id())
+	}
+
 	return nil, nil
 }
@@ -2,7 +2,6 @@ package terraform
 
 import (
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/hashicorp/terraform/config"
@@ -42,10 +41,11 @@ func (n *EvalCompareDiff) Eval(ctx EvalContext) (interface{}, error) {
 	}()
 
 	if same, reason := one.Same(two); !same {
-		log.Printf("[ERROR] %s: diffs didn't match", n.Info.Id)
-		log.Printf("[ERROR] %s: reason: %s", n.Info.Id, reason)
-		log.Printf("[ERROR] %s: diff one: %#v", n.Info.Id, one)
-		log.Printf("[ERROR] %s: diff two: %#v", n.Info.Id, two)
+		l := applyLog.Resource(n.Info.Id)
+		l.Error("diffs didn't match")
+		l.Error("reason: %s", reason)
+		l.Error("diff one: %#v", one)
+		l.Error("diff two: %#v", two)
 		return nil, fmt.Errorf(
 			"%s: diffs didn't match during apply. This is a bug with "+
 				"Terraform and should be reported as a GitHub Issue.\n"+
@@ -81,6 +81,10 @@ type EvalDiff struct {
 	// Resource is needed to fetch the ignore_changes list so we can
 	// filter user-requested ignored attributes from the diff.
 	Resource *config.Resource
+
+	// Replace, if true, plans the replacement of an existing resource
+	// as if it were tainted, without marking it tainted in the state.
+	Replace bool
 }
 
 // TODO: test
@@ -104,8 +108,21 @@ func (n *EvalDiff) Eval(ctx EvalContext) (interface{}, error) {
 	}
 	diffState.init()
 
+	// If we're forcing a replacement then diff against a tainted copy
+	// of the state so the provider produces a full replacement diff.
+	replace := n.Replace && state != nil && state.ID != ""
+	if replace && !diffState.Tainted {
+		diffState = diffState.DeepCopy()
+		diffState.Tainted = true
+	}
+
 	// Diff!
-	diff, err := provider.Diff(n.Info, diffState, config)
+	var diff *InstanceDiff
+	err = ctx.RetryTransient(n.Info.Id, func() error {
+		var err error
+		diff, err = provider.Diff(n.Info, diffState, config)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -125,9 +142,15 @@ func (n *EvalDiff) Eval(ctx EvalContext) (interface{}, error) {
 		return nil, err
 	}
 
-	// Preserve the DestroyTainted flag
-	if n.Diff != nil {
+	// Preserve the DestroyTainted flag and the adopted object
+	if n.Diff != nil && *n.Diff != nil {
 		diff.SetTainted((*n.Diff).GetDestroyTainted())
+		diff.SetAdoptID((*n.Diff).GetAdoptID())
+	}
+
+	// Mark the diff as tainted if a replacement was requested
+	if replace {
+		diff.SetTainted(true)
 	}
 
 	// Require a destroy if there is an ID and it requires new.
@@ -157,6 +180,8 @@ func (n *EvalDiff) Eval(ctx EvalContext) (interface{}, error) {
 		return nil, err
 	}
 
+	n.processSensitive(ctx, diff)
+
 	// Call post-refresh hook
 	err = ctx.Hook(func(h Hook) (HookAction, error) {
 		return h.PostDiff(n.Info, diff)
@@ -175,12 +200,62 @@ func (n *EvalDiff) Eval(ctx EvalContext) (interface{}, error) {
 		// Merge our state so that the state is updated with our plan
 		if !diff.Empty() && n.OutputState != nil {
 			*n.OutputState = state.MergeDiff(diff)
+
+			// A replacement runs the provisioners again, so the outputs
+			// they recorded aren't known until then
+			if diff.RequiresNew() {
+				(*n.OutputState).clearProvisionerOutputs()
+			}
 		}
 	}
 
 	return nil, nil
 }
 
+// processSensitive marks the attributes of the diff that are configured
+// with sensitive values, such as sensitive variables or sensitive outputs of
+// child modules, as sensitive, so that their values aren't shown.
+func (n *EvalDiff) processSensitive(ctx EvalContext, diff *InstanceDiff) {
+	if n.Resource == nil || n.Resource.RawConfig == nil {
+		return
+	}
+
+	state, lock := ctx.State()
+	if state == nil {
+		return
+	}
+	lock.RLock()
+	defer lock.RUnlock()
+
+	rc := n.Resource.RawConfig
+	if !referencesSensitive(ctx, state, rc.Variables) {
+		return
+	}
+
+	// Find the top-level keys of the configuration that reference them
+	var keys []string
+	for k, v := range rc.Raw {
+		kc, err := config.NewRawConfig(map[string]interface{}{k: v})
+		if err != nil {
+			continue
+		}
+		if referencesSensitive(ctx, state, kc.Variables) {
+			keys = append(keys, k)
+		}
+	}
+
+	for name, attr := range diff.CopyAttributes() {
+		for _, k := range keys {
+			if name == k || strings.HasPrefix(name, k+".") {
+				sensitive := *attr
+				sensitive.Sensitive = true
+				diff.SetAttribute(name, &sensitive)
+				break
+			}
+		}
+	}
+}
+
 func (n *EvalDiff) processIgnoreChanges(diff *InstanceDiff) error {
 	if diff == nil || n.Resource == nil || n.Resource.Id() == "" {
 		return nil
@@ -261,15 +336,15 @@ func (n *EvalDiff) processIgnoreChanges(diff *InstanceDiff) error {
 
 	// Here we undo the two reactions to RequireNew in EvalDiff - the "id"
 	// attribute diff and the Destroy boolean field
-	log.Printf("[DEBUG] Removing 'id' diff and setting Destroy to false " +
-		"because after ignore_changes, this diff no longer requires replacement")
+	planLog.Resource(n.Resource.Id()).Debug(
+		"Removing 'id' diff and setting Destroy to false " +
+			"because after ignore_changes, this diff no longer requires replacement")
 	diff.DelAttribute("id")
 	diff.SetDestroy(false)
 
 	// If we didn't hit any of our early exit conditions, we can filter the diff.
 	for k := range ignorableAttrKeys {
-		log.Printf("[DEBUG] [EvalIgnoreChanges] %s - Ignoring diff attribute: %s",
-			n.Resource.Id(), k)
+		planLog.Resource(n.Resource.Id()).Debug("Ignoring diff attribute: %s", k)
 		diff.DelAttribute(k)
 	}
 
@@ -0,0 +1,44 @@
+package terraform
+
+import (
+	"github.com/hashicorp/terraform/config"
+)
+
+// EvalForEachValue is an EvalNode implementation that sets the value that
+// a resource instance sees as "each.value", by interpolating the for_each
+// of its resource configuration and looking up the instance key.
+//
+// If the instance key is no longer present in the for_each, such as when
+// destroying an orphan, the value is left unset.
+type EvalForEachValue struct {
+	Config   *config.Resource
+	Resource *Resource
+}
+
+func (n *EvalForEachValue) Eval(ctx EvalContext) (interface{}, error) {
+	if n.Config == nil || n.Config.RawForEach == nil || n.Resource.EachKey == "" {
+		return nil, nil
+	}
+
+	// Interpolate a copy so that we don't race with other instances
+	rc := n.Config.Copy()
+	if _, err := ctx.Interpolate(rc.RawForEach, nil); err != nil {
+		return nil, err
+	}
+
+	// A computed for_each leaves the value unknown
+	if len(rc.RawForEach.UnknownKeys()) > 0 {
+		return nil, nil
+	}
+
+	forEach, err := rc.ForEach()
+	if err != nil {
+		return nil, err
+	}
+
+	if v, ok := forEach[n.Resource.EachKey]; ok {
+		n.Resource.EachValue = v
+	}
+
+	return nil, nil
+}
@@ -66,6 +66,14 @@ func (n *EvalWriteOutput) Eval(ctx EvalContext) (interface{}, error) {
 		mod = state.AddModule(ctx.Path())
 	}
 
+	// An output of a sensitive value, such as a sensitive variable or a
+	// sensitive output of a child module, is sensitive too, so that the
+	// value stays hidden however far it's passed.
+	sensitive := n.Sensitive
+	if !sensitive && n.Value != nil {
+		sensitive = referencesSensitive(ctx, state, n.Value.Variables)
+	}
+
 	// Get the value from the config
 	var valueRaw interface{} = config.UnknownVariableValue
 	if cfg != nil {
@@ -83,19 +91,19 @@ func (n *EvalWriteOutput) Eval(ctx EvalContext) (interface{}, error) {
 	case string:
 		mod.Outputs[n.Name] = &OutputState{
 			Type:      "string",
-			Sensitive: n.Sensitive,
+			Sensitive: sensitive,
 			Value:     valueTyped,
 		}
 	case []interface{}:
 		mod.Outputs[n.Name] = &OutputState{
 			Type:      "list",
-			Sensitive: n.Sensitive,
+			Sensitive: sensitive,
 			Value:     valueTyped,
 		}
 	case map[string]interface{}:
 		mod.Outputs[n.Name] = &OutputState{
 			Type:      "map",
-			Sensitive: n.Sensitive,
+			Sensitive: sensitive,
 			Value:     valueTyped,
 		}
 	case []map[string]interface{}:
@@ -104,7 +112,7 @@ func (n *EvalWriteOutput) Eval(ctx EvalContext) (interface{}, error) {
 		if len(valueTyped) == 1 {
 			mod.Outputs[n.Name] = &OutputState{
 				Type:      "map",
-				Sensitive: n.Sensitive,
+				Sensitive: sensitive,
 				Value:     valueTyped[0],
 			}
 			break
@@ -117,3 +125,38 @@ func (n *EvalWriteOutput) Eval(ctx EvalContext) (interface{}, error) {
 
 	return nil, nil
 }
+
+// referencesSensitive returns true if any of vars refers to a sensitive
+// value of the configuration, or to an output of a child module that is
+// sensitive in the state. The caller must hold the lock of the state.
+func referencesSensitive(
+	ctx EvalContext, state *State, vars map[string]config.InterpolatedVariable) bool {
+	return ctx.Sensitive(vars) || referencesSensitiveOutput(state, ctx.Path(), vars)
+}
+
+// referencesSensitiveOutput returns true if any of vars refers to an output
+// of a child of the module at path that is sensitive in the state. The
+// caller must hold the lock of the state.
+func referencesSensitiveOutput(
+	state *State, path []string, vars map[string]config.InterpolatedVariable) bool {
+	for _, v := range vars {
+		mv, ok := v.(*config.ModuleVariable)
+		if !ok {
+			continue
+		}
+
+		childPath := make([]string, len(path), len(path)+1)
+		copy(childPath, path)
+		childPath = append(childPath, mv.Name)
+
+		mod := state.ModuleByPath(childPath)
+		if mod == nil {
+			continue
+		}
+		if o, ok := mod.Outputs[mv.Field]; ok && o.Sensitive {
+			return true
+		}
+	}
+
+	return false
+}
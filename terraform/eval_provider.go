@@ -2,6 +2,7 @@ package terraform
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/terraform/config"
 )
@@ -23,6 +24,13 @@ type EvalBuildProviderConfig struct {
 	Provider string
 	Config   **ResourceConfig
 	Output   **ResourceConfig
+
+	// Base, if set, is the provider whose configuration this one overrides
+	// the arguments of, as with a provider_config block. The configuration
+	// of Base, as it's built in this module or inherited from a parent
+	// module, is used in place of a parent configuration, and the arguments
+	// of Config take precedence over it.
+	Base string
 }
 
 func (n *EvalBuildProviderConfig) Eval(ctx EvalContext) (interface{}, error) {
@@ -44,9 +52,23 @@ func (n *EvalBuildProviderConfig) Eval(ctx EvalContext) (interface{}, error) {
 		cfg = NewResourceConfig(merged)
 	}
 
-	// Get the parent configuration if there is one
+	// Overrides are merged onto their base. Their alias is never declared
+	// in a parent module, so there's no other parent configuration.
+	if n.Base != "" {
+		if base := ctx.ParentProviderConfig(n.Base); base != nil {
+			merged := base.raw.MergeNested(cfg.raw)
+			cfg = NewResourceConfig(merged)
+		}
+
+		*n.Output = cfg
+		return nil, nil
+	}
+
+	// Get the parent configuration if there is one. Its maps and nested
+	// blocks are merged with those of this configuration, so that setting
+	// one key of them in a module doesn't drop the others.
 	if parent := ctx.ParentProviderConfig(n.Provider); parent != nil {
-		merged := cfg.raw.Merge(parent.raw)
+		merged := cfg.raw.MergeNested(parent.raw)
 		cfg = NewResourceConfig(merged)
 	}
 
@@ -92,12 +114,35 @@ func (n *EvalCloseProvider) Eval(ctx EvalContext) (interface{}, error) {
 type EvalGetProvider struct {
 	Name   string
 	Output *ResourceProvider
+
+	// Config and Resource, if set, are the configuration and the instance
+	// of a resource. If the configuration selects its provider with an
+	// expression, the provider selected for the instance is retrieved
+	// instead of Name, and stored in Resource.ProviderName.
+	Config   *config.Resource
+	Resource *Resource
 }
 
 func (n *EvalGetProvider) Eval(ctx EvalContext) (interface{}, error) {
-	result := ctx.Provider(n.Name)
+	name := n.Name
+	if n.Config != nil && n.Config.RawProvider != nil {
+		// Interpolate a copy so that we don't race with other instances
+		rc := n.Config.Copy()
+		if _, err := ctx.Interpolate(rc.RawProvider, n.Resource); err != nil {
+			return nil, err
+		}
+
+		var err error
+		name, err = rc.SelectedProvider()
+		if err != nil {
+			return nil, err
+		}
+		n.Resource.ProviderName = name
+	}
+
+	result := ctx.Provider(name)
 	if result == nil {
-		return nil, fmt.Errorf("provider %s not initialized", n.Name)
+		return nil, fmt.Errorf("provider %s not initialized", name)
 	}
 
 	if n.Output != nil {
@@ -149,9 +194,12 @@ func (n *EvalInputProvider) Eval(ctx EvalContext) (interface{}, error) {
 		// will be merged in by EvalBuildProviderConfig on subsequent
 		// (post-input) walks.
 		confMap := config.Config
+		// The keys of nested values, such as "tags.0.a", drop the whole
+		// top-level value, since it would otherwise be merged over the
+		// real one with the placeholder inside.
 		if config.ComputedKeys != nil {
 			for _, key := range config.ComputedKeys {
-				delete(confMap, key)
+				delete(confMap, strings.SplitN(key, ".", 2)[0])
 			}
 		}
 
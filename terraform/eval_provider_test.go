@@ -87,6 +87,42 @@ func TestEvalBuildProviderConfig_parentPriority(t *testing.T) {
 	}
 }
 
+func TestEvalBuildProviderConfig_base(t *testing.T) {
+	config := testResourceConfig(t, map[string]interface{}{
+		"region": "west",
+	})
+
+	n := &EvalBuildProviderConfig{
+		Provider: "foo.provider_config_foo_instance_bar",
+		Config:   &config,
+		Output:   &config,
+		Base:     "foo",
+	}
+
+	ctx := &MockEvalContext{
+		ParentProviderConfigConfig: testResourceConfig(t, map[string]interface{}{
+			"region": "east",
+			"zone":   "a",
+		}),
+	}
+	if _, err := n.Eval(ctx); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The base is looked up instead of the provider itself, and the
+	// overridden arguments take precedence over it.
+	if ctx.ParentProviderConfigName != "foo" {
+		t.Fatalf("bad: %s", ctx.ParentProviderConfigName)
+	}
+	expected := map[string]interface{}{
+		"region": "west",
+		"zone":   "a",
+	}
+	if !reflect.DeepEqual(config.Raw, expected) {
+		t.Fatalf("bad: %#v", config.Raw)
+	}
+}
+
 func TestEvalConfigProvider_impl(t *testing.T) {
 	var _ EvalNode = new(EvalConfigProvider)
 }
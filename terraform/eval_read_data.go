@@ -38,8 +38,11 @@ func (n *EvalReadDataDiff) Eval(ctx EvalContext) (interface{}, error) {
 		provider := *n.Provider
 		config := *n.Config
 
-		var err error
-		diff, err = provider.ReadDataDiff(n.Info, config)
+		err := ctx.RetryTransient(n.Info.Id, func() error {
+			var err error
+			diff, err = provider.ReadDataDiff(n.Info, config)
+			return err
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -119,7 +122,12 @@ func (n *EvalReadDataApply) Eval(ctx EvalContext) (interface{}, error) {
 		return nil, err
 	}
 
-	state, err := provider.ReadDataApply(n.Info, diff)
+	var state *InstanceState
+	err = ctx.RetryTransient(n.Info.Id, func() error {
+		var err error
+		state, err = provider.ReadDataApply(n.Info, diff)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %s", n.Info.Id, err)
 	}
@@ -2,7 +2,6 @@ package terraform
 
 import (
 	"fmt"
-	"log"
 )
 
 // EvalRefresh is an EvalNode implementation that does a refresh for
@@ -21,7 +20,7 @@ func (n *EvalRefresh) Eval(ctx EvalContext) (interface{}, error) {
 
 	// If we have no state, we don't do any refreshing
 	if state == nil {
-		log.Printf("[DEBUG] refresh: %s: no state, not refreshing", n.Info.Id)
+		refreshLog.Resource(n.Info.Id).Debug("no state, not refreshing")
 		return nil, nil
 	}
 
@@ -33,11 +32,24 @@ func (n *EvalRefresh) Eval(ctx EvalContext) (interface{}, error) {
 		return nil, err
 	}
 
-	// Refresh!
-	state, err = provider.Refresh(n.Info, state)
+	// Refresh! Providers that can refresh many resources at once are
+	// given the refreshes that run at the same time together.
+	prior := state
+	err = ctx.RetryTransient(n.Info.Id, func() error {
+		var err error
+		if _, ok := provider.(ResourceProviderBatchRefresher); ok {
+			state, err = ctx.RefreshBatch(provider, n.Info, prior)
+		} else {
+			state, err = provider.Refresh(n.Info, prior)
+		}
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %s", n.Info.Id, err.Error())
 	}
+	if state != nil {
+		state.keepProvisionerOutputs(prior)
+	}
 
 	// Call post-refresh hook
 	err = ctx.Hook(func(h Hook) (HookAction, error) {
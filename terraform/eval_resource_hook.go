@@ -0,0 +1,228 @@
+package terraform
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"text/template"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform/config"
+	"github.com/mitchellh/go-linereader"
+)
+
+// ResourceHookData is the data that the lifecycle hooks of a resource are
+// templates of.
+type ResourceHookData struct {
+	// Address is the address of the resource instance, such as
+	// "aws_instance.web.0".
+	Address string
+
+	// Action is "create", "update" or "destroy".
+	Action string
+
+	// ID and Attributes are of the instance before it's changed, for the
+	// hooks that run before, and after it's changed, for the hooks that
+	// run after. They're empty before it's created and after it's
+	// destroyed, except that the hooks after destroying have those from
+	// before destroying.
+	ID         string
+	Attributes map[string]string
+}
+
+// EvalResourceHook is an EvalNode implementation that runs a lifecycle hook
+// of a resource: a shell command before or after it's created, updated or
+// destroyed.
+//
+// A hook that fails before the change stops the change. A hook that fails
+// after it is added to Error, but the change is kept.
+type EvalResourceHook struct {
+	Info     *InstanceInfo
+	Resource *config.Resource
+	After    bool
+	Destroy  bool
+	State    **InstanceState
+	Diff     **InstanceDiff
+
+	// CreateNew is whether the resource was created, for the hooks after
+	// creating or updating it.
+	CreateNew *bool
+
+	// Error is the error of the change, for the hooks after it. The hooks
+	// don't run if the change failed.
+	Error *error
+}
+
+func (n *EvalResourceHook) Eval(ctx EvalContext) (interface{}, error) {
+	if n.Resource == nil || n.Resource.Mode != config.ManagedResourceMode {
+		return nil, nil
+	}
+	if n.After && n.Error != nil && *n.Error != nil {
+		return nil, nil
+	}
+
+	var state *InstanceState
+	if n.State != nil {
+		state = *n.State
+	}
+	var diff *InstanceDiff
+	if n.Diff != nil {
+		diff = *n.Diff
+	}
+
+	// Like EvalApply, nothing is done without changes
+	if !n.Destroy {
+		if diff.Empty() || (diff.GetAdoptID() != "" && diff.GetAttributesLen() == 0) {
+			return nil, nil
+		}
+	}
+
+	action := "update"
+	switch {
+	case n.Destroy:
+		action = "destroy"
+	case n.After:
+		if n.CreateNew != nil && *n.CreateNew {
+			action = "create"
+		}
+	case state == nil || state.ID == "" || diff.RequiresNew():
+		action = "create"
+		state = nil
+	}
+
+	name := "before_" + action
+	if n.After {
+		name = "after_" + action
+	}
+	command := n.Resource.Lifecycle.Hooks()[name]
+	if command == "" {
+		return nil, nil
+	}
+
+	data := &ResourceHookData{
+		Address:    n.Info.HumanId(),
+		Action:     action,
+		Attributes: make(map[string]string),
+	}
+	if state != nil {
+		data.ID = state.ID
+		for k, v := range state.Attributes {
+			data.Attributes[k] = v
+		}
+	}
+
+	err := runResourceHook(ctx, n.Info, name, command, data)
+	if err == nil {
+		return nil, nil
+	}
+
+	err = fmt.Errorf("%s: lifecycle %s: %s", n.Info.Id, name, err)
+	if !n.After || n.Error == nil {
+		return nil, err
+	}
+
+	*n.Error = multierror.Append(*n.Error, err)
+	return nil, nil
+}
+
+// runResourceHook runs the command of the hook with the given name, with the
+// output going to the hooks of the context like that of provisioners.
+func runResourceHook(ctx EvalContext, info *InstanceInfo, name, command string, data *ResourceHookData) error {
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(command)
+	if err != nil {
+		return err
+	}
+
+	env, refs := resourceHookEnv(data)
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, refs); err != nil {
+		return err
+	}
+
+	// Delayed expansion makes cmd expand the variables after it has parsed
+	// the command, like the shell does.
+	argv := []string{"/bin/sh", "-c"}
+	if runtime.GOOS == "windows" {
+		argv = []string{"cmd", "/V:ON", "/C"}
+	}
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to initialize pipe for output: %s", err)
+	}
+
+	cmd := exec.Command(argv[0], append(argv[1:], buf.String())...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		copyResourceHookOutput(ctx, info, name, pr)
+	}()
+
+	applyLog.Resource(info.Id).Debug("running lifecycle %s: %q", name, buf.String())
+	err = cmd.Start()
+	if err == nil {
+		err = cmd.Wait()
+	}
+
+	// Closing the write end of the pipe ends the copying of the output
+	pw.Close()
+	<-doneCh
+	pr.Close()
+
+	return err
+}
+
+// resourceHookEnv returns the environment variables that hold the fields of
+// data, along with a copy of data whose fields are quoted references to
+// those variables. The commands are templates of the copy, so that the
+// values, which come from the provider, are never parsed as commands.
+func resourceHookEnv(data *ResourceHookData) ([]string, *ResourceHookData) {
+	var env []string
+	ref := func(name, value string) string {
+		env = append(env, name+"="+value)
+		if runtime.GOOS == "windows" {
+			return `"!` + name + `!"`
+		}
+		return `"$` + name + `"`
+	}
+
+	refs := &ResourceHookData{
+		Address:    ref("TF_HOOK_ADDRESS", data.Address),
+		Action:     ref("TF_HOOK_ACTION", data.Action),
+		ID:         ref("TF_HOOK_ID", data.ID),
+		Attributes: make(map[string]string, len(data.Attributes)),
+	}
+
+	// Attribute names aren't valid variable names, so the variables are
+	// numbered in the order of the names instead.
+	keys := make([]string, 0, len(data.Attributes))
+	for k := range data.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		refs.Attributes[k] = ref(fmt.Sprintf("TF_HOOK_ATTRIBUTE_%d", i), data.Attributes[k])
+	}
+
+	return env, refs
+}
+
+// copyResourceHookOutput passes the lines of the output of a hook to the
+// hooks of the context.
+func copyResourceHookOutput(ctx EvalContext, info *InstanceInfo, name string, r io.Reader) {
+	lr := linereader.New(r)
+	for line := range lr.Ch {
+		ctx.Hook(func(h Hook) (HookAction, error) {
+			h.ProvisionOutput(info, name, line)
+			return HookActionContinue, nil
+		})
+	}
+}
@@ -131,10 +131,19 @@ type EvalWriteState struct {
 	Provider     string
 	Dependencies []string
 	State        **InstanceState
+
+	// Resource, if set, is the instance being written. The provider
+	// selected for it, if any, is recorded instead of Provider.
+	Resource *Resource
 }
 
 func (n *EvalWriteState) Eval(ctx EvalContext) (interface{}, error) {
-	return writeInstanceToState(ctx, n.Name, n.ResourceType, n.Provider, n.Dependencies,
+	provider := n.Provider
+	if n.Resource != nil && n.Resource.ProviderName != "" {
+		provider = n.Resource.ProviderName
+	}
+
+	return writeInstanceToState(ctx, n.Name, n.ResourceType, provider, n.Dependencies,
 		func(rs *ResourceState) error {
 			rs.Primary = *n.State
 			return nil
@@ -50,6 +50,22 @@ func (n *EvalValidateCount) Eval(ctx EvalContext) (interface{}, error) {
 			"Count is less than zero: %d", count))
 	}
 
+	if n.Resource.RawForEach != nil {
+		if _, err := ctx.Interpolate(n.Resource.RawForEach, nil); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"Failed to interpolate for_each: %s", err))
+			goto RETURN
+		}
+
+		// A computed for_each can't be checked until plan
+		if len(n.Resource.RawForEach.UnknownKeys()) == 0 {
+			if _, err := n.Resource.ForEach(); err != nil {
+				errs = append(errs, fmt.Errorf(
+					"Invalid for_each: %s", err))
+			}
+		}
+	}
+
 RETURN:
 	if len(errs) != 0 {
 		err = &EvalValidateError{
@@ -188,6 +204,10 @@ type EvalValidateResource struct {
 	ResourceType string
 	ResourceMode config.ResourceMode
 
+	// Resource, if set, is used to point out where the attributes that
+	// errors are about are configured.
+	Resource *config.Resource
+
 	// IgnoreWarnings means that warnings will not be passed through. This allows
 	// "just-in-time" passes of validation to continue execution through warnings.
 	IgnoreWarnings bool
@@ -220,6 +240,8 @@ func (n *EvalValidateResource) Eval(ctx EvalContext) (interface{}, error) {
 		return nil, nil
 	}
 
+	locateAttributeErrors(n.Resource, errs...)
+
 	return nil, &EvalValidateError{
 		Warnings: warns,
 		Errors:   errs,
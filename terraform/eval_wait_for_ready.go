@@ -0,0 +1,82 @@
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform/config"
+)
+
+// EvalWaitForReady is an EvalNode implementation that waits for a newly
+// created resource to be ready for use, if its lifecycle has wait_for_ready
+// set.
+//
+// If the resource doesn't become ready, it is tainted, just as it is when a
+// provisioner fails, so that it is replaced by the next apply.
+type EvalWaitForReady struct {
+	Info      *InstanceInfo
+	State     **InstanceState
+	Resource  *config.Resource
+	Provider  *ResourceProvider
+	CreateNew *bool
+	Error     *error
+}
+
+func (n *EvalWaitForReady) Eval(ctx EvalContext) (interface{}, error) {
+	if n.Resource == nil || !n.Resource.Lifecycle.WaitForReady {
+		return nil, nil
+	}
+
+	// Only newly created resources are waited for
+	if n.CreateNew != nil && !*n.CreateNew {
+		return nil, nil
+	}
+
+	// If the apply failed, there's nothing to wait for
+	state := *n.State
+	if state == nil || state.ID == "" || (n.Error != nil && *n.Error != nil) {
+		return nil, nil
+	}
+
+	var err error
+	waiter, ok := (*n.Provider).(ResourceProviderReadyWaiter)
+	if !ok {
+		err = ErrWaitForReadyUnsupported
+	} else {
+		applyLog.Resource(n.Info.Id).Debug("waiting for the resource to be ready")
+
+		var newState *InstanceState
+		newState, err = waiter.WaitForReady(n.Info, state)
+		if err == nil && newState != nil {
+			newState.init()
+			if newState.ID != "" {
+				newState.Attributes["id"] = newState.ID
+			}
+
+			state = newState
+			*n.State = state
+		}
+	}
+
+	if err == nil {
+		return nil, nil
+	}
+
+	// A resource that can't be waited for is still usable, so we only
+	// taint resources that didn't become ready.
+	if err == ErrWaitForReadyUnsupported {
+		err = fmt.Errorf(
+			"wait_for_ready: the provider can't wait for resources of type %q",
+			n.Info.Type)
+	} else {
+		state.Tainted = true
+		err = fmt.Errorf("wait_for_ready: %s", err)
+	}
+
+	if n.Error == nil {
+		return nil, err
+	}
+
+	*n.Error = multierror.Append(*n.Error, fmt.Errorf("%s: %s", n.Info.Id, err))
+	return nil, nil
+}
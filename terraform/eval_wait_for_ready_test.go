@@ -0,0 +1,68 @@
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+func TestEvalWaitForReady_impl(t *testing.T) {
+	var _ EvalNode = new(EvalWaitForReady)
+}
+
+func TestEvalWaitForReady_notCreated(t *testing.T) {
+	var provider ResourceProvider = new(MockResourceProvider)
+	state := &InstanceState{ID: "foo"}
+	createNew := false
+	var err error
+	n := &EvalWaitForReady{
+		Info:  &InstanceInfo{Id: "aws_instance.foo", Type: "aws_instance"},
+		State: &state,
+		Resource: &config.Resource{
+			Lifecycle: config.ResourceLifecycle{WaitForReady: true},
+		},
+		Provider:  &provider,
+		CreateNew: &createNew,
+		Error:     &err,
+	}
+	if _, err := n.Eval(&MockEvalContext{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if provider.(*MockResourceProvider).WaitForReadyCalled {
+		t.Fatal("should not be called")
+	}
+}
+
+func TestEvalWaitForReady_unsupported(t *testing.T) {
+	// A provider that only implements ResourceProvider
+	var provider ResourceProvider = struct {
+		ResourceProvider
+	}{new(MockResourceProvider)}
+	state := &InstanceState{ID: "foo"}
+	createNew := true
+	var err error
+	n := &EvalWaitForReady{
+		Info:  &InstanceInfo{Id: "aws_instance.foo", Type: "aws_instance"},
+		State: &state,
+		Resource: &config.Resource{
+			Lifecycle: config.ResourceLifecycle{WaitForReady: true},
+		},
+		Provider:  &provider,
+		CreateNew: &createNew,
+		Error:     &err,
+	}
+	if _, err := n.Eval(&MockEvalContext{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err == nil || !strings.Contains(err.Error(), `can't wait for resources of type "aws_instance"`) {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// The resource was created, so it shouldn't be replaced
+	if state.Tainted {
+		t.Fatal("should not be tainted")
+	}
+}
@@ -5,8 +5,9 @@ import (
 )
 
 // ProviderEvalTree returns the evaluation tree for initializing and
-// configuring providers.
-func ProviderEvalTree(n string, config *config.RawConfig) EvalNode {
+// configuring providers. base is the provider whose configuration this one
+// overrides the arguments of, if any.
+func ProviderEvalTree(n string, config *config.RawConfig, base string) EvalNode {
 	var provider ResourceProvider
 	var resourceConfig *ResourceConfig
 
@@ -30,6 +31,7 @@ func ProviderEvalTree(n string, config *config.RawConfig) EvalNode {
 					Provider: n,
 					Config:   &resourceConfig,
 					Output:   &resourceConfig,
+					Base:     base,
 				},
 				&EvalInputProvider{
 					Name:     n,
@@ -56,6 +58,7 @@ func ProviderEvalTree(n string, config *config.RawConfig) EvalNode {
 					Provider: n,
 					Config:   &resourceConfig,
 					Output:   &resourceConfig,
+					Base:     base,
 				},
 				&EvalValidateProvider{
 					Provider: &provider,
@@ -86,6 +89,7 @@ func ProviderEvalTree(n string, config *config.RawConfig) EvalNode {
 					Provider: n,
 					Config:   &resourceConfig,
 					Output:   &resourceConfig,
+					Base:     base,
 				},
 				&EvalSetProviderConfig{
 					Provider: n,
@@ -40,6 +40,10 @@ type ApplyGraphBuilder struct {
 	// Destroy, if true, represents a pure destroy operation
 	Destroy bool
 
+	// Order, if set, is an ordering of the nodes of the graph computed
+	// outside of Terraform that's enforced on top of the dependencies.
+	Order *GraphOrder
+
 	// Validate will do structural validation of the graph.
 	Validate bool
 }
@@ -91,8 +95,8 @@ func (b *ApplyGraphBuilder) Steps() []GraphTransformer {
 		&MissingProviderTransformer{Providers: b.Providers, Concrete: concreteProvider},
 		&ProviderTransformer{},
 		&DisableProviderTransformer{},
-		&ParentProviderTransformer{},
 		&AttachProviderConfigTransformer{Module: b.Module},
+		&ParentProviderTransformer{},
 
 		// Destruction ordering
 		&DestroyEdgeTransformer{Module: b.Module, State: b.State},
@@ -123,10 +127,19 @@ func (b *ApplyGraphBuilder) Steps() []GraphTransformer {
 		// Target
 		&TargetsTransformer{Targets: b.Targets},
 
+		// Apply the stages in order
+		&StageTransformer{},
+
+		// Order destruction by priority, within the order from dependencies
+		&DestroyPriorityTransformer{},
+
 		// Close opened plugin connections
 		&CloseProviderTransformer{},
 		&CloseProvisionerTransformer{},
 
+		// Follow the ordering from outside of Terraform, if there is one
+		&GraphOrderTransformer{Order: b.Order},
+
 		// Single root
 		&RootTransformer{},
 	}
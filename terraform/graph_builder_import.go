@@ -52,16 +52,29 @@ func (b *ImportGraphBuilder) Steps() []GraphTransformer {
 		// Add the import steps
 		&ImportStateTransformer{Targets: b.ImportTargets},
 
+		// Add root variables
+		&RootVariableTransformer{Module: mod},
+
 		// Provider-related transformations
 		&MissingProviderTransformer{Providers: b.Providers, Concrete: concreteProvider},
 		&ProviderTransformer{},
 		&DisableProviderTransformer{},
-		&ParentProviderTransformer{},
 		&AttachProviderConfigTransformer{Module: mod},
+		&ParentProviderTransformer{},
+
+		// Add the module variables once the providers are configured, since
+		// only the variables that something refers to are added, so that
+		// the providers of child modules can be configured with them
+		&ModuleVariableTransformer{Module: mod},
 
 		// This validates that the providers only depend on variables
 		&ImportProviderValidateTransformer{},
 
+		// Connect the providers to the variables they depend on, and
+		// remove the variables that no provider needs
+		&ReferenceTransformer{},
+		&ImportVariablePruneTransformer{},
+
 		// Close opened plugin connections
 		&CloseProviderTransformer{},
 
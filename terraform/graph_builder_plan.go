@@ -34,6 +34,10 @@ type PlanGraphBuilder struct {
 	// Targets are resources to target
 	Targets []string
 
+	// ForceReplace are resources that will be planned for replacement
+	// even if their configuration hasn't changed.
+	ForceReplace []*ResourceAddress
+
 	// DisableReduce, if true, will not reduce the graph. Great for testing.
 	DisableReduce bool
 
@@ -94,8 +98,8 @@ func (b *PlanGraphBuilder) Steps() []GraphTransformer {
 		&MissingProviderTransformer{Providers: b.Providers, Concrete: b.ConcreteProvider},
 		&ProviderTransformer{},
 		&DisableProviderTransformer{},
-		&ParentProviderTransformer{},
 		&AttachProviderConfigTransformer{Module: b.Module},
+		&ParentProviderTransformer{},
 
 		// Provisioner-related transformations. Only add these if requested.
 		GraphTransformIf(
@@ -153,6 +157,7 @@ func (b *PlanGraphBuilder) init() {
 			NodeAbstractCountResource: &NodeAbstractCountResource{
 				NodeAbstractResource: a,
 			},
+			ForceReplace: b.ForceReplace,
 		}
 	}
 
@@ -130,8 +130,8 @@ func (b *RefreshGraphBuilder) Steps() []GraphTransformer {
 		&MissingProviderTransformer{Providers: b.Providers, Concrete: concreteProvider},
 		&ProviderTransformer{},
 		&DisableProviderTransformer{},
-		&ParentProviderTransformer{},
 		&AttachProviderConfigTransformer{Module: b.Module},
+		&ParentProviderTransformer{},
 
 		// Add the outputs
 		&OutputTransformer{Module: b.Module},
@@ -1,9 +1,198 @@
 package terraform
 
-import "github.com/hashicorp/terraform/dag"
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+// GraphDotOpts are the options for GraphDot.
+type GraphDotOpts struct {
+	dag.DotOpts
+
+	// Filter is a comma-separated list of patterns, as for path.Match, that
+	// the names of the nodes to draw must match. Patterns that start with
+	// "!" exclude the nodes that match them instead. Edges are kept through
+	// the nodes that are left out, so the order of the remaining nodes is
+	// still shown.
+	Filter string
+}
 
 // GraphDot returns the dot formatting of a visual representation of
 // the given Terraform graph.
-func GraphDot(g *Graph, opts *dag.DotOpts) (string, error) {
-	return string(g.Dot(opts)), nil
+//
+// If opts.MaxDepth isn't negative, every module nested deeper than it is
+// collapsed into a single node, with the edges of everything in it, so
+// that large configurations can still be laid out. A depth of 0 collapses
+// every module.
+func GraphDot(g *Graph, opts *GraphDotOpts) (string, error) {
+	if opts == nil {
+		return string(g.Dot(nil)), nil
+	}
+
+	filter, err := newGraphDotFilter(opts.Filter)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.MaxDepth >= 0 {
+		g = graphDotCollapse(g, opts.MaxDepth)
+	}
+	if filter != nil {
+		g = graphDotFilter(g, filter)
+	}
+
+	return string(g.Dot(&opts.DotOpts)), nil
+}
+
+// graphDotCollapse returns a copy of g where the nodes of the modules
+// deeper than depth are replaced by a node for their module at that depth.
+func graphDotCollapse(g *Graph, depth int) *Graph {
+	modules := make(map[string]dag.Vertex)
+	collapsed := make(map[dag.Vertex]dag.Vertex)
+	for _, v := range g.Vertices() {
+		collapsed[v] = v
+
+		sn, ok := v.(GraphNodeSubPath)
+		if !ok || len(sn.Path())-1 <= depth {
+			continue
+		}
+
+		p := sn.Path()[:depth+2]
+		key := modulePrefixStr(p)
+		if _, ok := modules[key]; !ok {
+			modules[key] = &graphNodeCollapsedModule{PathValue: p}
+		}
+		collapsed[v] = modules[key]
+	}
+
+	result := &Graph{Path: g.Path}
+	for _, v := range g.Vertices() {
+		result.Add(collapsed[v])
+	}
+	for _, e := range g.Edges() {
+		source, target := collapsed[e.Source()], collapsed[e.Target()]
+		if source != target {
+			result.Connect(dag.BasicEdge(source, target))
+		}
+	}
+
+	return result
+}
+
+// graphDotFilter returns a copy of g with only the nodes that match the
+// filter, and an edge between two of them wherever one depended on the
+// other in g, directly or through nodes that were left out.
+func graphDotFilter(g *Graph, filter *graphDotFilterPatterns) *Graph {
+	result := &Graph{Path: g.Path}
+	for _, v := range g.Vertices() {
+		if filter.Match(dag.VertexName(v)) {
+			result.Add(v)
+		}
+	}
+
+	for _, v := range result.Vertices() {
+		seen := make(map[dag.Vertex]bool)
+		next := []dag.Vertex{v}
+		for len(next) > 0 {
+			current := next[len(next)-1]
+			next = next[:len(next)-1]
+
+			for _, dep := range g.DownEdges(current).List() {
+				if seen[dep] {
+					continue
+				}
+				seen[dep] = true
+
+				if result.HasVertex(dep) {
+					result.Connect(dag.BasicEdge(v, dep))
+					continue
+				}
+				next = append(next, dep)
+			}
+		}
+	}
+
+	return result
+}
+
+// graphDotFilterPatterns are the parsed patterns of GraphDotOpts.Filter.
+type graphDotFilterPatterns struct {
+	include []string
+	exclude []string
+}
+
+func newGraphDotFilter(s string) (*graphDotFilterPatterns, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var result graphDotFilterPatterns
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		list := &result.include
+		if strings.HasPrefix(p, "!") {
+			p = p[1:]
+			list = &result.exclude
+		}
+
+		// Check the pattern now, since Match only fails for a bad
+		// pattern once it gets to the bad part.
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid graph filter pattern %q: %s", p, err)
+		}
+		*list = append(*list, p)
+	}
+
+	return &result, nil
+}
+
+// Match returns true if the name of a node matches an included pattern, or
+// there are none, and doesn't match an excluded pattern.
+func (f *graphDotFilterPatterns) Match(name string) bool {
+	match := len(f.include) == 0
+	for _, p := range f.include {
+		if ok, _ := path.Match(p, name); ok {
+			match = true
+			break
+		}
+	}
+	if !match {
+		return false
+	}
+
+	for _, p := range f.exclude {
+		if ok, _ := path.Match(p, name); ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// graphNodeCollapsedModule stands for every node of a module, and the
+// modules in it, when they're collapsed in the dot output.
+type graphNodeCollapsedModule struct {
+	PathValue []string
+}
+
+func (n *graphNodeCollapsedModule) Name() string {
+	return modulePrefixStr(n.PathValue)
+}
+
+// GraphNodeDotter impl.
+func (n *graphNodeCollapsedModule) DotNode(name string, opts *dag.DotOpts) *dag.DotNode {
+	return &dag.DotNode{
+		Name: name,
+		Attrs: map[string]string{
+			"label": n.Name(),
+			"shape": "folder",
+		},
+	}
 }
@@ -311,3 +311,123 @@ func (node *testDrawableSubgraph) DotNode(n string, opts *dag.DotOpts) *dag.DotN
 func (node *testDrawableSubgraph) DependentOn() []string {
 	return node.DependentOnMock
 }
+
+func TestGraphDot_moduleDepth(t *testing.T) {
+	var g Graph
+	root := g.Add(&testDrawablePath{"aws_instance.web", []string{"root"}})
+	a := g.Add(&testDrawablePath{"module.a.aws_instance.foo", []string{"root", "a"}})
+	b := g.Add(&testDrawablePath{"module.a.module.b.aws_instance.bar", []string{"root", "a", "b"}})
+	c := g.Add(&testDrawablePath{"module.a.module.b.aws_instance.baz", []string{"root", "a", "b"}})
+	g.Connect(dag.BasicEdge(root, a))
+	g.Connect(dag.BasicEdge(a, b))
+	g.Connect(dag.BasicEdge(b, c))
+
+	actual, err := GraphDot(&g, &GraphDotOpts{DotOpts: dag.DotOpts{MaxDepth: 1}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := strings.TrimSpace(testGraphDotModuleDepthStr) + "\n"
+	if actual != expected {
+		t.Fatalf("bad:\n\n%s", actual)
+	}
+
+	actual, err = GraphDot(&g, &GraphDotOpts{DotOpts: dag.DotOpts{MaxDepth: 0}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected = strings.TrimSpace(testGraphDotModuleDepthZeroStr) + "\n"
+	if actual != expected {
+		t.Fatalf("bad:\n\n%s", actual)
+	}
+}
+
+func TestGraphDot_filter(t *testing.T) {
+	var g Graph
+	web := g.Add(&testDrawable{VertexName: "aws_instance.web"})
+	lb := g.Add(&testDrawable{VertexName: "aws_elb.lb"})
+	sg := g.Add(&testDrawable{VertexName: "aws_security_group.sg"})
+	db := g.Add(&testDrawable{VertexName: "aws_instance.db"})
+	provider := g.Add(&testDrawable{VertexName: "provider.aws"})
+	g.Connect(dag.BasicEdge(lb, web))
+	g.Connect(dag.BasicEdge(web, sg))
+	g.Connect(dag.BasicEdge(sg, db))
+	for _, v := range []dag.Vertex{web, lb, sg, db} {
+		g.Connect(dag.BasicEdge(v, provider))
+	}
+
+	actual, err := GraphDot(&g, &GraphDotOpts{
+		DotOpts: dag.DotOpts{MaxDepth: -1},
+		Filter:  "aws_instance.*, aws_elb.*, !aws_instance.web",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := strings.TrimSpace(testGraphDotFilterStr) + "\n"
+	if actual != expected {
+		t.Fatalf("bad:\n\n%s", actual)
+	}
+}
+
+func TestGraphDot_filterInvalid(t *testing.T) {
+	var g Graph
+	_, err := GraphDot(&g, &GraphDotOpts{Filter: "aws_instance.[web"})
+	if err == nil {
+		t.Fatal("should error")
+	}
+}
+
+type testDrawablePath struct {
+	VertexName string
+	PathValue  []string
+}
+
+func (node *testDrawablePath) Name() string {
+	return node.VertexName
+}
+func (node *testDrawablePath) Path() []string {
+	return node.PathValue
+}
+func (node *testDrawablePath) DotNode(n string, opts *dag.DotOpts) *dag.DotNode {
+	return &dag.DotNode{Name: n, Attrs: map[string]string{}}
+}
+
+const testGraphDotModuleDepthStr = `
+digraph {
+	compound = "true"
+	newrank = "true"
+	subgraph "root" {
+		"[root] aws_instance.web"
+		"[root] module.a.aws_instance.foo"
+		"[root] module.a.module.b" [label = "module.a.module.b", shape = "folder"]
+		"[root] aws_instance.web" -> "[root] module.a.aws_instance.foo"
+		"[root] module.a.aws_instance.foo" -> "[root] module.a.module.b"
+	}
+}
+`
+
+const testGraphDotModuleDepthZeroStr = `
+digraph {
+	compound = "true"
+	newrank = "true"
+	subgraph "root" {
+		"[root] aws_instance.web"
+		"[root] module.a" [label = "module.a", shape = "folder"]
+		"[root] aws_instance.web" -> "[root] module.a"
+	}
+}
+`
+
+const testGraphDotFilterStr = `
+digraph {
+	compound = "true"
+	newrank = "true"
+	subgraph "root" {
+		"[root] aws_elb.lb"
+		"[root] aws_instance.db"
+		"[root] aws_elb.lb" -> "[root] aws_instance.db"
+	}
+}
+`
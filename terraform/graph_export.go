@@ -0,0 +1,243 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/dag"
+)
+
+// GraphExportVersion is the version of the format of GraphExport.
+const GraphExportVersion = 1
+
+// The types of the nodes of a GraphExport.
+const (
+	GraphExportResource         = "resource"
+	GraphExportProvider         = "provider"
+	GraphExportProviderClose    = "provider_close"
+	GraphExportProvisioner      = "provisioner"
+	GraphExportProvisionerClose = "provisioner_close"
+	GraphExportOutput           = "output"
+	GraphExportVariable         = "variable"
+	GraphExportOther            = "other"
+)
+
+// GraphExport is the apply graph of a plan, built and validated as it is
+// for an apply, in a documented JSON format for tools outside of Terraform,
+// such as custom schedulers and visualizations. It's written by
+// "terraform plan -graph-out".
+type GraphExport struct {
+	Version          int    `json:"version"`
+	TerraformVersion string `json:"terraform_version"`
+
+	// Destroy is true if the graph is for a destroy.
+	Destroy bool `json:"destroy"`
+
+	// Nodes are the nodes of the graph, sorted by ID.
+	Nodes []*GraphExportNode `json:"nodes"`
+
+	// Edges are the dependencies between the nodes, sorted by From and
+	// then To.
+	Edges []*GraphExportEdge `json:"edges"`
+}
+
+// GraphExportNode is a node of a GraphExport, with a description of what
+// it does when the graph is walked.
+type GraphExportNode struct {
+	// ID is the name of the node, which is unique within the graph.
+	ID string `json:"id"`
+
+	// Type is what the node is, such as GraphExportResource.
+	Type string `json:"type"`
+
+	// Address is the address of the resource, or the name of the
+	// provider or provisioner, that the node is for. It's empty for other
+	// types of nodes.
+	Address string `json:"address,omitempty"`
+
+	// Action is the change that a resource node makes: "create", "read",
+	// "update", "replace" or "destroy". It's empty for other types of
+	// nodes.
+	Action string `json:"action,omitempty"`
+
+	// Priority is the scheduling priority of the node, from the priority
+	// lifecycle settings. Nodes with a higher priority are started first
+	// when more are ready than the parallelism allows.
+	Priority int `json:"priority,omitempty"`
+}
+
+// GraphExportEdge is a dependency between two nodes of a GraphExport: the
+// node From must be done before the node To starts.
+type GraphExportEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ApplyGraph returns the graph that Apply walks for the diff of the
+// context, as set by Plan, in its exported form.
+func (c *Context) ApplyGraph() (*GraphExport, error) {
+	g, err := c.Graph(GraphTypeApply, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return exportGraph(g, c.diff, c.destroy)
+}
+
+// WriteGraphExport writes g to dst as JSON.
+func WriteGraphExport(g *GraphExport, dst io.Writer) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = dst.Write(append(data, '\n'))
+	return err
+}
+
+// exportGraph returns the exported form of the apply graph g of diff. The
+// root node is left out, since it does nothing and is always walked last.
+func exportGraph(g *Graph, diff *Diff, destroy bool) (*GraphExport, error) {
+	ids, err := graphNodeIDs(g)
+	if err != nil {
+		return nil, err
+	}
+
+	priorities := schedulePriorities(g)
+	result := &GraphExport{
+		Version:          GraphExportVersion,
+		TerraformVersion: VersionString(),
+		Destroy:          destroy,
+		Nodes:            make([]*GraphExportNode, 0, len(ids)),
+		Edges:            make([]*GraphExportEdge, 0),
+	}
+	for id, v := range ids {
+		n := exportGraphNode(v, diff)
+		n.ID = id
+		n.Priority = priorities[v]
+		result.Nodes = append(result.Nodes, n)
+	}
+
+	for _, e := range g.Edges() {
+		// The source of an edge depends on its target
+		if _, ok := e.Source().(graphNodeRoot); ok {
+			continue
+		}
+		result.Edges = append(result.Edges, &GraphExportEdge{
+			From: dag.VertexName(e.Target()),
+			To:   dag.VertexName(e.Source()),
+		})
+	}
+
+	sort.Slice(result.Nodes, func(i, j int) bool {
+		return result.Nodes[i].ID < result.Nodes[j].ID
+	})
+	sort.Slice(result.Edges, func(i, j int) bool {
+		a, b := result.Edges[i], result.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+
+	return result, nil
+}
+
+// graphNodeIDs returns the nodes of g, other than the root node, by their
+// names, which are the IDs they're exported and ordered with.
+func graphNodeIDs(g *Graph) (map[string]dag.Vertex, error) {
+	result := make(map[string]dag.Vertex)
+	for _, v := range g.Vertices() {
+		if _, ok := v.(graphNodeRoot); ok {
+			continue
+		}
+
+		id := dag.VertexName(v)
+		if _, ok := result[id]; ok {
+			return nil, fmt.Errorf("the graph has more than one node named %q", id)
+		}
+		result[id] = v
+	}
+
+	return result, nil
+}
+
+// exportGraphNode returns the description of v, which is a node of the
+// apply graph of diff, without its ID.
+func exportGraphNode(v dag.Vertex, diff *Diff) *GraphExportNode {
+	if n, ok := v.(GraphNodeDestroyer); ok {
+		if addr := n.DestroyAddr(); addr != nil {
+			return &GraphExportNode{
+				Type:    GraphExportResource,
+				Address: addr.String(),
+				Action:  "destroy",
+			}
+		}
+	}
+
+	switch n := v.(type) {
+	case GraphNodeResource:
+		addr := n.ResourceAddr()
+		return &GraphExportNode{
+			Type:    GraphExportResource,
+			Address: addr.String(),
+			Action:  exportResourceAction(addr, diff),
+		}
+	case GraphNodeCloseProvider:
+		return &GraphExportNode{
+			Type:    GraphExportProviderClose,
+			Address: n.CloseProviderName(),
+		}
+	case GraphNodeProvider:
+		return &GraphExportNode{
+			Type:    GraphExportProvider,
+			Address: n.ProviderName(),
+		}
+	case GraphNodeCloseProvisioner:
+		return &GraphExportNode{
+			Type:    GraphExportProvisionerClose,
+			Address: n.CloseProvisionerName(),
+		}
+	case GraphNodeProvisioner:
+		return &GraphExportNode{
+			Type:    GraphExportProvisioner,
+			Address: n.ProvisionerName(),
+		}
+	case *NodeApplyableOutput, *NodeOutputOrphan:
+		return &GraphExportNode{Type: GraphExportOutput}
+	case *NodeRootVariable, *NodeApplyableModuleVariable:
+		return &GraphExportNode{Type: GraphExportVariable}
+	}
+
+	return &GraphExportNode{Type: GraphExportOther}
+}
+
+// exportResourceAction returns the change that diff has for the resource
+// with the given address.
+func exportResourceAction(addr *ResourceAddress, diff *Diff) string {
+	var rd *InstanceDiff
+	if diff != nil {
+		if md := diff.ModuleByPath(append([]string{"root"}, addr.Path...)); md != nil {
+			rd = md.Resources[addr.stateId()]
+		}
+	}
+
+	switch rd.ChangeType() {
+	case DiffCreate:
+		if addr.Mode == config.DataResourceMode {
+			return "read"
+		}
+		return "create"
+	case DiffDestroyCreate:
+		return "replace"
+	case DiffDestroy:
+		return "destroy"
+	case DiffUpdate:
+		return "update"
+	}
+
+	return ""
+}
@@ -0,0 +1,146 @@
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestContextApplyGraph(t *testing.T) {
+	m := testModule(t, "apply-good")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	g, err := ctx.ApplyGraph()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if g.Version != GraphExportVersion || g.Destroy {
+		t.Fatalf("bad: %#v", g)
+	}
+
+	nodes := make(map[string]*GraphExportNode)
+	for _, n := range g.Nodes {
+		nodes[n.ID] = n
+	}
+	if _, ok := nodes["root"]; ok {
+		t.Fatal("the root node shouldn't be exported")
+	}
+
+	expected := &GraphExportNode{
+		ID:      "aws_instance.foo",
+		Type:    GraphExportResource,
+		Address: "aws_instance.foo",
+		Action:  "create",
+	}
+	if actual := nodes["aws_instance.foo"]; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	expected = &GraphExportNode{
+		ID:      "provider.aws",
+		Type:    GraphExportProvider,
+		Address: "aws",
+	}
+	if actual := nodes["provider.aws"]; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Resources depend on their provider
+	found := false
+	for _, e := range g.Edges {
+		if _, ok := nodes[e.From]; !ok {
+			t.Fatalf("unknown node: %#v", e)
+		}
+		if _, ok := nodes[e.To]; !ok {
+			t.Fatalf("unknown node: %#v", e)
+		}
+		if e.From == "provider.aws" && e.To == "aws_instance.foo" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("missing provider edge: %#v", g.Edges)
+	}
+
+	// The JSON form is documented, so its keys must not change
+	var buf bytes.Buffer
+	if err := WriteGraphExport(g, &buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var keys []string
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"destroy", "edges", "nodes", "terraform_version", "version"}) {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
+
+func TestContextApplyGraph_destroy(t *testing.T) {
+	m := testModule(t, "apply-destroy")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		State: &State{
+			Modules: []*ModuleState{
+				&ModuleState{
+					Path: rootModulePath,
+					Resources: map[string]*ResourceState{
+						"aws_instance.foo": &ResourceState{
+							Type:    "aws_instance",
+							Primary: &InstanceState{ID: "foo"},
+						},
+					},
+				},
+			},
+		},
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+		Destroy: true,
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	g, err := ctx.ApplyGraph()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !g.Destroy {
+		t.Fatal("graph should be for a destroy")
+	}
+
+	for _, n := range g.Nodes {
+		if n.ID == "aws_instance.foo (destroy)" {
+			if n.Action != "destroy" || n.Address != "aws_instance.foo" {
+				t.Fatalf("bad: %#v", n)
+			}
+			return
+		}
+	}
+	t.Fatalf("missing destroy node: %#v", g.Nodes)
+}
@@ -0,0 +1,60 @@
+package terraform
+
+import (
+	"github.com/hashicorp/terraform/dag"
+)
+
+// GraphNodeSchedulePriority is implemented by nodes that can be given a
+// scheduling priority with the priority lifecycle setting.
+type GraphNodeSchedulePriority interface {
+	// SchedulePriority is the priority of the node. When more nodes are
+	// ready to be evaluated than the parallelism allows, those with a
+	// higher priority are started first.
+	SchedulePriority() int
+}
+
+// schedulePriorities returns the priorities that the nodes of the graph are
+// scheduled with. Nodes that aren't in the result have a priority of 0.
+//
+// A positive priority is passed on to everything that the node depends on,
+// since the node can't start until they are done: a long-running creation
+// is only started early if the resources it waits for are too. Priorities
+// never add edges, so they only choose between nodes that are ready at the
+// same time and never serialize the walk.
+func schedulePriorities(g *Graph) map[dag.Vertex]int {
+	result := make(map[dag.Vertex]int)
+	var prioritized []dag.Vertex
+	for _, v := range g.Vertices() {
+		pn, ok := v.(GraphNodeSchedulePriority)
+		if !ok {
+			continue
+		}
+
+		p := pn.SchedulePriority()
+		if p == 0 {
+			continue
+		}
+
+		result[v] = p
+		if p > 0 {
+			prioritized = append(prioritized, v)
+		}
+	}
+
+	for _, v := range prioritized {
+		p := result[v]
+		deps, err := g.Ancestors(v)
+		if err != nil {
+			// Can't happen, since v comes from the graph
+			continue
+		}
+
+		for _, dep := range deps.List() {
+			if current, ok := result[dep]; !ok || p > current {
+				result[dep] = p
+			}
+		}
+	}
+
+	return result
+}
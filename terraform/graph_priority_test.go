@@ -0,0 +1,55 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+func TestSchedulePriorities(t *testing.T) {
+	var g Graph
+	a := g.Add(&graphNodeSchedulePriorityTest{NameValue: "A"})
+	b := g.Add(&graphNodeSchedulePriorityTest{NameValue: "B", Priority: 10})
+	c := g.Add(&graphNodeSchedulePriorityTest{NameValue: "C", Priority: -1})
+	d := g.Add(&graphNodeSchedulePriorityTest{NameValue: "D", Priority: 5})
+	e := g.Add(&graphNodeSchedulePriorityTest{NameValue: "E", Priority: -1})
+	g.Add(&graphNodeSchedulePriorityTest{NameValue: "F"})
+
+	// B depends on C, which depends on A. D depends on A too.
+	g.Connect(dag.BasicEdge(b, c))
+	g.Connect(dag.BasicEdge(c, a))
+	g.Connect(dag.BasicEdge(d, a))
+
+	// E depends on D, but its negative priority isn't passed on.
+	g.Connect(dag.BasicEdge(e, d))
+
+	actual := make(map[string]int)
+	for v, p := range schedulePriorities(&g) {
+		actual[dag.VertexName(v)] = p
+	}
+
+	expected := map[string]int{
+		"A": 10,
+		"B": 10,
+		"C": 10,
+		"D": 5,
+		"E": -1,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+type graphNodeSchedulePriorityTest struct {
+	NameValue string
+	Priority  int
+}
+
+func (n *graphNodeSchedulePriorityTest) Name() string {
+	return n.NameValue
+}
+
+func (n *graphNodeSchedulePriorityTest) SchedulePriority() int {
+	return n.Priority
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/terraform/dag"
+	"github.com/hashicorp/terraform/helper/warnings"
 )
 
 // ContextGraphWalker is the GraphWalker implementation used with the
@@ -20,6 +22,10 @@ type ContextGraphWalker struct {
 	Operation   walkOperation
 	StopContext context.Context
 
+	// priorities are the scheduling priorities of the nodes of the graph,
+	// from schedulePriorities.
+	priorities map[dag.Vertex]int
+
 	// Outputs, do not set these. Do not read these while the graph
 	// is being walked.
 	ValidationWarnings []string
@@ -33,9 +39,13 @@ type ContextGraphWalker struct {
 	interpolaterVarLock sync.Mutex
 	providerCache       map[string]ResourceProvider
 	providerConfigCache map[string]*ResourceConfig
+	providerCredGens    map[string]uint64
 	providerLock        sync.Mutex
 	provisionerCache    map[string]ResourceProvisioner
 	provisionerLock     sync.Mutex
+	refreshBatchers     map[ResourceProvider]*refreshBatcher
+	refreshBatchLock    sync.Mutex
+	sensitiveValues     *sensitiveValues
 }
 
 func (w *ContextGraphWalker) EnterPath(path []string) EvalContext {
@@ -75,13 +85,20 @@ func (w *ContextGraphWalker) EnterPath(path []string) EvalContext {
 		ProviderCache:       w.providerCache,
 		ProviderConfigCache: w.providerConfigCache,
 		ProviderInputConfig: w.Context.providerInputConfig,
+		ProviderCredentials: w.Context.providerCredentials,
+		ProviderCredGens:    w.providerCredGens,
 		ProviderLock:        &w.providerLock,
 		ProvisionerCache:    w.provisionerCache,
 		ProvisionerLock:     &w.provisionerLock,
+		RefreshBatchers:     w.refreshBatchers,
+		RefreshBatchLock:    &w.refreshBatchLock,
+		RetryPolicy:         w.Context.providerRetry,
+		ParallelismTuner:    w.Context.parallelismTuner,
 		DiffValue:           w.Context.diff,
 		DiffLock:            &w.Context.diffLock,
 		StateValue:          w.Context.state,
 		StateLock:           &w.Context.stateLock,
+		SensitiveValues:     w.sensitiveValues,
 		Interpolater: &Interpolater{
 			Operation:          w.Operation,
 			Meta:               w.Context.meta,
@@ -90,6 +107,7 @@ func (w *ContextGraphWalker) EnterPath(path []string) EvalContext {
 			StateLock:          &w.Context.stateLock,
 			VariableValues:     variables,
 			VariableValuesLock: &w.interpolaterVarLock,
+			GlobalValues:       w.Context.variables,
 		},
 		InterpolaterVars:    w.interpolaterVars,
 		InterpolaterVarLock: &w.interpolaterVarLock,
@@ -104,7 +122,7 @@ func (w *ContextGraphWalker) EnterEvalTree(v dag.Vertex, n EvalNode) EvalNode {
 		w.Operation, dag.VertexName(v))
 
 	// Acquire a lock on the semaphore
-	w.Context.parallelSem.Acquire()
+	w.sem().Acquire(w.priority(v))
 
 	// We want to filter the evaluation tree to only include operations
 	// that belong in this operation.
@@ -117,7 +135,7 @@ func (w *ContextGraphWalker) ExitEvalTree(
 		w.Operation, dag.VertexName(v))
 
 	// Release the semaphore
-	w.Context.parallelSem.Release()
+	w.sem().Release()
 
 	if err == nil {
 		return nil
@@ -135,9 +153,14 @@ func (w *ContextGraphWalker) ExitEvalTree(
 	}
 
 	for _, msg := range verr.Warnings {
+		code := warnings.CodeValidation
+		if strings.Contains(msg, "[DEPRECATED]") {
+			code = warnings.CodeDeprecatedAttribute
+		}
+
 		w.ValidationWarnings = append(
 			w.ValidationWarnings,
-			fmt.Sprintf("%s: %s", dag.VertexName(v), msg))
+			warnings.Format(code, fmt.Sprintf("%s: %s", dag.VertexName(v), msg)))
 	}
 	for _, e := range verr.Errors {
 		w.ValidationErrors = append(
@@ -148,10 +171,37 @@ func (w *ContextGraphWalker) ExitEvalTree(
 	return nil
 }
 
+// sem returns the semaphore that limits the parallelism of the walk.
+// Refreshes only read, so they have their own, usually larger, limit.
+func (w *ContextGraphWalker) sem() *PrioritySemaphore {
+	if w.Operation == walkRefresh {
+		return w.Context.refreshSem
+	}
+
+	return w.Context.parallelSem
+}
+
+// priority returns the scheduling priority of v. The nodes of subgraphs
+// from dynamic expansion aren't in the priorities of the graph, so they
+// only have their own.
+func (w *ContextGraphWalker) priority(v dag.Vertex) int {
+	if p, ok := w.priorities[v]; ok {
+		return p
+	}
+	if pn, ok := v.(GraphNodeSchedulePriority); ok {
+		return pn.SchedulePriority()
+	}
+
+	return 0
+}
+
 func (w *ContextGraphWalker) init() {
 	w.contexts = make(map[string]*BuiltinEvalContext, 5)
 	w.providerCache = make(map[string]ResourceProvider, 5)
 	w.providerConfigCache = make(map[string]*ResourceConfig, 5)
+	w.providerCredGens = make(map[string]uint64, 5)
 	w.provisionerCache = make(map[string]ResourceProvisioner, 5)
+	w.refreshBatchers = make(map[ResourceProvider]*refreshBatcher, 5)
 	w.interpolaterVars = make(map[string]map[string]interface{}, 5)
+	w.sensitiveValues = newSensitiveValues(w.Context.module)
 }
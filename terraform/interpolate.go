@@ -3,7 +3,7 @@ package terraform
 import (
 	"fmt"
 	"log"
-	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,6 +31,10 @@ type Interpolater struct {
 	StateLock          *sync.RWMutex
 	VariableValues     map[string]interface{}
 	VariableValuesLock *sync.Mutex
+
+	// GlobalValues are the values of the root module variables, which
+	// can be referenced from any module as "global.NAME".
+	GlobalValues map[string]interface{}
 }
 
 // InterpolationScope is the current scope of execution. This is required
@@ -73,11 +77,27 @@ func (i *Interpolater) Values(
 		}
 	}
 
+	// The path variables are always set, even if they aren't referenced,
+	// since file() uses them to resolve paths relative to the module.
+	if i.Module != nil {
+		vs, err := i.pathValues(scope)
+		if err != nil {
+			return nil, err
+		}
+		for n, v := range vs {
+			result[n] = v
+		}
+	}
+
 	for n, rawV := range vars {
 		var err error
 		switch v := rawV.(type) {
 		case *config.CountVariable:
 			err = i.valueCountVar(scope, n, v, result)
+		case *config.EachVariable:
+			err = i.valueEachVar(scope, n, v, result)
+		case *config.GlobalVariable:
+			err = i.valueGlobalVar(scope, n, v, result)
 		case *config.ModuleVariable:
 			err = i.valueModuleVar(scope, n, v, result)
 		case *config.PathVariable:
@@ -124,6 +144,49 @@ func (i *Interpolater) valueCountVar(
 	}
 }
 
+func (i *Interpolater) valueEachVar(
+	scope *InterpolationScope,
+	n string,
+	v *config.EachVariable,
+	result map[string]ast.Variable) error {
+	// During validation the for_each may not be known, in which case the
+	// resource isn't expanded and each.* is unknown.
+	if i.Operation == walkValidate && scope.Resource != nil && scope.Resource.EachKey == "" {
+		result[n] = unknownVariable()
+		return nil
+	}
+
+	if scope.Resource == nil || scope.Resource.EachKey == "" {
+		return fmt.Errorf(
+			"%s: each variables are only valid within resources that set for_each", n)
+	}
+
+	switch v.Type {
+	case config.EachValueKey:
+		result[n] = ast.Variable{
+			Value: scope.Resource.EachKey,
+			Type:  ast.TypeString,
+		}
+		return nil
+	case config.EachValueValue:
+		// The value may not be known yet if the for_each couldn't be
+		// interpolated for this walk, such as when destroying an orphan.
+		if scope.Resource.EachValue == nil {
+			result[n] = unknownVariable()
+			return nil
+		}
+
+		variable, err := hil.InterfaceToVariable(scope.Resource.EachValue)
+		if err != nil {
+			return fmt.Errorf("%s: %s", n, err)
+		}
+		result[n] = variable
+		return nil
+	default:
+		return fmt.Errorf("%s: unknown each type: %#v", n, v.Type)
+	}
+}
+
 func unknownVariable() ast.Variable {
 	return ast.Variable{
 		Type:  ast.TypeUnknown,
@@ -135,6 +198,30 @@ func unknownValue() string {
 	return hil.UnknownValue
 }
 
+func (i *Interpolater) valueGlobalVar(
+	scope *InterpolationScope,
+	n string,
+	v *config.GlobalVariable,
+	result map[string]ast.Variable) error {
+	val, ok := i.GlobalValues[v.Name]
+	if !ok {
+		if i.Operation == walkValidate {
+			result[n] = unknownVariable()
+			return nil
+		}
+
+		return fmt.Errorf("%s: root module variable %q is not set", n, v.Name)
+	}
+
+	varValue, err := hil.InterfaceToVariable(val)
+	if err != nil {
+		return fmt.Errorf("cannot convert %s value %q to an ast.Variable for interpolation: %s",
+			v.Name, val, err)
+	}
+	result[n] = varValue
+	return nil
+}
+
 func (i *Interpolater) valueModuleVar(
 	scope *InterpolationScope,
 	n string,
@@ -198,36 +285,53 @@ func (i *Interpolater) valuePathVar(
 	v *config.PathVariable,
 	result map[string]ast.Variable) error {
 	switch v.Type {
-	case config.PathValueCwd:
-		wd, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf(
-				"Couldn't get cwd for var %s: %s",
-				v.FullKey(), err)
-		}
-
-		result[n] = ast.Variable{
-			Value: wd,
-			Type:  ast.TypeString,
-		}
-	case config.PathValueModule:
-		if t := i.Module.Child(scope.Path[1:]); t != nil {
-			result[n] = ast.Variable{
-				Value: t.Config().Dir,
-				Type:  ast.TypeString,
-			}
-		}
-	case config.PathValueRoot:
-		result[n] = ast.Variable{
-			Value: i.Module.Config().Dir,
-			Type:  ast.TypeString,
-		}
+	case config.PathValueCwd, config.PathValueModule, config.PathValueRoot:
 	default:
 		return fmt.Errorf("%s: unknown path type: %#v", n, v.Type)
 	}
 
+	vs, err := i.pathValues(scope)
+	if err != nil {
+		return err
+	}
+	if value, ok := vs[v.FullKey()]; ok {
+		result[n] = value
+	}
+
 	return nil
+}
 
+// pathValues returns the values of the path variables for the module of
+// the given scope. Only path.cwd is set if there are no modules, and
+// path.module is missing if the module of the scope isn't loaded.
+func (i *Interpolater) pathValues(
+	scope *InterpolationScope) (map[string]ast.Variable, error) {
+	var root, dir string
+	var t *module.Tree
+	if i.Module != nil {
+		root = i.Module.Config().Dir
+
+		t = i.Module
+		if len(scope.Path) > 1 {
+			t = i.Module.Child(scope.Path[1:])
+		}
+		if t != nil {
+			dir = t.Config().Dir
+		}
+	}
+
+	vs, err := config.PathValues(dir, root)
+	if err != nil {
+		return nil, err
+	}
+	if i.Module == nil {
+		delete(vs, config.PathRootVariable)
+	}
+	if t == nil {
+		delete(vs, config.PathModuleVariable)
+	}
+
+	return vs, nil
 }
 
 func (i *Interpolater) valueResourceVar(
@@ -317,9 +421,10 @@ func (i *Interpolater) valueTerraformVar(
 	n string,
 	v *config.TerraformVariable,
 	result map[string]ast.Variable) error {
-	if v.Field != "env" {
+	// "workspace" is the same as "env", by the newer name of environments.
+	if v.Field != "env" && v.Field != "workspace" {
 		return fmt.Errorf(
-			"%s: only supported key for 'terraform.X' interpolations is 'env'", n)
+			"%s: only supported keys for 'terraform.X' interpolations are 'env' and 'workspace'", n)
 	}
 
 	if i.Meta == nil {
@@ -469,6 +574,12 @@ func (i *Interpolater) computeResourceVariable(
 		return &variable, err
 	}
 
+	// Provisioners only record their outputs when they run, so an output
+	// that isn't in the state yet is unknown until apply.
+	if i.Operation == walkPlan && strings.HasPrefix(v.Field+".", ProvisionerOutputPrefix) {
+		return &unknownVariable, nil
+	}
+
 	// At apply time, we can't do the "maybe has it" check below
 	// that we need for plans since parent elements might be computed.
 	// Therefore, it is an error and we're missing the key.
@@ -554,6 +665,12 @@ func (i *Interpolater) computeResourceMultiVariable(
 		return nil, err
 	}
 
+	// Resources using for_each are keyed rather than indexed, so their
+	// instances are gathered from the state in key order.
+	if cr != nil && cr.RawForEach != nil {
+		return i.computeResourceForEachVariable(module, cr, v)
+	}
+
 	// Get the keys for all the resources that are created for this resource
 	countMax, err := i.resourceCountMax(module, cr, v)
 	if err != nil {
@@ -639,6 +756,80 @@ func (i *Interpolater) computeResourceMultiVariable(
 	return &variable, err
 }
 
+// computeResourceForEachVariable computes a splat variable, such as
+// "aws_instance.foo.*.id", that refers to a resource using for_each. The
+// values are ordered by instance key.
+func (i *Interpolater) computeResourceForEachVariable(
+	module *ModuleState,
+	cr *config.Resource,
+	v *config.ResourceVariable) (*ast.Variable, error) {
+	unknownVariable := unknownVariable()
+
+	// If we have no module in the state yet, return unknown
+	if module == nil || len(module.Resources) == 0 {
+		return &unknownVariable, nil
+	}
+
+	// If we're NOT applying, the state may still contain instances whose
+	// keys were removed from the for_each, so we only consider the keys
+	// that are currently configured if the for_each is known. During apply
+	// we depend on the state, just like resourceCountMax does for counts.
+	var forEach map[string]interface{}
+	if i.Operation != walkApply && len(cr.RawForEach.UnknownKeys()) == 0 {
+		forEach, _ = cr.ForEach()
+	}
+
+	prefix := v.ResourceId() + "["
+	var keys []string
+	for k := range module.Resources {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+
+		if forEach != nil {
+			key, err := ParseResourceStateKey(k)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := forEach[key.Key]; !ok {
+				continue
+			}
+		}
+
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	values := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		r := module.Resources[k]
+		if r.Primary == nil {
+			continue
+		}
+
+		if singleAttr, ok := r.Primary.Attributes[v.Field]; ok {
+			values = append(values, singleAttr)
+			continue
+		}
+
+		// computed list or map attribute
+		_, isList := r.Primary.Attributes[v.Field+".#"]
+		_, isMap := r.Primary.Attributes[v.Field+".%"]
+		if !(isList || isMap) {
+			continue
+		}
+		multiAttr, err := i.interpolateComplexTypeAttribute(v.Field, r.Primary.Attributes)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, multiAttr)
+	}
+
+	variable, err := hil.InterfaceToVariable(values)
+	return &variable, err
+}
+
 func (i *Interpolater) interpolateComplexTypeAttribute(
 	resourceID string,
 	attributes map[string]string) (ast.Variable, error) {
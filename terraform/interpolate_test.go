@@ -1014,6 +1014,21 @@ func TestInterpolater_terraformEnv(t *testing.T) {
 	})
 }
 
+func TestInterpolater_terraformWorkspace(t *testing.T) {
+	i := &Interpolater{
+		Meta: &ContextMeta{Env: "foo"},
+	}
+
+	scope := &InterpolationScope{
+		Path: rootModulePath,
+	}
+
+	testInterpolate(t, i, scope, "terraform.workspace", ast.Variable{
+		Value: "foo",
+		Type:  ast.TypeString,
+	})
+}
+
 func TestInterpolater_terraformInvalid(t *testing.T) {
 	i := &Interpolater{
 		Meta: &ContextMeta{Env: "foo"},
@@ -1042,6 +1057,11 @@ func testInterpolate(
 		t.Fatalf("err: %s", err)
 	}
 
+	// The path variables are always set, whether or not they're referenced
+	delete(actual, config.PathCwdVariable)
+	delete(actual, config.PathModuleVariable)
+	delete(actual, config.PathRootVariable)
+
 	expected := map[string]ast.Variable{
 		"foo": expectedVar,
 	}
@@ -0,0 +1,12 @@
+package terraform
+
+import "github.com/hashicorp/terraform/helper/logging"
+
+// The loggers of Terraform core, and of the walks for the entries about
+// the resources in them.
+var (
+	logger     = logging.New("terraform")
+	applyLog   = logging.New("terraform/apply")
+	planLog    = logging.New("terraform/plan")
+	refreshLog = logging.New("terraform/refresh")
+)
@@ -0,0 +1,100 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/helper/warnings"
+)
+
+// ModuleWarnings returns warnings about the child modules used by the
+// configuration in m: modules whose authors have marked them as deprecated,
+// modules that recommend a version of Terraform other than the one that is
+// running, and module blocks that set variables that are deprecated.
+//
+// This lets module authors steer users off of old modules and variables
+// without breaking the configurations that use them, as required_version
+// or removing the variables would.
+func ModuleWarnings(m *module.Tree) []string {
+	var ws []string
+	for _, c := range m.Children() {
+		ws = append(ws, moduleWarnings(c)...)
+	}
+	ws = append(ws, deprecatedVariableWarnings(m)...)
+
+	sort.Strings(ws)
+	return ws
+}
+
+func moduleWarnings(m *module.Tree) []string {
+	var ws []string
+	for _, c := range m.Children() {
+		ws = append(ws, moduleWarnings(c)...)
+	}
+
+	c := m.Config()
+	if c == nil || c.Terraform == nil {
+		return ws
+	}
+	tf := c.Terraform
+
+	module := modulePrefixStr(normalizeModulePath(m.Path()))
+	if tf.Deprecated != "" {
+		ws = append(ws, warnings.Format(warnings.CodeModuleDeprecated, fmt.Sprintf(
+			"%s: module is deprecated: %s", module, tf.Deprecated)))
+	}
+
+	if tf.RecommendedVersion != "" {
+		// Invalid constraints are reported by validation, so we don't
+		// need to warn about them here.
+		cs, err := version.NewConstraint(tf.RecommendedVersion)
+		if err == nil && !cs.Check(SemVersion) {
+			ws = append(ws, warnings.Format(warnings.CodeModuleVersion, fmt.Sprintf(
+				"%s: module recommends Terraform version %s, but this is version %s",
+				module, tf.RecommendedVersion, SemVersion)))
+		}
+	}
+
+	return ws
+}
+
+// deprecatedVariableWarnings returns warnings about the module blocks in m
+// and its descendants that set deprecated variables of their modules, with
+// where the variables are set.
+func deprecatedVariableWarnings(m *module.Tree) []string {
+	var ws []string
+	children := m.Children()
+	if c := m.Config(); c != nil {
+		for _, mc := range c.Modules {
+			child, ok := children[mc.Name]
+			if !ok || child.Config() == nil || mc.RawConfig == nil {
+				continue
+			}
+
+			module := modulePrefixStr(normalizeModulePath(child.Path()))
+			for _, v := range child.Config().Variables {
+				if v.Deprecated == "" {
+					continue
+				}
+				if _, ok := mc.RawConfig.Raw[v.Name]; !ok {
+					continue
+				}
+
+				msg := fmt.Sprintf("%s: variable %q is deprecated: %s",
+					module, v.Name, v.Deprecated)
+				if pos := mc.ArgumentPos(v.Name); pos != "" {
+					msg = fmt.Sprintf("%s (set at %s)", msg, pos)
+				}
+				ws = append(ws, warnings.Format(warnings.CodeVariableDeprecated, msg))
+			}
+		}
+	}
+
+	for _, child := range children {
+		ws = append(ws, deprecatedVariableWarnings(child)...)
+	}
+
+	return ws
+}
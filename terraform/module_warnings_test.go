@@ -0,0 +1,67 @@
+package terraform
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+)
+
+func TestModuleWarnings(t *testing.T) {
+	old := SemVersion
+	SemVersion = version.Must(version.NewVersion("0.8.0"))
+	defer func() { SemVersion = old }()
+
+	mod := testModule(t, "module-warnings")
+
+	got := ModuleWarnings(mod)
+	want := []string{
+		"[module-deprecated] module.old: module is deprecated: Use the current module instead.",
+		"[module-version] module.old.module.nested: module recommends Terraform version >= 0.9.0, but this is version 0.8.0",
+		"[module-version] module.old: module recommends Terraform version >= 0.9.0, but this is version 0.8.0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong warnings\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestModuleWarnings_versionMatches(t *testing.T) {
+	old := SemVersion
+	SemVersion = version.Must(version.NewVersion("0.9.0"))
+	defer func() { SemVersion = old }()
+
+	mod := testModule(t, "module-warnings")
+
+	got := ModuleWarnings(mod)
+	want := []string{
+		"[module-deprecated] module.old: module is deprecated: Use the current module instead.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong warnings\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestModuleWarnings_deprecatedVariables(t *testing.T) {
+	mod := testModule(t, "module-warnings-variables")
+
+	got := ModuleWarnings(mod)
+	if len(got) != 3 {
+		t.Fatalf("wrong warnings: %#v", got)
+	}
+
+	want := `[variable-deprecated] module.child: variable "old_name" is deprecated: Use name instead. (set at test-fixtures/module-warnings-variables/main.tf:3)`
+	if got[1] != want {
+		t.Fatalf("wrong warning\ngot:  %s\nwant: %s", got[1], want)
+	}
+
+	// Child modules are loaded from where they're installed, which is
+	// where the variables of nested modules are set.
+	for i, prefix := range map[int]string{0: "module.child", 2: "module.current"} {
+		want := fmt.Sprintf(`[variable-deprecated] %s.module.nested: variable "legacy" is deprecated: It has no effect anymore. (set at `, prefix)
+		if !strings.HasPrefix(got[i], want) || !strings.HasSuffix(got[i], "main.tf:10)") {
+			t.Fatalf("wrong warning: %s", got[i])
+		}
+	}
+}
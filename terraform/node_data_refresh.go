@@ -124,6 +124,7 @@ func (n *NodeRefreshableDataResourceInstance) EvalTree() EvalNode {
 		Name:       addr.Name,
 		Type:       addr.Type,
 		CountIndex: addr.Index,
+		EachKey:    addr.Key,
 	}
 	if resource.CountIndex < 0 {
 		resource.CountIndex = 0
@@ -185,8 +186,10 @@ func (n *NodeRefreshableDataResourceInstance) EvalTree() EvalNode {
 			// provider configurations that need this data during
 			// refresh/plan.
 			&EvalGetProvider{
-				Name:   n.ProvidedBy()[0],
-				Output: &provider,
+				Name:     n.ProvidedBy()[0],
+				Output:   &provider,
+				Config:   n.Config,
+				Resource: resource,
 			},
 
 			&EvalReadDataDiff{
@@ -210,6 +213,7 @@ func (n *NodeRefreshableDataResourceInstance) EvalTree() EvalNode {
 				Provider:     rs.Provider,
 				Dependencies: rs.Dependencies,
 				State:        &state,
+				Resource:     resource,
 			},
 
 			&EvalUpdateStateHook{},
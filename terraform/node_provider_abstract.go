@@ -68,6 +68,15 @@ func (n *NodeAbstractProvider) ProviderConfig() *config.RawConfig {
 	return n.Config.RawConfig
 }
 
+// GraphNodeProviderBase
+func (n *NodeAbstractProvider) ProviderBase() string {
+	if n.Config == nil {
+		return ""
+	}
+
+	return n.Config.Base
+}
+
 // GraphNodeAttachProvider
 func (n *NodeAbstractProvider) AttachProvider(c *config.ProviderConfig) {
 	n.Config = c
@@ -28,6 +28,7 @@ func (n *NodeDisabledProvider) EvalTree() EvalNode {
 				Provider: n.ProviderName(),
 				Config:   &resourceConfig,
 				Output:   &resourceConfig,
+				Base:     n.ProviderBase(),
 			},
 			&EvalSetProviderConfig{
 				Provider: n.ProviderName(),
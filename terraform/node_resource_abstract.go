@@ -55,6 +55,7 @@ func (n *NodeAbstractResource) ReferenceableName() []string {
 		addrCopy := n.Addr.Copy()
 		addrCopy.Path = nil // ReferenceTransformer handles paths
 		addrCopy.Index = -1 // We handle indexes below
+		addrCopy.Key = ""
 		id = addrCopy.String()
 	} else {
 		// No way to determine our type.name, just return
@@ -71,6 +72,11 @@ func (n *NodeAbstractResource) ReferenceableName() []string {
 	// We represent all multi-access
 	result = append(result, fmt.Sprintf("%s.*", id))
 
+	// Instances of a for_each resource are only referenceable as a whole
+	if n.Addr != nil && n.Addr.Key != "" {
+		return result
+	}
+
 	// We represent either a specific number, or all numbers
 	suffix := "N"
 	if n.Addr != nil {
@@ -94,6 +100,12 @@ func (n *NodeAbstractResource) References() []string {
 		var result []string
 		result = append(result, c.DependsOn...)
 		result = append(result, ReferencesFromConfig(c.RawCount)...)
+		if c.RawForEach != nil {
+			result = append(result, ReferencesFromConfig(c.RawForEach)...)
+		}
+		if c.RawProvider != nil {
+			result = append(result, ReferencesFromConfig(c.RawProvider)...)
+		}
 		result = append(result, ReferencesFromConfig(c.RawConfig)...)
 		for _, p := range c.Provisioners {
 			if p.When == config.ProvisionerWhenCreate {
@@ -122,6 +134,7 @@ func (n *NodeAbstractResource) StateReferences() []string {
 	// ourself.
 	addrCopy := n.Addr.Copy()
 	addrCopy.Index = -1
+	addrCopy.Key = ""
 	selfPrefix := addrCopy.String() + "."
 
 	depsRaw := n.References()
@@ -174,6 +187,10 @@ func (n *NodeAbstractResource) StateReferences() []string {
 func (n *NodeAbstractResource) ProvidedBy() []string {
 	// If we have a config we prefer that above all else
 	if n.Config != nil {
+		if n.Config.RawProvider != nil {
+			return n.providerChoices()
+		}
+
 		return []string{resourceProvider(n.Config.Type, n.Config.Provider)}
 	}
 
@@ -186,6 +203,35 @@ func (n *NodeAbstractResource) ProvidedBy() []string {
 	return []string{resourceProvider(n.Addr.Type, "")}
 }
 
+// providerChoices returns the providers that a resource that selects its
+// provider with an expression can select, all of which it depends on. The
+// provider recorded in the state, if it is one of them, comes first, so
+// that existing instances that aren't selected again, such as those being
+// destroyed, keep their provider.
+func (n *NodeAbstractResource) providerChoices() []string {
+	choices := n.Config.ProviderChoices
+	if n.ResourceState == nil || n.ResourceState.Provider == "" {
+		return choices
+	}
+
+	result := make([]string, 0, len(choices))
+	for _, p := range choices {
+		if p == n.ResourceState.Provider {
+			result = append(result, p)
+		}
+	}
+	if len(result) == 0 {
+		return choices
+	}
+	for _, p := range choices {
+		if p != n.ResourceState.Provider {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
 // GraphNodeProvisionerConsumer
 func (n *NodeAbstractResource) ProvisionedBy() []string {
 	// If we have no configuration, then we have no provisioners
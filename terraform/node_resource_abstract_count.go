@@ -1,7 +1,7 @@
 package terraform
 
 // NodeAbstractCountResource should be embedded instead of NodeAbstractResource
-// if the resource has a `count` value that needs to be expanded.
+// if the resource has a `count` or `for_each` value that needs to be expanded.
 //
 // The embedder should implement `DynamicExpand` to process the count.
 type NodeAbstractCountResource struct {
@@ -22,6 +22,12 @@ func (n *NodeAbstractCountResource) EvalTree() EvalNode {
 		evalCountCheckComputed = &EvalCountCheckComputed{Resource: n.Config}
 	}
 
+	// The for_each is interpolated alongside the count when it is set
+	var evalForEachInterpolate EvalNode
+	if n.Config.RawForEach != nil {
+		evalForEachInterpolate = &EvalInterpolate{Config: n.Config.RawForEach}
+	}
+
 	return &EvalSequence{
 		Nodes: []EvalNode{
 			// The EvalTree for a plannable resource primarily involves
@@ -31,6 +37,7 @@ func (n *NodeAbstractCountResource) EvalTree() EvalNode {
 			// With the interpolated count, we can then DynamicExpand
 			// into the proper number of instances.
 			&EvalInterpolate{Config: n.Config.RawCount},
+			evalForEachInterpolate,
 
 			// Check if the count is computed
 			evalCountCheckComputed,
@@ -48,3 +55,56 @@ func (n *NodeAbstractCountResource) EvalTree() EvalNode {
 		},
 	}
 }
+
+// expandTransformers returns the transformers that add the instances of
+// this resource to the graph, using its for_each if it has one and its
+// count otherwise, along with the transformer that adds the instances
+// orphaned in the state. The orphan transformer is nil if orphan is nil.
+//
+// This assumes that the count and for_each are already interpolated.
+func (n *NodeAbstractCountResource) expandTransformers(
+	state *State, concrete, orphan ConcreteResourceNodeFunc) (GraphTransformer, GraphTransformer, error) {
+	if n.Config.RawForEach != nil {
+		forEach, err := n.Config.ForEach()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var orphans GraphTransformer
+		if orphan != nil {
+			orphans = &OrphanResourceForEachTransformer{
+				Concrete: orphan,
+				ForEach:  forEach,
+				Addr:     n.ResourceAddr(),
+				State:    state,
+			}
+		}
+
+		return &ResourceForEachTransformer{
+			Concrete: concrete,
+			ForEach:  forEach,
+			Addr:     n.ResourceAddr(),
+		}, orphans, nil
+	}
+
+	count, err := n.Config.Count()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var orphans GraphTransformer
+	if orphan != nil {
+		orphans = &OrphanResourceCountTransformer{
+			Concrete: orphan,
+			Count:    count,
+			Addr:     n.ResourceAddr(),
+			State:    state,
+		}
+	}
+
+	return &ResourceCountTransformer{
+		Concrete: concrete,
+		Count:    count,
+		Addr:     n.ResourceAddr(),
+	}, orphans, nil
+}
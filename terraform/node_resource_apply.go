@@ -17,6 +17,24 @@ func (n *NodeApplyableResource) CreateAddr() *ResourceAddress {
 	return n.NodeAbstractResource.Addr
 }
 
+// GraphNodeSchedulePriority
+func (n *NodeApplyableResource) SchedulePriority() int {
+	if n.Config == nil {
+		return 0
+	}
+
+	return n.Config.Lifecycle.Priority
+}
+
+// GraphNodeApplyStage
+func (n *NodeApplyableResource) ApplyStage() int {
+	if n.Config == nil {
+		return 0
+	}
+
+	return n.Config.Stage
+}
+
 // GraphNodeReferencer, overriding NodeAbstractResource
 func (n *NodeApplyableResource) References() []string {
 	result := n.NodeAbstractResource.References()
@@ -65,6 +83,7 @@ func (n *NodeApplyableResource) EvalTree() EvalNode {
 		Name:       addr.Name,
 		Type:       addr.Type,
 		CountIndex: addr.Index,
+		EachKey:    addr.Key,
 	}
 	if resource.CountIndex < 0 {
 		resource.CountIndex = 0
@@ -76,9 +95,14 @@ func (n *NodeApplyableResource) EvalTree() EvalNode {
 	// Eval info is different depending on what kind of resource this is
 	switch n.Config.Mode {
 	case config.ManagedResourceMode:
-		return n.evalTreeManagedResource(
-			stateId, info, resource, stateDeps,
-		)
+		return &EvalSequence{
+			Nodes: []EvalNode{
+				&EvalForEachValue{Config: n.Config, Resource: resource},
+				n.evalTreeManagedResource(
+					stateId, info, resource, stateDeps,
+				),
+			},
+		}
 	case config.DataResourceMode:
 		return n.evalTreeDataResource(
 			stateId, info, resource, stateDeps)
@@ -135,8 +159,10 @@ func (n *NodeApplyableResource) evalTreeDataResource(
 			},
 
 			&EvalGetProvider{
-				Name:   n.ProvidedBy()[0],
-				Output: &provider,
+				Name:     n.ProvidedBy()[0],
+				Output:   &provider,
+				Config:   n.Config,
+				Resource: resource,
 			},
 
 			// Make a new diff with our newly-interpolated config.
@@ -161,6 +187,7 @@ func (n *NodeApplyableResource) evalTreeDataResource(
 				Provider:     n.Config.Provider,
 				Dependencies: stateDeps,
 				State:        &state,
+				Resource:     resource,
 			},
 
 			// Clear the diff now that we've applied it, so
@@ -218,6 +245,19 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 				Then: EvalNoop{},
 			},
 
+			// Run the lifecycle hook before creating or updating, which
+			// stops the apply if it fails
+			&EvalReadState{
+				Name:   stateId,
+				Output: &state,
+			},
+			&EvalResourceHook{
+				Info:     info,
+				Resource: n.Config,
+				State:    &state,
+				Diff:     &diffApply,
+			},
+
 			&EvalIf{
 				If: func(ctx EvalContext) (bool, error) {
 					destroy := false
@@ -242,13 +282,37 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 				Output:   &resourceConfig,
 			},
 			&EvalGetProvider{
-				Name:   n.ProvidedBy()[0],
-				Output: &provider,
+				Name:     n.ProvidedBy()[0],
+				Output:   &provider,
+				Config:   n.Config,
+				Resource: resource,
 			},
 			&EvalReadState{
 				Name:   stateId,
 				Output: &state,
 			},
+			// Read an existing object that the plan adopts into the state,
+			// so that the diff is applied to it.
+			&EvalReadAdopted{
+				Info:     info,
+				Provider: &provider,
+				Diff:     &diffApply,
+				State:    &state,
+				Output:   &state,
+			},
+			&EvalIf{
+				If: func(ctx EvalContext) (bool, error) {
+					return diffApply.GetAdoptID() != "", nil
+				},
+				Then: &EvalWriteState{
+					Name:         stateId,
+					ResourceType: n.Config.Type,
+					Provider:     n.Config.Provider,
+					Dependencies: stateDeps,
+					State:        &state,
+					Resource:     resource,
+				},
+			},
 			// Re-run validation to catch any errors we missed, e.g. type
 			// mismatches on computed values.
 			&EvalValidateResource{
@@ -257,6 +321,7 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 				ResourceName:   n.Config.Name,
 				ResourceType:   n.Config.Type,
 				ResourceMode:   n.Config.Mode,
+				Resource:       n.Config,
 				IgnoreWarnings: true,
 			},
 			&EvalDiff{
@@ -283,8 +348,10 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 			},
 
 			&EvalGetProvider{
-				Name:   n.ProvidedBy()[0],
-				Output: &provider,
+				Name:     n.ProvidedBy()[0],
+				Output:   &provider,
+				Config:   n.Config,
+				Resource: resource,
 			},
 			&EvalReadState{
 				Name:   stateId,
@@ -304,6 +371,7 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 				Output:    &state,
 				Error:     &err,
 				CreateNew: &createNew,
+				Resource:  n.Config,
 			},
 			&EvalWriteState{
 				Name:         stateId,
@@ -311,6 +379,15 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 				Provider:     n.Config.Provider,
 				Dependencies: stateDeps,
 				State:        &state,
+				Resource:     resource,
+			},
+			&EvalWaitForReady{
+				Info:      info,
+				State:     &state,
+				Resource:  n.Config,
+				Provider:  &provider,
+				CreateNew: &createNew,
+				Error:     &err,
 			},
 			&EvalApplyProvisioners{
 				Info:           info,
@@ -321,6 +398,15 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 				Error:          &err,
 				When:           config.ProvisionerWhenCreate,
 			},
+			&EvalResourceHook{
+				Info:      info,
+				Resource:  n.Config,
+				After:     true,
+				State:     &state,
+				Diff:      &diffApply,
+				CreateNew: &createNew,
+				Error:     &err,
+			},
 			&EvalIf{
 				If: func(ctx EvalContext) (bool, error) {
 					return createBeforeDestroyEnabled && err != nil, nil
@@ -335,6 +421,7 @@ func (n *NodeApplyableResource) evalTreeManagedResource(
 					Provider:     n.Config.Provider,
 					Dependencies: stateDeps,
 					State:        &state,
+					Resource:     resource,
 				},
 			},
 
@@ -20,6 +20,15 @@ func (n *NodeDestroyResource) DestroyAddr() *ResourceAddress {
 	return n.Addr
 }
 
+// GraphNodeDestroyPrioritizer
+func (n *NodeDestroyResource) DestroyPriority() int {
+	if n.Config == nil {
+		return 0
+	}
+
+	return n.Config.Lifecycle.DestroyPriority
+}
+
 // GraphNodeDestroyerCBD
 func (n *NodeDestroyResource) CreateBeforeDestroy() bool {
 	// If we have no config, we just assume no
@@ -102,8 +111,9 @@ func (n *NodeDestroyResource) DynamicExpand(ctx EvalContext) (*Graph, error) {
 
 	// We want deposed resources in the state to be destroyed
 	steps = append(steps, &DeposedTransformer{
-		State: state,
-		View:  n.Addr.stateId(),
+		State:  state,
+		View:   n.Addr.stateId(),
+		Config: n.Config,
 	})
 
 	// Target
@@ -140,6 +150,7 @@ func (n *NodeDestroyResource) EvalTree() EvalNode {
 		Name:       addr.Name,
 		Type:       addr.Type,
 		CountIndex: addr.Index,
+		EachKey:    addr.Key,
 	}
 	if resource.CountIndex < 0 {
 		resource.CountIndex = 0
@@ -153,12 +164,14 @@ func (n *NodeDestroyResource) EvalTree() EvalNode {
 
 	var diffApply *InstanceDiff
 	var provider ResourceProvider
-	var state *InstanceState
+	var state, hookState *InstanceState
 	var err error
 	return &EvalOpFilter{
 		Ops: []walkOperation{walkApply, walkDestroy},
 		Node: &EvalSequence{
 			Nodes: []EvalNode{
+				&EvalForEachValue{Config: n.Config, Resource: resource},
+
 				// Get the saved diff for apply
 				&EvalReadDiff{
 					Name: stateId,
@@ -199,6 +212,20 @@ func (n *NodeDestroyResource) EvalTree() EvalNode {
 					State: &state,
 				},
 
+				// Run the lifecycle hook before destroying, which stops the
+				// destroy if it fails. The hook after destroying gets the
+				// state from before.
+				&EvalReadState{
+					Name:   stateId,
+					Output: &hookState,
+				},
+				&EvalResourceHook{
+					Info:     info,
+					Resource: n.Config,
+					Destroy:  true,
+					State:    &hookState,
+				},
+
 				// Call pre-apply hook
 				&EvalApplyPre{
 					Info:  info,
@@ -276,6 +303,14 @@ func (n *NodeDestroyResource) EvalTree() EvalNode {
 					Dependencies: rs.Dependencies,
 					State:        &state,
 				},
+				&EvalResourceHook{
+					Info:     info,
+					Resource: n.Config,
+					After:    true,
+					Destroy:  true,
+					State:    &hookState,
+					Error:    &err,
+				},
 				&EvalApplyPost{
 					Info:  info,
 					State: &state,
@@ -8,6 +8,10 @@ import (
 // it is ready to be planned in order to create a diff.
 type NodePlannableResource struct {
 	*NodeAbstractCountResource
+
+	// ForceReplace are the addresses of resources whose instances
+	// should be planned for replacement.
+	ForceReplace []*ResourceAddress
 }
 
 // GraphNodeDynamicExpandable
@@ -17,12 +21,6 @@ func (n *NodePlannableResource) DynamicExpand(ctx EvalContext) (*Graph, error) {
 	lock.RLock()
 	defer lock.RUnlock()
 
-	// Expand the resource count which must be available by now from EvalTree
-	count, err := n.Config.Count()
-	if err != nil {
-		return nil, err
-	}
-
 	// The concrete resource factory we'll use
 	concreteResource := func(a *NodeAbstractResource) dag.Vertex {
 		// Add the config and state since we don't do that via transforms
@@ -30,6 +28,7 @@ func (n *NodePlannableResource) DynamicExpand(ctx EvalContext) (*Graph, error) {
 
 		return &NodePlannableResourceInstance{
 			NodeAbstractResource: a,
+			ForceReplace:         n.ForceReplace,
 		}
 	}
 
@@ -43,22 +42,21 @@ func (n *NodePlannableResource) DynamicExpand(ctx EvalContext) (*Graph, error) {
 		}
 	}
 
+	// Expand the resource count or for_each which must be available by
+	// now from EvalTree
+	expand, orphans, err := n.expandTransformers(
+		state, concreteResource, concreteResourceOrphan)
+	if err != nil {
+		return nil, err
+	}
+
 	// Start creating the steps
 	steps := []GraphTransformer{
 		// Expand the count.
-		&ResourceCountTransformer{
-			Concrete: concreteResource,
-			Count:    count,
-			Addr:     n.ResourceAddr(),
-		},
+		expand,
 
 		// Add the count orphans
-		&OrphanResourceCountTransformer{
-			Concrete: concreteResourceOrphan,
-			Count:    count,
-			Addr:     n.ResourceAddr(),
-			State:    state,
-		},
+		orphans,
 
 		// Attach the state
 		&AttachStateTransformer{State: state},
@@ -11,6 +11,11 @@ import (
 // count index, for example.
 type NodePlannableResourceInstance struct {
 	*NodeAbstractResource
+
+	// ForceReplace are the addresses of resources that should be
+	// replaced. If any of them matches this instance then the instance
+	// is planned for replacement as if it were tainted.
+	ForceReplace []*ResourceAddress
 }
 
 // GraphNodeEvalable
@@ -32,6 +37,7 @@ func (n *NodePlannableResourceInstance) EvalTree() EvalNode {
 		Name:       addr.Name,
 		Type:       addr.Type,
 		CountIndex: addr.Index,
+		EachKey:    addr.Key,
 	}
 	if resource.CountIndex < 0 {
 		resource.CountIndex = 0
@@ -43,9 +49,14 @@ func (n *NodePlannableResourceInstance) EvalTree() EvalNode {
 	// Eval info is different depending on what kind of resource this is
 	switch n.Config.Mode {
 	case config.ManagedResourceMode:
-		return n.evalTreeManagedResource(
-			stateId, info, resource, stateDeps,
-		)
+		return &EvalSequence{
+			Nodes: []EvalNode{
+				&EvalForEachValue{Config: n.Config, Resource: resource},
+				n.evalTreeManagedResource(
+					stateId, info, resource, stateDeps,
+				),
+			},
+		}
 	case config.DataResourceMode:
 		return n.evalTreeDataResource(
 			stateId, info, resource, stateDeps)
@@ -97,8 +108,10 @@ func (n *NodePlannableResourceInstance) evalTreeDataResource(
 			},
 
 			&EvalGetProvider{
-				Name:   n.ProvidedBy()[0],
-				Output: &provider,
+				Name:     n.ProvidedBy()[0],
+				Output:   &provider,
+				Config:   n.Config,
+				Resource: resource,
 			},
 
 			&EvalReadDataDiff{
@@ -115,6 +128,7 @@ func (n *NodePlannableResourceInstance) evalTreeDataResource(
 				Provider:     n.Config.Provider,
 				Dependencies: stateDeps,
 				State:        &state,
+				Resource:     resource,
 			},
 
 			&EvalWriteDiff{
@@ -134,6 +148,7 @@ func (n *NodePlannableResourceInstance) evalTreeManagedResource(
 	var diff *InstanceDiff
 	var state *InstanceState
 	var resourceConfig *ResourceConfig
+	var adoptDiff *InstanceDiff
 
 	return &EvalSequence{
 		Nodes: []EvalNode{
@@ -143,8 +158,10 @@ func (n *NodePlannableResourceInstance) evalTreeManagedResource(
 				Output:   &resourceConfig,
 			},
 			&EvalGetProvider{
-				Name:   n.ProvidedBy()[0],
-				Output: &provider,
+				Name:     n.ProvidedBy()[0],
+				Output:   &provider,
+				Config:   n.Config,
+				Resource: resource,
 			},
 			// Re-run validation to catch any errors we missed, e.g. type
 			// mismatches on computed values.
@@ -154,21 +171,36 @@ func (n *NodePlannableResourceInstance) evalTreeManagedResource(
 				ResourceName:   n.Config.Name,
 				ResourceType:   n.Config.Type,
 				ResourceMode:   n.Config.Mode,
+				Resource:       n.Config,
 				IgnoreWarnings: true,
 			},
 			&EvalReadState{
 				Name:   stateId,
 				Output: &state,
 			},
+			&EvalAdoptExisting{
+				Info:     info,
+				Resource: n.Config,
+				Provider: &provider,
+				Config:   &resourceConfig,
+				State:    &state,
+				Output:   &adoptDiff,
+			},
 			&EvalDiff{
 				Name:        stateId,
 				Info:        info,
 				Config:      &resourceConfig,
 				Resource:    n.Config,
 				Provider:    &provider,
+				Diff:        &adoptDiff,
 				State:       &state,
 				OutputDiff:  &diff,
 				OutputState: &state,
+				Replace:     n.forceReplace(),
+			},
+			&EvalCheckAdoptedReplace{
+				Info: info,
+				Diff: &diff,
 			},
 			&EvalCheckPreventDestroy{
 				Resource: n.Config,
@@ -180,6 +212,7 @@ func (n *NodePlannableResourceInstance) evalTreeManagedResource(
 				Provider:     n.Config.Provider,
 				Dependencies: stateDeps,
 				State:        &state,
+				Resource:     resource,
 			},
 			&EvalWriteDiff{
 				Name: stateId,
@@ -188,3 +221,31 @@ func (n *NodePlannableResourceInstance) evalTreeManagedResource(
 		},
 	}
 }
+
+// forceReplace returns true if this instance was requested to be replaced.
+func (n *NodePlannableResourceInstance) forceReplace() bool {
+	for _, addr := range n.ForceReplace {
+		if forceReplaceMatch(addr, n.Addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forceReplaceMatch returns true if the -replace address addr names the
+// instance inst. Unlike Equals, an address without an index or key only
+// names a resource without count or for_each, so that a single instance
+// of a resource is never replaced along with all the others. The only
+// exception is that index 0 names the single instance of a resource with
+// a count of one, whose address has no index.
+func forceReplaceMatch(addr, inst *ResourceAddress) bool {
+	if addr.Key != inst.Key {
+		return false
+	}
+
+	indexMatch := addr.Index == inst.Index ||
+		addr.Index == 0 && inst.Index == -1 && inst.Key == ""
+
+	return indexMatch && addr.Equals(inst)
+}
@@ -20,12 +20,6 @@ func (n *NodeRefreshableManagedResource) DynamicExpand(ctx EvalContext) (*Graph,
 	lock.RLock()
 	defer lock.RUnlock()
 
-	// Expand the resource count which must be available by now from EvalTree
-	count, err := n.Config.Count()
-	if err != nil {
-		return nil, err
-	}
-
 	// The concrete resource factory we'll use
 	concreteResource := func(a *NodeAbstractResource) dag.Vertex {
 		// Add the config and state since we don't do that via transforms
@@ -36,14 +30,18 @@ func (n *NodeRefreshableManagedResource) DynamicExpand(ctx EvalContext) (*Graph,
 		}
 	}
 
+	// Expand the resource count or for_each which must be available by
+	// now from EvalTree
+	expand, orphans, err := n.expandTransformers(
+		state, concreteResource, concreteResource)
+	if err != nil {
+		return nil, err
+	}
+
 	// Start creating the steps
 	steps := []GraphTransformer{
 		// Expand the count.
-		&ResourceCountTransformer{
-			Concrete: concreteResource,
-			Count:    count,
-			Addr:     n.ResourceAddr(),
-		},
+		expand,
 
 		// Switch up any node missing state to a plannable resource. This helps
 		// catch cases where data sources depend on the counts from this resource
@@ -54,12 +52,7 @@ func (n *NodeRefreshableManagedResource) DynamicExpand(ctx EvalContext) (*Graph,
 
 		// Add the count orphans to make sure these resources are accounted for
 		// during a scale in.
-		&OrphanResourceCountTransformer{
-			Concrete: concreteResource,
-			Count:    count,
-			Addr:     n.ResourceAddr(),
-			State:    state,
-		},
+		orphans,
 
 		// Attach the state
 		&AttachStateTransformer{State: state},
@@ -134,6 +127,18 @@ func (n *NodeRefreshableManagedResourceInstance) evalTreeManagedResource() EvalN
 		Type: addr.Type,
 	}
 
+	// Build the resource for eval, which selects its provider if the
+	// configuration does so with an expression
+	resource := &Resource{
+		Name:       addr.Name,
+		Type:       addr.Type,
+		CountIndex: addr.Index,
+		EachKey:    addr.Key,
+	}
+	if resource.CountIndex < 0 {
+		resource.CountIndex = 0
+	}
+
 	// Declare a bunch of variables that are used for state during
 	// evaluation. Most of this are written to by-address below.
 	var provider ResourceProvider
@@ -153,8 +158,10 @@ func (n *NodeRefreshableManagedResourceInstance) evalTreeManagedResource() EvalN
 	return &EvalSequence{
 		Nodes: []EvalNode{
 			&EvalGetProvider{
-				Name:   n.ProvidedBy()[0],
-				Output: &provider,
+				Name:     n.ProvidedBy()[0],
+				Output:   &provider,
+				Config:   n.Config,
+				Resource: resource,
 			},
 			&EvalReadState{
 				Name:   stateId,
@@ -172,6 +179,7 @@ func (n *NodeRefreshableManagedResourceInstance) evalTreeManagedResource() EvalN
 				Provider:     n.ResourceState.Provider,
 				Dependencies: n.ResourceState.Dependencies,
 				State:        &state,
+				Resource:     resource,
 			},
 		},
 	}
@@ -45,14 +45,30 @@ func (n *NodeValidatableResource) DynamicExpand(ctx EvalContext) (*Graph, error)
 		}
 	}
 
+	// Expand the count, or the for_each if it is known. A computed
+	// for_each is validated as a single instance.
+	var expand GraphTransformer = &ResourceCountTransformer{
+		Concrete: concreteResource,
+		Count:    count,
+		Addr:     n.ResourceAddr(),
+	}
+	if rf := n.Config.RawForEach; rf != nil && len(rf.UnknownKeys()) == 0 {
+		forEach, err := n.Config.ForEach()
+		if err != nil {
+			return nil, err
+		}
+
+		expand = &ResourceForEachTransformer{
+			Concrete: concreteResource,
+			ForEach:  forEach,
+			Addr:     n.ResourceAddr(),
+		}
+	}
+
 	// Start creating the steps
 	steps := []GraphTransformer{
 		// Expand the count.
-		&ResourceCountTransformer{
-			Concrete: concreteResource,
-			Count:    count,
-			Addr:     n.ResourceAddr(),
-		},
+		expand,
 
 		// Attach the state
 		&AttachStateTransformer{State: state},
@@ -91,6 +107,7 @@ func (n *NodeValidatableResourceInstance) EvalTree() EvalNode {
 		Name:       addr.Name,
 		Type:       addr.Type,
 		CountIndex: addr.Index,
+		EachKey:    addr.Key,
 	}
 	if resource.CountIndex < 0 {
 		resource.CountIndex = 0
@@ -103,6 +120,7 @@ func (n *NodeValidatableResourceInstance) EvalTree() EvalNode {
 
 	seq := &EvalSequence{
 		Nodes: []EvalNode{
+			&EvalForEachValue{Config: n.Config, Resource: resource},
 			&EvalValidateResourceSelfRef{
 				Addr:   &addr,
 				Config: &n.Config.RawConfig,
@@ -122,6 +140,7 @@ func (n *NodeValidatableResourceInstance) EvalTree() EvalNode {
 				ResourceName: n.Config.Name,
 				ResourceType: n.Config.Type,
 				ResourceMode: n.Config.Mode,
+				Resource:     n.Config,
 			},
 		},
 	}
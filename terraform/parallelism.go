@@ -0,0 +1,127 @@
+package terraform
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// defaultAutoParallelismMax is the highest parallelism that automatic
+	// tuning raises the limit to.
+	defaultAutoParallelismMax = 64
+
+	// autoParallelismCooldown is how long automatic tuning waits after
+	// lowering the parallelism before it lowers it again, so that the
+	// operations that were already running when a provider started to
+	// throttle don't bring it all the way down.
+	autoParallelismCooldown = 5 * time.Second
+
+	// autoParallelismPressureInterval is how often automatic tuning checks
+	// for CPU and memory pressure.
+	autoParallelismPressureInterval = time.Second
+)
+
+// parallelismTuner adjusts the parallelism of a context while it walks its
+// graphs, based on how provider operations fare and on the load of the
+// system.
+//
+// It raises the limit by one each time as many operations as the limit have
+// succeeded in a row, and halves it when an operation fails with a transient
+// error, such as a provider being throttled, or when the system is under
+// CPU or memory pressure. This finds the highest parallelism that providers
+// and the system tolerate without configuring it for each plan.
+type parallelismTuner struct {
+	// sem is the semaphore whose limit is tuned. refreshSem, if not nil, is
+	// tuned along with it, with refreshFactor times the limit.
+	sem           *PrioritySemaphore
+	refreshSem    *PrioritySemaphore
+	refreshFactor int
+
+	min, max int
+
+	// pressure returns true if the system is under CPU or memory pressure,
+	// and now returns the current time. Both are replaced in tests.
+	pressure func() bool
+	now      func() time.Time
+
+	lock          sync.Mutex
+	successes     int
+	lastDecrease  time.Time
+	lastPressure  time.Time
+	underPressure bool
+}
+
+func newParallelismTuner(sem, refreshSem *PrioritySemaphore, refreshFactor int) *parallelismTuner {
+	return &parallelismTuner{
+		sem:           sem,
+		refreshSem:    refreshSem,
+		refreshFactor: refreshFactor,
+		min:           1,
+		max:           defaultAutoParallelismMax,
+		pressure:      systemPressure,
+		now:           time.Now,
+	}
+}
+
+// Observe records the outcome of a provider operation. throttled is true if
+// it failed with a transient error.
+//
+// Observe can be called on a nil tuner, which does nothing.
+func (t *parallelismTuner) Observe(throttled bool) {
+	if t == nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := t.now()
+	if now.Sub(t.lastPressure) >= autoParallelismPressureInterval {
+		t.lastPressure = now
+		t.underPressure = t.pressure()
+	}
+
+	limit := t.sem.Limit()
+	if throttled || t.underPressure {
+		t.successes = 0
+		if now.Sub(t.lastDecrease) < autoParallelismCooldown {
+			return
+		}
+		t.lastDecrease = now
+
+		reason := "transient provider error"
+		if !throttled {
+			reason = "system under CPU or memory pressure"
+		}
+		t.setLimit(limit/2, reason)
+		return
+	}
+
+	t.successes++
+	if t.successes >= limit {
+		t.successes = 0
+		t.setLimit(limit+1, "operations succeeding")
+	}
+}
+
+// setLimit sets the limit of the semaphores to n, within the bounds of the
+// tuner. The lock must be held.
+func (t *parallelismTuner) setLimit(n int, reason string) {
+	if n < t.min {
+		n = t.min
+	}
+	if n > t.max {
+		n = t.max
+	}
+
+	old := t.sem.Limit()
+	if n == old {
+		return
+	}
+
+	logger.Info("parallelism: %d -> %d (%s)", old, n, reason)
+	t.sem.SetLimit(n)
+	if t.refreshSem != nil {
+		t.refreshSem.SetLimit(n * t.refreshFactor)
+	}
+}
@@ -0,0 +1,125 @@
+package terraform
+
+import (
+	"testing"
+	"time"
+)
+
+func testParallelismTuner(par int) (*parallelismTuner, *time.Time, *bool) {
+	now := time.Unix(1000, 0)
+	pressure := false
+
+	t := newParallelismTuner(
+		NewPrioritySemaphore(par),
+		NewPrioritySemaphore(par*defaultRefreshParallelismFactor),
+		defaultRefreshParallelismFactor)
+	t.now = func() time.Time { return now }
+	t.pressure = func() bool { return pressure }
+
+	return t, &now, &pressure
+}
+
+func TestParallelismTuner(t *testing.T) {
+	tuner, now, _ := testParallelismTuner(4)
+
+	// As many successes as the limit raise it by one
+	for i := 0; i < 3; i++ {
+		tuner.Observe(false)
+	}
+	if n := tuner.sem.Limit(); n != 4 {
+		t.Fatalf("bad: %d", n)
+	}
+	tuner.Observe(false)
+	if n := tuner.sem.Limit(); n != 5 {
+		t.Fatalf("bad: %d", n)
+	}
+	if n := tuner.refreshSem.Limit(); n != 25 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	// A transient error halves it
+	tuner.Observe(true)
+	if n := tuner.sem.Limit(); n != 2 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	// More errors during the cooldown don't lower it further
+	tuner.Observe(true)
+	if n := tuner.sem.Limit(); n != 2 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	*now = now.Add(autoParallelismCooldown)
+	tuner.Observe(true)
+	if n := tuner.sem.Limit(); n != 1 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	// It never goes below the minimum
+	*now = now.Add(autoParallelismCooldown)
+	tuner.Observe(true)
+	if n := tuner.sem.Limit(); n != 1 {
+		t.Fatalf("bad: %d", n)
+	}
+}
+
+func TestParallelismTuner_max(t *testing.T) {
+	tuner, _, _ := testParallelismTuner(defaultAutoParallelismMax)
+
+	for i := 0; i < defaultAutoParallelismMax*2; i++ {
+		tuner.Observe(false)
+	}
+	if n := tuner.sem.Limit(); n != defaultAutoParallelismMax {
+		t.Fatalf("bad: %d", n)
+	}
+}
+
+func TestParallelismTuner_pressure(t *testing.T) {
+	tuner, now, pressure := testParallelismTuner(4)
+
+	*pressure = true
+	tuner.Observe(false)
+	if n := tuner.sem.Limit(); n != 2 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	// Successes don't raise the limit while under pressure
+	*now = now.Add(autoParallelismPressureInterval)
+	for i := 0; i < 10; i++ {
+		tuner.Observe(false)
+	}
+	if n := tuner.sem.Limit(); n != 2 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	// Pressure is only checked periodically
+	*pressure = false
+	tuner.Observe(false)
+	tuner.Observe(false)
+	if n := tuner.sem.Limit(); n != 2 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	*now = now.Add(autoParallelismPressureInterval)
+	tuner.Observe(false)
+	tuner.Observe(false)
+	if n := tuner.sem.Limit(); n != 3 {
+		t.Fatalf("bad: %d", n)
+	}
+}
+
+func TestParallelismTuner_fixedRefresh(t *testing.T) {
+	tuner := newParallelismTuner(NewPrioritySemaphore(2), nil, 0)
+	tuner.pressure = func() bool { return false }
+
+	tuner.Observe(false)
+	tuner.Observe(false)
+	if n := tuner.sem.Limit(); n != 3 {
+		t.Fatalf("bad: %d", n)
+	}
+}
+
+func TestParallelismTuner_nil(t *testing.T) {
+	var tuner *parallelismTuner
+	tuner.Observe(true)
+}
@@ -2,13 +2,18 @@ package terraform
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"sync"
 
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/terraform/config/module"
+	"github.com/hashicorp/terraform/helper/typedjson"
 )
 
 func init() {
@@ -66,14 +71,22 @@ func (p *Plan) contextOpts(base *ContextOpts) (*ContextOpts, error) {
 	opts.ProviderSHA256s = p.ProviderSHA256s
 
 	thisVersion := VersionString()
-	if p.TerraformVersion != "" && p.TerraformVersion != thisVersion {
+	if p.TerraformVersion != "" && !planVersionCompatible(p.TerraformVersion, thisVersion) {
 		return nil, fmt.Errorf(
 			"plan was created with a different version of Terraform (created with %s, but running %s)",
 			p.TerraformVersion, thisVersion,
 		)
 	}
 
+	// Ephemeral variables aren't stored in the plan, so their values are
+	// taken from the given options instead, such as from -var flags.
+	given := opts.Variables
 	opts.Variables = make(map[string]interface{})
+	for k := range ephemeralVariables(p.Module) {
+		if v, ok := given[k]; ok {
+			opts.Variables[k] = v
+		}
+	}
 	for k, v := range p.Vars {
 		opts.Variables[k] = v
 	}
@@ -81,6 +94,32 @@ func (p *Plan) contextOpts(base *ContextOpts) (*ContextOpts, error) {
 	return opts, nil
 }
 
+// planVersionCompatible returns true if a plan created with the version of
+// Terraform planVersion can be applied with the version thisVersion. Plans
+// can be applied by the same or a later patch release of the version that
+// created them, since the plan format is the same within a minor version.
+func planVersionCompatible(planVersion, thisVersion string) bool {
+	if planVersion == thisVersion {
+		return true
+	}
+
+	pv, err := version.NewVersion(planVersion)
+	if err != nil {
+		return false
+	}
+	tv, err := version.NewVersion(thisVersion)
+	if err != nil {
+		return false
+	}
+
+	ps, ts := pv.Segments(), tv.Segments()
+	if ps[0] != ts[0] || ps[1] != ts[1] {
+		return false
+	}
+
+	return !pv.GreaterThan(tv)
+}
+
 func (p *Plan) String() string {
 	buf := new(bytes.Buffer)
 	buf.WriteString("DIFF:\n\n")
@@ -112,7 +151,29 @@ func (p *Plan) init() {
 // the ability in the future to change the file format if we want for any
 // reason.
 const planFormatMagic = "tfplan"
-const planFormatVersion byte = 2
+const planFormatVersion byte = 3
+
+// planFormatVersionGob is the last version of the plan file format that
+// was encoded with gob. Plans in this format can still be read, but gob
+// can't decode them once any of the structures in the plan change, so they
+// aren't written anymore.
+const planFormatVersionGob byte = 2
+
+// planJSON is the JSON document that is the body of a plan file.
+//
+// Fields may be added to this structure, and to the structures within it,
+// but existing fields must not be removed or change meaning so that plans
+// can be read by later versions of Terraform, and by other tools.
+type planJSON struct {
+	TerraformVersion string            `json:"terraform_version"`
+	Diff             *Diff             `json:"diff"`
+	Module           *module.Tree      `json:"module"`
+	State            json.RawMessage   `json:"state"`
+	Vars             typedjson.Map     `json:"variables"`
+	Targets          []string          `json:"targets"`
+	ProviderSHA256s  map[string][]byte `json:"provider_sha256s"`
+	Backend          *BackendState     `json:"backend"`
+}
 
 // ReadPlan reads a plan structure out of a reader in the format that
 // was written by WritePlan.
@@ -143,14 +204,59 @@ func ReadPlan(src io.Reader) (*Plan, error) {
 		return nil, errors.New("failed to read plan version byte")
 	}
 
-	if formatByte[0] != planFormatVersion {
+	switch formatByte[0] {
+	case planFormatVersion:
+		return readPlanJSON(src)
+	case planFormatVersionGob:
+		dec := gob.NewDecoder(src)
+		if err := dec.Decode(&result); err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	default:
 		return nil, fmt.Errorf("unknown plan file version: %d", formatByte[0])
 	}
+}
 
-	dec := gob.NewDecoder(src)
-	if err := dec.Decode(&result); err != nil {
+// readPlanJSON reads the body of a plan file after the version byte: the
+// length of the JSON document as a big-endian uint64, followed by the
+// document itself.
+func readPlanJSON(src io.Reader) (*Plan, error) {
+	var length uint64
+	if err := binary.Read(src, binary.BigEndian, &length); err != nil {
+		return nil, fmt.Errorf("failed to read plan length: %s", err)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(src, int64(length)))
+	if err != nil {
 		return nil, err
 	}
+	if uint64(len(data)) != length {
+		return nil, errors.New("plan file is truncated")
+	}
+
+	var p planJSON
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode plan: %s", err)
+	}
+
+	result := &Plan{
+		Diff:             p.Diff,
+		Module:           p.Module,
+		Vars:             p.Vars,
+		Targets:          p.Targets,
+		TerraformVersion: p.TerraformVersion,
+		ProviderSHA256s:  p.ProviderSHA256s,
+		Backend:          p.Backend,
+	}
+
+	if len(p.State) > 0 && string(p.State) != "null" {
+		result.State, err = ReadState(bytes.NewReader(p.State))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode plan state: %s", err)
+		}
+	}
 
 	return result, nil
 }
@@ -175,5 +281,43 @@ func WritePlan(d *Plan, dst io.Writer) error {
 		return errors.New("failed to write plan version byte")
 	}
 
-	return gob.NewEncoder(dst).Encode(d)
+	p := &planJSON{
+		TerraformVersion: d.TerraformVersion,
+		Diff:             d.Diff,
+		Module:           d.Module,
+		Vars:             d.Vars,
+		Targets:          d.Targets,
+		ProviderSHA256s:  d.ProviderSHA256s,
+		Backend:          d.Backend,
+	}
+
+	// The modules of the diff are written in order, so that plans of the
+	// same changes are byte-identical. Everything else is either in order
+	// already or a map, which is written with sorted keys.
+	if d.Diff != nil {
+		p.Diff = &Diff{Modules: d.Diff.SortedModules()}
+	}
+
+	// The state is written in the same format as state files, so that it
+	// is upgraded in the same way when it's read. WriteState modifies the
+	// state it writes, so we write a copy.
+	if d.State != nil {
+		var buf bytes.Buffer
+		if err := WriteState(d.State.DeepCopy(), &buf); err != nil {
+			return err
+		}
+		p.State = buf.Bytes()
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %s", err)
+	}
+
+	if err := binary.Write(dst, binary.BigEndian, uint64(len(data))); err != nil {
+		return err
+	}
+
+	_, err = dst.Write(data)
+	return err
 }
@@ -0,0 +1,137 @@
+package terraform
+
+import (
+	"strings"
+)
+
+// PlanChangeCause is why a resource instance has a change in a plan.
+type PlanChangeCause string
+
+const (
+	// PlanCauseConfig is a change because the configuration differs from
+	// the state.
+	PlanCauseConfig PlanChangeCause = "config"
+
+	// PlanCauseDrift is a change back to what was recorded in the state,
+	// because refreshing found that the real resource was changed or
+	// deleted outside of Terraform.
+	PlanCauseDrift PlanChangeCause = "drift"
+
+	// PlanCauseForced is a replacement that was forced, because the
+	// resource is tainted or its replacement was requested with -replace.
+	PlanCauseForced PlanChangeCause = "forced"
+)
+
+// Causes returns why each resource instance with a change in the plan has
+// it, by the address of the instance, given the state before it was
+// refreshed for the plan. The plan's state is the refreshed state.
+//
+// An attribute change is caused by drift if refreshing changed the value
+// of the attribute and the change sets it back to the value from before,
+// and is otherwise caused by the configuration. A change can have both
+// causes, which are then in the order config, drift. Forced replacements
+// only have the forced cause. Reading data sources and destroying deposed
+// objects have no cause.
+func (p *Plan) Causes(prior *State) map[string][]PlanChangeCause {
+	result := make(map[string][]PlanChangeCause)
+	if p.Diff == nil {
+		return result
+	}
+
+	for _, m := range p.Diff.Modules {
+		for k, rdiff := range m.Resources {
+			if rdiff.Empty() || strings.HasPrefix(k, "data.") {
+				continue
+			}
+
+			causes := planChangeCauses(
+				rdiff,
+				planCauseInstance(prior, m.Path, k),
+				planCauseInstance(p.State, m.Path, k))
+			if len(causes) > 0 {
+				info := &InstanceInfo{Id: k, ModulePath: m.Path}
+				result[info.HumanId()] = causes
+			}
+		}
+	}
+
+	return result
+}
+
+func planChangeCauses(
+	rdiff *InstanceDiff, prior, refreshed *InstanceState) []PlanChangeCause {
+	if rdiff.GetDestroyTainted() {
+		return []PlanChangeCause{PlanCauseForced}
+	}
+
+	// Destroying a resource that is still in the state is caused by
+	// removing it from the configuration.
+	if rdiff.GetDestroy() && !rdiff.RequiresNew() {
+		return []PlanChangeCause{PlanCauseConfig}
+	}
+	if rdiff.GetDestroyDeposed() && len(rdiff.Attributes) == 0 {
+		return nil
+	}
+
+	var priorAttrs, refreshedAttrs map[string]string
+	if prior != nil && prior.ID != "" {
+		priorAttrs = prior.Attributes
+	}
+	if refreshed != nil && refreshed.ID != "" {
+		refreshedAttrs = refreshed.Attributes
+	} else if priorAttrs != nil {
+		// The resource was deleted outside of Terraform, so it's created
+		// again.
+		return []PlanChangeCause{PlanCauseDrift}
+	}
+
+	var config, drift bool
+	for k, attr := range rdiff.Attributes {
+		// Computed values follow from the other changes
+		if k == "id" || attr.NewComputed {
+			continue
+		}
+
+		priorV, priorOk := priorAttrs[k]
+		refreshedV, refreshedOk := refreshedAttrs[k]
+		drifted := priorOk != refreshedOk || priorV != refreshedV
+		switch {
+		case drifted && attr.NewRemoved && !priorOk:
+			drift = true
+		case drifted && !attr.NewRemoved && priorOk && attr.New == priorV:
+			drift = true
+		default:
+			config = true
+		}
+	}
+
+	var result []PlanChangeCause
+	if config || !drift {
+		result = append(result, PlanCauseConfig)
+	}
+	if drift {
+		result = append(result, PlanCauseDrift)
+	}
+
+	return result
+}
+
+// planCauseInstance returns the primary instance of the resource with the
+// given key in the module with the given path of s, or nil.
+func planCauseInstance(s *State, path []string, k string) *InstanceState {
+	if s == nil {
+		return nil
+	}
+
+	m := s.ModuleByPath(path)
+	if m == nil {
+		return nil
+	}
+
+	rs, ok := m.Resources[k]
+	if !ok {
+		return nil
+	}
+
+	return rs.Primary
+}
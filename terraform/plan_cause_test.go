@@ -0,0 +1,132 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanCauses(t *testing.T) {
+	prior := &State{
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"aws_instance.config": &ResourceState{
+						Type:    "aws_instance",
+						Primary: &InstanceState{ID: "i-1", Attributes: map[string]string{"ami": "ami-1"}},
+					},
+					"aws_instance.drift": &ResourceState{
+						Type:    "aws_instance",
+						Primary: &InstanceState{ID: "i-2", Attributes: map[string]string{"ami": "ami-1"}},
+					},
+					"aws_instance.both": &ResourceState{
+						Type: "aws_instance",
+						Primary: &InstanceState{ID: "i-3", Attributes: map[string]string{
+							"ami":  "ami-1",
+							"type": "small",
+						}},
+					},
+					"aws_instance.deleted": &ResourceState{
+						Type:    "aws_instance",
+						Primary: &InstanceState{ID: "i-4", Attributes: map[string]string{"ami": "ami-1"}},
+					},
+					"aws_instance.tainted": &ResourceState{
+						Type:    "aws_instance",
+						Primary: &InstanceState{ID: "i-5", Attributes: map[string]string{"ami": "ami-1"}},
+					},
+					"aws_instance.orphan": &ResourceState{
+						Type:    "aws_instance",
+						Primary: &InstanceState{ID: "i-6", Attributes: map[string]string{"ami": "ami-1"}},
+					},
+				},
+			},
+		},
+	}
+
+	refreshed := prior.DeepCopy()
+	root := refreshed.RootModule()
+	root.Resources["aws_instance.drift"].Primary.Attributes["ami"] = "ami-2"
+	root.Resources["aws_instance.both"].Primary.Attributes["ami"] = "ami-2"
+	delete(root.Resources, "aws_instance.deleted")
+
+	plan := &Plan{
+		State: refreshed,
+		Diff: &Diff{
+			Modules: []*ModuleDiff{
+				&ModuleDiff{
+					Path: rootModulePath,
+					Resources: map[string]*InstanceDiff{
+						"aws_instance.config": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"ami": &ResourceAttrDiff{Old: "ami-1", New: "ami-3"},
+							},
+						},
+						"aws_instance.drift": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"ami": &ResourceAttrDiff{Old: "ami-2", New: "ami-1"},
+							},
+						},
+						"aws_instance.both": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"ami":  &ResourceAttrDiff{Old: "ami-2", New: "ami-1"},
+								"type": &ResourceAttrDiff{Old: "small", New: "large"},
+							},
+						},
+						"aws_instance.deleted": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"id":  &ResourceAttrDiff{NewComputed: true, RequiresNew: true},
+								"ami": &ResourceAttrDiff{New: "ami-1"},
+							},
+						},
+						"aws_instance.tainted": &InstanceDiff{
+							DestroyTainted: true,
+							Attributes: map[string]*ResourceAttrDiff{
+								"id": &ResourceAttrDiff{Old: "i-5", NewComputed: true, RequiresNew: true},
+							},
+						},
+						"aws_instance.orphan": &InstanceDiff{
+							Destroy: true,
+						},
+						"aws_instance.new": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"id":  &ResourceAttrDiff{NewComputed: true, RequiresNew: true},
+								"ami": &ResourceAttrDiff{New: "ami-1"},
+							},
+						},
+						"data.aws_ami.ami": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"id": &ResourceAttrDiff{NewComputed: true, RequiresNew: true},
+							},
+						},
+					},
+				},
+				&ModuleDiff{
+					Path: []string{"root", "child"},
+					Resources: map[string]*InstanceDiff{
+						"aws_instance.new": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"id": &ResourceAttrDiff{NewComputed: true, RequiresNew: true},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	actual := plan.Causes(prior)
+	expected := map[string][]PlanChangeCause{
+		"aws_instance.config":  []PlanChangeCause{PlanCauseConfig},
+		"aws_instance.drift":   []PlanChangeCause{PlanCauseDrift},
+		"aws_instance.both":    []PlanChangeCause{PlanCauseConfig, PlanCauseDrift},
+		"aws_instance.deleted": []PlanChangeCause{PlanCauseDrift},
+		"aws_instance.tainted": []PlanChangeCause{PlanCauseForced},
+		"aws_instance.orphan":  []PlanChangeCause{PlanCauseConfig},
+		"aws_instance.new":     []PlanChangeCause{PlanCauseConfig},
+
+		"module.child.aws_instance.new": []PlanChangeCause{PlanCauseConfig},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected:\n%#v\n\ngot:\n%#v", expected, actual)
+	}
+}
@@ -0,0 +1,108 @@
+package terraform
+
+import (
+	"sort"
+)
+
+// PlanConfidence describes which parts of a plan are speculative: the
+// resource instances with attribute values that are unknown until apply,
+// and the changes that depend on those values, which may turn out to be
+// different, or to be no changes at all, once the values are known.
+type PlanConfidence struct {
+	// Changes is the number of resource instances with changes in the plan.
+	Changes int
+
+	// Unknown are the resource instances with changes that have values that
+	// are unknown until apply, sorted by address.
+	Unknown []*PlanUnknownValues
+
+	// Speculative are the addresses of the resource instances whose changes
+	// depend on values that are unknown until apply, sorted.
+	Speculative []string
+}
+
+// PlanUnknownValues are the values of a resource instance that are unknown
+// until apply.
+type PlanUnknownValues struct {
+	Address    string
+	ChangeType DiffChangeType
+
+	// Keys are the keys of the attributes with unknown values, sorted.
+	Keys []string
+
+	// Downstream are the addresses of the resource instances with unknown
+	// values that come from this one, directly or through other resources
+	// with changes, sorted. Their diffs at apply may differ from the plan.
+	Downstream []string
+}
+
+// Confidence returns which parts of the plan are speculative.
+//
+// A value is unknown until apply if the provider reports it as computed,
+// such as the ID of a resource that is created, or if it comes from such a
+// value. The changes that depend on a resource with unknown values are
+// traced as by Explain, through the attributes that have unknown values.
+func (p *Plan) Confidence() *PlanConfidence {
+	e := &planExplainer{plan: p}
+	changes := e.changes(planExplainNode{})
+
+	result := &PlanConfidence{Changes: len(changes)}
+	unknown := make(map[string]*PlanUnknownValues)
+	for _, c := range changes {
+		var keys []string
+		for k, attr := range c.diff.Attributes {
+			if attr.NewComputed {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		sort.Strings(keys)
+
+		u := &PlanUnknownValues{
+			Address:    c.addr.String(),
+			ChangeType: c.diff.ChangeType(),
+			Keys:       keys,
+		}
+		unknown[u.Address] = u
+		result.Unknown = append(result.Unknown, u)
+	}
+
+	speculative := make(map[string]bool)
+	for _, c := range changes {
+		addr := c.addr.String()
+		if unknown[addr] == nil {
+			continue
+		}
+
+		// Only the attributes with unknown values can come from the unknown
+		// values of other resources.
+		from := make(map[string]bool)
+		for _, attr := range e.explain(c).Attributes {
+			if !attr.Diff.NewComputed {
+				continue
+			}
+			for _, up := range attr.Upstream {
+				if unknown[up] != nil {
+					from[up] = true
+				}
+			}
+		}
+
+		for up := range from {
+			unknown[up].Downstream = append(unknown[up].Downstream, addr)
+			speculative[addr] = true
+		}
+	}
+
+	for _, u := range result.Unknown {
+		sort.Strings(u.Downstream)
+	}
+	for addr := range speculative {
+		result.Speculative = append(result.Speculative, addr)
+	}
+	sort.Strings(result.Speculative)
+
+	return result
+}
@@ -0,0 +1,129 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+func TestPlanConfidence(t *testing.T) {
+	plan := &Plan{
+		Module: testModule(t, "plan-confidence"),
+		Diff: &Diff{
+			Modules: []*ModuleDiff{
+				&ModuleDiff{
+					Path: rootModulePath,
+					Resources: map[string]*InstanceDiff{
+						"aws_vpc.main": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"id":         &ResourceAttrDiff{NewComputed: true, RequiresNew: true},
+								"cidr_block": &ResourceAttrDiff{New: "10.0.0.0/16"},
+							},
+						},
+						"aws_subnet.main": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"id":     &ResourceAttrDiff{NewComputed: true, RequiresNew: true},
+								"vpc_id": &ResourceAttrDiff{NewComputed: true},
+							},
+						},
+						"aws_instance.web": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"subnet": &ResourceAttrDiff{
+									Old:         "subnet-1",
+									NewComputed: true,
+								},
+							},
+						},
+						"aws_instance.known": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"cidr": &ResourceAttrDiff{
+									Old: "10.1.0.0/16",
+									New: "10.0.0.0/16",
+								},
+							},
+						},
+					},
+				},
+				&ModuleDiff{
+					Path: []string{"root", "child"},
+					Resources: map[string]*InstanceDiff{
+						"aws_eip.ip": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"id":     &ResourceAttrDiff{NewComputed: true, RequiresNew: true},
+								"subnet": &ResourceAttrDiff{NewComputed: true},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	actual := plan.Confidence()
+	expected := &PlanConfidence{
+		Changes: 5,
+		Unknown: []*PlanUnknownValues{
+			{
+				Address:    "aws_instance.web",
+				ChangeType: DiffUpdate,
+				Keys:       []string{"subnet"},
+			},
+			{
+				Address:    "aws_subnet.main",
+				ChangeType: DiffCreate,
+				Keys:       []string{"id", "vpc_id"},
+				Downstream: []string{"aws_instance.web", "module.child.aws_eip.ip"},
+			},
+			{
+				Address:    "aws_vpc.main",
+				ChangeType: DiffCreate,
+				Keys:       []string{"id"},
+				Downstream: []string{
+					"aws_instance.web",
+					"aws_subnet.main",
+					"module.child.aws_eip.ip",
+				},
+			},
+			{
+				Address:    "module.child.aws_eip.ip",
+				ChangeType: DiffCreate,
+				Keys:       []string{"id", "subnet"},
+			},
+		},
+		Speculative: []string{
+			"aws_instance.web",
+			"aws_subnet.main",
+			"module.child.aws_eip.ip",
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad:\n\n%s\n\n%s", spew.Sdump(actual), spew.Sdump(expected))
+	}
+}
+
+func TestPlanConfidence_known(t *testing.T) {
+	plan := &Plan{
+		Module: testModule(t, "plan-confidence"),
+		Diff: &Diff{
+			Modules: []*ModuleDiff{
+				&ModuleDiff{
+					Path: rootModulePath,
+					Resources: map[string]*InstanceDiff{
+						"aws_instance.web": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"subnet": &ResourceAttrDiff{Old: "a", New: "b"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	actual := plan.Confidence()
+	expected := &PlanConfidence{Changes: 1}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad:\n\n%#v\n\n%#v", actual, expected)
+	}
+}
@@ -0,0 +1,417 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// PlanExplanation explains the change to a single resource instance in a
+// plan: which of its attributes change, and which changes to the resources
+// that it depends on cause them.
+type PlanExplanation struct {
+	// Address is the address of the resource instance.
+	Address string
+
+	// ChangeType is the change to the resource instance. It is DiffNone if
+	// the plan doesn't change it.
+	ChangeType DiffChangeType
+
+	// Tainted and Deposed are true if the resource instance is replaced
+	// because it is tainted, or destroyed because it is deposed.
+	Tainted bool
+	Deposed bool
+
+	// Attributes are the attributes that change, sorted by key.
+	Attributes []*PlanExplainedAttribute
+
+	// Upstream are the changes to the resources that this resource depends
+	// on, directly or through other resources with changes, sorted by
+	// address.
+	Upstream []*PlanUpstreamChange
+}
+
+// PlanExplainedAttribute is an attribute that changes in a plan.
+type PlanExplainedAttribute struct {
+	Key  string
+	Diff *ResourceAttrDiff
+
+	// Upstream are the addresses of the resources with changes that the
+	// configuration of the attribute depends on, sorted.
+	Upstream []string
+}
+
+// PlanUpstreamChange is a change to a resource that the explained resource
+// depends on.
+type PlanUpstreamChange struct {
+	Address    string
+	ChangeType DiffChangeType
+
+	// Through are the addresses of the resources with changes through which
+	// the explained resource depends on this one, nearest first. It is empty
+	// if the explained resource depends on it directly.
+	Through []string
+}
+
+// Explain explains the changes that the plan makes to the resource
+// instances at the given address, which may be a resource or a single
+// instance of one. There is one explanation for each instance with changes,
+// or a single explanation with ChangeType DiffNone if there are none.
+//
+// Dependencies are traced through the configuration: through references to
+// resources, to the outputs of modules and to module variables, and through
+// depends_on. A dependency on a resource only passes changes on if that
+// resource changes too.
+func (p *Plan) Explain(addr string) ([]*PlanExplanation, error) {
+	target, err := ParseResourceAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !target.HasResourceSpec() {
+		return nil, fmt.Errorf("%s is not the address of a resource", addr)
+	}
+
+	e := &planExplainer{plan: p}
+
+	var result []*PlanExplanation
+	for _, c := range e.changes(planExplainNode{Path: target.Path}) {
+		if targetedApplyCovers(target, c.addr) {
+			result = append(result, e.explain(c))
+		}
+	}
+
+	if len(result) == 0 {
+		return []*PlanExplanation{{
+			Address:    target.String(),
+			ChangeType: DiffNone,
+		}}, nil
+	}
+
+	return result, nil
+}
+
+// planExplainer traces the dependencies of resources through the
+// configuration of a plan.
+type planExplainer struct {
+	plan *Plan
+}
+
+// planExplainNode is something in the configuration that can be depended
+// on. Name is the ID of a resource, "output.NAME" for an output,
+// "var.NAME" for a variable, or empty for a whole module.
+type planExplainNode struct {
+	Path []string
+	Name string
+}
+
+func (n planExplainNode) key() string {
+	return strings.Join(n.Path, ".") + "\x00" + n.Name
+}
+
+// planExplainRef is a dependency on a node, from the configuration of the
+// top-level attribute with the given key, or from depends_on if the key is
+// empty.
+type planExplainRef struct {
+	Key  string
+	Node planExplainNode
+}
+
+// planExplainChange is a change to a resource instance in the diff.
+type planExplainChange struct {
+	addr *ResourceAddress
+	node planExplainNode
+	diff *InstanceDiff
+}
+
+func (e *planExplainer) explain(c *planExplainChange) *PlanExplanation {
+	result := &PlanExplanation{
+		Address:    c.addr.String(),
+		ChangeType: c.diff.ChangeType(),
+		Tainted:    c.diff.DestroyTainted,
+		Deposed:    c.diff.DestroyDeposed,
+	}
+
+	// The nodes still to visit, breadth-first so that each change is
+	// reached through as few other resources as possible.
+	type visit struct {
+		key     string
+		node    planExplainNode
+		through []string
+	}
+	var queue []visit
+	for _, ref := range e.refs(c.node) {
+		queue = append(queue, visit{key: ref.Key, node: ref.Node})
+	}
+
+	upstream := make(map[string]*PlanUpstreamChange)
+	byKey := make(map[string]map[string]bool)
+	seen := map[string]bool{c.node.key(): true}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+
+		changes := e.changes(v.node)
+		for _, uc := range changes {
+			addr := uc.addr.String()
+			if _, ok := upstream[addr]; !ok {
+				upstream[addr] = &PlanUpstreamChange{
+					Address:    addr,
+					ChangeType: uc.diff.ChangeType(),
+					Through:    v.through,
+				}
+			}
+
+			if v.key != "" {
+				if byKey[v.key] == nil {
+					byKey[v.key] = make(map[string]bool)
+				}
+				byKey[v.key][addr] = true
+			}
+		}
+
+		// A node reached through another attribute has to be visited again
+		// to find the changes behind it for this one.
+		seenKey := v.key + "\x00" + v.node.key()
+		if seen[v.node.key()] || seen[seenKey] {
+			continue
+		}
+		seen[seenKey] = true
+
+		through := v.through
+		if planExplainIsResource(v.node) {
+			// A resource that doesn't change doesn't pass changes on
+			if len(changes) == 0 {
+				continue
+			}
+
+			through = make([]string, len(v.through), len(v.through)+1)
+			copy(through, v.through)
+			through = append(through, planExplainResourceAddress(v.node).String())
+		}
+
+		for _, ref := range e.refs(v.node) {
+			queue = append(queue, visit{key: v.key, node: ref.Node, through: through})
+		}
+	}
+
+	for k, attr := range c.diff.Attributes {
+		ea := &PlanExplainedAttribute{Key: k, Diff: attr}
+		for addr := range byKey[strings.SplitN(k, ".", 2)[0]] {
+			ea.Upstream = append(ea.Upstream, addr)
+		}
+		sort.Strings(ea.Upstream)
+
+		result.Attributes = append(result.Attributes, ea)
+	}
+	sort.Slice(result.Attributes, func(i, j int) bool {
+		return result.Attributes[i].Key < result.Attributes[j].Key
+	})
+
+	for _, uc := range upstream {
+		result.Upstream = append(result.Upstream, uc)
+	}
+	sort.Slice(result.Upstream, func(i, j int) bool {
+		return result.Upstream[i].Address < result.Upstream[j].Address
+	})
+
+	return result
+}
+
+// changes returns the changes in the diff to the resource instances of the
+// given node, sorted by address. For a whole module, these are all the
+// changes within it and its descendants.
+func (e *planExplainer) changes(n planExplainNode) []*planExplainChange {
+	if e.plan.Diff == nil ||
+		n.Name != "" && !planExplainIsResource(n) {
+		return nil
+	}
+
+	var result []*planExplainChange
+	for _, m := range e.plan.Diff.Modules {
+		path := normalizeModulePath(m.Path)[1:]
+		if n.Name == "" && !stateDepHasPrefix(path, n.Path) ||
+			n.Name != "" && !stateDepPathEqual(path, n.Path) {
+			continue
+		}
+
+		for k, rd := range m.Resources {
+			if rd.Empty() {
+				continue
+			}
+
+			key, err := ParseResourceStateKey(k)
+			if err != nil {
+				continue
+			}
+
+			node := planExplainNode{Path: path, Name: planExplainResourceId(key)}
+			if n.Name != "" && node.Name != n.Name {
+				continue
+			}
+
+			result = append(result, &planExplainChange{
+				addr: &ResourceAddress{
+					Path:  path,
+					Mode:  key.Mode,
+					Type:  key.Type,
+					Name:  key.Name,
+					Index: key.Index,
+					Key:   key.Key,
+				},
+				node: node,
+				diff: rd,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].addr.String() < result[j].addr.String()
+	})
+	return result
+}
+
+// refs returns the dependencies of the given node in the configuration.
+func (e *planExplainer) refs(n planExplainNode) []planExplainRef {
+	if e.plan.Module == nil {
+		return nil
+	}
+
+	var result []planExplainRef
+	addRaw := func(path []string, key string, raw *config.RawConfig) {
+		if raw == nil {
+			return
+		}
+		for _, v := range raw.Variables {
+			if node, ok := planExplainVariableNode(path, v); ok {
+				result = append(result, planExplainRef{Key: key, Node: node})
+			}
+		}
+	}
+	addDependsOn := func(path []string, deps []string) {
+		for _, dep := range deps {
+			node := planExplainNode{Path: path, Name: dep}
+			if strings.HasPrefix(dep, "module.") {
+				node = planExplainNode{
+					Path: stateDepChildPath(path, strings.TrimPrefix(dep, "module.")),
+				}
+			}
+			result = append(result, planExplainRef{Node: node})
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(n.Name, "output."):
+		mod := e.plan.Module.Child(n.Path)
+		if mod == nil {
+			return nil
+		}
+		name := strings.TrimPrefix(n.Name, "output.")
+		for _, o := range mod.Config().Outputs {
+			if o.Name == name {
+				addRaw(n.Path, "", o.RawConfig)
+				addDependsOn(n.Path, o.DependsOn)
+			}
+		}
+
+	case strings.HasPrefix(n.Name, "var."):
+		// Variables of the root module are set outside the configuration
+		if len(n.Path) == 0 {
+			return nil
+		}
+
+		parentPath := n.Path[:len(n.Path)-1]
+		parent := e.plan.Module.Child(parentPath)
+		if parent == nil {
+			return nil
+		}
+		name := strings.TrimPrefix(n.Name, "var.")
+		for _, m := range parent.Config().Modules {
+			if m.Name != n.Path[len(n.Path)-1] || m.RawConfig == nil {
+				continue
+			}
+
+			v, ok := m.RawConfig.Raw[name]
+			if !ok {
+				continue
+			}
+			raw, err := config.NewRawConfig(map[string]interface{}{name: v})
+			if err == nil {
+				addRaw(parentPath, "", raw)
+			}
+		}
+
+	case planExplainIsResource(n):
+		mod := e.plan.Module.Child(n.Path)
+		if mod == nil {
+			return nil
+		}
+		for _, r := range mod.Config().Resources {
+			if r.Id() != n.Name {
+				continue
+			}
+
+			addRaw(n.Path, "count", r.RawCount)
+			addRaw(n.Path, "for_each", r.RawForEach)
+			if r.RawConfig != nil {
+				for k, v := range r.RawConfig.Raw {
+					raw, err := config.NewRawConfig(map[string]interface{}{k: v})
+					if err == nil {
+						addRaw(n.Path, k, raw)
+					}
+				}
+			}
+			addDependsOn(n.Path, r.DependsOn)
+		}
+	}
+
+	return result
+}
+
+// planExplainVariableNode returns the node that an interpolated variable in
+// the module at path refers to, if it refers to one.
+func planExplainVariableNode(
+	path []string, v config.InterpolatedVariable) (planExplainNode, bool) {
+	switch v := v.(type) {
+	case *config.ResourceVariable:
+		return planExplainNode{Path: path, Name: v.ResourceId()}, true
+	case *config.ModuleVariable:
+		return planExplainNode{
+			Path: stateDepChildPath(path, v.Name),
+			Name: "output." + v.Field,
+		}, true
+	case *config.UserVariable:
+		return planExplainNode{Path: path, Name: "var." + v.Name}, true
+	}
+
+	return planExplainNode{}, false
+}
+
+func planExplainIsResource(n planExplainNode) bool {
+	return n.Name != "" &&
+		!strings.HasPrefix(n.Name, "output.") &&
+		!strings.HasPrefix(n.Name, "var.")
+}
+
+// planExplainResourceId returns the ID of the resource in the configuration
+// that the instance with the given key belongs to.
+func planExplainResourceId(key *ResourceStateKey) string {
+	return (&ResourceStateKey{
+		Mode:  key.Mode,
+		Type:  key.Type,
+		Name:  key.Name,
+		Index: -1,
+	}).String()
+}
+
+// planExplainResourceAddress returns the address of the resource node n.
+func planExplainResourceAddress(n planExplainNode) *ResourceAddress {
+	addr, err := parseResourceAddressInternal(n.Name)
+	if err != nil {
+		return &ResourceAddress{Path: n.Path, Name: n.Name, Index: -1}
+	}
+
+	addr.Path = n.Path
+	return addr
+}
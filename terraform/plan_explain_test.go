@@ -0,0 +1,184 @@
+package terraform
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestPlanExplain(t *testing.T) {
+	replace := func() *InstanceDiff {
+		return &InstanceDiff{
+			Attributes: map[string]*ResourceAttrDiff{
+				"ami": &ResourceAttrDiff{
+					Old:         "ami-1",
+					NewComputed: true,
+					RequiresNew: true,
+				},
+				"security_groups.#": &ResourceAttrDiff{
+					Old:         "1",
+					NewComputed: true,
+					RequiresNew: true,
+				},
+			},
+			Destroy: true,
+		}
+	}
+
+	plan := &Plan{
+		Module: testModule(t, "plan-explain"),
+		Diff: &Diff{
+			Modules: []*ModuleDiff{
+				&ModuleDiff{
+					Path: rootModulePath,
+					Resources: map[string]*InstanceDiff{
+						"aws_vpc.main": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"tags.Name": &ResourceAttrDiff{Old: "a", New: "b"},
+							},
+						},
+						"aws_security_group.sg": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"name": &ResourceAttrDiff{
+									Old:         "a",
+									New:         "b",
+									RequiresNew: true,
+								},
+							},
+							Destroy: true,
+						},
+						"aws_instance.other": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"tags.Name": &ResourceAttrDiff{Old: "a", New: "b"},
+							},
+						},
+						"aws_instance.web.0": replace(),
+						"aws_instance.web.1": replace(),
+					},
+				},
+				&ModuleDiff{
+					Path: []string{"root", "child"},
+					Resources: map[string]*InstanceDiff{
+						"aws_ami.base": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"vpc": &ResourceAttrDiff{
+									Old:         "vpc-1",
+									NewComputed: true,
+									RequiresNew: true,
+								},
+							},
+							Destroy: true,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	actual, err := plan.Explain("aws_instance.web[1]")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []*PlanExplanation{
+		{
+			Address:    "aws_instance.web[1]",
+			ChangeType: DiffDestroyCreate,
+			Attributes: []*PlanExplainedAttribute{
+				{
+					Key:      "ami",
+					Diff:     plan.Diff.RootModule().Resources["aws_instance.web.1"].Attributes["ami"],
+					Upstream: []string{"aws_vpc.main", "module.child.aws_ami.base"},
+				},
+				{
+					Key:      "security_groups.#",
+					Diff:     plan.Diff.RootModule().Resources["aws_instance.web.1"].Attributes["security_groups.#"],
+					Upstream: []string{"aws_security_group.sg"},
+				},
+			},
+			Upstream: []*PlanUpstreamChange{
+				{
+					Address:    "aws_instance.other",
+					ChangeType: DiffUpdate,
+				},
+				{
+					Address:    "aws_security_group.sg",
+					ChangeType: DiffDestroyCreate,
+				},
+				{
+					Address:    "aws_vpc.main",
+					ChangeType: DiffUpdate,
+					Through:    []string{"module.child.aws_ami.base"},
+				},
+				{
+					Address:    "module.child.aws_ami.base",
+					ChangeType: DiffDestroyCreate,
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad:\n\n%s", testPlanExplainString(actual))
+	}
+
+	// Every instance of a resource is explained
+	actual, err = plan.Explain("aws_instance.web")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(actual) != 2 ||
+		actual[0].Address != "aws_instance.web[0]" ||
+		actual[1].Address != "aws_instance.web[1]" {
+		t.Fatalf("bad:\n\n%s", testPlanExplainString(actual))
+	}
+}
+
+func TestPlanExplain_noChanges(t *testing.T) {
+	plan := &Plan{
+		Module: testModule(t, "plan-explain"),
+		Diff:   new(Diff),
+	}
+
+	actual, err := plan.Explain("aws_instance.unchanged")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []*PlanExplanation{
+		{
+			Address:    "aws_instance.unchanged",
+			ChangeType: DiffNone,
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad:\n\n%s", testPlanExplainString(actual))
+	}
+}
+
+func TestPlanExplain_invalid(t *testing.T) {
+	plan := &Plan{
+		Module: testModule(t, "plan-explain"),
+		Diff:   new(Diff),
+	}
+
+	for _, addr := range []string{"module.child", "not an address"} {
+		if _, err := plan.Explain(addr); err == nil {
+			t.Fatalf("%s: should error", addr)
+		}
+	}
+}
+
+func testPlanExplainString(es []*PlanExplanation) string {
+	var result string
+	for _, e := range es {
+		result += fmt.Sprintf("%s %d\n", e.Address, e.ChangeType)
+		for _, a := range e.Attributes {
+			result += fmt.Sprintf("  %s: %v\n", a.Key, a.Upstream)
+		}
+		for _, u := range e.Upstream {
+			result += fmt.Sprintf("  <- %s %d %v\n", u.Address, u.ChangeType, u.Through)
+		}
+	}
+
+	return result
+}
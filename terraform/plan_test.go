@@ -2,6 +2,7 @@ package terraform
 
 import (
 	"bytes"
+	"encoding/gob"
 	"reflect"
 	"strings"
 	"testing"
@@ -118,3 +119,193 @@ func TestReadWritePlan(t *testing.T) {
 		t.Fatalf("bad:\n\n%s\n\nexpected:\n\n%s", actualStr, expectedStr)
 	}
 }
+
+func TestWritePlan_moduleOrder(t *testing.T) {
+	root := &ModuleDiff{
+		Path: rootModulePath,
+		Resources: map[string]*InstanceDiff{
+			"aws_instance.foo": &InstanceDiff{Destroy: true},
+		},
+	}
+	child := &ModuleDiff{
+		Path: []string{"root", "child"},
+		Resources: map[string]*InstanceDiff{
+			"aws_instance.foo": &InstanceDiff{Destroy: true},
+		},
+	}
+
+	var first []byte
+	for _, modules := range [][]*ModuleDiff{{root, child}, {child, root}} {
+		plan := &Plan{
+			Module: testModule(t, "new-good"),
+			Diff:   &Diff{Modules: modules},
+		}
+
+		var buf bytes.Buffer
+		if err := WritePlan(plan, &buf); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if first == nil {
+			first = buf.Bytes()
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), first) {
+			t.Fatalf("expected the same plan file, got:\n\n%s\n\n%s", first, buf.Bytes())
+		}
+
+		// The modules of the plan that was written aren't reordered
+		if plan.Diff.Modules[0] != modules[0] {
+			t.Fatal("expected the modules of the plan to be left as they were")
+		}
+	}
+}
+
+func TestReadWritePlan_types(t *testing.T) {
+	plan := &Plan{
+		Module: testModule(t, "new-good"),
+		Diff: &Diff{
+			Modules: []*ModuleDiff{
+				&ModuleDiff{
+					Path: rootModulePath,
+					Resources: map[string]*InstanceDiff{
+						"aws_instance.foo": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"count": &ResourceAttrDiff{
+									Old:      "1",
+									New:      "2",
+									NewExtra: 2,
+									Type:     DiffAttrInput,
+								},
+							},
+							Meta: map[string]interface{}{
+								"schema_version": "1",
+								"timeouts": map[string]interface{}{
+									"create": int64(600000000000),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Vars: map[string]interface{}{
+			"list": []interface{}{"a", "b"},
+			"map":  map[string]interface{}{"a": "b"},
+		},
+		Targets:          []string{"aws_instance.foo"},
+		TerraformVersion: VersionString(),
+		ProviderSHA256s: map[string][]byte{
+			"aws": []byte("placeholder"),
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := WritePlan(plan, buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ReadPlan(buf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual.Diff, plan.Diff) {
+		t.Fatalf("bad diff:\n\n%#v\n\nexpected:\n\n%#v", actual.Diff, plan.Diff)
+	}
+	if !reflect.DeepEqual(actual.Vars, plan.Vars) {
+		t.Fatalf("bad vars: %#v", actual.Vars)
+	}
+	if !reflect.DeepEqual(actual.Targets, plan.Targets) {
+		t.Fatalf("bad targets: %#v", actual.Targets)
+	}
+	if actual.TerraformVersion != plan.TerraformVersion {
+		t.Fatalf("bad version: %s", actual.TerraformVersion)
+	}
+	if !reflect.DeepEqual(actual.ProviderSHA256s, plan.ProviderSHA256s) {
+		t.Fatalf("bad provider hashes: %#v", actual.ProviderSHA256s)
+	}
+	if actual.Module.String() != plan.Module.String() {
+		t.Fatalf("bad module:\n\n%s\n\nexpected:\n\n%s", actual.Module, plan.Module)
+	}
+}
+
+func TestReadPlan_gob(t *testing.T) {
+	plan := &Plan{
+		Module: testModule(t, "new-good"),
+		Diff: &Diff{
+			Modules: []*ModuleDiff{
+				&ModuleDiff{
+					Path: rootModulePath,
+					Resources: map[string]*InstanceDiff{
+						"nodeA": &InstanceDiff{
+							Attributes: map[string]*ResourceAttrDiff{
+								"foo": &ResourceAttrDiff{
+									Old: "foo",
+									New: "bar",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Vars: map[string]interface{}{
+			"foo": "bar",
+		},
+	}
+
+	// Plans written before the current format was introduced are still
+	// readable.
+	buf := new(bytes.Buffer)
+	buf.WriteString(planFormatMagic)
+	buf.WriteByte(planFormatVersionGob)
+	if err := gob.NewEncoder(buf).Encode(plan); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ReadPlan(buf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actualStr := strings.TrimSpace(actual.String())
+	expectedStr := strings.TrimSpace(plan.String())
+	if actualStr != expectedStr {
+		t.Fatalf("bad:\n\n%s\n\nexpected:\n\n%s", actualStr, expectedStr)
+	}
+}
+
+func TestReadPlan_truncated(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := WritePlan(&Plan{}, buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	data := buf.Bytes()
+	if _, err := ReadPlan(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestPlanVersionCompatible(t *testing.T) {
+	cases := []struct {
+		Plan, This string
+		Result     bool
+	}{
+		{"0.10.0", "0.10.0", true},
+		{"0.10.0-dev", "0.10.0-dev", true},
+		{"0.10.0", "0.10.1", true},
+		{"0.10.0-beta1", "0.10.0", true},
+		{"0.10.1", "0.10.0", false},
+		{"0.9.8", "0.10.0", false},
+		{"0.10.0", "0.11.0", false},
+		{"1.10.0", "0.10.0", false},
+		{"bogus", "0.10.0", false},
+	}
+
+	for _, tc := range cases {
+		if actual := planVersionCompatible(tc.Plan, tc.This); actual != tc.Result {
+			t.Errorf("%s with %s: got %t, want %t", tc.Plan, tc.This, actual, tc.Result)
+		}
+	}
+}
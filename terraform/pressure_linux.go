@@ -0,0 +1,48 @@
+package terraform
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// systemPressure returns true if the system is under CPU pressure, with a
+// load average above the number of CPUs, or under memory pressure, with
+// less than a tenth of its memory available.
+func systemPressure() bool {
+	if raw, err := ioutil.ReadFile("/proc/loadavg"); err == nil {
+		fields := strings.Fields(string(raw))
+		if len(fields) > 0 {
+			load, err := strconv.ParseFloat(fields[0], 64)
+			if err == nil && load > float64(runtime.NumCPU()) {
+				return true
+			}
+		}
+	}
+
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	var total, available int64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total, _ = strconv.ParseInt(fields[1], 10, 64)
+		case "MemAvailable:":
+			available, _ = strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+
+	return total > 0 && available > 0 && available < total/10
+}
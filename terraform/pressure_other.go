@@ -0,0 +1,10 @@
+// +build !linux
+
+package terraform
+
+// systemPressure returns true if the system is under CPU or memory
+// pressure. The load of the system is only known on Linux, so elsewhere
+// parallelism is only tuned by how provider operations fare.
+func systemPressure() bool {
+	return false
+}
@@ -0,0 +1,132 @@
+package terraform
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// refreshBatchWait is how long a batch of refreshes waits for more
+	// refreshes to join it before it's sent to the provider.
+	refreshBatchWait = 20 * time.Millisecond
+
+	// refreshBatchMax is the most refreshes that are sent to the provider
+	// in one batch.
+	refreshBatchMax = 100
+)
+
+// refreshBatcher batches together the refreshes for a provider that
+// implements ResourceProviderBatchRefresher.
+//
+// The first refresh starts a batch, which is sent to the provider once
+// refreshBatchWait has passed or it has refreshBatchMax refreshes. If the
+// provider turns out not to support batches, the refreshes in the batch and
+// all later refreshes are sent to the provider one at a time.
+type refreshBatcher struct {
+	Provider ResourceProvider
+
+	lock        sync.Mutex
+	pending     *refreshBatch
+	unsupported bool
+}
+
+// refreshBatch is a single batch of refreshes. The results are only set
+// once done is closed.
+type refreshBatch struct {
+	Info    []*InstanceInfo
+	State   []*InstanceState
+	Results []*InstanceState
+	Errors  []error
+
+	done chan struct{}
+}
+
+// Refresh refreshes a single resource as part of a batch, blocking until
+// the batch is complete.
+func (b *refreshBatcher) Refresh(
+	info *InstanceInfo, state *InstanceState) (*InstanceState, error) {
+	b.lock.Lock()
+	if b.unsupported {
+		b.lock.Unlock()
+		return b.Provider.Refresh(info, state)
+	}
+
+	batch := b.pending
+	if batch == nil {
+		batch = &refreshBatch{done: make(chan struct{})}
+		b.pending = batch
+		time.AfterFunc(refreshBatchWait, func() { b.send(batch) })
+	}
+
+	idx := len(batch.Info)
+	batch.Info = append(batch.Info, info)
+	batch.State = append(batch.State, state)
+	full := len(batch.Info) >= refreshBatchMax
+	b.lock.Unlock()
+
+	if full {
+		b.send(batch)
+	}
+
+	<-batch.done
+	return batch.Results[idx], batch.Errors[idx]
+}
+
+// send sends the batch to the provider, if it hasn't been sent already.
+func (b *refreshBatcher) send(batch *refreshBatch) {
+	b.lock.Lock()
+	if b.pending != batch {
+		// Already sent because it was full
+		b.lock.Unlock()
+		return
+	}
+	b.pending = nil
+	b.lock.Unlock()
+
+	defer close(batch.done)
+
+	n := len(batch.Info)
+	batch.Results = make([]*InstanceState, n)
+	batch.Errors = make([]error, n)
+
+	refreshLog.Debug("refreshing a batch of %d resources", n)
+	results, err := b.Provider.(ResourceProviderBatchRefresher).RefreshBatch(
+		batch.Info, batch.State)
+	if err == ErrRefreshBatchUnsupported {
+		refreshLog.Debug("provider doesn't support batches")
+
+		b.lock.Lock()
+		b.unsupported = true
+		b.lock.Unlock()
+
+		// These were all running at the same time before they were
+		// batched, so they still run at the same time.
+		var wg sync.WaitGroup
+		for i := range batch.Info {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				batch.Results[i], batch.Errors[i] = b.Provider.Refresh(
+					batch.Info[i], batch.State[i])
+			}(i)
+		}
+		wg.Wait()
+
+		return
+	}
+
+	if err == nil && len(results) != n {
+		err = fmt.Errorf(
+			"provider returned %d states for a batch of %d resources",
+			len(results), n)
+	}
+	for i := range batch.Info {
+		if err != nil {
+			batch.Errors[i] = err
+			continue
+		}
+
+		batch.Results[i] = results[i]
+	}
+}
@@ -0,0 +1,117 @@
+package terraform
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRefreshBatcher(t *testing.T) {
+	p := new(MockResourceProvider)
+	p.RefreshBatchFn = func(
+		info []*InstanceInfo, states []*InstanceState) ([]*InstanceState, error) {
+		result := make([]*InstanceState, len(states))
+		for i, s := range states {
+			result[i] = &InstanceState{ID: s.ID + "-refreshed"}
+		}
+
+		return result, nil
+	}
+
+	b := &refreshBatcher{Provider: p}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			id := fmt.Sprintf("foo%d", i)
+			s, err := b.Refresh(&InstanceInfo{Id: id}, &InstanceState{ID: id})
+			if err == nil && s.ID != id+"-refreshed" {
+				err = fmt.Errorf("bad: %#v", s)
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+	}
+	if p.RefreshCalled {
+		t.Fatal("refresh should not be called")
+	}
+}
+
+func TestRefreshBatcher_max(t *testing.T) {
+	p := new(MockResourceProvider)
+
+	var lock sync.Mutex
+	var sizes []int
+	p.RefreshBatchFn = func(
+		info []*InstanceInfo, states []*InstanceState) ([]*InstanceState, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		sizes = append(sizes, len(states))
+		return states, nil
+	}
+
+	b := &refreshBatcher{Provider: p}
+
+	var wg sync.WaitGroup
+	for i := 0; i < refreshBatchMax+1; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Refresh(&InstanceInfo{Id: "foo"}, &InstanceState{ID: "foo"})
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for _, n := range sizes {
+		if n > refreshBatchMax {
+			t.Fatalf("bad: %#v", sizes)
+		}
+		total += n
+	}
+	if total != refreshBatchMax+1 {
+		t.Fatalf("bad: %#v", sizes)
+	}
+}
+
+func TestRefreshBatcher_unsupported(t *testing.T) {
+	p := new(MockResourceProvider)
+	p.RefreshReturn = &InstanceState{ID: "bar"}
+
+	b := &refreshBatcher{Provider: p}
+	for i := 0; i < 2; i++ {
+		s, err := b.Refresh(&InstanceInfo{Id: "foo"}, &InstanceState{ID: "foo"})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if s.ID != "bar" {
+			t.Fatalf("bad: %#v", s)
+		}
+	}
+
+	if !b.unsupported {
+		t.Fatal("should be unsupported")
+	}
+}
+
+func TestRefreshBatcher_badLength(t *testing.T) {
+	p := new(MockResourceProvider)
+	p.RefreshBatchReturn = []*InstanceState{}
+
+	b := &refreshBatcher{Provider: p}
+	_, err := b.Refresh(&InstanceInfo{Id: "foo"}, &InstanceState{ID: "foo"})
+	if err == nil || !strings.Contains(err.Error(), "returned 0 states") {
+		t.Fatalf("bad: %v", err)
+	}
+}
@@ -34,6 +34,15 @@ type Resource struct {
 	Type       string
 	CountIndex int
 
+	// EachKey and EachValue are the key and value of this instance
+	// within the for_each of its resource, if it has one.
+	EachKey   string
+	EachValue interface{}
+
+	// ProviderName is the provider configuration selected for this
+	// instance, if its resource selects it with an expression.
+	ProviderName string
+
 	// These aren't really used anymore anywhere, but we keep them around
 	// since we haven't done a proper cleanup yet.
 	Id           string
@@ -139,6 +148,32 @@ func (c *ResourceConfig) DeepCopy() *ResourceConfig {
 	return result
 }
 
+// WithDefaults returns a copy of c in which the keys of defaults that
+// aren't set in c are set to their values in defaults.
+func (c *ResourceConfig) WithDefaults(defaults map[string]string) *ResourceConfig {
+	result := c.DeepCopy()
+	if result == nil {
+		result = new(ResourceConfig)
+	}
+	if result.Raw == nil {
+		result.Raw = make(map[string]interface{})
+	}
+	if result.Config == nil {
+		result.Config = make(map[string]interface{})
+	}
+
+	for k, v := range defaults {
+		if _, ok := result.Raw[k]; ok {
+			continue
+		}
+
+		result.Raw[k] = v
+		result.Config[k] = v
+	}
+
+	return result
+}
+
 // Equal checks the equality of two resource configs.
 func (c *ResourceConfig) Equal(c2 *ResourceConfig) bool {
 	// If either are nil, then they're only equal if they're both nil
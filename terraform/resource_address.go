@@ -21,6 +21,11 @@ type ResourceAddress struct {
 	// Addresses a specific resource that occurs in a list
 	Index int
 
+	// Addresses a specific instance of a resource that uses for_each,
+	// such as "aws_instance.web[\"foo\"]". This is empty for resources
+	// that are not expanded with for_each.
+	Key string
+
 	InstanceType    InstanceType
 	InstanceTypeSet bool
 	Name            string
@@ -37,6 +42,7 @@ func (r *ResourceAddress) Copy() *ResourceAddress {
 	n := &ResourceAddress{
 		Path:         make([]string, 0, len(r.Path)),
 		Index:        r.Index,
+		Key:          r.Key,
 		InstanceType: r.InstanceType,
 		Name:         r.Name,
 		Type:         r.Type,
@@ -84,6 +90,9 @@ func (r *ResourceAddress) String() string {
 		if r.Index >= 0 {
 			name += fmt.Sprintf("[%d]", r.Index)
 		}
+		if r.Key != "" {
+			name += fmt.Sprintf("[%s]", strconv.Quote(r.Key))
+		}
 		result = append(result, name)
 	}
 
@@ -151,6 +160,9 @@ func (r *ResourceAddress) stateId() string {
 	if r.Index >= 0 {
 		result += fmt.Sprintf(".%d", r.Index)
 	}
+	if r.Key != "" {
+		result += fmt.Sprintf("[%s]", strconv.Quote(r.Key))
+	}
 
 	return result
 }
@@ -169,6 +181,13 @@ func parseResourceAddressConfig(r *config.Resource) (*ResourceAddress, error) {
 // parseResourceAddressInternal parses the somewhat bespoke resource
 // identifier used in states and diffs, such as "instance.name.0".
 func parseResourceAddressInternal(s string) (*ResourceAddress, error) {
+	// Instances of a for_each resource have a trailing quoted key which
+	// may itself contain dots, so we strip it before splitting.
+	s, key, err := splitResourceInstanceKey(s)
+	if err != nil {
+		return nil, err
+	}
+
 	// Split based on ".". Every resource address should have at least two
 	// elements (type and name).
 	parts := strings.Split(s, ".")
@@ -193,12 +212,18 @@ func parseResourceAddressInternal(s string) (*ResourceAddress, error) {
 		Type:         parts[0],
 		Name:         parts[1],
 		Index:        -1,
+		Key:          key,
 		InstanceType: TypePrimary,
 		Mode:         mode,
 	}
 
 	// If we have more parts, then we have an index. Parse that.
 	if len(parts) > 2 {
+		if key != "" {
+			return nil, fmt.Errorf(
+				"Invalid internal resource address format: %s", s)
+		}
+
 		idx, err := strconv.ParseInt(parts[2], 0, 0)
 		if err != nil {
 			return nil, fmt.Errorf("Error parsing resource address %q: %s", s, err)
@@ -210,6 +235,27 @@ func parseResourceAddressInternal(s string) (*ResourceAddress, error) {
 	return addr, nil
 }
 
+// splitResourceInstanceKey splits the trailing for_each instance key, such
+// as `["foo"]`, from an internal resource identifier. If the identifier
+// has no instance key then it is returned as-is along with an empty key.
+func splitResourceInstanceKey(s string) (string, string, error) {
+	if !strings.HasSuffix(s, "]") {
+		return s, "", nil
+	}
+
+	idx := strings.Index(s, "[")
+	if idx == -1 {
+		return "", "", fmt.Errorf("Invalid resource instance key: %s", s)
+	}
+
+	key, err := strconv.Unquote(s[idx+1 : len(s)-1])
+	if err != nil || key == "" {
+		return "", "", fmt.Errorf("Invalid resource instance key: %s", s)
+	}
+
+	return s[:idx], key, nil
+}
+
 func ParseResourceAddress(s string) (*ResourceAddress, error) {
 	matches, err := tokenizeResourceAddress(s)
 	if err != nil {
@@ -223,6 +269,10 @@ func ParseResourceAddress(s string) (*ResourceAddress, error) {
 	if err != nil {
 		return nil, err
 	}
+	resourceKey, err := ParseResourceKey(matches["key"])
+	if err != nil {
+		return nil, err
+	}
 	instanceType, err := ParseInstanceType(matches["instance_type"])
 	if err != nil {
 		return nil, err
@@ -240,6 +290,7 @@ func ParseResourceAddress(s string) (*ResourceAddress, error) {
 	return &ResourceAddress{
 		Path:            path,
 		Index:           resourceIndex,
+		Key:             resourceKey,
 		InstanceType:    instanceType,
 		InstanceTypeSet: matches["instance_type"] != "",
 		Name:            matches["name"],
@@ -261,6 +312,12 @@ func (addr *ResourceAddress) Equals(raw interface{}) bool {
 		other.Index == -1 ||
 		addr.Index == other.Index
 
+	// An address with neither an index nor a key matches every instance,
+	// otherwise the keys must agree.
+	keyMatch := addr.Key == other.Key ||
+		addr.Key == "" && addr.Index == -1 ||
+		other.Key == "" && other.Index == -1
+
 	nameMatch := addr.Name == "" ||
 		other.Name == "" ||
 		addr.Name == other.Name
@@ -276,6 +333,7 @@ func (addr *ResourceAddress) Equals(raw interface{}) bool {
 
 	return pathMatch &&
 		indexMatch &&
+		keyMatch &&
 		addr.InstanceType == other.InstanceType &&
 		nameMatch &&
 		typeMatch &&
@@ -289,6 +347,15 @@ func ParseResourceIndex(s string) (int, error) {
 	return strconv.Atoi(s)
 }
 
+// ParseResourceKey parses the quoted for_each instance key of a resource
+// address, such as `"foo"`. An empty string returns an empty key.
+func ParseResourceKey(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	return strconv.Unquote(s)
+}
+
 func ParseResourcePath(s string) []string {
 	if s == "" {
 		return nil
@@ -331,8 +398,8 @@ func tokenizeResourceAddress(s string) (map[string]string, error) {
 		`(?:(?P<type>[^.]+)\.(?P<name>[^.[]+))?` +
 		// "tainted" (optional, omission implies: "primary")
 		`(?:\.(?P<instance_type>\w+))?` +
-		// "1" (optional, omission implies: "0")
-		`(?:\[(?P<index>\d+)\])?` +
+		// "1" or "\"foo\"" (optional, omission implies: "0")
+		`(?:\[(?:(?P<index>\d+)|(?P<key>"(?:[^"\\]|\\.)+"))\])?` +
 		`\z`)
 
 	groupNames := re.SubexpNames()
@@ -39,6 +39,19 @@ func TestParseResourceAddressInternal(t *testing.T) {
 			"aws_instance.foo[1]",
 		},
 
+		"basic resource with for_each key": {
+			`aws_instance.foo["a.b"]`,
+			&ResourceAddress{
+				Mode:         config.ManagedResourceMode,
+				Type:         "aws_instance",
+				Name:         "foo",
+				InstanceType: TypePrimary,
+				Index:        -1,
+				Key:          "a.b",
+			},
+			`aws_instance.foo["a.b"]`,
+		},
+
 		"data resource": {
 			"data.aws_ami.foo",
 			&ResourceAddress{
@@ -133,6 +146,33 @@ func TestParseResourceAddress(t *testing.T) {
 			"",
 			false,
 		},
+		"implicit primary, explicit key": {
+			`aws_instance.foo["bar"]`,
+			&ResourceAddress{
+				Mode:         config.ManagedResourceMode,
+				Type:         "aws_instance",
+				Name:         "foo",
+				InstanceType: TypePrimary,
+				Index:        -1,
+				Key:          "bar",
+			},
+			"",
+			false,
+		},
+		"implicit primary, explicit key with dots and quotes": {
+			`module.child.aws_instance.foo["a.\"b\""]`,
+			&ResourceAddress{
+				Path:         []string{"child"},
+				Mode:         config.ManagedResourceMode,
+				Type:         "aws_instance",
+				Name:         "foo",
+				InstanceType: TypePrimary,
+				Index:        -1,
+				Key:          `a."b"`,
+			},
+			"",
+			false,
+		},
 		"implicit primary, explicit index": {
 			"aws_instance.foo[2]",
 			&ResourceAddress{
@@ -327,6 +367,80 @@ func TestResourceAddressEquals(t *testing.T) {
 			},
 			Expect: true,
 		},
+		"key match": {
+			Address: &ResourceAddress{
+				Mode:         config.ManagedResourceMode,
+				Type:         "aws_instance",
+				Name:         "foo",
+				InstanceType: TypePrimary,
+				Index:        -1,
+				Key:          "a",
+			},
+			Other: &ResourceAddress{
+				Mode:         config.ManagedResourceMode,
+				Type:         "aws_instance",
+				Name:         "foo",
+				InstanceType: TypePrimary,
+				Index:        -1,
+				Key:          "a",
+			},
+			Expect: true,
+		},
+		"key mismatch": {
+			Address: &ResourceAddress{
+				Mode:         config.ManagedResourceMode,
+				Type:         "aws_instance",
+				Name:         "foo",
+				InstanceType: TypePrimary,
+				Index:        -1,
+				Key:          "a",
+			},
+			Other: &ResourceAddress{
+				Mode:         config.ManagedResourceMode,
+				Type:         "aws_instance",
+				Name:         "foo",
+				InstanceType: TypePrimary,
+				Index:        -1,
+				Key:          "b",
+			},
+			Expect: false,
+		},
+		"key against index": {
+			Address: &ResourceAddress{
+				Mode:         config.ManagedResourceMode,
+				Type:         "aws_instance",
+				Name:         "foo",
+				InstanceType: TypePrimary,
+				Index:        -1,
+				Key:          "a",
+			},
+			Other: &ResourceAddress{
+				Mode:         config.ManagedResourceMode,
+				Type:         "aws_instance",
+				Name:         "foo",
+				InstanceType: TypePrimary,
+				Index:        0,
+			},
+			Expect: false,
+		},
+		"other does not set key": {
+			Address: &ResourceAddress{
+				Mode:         config.ManagedResourceMode,
+				Type:         "aws_instance",
+				Name:         "foo",
+				InstanceType: TypePrimary,
+				Index:        -1,
+				Key:          "a",
+			},
+			Other: &ResourceAddress{
+				Mode:         config.ManagedResourceMode,
+				Type:         "aws_instance",
+				Name:         "foo",
+				InstanceType: TypePrimary,
+				Index:        -1,
+			},
+			Expect: true,
+		},
 		"address does not set index": {
 			Address: &ResourceAddress{
 				Mode:         config.ManagedResourceMode,
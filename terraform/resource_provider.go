@@ -168,6 +168,85 @@ type ResourceProviderCloser interface {
 	Close() error
 }
 
+// ResourceProviderCanceler is an interface that providers that can cancel
+// their in-flight actions gracefully, rather than halting them, must
+// implement. Providers that don't implement it are stopped with Stop on the
+// first interrupt.
+type ResourceProviderCanceler interface {
+	// Cancel is called on the first interrupt, to gracefully cancel the
+	// in-flight actions rather than halting them.
+	//
+	// The provider should stop starting new remote operations and stop
+	// polling for the ones in progress, and return from Apply as soon as it
+	// can with the state of what it has created so far, along with an
+	// error. That state is recorded, so that resources that were created
+	// aren't orphaned. Like Stop, Cancel shouldn't block.
+	//
+	// Providers that haven't returned a short time after Cancel are
+	// stopped with Stop.
+	Cancel() error
+}
+
+// ResourceProviderReadyWaiter is an interface that providers that can wait
+// for a newly created resource to be ready for use, such as for an instance
+// to pass its status checks, must implement. It is only called for resources
+// that have wait_for_ready set in their lifecycle.
+type ResourceProviderReadyWaiter interface {
+	// WaitForReady waits for a resource that was just created to be ready,
+	// and returns its state once it is, since it may have changed while the
+	// resource was becoming ready. A nil state means that it didn't change.
+	//
+	// If the provider can't wait for resources of this type, it returns
+	// ErrWaitForReadyUnsupported.
+	WaitForReady(*InstanceInfo, *InstanceState) (*InstanceState, error)
+}
+
+// ErrWaitForReadyUnsupported is returned by WaitForReady for resources
+// that the provider can't wait for.
+var ErrWaitForReadyUnsupported = errors.New(
+	"provider doesn't support waiting for this resource to be ready")
+
+// ResourceProviderAdopter is an interface that providers that can look up
+// an existing object by the configuration of a resource, such as by its
+// name, must implement. It is only called for resources that have
+// adopt_existing set in their lifecycle and aren't in the state yet.
+type ResourceProviderAdopter interface {
+	// LookupExisting returns the state of the existing object that matches
+	// the configuration, or nil if there isn't one. The configuration can
+	// have computed values, which aren't known yet.
+	//
+	// If the provider can't look up resources of this type, it returns
+	// ErrLookupExistingUnsupported.
+	LookupExisting(*InstanceInfo, *ResourceConfig) (*InstanceState, error)
+}
+
+// ErrLookupExistingUnsupported is returned by LookupExisting for resources
+// that the provider can't look up.
+var ErrLookupExistingUnsupported = errors.New(
+	"provider doesn't support looking up existing objects for this resource")
+
+// ResourceProviderBatchRefresher is an interface that providers that can
+// refresh many resources with a single request must implement. Terraform
+// batches together the refreshes for a provider that run at the same time
+// and refreshes them with one call to RefreshBatch instead of many calls to
+// Refresh.
+type ResourceProviderBatchRefresher interface {
+	// RefreshBatch refreshes the resources with the given info and states,
+	// which can be of different types, and returns their new states in the
+	// same order. As with Refresh, a nil state means that the resource no
+	// longer exists. An error fails the refresh of every resource in the
+	// batch.
+	//
+	// If the provider can't refresh resources in batches, it returns
+	// ErrRefreshBatchUnsupported, and Refresh is used instead.
+	RefreshBatch([]*InstanceInfo, []*InstanceState) ([]*InstanceState, error)
+}
+
+// ErrRefreshBatchUnsupported is returned by RefreshBatch for providers
+// that can't refresh resources in batches.
+var ErrRefreshBatchUnsupported = errors.New(
+	"provider doesn't support refreshing resources in batches")
+
 // ResourceType is a type of resource that a resource provider can manage.
 type ResourceType struct {
 	Name       string // Name of the resource, example "instance" (no provider prefix)
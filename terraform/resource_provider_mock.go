@@ -59,6 +59,9 @@ type MockResourceProvider struct {
 	StopCalled                     bool
 	StopFn                         func() error
 	StopReturnError                error
+	CancelCalled                   bool
+	CancelFn                       func() error
+	CancelReturnError              error
 	DataSourcesCalled              bool
 	DataSourcesReturn              []DataSource
 	ValidateCalled                 bool
@@ -85,6 +88,27 @@ type MockResourceProvider struct {
 	ImportStateReturn      []*InstanceState
 	ImportStateReturnError error
 	ImportStateFn          func(*InstanceInfo, string) ([]*InstanceState, error)
+
+	WaitForReadyCalled      bool
+	WaitForReadyInfo        *InstanceInfo
+	WaitForReadyState       *InstanceState
+	WaitForReadyReturn      *InstanceState
+	WaitForReadyReturnError error
+	WaitForReadyFn          func(*InstanceInfo, *InstanceState) (*InstanceState, error)
+
+	LookupExistingCalled      bool
+	LookupExistingInfo        *InstanceInfo
+	LookupExistingConfig      *ResourceConfig
+	LookupExistingReturn      *InstanceState
+	LookupExistingReturnError error
+	LookupExistingFn          func(*InstanceInfo, *ResourceConfig) (*InstanceState, error)
+
+	RefreshBatchCalled      bool
+	RefreshBatchInfo        []*InstanceInfo
+	RefreshBatchState       []*InstanceState
+	RefreshBatchReturn      []*InstanceState
+	RefreshBatchReturnError error
+	RefreshBatchFn          func([]*InstanceInfo, []*InstanceState) ([]*InstanceState, error)
 }
 
 func (p *MockResourceProvider) Close() error {
@@ -156,6 +180,18 @@ func (p *MockResourceProvider) Stop() error {
 	return p.StopReturnError
 }
 
+func (p *MockResourceProvider) Cancel() error {
+	p.Lock()
+	defer p.Unlock()
+
+	p.CancelCalled = true
+	if p.CancelFn != nil {
+		return p.CancelFn()
+	}
+
+	return p.CancelReturnError
+}
+
 func (p *MockResourceProvider) Apply(
 	info *InstanceInfo,
 	state *InstanceState,
@@ -210,6 +246,67 @@ func (p *MockResourceProvider) Refresh(
 	return p.RefreshReturn.DeepCopy(), p.RefreshReturnError
 }
 
+func (p *MockResourceProvider) WaitForReady(
+	info *InstanceInfo,
+	s *InstanceState) (*InstanceState, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.WaitForReadyCalled = true
+	p.WaitForReadyInfo = info
+	p.WaitForReadyState = s
+
+	if p.WaitForReadyFn != nil {
+		return p.WaitForReadyFn(info, s)
+	}
+
+	return p.WaitForReadyReturn.DeepCopy(), p.WaitForReadyReturnError
+}
+
+func (p *MockResourceProvider) LookupExisting(
+	info *InstanceInfo,
+	c *ResourceConfig) (*InstanceState, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.LookupExistingCalled = true
+	p.LookupExistingInfo = info
+	p.LookupExistingConfig = c
+
+	if p.LookupExistingFn != nil {
+		return p.LookupExistingFn(info, c)
+	}
+
+	return p.LookupExistingReturn.DeepCopy(), p.LookupExistingReturnError
+}
+
+// RefreshBatch is unsupported unless RefreshBatchFn or one of the return
+// values is set, so that refreshes use Refresh by default.
+func (p *MockResourceProvider) RefreshBatch(
+	info []*InstanceInfo,
+	s []*InstanceState) ([]*InstanceState, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.RefreshBatchCalled = true
+	p.RefreshBatchInfo = info
+	p.RefreshBatchState = s
+
+	if p.RefreshBatchFn != nil {
+		return p.RefreshBatchFn(info, s)
+	}
+	if p.RefreshBatchReturn == nil && p.RefreshBatchReturnError == nil {
+		return nil, ErrRefreshBatchUnsupported
+	}
+
+	result := make([]*InstanceState, len(p.RefreshBatchReturn))
+	for i, r := range p.RefreshBatchReturn {
+		result[i] = r.DeepCopy()
+	}
+
+	return result, p.RefreshBatchReturnError
+}
+
 func (p *MockResourceProvider) Resources() []ResourceType {
 	p.Lock()
 	defer p.Unlock()
@@ -7,4 +7,8 @@ import (
 func TestMockResourceProvider_impl(t *testing.T) {
 	var _ ResourceProvider = new(MockResourceProvider)
 	var _ ResourceProviderCloser = new(MockResourceProvider)
+	var _ ResourceProviderCanceler = new(MockResourceProvider)
+	var _ ResourceProviderReadyWaiter = new(MockResourceProvider)
+	var _ ResourceProviderBatchRefresher = new(MockResourceProvider)
+	var _ ResourceProviderAdopter = new(MockResourceProvider)
 }
@@ -0,0 +1,123 @@
+package terraform
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// TransientError marks err as transient: the operation that failed with it,
+// such as a request that was throttled or failed with a 5xx response, is
+// expected to succeed if it's tried again. Providers should only mark
+// errors of operations that are safe to repeat.
+//
+// err is allowed to be nil.
+func TransientError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &transientError{Err: err}
+}
+
+// IsTransientError returns true if err was marked as transient with
+// TransientError.
+func IsTransientError(err error) bool {
+	t, ok := err.(interface {
+		Transient() bool
+	})
+
+	return ok && t.Transient()
+}
+
+type transientError struct {
+	Err error
+}
+
+func (e *transientError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *transientError) Transient() bool {
+	return true
+}
+
+// WrappedErrors implements errwrap.Wrapper.
+func (e *transientError) WrappedErrors() []error {
+	return []error{e.Err}
+}
+
+// RetryPolicy limits how provider operations that fail with transient
+// errors are retried.
+type RetryPolicy struct {
+	// MaxRetries is the most times an operation is retried after it first
+	// fails. Zero disables retries.
+	MaxRetries int
+
+	// MinWait is the wait before the first retry. The wait doubles with
+	// each retry, up to MaxWait.
+	MinWait time.Duration
+	MaxWait time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used when none is given to the
+// context.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxRetries: 3,
+		MinWait:    time.Second,
+		MaxWait:    30 * time.Second,
+	}
+}
+
+// Retry calls fn, and calls it again with backoff for as long as it fails
+// with a transient error, up to the limits of the policy. It returns the
+// error of the last call. If stopCh is closed while waiting, the error of
+// the last call is returned straight away.
+//
+// desc is the address of the resource that the operation is for, which the
+// retries are logged against.
+func (p *RetryPolicy) Retry(stopCh <-chan struct{}, desc string, fn func() error) error {
+	err := fn()
+	if p == nil {
+		return err
+	}
+
+	for i := 0; i < p.MaxRetries && IsTransientError(err); i++ {
+		wait := p.wait(i)
+		logger.Resource(desc).Warn("transient error, retrying in %s (%d/%d): %s",
+			wait, i+1, p.MaxRetries, err)
+
+		select {
+		case <-time.After(wait):
+		case <-stopCh:
+			return err
+		}
+
+		err = fn()
+	}
+
+	if err != nil && IsTransientError(err) && p.MaxRetries > 0 {
+		err = fmt.Errorf("%s (gave up after %d retries)", err, p.MaxRetries)
+	}
+
+	return err
+}
+
+// wait returns the wait before the given retry, counting from zero. The
+// wait is jittered so that operations failing together, such as those
+// throttled at the same time, don't all retry together.
+func (p *RetryPolicy) wait(retry int) time.Duration {
+	wait := p.MinWait
+	for i := 0; i < retry && wait < p.MaxWait; i++ {
+		wait *= 2
+	}
+	if wait > p.MaxWait {
+		wait = p.MaxWait
+	}
+	if wait <= 0 {
+		return 0
+	}
+
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
@@ -0,0 +1,135 @@
+package terraform
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransientError(t *testing.T) {
+	if TransientError(nil) != nil {
+		t.Fatal("should be nil")
+	}
+
+	err := TransientError(errors.New("throttled"))
+	if !IsTransientError(err) {
+		t.Fatal("should be transient")
+	}
+	if err.Error() != "throttled" {
+		t.Fatalf("bad: %s", err)
+	}
+
+	if IsTransientError(errors.New("throttled")) {
+		t.Fatal("should not be transient")
+	}
+	if IsTransientError(nil) {
+		t.Fatal("should not be transient")
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p := &RetryPolicy{MaxRetries: 3}
+
+	calls := 0
+	err := p.Retry(nil, "test", func() error {
+		calls++
+		if calls < 3 {
+			return TransientError(errors.New("throttled"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if calls != 3 {
+		t.Fatalf("bad: %d", calls)
+	}
+
+	// Other errors aren't retried
+	calls = 0
+	err = p.Retry(nil, "test", func() error {
+		calls++
+		return errors.New("invalid")
+	})
+	if err == nil || err.Error() != "invalid" {
+		t.Fatalf("bad: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("bad: %d", calls)
+	}
+
+	// Retries are limited
+	calls = 0
+	err = p.Retry(nil, "test", func() error {
+		calls++
+		return TransientError(errors.New("throttled"))
+	})
+	if err == nil || !strings.Contains(err.Error(), "gave up after 3 retries") {
+		t.Fatalf("bad: %v", err)
+	}
+	if IsTransientError(err) {
+		t.Fatal("the final error shouldn't be transient")
+	}
+	if calls != 4 {
+		t.Fatalf("bad: %d", calls)
+	}
+}
+
+func TestRetryPolicy_nil(t *testing.T) {
+	var p *RetryPolicy
+
+	calls := 0
+	err := p.Retry(nil, "test", func() error {
+		calls++
+		return TransientError(errors.New("throttled"))
+	})
+	if !IsTransientError(err) {
+		t.Fatalf("bad: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("bad: %d", calls)
+	}
+}
+
+func TestRetryPolicy_stop(t *testing.T) {
+	p := &RetryPolicy{MaxRetries: 3, MinWait: time.Hour, MaxWait: time.Hour}
+
+	stopCh := make(chan struct{})
+	close(stopCh)
+
+	calls := 0
+	err := p.Retry(stopCh, "test", func() error {
+		calls++
+		return TransientError(errors.New("throttled"))
+	})
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if calls != 1 {
+		t.Fatalf("bad: %d", calls)
+	}
+}
+
+func TestRetryPolicy_wait(t *testing.T) {
+	p := &RetryPolicy{MinWait: time.Second, MaxWait: 5 * time.Second}
+
+	cases := []struct {
+		Retry    int
+		Min, Max time.Duration
+	}{
+		{0, 500 * time.Millisecond, time.Second},
+		{1, time.Second, 2 * time.Second},
+		{2, 2 * time.Second, 4 * time.Second},
+		{3, 2500 * time.Millisecond, 5 * time.Second},
+		{10, 2500 * time.Millisecond, 5 * time.Second},
+	}
+
+	for _, tc := range cases {
+		for i := 0; i < 10; i++ {
+			if w := p.wait(tc.Retry); w < tc.Min || w > tc.Max {
+				t.Fatalf("%d: wait %s not between %s and %s", tc.Retry, w, tc.Min, tc.Max)
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package terraform
+
+import (
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/config/module"
+)
+
+// sensitiveValues records which variables, outputs and resource attributes
+// of the modules of a configuration are sensitive: the variables and outputs
+// that are declared sensitive, and the values that are interpolated from
+// sensitive values, however far they're passed between resources and
+// modules. Anything that is interpolated from them is
+// then hidden in the output of plan and apply, rather than only the value
+// that was declared sensitive.
+//
+// It only depends on the configuration, so it's worked out once for a walk.
+type sensitiveValues struct {
+	// variables and outputs are the sensitive values by the path of their
+	// module, as in sensitiveKey.
+	variables map[string]bool
+	outputs   map[string]bool
+
+	// attributes are the sensitive arguments of resources, as in
+	// sensitiveKey with the ID of the resource and the argument as the
+	// name. Only the arguments set in the configuration are known, so an
+	// attribute that the provider computes from them, such as an ID, isn't
+	// sensitive.
+	attributes map[string]bool
+}
+
+// newSensitiveValues works out the sensitive values of the module tree.
+func newSensitiveValues(tree *module.Tree) *sensitiveValues {
+	s := &sensitiveValues{
+		variables:  make(map[string]bool),
+		outputs:    make(map[string]bool),
+		attributes: make(map[string]bool),
+	}
+	if tree == nil {
+		return s
+	}
+
+	// Sensitivity is passed between variables, resources and outputs within
+	// a module, and from outputs to variables between modules, in either
+	// direction, so repeat until nothing more is found.
+	for s.walk(tree, RootModulePath) {
+	}
+
+	return s
+}
+
+// walk marks the values of the module at path, and of its descendants,
+// that are sensitive as far as is known, and returns true if it marked
+// any that weren't already.
+func (s *sensitiveValues) walk(tree *module.Tree, path []string) bool {
+	changed := false
+	mark := func(m map[string]bool, key string) {
+		if !m[key] {
+			m[key] = true
+			changed = true
+		}
+	}
+
+	cfg := tree.Config()
+	if cfg != nil {
+		for _, v := range cfg.Variables {
+			if v.Sensitive {
+				mark(s.variables, sensitiveKey(path, v.Name))
+			}
+		}
+
+		for _, o := range cfg.Outputs {
+			if o.Sensitive || (o.RawConfig != nil && s.references(path, o.RawConfig.Variables)) {
+				mark(s.outputs, sensitiveKey(path, o.Name))
+			}
+		}
+
+		for _, r := range cfg.Resources {
+			for _, k := range s.sensitiveArgs(path, r.RawConfig) {
+				mark(s.attributes, sensitiveKey(path, r.Id()+"."+k))
+			}
+		}
+
+		// The arguments of a module block are the variables of the child
+		for _, m := range cfg.Modules {
+			childPath := make([]string, len(path), len(path)+1)
+			copy(childPath, path)
+			childPath = append(childPath, m.Name)
+			for _, k := range s.sensitiveArgs(path, m.RawConfig) {
+				mark(s.variables, sensitiveKey(childPath, k))
+			}
+		}
+	}
+
+	for name, child := range tree.Children() {
+		childPath := make([]string, len(path), len(path)+1)
+		copy(childPath, path)
+		childPath = append(childPath, name)
+		if s.walk(child, childPath) {
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// sensitiveArgs returns the arguments of rc, interpolated in the module at
+// path, that are sensitive as far as is known.
+func (s *sensitiveValues) sensitiveArgs(path []string, rc *config.RawConfig) []string {
+	if rc == nil {
+		return nil
+	}
+
+	var result []string
+	for k, v := range rc.Raw {
+		kc, err := config.NewRawConfig(map[string]interface{}{k: v})
+		if err != nil {
+			continue
+		}
+		if s.references(path, kc.Variables) {
+			result = append(result, k)
+		}
+	}
+
+	return result
+}
+
+// references returns true if any of vars, interpolated in the module at
+// path, is a sensitive variable or resource attribute of the module or a
+// sensitive output of one of its children.
+func (s *sensitiveValues) references(
+	path []string, vars map[string]config.InterpolatedVariable) bool {
+	for _, v := range vars {
+		switch v := v.(type) {
+		case *config.UserVariable:
+			if s.variables[sensitiveKey(path, v.Name)] {
+				return true
+			}
+		case *config.ModuleVariable:
+			childPath := make([]string, len(path), len(path)+1)
+			copy(childPath, path)
+			childPath = append(childPath, v.Name)
+			if s.outputs[sensitiveKey(childPath, v.Field)] {
+				return true
+			}
+		case *config.ResourceVariable:
+			// The field may be nested in the argument, as in tags.Name
+			arg := v.Field
+			if i := strings.Index(arg, "."); i >= 0 {
+				arg = arg[:i]
+			}
+			if s.attributes[sensitiveKey(path, v.ResourceId()+"."+arg)] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func sensitiveKey(path []string, name string) string {
+	return strings.Join(path, ".") + "|" + name
+}
@@ -59,10 +59,13 @@ func newShadowContext(c *Context) (*Context, *Context, Shadow) {
 		// any panics but even a "nil" value worked here.
 		uiInput: new(MockUIInput),
 
+		forceReplace: c.forceReplace,
+
 		// Hardcoded to 4 since parallelism in the shadow doesn't matter
 		// a ton since we're doing far less compared to the real side
 		// and our operations are MUCH faster.
-		parallelSem:         NewSemaphore(4),
+		parallelSem:         NewPrioritySemaphore(4),
+		refreshSem:          NewPrioritySemaphore(4),
 		providerInputConfig: providerInputRaw.(map[string]map[string]interface{}),
 	}
 
@@ -88,8 +91,15 @@ func newShadowContext(c *Context) (*Context, *Context, Shadow) {
 		variables: c.variables,
 
 		// l - no copy
+		forceReplace:        c.forceReplace,
+		graphOrder:          c.graphOrder,
 		parallelSem:         c.parallelSem,
+		refreshSem:          c.refreshSem,
+		parallelismTuner:    c.parallelismTuner,
+		strictWarnings:      c.strictWarnings,
 		providerInputConfig: c.providerInputConfig,
+		providerCredentials: c.providerCredentials,
+		providerRetry:       c.providerRetry,
 		runContext:          c.runContext,
 		runContextCancel:    c.runContextCancel,
 		shadowErr:           c.shadowErr,
@@ -110,6 +110,14 @@ func (p *shadowResourceProviderReal) Stop() error {
 	return p.ResourceProvider.Stop()
 }
 
+func (p *shadowResourceProviderReal) Cancel() error {
+	if c, ok := p.ResourceProvider.(ResourceProviderCanceler); ok {
+		return c.Cancel()
+	}
+
+	return p.ResourceProvider.Stop()
+}
+
 func (p *shadowResourceProviderReal) ValidateResource(
 	t string, c *ResourceConfig) ([]string, []error) {
 	key := t
@@ -204,6 +212,84 @@ func (p *shadowResourceProviderReal) Refresh(
 	return result, err
 }
 
+func (p *shadowResourceProviderReal) WaitForReady(
+	info *InstanceInfo,
+	state *InstanceState) (*InstanceState, error) {
+	// Thse have to be copied before the call since call can modify
+	stateCopy := state.DeepCopy()
+
+	var result *InstanceState
+	err := ErrWaitForReadyUnsupported
+	if w, ok := p.ResourceProvider.(ResourceProviderReadyWaiter); ok {
+		result, err = w.WaitForReady(info, state)
+	}
+	p.Shared.WaitForReady.SetValue(info.uniqueId(), &shadowResourceProviderWaitForReady{
+		State:     stateCopy,
+		Result:    result.DeepCopy(),
+		ResultErr: err,
+	})
+
+	return result, err
+}
+
+func (p *shadowResourceProviderReal) LookupExisting(
+	info *InstanceInfo,
+	c *ResourceConfig) (*InstanceState, error) {
+	// These have to be copied before the call since call can modify
+	configCopy := c.DeepCopy()
+
+	var result *InstanceState
+	err := ErrLookupExistingUnsupported
+	if a, ok := p.ResourceProvider.(ResourceProviderAdopter); ok {
+		result, err = a.LookupExisting(info, c)
+	}
+	p.Shared.LookupExisting.SetValue(info.uniqueId(), &shadowResourceProviderLookupExisting{
+		Config:    configCopy,
+		Result:    result.DeepCopy(),
+		ResultErr: err,
+	})
+
+	return result, err
+}
+
+func (p *shadowResourceProviderReal) RefreshBatch(
+	info []*InstanceInfo,
+	state []*InstanceState) ([]*InstanceState, error) {
+	b, ok := p.ResourceProvider.(ResourceProviderBatchRefresher)
+	if !ok {
+		return nil, ErrRefreshBatchUnsupported
+	}
+
+	// These have to be copied before the call since call can modify
+	stateCopy := make([]*InstanceState, len(state))
+	for i, s := range state {
+		stateCopy[i] = s.DeepCopy()
+	}
+
+	result, err := b.RefreshBatch(info, state)
+	if err == ErrRefreshBatchUnsupported {
+		// Refresh is called instead, which records the values
+		return result, err
+	}
+
+	// The shadow doesn't batch its refreshes, so we record the result for
+	// each resource as if Refresh had been called.
+	for i, in := range info {
+		var r *InstanceState
+		if err == nil && i < len(result) {
+			r = result[i]
+		}
+
+		p.Shared.Refresh.SetValue(in.uniqueId(), &shadowResourceProviderRefresh{
+			State:     stateCopy[i],
+			Result:    r.DeepCopy(),
+			ResultErr: err,
+		})
+	}
+
+	return result, err
+}
+
 func (p *shadowResourceProviderReal) ValidateDataSource(
 	t string, c *ResourceConfig) ([]string, []error) {
 	key := t
@@ -302,6 +388,8 @@ type shadowResourceProviderShared struct {
 	Apply              shadow.KeyedValue
 	Diff               shadow.KeyedValue
 	Refresh            shadow.KeyedValue
+	WaitForReady       shadow.KeyedValue
+	LookupExisting     shadow.KeyedValue
 	ValidateDataSource shadow.KeyedValue
 	ReadDataDiff       shadow.KeyedValue
 	ReadDataApply      shadow.KeyedValue
@@ -434,6 +522,11 @@ func (p *shadowResourceProviderShadow) Stop() error {
 	return nil
 }
 
+// Cancel returns immediately.
+func (p *shadowResourceProviderShadow) Cancel() error {
+	return nil
+}
+
 func (p *shadowResourceProviderShadow) ValidateResource(t string, c *ResourceConfig) ([]string, []error) {
 	// Unique key
 	key := t
@@ -610,6 +703,78 @@ func (p *shadowResourceProviderShadow) Refresh(
 	return result.Result, result.ResultErr
 }
 
+func (p *shadowResourceProviderShadow) WaitForReady(
+	info *InstanceInfo,
+	state *InstanceState) (*InstanceState, error) {
+	// Unique key
+	key := info.uniqueId()
+	raw := p.Shared.WaitForReady.Value(key)
+	if raw == nil {
+		p.ErrorLock.Lock()
+		defer p.ErrorLock.Unlock()
+		p.Error = multierror.Append(p.Error, fmt.Errorf(
+			"Unknown 'wait for ready' call for %q:\n\n%#v",
+			key, state))
+		return nil, nil
+	}
+
+	result, ok := raw.(*shadowResourceProviderWaitForReady)
+	if !ok {
+		p.ErrorLock.Lock()
+		defer p.ErrorLock.Unlock()
+		p.Error = multierror.Append(p.Error, fmt.Errorf(
+			"Unknown 'wait for ready' shadow value: %#v", raw))
+		return nil, nil
+	}
+
+	// Compare the parameters, which should be identical
+	if !state.Equal(result.State) {
+		p.ErrorLock.Lock()
+		p.Error = multierror.Append(p.Error, fmt.Errorf(
+			"WaitForReady %q had unequal states (real, then shadow):\n\n%#v\n\n%#v",
+			key, result.State, state))
+		p.ErrorLock.Unlock()
+	}
+
+	return result.Result, result.ResultErr
+}
+
+func (p *shadowResourceProviderShadow) LookupExisting(
+	info *InstanceInfo,
+	c *ResourceConfig) (*InstanceState, error) {
+	// Unique key
+	key := info.uniqueId()
+	raw := p.Shared.LookupExisting.Value(key)
+	if raw == nil {
+		p.ErrorLock.Lock()
+		defer p.ErrorLock.Unlock()
+		p.Error = multierror.Append(p.Error, fmt.Errorf(
+			"Unknown 'lookup existing' call for %q:\n\n%#v",
+			key, c))
+		return nil, nil
+	}
+
+	result, ok := raw.(*shadowResourceProviderLookupExisting)
+	if !ok {
+		p.ErrorLock.Lock()
+		defer p.ErrorLock.Unlock()
+		p.Error = multierror.Append(p.Error, fmt.Errorf(
+			"Unknown 'lookup existing' shadow value: %#v", raw))
+		return nil, nil
+	}
+
+	// Compare the parameters, which should be identical
+	if !c.Equal(result.Config) {
+		p.ErrorLock.Lock()
+		p.Error = multierror.Append(p.Error, fmt.Errorf(
+			"LookupExisting %q had unequal configurations (real, then shadow):\n\n%#v\n\n%#v",
+			key, result.Config, c))
+		p.ErrorLock.Unlock()
+	}
+
+	return result.Result, result.ResultErr
+}
+
 func (p *shadowResourceProviderShadow) ValidateDataSource(
 	t string, c *ResourceConfig) ([]string, []error) {
 	// Unique key
@@ -790,6 +955,18 @@ type shadowResourceProviderRefresh struct {
 	ResultErr error
 }
 
+type shadowResourceProviderWaitForReady struct {
+	State     *InstanceState
+	Result    *InstanceState
+	ResultErr error
+}
+
+type shadowResourceProviderLookupExisting struct {
+	Config    *ResourceConfig
+	Result    *InstanceState
+	ResultErr error
+}
+
 type shadowResourceProviderValidateDataSourceWrapper struct {
 	sync.RWMutex
 
@@ -107,6 +107,7 @@ func (p *shadowResourceProvisionerReal) Apply(
 	compareVal.SetValue(&shadowResourceProvisionerApply{
 		Config:    c,
 		ResultErr: err,
+		Outputs:   s.ProvisionerOutputs(),
 	})
 
 	return err
@@ -229,6 +230,11 @@ func (p *shadowResourceProvisionerShadow) Apply(
 		return nil
 	}
 
+	// Record the same outputs as the real provisioner
+	for k, v := range result.Outputs {
+		s.SetProvisionerOutput(k, v)
+	}
+
 	return result.ResultErr
 }
 
@@ -251,6 +257,7 @@ type shadowResourceProvisionerValidate struct {
 type shadowResourceProvisionerApply struct {
 	Config    *ResourceConfig
 	ResultErr error
+	Outputs   map[string]string
 }
 
 func shadowResourceProvisionerValidateCompare(k, v interface{}) bool {
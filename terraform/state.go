@@ -3,6 +3,8 @@ package terraform
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -75,6 +77,12 @@ type State struct {
 	// should only compare lineage strings byte-for-byte for equality.
 	Lineage string `json:"lineage"`
 
+	// Checksum is the SHA-256 hash of the rest of the state, as it's
+	// written by WriteState. It's verified and cleared by ReadState, to
+	// detect a state that was truncated or corrupted after it was written,
+	// such as by a failed upload to remote storage.
+	Checksum string `json:"checksum,omitempty"`
+
 	// Remote is used to track the metadata required to
 	// pull and push state files from a remote storage endpoint.
 	Remote *RemoteState `json:"remote,omitempty"`
@@ -84,6 +92,10 @@ type State struct {
 	// configuration.
 	Backend *BackendState `json:"backend,omitempty"`
 
+	// TargetedApply records the last apply, if it was limited to some
+	// resources with targets. It is cleared by the next apply that isn't.
+	TargetedApply *TargetedApplyState `json:"targeted_apply,omitempty"`
+
 	// Modules contains all the modules in a breadth-first order
 	Modules []*ModuleState `json:"modules"`
 
@@ -513,6 +525,10 @@ func (s *State) equal(other *State) bool {
 		return false
 	}
 
+	if !reflect.DeepEqual(s.TargetedApply, other.TargetedApply) {
+		return false
+	}
+
 	// If any of the modules are not equal, then this state isn't equal
 	if len(s.Modules) != len(other.Modules) {
 		return false
@@ -635,6 +651,30 @@ func (s *State) IncrementSerialMaybe(other *State) {
 	}
 }
 
+// stateChecksum returns the checksum of the encoded state data: the
+// SHA-256 hash of the data without its checksum field, in a canonical
+// encoding. The data isn't decoded into a State, so that fields this
+// version doesn't know about are covered too, and the encoding is
+// canonical so that the checksum survives re-indenting the state, such as
+// in a plan, and reordering its keys.
+func stateChecksum(data []byte) (string, error) {
+	var v map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("Decoding state file failed: %v", err)
+	}
+	delete(v, "checksum")
+
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
 // FromFutureTerraform checks if this state was written by a Terraform
 // version from the future.
 func (s *State) FromFutureTerraform() bool {
@@ -1074,7 +1114,7 @@ func (m *ModuleState) Orphans(c *config.Config) []string {
 			delete(keys, r.Id())
 
 			for k, _ := range keys {
-				if strings.HasPrefix(k, r.Id()+".") {
+				if strings.HasPrefix(k, r.Id()+".") || strings.HasPrefix(k, r.Id()+"[") {
 					delete(keys, k)
 				}
 			}
@@ -1097,7 +1137,7 @@ func (m *ModuleState) View(id string) *ModuleState {
 
 	r := m.deepcopy()
 	for k, _ := range r.Resources {
-		if id == k || strings.HasPrefix(k, id+".") {
+		if id == k || strings.HasPrefix(k, id+".") || strings.HasPrefix(k, id+"[") {
 			continue
 		}
 
@@ -1297,6 +1337,7 @@ type ResourceStateKey struct {
 	Type  string
 	Mode  config.ResourceMode
 	Index int
+	Key   string
 }
 
 // Equal determines whether two ResourceStateKeys are the same
@@ -1316,6 +1357,9 @@ func (rsk *ResourceStateKey) Equal(other *ResourceStateKey) bool {
 	if rsk.Index != other.Index {
 		return false
 	}
+	if rsk.Key != other.Key {
+		return false
+	}
 	return true
 }
 
@@ -1332,6 +1376,9 @@ func (rsk *ResourceStateKey) String() string {
 	default:
 		panic(fmt.Errorf("unknown resource mode %s", rsk.Mode))
 	}
+	if rsk.Key != "" {
+		return fmt.Sprintf("%s%s.%s[%s]", prefix, rsk.Type, rsk.Name, strconv.Quote(rsk.Key))
+	}
 	if rsk.Index == -1 {
 		return fmt.Sprintf("%s%s.%s", prefix, rsk.Type, rsk.Name)
 	}
@@ -1341,9 +1388,15 @@ func (rsk *ResourceStateKey) String() string {
 // ParseResourceStateKey accepts a key in the format used by
 // ModuleState.Resources and returns a resource name and resource index. In the
 // state, a resource has the format "type.name.index" or "type.name". In the
-// latter case, the index is returned as -1.
+// latter case, the index is returned as -1. Instances of a resource using
+// for_each have the format `type.name["key"]`.
 func ParseResourceStateKey(k string) (*ResourceStateKey, error) {
-	parts := strings.Split(k, ".")
+	prefix, key, err := splitResourceInstanceKey(k)
+	if err != nil {
+		return nil, fmt.Errorf("Malformed resource state key: %s", k)
+	}
+
+	parts := strings.Split(prefix, ".")
 	mode := config.ManagedResourceMode
 	if len(parts) > 0 && parts[0] == "data" {
 		mode = config.DataResourceMode
@@ -1359,8 +1412,13 @@ func ParseResourceStateKey(k string) (*ResourceStateKey, error) {
 		Type:  parts[0],
 		Name:  parts[1],
 		Index: -1,
+		Key:   key,
 	}
 	if len(parts) == 3 {
+		if key != "" {
+			return nil, fmt.Errorf("Malformed resource state key: %s", k)
+		}
+
 		index, err := strconv.Atoi(parts[2])
 		if err != nil {
 			return nil, fmt.Errorf("Malformed resource state key index: %s", k)
@@ -1426,6 +1484,11 @@ type ResourceState struct {
 	// If the resource block contained a "provider" key, that value will be set here.
 	Provider string `json:"provider"`
 
+	// Lifecycle records when Terraform last changed the resource. It's nil
+	// if the resource hasn't been changed since this was recorded. It isn't
+	// compared by Equal.
+	Lifecycle *ResourceLifecycle `json:"lifecycle,omitempty"`
+
 	mu sync.Mutex
 }
 
@@ -1715,6 +1778,82 @@ func (s *InstanceState) MergeDiff(d *InstanceDiff) *InstanceState {
 	return result
 }
 
+// ProvisionerOutputPrefix is the prefix of the attributes that provisioners
+// record outputs in. Together they form the map attribute
+// "provisioner_output", which Terraform keeps for as long as the instance
+// exists, regardless of what the provider returns.
+const ProvisionerOutputPrefix = "provisioner_output."
+
+// SetProvisionerOutput records an output of a provisioner in the state, so
+// that it can be interpolated as ${TYPE.NAME.provisioner_output.KEY}.
+func (s *InstanceState) SetProvisionerOutput(key, value string) {
+	s.init()
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.Attributes[ProvisionerOutputPrefix+key] = value
+
+	count := 0
+	for k := range s.Attributes {
+		if strings.HasPrefix(k, ProvisionerOutputPrefix) && k != ProvisionerOutputPrefix+"%" {
+			count++
+		}
+	}
+	s.Attributes[ProvisionerOutputPrefix+"%"] = strconv.Itoa(count)
+}
+
+// ProvisionerOutputs returns the outputs that provisioners recorded in the
+// state by key.
+func (s *InstanceState) ProvisionerOutputs() map[string]string {
+	if s == nil {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	var result map[string]string
+	for k, v := range s.Attributes {
+		if !strings.HasPrefix(k, ProvisionerOutputPrefix) || k == ProvisionerOutputPrefix+"%" {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]string)
+		}
+		result[strings.TrimPrefix(k, ProvisionerOutputPrefix)] = v
+	}
+
+	return result
+}
+
+// clearProvisionerOutputs removes the outputs that provisioners recorded.
+func (s *InstanceState) clearProvisionerOutputs() {
+	s.Lock()
+	defer s.Unlock()
+
+	for k := range s.Attributes {
+		if strings.HasPrefix(k, ProvisionerOutputPrefix) {
+			delete(s.Attributes, k)
+		}
+	}
+}
+
+// keepProvisionerOutputs copies the provisioner outputs of the prior state
+// of the same instance into s, since providers don't know about them.
+func (s *InstanceState) keepProvisionerOutputs(prior *InstanceState) {
+	if s == nil || s == prior {
+		return
+	}
+
+	current := s.ProvisionerOutputs()
+	for k, v := range prior.ProvisionerOutputs() {
+		if _, ok := current[k]; !ok {
+			s.SetProvisionerOutput(k, v)
+		}
+	}
+}
+
 func (s *InstanceState) String() string {
 	s.Lock()
 	defer s.Unlock()
@@ -1803,6 +1942,23 @@ func testForV0State(buf *bufio.Reader) error {
 // ErrNoState is returned by ReadState when the io.Reader contains no data
 var ErrNoState = errors.New("no state")
 
+// StateChecksumError is returned by ReadState when the content of the state
+// doesn't match its checksum.
+type StateChecksumError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *StateChecksumError) Error() string {
+	return fmt.Sprintf(
+		"State checksum mismatch: expected %s, got %s\n\n"+
+			"The state doesn't match the checksum it was written with, so it\n"+
+			"was truncated or corrupted after it was written, such as by a failed\n"+
+			"upload. Restore the state from a backup. If you edited the state by\n"+
+			"hand, remove the \"checksum\" field to accept your changes.",
+		e.Expected, e.Actual)
+}
+
 // ReadState reads a state structure out of a reader in the format that
 // was written by WriteState.
 func ReadState(src io.Reader) (*State, error) {
@@ -1974,20 +2130,35 @@ func ReadStateV3(jsonBytes []byte) (*State, error) {
 	// Sort it
 	state.sort()
 
+	// Verify the checksum, if the state was written with one
+	if state.Checksum != "" {
+		sum, err := stateChecksum(jsonBytes)
+		if err != nil {
+			return nil, err
+		}
+		if sum != state.Checksum {
+			return nil, &StateChecksumError{Expected: state.Checksum, Actual: sum}
+		}
+	}
+
 	// Now we write the state back out to detect any changes in normaliztion.
 	// If our state is now written out differently, bump the serial number to
-	// prevent conflicts.
-	var buf bytes.Buffer
-	err := WriteState(state, &buf)
+	// prevent conflicts. The checksum is left as it was read, so that states
+	// written without one aren't bumped.
+	data, err := encodeState(state)
 	if err != nil {
 		return nil, err
 	}
 
-	if !bytes.Equal(jsonBytes, buf.Bytes()) {
+	if !bytes.Equal(jsonBytes, data) {
 		log.Println("[INFO] state modified during read or write. incrementing serial number")
 		state.Serial++
 	}
 
+	// The checksum is only for the state as it's written, so it's not kept
+	// around to go stale.
+	state.Checksum = ""
+
 	return state, nil
 }
 
@@ -2020,14 +2191,21 @@ func WriteState(d *State, dst io.Writer) error {
 		}
 	}
 
-	// Encode the data in a human-friendly way
-	data, err := json.MarshalIndent(d, "", "    ")
+	// Record the checksum of everything else, in what's written
+	data, err := encodeState(d)
 	if err != nil {
-		return fmt.Errorf("Failed to encode state: %s", err)
+		return err
+	}
+	sum, err := stateChecksum(data)
+	if err != nil {
+		return err
+	}
+	d.Checksum = sum
+	data, err = encodeState(d)
+	d.Checksum = ""
+	if err != nil {
+		return err
 	}
-
-	// We append a newline to the data because MarshalIndent doesn't
-	data = append(data, '\n')
 
 	// Write the data out to the dst
 	if _, err := io.Copy(dst, bytes.NewReader(data)); err != nil {
@@ -2037,6 +2215,17 @@ func WriteState(d *State, dst io.Writer) error {
 	return nil
 }
 
+// encodeState encodes the state as it is, in a human-friendly way.
+func encodeState(d *State) ([]byte, error) {
+	data, err := json.MarshalIndent(d, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode state: %s", err)
+	}
+
+	// We append a newline to the data because MarshalIndent doesn't
+	return append(data, '\n'), nil
+}
+
 // resourceNameSort implements the sort.Interface to sort name parts lexically for
 // strings and numerically for integer indexes.
 type resourceNameSort []string
@@ -113,6 +113,7 @@ func stateAddFunc_Module_Module(s *State, fromAddr, addr *ResourceAddress, raw i
 		addrCopy.Type = resourceKey.Type
 		addrCopy.Name = resourceKey.Name
 		addrCopy.Index = resourceKey.Index
+		addrCopy.Key = resourceKey.Key
 		addrCopy.Mode = resourceKey.Mode
 
 		// Perform an add
@@ -333,6 +334,7 @@ func stateAddInitAddr(s *State, addr *ResourceAddress) (interface{}, bool) {
 		Name:  addr.Name,
 		Type:  addr.Type,
 		Index: addr.Index,
+		Key:   addr.Key,
 		Mode:  addr.Mode,
 	}).String()
 	exists = true
@@ -0,0 +1,240 @@
+package terraform
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MoveDependencies updates the dependencies recorded in the state for a
+// move of the resource or module at fromAddr to toAddr, as done by
+// "terraform state mv". It must be called before the move itself, so that
+// the moved resources carry their updated dependencies along with them.
+//
+// Dependencies are relative to the module of the resource that has them, so
+// moving a resource into or out of a module changes both how other
+// resources refer to it and how it refers to them. Dependencies that can't
+// be expressed from the new location, such as those on a resource in a
+// parent module, are removed.
+//
+// If external is true, the item is being moved to another state, so the
+// dependencies between the moved resources and those that remain are
+// removed.
+func (s *State) MoveDependencies(fromAddr, toAddr string, external bool) error {
+	from, err := ParseResourceAddress(fromAddr)
+	if err != nil {
+		return err
+	}
+	to, err := ParseResourceAddress(toAddr)
+	if err != nil {
+		return err
+	}
+
+	// Moving an instance leaves its resource, and the dependencies of that
+	// resource, where they are.
+	if from.InstanceTypeSet || to.InstanceTypeSet {
+		return nil
+	}
+
+	// A resource moved to a module address keeps its name, as in Add.
+	if from.Name != "" && to.Name == "" {
+		to = to.Copy()
+		to.Mode = from.Mode
+		to.Type = from.Type
+		to.Name = from.Name
+	}
+
+	m := &stateDepMove{from: from, to: to}
+	for _, mod := range s.Modules {
+		path := normalizeModulePath(mod.Path)[1:]
+		for k, rs := range mod.Resources {
+			if len(rs.Dependencies) == 0 {
+				continue
+			}
+
+			holder, holderMoved := m.target(stateDepTarget{Path: path, Name: k})
+			rs.Dependencies = m.dependencies(
+				path, holder.Path, holderMoved, rs.Dependencies, external)
+		}
+	}
+
+	return nil
+}
+
+// stateDepTarget is the absolute location of something that a resource
+// depends on: either a resource, with Name in the format of the keys of
+// ModuleState.Resources, or a whole module, with an empty Name.
+type stateDepTarget struct {
+	Path []string
+	Name string
+}
+
+// stateDepTargetFor returns the target of the dependency dep of a resource
+// in the module at path.
+func stateDepTargetFor(path []string, dep string) stateDepTarget {
+	if strings.HasPrefix(dep, "module.") {
+		parts := strings.SplitN(dep, ".", 3)
+		return stateDepTarget{Path: stateDepChildPath(path, parts[1])}
+	}
+
+	return stateDepTarget{Path: path, Name: dep}
+}
+
+// stateDepMove is a move of a resource or module, for rewriting
+// dependencies.
+type stateDepMove struct {
+	from, to *ResourceAddress
+}
+
+// dependencies returns the dependencies deps of a resource in the module at
+// path, as they must be for the resource at newPath after the move.
+func (m *stateDepMove) dependencies(
+	path, newPath []string, holderMoved bool, deps []string, external bool) []string {
+	// Where the moved item ends up
+	movedTo := stateDepTarget{Path: m.to.Path}
+	if m.to.Name != "" {
+		movedTo.Name = stateDepResourceName(m.to)
+	}
+
+	result := make([]string, 0, len(deps))
+	add := func(t stateDepTarget) {
+		if dep := stateDepRelative(newPath, t); dep != "" {
+			result = append(result, dep)
+		}
+	}
+
+	for _, dep := range deps {
+		t := stateDepTargetFor(path, dep)
+		nt, targetMoved := m.target(t)
+
+		// Resources in different states can't depend on each other
+		if external && targetMoved != holderMoved {
+			continue
+		}
+
+		add(nt)
+
+		// A dependency on a module that contained the moved item may also
+		// have been a dependency on the moved item, so we keep both.
+		if !targetMoved && t.Name == "" && !external &&
+			stateDepHasPrefix(m.from.Path, t.Path) {
+			add(movedTo)
+		}
+	}
+
+	return uniqueStrings(result)
+}
+
+// target returns the location of t after the move, and whether the move
+// affects it.
+func (m *stateDepMove) target(t stateDepTarget) (stateDepTarget, bool) {
+	// Moving a module moves everything within it
+	if m.from.Name == "" {
+		if len(m.from.Path) == 0 || !stateDepHasPrefix(t.Path, m.from.Path) {
+			return t, false
+		}
+
+		path := append([]string{}, m.to.Path...)
+		path = append(path, t.Path[len(m.from.Path):]...)
+		return stateDepTarget{Path: path, Name: t.Name}, true
+	}
+
+	if t.Name == "" || !stateDepPathEqual(t.Path, m.from.Path) {
+		return t, false
+	}
+
+	// Targets are either the whole resource, or one of its instances
+	fromBase := stateDepResourceBase(m.from)
+	var suffix string
+	switch {
+	case t.Name == fromBase:
+	case strings.HasPrefix(t.Name, fromBase+".") || strings.HasPrefix(t.Name, fromBase+"["):
+		suffix = t.Name[len(fromBase):]
+	default:
+		return t, false
+	}
+
+	// If only one instance moved, a dependency on the whole resource or on
+	// another instance stays where it is.
+	if fromSuffix := stateDepInstanceSuffix(m.from); fromSuffix != "" {
+		if suffix != fromSuffix {
+			return t, false
+		}
+	}
+
+	if toSuffix := stateDepInstanceSuffix(m.to); toSuffix != "" {
+		suffix = toSuffix
+	} else if suffix == stateDepInstanceSuffix(m.from) {
+		suffix = ""
+	}
+
+	return stateDepTarget{
+		Path: m.to.Path,
+		Name: stateDepResourceBase(m.to) + suffix,
+	}, true
+}
+
+// stateDepRelative returns the dependency on t of a resource in the module
+// at path, or an empty string if it can't depend on t.
+func stateDepRelative(path []string, t stateDepTarget) string {
+	if t.Name != "" && stateDepPathEqual(t.Path, path) {
+		return t.Name
+	}
+
+	// Anything within a child module is a dependency on that module
+	if len(t.Path) > len(path) && stateDepHasPrefix(t.Path, path) {
+		return "module." + t.Path[len(path)]
+	}
+
+	return ""
+}
+
+func stateDepResourceBase(addr *ResourceAddress) string {
+	return (&ResourceStateKey{
+		Mode:  addr.Mode,
+		Type:  addr.Type,
+		Name:  addr.Name,
+		Index: -1,
+	}).String()
+}
+
+func stateDepResourceName(addr *ResourceAddress) string {
+	return stateDepResourceBase(addr) + stateDepInstanceSuffix(addr)
+}
+
+func stateDepInstanceSuffix(addr *ResourceAddress) string {
+	if addr.Key != "" {
+		return "[" + strconv.Quote(addr.Key) + "]"
+	}
+	if addr.Index >= 0 {
+		return "." + strconv.Itoa(addr.Index)
+	}
+
+	return ""
+}
+
+func stateDepChildPath(path []string, name string) []string {
+	result := make([]string, len(path), len(path)+1)
+	copy(result, path)
+	return append(result, name)
+}
+
+func stateDepHasPrefix(path, prefix []string) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+
+	return stateDepPathEqual(path[:len(prefix)], prefix)
+}
+
+func stateDepPathEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,236 @@
+package terraform
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStateMoveDependencies(t *testing.T) {
+	cases := []struct {
+		Name     string
+		From, To string
+		External bool
+
+		// Deps and Want are the dependencies of each resource, keyed by
+		// module path and resource key, such as "root.foo:test_instance.a".
+		Deps map[string][]string
+		Want map[string][]string
+	}{
+		{
+			"resource into module",
+			"test_instance.a",
+			"module.foo.test_instance.a",
+			false,
+			map[string][]string{
+				"root:test_instance.a":   []string{"test_instance.vpc"},
+				"root:test_instance.b":   []string{"test_instance.a", "test_instance.vpc"},
+				"root:test_instance.vpc": []string{},
+			},
+			map[string][]string{
+				"root:test_instance.a":   []string{},
+				"root:test_instance.b":   []string{"module.foo", "test_instance.vpc"},
+				"root:test_instance.vpc": []string{},
+			},
+		},
+
+		{
+			"resource into module address",
+			"test_instance.a",
+			"module.foo",
+			false,
+			map[string][]string{
+				"root:test_instance.b": []string{"test_instance.a"},
+			},
+			map[string][]string{
+				"root:test_instance.b": []string{"module.foo"},
+			},
+		},
+
+		{
+			"resource out of module",
+			"module.foo.test_instance.a",
+			"test_instance.a",
+			false,
+			map[string][]string{
+				"root.foo:test_instance.a":      []string{"test_instance.subnet"},
+				"root.foo:test_instance.subnet": []string{},
+				"root:test_instance.b":          []string{"module.foo"},
+			},
+			map[string][]string{
+				"root.foo:test_instance.a":      []string{"module.foo"},
+				"root.foo:test_instance.subnet": []string{},
+				"root:test_instance.b":          []string{"module.foo", "test_instance.a"},
+			},
+		},
+
+		{
+			"resource between modules",
+			"module.foo.module.child.test_instance.a",
+			"module.bar.test_instance.a",
+			false,
+			map[string][]string{
+				"root.foo.child:test_instance.a": []string{},
+				"root.foo:test_instance.b":       []string{"module.child"},
+				"root:test_instance.c":           []string{"module.foo"},
+			},
+			map[string][]string{
+				"root.foo.child:test_instance.a": []string{},
+				"root.foo:test_instance.b":       []string{"module.child"},
+				"root:test_instance.c":           []string{"module.bar", "module.foo"},
+			},
+		},
+
+		{
+			"resource rename",
+			"test_instance.a",
+			"test_instance.renamed",
+			false,
+			map[string][]string{
+				"root:test_instance.a.0": []string{"test_instance.vpc"},
+				"root:test_instance.a.1": []string{"test_instance.vpc"},
+				"root:test_instance.b":   []string{"test_instance.a", "test_instance.a.1"},
+			},
+			map[string][]string{
+				"root:test_instance.a.0": []string{"test_instance.vpc"},
+				"root:test_instance.a.1": []string{"test_instance.vpc"},
+				"root:test_instance.b":   []string{"test_instance.renamed", "test_instance.renamed.1"},
+			},
+		},
+
+		{
+			"single instance",
+			"test_instance.a[1]",
+			"test_instance.c",
+			false,
+			map[string][]string{
+				"root:test_instance.b": []string{"test_instance.a", "test_instance.a.1"},
+			},
+			map[string][]string{
+				"root:test_instance.b": []string{"test_instance.a", "test_instance.c"},
+			},
+		},
+
+		{
+			"module rename",
+			"module.foo",
+			"module.bar",
+			false,
+			map[string][]string{
+				"root.foo:test_instance.a":       []string{"module.child", "test_instance.b"},
+				"root.foo.child:test_instance.a": []string{},
+				"root:test_instance.c":           []string{"module.foo"},
+			},
+			map[string][]string{
+				"root.foo:test_instance.a":       []string{"module.child", "test_instance.b"},
+				"root.foo.child:test_instance.a": []string{},
+				"root:test_instance.c":           []string{"module.bar"},
+			},
+		},
+
+		{
+			"nested module out of parent",
+			"module.foo.module.child",
+			"module.child",
+			false,
+			map[string][]string{
+				"root.foo:test_instance.a": []string{"module.child"},
+				"root:test_instance.c":     []string{"module.foo"},
+			},
+			map[string][]string{
+				"root.foo:test_instance.a": []string{},
+				"root:test_instance.c":     []string{"module.child", "module.foo"},
+			},
+		},
+
+		{
+			"data resource",
+			"data.test_data.a",
+			"module.foo.data.test_data.a",
+			false,
+			map[string][]string{
+				"root:test_instance.b": []string{"data.test_data.a"},
+			},
+			map[string][]string{
+				"root:test_instance.b": []string{"module.foo"},
+			},
+		},
+
+		{
+			"external",
+			"test_instance.a",
+			"test_instance.a",
+			true,
+			map[string][]string{
+				"root:test_instance.a": []string{"test_instance.vpc", "test_instance.c"},
+				"root:test_instance.b": []string{"test_instance.a", "test_instance.vpc"},
+				"root:test_instance.c": []string{},
+			},
+			map[string][]string{
+				"root:test_instance.a": []string{},
+				"root:test_instance.b": []string{"test_instance.vpc"},
+				"root:test_instance.c": []string{},
+			},
+		},
+
+		{
+			"external with module",
+			"module.foo",
+			"module.foo",
+			true,
+			map[string][]string{
+				"root.foo:test_instance.a": []string{"module.child", "test_instance.b"},
+				"root:test_instance.c":     []string{"module.foo", "test_instance.d"},
+			},
+			map[string][]string{
+				"root.foo:test_instance.a": []string{"module.child", "test_instance.b"},
+				"root:test_instance.c":     []string{"test_instance.d"},
+			},
+		},
+
+		{
+			"instance address",
+			"test_instance.a",
+			"test_instance.b.deposed",
+			false,
+			map[string][]string{
+				"root:test_instance.c": []string{"test_instance.a"},
+			},
+			map[string][]string{
+				"root:test_instance.c": []string{"test_instance.a"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			s := &State{}
+			for k, deps := range tc.Deps {
+				parts := strings.SplitN(k, ":", 2)
+				mod := s.ModuleByPath(strings.Split(parts[0], "."))
+				if mod == nil {
+					mod = s.AddModule(strings.Split(parts[0], "."))
+				}
+				mod.Resources[parts[1]] = &ResourceState{
+					Type:         "test_instance",
+					Dependencies: deps,
+					Primary:      &InstanceState{ID: "foo"},
+				}
+			}
+
+			if err := s.MoveDependencies(tc.From, tc.To, tc.External); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			got := make(map[string][]string)
+			for _, mod := range s.Modules {
+				for k, rs := range mod.Resources {
+					got[strings.Join(mod.Path, ".")+":"+k] = rs.Dependencies
+				}
+			}
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("wrong dependencies\ngot:  %#v\nwant: %#v", got, tc.Want)
+			}
+		})
+	}
+}
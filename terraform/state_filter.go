@@ -111,6 +111,11 @@ func (f *StateFilter) filterSingle(a *ResourceAddress) []*StateFilterResult {
 					continue
 				}
 
+				if a.Key != "" && key.Key != a.Key {
+					// Key doesn't match
+					continue
+				}
+
 				if a.Name != "" && a.Name != key.Name {
 					continue
 				}
@@ -121,6 +126,7 @@ func (f *StateFilter) filterSingle(a *ResourceAddress) []*StateFilterResult {
 					Name:  key.Name,
 					Type:  key.Type,
 					Index: key.Index,
+					Key:   key.Key,
 				}
 
 				// Add the resource level result
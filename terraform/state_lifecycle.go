@@ -0,0 +1,154 @@
+package terraform
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Actions that are recorded as the last action in a ResourceLifecycle.
+const (
+	LifecycleActionCreate  = "create"
+	LifecycleActionUpdate  = "update"
+	LifecycleActionReplace = "replace"
+	LifecycleActionImport  = "import"
+)
+
+// ResourceLifecycle records when Terraform last changed a resource instance,
+// so that this is known without the audit trail of the provider.
+type ResourceLifecycle struct {
+	// CreatedAt is when the current object was created or imported. It's
+	// the zero time if the resource was created before this was recorded.
+	CreatedAt time.Time `json:"created_at"`
+
+	// ModifiedAt is when the object was last created, updated, replaced or
+	// imported, and LastAction which of these it was.
+	ModifiedAt time.Time `json:"modified_at"`
+	LastAction string    `json:"last_action"`
+
+	// RunID identifies the run that last modified the object. It's the run
+	// ID given in the ContextMeta, or a random one.
+	RunID string `json:"run_id"`
+}
+
+// record records an action on the object at the given time.
+func (l *ResourceLifecycle) record(action, runID string, t time.Time) {
+	if action != LifecycleActionUpdate {
+		l.CreatedAt = t
+	}
+	l.ModifiedAt = t
+	l.LastAction = action
+	l.RunID = runID
+}
+
+// lifecycleHook is a private Hook implementation that collects the resource
+// instances that an apply changed successfully, so that their lifecycles
+// can be recorded in the state afterwards.
+type lifecycleHook struct {
+	NilHook
+
+	sync.Mutex
+	pending map[string]string
+	applied []lifecycleHookApplied
+}
+
+type lifecycleHookApplied struct {
+	path   []string
+	id     string
+	action string
+}
+
+func (h *lifecycleHook) PreApply(
+	info *InstanceInfo, s *InstanceState, d *InstanceDiff) (HookAction, error) {
+	var action string
+	switch d.ChangeType() {
+	case DiffCreate:
+		action = LifecycleActionCreate
+	case DiffUpdate:
+		action = LifecycleActionUpdate
+	case DiffDestroyCreate:
+		action = LifecycleActionReplace
+	default:
+		return HookActionContinue, nil
+	}
+
+	// Data sources are read again on every run
+	if strings.HasPrefix(info.Id, "data.") {
+		return HookActionContinue, nil
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	if h.pending == nil {
+		h.pending = make(map[string]string)
+	}
+	h.pending[info.HumanId()] = action
+
+	return HookActionContinue, nil
+}
+
+func (h *lifecycleHook) PostApply(
+	info *InstanceInfo, s *InstanceState, err error) (HookAction, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	action, ok := h.pending[info.HumanId()]
+	if !ok {
+		return HookActionContinue, nil
+	}
+	delete(h.pending, info.HumanId())
+
+	if err == nil && s != nil && s.ID != "" {
+		h.applied = append(h.applied, lifecycleHookApplied{
+			path:   normalizeModulePath(info.ModulePath),
+			id:     info.Id,
+			action: action,
+		})
+	}
+
+	return HookActionContinue, nil
+}
+
+// record records the lifecycle of the instances that were applied in the
+// state.
+func (h *lifecycleHook) record(s *State, runID string, t time.Time) {
+	h.Lock()
+	defer h.Unlock()
+
+	for _, a := range h.applied {
+		m := s.ModuleByPath(a.path)
+		if m == nil {
+			continue
+		}
+		rs, ok := m.Resources[a.id]
+		if !ok || rs.Primary == nil {
+			continue
+		}
+
+		if rs.Lifecycle == nil {
+			rs.Lifecycle = new(ResourceLifecycle)
+		}
+		rs.Lifecycle.record(a.action, runID, t)
+	}
+}
+
+// recordImportLifecycles records the import of the resource instances that
+// are in the state s, but weren't in the prior state.
+func recordImportLifecycles(prior, s *State, runID string, t time.Time) {
+	for _, m := range s.Modules {
+		pm := prior.ModuleByPath(m.Path)
+		for k, rs := range m.Resources {
+			if rs.Primary == nil || strings.HasPrefix(k, "data.") {
+				continue
+			}
+			if pm != nil {
+				if _, ok := pm.Resources[k]; ok {
+					continue
+				}
+			}
+
+			rs.Lifecycle = new(ResourceLifecycle)
+			rs.Lifecycle.record(LifecycleActionImport, runID, t)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package terraform
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContext2Apply_lifecycle(t *testing.T) {
+	m := testModule(t, "apply-good")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	providers := ResourceProviderResolverFixed(
+		map[string]ResourceProviderFactory{
+			"aws": testProviderFuncFixed(p),
+		},
+	)
+
+	created := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	ctx := testContext2(t, &ContextOpts{
+		Module:           m,
+		ProviderResolver: providers,
+		Meta:             &ContextMeta{RunID: "run-2"},
+		State: &State{
+			Modules: []*ModuleState{
+				&ModuleState{
+					Path: rootModulePath,
+					Resources: map[string]*ResourceState{
+						"aws_instance.foo": &ResourceState{
+							Type: "aws_instance",
+							Primary: &InstanceState{
+								ID: "foo",
+								Attributes: map[string]string{
+									"num":  "1",
+									"type": "aws_instance",
+								},
+							},
+							Lifecycle: &ResourceLifecycle{
+								CreatedAt:  created,
+								ModifiedAt: created,
+								LastAction: LifecycleActionCreate,
+								RunID:      "run-1",
+							},
+						},
+						"aws_instance.bar": &ResourceState{
+							Type: "aws_instance",
+							Primary: &InstanceState{
+								ID: "foo",
+								Attributes: map[string]string{
+									"foo":  "bar",
+									"type": "aws_instance",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	mod := state.RootModule()
+
+	// The update keeps when the object was created
+	l := mod.Resources["aws_instance.foo"].Lifecycle
+	if l == nil {
+		t.Fatal("expected the lifecycle of aws_instance.foo")
+	}
+	if !l.CreatedAt.Equal(created) {
+		t.Fatalf("expected the creation time to be kept, got %s", l.CreatedAt)
+	}
+	if !l.ModifiedAt.After(created) {
+		t.Fatalf("expected the modification time to be updated, got %s", l.ModifiedAt)
+	}
+	if l.LastAction != LifecycleActionUpdate || l.RunID != "run-2" {
+		t.Fatalf("bad: %#v", l)
+	}
+
+	// Resources without changes aren't recorded
+	if l := mod.Resources["aws_instance.bar"].Lifecycle; l != nil {
+		t.Fatalf("expected no lifecycle for aws_instance.bar, got %#v", l)
+	}
+}
+
+func TestContext2Apply_lifecycleCreate(t *testing.T) {
+	m := testModule(t, "apply-good")
+	p := testProvider("aws")
+	p.ApplyFn = testApplyFn
+	p.DiffFn = testDiffFn
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	if _, err := ctx.Plan(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state, err := ctx.Apply()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Without a run ID, a random one is shared by the run
+	var runID string
+	for k, rs := range state.RootModule().Resources {
+		l := rs.Lifecycle
+		if l == nil {
+			t.Fatalf("expected the lifecycle of %s", k)
+		}
+		if l.LastAction != LifecycleActionCreate || l.CreatedAt.IsZero() || !l.ModifiedAt.Equal(l.CreatedAt) {
+			t.Fatalf("bad lifecycle of %s: %#v", k, l)
+		}
+		if l.RunID == "" || (runID != "" && l.RunID != runID) {
+			t.Fatalf("bad run ID of %s: %q", k, l.RunID)
+		}
+		runID = l.RunID
+	}
+}
+
+func TestContextImport_lifecycle(t *testing.T) {
+	p := testProvider("aws")
+	m := testModule(t, "import-provider")
+	ctx := testContext2(t, &ContextOpts{
+		Module: m,
+		Meta:   &ContextMeta{RunID: "run-1"},
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	})
+
+	p.ImportStateReturn = []*InstanceState{
+		&InstanceState{
+			ID:        "foo",
+			Ephemeral: EphemeralState{Type: "aws_instance"},
+		},
+	}
+
+	state, err := ctx.Import(&ImportOpts{
+		Targets: []*ImportTarget{
+			&ImportTarget{
+				Addr: "aws_instance.foo",
+				ID:   "bar",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	l := state.RootModule().Resources["aws_instance.foo"].Lifecycle
+	if l == nil {
+		t.Fatal("expected the lifecycle of aws_instance.foo")
+	}
+	if l.LastAction != LifecycleActionImport || l.RunID != "run-1" || l.CreatedAt.IsZero() {
+		t.Fatalf("bad: %#v", l)
+	}
+}
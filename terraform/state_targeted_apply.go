@@ -0,0 +1,116 @@
+package terraform
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hashicorp/terraform/config"
+)
+
+// TargetedApplyState records an apply that was limited to some resources
+// with targets. Changes to the other resources were skipped, and are still
+// pending until a full apply.
+type TargetedApplyState struct {
+	// Targets are the target addresses given to the apply.
+	Targets []string `json:"targets"`
+
+	// Time is when the apply was performed.
+	Time time.Time `json:"time"`
+}
+
+// Skipped returns the addresses of the managed resources with changes in
+// the given diff that the targeted apply didn't include, sorted.
+//
+// Resources that the targets depended on were applied along with them, so
+// if any of these resources have changes, they were most likely skipped.
+func (s *TargetedApplyState) Skipped(d *Diff) []string {
+	if s == nil || d == nil {
+		return nil
+	}
+
+	targets := make([]*ResourceAddress, 0, len(s.Targets))
+	for _, t := range s.Targets {
+		addr, err := ParseResourceAddress(t)
+		if err != nil {
+			// Targets were validated when they were given, so this
+			// can only happen if the state was edited.
+			continue
+		}
+		targets = append(targets, addr)
+	}
+
+	var result []string
+	for _, m := range d.Modules {
+		for k, rd := range m.Resources {
+			if rd.Empty() {
+				continue
+			}
+
+			key, err := ParseResourceStateKey(k)
+			if err != nil || key.Mode == config.DataResourceMode {
+				continue
+			}
+
+			addr := &ResourceAddress{
+				Path:  normalizeModulePath(m.Path)[1:],
+				Mode:  key.Mode,
+				Type:  key.Type,
+				Name:  key.Name,
+				Index: key.Index,
+				Key:   key.Key,
+			}
+
+			covered := false
+			for _, t := range targets {
+				if targetedApplyCovers(t, addr) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				result = append(result, addr.String())
+			}
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+// targetedApplyCovers reports whether the target t includes the resource
+// instance at addr.
+func targetedApplyCovers(t, addr *ResourceAddress) bool {
+	if len(addr.Path) < len(t.Path) {
+		return false
+	}
+	for i, p := range t.Path {
+		if addr.Path[i] != p {
+			return false
+		}
+	}
+
+	// A module target includes everything within it
+	if !t.HasResourceSpec() {
+		return true
+	}
+
+	if len(addr.Path) != len(t.Path) ||
+		t.Mode != addr.Mode || t.Type != addr.Type || t.Name != addr.Name {
+		return false
+	}
+
+	if t.Key != "" {
+		return t.Key == addr.Key
+	}
+	if t.Index >= 0 {
+		// A resource without count has index -1 in the state, but can be
+		// targeted as index 0.
+		index := addr.Index
+		if index < 0 {
+			index = 0
+		}
+		return t.Index == index
+	}
+
+	return true
+}
@@ -0,0 +1,87 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTargetedApplyStateSkipped(t *testing.T) {
+	d := &Diff{
+		Modules: []*ModuleDiff{
+			&ModuleDiff{
+				Path: rootModulePath,
+				Resources: map[string]*InstanceDiff{
+					"aws_instance.foo": &InstanceDiff{
+						Attributes: map[string]*ResourceAttrDiff{
+							"ami": &ResourceAttrDiff{Old: "a", New: "b"},
+						},
+					},
+					"aws_instance.bar.0": &InstanceDiff{Destroy: true},
+					"aws_instance.bar.1": &InstanceDiff{Destroy: true},
+					"aws_instance.empty": &InstanceDiff{},
+					"data.aws_ami.foo": &InstanceDiff{
+						Attributes: map[string]*ResourceAttrDiff{
+							"id": &ResourceAttrDiff{NewComputed: true},
+						},
+					},
+				},
+			},
+			&ModuleDiff{
+				Path: []string{"root", "child"},
+				Resources: map[string]*InstanceDiff{
+					"aws_instance.foo": &InstanceDiff{Destroy: true},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		Name    string
+		Targets []string
+		Want    []string
+	}{
+		{
+			"resource",
+			[]string{"aws_instance.foo"},
+			[]string{
+				"aws_instance.bar[0]",
+				"aws_instance.bar[1]",
+				"module.child.aws_instance.foo",
+			},
+		},
+
+		{
+			"resource index",
+			[]string{"aws_instance.bar[1]"},
+			[]string{
+				"aws_instance.bar[0]",
+				"aws_instance.foo",
+				"module.child.aws_instance.foo",
+			},
+		},
+
+		{
+			"module",
+			[]string{"module.child", "aws_instance.bar"},
+			[]string{
+				"aws_instance.foo",
+			},
+		},
+
+		{
+			"everything",
+			[]string{"aws_instance.foo", "aws_instance.bar", "module.child"},
+			nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			s := &TargetedApplyState{Targets: tc.Targets}
+			got := s.Skipped(d)
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("wrong result\ngot:  %#v\nwant: %#v", got, tc.Want)
+			}
+		})
+	}
+}
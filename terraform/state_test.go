@@ -1625,6 +1625,126 @@ func TestReadStateEmptyOrNilFile(t *testing.T) {
 	}
 }
 
+func TestReadStateChecksum(t *testing.T) {
+	state := &State{
+		Serial: 4,
+		Modules: []*ModuleState{
+			&ModuleState{
+				Path: rootModulePath,
+				Resources: map[string]*ResourceState{
+					"foo": &ResourceState{
+						Primary: &InstanceState{ID: "bar"},
+					},
+				},
+			},
+		},
+	}
+	state.init()
+
+	buf := new(bytes.Buffer)
+	if err := WriteState(state, buf); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	data := buf.Bytes()
+	if !bytes.Contains(data, []byte(`"checksum"`)) {
+		t.Fatalf("checksum should be written:\n%s", data)
+	}
+
+	// The state reads back as it was written
+	actual, err := ReadState(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual.Serial != state.Serial {
+		t.Fatalf("bad serial: %d", actual.Serial)
+	}
+	if actual.Checksum != "" {
+		t.Fatalf("checksum should be cleared: %s", actual.Checksum)
+	}
+
+	// A corrupted state is detected
+	corrupt := bytes.Replace(data, []byte(`"bar"`), []byte(`"baz"`), 1)
+	_, err = ReadState(bytes.NewReader(corrupt))
+	if _, ok := err.(*StateChecksumError); !ok {
+		t.Fatalf("expected checksum error, got: %v", err)
+	}
+}
+
+func TestReadStateChecksum_unknownFields(t *testing.T) {
+	// A state from a newer version can have fields that this version
+	// doesn't know about, which are still covered by the checksum.
+	data := []byte(`{
+    "version": 3,
+    "serial": 4,
+    "lineage": "5d1ad1a1-4027-4665-a908-dbe6adff11d8",
+    "checksum": "",
+    "modules": [
+        {
+            "path": [
+                "root"
+            ],
+            "outputs": {},
+            "resources": {},
+            "depends_on": [],
+            "from_the_future": true
+        }
+    ]
+}
+`)
+	sum, err := stateChecksum(data)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	data = bytes.Replace(data, []byte(`""`), []byte(`"`+sum+`"`), 1)
+
+	actual, err := ReadState(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual.Lineage != "5d1ad1a1-4027-4665-a908-dbe6adff11d8" {
+		t.Fatalf("bad lineage: %s", actual.Lineage)
+	}
+
+	// Removing the unknown field breaks the checksum
+	data = bytes.Replace(data, []byte(`,
+            "from_the_future": true`), nil, 1)
+	_, err = ReadState(bytes.NewReader(data))
+	if _, ok := err.(*StateChecksumError); !ok {
+		t.Fatalf("expected checksum error, got: %v", err)
+	}
+}
+
+func TestReadStateChecksum_none(t *testing.T) {
+	// States written before checksums were added are read as they are
+	data := []byte(`{
+    "version": 3,
+    "serial": 4,
+    "lineage": "5d1ad1a1-4027-4665-a908-dbe6adff11d8",
+    "modules": [
+        {
+            "path": [
+                "root"
+            ],
+            "outputs": {},
+            "resources": {},
+            "depends_on": []
+        }
+    ]
+}
+`)
+
+	actual, err := ReadState(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual.Lineage != "5d1ad1a1-4027-4665-a908-dbe6adff11d8" {
+		t.Fatalf("bad lineage: %s", actual.Lineage)
+	}
+	if actual.Checksum != "" {
+		t.Fatalf("bad checksum: %s", actual.Checksum)
+	}
+}
+
 func TestReadStateTFVersion(t *testing.T) {
 	type tfVersion struct {
 		Version   int    `json:"version"`
@@ -1735,6 +1855,20 @@ func TestParseResourceStateKey(t *testing.T) {
 				Index: 3,
 			},
 		},
+		{
+			Input: `aws_instance.foo["bar.baz"]`,
+			Expected: &ResourceStateKey{
+				Mode:  config.ManagedResourceMode,
+				Type:  "aws_instance",
+				Name:  "foo",
+				Index: -1,
+				Key:   "bar.baz",
+			},
+		},
+		{
+			Input:       `aws_instance.foo.0["bar"]`,
+			ExpectedErr: true,
+		},
 		{
 			Input: "aws_instance.foo.0",
 			Expected: &ResourceStateKey{
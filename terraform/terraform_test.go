@@ -996,6 +996,63 @@ STATE:
 <no state>
 `
 
+const testTerraformApplyForEachStr = `
+aws_instance.foo["a"]:
+  ID = foo
+  foo = bar-a
+  type = aws_instance
+aws_instance.foo["c"]:
+  ID = foo
+  foo = bar-c
+  type = aws_instance
+
+Outputs:
+
+output = bar-a,bar-c
+`
+
+const testTerraformPlanForEachStr = `
+DIFF:
+
+CREATE: aws_instance.bar
+  foo:  "" => "a-alpha,b-beta"
+  type: "" => "aws_instance"
+CREATE: aws_instance.foo["a"]
+  foo:  "" => "a-alpha"
+  type: "" => "aws_instance"
+CREATE: aws_instance.foo["b"]
+  foo:  "" => "b-beta"
+  type: "" => "aws_instance"
+
+STATE:
+
+<no state>
+`
+
+const testTerraformPlanForEachKeyRemovedStr = `
+DIFF:
+
+CREATE: aws_instance.bar
+  foo:  "" => "a-alpha,b-beta"
+  type: "" => "aws_instance"
+DESTROY: aws_instance.foo["c"]
+
+STATE:
+
+aws_instance.foo["a"]:
+  ID = a
+  foo = a-alpha
+  type = aws_instance
+aws_instance.foo["b"]:
+  ID = b
+  foo = b-beta
+  type = aws_instance
+aws_instance.foo["c"]:
+  ID = c
+  foo = c-gamma
+  type = aws_instance
+`
+
 const testTerraformPlanCountIndexStr = `
 DIFF:
 
@@ -1299,6 +1356,19 @@ module.child:
     ID = bar1
 `
 
+const testTerraformPlanGlobalsStr = `
+DIFF:
+
+module.child:
+  CREATE: aws_instance.foo
+    foo:  "" => "eu-west-1"
+    type: "" => "aws_instance"
+
+STATE:
+
+<no state>
+`
+
 const testTerraformPlanModuleInputStr = `
 DIFF:
 
@@ -1495,6 +1565,24 @@ aws_instance.foo:
   num = 2
 `
 
+const testTerraformPlanForceReplaceStr = `
+DIFF:
+
+DESTROY/CREATE: aws_instance.bar
+  foo:  "2" => "2"
+  type: "" => "aws_instance"
+
+STATE:
+
+aws_instance.bar:
+  ID = baz
+  foo = 2
+  type = aws_instance
+aws_instance.foo:
+  ID = bar
+  num = 2
+`
+
 const testTerraformPlanTaintIgnoreChangesStr = `
 DIFF:
 
@@ -0,0 +1,13 @@
+resource "aws_instance" "alb" {}
+
+resource "aws_instance" "asg" {
+    lifecycle {
+        destroy_priority = 10
+    }
+}
+
+resource "aws_instance" "web" {
+    lifecycle {
+        destroy_priority = 5
+    }
+}
@@ -0,0 +1,12 @@
+variable "names" {
+    type = "list"
+}
+
+resource "aws_instance" "foo" {
+    for_each = "${var.names}"
+    foo      = "bar-${each.value}"
+}
+
+output "output" {
+    value = "${join(",", aws_instance.foo.*.foo)}"
+}
@@ -0,0 +1,7 @@
+resource "aws_instance" "foo" {
+  num = "2"
+
+  lifecycle {
+    before_create = "exit 1"
+  }
+}
@@ -0,0 +1,16 @@
+variable "num" {
+  default = "2"
+}
+
+resource "aws_instance" "foo" {
+  num = "${var.num}"
+
+  lifecycle {
+    before_create  = "echo {{.Action}} {{.Address}}"
+    after_create   = "echo created {{.ID}} {{index .Attributes \"num\"}}"
+    before_update  = "echo updating {{index .Attributes \"num\"}}"
+    after_update   = "echo updated {{index .Attributes \"num\"}}"
+    before_destroy = "echo destroying {{.ID}}"
+    after_destroy  = "echo destroyed {{.ID}}"
+  }
+}
@@ -0,0 +1,13 @@
+provider "aws" {
+    endpoints {
+        ec2 = "ec2.child"
+    }
+
+    tags = {
+        b = "child"
+    }
+
+    ids = ["child"]
+}
+
+resource "aws_instance" "foo" {}
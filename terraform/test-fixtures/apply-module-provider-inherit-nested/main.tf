@@ -0,0 +1,15 @@
+provider "aws" {
+    region = "root"
+
+    endpoints {
+        s3 = "s3.root"
+    }
+
+    tags = {
+        a = "root"
+    }
+}
+
+module "child" {
+    source = "./child"
+}
@@ -0,0 +1,4 @@
+output "password" {
+    value = "secret"
+    sensitive = true
+}
@@ -0,0 +1,11 @@
+module "child" {
+    source = "./child"
+}
+
+resource "aws_instance" "bar" {
+    foo = "${module.child.password}"
+}
+
+output "password" {
+    value = "${module.child.password}"
+}
@@ -0,0 +1,7 @@
+resource "aws_instance" "foo" {
+    provider_config {
+        region = "west"
+    }
+}
+
+resource "aws_instance" "bar" {}
@@ -0,0 +1,8 @@
+provider "aws" {
+    region = "east"
+    zone   = "a"
+}
+
+module "child" {
+    source = "./child"
+}
@@ -0,0 +1,12 @@
+provider "aws" {
+    region = "east"
+    zone   = "a"
+}
+
+resource "aws_instance" "foo" {
+    provider_config {
+        region = "west"
+    }
+}
+
+resource "aws_instance" "bar" {}
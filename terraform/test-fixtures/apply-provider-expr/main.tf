@@ -0,0 +1,18 @@
+variable "regions" {
+    default = ["east", "west"]
+}
+
+provider "aws" {
+    alias = "east"
+    region = "east"
+}
+
+provider "aws" {
+    alias = "west"
+    region = "west"
+}
+
+resource "aws_instance" "foo" {
+    count = 2
+    provider = "aws.${element(var.regions, count.index)}"
+}
@@ -0,0 +1,9 @@
+resource "aws_instance" "foo" {
+    num = "2"
+
+    provisioner "shell" {}
+}
+
+resource "aws_instance" "bar" {
+    foo = "${aws_instance.foo.provisioner_output.version}"
+}
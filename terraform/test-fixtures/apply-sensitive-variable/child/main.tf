@@ -0,0 +1,9 @@
+variable "password" {}
+
+resource "aws_instance" "child" {
+    foo = "prefix-${var.password}"
+}
+
+output "greeting" {
+    value = "hello ${var.password}"
+}
@@ -0,0 +1,35 @@
+variable "secret" {
+    default = "hunter2"
+    sensitive = true
+}
+
+module "child" {
+    source = "./child"
+    password = "${var.secret}-suffix"
+}
+
+resource "aws_instance" "foo" {
+    foo = "${var.secret}-suffix"
+    num = "2"
+}
+
+output "derived" {
+    value = "${upper(var.secret)}"
+}
+
+output "from_child" {
+    value = "${module.child.greeting}"
+}
+
+output "plain" {
+    value = "plain"
+}
+
+resource "aws_instance" "bar" {
+    foo = "${aws_instance.foo.foo}"
+    num = "${aws_instance.foo.num}"
+}
+
+output "from_resource" {
+    value = "${aws_instance.bar.foo}"
+}
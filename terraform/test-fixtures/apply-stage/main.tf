@@ -0,0 +1,14 @@
+resource "aws_instance" "dns" {
+    count = 2
+    stage = 1
+}
+
+resource "aws_instance" "cert" {
+    stage = 2
+}
+
+resource "aws_instance" "web" {}
+
+module "child" {
+    source = "./child"
+}
@@ -0,0 +1,11 @@
+resource "aws_instance" "web" {
+    foo = "bar"
+
+    lifecycle {
+        wait_for_ready = true
+    }
+}
+
+resource "aws_instance" "bar" {
+    foo = "baz"
+}
@@ -0,0 +1,3 @@
+provider "do" {}
+
+resource "do_droplet" "foo" {}
@@ -0,0 +1,21 @@
+provider "aws" {}
+
+provider "do" {
+    version = "~> 1.0"
+}
+
+resource "aws_instance" "foo" {
+    ami = "${concat("${list("a")}", list("b"))}"
+}
+
+resource "do_droplet" "foo" {}
+
+resource "test_thing" "foo" {}
+
+module "child" {
+    source = "./child"
+}
+
+module "explicit" {
+    source = "./explicit"
+}
@@ -0,0 +1,10 @@
+variable "foo" {}
+variable "bar" {}
+
+provider "aws" {
+  foo = "${var.foo}"
+}
+
+resource "aws_instance" "foo" {
+  ami = "${var.bar}"
+}
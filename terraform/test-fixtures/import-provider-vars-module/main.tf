@@ -0,0 +1,9 @@
+variable "foo" {}
+
+resource "aws_instance" "bar" {}
+
+module "foo" {
+  source = "./child"
+  foo    = "${var.foo}"
+  bar    = "${aws_instance.bar.id}"
+}
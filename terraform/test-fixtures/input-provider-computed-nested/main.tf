@@ -0,0 +1,10 @@
+resource "test_instance" "foo" {}
+
+provider "aws" {
+    tags = {
+        a = "${test_instance.foo.id}"
+        b = "b"
+    }
+}
+
+resource "aws_instance" "bar" {}
@@ -0,0 +1,11 @@
+variable "old_name" {
+    default = ""
+    deprecated = "Use name instead."
+}
+
+variable "name" {}
+
+module "nested" {
+    source = "./nested"
+    legacy = "${var.name}"
+}
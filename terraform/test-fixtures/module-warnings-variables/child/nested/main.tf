@@ -0,0 +1,3 @@
+variable "legacy" {
+    deprecated = "It has no effect anymore."
+}
@@ -0,0 +1,10 @@
+module "child" {
+    source = "./child"
+    old_name = "foo"
+    name = "bar"
+}
+
+module "current" {
+    source = "./child"
+    name = "baz"
+}
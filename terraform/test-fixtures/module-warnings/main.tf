@@ -0,0 +1,7 @@
+module "old" {
+    source = "./old"
+}
+
+module "current" {
+    source = "./current"
+}
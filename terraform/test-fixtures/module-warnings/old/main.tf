@@ -0,0 +1,8 @@
+terraform {
+    deprecated = "Use the current module instead."
+    recommended_version = ">= 0.9.0"
+}
+
+module "nested" {
+    source = "./nested"
+}
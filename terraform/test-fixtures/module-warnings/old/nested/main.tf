@@ -0,0 +1,3 @@
+terraform {
+    recommended_version = ">= 0.9.0"
+}
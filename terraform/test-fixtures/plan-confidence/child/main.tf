@@ -0,0 +1,5 @@
+variable "subnet" {}
+
+resource "aws_eip" "ip" {
+  subnet = "${var.subnet}"
+}
@@ -0,0 +1,18 @@
+resource "aws_vpc" "main" {}
+
+resource "aws_subnet" "main" {
+  vpc_id = "${aws_vpc.main.id}"
+}
+
+module "child" {
+  source = "./child"
+  subnet = "${aws_subnet.main.id}"
+}
+
+resource "aws_instance" "web" {
+  subnet = "${aws_subnet.main.id}"
+}
+
+resource "aws_instance" "known" {
+  cidr = "${aws_vpc.main.cidr_block}"
+}
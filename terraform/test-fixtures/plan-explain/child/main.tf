@@ -0,0 +1,9 @@
+variable "vpc" {}
+
+resource "aws_ami" "base" {
+  vpc = "${var.vpc}"
+}
+
+output "ami" {
+  value = "${aws_ami.base.id}"
+}
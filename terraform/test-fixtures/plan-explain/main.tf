@@ -0,0 +1,22 @@
+resource "aws_vpc" "main" {}
+
+resource "aws_security_group" "sg" {}
+
+module "child" {
+  source = "./child"
+  vpc    = "${aws_vpc.main.id}"
+}
+
+resource "aws_instance" "other" {}
+
+resource "aws_instance" "unchanged" {
+  vpc = "${aws_vpc.main.id}"
+}
+
+resource "aws_instance" "web" {
+  count           = 2
+  ami             = "${module.child.ami}"
+  security_groups = ["${aws_security_group.sg.id}"]
+  subnet          = "${aws_instance.unchanged.id}"
+  depends_on      = ["aws_instance.other"]
+}
@@ -0,0 +1,15 @@
+variable "names" {
+    default = {
+        a = "alpha"
+        b = "beta"
+    }
+}
+
+resource "aws_instance" "foo" {
+    for_each = "${var.names}"
+    foo      = "${each.key}-${each.value}"
+}
+
+resource "aws_instance" "bar" {
+    foo = "${join(",", aws_instance.foo.*.foo)}"
+}
@@ -0,0 +1,7 @@
+terraform {
+    globals = ["region"]
+}
+
+resource "aws_instance" "foo" {
+    foo = "${global.region}"
+}
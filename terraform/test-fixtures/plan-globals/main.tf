@@ -0,0 +1,7 @@
+variable "region" {
+    default = "us-east-1"
+}
+
+module "child" {
+    source = "./child"
+}
@@ -0,0 +1,15 @@
+variable "password" {
+    ephemeral = true
+}
+
+variable "region" {
+    default = "us-east-1"
+}
+
+provider "aws" {
+    password = "${var.password}"
+}
+
+resource "aws_instance" "foo" {
+    foo = "${var.region}"
+}
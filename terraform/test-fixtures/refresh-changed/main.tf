@@ -0,0 +1,15 @@
+resource "aws_vpc" "changed" {
+  foo = "new"
+}
+
+resource "aws_instance" "dependent" {
+  foo = "${aws_vpc.changed.id}"
+}
+
+resource "aws_instance" "unchanged" {
+  foo = "bar"
+}
+
+resource "aws_elb" "other" {
+  foo = "${aws_instance.unchanged.id}"
+}
@@ -0,0 +1,7 @@
+resource "aws_instance" "sg" {}
+
+resource "aws_instance" "web" {
+    foo = "${aws_instance.sg.id}"
+}
+
+resource "aws_instance" "db" {}
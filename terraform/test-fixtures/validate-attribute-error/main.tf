@@ -0,0 +1,11 @@
+resource "aws_instance" "foo" {
+    ami = "bar"
+
+    ingress {
+        from = 80
+    }
+
+    ingress {
+        from = 0
+    }
+}
@@ -0,0 +1,7 @@
+terraform {
+    strict {
+        warnings = ["provider-unconstrained"]
+    }
+}
+
+resource "aws_instance" "foo" {}
@@ -1,6 +1,10 @@
 package terraform
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/config"
+)
 
 // DeposedTransformer is a GraphTransformer that adds deposed resources
 // to the graph.
@@ -12,6 +16,10 @@ type DeposedTransformer struct {
 	// View, if non-empty, is the ModuleState.View used around the state
 	// to find deposed resources.
 	View string
+
+	// Config, if set, is the configuration of the resource that the deposed
+	// resources are of, for running its lifecycle hooks.
+	Config *config.Resource
 }
 
 func (t *DeposedTransformer) Transform(g *Graph) error {
@@ -41,6 +49,7 @@ func (t *DeposedTransformer) Transform(g *Graph) error {
 				ResourceName: k,
 				ResourceType: rs.Type,
 				Provider:     rs.Provider,
+				Config:       t.Config,
 			})
 		}
 	}
@@ -54,6 +63,7 @@ type graphNodeDeposedResource struct {
 	ResourceName string
 	ResourceType string
 	Provider     string
+	Config       *config.Resource
 }
 
 func (n *graphNodeDeposedResource) Name() string {
@@ -67,7 +77,7 @@ func (n *graphNodeDeposedResource) ProvidedBy() []string {
 // GraphNodeEvalable impl.
 func (n *graphNodeDeposedResource) EvalTree() EvalNode {
 	var provider ResourceProvider
-	var state *InstanceState
+	var state, hookState *InstanceState
 
 	seq := &EvalSequence{Nodes: make([]EvalNode, 0, 5)}
 
@@ -127,6 +137,17 @@ func (n *graphNodeDeposedResource) EvalTree() EvalNode {
 					State:  &state,
 					Output: &diff,
 				},
+				&EvalReadStateDeposed{
+					Name:   n.ResourceName,
+					Output: &hookState,
+					Index:  n.Index,
+				},
+				&EvalResourceHook{
+					Info:     info,
+					Resource: n.Config,
+					Destroy:  true,
+					State:    &hookState,
+				},
 				// Call pre-apply hook
 				&EvalApplyPre{
 					Info:  info,
@@ -151,6 +172,14 @@ func (n *graphNodeDeposedResource) EvalTree() EvalNode {
 					State:        &state,
 					Index:        n.Index,
 				},
+				&EvalResourceHook{
+					Info:     info,
+					Resource: n.Config,
+					After:    true,
+					Destroy:  true,
+					State:    &hookState,
+					Error:    &err,
+				},
 				&EvalApplyPost{
 					Info:  info,
 					State: &state,
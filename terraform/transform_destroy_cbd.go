@@ -95,9 +95,10 @@ func (t *CBDEdgeTransformer) Transform(g *Graph) error {
 		// this will have to change. We have a test case covering this
 		// (depNonCBDCountBoth) so it'll be caught.
 		addr := dn.DestroyAddr()
-		if addr.Index >= 0 {
+		if addr.Index >= 0 || addr.Key != "" {
 			addr = addr.Copy() // Copy so that we don't modify any pointers
 			addr.Index = -1
+			addr.Key = ""
 		}
 
 		// Add this to the list of nodes that we need to fix up
@@ -151,9 +152,10 @@ func (t *CBDEdgeTransformer) Transform(g *Graph) error {
 		// dependencies. One day when we limit dependencies more exactly
 		// this will have to change. We have a test case covering this
 		// (depNonCBDCount) so it'll be caught.
-		if addr.Index >= 0 {
+		if addr.Index >= 0 || addr.Key != "" {
 			addr = addr.Copy() // Copy so that we don't modify any pointers
 			addr.Index = -1
+			addr.Key = ""
 		}
 
 		// If there is nothing this resource should depend on, ignore it
@@ -128,8 +128,8 @@ func (t *DestroyEdgeTransformer) Transform(g *Graph) error {
 		&MissingProviderTransformer{AllowAny: true, Concrete: providerFn},
 		&ProviderTransformer{},
 		&DisableProviderTransformer{},
-		&ParentProviderTransformer{},
 		&AttachProviderConfigTransformer{Module: t.Module},
+		&ParentProviderTransformer{},
 
 		// Add all the variables. We can depend on resources through
 		// variables due to module parameters, and we need to properly
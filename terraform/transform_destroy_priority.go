@@ -0,0 +1,103 @@
+package terraform
+
+import (
+	"log"
+	"sort"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+// GraphNodeDestroyPrioritizer is implemented by destroyers whose order of
+// destruction can be adjusted with the destroy_priority lifecycle setting.
+type GraphNodeDestroyPrioritizer interface {
+	GraphNodeDestroyer
+
+	// DestroyPriority is the priority of the destruction. Higher priority
+	// destruction happens first.
+	DestroyPriority() int
+}
+
+// DestroyPriorityTransformer is a GraphTransformer that orders the
+// destruction of resources by their destroy priority, so that resources
+// with a higher priority are destroyed before those with a lower one.
+//
+// The priority is only a hint: it never overrides the order that comes from
+// dependencies. If a resource with a lower priority must already be
+// destroyed first, no edge is added between the two.
+//
+// This must run after all the other edges between destroyers exist, and
+// after targeting, so that a priority never pulls in extra resources to
+// destroy.
+type DestroyPriorityTransformer struct{}
+
+func (t *DestroyPriorityTransformer) Transform(g *Graph) error {
+	var nodes []GraphNodeDestroyPrioritizer
+	prioritized := false
+	for _, v := range g.Vertices() {
+		dn, ok := v.(GraphNodeDestroyPrioritizer)
+		if !ok || dn.DestroyAddr() == nil {
+			continue
+		}
+
+		nodes = append(nodes, dn)
+		if dn.DestroyPriority() != 0 {
+			prioritized = true
+		}
+	}
+
+	// If nothing has a priority, everything is in the same order as it was
+	if !prioritized {
+		return nil
+	}
+
+	// Sort from the highest priority to the lowest, and group the nodes into
+	// levels of the same priority. Nodes with the same priority are sorted by
+	// name so the result is always the same.
+	sort.Slice(nodes, func(i, j int) bool {
+		pi, pj := nodes[i].DestroyPriority(), nodes[j].DestroyPriority()
+		if pi != pj {
+			return pi > pj
+		}
+
+		return dag.VertexName(nodes[i]) < dag.VertexName(nodes[j])
+	})
+
+	var levels [][]GraphNodeDestroyPrioritizer
+	for i, n := range nodes {
+		if i == 0 || n.DestroyPriority() != nodes[i-1].DestroyPriority() {
+			levels = append(levels, nil)
+		}
+		levels[len(levels)-1] = append(levels[len(levels)-1], n)
+	}
+
+	// Ordering is transitive, so each level only needs to be connected to
+	// the next lower one.
+	for i := 0; i+1 < len(levels); i++ {
+		for _, a := range levels[i] {
+			// The edges added below only add dependents of a, so its
+			// ancestors stay the same.
+			deps, err := g.Ancestors(a)
+			if err != nil {
+				return err
+			}
+
+			for _, b := range levels[i+1] {
+				// b is destroyed after a, unless a already depends on b
+				if deps.Include(b) {
+					log.Printf(
+						"[DEBUG] DestroyPriorityTransformer: %s must be destroyed before %s "+
+							"despite its lower priority",
+						dag.VertexName(b), dag.VertexName(a))
+					continue
+				}
+
+				log.Printf(
+					"[TRACE] DestroyPriorityTransformer: %s destroyed before %s",
+					dag.VertexName(a), dag.VertexName(b))
+				g.Connect(dag.BasicEdge(b, a))
+			}
+		}
+	}
+
+	return nil
+}
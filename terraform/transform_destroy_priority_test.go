@@ -0,0 +1,104 @@
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+func TestDestroyPriorityTransformer(t *testing.T) {
+	g := Graph{Path: RootModulePath}
+	g.Add(&graphNodeDestroyPriorityTest{AddrString: "test.A"})
+	g.Add(&graphNodeDestroyPriorityTest{AddrString: "test.B", Priority: 10})
+	g.Add(&graphNodeDestroyPriorityTest{AddrString: "test.C", Priority: 5})
+	tf := &DestroyPriorityTransformer{}
+	if err := tf.Transform(&g); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(g.String())
+	expected := strings.TrimSpace(testTransformDestroyPriorityStr)
+	if actual != expected {
+		t.Fatalf("bad:\n\n%s", actual)
+	}
+}
+
+func TestDestroyPriorityTransformer_none(t *testing.T) {
+	g := Graph{Path: RootModulePath}
+	g.Add(&graphNodeDestroyPriorityTest{AddrString: "test.A"})
+	g.Add(&graphNodeDestroyPriorityTest{AddrString: "test.B"})
+	tf := &DestroyPriorityTransformer{}
+	if err := tf.Transform(&g); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(g.String())
+	expected := strings.TrimSpace(testTransformDestroyPriorityNoneStr)
+	if actual != expected {
+		t.Fatalf("bad:\n\n%s", actual)
+	}
+}
+
+func TestDestroyPriorityTransformer_dependencies(t *testing.T) {
+	// B has the highest priority, but must be destroyed after A since A
+	// depends on it. C is still destroyed after B, so it's destroyed last.
+	g := Graph{Path: RootModulePath}
+	a := g.Add(&graphNodeDestroyPriorityTest{AddrString: "test.A"})
+	b := g.Add(&graphNodeDestroyPriorityTest{AddrString: "test.B", Priority: 10})
+	g.Add(&graphNodeDestroyPriorityTest{AddrString: "test.C", Priority: 5})
+	g.Connect(dag.BasicEdge(b, a))
+	tf := &DestroyPriorityTransformer{}
+	if err := tf.Transform(&g); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(g.String())
+	expected := strings.TrimSpace(testTransformDestroyPriorityDepsStr)
+	if actual != expected {
+		t.Fatalf("bad:\n\n%s", actual)
+	}
+}
+
+type graphNodeDestroyPriorityTest struct {
+	AddrString string
+	Priority   int
+}
+
+func (n *graphNodeDestroyPriorityTest) Name() string {
+	return n.DestroyAddr().String() + " (destroy)"
+}
+
+func (n *graphNodeDestroyPriorityTest) DestroyAddr() *ResourceAddress {
+	addr, err := ParseResourceAddress(n.AddrString)
+	if err != nil {
+		panic(err)
+	}
+
+	return addr
+}
+
+func (n *graphNodeDestroyPriorityTest) DestroyPriority() int {
+	return n.Priority
+}
+
+const testTransformDestroyPriorityStr = `
+test.A (destroy)
+  test.C (destroy)
+test.B (destroy)
+test.C (destroy)
+  test.B (destroy)
+`
+
+const testTransformDestroyPriorityNoneStr = `
+test.A (destroy)
+test.B (destroy)
+`
+
+const testTransformDestroyPriorityDepsStr = `
+test.A (destroy)
+test.B (destroy)
+  test.A (destroy)
+test.C (destroy)
+  test.B (destroy)
+`
@@ -63,8 +63,9 @@ func (t *DiffTransformer) Transform(g *Graph) error {
 				g.Add(&NodeDestroyResource{NodeAbstractResource: abstract})
 			}
 
-			// If we have changes, then add the applyable version
-			if len(inst.Attributes) > 0 {
+			// If we have changes, then add the applyable version. Adopting
+			// an existing object is a change even if it needs no updates.
+			if len(inst.Attributes) > 0 || inst.AdoptID != "" {
 				// Add the resource to the graph
 				abstract := &NodeAbstractResource{Addr: addr}
 				var node dag.Vertex = abstract
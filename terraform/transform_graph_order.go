@@ -0,0 +1,141 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+// GraphOrderVersion is the version of the format of GraphOrder.
+const GraphOrderVersion = 1
+
+// GraphOrder is an ordering of the nodes of an apply graph, computed
+// outside of Terraform from a GraphExport, such as by a custom scheduler.
+// It's read by "terraform apply -graph-order".
+type GraphOrder struct {
+	Version int `json:"version"`
+
+	// Stages are the IDs of the nodes of the graph, in stages. Every node
+	// in a stage is done before any node in a later stage starts, and the
+	// nodes within a stage run in parallel, as far as their dependencies
+	// and the parallelism allow. Every node of the graph must be in
+	// exactly one stage, after every node it depends on.
+	Stages [][]string `json:"stages"`
+}
+
+// ReadGraphOrder reads a GraphOrder from its JSON form.
+func ReadGraphOrder(src io.Reader) (*GraphOrder, error) {
+	var result GraphOrder
+	if err := json.NewDecoder(src).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error decoding the graph order: %s", err)
+	}
+
+	if result.Version != GraphOrderVersion {
+		return nil, fmt.Errorf(
+			"unsupported graph order version %d; this version of Terraform "+
+				"supports version %d", result.Version, GraphOrderVersion)
+	}
+
+	return &result, nil
+}
+
+// GraphOrderTransformer is a GraphTransformer that makes the walk of the
+// graph follow an ordering computed outside of Terraform, by putting
+// barriers between its stages like StageTransformer does.
+//
+// The ordering can only add to the order from the dependencies, so an
+// ordering that doesn't have every node of the graph, or that runs a node
+// before one it depends on, is an error. The graph is then not walked at
+// all, rather than in an order that wasn't asked for.
+//
+// This must run before the RootTransformer, since the root node isn't in
+// the ordering.
+type GraphOrderTransformer struct {
+	Order *GraphOrder
+}
+
+func (t *GraphOrderTransformer) Transform(g *Graph) error {
+	if t.Order == nil {
+		return nil
+	}
+
+	ids, err := graphNodeIDs(g)
+	if err != nil {
+		return err
+	}
+
+	stageOf := make(map[dag.Vertex]int, len(ids))
+	for i, stage := range t.Order.Stages {
+		for _, id := range stage {
+			v, ok := ids[id]
+			if !ok {
+				return fmt.Errorf("graph order: %q isn't a node of the apply graph", id)
+			}
+			if _, ok := stageOf[v]; ok {
+				return fmt.Errorf("graph order: %q is in more than one stage", id)
+			}
+
+			stageOf[v] = i
+		}
+	}
+	for id, v := range ids {
+		if _, ok := stageOf[v]; !ok {
+			return fmt.Errorf("graph order: %q isn't in any stage", id)
+		}
+	}
+
+	for _, e := range g.Edges() {
+		// The source of an edge depends on its target
+		if stageOf[e.Target()] >= stageOf[e.Source()] {
+			return fmt.Errorf(
+				"graph order: %q is in stage %d, but it depends on %q in stage %d",
+				dag.VertexName(e.Source()), stageOf[e.Source()]+1,
+				dag.VertexName(e.Target()), stageOf[e.Target()]+1)
+		}
+	}
+
+	// Each barrier depends on every node in its stage, and every node in a
+	// stage depends on the barrier of the stage before it. Barriers depend
+	// on each other too, so that empty stages don't break the chain. The
+	// last stage doesn't need a barrier, since nothing comes after it.
+	var prev dag.Vertex
+	for i, stage := range t.Order.Stages {
+		var barrier dag.Vertex
+		if i < len(t.Order.Stages)-1 {
+			barrier = g.Add(&NodeOrderBarrier{Stage: i + 1})
+			if prev != nil {
+				g.Connect(dag.BasicEdge(barrier, prev))
+			}
+		}
+
+		for _, id := range stage {
+			v := ids[id]
+			if prev != nil {
+				g.Connect(dag.BasicEdge(v, prev))
+			}
+			if barrier != nil {
+				g.Connect(dag.BasicEdge(barrier, v))
+			}
+		}
+
+		prev = barrier
+	}
+
+	log.Printf(
+		"[TRACE] GraphOrderTransformer: %d node(s) in %d stage(s)",
+		len(ids), len(t.Order.Stages))
+	return nil
+}
+
+// NodeOrderBarrier is the node that's reached once every node in a stage
+// of a GraphOrder is done. It does nothing itself.
+type NodeOrderBarrier struct {
+	Stage int
+}
+
+func (n *NodeOrderBarrier) Name() string {
+	return fmt.Sprintf("graph order stage %d", n.Stage)
+}
@@ -0,0 +1,182 @@
+package terraform
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReadGraphOrder(t *testing.T) {
+	order, err := ReadGraphOrder(strings.NewReader(
+		`{"version": 1, "stages": [["provider.aws"], ["aws_instance.foo"]]}`))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(order.Stages) != 2 || order.Stages[1][0] != "aws_instance.foo" {
+		t.Fatalf("bad: %#v", order)
+	}
+
+	if _, err := ReadGraphOrder(strings.NewReader(`{"version": 2}`)); err == nil {
+		t.Fatal("should error")
+	}
+	if _, err := ReadGraphOrder(strings.NewReader(`{"version": `)); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+// testGraphOrder returns an ordering of g with one node in each stage,
+// which picks the node with the greatest ID from those that are ready.
+func testGraphOrder(g *GraphExport) *GraphOrder {
+	deps := make(map[string]map[string]bool)
+	for _, n := range g.Nodes {
+		deps[n.ID] = make(map[string]bool)
+	}
+	for _, e := range g.Edges {
+		deps[e.To][e.From] = true
+	}
+
+	result := &GraphOrder{Version: GraphOrderVersion}
+	for len(deps) > 0 {
+		var ready []string
+		for id, d := range deps {
+			if len(d) == 0 {
+				ready = append(ready, id)
+			}
+		}
+		sort.Strings(ready)
+
+		next := ready[len(ready)-1]
+		result.Stages = append(result.Stages, []string{next})
+		delete(deps, next)
+		for _, d := range deps {
+			delete(d, next)
+		}
+	}
+
+	return result
+}
+
+func TestContext2Apply_graphOrder(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		testContext2Apply_graphOrder(t)
+	}
+}
+
+func testContext2Apply_graphOrder(t *testing.T) {
+	m := testModule(t, "apply-good")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+
+	var actual []string
+	var actualLock sync.Mutex
+	p.ApplyFn = func(
+		info *InstanceInfo, _ *InstanceState, _ *InstanceDiff) (*InstanceState, error) {
+		actualLock.Lock()
+		defer actualLock.Unlock()
+		actual = append(actual, info.HumanId())
+		return &InstanceState{ID: info.Id}, nil
+	}
+
+	opts := &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	}
+	ctx := testContext2(t, opts)
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	g, err := ctx.ApplyGraph()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The resources don't depend on each other, but the ordering runs foo
+	// before bar.
+	opts.GraphOrder = testGraphOrder(g)
+	ctx, err = plan.Context(opts)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := ctx.Apply(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"aws_instance.foo", "aws_instance.bar"}
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestContext2Apply_graphOrderInvalid(t *testing.T) {
+	m := testModule(t, "apply-good")
+	p := testProvider("aws")
+	p.DiffFn = testDiffFn
+	p.ApplyFn = testApplyFn
+
+	opts := &ContextOpts{
+		Module: m,
+		ProviderResolver: ResourceProviderResolverFixed(
+			map[string]ResourceProviderFactory{
+				"aws": testProviderFuncFixed(p),
+			},
+		),
+	}
+	ctx := testContext2(t, opts)
+	plan, err := ctx.Plan()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	g, err := ctx.ApplyGraph()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var ids []string
+	for _, n := range g.Nodes {
+		ids = append(ids, n.ID)
+	}
+
+	cases := map[string]struct {
+		Stages [][]string
+		Err    string
+	}{
+		"unknown node": {
+			append([][]string{{"aws_instance.baz"}}, testGraphOrder(g).Stages...),
+			`"aws_instance.baz" isn't a node`,
+		},
+		"missing node": {
+			testGraphOrder(g).Stages[1:],
+			"isn't in any stage",
+		},
+		"repeated node": {
+			append(testGraphOrder(g).Stages, []string{"aws_instance.foo"}),
+			"more than one stage",
+		},
+		"dependency": {
+			[][]string{ids},
+			"but it depends on",
+		},
+	}
+
+	for name, tc := range cases {
+		opts.GraphOrder = &GraphOrder{Version: GraphOrderVersion, Stages: tc.Stages}
+		ctx, err := plan.Context(opts)
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+
+		_, err = ctx.Apply()
+		if err == nil || !strings.Contains(err.Error(), tc.Err) {
+			t.Fatalf("%s: bad: %v", name, err)
+		}
+		if p.ApplyCalled {
+			t.Fatalf("%s: nothing should be applied", name)
+		}
+	}
+}
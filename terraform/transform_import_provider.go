@@ -2,7 +2,10 @@ package terraform
 
 import (
 	"fmt"
+	"log"
 	"strings"
+
+	"github.com/hashicorp/terraform/dag"
 )
 
 // ImportProviderValidateTransformer is a GraphTransformer that goes through
@@ -36,3 +39,38 @@ func (t *ImportProviderValidateTransformer) Transform(g *Graph) error {
 
 	return nil
 }
+
+// ImportVariablePruneTransformer is a GraphTransformer that removes the
+// module variables that no provider depends on. Only providers are
+// configured during an import, and the other variables may refer to
+// resources, which have no values yet.
+type ImportVariablePruneTransformer struct{}
+
+func (t *ImportVariablePruneTransformer) Transform(g *Graph) error {
+	needed := make(map[dag.Vertex]struct{})
+	for _, v := range g.Vertices() {
+		if _, ok := v.(GraphNodeProvider); !ok {
+			continue
+		}
+
+		deps, err := g.Ancestors(v)
+		if err != nil {
+			return err
+		}
+		for _, dep := range deps.List() {
+			needed[dep] = struct{}{}
+		}
+	}
+
+	for _, v := range g.Vertices() {
+		if _, ok := v.(*NodeApplyableModuleVariable); !ok {
+			continue
+		}
+		if _, ok := needed[v]; !ok {
+			log.Printf("[DEBUG] Pruning variable that no provider needs: %s", dag.VertexName(v))
+			g.Remove(v)
+		}
+	}
+
+	return nil
+}
@@ -49,6 +49,7 @@ func (t *OrphanResourceCountTransformer) Transform(g *Graph) error {
 		// the same resource, then just ignore it.
 		addrCopy := addr.Copy()
 		addrCopy.Index = -1
+		addrCopy.Key = ""
 		if !addrCopy.Equals(t.Addr) {
 			continue
 		}
@@ -57,6 +58,12 @@ func (t *OrphanResourceCountTransformer) Transform(g *Graph) error {
 
 		idx := addr.Index
 
+		// Instances with a key were created by a for_each that has since
+		// been replaced by count, so they are always orphans.
+		if addr.Key != "" {
+			idx = t.Count + 1
+		}
+
 		// If we have zero and the index here is 0 or 1, then we
 		// change the index to a high number so that we treat it as
 		// an orphan.
@@ -0,0 +1,69 @@
+package terraform
+
+import (
+	"log"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+// OrphanResourceForEachTransformer is a GraphTransformer that adds orphans
+// for an expanded for_each to the graph.
+//
+// Orphans are the instances of the resource found in the state whose key
+// is no longer present in the for_each. Instances without a key, left
+// behind by a resource that previously used count, are always orphans.
+type OrphanResourceForEachTransformer struct {
+	Concrete ConcreteResourceNodeFunc
+
+	ForEach map[string]interface{} // Actual for_each of the resource
+	Addr    *ResourceAddress       // Addr of the resource to look for orphans
+	State   *State                 // Full global state
+}
+
+func (t *OrphanResourceForEachTransformer) Transform(g *Graph) error {
+	log.Printf("[TRACE] OrphanResourceForEach: Starting...")
+
+	// Grab the module in the state just for this resource address
+	ms := t.State.ModuleByPath(normalizeModulePath(t.Addr.Path))
+	if ms == nil {
+		// If no state, there can't be orphans
+		return nil
+	}
+
+	for key, _ := range ms.Resources {
+		// Build the address
+		addr, err := parseResourceAddressInternal(key)
+		if err != nil {
+			return err
+		}
+		addr.Path = ms.Path[1:]
+
+		// Copy the address for comparison. If we aren't looking at
+		// the same resource, then just ignore it.
+		addrCopy := addr.Copy()
+		addrCopy.Index = -1
+		addrCopy.Key = ""
+		if !addrCopy.Equals(t.Addr) {
+			continue
+		}
+
+		log.Printf("[TRACE] OrphanResourceForEach: Checking: %s", addr)
+
+		// If the key is still in the for_each, it is not an orphan
+		if _, ok := t.ForEach[addr.Key]; ok && addr.Key != "" {
+			continue
+		}
+
+		// Build the abstract node and the concrete one
+		abstract := &NodeAbstractResource{Addr: addr}
+		var node dag.Vertex = abstract
+		if f := t.Concrete; f != nil {
+			node = f(abstract)
+		}
+
+		// Add it to the graph
+		g.Add(node)
+	}
+
+	return nil
+}
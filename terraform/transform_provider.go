@@ -16,6 +16,14 @@ type GraphNodeProvider interface {
 	ProviderName() string
 }
 
+// GraphNodeProviderBase is implemented by providers whose configuration
+// overrides the arguments of another provider, as with a provider_config
+// block. ProviderBase returns the name of that provider, or "" if there
+// isn't one.
+type GraphNodeProviderBase interface {
+	ProviderBase() string
+}
+
 // GraphNodeCloseProvider is an interface that nodes that can be a close
 // provider must implement. The CloseProviderName returned is the name of
 // the provider they satisfy.
@@ -206,15 +214,19 @@ func (t *MissingProviderTransformer) Transform(g *Graph) error {
 //
 // This works by finding nodes that are both GraphNodeProviders and
 // GraphNodeSubPath. It then connects the providers to their parent
-// path.
+// path. Providers with a base, from GraphNodeProviderBase, are connected to
+// the base instead, in the same module or the closest parent module that
+// has it.
 type ParentProviderTransformer struct{}
 
 func (t *ParentProviderTransformer) Transform(g *Graph) error {
 	// Make a mapping of path to dag.Vertex, where path is: "path.name"
 	m := make(map[string]dag.Vertex)
 
-	// Also create a map that maps a provider to its parent
+	// Also create a map that maps a provider to its parent, and one that
+	// maps a provider with a base to the keys it could have, closest first
 	parentMap := make(map[dag.Vertex]string)
+	baseKeys := make(map[dag.Vertex][]string)
 	for _, raw := range g.Vertices() {
 		// If it is the flat version, then make it the non-flat version.
 		// We eventually want to get rid of the flat version entirely so
@@ -240,6 +252,15 @@ func (t *ParentProviderTransformer) Transform(g *Graph) error {
 		key := fmt.Sprintf("%s.%s", strings.Join(path, "."), pn.ProviderName())
 		m[key] = raw
 
+		if bn, ok := raw.(GraphNodeProviderBase); ok && bn.ProviderBase() != "" {
+			base := bn.ProviderBase()
+			for i := len(path); i > 0; i-- {
+				baseKeys[raw] = append(baseKeys[raw],
+					fmt.Sprintf("%s.%s", strings.Join(path[:i], "."), base))
+			}
+			continue
+		}
+
 		// Determine the parent if we're non-root. This is length 1 since
 		// the 0 index should be "root" since we normalize above.
 		if len(path) > 1 {
@@ -255,6 +276,14 @@ func (t *ParentProviderTransformer) Transform(g *Graph) error {
 			g.Connect(dag.BasicEdge(v, parent))
 		}
 	}
+	for v, keys := range baseKeys {
+		for _, key := range keys {
+			if base, ok := m[key]; ok {
+				g.Connect(dag.BasicEdge(v, base))
+				break
+			}
+		}
+	}
 
 	return nil
 }
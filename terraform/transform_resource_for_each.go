@@ -0,0 +1,46 @@
+package terraform
+
+import (
+	"sort"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+// ResourceForEachTransformer is a GraphTransformer that expands the
+// for_each out for a specific resource, adding one instance per key.
+//
+// This assumes that the for_each is already interpolated.
+type ResourceForEachTransformer struct {
+	Concrete ConcreteResourceNodeFunc
+
+	ForEach map[string]interface{}
+	Addr    *ResourceAddress
+}
+
+func (t *ResourceForEachTransformer) Transform(g *Graph) error {
+	// Sort the keys so the graph is built deterministically
+	keys := make([]string, 0, len(t.ForEach))
+	for k := range t.ForEach {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		// Build the resource address
+		addr := t.Addr.Copy()
+		addr.Index = -1
+		addr.Key = k
+
+		// Build the abstract node and the concrete one
+		abstract := &NodeAbstractResource{Addr: addr}
+		var node dag.Vertex = abstract
+		if f := t.Concrete; f != nil {
+			node = f(abstract)
+		}
+
+		// Add it to the graph
+		g.Add(node)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package terraform
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/hashicorp/terraform/dag"
+)
+
+// GraphNodeApplyStage is implemented by nodes that can be put in an apply
+// stage with the stage meta-argument.
+type GraphNodeApplyStage interface {
+	// ApplyStage is the stage of the node, or 0 if it isn't in one.
+	ApplyStage() int
+}
+
+// StageTransformer is a GraphTransformer that puts barriers between the
+// apply stages, so that every node in a stage is applied before any node in
+// a later stage starts, whether or not they depend on each other. Nodes that
+// aren't in a stage are ordered only by their dependencies.
+//
+// The graph is flat, so stages are shared by all the modules: stage 1 of a
+// module is applied along with stage 1 of every other module.
+//
+// Only creation and updates are staged. Destruction is ordered by
+// dependencies and the destroy priority, as always.
+//
+// This must run after targeting, so that stages that have nothing to apply
+// don't get a barrier.
+type StageTransformer struct{}
+
+func (t *StageTransformer) Transform(g *Graph) error {
+	stages := make(map[int][]dag.Vertex)
+	for _, v := range g.Vertices() {
+		sn, ok := v.(GraphNodeApplyStage)
+		if !ok || sn.ApplyStage() == 0 {
+			continue
+		}
+
+		stages[sn.ApplyStage()] = append(stages[sn.ApplyStage()], v)
+	}
+
+	numbers := make([]int, 0, len(stages))
+	for stage := range stages {
+		numbers = append(numbers, stage)
+	}
+	sort.Ints(numbers)
+
+	// Each barrier depends on every node in its stage, and every node in a
+	// stage depends on the barrier of the stage before it.
+	var prev dag.Vertex
+	for _, stage := range numbers {
+		barrier := g.Add(&NodeStageBarrier{Stage: stage})
+		for _, v := range stages[stage] {
+			if prev != nil {
+				g.Connect(dag.BasicEdge(v, prev))
+			}
+			g.Connect(dag.BasicEdge(barrier, v))
+		}
+
+		log.Printf(
+			"[TRACE] StageTransformer: %d node(s) in stage %d",
+			len(stages[stage]), stage)
+		prev = barrier
+	}
+
+	return nil
+}
+
+// NodeStageBarrier is the node that's reached once every node in an apply
+// stage has been applied. It does nothing itself.
+type NodeStageBarrier struct {
+	Stage int
+}
+
+func (n *NodeStageBarrier) Name() string {
+	return fmt.Sprintf("stage %d", n.Stage)
+}
@@ -0,0 +1,76 @@
+package terraform
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStageTransformer(t *testing.T) {
+	g := Graph{Path: RootModulePath}
+	g.Add(&graphNodeStageTest{NameValue: "A", Stage: 2})
+	g.Add(&graphNodeStageTest{NameValue: "B", Stage: 1})
+	g.Add(&graphNodeStageTest{NameValue: "C", Stage: 1})
+	g.Add(&graphNodeStageTest{NameValue: "D"})
+	g.Add(&graphNodeStageTest{NameValue: "E", Stage: 5})
+	tf := &StageTransformer{}
+	if err := tf.Transform(&g); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(g.String())
+	expected := strings.TrimSpace(testTransformStageStr)
+	if actual != expected {
+		t.Fatalf("bad:\n\n%s", actual)
+	}
+}
+
+func TestStageTransformer_none(t *testing.T) {
+	g := Graph{Path: RootModulePath}
+	g.Add(&graphNodeStageTest{NameValue: "A"})
+	g.Add(&graphNodeStageTest{NameValue: "B"})
+	tf := &StageTransformer{}
+	if err := tf.Transform(&g); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := strings.TrimSpace(g.String())
+	expected := strings.TrimSpace(testTransformStageNoneStr)
+	if actual != expected {
+		t.Fatalf("bad:\n\n%s", actual)
+	}
+}
+
+type graphNodeStageTest struct {
+	NameValue string
+	Stage     int
+}
+
+func (n *graphNodeStageTest) Name() string {
+	return n.NameValue
+}
+
+func (n *graphNodeStageTest) ApplyStage() int {
+	return n.Stage
+}
+
+const testTransformStageStr = `
+A
+  stage 1
+B
+C
+D
+E
+  stage 2
+stage 1
+  B
+  C
+stage 2
+  A
+stage 5
+  E
+`
+
+const testTransformStageNoneStr = `
+A
+B
+`
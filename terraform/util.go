@@ -1,7 +1,9 @@
 package terraform
 
 import (
+	"container/heap"
 	"sort"
+	"sync"
 
 	"github.com/hashicorp/terraform/config"
 )
@@ -48,6 +50,143 @@ func (s Semaphore) Release() {
 	}
 }
 
+// PrioritySemaphore is a semaphore whose free slots go to the waiting
+// acquirer with the highest priority. Acquirers with the same priority get
+// slots in the order that they started waiting.
+type PrioritySemaphore struct {
+	lock    sync.Mutex
+	free    int
+	limit   int
+	seq     int
+	waiting prioritySemaphoreQueue
+}
+
+// NewPrioritySemaphore creates a semaphore that allows up to a given limit
+// of simultaneous acquisitions.
+func NewPrioritySemaphore(n int) *PrioritySemaphore {
+	if n == 0 {
+		panic("semaphore with limit 0")
+	}
+
+	return &PrioritySemaphore{free: n, limit: n}
+}
+
+// Acquire is used to acquire an available slot with the given priority.
+// Blocks until available.
+func (s *PrioritySemaphore) Acquire(priority int) {
+	s.lock.Lock()
+	if s.free > 0 {
+		s.free--
+		s.lock.Unlock()
+		return
+	}
+
+	w := &prioritySemaphoreWaiter{
+		priority: priority,
+		seq:      s.seq,
+		ch:       make(chan struct{}),
+	}
+	s.seq++
+	heap.Push(&s.waiting, w)
+	s.lock.Unlock()
+
+	<-w.ch
+}
+
+// TryAcquire is used to do a non-blocking acquire.
+// Returns a bool indicating success
+func (s *PrioritySemaphore) TryAcquire() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.free == 0 {
+		return false
+	}
+
+	s.free--
+	return true
+}
+
+// Release is used to return a slot. Acquire must
+// be called as a pre-condition.
+func (s *PrioritySemaphore) Release() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.free == s.limit {
+		panic("release without an acquire")
+	}
+	s.free++
+	s.wake()
+}
+
+// Limit returns the current limit of simultaneous acquisitions.
+func (s *PrioritySemaphore) Limit() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.limit
+}
+
+// SetLimit changes the limit of simultaneous acquisitions. Lowering the
+// limit doesn't affect the slots that are already acquired, but no slot is
+// given out again until fewer than the new limit are in use.
+func (s *PrioritySemaphore) SetLimit(n int) {
+	if n <= 0 {
+		panic("semaphore with limit 0")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.free += n - s.limit
+	s.limit = n
+	s.wake()
+}
+
+// wake hands the free slots straight to the waiters with the highest
+// priority, so that nothing can take them in between. The lock must be
+// held.
+func (s *PrioritySemaphore) wake() {
+	for s.free > 0 && s.waiting.Len() > 0 {
+		w := heap.Pop(&s.waiting).(*prioritySemaphoreWaiter)
+		s.free--
+		close(w.ch)
+	}
+}
+
+type prioritySemaphoreWaiter struct {
+	priority int
+	seq      int
+	ch       chan struct{}
+}
+
+// prioritySemaphoreQueue implements heap.Interface, with the waiter to
+// give the next slot to first.
+type prioritySemaphoreQueue []*prioritySemaphoreWaiter
+
+func (q prioritySemaphoreQueue) Len() int      { return len(q) }
+func (q prioritySemaphoreQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q prioritySemaphoreQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+
+	return q[i].seq < q[j].seq
+}
+
+func (q *prioritySemaphoreQueue) Push(x interface{}) {
+	*q = append(*q, x.(*prioritySemaphoreWaiter))
+}
+
+func (q *prioritySemaphoreQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	*q = old[:n-1]
+	return w
+}
+
 func resourceProvider(resourceType, explicitProvider string) string {
 	return config.ResourceProviderFullName(resourceType, explicitProvider)
 }
@@ -3,6 +3,7 @@ package terraform
 import (
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
@@ -34,6 +35,124 @@ func TestSemaphore(t *testing.T) {
 	s.Release()
 }
 
+func TestPrioritySemaphore(t *testing.T) {
+	s := NewPrioritySemaphore(2)
+	timer := time.AfterFunc(time.Second, func() {
+		panic("deadlock")
+	})
+	defer timer.Stop()
+
+	s.Acquire(0)
+	if !s.TryAcquire() {
+		t.Fatalf("should acquire")
+	}
+	if s.TryAcquire() {
+		t.Fatalf("should not acquire")
+	}
+	s.Release()
+	s.Release()
+
+	// This release should panic
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatalf("should panic")
+		}
+	}()
+	s.Release()
+}
+
+func TestPrioritySemaphore_order(t *testing.T) {
+	s := NewPrioritySemaphore(1)
+	timer := time.AfterFunc(5*time.Second, func() {
+		panic("deadlock")
+	})
+	defer timer.Stop()
+
+	// Hold the only slot while the others queue up behind it
+	s.Acquire(0)
+
+	var lock sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i, p := range []int{1, 5, -1, 5, 10} {
+		wg.Add(1)
+		go func(i, p int) {
+			defer wg.Done()
+			s.Acquire(p)
+			lock.Lock()
+			order = append(order, i)
+			lock.Unlock()
+			s.Release()
+		}(i, p)
+
+		// Wait for each to be queued, so the order of equal priorities
+		// is known.
+		for {
+			s.lock.Lock()
+			n := s.waiting.Len()
+			s.lock.Unlock()
+			if n == i+1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	s.Release()
+	wg.Wait()
+
+	expected := []int{4, 1, 3, 0, 2}
+	if !reflect.DeepEqual(order, expected) {
+		t.Fatalf("bad: %#v", order)
+	}
+}
+
+func TestPrioritySemaphore_setLimit(t *testing.T) {
+	s := NewPrioritySemaphore(2)
+	timer := time.AfterFunc(5*time.Second, func() {
+		panic("deadlock")
+	})
+	defer timer.Stop()
+
+	s.Acquire(0)
+	s.Acquire(0)
+
+	// Lowering the limit keeps both slots acquired, but neither is given
+	// out again once released.
+	s.SetLimit(1)
+	s.Release()
+	if s.TryAcquire() {
+		t.Fatalf("should not acquire")
+	}
+	s.Release()
+	if !s.TryAcquire() {
+		t.Fatalf("should acquire")
+	}
+
+	// Raising the limit hands the new slots to the waiters
+	acquired := make(chan struct{})
+	go func() {
+		s.Acquire(0)
+		close(acquired)
+	}()
+	for {
+		s.lock.Lock()
+		n := s.waiting.Len()
+		s.lock.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.SetLimit(2)
+	<-acquired
+
+	if s.Limit() != 2 {
+		t.Fatalf("bad: %d", s.Limit())
+	}
+}
+
 func TestStrSliceContains(t *testing.T) {
 	if strSliceContains(nil, "foo") {
 		t.Fatalf("Bad")
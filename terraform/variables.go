@@ -164,3 +164,35 @@ func varSetMap(current map[string]interface{}, k string, v interface{}) error {
 	}
 	return nil
 }
+
+// PersistedVariables returns a copy of vars, the values of the variables of
+// the root module of m, without the values of ephemeral variables, which
+// must never be stored, such as in a plan.
+func PersistedVariables(m *module.Tree, vars map[string]interface{}) map[string]interface{} {
+	ephemeral := ephemeralVariables(m)
+	result := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		if !ephemeral[k] {
+			result[k] = v
+		}
+	}
+
+	return result
+}
+
+// ephemeralVariables returns the names of the ephemeral variables of the
+// root module of m.
+func ephemeralVariables(m *module.Tree) map[string]bool {
+	result := make(map[string]bool)
+	if m == nil || m.Config() == nil {
+		return result
+	}
+
+	for _, v := range m.Config().Variables {
+		if v.Ephemeral {
+			result[v.Name] = true
+		}
+	}
+
+	return result
+}